@@ -0,0 +1,109 @@
+// Package apikey identifies the clients of tile requests by API key. Each key is scoped to
+// maps and has its own rate limit, so tegola can be exposed to third parties directly.
+// The keys are kept in a Store, i.e. a file, a database table or the cache backend.
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-spatial/tegola/dict"
+)
+
+// ErrKeyNotFound is returned by a Store deleting a key it doesn't have
+var ErrKeyNotFound = errors.New("apikey: key not found")
+
+// Key is an API key and the maps and rate its requests are limited to
+type Key struct {
+	// Key is the secret value requests supply
+	Key string `json:"key"`
+	// Name of the key's owner, for logs and usage reports
+	Name string `json:"name,omitempty"`
+	// Class is the key class of the key's requests, for geofences and cache bypass
+	Class string `json:"class,omitempty"`
+	// Maps the key may request the tiles of. Every map when empty.
+	Maps []string `json:"maps,omitempty"`
+	// RateLimit is the number of requests per second. Unlimited when 0.
+	RateLimit float64 `json:"rate_limit,omitempty"`
+	// Burst is the number of requests allowed at once above the rate. Defaults to the rate
+	// limit rounded up.
+	Burst int `json:"burst,omitempty"`
+	// Disabled keys are rejected
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// AllowsMap reports if the key may request the tiles of the map
+func (k Key) AllowsMap(name string) bool {
+	if len(k.Maps) == 0 {
+		return true
+	}
+	for _, m := range k.Maps {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the key can be stored
+func (k Key) Validate() error {
+	if k.Key == "" {
+		return errors.New("apikey: key is required")
+	}
+	if k.RateLimit < 0 || k.Burst < 0 {
+		return fmt.Errorf("apikey: key (%v) rate_limit and burst can't be negative", k.Name)
+	}
+	return nil
+}
+
+// Store keeps the API keys
+type Store interface {
+	// Keys returns all the keys of the store
+	Keys(ctx context.Context) ([]Key, error)
+	// Put adds the key to the store, replacing the key with the same value
+	Put(ctx context.Context, key Key) error
+	// Delete removes the key with the value from the store, or returns ErrKeyNotFound
+	Delete(ctx context.Context, key string) error
+}
+
+// InitFunc initializes a store given a config map.
+// The InitFunc should validate the config map, and report any errors.
+// This is called by the For function.
+type InitFunc func(dict.Dicter) (Store, error)
+
+var stores map[string]InitFunc
+
+// Register is called by the init functions of the stores
+func Register(storeType string, init InitFunc) error {
+	if stores == nil {
+		stores = make(map[string]InitFunc)
+	}
+
+	if _, ok := stores[storeType]; ok {
+		return fmt.Errorf("apikey: store (%v) already exists", storeType)
+	}
+	stores[storeType] = init
+
+	return nil
+}
+
+// Registered returns the stores that have been registered
+func Registered() (s []string) {
+	for k := range stores {
+		s = append(s, k)
+	}
+	sort.Strings(s)
+	return s
+}
+
+// For returns a configured store of the given type, provided the correct config map
+func For(storeType string, config dict.Dicter) (Store, error) {
+	init, ok := stores[storeType]
+	if !ok {
+		return nil, fmt.Errorf("apikey: no store registered by the type: (%v)", storeType)
+	}
+
+	return init(config)
+}
@@ -0,0 +1,177 @@
+package apikey
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/ratelimit"
+)
+
+// DefaultRefreshInterval is how often the keys are loaded from the store
+const DefaultRefreshInterval = time.Minute
+
+// Keys are the API keys of a store, held in memory with the state of their rate limits
+type Keys struct {
+	Store Store
+	// Required rejects the tile requests without a known key
+	Required bool
+	// RefreshInterval is how often Run loads the keys. Defaults to DefaultRefreshInterval.
+	RefreshInterval time.Duration
+
+	mu   sync.RWMutex
+	keys map[string]*entry
+}
+
+// entry is a key and its token bucket
+type entry struct {
+	Key
+
+	mu       sync.Mutex
+	bucket   ratelimit.Bucket
+	requests uint64
+	limited  uint64
+}
+
+// Usage reports the requests of a key
+type Usage struct {
+	Key
+	// Requests is the number of requests of the key since the process started
+	Requests uint64 `json:"requests"`
+	// Limited is the number of requests rejected by the key's rate limit
+	Limited uint64 `json:"limited"`
+}
+
+// New returns the keys of the store. The keys are loaded with Refresh.
+func New(store Store) *Keys {
+	return &Keys{Store: store, keys: map[string]*entry{}}
+}
+
+// Refresh loads the keys of the store. The rate limits of the keys which didn't change are
+// kept.
+func (ks *Keys) Refresh(ctx context.Context) error {
+	keys, err := ks.Store.Keys(ctx)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	entries := make(map[string]*entry, len(keys))
+	for _, k := range keys {
+		if e, ok := ks.keys[k.Key]; ok && e.RateLimit == k.RateLimit && e.Burst == k.Burst {
+			e.mu.Lock()
+			e.Key = k
+			e.mu.Unlock()
+			entries[k.Key] = e
+			continue
+		}
+		entries[k.Key] = &entry{Key: k, bucket: ratelimit.Bucket{Rate: k.RateLimit, Burst: ratelimit.BurstFor(k.RateLimit, k.Burst)}}
+	}
+	ks.keys = entries
+	return nil
+}
+
+// Run refreshes the keys every RefreshInterval until the context is done, so the keys changed
+// in the store by other instances or tools are picked up. Failed refreshes are logged and the
+// previous keys are kept.
+func (ks *Keys) Run(ctx context.Context) {
+	interval := ks.RefreshInterval
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.Refresh(ctx); err != nil {
+				log.Errorf("apikey: refreshing the keys: %v", err)
+			}
+		}
+	}
+}
+
+// Lookup returns the key with the value
+func (ks *Keys) Lookup(key string) (Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	e, ok := ks.keys[key]
+	if !ok {
+		return Key{}, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.Key, true
+}
+
+// Allow takes a request from the rate limit of the key. When the key is over its limit false
+// is returned with the time until the next request is allowed.
+func (ks *Keys) Allow(key string, now time.Time) (bool, time.Duration) {
+	ks.mu.RLock()
+	e, ok := ks.keys[key]
+	ks.mu.RUnlock()
+	if !ok {
+		return false, 0
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests++
+	if e.RateLimit <= 0 {
+		return true, 0
+	}
+
+	allowed, retry := e.bucket.Take(now)
+	if !allowed {
+		e.limited++
+	}
+	return allowed, retry
+}
+
+// Usage returns the usage of the keys, sorted by name and key
+func (ks *Keys) Usage() []Usage {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	usage := make([]Usage, 0, len(ks.keys))
+	for _, e := range ks.keys {
+		e.mu.Lock()
+		usage = append(usage, Usage{Key: e.Key, Requests: e.requests, Limited: e.limited})
+		e.mu.Unlock()
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Name != usage[j].Name {
+			return usage[i].Name < usage[j].Name
+		}
+		return usage[i].Key.Key < usage[j].Key.Key
+	})
+	return usage
+}
+
+// Put adds the key to the store and refreshes the keys
+func (ks *Keys) Put(ctx context.Context, key Key) error {
+	if err := key.Validate(); err != nil {
+		return err
+	}
+	if err := ks.Store.Put(ctx, key); err != nil {
+		return err
+	}
+	return ks.Refresh(ctx)
+}
+
+// Delete removes the key from the store and refreshes the keys
+func (ks *Keys) Delete(ctx context.Context, key string) error {
+	if err := ks.Store.Delete(ctx, key); err != nil {
+		return err
+	}
+	return ks.Refresh(ctx)
+}
@@ -0,0 +1,116 @@
+package apikey_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/apikey"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/dict"
+)
+
+func TestKeysAllow(t *testing.T) {
+	c, _ := memory.New(nil)
+	keys := apikey.New(apikey.NewCacheStore(c))
+	ctx := context.Background()
+
+	if err := keys.Put(ctx, apikey.Key{Key: "limited", Name: "acme", RateLimit: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := keys.Put(ctx, apikey.Key{Key: "unlimited", Name: "internal"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	// the burst defaults to the rate
+	for i := 0; i < 2; i++ {
+		if ok, _ := keys.Allow("limited", now); !ok {
+			t.Fatalf("request %v, expected allowed", i)
+		}
+	}
+	ok, retry := keys.Allow("limited", now)
+	if ok {
+		t.Fatalf("expected the request over the burst to be limited")
+	}
+	if retry != 500*time.Millisecond {
+		t.Errorf("retry, expected 500ms got %v", retry)
+	}
+	// the bucket refills at the rate
+	if ok, _ := keys.Allow("limited", now.Add(500*time.Millisecond)); !ok {
+		t.Errorf("expected the request after the refill to be allowed")
+	}
+
+	for i := 0; i < 100; i++ {
+		if ok, _ := keys.Allow("unlimited", now); !ok {
+			t.Fatalf("expected keys without a rate limit to be allowed")
+		}
+	}
+	if ok, _ := keys.Allow("unknown", now); ok {
+		t.Errorf("expected unknown keys to be rejected")
+	}
+
+	// refreshing keeps the state of the rate limits
+	if err := keys.Refresh(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []apikey.Usage{
+		{Key: apikey.Key{Key: "limited", Name: "acme", RateLimit: 2}, Requests: 4, Limited: 1},
+		{Key: apikey.Key{Key: "unlimited", Name: "internal"}, Requests: 100},
+	}
+	if usage := keys.Usage(); !reflect.DeepEqual(usage, expected) {
+		t.Errorf("usage, expected %+v got %+v", expected, usage)
+	}
+
+	if err := keys.Delete(ctx, "limited"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := keys.Lookup("limited"); ok {
+		t.Errorf("expected the deleted key to be removed")
+	}
+	if err := keys.Delete(ctx, "limited"); err != apikey.ErrKeyNotFound {
+		t.Errorf("error, expected %v got %v", apikey.ErrKeyNotFound, err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tegola-apikey")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keys.json")
+	store, err := apikey.For(apikey.FileStoreType, dict.Dict{"path": path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	keys, err := store.Keys(ctx)
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("expected no keys without a file, got %v, %v", keys, err)
+	}
+
+	key := apikey.Key{Key: "abc", Name: "acme", Maps: []string{"osm"}, RateLimit: 10, Burst: 20}
+	if err := store.Put(ctx, key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the keys are read from the file
+	store, _ = apikey.NewFileStore(dict.Dict{"path": path})
+	keys, err = store.Keys(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []apikey.Key{key}) {
+		t.Errorf("keys, expected %+v got %+v", []apikey.Key{key}, keys)
+	}
+	if !keys[0].AllowsMap("osm") || keys[0].AllowsMap("private") {
+		t.Errorf("expected the key to be scoped to the osm map")
+	}
+}
@@ -0,0 +1,158 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/dict"
+)
+
+const (
+	FileStoreType = "file"
+	// CacheStoreType keeps the keys in the cache backend, see NewCacheStore
+	CacheStoreType = "cache"
+)
+
+const ConfigKeyPath = "path"
+
+func init() {
+	Register(FileStoreType, NewFileStore)
+}
+
+// CacheKey is the cache key the keys of a CacheStore are stored under
+var CacheKey = cache.Key{MapName: "_api_keys"}
+
+// putKey returns the keys with the key added, replacing the key with the same value
+func putKey(keys []Key, key Key) []Key {
+	for i := range keys {
+		if keys[i].Key == key.Key {
+			keys[i] = key
+			return keys
+		}
+	}
+	return append(keys, key)
+}
+
+// deleteKey returns the keys without the key with the value
+func deleteKey(keys []Key, key string) ([]Key, error) {
+	for i := range keys {
+		if keys[i].Key == key {
+			return append(keys[:i], keys[i+1:]...), nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// listStore stores the keys as a JSON list read and written by load and save
+type listStore struct {
+	mu   sync.Mutex
+	load func() ([]byte, error)
+	save func([]byte) error
+}
+
+func (s *listStore) Keys(ctx context.Context) ([]Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.keys()
+}
+
+func (s *listStore) keys() ([]Key, error) {
+	b, err := s.load()
+	if err != nil || len(b) == 0 {
+		return nil, err
+	}
+	var keys []Key
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *listStore) Put(ctx context.Context, key Key) error {
+	return s.update(func(keys []Key) ([]Key, error) { return putKey(keys, key), nil })
+}
+
+func (s *listStore) Delete(ctx context.Context, key string) error {
+	return s.update(func(keys []Key) ([]Key, error) { return deleteKey(keys, key) })
+}
+
+func (s *listStore) update(fn func([]Key) ([]Key, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.keys()
+	if err != nil {
+		return err
+	}
+	if keys, err = fn(keys); err != nil {
+		return err
+	}
+	if keys == nil {
+		keys = []Key{}
+	}
+	b, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.save(b)
+}
+
+// NewFileStore returns a store keeping the keys in a JSON file, a list of keys. The file is
+// created when a key is put. The config supports the following params:
+//
+//	path (string): [Required] the path of the file
+func NewFileStore(config dict.Dicter) (Store, error) {
+	path, err := config.String(ConfigKeyPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listStore{
+		load: func() ([]byte, error) {
+			b, err := ioutil.ReadFile(path)
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return b, err
+		},
+		// replace the file, so it's never read half written
+		save: func(b []byte) error {
+			tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp.Name())
+			if _, err := tmp.Write(b); err != nil {
+				tmp.Close()
+				return err
+			}
+			if err := tmp.Close(); err != nil {
+				return err
+			}
+			return os.Rename(tmp.Name(), path)
+		},
+	}, nil
+}
+
+// NewCacheStore returns a store keeping the keys in the cache backend under CacheKey, so the
+// instances of a deployment sharing the cache share the keys. Keys put by instances at the
+// same time may be lost.
+func NewCacheStore(c cache.Interface) Store {
+	return &listStore{
+		load: func() ([]byte, error) {
+			key := CacheKey
+			b, _, err := c.Get(&key)
+			return b, err
+		},
+		save: func(b []byte) error {
+			key := CacheKey
+			return c.Set(&key, b)
+		},
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-spatial/geom/slippy"
 	"github.com/go-spatial/tegola"
@@ -116,22 +117,25 @@ func (a *Atlas) SeedMapTile(ctx context.Context, m Map, z, x, y uint) error {
 	}
 
 	tile := slippy.NewTile(z, x, y)
+	start := time.Now()
 
-	// encode the tile
+	// encode the tile, tracking the soonest feature expiry
+	ctx = WithExpiry(ctx)
 	b, err := m.Encode(ctx, tile)
-	if err != nil {
-		return err
-	}
+	if err == nil {
+		// cache key
+		key := cache.Key{
+			MapName: m.Name,
+			Z:       z,
+			X:       x,
+			Y:       y,
+		}
 
-	// cache key
-	key := cache.Key{
-		MapName: m.Name,
-		Z:       z,
-		X:       x,
-		Y:       y,
+		err = cache.SetExpires(a.cacher, &key, b, Expiry(ctx))
 	}
 
-	return a.cacher.Set(&key, b)
+	reportSeed(ctx, m.Name, z, x, y, start, err)
+	return err
 }
 
 // PurgeMapTile will purge a map tile from the configured cache backend
@@ -201,6 +205,43 @@ func (a *Atlas) AddMap(m Map) {
 	a.maps[m.Name] = m
 }
 
+// RemoveMap removes the map by name. false is returned when the map does not exist.
+func (a *Atlas) RemoveMap(mapName string) bool {
+	if a == nil {
+		// Use the default Atlas if a, is nil. This way the empty value is
+		// still useful.
+		return defaultAtlas.RemoveMap(mapName)
+	}
+	a.Lock()
+	defer a.Unlock()
+
+	if _, ok := a.maps[mapName]; !ok {
+		return false
+	}
+	delete(a.maps, mapName)
+	return true
+}
+
+// ReplaceMaps replaces all the maps of the atlas at once, so requests never see a mix of
+// the old and new maps
+func (a *Atlas) ReplaceMaps(maps []Map) {
+	if a == nil {
+		// Use the default Atlas if a, is nil. This way the empty value is
+		// still useful.
+		defaultAtlas.ReplaceMaps(maps)
+		return
+	}
+
+	byName := make(map[string]Map, len(maps))
+	for _, m := range maps {
+		byName[m.Name] = m
+	}
+
+	a.Lock()
+	a.maps = byName
+	a.Unlock()
+}
+
 // GetCache returns the registered cache if one is registered, otherwise nil
 func (a *Atlas) GetCache() cache.Interface {
 	if a == nil {
@@ -219,7 +260,20 @@ func (a *Atlas) SetCache(c cache.Interface) {
 		defaultAtlas.SetCache(c)
 		return
 	}
-	a.cacher = c
+	if c == nil {
+		a.cacher = nil
+		return
+	}
+	// the keys of the maps' tiles include their cache version
+	a.cacher = cache.NewVersioned(c, a.cacheVersion)
+}
+
+// cacheVersion returns the cache version of the map, "" for unknown maps
+func (a *Atlas) cacheVersion(mapName string) string {
+	a.RLock()
+	defer a.RUnlock()
+
+	return a.maps[mapName].CacheVersion
 }
 
 // AllMaps returns all registered maps in defaultAtlas
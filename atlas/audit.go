@@ -0,0 +1,128 @@
+package atlas
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// auditMaxFeatures bounds the features of a layer checked by AuditCoordinates
+const auditMaxFeatures = 1000
+
+// ErrCoordinatesOutOfTile is returned by AuditCoordinates when a layer returns a feature
+// outside of the tile it was asked for, which is usually a misconfigured SRID
+type ErrCoordinatesOutOfTile struct {
+	Map     string
+	Layer   string
+	Z, X, Y uint
+	// FeatureID and Extent, in the map's SRID, of the misplaced feature
+	FeatureID uint64
+	Extent    *geom.Extent
+}
+
+// NearNullIsland reports if the feature is within a few hundred meters of 0,0, where
+// geographic coordinates read as web mercator meters end up
+func (e ErrCoordinatesOutOfTile) NearNullIsland() bool {
+	return math.Abs(e.Extent.MinX()) <= 180 && math.Abs(e.Extent.MaxX()) <= 180 &&
+		math.Abs(e.Extent.MinY()) <= 90 && math.Abs(e.Extent.MaxY()) <= 90
+}
+
+func (e ErrCoordinatesOutOfTile) Error() string {
+	msg := fmt.Sprintf("atlas: map (%v) layer (%v) returned feature (%v) with extent %v outside of the sample tile %v/%v/%v, check the SRID of the layer's data",
+		e.Map, e.Layer, e.FeatureID, e.Extent, e.Z, e.X, e.Y)
+	if e.NearNullIsland() {
+		msg += " (the feature is near null island, geographic coordinates may be read as web mercator)"
+	}
+	return msg
+}
+
+// sampleTile returns the tile of the map's center, or of the center of its bounds when the
+// center is not set, at the zoom of the center limited to the zooms of the layer
+func (m Map) sampleTile(l Layer) *slippy.Tile {
+	lon, lat, zoom := m.Center[0], m.Center[1], m.Center[2]
+	if m.Center == [3]float64{} && m.Bounds != nil {
+		lon, lat = (m.Bounds.MinX()+m.Bounds.MaxX())/2, (m.Bounds.MinY()+m.Bounds.MaxY())/2
+	}
+
+	z := uint(zoom)
+	if z < l.MinZoom {
+		z = l.MinZoom
+	}
+	if l.MaxZoom != 0 && z > l.MaxZoom {
+		z = l.MaxZoom
+	}
+	return slippy.NewTileLatLon(z, lat, lon)
+}
+
+// AuditCoordinates fetches the sample tile of each of the map's layers and checks that
+// the features returned by the layer's provider are within the tile's buffered extent.
+// Features far from the tile they were requested for are usually the result of an SRID
+// misconfiguration, i.e. geographic coordinates stored in a column declared as web mercator.
+// Layers of MVT providers are not audited.
+func (m Map) AuditCoordinates(ctx context.Context) error {
+	for _, l := range m.Layers {
+		if l.Provider == nil {
+			continue
+		}
+
+		tile := m.sampleTile(l)
+		ptile := provider.NewTile(tile.Z, tile.X, tile.Y, uint(m.TileBuffer), uint(m.SRID))
+		buffered, _ := ptile.BufferedExtent()
+
+		var (
+			checked int
+			outside *ErrCoordinatesOutOfTile
+		)
+		layerCtx, cancel := l.layerContext(ctx)
+		err := l.Provider.TileFeatures(layerCtx, l.ProviderLayerID, ptile, func(f *provider.Feature) error {
+			if checked == auditMaxFeatures {
+				return provider.ErrCanceled
+			}
+			checked++
+
+			geo := f.Geometry
+			if f.SRID != m.SRID {
+				g, err := basic.ToWebMercator(f.SRID, geo)
+				if err != nil {
+					return fmt.Errorf("unable to transform geometry to webmercator from SRID (%v) for feature %v due to error: %w", f.SRID, f.ID, err)
+				}
+				geo = g
+			}
+
+			ext, err := geom.NewExtentFromGeometry(geo)
+			if err != nil {
+				// empty geometries have no extent
+				return nil
+			}
+			// Extent.Intersect is false for the extents of points, which have no area
+			if ext.MinX() > buffered.MaxX() || ext.MaxX() < buffered.MinX() || ext.MinY() > buffered.MaxY() || ext.MaxY() < buffered.MinY() {
+				z, x, y := tile.ZXY()
+				outside = &ErrCoordinatesOutOfTile{
+					Map:       m.Name,
+					Layer:     l.MVTName(),
+					Z:         z,
+					X:         x,
+					Y:         y,
+					FeatureID: f.ID,
+					Extent:    ext,
+				}
+				return provider.ErrCanceled
+			}
+			return nil
+		})
+		cancel()
+
+		if outside != nil {
+			return *outside
+		}
+		if err != nil && checked < auditMaxFeatures {
+			return fmt.Errorf("atlas: map (%v) layer (%v) sample tile %v/%v/%v: %w", m.Name, l.MVTName(), tile.Z, tile.X, tile.Y, err)
+		}
+	}
+	return nil
+}
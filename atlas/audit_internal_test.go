@@ -0,0 +1,86 @@
+package atlas
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+// pointTiler returns a point for every tile
+type pointTiler struct {
+	test.TileProvider
+	point geom.Point
+	srid  uint64
+}
+
+func (pt *pointTiler) TileFeatures(ctx context.Context, layer string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	return fn(&provider.Feature{ID: 1, Geometry: pt.point, SRID: pt.srid})
+}
+
+func TestAuditCoordinates(t *testing.T) {
+	type tcase struct {
+		tiler          provider.Tiler
+		center         [3]float64
+		err            error
+		nearNullIsland bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			m := NewWebMercatorMap("test")
+			m.Center = tc.center
+			m.Layers = []Layer{{Name: "a", ProviderLayerID: "test-layer", Provider: tc.tiler}}
+
+			err := m.AuditCoordinates(context.Background())
+			if tc.err == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			e, ok := err.(ErrCoordinatesOutOfTile)
+			if !ok {
+				t.Fatalf("expected ErrCoordinatesOutOfTile got %v", err)
+			}
+			if e.Map != "test" || e.Layer != "a" || e.FeatureID != 1 {
+				t.Errorf("unexpected error %+v", e)
+			}
+			if e.NearNullIsland() != tc.nearNullIsland {
+				t.Errorf("near null island, expected %v got %v", tc.nearNullIsland, e.NearNullIsland())
+			}
+		}
+	}
+
+	// berlin
+	center := [3]float64{13.4, 52.5, 12}
+
+	tests := map[string]tcase{
+		"in tile": {
+			tiler:  &pointTiler{point: geom.Point{13.4, 52.5}, srid: tegola.WGS84},
+			center: center,
+		},
+		"degrees as web mercator": {
+			tiler:          &pointTiler{point: geom.Point{13.4, 52.5}, srid: tegola.WebMercator},
+			center:         center,
+			err:            ErrCoordinatesOutOfTile{},
+			nearNullIsland: true,
+		},
+		"other tile": {
+			tiler:  &pointTiler{point: geom.Point{2.35, 48.85}, srid: tegola.WGS84},
+			center: center,
+			err:    ErrCoordinatesOutOfTile{},
+		},
+		"world tile": {
+			tiler: &pointTiler{point: geom.Point{13.4, 52.5}, srid: tegola.WebMercator},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,69 @@
+package atlas
+
+import "time"
+
+// Window is a window of time a map or layer is served. A zero From or Until leaves the window
+// open at that end. Until is exclusive.
+type Window struct {
+	From  time.Time
+	Until time.Time
+}
+
+// Contains reports if t is within the window
+func (w Window) Contains(t time.Time) bool {
+	return (w.From.IsZero() || !t.Before(w.From)) && (w.Until.IsZero() || t.Before(w.Until))
+}
+
+// Availability is the set of windows a map or layer is served in. An empty Availability is
+// always available.
+type Availability []Window
+
+// Available reports if t is within any of the windows
+func (a Availability) Available(t time.Time) bool {
+	if len(a) == 0 {
+		return true
+	}
+	for _, w := range a {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextChange returns the soonest window boundary after t, when the availability may change.
+// The zero time is returned if the availability doesn't change after t.
+func (a Availability) NextChange(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range a {
+		for _, b := range [2]time.Time{w.From, w.Until} {
+			if b.After(t) && (next.IsZero() || b.Before(next)) {
+				next = b
+			}
+		}
+	}
+	return next
+}
+
+// FilterLayersByAvailability returns a copy of a Map with a subset of layers which are available
+// at now. The soonest change in the availability of the map or its layers is kept so encoded
+// tiles expire (and their cache entries are bounded) when the layers served change.
+func (m Map) FilterLayersByAvailability(now time.Time) Map {
+	var layers []Layer
+
+	next := m.Availability.NextChange(now)
+	for i := range m.Layers {
+		if n := m.Layers[i].Availability.NextChange(now); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+		if m.Layers[i].Availability.Available(now) {
+			layers = append(layers, m.Layers[i])
+		}
+	}
+
+	// overwrite the Map's layers with our subset
+	m.Layers = layers
+	m.availabilityChange = next
+
+	return m
+}
@@ -0,0 +1,105 @@
+package atlas
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilterLayersByAvailability(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 6, d, 0, 0, 0, 0, time.UTC) }
+
+	type tcase struct {
+		m         Map
+		now       time.Time
+		available bool
+		layers    []string
+		change    time.Time
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			if got := tc.m.Availability.Available(tc.now); got != tc.available {
+				t.Errorf("available, expected %v got %v", tc.available, got)
+			}
+
+			m := tc.m.FilterLayersByAvailability(tc.now)
+			if len(m.Layers) != len(tc.layers) {
+				t.Fatalf("layers, expected %v got %v", len(tc.layers), len(m.Layers))
+			}
+			for i := range m.Layers {
+				if m.Layers[i].Name != tc.layers[i] {
+					t.Errorf("layer %v, expected %v got %v", i, tc.layers[i], m.Layers[i].Name)
+				}
+			}
+			if !m.availabilityChange.Equal(tc.change) {
+				t.Errorf("change, expected %v got %v", tc.change, m.availabilityChange)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"always available": {
+			m: Map{
+				Layers: []Layer{{Name: "a"}, {Name: "b"}},
+			},
+			now:       day(2),
+			available: true,
+			layers:    []string{"a", "b"},
+		},
+		"switch datasets": {
+			m: Map{
+				Layers: []Layer{
+					{Name: "old", Availability: Availability{{Until: day(5)}}},
+					{Name: "new", Availability: Availability{{From: day(5)}}},
+				},
+			},
+			now:       day(2),
+			available: true,
+			layers:    []string{"old"},
+			change:    day(5),
+		},
+		"switched datasets": {
+			m: Map{
+				Layers: []Layer{
+					{Name: "old", Availability: Availability{{Until: day(5)}}},
+					{Name: "new", Availability: Availability{{From: day(5)}}},
+				},
+			},
+			now:       day(5),
+			available: true,
+			layers:    []string{"new"},
+		},
+		"map window ends first": {
+			m: Map{
+				Availability: Availability{{From: day(1), Until: day(3)}},
+				Layers: []Layer{
+					{Name: "a", Availability: Availability{{Until: day(10)}}},
+				},
+			},
+			now:       day(2),
+			available: true,
+			layers:    []string{"a"},
+			change:    day(3),
+		},
+		"map not yet available": {
+			m: Map{
+				Availability: Availability{{From: day(4), Until: day(6)}, {From: day(8)}},
+				Layers:       []Layer{{Name: "a"}},
+			},
+			now:    day(2),
+			layers: []string{"a"},
+			change: day(4),
+		},
+		"between windows": {
+			m: Map{
+				Availability: Availability{{From: day(4), Until: day(6)}, {From: day(8)}},
+			},
+			now:    day(7),
+			change: day(8),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
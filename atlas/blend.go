@@ -0,0 +1,25 @@
+package atlas
+
+import (
+	"github.com/go-spatial/geom/encoding/mvt"
+)
+
+// blendLayers merges the encoded layers sharing a name into the first layer of the name: the
+// features of the map's layers with the same MVTName are one layer of the tile, in the order of
+// the map's layers. nil layers, of omitted layers, are skipped.
+func blendLayers(layers []*mvt.Layer) []*mvt.Layer {
+	blended := make([]*mvt.Layer, 0, len(layers))
+	byName := make(map[string]*mvt.Layer, len(layers))
+	for _, l := range layers {
+		if l == nil {
+			continue
+		}
+		if first, ok := byName[l.Name]; ok {
+			first.AddFeatures(l.Features()...)
+			continue
+		}
+		byName[l.Name] = l
+		blended = append(blended, l)
+	}
+	return blended
+}
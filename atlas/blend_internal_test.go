@@ -0,0 +1,84 @@
+package atlas
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/mvt"
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola"
+)
+
+func TestBlendLayers(t *testing.T) {
+	feature := func(id uint64) mvt.Feature {
+		return mvt.Feature{ID: &id, Geometry: geom.Point{1, 1}}
+	}
+	layer := func(name string, ids ...uint64) *mvt.Layer {
+		l := &mvt.Layer{Name: name}
+		for _, id := range ids {
+			l.AddFeatures(feature(id))
+		}
+		return l
+	}
+
+	type tcase struct {
+		layers   []*mvt.Layer
+		expected []*mvt.Layer
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			got := blendLayers(tc.layers)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"distinct names": {
+			layers:   []*mvt.Layer{layer("roads", 1), layer("water", 2)},
+			expected: []*mvt.Layer{layer("roads", 1), layer("water", 2)},
+		},
+		"shared name": {
+			layers:   []*mvt.Layer{layer("roads", 1), layer("water", 2), layer("roads", 3, 4)},
+			expected: []*mvt.Layer{layer("roads", 1, 3, 4), layer("water", 2)},
+		},
+		"omitted layers": {
+			layers:   []*mvt.Layer{nil, layer("roads", 1), nil, layer("roads", 2)},
+			expected: []*mvt.Layer{layer("roads", 1, 2)},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestEncodeBlendedLayers(t *testing.T) {
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{
+		{Name: "places", ProviderLayerID: "test-layer", Provider: &pointTiler{point: geom.Point{13.4, 52.5}, srid: tegola.WGS84}},
+		{Name: "places", ProviderLayerID: "test-layer", Provider: &pointTiler{point: geom.Point{13.5, 52.4}, srid: tegola.WGS84}},
+	}
+
+	b, err := m.encodeMVTTile(context.Background(), slippy.NewTile(0, 0, 0))
+	if err != nil {
+		t.Fatalf("error, expected nil got %v", err)
+	}
+	var dst vectorTile.Tile
+	if err := proto.Unmarshal(b, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if len(dst.Layers) != 1 || dst.Layers[0].GetName() != "places" {
+		t.Fatalf("layers, expected places got %v", dst.Layers)
+	}
+	if len(dst.Layers[0].Features) != 2 {
+		t.Errorf("features, expected 2 got %v", len(dst.Layers[0].Features))
+	}
+}
@@ -3,4 +3,6 @@ package atlas
 // The point of this file is to load and register the default cache backends
 import (
 	_ "github.com/go-spatial/tegola/cache/file"
+	_ "github.com/go-spatial/tegola/cache/memory"
+	_ "github.com/go-spatial/tegola/cache/tiered"
 )
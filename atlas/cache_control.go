@@ -0,0 +1,28 @@
+package atlas
+
+import "time"
+
+// CacheControl is how long clients and shared caches (i.e. CDNs) may reuse the tiles of a
+// map at a range of zooms
+type CacheControl struct {
+	MinZoom uint
+	MaxZoom uint
+	// MaxAge is the time clients and shared caches reuse a tile for
+	MaxAge time.Duration
+	// SMaxAge, when set, is the time shared caches reuse a tile for instead of MaxAge
+	SMaxAge time.Duration
+	// StaleWhileRevalidate, when set, is the time a stale tile may be served while it's
+	// revalidated in the background
+	StaleWhileRevalidate time.Duration
+}
+
+// CacheControlAt returns the first of the map's cache controls whose zooms include the zoom.
+// false is returned when none does.
+func (m Map) CacheControlAt(zoom uint) (CacheControl, bool) {
+	for _, cc := range m.CacheControls {
+		if zoom >= cc.MinZoom && zoom <= cc.MaxZoom {
+			return cc, true
+		}
+	}
+	return CacheControl{}, false
+}
@@ -0,0 +1,113 @@
+package atlas
+
+import (
+	"github.com/go-spatial/tegola/provider"
+)
+
+// NewMap registers a new web mercator map without layers. Layers are added with
+// AddLayerFromProvider. An error is returned if a map of the name is already registered.
+func (a *Atlas) NewMap(name string) (Map, error) {
+	if a == nil {
+		// Use the default Atlas if a, is nil. This way the empty value is
+		// still useful.
+		return defaultAtlas.NewMap(name)
+	}
+	a.Lock()
+	defer a.Unlock()
+
+	if _, ok := a.maps[name]; ok {
+		return Map{}, ErrMapExists{Name: name}
+	}
+	if a.maps == nil {
+		a.maps = map[string]Map{}
+	}
+
+	m := NewWebMercatorMap(name)
+	a.maps[name] = m
+	return m, nil
+}
+
+// AddLayerFromProvider adds a layer of the provider's layer, providerLayerID, to the registered
+// map. The layer's Provider, ProviderLayerID and GeomType are set from the provider, the rest of
+// the layer, i.e. its Name and zooms, is taken from l.
+func (a *Atlas) AddLayerFromProvider(mapName string, p provider.Tiler, providerLayerID string, l Layer) error {
+	if a == nil {
+		// Use the default Atlas if a, is nil. This way the empty value is
+		// still useful.
+		return defaultAtlas.AddLayerFromProvider(mapName, p, providerLayerID, l)
+	}
+
+	// read the provider's layers before taking the lock, providers may be slow to answer
+	infos, err := p.Layers()
+	if err != nil {
+		return err
+	}
+	var info provider.LayerInfo
+	for i := range infos {
+		if infos[i].ID() == providerLayerID {
+			info = infos[i]
+			break
+		}
+	}
+	if info == nil {
+		return ErrProviderLayerNotFound{Map: mapName, ProviderLayer: providerLayerID}
+	}
+
+	l.Provider = p
+	l.ProviderLayerID = providerLayerID
+	l.GeomType = info.GeomType()
+
+	a.Lock()
+	defer a.Unlock()
+
+	m, ok := a.maps[mapName]
+	if !ok {
+		return ErrMapNotFound{Name: mapName}
+	}
+
+	// the map's layers are copied, the maps handed out before keep their layers
+	layers := make([]Layer, len(m.Layers), len(m.Layers)+1)
+	copy(layers, m.Layers)
+	m.Layers = append(layers, l)
+
+	a.maps[mapName] = m
+	return nil
+}
+
+// ReplaceMap replaces a registered map with the map of the same name. Unlike AddMap an error
+// is returned if the map is not registered, so a map removed meanwhile isn't added back.
+func (a *Atlas) ReplaceMap(m Map) error {
+	if a == nil {
+		// Use the default Atlas if a, is nil. This way the empty value is
+		// still useful.
+		return defaultAtlas.ReplaceMap(m)
+	}
+	a.Lock()
+	defer a.Unlock()
+
+	if _, ok := a.maps[m.Name]; !ok {
+		return ErrMapNotFound{Name: m.Name}
+	}
+	a.maps[m.Name] = m
+	return nil
+}
+
+// NewMap registers a new map without layers with defaultAtlas
+func NewMap(name string) (Map, error) {
+	return defaultAtlas.NewMap(name)
+}
+
+// AddLayerFromProvider adds a layer of the provider's layer to a map of defaultAtlas
+func AddLayerFromProvider(mapName string, p provider.Tiler, providerLayerID string, l Layer) error {
+	return defaultAtlas.AddLayerFromProvider(mapName, p, providerLayerID, l)
+}
+
+// RemoveMap removes the map by name from defaultAtlas. false is returned when the map does not exist.
+func RemoveMap(mapName string) bool {
+	return defaultAtlas.RemoveMap(mapName)
+}
+
+// ReplaceMap replaces a registered map of defaultAtlas
+func ReplaceMap(m Map) error {
+	return defaultAtlas.ReplaceMap(m)
+}
@@ -0,0 +1,115 @@
+package atlas_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+func TestAtlasCompose(t *testing.T) {
+	a := &atlas.Atlas{}
+
+	if _, err := a.NewMap("roads"); err != nil {
+		t.Fatalf("new map, unexpected error: %v", err)
+	}
+	if _, err := a.NewMap("roads"); !errors.As(err, &atlas.ErrMapExists{}) {
+		t.Errorf("new map twice, expected ErrMapExists got %v", err)
+	}
+
+	// a copy of the map before the layer is added
+	before, _ := a.Map("roads")
+
+	err := a.AddLayerFromProvider("roads", &test.TileProvider{}, "test-layer", atlas.Layer{Name: "land", MinZoom: 2, MaxZoom: 8})
+	if err != nil {
+		t.Fatalf("add layer, unexpected error: %v", err)
+	}
+	m, err := a.Map("roads")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Layers) != 1 {
+		t.Fatalf("layers, expected 1 got %v", len(m.Layers))
+	}
+	l := m.Layers[0]
+	if l.Name != "land" || l.ProviderLayerID != "test-layer" || l.MinZoom != 2 || l.MaxZoom != 8 {
+		t.Errorf("layer, expected land of test-layer at zooms 2-8 got %+v", l)
+	}
+	if _, ok := l.GeomType.(geom.Polygon); !ok {
+		t.Errorf("layer geom type, expected the provider's polygon got %T", l.GeomType)
+	}
+	if len(before.Layers) != 0 {
+		t.Errorf("map copy layers, expected 0 got %v", len(before.Layers))
+	}
+
+	// the composed map encodes the provider's features
+	if _, err := m.Encode(context.Background(), slippy.NewTile(4, 2, 3)); err != nil {
+		t.Errorf("encode, unexpected error: %v", err)
+	}
+
+	err = a.AddLayerFromProvider("roads", &test.TileProvider{}, "missing-layer", atlas.Layer{})
+	if !errors.As(err, &atlas.ErrProviderLayerNotFound{}) {
+		t.Errorf("add missing provider layer, expected ErrProviderLayerNotFound got %v", err)
+	}
+	err = a.AddLayerFromProvider("rivers", &test.TileProvider{}, "test-layer", atlas.Layer{})
+	if !errors.As(err, &atlas.ErrMapNotFound{}) {
+		t.Errorf("add layer to missing map, expected ErrMapNotFound got %v", err)
+	}
+
+	m.Attribution = "replaced"
+	if err := a.ReplaceMap(m); err != nil {
+		t.Errorf("replace map, unexpected error: %v", err)
+	}
+	if got, _ := a.Map("roads"); got.Attribution != "replaced" {
+		t.Errorf("replaced map attribution, expected replaced got %v", got.Attribution)
+	}
+
+	if !a.RemoveMap("roads") {
+		t.Errorf("remove map, expected true")
+	}
+	if err := a.ReplaceMap(m); !errors.As(err, &atlas.ErrMapNotFound{}) {
+		t.Errorf("replace removed map, expected ErrMapNotFound got %v", err)
+	}
+}
+
+func TestAtlasComposeConcurrent(t *testing.T) {
+	a := &atlas.Atlas{}
+	if _, err := a.NewMap("roads"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			l := atlas.Layer{Name: fmt.Sprintf("layer-%v", i)}
+			if err := a.AddLayerFromProvider("roads", &test.TileProvider{}, "test-layer", l); err != nil {
+				t.Errorf("add layer, unexpected error: %v", err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			m, err := a.Map("roads")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			m.Attribution = "concurrent"
+			a.AddMap(atlas.NewWebMercatorMap("other"))
+			a.RemoveMap("other")
+		}()
+	}
+	wg.Wait()
+
+	m, _ := a.Map("roads")
+	if len(m.Layers) != 20 {
+		t.Errorf("layers, expected 20 got %v", len(m.Layers))
+	}
+}
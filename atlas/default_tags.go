@@ -0,0 +1,61 @@
+package atlas
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// ParseDefaultTags splits the default tags of the config into the tags with static values and
+// the tags with templates, the string values containing "{{", i.e. {{.name}} ({{.class}}) or
+// {{.Z}}. An error is returned for templates which don't parse.
+func ParseDefaultTags(tags map[string]interface{}) (static map[string]interface{}, templates map[string]*template.Template, err error) {
+	static = make(map[string]interface{}, len(tags))
+	for tag, v := range tags {
+		text, ok := v.(string)
+		if !ok || !strings.Contains(text, "{{") {
+			static[tag] = v
+			continue
+		}
+		if templates == nil {
+			templates = map[string]*template.Template{}
+		}
+		// missing tags fail the template rather than rendering as "<no value>"
+		if templates[tag], err = template.New(tag).Option("missingkey=error").Parse(text); err != nil {
+			return nil, nil, fmt.Errorf("atlas: invalid template for default tag (%v): %w", tag, err)
+		}
+	}
+	return static, templates, nil
+}
+
+// addDefaultTags adds the layer's DefaultTags and DefaultTagTemplates to the tags of a feature of
+// the tile, without overwriting the tags the feature has. The templates are executed with the
+// feature's tags and the tile's Z, X and Y, which take precedence over tags of the same name. A
+// template failing, i.e. for a missing tag, leaves its tag off the feature.
+func (l Layer) addDefaultTags(z, x, y uint, tags map[string]interface{}) {
+	if len(l.DefaultTagTemplates) > 0 {
+		data := make(map[string]interface{}, len(tags)+3)
+		for k, v := range tags {
+			data[k] = v
+		}
+		data["Z"], data["X"], data["Y"] = z, x, y
+
+		var sb strings.Builder
+		for tag, tmpl := range l.DefaultTagTemplates {
+			if _, ok := tags[tag]; ok {
+				continue
+			}
+			sb.Reset()
+			if err := tmpl.Execute(&sb, data); err != nil {
+				continue
+			}
+			tags[tag] = sb.String()
+		}
+	}
+
+	for k, v := range l.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+}
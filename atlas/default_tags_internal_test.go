@@ -0,0 +1,72 @@
+package atlas
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayerAddDefaultTags(t *testing.T) {
+	type tcase struct {
+		defaultTags map[string]interface{}
+		tags        map[string]interface{}
+		expected    map[string]interface{}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			static, templates, err := ParseDefaultTags(tc.defaultTags)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			l := Layer{DefaultTags: static, DefaultTagTemplates: templates}
+
+			l.addDefaultTags(12, 2200, 1343, tc.tags)
+			if !reflect.DeepEqual(tc.tags, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, tc.tags)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"static": {
+			defaultTags: map[string]interface{}{"class": "park", "rank": int64(2)},
+			tags:        map[string]interface{}{"name": "Tiergarten"},
+			expected:    map[string]interface{}{"name": "Tiergarten", "class": "park", "rank": int64(2)},
+		},
+		"feature tags take precedence": {
+			defaultTags: map[string]interface{}{"class": "park", "label": "{{.name}}"},
+			tags:        map[string]interface{}{"class": "garden", "label": "Zoo", "name": "Tiergarten"},
+			expected:    map[string]interface{}{"class": "garden", "label": "Zoo", "name": "Tiergarten"},
+		},
+		"tile": {
+			defaultTags: map[string]interface{}{"zoom": "{{.Z}}", "tile": "{{.Z}}/{{.X}}/{{.Y}}"},
+			tags:        map[string]interface{}{},
+			expected:    map[string]interface{}{"zoom": "12", "tile": "12/2200/1343"},
+		},
+		"tags": {
+			defaultTags: map[string]interface{}{"label": "{{.name}} ({{.class}})"},
+			tags:        map[string]interface{}{"name": "Tiergarten", "class": "park"},
+			expected:    map[string]interface{}{"name": "Tiergarten", "class": "park", "label": "Tiergarten (park)"},
+		},
+		"templates don't see default tags": {
+			defaultTags: map[string]interface{}{"class": "park", "label": "{{.name}} ({{.class}})"},
+			tags:        map[string]interface{}{"name": "Tiergarten"},
+			expected:    map[string]interface{}{"name": "Tiergarten", "class": "park"},
+		},
+		"missing tag": {
+			defaultTags: map[string]interface{}{"label": "{{.name}}"},
+			tags:        map[string]interface{}{},
+			expected:    map[string]interface{}{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestParseDefaultTagsErrors(t *testing.T) {
+	if _, _, err := ParseDefaultTags(map[string]interface{}{"label": "{{.name"}); err == nil {
+		t.Errorf("invalid template, expected an error")
+	}
+}
@@ -0,0 +1,34 @@
+package atlas
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+)
+
+// IsEmptyTile reports if none of the layers of the encoded tile have features. The tile may
+// be gzip compressed.
+func IsEmptyTile(tile []byte) (bool, error) {
+	if isGzipped(tile) {
+		b, release, err := gunzipTile(tile)
+		if err != nil {
+			return false, err
+		}
+		defer release()
+		tile = b
+	}
+
+	var vt vectorTile.Tile
+	if err := proto.Unmarshal(tile, &vt); err != nil {
+		return false, fmt.Errorf("atlas: decoding tile: %v", err)
+	}
+
+	for _, l := range vt.Layers {
+		if len(l.Features) > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
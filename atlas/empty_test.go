@@ -0,0 +1,52 @@
+package atlas_test
+
+import (
+	"testing"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+func TestIsEmptyTile(t *testing.T) {
+	name, version := "roads", uint32(2)
+	tests := map[string]struct {
+		tile     vectorTile.Tile
+		expected bool
+	}{
+		"no layers": {
+			expected: true,
+		},
+		"layers without features": {
+			tile:     vectorTile.Tile{Layers: []*vectorTile.Tile_Layer{{Name: &name, Version: &version}}},
+			expected: true,
+		},
+		"features": {
+			tile:     vectorTile.Tile{Layers: []*vectorTile.Tile_Layer{{Name: &name, Version: &version}, {Name: &name, Version: &version, Features: []*vectorTile.Tile_Feature{{}}}}},
+			expected: false,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			b, err := proto.Marshal(&tc.tile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			empty, err := atlas.IsEmptyTile(b)
+			if err != nil {
+				t.Fatalf("error, expected nil got %v", err)
+			}
+			if empty != tc.expected {
+				t.Errorf("empty, expected %v got %v", tc.expected, empty)
+			}
+		})
+	}
+
+	if _, err := atlas.IsEmptyTile([]byte("not a tile")); err == nil {
+		t.Errorf("error of an invalid tile, expected an error got nil")
+	}
+}
@@ -3,6 +3,7 @@ package atlas
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 var (
@@ -17,3 +18,52 @@ type ErrMapNotFound struct {
 func (e ErrMapNotFound) Error() string {
 	return fmt.Sprintf("atlas: map (%v) not found", e.Name)
 }
+
+// ErrMapExists is returned when a new map has the name of a registered map
+type ErrMapExists struct {
+	Name string
+}
+
+func (e ErrMapExists) Error() string {
+	return fmt.Sprintf("atlas: map (%v) already exists", e.Name)
+}
+
+// ErrProviderLayerNotFound is returned when a layer is added to a map from a provider without the layer
+type ErrProviderLayerNotFound struct {
+	Map           string
+	ProviderLayer string
+}
+
+func (e ErrProviderLayerNotFound) Error() string {
+	return fmt.Sprintf("atlas: map (%v) provider has no layer (%v)", e.Map, e.ProviderLayer)
+}
+
+// ErrLayerFailed is returned when a required layer's provider fails while encoding a tile
+type ErrLayerFailed struct {
+	Map   string
+	Layer string
+	Err   error
+}
+
+func (e ErrLayerFailed) Unwrap() error { return e.Err }
+func (e ErrLayerFailed) Error() string {
+	return fmt.Sprintf("atlas: map (%v) layer (%v) failed: %v", e.Map, e.Layer, e.Err)
+}
+
+// ErrLayerTimeout is the error of a layer which exceeded its timeout
+type ErrLayerTimeout struct {
+	Timeout time.Duration
+}
+
+func (e ErrLayerTimeout) Error() string {
+	return fmt.Sprintf("layer exceeded its timeout (%v)", e.Timeout)
+}
+
+// ErrHooksExist is returned when hooks are registered under the name of registered hooks
+type ErrHooksExist struct {
+	Name string
+}
+
+func (e ErrHooksExist) Error() string {
+	return fmt.Sprintf("atlas: hooks (%v) already registered", e.Name)
+}
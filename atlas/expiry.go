@@ -0,0 +1,107 @@
+package atlas
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// expiresLayouts are the string formats accepted for expiry tag values. The last
+// layout is the format timestamps are converted to by the postgis provider.
+var expiresLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+}
+
+// expiresAt converts an expiry tag value to a time. Numeric values are unix timestamps in seconds.
+func expiresAt(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, !t.IsZero()
+	case string:
+		for _, layout := range expiresLayouts {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed, true
+			}
+		}
+		if secs, err := strconv.ParseFloat(t, 64); err == nil {
+			return unixSeconds(secs), true
+		}
+	case int64:
+		return time.Unix(t, 0), true
+	case int:
+		return time.Unix(int64(t), 0), true
+	case uint64:
+		return time.Unix(int64(t), 0), true
+	case float64:
+		return unixSeconds(t), true
+	}
+	return time.Time{}, false
+}
+
+func unixSeconds(secs float64) time.Time {
+	return time.Unix(0, int64(secs*float64(time.Second)))
+}
+
+type expiryKey struct{}
+
+// tileExpiry tracks the soonest expiry of the features encoded in a tile
+type tileExpiry struct {
+	sync.Mutex
+	soonest time.Time
+}
+
+// WithExpiry returns a context which records the soonest feature expiry when passed to Map.Encode.
+// The expiry is read back with Expiry.
+func WithExpiry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, expiryKey{}, &tileExpiry{})
+}
+
+// Expiry returns the soonest expiry of the features encoded with a context from WithExpiry.
+// The zero time is returned if no encoded features expire.
+func Expiry(ctx context.Context) time.Time {
+	te, ok := ctx.Value(expiryKey{}).(*tileExpiry)
+	if !ok {
+		return time.Time{}
+	}
+
+	te.Lock()
+	defer te.Unlock()
+	return te.soonest
+}
+
+// recordExpiry keeps the soonest of t and the expiry already recorded in ctx
+func recordExpiry(ctx context.Context, t time.Time) {
+	te, ok := ctx.Value(expiryKey{}).(*tileExpiry)
+	if !ok {
+		return
+	}
+
+	te.Lock()
+	defer te.Unlock()
+	if te.soonest.IsZero() || t.Before(te.soonest) {
+		te.soonest = t
+	}
+}
+
+// expired reports if the feature tags mark the feature as expired at time now. Features
+// which have not yet expired have their expiry recorded in ctx.
+func (l Layer) expired(ctx context.Context, tags map[string]interface{}, now time.Time) bool {
+	if l.ExpiresField == "" {
+		return false
+	}
+
+	t, ok := expiresAt(tags[l.ExpiresField])
+	if !ok {
+		return false
+	}
+	if !t.After(now) {
+		return true
+	}
+
+	recordExpiry(ctx, t)
+	return false
+}
@@ -0,0 +1,93 @@
+package atlas
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLayerExpired(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	type tcase struct {
+		field   string
+		tags    map[string]interface{}
+		expired bool
+		// expiry is the expected expiry recorded in the context
+		expiry time.Time
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			l := Layer{ExpiresField: tc.field}
+			ctx := WithExpiry(context.Background())
+
+			if got := l.expired(ctx, tc.tags, now); got != tc.expired {
+				t.Errorf("expired, expected %v got %v", tc.expired, got)
+			}
+			if got := Expiry(ctx); !got.Equal(tc.expiry) {
+				t.Errorf("expiry, expected %v got %v", tc.expiry, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"no expires field": {
+			tags: map[string]interface{}{"expires_at": "2020-06-01T11:00:00Z"},
+		},
+		"missing tag": {
+			field: "expires_at",
+			tags:  map[string]interface{}{},
+		},
+		"expired rfc3339": {
+			field:   "expires_at",
+			tags:    map[string]interface{}{"expires_at": "2020-06-01T11:00:00Z"},
+			expired: true,
+		},
+		"future rfc3339": {
+			field:  "expires_at",
+			tags:   map[string]interface{}{"expires_at": "2020-06-01T13:00:00Z"},
+			expiry: time.Date(2020, 6, 1, 13, 0, 0, 0, time.UTC),
+		},
+		"future postgis timestamptz": {
+			field:  "expires_at",
+			tags:   map[string]interface{}{"expires_at": "2020-06-01 12:30:00 +0000 UTC"},
+			expiry: time.Date(2020, 6, 1, 12, 30, 0, 0, time.UTC),
+		},
+		"future unix seconds": {
+			field:  "expires_at",
+			tags:   map[string]interface{}{"expires_at": now.Add(time.Minute).Unix()},
+			expiry: now.Add(time.Minute),
+		},
+		"expired unix seconds": {
+			field:   "expires_at",
+			tags:    map[string]interface{}{"expires_at": float64(now.Unix())},
+			expired: true,
+		},
+		"unparsable": {
+			field: "expires_at",
+			tags:  map[string]interface{}{"expires_at": "tomorrow"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestExpirySoonest(t *testing.T) {
+	ctx := WithExpiry(context.Background())
+	soonest := time.Now().Add(time.Minute)
+
+	recordExpiry(ctx, soonest.Add(time.Hour))
+	recordExpiry(ctx, soonest)
+	recordExpiry(ctx, soonest.Add(time.Second))
+
+	if got := Expiry(ctx); !got.Equal(soonest) {
+		t.Errorf("expected %v got %v", soonest, got)
+	}
+
+	if got := Expiry(context.Background()); !got.IsZero() {
+		t.Errorf("expected zero time without WithExpiry, got %v", got)
+	}
+}
@@ -0,0 +1,50 @@
+package atlas
+
+import (
+	"context"
+	"sync"
+)
+
+type featureCountKey struct{}
+
+// featureCount tracks the number of features encoded in a tile
+type featureCount struct {
+	sync.Mutex
+	n int
+	// counted is set when the tile is encoded from the map's layers
+	counted bool
+}
+
+// WithFeatureCount returns a context which counts the features encoded in the tile when
+// passed to Map.Encode. The count is read back with FeatureCount.
+func WithFeatureCount(ctx context.Context) context.Context {
+	return context.WithValue(ctx, featureCountKey{}, &featureCount{})
+}
+
+// FeatureCount returns the number of features encoded in the tile with a context from
+// WithFeatureCount. false is returned when the tile wasn't encoded from the map's layers,
+// i.e. the tiles of upstream and mvt provider maps.
+func FeatureCount(ctx context.Context) (int, bool) {
+	fc, ok := ctx.Value(featureCountKey{}).(*featureCount)
+	if !ok {
+		return 0, false
+	}
+
+	fc.Lock()
+	defer fc.Unlock()
+
+	return fc.n, fc.counted
+}
+
+// recordFeatures adds the features of a layer to the count of the tile
+func recordFeatures(ctx context.Context, n int) {
+	fc, ok := ctx.Value(featureCountKey{}).(*featureCount)
+	if !ok {
+		return
+	}
+
+	fc.Lock()
+	fc.n += n
+	fc.counted = true
+	fc.Unlock()
+}
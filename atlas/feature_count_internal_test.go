@@ -0,0 +1,60 @@
+package atlas
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+func TestFeatureCount(t *testing.T) {
+	type tcase struct {
+		layers  []Layer
+		count   int
+		counted bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			m := NewWebMercatorMap("test")
+			m.Layers = tc.layers
+
+			ctx := WithFeatureCount(WithOmittedLayers(context.Background()))
+			if _, err := m.encodeMVTTile(ctx, slippy.NewTile(2, 3, 1)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			count, counted := FeatureCount(ctx)
+			if count != tc.count || counted != tc.counted {
+				t.Errorf("feature count, expected %v %v got %v %v", tc.count, tc.counted, count, counted)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"layers": {
+			layers: []Layer{
+				{Name: "a", ProviderLayerID: "test-layer", Provider: &test.TileProvider{}},
+				{Name: "b", ProviderLayerID: "test-layer", Provider: &test.TileProvider{}},
+			},
+			count:   2,
+			counted: true,
+		},
+		"omitted layer": {
+			layers: []Layer{
+				{Name: "a", ProviderLayerID: "test-layer", Provider: &failingTiler{}, Optional: true},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+
+	// contexts without a feature count
+	if _, counted := FeatureCount(context.Background()); counted {
+		t.Errorf("counted, expected false got true")
+	}
+}
@@ -0,0 +1,103 @@
+package atlas
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+)
+
+// ErrMalformedTags is returned by FilterTileFields for features whose tags don't follow the MVT spec
+var ErrMalformedTags = errors.New("atlas: malformed mvt feature tags")
+
+// FilterTileFields returns the encoded tile with only the tags of its features whose keys are
+// one of the fields. The keys and values no feature uses anymore are left out of the layers.
+// Gzip compressed tiles are returned gzip compressed.
+func FilterTileFields(tile []byte, fields []string) ([]byte, error) {
+	gzipped := isGzipped(tile)
+	if gzipped {
+		b, release, err := gunzipTile(tile)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		tile = b
+	}
+
+	var vt vectorTile.Tile
+	if err := proto.Unmarshal(tile, &vt); err != nil {
+		return nil, fmt.Errorf("atlas: decoding tile: %v", err)
+	}
+
+	keep := map[string]bool{}
+	for _, f := range fields {
+		keep[f] = true
+	}
+
+	for _, l := range vt.Layers {
+		if err := filterLayerFields(l, keep); err != nil {
+			return nil, fmt.Errorf("layer (%v): %w", l.GetName(), err)
+		}
+	}
+
+	tileBytes, err := marshalTile(&vt)
+	if err != nil {
+		return nil, err
+	}
+	if !gzipped {
+		return tileBytes, nil
+	}
+	return gzipTile(tileBytes)
+}
+
+// filterLayerFields drops the tags of the features of the layer whose keys are not kept, and
+// re-indexes the keys and values of the remaining tags
+func filterLayerFields(l *vectorTile.Tile_Layer, keep map[string]bool) error {
+	var (
+		keys     []string
+		values   []*vectorTile.Tile_Value
+		keyIdx   = map[uint32]uint32{}
+		valueIdx = map[uint32]uint32{}
+		keyKept  = make([]bool, len(l.Keys))
+	)
+	for i, k := range l.Keys {
+		keyKept[i] = keep[k]
+	}
+
+	for _, f := range l.Features {
+		if len(f.Tags)%2 != 0 {
+			return ErrMalformedTags
+		}
+
+		tags := f.Tags[:0]
+		for i := 0; i < len(f.Tags); i += 2 {
+			k, v := f.Tags[i], f.Tags[i+1]
+			if int(k) >= len(l.Keys) || int(v) >= len(l.Values) {
+				return ErrMalformedTags
+			}
+			if !keyKept[k] {
+				continue
+			}
+
+			nk, ok := keyIdx[k]
+			if !ok {
+				nk = uint32(len(keys))
+				keyIdx[k] = nk
+				keys = append(keys, l.Keys[k])
+			}
+			nv, ok := valueIdx[v]
+			if !ok {
+				nv = uint32(len(values))
+				valueIdx[v] = nv
+				values = append(values, l.Values[v])
+			}
+			tags = append(tags, nk, nv)
+		}
+		f.Tags = tags
+	}
+
+	l.Keys, l.Values = keys, values
+	return nil
+}
@@ -0,0 +1,68 @@
+package atlas_test
+
+import (
+	"reflect"
+	"testing"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+func TestFilterTileFields(t *testing.T) {
+	point := vectorTile.Tile_POINT
+	src := vectorTile.Tile{Layers: []*vectorTile.Tile_Layer{{
+		Version: proto.Uint32(2),
+		Name:    proto.String("pois"),
+		Extent:  proto.Uint32(4096),
+		Keys:    []string{"name", "class", "osm_id"},
+		Values: []*vectorTile.Tile_Value{
+			{StringValue: proto.String("cafe")},
+			{StringValue: proto.String("amenity")},
+			{IntValue: proto.Int64(42)},
+			{StringValue: proto.String("park")},
+		},
+		Features: []*vectorTile.Tile_Feature{
+			{Id: proto.Uint64(1), Type: &point, Tags: []uint32{0, 0, 1, 1, 2, 2}, Geometry: []uint32{9, 2, 2}},
+			{Id: proto.Uint64(2), Type: &point, Tags: []uint32{2, 2, 1, 3}, Geometry: []uint32{9, 4, 4}},
+		},
+	}}}
+	b, err := proto.Marshal(&src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filtered, err := atlas.FilterTileFields(b, []string{"class"})
+	if err != nil {
+		t.Fatalf("error, expected nil got %v", err)
+	}
+	var dst vectorTile.Tile
+	if err := proto.Unmarshal(filtered, &dst); err != nil {
+		t.Fatal(err)
+	}
+
+	l := dst.Layers[0]
+	if !reflect.DeepEqual(l.Keys, []string{"class"}) {
+		t.Errorf("keys, expected [class] got %v", l.Keys)
+	}
+	if len(l.Values) != 2 || l.Values[0].GetStringValue() != "amenity" || l.Values[1].GetStringValue() != "park" {
+		t.Errorf("values, expected [amenity park] got %v", l.Values)
+	}
+	for i, expected := range [][]uint32{{0, 0}, {0, 1}} {
+		if !reflect.DeepEqual(l.Features[i].Tags, expected) {
+			t.Errorf("feature %v tags, expected %v got %v", i, expected, l.Features[i].Tags)
+		}
+		if len(l.Features[i].Geometry) != 3 {
+			t.Errorf("feature %v geometry, expected unchanged got %v", i, l.Features[i].Geometry)
+		}
+	}
+
+	src.Layers[0].Features[0].Tags = []uint32{0, 9}
+	if b, err = proto.Marshal(&src); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = atlas.FilterTileFields(b, []string{"name"}); err == nil {
+		t.Errorf("expected an error for tags of unknown values")
+	}
+}
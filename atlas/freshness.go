@@ -0,0 +1,251 @@
+package atlas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const (
+	// DefaultFreshnessInterval is the time between freshness checks
+	DefaultFreshnessInterval = time.Minute
+	// DefaultFreshnessWebhookTimeout bounds the webhook alert requests
+	DefaultFreshnessWebhookTimeout = 10 * time.Second
+)
+
+// Freshness alert statuses sent to the webhook
+const (
+	FreshnessStatusViolation = "violation"
+	FreshnessStatusResolved  = "resolved"
+)
+
+// LayerFreshness is the freshness of a map layer with a freshness SLA
+type LayerFreshness struct {
+	Map           string `json:"map"`
+	Layer         string `json:"layer"`
+	ProviderLayer string `json:"provider_layer"`
+	// SLA is the maximum age of the layer's data in seconds
+	SLA int64 `json:"sla"`
+	// Updated is the time the layer's data was last updated, as reported by the provider
+	Updated time.Time `json:"updated"`
+	// Age of the layer's data in seconds when it was last checked
+	Age int64 `json:"age"`
+	// Violation is set while the layer is older than its SLA or its freshness can't be determined
+	Violation bool `json:"violation"`
+	// Violations is the number of times the layer went into violation
+	Violations uint64 `json:"violations"`
+	// Checks is the number of freshness checks made
+	Checks uint64 `json:"checks"`
+	// Checked is the time of the last check
+	Checked time.Time `json:"checked"`
+	// Error of the last check, i.e. the provider does not report freshness
+	Error string `json:"error,omitempty"`
+}
+
+// FreshnessAlert is the JSON body posted to the webhook when a layer goes into or out of violation
+type FreshnessAlert struct {
+	Status string `json:"status"`
+	LayerFreshness
+}
+
+// FreshnessMonitor periodically checks the map layers with a FreshnessSLA against the
+// freshness reported by their providers. Providers report freshness by implementing
+// provider.Freshness.
+type FreshnessMonitor struct {
+	// Atlas holding the maps to check. The default atlas is used when nil.
+	Atlas *Atlas
+	// Interval between checks. DefaultFreshnessInterval when 0.
+	Interval time.Duration
+	// WebhookURL, when set, is posted a FreshnessAlert when a layer goes into or out of violation
+	WebhookURL string
+	// Client used for the webhook. A client with DefaultFreshnessWebhookTimeout is used when nil.
+	Client *http.Client
+
+	lock sync.Mutex
+	// layers is keyed by the map and layer name
+	layers map[[2]string]*LayerFreshness
+}
+
+// Run checks the freshness of the layers every Interval until the context is done
+func (fm *FreshnessMonitor) Run(ctx context.Context) {
+	interval := fm.Interval
+	if interval <= 0 {
+		interval = DefaultFreshnessInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fm.Check(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check checks the freshness of every map layer with a FreshnessSLA at now
+func (fm *FreshnessMonitor) Check(ctx context.Context, now time.Time) {
+	var alerts []FreshnessAlert
+
+	for _, m := range fm.Atlas.AllMaps() {
+		for _, l := range m.Layers {
+			if l.FreshnessSLA <= 0 {
+				continue
+			}
+
+			updated, err := layerUpdated(ctx, m, l)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if alert, ok := fm.record(m.Name, l, updated, err, now); ok {
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+
+	for _, alert := range alerts {
+		if err := fm.notify(ctx, alert); err != nil {
+			log.Errorf("freshness webhook for map (%v) layer (%v) failed: %v", alert.Map, alert.Layer, err)
+		}
+	}
+}
+
+// layerUpdated asks the layer's provider when its data was last updated
+func layerUpdated(ctx context.Context, m Map, l Layer) (time.Time, error) {
+	f, ok := layerProvider(m, l).(provider.Freshness)
+	if !ok {
+		return time.Time{}, fmt.Errorf("provider does not report freshness")
+	}
+
+	updated, err := f.LayerUpdated(ctx, l.ProviderLayerID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if updated.IsZero() {
+		return updated, fmt.Errorf("layer has not been updated")
+	}
+	return updated, nil
+}
+
+// record records the check of the layer. An alert is returned when the layer went into or out of violation.
+func (fm *FreshnessMonitor) record(mapName string, l Layer, updated time.Time, err error, now time.Time) (FreshnessAlert, bool) {
+	fm.lock.Lock()
+	defer fm.lock.Unlock()
+
+	if fm.layers == nil {
+		fm.layers = map[[2]string]*LayerFreshness{}
+	}
+
+	// layers of MVT providers don't have a Provider to look up their name with
+	name := l.Name
+	if name == "" {
+		name = l.ProviderLayerID
+	}
+
+	key := [2]string{mapName, name}
+	lf, ok := fm.layers[key]
+	if !ok {
+		lf = &LayerFreshness{Map: mapName, Layer: name, ProviderLayer: l.ProviderLayerID}
+		fm.layers[key] = lf
+	}
+
+	wasViolation := lf.Violation
+
+	lf.SLA = int64(l.FreshnessSLA / time.Second)
+	lf.Checks++
+	lf.Checked = now
+	lf.Error = ""
+	if err != nil {
+		lf.Error = err.Error()
+	} else {
+		lf.Updated = updated
+		lf.Age = int64(now.Sub(updated) / time.Second)
+	}
+	lf.Violation = err != nil || now.Sub(updated) > l.FreshnessSLA
+
+	switch {
+	case lf.Violation && !wasViolation:
+		lf.Violations++
+		if err != nil {
+			log.Warnf("map (%v) layer (%v) violates its freshness SLA (%v): %v", mapName, lf.Layer, l.FreshnessSLA, err)
+		} else {
+			log.Warnf("map (%v) layer (%v) violates its freshness SLA (%v): last updated %v", mapName, lf.Layer, l.FreshnessSLA, updated.Format(time.RFC3339))
+		}
+		return FreshnessAlert{Status: FreshnessStatusViolation, LayerFreshness: *lf}, true
+	case !lf.Violation && wasViolation:
+		log.Infof("map (%v) layer (%v) meets its freshness SLA (%v) again", mapName, lf.Layer, l.FreshnessSLA)
+		return FreshnessAlert{Status: FreshnessStatusResolved, LayerFreshness: *lf}, true
+	}
+	return FreshnessAlert{}, false
+}
+
+// notify posts the alert to the webhook
+func (fm *FreshnessMonitor) notify(ctx context.Context, alert FreshnessAlert) error {
+	if fm.WebhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fm.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := fm.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultFreshnessWebhookTimeout}
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// Freshness returns the freshness of the checked layers sorted by map and layer
+func (fm *FreshnessMonitor) Freshness() []LayerFreshness {
+	if fm == nil {
+		return []LayerFreshness{}
+	}
+
+	fm.lock.Lock()
+	defer fm.lock.Unlock()
+
+	lfs := make([]LayerFreshness, 0, len(fm.layers))
+	for _, lf := range fm.layers {
+		lfs = append(lfs, *lf)
+	}
+
+	sort.Slice(lfs, func(i, j int) bool {
+		if lfs[i].Map != lfs[j].Map {
+			return lfs[i].Map < lfs[j].Map
+		}
+		return lfs[i].Layer < lfs[j].Layer
+	})
+
+	return lfs
+}
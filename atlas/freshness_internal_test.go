@@ -0,0 +1,106 @@
+package atlas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+// freshTiler reports the updated time of its layers
+type freshTiler struct {
+	test.TileProvider
+	updated time.Time
+}
+
+func (ft *freshTiler) LayerUpdated(ctx context.Context, layer string) (time.Time, error) {
+	return ft.updated, nil
+}
+
+func TestFreshnessMonitor(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var (
+		lock   sync.Mutex
+		alerts []FreshnessAlert
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert FreshnessAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("decoding alert: %v", err)
+		}
+		lock.Lock()
+		alerts = append(alerts, alert)
+		lock.Unlock()
+	}))
+	defer srv.Close()
+
+	tiler := &freshTiler{updated: now.Add(-time.Hour)}
+
+	a := &Atlas{}
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{
+		{Name: "fresh", ProviderLayerID: "a", Provider: tiler, FreshnessSLA: 2 * time.Hour},
+		{Name: "unmonitored", ProviderLayerID: "b", Provider: tiler},
+		{Name: "unsupported", ProviderLayerID: "c", Provider: &test.TileProvider{}, FreshnessSLA: time.Hour},
+	}
+	a.AddMap(m)
+
+	fm := FreshnessMonitor{Atlas: a, WebhookURL: srv.URL}
+
+	type step struct {
+		updated    time.Time
+		violation  bool
+		violations uint64
+		alerts     []string
+	}
+
+	steps := []step{
+		// the layer without freshness goes into violation
+		{updated: now.Add(-time.Hour), alerts: []string{"unsupported:" + FreshnessStatusViolation}},
+		{updated: now.Add(-3 * time.Hour), violation: true, violations: 1, alerts: []string{"fresh:" + FreshnessStatusViolation}},
+		// no alerts while the violation continues
+		{updated: now.Add(-3 * time.Hour), violation: true, violations: 1},
+		{updated: now, violations: 1, alerts: []string{"fresh:" + FreshnessStatusResolved}},
+	}
+
+	for i, s := range steps {
+		alerts = nil
+		tiler.updated = s.updated
+
+		fm.Check(context.Background(), now)
+
+		lfs := fm.Freshness()
+		if len(lfs) != 2 {
+			t.Fatalf("step %v: layers, expected 2 got %v", i, len(lfs))
+		}
+		fresh, unsupported := lfs[0], lfs[1]
+		if fresh.Layer != "fresh" || fresh.Violation != s.violation || fresh.Violations != s.violations || fresh.Checks != uint64(i+1) {
+			t.Errorf("step %v: unexpected freshness %+v", i, fresh)
+		}
+		if fresh.SLA != 7200 || !fresh.Updated.Equal(s.updated) || fresh.Age != int64(now.Sub(s.updated)/time.Second) {
+			t.Errorf("step %v: unexpected age %+v", i, fresh)
+		}
+		if !unsupported.Violation || unsupported.Violations != 1 || unsupported.Error == "" {
+			t.Errorf("step %v: expected the layer without freshness to be in violation, got %+v", i, unsupported)
+		}
+
+		var got []string
+		for _, a := range alerts {
+			got = append(got, a.Layer+":"+a.Status)
+		}
+		if len(got) != len(s.alerts) {
+			t.Fatalf("step %v: alerts, expected %v got %v", i, s.alerts, got)
+		}
+		for j := range got {
+			if got[j] != s.alerts[j] {
+				t.Errorf("step %v: alert %v, expected %v got %v", i, j, s.alerts[j], got[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package atlas
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// GeometryAttributes controls how attribute values which echo a geometry
+// (WKT or GeoJSON) are handled when a layer is encoded.
+type GeometryAttributes string
+
+const (
+	// GeometryAttributesKeep leaves attribute values untouched and skips detection
+	GeometryAttributesKeep GeometryAttributes = ""
+	// GeometryAttributesWarn detects geometry attributes and reports them without modifying the value
+	GeometryAttributesWarn GeometryAttributes = "warn"
+	// GeometryAttributesStrip removes detected geometry attributes from the feature
+	GeometryAttributesStrip GeometryAttributes = "strip"
+	// GeometryAttributesRound reduces the coordinate precision of detected geometry attributes
+	GeometryAttributesRound GeometryAttributes = "round"
+)
+
+// DefaultGeometryAttributesPrecision is the number of decimal places coordinates are rounded to
+const DefaultGeometryAttributesPrecision = 6
+
+// ParseGeometryAttributes returns the GeometryAttributes for the given config value
+func ParseGeometryAttributes(s string) (GeometryAttributes, error) {
+	switch ga := GeometryAttributes(strings.ToLower(strings.TrimSpace(s))); ga {
+	case GeometryAttributesKeep, GeometryAttributesWarn, GeometryAttributesStrip, GeometryAttributesRound:
+		return ga, nil
+	default:
+		return GeometryAttributesKeep, fmt.Errorf("atlas: invalid geometry_attributes value (%v), expected one of: warn, strip, round", s)
+	}
+}
+
+var (
+	// wktPrefix matches the geometry types of WKT and EWKT values
+	wktPrefix = regexp.MustCompile(`^(?i)(SRID=\d+;\s*)?(POINT|LINESTRING|POLYGON|MULTIPOINT|MULTILINESTRING|MULTIPOLYGON|GEOMETRYCOLLECTION|TRIANGLE|TIN|POLYHEDRALSURFACE)\s*(Z|M|ZM)?\s*(\(|EMPTY)`)
+	// geoJSONCoordinates matches the members of a GeoJSON geometry
+	geoJSONCoordinates = regexp.MustCompile(`"(coordinates|geometries)"\s*:`)
+	// decimalNumber matches numbers with a fractional part
+	decimalNumber = regexp.MustCompile(`-?\d+\.\d+([eE][-+]?\d+)?`)
+)
+
+// isGeometryAttribute reports if the value looks like a WKT or GeoJSON geometry
+func isGeometryAttribute(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	if s[0] == '{' {
+		return geoJSONCoordinates.MatchString(s)
+	}
+	return wktPrefix.MatchString(s)
+}
+
+// roundCoordinates reduces every decimal number in s to at most precision decimal places
+func roundCoordinates(s string, precision uint) string {
+	return decimalNumber.ReplaceAllStringFunc(s, func(n string) string {
+		f, err := strconv.ParseFloat(n, 64)
+		if err != nil || math.IsInf(f, 0) {
+			return n
+		}
+		return trimZeros(strconv.FormatFloat(f, 'f', int(precision), 64))
+	})
+}
+
+// trimZeros removes trailing zeros (and a trailing decimal point) from a formatted float
+func trimZeros(n string) string {
+	if !strings.Contains(n, ".") {
+		return n
+	}
+	n = strings.TrimRight(n, "0")
+	n = strings.TrimSuffix(n, ".")
+	if n == "-0" {
+		return "0"
+	}
+	return n
+}
+
+// GeometryAttributeStat reports the geometry attributes found while encoding a map layer
+type GeometryAttributeStat struct {
+	Map   string             `json:"map"`
+	Layer string             `json:"layer"`
+	Mode  GeometryAttributes `json:"mode"`
+	// Tags are the attribute keys geometries were found in
+	Tags []string `json:"tags"`
+	// Detected is the number of attribute values recognized as geometries
+	Detected uint64 `json:"detected"`
+	// BytesSaved is the size reduction from stripping or rounding the values
+	BytesSaved uint64 `json:"bytes_saved"`
+}
+
+var (
+	geometryAttributeStatsLock sync.Mutex
+	// geometryAttributeStats is keyed by map name and layer name
+	geometryAttributeStats = map[[2]string]*GeometryAttributeStat{}
+)
+
+// GeometryAttributeStats returns the geometry attributes detected in encoded layers, sorted by map and layer
+func GeometryAttributeStats() []GeometryAttributeStat {
+	geometryAttributeStatsLock.Lock()
+	defer geometryAttributeStatsLock.Unlock()
+
+	stats := make([]GeometryAttributeStat, 0, len(geometryAttributeStats))
+	for _, s := range geometryAttributeStats {
+		stat := *s
+		stat.Tags = append([]string(nil), s.Tags...)
+		stats = append(stats, stat)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Map != stats[j].Map {
+			return stats[i].Map < stats[j].Map
+		}
+		return stats[i].Layer < stats[j].Layer
+	})
+
+	return stats
+}
+
+// recordGeometryAttribute adds a detected geometry attribute to the stats. A warning is logged
+// the first time a tag is seen for a layer.
+func recordGeometryAttribute(mapName string, l Layer, tag string, saved int) {
+	geometryAttributeStatsLock.Lock()
+	defer geometryAttributeStatsLock.Unlock()
+
+	key := [2]string{mapName, l.MVTName()}
+	s, ok := geometryAttributeStats[key]
+	if !ok {
+		s = &GeometryAttributeStat{
+			Map:   key[0],
+			Layer: key[1],
+			Mode:  l.GeometryAttributes,
+		}
+		geometryAttributeStats[key] = s
+	}
+
+	s.Detected++
+	if saved > 0 {
+		s.BytesSaved += uint64(saved)
+	}
+
+	for _, t := range s.Tags {
+		if t == tag {
+			return
+		}
+	}
+	s.Tags = append(s.Tags, tag)
+
+	log.Warnf("map (%v) layer (%v) attribute (%v) contains a geometry (geometry_attributes: %q)", key[0], key[1], tag, l.GeometryAttributes)
+}
+
+// processGeometryAttributes detects attribute values which echo a geometry and
+// strips or rounds them according to the layer's GeometryAttributes setting
+func (l Layer) processGeometryAttributes(mapName string, tags map[string]interface{}) {
+	if l.GeometryAttributes == GeometryAttributesKeep {
+		return
+	}
+
+	for k, v := range tags {
+		s, ok := v.(string)
+		if !ok || !isGeometryAttribute(s) {
+			continue
+		}
+
+		saved := 0
+		switch l.GeometryAttributes {
+		case GeometryAttributesStrip:
+			delete(tags, k)
+			saved = len(s)
+		case GeometryAttributesRound:
+			rounded := roundCoordinates(s, l.GeometryAttributesPrecision)
+			tags[k] = rounded
+			saved = len(s) - len(rounded)
+		}
+
+		recordGeometryAttribute(mapName, l, k, saved)
+	}
+}
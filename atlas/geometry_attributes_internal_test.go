@@ -0,0 +1,76 @@
+package atlas
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProcessGeometryAttributes(t *testing.T) {
+	type tcase struct {
+		mode      GeometryAttributes
+		precision uint
+		tags      map[string]interface{}
+		expected  map[string]interface{}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			l := Layer{
+				Name:                        "geom_attrs",
+				GeometryAttributes:          tc.mode,
+				GeometryAttributesPrecision: tc.precision,
+			}
+
+			l.processGeometryAttributes("test-map", tc.tags)
+
+			if !reflect.DeepEqual(tc.tags, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, tc.tags)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"keep": {
+			mode:     GeometryAttributesKeep,
+			tags:     map[string]interface{}{"wkt": "POINT(1.123456789 2.123456789)"},
+			expected: map[string]interface{}{"wkt": "POINT(1.123456789 2.123456789)"},
+		},
+		"warn": {
+			mode:     GeometryAttributesWarn,
+			tags:     map[string]interface{}{"wkt": "POINT(1.123456789 2.123456789)"},
+			expected: map[string]interface{}{"wkt": "POINT(1.123456789 2.123456789)"},
+		},
+		"strip": {
+			mode: GeometryAttributesStrip,
+			tags: map[string]interface{}{
+				"wkt":     "SRID=4326;LINESTRING Z (1 2 3, 4 5 6)",
+				"geojson": `{"type":"Point","coordinates":[1.5,2.5]}`,
+				"name":    "Point Reyes",
+				"class":   1,
+			},
+			expected: map[string]interface{}{
+				"name":  "Point Reyes",
+				"class": 1,
+			},
+		},
+		"round": {
+			mode:      GeometryAttributesRound,
+			precision: 3,
+			tags: map[string]interface{}{
+				"wkt":     "POLYGON((-122.4194155 37.7749295, -122.4194 37.77, -0.0001 1.10009))",
+				"geojson": `{"type":"Point","coordinates":[1.23456,-2.5]}`,
+				"height":  "12.3456",
+			},
+			expected: map[string]interface{}{
+				"wkt":     "POLYGON((-122.419 37.775, -122.419 37.77, 0 1.1))",
+				"geojson": `{"type":"Point","coordinates":[1.235,-2.5]}`,
+				"height":  "12.3456",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
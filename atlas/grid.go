@@ -0,0 +1,330 @@
+package atlas
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const (
+	// gridTileSize is the size in pixels of a tile of a Grid's Resolutions
+	gridTileSize = 256
+	// webMercatorRadius is the radius of the sphere of web mercator
+	webMercatorRadius = 6378137.0
+)
+
+// Grid is the tile matrix set of a map: the tiles covering the grid's extent at each zoom,
+// in the grid's CRS. The tiles are numbered from the top left corner of the extent.
+type Grid struct {
+	// Name of the grid, i.e. WebMercatorQuad
+	Name string
+	// SRID of the grid's CRS. The features of grids of other CRSs than tegola.WebMercator and
+	// tegola.WGS84 must be in the grid's CRS, as tegola only reprojects between the two.
+	SRID uint64
+	// Extent covered by the tiles, in the grid's CRS
+	Extent geom.Extent
+	// MatrixWidth and MatrixHeight are the columns and rows of tiles of zoom 0. Defaults to 1.
+	MatrixWidth  uint
+	MatrixHeight uint
+	// Resolutions are the units of the CRS per pixel of a 256 pixel tile at each zoom, from
+	// zoom 0. When empty every zoom halves the tiles of the zoom below it.
+	Resolutions []float64
+}
+
+var (
+	// WebMercatorGrid is the grid of slippy map tiles, the default of maps
+	WebMercatorGrid = Grid{
+		Name:   "WebMercatorQuad",
+		SRID:   tegola.WebMercator,
+		Extent: geom.Extent{-slippy.WebMercatorMax, -slippy.WebMercatorMax, slippy.WebMercatorMax, slippy.WebMercatorMax},
+	}
+	// WGS84Grid covers the world in longitude and latitude with two tiles at zoom 0
+	WGS84Grid = Grid{
+		Name:        "WorldCRS84Quad",
+		SRID:        tegola.WGS84,
+		Extent:      geom.Extent{-180, -90, 180, 90},
+		MatrixWidth: 2,
+	}
+)
+
+// Grids are the predefined grids by name
+var Grids = map[string]Grid{
+	WebMercatorGrid.Name: WebMercatorGrid,
+	WGS84Grid.Name:       WGS84Grid,
+}
+
+// GridByName returns a predefined grid, the names are case insensitive
+func GridByName(name string) (Grid, error) {
+	for n, g := range Grids {
+		if strings.EqualFold(n, name) {
+			return g, nil
+		}
+	}
+	return Grid{}, fmt.Errorf("atlas: unknown grid (%v)", name)
+}
+
+// Validate reports the first problem of the grid
+func (g Grid) Validate() error {
+	if g.SRID == 0 {
+		return errors.New("atlas: grid srid is missing")
+	}
+	if g.Extent.MinX() >= g.Extent.MaxX() || g.Extent.MinY() >= g.Extent.MaxY() {
+		return errors.New("atlas: grid extent is empty")
+	}
+	for i, r := range g.Resolutions {
+		if r <= 0 || (i > 0 && r >= g.Resolutions[i-1]) {
+			return errors.New("atlas: grid resolutions must be positive and decrease with each zoom")
+		}
+	}
+	if len(g.Resolutions) > MaxZoom+1 {
+		return fmt.Errorf("atlas: grid has more resolutions than zooms (%v)", MaxZoom+1)
+	}
+	return nil
+}
+
+// reprojects reports if features and bounds are reprojected to the grid's CRS
+func (g Grid) reprojects() bool {
+	return g.SRID == tegola.WebMercator || g.SRID == tegola.WGS84
+}
+
+// IsWebMercator reports if the grid is the grid of slippy map tiles
+func (g Grid) IsWebMercator() bool {
+	return g.SRID == tegola.WebMercator && g.Extent == WebMercatorGrid.Extent &&
+		g.matrixWidth() == 1 && g.matrixHeight() == 1 && len(g.Resolutions) == 0
+}
+
+func (g Grid) matrixWidth() uint {
+	if g.MatrixWidth == 0 {
+		return 1
+	}
+	return g.MatrixWidth
+}
+
+func (g Grid) matrixHeight() uint {
+	if g.MatrixHeight == 0 {
+		return 1
+	}
+	return g.MatrixHeight
+}
+
+// MaxZoom is the highest zoom of the grid
+func (g Grid) MaxZoom() uint {
+	if len(g.Resolutions) > 0 {
+		return uint(len(g.Resolutions) - 1)
+	}
+	return MaxZoom
+}
+
+// tileSpan returns the width and height of the tiles of the zoom in the grid's CRS
+func (g Grid) tileSpan(z uint) (w, h float64) {
+	if len(g.Resolutions) > 0 {
+		span := g.Resolutions[z] * gridTileSize
+		return span, span
+	}
+	scale := math.Exp2(float64(z))
+	return (g.Extent.MaxX() - g.Extent.MinX()) / (float64(g.matrixWidth()) * scale),
+		(g.Extent.MaxY() - g.Extent.MinY()) / (float64(g.matrixHeight()) * scale)
+}
+
+// MatrixSize returns the number of columns and rows of tiles of the zoom. Zooms above the
+// grid's MaxZoom have no tiles.
+func (g Grid) MatrixSize(z uint) (cols, rows uint) {
+	if z > g.MaxZoom() {
+		return 0, 0
+	}
+	if len(g.Resolutions) > 0 {
+		w, h := g.tileSpan(z)
+		// tiles partly covering the extent are included
+		return uint(math.Ceil((g.Extent.MaxX() - g.Extent.MinX()) / w)),
+			uint(math.Ceil((g.Extent.MaxY() - g.Extent.MinY()) / h))
+	}
+	scale := uint(1) << z
+	return g.matrixWidth() * scale, g.matrixHeight() * scale
+}
+
+// ContainsTile reports if the tile is a tile of the grid
+func (g Grid) ContainsTile(z, x, y uint) bool {
+	cols, rows := g.MatrixSize(z)
+	return x < cols && y < rows
+}
+
+// TileExtent returns the extent of the tile in the grid's CRS
+func (g Grid) TileExtent(z, x, y uint) *geom.Extent {
+	if g.IsWebMercator() {
+		return slippy.NewTile(z, x, y).Extent3857()
+	}
+	w, h := g.tileSpan(z)
+	minX := g.Extent.MinX() + float64(x)*w
+	maxY := g.Extent.MaxY() - float64(y)*h
+	return geom.NewExtent([2]float64{minX, maxY - h}, [2]float64{minX + w, maxY})
+}
+
+// ancestor returns the column and row of the tile of the lower zoom pz containing the tile
+func (g Grid) ancestor(z, x, y, pz uint) (uint, uint) {
+	if len(g.Resolutions) == 0 {
+		return x >> (z - pz), y >> (z - pz)
+	}
+	// the tiles of grids with resolutions may not nest, the ancestor contains the tile's center
+	e := g.TileExtent(z, x, y)
+	w, h := g.tileSpan(pz)
+	cx, cy := (e.MinX()+e.MaxX())/2, (e.MinY()+e.MaxY())/2
+	return uint((cx - g.Extent.MinX()) / w), uint((g.Extent.MaxY() - cy) / h)
+}
+
+// Bounds returns the extent covered by the grid's tiles in WGS84. The bounds of grids whose
+// CRS can't be reprojected are the world's.
+func (g Grid) Bounds() *geom.Extent {
+	if g.IsWebMercator() || !g.reprojects() {
+		return tegola.WGS84Bounds
+	}
+	return g.toWGS84(&g.Extent)
+}
+
+// TileBounds returns the extent of the tile in WGS84, for checks against a map's Bounds. nil
+// for the tiles of grids whose CRS can't be reprojected.
+func (g Grid) TileBounds(z, x, y uint) *geom.Extent {
+	if g.IsWebMercator() {
+		return slippy.NewTile(z, x, y).Extent4326()
+	}
+	if !g.reprojects() {
+		return nil
+	}
+	return g.toWGS84(g.TileExtent(z, x, y))
+}
+
+// TileRange returns the first and last columns and rows of the tiles of the zoom intersecting
+// the bounds, in WGS84, expanded by buffer tiles on each side. ok is false when the bounds
+// don't intersect the grid or the grid's CRS can't be reprojected.
+func (g Grid) TileRange(z uint, bounds geom.Extent, buffer float64) (minX, minY, maxX, maxY uint, ok bool) {
+	cols, rows := g.MatrixSize(z)
+	if cols == 0 || rows == 0 {
+		return 0, 0, 0, 0, false
+	}
+
+	var min, max [2]float64
+	switch g.SRID {
+	case tegola.WebMercator:
+		var err error
+		if min, max, err = webMercatorExtent(bounds); err != nil {
+			return 0, 0, 0, 0, false
+		}
+	case tegola.WGS84:
+		min, max = bounds.Min(), bounds.Max()
+	default:
+		return 0, 0, 0, 0, false
+	}
+
+	w, h := g.tileSpan(z)
+	min[0], min[1] = min[0]-buffer*w, min[1]-buffer*h
+	max[0], max[1] = max[0]+buffer*w, max[1]+buffer*h
+	if !extentsIntersect(g.Extent, geom.Extent{min[0], min[1], max[0], max[1]}) {
+		return 0, 0, 0, 0, false
+	}
+
+	// the column or row containing v, clamped to the tiles of the zoom
+	tile := func(v, span float64, n uint) uint {
+		t := math.Floor(v / span)
+		switch {
+		case t < 0:
+			return 0
+		case t >= float64(n):
+			return n - 1
+		}
+		return uint(t)
+	}
+	// tile rows grow southwards
+	return tile(min[0]-g.Extent.MinX(), w, cols), tile(g.Extent.MaxY()-max[1], h, rows),
+		tile(max[0]-g.Extent.MinX(), w, cols), tile(g.Extent.MaxY()-min[1], h, rows), true
+}
+
+// extentsIntersect reports if the extents intersect, including touching edges
+func extentsIntersect(a, b geom.Extent) bool {
+	return a.MinX() <= b.MaxX() && a.MaxX() >= b.MinX() && a.MinY() <= b.MaxY() && a.MaxY() >= b.MinY()
+}
+
+func (g Grid) toWGS84(e *geom.Extent) *geom.Extent {
+	if g.SRID == tegola.WGS84 {
+		return e
+	}
+	// inverse spherical mercator of the extent's corners
+	lon := func(x float64) float64 { return x * 180 / (math.Pi * webMercatorRadius) }
+	lat := func(y float64) float64 { return math.Atan(math.Sinh(y/webMercatorRadius)) * 180 / math.Pi }
+	return geom.NewExtent(
+		[2]float64{lon(e.MinX()), lat(e.MinY())},
+		[2]float64{lon(e.MaxX()), lat(e.MaxY())},
+	)
+}
+
+// gridTile is a tile of a grid given to providers, its extents are in the grid's CRS
+type gridTile struct {
+	z, x, y uint
+	extent  *geom.Extent
+	srid    uint64
+	// buffer in pixels of the tile's extent
+	buffer uint
+}
+
+func (g Grid) newProviderTile(z, x, y, buffer uint) provider.Tile {
+	if g.IsWebMercator() {
+		return provider.NewTile(z, x, y, buffer, uint(g.SRID))
+	}
+	return &gridTile{z: z, x: x, y: y, extent: g.TileExtent(z, x, y), srid: g.SRID, buffer: buffer}
+}
+
+func (t *gridTile) ZXY() (uint, uint, uint)                { return t.z, t.x, t.y }
+func (t *gridTile) Extent() (*geom.Extent, uint64)         { return t.extent, t.srid }
+func (t *gridTile) BufferedExtent() (*geom.Extent, uint64) { return t.bufferedExtent(), t.srid }
+
+func (t *gridTile) bufferedExtent() *geom.Extent {
+	// the buffer is in pixels of the 4096 pixel extent the tiles are encoded with
+	return t.extent.ExpandBy(t.extent.XSpan() * float64(t.buffer) / float64(slippy.MvtTileDim))
+}
+
+// epsilon returns the simplification tolerance of the tile, scaled from web mercator to the
+// units of the grid's tiles
+func (g Grid) epsilon(t *tegola.Tile) float64 {
+	if g.IsWebMercator() {
+		return t.ZEpislon()
+	}
+	w, _ := g.tileSpan(t.Z)
+	return t.ZEpislon() * w / (2 * slippy.WebMercatorMax / math.Exp2(float64(t.Z)))
+}
+
+// toGridSRID transforms a geometry of the srid to the CRS of the grid
+func (g Grid) toGridSRID(srid uint64, geo geom.Geometry) (geom.Geometry, error) {
+	if srid == g.SRID {
+		return geo, nil
+	}
+	if !g.reprojects() {
+		return nil, fmt.Errorf("atlas: features of SRID (%v) can't be reprojected to the grid's SRID (%v)", srid, g.SRID)
+	}
+	wm, err := basic.ToWebMercator(srid, geo)
+	if err != nil {
+		return nil, err
+	}
+	return basic.FromWebMercator(g.SRID, wm)
+}
+
+// TileGrid returns the map's Grid, WebMercatorGrid when the map has none
+func (m Map) TileGrid() Grid {
+	if m.Grid == nil {
+		return WebMercatorGrid
+	}
+	return *m.Grid
+}
+
+// NewMapWithGrid creates a new map of the grid's tiles with the necessary default values
+func NewMapWithGrid(name string, g Grid) Map {
+	m := NewWebMercatorMap(name)
+	m.Grid = &g
+	m.SRID = g.SRID
+	m.Bounds = g.Bounds()
+	return m
+}
@@ -0,0 +1,258 @@
+package atlas_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+func TestGrid(t *testing.T) {
+	type tcase struct {
+		grid   atlas.Grid
+		z      uint
+		cols   uint
+		rows   uint
+		x, y   uint
+		extent geom.Extent
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			if err := tc.grid.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cols, rows := tc.grid.MatrixSize(tc.z)
+			if cols != tc.cols || rows != tc.rows {
+				t.Errorf("matrix size, expected %vx%v got %vx%v", tc.cols, tc.rows, cols, rows)
+			}
+			if !tc.grid.ContainsTile(tc.z, tc.x, tc.y) {
+				t.Errorf("contains tile, expected true")
+			}
+			if tc.grid.ContainsTile(tc.z, cols, 0) || tc.grid.ContainsTile(tc.z, 0, rows) {
+				t.Errorf("contains tile outside of the matrix, expected false")
+			}
+			if got := tc.grid.TileExtent(tc.z, tc.x, tc.y); *got != tc.extent {
+				t.Errorf("tile extent, expected %v got %v", tc.extent, *got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"web mercator": {
+			grid:   atlas.WebMercatorGrid,
+			z:      1,
+			cols:   2,
+			rows:   2,
+			x:      1,
+			y:      0,
+			extent: *slippy.NewTile(1, 1, 0).Extent3857(),
+		},
+		"wgs84 zoom 0": {
+			grid:   atlas.WGS84Grid,
+			z:      0,
+			cols:   2,
+			rows:   1,
+			x:      1,
+			y:      0,
+			extent: geom.Extent{0, -90, 180, 90},
+		},
+		"wgs84 zoom 2": {
+			grid:   atlas.WGS84Grid,
+			z:      2,
+			cols:   8,
+			rows:   4,
+			x:      3,
+			y:      1,
+			extent: geom.Extent{-45, 0, 0, 45},
+		},
+		"resolutions": {
+			grid: atlas.Grid{
+				SRID:        tegola.WGS84,
+				Extent:      geom.Extent{0, 0, 10, 5},
+				Resolutions: []float64{1.0 / 64, 1.0 / 256},
+			},
+			z: 1,
+			// tiles of 1 degree, the extent's width
+			cols:   10,
+			rows:   5,
+			x:      2,
+			y:      1,
+			extent: geom.Extent{2, 3, 3, 4},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+
+	t.Run("max zoom", func(t *testing.T) {
+		g := atlas.Grid{SRID: tegola.WGS84, Extent: geom.Extent{0, 0, 10, 5}, Resolutions: []float64{0.1, 0.05}}
+		if g.MaxZoom() != 1 {
+			t.Errorf("max zoom, expected 1 got %v", g.MaxZoom())
+		}
+		if g.ContainsTile(2, 0, 0) {
+			t.Errorf("contains tile above max zoom, expected false")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := map[string]atlas.Grid{
+			"srid":        {Extent: geom.Extent{0, 0, 700000, 1300000}},
+			"extent":      {SRID: tegola.WGS84, Extent: geom.Extent{0, 0, 0, 5}},
+			"resolutions": {SRID: tegola.WGS84, Extent: geom.Extent{0, 0, 10, 5}, Resolutions: []float64{0.1, 0.2}},
+		}
+		for name, g := range invalid {
+			if err := g.Validate(); err == nil {
+				t.Errorf("%v, expected an error", name)
+			}
+		}
+	})
+
+	t.Run("national grid", func(t *testing.T) {
+		// the british national grid, whose features can't be reprojected
+		g := atlas.Grid{SRID: 27700, Extent: geom.Extent{0, 0, 700000, 1300000}, Resolutions: []float64{896, 448}}
+		if err := g.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if g.TileBounds(0, 0, 0) != nil {
+			t.Errorf("tile bounds, expected nil")
+		}
+		if _, _, _, _, ok := g.TileRange(0, *tegola.WGS84Bounds, 0); ok {
+			t.Errorf("tile range, expected the bounds not to be projected")
+		}
+	})
+
+	t.Run("tile range", func(t *testing.T) {
+		type rcase struct {
+			grid                   atlas.Grid
+			z                      uint
+			bounds                 geom.Extent
+			buffer                 float64
+			minX, minY, maxX, maxY uint
+			ok                     bool
+		}
+		ranges := map[string]rcase{
+			"web mercator": {
+				grid:   atlas.WebMercatorGrid,
+				z:      2,
+				bounds: geom.Extent{-10, 10, 10, 20},
+				minX:   1, minY: 1, maxX: 2, maxY: 1,
+				ok: true,
+			},
+			"web mercator world": {
+				grid:   atlas.WebMercatorGrid,
+				z:      1,
+				bounds: *tegola.WGS84Bounds,
+				minX:   0, minY: 0, maxX: 1, maxY: 1,
+				ok: true,
+			},
+			"wgs84": {
+				grid:   atlas.WGS84Grid,
+				z:      1,
+				bounds: geom.Extent{10, -10, 20, -5},
+				minX:   2, minY: 1, maxX: 2, maxY: 1,
+				ok: true,
+			},
+			"wgs84 buffer": {
+				grid:   atlas.WGS84Grid,
+				z:      1,
+				bounds: geom.Extent{10, -10, 20, -5},
+				buffer: 0.25,
+				minX:   1, minY: 0, maxX: 2, maxY: 1,
+				ok: true,
+			},
+			"outside": {
+				grid:   atlas.Grid{SRID: tegola.WGS84, Extent: geom.Extent{0, 0, 10, 5}},
+				z:      0,
+				bounds: geom.Extent{20, 20, 30, 30},
+			},
+		}
+		for name, tc := range ranges {
+			minX, minY, maxX, maxY, ok := tc.grid.TileRange(tc.z, tc.bounds, tc.buffer)
+			if ok != tc.ok || minX != tc.minX || minY != tc.minY || maxX != tc.maxX || maxY != tc.maxY {
+				t.Errorf("%v: expected %v %v %v %v %v got %v %v %v %v %v", name, tc.minX, tc.minY, tc.maxX, tc.maxY, tc.ok, minX, minY, maxX, maxY, ok)
+			}
+		}
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		g, err := atlas.GridByName("worldcrs84quad")
+		if err != nil || g.SRID != tegola.WGS84 {
+			t.Errorf("expected WGS84Grid got %v, %v", g, err)
+		}
+		if _, err := atlas.GridByName("unknown"); err == nil {
+			t.Errorf("unknown grid, expected an error")
+		}
+	})
+}
+
+func TestMapEncodeGrid(t *testing.T) {
+	m := atlas.NewMapWithGrid("wgs84", atlas.WGS84Grid)
+	m.Layers = []atlas.Layer{{
+		Name:            "outline",
+		ProviderLayerID: "test-layer",
+		Provider:        &test.TileProvider{},
+	}}
+
+	b, err := m.Encode(context.Background(), slippy.NewTile(0, 1, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tile vectorTile.Tile
+	if err := proto.Unmarshal(b, &tile); err != nil {
+		t.Fatal(err)
+	}
+	if len(tile.Layers) != 1 || len(tile.Layers[0].Features) != 1 {
+		t.Fatalf("expected a layer of 1 feature, got %v", tile.Layers)
+	}
+
+	// the test provider's feature outlines the tile, which covers the tile's pixels once encoded
+	var x, y, minX, minY, maxX, maxY int32
+	geo := tile.Layers[0].Features[0].Geometry
+	for i := 0; i < len(geo); {
+		count := int(geo[i] >> 3)
+		i++
+		if geo[i-1]&0x7 == 7 { // ClosePath
+			continue
+		}
+		for ; count > 0; count-- {
+			x += int32(geo[i]>>1) ^ -int32(geo[i]&1)
+			y += int32(geo[i+1]>>1) ^ -int32(geo[i+1]&1)
+			i += 2
+			if x < minX {
+				minX = x
+			}
+			if x > maxX {
+				maxX = x
+			}
+			if y < minY {
+				minY = y
+			}
+			if y > maxY {
+				maxY = y
+			}
+		}
+	}
+	if minX != 0 || minY != 0 || maxX != 4096 || maxY != 4096 {
+		t.Errorf("feature pixels, expected [0 0 4096 4096] got [%v %v %v %v]", minX, minY, maxX, maxY)
+	}
+}
@@ -0,0 +1,148 @@
+package atlas
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// Hooks are called as the maps' tiles are requested, encoded and seeded, so embedders can
+// filter, audit or measure them without changing the atlas. Any of the hooks may be nil.
+// The hooks are called concurrently by the tiles and layers encoded at once.
+type Hooks struct {
+	// OnTileRequest is called before a map tile is encoded by Encode. A non nil error stops
+	// the encoding and is returned by Encode, i.e. to deny the tile to the user of the context.
+	OnTileRequest func(ctx context.Context, m Map, tile *slippy.Tile) error
+	// OnFeature is called with each feature of a layer encoded in a tile, a UTFGrid or a
+	// query result, after the layer's tag transform and before the feature is reprojected
+	// and the default tags are added. The feature's tags and geometry, in the feature's SRID,
+	// may be changed, i.e. to redact tags. The feature is dropped when false is returned.
+	// Features of MVT providers and upstreams are not passed to the hook.
+	OnFeature func(ctx context.Context, m Map, l Layer, tile *slippy.Tile, f *provider.Feature) bool
+	// OnTileEncoded is called with the compressed bytes of a tile returned by Encode, or the
+	// error of the encoding. The bytes must not be changed.
+	OnTileEncoded func(ctx context.Context, m Map, tile *slippy.Tile, tileBytes []byte, err error)
+	// OnSeedProgress is called after each tile is seeded to the cache, by the cache seed
+	// command, warm-ups and re-seeds
+	OnSeedProgress func(ctx context.Context, p SeedProgress)
+}
+
+// SeedProgress reports a tile seeded to the cache
+type SeedProgress struct {
+	Map     string
+	Z, X, Y uint
+	// Duration of the tile's encoding and caching
+	Duration time.Duration
+	// Err is the error of the tile, nil when the tile was cached
+	Err error
+}
+
+type namedHooks struct {
+	name string
+	Hooks
+}
+
+var (
+	hooksLock sync.RWMutex
+	// registered holds the hooks in the order they are registered
+	registered []namedHooks
+)
+
+// RegisterHooks registers the hooks under the name. The hooks are called after the hooks
+// registered before them. An error is returned if hooks of the name are already registered.
+func RegisterHooks(name string, h Hooks) error {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+
+	for _, nh := range registered {
+		if nh.name == name {
+			return ErrHooksExist{Name: name}
+		}
+	}
+	// the slice is replaced, the hooks read before keep their hooks
+	hooks := make([]namedHooks, len(registered), len(registered)+1)
+	copy(hooks, registered)
+	registered = append(hooks, namedHooks{name: name, Hooks: h})
+	return nil
+}
+
+// UnregisterHooks removes the hooks of the name. false is returned when no hooks of the name
+// are registered.
+func UnregisterHooks(name string) bool {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+
+	for i, nh := range registered {
+		if nh.name != name {
+			continue
+		}
+		hooks := make([]namedHooks, 0, len(registered)-1)
+		hooks = append(hooks, registered[:i]...)
+		registered = append(hooks, registered[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// hookList is the registered hooks at the time it was read
+type hookList []namedHooks
+
+func registeredHooks() hookList {
+	hooksLock.RLock()
+	defer hooksLock.RUnlock()
+	return registered
+}
+
+// tileRequest calls the OnTileRequest hooks until one returns an error
+func (hl hookList) tileRequest(ctx context.Context, m Map, tile *slippy.Tile) error {
+	for _, h := range hl {
+		if h.OnTileRequest == nil {
+			continue
+		}
+		if err := h.OnTileRequest(ctx, m, tile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepFeature calls the OnFeature hooks until one drops the feature
+func (hl hookList) keepFeature(ctx context.Context, m Map, l Layer, tile *slippy.Tile, f *provider.Feature) bool {
+	for _, h := range hl {
+		if h.OnFeature != nil && !h.OnFeature(ctx, m, l, tile, f) {
+			return false
+		}
+	}
+	return true
+}
+
+func (hl hookList) tileEncoded(ctx context.Context, m Map, tile *slippy.Tile, tileBytes []byte, err error) {
+	for _, h := range hl {
+		if h.OnTileEncoded != nil {
+			h.OnTileEncoded(ctx, m, tile, tileBytes, err)
+		}
+	}
+}
+
+func (hl hookList) seedProgress(ctx context.Context, p SeedProgress) {
+	for _, h := range hl {
+		if h.OnSeedProgress != nil {
+			h.OnSeedProgress(ctx, p)
+		}
+	}
+}
+
+// reportSeed reports the map tile seeded since start to the OnSeedProgress hooks
+func reportSeed(ctx context.Context, mapName string, z, x, y uint, start time.Time, err error) {
+	registeredHooks().seedProgress(ctx, SeedProgress{
+		Map:      mapName,
+		Z:        z,
+		X:        x,
+		Y:        y,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+}
@@ -0,0 +1,187 @@
+package atlas
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/provider"
+)
+
+type userKey struct{}
+
+func TestRegisterHooks(t *testing.T) {
+	if err := RegisterHooks("audit", Hooks{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer UnregisterHooks("audit")
+
+	if err := RegisterHooks("audit", Hooks{}); !errors.As(err, &ErrHooksExist{}) {
+		t.Errorf("register twice, expected ErrHooksExist got %v", err)
+	}
+	if !UnregisterHooks("audit") {
+		t.Errorf("unregister, expected true")
+	}
+	if UnregisterHooks("audit") {
+		t.Errorf("unregister twice, expected false")
+	}
+	if len(registeredHooks()) != 0 {
+		t.Errorf("registered hooks, expected none got %v", len(registeredHooks()))
+	}
+}
+
+func TestEncodeHooks(t *testing.T) {
+	errDenied := errors.New("denied")
+
+	var (
+		mu      sync.Mutex
+		encoded []error
+	)
+	err := RegisterHooks("redact", Hooks{
+		OnTileRequest: func(ctx context.Context, m Map, tile *slippy.Tile) error {
+			if ctx.Value(userKey{}) == nil {
+				return errDenied
+			}
+			return nil
+		},
+		OnFeature: func(ctx context.Context, m Map, l Layer, tile *slippy.Tile, f *provider.Feature) bool {
+			// guests don't see the tag a
+			if ctx.Value(userKey{}) == "guest" {
+				delete(f.Tags, "a")
+			}
+			return true
+		},
+		OnTileEncoded: func(ctx context.Context, m Map, tile *slippy.Tile, tileBytes []byte, err error) {
+			mu.Lock()
+			encoded = append(encoded, err)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer UnregisterHooks("redact")
+
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{{Name: "places", ProviderLayerID: "test-layer", Provider: &tagsTiler{}}}
+	tile := slippy.NewTile(0, 0, 0)
+
+	// the keys of the tile's layer
+	keys := func(user string) []string {
+		ctx := context.WithValue(context.Background(), userKey{}, user)
+		b, err := m.Encode(ctx, tile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, release, err := gunzipTile(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+		var vt vectorTile.Tile
+		if err := proto.Unmarshal(b, &vt); err != nil {
+			t.Fatal(err)
+		}
+		if len(vt.Layers) != 1 {
+			t.Fatalf("layers, expected 1 got %v", len(vt.Layers))
+		}
+		return vt.Layers[0].Keys
+	}
+
+	if _, err := m.Encode(context.Background(), tile); err != errDenied {
+		t.Errorf("without a user, expected %v got %v", errDenied, err)
+	}
+	if got := keys("admin"); len(got) != 8 || got[0] != "a" {
+		t.Errorf("admin keys, expected a to h got %v", got)
+	}
+	if got := keys("guest"); len(got) != 7 || got[0] != "b" {
+		t.Errorf("guest keys, expected b to h got %v", got)
+	}
+	// denied tiles aren't encoded
+	if len(encoded) != 2 || encoded[0] != nil || encoded[1] != nil {
+		t.Errorf("encoded tiles, expected 2 without errors got %v", encoded)
+	}
+
+	// the features of queries are passed to the hook too
+	ctx := context.WithValue(context.Background(), userKey{}, "guest")
+	features, err := m.QueryFeatures(ctx, 13.4, 52.5, 10, 10)
+	if err != nil {
+		t.Fatalf("query, unexpected error: %v", err)
+	}
+	if len(features) != 1 {
+		t.Fatalf("query features, expected 1 got %v", len(features))
+	}
+	if _, ok := features[0].Tags["a"]; ok {
+		t.Errorf("query feature tags, expected a to be redacted got %v", features[0].Tags)
+	}
+}
+
+func TestFeatureHooksDrop(t *testing.T) {
+	err := RegisterHooks("drop", Hooks{
+		OnFeature: func(ctx context.Context, m Map, l Layer, tile *slippy.Tile, f *provider.Feature) bool {
+			return l.Name != "hidden"
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer UnregisterHooks("drop")
+
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{
+		{Name: "places", ProviderLayerID: "test-layer", Provider: &tagsTiler{}},
+		{Name: "hidden", ProviderLayerID: "test-layer", Provider: &tagsTiler{}},
+	}
+	b, err := m.encodeMVTTile(context.Background(), slippy.NewTile(0, 0, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var vt vectorTile.Tile
+	if err := proto.Unmarshal(b, &vt); err != nil {
+		t.Fatal(err)
+	}
+	features := map[string]int{}
+	for _, l := range vt.Layers {
+		features[l.GetName()] = len(l.Features)
+	}
+	if features["places"] != 1 || features["hidden"] != 0 {
+		t.Errorf("layer features, expected 1 place and no hidden features got %v", features)
+	}
+}
+
+func TestSeedProgressHooks(t *testing.T) {
+	var progress []SeedProgress
+	err := RegisterHooks("progress", Hooks{
+		OnSeedProgress: func(ctx context.Context, p SeedProgress) {
+			progress = append(progress, p)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer UnregisterHooks("progress")
+
+	a := &Atlas{}
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{{Name: "places", ProviderLayerID: "test-layer", Provider: &tagsTiler{}}}
+	if err := a.SeedMapTile(context.Background(), m, 3, 4, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(progress) != 1 {
+		t.Fatalf("progress, expected 1 tile got %v", len(progress))
+	}
+	p := progress[0]
+	if p.Map != "test" || p.Z != 3 || p.X != 4 || p.Y != 2 || p.Err != nil {
+		t.Errorf("progress, expected test 3/4/2 without an error got %+v", p)
+	}
+}
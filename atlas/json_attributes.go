@@ -0,0 +1,92 @@
+package atlas
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// DefaultJSONAttributesMaxBytes is the largest JSON string a complex attribute value is encoded to
+const DefaultJSONAttributesMaxBytes = 1024
+
+// JSONAttributesAll opts every attribute of a layer in to JSON encoding
+const JSONAttributesAll = "*"
+
+// isComplexAttribute reports if the value is a list or map, which MVT values can't represent
+func isComplexAttribute(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice, reflect.Array:
+		// byte slices are left to the encoder
+		return rv.Type().Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
+}
+
+func (l Layer) jsonAttribute(tag string) bool {
+	for _, f := range l.JSONAttributes {
+		if f == tag || f == JSONAttributesAll {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	droppedAttributesLock sync.Mutex
+	// droppedAttributes is keyed by map name, layer name and tag
+	droppedAttributes = map[[3]string]struct{}{}
+)
+
+// warnDroppedAttribute logs the first time an attribute of a layer is dropped
+func warnDroppedAttribute(mapName string, l Layer, tag string, reason string) {
+	droppedAttributesLock.Lock()
+	defer droppedAttributesLock.Unlock()
+
+	key := [3]string{mapName, l.MVTName(), tag}
+	if _, ok := droppedAttributes[key]; ok {
+		return
+	}
+	droppedAttributes[key] = struct{}{}
+
+	log.Warnf("map (%v) layer (%v) attribute (%v) dropped: %v", key[0], key[1], tag, reason)
+}
+
+// processJSONAttributes encodes list and map attribute values of the layer's JSONAttributes
+// as JSON strings. Values of other attributes, and values encoding to more than
+// JSONAttributesMaxBytes, are dropped.
+func (l Layer) processJSONAttributes(mapName string, tags map[string]interface{}) {
+	for k, v := range tags {
+		if !isComplexAttribute(v) {
+			continue
+		}
+
+		if !l.jsonAttribute(k) {
+			delete(tags, k)
+			warnDroppedAttribute(mapName, l, k, "list and map values are only encoded for json_attributes")
+			continue
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			delete(tags, k)
+			warnDroppedAttribute(mapName, l, k, err.Error())
+			continue
+		}
+		if l.JSONAttributesMaxBytes > 0 && uint(len(b)) > l.JSONAttributesMaxBytes {
+			delete(tags, k)
+			warnDroppedAttribute(mapName, l, k, "value is larger than json_attributes_max_bytes")
+			continue
+		}
+
+		tags[k] = string(b)
+	}
+}
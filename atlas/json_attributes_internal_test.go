@@ -0,0 +1,68 @@
+package atlas
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProcessJSONAttributes(t *testing.T) {
+	type tcase struct {
+		attributes []string
+		maxBytes   uint
+		tags       map[string]interface{}
+		expected   map[string]interface{}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			l := Layer{
+				Name:                   "test",
+				JSONAttributes:         tc.attributes,
+				JSONAttributesMaxBytes: tc.maxBytes,
+			}
+
+			l.processJSONAttributes("test", tc.tags)
+
+			if !reflect.DeepEqual(tc.tags, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, tc.tags)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"scalars untouched": {
+			tags:     map[string]interface{}{"name": "foo", "count": 3, "raw": []byte("b")},
+			expected: map[string]interface{}{"name": "foo", "count": 3, "raw": []byte("b")},
+		},
+		"not opted in dropped": {
+			tags:     map[string]interface{}{"name": "foo", "props": map[string]interface{}{"a": 1}},
+			expected: map[string]interface{}{"name": "foo"},
+		},
+		"map encoded": {
+			attributes: []string{"props"},
+			tags:       map[string]interface{}{"props": map[string]interface{}{"a": 1}, "list": []string{"a"}},
+			expected:   map[string]interface{}{"props": `{"a":1}`},
+		},
+		"all attributes": {
+			attributes: []string{JSONAttributesAll},
+			tags:       map[string]interface{}{"props": map[string]interface{}{"a": 1}, "list": []string{"a", "b"}},
+			expected:   map[string]interface{}{"props": `{"a":1}`, "list": `["a","b"]`},
+		},
+		"too large dropped": {
+			attributes: []string{"list"},
+			maxBytes:   8,
+			tags:       map[string]interface{}{"list": []int{1, 2, 3, 4, 5}, "small": []int{1}},
+			expected:   map[string]interface{}{},
+		},
+		"at the limit": {
+			attributes: []string{"list"},
+			maxBytes:   7,
+			tags:       map[string]interface{}{"list": []int{1, 2, 3}},
+			expected:   map[string]interface{}{"list": "[1,2,3]"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
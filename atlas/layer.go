@@ -1,6 +1,9 @@
 package atlas
 
 import (
+	"text/template"
+	"time"
+
 	"github.com/go-spatial/geom"
 	"github.com/go-spatial/tegola/provider"
 )
@@ -12,17 +15,88 @@ type Layer struct {
 	ProviderLayerID string
 	MinZoom         uint
 	MaxZoom         uint
+	// OverzoomLevels serves the layer at this many zooms above its MaxZoom: the features of the
+	// tile's ancestor at MaxZoom are fetched and clipped to the tile
+	OverzoomLevels uint
 	// instantiated provider
 	Provider provider.Tiler
 	// default tags to include when encoding the layer. provider tags take precedence
 	DefaultTags map[string]interface{}
-	GeomType    geom.Geometry
+	// DefaultTagTemplates are default tags set to the output of their template, executed with the
+	// feature's tags and the tile's Z, X and Y when the layer is encoded. See ParseDefaultTags.
+	DefaultTagTemplates map[string]*template.Template
+	GeomType            geom.Geometry
 	// DontSimplify indicates wheather feature simplification should be applied.
 	// We use a negative in the name so the default is to simplify
 	DontSimplify bool
 	// DontClip indicates wheather feature clipping should be applied.
 	// We use a negative in the name so the default is to clip
 	DontClip bool
+	// MakeValid repairs the geometries of the features before they are simplified and encoded:
+	// repeated points are removed, self-intersecting rings are split and rings are rewound
+	MakeValid bool
+	// MinPolygonArea drops polygons with a smaller area, in square tile pixels. 0 keeps every polygon.
+	MinPolygonArea float64
+	// MinLineLength drops lines which are shorter, in tile pixels. 0 keeps every line.
+	MinLineLength float64
+	// MergeLines merges the touching lines of the features with the same tags into longer lines
+	// when the layer is encoded
+	MergeLines bool
+	// SortFeatures sorts the layer's features by ID when the layer is encoded, so tiles are
+	// reproducible whatever the order the provider returns the features in. Otherwise the features
+	// are in the provider's order, which is the order they are drawn in.
+	SortFeatures bool
+	// TagTransform shapes the tags of the features once the provider returns them
+	TagTransform TagTransform
+	// GeometryAttributes controls the handling of attribute values which echo a geometry (WKT or GeoJSON)
+	GeometryAttributes GeometryAttributes
+	// GeometryAttributesPrecision is the number of decimal places used when rounding geometry attributes
+	GeometryAttributesPrecision uint
+	// JSONAttributes are the attributes whose list and map values are encoded as JSON strings.
+	// "*" includes every attribute. List and map values of other attributes are dropped.
+	JSONAttributes []string
+	// JSONAttributesMaxBytes drops JSON encoded values larger than this. 0 disables the limit.
+	JSONAttributesMaxBytes uint
+	// ExpiresField is the name of a feature tag holding the time the feature expires.
+	// Expired features are dropped when the tile is encoded.
+	ExpiresField string
+	// Timeout bounds the layer's provider call. When exceeded the layer is left out of the
+	// tile and the tile is still returned. 0 leaves the layer bound only by the tile's context.
+	Timeout time.Duration
+	// Optional layers whose provider fails are left out of the tile and the tile is still
+	// returned. A failing required layer fails the tile.
+	Optional bool
+	// Availability limits the times the layer is served. Always available when empty.
+	Availability Availability
+	// FreshnessSLA is the maximum age of the layer's data, as reported by providers implementing
+	// provider.Freshness. 0 disables freshness monitoring of the layer.
+	FreshnessSLA time.Duration
+	// Paint are the paint properties of the layer in the map's generated style, set over the
+	// paint properties generated for the layer's geometry type
+	Paint map[string]interface{}
+	// UTFGridKey is the tag keying the layer's features in the UTFGrid of a tile. Layers
+	// without a key are left out of the grid.
+	UTFGridKey string
+}
+
+// ServedMaxZoom returns the highest zoom the layer is served at, its MaxZoom and OverzoomLevels.
+// 0 is returned for layers without a MaxZoom, which are served at every zoom.
+func (l Layer) ServedMaxZoom() uint {
+	if l.MaxZoom == 0 {
+		return 0
+	}
+	if max := l.MaxZoom + l.OverzoomLevels; max < MaxZoom {
+		return max
+	}
+	if l.MaxZoom > MaxZoom {
+		return l.MaxZoom
+	}
+	return MaxZoom
+}
+
+// overzoomed reports if the tiles of the zoom are above the layer's MaxZoom
+func (l Layer) overzoomed(z uint) bool {
+	return l.MaxZoom != 0 && z > l.MaxZoom
 }
 
 // MVTName will return the value that will be encoded in the Name field when the layer is encoded as MVT
@@ -0,0 +1,77 @@
+package atlas
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMapForEachLayer(t *testing.T) {
+	type tcase struct {
+		concurrency int
+		layers      int
+		// the most layers expected to be fetched at once
+		expected int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			m := Map{LayerConcurrency: tc.concurrency}
+			layers := make([]Layer, tc.layers)
+
+			var (
+				mu            sync.Mutex
+				running, most int
+				called        = make([]int, tc.layers)
+			)
+			m.forEachLayer(context.Background(), layers, func(i int, l Layer) {
+				mu.Lock()
+				called[i]++
+				running++
+				if running > most {
+					most = running
+				}
+				mu.Unlock()
+
+				// hold the worker so the other layers are started
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+			})
+
+			for i, n := range called {
+				if n != 1 {
+					t.Errorf("layer %v, expected 1 call got %v", i, n)
+				}
+			}
+			if most != tc.expected {
+				t.Errorf("concurrent layers, expected %v got %v", tc.expected, most)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"bounded":            {concurrency: 2, layers: 6, expected: 2},
+		"unbounded":          {concurrency: 0, layers: 6, expected: 6},
+		"bound above layers": {concurrency: 8, layers: 3, expected: 3},
+		"no layers":          {concurrency: 2, layers: 0, expected: 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var calls int
+		Map{LayerConcurrency: 1}.forEachLayer(ctx, make([]Layer, 3), func(int, Layer) { calls++ })
+		if calls != 0 {
+			t.Errorf("calls, expected 0 got %v", calls)
+		}
+	})
+}
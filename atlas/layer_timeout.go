@@ -0,0 +1,34 @@
+package atlas
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// layerContext returns the context for the layer's provider call. The tile's context
+// governs the whole render, layers with a Timeout get a child context bound by it.
+func (l Layer) layerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, l.Timeout)
+}
+
+// layerTimedOut reports if the layer's own timeout ended the provider call,
+// as opposed to the tile's context being canceled
+func layerTimedOut(tileCtx, layerCtx context.Context) bool {
+	return tileCtx.Err() == nil && layerCtx.Err() == context.DeadlineExceeded
+}
+
+// skipTimedOutLayer logs the skipped layer and marks the tile as expired so the
+// tile missing the layer is not cached
+func skipTimedOutLayer(ctx context.Context, mapName string, l Layer, tile *slippy.Tile) {
+	z, x, y := tile.ZXY()
+	log.Warnf("map (%v) layer (%v) exceeded its timeout (%v) for tile (z: %v, x: %v, y: %v), the layer is skipped", mapName, l.MVTName(), l.Timeout, z, x, y)
+
+	recordOmitted(ctx, l)
+	recordExpiry(ctx, time.Now())
+}
@@ -0,0 +1,86 @@
+package atlas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom/slippy"
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+// slowTiler blocks until its context is done
+type slowTiler struct {
+	test.TileProvider
+}
+
+func (st *slowTiler) TileFeatures(ctx context.Context, layer string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	<-ctx.Done()
+	return provider.ErrCanceled
+}
+
+func TestEncodeLayerTimeout(t *testing.T) {
+	type tcase struct {
+		timeout time.Duration
+		layers  []string
+		expired bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			m := NewWebMercatorMap("test")
+			m.Layers = []Layer{
+				{Name: "fast", ProviderLayerID: "test-layer", Provider: &test.TileProvider{}},
+				{Name: "slow", ProviderLayerID: "test-layer", Provider: &slowTiler{}, Timeout: tc.timeout},
+			}
+
+			ctx := WithExpiry(context.Background())
+			// the tile context bounds layers without a timeout
+			ctx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+
+			b, err := m.encodeMVTTile(ctx, slippy.NewTile(2, 3, 1))
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			var vt vectorTile.Tile
+			if err := proto.Unmarshal(b, &vt); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			var got []string
+			for _, l := range vt.Layers {
+				got = append(got, l.GetName())
+			}
+			if len(got) != len(tc.layers) {
+				t.Fatalf("expected layers %v got %v", tc.layers, got)
+			}
+			for i := range got {
+				if got[i] != tc.layers[i] {
+					t.Errorf("expected layers %v got %v", tc.layers, got)
+				}
+			}
+
+			if expired := !Expiry(ctx).IsZero(); expired != tc.expired {
+				t.Errorf("expected expired %v got %v", tc.expired, expired)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"slow layer skipped": {
+			timeout: 10 * time.Millisecond,
+			layers:  []string{"fast"},
+			expired: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,220 @@
+package atlas
+
+import (
+	"math"
+
+	"github.com/go-spatial/geom"
+)
+
+// repairGeometry repairs the geometry of a feature of a layer with MakeValid set before it's
+// simplified and encoded:
+//
+//   - repeated points and the closing point of rings, which the encoder closes, are removed
+//   - self-intersecting rings are split at their intersections into simple rings
+//   - rings and lines left without an area or a length are dropped, as are holes outside of
+//     their polygon
+//   - exterior rings are wound counter clockwise and holes clockwise, which is clockwise
+//     and counter clockwise once the y axis is flipped into tile coordinates
+//
+// nil is returned when nothing is left of the geometry.
+func repairGeometry(g geom.Geometry) geom.Geometry {
+	switch g := g.(type) {
+	case geom.Polygoner:
+		return multiPolygonOrPolygon(repairPolygon(g.LinearRings()))
+
+	case geom.MultiPolygoner:
+		var plys []geom.Polygon
+		for _, p := range g.Polygons() {
+			plys = append(plys, repairPolygon(p)...)
+		}
+		return multiPolygonOrPolygon(plys)
+
+	case geom.LineStringer:
+		ls := removeRepeatedPoints(g.Verticies())
+		if len(ls) < 2 {
+			return nil
+		}
+		return geom.LineString(ls)
+
+	case geom.MultiLineStringer:
+		var mls geom.MultiLineString
+		for _, l := range g.LineStrings() {
+			if ls := removeRepeatedPoints(l); len(ls) >= 2 {
+				mls = append(mls, ls)
+			}
+		}
+		if len(mls) == 0 {
+			return nil
+		}
+		return mls
+
+	case geom.Collectioner:
+		var c geom.Collection
+		for _, cg := range g.Geometries() {
+			if rg := repairGeometry(cg); rg != nil {
+				c = append(c, rg)
+			}
+		}
+		if len(c) == 0 {
+			return nil
+		}
+		return c
+
+	default:
+		return g
+	}
+}
+
+// repairPolygon returns the valid polygons of the rings of a polygon. A self-intersecting
+// exterior ring is split into several polygons.
+func repairPolygon(rings [][][2]float64) []geom.Polygon {
+	if len(rings) == 0 {
+		return nil
+	}
+
+	var plys []geom.Polygon
+	for _, ring := range splitRing(rings[0]) {
+		if ringSignedArea(ring) < 0 {
+			reverseRing(ring)
+		}
+		plys = append(plys, geom.Polygon{ring})
+	}
+
+	for _, hole := range rings[1:] {
+		for _, ring := range splitRing(hole) {
+			if ringSignedArea(ring) > 0 {
+				reverseRing(ring)
+			}
+			// holes outside of the polygon's exterior rings are dropped
+			for i := range plys {
+				if ringContains(plys[i][0], geom.Point(ring[0])) {
+					plys[i] = append(plys[i], ring)
+					break
+				}
+			}
+		}
+	}
+
+	return plys
+}
+
+// splitRing splits a ring at its self-intersections into simple rings. Rings without an
+// area are dropped.
+func splitRing(ring [][2]float64) (rings [][][2]float64) {
+	todo := [][][2]float64{removeRepeatedPoints(closeRing(ring))}
+	for len(todo) > 0 {
+		r := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+
+		if len(r) < 3 {
+			continue
+		}
+
+		i, j, pt, ok := ringIntersection(r)
+		if !ok {
+			if ringSignedArea(r) != 0 {
+				rings = append(rings, r)
+			}
+			continue
+		}
+
+		// both rings have fewer points than r, as j > i+1 and the segments aren't the
+		// first and last segments
+		loop := append([][2]float64{pt}, r[i+1:j+1]...)
+		rest := append(append(append([][2]float64{}, r[:i+1]...), pt), r[j+1:]...)
+		todo = append(todo, removeRepeatedPoints(closeRing(loop)), removeRepeatedPoints(closeRing(rest)))
+	}
+	return rings
+}
+
+// ringIntersection returns the first segments, i and j, of a ring which intersect and aren't
+// adjacent, and their intersection. Segment i runs from point i to point i+1 of the ring.
+func ringIntersection(ring [][2]float64) (i, j int, pt [2]float64, ok bool) {
+	n := len(ring)
+	for i = 0; i < n; i++ {
+		for j = i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				// the last segment closes the ring at the first point
+				continue
+			}
+			if pt, ok = segmentIntersection(ring[i], ring[(i+1)%n], ring[j], ring[(j+1)%n]); ok {
+				return i, j, pt, true
+			}
+		}
+	}
+	return 0, 0, pt, false
+}
+
+// segmentIntersection returns the point the segments a1-a2 and b1-b2 intersect at, the
+// end points included. Parallel segments don't intersect.
+func segmentIntersection(a1, a2, b1, b2 [2]float64) (pt [2]float64, ok bool) {
+	if math.Max(a1[0], a2[0]) < math.Min(b1[0], b2[0]) || math.Max(b1[0], b2[0]) < math.Min(a1[0], a2[0]) ||
+		math.Max(a1[1], a2[1]) < math.Min(b1[1], b2[1]) || math.Max(b1[1], b2[1]) < math.Min(a1[1], a2[1]) {
+		return pt, false
+	}
+
+	dax, day := a2[0]-a1[0], a2[1]-a1[1]
+	dbx, dby := b2[0]-b1[0], b2[1]-b1[1]
+	denom := dax*dby - day*dbx
+	if denom == 0 {
+		return pt, false
+	}
+
+	t := ((b1[0]-a1[0])*dby - (b1[1]-a1[1])*dbx) / denom
+	u := ((b1[0]-a1[0])*day - (b1[1]-a1[1])*dax) / denom
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return pt, false
+	}
+	return [2]float64{a1[0] + t*dax, a1[1] + t*day}, true
+}
+
+// closeRing removes the closing point of a ring which repeats its first point, as rings
+// are closed when they are encoded.
+func closeRing(ring [][2]float64) [][2]float64 {
+	for len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	return ring
+}
+
+// removeRepeatedPoints returns a copy of the points without consecutive repeated points.
+func removeRepeatedPoints(pts [][2]float64) [][2]float64 {
+	out := make([][2]float64, 0, len(pts))
+	for i, pt := range pts {
+		if i > 0 && pt == pts[i-1] {
+			continue
+		}
+		out = append(out, pt)
+	}
+	return out
+}
+
+// ringSignedArea returns the signed area of a ring, positive when the ring is wound counter clockwise.
+func ringSignedArea(ring [][2]float64) float64 {
+	var sum float64
+	for i := range ring {
+		next := ring[(i+1)%len(ring)]
+		sum += ring[i][0]*next[1] - next[0]*ring[i][1]
+	}
+	return sum / 2
+}
+
+func reverseRing(ring [][2]float64) {
+	for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+		ring[i], ring[j] = ring[j], ring[i]
+	}
+}
+
+func multiPolygonOrPolygon(plys []geom.Polygon) geom.Geometry {
+	switch len(plys) {
+	case 0:
+		return nil
+	case 1:
+		return plys[0]
+	}
+	mp := make(geom.MultiPolygon, len(plys))
+	for i := range plys {
+		mp[i] = plys[i]
+	}
+	return mp
+}
@@ -0,0 +1,79 @@
+package atlas
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+)
+
+func TestRepairGeometry(t *testing.T) {
+	type tcase struct {
+		geom     geom.Geometry
+		expected geom.Geometry
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			got := repairGeometry(tc.geom)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"valid polygon": {
+			geom:     geom.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 10}}},
+			expected: geom.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 10}}},
+		},
+		"closing and repeated points": {
+			geom:     geom.Polygon{{{0, 0}, {10, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}},
+			expected: geom.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 10}}},
+		},
+		"clockwise exterior and counter clockwise hole": {
+			geom: geom.Polygon{
+				{{0, 0}, {0, 10}, {10, 10}, {10, 0}},
+				{{2, 2}, {8, 2}, {8, 8}, {2, 8}},
+			},
+			expected: geom.Polygon{
+				{{10, 0}, {10, 10}, {0, 10}, {0, 0}},
+				{{2, 8}, {8, 8}, {8, 2}, {2, 2}},
+			},
+		},
+		"hole outside the exterior": {
+			geom: geom.Polygon{
+				{{0, 0}, {10, 0}, {10, 10}, {0, 10}},
+				{{20, 28}, {28, 28}, {28, 20}, {20, 20}},
+			},
+			expected: geom.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 10}}},
+		},
+		"bowtie": {
+			geom: geom.Polygon{{{0, 0}, {10, 10}, {10, 0}, {0, 10}}},
+			expected: geom.MultiPolygon{
+				{{{0, 0}, {5, 5}, {0, 10}}},
+				{{{10, 0}, {10, 10}, {5, 5}}},
+			},
+		},
+		"collapsed ring": {
+			geom:     geom.Polygon{{{0, 0}, {10, 0}, {20, 0}}},
+			expected: nil,
+		},
+		"line repeated points": {
+			geom:     geom.LineString{{0, 0}, {0, 0}, {5, 5}, {5, 5}},
+			expected: geom.LineString{{0, 0}, {5, 5}},
+		},
+		"collapsed line": {
+			geom:     geom.MultiLineString{{{1, 1}, {1, 1}}},
+			expected: nil,
+		},
+		"point": {
+			geom:     geom.Point{1, 2},
+			expected: geom.Point{1, 2},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -1,24 +1,21 @@
 package atlas
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 	"sync"
-
-	"github.com/golang/protobuf/proto"
+	"time"
 
 	"github.com/go-spatial/geom"
 	"github.com/go-spatial/geom/encoding/mvt"
 	"github.com/go-spatial/geom/slippy"
 	"github.com/go-spatial/tegola"
-	"github.com/go-spatial/tegola/basic"
 	"github.com/go-spatial/tegola/dict"
 	"github.com/go-spatial/tegola/internal/convert"
+	"github.com/go-spatial/tegola/internal/servertiming"
+	"github.com/go-spatial/tegola/internal/tracing"
 	"github.com/go-spatial/tegola/maths/simplify"
 	"github.com/go-spatial/tegola/maths/validate"
 	"github.com/go-spatial/tegola/provider"
@@ -35,6 +32,7 @@ func NewWebMercatorMap(name string) Map {
 		SRID:       tegola.WebMercator,
 		TileExtent: 4096,
 		TileBuffer: uint64(tegola.DefaultTileBuffer),
+		MVTVersion: DefaultMVTVersion,
 	}
 }
 
@@ -56,6 +54,44 @@ type Map struct {
 	// MVT output values
 	TileExtent uint64
 	TileBuffer uint64
+	// MVTVersion is the Mapbox Vector Tile spec version encoded tiles are emitted
+	// and validated against. Default: DefaultMVTVersion
+	MVTVersion uint
+	// Upstream, when set, is the tile service the map's tiles are pulled from
+	// instead of being encoded from the map's layers
+	Upstream *Upstream
+	// Raster, when set, is a raster tile source served alongside the map's vector tiles
+	Raster *Raster
+	// Availability limits the times the map is served. Always available when empty.
+	Availability Availability
+	// Style is the path or url of a hosted Mapbox GL style of the map, described by the
+	// map's legend. Empty when the map has no hosted style.
+	Style string
+	// CacheVersion is part of the cache keys of the map's tiles, so changing it invalidates
+	// the map's cached tiles. Empty for none.
+	CacheVersion string
+	// LayerConcurrency is the most layers of a tile fetched from their providers and encoded at
+	// once. Every layer is fetched at once when 0.
+	LayerConcurrency int
+	// CacheMaxZoom, when set, is the highest zoom the map's tiles are cached at. Tiles above
+	// it are extracted from their ancestor at the zoom, see OverzoomTile.
+	CacheMaxZoom *uint
+	// MinZoom and MaxZoom, when set, are the zooms the map's tiles are served at, see Zooms
+	MinZoom *uint
+	MaxZoom *uint
+	// EmptyTileStatus is the HTTP status code the map's tiles without features are served
+	// with: http.StatusOK (or 0, the default) serves the empty tile, http.StatusNoContent and
+	// http.StatusNotFound serve no body.
+	EmptyTileStatus int
+	// CacheControls are the Cache-Control headers of the map's tiles by zoom, see CacheControlAt
+	CacheControls []CacheControl
+	// Grid is the tile matrix set of the map's tiles, the web mercator grid of slippy map tiles
+	// when nil. See TileGrid.
+	Grid *Grid
+
+	// availabilityChange is the soonest change of the availability of the map or its layers,
+	// set by FilterLayersByAvailability
+	availabilityChange time.Time
 
 	mvtProviderID string
 	mvtProvider   provider.MVTTiler
@@ -116,12 +152,63 @@ func (m Map) AddDebugLayers() Map {
 	return m
 }
 
+// Zooms returns the zooms the map's tiles are served at: the MinZoom and MaxZoom of the map
+// when set, otherwise the zooms of its layers and raster, or of its upstream. Maps with a
+// CacheMaxZoom serve the tiles above their layers' zooms from their ancestors, up to MaxZoom.
+// The zooms are limited to the zooms of the map's grid.
+func (m Map) Zooms() (min, max uint) {
+	switch {
+	case m.HasUpstream():
+		min, max = m.Upstream.MinZoom, m.Upstream.MaxZoom
+	case len(m.Layers) == 0 && !m.HasRaster():
+		min, max = 0, MaxZoom
+	default:
+		min = MaxZoom
+		for _, l := range m.Layers {
+			lmax := l.ServedMaxZoom()
+			// layers without a max zoom are served at every zoom above their min zoom
+			if lmax == 0 {
+				lmax = MaxZoom
+			}
+			if l.MinZoom < min {
+				min = l.MinZoom
+			}
+			if lmax > max {
+				max = lmax
+			}
+		}
+		if m.HasRaster() {
+			if m.Raster.MinZoom < min {
+				min = m.Raster.MinZoom
+			}
+			if m.Raster.MaxZoom > max {
+				max = m.Raster.MaxZoom
+			}
+		}
+		if m.CacheMaxZoom != nil {
+			max = MaxZoom
+		}
+	}
+
+	if m.MinZoom != nil {
+		min = *m.MinZoom
+	}
+	if m.MaxZoom != nil {
+		max = *m.MaxZoom
+	}
+	// grids with resolutions have fewer zooms
+	if gridMax := m.TileGrid().MaxZoom(); max > gridMax {
+		max = gridMax
+	}
+	return min, max
+}
+
 // FilterLayersByZoom returns a copy of a Map with a subset of layers that match the given zoom
 func (m Map) FilterLayersByZoom(zoom uint) Map {
 	var layers []Layer
 
 	for i := range m.Layers {
-		if (m.Layers[i].MinZoom <= zoom || m.Layers[i].MinZoom == 0) && (m.Layers[i].MaxZoom >= zoom || m.Layers[i].MaxZoom == 0) {
+		if (m.Layers[i].MinZoom <= zoom || m.Layers[i].MinZoom == 0) && (m.Layers[i].ServedMaxZoom() >= zoom || m.Layers[i].MaxZoom == 0) {
 			layers = append(layers, m.Layers[i])
 			continue
 		}
@@ -162,210 +249,373 @@ func (m Map) encodeMVTProviderTile(ctx context.Context, tile *slippy.Tile) ([]by
 	layers := make([]provider.Layer, len(m.Layers))
 	for i := range m.Layers {
 		layers[i] = provider.Layer{
-			ID:      m.Layers[i].ID,
+			ID:      m.Layers[i].ProviderLayerID,
 			MVTName: m.Layers[i].MVTName(),
 		}
 	}
-	return m.mvtProvider.MVTForLayers(ctx, ptile, layers)
+	ctx, span := tracing.Start(ctx, "provider.mvt_for_layers")
+	defer span.Finish()
+	span.SetAttribute("tegola.provider", m.mvtProviderID)
+	span.SetAttribute("tegola.layers", len(layers))
+	defer servertiming.Since(ctx, "provider", m.mvtProviderID, time.Now())
+
+	b, err := m.mvtProvider.MVTForLayers(ctx, ptile, layers)
+	span.SetError(err)
+	return b, err
 
 }
 
+// forEachLayer calls fn with each of the layers and their index concurrently, by a pool of at
+// most LayerConcurrency workers (a worker per layer when 0), and returns once the calls return.
+// Layers which haven't been started when the context is done are skipped.
+func (m Map) forEachLayer(ctx context.Context, layers []Layer, fn func(i int, l Layer)) {
+	workers := m.LayerConcurrency
+	if workers <= 0 || workers > len(layers) {
+		workers = len(layers)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					continue
+				}
+				fn(i, layers[i])
+			}
+		}()
+	}
+
+	for i := range layers {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
 // encodeMVTTile will encode the given tile into mvt format
 // TODO (arolek): support for max zoom
 func (m Map) encodeMVTTile(ctx context.Context, tile *slippy.Tile) ([]byte, error) {
 
 	// tile container
 	var mvtTile mvt.Tile
-	// wait group for concurrent layer fetching
-	var wg sync.WaitGroup
 
-	// layer stack
+	// layer stack, in the order of the map's layers however they are fetched
 	mvtLayers := make([]*mvt.Layer, len(m.Layers))
+	// errors of the required layers
+	layerErrs := make([]error, len(m.Layers))
+
+	// the tile's extent in the CRS of the map's grid
+	grid := m.TileGrid()
+	tileExtent := grid.TileExtent(tile.Z, tile.X, tile.Y)
+	hooks := registeredHooks()
+
+	// fetch and encode the layers concurrently
+	m.forEachLayer(ctx, m.Layers, func(i int, l Layer) {
+		mvtLayer := mvt.Layer{
+			Name: l.MVTName(),
+		}
+
+		// overzoomed layers fetch the features of the tile's ancestor at their max zoom, which
+		// are clipped to the tile
+		dataZ, dataX, dataY := tile.Z, tile.X, tile.Y
+		if l.overzoomed(tile.Z) {
+			dataZ = l.MaxZoom
+			dataX, dataY = grid.ancestor(tile.Z, tile.X, tile.Y, dataZ)
+		}
+		ptile := grid.newProviderTile(dataZ, dataX, dataY, uint(m.TileBuffer))
+
+		// used to check for expired features
+		now := time.Now()
+
+		// the features of the layer, added to the layer at once
+		layerFeatures := getFeatures()
+		defer putFeatures(layerFeatures)
+		// the line features held back to be merged, when the layer merges lines
+		var lines []mvt.Feature
+
+		// TODO (arolek): change out the tile type for VTile. tegola.Tile will be deprecated
+		tegolaTile := tegola.NewTile(tile.ZXY())
+		// the features are as detailed as the zoom they are fetched at
+		epsilon := grid.epsilon(tegola.NewTile(dataZ, dataX, dataY))
+		// the clip region (tile extent), set for the layer's first feature
+		var clipRegion *geom.Extent
+
+		// the layer's provider call is bound by the layer's timeout
+		layerCtx, cancel := l.layerContext(ctx)
+		defer cancel()
+
+		layerCtx, span := tracing.Start(layerCtx, "provider.tile_features")
+		defer span.Finish()
+		span.SetAttribute("tegola.layer", l.MVTName())
+		span.SetAttribute("tegola.provider_layer", l.ProviderLayerID)
+		defer servertiming.Since(ctx, "layer", l.MVTName(), now)
+
+		// fetch layer from data provider
+		err := l.Provider.TileFeatures(layerCtx, l.ProviderLayerID, ptile, func(f *provider.Feature) error {
+			// skip row if geometry collection empty.
+			g, ok := f.Geometry.(geom.Collection)
+			if ok && len(g.Geometries()) == 0 {
+				return nil
+			}
 
-	// set our waitgroup count
-	wg.Add(len(m.Layers))
+			// skip features which have expired
+			if l.expired(ctx, f.Tags, now) {
+				return nil
+			}
 
-	// iterate our layers
-	for i, layer := range m.Layers {
+			// rename, compute, cast and drop tags
+			l.transformTags(m.Name, f.Tags)
 
-		// go routine for fetching the layer concurrently
-		go func(i int, l Layer) {
-			mvtLayer := mvt.Layer{
-				Name: l.MVTName(),
+			if !hooks.keepFeature(ctx, m, l, tile, f) {
+				return nil
 			}
 
-			// on completion let the wait group know
-			defer wg.Done()
+			geo := f.Geometry
 
-			ptile := provider.NewTile(tile.Z, tile.X, tile.Y,
-				uint(m.TileBuffer), uint(m.SRID))
+			// check if the feature SRID and the SRID of the map's grid are different. If they are then reporject
+			if f.SRID != grid.SRID {
+				// TODO(arolek): support for additional projections
+				g, err := grid.toGridSRID(f.SRID, geo)
+				if err != nil {
+					return fmt.Errorf("unable to transform geometry to SRID (%v) from SRID (%v) for feature %v due to error: %w", grid.SRID, f.SRID, f.ID, err)
+				}
+				geo = g
+			}
 
-			// fetch layer from data provider
-			err := l.Provider.TileFeatures(ctx, l.ProviderLayerID, ptile, func(f *provider.Feature) error {
-				// skip row if geometry collection empty.
-				g, ok := f.Geometry.(geom.Collection)
-				if ok && len(g.Geometries()) == 0 {
+			// repair invalid geometries before they are simplified and clipped
+			if l.MakeValid {
+				if geo = repairGeometry(geo); geo == nil {
 					return nil
 				}
+			}
 
-				geo := f.Geometry
+			// TODO: remove this geom conversion step once the simplify function uses geom types
+			tegolaGeo, err := convert.ToTegola(geo)
+			if err != nil {
+				return err
+			}
 
-				// check if the feature SRID and map SRID are different. If they are then reporject
-				if f.SRID != m.SRID {
-					// TODO(arolek): support for additional projections
-					g, err := basic.ToWebMercator(f.SRID, geo)
-					if err != nil {
-						return fmt.Errorf("unable to transform geometry to webmercator from SRID (%v) for feature %v due to error: %w", f.SRID, f.ID, err)
-					}
-					geo = g
-				}
+			// encode list and map attribute values as JSON strings
+			l.processJSONAttributes(m.Name, f.Tags)
 
-				// TODO: remove this geom conversion step once the simplify function uses geom types
-				tegolaGeo, err := convert.ToTegola(geo)
-				if err != nil {
-					return err
-				}
+			// detect and strip or round attribute values which echo a geometry
+			l.processGeometryAttributes(m.Name, f.Tags)
 
-				// add default tags, but don't overwrite a tag that already exists
-				for k, v := range l.DefaultTags {
-					if _, ok := f.Tags[k]; !ok {
-						f.Tags[k] = v
-					}
-				}
+			// add default tags, but don't overwrite a tag that already exists
+			l.addDefaultTags(tile.Z, tile.X, tile.Y, f.Tags)
 
-				// TODO (arolek): change out the tile type for VTile. tegola.Tile will be deprecated
-				tegolaTile := tegola.NewTile(tile.ZXY())
+			sg := tegolaGeo
+			// multiple ways to turn off simplification. check the atlas init() function
+			// for how the second two conditions are set
+			if !l.DontSimplify && simplifyGeometries && dataZ < simplificationMaxZoom {
+				sg = simplify.SimplifyGeometry(tegolaGeo, epsilon)
+			}
 
-				sg := tegolaGeo
-				// multiple ways to turn off simplification. check the atlas init() function
-				// for how the second two conditions are set
-				if !l.DontSimplify && simplifyGeometries && tile.Z < simplificationMaxZoom {
-					sg = simplify.SimplifyGeometry(tegolaGeo, tegolaTile.ZEpislon())
+			// check if we need to clip and if we do build the clip region (tile extent)
+			if !l.DontClip && clipRegion == nil {
+				// CleanGeometry is expecting to operate in pixel coordinates so the clipRegion
+				// will need to be in this same coordinate system. this will change when the new
+				// make valid routing is implemented
+				pbb, err := tegolaTile.PixelBufferedBounds()
+				if err != nil {
+					return fmt.Errorf("err calculating tile pixel buffer bounds: %w", err)
 				}
 
-				// check if we need to clip and if we do build the clip region (tile extent)
-				var clipRegion *geom.Extent
-				if !l.DontClip {
-					// CleanGeometry is expecting to operate in pixel coordinates so the clipRegion
-					// will need to be in this same coordinate system. this will change when the new
-					// make valid routing is implemented
-					pbb, err := tegolaTile.PixelBufferedBounds()
-					if err != nil {
-						return fmt.Errorf("err calculating tile pixel buffer bounds: %w", err)
-					}
-
-					clipRegion = geom.NewExtent([2]float64{pbb[0], pbb[1]}, [2]float64{pbb[2], pbb[3]})
-				}
+				clipRegion = geom.NewExtent([2]float64{pbb[0], pbb[1]}, [2]float64{pbb[2], pbb[3]})
+			}
 
-				// TODO: remove this geom conversion step once the simplify function uses geom types
-				geo, err = convert.ToGeom(sg)
-				if err != nil {
-					return err
-				}
+			// TODO: remove this geom conversion step once the simplify function uses geom types
+			geo, err = convert.ToGeom(sg)
+			if err != nil {
+				return err
+			}
 
-				// TODO(arolek): currently the validate.CleanGeometry method does not operate
-				// well on geometries that are not scaled to tile coordinate space. this will change
-				// with the adoption of the new make valid routine. once implemented, the clipRegion
-				// calculation will need to be in the same coordinate space as the geometry the
-				// make valid function will be operating on.
-				geo = mvt.PrepareGeo(geo, tile.Extent3857(), float64(mvt.DefaultExtent))
+			// TODO(arolek): currently the validate.CleanGeometry method does not operate
+			// well on geometries that are not scaled to tile coordinate space. this will change
+			// with the adoption of the new make valid routine. once implemented, the clipRegion
+			// calculation will need to be in the same coordinate space as the geometry the
+			// make valid function will be operating on.
+			geo = mvt.PrepareGeo(geo, tileExtent, float64(mvt.DefaultExtent))
 
-				// TODO: remove this geom conversion step once the validate function uses geom types
-				sg, err = convert.ToTegola(geo)
-				if err != nil {
-					return err
-				}
+			// TODO: remove this geom conversion step once the validate function uses geom types
+			sg, err = convert.ToTegola(geo)
+			if err != nil {
+				return err
+			}
 
-				tegolaGeo, err = validate.CleanGeometry(ctx, sg, clipRegion)
-				if err != nil {
-					return fmt.Errorf("err making geometry valid: %w", err)
-				}
+			tegolaGeo, err = validate.CleanGeometry(layerCtx, sg, clipRegion)
+			if err != nil {
+				return fmt.Errorf("err making geometry valid: %w", err)
+			}
 
-				geo, err = convert.ToGeom(tegolaGeo)
-				if err != nil {
-					return nil
-				}
+			geo, err = convert.ToGeom(tegolaGeo)
+			if err != nil {
+				return nil
+			}
 
-				mvtLayer.AddFeatures(mvt.Feature{
-					ID:       &f.ID,
-					Tags:     f.Tags,
-					Geometry: geo,
-				})
+			// drop polygons and lines too small to be seen in the tile
+			if geo = l.dropTinyFeatures(geo); geo == nil {
+				return nil
+			}
 
+			feature := mvt.Feature{
+				ID:       &f.ID,
+				Tags:     f.Tags,
+				Geometry: geo,
+			}
+			if l.MergeLines && isLineFeature(geo) {
+				lines = append(lines, feature)
 				return nil
-			})
-			if err != nil {
-				switch {
-				case errors.Is(err, context.Canceled):
-					// Do nothing if we were cancelled.
-
-				default:
-					z, x, y := tile.ZXY()
-					// TODO (arolek): should we return an error to the response or just log the error?
-					// we can't just write to the response as the waitgroup is going to write to the response as well
-					log.Printf("err fetching tile (z: %v, x: %v, y: %v) features: %v", z, x, y, err)
-				}
-				return
 			}
 
-			// add the layer to the slice position
-			mvtLayers[i] = &mvtLayer
-		}(i, layer)
-	}
+			*layerFeatures = append(*layerFeatures, feature)
 
-	// wait for the waitgroup to finish
-	wg.Wait()
+			return nil
+		})
+		if err == nil && len(lines) > 0 {
+			*layerFeatures = append(*layerFeatures, mergeLines(lines)...)
+		}
+		// the number of features added to the layer
+		features := len(*layerFeatures)
+		if err == nil {
+			if l.SortFeatures {
+				sortFeatures(*layerFeatures)
+			}
+			mvtLayer.AddFeatures(*layerFeatures...)
+		}
+		timedOut := layerTimedOut(ctx, layerCtx)
+		if timedOut {
+			err = ErrLayerTimeout{Timeout: l.Timeout}
+		}
+
+		span.SetAttribute("tegola.features", features)
+		span.SetError(err)
+
+		if err != nil {
+			switch {
+			case errors.Is(err, context.Canceled):
+				// Do nothing if we were cancelled.
+
+			case timedOut:
+				// skip slow layers but still return the tile
+				skipTimedOutLayer(ctx, m.Name, l, tile)
+
+			case l.Optional:
+				// skip the failed optional layer but still return the tile
+				omitLayer(ctx, m.Name, l, tile, err)
+
+			default:
+				// the tile can't be returned without the layer
+				layerErrs[i] = ErrLayerFailed{Map: m.Name, Layer: l.MVTName(), Err: err}
+			}
+			return
+		}
+
+		recordFeatures(ctx, features)
+
+		// add the layer to the slice position
+		mvtLayers[i] = &mvtLayer
+	})
 
 	// stop processing if the context has an error. this check is necessary
 	// otherwise the server continues processing even if the request was canceled
-	// as the waitgroup was not notified of the cancel
+	// as the layers which weren't fetched yet were skipped
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	// add layers to our tile
-	mvtTile.AddLayers(mvtLayers...)
+	for _, err := range layerErrs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, span := tracing.Start(ctx, "mvt.encode")
+	defer span.Finish()
+	defer servertiming.Since(ctx, "encode", "", time.Now())
+
+	// add layers to our tile, the layers sharing a name are blended into one
+	if err := mvtTile.AddLayers(blendLayers(mvtLayers)...); err != nil {
+		span.SetError(err)
+		return nil, err
+	}
 
 	// generate the MVT tile
 	vtile, err := mvtTile.VTile(ctx)
 	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+
+	// sort the tags so the same features are always encoded to the same bytes
+	if err = sortTileTags(vtile); err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+
+	// set the layer versions and check the output conforms to the map's spec version
+	if err = prepareVTile(m.mvtVersion(), vtile); err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 
 	// encode our mvt tile
-	return proto.Marshal(vtile)
+	b, err := marshalTile(vtile)
+	span.SetAttribute("tegola.bytes", len(b))
+	span.SetError(err)
+	return b, err
 }
 
 // Encode will encode the given tile into mvt format
 func (m Map) Encode(ctx context.Context, tile *slippy.Tile) ([]byte, error) {
+	hooks := registeredHooks()
+	if err := hooks.tileRequest(ctx, m, tile); err != nil {
+		return nil, err
+	}
+
+	tileBytes, err := m.encode(ctx, tile)
+	hooks.tileEncoded(ctx, m, tile, tileBytes, err)
+	return tileBytes, err
+}
+
+// encode returns the gzipped tile of the map's upstream, MVT provider or layers
+func (m Map) encode(ctx context.Context, tile *slippy.Tile) ([]byte, error) {
 	var (
 		tileBytes []byte
 		err       error
 	)
-	if m.HasMVTProvider() {
+	ctx, span := tracing.Start(ctx, "atlas.encode")
+	defer span.Finish()
+	span.SetAttribute("tegola.map", m.Name)
+	span.SetAttribute("tegola.tile", fmt.Sprintf("%v/%v/%v", tile.Z, tile.X, tile.Y))
+
+	// tiles must not be reused once the map's or its layers' availability changes
+	if !m.availabilityChange.IsZero() {
+		recordExpiry(ctx, m.availabilityChange)
+	}
+
+	switch {
+	case m.HasUpstream():
+		start := time.Now()
+		tileBytes, err = m.Upstream.fetch(ctx, tile)
+		servertiming.Since(ctx, "upstream", "", start)
+		// already compressed tiles are passed through
+		if err == nil && isGzipped(tileBytes) {
+			return tileBytes, nil
+		}
+	case m.HasMVTProvider():
 		tileBytes, err = m.encodeMVTProviderTile(ctx, tile)
-	} else {
+	default:
 		tileBytes, err = m.encodeMVTTile(ctx, tile)
 	}
 	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 
-	// buffer to store our compressed bytes
-	var gzipBuf bytes.Buffer
-
-	// compress the encoded bytes
-	w := gzip.NewWriter(&gzipBuf)
-	_, err = w.Write(tileBytes)
-	if err != nil {
-		return nil, err
-	}
-
-	// flush and close the writer
-	if err = w.Close(); err != nil {
-		return nil, err
-	}
-
-	// return encoded, gzipped tile
-	return gzipBuf.Bytes(), nil
+	return gzipTile(tileBytes)
 }
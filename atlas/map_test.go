@@ -99,6 +99,41 @@ func TestMapFilterLayersByZoom(t *testing.T) {
 	}
 }
 
+func TestMapZooms(t *testing.T) {
+	type tcase struct {
+		atlasMap atlas.Map
+		min, max uint
+	}
+
+	uintPtr := func(v uint) *uint { return &v }
+	layers := []atlas.Layer{
+		{Name: "layer1", MinZoom: 4, MaxZoom: 9},
+		{Name: "layer2", MinZoom: 6, MaxZoom: 14},
+	}
+
+	tests := map[string]tcase{
+		"layers":              {atlasMap: atlas.Map{Layers: layers}, min: 4, max: 14},
+		"layer without max":   {atlasMap: atlas.Map{Layers: []atlas.Layer{{Name: "layer1", MinZoom: 2}}}, min: 2, max: atlas.MaxZoom},
+		"raster":              {atlasMap: atlas.Map{Layers: layers, Raster: &atlas.Raster{MinZoom: 0, MaxZoom: 18}}, min: 0, max: 18},
+		"cache max zoom":      {atlasMap: atlas.Map{Layers: layers, CacheMaxZoom: uintPtr(12)}, min: 4, max: atlas.MaxZoom},
+		"configured":          {atlasMap: atlas.Map{Layers: layers, MinZoom: uintPtr(0), MaxZoom: uintPtr(12)}, min: 0, max: 12},
+		"configured max":      {atlasMap: atlas.Map{Layers: layers, MaxZoom: uintPtr(16)}, min: 4, max: 16},
+		"no layers":           {atlasMap: atlas.Map{}, min: 0, max: atlas.MaxZoom},
+		"upstream":            {atlasMap: atlas.Map{Upstream: &atlas.Upstream{MinZoom: 2, MaxZoom: 19}}, min: 2, max: 19},
+		"configured upstream": {atlasMap: atlas.Map{Upstream: &atlas.Upstream{MinZoom: 2, MaxZoom: 19}, MaxZoom: uintPtr(17)}, min: 2, max: 17},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			min, max := tc.atlasMap.Zooms()
+			if min != tc.min || max != tc.max {
+				t.Errorf("zooms, expected %v-%v got %v-%v", tc.min, tc.max, min, max)
+			}
+		})
+	}
+}
+
 func TestMapFilterLayersByName(t *testing.T) {
 	testcases := []struct {
 		grid     atlas.Map
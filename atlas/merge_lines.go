@@ -0,0 +1,157 @@
+package atlas
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/mvt"
+)
+
+// isLineFeature reports if the geometry is merged by mergeLines
+func isLineFeature(g geom.Geometry) bool {
+	switch g.(type) {
+	case geom.LineStringer, geom.MultiLineStringer:
+		return true
+	}
+	return false
+}
+
+// mergeLines merges the touching lines of the line features which have the same tags into
+// longer lines. Lines are joined where the ends of exactly two lines meet, so lines meeting at
+// a junction are left apart. A merged feature has the ID of its first feature and the features
+// are returned in the order of their first feature.
+func mergeLines(features []mvt.Feature) []mvt.Feature {
+	var (
+		keys   []string
+		groups = make(map[string][]mvt.Feature)
+	)
+	for _, f := range features {
+		key := tagsKey(f.Tags)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], f)
+	}
+
+	merged := make([]mvt.Feature, 0, len(groups))
+	for _, key := range keys {
+		group := groups[key]
+
+		var lines [][][2]float64
+		for _, f := range group {
+			switch g := f.Geometry.(type) {
+			case geom.LineStringer:
+				lines = append(lines, g.Verticies())
+			case geom.MultiLineStringer:
+				lines = append(lines, g.LineStrings()...)
+			}
+		}
+
+		var geo geom.Geometry
+		lines = joinLines(lines)
+		if len(lines) == 1 {
+			geo = geom.LineString(lines[0])
+		} else {
+			geo = geom.MultiLineString(lines)
+		}
+
+		merged = append(merged, mvt.Feature{
+			ID:       group[0].ID,
+			Tags:     group[0].Tags,
+			Geometry: geo,
+		})
+	}
+	return merged
+}
+
+// joinLines joins the lines whose ends meet where no other line ends, reversing lines as needed
+func joinLines(lines [][][2]float64) [][][2]float64 {
+	// the lines ending at each point
+	ends := make(map[[2]float64][]int)
+	for i, l := range lines {
+		if len(l) < 2 {
+			continue
+		}
+		ends[l[0]] = append(ends[l[0]], i)
+		ends[l[len(l)-1]] = append(ends[l[len(l)-1]], i)
+	}
+
+	used := make([]bool, len(lines))
+	// next returns the unused line other than i ending at pt, if it's the only other line
+	next := func(pt [2]float64, i int) (int, bool) {
+		at := ends[pt]
+		if len(at) != 2 {
+			return 0, false
+		}
+		j := at[0]
+		if j == i {
+			j = at[1]
+		}
+		return j, j != i && !used[j]
+	}
+
+	var joined [][][2]float64
+	for i, l := range lines {
+		if used[i] || len(l) < 2 {
+			continue
+		}
+		used[i] = true
+		line := append([][2]float64{}, l...)
+
+		// extend the end of the line, then its start
+		for last := i; ; {
+			j, ok := next(line[len(line)-1], last)
+			if !ok {
+				break
+			}
+			used[j] = true
+			nl := lines[j]
+			if nl[0] != line[len(line)-1] {
+				nl = reversed(nl)
+			}
+			line = append(line, nl[1:]...)
+			last = j
+		}
+		for first := i; ; {
+			j, ok := next(line[0], first)
+			if !ok {
+				break
+			}
+			used[j] = true
+			nl := lines[j]
+			if nl[len(nl)-1] != line[0] {
+				nl = reversed(nl)
+			}
+			line = append(nl[:len(nl)-1:len(nl)-1], line...)
+			first = j
+		}
+
+		joined = append(joined, line)
+	}
+	return joined
+}
+
+func reversed(pts [][2]float64) [][2]float64 {
+	r := make([][2]float64, len(pts))
+	for i, pt := range pts {
+		r[len(pts)-1-i] = pt
+	}
+	return r
+}
+
+// tagsKey returns a key of the tags which is the same for equal tags
+func tagsKey(tags map[string]interface{}) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%q=%T:%v;", k, tags[k], tags[k])
+	}
+	return sb.String()
+}
@@ -0,0 +1,103 @@
+package atlas
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/mvt"
+)
+
+func TestMergeLines(t *testing.T) {
+	feature := func(id uint64, class string, g geom.Geometry) mvt.Feature {
+		return mvt.Feature{ID: &id, Tags: map[string]interface{}{"class": class}, Geometry: g}
+	}
+
+	type tcase struct {
+		features []mvt.Feature
+		expected []mvt.Feature
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			got := mergeLines(tc.features)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"touching": {
+			features: []mvt.Feature{
+				feature(1, "road", geom.LineString{{0, 0}, {1, 0}}),
+				feature(2, "road", geom.LineString{{1, 0}, {2, 0}}),
+			},
+			expected: []mvt.Feature{
+				feature(1, "road", geom.LineString{{0, 0}, {1, 0}, {2, 0}}),
+			},
+		},
+		"reversed and out of order": {
+			features: []mvt.Feature{
+				feature(1, "road", geom.LineString{{2, 0}, {3, 0}}),
+				feature(2, "road", geom.LineString{{0, 0}, {1, 0}}),
+				feature(3, "road", geom.LineString{{2, 0}, {1, 0}}),
+			},
+			expected: []mvt.Feature{
+				feature(1, "road", geom.LineString{{0, 0}, {1, 0}, {2, 0}, {3, 0}}),
+			},
+		},
+		"different tags": {
+			features: []mvt.Feature{
+				feature(1, "road", geom.LineString{{0, 0}, {1, 0}}),
+				feature(2, "path", geom.LineString{{1, 0}, {2, 0}}),
+			},
+			expected: []mvt.Feature{
+				feature(1, "road", geom.LineString{{0, 0}, {1, 0}}),
+				feature(2, "path", geom.LineString{{1, 0}, {2, 0}}),
+			},
+		},
+		"junction": {
+			features: []mvt.Feature{
+				feature(1, "road", geom.LineString{{0, 0}, {1, 0}}),
+				feature(2, "road", geom.LineString{{1, 0}, {2, 0}}),
+				feature(3, "road", geom.LineString{{1, 0}, {1, 1}}),
+			},
+			expected: []mvt.Feature{
+				feature(1, "road", geom.MultiLineString{{{0, 0}, {1, 0}}, {{1, 0}, {2, 0}}, {{1, 0}, {1, 1}}}),
+			},
+		},
+		"apart and multi line string": {
+			features: []mvt.Feature{
+				feature(1, "road", geom.MultiLineString{{{0, 0}, {1, 0}}, {{5, 5}, {6, 6}}}),
+				feature(2, "road", geom.LineString{{1, 0}, {1, 1}}),
+			},
+			expected: []mvt.Feature{
+				feature(1, "road", geom.MultiLineString{{{0, 0}, {1, 0}, {1, 1}}, {{5, 5}, {6, 6}}}),
+			},
+		},
+		"loop": {
+			features: []mvt.Feature{
+				feature(1, "road", geom.LineString{{0, 0}, {1, 0}, {1, 1}}),
+				feature(2, "road", geom.LineString{{1, 1}, {0, 0}}),
+			},
+			expected: []mvt.Feature{
+				feature(1, "road", geom.LineString{{0, 0}, {1, 0}, {1, 1}, {0, 0}}),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestTagsKey(t *testing.T) {
+	a := tagsKey(map[string]interface{}{"a": 1, "b": "2"})
+	if b := tagsKey(map[string]interface{}{"b": "2", "a": 1}); a != b {
+		t.Errorf("equal tags, expected %q got %q", a, b)
+	}
+	if b := tagsKey(map[string]interface{}{"a": "1", "b": "2"}); a == b {
+		t.Errorf("tags of different types, got the same key %q", a)
+	}
+}
@@ -0,0 +1,151 @@
+package atlas
+
+import (
+	"fmt"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+)
+
+const (
+	// MVTVersion1 is version 1 of the Mapbox Vector Tile spec
+	MVTVersion1 uint = 1
+	// MVTVersion2 is version 2 of the Mapbox Vector Tile spec
+	MVTVersion2 uint = 2
+)
+
+const (
+	// DefaultMVTVersion is the spec version emitted when a map does not configure one
+	DefaultMVTVersion = MVTVersion2
+	// MVTProviderVersion is the spec version emitted by MVT providers (i.e. ST_AsMVT).
+	// MVT provider tiles are passed through as is so maps using them can't select another version.
+	MVTProviderVersion = MVTVersion2
+)
+
+// SupportedMVTVersions are the Mapbox Vector Tile spec versions the encoder can emit
+var SupportedMVTVersions = []uint{MVTVersion1, MVTVersion2}
+
+// ErrMVTVersionUnsupported is returned when a map is configured with an MVT spec version the encoder can't emit
+type ErrMVTVersionUnsupported struct {
+	Version uint
+}
+
+func (e ErrMVTVersionUnsupported) Error() string {
+	return fmt.Sprintf("atlas: unsupported mvt version (%v), supported versions: %v", e.Version, SupportedMVTVersions)
+}
+
+// ErrMVTInvalid is returned when an encoded tile does not conform to the selected MVT spec version
+type ErrMVTInvalid struct {
+	Version uint
+	Layer   string
+	Reason  string
+}
+
+func (e ErrMVTInvalid) Error() string {
+	return fmt.Sprintf("atlas: layer (%v) is not valid mvt version %v: %v", e.Layer, e.Version, e.Reason)
+}
+
+// ValidateMVTVersion returns an error if the version can't be emitted by the encoder
+func ValidateMVTVersion(version uint) error {
+	for _, v := range SupportedMVTVersions {
+		if v == version {
+			return nil
+		}
+	}
+	return ErrMVTVersionUnsupported{Version: version}
+}
+
+// mvtVersion returns the spec version configured for the map, falling back to DefaultMVTVersion
+func (m Map) mvtVersion() uint {
+	if m.MVTVersion == 0 {
+		return DefaultMVTVersion
+	}
+	return m.MVTVersion
+}
+
+// prepareVTile stamps the layers of vt with the spec version and validates
+// the encoded layers against the rules of that version
+func prepareVTile(version uint, vt *vectorTile.Tile) error {
+	if err := ValidateMVTVersion(version); err != nil {
+		return err
+	}
+
+	v := uint32(version)
+	names := make(map[string]struct{}, len(vt.Layers))
+
+	for _, l := range vt.Layers {
+		l.Version = &v
+
+		name := l.GetName()
+		invalid := func(format string, args ...interface{}) error {
+			return ErrMVTInvalid{
+				Version: version,
+				Layer:   name,
+				Reason:  fmt.Sprintf(format, args...),
+			}
+		}
+
+		if name == "" {
+			return invalid("layer name is required")
+		}
+		if l.GetExtent() == 0 {
+			return invalid("layer extent must be greater than 0")
+		}
+
+		// version 2 tiles must not contain layers with the same name
+		if version >= MVTVersion2 {
+			if _, ok := names[name]; ok {
+				return invalid("duplicate layer name")
+			}
+			names[name] = struct{}{}
+		}
+
+		for _, val := range l.Values {
+			if n := valueFieldCount(val); n != 1 {
+				return invalid("value must have exactly one field set, has %v", n)
+			}
+		}
+
+		for _, f := range l.Features {
+			if len(f.Tags)%2 != 0 {
+				return invalid("feature (%v) has an odd number of tags", f.GetId())
+			}
+			for i := 0; i < len(f.Tags); i += 2 {
+				if int(f.Tags[i]) >= len(l.Keys) || int(f.Tags[i+1]) >= len(l.Values) {
+					return invalid("feature (%v) tag index out of range", f.GetId())
+				}
+			}
+
+			if version >= MVTVersion2 {
+				if f.GetType() == vectorTile.Tile_UNKNOWN {
+					return invalid("feature (%v) geometry type is required", f.GetId())
+				}
+				if len(f.Geometry) == 0 {
+					return invalid("feature (%v) geometry is required", f.GetId())
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// valueFieldCount returns the number of fields set on a layer value
+func valueFieldCount(v *vectorTile.Tile_Value) (n int) {
+	if v == nil {
+		return 0
+	}
+	for _, set := range [...]bool{
+		v.StringValue != nil,
+		v.FloatValue != nil,
+		v.DoubleValue != nil,
+		v.IntValue != nil,
+		v.UintValue != nil,
+		v.SintValue != nil,
+		v.BoolValue != nil,
+	} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
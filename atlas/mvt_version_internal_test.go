@@ -0,0 +1,97 @@
+package atlas
+
+import (
+	"testing"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+)
+
+func TestPrepareVTile(t *testing.T) {
+	type tcase struct {
+		version uint
+		layers  []*vectorTile.Tile_Layer
+		err     error
+	}
+
+	str := func(s string) *string { return &s }
+	u32 := func(u uint32) *uint32 { return &u }
+	u64 := func(u uint64) *uint64 { return &u }
+
+	point := vectorTile.Tile_POINT
+	unknown := vectorTile.Tile_UNKNOWN
+
+	feature := func(typ *vectorTile.Tile_GeomType, tags ...uint32) *vectorTile.Tile_Feature {
+		return &vectorTile.Tile_Feature{
+			Id:       u64(1),
+			Type:     typ,
+			Tags:     tags,
+			Geometry: []uint32{9, 50, 34},
+		}
+	}
+
+	layer := func(name string, features ...*vectorTile.Tile_Feature) *vectorTile.Tile_Layer {
+		return &vectorTile.Tile_Layer{
+			Name:     str(name),
+			Extent:   u32(4096),
+			Keys:     []string{"name"},
+			Values:   []*vectorTile.Tile_Value{{StringValue: str("foo")}},
+			Features: features,
+		}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			err := prepareVTile(tc.version, &vectorTile.Tile{Layers: tc.layers})
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("expected err %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected err: %v", err)
+				return
+			}
+
+			for _, l := range tc.layers {
+				if l.GetVersion() != uint32(tc.version) {
+					t.Errorf("expected layer version %v got %v", tc.version, l.GetVersion())
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"v2": {
+			version: MVTVersion2,
+			layers:  []*vectorTile.Tile_Layer{layer("a", feature(&point, 0, 0)), layer("b")},
+		},
+		"v1 duplicate names": {
+			version: MVTVersion1,
+			layers:  []*vectorTile.Tile_Layer{layer("a", feature(&unknown)), layer("a")},
+		},
+		"v2 duplicate names": {
+			version: MVTVersion2,
+			layers:  []*vectorTile.Tile_Layer{layer("a"), layer("a")},
+			err:     ErrMVTInvalid{Version: MVTVersion2, Layer: "a", Reason: "duplicate layer name"},
+		},
+		"v2 unknown geometry type": {
+			version: MVTVersion2,
+			layers:  []*vectorTile.Tile_Layer{layer("a", feature(&unknown))},
+			err:     ErrMVTInvalid{Version: MVTVersion2, Layer: "a", Reason: "feature (1) geometry type is required"},
+		},
+		"tag index out of range": {
+			version: MVTVersion1,
+			layers:  []*vectorTile.Tile_Layer{layer("a", feature(&point, 0, 1))},
+			err:     ErrMVTInvalid{Version: MVTVersion1, Layer: "a", Reason: "feature (1) tag index out of range"},
+		},
+		"unsupported version": {
+			version: 3,
+			err:     ErrMVTVersionUnsupported{Version: 3},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,60 @@
+package atlas
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+type omittedLayersKey struct{}
+
+// omittedLayers tracks the layers left out of a tile
+type omittedLayers struct {
+	sync.Mutex
+	names []string
+}
+
+// WithOmittedLayers returns a context which records the names of the layers
+// left out of the tile when passed to Map.Encode. The names are read back with OmittedLayers.
+func WithOmittedLayers(ctx context.Context) context.Context {
+	return context.WithValue(ctx, omittedLayersKey{}, &omittedLayers{})
+}
+
+// OmittedLayers returns the sorted MVT names of the layers left out of the tile encoded
+// with a context from WithOmittedLayers.
+func OmittedLayers(ctx context.Context) []string {
+	ol, ok := ctx.Value(omittedLayersKey{}).(*omittedLayers)
+	if !ok {
+		return nil
+	}
+
+	ol.Lock()
+	defer ol.Unlock()
+
+	names := append([]string(nil), ol.names...)
+	sort.Strings(names)
+	return names
+}
+
+// omitLayer logs the failed optional layer, records it in ctx and marks the tile as
+// expired so the tile missing the layer is not cached
+func omitLayer(ctx context.Context, mapName string, l Layer, tile *slippy.Tile, err error) {
+	z, x, y := tile.ZXY()
+	log.Warnf("map (%v) optional layer (%v) omitted from tile (z: %v, x: %v, y: %v): %v", mapName, l.MVTName(), z, x, y, err)
+
+	recordOmitted(ctx, l)
+	recordExpiry(ctx, time.Now())
+}
+
+// recordOmitted records the layer left out of the tile in ctx
+func recordOmitted(ctx context.Context, l Layer) {
+	if ol, ok := ctx.Value(omittedLayersKey{}).(*omittedLayers); ok {
+		ol.Lock()
+		ol.names = append(ol.names, l.MVTName())
+		ol.Unlock()
+	}
+}
@@ -0,0 +1,119 @@
+package atlas
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom/slippy"
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+// failingTiler always errors
+type failingTiler struct {
+	test.TileProvider
+}
+
+var errTilerFailed = errors.New("failed")
+
+func (ft *failingTiler) TileFeatures(ctx context.Context, layer string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	return errTilerFailed
+}
+
+func TestEncodeLayerFailures(t *testing.T) {
+	type tcase struct {
+		layer Layer
+		// layers are the expected layers of the tile
+		layers  []string
+		omitted []string
+		err     error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			m := NewWebMercatorMap("test")
+			m.Layers = []Layer{
+				{Name: "fast", ProviderLayerID: "test-layer", Provider: &test.TileProvider{}},
+				tc.layer,
+			}
+
+			ctx := WithOmittedLayers(WithExpiry(context.Background()))
+			// the tile context bounds layers without a timeout
+			ctx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+
+			b, err := m.encodeMVTTile(ctx, slippy.NewTile(2, 3, 1))
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Fatalf("expected err %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			var vt vectorTile.Tile
+			if err := proto.Unmarshal(b, &vt); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			var got []string
+			for _, l := range vt.Layers {
+				got = append(got, l.GetName())
+			}
+			if len(got) != len(tc.layers) {
+				t.Fatalf("expected layers %v got %v", tc.layers, got)
+			}
+			for i := range got {
+				if got[i] != tc.layers[i] {
+					t.Errorf("expected layers %v got %v", tc.layers, got)
+				}
+			}
+
+			omitted := OmittedLayers(ctx)
+			if len(omitted) != len(tc.omitted) {
+				t.Fatalf("expected omitted layers %v got %v", tc.omitted, omitted)
+			}
+			// tiles missing a layer must not be cached
+			if expired := !Expiry(ctx).IsZero(); expired != (len(tc.omitted) > 0) {
+				t.Errorf("expected expired %v got %v", len(tc.omitted) > 0, expired)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"optional slow layer omitted": {
+			layer:   Layer{Name: "slow", ProviderLayerID: "test-layer", Provider: &slowTiler{}, Timeout: 10 * time.Millisecond, Optional: true},
+			layers:  []string{"fast"},
+			omitted: []string{"slow"},
+		},
+		"optional failing layer omitted": {
+			layer:   Layer{Name: "failing", ProviderLayerID: "test-layer", Provider: &failingTiler{}, Optional: true},
+			layers:  []string{"fast"},
+			omitted: []string{"failing"},
+		},
+		"required slow layer skipped": {
+			layer:   Layer{Name: "slow", ProviderLayerID: "test-layer", Provider: &slowTiler{}, Timeout: 10 * time.Millisecond},
+			layers:  []string{"fast"},
+			omitted: []string{"slow"},
+		},
+		"required failing layer": {
+			layer: Layer{Name: "failing", ProviderLayerID: "test-layer", Provider: &failingTiler{}},
+			err:   ErrLayerFailed{Map: "test", Layer: "failing", Err: errTilerFailed},
+		},
+		"required layer": {
+			layer:  Layer{Name: "other", ProviderLayerID: "test-layer", Provider: &test.TileProvider{}},
+			layers: []string{"fast", "other"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,396 @@
+package atlas
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/geom/slippy"
+)
+
+// the commands of the geometry encoding of the MVT spec
+const (
+	mvtMoveTo    uint32 = 1
+	mvtLineTo    uint32 = 2
+	mvtClosePath uint32 = 7
+)
+
+// ErrMalformedGeometry is returned by OverzoomTile for geometries of the parent tile not
+// encoded following the MVT spec
+var ErrMalformedGeometry = errors.New("atlas: malformed mvt geometry")
+
+// OverzoomTile extracts the tile from the encoded tile of its ancestor at a lower zoom:
+// the geometries of the ancestor within the tile, and the map's tile buffer, are scaled up to
+// the tile's zoom and clipped to it. Only the map's layers at the tile's zoom are kept, all
+// layers for upstream maps. The tiles are gzip compressed, as Encode returns them.
+//
+// The geometries are as detailed as the ancestor's, the precision of a tile extracted n zooms
+// above its ancestor is 2^n pixels of its extent.
+func (m Map) OverzoomTile(parentTile []byte, parent, tile *slippy.Tile) ([]byte, error) {
+	pz, px, py := parent.ZXY()
+	z, x, y := tile.ZXY()
+	if pz > z || x>>(z-pz) != px || y>>(z-pz) != py {
+		return nil, fmt.Errorf("atlas: tile (%v/%v/%v) is not within tile (%v/%v/%v)", z, x, y, pz, px, py)
+	}
+	dz := z - pz
+
+	if isGzipped(parentTile) {
+		b, release, err := gunzipTile(parentTile)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		parentTile = b
+	}
+
+	var src vectorTile.Tile
+	if err := proto.Unmarshal(parentTile, &src); err != nil {
+		return nil, fmt.Errorf("atlas: decoding tile (%v/%v/%v): %v", pz, px, py, err)
+	}
+
+	// the layers of the tile's zoom
+	var layers map[string]bool
+	if !m.HasUpstream() {
+		layers = map[string]bool{}
+		for _, l := range m.FilterLayersByZoom(z).Layers {
+			layers[l.MVTName()] = true
+		}
+	}
+
+	var dst vectorTile.Tile
+	for _, sl := range src.Layers {
+		if layers != nil && !layers[sl.GetName()] {
+			continue
+		}
+
+		o := overzoom{
+			scale:   math.Exp2(float64(dz)),
+			offsetX: float64(x-px<<dz) * float64(sl.GetExtent()),
+			offsetY: float64(y-py<<dz) * float64(sl.GetExtent()),
+		}
+		// the buffer is relative to the map's tile extent
+		buffer := float64(sl.GetExtent())
+		if m.TileExtent != 0 {
+			buffer = float64(m.TileBuffer) * float64(sl.GetExtent()) / float64(m.TileExtent)
+		}
+		o.min, o.max = -buffer, float64(sl.GetExtent())+buffer
+
+		dl := &vectorTile.Tile_Layer{
+			Version: sl.Version,
+			Name:    sl.Name,
+			Keys:    sl.Keys,
+			Values:  sl.Values,
+			Extent:  sl.Extent,
+		}
+		for _, f := range sl.Features {
+			geometry, err := o.geometry(f.GetType(), f.Geometry)
+			if err != nil {
+				return nil, fmt.Errorf("atlas: feature (%v) of layer (%v) of tile (%v/%v/%v): %w", f.GetId(), sl.GetName(), pz, px, py, err)
+			}
+			if len(geometry) == 0 {
+				continue
+			}
+			dl.Features = append(dl.Features, &vectorTile.Tile_Feature{
+				Id:       f.Id,
+				Tags:     f.Tags,
+				Type:     f.Type,
+				Geometry: geometry,
+			})
+		}
+		if len(dl.Features) == 0 {
+			continue
+		}
+		dst.Layers = append(dst.Layers, dl)
+	}
+
+	tileBytes, err := marshalTile(&dst)
+	if err != nil {
+		return nil, err
+	}
+	return gzipTile(tileBytes)
+}
+
+type ozPoint [2]float64
+
+// overzoom scales geometries of a tile up to a tile at a higher zoom and clips them
+type overzoom struct {
+	scale            float64
+	offsetX, offsetY float64
+	// the clipping box, in the coordinates of the tile
+	min, max float64
+}
+
+// geometry returns the encoded geometry scaled up and clipped, nil when the geometry is outside the tile
+func (o overzoom) geometry(typ vectorTile.Tile_GeomType, geometry []uint32) ([]uint32, error) {
+	parts, err := decodeMVTGeometry(geometry)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		for i, p := range part {
+			part[i] = ozPoint{p[0]*o.scale - o.offsetX, p[1]*o.scale - o.offsetY}
+		}
+	}
+
+	var clipped [][]ozPoint
+	switch typ {
+	case vectorTile.Tile_POINT:
+		var points []ozPoint
+		for _, part := range parts {
+			for _, p := range part {
+				if o.contains(p) {
+					points = append(points, p)
+				}
+			}
+		}
+		if len(points) > 0 {
+			clipped = [][]ozPoint{points}
+		}
+	case vectorTile.Tile_LINESTRING:
+		for _, part := range parts {
+			for _, line := range o.clipLine(part) {
+				if line = roundPoints(line); len(line) > 1 {
+					clipped = append(clipped, line)
+				}
+			}
+		}
+	case vectorTile.Tile_POLYGON:
+		// interior rings follow their exterior ring, and are dropped with it
+		exterior := false
+		for _, ring := range parts {
+			isExterior := ringArea(ring) > 0
+			if !isExterior && !exterior {
+				continue
+			}
+			ring = roundPoints(o.clipRing(ring))
+			if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+				ring = ring[:len(ring)-1]
+			}
+			keep := len(ring) > 2 && ringArea(ring) != 0
+			if isExterior {
+				exterior = keep
+			}
+			if keep {
+				clipped = append(clipped, ring)
+			}
+		}
+	default:
+		return nil, nil
+	}
+
+	return encodeMVTGeometry(typ, clipped), nil
+}
+
+func (o overzoom) contains(p ozPoint) bool {
+	return p[0] >= o.min && p[0] <= o.max && p[1] >= o.min && p[1] <= o.max
+}
+
+// clipLine clips the line to the clipping box, which may split it into several lines
+func (o overzoom) clipLine(line []ozPoint) (lines [][]ozPoint) {
+	var current []ozPoint
+	for i := 0; i+1 < len(line); i++ {
+		a, b, ok := o.clipSegment(line[i], line[i+1])
+		if !ok {
+			if len(current) > 1 {
+				lines = append(lines, current)
+			}
+			current = nil
+			continue
+		}
+		if len(current) == 0 || current[len(current)-1] != a {
+			if len(current) > 1 {
+				lines = append(lines, current)
+			}
+			current = []ozPoint{a}
+		}
+		current = append(current, b)
+	}
+	if len(current) > 1 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// clipSegment clips the segment to the clipping box (Liang–Barsky)
+func (o overzoom) clipSegment(a, b ozPoint) (ozPoint, ozPoint, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	for _, edge := range [4][2]float64{
+		{-dx, a[0] - o.min},
+		{dx, o.max - a[0]},
+		{-dy, a[1] - o.min},
+		{dy, o.max - a[1]},
+	} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return a, b, false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return a, b, false
+			}
+			if t > t0 {
+				t0 = t
+			}
+		} else {
+			if t < t0 {
+				return a, b, false
+			}
+			if t < t1 {
+				t1 = t
+			}
+		}
+	}
+
+	ca, cb := a, b
+	if t0 > 0 {
+		ca = ozPoint{a[0] + t0*dx, a[1] + t0*dy}
+	}
+	if t1 < 1 {
+		cb = ozPoint{a[0] + t1*dx, a[1] + t1*dy}
+	}
+	return ca, cb, true
+}
+
+// clipRing clips the ring to the clipping box (Sutherland–Hodgman), keeping its orientation
+func (o overzoom) clipRing(ring []ozPoint) []ozPoint {
+	edges := []struct {
+		inside    func(p ozPoint) bool
+		intersect func(a, b ozPoint) ozPoint
+	}{
+		{func(p ozPoint) bool { return p[0] >= o.min }, func(a, b ozPoint) ozPoint { return intersectX(a, b, o.min) }},
+		{func(p ozPoint) bool { return p[0] <= o.max }, func(a, b ozPoint) ozPoint { return intersectX(a, b, o.max) }},
+		{func(p ozPoint) bool { return p[1] >= o.min }, func(a, b ozPoint) ozPoint { return intersectY(a, b, o.min) }},
+		{func(p ozPoint) bool { return p[1] <= o.max }, func(a, b ozPoint) ozPoint { return intersectY(a, b, o.max) }},
+	}
+
+	for _, e := range edges {
+		if len(ring) == 0 {
+			break
+		}
+		var out []ozPoint
+		prev := ring[len(ring)-1]
+		for _, p := range ring {
+			switch {
+			case e.inside(p):
+				if !e.inside(prev) {
+					out = append(out, e.intersect(prev, p))
+				}
+				out = append(out, p)
+			case e.inside(prev):
+				out = append(out, e.intersect(prev, p))
+			}
+			prev = p
+		}
+		ring = out
+	}
+	return ring
+}
+
+func intersectX(a, b ozPoint, x float64) ozPoint {
+	return ozPoint{x, a[1] + (b[1]-a[1])*(x-a[0])/(b[0]-a[0])}
+}
+
+func intersectY(a, b ozPoint, y float64) ozPoint {
+	return ozPoint{a[0] + (b[0]-a[0])*(y-a[1])/(b[1]-a[1]), y}
+}
+
+// roundPoints rounds the points to the integer coordinates of the tile, dropping repeated points
+func roundPoints(points []ozPoint) []ozPoint {
+	out := points[:0]
+	for _, p := range points {
+		p = ozPoint{math.Round(p[0]), math.Round(p[1])}
+		if len(out) > 0 && out[len(out)-1] == p {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// ringArea returns twice the signed area of the ring, which is positive for the exterior
+// rings of the MVT spec
+func ringArea(ring []ozPoint) float64 {
+	var area float64
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+	return area
+}
+
+// decodeMVTGeometry decodes the geometry commands into their parts: the points, lines or
+// rings of the geometry, without the closing point of rings
+func decodeMVTGeometry(geometry []uint32) (parts [][]ozPoint, err error) {
+	var x, y int64
+	for i := 0; i < len(geometry); {
+		id, count := geometry[i]&0x7, int(geometry[i]>>3)
+		i++
+
+		switch id {
+		case mvtMoveTo, mvtLineTo:
+			if i+2*count > len(geometry) {
+				return nil, ErrMalformedGeometry
+			}
+			for j := 0; j < count; j++ {
+				x += decodeZigZag(geometry[i])
+				y += decodeZigZag(geometry[i+1])
+				i += 2
+
+				if id == mvtMoveTo {
+					parts = append(parts, nil)
+				}
+				if len(parts) == 0 {
+					return nil, ErrMalformedGeometry
+				}
+				parts[len(parts)-1] = append(parts[len(parts)-1], ozPoint{float64(x), float64(y)})
+			}
+		case mvtClosePath:
+			// rings are closed when encoded
+		default:
+			return nil, ErrMalformedGeometry
+		}
+	}
+	return parts, nil
+}
+
+// encodeMVTGeometry encodes the parts of the geometry, which have integer coordinates
+func encodeMVTGeometry(typ vectorTile.Tile_GeomType, parts [][]ozPoint) (geometry []uint32) {
+	var x, y int64
+	moveTo := func(cmd uint32, points []ozPoint) {
+		geometry = append(geometry, cmd&0x7|uint32(len(points))<<3)
+		for _, p := range points {
+			px, py := int64(p[0]), int64(p[1])
+			geometry = append(geometry, encodeZigZag(px-x), encodeZigZag(py-y))
+			x, y = px, py
+		}
+	}
+
+	for _, part := range parts {
+		if typ == vectorTile.Tile_POINT {
+			moveTo(mvtMoveTo, part)
+			continue
+		}
+		moveTo(mvtMoveTo, part[:1])
+		moveTo(mvtLineTo, part[1:])
+		if typ == vectorTile.Tile_POLYGON {
+			geometry = append(geometry, mvtClosePath|1<<3)
+		}
+	}
+	return geometry
+}
+
+func decodeZigZag(v uint32) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func encodeZigZag(v int64) uint32 {
+	return uint32((v << 1) ^ (v >> 63))
+}
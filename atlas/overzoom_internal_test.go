@@ -0,0 +1,152 @@
+package atlas
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/geom/slippy"
+)
+
+func TestOverzoomGeometry(t *testing.T) {
+	type tcase struct {
+		typ      vectorTile.Tile_GeomType
+		parts    [][]ozPoint
+		expected [][]ozPoint
+	}
+
+	// tile 1/1/0 of tile 0/0/0, without a buffer
+	o := overzoom{scale: 2, offsetX: 4096, offsetY: 0, min: 0, max: 4096}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			geometry, err := o.geometry(tc.typ, encodeMVTGeometry(tc.typ, tc.parts))
+			if err != nil {
+				t.Fatalf("error, expected nil got %v", err)
+			}
+			parts, err := decodeMVTGeometry(geometry)
+			if err != nil {
+				t.Fatalf("error decoding, expected nil got %v", err)
+			}
+			if !reflect.DeepEqual(parts, tc.expected) {
+				t.Errorf("parts, expected %v got %v", tc.expected, parts)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"points": {
+			typ:      vectorTile.Tile_POINT,
+			parts:    [][]ozPoint{{{3072, 1024}, {1000, 1000}}},
+			expected: [][]ozPoint{{{2048, 2048}}},
+		},
+		"line": {
+			typ:      vectorTile.Tile_LINESTRING,
+			parts:    [][]ozPoint{{{1024, 1024}, {3072, 1024}}},
+			expected: [][]ozPoint{{{0, 2048}, {2048, 2048}}},
+		},
+		"line split": {
+			typ:   vectorTile.Tile_LINESTRING,
+			parts: [][]ozPoint{{{2560, 512}, {2560, 3072}, {3584, 3072}, {3584, 512}}},
+			expected: [][]ozPoint{
+				{{1024, 1024}, {1024, 4096}},
+				{{3072, 4096}, {3072, 1024}},
+			},
+		},
+		"polygon": {
+			typ:      vectorTile.Tile_POLYGON,
+			parts:    [][]ozPoint{{{1024, 0}, {3072, 0}, {3072, 2048}, {1024, 2048}}},
+			expected: [][]ozPoint{{{0, 0}, {2048, 0}, {2048, 4096}, {0, 4096}}},
+		},
+		"polygon with hole outside": {
+			typ: vectorTile.Tile_POLYGON,
+			parts: [][]ozPoint{
+				{{1024, 0}, {3072, 0}, {3072, 2048}, {1024, 2048}},
+				{{1100, 100}, {1100, 200}, {1200, 200}, {1200, 100}},
+			},
+			expected: [][]ozPoint{{{0, 0}, {2048, 0}, {2048, 4096}, {0, 4096}}},
+		},
+		"polygon outside": {
+			typ: vectorTile.Tile_POLYGON,
+			parts: [][]ozPoint{
+				{{0, 2048}, {1024, 2048}, {1024, 4096}, {0, 4096}},
+				{{100, 2100}, {100, 2200}, {200, 2200}, {200, 2100}},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestOverzoomTile(t *testing.T) {
+	m := NewWebMercatorMap("test")
+	m.TileBuffer = 0
+	m.Layers = []Layer{{Name: "roads", MaxZoom: 20}, {Name: "buildings", MinZoom: 1, MaxZoom: 1}}
+
+	line := vectorTile.Tile_LINESTRING
+	src := vectorTile.Tile{Layers: []*vectorTile.Tile_Layer{
+		{
+			Version: proto.Uint32(2),
+			Name:    proto.String("roads"),
+			Extent:  proto.Uint32(4096),
+			Features: []*vectorTile.Tile_Feature{
+				{Id: proto.Uint64(1), Type: &line, Geometry: encodeMVTGeometry(line, [][]ozPoint{{{1024, 1024}, {3072, 1024}}})},
+				// outside of the tile
+				{Id: proto.Uint64(2), Type: &line, Geometry: encodeMVTGeometry(line, [][]ozPoint{{{0, 3072}, {1024, 3072}}})},
+			},
+		},
+		{
+			Version:  proto.Uint32(2),
+			Name:     proto.String("buildings"),
+			Extent:   proto.Uint32(4096),
+			Features: []*vectorTile.Tile_Feature{{Id: proto.Uint64(3), Type: &line, Geometry: encodeMVTGeometry(line, [][]ozPoint{{{2048, 0}, {2048, 512}}})}},
+		},
+	}}
+	b, err := proto.Marshal(&src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentTile, err := gzipTile(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the buildings layer is not at zoom 2
+	tileBytes, err := m.OverzoomTile(parentTile, slippy.NewTile(0, 0, 0), slippy.NewTile(2, 2, 0))
+	if err != nil {
+		t.Fatalf("error, expected nil got %v", err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(tileBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tileBytes, err = ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+
+	var dst vectorTile.Tile
+	if err := proto.Unmarshal(tileBytes, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if len(dst.Layers) != 1 || dst.Layers[0].GetName() != "roads" {
+		t.Fatalf("layers, expected only roads got %v", dst.Layers)
+	}
+	if fs := dst.Layers[0].Features; len(fs) != 1 || fs[0].GetId() != 1 {
+		t.Fatalf("features, expected only feature 1 got %v", fs)
+	}
+	parts, _ := decodeMVTGeometry(dst.Layers[0].Features[0].Geometry)
+	if expected := [][]ozPoint{{{0, 4096}, {4096, 4096}}}; !reflect.DeepEqual(parts, expected) {
+		t.Errorf("geometry, expected %v got %v", expected, parts)
+	}
+
+	if _, err := m.OverzoomTile(parentTile, slippy.NewTile(1, 0, 0), slippy.NewTile(2, 2, 0)); err == nil {
+		t.Errorf("error for a tile outside of the parent, expected an error got nil")
+	}
+}
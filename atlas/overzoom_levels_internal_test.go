@@ -0,0 +1,113 @@
+package atlas
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+// diagonalTiler returns a line across each tile from its bottom left to its top right corner,
+// and records the tiles requested
+type diagonalTiler struct {
+	test.TileProvider
+	sync.Mutex
+	tiles [][3]uint
+}
+
+func (dt *diagonalTiler) TileFeatures(ctx context.Context, layer string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	z, x, y := t.ZXY()
+	dt.Lock()
+	dt.tiles = append(dt.tiles, [3]uint{z, x, y})
+	dt.Unlock()
+
+	ext, srid := t.Extent()
+	return fn(&provider.Feature{
+		ID:       1,
+		Geometry: geom.LineString{{ext.MinX(), ext.MinY()}, {ext.MaxX(), ext.MaxY()}},
+		SRID:     srid,
+		Tags:     map[string]interface{}{},
+	})
+}
+
+func TestLayerServedMaxZoom(t *testing.T) {
+	tests := map[string]struct {
+		layer    Layer
+		expected uint
+	}{
+		"no max zoom":      {layer: Layer{OverzoomLevels: 2}, expected: 0},
+		"no overzoom":      {layer: Layer{MaxZoom: 10}, expected: 10},
+		"overzoom":         {layer: Layer{MaxZoom: 10, OverzoomLevels: 2}, expected: 12},
+		"above atlas zoom": {layer: Layer{MaxZoom: 20, OverzoomLevels: 4}, expected: MaxZoom},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.layer.ServedMaxZoom(); got != tc.expected {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestEncodeOverzoomLevels(t *testing.T) {
+	tiler := &diagonalTiler{}
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{{Name: "roads", ProviderLayerID: "test-layer", Provider: tiler, MaxZoom: 10, OverzoomLevels: 2}}
+
+	if min, max := m.Zooms(); min != 0 || max != 12 {
+		t.Errorf("zooms, expected 0-12 got %v-%v", min, max)
+	}
+	if layers := m.FilterLayersByZoom(13).Layers; len(layers) != 0 {
+		t.Errorf("layers at zoom 13, expected none got %v", len(layers))
+	}
+
+	// the tile is the second column and third row of the tiles of 10/500/300 at zoom 12,
+	// the diagonal of its ancestor is the tile's diagonal
+	tileBytes, err := m.Encode(context.Background(), slippy.NewTile(12, 2001, 1202))
+	if err != nil {
+		t.Fatalf("error, expected nil got %v", err)
+	}
+	if len(tiler.tiles) != 1 || tiler.tiles[0] != [3]uint{10, 500, 300} {
+		t.Errorf("provider tiles, expected [10 500 300] got %v", tiler.tiles)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(tileBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tileBytes, err = ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	var dst vectorTile.Tile
+	if err := proto.Unmarshal(tileBytes, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if len(dst.Layers) != 1 || len(dst.Layers[0].Features) != 1 {
+		t.Fatalf("layers, expected roads with 1 feature got %v", dst.Layers)
+	}
+
+	// the line is clipped to the tile and its buffer
+	parts, err := decodeMVTGeometry(dst.Layers[0].Features[0].Geometry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := float64(m.TileBuffer)
+	for _, p := range parts[0] {
+		if p[0] < -buffer || p[0] > 4096+buffer || p[1] < -buffer || p[1] > 4096+buffer {
+			t.Errorf("point %v outside of the tile's buffer", p)
+		}
+	}
+	if first, last := parts[0][0], parts[0][len(parts[0])-1]; first[0] > 0 || first[1] < 4096 || last[0] < 4096 || last[1] > 0 {
+		t.Errorf("line, expected across the tile from bottom left to top right got %v", parts[0])
+	}
+}
@@ -0,0 +1,129 @@
+package atlas
+
+import (
+	"bytes"
+	"compress/gzip"
+	"sync"
+
+	"github.com/go-spatial/geom/encoding/mvt"
+	"github.com/golang/protobuf/proto"
+)
+
+// maxPooledBufferSize is the capacity above which buffers aren't returned to their pool, so a
+// few large tiles don't keep their memory for the life of the process
+const maxPooledBufferSize = 4 << 20
+
+// the pools of the buffers and writers used to encode tiles, which are reused across tiles to
+// cut the allocations of high-throughput encoding, i.e. seeding
+var (
+	gzipWriterPool = sync.Pool{
+		New: func() interface{} { return gzip.NewWriter(nil) },
+	}
+	gzipReaderPool = sync.Pool{
+		New: func() interface{} { return new(gzip.Reader) },
+	}
+	bufferPool = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
+	protoBufferPool = sync.Pool{
+		New: func() interface{} { return proto.NewBuffer(nil) },
+	}
+	// featuresPool holds the slices the features of a layer are collected in, before they are
+	// added to the layer at once: mvt.Layer.AddFeatures copies the layer's features on each call
+	featuresPool = sync.Pool{
+		New: func() interface{} {
+			fs := make([]mvt.Feature, 0, 256)
+			return &fs
+		},
+	}
+)
+
+// gzipTile compresses the encoded tile
+func gzipTile(tileBytes []byte) ([]byte, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	// compress the encoded bytes
+	w := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(w)
+	w.Reset(buf)
+
+	if _, err := w.Write(tileBytes); err != nil {
+		return nil, err
+	}
+	// flush and close the writer
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	// the buffer is reused, the compressed tile is copied out of it
+	gzipped := make([]byte, buf.Len())
+	copy(gzipped, buf.Bytes())
+	return gzipped, nil
+}
+
+// gunzipTile decompresses the gzip compressed tile into a pooled buffer. The returned bytes are
+// only valid until release is called, they are meant to be decoded, i.e. by proto.Unmarshal
+// which copies the values it decodes.
+func gunzipTile(tile []byte) (b []byte, release func(), err error) {
+	r := gzipReaderPool.Get().(*gzip.Reader)
+	defer gzipReaderPool.Put(r)
+	if err := r.Reset(bytes.NewReader(tile)); err != nil {
+		return nil, nil, err
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	release = func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}
+	if _, err := buf.ReadFrom(r); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return buf.Bytes(), release, nil
+}
+
+// marshalTile encodes the message, i.e. a vector tile, with a pooled protobuf buffer
+func marshalTile(pb proto.Message) ([]byte, error) {
+	buf := protoBufferPool.Get().(*proto.Buffer)
+	buf.Reset()
+	defer func() {
+		if cap(buf.Bytes()) <= maxPooledBufferSize {
+			protoBufferPool.Put(buf)
+		}
+	}()
+
+	if err := buf.Marshal(pb); err != nil {
+		return nil, err
+	}
+	// the buffer is reused, the encoded tile is copied out of it
+	b := make([]byte, len(buf.Bytes()))
+	copy(b, buf.Bytes())
+	return b, nil
+}
+
+// getFeatures returns an empty slice of features from the pool
+func getFeatures() *[]mvt.Feature {
+	return featuresPool.Get().(*[]mvt.Feature)
+}
+
+// putFeatures returns the slice of features to the pool, once the features are added to their layer
+func putFeatures(fs *[]mvt.Feature) {
+	if cap(*fs) > maxPooledBufferSize/64 {
+		return
+	}
+	// release the features' tags and geometries
+	for i := range *fs {
+		(*fs)[i] = mvt.Feature{}
+	}
+	*fs = (*fs)[:0]
+	featuresPool.Put(fs)
+}
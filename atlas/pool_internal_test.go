@@ -0,0 +1,101 @@
+package atlas
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/mvt"
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola"
+)
+
+func TestGzipTile(t *testing.T) {
+	for _, tile := range [][]byte{{}, []byte("tile"), bytes.Repeat([]byte("a tile of many bytes"), 1000)} {
+		gzipped, err := gzipTile(tile)
+		if err != nil {
+			t.Fatalf("gzip, unexpected error: %v", err)
+		}
+		if !isGzipped(gzipped) {
+			t.Fatalf("expected gzipped bytes got %v", gzipped)
+		}
+
+		// the tile is copied out of the pooled buffer, the next tile doesn't overwrite it
+		if _, err := gzipTile([]byte("another tile")); err != nil {
+			t.Fatalf("gzip, unexpected error: %v", err)
+		}
+
+		b, release, err := gunzipTile(gzipped)
+		if err != nil {
+			t.Fatalf("gunzip, unexpected error: %v", err)
+		}
+		if !bytes.Equal(b, tile) {
+			t.Errorf("expected %q got %q", tile, b)
+		}
+		release()
+	}
+
+	if _, _, err := gunzipTile([]byte("not gzipped")); err == nil {
+		t.Errorf("gunzip of plain bytes, expected an error")
+	}
+}
+
+func TestMarshalTile(t *testing.T) {
+	vt := &vectorTile.Tile{Layers: []*vectorTile.Tile_Layer{{
+		Version: proto.Uint32(2),
+		Name:    proto.String("pois"),
+		Keys:    []string{"name"},
+		Values:  []*vectorTile.Tile_Value{{StringValue: proto.String("cafe")}},
+	}}}
+
+	expected, err := proto.Marshal(vt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := marshalTile(vt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// a second tile reuses the buffer of the first
+	if _, err := marshalTile(&vectorTile.Tile{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("expected %v got %v", expected, got)
+	}
+}
+
+func TestPutFeatures(t *testing.T) {
+	fs := getFeatures()
+	id := uint64(1)
+	*fs = append(*fs, mvt.Feature{ID: &id, Geometry: geom.Point{1, 1}})
+	backing := (*fs)[:1]
+	putFeatures(fs)
+
+	if len(*fs) != 0 {
+		t.Errorf("length, expected 0 got %v", len(*fs))
+	}
+	if backing[0].ID != nil || backing[0].Geometry != nil {
+		t.Errorf("pooled features, expected released got %v", backing[0])
+	}
+}
+
+func BenchmarkEncodeMVTTile(b *testing.B) {
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{
+		{Name: "places", ProviderLayerID: "test-layer", Provider: &pointTiler{point: geom.Point{13.4, 52.5}, srid: tegola.WGS84}},
+		{Name: "roads", ProviderLayerID: "test-layer", Provider: &diagonalTiler{}},
+	}
+	tile := slippy.NewTile(12, 2200, 1343)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.Encode(context.Background(), tile); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package atlas
 
-// The point of this file is to load and register the default provider backends
+// The point of this file is to load and register the default provider backends and decorators
 import (
 	_ "github.com/go-spatial/tegola/provider/debug"
+	_ "github.com/go-spatial/tegola/provider/decorators"
 )
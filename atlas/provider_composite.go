@@ -0,0 +1,12 @@
+// +build !noCompositeProvider
+
+package atlas
+
+// The point of this file is to load and register the composite provider.
+// the composite provider can be excluded during the build with the `noCompositeProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noCompositeProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/composite"
+)
@@ -0,0 +1,12 @@
+// +build !noContourProvider
+
+package atlas
+
+// The point of this file is to load and register the contour provider.
+// the contour provider can be excluded during the build with the `noContourProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noContourProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/contour"
+)
@@ -0,0 +1,12 @@
+// +build !noGeoRSSProvider
+
+package atlas
+
+// The point of this file is to load and register the georss provider.
+// the georss provider can be excluded during the build with the `noGeoRSSProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noGeoRSSProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/georss"
+)
@@ -0,0 +1,12 @@
+// +build !noGPXProvider
+
+package atlas
+
+// The point of this file is to load and register the gpx provider.
+// the gpx provider can be excluded during the build with the `noGPXProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noGPXProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/gpx"
+)
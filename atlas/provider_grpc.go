@@ -0,0 +1,12 @@
+// +build !noGRPCProvider
+
+package atlas
+
+// The point of this file is to load and register the grpc provider.
+// the grpc provider can be excluded during the build with the `noGRPCProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noGRPCProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/grpc"
+)
@@ -0,0 +1,12 @@
+// +build !noGTFSRTProvider
+
+package atlas
+
+// The point of this file is to load and register the gtfsrt provider.
+// the gtfsrt provider can be excluded during the build with the `noGTFSRTProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noGTFSRTProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/gtfsrt"
+)
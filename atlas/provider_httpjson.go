@@ -0,0 +1,12 @@
+// +build !noHTTPJSONProvider
+
+package atlas
+
+// The point of this file is to load and register the httpjson provider.
+// the httpjson provider can be excluded during the build with the `noHTTPJSONProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noHTTPJSONProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/httpjson"
+)
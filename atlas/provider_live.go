@@ -0,0 +1,12 @@
+// +build !noLiveProvider
+
+package atlas
+
+// The point of this file is to load and register the live provider.
+// the live provider can be excluded during the build with the `noLiveProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noLiveProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/live"
+)
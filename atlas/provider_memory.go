@@ -0,0 +1,12 @@
+// +build !noMemoryProvider
+
+package atlas
+
+// The point of this file is to load and register the memory provider.
+// the memory provider can be excluded during the build with the `noMemoryProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noMemoryProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/memory"
+)
@@ -0,0 +1,12 @@
+// +build !noOGCAPIProvider
+
+package atlas
+
+// The point of this file is to load and register the OGC API - Features / WFS provider.
+// the ogcapi provider can be excluded during the build with the `noOGCAPIProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noOGCAPIProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/ogcapi"
+)
@@ -0,0 +1,12 @@
+// +build gdal
+
+package atlas
+
+// The point of this file is to load and register the GDAL/OGR provider.
+// the OGR provider requires cgo and the GDAL development files, so it's only included
+// with the `gdal` build flag. for example from the cmd/tegola directory:
+//
+// go build -tags 'gdal'
+import (
+	_ "github.com/go-spatial/tegola/provider/ogr"
+)
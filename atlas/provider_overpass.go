@@ -0,0 +1,12 @@
+// +build !noOverpassProvider
+
+package atlas
+
+// The point of this file is to load and register the overpass provider.
+// the overpass provider can be excluded during the build with the `noOverpassProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noOverpassProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/overpass"
+)
@@ -0,0 +1,12 @@
+// +build !noRedisProvider
+
+package atlas
+
+// The point of this file is to load and register the redis provider.
+// the redis provider can be excluded during the build with the `noRedisProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noRedisProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/redis"
+)
@@ -0,0 +1,12 @@
+// +build !noRemoteFileProvider
+
+package atlas
+
+// The point of this file is to load and register the remote file (PMTiles) provider.
+// the remotefile provider can be excluded during the build with the `noRemoteFileProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noRemoteFileProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/remotefile"
+)
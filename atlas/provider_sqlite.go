@@ -0,0 +1,12 @@
+// +build !noSqliteProvider
+
+package atlas
+
+// The point of this file is to load and register the sqlite provider.
+// the sqlite provider can be excluded during the build with the `noSqliteProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noSqliteProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/sqlite"
+)
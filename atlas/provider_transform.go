@@ -0,0 +1,12 @@
+// +build !noTransformProvider
+
+package atlas
+
+// The point of this file is to load and register the transform provider.
+// the transform provider can be excluded during the build with the `noTransformProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noTransformProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/transform"
+)
@@ -0,0 +1,12 @@
+// +build !noTrinoProvider
+
+package atlas
+
+// The point of this file is to load and register the trino provider.
+// the trino provider can be excluded during the build with the `noTrinoProvider` build flag
+// for example from the cmd/tegola directory:
+//
+// go build -tags 'noTrinoProvider'
+import (
+	_ "github.com/go-spatial/tegola/provider/trino"
+)
@@ -0,0 +1,268 @@
+package atlas
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/internal/tracing"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/debug"
+)
+
+// QueryFeature is a feature found near the point of a query
+type QueryFeature struct {
+	// Layer is the name of the map layer of the feature
+	Layer string
+	ID    uint64
+	// Geometry is the geometry of the feature in WGS84
+	Geometry geom.Geometry
+	Tags     map[string]interface{}
+	// Distance is the distance of the feature from the point, in web mercator meters
+	Distance float64
+}
+
+// queryTile is the extent of a query, in web mercator
+type queryTile struct {
+	z, x, y uint
+	extent  *geom.Extent
+}
+
+func (t queryTile) ZXY() (uint, uint, uint)                { return t.z, t.x, t.y }
+func (t queryTile) Extent() (*geom.Extent, uint64)         { return t.extent, tegola.WebMercator }
+func (t queryTile) BufferedExtent() (*geom.Extent, uint64) { return t.extent, tegola.WebMercator }
+
+// queryExtent returns the extent, in web mercator, within radius pixels (of 256 pixel tiles at
+// the zoom) of the point (lng/lat), and the point in web mercator
+func queryExtent(lng, lat float64, zoom uint, radius float64) (*geom.Extent, geom.Point, error) {
+	pt, err := basic.ToWebMercator(tegola.WGS84, geom.Point{lng, lat})
+	if err != nil {
+		return nil, geom.Point{}, err
+	}
+	center := pt.(geom.Point)
+
+	// the distance of the radius in web mercator meters at the zoom
+	dist := radius * slippy.WebMercatorMax * 2 / math.Exp2(float64(zoom)) / 256
+
+	return geom.NewExtent(
+		[2]float64{center[0] - dist, center[1] - dist},
+		[2]float64{center[0] + dist, center[1] + dist},
+	), center, nil
+}
+
+// QueryExtent returns the extent, in WGS84, queried by QueryFeatures for the point, zoom and radius
+func QueryExtent(lng, lat float64, zoom uint, radius float64) (*geom.Extent, error) {
+	ext, _, err := queryExtent(lng, lat, zoom, radius)
+	if err != nil {
+		return nil, err
+	}
+	g, err := basic.FromWebMercator(tegola.WGS84, geom.MultiPoint{{ext.MinX(), ext.MinY()}, {ext.MaxX(), ext.MaxY()}})
+	if err != nil {
+		return nil, err
+	}
+	pts := g.(geom.MultiPoint)
+	return geom.NewExtent(pts[0], pts[1]), nil
+}
+
+// QueryFeatures returns the features of the map's layers at the zoom within radius pixels
+// (of 256 pixel tiles at the zoom) of the point (lng/lat), nearest first. The features of
+// each layer are fetched from its provider concurrently, as when a tile is encoded. The
+// layers of MVT providers, which only encode tiles, are not queried.
+func (m Map) QueryFeatures(ctx context.Context, lng, lat float64, zoom uint, radius float64) ([]QueryFeature, error) {
+	if m.HasUpstream() {
+		return nil, fmt.Errorf("atlas: map (%v) is an upstream map and can't be queried", m.Name)
+	}
+
+	ext, center, err := queryExtent(lng, lat, zoom, radius)
+	if err != nil {
+		return nil, err
+	}
+	dist := ext.MaxX() - center[0]
+
+	tile := slippy.NewTileLatLon(zoom, lat, lng)
+	qt := queryTile{extent: ext}
+	qt.z, qt.x, qt.y = tile.ZXY()
+
+	var layers []Layer
+	for _, l := range m.Layers {
+		if l.Provider == nil {
+			continue
+		}
+		if _, ok := l.Provider.(*debug.Provider); ok {
+			continue
+		}
+		layers = append(layers, l)
+	}
+
+	var (
+		mu       sync.Mutex
+		features []QueryFeature
+		errs     = make([]error, len(layers))
+		hooks    = registeredHooks()
+	)
+	m.forEachLayer(ctx, layers, func(i int, l Layer) {
+		now := time.Now()
+		layerCtx, cancel := l.layerContext(ctx)
+		defer cancel()
+
+		layerCtx, span := tracing.Start(layerCtx, "provider.tile_features")
+		defer span.Finish()
+		span.SetAttribute("tegola.layer", l.MVTName())
+		span.SetAttribute("tegola.provider_layer", l.ProviderLayerID)
+
+		err := l.Provider.TileFeatures(layerCtx, l.ProviderLayerID, qt, func(f *provider.Feature) error {
+			if l.expired(ctx, f.Tags, now) {
+				return nil
+			}
+
+			// shape the tags as they are encoded in tiles
+			l.transformTags(m.Name, f.Tags)
+			if !hooks.keepFeature(ctx, m, l, tile, f) {
+				return nil
+			}
+
+			g, err := basic.ToWebMercator(f.SRID, f.Geometry)
+			if err != nil {
+				return fmt.Errorf("unable to transform geometry to webmercator from SRID (%v) for feature %v due to error: %w", f.SRID, f.ID, err)
+			}
+			d, ok := distance(g, center)
+			if !ok || d > dist {
+				return nil
+			}
+			if g, err = basic.FromWebMercator(tegola.WGS84, g); err != nil {
+				return err
+			}
+
+			tags := map[string]interface{}{}
+			for k, v := range f.Tags {
+				tags[k] = v
+			}
+			l.addDefaultTags(qt.z, qt.x, qt.y, tags)
+
+			mu.Lock()
+			features = append(features, QueryFeature{
+				Layer:    l.MVTName(),
+				ID:       f.ID,
+				Geometry: g,
+				Tags:     tags,
+				Distance: d,
+			})
+			mu.Unlock()
+			return nil
+		})
+		span.SetError(err)
+		if err != nil && !l.Optional {
+			errs[i] = fmt.Errorf("layer (%v): %w", l.MVTName(), err)
+		}
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(features, func(i, j int) bool { return features[i].Distance < features[j].Distance })
+	return features, nil
+}
+
+// distance returns the distance of the geometry from the point, 0 for points within polygons.
+// false is returned for empty geometries.
+func distance(g geom.Geometry, pt geom.Point) (float64, bool) {
+	switch g := g.(type) {
+	case geom.Pointer:
+		xy := g.XY()
+		return math.Hypot(xy[0]-pt[0], xy[1]-pt[1]), true
+	case geom.LineStringer:
+		return lineDistance(g.Verticies(), pt)
+	case geom.MultiPointer:
+		points := g.Points()
+		return minDistance(len(points), func(i int) (float64, bool) { return distance(geom.Point(points[i]), pt) })
+	case geom.MultiLineStringer:
+		lines := g.LineStrings()
+		return minDistance(len(lines), func(i int) (float64, bool) { return lineDistance(lines[i], pt) })
+	case geom.Polygoner:
+		return polygonDistance(g.LinearRings(), pt)
+	case geom.MultiPolygoner:
+		polygons := g.Polygons()
+		return minDistance(len(polygons), func(i int) (float64, bool) { return polygonDistance(polygons[i], pt) })
+	case geom.Collectioner:
+		geoms := g.Geometries()
+		return minDistance(len(geoms), func(i int) (float64, bool) { return distance(geoms[i], pt) })
+	default:
+		return 0, false
+	}
+}
+
+// minDistance returns the smallest of the n distances
+func minDistance(n int, fn func(i int) (float64, bool)) (min float64, found bool) {
+	for i := 0; i < n; i++ {
+		d, ok := fn(i)
+		if ok && (!found || d < min) {
+			min, found = d, true
+		}
+	}
+	return min, found
+}
+
+// lineDistance returns the distance of the line from the point
+func lineDistance(line [][2]float64, pt geom.Point) (float64, bool) {
+	if len(line) == 1 {
+		return math.Hypot(line[0][0]-pt[0], line[0][1]-pt[1]), true
+	}
+	return minDistance(len(line)-1, func(i int) (float64, bool) {
+		return segmentDistance(line[i], line[i+1], pt), true
+	})
+}
+
+// polygonDistance returns the distance of the polygon from the point, 0 within the polygon
+func polygonDistance(rings [][][2]float64, pt geom.Point) (float64, bool) {
+	if len(rings) == 0 {
+		return 0, false
+	}
+	inside := ringContains(rings[0], pt)
+	for _, hole := range rings[1:] {
+		if ringContains(hole, pt) {
+			inside = false
+		}
+	}
+	if inside {
+		return 0, true
+	}
+	return minDistance(len(rings), func(i int) (float64, bool) {
+		// close the ring
+		ring := append(rings[i][:len(rings[i]):len(rings[i])], rings[i][0])
+		return lineDistance(ring, pt)
+	})
+}
+
+// segmentDistance returns the distance of the segment from the point
+func segmentDistance(a, b [2]float64, pt geom.Point) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	if dx == 0 && dy == 0 {
+		return math.Hypot(pt[0]-a[0], pt[1]-a[1])
+	}
+	t := ((pt[0]-a[0])*dx + (pt[1]-a[1])*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(pt[0]-(a[0]+t*dx), pt[1]-(a[1]+t*dy))
+}
+
+// ringContains reports if the point is within the ring, by the even-odd rule
+func ringContains(ring [][2]float64, pt geom.Point) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > pt[1]) != (b[1] > pt[1]) &&
+			pt[0] < (b[0]-a[0])*(pt[1]-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,43 @@
+package atlas
+
+import (
+	"testing"
+
+	"github.com/go-spatial/geom"
+)
+
+func TestQueryDistance(t *testing.T) {
+	type tcase struct {
+		geom     geom.Geometry
+		expected float64
+		ok       bool
+	}
+
+	square := geom.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 10}}}
+	pt := geom.Point{5, 5}
+
+	tests := map[string]tcase{
+		"point":             {geom: geom.Point{8, 9}, expected: 5, ok: true},
+		"line":              {geom: geom.LineString{{0, 0}, {0, 10}}, expected: 5, ok: true},
+		"line end":          {geom: geom.LineString{{8, 9}, {20, 20}}, expected: 5, ok: true},
+		"within polygon":    {geom: square, expected: 0, ok: true},
+		"within hole":       {geom: geom.Polygon{square[0], {{4, 4}, {6, 4}, {6, 6}, {4, 6}}}, expected: 1, ok: true},
+		"outside polygon":   {geom: geom.Polygon{{{8, 0}, {10, 0}, {10, 10}, {8, 10}}}, expected: 3, ok: true},
+		"nearest of multi":  {geom: geom.MultiPoint{{50, 50}, {5, 7}}, expected: 2, ok: true},
+		"empty collection":  {geom: geom.Collection{}, ok: false},
+		"nested collection": {geom: geom.Collection{geom.Point{5, 6}}, expected: 1, ok: true},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			d, ok := distance(tc.geom, pt)
+			if ok != tc.ok {
+				t.Fatalf("ok, expected %v got %v", tc.ok, ok)
+			}
+			if d != tc.expected {
+				t.Errorf("distance, expected %v got %v", tc.expected, d)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package atlas
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola/raster"
+)
+
+// ErrRasterTileNotFound is returned when the raster of a map has no tile
+type ErrRasterTileNotFound struct {
+	Map     string
+	Z, X, Y uint
+}
+
+func (e ErrRasterTileNotFound) Error() string {
+	return fmt.Sprintf("atlas: map (%v) raster has no tile at %v/%v/%v", e.Map, e.Z, e.X, e.Y)
+}
+
+// Raster is a raster tile source served alongside the vector tiles of a map, from the map's
+// tile urls with the raster's format as the extension (i.e. /maps/:map_name/:z/:x/:y.png).
+// The tiles are either pulled from an Upstream tile service or rendered from a COG.
+type Raster struct {
+	// Upstream is the XYZ / WMTS tile service the raster's tiles are pulled from
+	Upstream *Upstream
+	// COG is the Cloud Optimized GeoTIFF the raster's tiles are rendered from
+	COG *raster.COG
+	// MinZoom and MaxZoom limit the zooms the raster is served at
+	MinZoom uint
+	MaxZoom uint
+}
+
+// ContentType returns the content type of the raster's tiles
+func (r *Raster) ContentType() string {
+	if r.Upstream != nil {
+		return r.Upstream.ContentType
+	}
+	return raster.ContentType
+}
+
+// Format returns the file extension of the raster's tiles (i.e. "png")
+func (r *Raster) Format() string {
+	return tileFormat(r.ContentType())
+}
+
+// HasRaster indicates if the map serves raster tiles alongside its vector tiles
+func (m Map) HasRaster() bool { return m.Raster != nil }
+
+// EncodeRaster will encode the given tile of the map's raster. The tile is compressed
+// like the map's vector tiles are, so both are served the same way.
+func (m Map) EncodeRaster(ctx context.Context, tile *slippy.Tile) ([]byte, error) {
+	z, x, y := tile.ZXY()
+	if m.Raster == nil || z < m.Raster.MinZoom || z > m.Raster.MaxZoom {
+		return nil, ErrRasterTileNotFound{Map: m.Name, Z: z, X: x, Y: y}
+	}
+
+	// tiles must not be reused once the map's availability changes
+	if !m.availabilityChange.IsZero() {
+		recordExpiry(ctx, m.availabilityChange)
+	}
+
+	var (
+		tileBytes []byte
+		err       error
+	)
+	switch {
+	case m.Raster.Upstream != nil:
+		tileBytes, err = m.Raster.Upstream.fetch(ctx, tile)
+	default:
+		tileBytes, err = m.Raster.COG.Tile(ctx, z, x, y)
+		if _, ok := err.(raster.ErrTileNotFound); ok {
+			return nil, ErrRasterTileNotFound{Map: m.Name, Z: z, X: x, Y: y}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return gzipTile(tileBytes)
+}
@@ -0,0 +1,405 @@
+package atlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const (
+	// DefaultReseedInterval is the time between checks for changed data
+	DefaultReseedInterval = 5 * time.Minute
+	// DefaultReseedMaxTiles is the number of tiles of a map checked for each check
+	DefaultReseedMaxTiles = 10000
+)
+
+// LayerReseed is the state of the re-seeding of a map layer whose provider reports its changes
+type LayerReseed struct {
+	Map           string `json:"map"`
+	Layer         string `json:"layer"`
+	ProviderLayer string `json:"provider_layer"`
+	// Since is the time the next check asks for the changes since
+	Since time.Time `json:"since"`
+	// Changes is the number of changed extents reported by the provider
+	Changes uint64 `json:"changes"`
+	// Reseeded is the number of cached tiles re-rendered for the layer's changes
+	Reseeded uint64 `json:"reseeded"`
+	// Skipped is the number of tiles of the layer's changes not checked as MaxTiles was reached
+	Skipped uint64 `json:"skipped"`
+	// Checks is the number of checks made
+	Checks uint64 `json:"checks"`
+	// Checked is the time of the last check
+	Checked time.Time `json:"checked"`
+	// Error of the last check
+	Error string `json:"error,omitempty"`
+}
+
+// Reseeder periodically asks the providers of the map layers for the extents of the data
+// changed since the previous check, and re-renders the cached tiles intersecting them.
+// Tiles which are not cached are left to be rendered when requested. Providers report
+// changes by implementing provider.Changes.
+type Reseeder struct {
+	// Atlas holding the maps to re-seed. The default atlas is used when nil.
+	Atlas *Atlas
+	// Interval between checks. DefaultReseedInterval when 0.
+	Interval time.Duration
+	// MaxTiles bounds the tiles of a map checked for each check, so a change covering a large
+	// area doesn't re-render a large part of the cache. DefaultReseedMaxTiles when 0.
+	MaxTiles int
+	// OnReseed, when set, is called with the keys of the tiles of a map re-rendered by a check
+	OnReseed func(keys []cache.Key)
+
+	lock sync.Mutex
+	// layers is keyed by the map and layer name. Layers which don't report their changes are nil.
+	layers map[[2]string]*LayerReseed
+}
+
+// Run checks for changed data every Interval until the context is done. The first check
+// starts tracking the changes of the layers.
+func (rs *Reseeder) Run(ctx context.Context) {
+	interval := rs.Interval
+	if interval <= 0 {
+		interval = DefaultReseedInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		rs.Check(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check re-seeds the cached tiles of the data changed since the previous check, as of now
+func (rs *Reseeder) Check(ctx context.Context, now time.Time) {
+	cacher := rs.Atlas.GetCache()
+	if cacher == nil {
+		return
+	}
+
+	for _, m := range rs.Atlas.AllMaps() {
+		// maps with their own cache backend may not be cached
+		if cache.MapBackend(cacher, m.Name) == nil || !m.Availability.Available(now) {
+			continue
+		}
+
+		tiles := changedTiles{}
+		for _, l := range m.Layers {
+			ch, ok := layerProvider(m, l).(provider.Changes)
+			if !ok {
+				continue
+			}
+
+			lr, since, ok := rs.layer(m.Name, l, now)
+			if !ok {
+				// the layer is tracked from now
+				continue
+			}
+
+			extents, err := ch.LayerChanges(ctx, l.ProviderLayerID, since)
+			if ctx.Err() != nil {
+				return
+			}
+			rs.record(lr, func() {
+				lr.Checks++
+				lr.Checked = now
+				lr.Error = ""
+				if err != nil {
+					lr.Error = err.Error()
+					return
+				}
+				lr.Since = now
+				lr.Changes += uint64(len(extents))
+			})
+			if errors.Is(err, provider.ErrUnsupported) {
+				// the layer is not configured to report its changes
+				rs.untrack(m.Name, lr)
+				continue
+			}
+			if err != nil {
+				log.Errorf("reseed: map (%v) layer (%v) changes: %v", m.Name, lr.Layer, err)
+				continue
+			}
+
+			rs.addTiles(tiles, m, l, lr, extents)
+		}
+
+		rs.reseed(ctx, cacher, m, tiles, now)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// layer returns the re-seed state of the map layer and the time its changes are asked since.
+// ok is false when the layer is checked for the first time, or doesn't report its changes.
+func (rs *Reseeder) layer(mapName string, l Layer, now time.Time) (lr *LayerReseed, since time.Time, ok bool) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	if rs.layers == nil {
+		rs.layers = map[[2]string]*LayerReseed{}
+	}
+
+	// layers of MVT providers don't have a Provider to look up their name with
+	name := l.Name
+	if name == "" {
+		name = l.ProviderLayerID
+	}
+
+	key := [2]string{mapName, name}
+	lr, ok = rs.layers[key]
+	if !ok {
+		rs.layers[key] = &LayerReseed{Map: mapName, Layer: name, ProviderLayer: l.ProviderLayerID, Since: now}
+		return nil, now, false
+	}
+	if lr == nil {
+		return nil, now, false
+	}
+	return lr, lr.Since, true
+}
+
+// untrack stops checking the changes of the layer
+func (rs *Reseeder) untrack(mapName string, lr *LayerReseed) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.layers[[2]string{mapName, lr.Layer}] = nil
+}
+
+func (rs *Reseeder) record(lr *LayerReseed, fn func()) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	fn()
+}
+
+// changedTile is a tile of changed data, with the layers whose data changed
+type changedTile struct {
+	tile   slippy.Tile
+	layers map[string]*LayerReseed
+}
+
+type changedTiles map[slippy.Tile]*changedTile
+
+// addTiles adds the tiles of the layer's zooms intersecting the extents, including the tiles
+// whose buffer intersects them, up to MaxTiles
+func (rs *Reseeder) addTiles(tiles changedTiles, m Map, l Layer, lr *LayerReseed, extents []geom.Extent) {
+	maxTiles := rs.MaxTiles
+	if maxTiles <= 0 {
+		maxTiles = DefaultReseedMaxTiles
+	}
+
+	grid := m.TileGrid()
+	minZoom, maxZoom := l.MinZoom, l.MaxZoom
+	if maxZoom == 0 || maxZoom > grid.MaxZoom() {
+		maxZoom = grid.MaxZoom()
+	}
+	// the tiles above the cache max zoom are not cached
+	if m.CacheMaxZoom != nil && maxZoom > *m.CacheMaxZoom {
+		maxZoom = *m.CacheMaxZoom
+	}
+
+	tileExtent := float64(m.TileExtent)
+	if tileExtent == 0 {
+		tileExtent = slippy.MvtTileDim
+	}
+	// the features in the buffer of a tile are part of the tile
+	buffer := float64(m.TileBuffer) / tileExtent
+
+	var skipped uint64
+	for _, ext := range extents {
+		for z := minZoom; z <= maxZoom; z++ {
+			minX, minY, maxX, maxY, ok := grid.TileRange(z, ext, buffer)
+			if !ok {
+				if z == minZoom {
+					log.Warnf("reseed: map (%v) layer (%v) changed extent (%v) has no tiles of the map's grid", m.Name, lr.Layer, ext)
+				}
+				continue
+			}
+
+			cols, rows := uint64(maxX-minX+1), uint64(maxY-minY+1)
+			for i := uint64(0); i < cols*rows; i++ {
+				tile := slippy.Tile{Z: z, X: minX + uint(i/rows), Y: minY + uint(i%rows)}
+				rt, ok := tiles[tile]
+				if !ok {
+					if len(tiles) >= maxTiles {
+						// the rest of the zoom's tiles are not checked
+						skipped += cols*rows - i
+						break
+					}
+					rt = &changedTile{tile: tile, layers: map[string]*LayerReseed{}}
+					tiles[tile] = rt
+				}
+				rt.layers[lr.Layer] = lr
+			}
+		}
+	}
+
+	if skipped > 0 {
+		log.Warnf("reseed: map (%v) layer (%v) changes cover more than %v tiles, %v tiles were not re-seeded", m.Name, lr.Layer, maxTiles, skipped)
+		rs.record(lr, func() { lr.Skipped += skipped })
+	}
+}
+
+// reseed re-renders the map tiles and layer tiles which are cached
+func (rs *Reseeder) reseed(ctx context.Context, cacher cache.Interface, m Map, tiles changedTiles, now time.Time) {
+	// re-seed in a stable order, so interrupted checks progress the same way
+	sorted := make([]*changedTile, 0, len(tiles))
+	for _, rt := range tiles {
+		sorted = append(sorted, rt)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i].tile, sorted[j].tile
+		if a.Z != b.Z {
+			return a.Z < b.Z
+		}
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		return a.Y < b.Y
+	})
+
+	var reseededKeys []cache.Key
+	if rs.OnReseed != nil {
+		defer func() {
+			if len(reseededKeys) > 0 {
+				rs.OnReseed(reseededKeys)
+			}
+		}()
+	}
+
+	available := m.FilterLayersByAvailability(now)
+	for _, rt := range sorted {
+		z, x, y := rt.tile.ZXY()
+		zm := available.FilterLayersByZoom(z)
+
+		keys := []cache.Key{{MapName: m.Name, Z: z, X: x, Y: y}}
+		for name := range rt.layers {
+			keys = append(keys, cache.Key{MapName: m.Name, LayerName: name, Z: z, X: x, Y: y})
+		}
+
+		for _, key := range keys {
+			tm := zm
+			if key.LayerName != "" {
+				tm = zm.FilterLayersByID(key.LayerName)
+			}
+
+			reseeded, err := reseedTile(ctx, cacher, tm, key)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Errorf("reseed: map (%v) tile (%v): %v", m.Name, key.String(), err)
+				continue
+			}
+			if !reseeded {
+				continue
+			}
+			reseededKeys = append(reseededKeys, key)
+			for _, lr := range rt.layers {
+				if key.LayerName == "" || key.LayerName == lr.Layer {
+					rs.record(lr, func() { lr.Reseeded++ })
+				}
+			}
+		}
+	}
+}
+
+// reseedTile re-renders the tile of the key when it's cached
+func reseedTile(ctx context.Context, cacher cache.Interface, m Map, key cache.Key) (bool, error) {
+	_, hit, err := cacher.Get(&key)
+	if err != nil || !hit {
+		return false, err
+	}
+
+	start := time.Now()
+	ctx = WithExpiry(ctx)
+	b, err := m.Encode(ctx, slippy.NewTile(key.Z, key.X, key.Y))
+	if err == nil {
+		err = cache.SetExpires(cacher, &key, b, Expiry(ctx))
+	}
+	reportSeed(ctx, m.Name, key.Z, key.X, key.Y, start, err)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Reseeds returns the re-seed state of the tracked layers sorted by map and layer
+func (rs *Reseeder) Reseeds() []LayerReseed {
+	if rs == nil {
+		return []LayerReseed{}
+	}
+
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	lrs := make([]LayerReseed, 0, len(rs.layers))
+	for _, lr := range rs.layers {
+		if lr != nil {
+			lrs = append(lrs, *lr)
+		}
+	}
+
+	sort.Slice(lrs, func(i, j int) bool {
+		if lrs[i].Map != lrs[j].Map {
+			return lrs[i].Map < lrs[j].Map
+		}
+		return lrs[i].Layer < lrs[j].Layer
+	})
+
+	return lrs
+}
+
+// layerProvider returns the provider of the map layer, the map's MVT provider for the
+// layers of MVT provider maps
+func layerProvider(m Map, l Layer) interface{} {
+	if l.Provider == nil {
+		return m.mvtProvider
+	}
+	return l.Provider
+}
+
+// webMercatorExtent converts a WGS84 extent to web mercator, clamped to the web mercator bounds
+func webMercatorExtent(ext geom.Extent) (min, max [2]float64, err error) {
+	const maxLat = 85.0511287798066
+	clamp := func(v, limit float64) float64 { return math.Max(-limit, math.Min(limit, v)) }
+
+	for i, pt := range []geom.Point{
+		{clamp(ext.MinX(), 180), clamp(ext.MinY(), maxLat)},
+		{clamp(ext.MaxX(), 180), clamp(ext.MaxY(), maxLat)},
+	} {
+		g, err := basic.ToWebMercator(tegola.WGS84, pt)
+		if err != nil {
+			return min, max, err
+		}
+		p, ok := g.(geom.Point)
+		if !ok {
+			return min, max, fmt.Errorf("unexpected geometry %T", g)
+		}
+		if i == 0 {
+			min = p
+		} else {
+			max = p
+		}
+	}
+	return min, max, nil
+}
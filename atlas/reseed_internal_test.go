@@ -0,0 +1,113 @@
+package atlas
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+// changedTiler reports the extents set as changed
+type changedTiler struct {
+	test.TileProvider
+	changes []geom.Extent
+	err     error
+	since   []time.Time
+}
+
+func (ct *changedTiler) LayerChanges(ctx context.Context, layer string, since time.Time) ([]geom.Extent, error) {
+	ct.since = append(ct.since, since)
+	return ct.changes, ct.err
+}
+
+func TestReseeder(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tiler := &changedTiler{}
+
+	a := &Atlas{}
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{
+		{Name: "changed", ProviderLayerID: "a", Provider: tiler, MinZoom: 2, MaxZoom: 3},
+		{Name: "static", ProviderLayerID: "b", Provider: &test.TileProvider{}},
+		{Name: "unconfigured", ProviderLayerID: "c", Provider: &changedTiler{err: provider.ErrUnsupported}},
+	}
+	a.AddMap(m)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	stale := []byte("stale")
+	keys := map[string]cache.Key{
+		// the tiles of the change, the layer tile and the tile whose buffer includes the change
+		"map":          {MapName: "test", Z: 2, X: 2, Y: 1},
+		"layer":        {MapName: "test", LayerName: "changed", Z: 3, X: 4, Y: 3},
+		"buffer":       {MapName: "test", Z: 3, X: 3, Y: 3},
+		"other layer":  {MapName: "test", LayerName: "static", Z: 2, X: 2, Y: 1},
+		"outside":      {MapName: "test", Z: 2, X: 0, Y: 0},
+		"out of zooms": {MapName: "test", Z: 4, X: 8, Y: 7},
+	}
+	for _, key := range keys {
+		key := key
+		cacher.Set(&key, stale)
+	}
+
+	rs := Reseeder{Atlas: a}
+
+	// the first check tracks the layers from now
+	tiler.changes = []geom.Extent{{0.1, 0.1, 1, 1}}
+	rs.Check(context.Background(), now)
+	if len(tiler.since) != 0 {
+		t.Fatalf("first check, expected no changes asked got %v", tiler.since)
+	}
+
+	rs.Check(context.Background(), now.Add(time.Minute))
+	if !reflect.DeepEqual(tiler.since, []time.Time{now}) {
+		t.Errorf("since, expected %v got %v", []time.Time{now}, tiler.since)
+	}
+
+	for name, reseeded := range map[string]bool{
+		"map":          true,
+		"layer":        true,
+		"buffer":       true,
+		"other layer":  false,
+		"outside":      false,
+		"out of zooms": false,
+	} {
+		key := keys[name]
+		b, hit, _ := cacher.Get(&key)
+		if !hit {
+			t.Errorf("%v, expected the tile to be cached", name)
+			continue
+		}
+		if got := string(b) != string(stale); got != reseeded {
+			t.Errorf("%v, expected reseeded %v got %v", name, reseeded, got)
+		}
+	}
+
+	// tiles which were not cached are not rendered
+	if _, hit, _ := cacher.Get(&cache.Key{MapName: "test", Z: 3, X: 4, Y: 3}); hit {
+		t.Errorf("expected the tile which was not cached to not be rendered")
+	}
+
+	reseeds := rs.Reseeds()
+	if len(reseeds) != 1 {
+		t.Fatalf("reseeds, expected 1 layer got %+v", reseeds)
+	}
+	if lr := reseeds[0]; lr.Layer != "changed" || lr.Changes != 1 || lr.Reseeded != 3 || !lr.Since.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected reseed %+v", lr)
+	}
+
+	// the tiles beyond MaxTiles are skipped
+	rs.MaxTiles = 1
+	tiler.changes = []geom.Extent{{-180, -85, 180, 85}}
+	rs.Check(context.Background(), now.Add(2*time.Minute))
+	if lr := rs.Reseeds()[0]; lr.Skipped != 16+64-1 {
+		t.Errorf("skipped, expected %v got %v", 16+64-1, lr.Skipped)
+	}
+}
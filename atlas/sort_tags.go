@@ -0,0 +1,80 @@
+package atlas
+
+import (
+	"sort"
+
+	"github.com/go-spatial/geom/encoding/mvt"
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+)
+
+// sortTileTags sorts the tags of the layers of the tile, see sortLayerTags
+func sortTileTags(vt *vectorTile.Tile) error {
+	for _, l := range vt.Layers {
+		if err := sortLayerTags(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortLayerTags orders the tags of the layer's features by key, and re-indexes the keys and
+// values of the layer in the order the features use them. The encoder indexes the tags in the
+// order of Go's map iteration, so equal features would otherwise be encoded to different bytes.
+func sortLayerTags(l *vectorTile.Tile_Layer) error {
+	var (
+		keys     []string
+		values   []*vectorTile.Tile_Value
+		keyIdx   = make(map[uint32]uint32, len(l.Keys))
+		valueIdx = make(map[uint32]uint32, len(l.Values))
+		pairs    [][2]uint32
+	)
+
+	for _, f := range l.Features {
+		if len(f.Tags)%2 != 0 {
+			return ErrMalformedTags
+		}
+
+		pairs = pairs[:0]
+		for i := 0; i < len(f.Tags); i += 2 {
+			k, v := f.Tags[i], f.Tags[i+1]
+			if int(k) >= len(l.Keys) || int(v) >= len(l.Values) {
+				return ErrMalformedTags
+			}
+			pairs = append(pairs, [2]uint32{k, v})
+		}
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return l.Keys[pairs[i][0]] < l.Keys[pairs[j][0]]
+		})
+
+		for i, p := range pairs {
+			nk, ok := keyIdx[p[0]]
+			if !ok {
+				nk = uint32(len(keys))
+				keyIdx[p[0]] = nk
+				keys = append(keys, l.Keys[p[0]])
+			}
+			nv, ok := valueIdx[p[1]]
+			if !ok {
+				nv = uint32(len(values))
+				valueIdx[p[1]] = nv
+				values = append(values, l.Values[p[1]])
+			}
+			f.Tags[2*i], f.Tags[2*i+1] = nk, nv
+		}
+	}
+
+	l.Keys, l.Values = keys, values
+	return nil
+}
+
+// sortFeatures sorts the features by ID, keeping the order of features with the same ID.
+// Features without an ID are first.
+func sortFeatures(features []mvt.Feature) {
+	sort.SliceStable(features, func(i, j int) bool {
+		a, b := features[i].ID, features[j].ID
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return *a < *b
+	})
+}
@@ -0,0 +1,110 @@
+package atlas
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/mvt"
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// tagsTiler returns a point with many tags for every tile
+type tagsTiler struct {
+	pointTiler
+}
+
+func (tt *tagsTiler) TileFeatures(ctx context.Context, layer string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	tags := map[string]interface{}{}
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		tags[k] = k + "-value"
+	}
+	return fn(&provider.Feature{ID: 1, Geometry: geom.Point{13.4, 52.5}, SRID: tegola.WGS84, Tags: tags})
+}
+
+func TestSortLayerTags(t *testing.T) {
+	str := func(s string) *vectorTile.Tile_Value { return &vectorTile.Tile_Value{StringValue: proto.String(s)} }
+
+	l := vectorTile.Tile_Layer{
+		Keys:   []string{"name", "class", "unused"},
+		Values: []*vectorTile.Tile_Value{str("unused"), str("park"), str("Tiergarten")},
+		Features: []*vectorTile.Tile_Feature{
+			{Tags: []uint32{0, 2, 1, 1}},
+			{Tags: []uint32{1, 1}},
+		},
+	}
+	if err := sortLayerTags(&l); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"class", "name"}; !reflect.DeepEqual(l.Keys, expected) {
+		t.Errorf("keys, expected %v got %v", expected, l.Keys)
+	}
+	if expected := []*vectorTile.Tile_Value{str("park"), str("Tiergarten")}; !reflect.DeepEqual(l.Values, expected) {
+		t.Errorf("values, expected %v got %v", expected, l.Values)
+	}
+	for i, expected := range [][]uint32{{0, 0, 1, 1}, {0, 0}} {
+		if !reflect.DeepEqual(l.Features[i].Tags, expected) {
+			t.Errorf("feature %v tags, expected %v got %v", i, expected, l.Features[i].Tags)
+		}
+	}
+
+	malformed := vectorTile.Tile_Layer{Keys: []string{"name"}, Features: []*vectorTile.Tile_Feature{{Tags: []uint32{0, 1}}}}
+	if err := sortLayerTags(&malformed); err != ErrMalformedTags {
+		t.Errorf("malformed tags, expected ErrMalformedTags got %v", err)
+	}
+}
+
+func TestSortFeatures(t *testing.T) {
+	id := func(i uint64) *uint64 { return &i }
+	features := []mvt.Feature{
+		{ID: id(3), Tags: map[string]interface{}{"n": 1}},
+		{ID: id(1)},
+		{},
+		{ID: id(3), Tags: map[string]interface{}{"n": 2}},
+	}
+	sortFeatures(features)
+
+	var got []interface{}
+	for _, f := range features {
+		if f.ID == nil {
+			got = append(got, nil)
+			continue
+		}
+		got = append(got, *f.ID)
+	}
+	if expected := []interface{}{nil, uint64(1), uint64(3), uint64(3)}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("ids, expected %v got %v", expected, got)
+	}
+	if features[2].Tags["n"] != 1 || features[3].Tags["n"] != 2 {
+		t.Errorf("features of the same id, expected in their order got %v", features[2:])
+	}
+}
+
+func TestEncodeDeterministic(t *testing.T) {
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{{Name: "places", ProviderLayerID: "test-layer", Provider: &tagsTiler{}}}
+	tile := slippy.NewTile(0, 0, 0)
+
+	expected, err := m.encodeMVTTile(context.Background(), tile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the tags of 8 keys are in the order of map iteration before they are sorted
+	for i := 0; i < 20; i++ {
+		got, err := m.encodeMVTTile(context.Background(), tile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("encode %v, expected the bytes of the first encode", i)
+		}
+	}
+}
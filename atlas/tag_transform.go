@@ -0,0 +1,219 @@
+package atlas
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// TagType is the type a tag value is cast to by a TagTransform
+type TagType string
+
+const (
+	TagTypeString TagType = "string"
+	TagTypeInt    TagType = "int"
+	TagTypeFloat  TagType = "float"
+	TagTypeBool   TagType = "bool"
+)
+
+// ParseTagType returns the TagType for the given config value
+func ParseTagType(s string) (TagType, error) {
+	switch tt := TagType(strings.ToLower(strings.TrimSpace(s))); tt {
+	case TagTypeString, TagTypeInt, TagTypeFloat, TagTypeBool:
+		return tt, nil
+	default:
+		return "", fmt.Errorf("atlas: invalid tag type (%v), expected one of: string, int, float, bool", s)
+	}
+}
+
+// TagTransform shapes the tags of a layer's features once the provider returns them.
+// The steps are applied in order: tags are renamed, computed, cast then dropped.
+type TagTransform struct {
+	// Rename maps tags to their new names. A renamed tag replaces a tag of the new name.
+	Rename map[string]string
+	// Computed are the tags set to the output of their template, executed with the feature's
+	// tags, i.e. {{.name}} ({{.ref}}). The tag is left off features missing a tag the
+	// template uses.
+	Computed map[string]*template.Template
+	// Cast are the types tag values are cast to. Values which can't be cast are dropped.
+	Cast map[string]TagType
+	// Drop are the tags removed from the features
+	Drop []string
+}
+
+// NewTagTransform returns the TagTransform of the config values, parsing the computed tags'
+// templates and the cast types
+func NewTagTransform(rename, computed, cast map[string]string, drop []string) (tt TagTransform, err error) {
+	tt.Rename = rename
+	tt.Drop = drop
+
+	if len(computed) > 0 {
+		tt.Computed = make(map[string]*template.Template, len(computed))
+	}
+	for tag, text := range computed {
+		// missing tags fail the template rather than rendering as "<no value>"
+		if tt.Computed[tag], err = template.New(tag).Option("missingkey=error").Parse(text); err != nil {
+			return tt, fmt.Errorf("atlas: invalid template for computed tag (%v): %w", tag, err)
+		}
+	}
+
+	if len(cast) > 0 {
+		tt.Cast = make(map[string]TagType, len(cast))
+	}
+	for tag, typ := range cast {
+		if tt.Cast[tag], err = ParseTagType(typ); err != nil {
+			return tt, err
+		}
+	}
+
+	return tt, nil
+}
+
+// IsZero reports if the transform leaves tags as they are
+func (tt TagTransform) IsZero() bool {
+	return len(tt.Rename) == 0 && len(tt.Computed) == 0 && len(tt.Cast) == 0 && len(tt.Drop) == 0
+}
+
+// transformTags applies the layer's TagTransform to the tags of a feature
+func (l Layer) transformTags(mapName string, tags map[string]interface{}) {
+	tt := l.TagTransform
+	if tt.IsZero() {
+		return
+	}
+
+	// read every tag to be renamed before setting them, so tags can be swapped
+	renamed := make(map[string]interface{}, len(tt.Rename))
+	for from := range tt.Rename {
+		if v, ok := tags[from]; ok {
+			renamed[from] = v
+			delete(tags, from)
+		}
+	}
+	for from, v := range renamed {
+		tags[tt.Rename[from]] = v
+	}
+
+	if len(tt.Computed) > 0 {
+		// every template sees the tags before any are computed
+		computed := make(map[string]interface{}, len(tt.Computed))
+		var sb strings.Builder
+		for tag, tmpl := range tt.Computed {
+			sb.Reset()
+			if err := tmpl.Execute(&sb, tags); err != nil {
+				continue
+			}
+			computed[tag] = sb.String()
+		}
+		for tag, v := range computed {
+			tags[tag] = v
+		}
+	}
+
+	for tag, typ := range tt.Cast {
+		v, ok := tags[tag]
+		if !ok || v == nil {
+			continue
+		}
+		cv, err := castTag(v, typ)
+		if err != nil {
+			delete(tags, tag)
+			warnDroppedAttribute(mapName, l, tag, err.Error())
+			continue
+		}
+		tags[tag] = cv
+	}
+
+	for _, tag := range tt.Drop {
+		delete(tags, tag)
+	}
+}
+
+// castTag casts a tag value to the type
+func castTag(v interface{}, typ TagType) (interface{}, error) {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+
+	switch typ {
+	case TagTypeString:
+		return fmt.Sprint(v), nil
+
+	case TagTypeInt:
+		switch v := v.(type) {
+		case string:
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return i, nil
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("value (%v) can't be cast to an int", v)
+			}
+			return int64(f), nil
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+		// integers are kept as they are, rather than through a float64
+		switch rv := reflect.ValueOf(v); rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int(), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(rv.Uint()), nil
+		}
+		if f, ok := tagNumber(v); ok {
+			return int64(f), nil
+		}
+
+	case TagTypeFloat:
+		switch v := v.(type) {
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("value (%v) can't be cast to a float", v)
+			}
+			return f, nil
+		case bool:
+			if v {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		}
+		if f, ok := tagNumber(v); ok {
+			return f, nil
+		}
+
+	case TagTypeBool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("value (%v) can't be cast to a bool", v)
+			}
+			return b, nil
+		}
+		if f, ok := tagNumber(v); ok {
+			return f != 0, nil
+		}
+	}
+
+	return nil, fmt.Errorf("value (%v) of type %T can't be cast to %v", v, v, typ)
+}
+
+// tagNumber returns the numeric tag value as a float64
+func tagNumber(v interface{}) (float64, bool) {
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,102 @@
+package atlas
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayerTransformTags(t *testing.T) {
+	type tcase struct {
+		rename   map[string]string
+		computed map[string]string
+		cast     map[string]string
+		drop     []string
+		tags     map[string]interface{}
+		expected map[string]interface{}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tt, err := NewTagTransform(tc.rename, tc.computed, tc.cast, tc.drop)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			l := Layer{Name: "roads", TagTransform: tt}
+
+			l.transformTags("test-map", tc.tags)
+			if !reflect.DeepEqual(tc.tags, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, tc.tags)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"none": {
+			tags:     map[string]interface{}{"name": "Main St"},
+			expected: map[string]interface{}{"name": "Main St"},
+		},
+		"rename": {
+			rename:   map[string]string{"name_en": "name"},
+			tags:     map[string]interface{}{"name_en": "Main St", "name": "Hauptstraße"},
+			expected: map[string]interface{}{"name": "Main St"},
+		},
+		"swap": {
+			rename:   map[string]string{"a": "b", "b": "a"},
+			tags:     map[string]interface{}{"a": 1, "b": 2},
+			expected: map[string]interface{}{"a": 2, "b": 1},
+		},
+		"computed after rename": {
+			rename:   map[string]string{"name_en": "name"},
+			computed: map[string]string{"label": "{{.name}} ({{.ref}})"},
+			tags:     map[string]interface{}{"name_en": "Main St", "ref": "A1"},
+			expected: map[string]interface{}{"name": "Main St", "ref": "A1", "label": "Main St (A1)"},
+		},
+		"computed missing tag": {
+			computed: map[string]string{"label": "{{.name}} ({{.ref}})"},
+			tags:     map[string]interface{}{"name": "Main St"},
+			expected: map[string]interface{}{"name": "Main St"},
+		},
+		"cast": {
+			cast: map[string]string{"lanes": "int", "width": "float", "oneway": "bool", "ref": "string", "maxspeed": "int"},
+			tags: map[string]interface{}{
+				"lanes":    "2",
+				"width":    int32(7),
+				"oneway":   "true",
+				"ref":      12,
+				"maxspeed": "fast",
+			},
+			// values which can't be cast are dropped
+			expected: map[string]interface{}{
+				"lanes":  int64(2),
+				"width":  float64(7),
+				"oneway": true,
+				"ref":    "12",
+			},
+		},
+		"cast computed": {
+			computed: map[string]string{"lanes": "{{.forward}}"},
+			cast:     map[string]string{"lanes": "int"},
+			tags:     map[string]interface{}{"forward": 3},
+			expected: map[string]interface{}{"forward": 3, "lanes": int64(3)},
+		},
+		"drop": {
+			computed: map[string]string{"label": "{{.name}}"},
+			drop:     []string{"name", "internal_id"},
+			tags:     map[string]interface{}{"name": "Main St", "internal_id": 7},
+			expected: map[string]interface{}{"label": "Main St"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTagTransformErrors(t *testing.T) {
+	if _, err := NewTagTransform(nil, map[string]string{"label": "{{.name"}, nil, nil); err == nil {
+		t.Errorf("invalid template, expected an error")
+	}
+	if _, err := NewTagTransform(nil, nil, map[string]string{"lanes": "integer"}, nil); err == nil {
+		t.Errorf("invalid cast type, expected an error")
+	}
+}
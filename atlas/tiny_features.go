@@ -0,0 +1,77 @@
+package atlas
+
+import (
+	"math"
+
+	"github.com/go-spatial/geom"
+)
+
+// dropTinyFeatures drops the polygons with an area below the layer's MinPolygonArea and the
+// lines shorter than its MinLineLength from a geometry in tile coordinates, so the thresholds
+// are in the pixels of the tile's extent and drop more of a layer's features at lower zooms.
+// nil is returned when nothing is left of the geometry.
+func (l Layer) dropTinyFeatures(g geom.Geometry) geom.Geometry {
+	switch g := g.(type) {
+	case geom.Polygoner:
+		if l.MinPolygonArea > 0 && polygonArea(g.LinearRings()) < l.MinPolygonArea {
+			return nil
+		}
+		return g
+
+	case geom.MultiPolygoner:
+		if l.MinPolygonArea <= 0 {
+			return g
+		}
+		var plys []geom.Polygon
+		for _, p := range g.Polygons() {
+			if polygonArea(p) >= l.MinPolygonArea {
+				plys = append(plys, p)
+			}
+		}
+		return multiPolygonOrPolygon(plys)
+
+	case geom.LineStringer:
+		if l.MinLineLength > 0 && lineLength(g.Verticies()) < l.MinLineLength {
+			return nil
+		}
+		return g
+
+	case geom.MultiLineStringer:
+		if l.MinLineLength <= 0 {
+			return g
+		}
+		var mls geom.MultiLineString
+		for _, ls := range g.LineStrings() {
+			if lineLength(ls) >= l.MinLineLength {
+				mls = append(mls, ls)
+			}
+		}
+		if len(mls) == 0 {
+			return nil
+		}
+		return mls
+
+	default:
+		return g
+	}
+}
+
+// polygonArea returns the area of the exterior ring of a polygon less the area of its holes
+func polygonArea(rings [][][2]float64) float64 {
+	var area float64
+	for i, ring := range rings {
+		a := math.Abs(ringSignedArea(ring))
+		if i > 0 {
+			a = -a
+		}
+		area += a
+	}
+	return area
+}
+
+func lineLength(ls [][2]float64) (length float64) {
+	for i := 1; i < len(ls); i++ {
+		length += math.Hypot(ls[i][0]-ls[i-1][0], ls[i][1]-ls[i-1][1])
+	}
+	return length
+}
@@ -0,0 +1,88 @@
+package atlas
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+)
+
+func TestLayerDropTinyFeatures(t *testing.T) {
+	type tcase struct {
+		layer    Layer
+		geom     geom.Geometry
+		expected geom.Geometry
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			got := tc.layer.dropTinyFeatures(tc.geom)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	// 100 square pixels
+	square := geom.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 10}}}
+	// 4 square pixels
+	small := geom.Polygon{{{20, 20}, {22, 20}, {22, 22}, {20, 22}}}
+	// 100 square pixels less a 64 square pixel hole
+	holed := geom.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 10}}, {{1, 1}, {1, 9}, {9, 9}, {9, 1}}}
+
+	tests := map[string]tcase{
+		"no thresholds": {
+			geom:     small,
+			expected: small,
+		},
+		"polygon kept": {
+			layer:    Layer{MinPolygonArea: 50},
+			geom:     square,
+			expected: square,
+		},
+		"polygon dropped": {
+			layer:    Layer{MinPolygonArea: 50},
+			geom:     small,
+			expected: nil,
+		},
+		"holes subtracted": {
+			layer:    Layer{MinPolygonArea: 50},
+			geom:     holed,
+			expected: nil,
+		},
+		"multi polygon": {
+			layer:    Layer{MinPolygonArea: 50},
+			geom:     geom.MultiPolygon{square, small},
+			expected: square,
+		},
+		"line kept": {
+			layer:    Layer{MinLineLength: 5},
+			geom:     geom.LineString{{0, 0}, {3, 4}},
+			expected: geom.LineString{{0, 0}, {3, 4}},
+		},
+		"line dropped": {
+			layer:    Layer{MinLineLength: 6},
+			geom:     geom.LineString{{0, 0}, {3, 4}},
+			expected: nil,
+		},
+		"multi line string": {
+			layer:    Layer{MinLineLength: 6},
+			geom:     geom.MultiLineString{{{0, 0}, {3, 4}}, {{0, 0}, {3, 4}, {3, 10}}},
+			expected: geom.MultiLineString{{{0, 0}, {3, 4}, {3, 10}}},
+		},
+		"line threshold keeps polygons": {
+			layer:    Layer{MinLineLength: 1000},
+			geom:     small,
+			expected: small,
+		},
+		"point": {
+			layer:    Layer{MinPolygonArea: 50, MinLineLength: 5},
+			geom:     geom.Point{1, 1},
+			expected: geom.Point{1, 1},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
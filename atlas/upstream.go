@@ -0,0 +1,229 @@
+package atlas
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/geom/encoding/mvt"
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola/maths"
+)
+
+// DefaultUpstreamTimeout is the time allowed for an upstream tile request
+const DefaultUpstreamTimeout = 10 * time.Second
+
+// ErrUpstreamURL is returned when an upstream url template is missing a tile coordinate token
+type ErrUpstreamURL struct {
+	URL string
+}
+
+func (e ErrUpstreamURL) Error() string {
+	return fmt.Sprintf("atlas: upstream url (%v) must contain the {z}, {x} and {y} (or {-y}) tokens", e.URL)
+}
+
+// ErrUpstreamTileNotFound is returned when the upstream tile service does not have the tile
+type ErrUpstreamTileNotFound struct {
+	URL string
+}
+
+func (e ErrUpstreamTileNotFound) Error() string {
+	return fmt.Sprintf("atlas: upstream tile (%v) not found", e.URL)
+}
+
+// ErrUpstreamStatus is returned when the upstream tile service responds with an unexpected status
+type ErrUpstreamStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrUpstreamStatus) Error() string {
+	return fmt.Sprintf("atlas: upstream tile (%v) responded with status %v", e.URL, e.Status)
+}
+
+// Upstream is a tile service a map pulls its tiles from (pull-through cache)
+// rather than encoding them from provider layers.
+type Upstream struct {
+	// URL is the tile url template. The following tokens are replaced:
+	// 	{z}, {x}, {y} - the slippy tile coordinates
+	// 	{-y} - the TMS row (flipped y)
+	// 	{TileMatrix}, {TileCol}, {TileRow} - WMTS aliases of z, x and y
+	URL string
+	// ContentType of the upstream tiles, served with the tiles from the cache
+	ContentType string
+	// TTL bounds the lifetime of cached upstream tiles. 0 caches the tiles without expiry
+	TTL time.Duration
+	// Headers are added to every upstream request (i.e. API keys)
+	Headers map[string]string
+	// MinZoom and MaxZoom limit the zooms requested from upstream
+	MinZoom uint
+	MaxZoom uint
+
+	client *http.Client
+	// sem limits the concurrent upstream requests. nil is unlimited
+	sem chan struct{}
+}
+
+// NewUpstream returns an Upstream for the url template. An empty content type is inferred
+// from the url's extension. maxRequests limits the requests in flight to the upstream
+// service, 0 is unlimited.
+func NewUpstream(url, contentType string, ttl, timeout time.Duration, maxRequests uint) (*Upstream, error) {
+	hasY := strings.Contains(url, "{y}") || strings.Contains(url, "{-y}") || strings.Contains(url, "{TileRow}")
+	hasX := strings.Contains(url, "{x}") || strings.Contains(url, "{TileCol}")
+	hasZ := strings.Contains(url, "{z}") || strings.Contains(url, "{TileMatrix}")
+	if !hasZ || !hasX || !hasY {
+		return nil, ErrUpstreamURL{URL: url}
+	}
+
+	if contentType == "" {
+		contentType = upstreamContentType(url)
+	}
+	if timeout == 0 {
+		timeout = DefaultUpstreamTimeout
+	}
+
+	u := Upstream{
+		URL:         url,
+		ContentType: contentType,
+		TTL:         ttl,
+		MaxZoom:     MaxZoom,
+		client:      &http.Client{Timeout: timeout},
+	}
+	if maxRequests > 0 {
+		u.sem = make(chan struct{}, maxRequests)
+	}
+
+	return &u, nil
+}
+
+// upstreamContentType infers the content type from the extension of the url template
+func upstreamContentType(url string) string {
+	if i := strings.IndexAny(url, "?#"); i != -1 {
+		url = url[:i]
+	}
+
+	switch strings.ToLower(path.Ext(url)) {
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".webp":
+		return "image/webp"
+	default:
+		return mvt.MimeType
+	}
+}
+
+// TileURL returns the upstream url of the tile
+func (u *Upstream) TileURL(z, x, y uint) string {
+	tmsY := uint(maths.Exp2(uint64(z))) - 1 - y
+
+	return strings.NewReplacer(
+		"{z}", strconv.FormatUint(uint64(z), 10),
+		"{x}", strconv.FormatUint(uint64(x), 10),
+		"{y}", strconv.FormatUint(uint64(y), 10),
+		"{-y}", strconv.FormatUint(uint64(tmsY), 10),
+		"{TileMatrix}", strconv.FormatUint(uint64(z), 10),
+		"{TileCol}", strconv.FormatUint(uint64(x), 10),
+		"{TileRow}", strconv.FormatUint(uint64(y), 10),
+	).Replace(u.URL)
+}
+
+// fetch requests the tile from the upstream service. The tile's TTL is
+// recorded in ctx so the cached tile expires.
+func (u *Upstream) fetch(ctx context.Context, tile *slippy.Tile) ([]byte, error) {
+	z, x, y := tile.ZXY()
+	url := u.TileURL(z, x, y)
+
+	if z < u.MinZoom || z > u.MaxZoom {
+		return nil, ErrUpstreamTileNotFound{URL: url}
+	}
+
+	if u.sem != nil {
+		select {
+		case u.sem <- struct{}{}:
+			defer func() { <-u.sem }()
+		case <-ctx.Done():
+			return nil, context.Canceled
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range u.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, context.Canceled
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusNoContent:
+		return nil, ErrUpstreamTileNotFound{URL: url}
+	default:
+		return nil, ErrUpstreamStatus{URL: url, Status: resp.StatusCode}
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.TTL > 0 {
+		recordExpiry(ctx, time.Now().Add(u.TTL))
+	}
+
+	return b, nil
+}
+
+// isGzipped reports if b starts with the gzip magic number. Some vector tile
+// services store tiles compressed and serve them without a Content-Encoding.
+func isGzipped(b []byte) bool {
+	return bytes.HasPrefix(b, []byte{0x1f, 0x8b})
+}
+
+// HasUpstream indicates if the map pulls its tiles from an upstream tile service
+func (m Map) HasUpstream() bool { return m.Upstream != nil }
+
+// ContentType returns the content type of the map's tiles
+func (m Map) ContentType() string {
+	if m.Upstream != nil {
+		return m.Upstream.ContentType
+	}
+	return mvt.MimeType
+}
+
+// TileFormat returns the file extension of the map's tiles (i.e. "pbf" or "png")
+func (m Map) TileFormat() string {
+	return tileFormat(m.ContentType())
+}
+
+// tileFormat returns the file extension of tiles of the content type
+func tileFormat(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return "png"
+	case "image/jpeg":
+		return "jpg"
+	case "image/webp":
+		return "webp"
+	default:
+		return "pbf"
+	}
+}
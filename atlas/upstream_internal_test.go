@@ -0,0 +1,154 @@
+package atlas
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom/encoding/mvt"
+	"github.com/go-spatial/geom/slippy"
+)
+
+func TestUpstreamTileURL(t *testing.T) {
+	type tcase struct {
+		url      string
+		z, x, y  uint
+		expected string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			u, err := NewUpstream(tc.url, "", 0, 0, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := u.TileURL(tc.z, tc.x, tc.y); got != tc.expected {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"xyz": {
+			url:      "https://tiles.example.com/{z}/{x}/{y}.png?key=abc",
+			z:        3,
+			x:        2,
+			y:        1,
+			expected: "https://tiles.example.com/3/2/1.png?key=abc",
+		},
+		"tms": {
+			url:      "https://tiles.example.com/{z}/{x}/{-y}.pbf",
+			z:        3,
+			x:        2,
+			y:        1,
+			expected: "https://tiles.example.com/3/2/6.pbf",
+		},
+		"wmts": {
+			url:      "https://tiles.example.com/wmts/osm/{TileMatrix}/{TileRow}/{TileCol}.jpg",
+			z:        3,
+			x:        2,
+			y:        1,
+			expected: "https://tiles.example.com/wmts/osm/3/1/2.jpg",
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestUpstreamContentType(t *testing.T) {
+	tests := map[string]string{
+		"https://tiles.example.com/{z}/{x}/{y}.png":         "image/png",
+		"https://tiles.example.com/{z}/{x}/{y}.jpeg?key=1":  "image/jpeg",
+		"https://tiles.example.com/{z}/{x}/{y}.webp":        "image/webp",
+		"https://tiles.example.com/{z}/{x}/{y}.pbf":         mvt.MimeType,
+		"https://tiles.example.com/tiles?z={z}&x={x}&y={y}": mvt.MimeType,
+	}
+
+	for url, expected := range tests {
+		if got := upstreamContentType(url); got != expected {
+			t.Errorf("url (%v) expected %v got %v", url, expected, got)
+		}
+	}
+}
+
+func TestUpstreamEncode(t *testing.T) {
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write([]byte("compressed tile"))
+	gw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/1/0/0.png":
+			w.Write([]byte("raw tile"))
+		case "/1/1/0.png":
+			w.Write(gzipped.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	u, err := NewUpstream(srv.URL+"/{z}/{x}/{y}.png", "", time.Minute, 0, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u.Headers = map[string]string{"X-Api-Key": "secret"}
+
+	m := NewWebMercatorMap("upstream")
+	m.Upstream = u
+
+	decode := func(b []byte) string {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s, _ := ioutil.ReadAll(r)
+		return string(s)
+	}
+
+	ctx := WithExpiry(context.Background())
+	b, err := m.Encode(ctx, slippy.NewTile(1, 0, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := decode(b); got != "raw tile" {
+		t.Errorf("expected raw tile got %q", got)
+	}
+	if expires := Expiry(ctx); expires.IsZero() || time.Until(expires) > time.Minute {
+		t.Errorf("expected expiry within the ttl, got %v", expires)
+	}
+
+	b, err = m.Encode(context.Background(), slippy.NewTile(1, 1, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(b, gzipped.Bytes()) {
+		t.Errorf("expected gzipped tile to be passed through")
+	}
+
+	_, err = m.Encode(context.Background(), slippy.NewTile(1, 1, 1))
+	if _, ok := err.(ErrUpstreamTileNotFound); !ok {
+		t.Errorf("expected ErrUpstreamTileNotFound got %v", err)
+	}
+
+	u.Headers = nil
+	_, err = m.Encode(context.Background(), slippy.NewTile(1, 0, 0))
+	if e, ok := err.(ErrUpstreamStatus); !ok || e.Status != http.StatusForbidden {
+		t.Errorf("expected ErrUpstreamStatus 403 got %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package atlas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola/internal/servertiming"
+	"github.com/go-spatial/tegola/internal/tracing"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/debug"
+)
+
+const (
+	// UTFGridFormat is the extension of the UTFGrid interactivity tiles of a map
+	UTFGridFormat = "grid.json"
+	// utfGridResolution is the number of pixels of a 256 pixel tile covered by a cell of a grid
+	utfGridResolution = 4
+	// utfGridSize is the number of rows and columns of a grid
+	utfGridSize = 256 / utfGridResolution
+)
+
+// ErrNoUTFGridLayers is returned when a UTFGrid is requested of a map without layers with a
+// UTFGridKey at the tile's zoom
+type ErrNoUTFGridLayers struct {
+	Map string
+}
+
+func (e ErrNoUTFGridLayers) Error() string {
+	return fmt.Sprintf("atlas: map (%v) has no utfgrid layers", e.Map)
+}
+
+// UTFGrid is an interactivity tile of the UTFGrid 1.2 spec
+// (https://github.com/mapbox/utfgrid-spec/tree/master/1.2). Each character of the rows of
+// the grid encodes the index of the key of the feature under a cell of the tile, the data of
+// the features are keyed by their key.
+type UTFGrid struct {
+	Grid []string                          `json:"grid"`
+	Keys []string                          `json:"keys"`
+	Data map[string]map[string]interface{} `json:"data"`
+}
+
+// utfGridFeature is a feature of a UTFGrid, in web mercator
+type utfGridFeature struct {
+	key    string
+	tags   map[string]interface{}
+	geom   geom.Geometry
+	extent *geom.Extent
+}
+
+// HasUTFGrid indicates if any of the map's layers have a UTFGridKey, so the map serves
+// UTFGrid interactivity tiles
+func (m Map) HasUTFGrid() bool {
+	for _, l := range m.Layers {
+		if l.UTFGridKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeUTFGrid will encode the UTFGrid of the given tile of the map's layers with a
+// UTFGridKey. The features of the layers are fetched concurrently, as when the vector tile
+// is encoded, and the cells of the grid are keyed by the topmost feature of the last layer
+// covering them. The grid is compressed like the map's vector tiles are, so both are served
+// the same way.
+func (m Map) EncodeUTFGrid(ctx context.Context, tile *slippy.Tile) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "atlas.encode_utfgrid")
+	defer span.Finish()
+	span.SetAttribute("tegola.map", m.Name)
+	span.SetAttribute("tegola.tile", fmt.Sprintf("%v/%v/%v", tile.Z, tile.X, tile.Y))
+
+	var layers []Layer
+	for _, l := range m.Layers {
+		if l.UTFGridKey == "" || l.Provider == nil {
+			continue
+		}
+		if _, ok := l.Provider.(*debug.Provider); ok {
+			continue
+		}
+		layers = append(layers, l)
+	}
+	if m.HasUpstream() || len(layers) == 0 {
+		return nil, ErrNoUTFGridLayers{Map: m.Name}
+	}
+
+	// tiles must not be reused once the map's or its layers' availability changes
+	if !m.availabilityChange.IsZero() {
+		recordExpiry(ctx, m.availabilityChange)
+	}
+
+	var (
+		layerFeatures = make([][]utfGridFeature, len(layers))
+		layerErrs     = make([]error, len(layers))
+		tileGrid      = m.TileGrid()
+		hooks         = registeredHooks()
+	)
+	m.forEachLayer(ctx, layers, func(i int, l Layer) {
+		ptile := tileGrid.newProviderTile(tile.Z, tile.X, tile.Y, uint(m.TileBuffer))
+
+		// used to check for expired features
+		now := time.Now()
+
+		layerCtx, cancel := l.layerContext(ctx)
+		defer cancel()
+
+		layerCtx, span := tracing.Start(layerCtx, "provider.tile_features")
+		defer span.Finish()
+		span.SetAttribute("tegola.layer", l.MVTName())
+		span.SetAttribute("tegola.provider_layer", l.ProviderLayerID)
+		defer servertiming.Since(ctx, "layer", l.MVTName(), now)
+
+		err := l.Provider.TileFeatures(layerCtx, l.ProviderLayerID, ptile, func(f *provider.Feature) error {
+			if l.expired(ctx, f.Tags, now) {
+				return nil
+			}
+
+			// shape the tags before the feature's key is read
+			l.transformTags(m.Name, f.Tags)
+			if !hooks.keepFeature(ctx, m, l, tile, f) {
+				return nil
+			}
+
+			key, ok := f.Tags[l.UTFGridKey]
+			if !ok || key == nil {
+				return nil
+			}
+
+			g, err := tileGrid.toGridSRID(f.SRID, f.Geometry)
+			if err != nil {
+				return fmt.Errorf("unable to transform geometry to SRID (%v) from SRID (%v) for feature %v due to error: %w", tileGrid.SRID, f.SRID, f.ID, err)
+			}
+			ext, err := geom.NewExtentFromGeometry(g)
+			if err != nil || ext == nil {
+				return nil
+			}
+
+			l.processJSONAttributes(m.Name, f.Tags)
+			l.processGeometryAttributes(m.Name, f.Tags)
+
+			// add default tags, but don't overwrite a tag that already exists
+			tags := map[string]interface{}{}
+			for k, v := range f.Tags {
+				tags[k] = v
+			}
+			l.addDefaultTags(tile.Z, tile.X, tile.Y, tags)
+
+			layerFeatures[i] = append(layerFeatures[i], utfGridFeature{
+				key:    fmt.Sprint(key),
+				tags:   tags,
+				geom:   g,
+				extent: ext,
+			})
+			return nil
+		})
+		timedOut := layerTimedOut(ctx, layerCtx)
+		if timedOut {
+			err = ErrLayerTimeout{Timeout: l.Timeout}
+		}
+
+		span.SetAttribute("tegola.features", len(layerFeatures[i]))
+		span.SetError(err)
+
+		if err != nil {
+			switch {
+			case errors.Is(err, context.Canceled):
+				// Do nothing if we were cancelled.
+
+			case timedOut:
+				// skip slow layers but still return the grid
+				skipTimedOutLayer(ctx, m.Name, l, tile)
+				layerFeatures[i] = nil
+
+			case l.Optional:
+				// skip the failed optional layer but still return the grid
+				omitLayer(ctx, m.Name, l, tile, err)
+				layerFeatures[i] = nil
+
+			default:
+				// the grid can't be returned without the layer
+				layerErrs[i] = ErrLayerFailed{Map: m.Name, Layer: l.MVTName(), Err: err}
+			}
+		}
+	})
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	for _, err := range layerErrs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, encodeSpan := tracing.Start(ctx, "utfgrid.encode")
+	defer encodeSpan.Finish()
+	defer servertiming.Since(ctx, "encode", "", time.Now())
+
+	// features are drawn in the order of their layers, the last feature is on top
+	var features []utfGridFeature
+	for _, lf := range layerFeatures {
+		features = append(features, lf...)
+	}
+
+	grid := newUTFGrid(tileGrid.TileExtent(tile.Z, tile.X, tile.Y), features)
+	b, err := json.Marshal(grid)
+	encodeSpan.SetAttribute("tegola.bytes", len(b))
+	if err != nil {
+		encodeSpan.SetError(err)
+		return nil, err
+	}
+
+	return gzipTile(b)
+}
+
+// newUTFGrid returns the grid of the features within the extent (in the CRS of the map's grid). Points and
+// lines key the cells within a cell of them, polygons the cells they cover.
+func newUTFGrid(extent *geom.Extent, features []utfGridFeature) UTFGrid {
+	grid := UTFGrid{
+		Grid: make([]string, utfGridSize),
+		Keys: []string{""},
+		Data: map[string]map[string]interface{}{},
+	}
+
+	cell := (extent.MaxX() - extent.MinX()) / utfGridSize
+	indexes := map[string]int{}
+
+	var row strings.Builder
+	for r := 0; r < utfGridSize; r++ {
+		row.Reset()
+		y := extent.MaxY() - (float64(r)+0.5)*cell
+		for c := 0; c < utfGridSize; c++ {
+			pt := geom.Point{extent.MinX() + (float64(c)+0.5)*cell, y}
+
+			idx := 0
+			for i := len(features) - 1; i >= 0; i-- {
+				f := features[i]
+				if pt[0] < f.extent.MinX()-cell || pt[0] > f.extent.MaxX()+cell ||
+					pt[1] < f.extent.MinY()-cell || pt[1] > f.extent.MaxY()+cell {
+					continue
+				}
+				if d, ok := distance(f.geom, pt); !ok || d > cell {
+					continue
+				}
+
+				var seen bool
+				if idx, seen = indexes[f.key]; !seen {
+					idx = len(grid.Keys)
+					indexes[f.key] = idx
+					grid.Keys = append(grid.Keys, f.key)
+					grid.Data[f.key] = f.tags
+				}
+				break
+			}
+			row.WriteRune(utfGridCode(idx))
+		}
+		grid.Grid[r] = row.String()
+	}
+	return grid
+}
+
+// utfGridCode encodes the index of a key as a character of the grid, skipping the quote and
+// backslash which would have to be escaped in JSON
+func utfGridCode(idx int) rune {
+	code := idx + 32
+	if code >= 34 {
+		code++
+	}
+	if code >= 92 {
+		code++
+	}
+	return rune(code)
+}
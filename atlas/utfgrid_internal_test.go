@@ -0,0 +1,87 @@
+package atlas
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+)
+
+func TestUTFGridCode(t *testing.T) {
+	tests := map[int]rune{
+		0:  ' ',
+		1:  '!',
+		2:  '#', // skips the quote
+		58: '[',
+		59: ']', // skips the backslash
+		60: '^',
+	}
+
+	for idx, expected := range tests {
+		if got := utfGridCode(idx); got != expected {
+			t.Errorf("index %v, expected %q got %q", idx, expected, got)
+		}
+	}
+}
+
+func TestNewUTFGrid(t *testing.T) {
+	feature := func(key string, g geom.Geometry) utfGridFeature {
+		ext, _ := geom.NewExtentFromGeometry(g)
+		return utfGridFeature{key: key, tags: map[string]interface{}{"id": key}, geom: g, extent: ext}
+	}
+
+	// the cells of a grid of the extent are 1 wide, the first row is at the top
+	extent := geom.NewExtent([2]float64{0, 0}, [2]float64{utfGridSize, utfGridSize})
+	features := []utfGridFeature{
+		// the left half of the grid
+		feature("west", geom.Polygon{{{0, 0}, {32, 0}, {32, 64}, {0, 64}}}),
+		// over the top left corner of the west polygon
+		feature("corner", geom.Polygon{{{0, 54}, {10, 54}, {10, 64}, {0, 64}}}),
+		// the center of the cell of row 3, column 60
+		feature("point", geom.Point{60.5, 60.5}),
+	}
+
+	grid := newUTFGrid(extent, features)
+
+	if len(grid.Grid) != utfGridSize {
+		t.Fatalf("rows, expected %v got %v", utfGridSize, len(grid.Grid))
+	}
+	for i, row := range grid.Grid {
+		if len([]rune(row)) != utfGridSize {
+			t.Fatalf("row %v columns, expected %v got %v", i, utfGridSize, len([]rune(row)))
+		}
+	}
+
+	// keys are indexed in the order their cells are found
+	expectedKeys := []string{"", "corner", "west", "point"}
+	if !reflect.DeepEqual(grid.Keys, expectedKeys) {
+		t.Errorf("keys, expected %v got %v", expectedKeys, grid.Keys)
+	}
+	for _, key := range expectedKeys[1:] {
+		if grid.Data[key]["id"] != key {
+			t.Errorf("data of key %v, expected %v got %v", key, map[string]interface{}{"id": key}, grid.Data[key])
+		}
+	}
+
+	cells := map[[2]int]string{
+		{0, 0}:   "corner",
+		{20, 5}:  "west",
+		{63, 31}: "west",
+		{3, 60}:  "point",
+		{2, 60}:  "point", // within a cell of the point
+		{2, 61}:  "",
+		{40, 50}: "",
+	}
+	for rc, expected := range cells {
+		code := []rune(grid.Grid[rc[0]])[rc[1]]
+		got := "unknown"
+		for i, key := range grid.Keys {
+			if utfGridCode(i) == code {
+				got = key
+			}
+		}
+		if got != expected {
+			t.Errorf("cell %v, expected %q got %q", rc, expected, got)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package atlas
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// WarmupArea is the area of a map seeded by a Warmup
+type WarmupArea struct {
+	Map string
+	// Bounds in WGS84. The bounds of the map when nil.
+	Bounds  *geom.Extent
+	MinZoom uint
+	MaxZoom uint
+}
+
+// WarmupResult reports the tiles of a warm-up
+type WarmupResult struct {
+	// Seeded is the number of tiles rendered and cached
+	Seeded uint64
+	// Cached is the number of tiles which were cached already
+	Cached uint64
+	// Failed is the number of tiles which could not be rendered
+	Failed uint64
+}
+
+// Warmup seeds the tiles of the areas of the maps which are not cached yet, i.e. the most
+// requested tiles, at startup before the server starts listening.
+type Warmup struct {
+	// Atlas holding the maps to warm up. The default atlas is used when nil.
+	Atlas *Atlas
+	// Concurrency is the number of tiles rendered at once. The number of CPUs when 0.
+	Concurrency int
+	Areas       []WarmupArea
+}
+
+// Run seeds the tiles of the areas until they are all seeded or the context is done. Tiles
+// which can't be rendered are logged and skipped.
+func (w *Warmup) Run(ctx context.Context) (WarmupResult, error) {
+	var res WarmupResult
+
+	cacher := w.Atlas.GetCache()
+	if cacher == nil {
+		return res, ErrMissingCache
+	}
+
+	concurrency := w.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	type warmupTile struct {
+		m       Map
+		z, x, y uint
+	}
+	tiles := make(chan warmupTile)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tiles {
+				seeded, err := warmupTileOf(ctx, w.Atlas, cacher, t.m, t.z, t.x, t.y)
+				switch {
+				case ctx.Err() != nil:
+				case err != nil:
+					atomic.AddUint64(&res.Failed, 1)
+					log.Errorf("warmup: map (%v) tile (%v/%v/%v): %v", t.m.Name, t.z, t.x, t.y, err)
+				case seeded:
+					atomic.AddUint64(&res.Seeded, 1)
+				default:
+					atomic.AddUint64(&res.Cached, 1)
+				}
+			}
+		}()
+	}
+
+	err := func() error {
+		defer close(tiles)
+		for _, area := range w.Areas {
+			m, err := w.Atlas.Map(area.Map)
+			if err != nil {
+				return err
+			}
+
+			// maps with their own cache backend may not be cached
+			if cache.MapBackend(cacher, m.Name) == nil {
+				continue
+			}
+
+			bounds := area.Bounds
+			if bounds == nil {
+				bounds = m.Bounds
+			}
+			if bounds == nil {
+				bounds = tegola.WGS84Bounds
+			}
+			maxZoom := area.MaxZoom
+			// tiles above the cache max zoom are extracted from their ancestors when requested
+			if m.CacheMaxZoom != nil && maxZoom > *m.CacheMaxZoom {
+				maxZoom = *m.CacheMaxZoom
+			}
+
+			grid := m.TileGrid()
+			if maxZoom > grid.MaxZoom() {
+				maxZoom = grid.MaxZoom()
+			}
+			for z := area.MinZoom; z <= maxZoom; z++ {
+				minX, minY, maxX, maxY, ok := grid.TileRange(z, *bounds, 0)
+				if !ok {
+					log.Warnf("warmup: map (%v) bounds (%v) have no tiles of the map's grid at zoom (%v)", m.Name, bounds, z)
+					continue
+				}
+				for x := minX; x <= maxX; x++ {
+					for y := minY; y <= maxY; y++ {
+						select {
+						case tiles <- warmupTile{m: m, z: z, x: x, y: y}:
+						case <-ctx.Done():
+							return ctx.Err()
+						}
+					}
+				}
+			}
+		}
+		return nil
+	}()
+	wg.Wait()
+
+	return res, err
+}
+
+// warmupTileOf renders and caches the map tile when it's not cached
+func warmupTileOf(ctx context.Context, a *Atlas, cacher cache.Interface, m Map, z, x, y uint) (bool, error) {
+	key := cache.Key{MapName: m.Name, Z: z, X: x, Y: y}
+	_, hit, err := cacher.Get(&key)
+	if err != nil || hit {
+		return false, err
+	}
+
+	m = m.FilterLayersByZoom(z).FilterLayersByAvailability(time.Now())
+	if err := a.SeedMapTile(ctx, m, z, x, y); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,65 @@
+package atlas
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+func TestWarmup(t *testing.T) {
+	a := &Atlas{}
+	m := NewWebMercatorMap("test")
+	m.Layers = []Layer{
+		{Name: "layer", ProviderLayerID: "a", Provider: &test.TileProvider{}},
+	}
+	a.AddMap(m)
+
+	w := Warmup{
+		Atlas: a,
+		Areas: []WarmupArea{
+			{Map: "test", Bounds: &geom.Extent{0.1, 0.1, 1, 1}, MinZoom: 1, MaxZoom: 3},
+		},
+	}
+	if _, err := w.Run(context.Background()); err != ErrMissingCache {
+		t.Fatalf("without a cache, expected %v got %v", ErrMissingCache, err)
+	}
+
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	cached := []byte("cached")
+	cacher.Set(&cache.Key{MapName: "test", Z: 2, X: 2, Y: 1}, cached)
+
+	res, err := w.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if res.Seeded != 2 || res.Cached != 1 || res.Failed != 0 {
+		t.Errorf("result, expected 2 seeded and 1 cached got %+v", res)
+	}
+
+	for _, key := range []cache.Key{
+		{MapName: "test", Z: 1, X: 1, Y: 0},
+		{MapName: "test", Z: 3, X: 4, Y: 3},
+	} {
+		if _, hit, _ := cacher.Get(&key); !hit {
+			t.Errorf("tile (%v), expected it to be seeded", key.String())
+		}
+	}
+	if b, _, _ := cacher.Get(&cache.Key{MapName: "test", Z: 2, X: 2, Y: 1}); string(b) != string(cached) {
+		t.Errorf("expected the cached tile to be kept")
+	}
+	if _, hit, _ := cacher.Get(&cache.Key{MapName: "test", Z: 4, X: 8, Y: 7}); hit {
+		t.Errorf("expected the tiles above the max zoom not to be seeded")
+	}
+
+	w.Areas[0].Map = "missing"
+	if _, err := w.Run(context.Background()); err == nil {
+		t.Errorf("unknown map, expected an error")
+	}
+}
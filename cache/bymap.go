@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"strings"
+	"time"
+)
+
+// ByMap routes the keys of maps to the cache backends configured for the maps, i.e. a static
+// basemap to s3 and a live layer to memory, and the keys of the other maps to Default.
+// Without a Default the tiles of the other maps are not cached.
+type ByMap struct {
+	Default Interface
+	// Maps are the backends of the maps with their own backend, by map name
+	Maps map[string]Interface
+}
+
+// NewByMap routes the keys of the maps to their backends, the keys of other maps to the default
+func NewByMap(defaultCache Interface, maps map[string]Interface) *ByMap {
+	return &ByMap{Default: defaultCache, Maps: maps}
+}
+
+// backend returns the backend of the map. The map name of the key may include its version.
+func (bm *ByMap) backend(mapName string) Interface {
+	if i := strings.LastIndex(mapName, VersionSeparator); i >= 0 {
+		mapName = mapName[:i]
+	}
+	if c, ok := bm.Maps[mapName]; ok {
+		return c
+	}
+	return bm.Default
+}
+
+func (bm *ByMap) Get(key *Key) ([]byte, bool, error) {
+	c := bm.backend(key.MapName)
+	if c == nil {
+		return nil, false, nil
+	}
+	return c.Get(key)
+}
+
+func (bm *ByMap) Set(key *Key, val []byte) error {
+	c := bm.backend(key.MapName)
+	if c == nil {
+		return nil
+	}
+	return c.Set(key, val)
+}
+
+func (bm *ByMap) Purge(key *Key) error {
+	c := bm.backend(key.MapName)
+	if c == nil {
+		return nil
+	}
+	return c.Purge(key)
+}
+
+func (bm *ByMap) PurgeLocal(key *Key) error {
+	c := bm.backend(key.MapName)
+	if c == nil {
+		return nil
+	}
+	return PurgeLocal(c, key)
+}
+
+// SetWithTTL sets the value with the expiration support of the map's backend
+func (bm *ByMap) SetWithTTL(key *Key, val []byte, ttl time.Duration) error {
+	c := bm.backend(key.MapName)
+	if c == nil {
+		return nil
+	}
+	return SetExpires(c, key, val, time.Now().Add(ttl))
+}
+
+// ListKeys lists the keys of each backend which belong to the maps routed to it, so backends
+// shared by maps are listed once per map. Backends which can't list their keys are skipped.
+func (bm *ByMap) ListKeys(fn func(path string) error) error {
+	listed := 0
+	list := func(c Interface, belongs func(mapName string) bool) error {
+		lister, ok := c.(Lister)
+		if !ok {
+			return nil
+		}
+		listed++
+		return lister.ListKeys(func(p string) error {
+			mapName := p
+			if i := strings.Index(p, "/"); i >= 0 {
+				mapName = p[:i]
+			}
+			if i := strings.LastIndex(mapName, VersionSeparator); i >= 0 {
+				mapName = mapName[:i]
+			}
+			if !belongs(mapName) {
+				return nil
+			}
+			return fn(p)
+		})
+	}
+
+	if bm.Default != nil {
+		err := list(bm.Default, func(mapName string) bool {
+			_, ok := bm.Maps[mapName]
+			return !ok
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for name, c := range bm.Maps {
+		name := name
+		if err := list(c, func(mapName string) bool { return mapName == name }); err != nil {
+			return err
+		}
+	}
+
+	if listed == 0 {
+		return ErrNotListable
+	}
+	return nil
+}
+
+// MapBackend returns the backend the tiles of the map are cached in, nil when they are not
+// cached. The cache versions of the atlas are unwrapped.
+func MapBackend(c Interface, mapName string) Interface {
+	if v, ok := c.(*Versioned); ok {
+		c = v.Interface
+	}
+	if bm, ok := c.(*ByMap); ok {
+		return bm.backend(mapName)
+	}
+	return c
+}
@@ -0,0 +1,73 @@
+package cache_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+func TestByMap(t *testing.T) {
+	basemap := cache.Key{MapName: "basemap", Z: 1, X: 1, Y: 0}
+	live := cache.Key{MapName: "live", Z: 1, X: 1, Y: 0}
+	versioned := cache.Key{MapName: "live@v2", Z: 2, X: 1, Y: 0}
+
+	defaultCache, _ := memory.New(nil)
+	liveCache, _ := memory.New(nil)
+	bm := cache.NewByMap(defaultCache, map[string]cache.Interface{"live": liveCache})
+
+	for _, key := range []cache.Key{basemap, live, versioned} {
+		if err := bm.Set(&key, []byte(key.MapName)); err != nil {
+			t.Fatalf("set %v, expected nil got %v", key, err)
+		}
+	}
+
+	if _, hit, _ := liveCache.Get(&live); !hit {
+		t.Errorf("map backend, expected the map's tile")
+	}
+	if _, hit, _ := liveCache.Get(&versioned); !hit {
+		t.Errorf("map backend, expected the tile of the map's version")
+	}
+	if _, hit, _ := defaultCache.Get(&live); hit {
+		t.Errorf("default backend, expected no tiles of maps with their own backend")
+	}
+	if _, hit, _ := bm.Get(&basemap); !hit {
+		t.Errorf("default backend, expected the tiles of the other maps")
+	}
+	if c := cache.MapBackend(cache.NewVersioned(bm, func(string) string { return "" }), "live"); c != liveCache {
+		t.Errorf("map backend, expected the map's backend got %v", c)
+	}
+
+	// the keys are listed once, by the backend of their map
+	defaultCache.Set(&live, []byte("stale"))
+	var keys []string
+	if err := bm.ListKeys(func(p string) error {
+		keys = append(keys, p)
+		return nil
+	}); err != nil {
+		t.Fatalf("list keys, expected nil got %v", err)
+	}
+	sort.Strings(keys)
+	if expected := []string{"basemap/1/1/0", "live/1/1/0", "live@v2/2/1/0"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("keys, expected %v got %v", expected, keys)
+	}
+
+	bm.Purge(&live)
+	if _, hit, _ := liveCache.Get(&live); hit {
+		t.Errorf("purge, expected the tile purged from the map's backend")
+	}
+
+	// without a default backend the other maps are not cached
+	bm = cache.NewByMap(nil, map[string]cache.Interface{"live": liveCache})
+	if err := bm.Set(&basemap, []byte("basemap")); err != nil {
+		t.Errorf("set without a default, expected nil got %v", err)
+	}
+	if _, hit, _ := bm.Get(&basemap); hit {
+		t.Errorf("get without a default, expected miss")
+	}
+	if c := cache.MapBackend(bm, "basemap"); c != nil {
+		t.Errorf("map backend without a default, expected nil got %v", c)
+	}
+}
@@ -112,15 +112,33 @@ type Key struct {
 	Z         uint
 	X         uint
 	Y         uint
+	// Format is the file extension of tiles other than the map's vector tiles (i.e. the png
+	// tiles of a map's raster), appended to the key. Empty for vector tiles.
+	Format string
+	// Hash replaces the layer and tile coordinates in the key's path, see Hashed
+	Hash string
 }
 
 func (k Key) String() string {
+	if len(k.Hash) > 2 {
+		// the first characters of the hash spread the keys over directories
+		return filepath.Join(k.MapName, k.Hash[:2], k.Hash)
+	}
+	if k.Hash != "" {
+		return filepath.Join(k.MapName, k.Hash)
+	}
+
+	y := strconv.FormatUint(uint64(k.Y), 10)
+	if k.Format != "" {
+		y += "." + k.Format
+	}
+
 	return filepath.Join(
 		k.MapName,
 		k.LayerName,
 		strconv.FormatUint(uint64(k.Z), 10),
 		strconv.FormatUint(uint64(k.X), 10),
-		strconv.FormatUint(uint64(k.Y), 10))
+		y)
 }
 
 // InitFunc initilize a cache given a config map.
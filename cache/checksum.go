@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// ConfigKeyChecksum is the cache config key enabling the checksums of cached tiles
+const ConfigKeyChecksum = "checksum"
+
+// checksumMagic starts the values written by Checksummed, followed by the length and the
+// CRC-32C of the tile
+var checksumMagic = []byte("TGC1")
+
+const checksumHeaderLen = 12
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksummed stores the length and CRC-32C checksum of the tiles alongside them in the cache
+// backend, and verifies them when the tiles are read. Truncated and corrupted tiles, i.e. of a
+// flaky disk or object store, are purged and reported as missing, so they are rendered again
+// instead of served. Values written without a checksum are returned as is.
+type Checksummed struct {
+	Interface
+
+	// corrupted is the number of corrupted tiles read
+	corrupted uint64
+}
+
+// NewChecksummed wraps the cache backend so its tiles are checksummed
+func NewChecksummed(c Interface) *Checksummed {
+	return &Checksummed{Interface: c}
+}
+
+// Corrupted returns the number of corrupted tiles read from the backend
+func (cs *Checksummed) Corrupted() uint64 {
+	return atomic.LoadUint64(&cs.corrupted)
+}
+
+// frame prefixes the tile with its checksum header
+func frame(val []byte) []byte {
+	b := make([]byte, checksumHeaderLen+len(val))
+	copy(b, checksumMagic)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(val)))
+	binary.BigEndian.PutUint32(b[8:], crc32.Checksum(val, castagnoli))
+	copy(b[checksumHeaderLen:], val)
+	return b
+}
+
+// unframe returns the tile of the value. ok is false when the tile doesn't match its checksum.
+func unframe(b []byte) (val []byte, ok bool) {
+	if !bytes.HasPrefix(b, checksumMagic) {
+		return b, true
+	}
+	if len(b) < checksumHeaderLen {
+		return nil, false
+	}
+	val = b[checksumHeaderLen:]
+	if uint32(len(val)) != binary.BigEndian.Uint32(b[4:]) || crc32.Checksum(val, castagnoli) != binary.BigEndian.Uint32(b[8:]) {
+		return nil, false
+	}
+	return val, true
+}
+
+func (cs *Checksummed) Get(key *Key) ([]byte, bool, error) {
+	b, hit, err := cs.Interface.Get(key)
+	if err != nil || !hit {
+		return nil, hit, err
+	}
+
+	val, ok := unframe(b)
+	if !ok {
+		atomic.AddUint64(&cs.corrupted, 1)
+		log.Warnf("cache: tile (%v) does not match its checksum, purging it", key.String())
+		if err := cs.Interface.Purge(key); err != nil {
+			log.Warnf("cache: purging corrupted tile (%v): %v", key.String(), err)
+		}
+		return nil, false, nil
+	}
+	return val, true, nil
+}
+
+func (cs *Checksummed) Set(key *Key, val []byte) error {
+	return cs.Interface.Set(key, frame(val))
+}
+
+func (cs *Checksummed) PurgeLocal(key *Key) error {
+	return PurgeLocal(cs.Interface, key)
+}
+
+// SetWithTTL sets the value with the expiration support of the wrapped backend
+func (cs *Checksummed) SetWithTTL(key *Key, val []byte, ttl time.Duration) error {
+	return SetExpires(cs.Interface, key, frame(val), time.Now().Add(ttl))
+}
+
+// ListKeys lists the keys of the wrapped backend, when it can list its keys
+func (cs *Checksummed) ListKeys(fn func(path string) error) error {
+	lister, ok := cs.Interface.(Lister)
+	if !ok {
+		return ErrNotListable
+	}
+	return lister.ListKeys(fn)
+}
@@ -0,0 +1,58 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+func TestChecksummed(t *testing.T) {
+	key := cache.Key{MapName: "osm", LayerName: "buildings", Z: 14, X: 8185, Y: 5448}
+	val := []byte("tile")
+
+	mc, _ := memory.New(nil)
+	cs := cache.NewChecksummed(mc)
+
+	if err := cs.Set(&key, val); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	got, hit, err := cs.Get(&key)
+	if err != nil || !hit || string(got) != string(val) {
+		t.Fatalf("checksummed, expected hit of %q got %q, %v, %v", val, got, hit, err)
+	}
+
+	// a tile cached without a checksum is served as is
+	plain := key
+	plain.X++
+	if err := mc.Set(&plain, val); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, hit, _ := cs.Get(&plain); !hit || string(got) != string(val) {
+		t.Errorf("unchecksummed tile, expected hit of %q got %q, %v", val, got, hit)
+	}
+
+	stored, _, _ := mc.Get(&key)
+	tests := map[string][]byte{
+		"truncated": stored[:len(stored)-1],
+		"corrupted": append(append([]byte{}, stored[:len(stored)-1]...), 'x'),
+		"header":    stored[:6],
+	}
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := mc.Set(&key, b); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			corrupted := cs.Corrupted()
+			if _, hit, err := cs.Get(&key); hit || err != nil {
+				t.Errorf("expected miss got hit %v, err %v", hit, err)
+			}
+			if cs.Corrupted() != corrupted+1 {
+				t.Errorf("corrupted, expected %v got %v", corrupted+1, cs.Corrupted())
+			}
+			if _, hit, _ := mc.Get(&key); hit {
+				t.Errorf("expected the corrupted tile to be purged")
+			}
+		})
+	}
+}
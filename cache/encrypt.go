@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const (
+	// ConfigKeyEncryptionKey is the cache config key of the key the tiles are encrypted with
+	ConfigKeyEncryptionKey = "encryption_key"
+	// ConfigKeyEncryptionKeySource is the cache config key of the name of the key source
+	// resolving the encryption_key, i.e. by decrypting it with a KMS
+	ConfigKeyEncryptionKeySource = "encryption_key_source"
+)
+
+// KeySourceBase64 is the name of the built in key source, decoding the base64 encoded key
+const KeySourceBase64 = "base64"
+
+// ErrInvalidEncryptionKey is returned for an encryption key which isn't an AES-128, AES-192 or AES-256 key
+var ErrInvalidEncryptionKey = errors.New("cache: encryption_key must be a 16, 24 or 32 byte key")
+
+// ErrUnknownKeySource is returned for a key source which has not been registered
+type ErrUnknownKeySource struct {
+	Name string
+}
+
+func (e ErrUnknownKeySource) Error() string {
+	return fmt.Sprintf("cache: no key source registered by the name (%v), expected one of %v", e.Name, KeySourcesRegistered())
+}
+
+// KeySourceFunc resolves the configured encryption_key into the key the tiles are encrypted with
+type KeySourceFunc func(key string) ([]byte, error)
+
+var keySources = map[string]KeySourceFunc{
+	KeySourceBase64: base64KeySource,
+}
+
+// RegisterKeySource registers a key source, so it can be configured as the cache's
+// encryption_key_source, i.e. to decrypt the encryption_key with a KMS
+func RegisterKeySource(name string, fn KeySourceFunc) error {
+	if _, ok := keySources[name]; ok {
+		return fmt.Errorf("cache: key source (%v) already exists", name)
+	}
+	keySources[name] = fn
+	return nil
+}
+
+// KeySourcesRegistered returns the names of the registered key sources
+func KeySourcesRegistered() (names []string) {
+	for k := range keySources {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EncryptionKeyFor resolves the encryption key with the registered key source of the name
+func EncryptionKeyFor(source, key string) ([]byte, error) {
+	fn, ok := keySources[source]
+	if !ok {
+		return nil, ErrUnknownKeySource{Name: source}
+	}
+	return fn(key)
+}
+
+func base64KeySource(key string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, ErrInvalidEncryptionKey
+	}
+	return b, nil
+}
+
+// encryptedMagic starts the values written by Encrypted, followed by the nonce and the sealed tile
+var encryptedMagic = []byte("TGE1")
+
+// errNotEncrypted is returned for values which were not written by Encrypted
+var errNotEncrypted = errors.New("value is not encrypted")
+
+// Encrypted encrypts the tiles of a cache backend with AES-GCM, so tiles cached on shared
+// storage can't be read without the key. The path of the key is authenticated with the tile,
+// so a tile moved to another key of the backend isn't served for it. Values which can't be
+// decrypted, including tiles cached before the encryption was configured, are reported as
+// missing so they are rendered again.
+type Encrypted struct {
+	Interface
+	aead cipher.AEAD
+}
+
+// NewEncrypted wraps the cache backend so its tiles are encrypted with the AES key
+func NewEncrypted(c Interface, key []byte) (*Encrypted, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidEncryptionKey
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypted{Interface: c, aead: aead}, nil
+}
+
+// additionalData returns the data authenticated with the tile of the key
+func additionalData(key *Key) []byte {
+	return []byte(filepath.ToSlash(key.String()))
+}
+
+func (e *Encrypted) seal(key *Key, val []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, len(encryptedMagic)+len(nonce)+len(val)+e.aead.Overhead())
+	b = append(b, encryptedMagic...)
+	b = append(b, nonce...)
+	return e.aead.Seal(b, nonce, val, additionalData(key)), nil
+}
+
+func (e *Encrypted) open(key *Key, b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, encryptedMagic) || len(b) < len(encryptedMagic)+e.aead.NonceSize() {
+		return nil, errNotEncrypted
+	}
+	b = b[len(encryptedMagic):]
+	nonce, sealed := b[:e.aead.NonceSize()], b[e.aead.NonceSize():]
+	return e.aead.Open(nil, nonce, sealed, additionalData(key))
+}
+
+func (e *Encrypted) Get(key *Key) ([]byte, bool, error) {
+	b, hit, err := e.Interface.Get(key)
+	if err != nil || !hit {
+		return nil, hit, err
+	}
+
+	val, err := e.open(key, b)
+	if err != nil {
+		log.Warnf("cache: tile (%v) can't be decrypted: %v", key.String(), err)
+		return nil, false, nil
+	}
+	return val, true, nil
+}
+
+func (e *Encrypted) Set(key *Key, val []byte) error {
+	b, err := e.seal(key, val)
+	if err != nil {
+		return err
+	}
+	return e.Interface.Set(key, b)
+}
+
+func (e *Encrypted) PurgeLocal(key *Key) error {
+	return PurgeLocal(e.Interface, key)
+}
+
+// SetWithTTL sets the value with the expiration support of the wrapped backend
+func (e *Encrypted) SetWithTTL(key *Key, val []byte, ttl time.Duration) error {
+	b, err := e.seal(key, val)
+	if err != nil {
+		return err
+	}
+	return SetExpires(e.Interface, key, b, time.Now().Add(ttl))
+}
+
+// ListKeys lists the keys of the wrapped backend, when it can list its keys
+func (e *Encrypted) ListKeys(fn func(path string) error) error {
+	lister, ok := e.Interface.(Lister)
+	if !ok {
+		return ErrNotListable
+	}
+	return lister.ListKeys(fn)
+}
@@ -0,0 +1,54 @@
+package cache_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+func TestEncrypted(t *testing.T) {
+	key := cache.Key{MapName: "osm", LayerName: "buildings", Z: 14, X: 8185, Y: 5448}
+	val := []byte("sensitive tile")
+
+	mc, _ := memory.New(nil)
+	enc, err := cache.NewEncrypted(mc, []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	other, _ := cache.NewEncrypted(mc, []byte("fedcba9876543210fedcba9876543210"))
+
+	if err := enc.Set(&key, val); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, hit, err := enc.Get(&key); err != nil || !hit || string(got) != string(val) {
+		t.Fatalf("encrypted, expected hit of %q got %q, %v, %v", val, got, hit, err)
+	}
+
+	stored, _, _ := mc.Get(&key)
+	if bytes.Contains(stored, val) {
+		t.Errorf("backend, expected the tile to be encrypted got %q", stored)
+	}
+	if _, hit, _ := other.Get(&key); hit {
+		t.Errorf("other key, expected miss")
+	}
+
+	// a tile moved to another key isn't served for it
+	moved := key
+	moved.X++
+	mc.Set(&moved, stored)
+	if _, hit, _ := enc.Get(&moved); hit {
+		t.Errorf("moved tile, expected miss")
+	}
+
+	// tiles cached before the encryption are rendered again
+	mc.Set(&moved, val)
+	if _, hit, _ := enc.Get(&moved); hit {
+		t.Errorf("unencrypted tile, expected miss")
+	}
+
+	if _, err := cache.NewEncrypted(mc, []byte("short")); err != cache.ErrInvalidEncryptionKey {
+		t.Errorf("invalid key, expected %v got %v", cache.ErrInvalidEncryptionKey, err)
+	}
+}
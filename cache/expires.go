@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"time"
+)
+
+// TTLSetter is implemented by cache backends which can expire entries
+type TTLSetter interface {
+	// SetWithTTL sets the value, expiring it after ttl. A cache backend which has its own
+	// expiration configured should use the sooner of the two.
+	SetWithTTL(key *Key, val []byte, ttl time.Duration) error
+}
+
+// SetExpires writes val to the cache so it's not served after expires. The zero time means
+// the value does not expire and is set with Set. Values are not written to backends which
+// don't implement TTLSetter, nor when expires has already passed, so expired tiles are never served.
+func SetExpires(c Interface, key *Key, val []byte, expires time.Time) error {
+	if expires.IsZero() {
+		return c.Set(key, val)
+	}
+
+	ttl := time.Until(expires)
+	if ttl <= 0 {
+		return nil
+	}
+
+	ttlSetter, ok := c.(TTLSetter)
+	if !ok {
+		return nil
+	}
+
+	return ttlSetter.SetWithTTL(key, val, ttl)
+}
@@ -0,0 +1,67 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+// noTTLCache is a cache backend without expiration support
+type noTTLCache struct {
+	sets int
+}
+
+func (c *noTTLCache) Get(key *cache.Key) ([]byte, bool, error) { return nil, false, nil }
+func (c *noTTLCache) Set(key *cache.Key, val []byte) error     { c.sets++; return nil }
+func (c *noTTLCache) Purge(key *cache.Key) error               { return nil }
+
+func TestSetExpires(t *testing.T) {
+	key := cache.Key{MapName: "test-map", Z: 1, X: 1, Y: 1}
+	val := []byte("tile")
+
+	type tcase struct {
+		expires time.Time
+		hit     bool
+		sets    int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			mc, _ := memory.New(nil)
+			if err := cache.SetExpires(mc, &key, val, tc.expires); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if _, hit, _ := mc.Get(&key); hit != tc.hit {
+				t.Errorf("memory cache hit, expected %v got %v", tc.hit, hit)
+			}
+
+			nc := &noTTLCache{}
+			if err := cache.SetExpires(nc, &key, val, tc.expires); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if nc.sets != tc.sets {
+				t.Errorf("sets on a backend without ttl support, expected %v got %v", tc.sets, nc.sets)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"no expiry": {
+			hit:  true,
+			sets: 1,
+		},
+		"future expiry": {
+			expires: time.Now().Add(time.Hour),
+			hit:     true,
+		},
+		"expired": {
+			expires: time.Now().Add(-time.Second),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
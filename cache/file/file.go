@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/cache"
@@ -145,3 +146,22 @@ func (fc *Cache) Purge(key *cache.Key) error {
 	// remove the locker key on purge
 	return os.Remove(path)
 }
+
+// ListKeys adheres to the cache.Lister interface
+func (fc *Cache) ListKeys(fn func(path string) error) error {
+	return filepath.Walk(fc.Basepath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// skip directories and the temporary files of tiles being written
+		if info.IsDir() || strings.HasSuffix(path, "-tmp") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(fc.Basepath, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel))
+	})
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// ConfigKeyKeyHash is the cache config key of the name of the key hasher
+	ConfigKeyKeyHash = "key_hash"
+	// ConfigKeyKeyHashSecret is the cache config key of the secret the keys are hashed with
+	ConfigKeyKeyHashSecret = "key_hash_secret"
+)
+
+// KeyHashHMACSHA256 is the name of the built in key hasher
+const KeyHashHMACSHA256 = "hmac-sha256"
+
+// ErrKeyHashSecretMissing is returned when a key hasher is configured without a secret.
+// As the tiles of a map are easily enumerated, unkeyed hashes would be reversed by hashing them all.
+var ErrKeyHashSecretMissing = errors.New("cache: key_hash_secret is required to hash the cache keys")
+
+// ErrUnknownKeyHasher is returned for a key hasher which has not been registered
+type ErrUnknownKeyHasher struct {
+	Name string
+}
+
+func (e ErrUnknownKeyHasher) Error() string {
+	return fmt.Sprintf("cache: no key hasher registered by the name (%v), expected one of %v", e.Name, KeyHashersRegistered())
+}
+
+// KeyHasher hashes the keys of a cache backend
+type KeyHasher interface {
+	// HashKey returns the hash of the key. The hash is used as a path segment of the key.
+	HashKey(key *Key) string
+}
+
+// KeyHasherInitFunc returns a KeyHasher hashing the keys with the secret
+type KeyHasherInitFunc func(secret string) (KeyHasher, error)
+
+var keyHashers = map[string]KeyHasherInitFunc{
+	KeyHashHMACSHA256: newHMACKeyHasher,
+}
+
+// RegisterKeyHasher registers a key hasher, so it can be configured as the cache's key_hash
+func RegisterKeyHasher(name string, init KeyHasherInitFunc) error {
+	if _, ok := keyHashers[name]; ok {
+		return fmt.Errorf("cache: key hasher (%v) already exists", name)
+	}
+	keyHashers[name] = init
+	return nil
+}
+
+// KeyHashersRegistered returns the names of the registered key hashers
+func KeyHashersRegistered() (names []string) {
+	for k := range keyHashers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KeyHasherFor returns the registered key hasher of the name, hashing with the secret
+func KeyHasherFor(name, secret string) (KeyHasher, error) {
+	init, ok := keyHashers[name]
+	if !ok {
+		return nil, ErrUnknownKeyHasher{Name: name}
+	}
+	return init(secret)
+}
+
+// hmacKeyHasher hashes keys with HMAC-SHA256
+type hmacKeyHasher struct {
+	secret []byte
+}
+
+func newHMACKeyHasher(secret string) (KeyHasher, error) {
+	if secret == "" {
+		return nil, ErrKeyHashSecretMissing
+	}
+	return hmacKeyHasher{secret: []byte(secret)}, nil
+}
+
+func (h hmacKeyHasher) HashKey(key *Key) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(filepath.ToSlash(key.String())))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Hashed replaces the layer and tile coordinates of the keys of a cache backend with their
+// hash, so listings of the backend (i.e. an object store bucket) don't reveal which areas
+// are requested. The map name is kept, so the tiles of a map can still be removed together.
+// Keys of a hashed backend can't be parsed back into tiles.
+type Hashed struct {
+	Interface
+	Hasher KeyHasher
+}
+
+// NewHashed wraps the cache backend so its keys are hashed with the hasher
+func NewHashed(c Interface, hasher KeyHasher) *Hashed {
+	return &Hashed{Interface: c, Hasher: hasher}
+}
+
+// key returns the hashed key. The zoom is kept for the max_zoom of the backend but is not
+// part of the key's path.
+func (h *Hashed) key(key *Key) *Key {
+	return &Key{
+		MapName: key.MapName,
+		Z:       key.Z,
+		Hash:    h.Hasher.HashKey(key),
+	}
+}
+
+func (h *Hashed) Get(key *Key) ([]byte, bool, error) {
+	return h.Interface.Get(h.key(key))
+}
+
+func (h *Hashed) Set(key *Key, val []byte) error {
+	return h.Interface.Set(h.key(key), val)
+}
+
+func (h *Hashed) Purge(key *Key) error {
+	return h.Interface.Purge(h.key(key))
+}
+
+func (h *Hashed) PurgeLocal(key *Key) error {
+	return PurgeLocal(h.Interface, h.key(key))
+}
+
+// SetWithTTL sets the value with the expiration support of the wrapped backend
+func (h *Hashed) SetWithTTL(key *Key, val []byte, ttl time.Duration) error {
+	return SetExpires(h.Interface, h.key(key), val, time.Now().Add(ttl))
+}
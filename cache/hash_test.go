@@ -0,0 +1,83 @@
+package cache_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+// recordingCache records the keys it's called with
+type recordingCache struct {
+	cache.Interface
+	keys []string
+}
+
+func (rc *recordingCache) Set(key *cache.Key, val []byte) error {
+	rc.keys = append(rc.keys, key.String())
+	return rc.Interface.Set(key, val)
+}
+
+func TestHashed(t *testing.T) {
+	key := cache.Key{MapName: "osm", LayerName: "buildings", Z: 14, X: 8185, Y: 5448}
+	val := []byte("tile")
+
+	hasher, err := cache.KeyHasherFor(cache.KeyHashHMACSHA256, "secret")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	otherHasher, _ := cache.KeyHasherFor(cache.KeyHashHMACSHA256, "other secret")
+
+	mc, _ := memory.New(nil)
+	rc := &recordingCache{Interface: mc}
+	hashed := cache.NewHashed(rc, hasher)
+
+	if err := hashed.Set(&key, val); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(rc.keys) != 1 {
+		t.Fatalf("backend keys, expected 1 got %v", rc.keys)
+	}
+	if k := rc.keys[0]; !strings.HasPrefix(k, "osm/") || strings.Contains(k, "buildings") || strings.Contains(k, "8185") || strings.Contains(k, "5448") {
+		t.Errorf("backend key, expected the layer and tile to be hashed got %v", k)
+	}
+
+	if _, hit, _ := hashed.Get(&key); !hit {
+		t.Errorf("hashed, expected hit")
+	}
+	if _, hit, _ := mc.Get(&key); hit {
+		t.Errorf("backend, expected miss of the unhashed key")
+	}
+	if _, hit, _ := cache.NewHashed(mc, otherHasher).Get(&key); hit {
+		t.Errorf("other secret, expected miss")
+	}
+	other := key
+	other.Format = "png"
+	if _, hit, _ := hashed.Get(&other); hit {
+		t.Errorf("other format, expected miss")
+	}
+
+	// the expiration support of the backend is used
+	if err := cache.SetExpires(cache.NewHashed(mc, hasher), &other, val, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if _, hit, _ := hashed.Get(&other); !hit {
+		t.Errorf("expiring value, expected hit")
+	}
+
+	if err := hashed.Purge(&key); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if _, hit, _ := hashed.Get(&key); hit {
+		t.Errorf("purged, expected miss")
+	}
+
+	if _, err := cache.KeyHasherFor(cache.KeyHashHMACSHA256, ""); err != cache.ErrKeyHashSecretMissing {
+		t.Errorf("missing secret, expected %v got %v", cache.ErrKeyHashSecretMissing, err)
+	}
+	if _, err := cache.KeyHasherFor("md5", "secret"); err == nil {
+		t.Errorf("unknown hasher, expected an error")
+	}
+}
@@ -0,0 +1,85 @@
+// Package invalidation propagates the purges of cached tiles between the instances of a
+// deployment, so the tiles the instances keep in memory are purged with the shared cache.
+package invalidation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/dict"
+)
+
+// InstanceID identifies the process in the messages it publishes, so it can ignore them
+var InstanceID = newInstanceID()
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("invalidation: generating the instance id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// Message reports the tiles purged by an instance
+type Message struct {
+	// Origin is the InstanceID of the instance which purged the tiles
+	Origin string `json:"origin"`
+	// Keys are the cache keys of the purged tiles
+	Keys []cache.Key `json:"keys,omitempty"`
+	// SurrogateKeys were purged, the instances purge the tiles they indexed with the keys
+	SurrogateKeys []string `json:"surrogate_keys,omitempty"`
+}
+
+// Bus delivers the messages published by an instance to all the instances subscribed to it,
+// including the instance itself
+type Bus interface {
+	// Publish sends the message to the subscribed instances
+	Publish(msg Message) error
+	// Subscribe calls fn with the messages published to the bus until the bus is closed
+	Subscribe(fn func(msg Message)) error
+	// Close unsubscribes and releases the connections of the bus
+	Close() error
+}
+
+// InitFunc initializes a bus given a config map.
+// The InitFunc should validate the config map, and report any errors.
+// This is called by the For function.
+type InitFunc func(dict.Dicter) (Bus, error)
+
+var buses map[string]InitFunc
+
+// Register is called by the init functions of the buses
+func Register(busType string, init InitFunc) error {
+	if buses == nil {
+		buses = make(map[string]InitFunc)
+	}
+
+	if _, ok := buses[busType]; ok {
+		return fmt.Errorf("invalidation: bus (%v) already exists", busType)
+	}
+	buses[busType] = init
+
+	return nil
+}
+
+// Registered returns the buses that have been registered
+func Registered() (b []string) {
+	for k := range buses {
+		b = append(b, k)
+	}
+	sort.Strings(b)
+	return b
+}
+
+// For returns a configured bus of the given type, provided the correct config map
+func For(busType string, config dict.Dicter) (Bus, error) {
+	init, ok := buses[busType]
+	if !ok {
+		return nil, fmt.Errorf("invalidation: no bus registered by the type: (%v)", busType)
+	}
+
+	return init(config)
+}
@@ -0,0 +1,158 @@
+// Package redis is an invalidation bus publishing the messages to a Redis pub/sub channel
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/go-spatial/tegola/cache/invalidation"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const BusType = "redis"
+
+const (
+	ConfigKeyNetwork  = "network"
+	ConfigKeyAddress  = "address"
+	ConfigKeyPassword = "password"
+	ConfigKeyDB       = "db"
+	ConfigKeyChannel  = "channel"
+)
+
+// DefaultChannel is the channel the messages are published to
+const DefaultChannel = "tegola:invalidation"
+
+func init() {
+	invalidation.Register(BusType, New)
+}
+
+// Bus publishes the messages to a Redis channel
+type Bus struct {
+	Channel string
+
+	client *redis.Client
+
+	mu     sync.Mutex
+	pubsub *redis.PubSub
+	closed bool
+}
+
+// New connects to the Redis server. The config supports the following params:
+//
+// 	network (string): [Optional] the network of the address. defaults to "tcp"
+// 	address (string): [Optional] the address of the server. defaults to "127.0.0.1:6379"
+// 	password (string): [Optional] the password of the server
+// 	db (int): [Optional] the database. defaults to 0
+// 	channel (string): [Optional] the channel of the messages. defaults to "tegola:invalidation"
+func New(config dict.Dicter) (invalidation.Bus, error) {
+	defaultNetwork := "tcp"
+	defaultAddress := "127.0.0.1:6379"
+	defaultPassword := ""
+	defaultDB := 0
+	defaultChannel := DefaultChannel
+
+	network, err := config.String(ConfigKeyNetwork, &defaultNetwork)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := config.String(ConfigKeyAddress, &defaultAddress)
+	if err != nil {
+		return nil, err
+	}
+	password, err := config.String(ConfigKeyPassword, &defaultPassword)
+	if err != nil {
+		return nil, err
+	}
+	db, err := config.Int(ConfigKeyDB, &defaultDB)
+	if err != nil {
+		return nil, err
+	}
+	channel, err := config.String(ConfigKeyChannel, &defaultChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Network:     network,
+		Addr:        addr,
+		Password:    password,
+		DB:          db,
+		PoolSize:    2,
+		DialTimeout: 3 * time.Second,
+	})
+
+	if _, err := client.Ping().Result(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("invalidation: redis (%v): %v", addr, err)
+	}
+
+	return &Bus{Channel: channel, client: client}, nil
+}
+
+// Publish publishes the message to the channel
+func (b *Bus) Publish(msg invalidation.Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(b.Channel, payload).Err()
+}
+
+// Subscribe subscribes to the channel, fn is called with the messages until the bus is closed.
+// The connection is reestablished on network errors, messages published while the
+// connection is lost are not delivered.
+func (b *Bus) Subscribe(fn func(msg invalidation.Message)) error {
+	pubsub := b.client.Subscribe(b.Channel)
+	// wait for the subscription so messages published once subscribed are received
+	if _, err := pubsub.Receive(); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("invalidation: subscribing to redis channel (%v): %v", b.Channel, err)
+	}
+
+	b.mu.Lock()
+	b.pubsub = pubsub
+	b.mu.Unlock()
+
+	go func() {
+		for {
+			m, err := pubsub.ReceiveMessage()
+			if err != nil {
+				b.mu.Lock()
+				closed := b.closed
+				b.mu.Unlock()
+				if closed {
+					return
+				}
+				log.Errorf("invalidation: receiving from redis channel (%v): %v", b.Channel, err)
+				time.Sleep(time.Second)
+				continue
+			}
+
+			var msg invalidation.Message
+			if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+				log.Warnf("invalidation: decoding message of redis channel (%v): %v", b.Channel, err)
+				continue
+			}
+			fn(msg)
+		}
+	}()
+	return nil
+}
+
+// Close unsubscribes and closes the connections to the server
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	pubsub := b.pubsub
+	b.mu.Unlock()
+
+	if pubsub != nil {
+		pubsub.Close()
+	}
+	return b.client.Close()
+}
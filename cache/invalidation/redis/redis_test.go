@@ -0,0 +1,51 @@
+package redis_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/invalidation"
+	"github.com/go-spatial/tegola/cache/invalidation/redis"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/ttools"
+)
+
+// TESTENV is the environment variable that must be set to "yes" to run the redis tests.
+const TESTENV = "RUN_REDIS_TESTS"
+
+// TestBus will run tests against a local redis instance
+// on 127.0.0.1:6379
+func TestBus(t *testing.T) {
+	ttools.ShouldSkip(t, TESTENV)
+
+	bus, err := redis.New(dict.Dict{redis.ConfigKeyChannel: "tegola:invalidation:test"})
+	if err != nil {
+		t.Fatalf("new, expected nil got %v", err)
+	}
+	defer bus.Close()
+
+	received := make(chan invalidation.Message, 1)
+	if err := bus.Subscribe(func(msg invalidation.Message) { received <- msg }); err != nil {
+		t.Fatalf("subscribe, expected nil got %v", err)
+	}
+
+	msg := invalidation.Message{
+		Origin:        "test",
+		Keys:          []cache.Key{{MapName: "osm", LayerName: "roads", Z: 1, X: 2, Y: 3}},
+		SurrogateKeys: []string{"osm:roads"},
+	}
+	if err := bus.Publish(msg); err != nil {
+		t.Fatalf("publish, expected nil got %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if !reflect.DeepEqual(got, msg) {
+			t.Errorf("message, expected %v got %v", msg, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("message, expected it to be received")
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// ErrNotListable is returned by List for cache backends which can't enumerate their keys
+var ErrNotListable = errors.New("cache: the cache backend can't list its keys")
+
+// Lister is implemented by cache backends which can enumerate their keys
+type Lister interface {
+	// ListKeys calls fn with the path of every key of the cache, as returned by Key.String
+	// with forward slashes. Listing stops at the first error returned by fn.
+	ListKeys(fn func(path string) error) error
+}
+
+// List calls fn with the key of every tile of the cache. Cached values which are not tiles
+// are skipped. Backends with hashed keys can't be listed.
+func List(c Interface, fn func(key *Key) error) error {
+	lister, ok := c.(Lister)
+	if !ok {
+		return ErrNotListable
+	}
+
+	return lister.ListKeys(func(p string) error {
+		parts := strings.Split(strings.Trim(p, "/"), "/")
+		if len(parts) < 3 || len(parts) > 5 {
+			return nil
+		}
+
+		key, err := ParseKey(p)
+		if err != nil {
+			return nil
+		}
+		// the extension of tiles other than vector tiles (i.e. png or grid.json), see Key.Format
+		if i := strings.Index(path.Base(p), "."); i >= 0 {
+			key.Format = path.Base(p)[i+1:]
+		}
+		return fn(key)
+	})
+}
@@ -0,0 +1,48 @@
+package cache_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+func TestList(t *testing.T) {
+	mc, _ := memory.New(nil)
+	east, _ := cache.NewNamespace(mc, "us-east-1")
+
+	keys := []cache.Key{
+		{MapName: "osm", Z: 1, X: 1, Y: 0},
+		{MapName: "osm", LayerName: "roads", Z: 2, X: 1, Y: 3},
+		{MapName: "osm", Z: 3, X: 2, Y: 1, Format: "png"},
+		{MapName: "osm", Z: 4, X: 2, Y: 1, Format: "grid.json"},
+	}
+	for i := range keys {
+		if err := east.Set(&keys[i], []byte("tile")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+	// keys outside of the namespace and values which aren't tiles are not listed
+	mc.Set(&cache.Key{MapName: "osm", Z: 1, X: 0, Y: 0}, []byte("tile"))
+	mc.Set(&cache.Key{Hash: "ab12"}, []byte("hashed"))
+
+	var listed []cache.Key
+	if err := cache.List(east, func(key *cache.Key) error {
+		listed = append(listed, *key)
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	sort.Slice(listed, func(i, j int) bool { return listed[i].Z < listed[j].Z })
+	if !reflect.DeepEqual(listed, keys) {
+		t.Errorf("keys, expected %+v got %+v", keys, listed)
+	}
+
+	hasher, _ := cache.KeyHasherFor(cache.KeyHashHMACSHA256, "secret")
+	if err := cache.List(cache.NewHashed(mc, hasher), func(*cache.Key) error { return nil }); err != cache.ErrNotListable {
+		t.Errorf("hashed, expected %v got %v", cache.ErrNotListable, err)
+	}
+}
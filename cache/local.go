@@ -0,0 +1,18 @@
+package cache
+
+// LocalPurger is implemented by the cache backends keeping tiles in the memory of the
+// process, which other instances of a deployment can't purge, and by the wrappers of
+// backends. PurgeLocal purges the key from the memory of the process only.
+type LocalPurger interface {
+	PurgeLocal(key *Key) error
+}
+
+// PurgeLocal purges the key from the parts of the cache backend kept in the memory of the
+// process, i.e. to apply a purge made by another instance sharing the rest of the backend.
+// Backends which don't keep tiles in memory are left as is.
+func PurgeLocal(c Interface, key *Key) error {
+	if lp, ok := c.(LocalPurger); ok {
+		return lp.PurgeLocal(key)
+	}
+	return nil
+}
@@ -1,48 +1,153 @@
 package memory
 
 import (
+	"container/list"
+	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/dict"
 )
 
-const CacheType  = "memory"
+const CacheType = "memory"
+
+const (
+	ConfigKeyMaxBytes = "max_bytes"
+	ConfigKeyMaxZoom  = "max_zoom"
+	ConfigKeyTTL      = "ttl"
+)
 
 func init() {
 	cache.Register(CacheType, New)
 }
 
-func New(_ dict.Dicter) (cache.Interface, error) {
-	return &MemoryCache{
-		keyVals: map[string][]byte{},
-	}, nil
+// New instantiates a MemoryCache. The config expects the following optional params:
+//
+// 	max_bytes (int): the size of the cached keys and values beyond which the least recently used are evicted. 0 (default) is unbounded
+// 	max_zoom (int): max zoom to use the cache. beyond this zoom cache Set() calls will be ignored
+// 	ttl (int): seconds before the values expire. 0 (default) is no expiration
+//
+// A nil config is an unbounded cache.
+func New(config dict.Dicter) (cache.Interface, error) {
+	mc := &MemoryCache{
+		MaxZoom: tegola.MaxZ,
+		ll:      list.New(),
+		entries: map[string]*list.Element{},
+	}
+	if config == nil {
+		return mc, nil
+	}
+
+	defaultMaxBytes := 0
+	maxBytes, err := config.Int(ConfigKeyMaxBytes, &defaultMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+
+	defaultMaxZoom := uint(tegola.MaxZ)
+	mc.MaxZoom, err = config.Uint(ConfigKeyMaxZoom, &defaultMaxZoom)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultTTL := 0
+	ttl, err := config.Int(ConfigKeyTTL, &defaultTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	mc.MaxBytes = int64(maxBytes)
+	mc.Expiration = time.Duration(ttl) * time.Second
+	return mc, nil
+}
+
+type entry struct {
+	key     string
+	val     []byte
+	expires time.Time
 }
 
-// test cacher, implements the cache.Interface
+func (e *entry) size() int64 { return int64(len(e.key) + len(e.val)) }
+
+// MemoryCache keeps the values in memory, evicting the least recently used once the keys and
+// values exceed MaxBytes. It implements the cache.Interface.
 type MemoryCache struct {
-	keyVals map[string][]byte
-	sync.RWMutex
+	// MaxBytes bounds the size of the cached keys and values. 0 is unbounded.
+	MaxBytes int64
+	// MaxZoom determines the max zoom the cache to persist. Beyond this
+	// zoom, cache Set() calls will be ignored.
+	MaxZoom uint
+	// Expiration of the values. 0 is no expiration.
+	Expiration time.Duration
+
+	sync.Mutex
+	// ll orders the entries from the most to the least recently used
+	ll      *list.List
+	entries map[string]*list.Element
+	bytes   int64
 }
 
 func (mc *MemoryCache) Get(key *cache.Key) ([]byte, bool, error) {
-	mc.RLock()
-	defer mc.RUnlock()
+	mc.Lock()
+	defer mc.Unlock()
 
-	val, ok := mc.keyVals[key.String()]
+	k := key.String()
+	el, ok := mc.entries[k]
 	if !ok {
 		return nil, false, nil
 	}
 
-	return val, true, nil
+	e := el.Value.(*entry)
+	if !e.expires.IsZero() && !time.Now().Before(e.expires) {
+		mc.remove(el)
+		return nil, false, nil
+	}
+
+	mc.ll.MoveToFront(el)
+	return e.val, true, nil
 }
 
 func (mc *MemoryCache) Set(key *cache.Key, val []byte) error {
+	return mc.SetWithTTL(key, val, 0)
+}
+
+// SetWithTTL adheres to the cache.TTLSetter interface. The sooner of ttl and the configured ttl
+// is used, a ttl of 0 is the configured ttl.
+func (mc *MemoryCache) SetWithTTL(key *cache.Key, val []byte, ttl time.Duration) error {
+	if key.Z > mc.MaxZoom {
+		return nil
+	}
+	if mc.Expiration > 0 && (ttl <= 0 || mc.Expiration < ttl) {
+		ttl = mc.Expiration
+	}
+
+	e := &entry{key: key.String(), val: val}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+
 	mc.Lock()
 	defer mc.Unlock()
 
-	mc.keyVals[key.String()] = val
+	if el, ok := mc.entries[e.key]; ok {
+		mc.remove(el)
+	}
+	// values larger than the cache would evict everything and then themselves
+	if mc.MaxBytes > 0 && e.size() > mc.MaxBytes {
+		return nil
+	}
+
+	mc.entries[e.key] = mc.ll.PushFront(e)
+	mc.bytes += e.size()
 
+	for mc.MaxBytes > 0 && mc.bytes > mc.MaxBytes {
+		mc.remove(mc.ll.Back())
+	}
 	return nil
 }
 
@@ -50,7 +155,47 @@ func (mc *MemoryCache) Purge(key *cache.Key) error {
 	mc.Lock()
 	defer mc.Unlock()
 
-	delete(mc.keyVals, key.String())
+	if el, ok := mc.entries[key.String()]; ok {
+		mc.remove(el)
+	}
+	return nil
+}
+
+// PurgeLocal purges the key, the cache is kept in the memory of the process
+func (mc *MemoryCache) PurgeLocal(key *cache.Key) error {
+	return mc.Purge(key)
+}
+
+// Bytes returns the size of the cached keys and values
+func (mc *MemoryCache) Bytes() int64 {
+	mc.Lock()
+	defer mc.Unlock()
+
+	return mc.bytes
+}
+
+// remove removes the entry of the element, the lock must be held
+func (mc *MemoryCache) remove(el *list.Element) {
+	e := el.Value.(*entry)
+	mc.ll.Remove(el)
+	delete(mc.entries, e.key)
+	mc.bytes -= e.size()
+}
+
+// ListKeys adheres to the cache.Lister interface
+func (mc *MemoryCache) ListKeys(fn func(path string) error) error {
+	// the keys are copied so fn can purge them
+	mc.Lock()
+	keys := make([]string, 0, len(mc.entries))
+	for k := range mc.entries {
+		keys = append(keys, filepath.ToSlash(k))
+	}
+	mc.Unlock()
 
+	for _, k := range keys {
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,152 @@
+package memory_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/dict"
+)
+
+func TestNew(t *testing.T) {
+	type tcase struct {
+		config     dict.Dict
+		maxBytes   int64
+		maxZoom    uint
+		expiration time.Duration
+		expectErr  bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			c, err := memory.New(tc.config)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			mc := c.(*memory.MemoryCache)
+			if mc.MaxBytes != tc.maxBytes {
+				t.Errorf("max bytes, expected %v got %v", tc.maxBytes, mc.MaxBytes)
+			}
+			if mc.MaxZoom != tc.maxZoom {
+				t.Errorf("max zoom, expected %v got %v", tc.maxZoom, mc.MaxZoom)
+			}
+			if mc.Expiration != tc.expiration {
+				t.Errorf("expiration, expected %v got %v", tc.expiration, mc.Expiration)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"nil": {
+			maxZoom: 22,
+		},
+		"limits": {
+			config: dict.Dict{
+				"max_bytes": 1 << 20,
+				"max_zoom":  uint(14),
+				"ttl":       60,
+			},
+			maxBytes:   1 << 20,
+			maxZoom:    14,
+			expiration: time.Minute,
+		},
+		"invalid max_bytes": {
+			config:    dict.Dict{"max_bytes": "lots"},
+			expectErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestEviction(t *testing.T) {
+	key := func(x uint) *cache.Key {
+		return &cache.Key{MapName: "m", Z: 1, X: x, Y: 0}
+	}
+	// the keys are "m/1/<x>/0", 7 bytes
+	val := make([]byte, 10)
+
+	c, _ := memory.New(dict.Dict{"max_bytes": 51})
+	mc := c.(*memory.MemoryCache)
+
+	for x := uint(0); x < 3; x++ {
+		if err := mc.Set(key(x), val); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if exp := int64(51); mc.Bytes() != exp {
+		t.Fatalf("bytes, expected %v got %v", exp, mc.Bytes())
+	}
+
+	// 0 is now the most recently used
+	if _, hit, _ := mc.Get(key(0)); !hit {
+		t.Fatalf("expected a hit for 0")
+	}
+	mc.Set(key(3), val)
+
+	for x, expected := range []bool{true, false, true, true} {
+		if _, hit, _ := mc.Get(key(uint(x))); hit != expected {
+			t.Errorf("hit %v, expected %v got %v", x, expected, hit)
+		}
+	}
+	if exp := int64(51); mc.Bytes() != exp {
+		t.Errorf("bytes, expected %v got %v", exp, mc.Bytes())
+	}
+
+	// values larger than the cache are not cached
+	mc.Set(key(4), make([]byte, 64))
+	if _, hit, _ := mc.Get(key(4)); hit {
+		t.Errorf("expected a miss for a value larger than the cache")
+	}
+
+	mc.Purge(key(0))
+	var keys []string
+	mc.ListKeys(func(path string) error {
+		keys = append(keys, path)
+		return nil
+	})
+	if len(keys) != 2 || mc.Bytes() != 34 {
+		t.Errorf("after purge, expected 2 keys of 34 bytes got %v of %v", keys, mc.Bytes())
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	key := &cache.Key{MapName: "m", Z: 1}
+
+	c, _ := memory.New(dict.Dict{"ttl": 60, "max_zoom": uint(10)})
+	mc := c.(*memory.MemoryCache)
+
+	// the sooner ttl is used
+	mc.SetWithTTL(key, []byte("a"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, hit, _ := mc.Get(key); hit {
+		t.Errorf("expected the value to have expired")
+	}
+	if mc.Bytes() != 0 {
+		t.Errorf("expected the expired value to be removed, got %v bytes", mc.Bytes())
+	}
+
+	mc.SetWithTTL(key, []byte("b"), time.Hour)
+	val, hit, _ := mc.Get(key)
+	if !hit || !reflect.DeepEqual(val, []byte("b")) {
+		t.Errorf("expected a hit of b, got %v %s", hit, val)
+	}
+
+	// beyond the max zoom
+	deep := &cache.Key{MapName: "m", Z: 11}
+	mc.Set(deep, []byte("c"))
+	if _, hit, _ := mc.Get(deep); hit {
+		t.Errorf("expected a miss beyond the max zoom")
+	}
+}
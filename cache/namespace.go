@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ConfigKeyNamespace is the cache config key of the namespace the cache keys are prefixed with
+const ConfigKeyNamespace = "namespace"
+
+// namespaces are used as a path segment of the keys
+var validNamespace = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ErrInvalidNamespace is returned for a namespace which can't be used as a key path segment
+type ErrInvalidNamespace struct {
+	Namespace string
+}
+
+func (e ErrInvalidNamespace) Error() string {
+	return fmt.Sprintf("cache: invalid namespace (%v), expected letters, digits, '-', '_' or '.'", e.Namespace)
+}
+
+// Namespace prefixes the keys of a cache backend, i.e. with the region of a deployment, so
+// deployments sharing a backend keep their tiles apart. Deployments configured with the same
+// namespace share their tiles.
+type Namespace struct {
+	Interface
+	// Name of the namespace
+	Name string
+}
+
+// NewNamespace wraps the cache backend so its keys are prefixed with the namespace
+func NewNamespace(c Interface, name string) (*Namespace, error) {
+	if !validNamespace.MatchString(name) {
+		return nil, ErrInvalidNamespace{Namespace: name}
+	}
+	return &Namespace{Interface: c, Name: name}, nil
+}
+
+// key returns the key within the namespace. The namespace is the key's first path segment.
+func (ns *Namespace) key(key *Key) *Key {
+	k := *key
+	k.MapName = ns.Name + "/" + k.MapName
+	return &k
+}
+
+func (ns *Namespace) Get(key *Key) ([]byte, bool, error) {
+	return ns.Interface.Get(ns.key(key))
+}
+
+func (ns *Namespace) Set(key *Key, val []byte) error {
+	return ns.Interface.Set(ns.key(key), val)
+}
+
+func (ns *Namespace) Purge(key *Key) error {
+	return ns.Interface.Purge(ns.key(key))
+}
+
+func (ns *Namespace) PurgeLocal(key *Key) error {
+	return PurgeLocal(ns.Interface, ns.key(key))
+}
+
+// SetWithTTL sets the value with the expiration support of the wrapped backend
+func (ns *Namespace) SetWithTTL(key *Key, val []byte, ttl time.Duration) error {
+	return SetExpires(ns.Interface, ns.key(key), val, time.Now().Add(ttl))
+}
+
+// ListKeys lists the keys of the namespace, without the namespace, when the wrapped
+// backend can list its keys
+func (ns *Namespace) ListKeys(fn func(path string) error) error {
+	lister, ok := ns.Interface.(Lister)
+	if !ok {
+		return ErrNotListable
+	}
+
+	prefix := ns.Name + "/"
+	return lister.ListKeys(func(p string) error {
+		if !strings.HasPrefix(p, prefix) {
+			return nil
+		}
+		return fn(strings.TrimPrefix(p, prefix))
+	})
+}
+
+// NamespaceOf returns the namespace of the cache backend, or "" when it's not namespaced
+func NamespaceOf(c Interface) string {
+	if v, ok := c.(*Versioned); ok {
+		c = v.Interface
+	}
+	if ns, ok := c.(*Namespace); ok {
+		return ns.Name
+	}
+	return ""
+}
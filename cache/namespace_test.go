@@ -0,0 +1,58 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+func TestNamespace(t *testing.T) {
+	key := cache.Key{MapName: "osm", Z: 1, X: 1, Y: 0}
+	val := []byte("tile")
+
+	mc, _ := memory.New(nil)
+	east, err := cache.NewNamespace(mc, "us-east-1")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	west, _ := cache.NewNamespace(mc, "us-west-2")
+
+	if err := east.Set(&key, val); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if _, hit, _ := east.Get(&key); !hit {
+		t.Errorf("namespace, expected hit")
+	}
+	if _, hit, _ := west.Get(&key); hit {
+		t.Errorf("other namespace, expected miss")
+	}
+	if _, hit, _ := mc.Get(&cache.Key{MapName: "us-east-1/osm", Z: 1, X: 1, Y: 0}); !hit {
+		t.Errorf("backend, expected the namespace to prefix the key")
+	}
+
+	// the expiration support of the backend is used
+	if err := cache.SetExpires(west, &key, val, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if _, hit, _ := west.Get(&key); !hit {
+		t.Errorf("expiring value, expected hit")
+	}
+
+	if err := east.Purge(&key); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if _, hit, _ := east.Get(&key); hit {
+		t.Errorf("purged, expected miss")
+	}
+
+	if cache.NamespaceOf(east) != "us-east-1" || cache.NamespaceOf(mc) != "" {
+		t.Errorf("NamespaceOf, unexpected %q, %q", cache.NamespaceOf(east), cache.NamespaceOf(mc))
+	}
+
+	if _, err := cache.NewNamespace(mc, "us/east"); err == nil {
+		t.Errorf("invalid namespace, expected an error")
+	}
+}
@@ -124,3 +124,39 @@ func (rdc *RedisCache) Get(key *cache.Key) (val []byte, hit bool, err error) {
 func (rdc *RedisCache) Purge(key *cache.Key) (err error) {
 	return rdc.Redis.Del(key.String()).Err()
 }
+
+// SetWithTTL adheres to the cache.TTLSetter interface. The sooner of ttl and the configured ttl is used.
+func (rdc *RedisCache) SetWithTTL(key *cache.Key, val []byte, ttl time.Duration) error {
+	if key.Z > rdc.MaxZoom {
+		return nil
+	}
+
+	if rdc.Expiration > 0 && rdc.Expiration < ttl {
+		ttl = rdc.Expiration
+	}
+
+	return rdc.Redis.
+		Set(key.String(), val, ttl).
+		Err()
+}
+
+// ListKeys adheres to the cache.Lister interface. The keys are scanned, so listing
+// does not block the redis server.
+func (rdc *RedisCache) ListKeys(fn func(path string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := rdc.Redis.Scan(cursor, "", 1000).Result()
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := fn(k); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
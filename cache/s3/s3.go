@@ -7,18 +7,18 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 
 	"github.com/go-spatial/geom/encoding/mvt"
 	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/awsutil"
 )
 
 var (
@@ -31,20 +31,23 @@ const (
 	// required
 	ConfigKeyBucket = "bucket"
 	// optional
-	ConfigKeyBasepath       = "basepath"
-	ConfigKeyMaxZoom        = "max_zoom"
-	ConfigKeyRegion         = "region"   // defaults to "us-east-1"
-	ConfigKeyEndpoint       = "endpoint" //	defaults to ""
-	ConfigKeyAWSAccessKeyID = "aws_access_key_id"
-	ConfigKeyAWSSecretKey   = "aws_secret_access_key"
-	ConfigKeyACL            = "access_control_list" //	defaults to ""
-	ConfigKeyCacheControl   = "cache_control"       //	defaults to ""
-	ConfigKeyContentType    = "content_type"        //	defaults to "application/vnd.mapbox-vector-tile"
+	ConfigKeyBasepath           = "basepath"
+	ConfigKeyMaxZoom            = "max_zoom"
+	ConfigKeyRegion             = awsutil.ConfigKeyRegion   // defaults to "us-east-1"
+	ConfigKeyEndpoint           = awsutil.ConfigKeyEndpoint //	defaults to ""
+	ConfigKeyAWSAccessKeyID     = awsutil.ConfigKeyAWSAccessKeyID
+	ConfigKeyAWSSecretKey       = awsutil.ConfigKeyAWSSecretKey
+	ConfigKeyForcePathStyle     = awsutil.ConfigKeyForcePathStyle     //	defaults to false
+	ConfigKeyInsecureSkipVerify = awsutil.ConfigKeyInsecureSkipVerify //	defaults to false
+	ConfigKeyCACert             = awsutil.ConfigKeyCACert             //	defaults to ""
+	ConfigKeyACL                = "access_control_list"               //	defaults to ""
+	ConfigKeyCacheControl       = "cache_control"                     //	defaults to ""
+	ConfigKeyContentType        = "content_type"                      //	defaults to "application/vnd.mapbox-vector-tile"
 )
 
 const (
 	DefaultBasepath    = ""
-	DefaultRegion      = "us-east-1"
+	DefaultRegion      = awsutil.DefaultRegion
 	DefaultAccessKey   = ""
 	DefaultSecretKey   = ""
 	DefaultContentType = mvt.MimeType
@@ -70,6 +73,9 @@ func init() {
 // 		basepath (string): a path prefix added to all cache operations inside of the S3 bucket
 // 		max_zoom (int): max zoom to use the cache. beyond this zoom cache Set() calls will be ignored
 // 		endpoint (string): the endpoint where the S3 compliant backend is located. only necessary for non-AWS deployments. defaults to ''
+// 		force_path_style (bool): use path style addressing (endpoint/bucket/key). required by most S3 compatible backends (i.e. MinIO). defaults to false
+// 		insecure_skip_verify (bool): skip TLS certificate verification for the endpoint. defaults to false
+// 		ca_cert (string): path to a PEM encoded CA bundle used to verify a self-signed endpoint certificate. defaults to ''
 //  	access_control_list (string): the S3 access control to set on the file when putting the file. defaults to ''.
 //  	cache_control (string): the http cache-control header to set on the file when putting the file. defaults to ''.
 //  	content_type (string): the http MIME-type set on the file when putting the file. defaults to 'application/vnd.mapbox-vector-tile'.
@@ -103,60 +109,21 @@ func New(config dict.Dicter) (cache.Interface, error) {
 		return nil, err
 	}
 
-	// check for region env var
-	region := os.Getenv("AWS_REGION")
-	if region == "" {
-		region = DefaultRegion
-	}
-	region, err = config.String(ConfigKeyRegion, &region)
-	if err != nil {
-		return nil, err
-	}
-
-	accessKey := DefaultAccessKey
-	accessKey, err = config.String(ConfigKeyAWSAccessKeyID, &accessKey)
-	if err != nil {
-		return nil, err
-	}
-	secretKey := DefaultSecretKey
-	secretKey, err = config.String(ConfigKeyAWSSecretKey, &secretKey)
+	// connection options (region, endpoint, credentials, path style addressing and TLS)
+	awsConfig, err := awsutil.ConfigFromDict(config)
 	if err != nil {
 		return nil, err
 	}
 
-	awsConfig := aws.Config{
-		Region: aws.String(region),
-	}
-
-	// check for endpoint env var
-	endpoint := os.Getenv("AWS_ENDPOINT")
-	if endpoint == "" {
-		endpoint = DefaultEndpoint
-	}
-
-	endpoint, err = config.String(ConfigKeyEndpoint, &endpoint)
+	sess, err := awsConfig.Session()
 	if err != nil {
 		return nil, err
 	}
 
-	// support for static credentials, this is not recommended by AWS but
-	// necessary for some environments
-	if accessKey != "" && secretKey != "" {
-		awsConfig.Credentials = credentials.NewStaticCredentials(accessKey, secretKey, "")
-	}
-
-	// if an endpoint is set, add it to the awsConfig
-	// otherwise do not set it and it will automatically use the correct aws-s3 endpoint
-	if endpoint != "" {
-		awsConfig.Endpoint = aws.String(endpoint)
-	}
-
-	// setup the s3 session.
+	// setup the s3 client.
 	// if the accessKey and secreteKey are not provided (static creds) then the provider chain is used
 	// http://docs.aws.amazon.com/sdk-for-go/v1/developer-guide/configuring-sdk.html
-	s3cache.Client = s3.New(
-		session.New(&awsConfig),
-	)
+	s3cache.Client = s3.New(sess)
 
 	// check for control_access_list env var
 	acl := os.Getenv("AWS_ACL")
@@ -343,3 +310,30 @@ func (s3c *Cache) Purge(key *cache.Key) error {
 
 	return nil
 }
+
+// ListKeys adheres to the cache.Lister interface. The keys under the basepath are listed.
+func (s3c *Cache) ListKeys(fn func(path string) error) error {
+	prefix := ""
+	if s3c.Basepath != "" {
+		prefix = strings.TrimSuffix(filepath.ToSlash(s3c.Basepath), "/") + "/"
+	}
+
+	input := s3.ListObjectsV2Input{
+		Bucket: aws.String(s3c.Bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	var fnErr error
+	err := s3c.Client.ListObjectsV2Pages(&input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			if fnErr = fn(strings.TrimPrefix(aws.StringValue(obj.Key), prefix)); fnErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
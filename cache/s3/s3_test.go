@@ -343,3 +343,54 @@ func TestMaxZoom(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+// skipMinIOTests will check the environment to see if the MinIO tests should be skipped
+func skipMinIOTests(t *testing.T) {
+	if strings.TrimSpace(strings.ToLower(os.Getenv("RUN_MINIO_TESTS"))) != "yes" {
+		t.Skipf("skipping %v, RUN_MINIO_TESTS not set to 'yes'", t.Name())
+	}
+}
+
+func TestMinIO(t *testing.T) {
+	skipMinIOTests(t)
+
+	config := dict.Dict{
+		"bucket":                os.Getenv("MINIO_TEST_BUCKET"),
+		"endpoint":              os.Getenv("MINIO_ENDPOINT"),
+		"aws_access_key_id":     os.Getenv("MINIO_ACCESS_KEY"),
+		"aws_secret_access_key": os.Getenv("MINIO_SECRET_KEY"),
+		"force_path_style":      true,
+		"basepath":              "cache",
+	}
+
+	fc, err := s3.New(config)
+	if err != nil {
+		t.Fatalf("new failed. err: %v", err)
+	}
+
+	key := cache.Key{
+		MapName: "test-map",
+		Z:       0,
+		X:       1,
+		Y:       2,
+	}
+
+	if err = fc.Set(&key, testData); err != nil {
+		t.Fatalf("write failed. err: %v", err)
+	}
+
+	output, hit, err := fc.Get(&key)
+	if err != nil {
+		t.Fatalf("read failed. err: %v", err)
+	}
+	if !hit {
+		t.Fatalf("read failed. should have been a hit but cache reported a miss")
+	}
+	if !reflect.DeepEqual(output, testData) {
+		t.Errorf("expected %v got %v", testData, output)
+	}
+
+	if err = fc.Purge(&key); err != nil {
+		t.Errorf("purge failed. err: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package tiered
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const CacheType = "tiered"
+
+const (
+	ConfigKeyTiers   = "tiers"
+	ConfigKeyType    = "type"
+	ConfigKeyMaxZoom = "max_zoom"
+)
+
+var (
+	ErrMissingTiers = errors.New("tieredcache: at least one of 'tiers' is required")
+	ErrNestedTiered = errors.New("tieredcache: a tier can't be a tiered cache")
+)
+
+func init() {
+	cache.Register(CacheType, New)
+}
+
+// New instantiates a Cache. The config expects the following params:
+//
+// 	tiers ([]map): the configs of the backends, from the fastest to the slowest. Each tier is
+// 		configured as a cache of its type, along with:
+// 		type (string): the cache type of the tier
+// 		max_zoom (int): max zoom of the tiles read from and written to the tier
+//
+func New(config dict.Dicter) (cache.Interface, error) {
+	tiers, err := config.MapSlice(ConfigKeyTiers)
+	if err != nil {
+		return nil, err
+	}
+	if len(tiers) == 0 {
+		return nil, ErrMissingTiers
+	}
+
+	tc := Cache{}
+	for i, tier := range tiers {
+		cType, err := tier.String(ConfigKeyType, nil)
+		if err != nil {
+			return nil, fmt.Errorf("tieredcache: tier %v: %w", i, err)
+		}
+		if cType == CacheType {
+			return nil, ErrNestedTiered
+		}
+
+		defaultMaxZoom := uint(tegola.MaxZ)
+		maxZoom, err := tier.Uint(ConfigKeyMaxZoom, &defaultMaxZoom)
+		if err != nil {
+			return nil, fmt.Errorf("tieredcache: tier %v: %w", i, err)
+		}
+
+		c, err := cache.For(cType, tier)
+		if err != nil {
+			return nil, fmt.Errorf("tieredcache: tier %v (%v): %w", i, cType, err)
+		}
+
+		tc.Tiers = append(tc.Tiers, Tier{
+			Interface: c,
+			Name:      fmt.Sprintf("%v (%v)", i, cType),
+			MaxZoom:   maxZoom,
+		})
+	}
+
+	return &tc, nil
+}
+
+// Tier is a backend of the tiered cache
+type Tier struct {
+	cache.Interface
+	// Name of the tier in logs, its position and type
+	Name string
+	// MaxZoom of the tiles read from and written to the tier
+	MaxZoom uint
+}
+
+// Cache chains the backends of its tiers, from the fastest to the slowest. Tiles are read
+// from the first tier holding them, and the faster tiers which missed them are back-filled
+// in the background. Tiles are written to, and purged from, every tier.
+type Cache struct {
+	Tiers []Tier
+
+	// the pending back-fills
+	backfills sync.WaitGroup
+}
+
+// tiers returns the tiers of the key's zoom
+func (tc *Cache) tiers(key *cache.Key) []Tier {
+	tiers := make([]Tier, 0, len(tc.Tiers))
+	for _, t := range tc.Tiers {
+		if key.Z <= t.MaxZoom {
+			tiers = append(tiers, t)
+		}
+	}
+	return tiers
+}
+
+// Get reads the tile from the first tier holding it. The errors of a tier are logged and
+// the next tier is read, and only returned when no tier holds the tile.
+func (tc *Cache) Get(key *cache.Key) ([]byte, bool, error) {
+	var firstErr error
+
+	tiers := tc.tiers(key)
+	for i, t := range tiers {
+		val, hit, err := t.Get(key)
+		if err != nil {
+			log.Warnf("tieredcache: tier %v error reading %v: %v", t.Name, key, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if !hit {
+			continue
+		}
+
+		if i > 0 {
+			tc.backfill(tiers[:i], key, val)
+		}
+		return val, true, nil
+	}
+
+	return nil, false, firstErr
+}
+
+// backfill writes the tile to the tiers in the background. The tiers' own ttls apply to
+// the tile, as the expiry of a tile is not known when it's read.
+func (tc *Cache) backfill(tiers []Tier, key *cache.Key, val []byte) {
+	k := *key
+	tc.backfills.Add(1)
+	go func() {
+		defer tc.backfills.Done()
+		for _, t := range tiers {
+			if err := t.Set(&k, val); err != nil {
+				log.Warnf("tieredcache: tier %v error back-filling %v: %v", t.Name, &k, err)
+			}
+		}
+	}()
+}
+
+// Wait blocks until the pending back-fills are done
+func (tc *Cache) Wait() {
+	tc.backfills.Wait()
+}
+
+// Set writes the tile to every tier of its zoom
+func (tc *Cache) Set(key *cache.Key, val []byte) error {
+	return tc.each(key, func(t Tier) error {
+		return t.Set(key, val)
+	})
+}
+
+// SetWithTTL adheres to the cache.TTLSetter interface. Tiers which can't expire their
+// tiles don't cache the tile.
+func (tc *Cache) SetWithTTL(key *cache.Key, val []byte, ttl time.Duration) error {
+	expires := time.Now().Add(ttl)
+	return tc.each(key, func(t Tier) error {
+		return cache.SetExpires(t.Interface, key, val, expires)
+	})
+}
+
+// Purge purges the tile from every tier, as the tile may have been written to the tiers
+// at a different max zoom
+func (tc *Cache) Purge(key *cache.Key) error {
+	var firstErr error
+	for _, t := range tc.Tiers {
+		if err := t.Purge(key); err != nil {
+			log.Warnf("tieredcache: tier %v error purging %v: %v", t.Name, key, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// PurgeLocal purges the tile from the tiers kept in the memory of the process
+func (tc *Cache) PurgeLocal(key *cache.Key) error {
+	var firstErr error
+	for _, t := range tc.Tiers {
+		if err := cache.PurgeLocal(t.Interface, key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// each calls fn with the tiers of the key's zoom, returning the first error once every tier is written
+func (tc *Cache) each(key *cache.Key, fn func(Tier) error) error {
+	var firstErr error
+	for _, t := range tc.tiers(key) {
+		if err := fn(t); err != nil {
+			log.Warnf("tieredcache: tier %v error writing %v: %v", t.Name, key, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// ListKeys adheres to the cache.Lister interface. The keys of every tier are listed once,
+// so every tier must be listable.
+func (tc *Cache) ListKeys(fn func(path string) error) error {
+	listers := make([]cache.Lister, len(tc.Tiers))
+	for i, t := range tc.Tiers {
+		lister, ok := t.Interface.(cache.Lister)
+		if !ok {
+			return cache.ErrNotListable
+		}
+		listers[i] = lister
+	}
+
+	seen := map[string]struct{}{}
+	for _, lister := range listers {
+		err := lister.ListKeys(func(path string) error {
+			if _, ok := seen[path]; ok {
+				return nil
+			}
+			seen[path] = struct{}{}
+			return fn(path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package tiered_test
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/cache"
+	_ "github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/cache/tiered"
+	"github.com/go-spatial/tegola/dict"
+)
+
+func TestNew(t *testing.T) {
+	type tcase struct {
+		config    dict.Dict
+		maxZooms  []uint
+		expectErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			c, err := tiered.New(tc.config)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Fatalf("error, expected %v got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var maxZooms []uint
+			for _, tier := range c.(*tiered.Cache).Tiers {
+				maxZooms = append(maxZooms, tier.MaxZoom)
+			}
+			if !reflect.DeepEqual(maxZooms, tc.maxZooms) {
+				t.Errorf("max zooms, expected %v got %v", tc.maxZooms, maxZooms)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"tiers": {
+			config: dict.Dict{
+				"tiers": []map[string]interface{}{
+					{"type": "memory", "max_zoom": uint(10)},
+					{"type": "memory"},
+				},
+			},
+			maxZooms: []uint{10, 22},
+		},
+		"no tiers": {
+			config:    dict.Dict{},
+			expectErr: tiered.ErrMissingTiers,
+		},
+		"nested": {
+			config: dict.Dict{
+				"tiers": []map[string]interface{}{
+					{"type": "tiered"},
+				},
+			},
+			expectErr: tiered.ErrNestedTiered,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func newTiered(t *testing.T) *tiered.Cache {
+	t.Helper()
+
+	c, err := tiered.New(dict.Dict{
+		"tiers": []map[string]interface{}{
+			{"type": "memory", "max_zoom": uint(10)},
+			{"type": "memory"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c.(*tiered.Cache)
+}
+
+func TestReadThrough(t *testing.T) {
+	tc := newTiered(t)
+	fast, slow := tc.Tiers[0], tc.Tiers[1]
+
+	key := &cache.Key{MapName: "m", Z: 5, X: 1, Y: 2}
+	deep := &cache.Key{MapName: "m", Z: 12, X: 1, Y: 2}
+
+	// only the slow tier holds the tiles
+	slow.Set(key, []byte("tile"))
+	slow.Set(deep, []byte("deep"))
+
+	val, hit, err := tc.Get(key)
+	if err != nil || !hit || string(val) != "tile" {
+		t.Fatalf("expected a hit of tile, got %v %s %v", hit, val, err)
+	}
+	tc.Wait()
+	if val, hit, _ := fast.Get(key); !hit || string(val) != "tile" {
+		t.Errorf("expected the fast tier to be back-filled, got %v %s", hit, val)
+	}
+
+	// beyond the fast tier's max zoom
+	if _, hit, _ := tc.Get(deep); !hit {
+		t.Fatalf("expected a hit of the deep tile")
+	}
+	tc.Wait()
+	if _, hit, _ := fast.Get(deep); hit {
+		t.Errorf("expected the fast tier not to be back-filled beyond its max zoom")
+	}
+
+	// misses
+	if _, hit, err := tc.Get(&cache.Key{MapName: "m", Z: 1}); hit || err != nil {
+		t.Errorf("expected a miss, got %v %v", hit, err)
+	}
+}
+
+func TestWriteThrough(t *testing.T) {
+	tc := newTiered(t)
+	fast, slow := tc.Tiers[0], tc.Tiers[1]
+
+	key := &cache.Key{MapName: "m", Z: 5}
+	deep := &cache.Key{MapName: "m", Z: 12}
+	expiring := &cache.Key{MapName: "m", Z: 6}
+
+	tc.Set(key, []byte("tile"))
+	tc.Set(deep, []byte("deep"))
+	tc.SetWithTTL(expiring, []byte("expiring"), time.Hour)
+
+	for _, tier := range []tiered.Tier{fast, slow} {
+		for _, k := range []*cache.Key{key, expiring} {
+			if _, hit, _ := tier.Get(k); !hit {
+				t.Errorf("tier %v, expected a hit of %v", tier.Name, k)
+			}
+		}
+	}
+	if _, hit, _ := fast.Get(deep); hit {
+		t.Errorf("expected the fast tier not to be written beyond its max zoom")
+	}
+	if _, hit, _ := slow.Get(deep); !hit {
+		t.Errorf("expected the slow tier to be written beyond the fast tier's max zoom")
+	}
+
+	var keys []string
+	err := tc.ListKeys(func(path string) error {
+		keys = append(keys, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if exp := []string{"m/12/0/0", "m/5/0/0", "m/6/0/0"}; !reflect.DeepEqual(keys, exp) {
+		t.Errorf("keys, expected %v got %v", exp, keys)
+	}
+
+	tc.Purge(key)
+	for _, tier := range []tiered.Tier{fast, slow} {
+		if _, hit, _ := tier.Get(key); hit {
+			t.Errorf("tier %v, expected the tile to be purged", tier.Name)
+		}
+	}
+}
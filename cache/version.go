@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"strings"
+	"time"
+)
+
+// VersionSeparator separates the map name and the cache version of the map in the keys
+const VersionSeparator = "@"
+
+// Versioned appends the cache version of the maps to the map name of the keys, so bumping
+// the version of a map invalidates its tiles without deleting them, and rolling the version
+// back serves the tiles cached with it again. Keys of maps without a version are unchanged.
+type Versioned struct {
+	Interface
+	// Version returns the cache version of the map, "" for none
+	Version func(mapName string) string
+}
+
+// NewVersioned wraps the cache backend so the keys of the maps include their version
+func NewVersioned(c Interface, version func(mapName string) string) *Versioned {
+	return &Versioned{Interface: c, Version: version}
+}
+
+// key returns the key of the map's version
+func (v *Versioned) key(key *Key) *Key {
+	version := v.Version(key.MapName)
+	if version == "" {
+		return key
+	}
+	k := *key
+	k.MapName += VersionSeparator + version
+	return &k
+}
+
+func (v *Versioned) Get(key *Key) ([]byte, bool, error) {
+	return v.Interface.Get(v.key(key))
+}
+
+func (v *Versioned) Set(key *Key, val []byte) error {
+	return v.Interface.Set(v.key(key), val)
+}
+
+func (v *Versioned) Purge(key *Key) error {
+	return v.Interface.Purge(v.key(key))
+}
+
+func (v *Versioned) PurgeLocal(key *Key) error {
+	return PurgeLocal(v.Interface, v.key(key))
+}
+
+// SetWithTTL sets the value with the expiration support of the wrapped backend
+func (v *Versioned) SetWithTTL(key *Key, val []byte, ttl time.Duration) error {
+	return SetExpires(v.Interface, v.key(key), val, time.Now().Add(ttl))
+}
+
+// ListKeys lists the keys of the wrapped backend. The keys of the maps' current version are
+// listed without their version, the keys of other versions are listed as is, so they are
+// not mistaken for the map's tiles. The keys a versioned map cached before it was versioned
+// can't be addressed through the wrapper and are not listed.
+func (v *Versioned) ListKeys(fn func(path string) error) error {
+	lister, ok := v.Interface.(Lister)
+	if !ok {
+		return ErrNotListable
+	}
+
+	return lister.ListKeys(func(p string) error {
+		mapName, rest := p, ""
+		if i := strings.Index(p, "/"); i >= 0 {
+			mapName, rest = p[:i], p[i:]
+		}
+
+		i := strings.LastIndex(mapName, VersionSeparator)
+		if i < 0 {
+			if v.Version(mapName) != "" {
+				return nil
+			}
+			return fn(p)
+		}
+		if version := v.Version(mapName[:i]); version != "" && version == mapName[i+1:] {
+			return fn(mapName[:i] + rest)
+		}
+		return fn(p)
+	})
+}
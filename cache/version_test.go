@@ -0,0 +1,59 @@
+package cache_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+func TestVersioned(t *testing.T) {
+	key := cache.Key{MapName: "osm", Z: 1, X: 1, Y: 0}
+	other := cache.Key{MapName: "other", Z: 1, X: 1, Y: 0}
+
+	versions := map[string]string{"osm": "v1"}
+	mc, _ := memory.New(nil)
+	vc := cache.NewVersioned(mc, func(name string) string { return versions[name] })
+
+	vc.Set(&key, []byte("v1"))
+	vc.Set(&other, []byte("other"))
+
+	if _, hit, _ := mc.Get(&cache.Key{MapName: "osm@v1", Z: 1, X: 1, Y: 0}); !hit {
+		t.Errorf("backend, expected the version to be appended to the map name")
+	}
+	if _, hit, _ := mc.Get(&other); !hit {
+		t.Errorf("backend, expected the key of an unversioned map to be unchanged")
+	}
+
+	// bumping the version invalidates the map's tiles
+	versions["osm"] = "v2"
+	if _, hit, _ := vc.Get(&key); hit {
+		t.Errorf("bumped version, expected miss")
+	}
+	vc.Set(&key, []byte("v2"))
+
+	var keys []string
+	vc.ListKeys(func(p string) error {
+		keys = append(keys, p)
+		return nil
+	})
+	sort.Strings(keys)
+	// the tiles of other versions are listed with their version
+	if exp := []string{"osm/1/1/0", "osm@v1/1/1/0", "other/1/1/0"}; !reflect.DeepEqual(keys, exp) {
+		t.Errorf("keys, expected %v got %v", exp, keys)
+	}
+
+	// rolling back serves the tiles of the version again
+	versions["osm"] = "v1"
+	if val, hit, _ := vc.Get(&key); !hit || string(val) != "v1" {
+		t.Errorf("rolled back version, expected hit of v1 got %v %s", hit, val)
+	}
+
+	// the listed keys of other versions address their tiles
+	vc.Purge(&cache.Key{MapName: "osm@v2", Z: 1, X: 1, Y: 0})
+	if _, hit, _ := mc.Get(&cache.Key{MapName: "osm@v2", Z: 1, X: 1, Y: 0}); hit {
+		t.Errorf("purged other version, expected miss")
+	}
+}
@@ -0,0 +1,68 @@
+package register
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-spatial/tegola/apikey"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/dict"
+
+	// the api key stores register themselves
+	_ "github.com/go-spatial/tegola/apikey/postgres"
+)
+
+const (
+	ConfigKeyAPIKeysRequired        = "required"
+	ConfigKeyAPIKeysRefreshInterval = "refresh_interval"
+)
+
+var (
+	ErrAPIKeysTypeMissing  = errors.New("register: api_keys 'type' parameter missing")
+	ErrAPIKeysTypeInvalid  = errors.New("register: api_keys 'type' value must be a string")
+	ErrAPIKeysCacheMissing = errors.New("register: api_keys of type 'cache' require a cache")
+)
+
+// APIKeys registers the store of the API keys and loads its keys. The keys of the "cache"
+// store are kept in the cache backend c.
+func APIKeys(config dict.Dicter, c cache.Interface) (*apikey.Keys, error) {
+	storeType, err := config.String("type", nil)
+	if err != nil {
+		switch err.(type) {
+		case dict.ErrKeyRequired:
+			return nil, ErrAPIKeysTypeMissing
+		case dict.ErrKeyType:
+			return nil, ErrAPIKeysTypeInvalid
+		default:
+			return nil, err
+		}
+	}
+
+	required := false
+	if required, err = config.Bool(ConfigKeyAPIKeysRequired, &required); err != nil {
+		return nil, err
+	}
+	interval := int(apikey.DefaultRefreshInterval / time.Second)
+	if interval, err = config.Int(ConfigKeyAPIKeysRefreshInterval, &interval); err != nil {
+		return nil, err
+	}
+
+	var store apikey.Store
+	if storeType == apikey.CacheStoreType {
+		if c == nil {
+			return nil, ErrAPIKeysCacheMissing
+		}
+		store = apikey.NewCacheStore(c)
+	} else if store, err = apikey.For(storeType, config); err != nil {
+		return nil, err
+	}
+
+	keys := apikey.New(store)
+	keys.Required = required
+	keys.RefreshInterval = time.Duration(interval) * time.Second
+	if err := keys.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
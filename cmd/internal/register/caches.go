@@ -2,8 +2,10 @@ package register
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/config"
 	"github.com/go-spatial/tegola/dict"
 )
 
@@ -27,5 +29,87 @@ func Cache(config dict.Dicter) (cache.Interface, error) {
 	}
 
 	// register the provider
-	return cache.For(cType, config)
+	c, err := cache.For(cType, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// checksum the tiles, so truncated and corrupted tiles are rendered again instead of served
+	checksum := false
+	if checksum, err = config.Bool(cache.ConfigKeyChecksum, &checksum); err != nil {
+		return nil, err
+	}
+	if checksum {
+		c = cache.NewChecksummed(c)
+	}
+
+	// encrypt the tiles, so they can't be read from the backend's storage without the key
+	encryptionKey := ""
+	if encryptionKey, err = config.String(cache.ConfigKeyEncryptionKey, &encryptionKey); err != nil {
+		return nil, err
+	}
+	if encryptionKey != "" {
+		source := cache.KeySourceBase64
+		if source, err = config.String(cache.ConfigKeyEncryptionKeySource, &source); err != nil {
+			return nil, err
+		}
+		key, err := cache.EncryptionKeyFor(source, encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := cache.NewEncrypted(c, key)
+		if err != nil {
+			return nil, err
+		}
+		c = encrypted
+	}
+
+	// hash the keys, so listings of the backend don't reveal the requested areas
+	keyHash := ""
+	if keyHash, err = config.String(cache.ConfigKeyKeyHash, &keyHash); err != nil {
+		return nil, err
+	}
+	if keyHash != "" {
+		secret := ""
+		if secret, err = config.String(cache.ConfigKeyKeyHashSecret, &secret); err != nil {
+			return nil, err
+		}
+		hasher, err := cache.KeyHasherFor(keyHash, secret)
+		if err != nil {
+			return nil, err
+		}
+		c = cache.NewHashed(c, hasher)
+	}
+
+	// prefix the keys with the deployment's namespace
+	namespace := ""
+	if namespace, err = config.String(cache.ConfigKeyNamespace, &namespace); err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		return c, nil
+	}
+	return cache.NewNamespace(c, namespace)
+}
+
+// MapCaches registers the cache backends of the maps configured with their own cache. The
+// returned cache routes the tiles of those maps to their backend, and the tiles of the other
+// maps to defaultCache, which is nil when only maps with their own cache are cached.
+func MapCaches(defaultCache cache.Interface, maps []config.Map) (cache.Interface, error) {
+	backends := map[string]cache.Interface{}
+	for _, m := range maps {
+		if len(m.Cache) == 0 {
+			continue
+		}
+		c, err := Cache(m.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("map (%v): %w", m.Name, err)
+		}
+		backends[string(m.Name)] = c
+	}
+
+	if len(backends) == 0 {
+		return defaultCache, nil
+	}
+	return cache.NewByMap(defaultCache, backends), nil
 }
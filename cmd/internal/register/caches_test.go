@@ -1,10 +1,14 @@
 package register_test
 
 import (
+	"os"
 	"testing"
 
+	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/cmd/internal/register"
+	"github.com/go-spatial/tegola/config"
 	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/env"
 )
 
 func TestCaches(t *testing.T) {
@@ -43,9 +47,117 @@ func TestCaches(t *testing.T) {
 			},
 			expectedErr: register.ErrCacheTypeInvalid,
 		},
+
+		"namespace": {
+			config: dict.Dict{
+				"type":      "file",
+				"basepath":  os.TempDir(),
+				"namespace": "us-east-1",
+			},
+		},
+
+		"invalid namespace": {
+			config: dict.Dict{
+				"type":      "file",
+				"basepath":  os.TempDir(),
+				"namespace": "us/east",
+			},
+			expectedErr: cache.ErrInvalidNamespace{Namespace: "us/east"},
+		},
+
+		"key hash": {
+			config: dict.Dict{
+				"type":            "file",
+				"basepath":        os.TempDir(),
+				"key_hash":        "hmac-sha256",
+				"key_hash_secret": "secret",
+			},
+		},
+
+		"key hash without secret": {
+			config: dict.Dict{
+				"type":     "file",
+				"basepath": os.TempDir(),
+				"key_hash": "hmac-sha256",
+			},
+			expectedErr: cache.ErrKeyHashSecretMissing,
+		},
+
+		"unknown key hash": {
+			config: dict.Dict{
+				"type":            "file",
+				"basepath":        os.TempDir(),
+				"key_hash":        "md5",
+				"key_hash_secret": "secret",
+			},
+			expectedErr: cache.ErrUnknownKeyHasher{Name: "md5"},
+		},
+
+		"encryption key": {
+			config: dict.Dict{
+				"type":           "file",
+				"basepath":       os.TempDir(),
+				"encryption_key": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+			},
+		},
+
+		"invalid encryption key": {
+			config: dict.Dict{
+				"type":           "file",
+				"basepath":       os.TempDir(),
+				"encryption_key": "c2hvcnQ=",
+			},
+			expectedErr: cache.ErrInvalidEncryptionKey,
+		},
+
+		"unknown encryption key source": {
+			config: dict.Dict{
+				"type":                  "file",
+				"basepath":              os.TempDir(),
+				"encryption_key":        "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+				"encryption_key_source": "kms",
+			},
+			expectedErr: cache.ErrUnknownKeySource{Name: "kms"},
+		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestMapCaches(t *testing.T) {
+	defaultCache, err := register.Cache(dict.Dict{"type": "memory"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	// without map caches the default cache is used as is
+	c, err := register.MapCaches(defaultCache, []config.Map{{Name: "basemap"}})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if c != defaultCache {
+		t.Errorf("expected the default cache, got %T", c)
+	}
+
+	maps := []config.Map{
+		{Name: "basemap"},
+		{Name: "live", Cache: env.Dict{"type": "memory"}},
+	}
+	c, err = register.MapCaches(nil, maps)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cache.MapBackend(c, "basemap") != nil {
+		t.Errorf("expected no backend for the map without a cache")
+	}
+	if cache.MapBackend(c, "live") == nil {
+		t.Errorf("expected a backend for the map with a cache")
+	}
+
+	maps[1].Cache = env.Dict{"type": 1}
+	if _, err = register.MapCaches(nil, maps); err == nil {
+		t.Errorf("expected an error for an invalid map cache")
+	}
+}
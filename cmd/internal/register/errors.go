@@ -1,6 +1,11 @@
 package register
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-spatial/tegola/atlas"
+)
 
 // ErrProviderLayerInvalid should be returned when an invalid Provider layer for a map is given
 type ErrProviderLayerInvalid struct {
@@ -51,5 +56,106 @@ type ErrDefaultTagsInvalid struct {
 
 func (e ErrDefaultTagsInvalid) Unwrap() error { return e.Err }
 func (e ErrDefaultTagsInvalid) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("'default_tags' for 'provider_layer' (%v) are invalid: %v", e.ProviderLayer, e.Err)
+	}
 	return fmt.Sprintf("'default_tags' for 'provider_layer' (%v) should be a TOML table", e.ProviderLayer)
 }
+
+// ErrGeometryAttributesInvalid should be returned when the geometry_attributes value is not supported.
+type ErrGeometryAttributesInvalid struct {
+	ProviderLayer string
+	Err           error
+}
+
+func (e ErrGeometryAttributesInvalid) Unwrap() error { return e.Err }
+func (e ErrGeometryAttributesInvalid) Error() string {
+	return fmt.Sprintf("'geometry_attributes' for 'provider_layer' (%v) is invalid: %v", e.ProviderLayer, e.Err)
+}
+
+// ErrGridInvalid should be returned when the grid of a map is invalid.
+type ErrGridInvalid struct {
+	Map string
+	Err error
+}
+
+func (e ErrGridInvalid) Unwrap() error { return e.Err }
+func (e ErrGridInvalid) Error() string {
+	return fmt.Sprintf("map (%v) 'grid' is invalid: %v", e.Map, e.Err)
+}
+
+// ErrTagTransformInvalid should be returned when the tag_transform of a map layer is invalid.
+type ErrTagTransformInvalid struct {
+	ProviderLayer string
+	Err           error
+}
+
+func (e ErrTagTransformInvalid) Unwrap() error { return e.Err }
+func (e ErrTagTransformInvalid) Error() string {
+	return fmt.Sprintf("'tag_transform' for 'provider_layer' (%v) is invalid: %v", e.ProviderLayer, e.Err)
+}
+
+// ErrMVTProviderVersion should be returned when a map using an MVT provider is configured with a different 'mvt_version'.
+type ErrMVTProviderVersion struct {
+	Map     string
+	Version uint
+}
+
+func (e ErrMVTProviderVersion) Error() string {
+	return fmt.Sprintf("map (%v) uses an MVT provider which only supports 'mvt_version' %v, got %v", e.Map, atlas.MVTProviderVersion, e.Version)
+}
+
+// ErrUpstreamWithLayers should be returned when a map is configured with both an 'upstream' and 'layers'.
+type ErrUpstreamWithLayers struct {
+	Map string
+}
+
+func (e ErrUpstreamWithLayers) Error() string {
+	return fmt.Sprintf("map (%v) has an 'upstream' and 'layers', upstream maps can't have layers", e.Map)
+}
+
+// ErrUpstreamInvalid should be returned when the 'upstream' config of a map is invalid.
+type ErrUpstreamInvalid struct {
+	Map string
+	Err error
+}
+
+func (e ErrUpstreamInvalid) Unwrap() error { return e.Err }
+func (e ErrUpstreamInvalid) Error() string {
+	return fmt.Sprintf("'upstream' for map (%v) is invalid: %v", e.Map, e.Err)
+}
+
+// ErrUpstreamWithRaster should be returned when a map is configured with both an 'upstream' and a 'raster'.
+type ErrUpstreamWithRaster struct {
+	Map string
+}
+
+func (e ErrUpstreamWithRaster) Error() string {
+	return fmt.Sprintf("map (%v) has an 'upstream' and a 'raster', upstream maps can't have a raster", e.Map)
+}
+
+// ErrRasterInvalid should be returned when the 'raster' config of a map is invalid.
+type ErrRasterInvalid struct {
+	Map string
+	Err error
+}
+
+func (e ErrRasterInvalid) Unwrap() error { return e.Err }
+func (e ErrRasterInvalid) Error() string {
+	return fmt.Sprintf("'raster' for map (%v) is invalid: %v", e.Map, e.Err)
+}
+
+// ErrGeofenceGeometryType is returned when a geofence geometry is not a polygon or multipolygon
+var ErrGeofenceGeometryType = errors.New("geometry must be a Polygon or MultiPolygon")
+
+// ErrGeofenceGeometryInvalid is returned when the geometry of a geofence can't be read
+type ErrGeofenceGeometryInvalid struct {
+	Geofence string
+	Err      error
+}
+
+func (e ErrGeofenceGeometryInvalid) Error() string {
+	return fmt.Sprintf("geofence (%v) has an invalid geometry: %v", e.Geofence, e.Err)
+}
+
+func (e ErrGeofenceGeometryInvalid) Unwrap() error { return e.Err }
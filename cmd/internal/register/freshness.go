@@ -0,0 +1,37 @@
+package register
+
+import (
+	"time"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/config"
+)
+
+// FreshnessMonitor creates the monitor of the freshness SLAs of the atlas' map layers
+func FreshnessMonitor(a *atlas.Atlas, cfg config.Freshness) *atlas.FreshnessMonitor {
+	fm := atlas.FreshnessMonitor{
+		Atlas:      a,
+		Interval:   atlas.DefaultFreshnessInterval,
+		WebhookURL: string(cfg.WebhookURL),
+	}
+	if cfg.Interval != nil && *cfg.Interval > 0 {
+		fm.Interval = time.Duration(*cfg.Interval) * time.Second
+	}
+	return &fm
+}
+
+// Reseeder creates the re-seeder of the cached tiles of the atlas' changed map layers
+func Reseeder(a *atlas.Atlas, cfg config.Reseed) *atlas.Reseeder {
+	rs := atlas.Reseeder{
+		Atlas:    a,
+		Interval: atlas.DefaultReseedInterval,
+		MaxTiles: atlas.DefaultReseedMaxTiles,
+	}
+	if cfg.Interval != nil && *cfg.Interval > 0 {
+		rs.Interval = time.Duration(*cfg.Interval) * time.Second
+	}
+	if cfg.MaxTiles != nil && *cfg.MaxTiles > 0 {
+		rs.MaxTiles = int(*cfg.MaxTiles)
+	}
+	return &rs
+}
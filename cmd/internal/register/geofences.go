@@ -0,0 +1,85 @@
+package register
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+
+	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/server"
+)
+
+// Geofences converts the config's geofences for the server. The regions are read from the
+// bounds or the GeoJSON geometry.
+func Geofences(geofences []config.Geofence) ([]server.Geofence, error) {
+	fences := make([]server.Geofence, 0, len(geofences))
+	for _, cfg := range geofences {
+		g := server.Geofence{
+			Name:  string(cfg.Name),
+			Block: !strings.EqualFold(string(cfg.Action), config.GeofenceActionLog),
+		}
+		if cfg.MinZoom != nil {
+			g.MinZoom = uint(*cfg.MinZoom)
+		}
+		for _, m := range cfg.Maps {
+			g.Maps = append(g.Maps, string(m))
+		}
+		for _, c := range cfg.KeyClasses {
+			g.KeyClasses = append(g.KeyClasses, string(c))
+		}
+
+		if len(cfg.Bounds) == 4 {
+			g.Extent = *geom.NewExtent(
+				[2]float64{float64(cfg.Bounds[0]), float64(cfg.Bounds[1])},
+				[2]float64{float64(cfg.Bounds[2]), float64(cfg.Bounds[3])},
+			)
+		}
+
+		if cfg.Geometry != "" {
+			var gg geojson.Geometry
+			if err := json.Unmarshal([]byte(cfg.Geometry), &gg); err != nil {
+				return nil, ErrGeofenceGeometryInvalid{Geofence: g.Name, Err: err}
+			}
+			switch geo := gg.Geometry.(type) {
+			case geom.Polygon:
+				g.Polygons = []geom.Polygon{geo}
+			case geom.MultiPolygon:
+				for _, p := range geo {
+					g.Polygons = append(g.Polygons, p)
+				}
+			default:
+				return nil, ErrGeofenceGeometryInvalid{Geofence: g.Name, Err: ErrGeofenceGeometryType}
+			}
+
+			ext, err := geom.NewExtentFromGeometry(multiPolygon(g.Polygons))
+			if err != nil {
+				return nil, ErrGeofenceGeometryInvalid{Geofence: g.Name, Err: err}
+			}
+			g.Extent = *ext
+		}
+
+		fences = append(fences, g)
+	}
+	return fences, nil
+}
+
+func multiPolygon(polygons []geom.Polygon) geom.MultiPolygon {
+	mp := make(geom.MultiPolygon, len(polygons))
+	for i := range polygons {
+		mp[i] = polygons[i]
+	}
+	return mp
+}
+
+// KeyClasses maps the API keys of the config's key classes to their class
+func KeyClasses(classes []config.KeyClass) map[string]string {
+	keys := map[string]string{}
+	for _, c := range classes {
+		for _, k := range c.Keys {
+			keys[string(k)] = string(c.Name)
+		}
+	}
+	return keys
+}
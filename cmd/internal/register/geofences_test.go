@@ -0,0 +1,87 @@
+package register_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/cmd/internal/register"
+	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/internal/env"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestGeofences(t *testing.T) {
+	type tcase struct {
+		config      []config.Geofence
+		expected    []server.Geofence
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			got, err := register.Geofences(tc.config)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("invalid error. expected: %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected err: %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("geofences, expected %+v got %+v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"bounds": {
+			config: []config.Geofence{{
+				Name:       "base",
+				Bounds:     []env.Float{-77.12, 38.80, -76.91, 39.0},
+				MinZoom:    env.UintPtr(12),
+				Maps:       []env.String{"imagery"},
+				KeyClasses: []env.String{"anonymous"},
+			}},
+			expected: []server.Geofence{{
+				Name:       "base",
+				Extent:     geom.Extent{-77.12, 38.80, -76.91, 39.0},
+				MinZoom:    12,
+				Block:      true,
+				Maps:       []string{"imagery"},
+				KeyClasses: []string{"anonymous"},
+			}},
+		},
+		"multipolygon geometry logged": {
+			config: []config.Geofence{{
+				Name:     "parks",
+				Geometry: `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[2,2],[3,2],[3,3],[2,2]]]]}`,
+				Action:   "log",
+			}},
+			expected: []server.Geofence{{
+				Name:   "parks",
+				Extent: geom.Extent{0, 0, 3, 3},
+				Polygons: []geom.Polygon{
+					{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}},
+					{{{2, 2}, {3, 2}, {3, 3}, {2, 2}}},
+				},
+			}},
+		},
+		"point geometry": {
+			config: []config.Geofence{{
+				Name:     "point",
+				Geometry: `{"type":"Point","coordinates":[0,0]}`,
+			}},
+			expectedErr: register.ErrGeofenceGeometryType,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,33 @@
+package register
+
+import (
+	"errors"
+
+	"github.com/go-spatial/tegola/cache/invalidation"
+	"github.com/go-spatial/tegola/dict"
+
+	// the invalidation buses register themselves
+	_ "github.com/go-spatial/tegola/cache/invalidation/redis"
+)
+
+var (
+	ErrInvalidationBusTypeMissing = errors.New("register: invalidation_bus 'type' parameter missing")
+	ErrInvalidationBusTypeInvalid = errors.New("register: invalidation_bus 'type' value must be a string")
+)
+
+// InvalidationBus registers the bus propagating cache purges between instances
+func InvalidationBus(config dict.Dicter) (invalidation.Bus, error) {
+	busType, err := config.String("type", nil)
+	if err != nil {
+		switch err.(type) {
+		case dict.ErrKeyRequired:
+			return nil, ErrInvalidationBusTypeMissing
+		case dict.ErrKeyType:
+			return nil, ErrInvalidationBusTypeInvalid
+		default:
+			return nil, err
+		}
+	}
+
+	return invalidation.For(busType, config)
+}
@@ -0,0 +1,166 @@
+package register
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/internal/env"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/server"
+)
+
+var importLock sync.Mutex
+
+// LayerImporter returns the server.LayerImporter registering the layers with the providers
+func LayerImporter(providers map[string]provider.TilerUnion) func(*atlas.Atlas, []server.LayerManifestEntry, bool) server.LayerImportReport {
+	return func(a *atlas.Atlas, entries []server.LayerManifestEntry, dryRun bool) server.LayerImportReport {
+		return ImportLayers(a, providers, entries, dryRun)
+	}
+}
+
+// ImportLayers registers the layers of the manifest with their providers and adds them to
+// their maps. Every layer is validated first and nothing is registered when any is invalid.
+// The maps are replaced once all the layers have been registered with their providers, so
+// they are unchanged when a provider fails to register a layer.
+func ImportLayers(a *atlas.Atlas, providers map[string]provider.TilerUnion, entries []server.LayerManifestEntry, dryRun bool) server.LayerImportReport {
+	// concurrent imports would validate against the same maps
+	importLock.Lock()
+	defer importLock.Unlock()
+
+	report := server.LayerImportReport{
+		DryRun: dryRun,
+		Layers: make([]server.LayerImportResult, len(entries)),
+	}
+	for i, e := range entries {
+		report.Layers[i] = server.LayerImportResult{Provider: e.Provider, Name: e.Name, Map: e.Map}
+	}
+
+	failed := false
+	fail := func(i int, err error) {
+		report.Layers[i].Error = err.Error()
+		failed = true
+	}
+
+	// the maps with their imported layers
+	maps := map[string]*atlas.Map{}
+	// the provider layers and map layers of the manifest, for duplicates
+	providerLayers := map[string]bool{}
+	mapLayers := map[string]bool{}
+
+	for i, e := range entries {
+		if e.Provider == "" || e.Name == "" {
+			fail(i, fmt.Errorf("'provider' and 'name' are required"))
+			continue
+		}
+
+		prvd, ok := providers[e.Provider]
+		if !ok {
+			fail(i, ErrProviderNotFound{e.Provider})
+			continue
+		}
+
+		plyr := e.Provider + "." + e.Name
+		if _, ok := prvd.Layer(e.Name); ok || providerLayers[plyr] {
+			fail(i, fmt.Errorf("provider (%v) already has a layer (%v)", e.Provider, e.Name))
+			continue
+		}
+		providerLayers[plyr] = true
+
+		if e.Map == "" {
+			continue
+		}
+
+		m, ok := maps[e.Map]
+		if !ok {
+			am, err := a.Map(e.Map)
+			if err != nil {
+				fail(i, err)
+				continue
+			}
+			if am.HasUpstream() {
+				fail(i, fmt.Errorf("map (%v) is an upstream map, upstream maps can't have layers", e.Map))
+				continue
+			}
+			// the layers are copied so the registered map is not changed
+			am.Layers = append([]atlas.Layer(nil), am.Layers...)
+			m = &am
+			maps[e.Map] = m
+			for _, l := range m.Layers {
+				mapLayers[e.Map+"."+l.MVTName()] = true
+			}
+		}
+
+		if mapLayers[e.Map+"."+e.Name] {
+			fail(i, fmt.Errorf("map (%v) already has a layer (%v)", e.Map, e.Name))
+			continue
+		}
+		mapLayers[e.Map+"."+e.Name] = true
+
+		if _, err := selectProvider(e.Provider, e.Map, m, providers); err != nil {
+			fail(i, err)
+		}
+	}
+
+	if failed || dryRun {
+		return report
+	}
+
+	// register the layers with their providers
+	for i, e := range entries {
+		cfg := env.Dict{}
+		for k, v := range e.Config {
+			cfg[k] = v
+		}
+		// the layer is looked up by its name, which is also the id of providers with layer ids
+		cfg["name"] = e.Name
+		cfg["id"] = e.Name
+
+		if err := providers[e.Provider].AddLayer(cfg); err != nil {
+			fail(i, err)
+			// the maps are not changed, the layers registered so far are not served
+			return report
+		}
+		report.Imported++
+	}
+
+	// add the layers to their maps
+	for i, e := range entries {
+		if e.Map == "" {
+			continue
+		}
+		m := maps[e.Map]
+
+		cfg := config.MapLayer{
+			ID:            env.String(e.Name),
+			Name:          env.String(e.Name),
+			ProviderLayer: env.String(e.Provider + "." + e.Name),
+		}
+		if e.MinZoom != nil {
+			z := env.Uint(*e.MinZoom)
+			cfg.MinZoom = &z
+		}
+		if e.MaxZoom != nil {
+			z := env.Uint(*e.MaxZoom)
+			cfg.MaxZoom = &z
+		}
+
+		layerer, err := selectProvider(e.Provider, e.Map, m, providers)
+		if err != nil {
+			fail(i, err)
+			return report
+		}
+		layer, err := atlasLayerFromConfigLayer(&cfg, e.Map, layerer)
+		if err != nil {
+			fail(i, err)
+			return report
+		}
+		m.Layers = append(m.Layers, layer)
+	}
+
+	for _, m := range maps {
+		a.AddMap(*m)
+	}
+	return report
+}
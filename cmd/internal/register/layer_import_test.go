@@ -0,0 +1,122 @@
+package register_test
+
+import (
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cmd/internal/register"
+	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/server"
+
+	_ "github.com/go-spatial/tegola/provider/memory"
+)
+
+func TestImportLayers(t *testing.T) {
+	type tcase struct {
+		entries []server.LayerManifestEntry
+		dryRun  bool
+		// errs are the layers of the manifest expected to fail
+		errs     []int
+		imported int
+		// layers are the expected layers of the map
+		layers []string
+	}
+
+	ten := uint(10)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			providers, err := register.Providers([]dict.Dicter{
+				dict.Dict{
+					"name":   "mem",
+					"type":   "memory",
+					"layers": []map[string]interface{}{{"name": "roads", "geometry_type": "linestring"}},
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			var a atlas.Atlas
+			if err := register.Maps(&a, []config.Map{{Name: "osm", Layers: []config.MapLayer{{Name: "roads", ProviderLayer: "mem.roads"}}}}, providers); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			report := register.ImportLayers(&a, providers, tc.entries, tc.dryRun)
+			if report.Imported != tc.imported {
+				t.Errorf("imported, expected %v got %v", tc.imported, report.Imported)
+			}
+			for i, l := range report.Layers {
+				failed := false
+				for _, e := range tc.errs {
+					failed = failed || e == i
+				}
+				if failed != (l.Error != "") {
+					t.Errorf("layer %v, expected failed %v got %+v", i, failed, l)
+				}
+			}
+
+			m, _ := a.Map("osm")
+			var layers []string
+			for _, l := range m.Layers {
+				layers = append(layers, l.MVTName())
+			}
+			if len(layers) != len(tc.layers) {
+				t.Fatalf("map layers, expected %v got %v", tc.layers, layers)
+			}
+			for i := range layers {
+				if layers[i] != tc.layers[i] {
+					t.Errorf("map layers, expected %v got %v", tc.layers, layers)
+				}
+			}
+		}
+	}
+
+	buildings := server.LayerManifestEntry{
+		Provider: "mem",
+		Name:     "buildings",
+		Map:      "osm",
+		MinZoom:  &ten,
+		Config:   map[string]interface{}{"geometry_type": "polygon", "srid": int64(3857)},
+	}
+	water := server.LayerManifestEntry{Provider: "mem", Name: "water"}
+
+	tests := map[string]tcase{
+		"import": {
+			entries:  []server.LayerManifestEntry{buildings, water},
+			imported: 2,
+			layers:   []string{"roads", "buildings"},
+		},
+		"dry run": {
+			entries: []server.LayerManifestEntry{buildings, water},
+			dryRun:  true,
+			layers:  []string{"roads"},
+		},
+		"invalid layers": {
+			entries: []server.LayerManifestEntry{
+				buildings,
+				{Provider: "mem", Name: "roads"},
+				{Provider: "postgis", Name: "parks"},
+				{Provider: "mem", Name: "railways", Map: "missing"},
+				{Provider: "mem", Name: "buildings"},
+			},
+			errs:   []int{1, 2, 3, 4},
+			layers: []string{"roads"},
+		},
+		"provider error": {
+			entries: []server.LayerManifestEntry{
+				water,
+				buildings,
+				{Provider: "mem", Name: "parks", Map: "osm", Config: map[string]interface{}{"geometry_type": "circle"}},
+			},
+			errs:     []int{2},
+			imported: 2,
+			layers:   []string{"roads"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -1,17 +1,48 @@
 package register
 
 import (
+	"context"
+	"errors"
 	"html"
+	"time"
 
 	"github.com/go-spatial/geom"
 	"github.com/go-spatial/tegola/atlas"
 	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/internal/env"
 	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/raster"
 )
 
 func webMercatorMapFromConfigMap(cfg config.Map) (newMap atlas.Map) {
 	newMap = atlas.NewWebMercatorMap(string(cfg.Name))
 	newMap.Attribution = html.EscapeString(string(cfg.Attribution))
+	newMap.Style = string(cfg.Style)
+	newMap.CacheVersion = string(cfg.CacheVersion)
+	newMap.EmptyTileStatus = int(cfg.EmptyTileStatus)
+	newMap.LayerConcurrency = int(cfg.LayerConcurrency)
+	for _, cc := range cfg.CacheControl {
+		minZoom, maxZoom := cc.Zooms()
+		newMap.CacheControls = append(newMap.CacheControls, atlas.CacheControl{
+			MinZoom:              minZoom,
+			MaxZoom:              maxZoom,
+			MaxAge:               time.Duration(cc.MaxAge) * time.Second,
+			SMaxAge:              time.Duration(cc.SMaxAge) * time.Second,
+			StaleWhileRevalidate: time.Duration(cc.StaleWhileRevalidate) * time.Second,
+		})
+	}
+	if cfg.CacheMaxZoom != nil {
+		maxZoom := uint(*cfg.CacheMaxZoom)
+		newMap.CacheMaxZoom = &maxZoom
+	}
+	if cfg.MinZoom != nil {
+		minZoom := uint(*cfg.MinZoom)
+		newMap.MinZoom = &minZoom
+	}
+	if cfg.MaxZoom != nil {
+		maxZoom := uint(*cfg.MaxZoom)
+		newMap.MaxZoom = &maxZoom
+	}
 
 	// convert from env package
 	for i, v := range cfg.Center {
@@ -32,6 +63,152 @@ func webMercatorMapFromConfigMap(cfg config.Map) (newMap atlas.Map) {
 
 }
 
+func upstreamFromConfig(cfg config.MapUpstream) (*atlas.Upstream, error) {
+	var ttl, timeout time.Duration
+	if cfg.TTL != nil {
+		ttl = time.Duration(*cfg.TTL) * time.Second
+	}
+	if cfg.Timeout != nil {
+		timeout = time.Duration(*cfg.Timeout) * time.Second
+	}
+
+	var maxRequests uint
+	if cfg.MaxRequests != nil {
+		maxRequests = uint(*cfg.MaxRequests)
+	}
+
+	upstream, err := atlas.NewUpstream(string(cfg.URL), string(cfg.ContentType), ttl, timeout, maxRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MinZoom != nil {
+		upstream.MinZoom = uint(*cfg.MinZoom)
+	}
+	if cfg.MaxZoom != nil {
+		upstream.MaxZoom = uint(*cfg.MaxZoom)
+	}
+
+	if len(cfg.Headers) != 0 {
+		upstream.Headers = make(map[string]string, len(cfg.Headers))
+		for k, v := range cfg.Headers {
+			upstream.Headers[k] = string(v)
+		}
+	}
+
+	return upstream, nil
+}
+
+// rasterFromConfig opens the tile service or the COG of a map's raster
+func rasterFromConfig(cfg config.MapRaster) (*atlas.Raster, error) {
+	if (cfg.URL == "") == (cfg.COG == "") {
+		return nil, errors.New("exactly one of 'url' or 'cog' must be set")
+	}
+
+	r := atlas.Raster{MaxZoom: atlas.MaxZoom}
+	if cfg.MinZoom != nil {
+		r.MinZoom = uint(*cfg.MinZoom)
+	}
+	if cfg.MaxZoom != nil {
+		r.MaxZoom = uint(*cfg.MaxZoom)
+	}
+
+	if cfg.URL != "" {
+		upstream, err := upstreamFromConfig(config.MapUpstream{
+			URL:         cfg.URL,
+			ContentType: cfg.ContentType,
+			TTL:         cfg.TTL,
+			Timeout:     cfg.Timeout,
+			MaxRequests: cfg.MaxRequests,
+			Headers:     cfg.Headers,
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.Upstream = upstream
+		return &r, nil
+	}
+
+	var timeout time.Duration
+	if cfg.Timeout != nil {
+		timeout = time.Duration(*cfg.Timeout) * time.Second
+	}
+
+	cacheSize := raster.DefaultCacheSizeMB
+	if cfg.CacheSizeMB != nil {
+		cacheSize = int(*cfg.CacheSizeMB)
+	}
+
+	headers := make(map[string]string, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		headers[k] = string(v)
+	}
+
+	cog, err := raster.Open(string(cfg.COG), headers, timeout, cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	r.COG = cog
+
+	return &r, nil
+}
+
+// availabilityFromConfig converts the config's availability windows
+func availabilityFromConfig(windows []config.AvailabilityWindow) (atlas.Availability, error) {
+	var availability atlas.Availability
+	for _, w := range windows {
+		from, until, err := w.Times()
+		if err != nil {
+			return nil, err
+		}
+		availability = append(availability, atlas.Window{From: from, Until: until})
+	}
+	return availability, nil
+}
+
+// gridFromConfig converts the config's grid, a predefined grid by name or a custom grid
+func gridFromConfig(cfg config.MapGrid) (atlas.Grid, error) {
+	if cfg.Name != "" && cfg.SRID == 0 && len(cfg.Extent) == 0 {
+		return atlas.GridByName(string(cfg.Name))
+	}
+
+	if len(cfg.Extent) != 4 {
+		return atlas.Grid{}, errors.New("extent must have 4 values: min x, min y, max x, max y")
+	}
+	grid := atlas.Grid{
+		Name:         string(cfg.Name),
+		SRID:         uint64(cfg.SRID),
+		Extent:       geom.Extent{float64(cfg.Extent[0]), float64(cfg.Extent[1]), float64(cfg.Extent[2]), float64(cfg.Extent[3])},
+		MatrixWidth:  uint(cfg.MatrixWidth),
+		MatrixHeight: uint(cfg.MatrixHeight),
+	}
+	for _, r := range cfg.Resolutions {
+		grid.Resolutions = append(grid.Resolutions, float64(r))
+	}
+	return grid, grid.Validate()
+}
+
+// tagTransformFromConfig converts the config's tag transform
+func tagTransformFromConfig(cfg config.TagTransform) (atlas.TagTransform, error) {
+	stringMap := func(m map[string]env.String) map[string]string {
+		if len(m) == 0 {
+			return nil
+		}
+		sm := make(map[string]string, len(m))
+		for k, v := range m {
+			sm[k] = string(v)
+		}
+		return sm
+	}
+
+	var drop []string
+	for _, tag := range cfg.Drop {
+		drop = append(drop, string(tag))
+	}
+
+	return atlas.NewTagTransform(stringMap(cfg.Rename), stringMap(cfg.Computed), stringMap(cfg.Cast), drop)
+}
+
 func layerInfosFindByID(infos []provider.LayerInfo, lyrID string) provider.LayerInfo {
 	if len(infos) == 0 {
 		return nil
@@ -48,7 +225,6 @@ func atlasLayerFromConfigLayer(cfg *config.MapLayer, mapName string, layerProvid
 	var (
 		// providerLayer is primary used for error reporting.
 		providerLayer = string(cfg.ProviderLayer)
-		ok            bool
 	)
 
 	cfg.GetName()
@@ -73,11 +249,19 @@ func atlasLayerFromConfigLayer(cfg *config.MapLayer, mapName string, layerProvid
 	layer.GeomType = layerInfo.GeomType()
 
 	if cfg.DefaultTags != nil {
-		if layer.DefaultTags, ok = cfg.DefaultTags.(map[string]interface{}); !ok {
+		defaultTags, ok := cfg.DefaultTags.(map[string]interface{})
+		if !ok {
 			return layer, ErrDefaultTagsInvalid{
 				ProviderLayer: providerLayer,
 			}
 		}
+		// string values with {{ }} are templates, executed when the layer is encoded
+		if layer.DefaultTags, layer.DefaultTagTemplates, err = atlas.ParseDefaultTags(defaultTags); err != nil {
+			return layer, ErrDefaultTagsInvalid{
+				ProviderLayer: providerLayer,
+				Err:           err,
+			}
+		}
 	}
 
 	// if layerProvider is not a provider.Tiler this will return nil, so
@@ -89,6 +273,53 @@ func atlasLayerFromConfigLayer(cfg *config.MapLayer, mapName string, layerProvid
 	layer.ProviderLayerID = plyrID
 	layer.DontSimplify = bool(cfg.DontSimplify)
 	layer.DontClip = bool(cfg.DontClip)
+	layer.MakeValid = bool(cfg.MakeValid)
+	if cfg.MinPolygonArea != nil {
+		layer.MinPolygonArea = float64(*cfg.MinPolygonArea)
+	}
+	if cfg.MinLineLength != nil {
+		layer.MinLineLength = float64(*cfg.MinLineLength)
+	}
+	layer.MergeLines = bool(cfg.MergeLines)
+	layer.SortFeatures = bool(cfg.SortFeatures)
+	layer.ExpiresField = string(cfg.ExpiresField)
+	if cfg.TimeoutMS != nil {
+		layer.Timeout = time.Duration(*cfg.TimeoutMS) * time.Millisecond
+	}
+	layer.Optional = cfg.Required != nil && !bool(*cfg.Required)
+	if cfg.FreshnessSLA != nil {
+		layer.FreshnessSLA = time.Duration(*cfg.FreshnessSLA) * time.Second
+	}
+	layer.Paint = cfg.Paint
+	layer.UTFGridKey = string(cfg.UTFGridKey)
+	if layer.Availability, err = availabilityFromConfig(cfg.Available); err != nil {
+		return layer, err
+	}
+
+	if layer.GeometryAttributes, err = atlas.ParseGeometryAttributes(string(cfg.GeometryAttributes)); err != nil {
+		return layer, ErrGeometryAttributesInvalid{
+			ProviderLayer: providerLayer,
+			Err:           err,
+		}
+	}
+	if layer.TagTransform, err = tagTransformFromConfig(cfg.TagTransform); err != nil {
+		return layer, ErrTagTransformInvalid{
+			ProviderLayer: providerLayer,
+			Err:           err,
+		}
+	}
+	layer.GeometryAttributesPrecision = atlas.DefaultGeometryAttributesPrecision
+	if cfg.GeometryAttributesPrecision != nil {
+		layer.GeometryAttributesPrecision = uint(*cfg.GeometryAttributesPrecision)
+	}
+
+	for _, attr := range cfg.JSONAttributes {
+		layer.JSONAttributes = append(layer.JSONAttributes, string(attr))
+	}
+	layer.JSONAttributesMaxBytes = atlas.DefaultJSONAttributesMaxBytes
+	if cfg.JSONAttributesMaxBytes != nil {
+		layer.JSONAttributesMaxBytes = uint(*cfg.JSONAttributesMaxBytes)
+	}
 
 	if cfg.MinZoom != nil {
 		layer.MinZoom = uint(*cfg.MinZoom)
@@ -96,6 +327,9 @@ func atlasLayerFromConfigLayer(cfg *config.MapLayer, mapName string, layerProvid
 	if cfg.MaxZoom != nil {
 		layer.MaxZoom = uint(*cfg.MaxZoom)
 	}
+	if cfg.OverzoomLevels != nil {
+		layer.OverzoomLevels = uint(*cfg.OverzoomLevels)
+	}
 	return layer, nil
 }
 
@@ -138,6 +372,60 @@ func Maps(a *atlas.Atlas, maps []config.Map, providers map[string]provider.Tiler
 	for _, m := range maps {
 		newMap := webMercatorMapFromConfigMap(m)
 
+		if m.MVTVersion != nil {
+			newMap.MVTVersion = uint(*m.MVTVersion)
+			if err := atlas.ValidateMVTVersion(newMap.MVTVersion); err != nil {
+				return err
+			}
+		}
+
+		availability, err := availabilityFromConfig(m.Available)
+		if err != nil {
+			return err
+		}
+		newMap.Availability = availability
+
+		if m.Upstream != nil {
+			if len(m.Layers) != 0 {
+				return ErrUpstreamWithLayers{Map: string(m.Name)}
+			}
+
+			upstream, err := upstreamFromConfig(*m.Upstream)
+			if err != nil {
+				return ErrUpstreamInvalid{Map: string(m.Name), Err: err}
+			}
+			newMap.Upstream = upstream
+		}
+
+		if m.Grid != nil {
+			grid, err := gridFromConfig(*m.Grid)
+			if err != nil {
+				return ErrGridInvalid{Map: string(m.Name), Err: err}
+			}
+			newMap.Grid = &grid
+			newMap.SRID = grid.SRID
+			if len(m.Bounds) != 4 {
+				newMap.Bounds = grid.Bounds()
+			}
+		}
+		// rasters, upstreams and MVT providers serve web mercator tiles
+		webMercator := newMap.TileGrid().IsWebMercator()
+		if !webMercator && (m.Upstream != nil || m.Raster != nil) {
+			return ErrGridInvalid{Map: string(m.Name), Err: errors.New("maps with an upstream or a raster only support the web mercator grid")}
+		}
+
+		if m.Raster != nil {
+			if m.Upstream != nil {
+				return ErrUpstreamWithRaster{Map: string(m.Name)}
+			}
+
+			r, err := rasterFromConfig(*m.Raster)
+			if err != nil {
+				return ErrRasterInvalid{Map: string(m.Name), Err: err}
+			}
+			newMap.Raster = r
+		}
+
 		// iterate our layers
 		for _, l := range m.Layers {
 			prdID, _, err := l.ProviderLayerID()
@@ -160,6 +448,24 @@ func Maps(a *atlas.Atlas, maps []config.Map, providers map[string]provider.Tiler
 			}
 			newMap.Layers = append(newMap.Layers, layer)
 		}
+
+		if !webMercator && newMap.HasMVTProvider() {
+			return ErrGridInvalid{Map: string(m.Name), Err: errors.New("maps of MVT providers only support the web mercator grid")}
+		}
+
+		// tiles from MVT providers are passed through so the version can't be changed
+		if newMap.HasMVTProvider() && newMap.MVTVersion != atlas.MVTProviderVersion {
+			return ErrMVTProviderVersion{
+				Map:     string(m.Name),
+				Version: newMap.MVTVersion,
+			}
+		}
+
+		if m.AuditCoordinates {
+			if err := newMap.AuditCoordinates(context.Background()); err != nil {
+				return err
+			}
+		}
 		a.AddMap(newMap)
 	}
 	return nil
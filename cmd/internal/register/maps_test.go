@@ -125,6 +125,66 @@ func TestMaps(t *testing.T) {
 				ProviderLayer: "test.debug-tile-outline",
 			},
 		},
+		"upstream with layers": {
+			maps: []config.Map{
+				{
+					Name: "foo",
+					Upstream: &config.MapUpstream{
+						URL: "https://tiles.example.com/{z}/{x}/{y}.png",
+					},
+					Layers: []config.MapLayer{
+						{
+							ProviderLayer: "test.debug-tile-outline",
+						},
+					},
+				},
+			},
+			providers: []dict.Dict{
+				{
+					"name": "test",
+					"type": "debug",
+				},
+			},
+			expectedErr: register.ErrUpstreamWithLayers{
+				Map: "foo",
+			},
+		},
+		"upstream url invalid": {
+			maps: []config.Map{
+				{
+					Name: "foo",
+					Upstream: &config.MapUpstream{
+						URL: "https://tiles.example.com/{z}/{x}.png",
+					},
+				},
+			},
+			providers: []dict.Dict{
+				{
+					"name": "test",
+					"type": "debug",
+				},
+			},
+			expectedErr: register.ErrUpstreamInvalid{
+				Map: "foo",
+				Err: atlas.ErrUpstreamURL{URL: "https://tiles.example.com/{z}/{x}.png"},
+			},
+		},
+		"upstream": {
+			maps: []config.Map{
+				{
+					Name: "foo",
+					Upstream: &config.MapUpstream{
+						URL: "https://tiles.example.com/{z}/{x}/{y}.png",
+					},
+				},
+			},
+			providers: []dict.Dict{
+				{
+					"name": "test",
+					"type": "debug",
+				},
+			},
+		},
 		"success": {
 			maps: []config.Map{},
 			providers: []dict.Dict{
@@ -79,6 +79,7 @@ func Providers(providers []dict.Dicter) (map[string]provider.TilerUnion, error)
 
 		// add the provider to our map of registered providers
 		registeredProviders[pname] = prov
+		provider.SetInstance(pname, prov)
 		log.Infof("registering provider(type): %v (%v)", pname, ptype)
 	}
 
@@ -0,0 +1,62 @@
+package register
+
+import (
+	"fmt"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/server"
+)
+
+// Tenants creates an atlas for each tenant with its maps from the atlas. The tenants with
+// their own cache are given the backend, with the backends of their maps configured with
+// their own cache. The other tenants share the cache of the atlas.
+func Tenants(a *atlas.Atlas, tenants []config.Tenant, maps []config.Map) ([]server.Tenant, error) {
+	var registered []server.Tenant
+	for _, t := range tenants {
+		tenant := server.Tenant{
+			Name:  string(t.Name),
+			Atlas: &atlas.Atlas{},
+		}
+		for _, h := range t.Hosts {
+			tenant.Hosts = append(tenant.Hosts, string(h))
+		}
+
+		var tenantMaps []config.Map
+		for _, name := range t.Maps {
+			m, err := a.Map(string(name))
+			if err != nil {
+				return nil, fmt.Errorf("tenant (%v): %w", t.Name, err)
+			}
+			tenant.Atlas.AddMap(m)
+
+			for _, cm := range maps {
+				if cm.Name == name {
+					tenantMaps = append(tenantMaps, cm)
+				}
+			}
+		}
+
+		if len(t.Cache) == 0 {
+			// the tenant's atlas versions the keys of its maps again
+			c := a.GetCache()
+			if v, ok := c.(*cache.Versioned); ok {
+				c = v.Interface
+			}
+			tenant.Atlas.SetCache(c)
+		} else {
+			c, err := Cache(t.Cache)
+			if err != nil {
+				return nil, fmt.Errorf("tenant (%v): %w", t.Name, err)
+			}
+			if c, err = MapCaches(c, tenantMaps); err != nil {
+				return nil, fmt.Errorf("tenant (%v): %w", t.Name, err)
+			}
+			tenant.Atlas.SetCache(c)
+		}
+
+		registered = append(registered, tenant)
+	}
+	return registered, nil
+}
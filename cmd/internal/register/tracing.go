@@ -0,0 +1,34 @@
+package register
+
+import (
+	"fmt"
+
+	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/internal/tracing"
+)
+
+// Tracer creates the tracer exporting the spans of tile requests to the OTLP endpoint. nil is
+// returned when no endpoint is configured.
+func Tracer(cfg config.Tracing) *tracing.Tracer {
+	if cfg.OTLPEndpoint == "" {
+		return nil
+	}
+
+	exporter := tracing.OTLPExporter{
+		Endpoint: string(cfg.OTLPEndpoint),
+		Headers:  map[string]string{},
+	}
+	for name, value := range cfg.Headers {
+		exporter.Headers[name] = fmt.Sprintf("%v", value)
+	}
+
+	serviceName := "tegola"
+	if cfg.ServiceName != "" {
+		serviceName = string(cfg.ServiceName)
+	}
+	sampleRate := 1.0
+	if cfg.SampleRate != nil {
+		sampleRate = float64(*cfg.SampleRate)
+	}
+	return tracing.NewTracer(serviceName, sampleRate, exporter)
+}
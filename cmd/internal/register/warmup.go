@@ -0,0 +1,36 @@
+package register
+
+import (
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/config"
+)
+
+// Warmup creates the warm-up of the atlas' maps configured to be seeded at startup
+func Warmup(a *atlas.Atlas, cfg config.Warmup) *atlas.Warmup {
+	w := atlas.Warmup{Atlas: a}
+	if cfg.Concurrency != nil {
+		w.Concurrency = int(*cfg.Concurrency)
+	}
+
+	for _, wm := range cfg.Maps {
+		area := atlas.WarmupArea{Map: string(wm.Name)}
+		if len(wm.Bounds) == 4 {
+			area.Bounds = &geom.Extent{
+				float64(wm.Bounds[0]),
+				float64(wm.Bounds[1]),
+				float64(wm.Bounds[2]),
+				float64(wm.Bounds[3]),
+			}
+		}
+		if wm.MinZoom != nil {
+			area.MinZoom = uint(*wm.MinZoom)
+		}
+		if wm.MaxZoom != nil {
+			area.MaxZoom = uint(*wm.MaxZoom)
+		}
+		w.Areas = append(w.Areas, area)
+	}
+	return &w
+}
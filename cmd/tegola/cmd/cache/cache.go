@@ -41,6 +41,9 @@ var Cmd = &cobra.Command{
 
 func init() {
 	Cmd.AddCommand(SeedPurgeCmd)
+	Cmd.AddCommand(ManifestCmd)
+	Cmd.AddCommand(VerifyCmd)
+	Cmd.AddCommand(PruneCmd)
 	Cmd.SetUsageTemplate(`Usage: {{.CommandPath}} [command]{{if .HasExample}}
 
 Examples:
@@ -48,7 +51,10 @@ Examples:
 
 Available Commands:
   {{rpad "seed" .NamePadding}} seed tiles to the cache
-  {{rpad "purge" .NamePadding}} purge tiles from the cache{{if .HasAvailableLocalFlags}}
+  {{rpad "purge" .NamePadding}} purge tiles from the cache
+  {{rpad "manifest" .NamePadding}} list cached tiles with their sizes and hashes
+  {{rpad "verify" .NamePadding}} verify cached tiles against a manifest
+  {{rpad "prune" .NamePadding}} remove cached tiles no longer served by the config{{if .HasAvailableLocalFlags}}
 
 Flags:
 {{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
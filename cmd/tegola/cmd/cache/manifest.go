@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-spatial/cobra"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	gdcmd "github.com/go-spatial/tegola/internal/cmd"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// manifest output formats
+const (
+	ManifestFormatJSON = "json"
+	ManifestFormatCSV  = "csv"
+)
+
+// flag parameters
+var (
+	manifestBaseURL string
+	manifestOutput  string
+	manifestFormat  string
+	manifestBounds  string
+)
+
+// variables that are not flags but set by the command.
+var (
+	manifestMaps       []atlas.Map
+	manifestTileBounds [4]float64
+)
+
+var ManifestCmd = &cobra.Command{
+	Use:     "manifest",
+	Short:   "list cached tiles with their sizes and hashes",
+	Long:    "command to generate a manifest of the cached tiles of a map, with the tile urls, sizes and sha256 hashes, for CDN pre-warming and integrity verification of static exports",
+	Example: "tegola cache manifest --map osm --min-zoom 0 --max-zoom 10 --base-url https://tiles.example.com",
+	PreRunE: manifestCmdValidate,
+	RunE:    manifestCommand,
+}
+
+func init() {
+	setupMinMaxZoomFlags(ManifestCmd, 0, atlas.MaxZoom)
+	ManifestCmd.Flags().StringVarP(&cacheMap, "map", "", "", "map name as defined in the config. defaults to all maps")
+	ManifestCmd.Flags().StringVarP(&manifestBounds, "bounds", "", "-180,-85.0511,180,85.0511", "lng/lat bounds of the tiles in the format: minx, miny, maxx, maxy")
+	ManifestCmd.Flags().IntVarP(&cacheConcurrency, "concurrency", "", runtime.NumCPU(), "the amount of concurrency to use. defaults to the number of CPUs on the machine")
+	ManifestCmd.Flags().StringVarP(&manifestBaseURL, "base-url", "", "", "scheme, host and uri prefix of the tile urls, i.e. https://tiles.example.com. defaults to relative urls")
+	ManifestCmd.Flags().StringVarP(&manifestOutput, "output", "", "-", "file the manifest is written to, - for stdout")
+	ManifestCmd.Flags().StringVarP(&manifestFormat, "output-format", "", ManifestFormatJSON, "format of the manifest: json (a JSON object per line) or csv")
+
+	ManifestCmd.SetUsageTemplate(defaultUsage)
+}
+
+func manifestCmdValidate(cmd *cobra.Command, args []string) (err error) {
+	if cacheMap != "" {
+		m, err := atlas.GetMap(cacheMap)
+		if err != nil {
+			return err
+		}
+		manifestMaps = []atlas.Map{m}
+	} else {
+		manifestMaps = atlas.AllMaps()
+		if len(manifestMaps) == 0 {
+			return fmt.Errorf("expected at least one map to be defined. check your config")
+		}
+	}
+
+	if manifestTileBounds, err = parseBounds(manifestBounds); err != nil {
+		return err
+	}
+
+	switch manifestFormat {
+	case ManifestFormatJSON, ManifestFormatCSV:
+	default:
+		return fmt.Errorf("invalid output-format (%v), expected %v or %v", manifestFormat, ManifestFormatJSON, ManifestFormatCSV)
+	}
+
+	return minMaxZoomValidate(cmd, args)
+}
+
+func manifestCommand(cmd *cobra.Command, args []string) (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer gdcmd.New().Complete()
+	gdcmd.OnComplete(provider.Cleanup)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gdcmd.Cancelled():
+			cancel()
+		}
+	}()
+
+	var out io.Writer = os.Stdout
+	if manifestOutput != "-" {
+		f, err := os.Create(manifestOutput)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	c := atlas.GetCache()
+	if c == nil {
+		return fmt.Errorf("no cache configured")
+	}
+
+	mw := newManifestWriter(out, manifestFormat)
+
+	maps, err := webMercatorMaps(manifestMaps)
+	if err != nil {
+		return err
+	}
+
+	log.Info("zoom list: ", zooms)
+	tilechannel := generateTilesForBounds(ctx, manifestTileBounds, zooms)
+
+	if err = doWork(ctx, tilechannel, maps, cacheConcurrency, manifestWorker(c, mw, manifestBaseURL)); err != nil {
+		return err
+	}
+
+	if err = mw.Flush(); err != nil {
+		return err
+	}
+
+	log.Infof("manifest lists %v cached tiles, %v tiles are not cached", mw.written, mw.missing)
+	return nil
+}
+
+// ManifestEntry is a cached tile listed in the manifest
+type ManifestEntry struct {
+	URL string `json:"url"`
+	Map string `json:"map"`
+	Z   uint   `json:"z"`
+	X   uint   `json:"x"`
+	Y   uint   `json:"y"`
+	// Size of the cached tile in bytes
+	Size int `json:"size"`
+	// SHA256 is the hex encoded sha256 hash of the cached tile
+	SHA256 string `json:"sha256"`
+}
+
+// manifestWorker reads the tile from the cache and writes its entry to the manifest.
+// Tiles missing from the cache are counted and left out of the manifest.
+func manifestWorker(c cache.Interface, mw *manifestWriter, baseURL string) func(context.Context, MapTile) error {
+	// maps are looked up for the extension of their tiles
+	exts := map[string]string{}
+	for _, m := range atlas.AllMaps() {
+		exts[m.Name] = tileExtension(m)
+	}
+
+	return func(ctx context.Context, mt MapTile) error {
+		z, x, y := mt.Tile.ZXY()
+
+		data, hit, err := c.Get(&cache.Key{MapName: mt.MapName, Z: z, X: x, Y: y})
+		if err != nil {
+			return fmt.Errorf("error reading map (%v) tile (%v/%v/%v) from cache: %v", mt.MapName, z, x, y, err)
+		}
+		if !hit {
+			mw.skip()
+			return nil
+		}
+
+		sum := sha256.Sum256(data)
+
+		return mw.Write(ManifestEntry{
+			URL:    fmt.Sprintf("%v/maps/%v/%v/%v/%v%v", strings.TrimSuffix(baseURL, "/"), mt.MapName, z, x, y, exts[mt.MapName]),
+			Map:    mt.MapName,
+			Z:      z,
+			X:      x,
+			Y:      y,
+			Size:   len(data),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+}
+
+// tileExtension returns the file extension of the map's tile urls
+func tileExtension(m atlas.Map) string {
+	if !m.HasUpstream() {
+		return ".pbf"
+	}
+	switch m.Upstream.ContentType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".pbf"
+	}
+}
+
+// manifestWriter writes manifest entries from concurrent workers
+type manifestWriter struct {
+	sync.Mutex
+	enc     *json.Encoder
+	csv     *csv.Writer
+	header  bool
+	written uint64
+	missing uint64
+}
+
+func newManifestWriter(w io.Writer, format string) *manifestWriter {
+	var mw manifestWriter
+	if format == ManifestFormatCSV {
+		mw.csv = csv.NewWriter(w)
+	} else {
+		mw.enc = json.NewEncoder(w)
+	}
+	return &mw
+}
+
+func (mw *manifestWriter) Write(e ManifestEntry) error {
+	mw.Lock()
+	defer mw.Unlock()
+
+	mw.written++
+
+	if mw.csv == nil {
+		return mw.enc.Encode(e)
+	}
+
+	if !mw.header {
+		mw.header = true
+		if err := mw.csv.Write([]string{"url", "map", "z", "x", "y", "size", "sha256"}); err != nil {
+			return err
+		}
+	}
+	return mw.csv.Write([]string{
+		e.URL,
+		e.Map,
+		strconv.FormatUint(uint64(e.Z), 10),
+		strconv.FormatUint(uint64(e.X), 10),
+		strconv.FormatUint(uint64(e.Y), 10),
+		strconv.Itoa(e.Size),
+		e.SHA256,
+	})
+}
+
+func (mw *manifestWriter) skip() {
+	mw.Lock()
+	mw.missing++
+	mw.Unlock()
+}
+
+// Flush writes any buffered entries
+func (mw *manifestWriter) Flush() error {
+	mw.Lock()
+	defer mw.Unlock()
+
+	if mw.csv == nil {
+		return nil
+	}
+	mw.csv.Flush()
+	return mw.csv.Error()
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestManifestWriter(t *testing.T) {
+	type tcase struct {
+		format   string
+		entries  []ManifestEntry
+		expected string
+	}
+
+	entry := ManifestEntry{
+		URL:    "https://tiles.example.com/maps/osm/1/0/1.pbf",
+		Map:    "osm",
+		Z:      1,
+		X:      0,
+		Y:      1,
+		Size:   3,
+		SHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			var buf bytes.Buffer
+			mw := newManifestWriter(&buf, tc.format)
+			for _, e := range tc.entries {
+				if err := mw.Write(e); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			mw.skip()
+			if err := mw.Flush(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if buf.String() != tc.expected {
+				t.Errorf("output, expected %q got %q", tc.expected, buf.String())
+			}
+			if mw.written != uint64(len(tc.entries)) || mw.missing != 1 {
+				t.Errorf("counts, expected %v written 1 missing got %v written %v missing", len(tc.entries), mw.written, mw.missing)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"json": {
+			format:   ManifestFormatJSON,
+			entries:  []ManifestEntry{entry},
+			expected: `{"url":"https://tiles.example.com/maps/osm/1/0/1.pbf","map":"osm","z":1,"x":0,"y":1,"size":3,"sha256":"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}` + "\n",
+		},
+		"csv": {
+			format:   ManifestFormatCSV,
+			entries:  []ManifestEntry{entry},
+			expected: "url,map,z,x,y,size,sha256\nhttps://tiles.example.com/maps/osm/1/0/1.pbf,osm,1,0,1,3,ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad\n",
+		},
+		"csv empty": {
+			format: ManifestFormatCSV,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
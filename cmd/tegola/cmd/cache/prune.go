@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-spatial/cobra"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	gdcmd "github.com/go-spatial/tegola/internal/cmd"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// flag parameters
+var (
+	pruneDryRun bool
+)
+
+var PruneCmd = &cobra.Command{
+	Use:     "prune",
+	Short:   "remove cached tiles no longer served by the config",
+	Long:    "command to list the keys of the cache and purge the tiles of maps, layers and zooms which are no longer in the config, reclaiming storage after maps are reorganized. the file, memory, redis and s3 caches can be listed",
+	Example: "tegola cache prune --dry-run",
+	RunE:    pruneCommand,
+}
+
+func init() {
+	PruneCmd.Flags().BoolVarP(&pruneDryRun, "dry-run", "", false, "log the tiles which would be pruned without purging them")
+
+	PruneCmd.SetUsageTemplate(defaultUsage)
+}
+
+func pruneCommand(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer gdcmd.New().Complete()
+	gdcmd.OnComplete(provider.Cleanup)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gdcmd.Cancelled():
+			cancel()
+		}
+	}()
+
+	c := atlas.GetCache()
+	if c == nil {
+		return fmt.Errorf("no cache configured")
+	}
+
+	p := newPruner(atlas.AllMaps(), pruneDryRun)
+	if err := p.run(ctx, c); err != nil {
+		return err
+	}
+
+	if pruneDryRun {
+		log.Infof("listed %v cached tiles, %v would be pruned", p.listed, p.pruned)
+	} else {
+		log.Infof("listed %v cached tiles, pruned %v", p.listed, p.pruned)
+	}
+	return nil
+}
+
+// pruner purges the cached tiles the maps don't serve
+type pruner struct {
+	maps   map[string]atlas.Map
+	dryRun bool
+
+	listed uint64
+	pruned uint64
+}
+
+func newPruner(maps []atlas.Map, dryRun bool) *pruner {
+	p := pruner{
+		maps:   make(map[string]atlas.Map, len(maps)),
+		dryRun: dryRun,
+	}
+	for _, m := range maps {
+		p.maps[m.Name] = m
+	}
+	return &p
+}
+
+// run lists the keys of the cache and purges the stale tiles
+func (p *pruner) run(ctx context.Context, c cache.Interface) error {
+	return cache.List(c, func(key *cache.Key) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.listed++
+
+		reason := p.stale(key)
+		if reason == "" {
+			return nil
+		}
+		p.pruned++
+
+		log.Infof("pruning %v: %v", key, reason)
+		if p.dryRun {
+			return nil
+		}
+		if err := c.Purge(key); err != nil {
+			return fmt.Errorf("error purging %v: %v", key, err)
+		}
+		return nil
+	})
+}
+
+// stale returns why the cached tile of the key is not served by the maps, or "" when it is
+func (p *pruner) stale(key *cache.Key) string {
+	m, ok := p.maps[key.MapName]
+	if !ok {
+		// tiles of the maps' previous cache versions, see atlas.Map.CacheVersion
+		if i := strings.LastIndex(key.MapName, cache.VersionSeparator); i > 0 {
+			if _, ok := p.maps[key.MapName[:i]]; ok {
+				return fmt.Sprintf("map (%v) cache version (%v) is not current", key.MapName[:i], key.MapName[i+1:])
+			}
+		}
+		return fmt.Sprintf("map (%v) is not in the config", key.MapName)
+	}
+
+	if key.Format == atlas.UTFGridFormat {
+		if key.LayerName != "" || !m.FilterLayersByZoom(key.Z).HasUTFGrid() {
+			return fmt.Sprintf("map (%v) has no UTFGrid layers at zoom %v", m.Name, key.Z)
+		}
+		return ""
+	}
+
+	if key.Format != "" {
+		if !m.HasRaster() || m.Raster.Format() != key.Format {
+			return fmt.Sprintf("map (%v) has no %v raster", m.Name, key.Format)
+		}
+		if key.LayerName != "" || key.Z < m.Raster.MinZoom || key.Z > m.Raster.MaxZoom {
+			return fmt.Sprintf("map (%v) raster is not served at zoom %v", m.Name, key.Z)
+		}
+		return ""
+	}
+
+	if m.HasUpstream() {
+		if key.LayerName != "" {
+			return fmt.Sprintf("map (%v) is an upstream map without layers", m.Name)
+		}
+		if key.Z < m.Upstream.MinZoom || key.Z > m.Upstream.MaxZoom {
+			return fmt.Sprintf("map (%v) upstream is not served at zoom %v", m.Name, key.Z)
+		}
+		return ""
+	}
+
+	layers := m.FilterLayersByZoom(key.Z).Layers
+	if key.LayerName == "" {
+		if len(layers) == 0 {
+			return fmt.Sprintf("map (%v) has no layers at zoom %v", m.Name, key.Z)
+		}
+		return ""
+	}
+
+	for _, l := range m.Layers {
+		if l.MVTName() != key.LayerName {
+			continue
+		}
+		for _, zl := range layers {
+			if zl.MVTName() == key.LayerName {
+				return ""
+			}
+		}
+		return fmt.Sprintf("map (%v) layer (%v) is not served at zoom %v", m.Name, key.LayerName, key.Z)
+	}
+	return fmt.Sprintf("map (%v) has no layer (%v)", m.Name, key.LayerName)
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+func TestPruner(t *testing.T) {
+	osm := atlas.NewWebMercatorMap("osm")
+	osm.Layers = []atlas.Layer{
+		{Name: "roads", MinZoom: 4, MaxZoom: 10, UTFGridKey: "name"},
+		{Name: "water", MaxZoom: 8},
+	}
+	osm.Raster = &atlas.Raster{MaxZoom: 12}
+
+	type tcase struct {
+		key   cache.Key
+		stale bool
+	}
+
+	tests := map[string]tcase{
+		"map tile":               {key: cache.Key{MapName: "osm", Z: 9}},
+		"map tile of no layer":   {key: cache.Key{MapName: "osm", Z: 11}, stale: true},
+		"layer tile":             {key: cache.Key{MapName: "osm", LayerName: "roads", Z: 10}},
+		"layer below min zoom":   {key: cache.Key{MapName: "osm", LayerName: "roads", Z: 3}, stale: true},
+		"removed layer":          {key: cache.Key{MapName: "osm", LayerName: "buildings", Z: 5}, stale: true},
+		"removed map":            {key: cache.Key{MapName: "old", Z: 5}, stale: true},
+		"previous cache version": {key: cache.Key{MapName: "osm@v1", Z: 5}, stale: true},
+		"raster tile":            {key: cache.Key{MapName: "osm", Z: 12, Format: "png"}},
+		"raster above max":       {key: cache.Key{MapName: "osm", Z: 13, Format: "png"}, stale: true},
+		"other raster format":    {key: cache.Key{MapName: "osm", Z: 5, Format: "jpg"}, stale: true},
+		"utfgrid tile":           {key: cache.Key{MapName: "osm", Z: 10, Format: atlas.UTFGridFormat}},
+		"utfgrid of no layer":    {key: cache.Key{MapName: "osm", Z: 3, Format: atlas.UTFGridFormat}, stale: true},
+	}
+
+	mc, _ := memory.New(nil)
+	for name, tc := range tests {
+		key := tc.key
+		if err := mc.Set(&key, []byte(name)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+
+	p := newPruner([]atlas.Map{osm}, false)
+	if err := p.run(context.Background(), mc); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if p.listed != uint64(len(tests)) {
+		t.Errorf("listed, expected %v got %v", len(tests), p.listed)
+	}
+
+	for name, tc := range tests {
+		key := tc.key
+		if _, hit, _ := mc.Get(&key); hit == tc.stale {
+			t.Errorf("%v: stale %v, expected the tile cached %v", name, tc.stale, !tc.stale)
+		}
+	}
+}
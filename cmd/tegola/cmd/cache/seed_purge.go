@@ -140,32 +140,41 @@ func seedPurgeCmdValidatePersistent(cmd *cobra.Command, args []string) error {
 func seedPurgeCmdValidate(cmd *cobra.Command, args []string) (err error) {
 
 	// validate and set bounds flag
-	boundsParts := strings.Split(strings.TrimSpace(cacheBounds), ",")
+	if seedPurgeBounds, err = parseBounds(cacheBounds); err != nil {
+		return err
+	}
+
+	// get the zoom ranges
+	if err = minMaxZoomValidate(cmd, args); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// parseBounds parses the lng/lat bounds flag in the format: minx, miny, maxx, maxy
+func parseBounds(bounds string) (b [4]float64, err error) {
+	boundsParts := strings.Split(strings.TrimSpace(bounds), ",")
 	if len(boundsParts) != 4 {
-		return fmt.Errorf("invalid value for bounds (%v). expecting minx, miny, maxx, maxy", cacheBounds)
+		return b, fmt.Errorf("invalid value for bounds (%v). expecting minx, miny, maxx, maxy", bounds)
 	}
 
 	var ok bool
 
-	if seedPurgeBounds[0], ok = IsValidLngString(boundsParts[0]); !ok {
-		return fmt.Errorf("invalid lng value(%v) for bounds (%v)", boundsParts[0], cacheBounds)
+	if b[0], ok = IsValidLngString(boundsParts[0]); !ok {
+		return b, fmt.Errorf("invalid lng value(%v) for bounds (%v)", boundsParts[0], bounds)
 	}
-	if seedPurgeBounds[1], ok = IsValidLatString(boundsParts[1]); !ok {
-		return fmt.Errorf("invalid lat value(%v) for bounds (%v)", boundsParts[1], cacheBounds)
+	if b[1], ok = IsValidLatString(boundsParts[1]); !ok {
+		return b, fmt.Errorf("invalid lat value(%v) for bounds (%v)", boundsParts[1], bounds)
 	}
-	if seedPurgeBounds[2], ok = IsValidLngString(boundsParts[2]); !ok {
-		return fmt.Errorf("invalid lng value(%v) for bounds (%v)", boundsParts[2], cacheBounds)
+	if b[2], ok = IsValidLngString(boundsParts[2]); !ok {
+		return b, fmt.Errorf("invalid lng value(%v) for bounds (%v)", boundsParts[2], bounds)
 	}
-	if seedPurgeBounds[3], ok = IsValidLatString(boundsParts[3]); !ok {
-		return fmt.Errorf("invalid lat value(%v) for bounds (%v)", boundsParts[3], cacheBounds)
+	if b[3], ok = IsValidLatString(boundsParts[3]); !ok {
+		return b, fmt.Errorf("invalid lat value(%v) for bounds (%v)", boundsParts[3], bounds)
 	}
 
-	// get the zoom ranges
-	if err = minMaxZoomValidate(cmd, args); err != nil {
-		return err
-	}
-
-	return nil
+	return b, nil
 }
 
 func seedPurgeCommand(cmd *cobra.Command, args []string) (err error) {
@@ -184,10 +193,32 @@ func seedPurgeCommand(cmd *cobra.Command, args []string) (err error) {
 		}
 	}()
 
+	maps, err := webMercatorMaps(seedPurgeMaps)
+	if err != nil {
+		return err
+	}
+
 	log.Info("zoom list: ", zooms)
 	tilechannel := generateTilesForBounds(ctx, seedPurgeBounds, zooms)
 
-	return doWork(ctx, tilechannel, seedPurgeMaps, cacheConcurrency, seedPurgeWorker)
+	return doWork(ctx, tilechannel, maps, cacheConcurrency, seedPurgeWorker)
+}
+
+// webMercatorMaps returns the maps of the web mercator grid, as the tiles of the bounds are
+// web mercator tiles. The maps of other grids are skipped.
+func webMercatorMaps(maps []atlas.Map) ([]atlas.Map, error) {
+	var wm []atlas.Map
+	for _, m := range maps {
+		if !m.TileGrid().IsWebMercator() {
+			log.Warnf("skipping map (%v), the tiles of its grid (%v) can't be generated for the bounds", m.Name, m.TileGrid().Name)
+			continue
+		}
+		wm = append(wm, m)
+	}
+	if len(wm) == 0 {
+		return nil, fmt.Errorf("no maps of the web mercator grid to generate the tiles of the bounds for")
+	}
+	return wm, nil
 }
 
 func generateTilesForBounds(ctx context.Context, bounds [4]float64, zooms []uint) *TileChannel {
@@ -0,0 +1,281 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/go-spatial/cobra"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	gdcmd "github.com/go-spatial/tegola/internal/cmd"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// flag parameters
+var (
+	verifyManifest string
+	verifyPurge    bool
+)
+
+var VerifyCmd = &cobra.Command{
+	Use:     "verify",
+	Short:   "verify cached tiles against a manifest",
+	Long:    "command to re-hash the cached tiles listed in a manifest (see tegola cache manifest) and compare them with the manifest's sizes and sha256 hashes, detecting silent corruption in file and object store caches",
+	Example: "tegola cache verify --manifest manifest.json --purge",
+	PreRunE: verifyCmdValidate,
+	RunE:    verifyCommand,
+}
+
+func init() {
+	VerifyCmd.Flags().StringVarP(&verifyManifest, "manifest", "", "", "the manifest to verify the cache against, in the json or csv format of tegola cache manifest. - for stdin")
+	VerifyCmd.Flags().StringVarP(&cacheMap, "map", "", "", "only verify the tiles of the map. defaults to all maps")
+	VerifyCmd.Flags().IntVarP(&cacheConcurrency, "concurrency", "", runtime.NumCPU(), "the amount of concurrency to use. defaults to the number of CPUs on the machine")
+	VerifyCmd.Flags().BoolVarP(&verifyPurge, "purge", "", false, "purge the corrupted tiles from the cache so they are regenerated")
+
+	VerifyCmd.SetUsageTemplate(defaultUsage)
+}
+
+func verifyCmdValidate(cmd *cobra.Command, args []string) error {
+	if verifyManifest == "" {
+		return fmt.Errorf("--manifest is required")
+	}
+	if cacheMap != "" {
+		if _, err := atlas.GetMap(cacheMap); err != nil {
+			return err
+		}
+	}
+	if cacheConcurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %v", cacheConcurrency)
+	}
+	return nil
+}
+
+func verifyCommand(cmd *cobra.Command, args []string) (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer gdcmd.New().Complete()
+	gdcmd.OnComplete(provider.Cleanup)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gdcmd.Cancelled():
+			cancel()
+		}
+	}()
+
+	var in io.Reader = os.Stdin
+	if verifyManifest != "-" {
+		f, err := os.Open(verifyManifest)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	c := atlas.GetCache()
+	if c == nil {
+		return fmt.Errorf("no cache configured")
+	}
+
+	v := verifier{cache: c, purge: verifyPurge}
+	if err = v.run(ctx, in, cacheMap, cacheConcurrency); err != nil {
+		return err
+	}
+
+	log.Infof("verified %v cached tiles: %v ok, %v corrupted, %v missing", v.ok+v.corrupted+v.missing, v.ok, v.corrupted, v.missing)
+	if v.corrupted > 0 {
+		return fmt.Errorf("%v cached tiles do not match the manifest", v.corrupted)
+	}
+	return nil
+}
+
+// verifier compares cached tiles with their manifest entries
+type verifier struct {
+	cache cache.Interface
+	// purge corrupted tiles
+	purge bool
+
+	sync.Mutex
+	ok        uint64
+	corrupted uint64
+	missing   uint64
+}
+
+// run verifies the entries of the manifest, of the map when set, with the concurrency
+func (v *verifier) run(ctx context.Context, manifest io.Reader, mapName string, concurrency int) error {
+	entries := make(chan ManifestEntry)
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var workerErr error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for e := range entries {
+				if err := v.verify(e); err != nil {
+					errOnce.Do(func() {
+						workerErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	err := readManifest(manifest, func(e ManifestEntry) error {
+		if mapName != "" && e.Map != mapName {
+			return nil
+		}
+		select {
+		case entries <- e:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(entries)
+	wg.Wait()
+
+	if workerErr != nil {
+		return workerErr
+	}
+	return err
+}
+
+// verify re-hashes the cached tile of the entry. Tiles missing from the cache are counted,
+// as they can have expired or been purged since the manifest was made.
+func (v *verifier) verify(e ManifestEntry) error {
+	key := cache.Key{MapName: e.Map, Z: e.Z, X: e.X, Y: e.Y}
+
+	data, hit, err := v.cache.Get(&key)
+	if err != nil {
+		return fmt.Errorf("error reading map (%v) tile (%v/%v/%v) from cache: %v", e.Map, e.Z, e.X, e.Y, err)
+	}
+
+	if !hit {
+		log.Warnf("map (%v) tile (%v/%v/%v) is missing from the cache", e.Map, e.Z, e.X, e.Y)
+		v.count(&v.missing)
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+	if hash := hex.EncodeToString(sum[:]); len(data) == e.Size && hash == e.SHA256 {
+		v.count(&v.ok)
+		return nil
+	}
+
+	log.Errorf("map (%v) tile (%v/%v/%v) is corrupted: expected %v bytes with sha256 %v got %v bytes with sha256 %x", e.Map, e.Z, e.X, e.Y, e.Size, e.SHA256, len(data), sum)
+	v.count(&v.corrupted)
+
+	if v.purge {
+		if err := v.cache.Purge(&key); err != nil {
+			return fmt.Errorf("error purging map (%v) tile (%v/%v/%v): %v", e.Map, e.Z, e.X, e.Y, err)
+		}
+	}
+	return nil
+}
+
+func (v *verifier) count(n *uint64) {
+	v.Lock()
+	*n++
+	v.Unlock()
+}
+
+// readManifest calls fn with the entries of a manifest written by the manifest command.
+// The format, JSON lines or CSV, is detected from the first character.
+func readManifest(r io.Reader, fn func(ManifestEntry) error) error {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if first[0] == '{' {
+		dec := json.NewDecoder(br)
+		for line := 1; ; line++ {
+			var e ManifestEntry
+			if err := dec.Decode(&e); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("invalid manifest entry (%v): %v", line, err)
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+
+	cr := csv.NewReader(br)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("invalid manifest header: %v", err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[h] = i
+	}
+	for _, h := range []string{"map", "z", "x", "y", "size", "sha256"} {
+		if _, ok := cols[h]; !ok {
+			return fmt.Errorf("invalid manifest header: missing the %v column", h)
+		}
+	}
+
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid manifest entry (%v): %v", line, err)
+		}
+
+		e := ManifestEntry{Map: rec[cols["map"]], SHA256: rec[cols["sha256"]]}
+		if i, ok := cols["url"]; ok {
+			e.URL = rec[i]
+		}
+
+		uints := []struct {
+			col string
+			val *uint
+		}{
+			{"z", &e.Z},
+			{"x", &e.X},
+			{"y", &e.Y},
+		}
+		for _, u := range uints {
+			n, err := strconv.ParseUint(rec[cols[u.col]], 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid manifest entry (%v) %v: %v", line, u.col, err)
+			}
+			*u.val = uint(n)
+		}
+		if e.Size, err = strconv.Atoi(rec[cols["size"]]); err != nil {
+			return fmt.Errorf("invalid manifest entry (%v) size: %v", line, err)
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+func TestReadManifest(t *testing.T) {
+	type tcase struct {
+		manifest string
+		expected []ManifestEntry
+		err      string
+	}
+
+	entry := ManifestEntry{
+		URL:    "https://tiles.example.com/maps/osm/1/0/1.pbf",
+		Map:    "osm",
+		Z:      1,
+		X:      0,
+		Y:      1,
+		Size:   3,
+		SHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			var entries []ManifestEntry
+			err := readManifest(strings.NewReader(tc.manifest), func(e ManifestEntry) error {
+				entries = append(entries, e)
+				return nil
+			})
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("expected error %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(entries, tc.expected) {
+				t.Errorf("entries, expected %+v got %+v", tc.expected, entries)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"json": {
+			manifest: `{"url":"https://tiles.example.com/maps/osm/1/0/1.pbf","map":"osm","z":1,"x":0,"y":1,"size":3,"sha256":"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}` + "\n",
+			expected: []ManifestEntry{entry},
+		},
+		"csv": {
+			manifest: "url,map,z,x,y,size,sha256\nhttps://tiles.example.com/maps/osm/1/0/1.pbf,osm,1,0,1,3,ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad\n",
+			expected: []ManifestEntry{entry},
+		},
+		"empty": {
+			manifest: "",
+		},
+		"csv missing column": {
+			manifest: "url,map,z,x,y,size\n",
+			err:      "invalid manifest header: missing the sha256 column",
+		},
+		"csv invalid zoom": {
+			manifest: "map,z,x,y,size,sha256\nosm,a,0,1,3,abc\n",
+			err:      `invalid manifest entry (2) z: strconv.ParseUint: parsing "a": invalid syntax`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	c, _ := memory.New(nil)
+	c.Set(&cache.Key{MapName: "osm", Z: 1, X: 0, Y: 1}, []byte("abc"))
+	c.Set(&cache.Key{MapName: "osm", Z: 1, X: 1, Y: 1}, []byte("ab"))
+
+	manifest := `{"map":"osm","z":1,"x":0,"y":1,"size":3,"sha256":"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}
+{"map":"osm","z":1,"x":1,"y":1,"size":3,"sha256":"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}
+{"map":"osm","z":1,"x":1,"y":0,"size":3,"sha256":"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}
+{"map":"other","z":1,"x":1,"y":0,"size":3,"sha256":"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}
+`
+
+	v := verifier{cache: c, purge: true}
+	if err := v.run(context.Background(), strings.NewReader(manifest), "osm", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v.ok != 1 || v.corrupted != 1 || v.missing != 1 {
+		t.Errorf("counts, expected 1 ok, 1 corrupted and 1 missing got %v ok, %v corrupted and %v missing", v.ok, v.corrupted, v.missing)
+	}
+
+	// the corrupted tile is purged
+	if _, hit, _ := c.Get(&cache.Key{MapName: "osm", Z: 1, X: 1, Y: 1}); hit {
+		t.Errorf("corrupted tile, expected it to be purged")
+	}
+	if _, hit, _ := c.Get(&cache.Key{MapName: "osm", Z: 1, X: 0, Y: 1}); !hit {
+		t.Errorf("valid tile, expected it to be kept")
+	}
+}
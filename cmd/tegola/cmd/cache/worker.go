@@ -43,8 +43,23 @@ func seedWorker(overwrite bool) func(ctx context.Context, mt MapTile) error {
 
 		z, x, y := mt.Tile.ZXY()
 
-		//	filter down the layers we need for this zoom
-		m = m.FilterLayersByZoom(z)
+		//	tiles above the cache max zoom are extracted from their ancestors when requested
+		if m.CacheMaxZoom != nil && z > *m.CacheMaxZoom {
+			log.Debugf("map (%v) tile (%v/%v/%v) is above the cache max zoom (%v). skipping", mt.MapName, z, x, y, *m.CacheMaxZoom)
+			return nil
+		}
+
+		//	maps outside of their availability windows are not seeded
+		now := time.Now()
+		if !m.Availability.Available(now) {
+			return seedPurgeWorkerTileError{
+				Tile: *mt.Tile,
+				Err:  fmt.Errorf("map (%v) is not available", mt.MapName),
+			}
+		}
+
+		//	filter down the layers we need for this zoom and time
+		m = m.FilterLayersByZoom(z).FilterLayersByAvailability(now)
 
 		//	check if overwriting the cache is not ok
 		if !overwrite {
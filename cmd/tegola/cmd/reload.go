@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cmd/internal/register"
+	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/server"
+)
+
+// providerCloseDelay is how long the providers replaced by a reload are kept open, so the
+// requests still using them can complete
+const providerCloseDelay = time.Minute
+
+// reloadLock serializes the map changes of the admin api
+var reloadLock sync.Mutex
+
+// registerMap validates the config of the map and adds it to the atlas with the registered
+// providers, replacing the map with the same name
+func registerMap(a *atlas.Atlas, m config.Map) error {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	if len(m.Cache) > 0 {
+		return fmt.Errorf("map (%v) cache backends can only be configured in the config file", m.Name)
+	}
+
+	cfg := config.Config{Providers: conf.Providers, Maps: []config.Map{m}}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	// register into an empty atlas, so the atlas is unchanged when the map is invalid
+	tmp := &atlas.Atlas{}
+	if err := register.Maps(tmp, cfg.Maps, registeredProviders); err != nil {
+		return err
+	}
+	newMap, err := tmp.Map(string(m.Name))
+	if err != nil {
+		return err
+	}
+	a.AddMap(newMap)
+	return nil
+}
+
+// reload loads the providers and maps of the config file and replaces the maps of the atlas.
+// The other sections of the config are only read on start.
+func reload(a *atlas.Atlas) error {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	log.Infof("Reloading config file: %v", configFile)
+	newConf, err := config.Load(configFile)
+	if err != nil {
+		return err
+	}
+	if err = newConf.Validate(); err != nil {
+		return err
+	}
+
+	provArr := make([]dict.Dicter, len(newConf.Providers))
+	for i := range provArr {
+		provArr[i] = newConf.Providers[i]
+	}
+	providers, err := register.Providers(provArr)
+	if err != nil {
+		discardProviders(providers)
+		return fmt.Errorf("could not register providers: %v", err)
+	}
+
+	tmp := &atlas.Atlas{}
+	if err = register.Maps(tmp, newConf.Maps, providers); err != nil {
+		discardProviders(providers)
+		return fmt.Errorf("could not register maps: %v", err)
+	}
+	a.ReplaceMaps(tmp.AllMaps())
+
+	old := registeredProviders
+	registeredProviders = providers
+	conf.Providers, conf.Maps = newConf.Providers, newConf.Maps
+	server.LayerImporter = register.LayerImporter(providers)
+
+	time.AfterFunc(providerCloseDelay, func() { closeProviders(old) })
+	return nil
+}
+
+// discardProviders closes the providers of a failed reload and restores the provider
+// instances they replaced
+func discardProviders(providers map[string]provider.TilerUnion) {
+	closeProviders(providers)
+	for name, p := range registeredProviders {
+		provider.SetInstance(name, p)
+	}
+}
+
+// closeProviders closes the providers holding connections
+func closeProviders(providers map[string]provider.TilerUnion) {
+	for name, p := range providers {
+		var prov interface{} = p.Std
+		if prov == nil {
+			prov = p.Mvt
+		}
+		switch c := prov.(type) {
+		case interface{ Close() error }:
+			if err := c.Close(); err != nil {
+				log.Errorf("closing provider (%v): %v", name, err)
+			}
+		case interface{ Close() }:
+			c.Close()
+		}
+	}
+}
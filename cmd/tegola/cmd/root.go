@@ -5,11 +5,13 @@ import (
 
 	"github.com/go-spatial/cobra"
 	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/cmd/internal/register"
 	cachecmd "github.com/go-spatial/tegola/cmd/tegola/cmd/cache"
 	"github.com/go-spatial/tegola/config"
 	"github.com/go-spatial/tegola/dict"
 	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
 )
 
 var (
@@ -21,6 +23,9 @@ var (
 
 	// require cache
 	RequireCache bool
+
+	// the providers registered from the config, for the layer import endpoint
+	registeredProviders map[string]provider.TilerUnion
 )
 
 func init() {
@@ -66,6 +71,15 @@ func initConfig(configFile string, cacheRequired bool) (err error) {
 		return err
 	}
 
+	// load provider plugins before the providers using them are registered
+	if conf.PluginDir != "" {
+		names, err := provider.LoadPlugins(string(conf.PluginDir))
+		if err != nil {
+			return fmt.Errorf("could not load provider plugins: %v", err)
+		}
+		log.Infof("loaded provider plugins from %v: %v", conf.PluginDir, names)
+	}
+
 	// init our providers
 	// but first convert []env.Map -> []dict.Dicter
 	provArr := make([]dict.Dicter, len(conf.Providers))
@@ -77,24 +91,35 @@ func initConfig(configFile string, cacheRequired bool) (err error) {
 	if err != nil {
 		return fmt.Errorf("could not register providers: %v", err)
 	}
+	registeredProviders = providers
 
 	// init our maps
 	if err = register.Maps(nil, conf.Maps, providers); err != nil {
 		return fmt.Errorf("could not register maps: %v", err)
 	}
-	if len(conf.Cache) == 0 && cacheRequired {
+	mapCaches := false
+	for _, m := range conf.Maps {
+		mapCaches = mapCaches || len(m.Cache) > 0
+	}
+	if len(conf.Cache) == 0 && !mapCaches && cacheRequired {
 		return fmt.Errorf("no cache defined in config, please check your config (%v)", configFile)
 	}
 	if serverNoCache {
 		log.Info("Cache explicitly turned off by user via command line")
-	} else if len(conf.Cache) > 0 {
+	} else if len(conf.Cache) > 0 || mapCaches {
 		// init cache backends
-		cache, err := register.Cache(conf.Cache)
-		if err != nil {
+		var cacher cache.Interface
+		if len(conf.Cache) > 0 {
+			if cacher, err = register.Cache(conf.Cache); err != nil {
+				return fmt.Errorf("could not register cache: %v", err)
+			}
+		}
+		// the maps with their own cache backend
+		if cacher, err = register.MapCaches(cacher, conf.Maps); err != nil {
 			return fmt.Errorf("could not register cache: %v", err)
 		}
-		if cache != nil {
-			atlas.SetCache(cache)
+		if cacher != nil {
+			atlas.SetCache(cacher)
 		}
 	}
 	return nil
@@ -5,10 +5,16 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/go-spatial/cobra"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cmd/internal/register"
+	"github.com/go-spatial/tegola/config"
 	gdcmd "github.com/go-spatial/tegola/internal/cmd"
+	"github.com/go-spatial/tegola/internal/ratelimit"
+	"github.com/go-spatial/tegola/internal/tracing"
 	"github.com/go-spatial/tegola/provider"
 	"github.com/go-spatial/tegola/server"
 )
@@ -37,6 +43,35 @@ var serverCmd = &cobra.Command{
 		// set our server version
 		server.Version = Version
 		server.HostName = string(conf.Webserver.HostName)
+		server.AdminToken = string(conf.Webserver.AdminToken)
+		// report the region and cache backend in the response headers
+		server.Region = string(conf.Webserver.Region)
+		server.CacheTier, _ = conf.Cache.String("type", nil)
+		for _, m := range conf.Maps {
+			if tier, _ := m.Cache.String("type", nil); tier != "" {
+				server.MapCacheTiers[string(m.Name)] = tier
+			}
+		}
+		if conf.Webserver.SurrogateKeyIndexSize != nil {
+			server.SurrogateKeyIndexSize = uint(*conf.Webserver.SurrogateKeyIndexSize)
+		}
+		if conf.Webserver.ContentEncodingCacheSize != nil {
+			server.ContentEncodingCacheSize = uint(*conf.Webserver.ContentEncodingCacheSize)
+		}
+		// cache empty tiles and failed tile requests
+		if nc := conf.Webserver.NegativeCache; nc.EmptyTTL != nil {
+			server.NegativeCacheEmptyTTL = time.Duration(*nc.EmptyTTL) * time.Second
+		}
+		if nc := conf.Webserver.NegativeCache; nc.ErrorTTL != nil {
+			server.NegativeCacheErrorTTL = time.Duration(*nc.ErrorTTL) * time.Second
+		}
+		if nc := conf.Webserver.NegativeCache; nc.MaxEntries != nil {
+			server.NegativeCacheSize = uint(*nc.MaxEntries)
+		}
+		// render tiles requested concurrently once
+		if conf.Webserver.CoalesceTileRequests != nil {
+			server.CoalesceTileRequests = bool(*conf.Webserver.CoalesceTileRequests)
+		}
 
 		// set user defined response headers
 		for name, value := range conf.Webserver.Headers {
@@ -50,10 +85,88 @@ var serverCmd = &cobra.Command{
 			server.Headers[name] = val
 		}
 
+		// set the geofences and the key classes they're limited to
+		geofences, err := register.Geofences(conf.Webserver.Geofences)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.Geofences = geofences
+		server.KeyClasses = register.KeyClasses(conf.Webserver.KeyClasses)
+		// authorize the requests bypassing the tile cache
+		server.CacheBypassSecret = string(conf.Webserver.CacheBypass.Secret)
+		for _, c := range conf.Webserver.CacheBypass.KeyClasses {
+			server.CacheBypassKeyClasses = append(server.CacheBypassKeyClasses, string(c))
+		}
+
+		// glyphs and sprites served to map clients
+		if conf.Webserver.Fonts != "" {
+			if server.Fonts, err = server.NewAssets(string(conf.Webserver.Fonts)); err != nil {
+				log.Fatalf("webserver.fonts: %v", err)
+			}
+		}
+		if conf.Webserver.Sprites != "" {
+			if server.Sprites, err = server.NewAssets(string(conf.Webserver.Sprites)); err != nil {
+				log.Fatalf("webserver.sprites: %v", err)
+			}
+		}
+
+		// rate limit the tile requests of each client address and api key
+		if rl := conf.Webserver.RateLimit; rl.Rate > 0 || rl.KeyRate > 0 {
+			if rl.Rate > 0 {
+				server.IPRateLimiter = &ratelimit.Limiter{Rate: float64(rl.Rate), Burst: int(rl.Burst), MaxClients: int(rl.MaxClients)}
+			}
+			if rl.KeyRate > 0 {
+				server.KeyRateLimiter = &ratelimit.Limiter{Rate: float64(rl.KeyRate), Burst: int(rl.KeyBurst), MaxClients: int(rl.MaxClients)}
+			}
+			// the allowlist is checked by the config validation
+			server.RateLimitAllowlist, _ = rl.AllowlistNetworks()
+			for _, k := range rl.AllowlistKeys {
+				server.RateLimitAllowlistKeys = append(server.RateLimitAllowlistKeys, string(k))
+			}
+			server.RateLimitForwardedFor = bool(rl.ForwardedFor)
+		}
+
+		server.AccessLog = bool(conf.Webserver.AccessLog)
+		server.ServerTiming = bool(conf.Webserver.ServerTiming)
+
+		// shed the load of the tile requests
+		server.RequestTimeout = time.Duration(conf.Webserver.RequestTimeoutMS) * time.Millisecond
+		server.MaxInFlightTiles = int(conf.Webserver.MaxInFlightTiles)
+
+		// authenticate the tile requests
+		if jwt := conf.Webserver.JWT; jwt.Secret != "" || jwt.JWKSURL != "" {
+			server.JWT = &server.JWTVerifier{
+				Secret:     []byte(jwt.Secret),
+				JWKSURL:    string(jwt.JWKSURL),
+				Issuer:     string(jwt.Issuer),
+				Audience:   string(jwt.Audience),
+				AdminScope: string(jwt.AdminScope),
+				Private:    bool(jwt.Private),
+			}
+		}
+
+		// authorize the tile requests of signed urls
+		if su := conf.Webserver.SignedURLs; len(su.Keys) > 0 {
+			server.SignedURLs = &server.URLSigner{
+				Keys:     map[string][]byte{},
+				Required: bool(su.Required),
+				MaxTTL:   time.Duration(su.MaxTTL) * time.Second,
+			}
+			for _, k := range su.Keys {
+				server.SignedURLs.Keys[string(k.Name)] = []byte(k.Secret)
+			}
+		}
+
 		if conf.Webserver.URIPrefix != "" {
 			server.URIPrefix = string(conf.Webserver.URIPrefix)
 		}
 
+		// the absolute urls of the responses, checked by the config validation
+		if conf.Webserver.URLRoot != "" {
+			server.ConfiguredURLRoot, _ = url.Parse(string(conf.Webserver.URLRoot))
+		}
+		server.TrustedProxies, _ = conf.Webserver.TrustedProxyNetworks()
+
 		if conf.Webserver.SSLCert+conf.Webserver.SSLKey != "" {
 			if conf.Webserver.SSLCert == "" {
 				// error
@@ -69,6 +182,81 @@ var serverCmd = &cobra.Command{
 			server.SSLKey = string(conf.Webserver.SSLKey)
 		}
 
+		// obtain the certificates of the hosts from an ACME certificate authority
+		if acme := conf.Webserver.ACME; len(acme.Hosts) > 0 {
+			hosts := make([]string, len(acme.Hosts))
+			for i := range acme.Hosts {
+				hosts[i] = string(acme.Hosts[i])
+			}
+			server.ACME = server.NewACME(hosts, string(acme.Email), string(acme.CacheDir), string(acme.DirectoryURL))
+			server.ACMEHTTPPort = string(acme.HTTPPort)
+		}
+
+		// export the spans of the tile requests to an OpenTelemetry collector
+		if tracer := register.Tracer(conf.Tracing); tracer != nil {
+			tracingCtx, cancelTracing := context.WithCancel(context.Background())
+			gdcmd.OnComplete(cancelTracing)
+			tracing.SetTracer(tracer)
+			go tracer.Run(tracingCtx)
+		}
+
+		// monitor the freshness SLAs of the map layers
+		freshnessCtx, cancelFreshness := context.WithCancel(context.Background())
+		gdcmd.OnComplete(cancelFreshness)
+		server.FreshnessMonitor = register.FreshnessMonitor(nil, conf.Freshness)
+		go server.FreshnessMonitor.Run(freshnessCtx)
+
+		// re-seed the cached tiles of changed data, sharing the freshness monitor's lifetime
+		if atlas.GetCache() != nil {
+			server.Reseeder = register.Reseeder(nil, conf.Reseed)
+			server.Reseeder.OnReseed = server.PublishInvalidationEvents
+			go server.Reseeder.Run(freshnessCtx)
+		}
+
+		// scope and rate limit the tile requests by api key
+		if len(conf.Webserver.APIKeys) > 0 {
+			keys, err := register.APIKeys(conf.Webserver.APIKeys, atlas.GetCache())
+			if err != nil {
+				log.Fatalf("could not register api keys: %v", err)
+			}
+			server.APIKeys = keys
+			go keys.Run(freshnessCtx)
+		}
+
+		// import provider layers through the admin api
+		server.LayerImporter = register.LayerImporter(registeredProviders)
+
+		// serve the maps of the tenants on their host names
+		if len(conf.Webserver.Tenants) > 0 {
+			tenants, err := register.Tenants(nil, conf.Webserver.Tenants, conf.Maps)
+			if err != nil {
+				log.Fatalf("could not register tenants: %v", err)
+			}
+			server.Tenants = tenants
+			server.TenantHeader = string(conf.Webserver.TenantHeader)
+			server.TenantFallback = bool(conf.Webserver.TenantFallback)
+		}
+
+		// manage the maps and reload the config through the admin api
+		server.MapRegistrar = registerMap
+		server.Reloader = reload
+
+		// propagate cache purges to the other instances of the deployment
+		if len(conf.InvalidationBus) > 0 {
+			bus, err := register.InvalidationBus(conf.InvalidationBus)
+			if err != nil {
+				log.Fatalf("could not register invalidation bus: %v", err)
+			}
+			server.InvalidationBus = bus
+			gdcmd.OnComplete(func() { bus.Close() })
+		}
+
+		// seed the hottest tiles before the server starts listening, so the instance is
+		// added to the load balancer with a warm cache
+		if len(conf.Warmup.Maps) > 0 && atlas.GetCache() != nil {
+			warmup(conf.Warmup)
+		}
+
 		// start our webserver
 		srv := server.Start(nil, serverPort)
 		shutdown(srv)
@@ -78,6 +266,33 @@ var serverCmd = &cobra.Command{
 	},
 }
 
+func warmup(cfg config.Warmup) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if cfg.Timeout != nil && *cfg.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(*cfg.Timeout)*time.Second)
+	}
+	defer cancel()
+	// stop warming up when the server is stopped
+	go func() {
+		select {
+		case <-gdcmd.Cancelled():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	t := time.Now()
+	log.Printf("warming up the cache of %v maps", len(cfg.Maps))
+	res, err := register.Warmup(nil, cfg).Run(ctx)
+	switch {
+	case err == context.DeadlineExceeded:
+		log.Printf("cache warm-up timed out after %v, starting the server", time.Since(t))
+	case err != nil && err != context.Canceled:
+		log.Fatalf("could not warm up the cache: %v", err)
+	}
+	log.Printf("cache warm-up seeded %v tiles (%v cached already, %v failed) in %v", res.Seeded, res.Cached, res.Failed, time.Since(t))
+}
+
 func shutdown(srv *http.Server) {
 	gdcmd.OnComplete(func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/akrylysov/algnhsa"
 	"github.com/dimfeld/httptreemux"
 
 	"github.com/go-spatial/geom/encoding/mvt"
 	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/cmd/internal/register"
 	"github.com/go-spatial/tegola/config"
 	"github.com/go-spatial/tegola/dict"
@@ -78,15 +80,19 @@ func init() {
 	}
 
 	// check if a cache backend is provided
+	var cacher cache.Interface
 	if len(conf.Cache) != 0 {
 		// register the cache backend
-		cache, err := register.Cache(conf.Cache)
-		if err != nil {
+		if cacher, err = register.Cache(conf.Cache); err != nil {
 			log.Fatal(err)
 		}
-		if cache != nil {
-			atlas.SetCache(cache)
-		}
+	}
+	// register the cache backends of the maps with their own cache
+	if cacher, err = register.MapCaches(cacher, conf.Maps); err != nil {
+		log.Fatal(err)
+	}
+	if cacher != nil {
+		atlas.SetCache(cacher)
 	}
 
 	// set our server version
@@ -94,6 +100,33 @@ func init() {
 	if conf.Webserver.HostName != "" {
 		server.HostName = string(conf.Webserver.HostName)
 	}
+	if conf.Webserver.URLRoot != "" {
+		server.ConfiguredURLRoot, _ = url.Parse(string(conf.Webserver.URLRoot))
+	}
+
+	// report the region and cache backend in the response headers
+	server.Region = string(conf.Webserver.Region)
+	server.CacheTier, _ = conf.Cache.String("type", nil)
+	for _, m := range conf.Maps {
+		if tier, _ := m.Cache.String("type", nil); tier != "" {
+			server.MapCacheTiers[string(m.Name)] = tier
+		}
+	}
+
+	// cache empty tiles and failed tile requests for the lifetime of the instance
+	if nc := conf.Webserver.NegativeCache; nc.EmptyTTL != nil {
+		server.NegativeCacheEmptyTTL = time.Duration(*nc.EmptyTTL) * time.Second
+	}
+	if nc := conf.Webserver.NegativeCache; nc.ErrorTTL != nil {
+		server.NegativeCacheErrorTTL = time.Duration(*nc.ErrorTTL) * time.Second
+	}
+	if nc := conf.Webserver.NegativeCache; nc.MaxEntries != nil {
+		server.NegativeCacheSize = uint(*nc.MaxEntries)
+	}
+	// render tiles requested concurrently once
+	if conf.Webserver.CoalesceTileRequests != nil {
+		server.CoalesceTileRequests = bool(*conf.Webserver.CoalesceTileRequests)
+	}
 
 	// set user defined response headers
 	for name, value := range conf.Webserver.Headers {
@@ -107,6 +140,19 @@ func init() {
 		server.Headers[name] = val
 	}
 
+	// set the geofences and the key classes they're limited to
+	geofences, err := register.Geofences(conf.Webserver.Geofences)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.Geofences = geofences
+	server.KeyClasses = register.KeyClasses(conf.Webserver.KeyClasses)
+	// authorize the requests bypassing the tile cache
+	server.CacheBypassSecret = string(conf.Webserver.CacheBypass.Secret)
+	for _, c := range conf.Webserver.CacheBypass.KeyClasses {
+		server.CacheBypassKeyClasses = append(server.CacheBypassKeyClasses, string(c))
+	}
+
 	if conf.Webserver.URIPrefix != "" {
 		server.URIPrefix = string(conf.Webserver.URIPrefix)
 	}
@@ -129,6 +175,11 @@ func main() {
 // URLRoot overrides the default server.URLRoot function in order to include the "stage" part of the root
 // that is part of lambda's URL scheme
 func URLRoot(r *http.Request) *url.URL {
+	if server.ConfiguredURLRoot != nil {
+		root := *server.ConfiguredURLRoot
+		return &root
+	}
+
 	u := url.URL{
 		Scheme: scheme(r),
 		Host:   r.Header.Get("Host"),
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"time"
+
+	"github.com/go-spatial/tegola/internal/env"
+)
+
+// availabilityLayouts are the formats accepted for availability window times
+var availabilityLayouts = []string{
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// AvailabilityWindow is a window of time a map or layer is served. An empty From or Until
+// leaves the window open at that end.
+type AvailabilityWindow struct {
+	// From is the RFC 3339 time or the date (midnight UTC) the window starts
+	From env.String `toml:"from"`
+	// Until is the RFC 3339 time or the date (midnight UTC) the window ends, exclusive
+	Until env.String `toml:"until"`
+}
+
+// Times returns the bounds of the window. The zero time is returned for open ends.
+func (w AvailabilityWindow) Times() (from, until time.Time, err error) {
+	parse := func(s env.String) (time.Time, error) {
+		if s == "" {
+			return time.Time{}, nil
+		}
+		var err error
+		for _, layout := range availabilityLayouts {
+			var t time.Time
+			if t, err = time.Parse(layout, string(s)); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, err
+	}
+
+	if from, err = parse(w.From); err != nil {
+		return from, until, ErrInvalidAvailability{From: string(w.From), Until: string(w.Until), Err: err}
+	}
+	if until, err = parse(w.Until); err != nil {
+		return from, until, ErrInvalidAvailability{From: string(w.From), Until: string(w.Until), Err: err}
+	}
+	if !from.IsZero() && !until.IsZero() && !until.After(from) {
+		return from, until, ErrInvalidAvailability{From: string(w.From), Until: string(w.Until), Err: errUntilBeforeFrom}
+	}
+	return from, until, nil
+}
+
+// validateAvailability checks the windows can be parsed
+func validateAvailability(windows []AvailabilityWindow) error {
+	for _, w := range windows {
+		if _, _, err := w.Times(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// availabilityOverlaps reports if any window of a overlaps any window of b. No windows
+// means always available.
+func availabilityOverlaps(a, b []AvailabilityWindow) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, wa := range a {
+		aFrom, aUntil, _ := wa.Times()
+		for _, wb := range b {
+			bFrom, bUntil, _ := wb.Times()
+			// the window ends are exclusive
+			if (aUntil.IsZero() || bFrom.IsZero() || bFrom.Before(aUntil)) &&
+				(bUntil.IsZero() || aFrom.IsZero() || aFrom.Before(bUntil)) {
+				return true
+			}
+		}
+	}
+	return false
+}
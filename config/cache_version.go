@@ -0,0 +1,62 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+
+	"github.com/go-spatial/tegola/internal/env"
+)
+
+// CacheVersionAuto is the cache_version of maps versioned by a hash of their config
+const CacheVersionAuto = "auto"
+
+// cache versions are part of the map name segment of the cache keys
+var validCacheVersion = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ConfigureCacheVersions replaces the "auto" cache version of the maps with a hash of the
+// config of the map and of the providers of its layers, so any change to them invalidates
+// the map's cached tiles
+func (c *Config) ConfigureCacheVersions() {
+	for mapKey, m := range c.Maps {
+		if m.CacheVersion != CacheVersionAuto {
+			continue
+		}
+
+		var hashed struct {
+			Map       Map
+			Providers []map[string]interface{}
+		}
+		hashed.Map = m
+		seen := map[string]bool{}
+		for _, l := range m.Layers {
+			prdID, _, err := l.ProviderLayerID()
+			if err != nil || seen[prdID] {
+				continue
+			}
+			seen[prdID] = true
+			for _, p := range c.Providers {
+				if name, _ := p.String("name", nil); name == prdID {
+					hashed.Providers = append(hashed.Providers, p)
+				}
+			}
+		}
+
+		b, err := json.Marshal(hashed)
+		if err != nil {
+			// leave the version for Validate to report
+			continue
+		}
+		sum := sha256.Sum256(b)
+		c.Maps[mapKey].CacheVersion = env.String(hex.EncodeToString(sum[:])[:12])
+	}
+}
+
+// validateCacheVersion checks the cache version can be used in the cache keys
+func validateCacheVersion(m Map) error {
+	if m.CacheVersion == "" || validCacheVersion.MatchString(string(m.CacheVersion)) {
+		return nil
+	}
+	return ErrInvalidCacheVersion{MapName: string(m.Name), Version: string(m.CacheVersion)}
+}
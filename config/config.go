@@ -4,7 +4,9 @@ package config
 import (
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -37,6 +39,70 @@ type Config struct {
 	// Note: Use the type to figure out if the provider is a mvt or std provider
 	Providers []env.Dict `toml:"providers"`
 	Maps      []Map      `toml:"maps"`
+	// PluginDir is a directory of Go plugins (.so files) loaded at startup which register
+	// additional providers
+	PluginDir env.String `toml:"plugin_dir"`
+	// Freshness configures the monitoring of map layers with a freshness_sla
+	Freshness Freshness `toml:"freshness"`
+	// InvalidationBus propagates the purges of cached tiles between the instances of a deployment
+	InvalidationBus env.Dict `toml:"invalidation_bus"`
+	// Reseed configures the re-seeding of the cached tiles of changed data
+	Reseed Reseed `toml:"reseed"`
+	// Warmup configures the seeding of tiles at startup, before the server starts listening
+	Warmup Warmup `toml:"warmup"`
+	// Tracing configures the export of the spans of tile requests to an OpenTelemetry collector
+	Tracing Tracing `toml:"tracing"`
+}
+
+// Tracing represents the config options of the OpenTelemetry tracing of tile requests.
+// Spans are recorded when an OTLP endpoint is configured.
+type Tracing struct {
+	// OTLPEndpoint is the base url of the OTLP/HTTP collector, i.e. http://otel-collector:4318
+	OTLPEndpoint env.String `toml:"otlp_endpoint"`
+	// ServiceName is the service.name of the spans. Defaults to "tegola".
+	ServiceName env.String `toml:"service_name"`
+	// SampleRate is the fraction of the traces started by tegola which are recorded. Defaults to 1.
+	SampleRate *env.Float `toml:"sample_rate"`
+	// Headers are added to the requests to the collector, i.e. for authentication
+	Headers env.Dict `toml:"headers"`
+}
+
+// Warmup represents the config options of the seeding of the hottest tiles into the cache at
+// startup, so an instance is added to a load balancer with a warm cache
+type Warmup struct {
+	// Concurrency is the number of tiles rendered at once. Defaults to the number of CPUs.
+	Concurrency *env.Uint `toml:"concurrency"`
+	// Timeout is the number of seconds the warm-up may take before the server starts listening
+	// regardless. Defaults to 0 (no timeout).
+	Timeout *env.Uint `toml:"timeout"`
+	// Maps are the bounds and zooms of the maps to warm up
+	Maps []WarmupMap `toml:"maps"`
+}
+
+// WarmupMap represents the area of a map seeded at startup
+type WarmupMap struct {
+	Name env.String `toml:"name"`
+	// Bounds of the area as minx, miny, maxx, maxy in WGS84. Defaults to the bounds of the map.
+	Bounds  []env.Float `toml:"bounds"`
+	MinZoom *env.Uint   `toml:"min_zoom"`
+	MaxZoom *env.Uint   `toml:"max_zoom"`
+}
+
+// Reseed represents the config options of the re-seeding of the cached tiles of the data
+// reported changed by the providers
+type Reseed struct {
+	// Interval is the number of seconds between checks for changed data. Defaults to 300.
+	Interval *env.Uint `toml:"interval"`
+	// MaxTiles is the number of tiles of a map checked for each check. Defaults to 10000.
+	MaxTiles *env.Uint `toml:"max_tiles"`
+}
+
+// Freshness represents the config options of the layer freshness monitor
+type Freshness struct {
+	// Interval is the number of seconds between freshness checks. Defaults to 60.
+	Interval *env.Uint `toml:"interval"`
+	// WebhookURL is posted a JSON alert when a layer goes into or out of violation of its freshness_sla
+	WebhookURL env.String `toml:"webhook_url"`
 }
 
 // Webserver represents the config options for the webserver part of Tegola
@@ -47,6 +113,179 @@ type Webserver struct {
 	Headers   env.Dict   `toml:"headers"`
 	SSLCert   env.String `toml:"ssl_cert"`
 	SSLKey    env.String `toml:"ssl_key"`
+	// URLRoot is the scheme, host and path prefix of the absolute urls of the responses, i.e.
+	// https://example.com/tiles, for deployments behind path prefixed ingresses. Takes
+	// precedence over the hostname and the X-Forwarded headers.
+	URLRoot env.String `toml:"url_root"`
+	// TrustedProxies are the addresses and CIDR networks of the proxies whose
+	// X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-Prefix headers are used to build
+	// the absolute urls of the responses
+	TrustedProxies []env.String `toml:"trusted_proxies"`
+	// ACME obtains and renews the certificates of the hosts from an ACME certificate
+	// authority, i.e. Let's Encrypt, instead of reading ssl_cert and ssl_key
+	ACME ACME `toml:"acme"`
+	// AdminToken enables the admin endpoints. requests to the endpoints must provide
+	// the token as a bearer token
+	AdminToken env.String `toml:"admin_token"`
+	// SurrogateKeyIndexSize is the maximum number of cached tiles indexed by surrogate key
+	// for PURGE requests. Defaults to 100000.
+	SurrogateKeyIndexSize *env.Uint `toml:"surrogate_key_index_size"`
+	// ContentEncodingCacheSize is the maximum number of tiles kept in memory encoded with
+	// brotli, deflate or a registered content coding. Defaults to 1000, 0 disables it.
+	ContentEncodingCacheSize *env.Uint `toml:"content_encoding_cache_size"`
+	// Geofences block or log tile requests inside sensitive regions
+	Geofences []Geofence `toml:"geofences"`
+	// KeyClasses group the API keys requests are identified by, for geofences
+	KeyClasses []KeyClass `toml:"key_classes"`
+	// Region of the deployment, reported in the Tegola-Region response header
+	Region env.String `toml:"region"`
+	// NegativeCache caches empty tiles and failed tile requests in memory
+	NegativeCache NegativeCache `toml:"negative_cache"`
+	// CoalesceTileRequests renders a tile requested by concurrent requests once.
+	// Defaults to true.
+	CoalesceTileRequests *env.Bool `toml:"coalesce_tile_requests"`
+	// CacheBypass authorizes the requests which skip reading the tile cache with the
+	// X-Tegola-No-Cache header
+	CacheBypass CacheBypass `toml:"cache_bypass"`
+	// Fonts is the directory or s3://bucket/prefix of the glyphs served on /fonts
+	Fonts env.String `toml:"fonts"`
+	// Sprites is the directory or s3://bucket/prefix of the sprites served on /sprites
+	Sprites env.String `toml:"sprites"`
+	// JWT authenticates the tile requests with JSON Web Tokens
+	JWT JWT `toml:"jwt"`
+	// APIKeys configures the store of the API keys scoping and rate limiting the tile requests
+	APIKeys env.Dict `toml:"api_keys"`
+	// RateLimit limits the rate of the tile requests of each client address and API key
+	RateLimit RateLimit `toml:"rate_limit"`
+	// AccessLog writes a JSON line for every tile request to stdout
+	AccessLog env.Bool `toml:"access_log"`
+	// ServerTiming sends the durations of the layer queries, the tile encoding and the cache
+	// read of the tile requests in a Server-Timing header
+	ServerTiming env.Bool `toml:"server_timing"`
+	// RequestTimeoutMS is the number of milliseconds a tile request may take, passed to the
+	// providers as the deadline of their queries. 0 (default) disables the timeout.
+	RequestTimeoutMS env.Uint `toml:"request_timeout_ms"`
+	// MaxInFlightTiles is the number of tiles rendered at once. Requests over the limit are
+	// answered with a 503. 0 (default) is unlimited.
+	MaxInFlightTiles env.Uint `toml:"max_in_flight_tiles"`
+	// Tenants are served a subset of the maps on their own host names
+	Tenants []Tenant `toml:"tenants"`
+	// TenantHeader is the request header naming the tenant of a request, set by a proxy in
+	// front of tegola. Takes precedence over the host names of the tenants.
+	TenantHeader env.String `toml:"tenant_header"`
+	// TenantFallback serves every map to the requests of no tenant, which are otherwise
+	// rejected. Defaults to false.
+	TenantFallback env.Bool `toml:"tenant_fallback"`
+	// SignedURLs authorizes the tile requests of expiring urls signed with HMAC keys
+	SignedURLs SignedURLs `toml:"signed_urls"`
+}
+
+// SignedURLs represents the config options of the signed tile urls, which authorize the tiles
+// of a map until they expire without a JWT or API key
+type SignedURLs struct {
+	// Keys are the keys the urls are signed with. Keys are named in the urls, so they can be
+	// rotated.
+	Keys []SigningKey `toml:"keys"`
+	// Required rejects the tile requests without a signature. Defaults to false.
+	Required env.Bool `toml:"required"`
+	// MaxTTL is the number of seconds a url can be signed for, checked against the expiry of
+	// the urls. 0 (default) is unlimited.
+	MaxTTL env.Uint `toml:"max_ttl"`
+}
+
+// SigningKey represents a key tile urls are signed with
+type SigningKey struct {
+	// Name identifies the key in the key parameter of the urls
+	Name env.String `toml:"name"`
+	// Secret is the HMAC-SHA256 secret of the key
+	Secret env.String `toml:"secret"`
+}
+
+// Tenant represents the config options of a tenant, whose requests are only served its maps
+type Tenant struct {
+	// Name of the tenant, matched against the tenant_header
+	Name env.String `toml:"name"`
+	// Hosts are the host names of the requests of the tenant
+	Hosts []env.String `toml:"hosts"`
+	// Maps are the names of the maps of the tenant
+	Maps []env.String `toml:"maps"`
+	// Cache is the cache backend of the tenant's tiles. Defaults to the cache of the config.
+	Cache env.Dict `toml:"cache"`
+}
+
+// ACME represents the config options of the certificates obtained from an ACME certificate
+// authority. Certificates are obtained when hosts are configured.
+type ACME struct {
+	// Hosts are the host names certificates are obtained for
+	Hosts []env.String `toml:"hosts"`
+	// Email is the contact address of the account, notified of problems with the certificates
+	Email env.String `toml:"email"`
+	// CacheDir is the directory the account key and certificates are kept in across restarts
+	CacheDir env.String `toml:"cache_dir"`
+	// DirectoryURL is the directory of the certificate authority. Defaults to Let's Encrypt.
+	DirectoryURL env.String `toml:"directory_url"`
+	// HTTPPort answers the HTTP-01 challenges and redirects the other requests to HTTPS.
+	// Not listened on when empty.
+	HTTPPort env.String `toml:"http_port"`
+}
+
+// RateLimit represents the config options of the token bucket rate limits of the tile requests
+type RateLimit struct {
+	// Rate is the number of requests per second of a client address. Not limited when 0.
+	Rate env.Float `toml:"rate"`
+	// Burst is the number of requests of an address allowed at once. Defaults to the rate.
+	Burst env.Uint `toml:"burst"`
+	// KeyRate is the number of requests per second of an API key. The requests with an API
+	// key are limited by address when 0.
+	KeyRate env.Float `toml:"key_rate"`
+	// KeyBurst is the number of requests of an API key allowed at once. Defaults to the key rate.
+	KeyBurst env.Uint `toml:"key_burst"`
+	// Allowlist are the addresses and CIDR networks which are not rate limited
+	Allowlist []env.String `toml:"allowlist"`
+	// AllowlistKeys are the API keys which are not rate limited
+	AllowlistKeys []env.String `toml:"allowlist_keys"`
+	// ForwardedFor reads the client address from the X-Forwarded-For header set by a proxy
+	ForwardedFor env.Bool `toml:"forwarded_for"`
+	// MaxClients is the number of clients tracked at once. Defaults to 100000.
+	MaxClients env.Uint `toml:"max_clients"`
+}
+
+// JWT represents the config options of the JSON Web Tokens the tile requests must supply.
+// Tile requests are authenticated when a secret or JWKS url is configured.
+type JWT struct {
+	// Secret verifies the tokens signed with HMAC (HS256, HS384, HS512)
+	Secret env.String `toml:"secret"`
+	// JWKSURL is the url of the JSON Web Key Set verifying the tokens signed with RSA or ECDSA
+	JWKSURL env.String `toml:"jwks_url"`
+	// Issuer the iss claim of the tokens must match
+	Issuer env.String `toml:"issuer"`
+	// Audience the aud claim of the tokens must contain
+	Audience env.String `toml:"audience"`
+	// AdminScope authorizes the tokens whose scope contains it on the admin endpoints
+	AdminScope env.String `toml:"admin_scope"`
+	// Private skips the shared caches for the tiles of authenticated requests, for providers
+	// which filter the features by the claims. Defaults to false.
+	Private env.Bool `toml:"private"`
+}
+
+// CacheBypass represents the config options of the X-Tegola-No-Cache header, which skips reading
+// the cached tile and writes the freshly rendered tile back to the cache
+type CacheBypass struct {
+	// Secret is the shared secret the header value must match
+	Secret env.String `toml:"secret"`
+	// KeyClasses are the key classes whose requests may bypass the cache with any header value
+	KeyClasses []env.String `toml:"key_classes"`
+}
+
+// NegativeCache represents the config options of the in-memory cache of empty tiles and
+// failed tile requests
+type NegativeCache struct {
+	// EmptyTTL is the number of seconds tiles without features are cached for. Defaults to 0 (disabled).
+	EmptyTTL *env.Uint `toml:"empty_ttl"`
+	// ErrorTTL is the number of seconds failed tile requests are cached for. Defaults to 0 (disabled).
+	ErrorTTL *env.Uint `toml:"error_ttl"`
+	// MaxEntries is the maximum number of cached results. Defaults to 10000.
+	MaxEntries *env.Uint `toml:"max_entries"`
 }
 
 // A Map represents a map in the Tegola Config file.
@@ -57,6 +296,372 @@ type Map struct {
 	Center      [3]env.Float `toml:"center"`
 	Layers      []MapLayer   `toml:"layers"`
 	TileBuffer  *env.Int     `toml:"tile_buffer"`
+	// MVTVersion is the Mapbox Vector Tile spec version to emit. Defaults to 2.
+	MVTVersion *env.Uint `toml:"mvt_version"`
+	// Upstream configures the map as a pull-through cache of another tile service.
+	// Upstream maps don't have layers.
+	Upstream *MapUpstream `toml:"upstream"`
+	// Raster configures a raster tile source served alongside the map's vector tiles
+	Raster *MapRaster `toml:"raster"`
+	// Available limits the times the map is served to the windows. Always available when empty.
+	Available []AvailabilityWindow `toml:"available"`
+	// Style is the path or http(s) url of a hosted Mapbox GL style of the map. The style
+	// layers drawing the map's layers are listed in the map's legend.
+	Style env.String `toml:"style"`
+	// AuditCoordinates fetches a sample tile of each layer at registration and fails when
+	// features are returned outside of the tile, which is usually an SRID misconfiguration
+	AuditCoordinates env.Bool `toml:"audit_coordinates"`
+	// CacheVersion is part of the cache keys of the map's tiles, so bumping it invalidates the
+	// map's cached tiles and rolling it back serves them again. "auto" derives the version
+	// from the config of the map and of its layers' providers.
+	CacheVersion env.String `toml:"cache_version"`
+	// CacheMaxZoom is the highest zoom the map's tiles are cached and seeded at. Tiles above it
+	// are extracted from their cached ancestor at the zoom instead of querying the providers.
+	CacheMaxZoom *env.Uint `toml:"cache_max_zoom"`
+	// Cache configures the cache backend of the map's tiles, overriding the global cache.
+	// The config is the same as the global cache's.
+	Cache env.Dict `toml:"cache"`
+	// EmptyTileStatus is the HTTP status code the map's tiles without features are served with:
+	// 200 (default) serves an empty tile, 204 and 404 serve no body.
+	EmptyTileStatus env.Uint `toml:"empty_tile_status"`
+	// CacheControl sets the Cache-Control header of the map's tiles by zoom. The first entry
+	// including the zoom of a tile applies.
+	CacheControl []MapCacheControl `toml:"cache_control"`
+	// MinZoom and MaxZoom are the zooms the map's tiles are served at. Requests for tiles of
+	// other zooms are rejected with a 400. Default to the zooms of the map's layers.
+	MinZoom *env.Uint `toml:"min_zoom"`
+	MaxZoom *env.Uint `toml:"max_zoom"`
+	// LayerConcurrency is the most layers of a tile fetched from their providers and encoded
+	// at once. 0 (default) fetches every layer of the tile at once.
+	LayerConcurrency env.Uint `toml:"layer_concurrency"`
+	// Grid is the tile matrix set of the map's tiles. Defaults to the web mercator grid of
+	// slippy map tiles.
+	Grid *MapGrid `toml:"grid"`
+}
+
+// MapGrid represents the tile matrix set of a map: a predefined grid by name, or a custom
+// grid of the extent in the CRS of the srid
+type MapGrid struct {
+	// Name of a predefined grid, "WebMercatorQuad" or "WorldCRS84Quad"
+	Name env.String `toml:"name"`
+	// SRID of the grid's CRS
+	SRID env.Uint `toml:"srid"`
+	// Extent covered by the grid's tiles in the grid's CRS, in the order min x, min y, max x, max y
+	Extent []env.Float `toml:"extent"`
+	// MatrixWidth and MatrixHeight are the columns and rows of tiles at zoom 0. Default to 1.
+	MatrixWidth  env.Uint `toml:"matrix_width"`
+	MatrixHeight env.Uint `toml:"matrix_height"`
+	// Resolutions are the CRS units per pixel of a 256 pixel tile at each zoom, from zoom 0.
+	// Defaults to halving the tiles of each zoom.
+	Resolutions []env.Float `toml:"resolutions"`
+}
+
+// MapCacheControl represents the Cache-Control header of a map's tiles at a range of zooms
+type MapCacheControl struct {
+	// MinZoom and MaxZoom are the zooms the header is set at. Default to all the zooms.
+	MinZoom *env.Uint `toml:"min_zoom"`
+	MaxZoom *env.Uint `toml:"max_zoom"`
+	// MaxAge is the number of seconds clients and shared caches may reuse the tiles for
+	MaxAge env.Uint `toml:"max_age"`
+	// SMaxAge is the number of seconds shared caches (i.e. CDNs) may reuse the tiles for,
+	// instead of max_age. Not set when 0.
+	SMaxAge env.Uint `toml:"s_maxage"`
+	// StaleWhileRevalidate is the number of seconds a stale tile may be served while it's
+	// revalidated in the background. Not set when 0.
+	StaleWhileRevalidate env.Uint `toml:"stale_while_revalidate"`
+}
+
+// Zooms returns the zooms the header is set at
+func (cc MapCacheControl) Zooms() (min, max uint) {
+	min, max = 0, tegola.MaxZ
+	if cc.MinZoom != nil {
+		min = uint(*cc.MinZoom)
+	}
+	if cc.MaxZoom != nil {
+		max = uint(*cc.MaxZoom)
+	}
+	return min, max
+}
+
+// validateCacheControl checks the zooms of the cache controls of the map
+func validateCacheControl(m Map) error {
+	for i, cc := range m.CacheControl {
+		min, max := cc.Zooms()
+		if max > tegola.MaxZ {
+			return ErrInvalidCacheControl{MapName: string(m.Name), Pos: i, Reason: fmt.Sprintf("max_zoom (%v) is above %v", max, tegola.MaxZ)}
+		}
+		if min > max {
+			return ErrInvalidCacheControl{MapName: string(m.Name), Pos: i, Reason: fmt.Sprintf("min_zoom (%v) is above max_zoom (%v)", min, max)}
+		}
+	}
+	return nil
+}
+
+// validateEmptyTileStatus checks the empty tile status of the map is one clients handle
+func validateEmptyTileStatus(m Map) error {
+	switch m.EmptyTileStatus {
+	case 0, http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return ErrInvalidEmptyTileStatus{MapName: string(m.Name), Status: uint(m.EmptyTileStatus)}
+	}
+}
+
+// validateMapZooms checks the zooms of the map are supported and ordered
+func validateMapZooms(m Map) error {
+	if m.MinZoom != nil && uint(*m.MinZoom) > tegola.MaxZ {
+		return ErrInvalidMapZooms{MapName: string(m.Name), Reason: fmt.Sprintf("min_zoom must be at most %v", tegola.MaxZ)}
+	}
+	if m.MaxZoom != nil && uint(*m.MaxZoom) > tegola.MaxZ {
+		return ErrInvalidMapZooms{MapName: string(m.Name), Reason: fmt.Sprintf("max_zoom must be at most %v", tegola.MaxZ)}
+	}
+	if m.MinZoom != nil && m.MaxZoom != nil && *m.MinZoom > *m.MaxZoom {
+		return ErrInvalidMapZooms{MapName: string(m.Name), Reason: "min_zoom is above max_zoom"}
+	}
+	return nil
+}
+
+// validateCacheMaxZoom checks the layers of the map have features at the cache max zoom, as
+// the tiles above it only have the layers of their ancestor at the zoom
+func validateCacheMaxZoom(m Map) error {
+	if m.CacheMaxZoom == nil {
+		return nil
+	}
+	maxZoom := uint(*m.CacheMaxZoom)
+	if maxZoom > tegola.MaxZ {
+		return ErrInvalidCacheMaxZoom{MapName: string(m.Name), MaxZoom: maxZoom}
+	}
+	for _, l := range m.Layers {
+		if l.MinZoom != nil && uint(*l.MinZoom) > maxZoom {
+			name, _ := l.GetName()
+			return ErrInvalidCacheMaxZoom{MapName: string(m.Name), MaxZoom: maxZoom, LayerName: name, MinZoom: uint(*l.MinZoom)}
+		}
+	}
+	return nil
+}
+
+func validateACME(ws Webserver) error {
+	if len(ws.ACME.Hosts) == 0 {
+		return nil
+	}
+	if ws.SSLCert != "" || ws.SSLKey != "" {
+		return ErrInvalidACME{Reason: "ssl_cert and ssl_key can't be used with acme"}
+	}
+	if ws.ACME.CacheDir == "" {
+		return ErrInvalidACME{Reason: "cache_dir is required, so the certificates are kept across restarts"}
+	}
+	for _, h := range ws.ACME.Hosts {
+		if h == "" || strings.ContainsAny(string(h), ":/ ") {
+			return ErrInvalidACME{Reason: fmt.Sprintf("host (%v) is not a host name", h)}
+		}
+	}
+	return nil
+}
+
+func validateTracing(t Tracing) error {
+	if t.SampleRate != nil && (*t.SampleRate < 0 || *t.SampleRate > 1) {
+		return ErrInvalidTracing{Reason: fmt.Sprintf("sample_rate (%v) must be between 0 and 1", *t.SampleRate)}
+	}
+	if t.OTLPEndpoint != "" {
+		u, err := url.Parse(string(t.OTLPEndpoint))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidTracing{Reason: fmt.Sprintf("otlp_endpoint (%v) is not an http or https url", t.OTLPEndpoint)}
+		}
+	}
+	return nil
+}
+
+func validateTenants(ws Webserver, maps []Map) error {
+	names := map[string]bool{}
+	hosts := map[string]string{}
+	for _, t := range ws.Tenants {
+		if t.Name == "" {
+			return ErrInvalidTenant{Reason: "name is required"}
+		}
+		if names[string(t.Name)] {
+			return ErrInvalidTenant{Name: string(t.Name), Reason: "the name is used by another tenant"}
+		}
+		names[string(t.Name)] = true
+
+		if len(t.Hosts) == 0 && ws.TenantHeader == "" {
+			return ErrInvalidTenant{Name: string(t.Name), Reason: "hosts are required without a tenant_header"}
+		}
+		for _, h := range t.Hosts {
+			host := strings.ToLower(string(h))
+			if host == "" || strings.ContainsAny(host, ":/ ") {
+				return ErrInvalidTenant{Name: string(t.Name), Reason: fmt.Sprintf("host (%v) is not a host name", h)}
+			}
+			if other, ok := hosts[host]; ok {
+				return ErrInvalidTenant{Name: string(t.Name), Reason: fmt.Sprintf("host (%v) is used by tenant (%v)", h, other)}
+			}
+			hosts[host] = string(t.Name)
+		}
+
+		if len(t.Maps) == 0 {
+			return ErrInvalidTenant{Name: string(t.Name), Reason: "maps are required"}
+		}
+		for _, tm := range t.Maps {
+			known := false
+			for _, m := range maps {
+				known = known || m.Name == tm
+			}
+			if !known {
+				return ErrInvalidTenant{Name: string(t.Name), Reason: fmt.Sprintf("map (%v) is not configured", tm)}
+			}
+		}
+	}
+	return nil
+}
+
+func validateSignedURLs(su SignedURLs) error {
+	if su.Required && len(su.Keys) == 0 {
+		return ErrInvalidSignedURLs{Reason: "keys are required to require signed urls"}
+	}
+	names := map[string]bool{}
+	for _, k := range su.Keys {
+		if k.Name == "" {
+			return ErrInvalidSignedURLs{Reason: "key name is required"}
+		}
+		if names[string(k.Name)] {
+			return ErrInvalidSignedURLs{Reason: fmt.Sprintf("key (%v) is configured twice", k.Name)}
+		}
+		names[string(k.Name)] = true
+		if k.Secret == "" {
+			return ErrInvalidSignedURLs{Reason: fmt.Sprintf("secret of key (%v) is required", k.Name)}
+		}
+	}
+	return nil
+}
+
+// TrustedProxyNetworks returns the networks of the trusted proxies, single addresses as
+// networks of one address
+func (ws Webserver) TrustedProxyNetworks() ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, a := range ws.TrustedProxies {
+		n, err := parseNetwork(string(a))
+		if err != nil {
+			return nil, ErrInvalidTrustedProxy(a)
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+// parseNetwork parses a CIDR network, or an address as a network of one address
+func parseNetwork(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		if ip := net.ParseIP(s); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+		}
+	}
+	_, n, err := net.ParseCIDR(s)
+	return n, err
+}
+
+func validateURLRoot(ws Webserver) error {
+	if ws.URLRoot == "" {
+		return nil
+	}
+	u, err := url.Parse(string(ws.URLRoot))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return ErrInvalidURLRoot(ws.URLRoot)
+	}
+	return nil
+}
+
+func validateRateLimit(rl RateLimit) error {
+	if rl.Rate < 0 || rl.KeyRate < 0 {
+		return ErrInvalidRateLimit{Reason: "rate and key_rate can't be negative"}
+	}
+	_, err := rl.AllowlistNetworks()
+	return err
+}
+
+// AllowlistNetworks parses the addresses and CIDR networks of the allowlist. An address is a
+// network of its own.
+func (rl RateLimit) AllowlistNetworks() ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, a := range rl.Allowlist {
+		s := string(a)
+		n, err := parseNetwork(s)
+		if err != nil {
+			return nil, ErrInvalidRateLimit{Reason: fmt.Sprintf("allowlist entry (%v) is not an address or CIDR network", s)}
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+func validateWarmup(w Warmup, maps []Map) error {
+	for _, wm := range w.Maps {
+		known := false
+		for _, m := range maps {
+			known = known || m.Name == wm.Name
+		}
+		if !known {
+			return ErrInvalidWarmup{MapName: string(wm.Name), Reason: "the map is not configured"}
+		}
+		if len(wm.Bounds) != 0 && len(wm.Bounds) != 4 {
+			return ErrInvalidWarmup{MapName: string(wm.Name), Reason: "bounds must have 4 values (minx, miny, maxx, maxy)"}
+		}
+		if wm.MaxZoom == nil {
+			return ErrInvalidWarmup{MapName: string(wm.Name), Reason: "max_zoom is required"}
+		}
+		if uint(*wm.MaxZoom) > tegola.MaxZ {
+			return ErrInvalidWarmup{MapName: string(wm.Name), Reason: fmt.Sprintf("max_zoom must be at most %v", tegola.MaxZ)}
+		}
+		if wm.MinZoom != nil && *wm.MinZoom > *wm.MaxZoom {
+			return ErrInvalidWarmup{MapName: string(wm.Name), Reason: "min_zoom is above max_zoom"}
+		}
+	}
+	return nil
+}
+
+// MapUpstream represents the config for an upstream XYZ / WMTS tile service
+type MapUpstream struct {
+	// URL is the tile url template, i.e. https://tiles.example.com/{z}/{x}/{y}.png
+	URL env.String `toml:"url"`
+	// ContentType of the upstream tiles. Defaults to the type of the url's extension.
+	ContentType env.String `toml:"content_type"`
+	// TTL is the number of seconds fetched tiles are cached for. Defaults to 0 (no expiry).
+	TTL *env.Uint `toml:"ttl"`
+	// Timeout is the number of seconds allowed for an upstream request. Defaults to 10.
+	Timeout *env.Uint `toml:"timeout"`
+	// MaxRequests limits the concurrent upstream requests. Defaults to 0 (unlimited).
+	MaxRequests *env.Uint `toml:"max_requests"`
+	// MinZoom and MaxZoom limit the zooms requested from upstream
+	MinZoom *env.Uint `toml:"min_zoom"`
+	MaxZoom *env.Uint `toml:"max_zoom"`
+	// Headers are added to every upstream request
+	Headers map[string]env.String `toml:"headers"`
+}
+
+// MapRaster represents the config for the raster tile source of a map, either an XYZ / WMTS
+// tile service or a Cloud Optimized GeoTIFF
+type MapRaster struct {
+	// URL is the tile url template of a tile service, i.e. https://tiles.example.com/{z}/{x}/{y}.png
+	URL env.String `toml:"url"`
+	// COG is the path or http(s) url of a Cloud Optimized GeoTIFF the tiles are rendered from.
+	COG env.String `toml:"cog"`
+	// ContentType of the tile service's tiles. Defaults to the type of the url's extension.
+	ContentType env.String `toml:"content_type"`
+	// TTL is the number of seconds tiles of the tile service are cached for. Defaults to 0 (no expiry).
+	TTL *env.Uint `toml:"ttl"`
+	// Timeout is the number of seconds allowed for a request of the tile service or the COG.
+	Timeout *env.Uint `toml:"timeout"`
+	// MaxRequests limits the concurrent requests of the tile service. Defaults to 0 (unlimited).
+	MaxRequests *env.Uint `toml:"max_requests"`
+	// CacheSizeMB is the megabytes of decoded COG blocks kept in memory. Defaults to 64.
+	CacheSizeMB *env.Uint `toml:"cache_size_mb"`
+	// MinZoom and MaxZoom limit the zooms the raster is served at
+	MinZoom *env.Uint `toml:"min_zoom"`
+	MaxZoom *env.Uint `toml:"max_zoom"`
+	// Headers are added to every request of the tile service or the COG
+	Headers map[string]env.String `toml:"headers"`
 }
 
 // MapLayer represents a the config for a layer in a map
@@ -69,12 +674,84 @@ type MapLayer struct {
 	MinZoom       *env.Uint   `toml:"min_zoom"`
 	MaxZoom       *env.Uint   `toml:"max_zoom"`
 	DefaultTags   interface{} `toml:"default_tags"`
+	// OverzoomLevels serves the layer at this many zooms above its max_zoom with the features of
+	// the tile's ancestor at max_zoom, clipped to the tile. Defaults to 0.
+	OverzoomLevels *env.Uint `toml:"overzoom_levels"`
+	// Blend merges the features of the layer into the other blended layers of the same name at
+	// the zooms they overlap, instead of the overlapping zooms being an error.
+	Blend env.Bool `toml:"blend"`
 	// DontSimplify indicates wheather feature simplification should be applied.
 	// We use a negative in the name so the default is to simplify
 	DontSimplify env.Bool `toml:"dont_simplify"`
 	// DontClip indicates wheather feature clipping should be applied.
 	// We use a negative in the name so the default is to clipping
 	DontClip env.Bool `toml:"dont_clip"`
+	// MakeValid repairs the geometries of the layer's features before they are encoded, removing
+	// repeated points, splitting self-intersecting rings and correcting the winding of rings.
+	MakeValid env.Bool `toml:"make_valid"`
+	// MinPolygonArea drops the layer's polygons with a smaller area, in square pixels of the tile's
+	// 4096 pixel extent, when a tile is encoded. Defaults to 0 (keep every polygon).
+	MinPolygonArea *env.Float `toml:"min_polygon_area"`
+	// MinLineLength drops the layer's lines which are shorter, in pixels of the tile's 4096 pixel
+	// extent, when a tile is encoded. Defaults to 0 (keep every line).
+	MinLineLength *env.Float `toml:"min_line_length"`
+	// MergeLines merges the lines of the layer's features which touch and have the same tags
+	// into longer lines when a tile is encoded, i.e. the segments of a road.
+	MergeLines env.Bool `toml:"merge_lines"`
+	// SortFeatures sorts the layer's features by ID when a tile is encoded, so the tiles are
+	// reproducible whatever the order the provider returns the features in.
+	SortFeatures env.Bool `toml:"sort_features"`
+	// TagTransform renames, computes, casts and drops the tags of the layer's features
+	// after the provider returns them.
+	TagTransform TagTransform `toml:"tag_transform"`
+	// GeometryAttributes controls the handling of attribute values which contain
+	// WKT or GeoJSON geometries. One of "warn", "strip" or "round". Defaults to no detection.
+	GeometryAttributes env.String `toml:"geometry_attributes"`
+	// GeometryAttributesPrecision is the number of decimal places coordinates are rounded to
+	// when GeometryAttributes is "round". Defaults to 6.
+	GeometryAttributesPrecision *env.Uint `toml:"geometry_attributes_precision"`
+	// JSONAttributes are the attributes whose list and map values (i.e. jsonb or arrays) are encoded
+	// as JSON strings. "*" includes every attribute. List and map values of other attributes are dropped.
+	JSONAttributes []env.String `toml:"json_attributes"`
+	// JSONAttributesMaxBytes is the largest JSON string encoded for json_attributes, larger values are dropped.
+	// 0 disables the limit. Defaults to 1024.
+	JSONAttributesMaxBytes *env.Uint `toml:"json_attributes_max_bytes"`
+	// ExpiresField is the name of the feature tag holding the time a feature expires.
+	// Expired features are dropped and the tile's cache lifetime is bounded by the soonest expiry.
+	ExpiresField env.String `toml:"expires_field"`
+	// TimeoutMS is the number of milliseconds the layer's provider is given to return its features.
+	// A layer exceeding its timeout is left out of the tile and the tile is still returned. 0 disables the timeout.
+	TimeoutMS *env.Uint `toml:"timeout_ms"`
+	// Required layers fail the tile when their provider errors. Tiles are returned without
+	// the failing layers which are not required. Defaults to true.
+	Required *env.Bool `toml:"required"`
+	// Available limits the times the layer is served to the windows. Always available when empty.
+	// Layers with the same name and overlapping zooms can be switched by date with windows which
+	// don't overlap.
+	Available []AvailabilityWindow `toml:"available"`
+	// FreshnessSLA is the maximum age, in seconds, of the layer's data as reported by its provider.
+	// Violations are reported by the freshness monitor.
+	FreshnessSLA *env.Uint `toml:"freshness_sla"`
+	// Paint are the paint properties of the layer in the style generated for the map, set over
+	// the generated paint properties, i.e. "fill-color" = "#8c6"
+	Paint map[string]interface{} `toml:"paint"`
+	// UTFGridKey is the feature tag keying the features of the layer in the map's UTFGrid
+	// interactivity tiles. Layers without a key aren't included in the grids.
+	UTFGridKey env.String `toml:"utfgrid_key"`
+}
+
+// TagTransform shapes the tags of a map layer's features. The steps are applied in order:
+// tags are renamed, computed, cast then dropped.
+type TagTransform struct {
+	// Rename maps tags to their new names, i.e. name_en = "name"
+	Rename map[string]env.String `toml:"rename"`
+	// Computed tags are set to the output of their Go template executed with the feature's tags,
+	// i.e. label = "{{.name}} ({{.ref}})"
+	Computed map[string]env.String `toml:"computed"`
+	// Cast are the types tag values are cast to. One of "string", "int", "float" or "bool".
+	Cast map[string]env.String `toml:"cast"`
+	// Drop are the tags removed from the features
+	Drop []env.String `toml:"drop"`
 }
 
 // ProviderLayerID returns the id of the layer and provider or an error
@@ -98,6 +775,16 @@ func (ml MapLayer) GetName() (string, error) {
 	return name, err
 }
 
+// servedMaxZoom returns the highest zoom the layer is served at, its max zoom and overzoom levels.
+// The max zoom must have been defaulted.
+func (ml MapLayer) servedMaxZoom() uint {
+	max := uint(*ml.MaxZoom)
+	if ml.OverzoomLevels != nil {
+		max += uint(*ml.OverzoomLevels)
+	}
+	return max
+}
+
 // Validate checks the config for issues
 func (c *Config) Validate() error {
 
@@ -141,6 +828,24 @@ func (c *Config) Validate() error {
 	// map of layers to providers
 	mapLayers := map[string]map[string]MapLayer{}
 	for mapKey, m := range c.Maps {
+		if err := validateAvailability(m.Available); err != nil {
+			return err
+		}
+		if err := validateCacheVersion(m); err != nil {
+			return err
+		}
+		if err := validateCacheMaxZoom(m); err != nil {
+			return err
+		}
+		if err := validateEmptyTileStatus(m); err != nil {
+			return err
+		}
+		if err := validateCacheControl(m); err != nil {
+			return err
+		}
+		if err := validateMapZooms(m); err != nil {
+			return err
+		}
 		if _, ok := mapLayers[string(m.Name)]; !ok {
 			mapLayers[string(m.Name)] = map[string]MapLayer{}
 		}
@@ -195,6 +900,19 @@ func (c *Config) Validate() error {
 				return err
 			}
 
+			// mvt providers encode the tiles, the features of an ancestor can't be clipped
+			if isMvt && l.OverzoomLevels != nil && *l.OverzoomLevels > 0 {
+				return ErrMVTOverzoomLevels{MapName: string(m.Name), LayerName: name}
+			}
+			// mvt providers encode each layer, their layers can't be blended
+			if isMvt && bool(l.Blend) {
+				return ErrMVTBlend{MapName: string(m.Name), LayerName: name}
+			}
+
+			if err := validateAvailability(l.Available); err != nil {
+				return err
+			}
+
 			// MaxZoom default
 			if l.MaxZoom == nil {
 				ph := env.Uint(tegola.MaxZ)
@@ -214,8 +932,9 @@ func (c *Config) Validate() error {
 
 			// check if we already have this layer
 			if val, ok := mapLayers[string(m.Name)][name]; ok {
-				// we have a hit. check for zoom range overlap
-				if uint(*val.MinZoom) <= uint(*l.MaxZoom) && uint(*l.MinZoom) <= uint(*val.MaxZoom) {
+				// we have a hit. check for zoom range and availability overlap, blended layers may overlap
+				if uint(*val.MinZoom) <= l.servedMaxZoom() && uint(*l.MinZoom) <= val.servedMaxZoom() &&
+					availabilityOverlaps(val.Available, l.Available) && !(bool(val.Blend) && bool(l.Blend)) {
 					return ErrOverlappingLayerZooms{
 						ProviderLayer1: string(val.ProviderLayer),
 						ProviderLayer2: string(l.ProviderLayer),
@@ -229,6 +948,14 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if err := validateWarmup(c.Warmup, c.Maps); err != nil {
+		return err
+	}
+
+	if err := validateTracing(c.Tracing); err != nil {
+		return err
+	}
+
 	// check for blacklisted headers
 	for k := range c.Webserver.Headers {
 		for _, v := range blacklistHeaders {
@@ -238,6 +965,44 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if err := validateGeofences(c.Webserver.Geofences, c.Webserver.KeyClasses); err != nil {
+		return err
+	}
+
+	// requests without a known API key can't bypass the cache
+	for _, class := range c.Webserver.CacheBypass.KeyClasses {
+		known := false
+		for _, kc := range c.Webserver.KeyClasses {
+			known = known || kc.Name == class
+		}
+		if !known {
+			return ErrUnknownCacheBypassKeyClass{Class: string(class)}
+		}
+	}
+
+	if err := validateRateLimit(c.Webserver.RateLimit); err != nil {
+		return err
+	}
+
+	if err := validateACME(c.Webserver); err != nil {
+		return err
+	}
+
+	if err := validateTenants(c.Webserver, c.Maps); err != nil {
+		return err
+	}
+
+	if err := validateSignedURLs(c.Webserver.SignedURLs); err != nil {
+		return err
+	}
+
+	if err := validateURLRoot(c.Webserver); err != nil {
+		return err
+	}
+	if _, err := c.Webserver.TrustedProxyNetworks(); err != nil {
+		return err
+	}
+
 	// check if webserver.uri_prefix is set and if so
 	// confirm it starts with a forward slash "/"
 	if string(c.Webserver.URIPrefix) != "" {
@@ -277,6 +1042,7 @@ func Parse(reader io.Reader, location string) (conf Config, err error) {
 	_, err = toml.DecodeReader(reader, &conf)
 	conf.LocationName = location
 	conf.ConfigureTileBuffers()
+	conf.ConfigureCacheVersions()
 
 	return conf, err
 }
@@ -285,8 +1051,19 @@ func Parse(reader io.Reader, location string) (conf Config, err error) {
 func Load(location string) (conf Config, err error) {
 	var reader io.Reader
 
-	// check for http prefix
-	if strings.HasPrefix(location, "http") {
+	// check for s3 prefix (s3://bucket/key). connection options are read from the
+	// environment so S3 compatible stores (i.e. MinIO) can be used. see the awsutil package.
+	if strings.HasPrefix(location, "s3://") {
+		log.Infof("loading s3 config (%v)", location)
+
+		body, err := loadS3(location)
+		if err != nil {
+			return conf, fmt.Errorf("error fetching s3 config file (%v): %v ", location, err)
+		}
+		defer body.Close()
+
+		reader = body
+	} else if strings.HasPrefix(location, "http") {
 		log.Infof("loading remote config (%v)", location)
 
 		// setup http client with a timeout
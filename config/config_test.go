@@ -957,6 +957,427 @@ func TestValidate(t *testing.T) {
 				},
 			},
 		},
+		"13 same name layers in non-overlapping availability windows": {
+			config: config.Config{
+				Providers: []env.Dict{
+					{
+						"name": "provider1",
+						"type": "test",
+					},
+				},
+				Maps: []config.Map{
+					{
+						Name: "osm",
+						Layers: []config.MapLayer{
+							{
+								ProviderLayer: "provider1.water_2019",
+								Name:          "water",
+								Available:     []config.AvailabilityWindow{{Until: "2020-01-01"}},
+							},
+							{
+								ProviderLayer: "provider1.water_2020",
+								Name:          "water",
+								Available:     []config.AvailabilityWindow{{From: "2020-01-01"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		"13 same name layers in overlapping availability windows": {
+			expectedErr: config.ErrOverlappingLayerZooms{
+				ProviderLayer1: "provider1.water_2019",
+				ProviderLayer2: "provider1.water_2020",
+			},
+			config: config.Config{
+				Providers: []env.Dict{
+					{
+						"name": "provider1",
+						"type": "test",
+					},
+				},
+				Maps: []config.Map{
+					{
+						Name: "osm",
+						Layers: []config.MapLayer{
+							{
+								ProviderLayer: "provider1.water_2019",
+								Name:          "water",
+								Available:     []config.AvailabilityWindow{{Until: "2020-02-01"}},
+							},
+							{
+								ProviderLayer: "provider1.water_2020",
+								Name:          "water",
+								Available:     []config.AvailabilityWindow{{From: "2020-01-01"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		"14 invalid availability window": {
+			expectedErr: config.ErrInvalidAvailability{
+				From:  "2020-02-01",
+				Until: "2020-01-01",
+			},
+			config: config.Config{
+				Maps: []config.Map{
+					{
+						Name:      "osm",
+						Available: []config.AvailabilityWindow{{From: "2020-02-01", Until: "2020-01-01"}},
+					},
+				},
+			},
+		},
+		"15 geofence unknown key class": {
+			expectedErr: config.ErrInvalidGeofence{
+				Name:   "base",
+				Reason: "unknown key class partner",
+			},
+			config: config.Config{
+				Webserver: config.Webserver{
+					KeyClasses: []config.KeyClass{
+						{Name: "public", Keys: []env.String{"abc"}},
+					},
+					Geofences: []config.Geofence{
+						{
+							Name:       "base",
+							Bounds:     []env.Float{-77.12, 38.80, -76.91, 39.0},
+							KeyClasses: []env.String{"anonymous", "public", "partner"},
+						},
+					},
+				},
+			},
+		},
+		"15 geofence bounds and geometry": {
+			expectedErr: config.ErrInvalidGeofence{
+				Name:   "base",
+				Reason: "bounds and geometry can't both be set",
+			},
+			config: config.Config{
+				Webserver: config.Webserver{
+					Geofences: []config.Geofence{
+						{
+							Name:     "base",
+							Bounds:   []env.Float{-77.12, 38.80, -76.91, 39.0},
+							Geometry: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`,
+						},
+					},
+				},
+			},
+		},
+		"16 cache max zoom below layer min zoom": {
+			expectedErr: config.ErrInvalidCacheMaxZoom{
+				MapName:   "osm",
+				MaxZoom:   12,
+				LayerName: "buildings",
+				MinZoom:   14,
+			},
+			config: config.Config{
+				Maps: []config.Map{
+					{
+						Name:         "osm",
+						CacheMaxZoom: env.UintPtr(12),
+						Layers: []config.MapLayer{
+							{
+								ProviderLayer: "provider1.water",
+								MinZoom:       env.UintPtr(0),
+							},
+							{
+								ProviderLayer: "provider1.buildings",
+								MinZoom:       env.UintPtr(14),
+							},
+						},
+					},
+				},
+			},
+		},
+		"16 cache max zoom too high": {
+			expectedErr: config.ErrInvalidCacheMaxZoom{
+				MapName: "osm",
+				MaxZoom: 30,
+			},
+			config: config.Config{
+				Maps: []config.Map{
+					{
+						Name:         "osm",
+						CacheMaxZoom: env.UintPtr(30),
+					},
+				},
+			},
+		},
+		"17 cache bypass unknown key class": {
+			expectedErr: config.ErrUnknownCacheBypassKeyClass{Class: "anonymous"},
+			config: config.Config{
+				Webserver: config.Webserver{
+					KeyClasses: []config.KeyClass{
+						{Name: "editors", Keys: []env.String{"abc"}},
+					},
+					CacheBypass: config.CacheBypass{
+						KeyClasses: []env.String{"editors", "anonymous"},
+					},
+				},
+			},
+		},
+		"18 warmup unknown map": {
+			expectedErr: config.ErrInvalidWarmup{MapName: "osm", Reason: "the map is not configured"},
+			config: config.Config{
+				Warmup: config.Warmup{
+					Maps: []config.WarmupMap{
+						{Name: "osm", MaxZoom: env.UintPtr(8)},
+					},
+				},
+			},
+		},
+		"18 warmup min zoom above max zoom": {
+			expectedErr: config.ErrInvalidWarmup{MapName: "osm", Reason: "min_zoom is above max_zoom"},
+			config: config.Config{
+				Maps: []config.Map{
+					{Name: "osm"},
+				},
+				Warmup: config.Warmup{
+					Maps: []config.WarmupMap{
+						{Name: "osm", Bounds: []env.Float{-77.12, 38.80, -76.91, 39.0}, MinZoom: env.UintPtr(10), MaxZoom: env.UintPtr(8)},
+					},
+				},
+			},
+		},
+		"19 rate limit invalid allowlist": {
+			expectedErr: config.ErrInvalidRateLimit{Reason: "allowlist entry (10.0.0.0/33) is not an address or CIDR network"},
+			config: config.Config{
+				Webserver: config.Webserver{
+					RateLimit: config.RateLimit{
+						Rate:      10,
+						Allowlist: []env.String{"127.0.0.1", "::1", "10.0.0.0/33"},
+					},
+				},
+			},
+		},
+		"19 rate limit negative rate": {
+			expectedErr: config.ErrInvalidRateLimit{Reason: "rate and key_rate can't be negative"},
+			config: config.Config{
+				Webserver: config.Webserver{
+					RateLimit: config.RateLimit{
+						KeyRate: -1,
+					},
+				},
+			},
+		},
+		"20 acme with ssl cert": {
+			expectedErr: config.ErrInvalidACME{Reason: "ssl_cert and ssl_key can't be used with acme"},
+			config: config.Config{
+				Webserver: config.Webserver{
+					SSLCert: "fullchain.pem",
+					SSLKey:  "privkey.pem",
+					ACME: config.ACME{
+						Hosts:    []env.String{"tiles.example.com"},
+						CacheDir: "/var/lib/tegola/acme",
+					},
+				},
+			},
+		},
+		"20 acme missing cache dir": {
+			expectedErr: config.ErrInvalidACME{Reason: "cache_dir is required, so the certificates are kept across restarts"},
+			config: config.Config{
+				Webserver: config.Webserver{
+					ACME: config.ACME{
+						Hosts: []env.String{"tiles.example.com"},
+					},
+				},
+			},
+		},
+		"20 acme invalid host": {
+			expectedErr: config.ErrInvalidACME{Reason: "host (https://tiles.example.com) is not a host name"},
+			config: config.Config{
+				Webserver: config.Webserver{
+					ACME: config.ACME{
+						Hosts:    []env.String{"https://tiles.example.com"},
+						CacheDir: "/var/lib/tegola/acme",
+					},
+				},
+			},
+		},
+		"21 tracing sample rate above 1": {
+			expectedErr: config.ErrInvalidTracing{Reason: "sample_rate (1.5) must be between 0 and 1"},
+			config: config.Config{
+				Tracing: config.Tracing{
+					OTLPEndpoint: "http://otel-collector:4318",
+					SampleRate:   env.FloatPtr(1.5),
+				},
+			},
+		},
+		"21 tracing invalid endpoint": {
+			expectedErr: config.ErrInvalidTracing{Reason: "otlp_endpoint (otel-collector:4318) is not an http or https url"},
+			config: config.Config{
+				Tracing: config.Tracing{
+					OTLPEndpoint: "otel-collector:4318",
+				},
+			},
+		},
+		"22 tenant unknown map": {
+			expectedErr: config.ErrInvalidTenant{Name: "acme", Reason: "map (osm) is not configured"},
+			config: config.Config{
+				Webserver: config.Webserver{
+					Tenants: []config.Tenant{
+						{Name: "acme", Hosts: []env.String{"acme.tiles.example.com"}, Maps: []env.String{"osm"}},
+					},
+				},
+			},
+		},
+		"22 tenant duplicate host": {
+			expectedErr: config.ErrInvalidTenant{Name: "globex", Reason: "host (ACME.tiles.example.com) is used by tenant (acme)"},
+			config: config.Config{
+				Maps: []config.Map{{Name: "osm"}},
+				Webserver: config.Webserver{
+					Tenants: []config.Tenant{
+						{Name: "acme", Hosts: []env.String{"acme.tiles.example.com"}, Maps: []env.String{"osm"}},
+						{Name: "globex", Hosts: []env.String{"ACME.tiles.example.com"}, Maps: []env.String{"osm"}},
+					},
+				},
+			},
+		},
+		"22 tenant missing hosts": {
+			expectedErr: config.ErrInvalidTenant{Name: "acme", Reason: "hosts are required without a tenant_header"},
+			config: config.Config{
+				Maps: []config.Map{{Name: "osm"}},
+				Webserver: config.Webserver{
+					Tenants: []config.Tenant{
+						{Name: "acme", Maps: []env.String{"osm"}},
+					},
+				},
+			},
+		},
+		"23 invalid empty tile status": {
+			expectedErr: config.ErrInvalidEmptyTileStatus{MapName: "osm", Status: 500},
+			config: config.Config{
+				Maps: []config.Map{{Name: "osm", EmptyTileStatus: 500}},
+			},
+		},
+		"24 cache control min zoom above max zoom": {
+			expectedErr: config.ErrInvalidCacheControl{MapName: "osm", Pos: 1, Reason: "min_zoom (14) is above max_zoom (10)"},
+			config: config.Config{
+				Maps: []config.Map{{
+					Name: "osm",
+					CacheControl: []config.MapCacheControl{
+						{MaxZoom: env.UintPtr(10), MaxAge: 86400},
+						{MinZoom: env.UintPtr(14), MaxZoom: env.UintPtr(10), MaxAge: 300},
+					},
+				}},
+			},
+		},
+		"25 signed urls duplicate key": {
+			expectedErr: config.ErrInvalidSignedURLs{Reason: "key (partner) is configured twice"},
+			config: config.Config{
+				Webserver: config.Webserver{
+					SignedURLs: config.SignedURLs{
+						Keys: []config.SigningKey{
+							{Name: "partner", Secret: "s3cr3t"},
+							{Name: "partner", Secret: "0th3r"},
+						},
+					},
+				},
+			},
+		},
+		"25 signed urls required without keys": {
+			expectedErr: config.ErrInvalidSignedURLs{Reason: "keys are required to require signed urls"},
+			config: config.Config{
+				Webserver: config.Webserver{
+					SignedURLs: config.SignedURLs{Required: true},
+				},
+			},
+		},
+		"26 url root without a scheme": {
+			expectedErr: config.ErrInvalidURLRoot("example.com/tiles"),
+			config: config.Config{
+				Webserver: config.Webserver{
+					URLRoot: "example.com/tiles",
+				},
+			},
+		},
+		"26 invalid trusted proxy": {
+			expectedErr: config.ErrInvalidTrustedProxy("10.0.0.0/33"),
+			config: config.Config{
+				Webserver: config.Webserver{
+					TrustedProxies: []env.String{"10.0.0.1", "10.0.0.0/33"},
+				},
+			},
+		},
+		"27 map min zoom above max zoom": {
+			expectedErr: config.ErrInvalidMapZooms{MapName: "osm", Reason: "min_zoom is above max_zoom"},
+			config: config.Config{
+				Maps: []config.Map{{Name: "osm", MinZoom: env.UintPtr(14), MaxZoom: env.UintPtr(10)}},
+			},
+		},
+		"27 map max zoom above supported zooms": {
+			expectedErr: config.ErrInvalidMapZooms{MapName: "osm", Reason: "max_zoom must be at most 22"},
+			config: config.Config{
+				Maps: []config.Map{{Name: "osm", MaxZoom: env.UintPtr(23)}},
+			},
+		},
+		"28 mvt provider layer overzoom levels": {
+			expectedErr: config.ErrMVTOverzoomLevels{MapName: "osm", LayerName: "water"},
+			config: config.Config{
+				Providers: []env.Dict{{"name": "provider1", "type": "mvt_test"}},
+				Maps: []config.Map{{
+					Name:   "osm",
+					Layers: []config.MapLayer{{ProviderLayer: "provider1.water", OverzoomLevels: env.UintPtr(2)}},
+				}},
+			},
+		},
+		"28 overzoomed layers overlapping zooms": {
+			expectedErr: config.ErrOverlappingLayerZooms{
+				ProviderLayer1: "provider1.water_0_5",
+				ProviderLayer2: "provider1.water_6_10",
+			},
+			config: config.Config{
+				Providers: []env.Dict{{"name": "provider1", "type": "test"}},
+				Maps: []config.Map{{
+					Name: "osm",
+					Layers: []config.MapLayer{
+						{Name: "water", ProviderLayer: "provider1.water_0_5", MinZoom: env.UintPtr(0), MaxZoom: env.UintPtr(5), OverzoomLevels: env.UintPtr(1)},
+						{Name: "water", ProviderLayer: "provider1.water_6_10", MinZoom: env.UintPtr(6), MaxZoom: env.UintPtr(10)},
+					},
+				}},
+			},
+		},
+		"29 blended layers overlapping zooms": {
+			config: config.Config{
+				Providers: []env.Dict{{"name": "provider1", "type": "test"}},
+				Maps: []config.Map{{
+					Name: "osm",
+					Layers: []config.MapLayer{
+						{Name: "water", ProviderLayer: "provider1.lakes", Blend: true},
+						{Name: "water", ProviderLayer: "provider1.rivers", Blend: true},
+					},
+				}},
+			},
+		},
+		"29 blended and unblended layers overlapping zooms": {
+			expectedErr: config.ErrOverlappingLayerZooms{
+				ProviderLayer1: "provider1.lakes",
+				ProviderLayer2: "provider1.rivers",
+			},
+			config: config.Config{
+				Providers: []env.Dict{{"name": "provider1", "type": "test"}},
+				Maps: []config.Map{{
+					Name: "osm",
+					Layers: []config.MapLayer{
+						{Name: "water", ProviderLayer: "provider1.lakes", Blend: true},
+						{Name: "water", ProviderLayer: "provider1.rivers"},
+					},
+				}},
+			},
+		},
+		"29 mvt provider layer blend": {
+			expectedErr: config.ErrMVTBlend{MapName: "osm", LayerName: "water"},
+			config: config.Config{
+				Providers: []env.Dict{{"name": "provider1", "type": "mvt_test"}},
+				Maps: []config.Map{{
+					Name:   "osm",
+					Layers: []config.MapLayer{{ProviderLayer: "provider1.water", Blend: true}},
+				}},
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -1079,3 +1500,48 @@ func TestConfigureTileBuffers(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestConfigureCacheVersions(t *testing.T) {
+	newConfig := func(version, sql string) config.Config {
+		return config.Config{
+			Providers: []env.Dict{
+				{"name": "postgis", "type": "postgis", "layers": []map[string]interface{}{{"name": "roads", "sql": sql}}},
+				{"name": "other", "type": "postgis"},
+			},
+			Maps: []config.Map{
+				{
+					Name:         "osm",
+					CacheVersion: env.String(version),
+					Layers:       []config.MapLayer{{ProviderLayer: "postgis.roads"}},
+				},
+			},
+		}
+	}
+	version := func(c config.Config) string {
+		c.ConfigureCacheVersions()
+		return string(c.Maps[0].CacheVersion)
+	}
+
+	if v := version(newConfig("v2", "SELECT 1")); v != "v2" {
+		t.Errorf("explicit version, expected v2 got %v", v)
+	}
+	if v := version(newConfig("", "SELECT 1")); v != "" {
+		t.Errorf("no version, expected none got %v", v)
+	}
+
+	auto := version(newConfig("auto", "SELECT 1"))
+	if len(auto) != 12 {
+		t.Fatalf("auto version, expected a 12 character hash got %q", auto)
+	}
+	if v := version(newConfig("auto", "SELECT 1")); v != auto {
+		t.Errorf("auto version of the same config, expected %v got %v", auto, v)
+	}
+	if v := version(newConfig("auto", "SELECT 2")); v == auto {
+		t.Errorf("auto version of a changed provider layer, expected a new version got %v", v)
+	}
+
+	invalid := newConfig("v/2", "SELECT 1")
+	if err := invalid.Validate(); !errors.As(err, &config.ErrInvalidCacheVersion{}) {
+		t.Errorf("invalid version, expected ErrInvalidCacheVersion got %v", err)
+	}
+}
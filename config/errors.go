@@ -1,8 +1,11 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/go-spatial/tegola"
 )
 
 type ErrMapNotFound struct {
@@ -72,6 +75,26 @@ func (e ErrMVTDifferentProviders) Error() string {
 	)
 }
 
+// ErrMVTOverzoomLevels represents a layer of an MVT provider configured with overzoom levels
+type ErrMVTOverzoomLevels struct {
+	MapName   string
+	LayerName string
+}
+
+func (e ErrMVTOverzoomLevels) Error() string {
+	return fmt.Sprintf("config: overzoom_levels of layer (%v) of map (%v) are not supported by mvt providers", e.LayerName, e.MapName)
+}
+
+// ErrMVTBlend represents a layer of an MVT provider configured to be blended
+type ErrMVTBlend struct {
+	MapName   string
+	LayerName string
+}
+
+func (e ErrMVTBlend) Error() string {
+	return fmt.Sprintf("config: blend of layer (%v) of map (%v) is not supported by mvt providers", e.LayerName, e.MapName)
+}
+
 // ErrMixedProviders represents the user configuration issue of using an MVT provider with another provider
 type ErrMixedProviders struct {
 	Map string
@@ -104,6 +127,20 @@ func (e ErrInvalidURIPrefix) Error() string {
 	return fmt.Sprintf("config: invalid uri_prefix (%v). uri_prefix must start with a forward slash '/' ", string(e))
 }
 
+// ErrInvalidURLRoot is returned for url roots which aren't absolute http or https urls
+type ErrInvalidURLRoot string
+
+func (e ErrInvalidURLRoot) Error() string {
+	return fmt.Sprintf("config: invalid url_root (%v). url_root must be an http or https url without a query, i.e. https://example.com/tiles", string(e))
+}
+
+// ErrInvalidTrustedProxy is returned for trusted proxies which aren't addresses or networks
+type ErrInvalidTrustedProxy string
+
+func (e ErrInvalidTrustedProxy) Error() string {
+	return fmt.Sprintf("config: invalid trusted_proxies entry (%v). entries must be addresses or CIDR networks", string(e))
+}
+
 // ErrUnknownProviderType is returned when the config contains a provider type that has not been registered
 type ErrUnknownProviderType struct {
 	Name           string // Name is the name of the entry in the config
@@ -150,3 +187,190 @@ type ErrProviderTypeRequired struct {
 func (e ErrProviderTypeRequired) Error() string {
 	return fmt.Sprintf("config: type field required for provider at position %v", e.Pos)
 }
+
+var errUntilBeforeFrom = errors.New("until must be after from")
+
+// ErrInvalidAvailability is returned when an availability window can't be parsed
+type ErrInvalidAvailability struct {
+	From  string
+	Until string
+	Err   error
+}
+
+func (e ErrInvalidAvailability) Error() string {
+	return fmt.Sprintf("config: invalid availability window (from: %q, until: %q): %v", e.From, e.Until, e.Err)
+}
+
+// Is returns whether the error is of type ErrInvalidAvailability, only checking the window values.
+func (e ErrInvalidAvailability) Is(err error) bool {
+	err1, ok := err.(ErrInvalidAvailability)
+	if !ok {
+		return false
+	}
+	return err1.From == e.From && err1.Until == e.Until
+}
+
+func (e ErrInvalidAvailability) Unwrap() error { return e.Err }
+
+// ErrGeofenceNameRequired is returned when the name of a geofence is missing
+type ErrGeofenceNameRequired struct {
+	Pos int
+}
+
+func (e ErrGeofenceNameRequired) Error() string {
+	return fmt.Sprintf("config: name field required for geofence at position %v", e.Pos)
+}
+
+// ErrInvalidGeofence is returned when a geofence is misconfigured
+type ErrInvalidGeofence struct {
+	Name   string
+	Reason string
+}
+
+func (e ErrInvalidGeofence) Error() string {
+	return fmt.Sprintf("config: invalid geofence (%v): %v", e.Name, e.Reason)
+}
+
+// ErrKeyClassNameRequired is returned when the name of a key class is missing
+type ErrKeyClassNameRequired struct {
+	Pos int
+}
+
+func (e ErrKeyClassNameRequired) Error() string {
+	return fmt.Sprintf("config: name field required for key class at position %v", e.Pos)
+}
+
+// ErrKeyClassKeyDuplicate is returned when a key is listed more than once in the key classes
+type ErrKeyClassKeyDuplicate struct {
+	Class string
+}
+
+func (e ErrKeyClassKeyDuplicate) Error() string {
+	return fmt.Sprintf("config: key class (%v) has a key already listed by a key class", e.Class)
+}
+
+// ErrUnknownCacheBypassKeyClass is returned when a key class authorized to bypass the cache is not defined
+type ErrUnknownCacheBypassKeyClass struct {
+	Class string
+}
+
+func (e ErrUnknownCacheBypassKeyClass) Error() string {
+	return fmt.Sprintf("config: cache_bypass key class (%v) is not defined in webserver.key_classes", e.Class)
+}
+
+// ErrInvalidCacheVersion is returned for a cache version which can't be used in the cache keys
+type ErrInvalidCacheVersion struct {
+	MapName string
+	Version string
+}
+
+func (e ErrInvalidCacheVersion) Error() string {
+	return fmt.Sprintf("config: invalid cache_version (%v) for map (%v), expected letters, digits, '-', '_' or '.'", e.Version, e.MapName)
+}
+
+// ErrInvalidCacheMaxZoom is returned for a cache max zoom beyond the supported zooms, or below
+// the min zoom of a layer of the map
+type ErrInvalidCacheMaxZoom struct {
+	MapName   string
+	MaxZoom   uint
+	LayerName string
+	MinZoom   uint
+}
+
+func (e ErrInvalidCacheMaxZoom) Error() string {
+	if e.LayerName != "" {
+		return fmt.Sprintf("config: cache_max_zoom (%v) of map (%v) is below the min_zoom (%v) of layer (%v), the layer would be missing from the tiles above it", e.MaxZoom, e.MapName, e.MinZoom, e.LayerName)
+	}
+	return fmt.Sprintf("config: invalid cache_max_zoom (%v) for map (%v), expected at most %v", e.MaxZoom, e.MapName, tegola.MaxZ)
+}
+
+// ErrInvalidMapZooms is returned for min / max zooms of a map which aren't supported or ordered
+type ErrInvalidMapZooms struct {
+	MapName string
+	Reason  string
+}
+
+func (e ErrInvalidMapZooms) Error() string {
+	return fmt.Sprintf("config: invalid zooms for map (%v): %v", e.MapName, e.Reason)
+}
+
+// ErrInvalidEmptyTileStatus is returned for an empty tile status other than 200, 204 and 404
+type ErrInvalidEmptyTileStatus struct {
+	MapName string
+	Status  uint
+}
+
+func (e ErrInvalidEmptyTileStatus) Error() string {
+	return fmt.Sprintf("config: invalid empty_tile_status (%v) for map (%v), expected 200, 204 or 404", e.Status, e.MapName)
+}
+
+// ErrInvalidCacheControl is returned for a cache control of a map with invalid zooms
+type ErrInvalidCacheControl struct {
+	MapName string
+	// Pos is the position of the cache control in the map's config
+	Pos    int
+	Reason string
+}
+
+func (e ErrInvalidCacheControl) Error() string {
+	return fmt.Sprintf("config: invalid cache_control (%v) of map (%v): %v", e.Pos, e.MapName, e.Reason)
+}
+
+// ErrInvalidWarmup is returned for a warm-up area which can't be seeded
+type ErrInvalidWarmup struct {
+	MapName string
+	Reason  string
+}
+
+func (e ErrInvalidWarmup) Error() string {
+	return fmt.Sprintf("config: invalid warmup of map (%v): %v", e.MapName, e.Reason)
+}
+
+// ErrInvalidTracing is returned for tracing configs spans can't be exported with
+type ErrInvalidTracing struct {
+	Reason string
+}
+
+func (e ErrInvalidTracing) Error() string {
+	return fmt.Sprintf("config: invalid tracing: %v", e.Reason)
+}
+
+// ErrInvalidACME is returned for ACME configs certificates can't be obtained with
+type ErrInvalidACME struct {
+	Reason string
+}
+
+func (e ErrInvalidACME) Error() string {
+	return fmt.Sprintf("config: invalid webserver.acme: %v", e.Reason)
+}
+
+// ErrInvalidTenant is returned for tenants whose requests can't be routed to their maps
+type ErrInvalidTenant struct {
+	Name   string
+	Reason string
+}
+
+func (e ErrInvalidTenant) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("config: invalid webserver.tenants: %v", e.Reason)
+	}
+	return fmt.Sprintf("config: invalid webserver.tenants (%v): %v", e.Name, e.Reason)
+}
+
+// ErrInvalidSignedURLs is returned for signed url configs tile urls can't be verified with
+type ErrInvalidSignedURLs struct {
+	Reason string
+}
+
+func (e ErrInvalidSignedURLs) Error() string {
+	return fmt.Sprintf("config: invalid webserver.signed_urls: %v", e.Reason)
+}
+
+// ErrInvalidRateLimit is returned for rate limits which can't be applied
+type ErrInvalidRateLimit struct {
+	Reason string
+}
+
+func (e ErrInvalidRateLimit) Error() string {
+	return fmt.Sprintf("config: invalid webserver.rate_limit: %v", e.Reason)
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/go-spatial/tegola/internal/env"
+)
+
+// the actions of a geofence
+const (
+	GeofenceActionBlock = "block"
+	GeofenceActionLog   = "log"
+)
+
+// KeyClassAnonymous is the key class of requests without a known API key
+const KeyClassAnonymous = "anonymous"
+
+// Geofence is a sensitive region where tile requests above a zoom are blocked or logged
+type Geofence struct {
+	Name env.String `toml:"name"`
+	// Bounds of the region in WGS84: [minx, miny, maxx, maxy]. One of Bounds or Geometry is required.
+	Bounds []env.Float `toml:"bounds"`
+	// Geometry of the region, a GeoJSON Polygon or MultiPolygon in WGS84
+	Geometry env.String `toml:"geometry"`
+	// MinZoom is the zoom the geofence applies from. Defaults to 0.
+	MinZoom *env.Uint `toml:"min_zoom"`
+	// Action is "block" or "log". Defaults to "block".
+	Action env.String `toml:"action"`
+	// Maps the geofence applies to. Defaults to every map.
+	Maps []env.String `toml:"maps"`
+	// KeyClasses the geofence applies to. Defaults to every request.
+	KeyClasses []env.String `toml:"key_classes"`
+}
+
+// KeyClass is a class of API keys. Geofences can be limited to key classes.
+type KeyClass struct {
+	Name env.String   `toml:"name"`
+	Keys []env.String `toml:"keys"`
+}
+
+// validateGeofences checks the geofences have a region and a known action, and their key classes are defined
+func validateGeofences(geofences []Geofence, keyClasses []KeyClass) error {
+	classes := map[string]bool{KeyClassAnonymous: true}
+	keys := map[string]bool{}
+	for i, kc := range keyClasses {
+		if kc.Name == "" {
+			return ErrKeyClassNameRequired{Pos: i}
+		}
+		classes[string(kc.Name)] = true
+		for _, k := range kc.Keys {
+			if keys[string(k)] {
+				return ErrKeyClassKeyDuplicate{Class: string(kc.Name)}
+			}
+			keys[string(k)] = true
+		}
+	}
+
+	for i, g := range geofences {
+		if g.Name == "" {
+			return ErrGeofenceNameRequired{Pos: i}
+		}
+		if len(g.Bounds) == 0 && g.Geometry == "" {
+			return ErrInvalidGeofence{Name: string(g.Name), Reason: "bounds or geometry is required"}
+		}
+		if len(g.Bounds) != 0 && g.Geometry != "" {
+			return ErrInvalidGeofence{Name: string(g.Name), Reason: "bounds and geometry can't both be set"}
+		}
+		if len(g.Bounds) != 0 && len(g.Bounds) != 4 {
+			return ErrInvalidGeofence{Name: string(g.Name), Reason: "bounds must be [minx, miny, maxx, maxy]"}
+		}
+		switch strings.ToLower(string(g.Action)) {
+		case "", GeofenceActionBlock, GeofenceActionLog:
+		default:
+			return ErrInvalidGeofence{Name: string(g.Name), Reason: "action must be block or log, got " + string(g.Action)}
+		}
+		for _, c := range g.KeyClasses {
+			if !classes[string(c)] {
+				return ErrInvalidGeofence{Name: string(g.Name), Reason: "unknown key class " + string(c)}
+			}
+		}
+	}
+	return nil
+}
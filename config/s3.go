@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/go-spatial/tegola/internal/awsutil"
+)
+
+// loadS3 fetches a config file from a location in the format s3://bucket/path/to/config.toml.
+// The region, endpoint, path style addressing and TLS options are read from the environment.
+func loadS3(location string) (io.ReadCloser, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return nil, fmt.Errorf("expected s3://bucket/key got %v", location)
+	}
+
+	sess, err := awsutil.ConfigFromEnv().Session()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s3.New(sess).GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(u.Host),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Body, nil
+}
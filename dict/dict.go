@@ -1,6 +1,9 @@
 package dict
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
+)
 
 // Dict is a pass-through implementation of the Dicter interface
 type Dict map[string]interface{}
@@ -202,3 +205,26 @@ func (d Dict) Interface(key string) (r interface{}, ok bool) {
 	r, ok = d[key]
 	return r, ok
 }
+
+// StringMap reads an optional table of strings of the Dicter, i.e. the headers of a provider.
+// Values which are not strings are formatted with fmt.Sprint. nil is returned when the key is
+// missing.
+func StringMap(d Dicter, key string) (map[string]string, error) {
+	v, ok := d.Interface(key)
+	if !ok {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, ErrKeyType{Key: key, Value: v, T: reflect.TypeOf(map[string]interface{}{})}
+	}
+
+	m := make(map[string]string, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = fmt.Sprint(iter.Value().Interface())
+	}
+
+	return m, nil
+}
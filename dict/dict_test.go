@@ -90,3 +90,45 @@ func TestDict(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestStringMap(t *testing.T) {
+	type tcase struct {
+		dict        dict.Dict
+		expected    map[string]string
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			m, err := dict.StringMap(tc.dict, "headers")
+			if !reflect.DeepEqual(err, tc.expectedErr) {
+				t.Fatalf("expected error %v got %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(m, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, m)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing": {
+			dict: dict.Dict{},
+		},
+		"strings": {
+			dict:     dict.Dict{"headers": map[string]interface{}{"Authorization": "Bearer x", "X-Count": 3}},
+			expected: map[string]string{"Authorization": "Bearer x", "X-Count": "3"},
+		},
+		"not a map": {
+			dict: dict.Dict{"headers": "Authorization"},
+			expectedErr: dict.ErrKeyType{
+				Key:   "headers",
+				Value: "Authorization",
+				T:     reflect.TypeOf(map[string]interface{}{}),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -31,6 +31,8 @@ require (
 	github.com/pkg/errors v0.8.1-0.20180311214515-816c9085562c // indirect
 	github.com/spf13/pflag v1.0.1-0.20180410213010-329ebf1e0480 // indirect
 	github.com/theckman/goconstraint v1.10.1-0.20180216224824-e867bde6e4e1
+	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
+	golang.org/x/net v0.0.0-20200226121028-0de0cce0169b
 	golang.org/x/tools v0.0.0-20200507205054-480da3ebd79c // indirect
 	gopkg.in/go-playground/colors.v1 v1.0.2-0.20150924111726-b53ecfb39623
 )
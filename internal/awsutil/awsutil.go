@@ -0,0 +1,159 @@
+// Package awsutil builds AWS sessions for S3 and S3 compatible object stores (i.e. MinIO, Ceph)
+// so every S3 touchpoint in tegola supports the same connection options.
+package awsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/go-spatial/tegola/dict"
+)
+
+const (
+	ConfigKeyRegion             = "region"   // defaults to "us-east-1"
+	ConfigKeyEndpoint           = "endpoint" // defaults to ""
+	ConfigKeyAWSAccessKeyID     = "aws_access_key_id"
+	ConfigKeyAWSSecretKey       = "aws_secret_access_key"
+	ConfigKeyForcePathStyle     = "force_path_style"     // defaults to false
+	ConfigKeyInsecureSkipVerify = "insecure_skip_verify" // defaults to false
+	ConfigKeyCACert             = "ca_cert"              // defaults to ""
+)
+
+const (
+	EnvRegion             = "AWS_REGION"
+	EnvEndpoint           = "AWS_ENDPOINT"
+	EnvForcePathStyle     = "AWS_S3_FORCE_PATH_STYLE"
+	EnvInsecureSkipVerify = "AWS_INSECURE_SKIP_VERIFY"
+	// EnvCACert matches the environment variable used by the AWS CLI and SDKs
+	EnvCACert = "AWS_CA_BUNDLE"
+)
+
+const DefaultRegion = "us-east-1"
+
+// Config holds the options for connecting to S3 or an S3 compatible object store
+type Config struct {
+	Region string
+	// Endpoint is only necessary for non-AWS deployments (i.e. https://minio.local:9000)
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	// ForcePathStyle uses http://endpoint/bucket/key addressing instead of
+	// http://bucket.endpoint/key. Most S3 compatible stores require this.
+	ForcePathStyle bool
+	// InsecureSkipVerify disables TLS certificate verification
+	InsecureSkipVerify bool
+	// CACert is the path to a PEM encoded CA bundle used to verify the endpoint's certificate.
+	// Useful for self-signed certificates.
+	CACert string
+}
+
+// ConfigFromEnv returns a Config populated from the environment
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Region:             os.Getenv(EnvRegion),
+		Endpoint:           os.Getenv(EnvEndpoint),
+		ForcePathStyle:     envBool(EnvForcePathStyle),
+		InsecureSkipVerify: envBool(EnvInsecureSkipVerify),
+		CACert:             os.Getenv(EnvCACert),
+	}
+	if cfg.Region == "" {
+		cfg.Region = DefaultRegion
+	}
+
+	return cfg
+}
+
+// ConfigFromDict returns a Config read from the provided config, falling back to the
+// environment (see ConfigFromEnv) for any keys which are not set.
+func ConfigFromDict(config dict.Dicter) (cfg Config, err error) {
+	cfg = ConfigFromEnv()
+
+	if cfg.Region, err = config.String(ConfigKeyRegion, &cfg.Region); err != nil {
+		return cfg, err
+	}
+	if cfg.Endpoint, err = config.String(ConfigKeyEndpoint, &cfg.Endpoint); err != nil {
+		return cfg, err
+	}
+	if cfg.AccessKeyID, err = config.String(ConfigKeyAWSAccessKeyID, &cfg.AccessKeyID); err != nil {
+		return cfg, err
+	}
+	if cfg.SecretAccessKey, err = config.String(ConfigKeyAWSSecretKey, &cfg.SecretAccessKey); err != nil {
+		return cfg, err
+	}
+	if cfg.ForcePathStyle, err = config.Bool(ConfigKeyForcePathStyle, &cfg.ForcePathStyle); err != nil {
+		return cfg, err
+	}
+	if cfg.InsecureSkipVerify, err = config.Bool(ConfigKeyInsecureSkipVerify, &cfg.InsecureSkipVerify); err != nil {
+		return cfg, err
+	}
+	if cfg.CACert, err = config.String(ConfigKeyCACert, &cfg.CACert); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// Session creates a new AWS session for the config. If static credentials are not
+// set the AWS credential provider chain is used.
+// http://docs.aws.amazon.com/sdk-for-go/v1/developer-guide/configuring-sdk.html
+func (cfg Config) Session() (*session.Session, error) {
+	awsConfig := aws.Config{
+		Region:           aws.String(cfg.Region),
+		S3ForcePathStyle: aws.Bool(cfg.ForcePathStyle),
+	}
+
+	// support for static credentials, this is not recommended by AWS but
+	// necessary for some environments
+	if cfg.AccessKeyID != "" && cfg.SecretAccessKey != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	}
+
+	// if an endpoint is set, add it to the awsConfig
+	// otherwise do not set it and it will automatically use the correct aws-s3 endpoint
+	if cfg.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(cfg.Endpoint)
+		// plain http endpoints are common for local MinIO deployments
+		awsConfig.DisableSSL = aws.Bool(strings.HasPrefix(strings.ToLower(cfg.Endpoint), "http://"))
+	}
+
+	if cfg.InsecureSkipVerify || cfg.CACert != "" {
+		tlsConfig := tls.Config{
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		}
+
+		if cfg.CACert != "" {
+			pem, err := ioutil.ReadFile(cfg.CACert)
+			if err != nil {
+				return nil, fmt.Errorf("awsutil: unable to read CA file (%v): %w", cfg.CACert, err)
+			}
+
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("awsutil: unable to add CA (%v) to cert pool", cfg.CACert)
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tlsConfig
+
+		awsConfig.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	return session.NewSession(&awsConfig)
+}
+
+func envBool(name string) bool {
+	b, _ := strconv.ParseBool(os.Getenv(name))
+	return b
+}
@@ -0,0 +1,310 @@
+// Package brotli implements a brotli (RFC 7932) compressor. It's written for tiles: the
+// input is buffered until the writer is closed, then compressed with greedy LZ77 matching and
+// one set of prefix codes per meta-block, without the static dictionary or context modeling.
+// The streams are decoded by any brotli decoder.
+package brotli
+
+import (
+	"errors"
+	"io"
+)
+
+const (
+	// the log2 of the window size written in the stream header
+	windowBits = 22
+	// the largest meta-block, matches don't reach into previous meta-blocks
+	maxMetaBlockLength = 1 << 20
+
+	minMatchLength = 4
+	maxMatchLength = 1 << 16
+	// the number of previous positions with the same hash tried for a match
+	maxChainLength = 32
+	hashBits       = 15
+)
+
+var ErrClosed = errors.New("brotli: writer is closed")
+
+// Writer compresses the data written to it, writing the stream when it's closed
+type Writer struct {
+	w      io.Writer
+	buf    []byte
+	closed bool
+}
+
+// NewWriter returns a writer compressing to w. Close must be called to write the stream.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write buffers p to be compressed when the writer is closed
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrClosed
+	}
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// Close compresses the data written and writes the stream. It doesn't close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	_, err := w.w.Write(Encode(w.buf))
+	w.buf = nil
+	return err
+}
+
+// Encode returns the brotli stream of b
+func Encode(b []byte) []byte {
+	var bw bitWriter
+
+	// the window size: 17 + n for n in 1..7
+	bw.writeBits(1, 1)
+	bw.writeBits(3, windowBits-17)
+
+	if len(b) == 0 {
+		// an empty stream is a single empty last meta-block: ISLAST, ISLASTEMPTY
+		bw.writeBits(2, 3)
+	}
+	for len(b) > 0 {
+		n := len(b)
+		if n > maxMetaBlockLength {
+			n = maxMetaBlockLength
+		}
+		writeMetaBlock(&bw, b[:n], n == len(b))
+		b = b[n:]
+	}
+
+	return bw.finish()
+}
+
+// command is a run of literals followed by a copy of previous output
+type command struct {
+	insert   int
+	copy     int
+	distance int
+}
+
+// writeMetaBlock writes the data as a compressed meta-block
+func writeMetaBlock(bw *bitWriter, data []byte, last bool) {
+	commands := findMatches(data)
+
+	// the symbols and the histograms of the prefix codes
+	litHisto := make([]uint32, 256)
+	cmdHisto := make([]uint32, 704)
+	distHisto := make([]uint32, 64)
+
+	type cmdSymbols struct {
+		cmd, dist                   int
+		insExtra, copyExtra         uint32
+		insBits, copyBits, distBits uint
+		distExtra                   uint32
+	}
+	symbols := make([]cmdSymbols, len(commands))
+
+	pos := 0
+	for i, c := range commands {
+		for _, l := range data[pos : pos+c.insert] {
+			litHisto[l]++
+		}
+		pos += c.insert + c.copy
+
+		insCode, copyLen := insertCode(c.insert), c.copy
+		if c.distance == 0 {
+			// the final literals, the copy length is never used
+			copyLen = 2
+		}
+		copyCode := copyLengthCode(copyLen)
+
+		s := cmdSymbols{
+			insExtra:  uint32(c.insert - insertBase[insCode]),
+			insBits:   insertExtra[insCode],
+			copyExtra: uint32(copyLen - copyBase[copyCode]),
+			copyBits:  copyExtra[copyCode],
+			dist:      -1,
+		}
+		s.cmd = commandCode(insCode, copyCode, c.distance == 0)
+		cmdHisto[s.cmd]++
+		if c.distance > 0 {
+			s.dist, s.distBits, s.distExtra = distanceCode(c.distance)
+			distHisto[s.dist]++
+		}
+		symbols[i] = s
+	}
+
+	// ISLAST, ISLASTEMPTY when last
+	if last {
+		bw.writeBits(2, 1)
+	} else {
+		bw.writeBits(1, 0)
+	}
+	// MNIBBLES and MLEN - 1
+	nibbles := 4
+	for nibbles < 6 && (len(data)-1)>>(uint(nibbles)*4) != 0 {
+		nibbles++
+	}
+	bw.writeBits(2, uint64(nibbles-4))
+	bw.writeBits(uint(nibbles)*4, uint64(len(data)-1))
+	if !last {
+		// ISUNCOMPRESSED
+		bw.writeBits(1, 0)
+	}
+
+	// a single block type for literals, commands and distances
+	bw.writeBits(3, 0)
+	// NPOSTFIX and NDIRECT
+	bw.writeBits(6, 0)
+	// the context mode of the literal block type
+	bw.writeBits(2, 0)
+	// a single literal and distance prefix code
+	bw.writeBits(2, 0)
+
+	litCode := writePrefixCode(bw, litHisto, 8)
+	cmdCode := writePrefixCode(bw, cmdHisto, 10)
+	distCode := writePrefixCode(bw, distHisto, 6)
+
+	pos = 0
+	for i, c := range commands {
+		s := symbols[i]
+		cmdCode.write(bw, s.cmd)
+		bw.writeBits(s.insBits, uint64(s.insExtra))
+		bw.writeBits(s.copyBits, uint64(s.copyExtra))
+		for _, l := range data[pos : pos+c.insert] {
+			litCode.write(bw, int(l))
+		}
+		pos += c.insert + c.copy
+		if s.dist >= 0 {
+			distCode.write(bw, s.dist)
+			bw.writeBits(s.distBits, uint64(s.distExtra))
+		}
+	}
+}
+
+// findMatches splits the data into commands with a hash chain of the positions of 4 byte
+// sequences. The last command has no copy when the data ends with literals.
+func findMatches(data []byte) []command {
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, len(data))
+
+	hash := func(i int) uint32 {
+		v := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		return (v * 0x1e35a7bd) >> (32 - hashBits)
+	}
+	insert := func(i int) {
+		if i+minMatchLength <= len(data) {
+			h := hash(i)
+			prev[i] = head[h]
+			head[h] = int32(i)
+		}
+	}
+
+	// longestMatch returns the length and distance of the longest previous match at i
+	longestMatch := func(i int) (int, int) {
+		if i+minMatchLength > len(data) {
+			return 0, 0
+		}
+		maxLen := len(data) - i
+		if maxLen > maxMatchLength {
+			maxLen = maxMatchLength
+		}
+
+		bestLen, bestDist := 0, 0
+		candidate := head[hash(i)]
+		for n := 0; candidate >= 0 && n < maxChainLength; n++ {
+			c := int(candidate)
+			l := 0
+			for l < maxLen && data[c+l] == data[i+l] {
+				l++
+			}
+			if l > bestLen {
+				bestLen, bestDist = l, i-c
+				if l == maxLen {
+					break
+				}
+			}
+			candidate = prev[c]
+		}
+		return bestLen, bestDist
+	}
+
+	var commands []command
+	literals := 0
+	matchLen, matchDist := longestMatch(0)
+	for i := 0; i < len(data); {
+		insert(i)
+		nextLen, nextDist := longestMatch(i + 1)
+
+		// a longer match at the next position is taken instead, after a literal
+		if matchLen < minMatchLength || nextLen > matchLen {
+			literals++
+			i++
+			matchLen, matchDist = nextLen, nextDist
+			continue
+		}
+
+		commands = append(commands, command{insert: literals, copy: matchLen, distance: matchDist})
+		literals = 0
+		for end := i + matchLen; i+1 < end; {
+			i++
+			insert(i)
+		}
+		i++
+		matchLen, matchDist = longestMatch(i)
+	}
+	if literals > 0 {
+		commands = append(commands, command{insert: literals})
+	}
+	return commands
+}
+
+// the insert and copy length codes: their base lengths and number of extra bits
+var (
+	insertBase  = []int{0, 1, 2, 3, 4, 5, 6, 8, 10, 14, 18, 26, 34, 50, 66, 98, 130, 194, 322, 578, 1090, 2114, 6210, 22594}
+	insertExtra = []uint{0, 0, 0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 7, 8, 9, 10, 12, 14, 24}
+	copyBase    = []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 12, 14, 18, 22, 30, 38, 54, 70, 102, 134, 198, 326, 582, 1094, 2118}
+	copyExtra   = []uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 7, 8, 9, 10, 24}
+)
+
+func lengthCode(base []int, n int) int {
+	code := len(base) - 1
+	for base[code] > n {
+		code--
+	}
+	return code
+}
+
+func insertCode(n int) int     { return lengthCode(insertBase, n) }
+func copyLengthCode(n int) int { return lengthCode(copyBase, n) }
+
+// commandCode combines the insert and copy length codes. Commands without a distance use the
+// last distance, which is only possible for the short insert and copy lengths.
+func commandCode(insCode, copyCode int, lastDistance bool) int {
+	low := (insCode&7)<<3 | copyCode&7
+	if lastDistance && insCode < 8 && copyCode < 16 {
+		if copyCode < 8 {
+			return low
+		}
+		return 64 | low
+	}
+	cells := [3][3]int{{128, 192, 384}, {256, 320, 512}, {448, 576, 640}}
+	return cells[insCode>>3][copyCode>>3] | low
+}
+
+// distanceCode returns the distance code, without postfix bits or direct codes, and its extra bits
+func distanceCode(d int) (int, uint, uint32) {
+	dd := uint32(d + 3)
+	nbits := uint(0)
+	for dd>>(nbits+2) != 0 {
+		nbits++
+	}
+	prefix := (dd >> nbits) & 1
+	code := 16 + 2*(int(nbits)-1) + int(prefix)
+	return code, nbits, dd - ((2 + prefix) << nbits)
+}
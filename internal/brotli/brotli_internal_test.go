@@ -0,0 +1,214 @@
+package brotli
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	type tcase struct {
+		data     string
+		expected []byte
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			if _, err := w.Write([]byte(tc.data)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !bytes.Equal(buf.Bytes(), tc.expected) {
+				t.Errorf("expected %#v got %#v", tc.expected, buf.Bytes())
+			}
+
+			if _, err := w.Write([]byte("a")); err != ErrClosed {
+				t.Errorf("expected %v got %v", ErrClosed, err)
+			}
+		}
+	}
+
+	// the streams were checked with the reference decoder
+	tests := map[string]tcase{
+		"empty": {
+			data:     "",
+			expected: []byte{0x3b},
+		},
+		"one literal": {
+			data:     "a",
+			expected: []byte{0x1b, 0x0, 0x0, 0x0, 0x20, 0xc2, 0x2, 0x81, 0x0, 0x0},
+		},
+		"repeats": {
+			data: "tegola tegola tegola tegola",
+			expected: []byte{
+				0x1b, 0x1a, 0x0, 0x0, 0x0, 0x1c, 0x72, 0xa4, 0xb1, 0x6,
+				0x2d, 0x69, 0x4b, 0x1e, 0xf3, 0x84, 0x54, 0xd2, 0xcf, 0x12,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestFindMatches(t *testing.T) {
+	data := []byte("abcdefgh abcdefgh abcdefgh!")
+	commands := findMatches(data)
+
+	expected := []command{
+		{insert: 9, copy: 17, distance: 9},
+		{insert: 1},
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected %v got %v", expected, commands)
+	}
+}
+
+func TestHuffmanLengths(t *testing.T) {
+	type tcase struct {
+		histo   []uint32
+		maxBits uint8
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			lengths := huffmanLengths(tc.histo, tc.maxBits)
+
+			// the code must be complete: the sum of 2^-length is 1
+			var kraft uint64
+			for s, l := range lengths {
+				if (l == 0) != (tc.histo[s] == 0) {
+					t.Fatalf("symbol %v with count %v has length %v", s, tc.histo[s], l)
+				}
+				if l > tc.maxBits {
+					t.Fatalf("symbol %v has length %v, longer than %v", s, l, tc.maxBits)
+				}
+				if l > 0 {
+					kraft += 1 << (tc.maxBits - l)
+				}
+			}
+			if kraft != 1<<tc.maxBits {
+				t.Errorf("incomplete code, expected kraft sum %v got %v", 1<<tc.maxBits, kraft)
+			}
+		}
+	}
+
+	// fibonacci counts build the deepest tree
+	fibonacci := make([]uint32, 30)
+	fibonacci[0], fibonacci[1] = 1, 1
+	for i := 2; i < len(fibonacci); i++ {
+		fibonacci[i] = fibonacci[i-1] + fibonacci[i-2]
+	}
+
+	tests := map[string]tcase{
+		"two symbols": {
+			histo:   []uint32{0, 3, 0, 1},
+			maxBits: 15,
+		},
+		"uniform": {
+			histo:   []uint32{5, 5, 5, 5, 5, 5, 5, 5},
+			maxBits: 15,
+		},
+		"fibonacci limited": {
+			histo:   fibonacci,
+			maxBits: 15,
+		},
+		"code length code": {
+			histo:   fibonacci[:18],
+			maxBits: 5,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestCommandCode(t *testing.T) {
+	type tcase struct {
+		insert       int
+		copy         int
+		lastDistance bool
+		expected     int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			code := commandCode(insertCode(tc.insert), copyLengthCode(tc.copy), tc.lastDistance)
+			if code != tc.expected {
+				t.Errorf("expected %v got %v", tc.expected, code)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"last distance": {
+			insert:       1,
+			copy:         2,
+			lastDistance: true,
+			expected:     8,
+		},
+		"last distance long copy": {
+			insert:       0,
+			copy:         12,
+			lastDistance: true,
+			expected:     64 | 1,
+		},
+		"short": {
+			insert:   0,
+			copy:     4,
+			expected: 128 | 2,
+		},
+		"long insert and copy": {
+			insert:   14,
+			copy:     10,
+			expected: 320 | 1<<3 | 0,
+		},
+		"long insert with last distance": {
+			insert:       130,
+			copy:         2,
+			lastDistance: true,
+			expected:     448 | 0<<3 | 0,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestDistanceCode(t *testing.T) {
+	type tcase struct {
+		distance int
+		code     int
+		bits     uint
+		extra    uint32
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			code, bits, extra := distanceCode(tc.distance)
+			if code != tc.code || bits != tc.bits || extra != tc.extra {
+				t.Errorf("expected (%v, %v, %v) got (%v, %v, %v)", tc.code, tc.bits, tc.extra, code, bits, extra)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"1":    {distance: 1, code: 16, bits: 1, extra: 0},
+		"3":    {distance: 3, code: 17, bits: 1, extra: 0},
+		"4":    {distance: 4, code: 17, bits: 1, extra: 1},
+		"5":    {distance: 5, code: 18, bits: 2, extra: 0},
+		"1000": {distance: 1000, code: 31, bits: 8, extra: 1003 - 3<<8},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,310 @@
+package brotli
+
+import "sort"
+
+// bitWriter packs bits least significant bit first
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+// writeBits writes the n (at most 32) low bits of v
+func (bw *bitWriter) writeBits(n uint, v uint64) {
+	bw.acc |= (v & (1<<n - 1)) << bw.n
+	bw.n += n
+	for bw.n >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.n -= 8
+	}
+}
+
+// finish pads the last byte with zeros and returns the written bytes
+func (bw *bitWriter) finish() []byte {
+	if bw.n > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.n = 0, 0
+	}
+	return bw.buf
+}
+
+const (
+	maxCodeLength = 15
+	// the code length symbols repeating the previous non zero length and zero
+	repeatPrevious = 16
+	repeatZero     = 17
+	// the length repeated by repeatPrevious before a length is written
+	initialRepeatedLength = 8
+)
+
+// the order the code lengths of the code length symbols are written in
+var codeLengthOrder = []int{1, 2, 3, 4, 0, 5, 17, 6, 16, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// the fixed codes of the code lengths of the code length symbols
+var (
+	codeLengthCodeBits   = []uint{2, 4, 3, 2, 2, 4}
+	codeLengthCodeValues = []uint64{0, 7, 3, 2, 1, 15}
+)
+
+// prefixCode is the canonical prefix code of an alphabet, with the codes bit reversed to be
+// written least significant bit first
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint16
+}
+
+func (pc prefixCode) write(bw *bitWriter, symbol int) {
+	bw.writeBits(uint(pc.lengths[symbol]), uint64(pc.codes[symbol]))
+}
+
+// newPrefixCode assigns the canonical codes of the code lengths
+func newPrefixCode(lengths []uint8) prefixCode {
+	var count [maxCodeLength + 1]int
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+
+	var next [maxCodeLength + 1]int
+	code := 0
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+
+	pc := prefixCode{lengths: lengths, codes: make([]uint16, len(lengths))}
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+
+		var rev uint16
+		for i := uint8(0); i < l; i++ {
+			rev = rev<<1 | uint16(c>>i&1)
+		}
+		pc.codes[s] = rev
+	}
+	return pc
+}
+
+// writePrefixCode writes the prefix code of the histogram and returns it. Alphabets with a
+// single used symbol, or none, are written as a simple prefix code whose symbol takes no bits.
+func writePrefixCode(bw *bitWriter, histo []uint32, alphabetBits uint) prefixCode {
+	var used []int
+	for s, n := range histo {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) <= 1 {
+		symbol := 0
+		if len(used) == 1 {
+			symbol = used[0]
+		}
+		// HSKIP 1 is a simple prefix code, of one symbol
+		bw.writeBits(2, 1)
+		bw.writeBits(2, 0)
+		bw.writeBits(alphabetBits, uint64(symbol))
+		return prefixCode{lengths: make([]uint8, len(histo)), codes: make([]uint16, len(histo))}
+	}
+
+	lengths := huffmanLengths(histo, maxCodeLength)
+	writeCodeLengths(bw, lengths[:used[len(used)-1]+1])
+	return newPrefixCode(lengths)
+}
+
+// writeCodeLengths writes the code lengths of a complex prefix code, run length encoded with
+// the repeat symbols
+func writeCodeLengths(bw *bitWriter, lengths []uint8) {
+	var symbols []uint8
+	var extra []uint8
+	add := func(s, e uint8) {
+		symbols = append(symbols, s)
+		extra = append(extra, e)
+	}
+	// repeats of more than one symbol are written most significant part first
+	reverseFrom := func(start int) {
+		for i, j := start, len(symbols)-1; i < j; i, j = i+1, j-1 {
+			symbols[i], symbols[j] = symbols[j], symbols[i]
+			extra[i], extra[j] = extra[j], extra[i]
+		}
+	}
+
+	previous := uint8(initialRepeatedLength)
+	for i := 0; i < len(lengths); {
+		value, reps := lengths[i], 1
+		for i+reps < len(lengths) && lengths[i+reps] == value {
+			reps++
+		}
+		i += reps
+
+		if value == 0 {
+			if reps == 11 {
+				add(0, 0)
+				reps--
+			}
+			if reps < 3 {
+				for ; reps > 0; reps-- {
+					add(0, 0)
+				}
+				continue
+			}
+			start := len(symbols)
+			for reps -= 3; ; reps-- {
+				add(repeatZero, uint8(reps&7))
+				if reps >>= 3; reps == 0 {
+					break
+				}
+			}
+			reverseFrom(start)
+			continue
+		}
+
+		if value != previous {
+			add(value, 0)
+			reps--
+			previous = value
+		}
+		if reps == 7 {
+			add(value, 0)
+			reps--
+		}
+		if reps < 3 {
+			for ; reps > 0; reps-- {
+				add(value, 0)
+			}
+			continue
+		}
+		start := len(symbols)
+		for reps -= 3; ; reps-- {
+			add(repeatPrevious, uint8(reps&3))
+			if reps >>= 2; reps == 0 {
+				break
+			}
+		}
+		reverseFrom(start)
+	}
+
+	histo := make([]uint32, len(codeLengthOrder))
+	for _, s := range symbols {
+		histo[s]++
+	}
+	clLengths := huffmanLengths(histo, 5)
+
+	// the code lengths are written up to the last one used, unless a single symbol is used
+	numCodes, last := 0, 0
+	for i, s := range codeLengthOrder {
+		if clLengths[s] != 0 {
+			numCodes++
+			last = i
+		}
+	}
+	if numCodes == 1 {
+		last = len(codeLengthOrder) - 1
+	}
+
+	skip := 0
+	if clLengths[codeLengthOrder[0]] == 0 && clLengths[codeLengthOrder[1]] == 0 {
+		skip = 2
+		if clLengths[codeLengthOrder[2]] == 0 {
+			skip = 3
+		}
+	}
+	bw.writeBits(2, uint64(skip))
+	for _, s := range codeLengthOrder[skip : last+1] {
+		l := clLengths[s]
+		bw.writeBits(codeLengthCodeBits[l], codeLengthCodeValues[l])
+	}
+
+	if numCodes == 1 {
+		// the single symbol takes no bits
+		for i := range clLengths {
+			clLengths[i] = 0
+		}
+	}
+	clCode := newPrefixCode(clLengths)
+	for i, s := range symbols {
+		clCode.write(bw, int(s))
+		switch s {
+		case repeatPrevious:
+			bw.writeBits(2, uint64(extra[i]))
+		case repeatZero:
+			bw.writeBits(3, uint64(extra[i]))
+		}
+	}
+}
+
+// huffmanLengths returns the code lengths, at most maxBits, of the symbols of the histogram.
+// The counts of rare symbols are raised until the code fits in maxBits. A single used symbol
+// has a length of 1.
+func huffmanLengths(histo []uint32, maxBits uint8) []uint8 {
+	type node struct {
+		weight      uint64
+		left, right int
+		symbol      int
+	}
+
+	lengths := make([]uint8, len(histo))
+	var leaves []int
+	for s, n := range histo {
+		if n > 0 {
+			leaves = append(leaves, s)
+		}
+	}
+	switch len(leaves) {
+	case 0:
+		return lengths
+	case 1:
+		lengths[leaves[0]] = 1
+		return lengths
+	}
+
+	for minCount := uint64(1); ; minCount *= 2 {
+		nodes := make([]node, 0, 2*len(leaves))
+		for _, s := range leaves {
+			w := uint64(histo[s])
+			if w < minCount {
+				w = minCount
+			}
+			nodes = append(nodes, node{weight: w, left: -1, right: -1, symbol: s})
+		}
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].weight < nodes[j].weight })
+
+		// merge the two lightest of the sorted leaves and the queue of internal nodes
+		n := len(nodes)
+		leaf, internal := 0, n
+		lightest := func() int {
+			if leaf < n && (internal >= len(nodes) || nodes[leaf].weight <= nodes[internal].weight) {
+				leaf++
+				return leaf - 1
+			}
+			internal++
+			return internal - 1
+		}
+		for len(nodes) < 2*n-1 {
+			a, b := lightest(), lightest()
+			nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, left: a, right: b, symbol: -1})
+		}
+
+		ok := true
+		var walk func(i int, depth uint8)
+		walk = func(i int, depth uint8) {
+			if nodes[i].symbol >= 0 {
+				lengths[nodes[i].symbol] = depth
+				if depth > maxBits {
+					ok = false
+				}
+				return
+			}
+			walk(nodes[i].left, depth+1)
+			walk(nodes[i].right, depth+1)
+		}
+		walk(len(nodes)-1, 0)
+		if ok {
+			return lengths
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package geotiff
+
+import (
+	"github.com/go-spatial/tegola/internal/ttlcache"
+)
+
+// BlockKey identifies a block of an image of the GeoTIFF
+type BlockKey struct {
+	Image, Block int
+}
+
+// BlockCache keeps the most recently used decoded blocks of the GeoTIFF in memory, so
+// neighbouring tiles don't read and decompress the same blocks again. The blocks are stored
+// as decoded by the reader of the samples.
+type BlockCache struct {
+	blocks *ttlcache.Cache
+}
+
+// NewBlockCache returns a cache of up to maxBlocks blocks. Nothing is cached when maxBlocks is 0.
+func NewBlockCache(maxBlocks int) *BlockCache {
+	bc := BlockCache{}
+	if maxBlocks > 0 {
+		bc.blocks = ttlcache.New(maxBlocks, 0)
+	}
+	return &bc
+}
+
+// Get returns the block of the key, false when it isn't cached
+func (bc *BlockCache) Get(key BlockKey) (interface{}, bool) {
+	if bc == nil {
+		return nil, false
+	}
+	return bc.blocks.Get(key)
+}
+
+// Set caches the block of the key, evicting the least recently used blocks
+func (bc *BlockCache) Set(key BlockKey, block interface{}) {
+	if bc == nil {
+		return
+	}
+	bc.blocks.Set(key, block)
+}
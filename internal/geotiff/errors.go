@@ -0,0 +1,14 @@
+package geotiff
+
+import (
+	"fmt"
+)
+
+// ErrInvalid is returned when the file is not a GeoTIFF which can be read
+type ErrInvalid struct {
+	Reason string
+}
+
+func (e ErrInvalid) Error() string {
+	return fmt.Sprintf("geotiff: invalid GeoTIFF: %v", e.Reason)
+}
@@ -0,0 +1,593 @@
+// Package geotiff reads the layout, georeferencing and blocks of GeoTIFFs and Cloud Optimized
+// GeoTIFFs. The samples of the blocks are decoded by the packages reading the GeoTIFFs, i.e. as
+// colors by the raster tiles and as elevations by the contour provider.
+package geotiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/tegola"
+)
+
+// TIFF tags read from the GeoTIFF
+const (
+	TagNewSubfileType  = 254
+	TagImageWidth      = 256
+	TagImageLength     = 257
+	TagBitsPerSample   = 258
+	TagCompression     = 259
+	TagPhotometric     = 262
+	TagStripOffsets    = 273
+	TagSamplesPerPixel = 277
+	TagRowsPerStrip    = 278
+	TagStripByteCounts = 279
+	TagPlanarConfig    = 284
+	TagPredictor       = 317
+	TagColorMap        = 320
+	TagTileWidth       = 322
+	TagTileLength      = 323
+	TagTileOffsets     = 324
+	TagTileByteCounts  = 325
+	TagSampleFormat    = 339
+	TagJPEGTables      = 347
+
+	TagModelPixelScale     = 33550
+	TagModelTiepoint       = 33922
+	TagModelTransformation = 34264
+	TagGeoKeyDirectory     = 34735
+	TagGDALNoData          = 42113
+)
+
+// GeoTIFF keys read from the GeoKeyDirectory
+const (
+	GeoKeyModelType      = 1024
+	GeoKeyRasterType     = 1025
+	GeoKeyGeographicType = 2048
+	GeoKeyProjectedType  = 3072
+
+	modelTypeGeographic = 2
+	rasterPixelIsPoint  = 2
+)
+
+const (
+	CompressionNone       = 1
+	CompressionJPEG       = 7
+	CompressionDeflate    = 8
+	CompressionDeflateOld = 32946
+
+	PhotometricMinIsWhite = 0
+	PhotometricMinIsBlack = 1
+	PhotometricRGB        = 2
+	PhotometricPalette    = 3
+	PhotometricYCbCr      = 6
+
+	PredictorNone          = 1
+	PredictorHorizontal    = 2
+	PredictorFloatingPoint = 3
+
+	SampleFormatUint  = 1
+	SampleFormatInt   = 2
+	SampleFormatFloat = 3
+)
+
+// size in bytes of the TIFF field types
+var fieldTypeSizes = map[uint16]uint64{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8, 13: 4, 16: 8, 17: 8, 18: 8,
+}
+
+// the largest IFD entry count and field size accepted, protecting against corrupt files
+const (
+	maxIFDEntries = 4096
+	maxFieldBytes = 64 * 1024 * 1024
+)
+
+type field struct {
+	typ   uint16
+	count uint64
+	data  []byte
+}
+
+type ifd map[uint16]field
+
+// Image is a single resolution of the GeoTIFF, read in blocks (tiles or strips)
+type Image struct {
+	// Index of the image within the file, used as part of the block cache key
+	Index int
+
+	Width, Height   int
+	BlockW, BlockH  int
+	BlocksAcross    int
+	Offsets, Counts []uint64
+	Compression     int
+	Predictor       int
+	Photometric     int
+	BitsPerSample   int
+	SampleFormat    int
+	SamplesPerPixel int
+	// Stride is the number of samples of a pixel stored in a block. The blocks of planar
+	// images hold a single sample, the first blocks being the first sample.
+	Stride int
+	Planar bool
+	// ColorMap is the red, green and blue values of the palette of palette images
+	ColorMap   []uint64
+	JPEGTables []byte
+
+	OriginX, OriginY  float64
+	ScaleX, ScaleY    float64
+	ReducedResolution bool
+}
+
+// File reads the images of a GeoTIFF
+type File struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+	big   bool
+
+	// SRID of the GeoTIFF identified from its geo keys, 0 when it can't be identified
+	SRID uint64
+	// NoData is the GDAL_NODATA value of the GeoTIFF
+	NoData    float64
+	HasNoData bool
+
+	// Images sorted from the full resolution to the coarsest overview
+	Images []*Image
+	// Cache keeps the decoded blocks of the images. Blocks aren't cached when nil.
+	Cache *BlockCache
+}
+
+// Open reads the header and the images' layout of the GeoTIFF. The compression and the
+// samples of the images are not checked, the reader of the samples checks them.
+func Open(r io.ReaderAt) (*File, error) {
+	var h [16]byte
+	if _, err := r.ReadAt(h[:8], 0); err != nil {
+		// errors other than a short file, i.e. of a remote GeoTIFF, are reported as is
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		return nil, ErrInvalid{Reason: "missing header"}
+	}
+
+	g := File{r: r}
+	switch string(h[:2]) {
+	case "II":
+		g.order = binary.LittleEndian
+	case "MM":
+		g.order = binary.BigEndian
+	default:
+		return nil, ErrInvalid{Reason: "invalid byte order"}
+	}
+
+	var next uint64
+	switch g.order.Uint16(h[2:4]) {
+	case 42:
+		next = uint64(g.order.Uint32(h[4:8]))
+	case 43:
+		// BigTIFF
+		g.big = true
+		if _, err := r.ReadAt(h[8:16], 8); err != nil {
+			return nil, ErrInvalid{Reason: "missing BigTIFF header"}
+		}
+		next = g.order.Uint64(h[8:16])
+	default:
+		return nil, ErrInvalid{Reason: "not a TIFF file"}
+	}
+
+	var ifds []ifd
+	seen := map[uint64]bool{}
+	for next != 0 {
+		if seen[next] {
+			return nil, ErrInvalid{Reason: "IFD loop"}
+		}
+		seen[next] = true
+
+		d, n, err := g.readIFD(next)
+		if err != nil {
+			return nil, err
+		}
+		ifds = append(ifds, d)
+		next = n
+	}
+	if len(ifds) == 0 {
+		return nil, ErrInvalid{Reason: "no images"}
+	}
+
+	for i, d := range ifds {
+		img, err := g.image(i, d)
+		if err != nil {
+			return nil, err
+		}
+		// masks are skipped
+		if img == nil {
+			continue
+		}
+		g.Images = append(g.Images, img)
+	}
+	if len(g.Images) == 0 || g.Images[0].ReducedResolution {
+		return nil, ErrInvalid{Reason: "missing full resolution image"}
+	}
+
+	if err := g.georeference(ifds[0]); err != nil {
+		return nil, err
+	}
+
+	if f, ok := ifds[0][TagGDALNoData]; ok {
+		s := strings.TrimSpace(strings.TrimRight(string(f.data), "\x00"))
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			g.NoData, g.HasNoData = v, true
+		}
+	}
+
+	return &g, nil
+}
+
+func (g *File) readIFD(off uint64) (ifd, uint64, error) {
+	countSize, entrySize, valueSize := uint64(2), uint64(12), uint64(4)
+	if g.big {
+		countSize, entrySize, valueSize = 8, 20, 8
+	}
+
+	buf := make([]byte, countSize)
+	if _, err := g.r.ReadAt(buf, int64(off)); err != nil {
+		return nil, 0, ErrInvalid{Reason: "truncated IFD"}
+	}
+	var n uint64
+	if g.big {
+		n = g.order.Uint64(buf)
+	} else {
+		n = uint64(g.order.Uint16(buf))
+	}
+	if n > maxIFDEntries {
+		return nil, 0, ErrInvalid{Reason: "too many IFD entries"}
+	}
+
+	buf = make([]byte, n*entrySize+valueSize)
+	if _, err := g.r.ReadAt(buf, int64(off+countSize)); err != nil {
+		return nil, 0, ErrInvalid{Reason: "truncated IFD"}
+	}
+
+	d := ifd{}
+	for i := uint64(0); i < n; i++ {
+		e := buf[i*entrySize : (i+1)*entrySize]
+
+		f := field{typ: g.order.Uint16(e[2:4])}
+		var value []byte
+		if g.big {
+			f.count, value = g.order.Uint64(e[4:12]), e[12:20]
+		} else {
+			f.count, value = uint64(g.order.Uint32(e[4:8])), e[8:12]
+		}
+
+		size, ok := fieldTypeSizes[f.typ]
+		if !ok {
+			// unknown field types are skipped
+			continue
+		}
+		if f.count > maxFieldBytes/size {
+			return nil, 0, ErrInvalid{Reason: "field too large"}
+		}
+		size *= f.count
+
+		if size <= valueSize {
+			f.data = append([]byte(nil), value[:size]...)
+		} else {
+			var at uint64
+			if g.big {
+				at = g.order.Uint64(value)
+			} else {
+				at = uint64(g.order.Uint32(value))
+			}
+			f.data = make([]byte, size)
+			if _, err := g.r.ReadAt(f.data, int64(at)); err != nil {
+				return nil, 0, ErrInvalid{Reason: "truncated field"}
+			}
+		}
+		d[g.order.Uint16(e[0:2])] = f
+	}
+
+	last := buf[n*entrySize:]
+	if g.big {
+		return d, g.order.Uint64(last), nil
+	}
+	return d, uint64(g.order.Uint32(last)), nil
+}
+
+// uints returns the values of an integer field
+func (g *File) uints(f field) []uint64 {
+	vs := make([]uint64, f.count)
+	for i := range vs {
+		switch f.typ {
+		case 1, 6, 7:
+			vs[i] = uint64(f.data[i])
+		case 3, 8:
+			vs[i] = uint64(g.order.Uint16(f.data[i*2:]))
+		case 4, 9, 13:
+			vs[i] = uint64(g.order.Uint32(f.data[i*4:]))
+		case 16, 17, 18:
+			vs[i] = g.order.Uint64(f.data[i*8:])
+		default:
+			return nil
+		}
+	}
+	return vs
+}
+
+// floats returns the values of a floating point field
+func (g *File) floats(f field) []float64 {
+	if f.typ != 12 {
+		return nil
+	}
+	vs := make([]float64, f.count)
+	for i := range vs {
+		vs[i] = math.Float64frombits(g.order.Uint64(f.data[i*8:]))
+	}
+	return vs
+}
+
+// uint returns the first value of an integer field or def when the tag is missing
+func (g *File) uint(d ifd, tag uint16, def int) int {
+	f, ok := d[tag]
+	if !ok {
+		return def
+	}
+	vs := g.uints(f)
+	if len(vs) == 0 {
+		return def
+	}
+	return int(vs[0])
+}
+
+// image reads the layout of the image described by d. nil is returned for transparency masks.
+func (g *File) image(index int, d ifd) (*Image, error) {
+	subfileType := g.uint(d, TagNewSubfileType, 0)
+	if subfileType&4 != 0 {
+		return nil, nil
+	}
+
+	img := Image{
+		Index:             index,
+		Width:             g.uint(d, TagImageWidth, 0),
+		Height:            g.uint(d, TagImageLength, 0),
+		Compression:       g.uint(d, TagCompression, CompressionNone),
+		Predictor:         g.uint(d, TagPredictor, PredictorNone),
+		BitsPerSample:     g.uint(d, TagBitsPerSample, 1),
+		SampleFormat:      g.uint(d, TagSampleFormat, SampleFormatUint),
+		SamplesPerPixel:   g.uint(d, TagSamplesPerPixel, 1),
+		Planar:            g.uint(d, TagPlanarConfig, 1) == 2,
+		JPEGTables:        d[TagJPEGTables].data,
+		ReducedResolution: subfileType&1 != 0,
+	}
+	if img.Width <= 0 || img.Height <= 0 {
+		return nil, ErrInvalid{Reason: "missing image size"}
+	}
+
+	img.Photometric = PhotometricMinIsBlack
+	if img.SamplesPerPixel >= 3 {
+		img.Photometric = PhotometricRGB
+	}
+	img.Photometric = g.uint(d, TagPhotometric, img.Photometric)
+	if f, ok := d[TagColorMap]; ok {
+		img.ColorMap = g.uints(f)
+	}
+
+	img.Stride = img.SamplesPerPixel
+	if img.Planar {
+		img.Stride = 1
+	}
+
+	offsetsTag, countsTag := uint16(TagStripOffsets), uint16(TagStripByteCounts)
+	if _, ok := d[TagTileWidth]; ok {
+		offsetsTag, countsTag = TagTileOffsets, TagTileByteCounts
+		img.BlockW, img.BlockH = g.uint(d, TagTileWidth, 0), g.uint(d, TagTileLength, 0)
+	} else {
+		img.BlockW, img.BlockH = img.Width, g.uint(d, TagRowsPerStrip, img.Height)
+		if img.BlockH > img.Height {
+			img.BlockH = img.Height
+		}
+	}
+	if img.BlockW <= 0 || img.BlockH <= 0 {
+		return nil, ErrInvalid{Reason: "invalid block size"}
+	}
+	img.BlocksAcross = (img.Width + img.BlockW - 1) / img.BlockW
+	blocksDown := (img.Height + img.BlockH - 1) / img.BlockH
+
+	img.Offsets, img.Counts = g.uints(d[offsetsTag]), g.uints(d[countsTag])
+	if len(img.Offsets) < img.BlocksAcross*blocksDown || len(img.Counts) < len(img.Offsets) {
+		return nil, ErrInvalid{Reason: "missing block offsets"}
+	}
+
+	return &img, nil
+}
+
+// georeference reads the GeoTIFF tags of the full resolution image and derives the
+// pixel sizes of the overviews from it
+func (g *File) georeference(d ifd) error {
+	full := g.Images[0]
+
+	if f, ok := d[TagModelTransformation]; ok {
+		m := g.floats(f)
+		if len(m) < 16 || m[1] != 0 || m[4] != 0 {
+			return ErrInvalid{Reason: "rotated rasters are not supported"}
+		}
+		full.ScaleX, full.ScaleY = m[0], -m[5]
+		full.OriginX, full.OriginY = m[3], m[7]
+	} else {
+		scale, tiepoint := g.floats(d[TagModelPixelScale]), g.floats(d[TagModelTiepoint])
+		if len(scale) < 2 || len(tiepoint) < 6 {
+			return ErrInvalid{Reason: "missing georeferencing"}
+		}
+		full.ScaleX, full.ScaleY = scale[0], scale[1]
+		full.OriginX = tiepoint[3] - tiepoint[0]*full.ScaleX
+		full.OriginY = tiepoint[4] + tiepoint[1]*full.ScaleY
+	}
+	if full.ScaleX <= 0 || full.ScaleY <= 0 {
+		return ErrInvalid{Reason: "invalid pixel scale"}
+	}
+
+	keys := map[int]int{}
+	if f, ok := d[TagGeoKeyDirectory]; ok {
+		dir := g.uints(f)
+		for i := 4; len(dir) >= 4 && i+3 < len(dir) && i < 4+int(dir[3])*4; i += 4 {
+			// only keys stored in the directory itself are read
+			if dir[i+1] == 0 {
+				keys[int(dir[i])] = int(dir[i+3])
+			}
+		}
+	}
+
+	if keys[GeoKeyRasterType] == rasterPixelIsPoint {
+		// the origin is the center of the first pixel, move it to its corner
+		full.OriginX -= full.ScaleX / 2
+		full.OriginY += full.ScaleY / 2
+	}
+
+	switch {
+	case isWebMercator(keys[GeoKeyProjectedType]):
+		g.SRID = tegola.WebMercator
+	case keys[GeoKeyGeographicType] == tegola.WGS84,
+		keys[GeoKeyModelType] == modelTypeGeographic && keys[GeoKeyGeographicType] == 0:
+		g.SRID = tegola.WGS84
+	}
+
+	for _, img := range g.Images[1:] {
+		img.OriginX, img.OriginY = full.OriginX, full.OriginY
+		img.ScaleX = full.ScaleX * float64(full.Width) / float64(img.Width)
+		img.ScaleY = full.ScaleY * float64(full.Height) / float64(img.Height)
+	}
+	sort.SliceStable(g.Images, func(i, j int) bool {
+		return g.Images[i].ScaleX < g.Images[j].ScaleX
+	})
+
+	return nil
+}
+
+// isWebMercator reports if the EPSG code is one of the codes used for web mercator
+func isWebMercator(code int) bool {
+	switch code {
+	case tegola.WebMercator, 3785, 900913, 102100, 102113:
+		return true
+	}
+	return false
+}
+
+// Level returns the coarsest image with pixels no larger than resolution, in the GeoTIFF's units
+func (g *File) Level(resolution float64) *Image {
+	img := g.Images[0]
+	for _, i := range g.Images[1:] {
+		if i.ScaleX > resolution {
+			break
+		}
+		img = i
+	}
+	return img
+}
+
+// ReadBlock reads the stored bytes of a block of the image. nil is returned for sparse blocks.
+func (g *File) ReadBlock(img *Image, index int) ([]byte, error) {
+	if img.Counts[index] == 0 {
+		return nil, nil
+	}
+	if img.Counts[index] > maxFieldBytes {
+		return nil, ErrInvalid{Reason: "block too large"}
+	}
+
+	data := make([]byte, img.Counts[index])
+	if _, err := g.r.ReadAt(data, int64(img.Offsets[index])); err != nil {
+		return nil, ErrInvalid{Reason: "truncated block"}
+	}
+	return data, nil
+}
+
+// DecodeBlock reads a block of an uncompressed or deflate compressed image and reverses its
+// predictor. The samples are returned with the number of rows they hold, which is 0 for sparse
+// blocks, and their byte order. The floating point predictor leaves the samples big endian.
+func (g *File) DecodeBlock(img *Image, index int) ([]byte, int, binary.ByteOrder, error) {
+	data, err := g.ReadBlock(img, index)
+	if err != nil || data == nil {
+		return nil, 0, g.order, err
+	}
+
+	switch img.Compression {
+	case CompressionNone:
+	case CompressionDeflate, CompressionDeflateOld:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, nil, ErrInvalid{Reason: "invalid deflate block: " + err.Error()}
+		}
+		if data, err = ioutil.ReadAll(zr); err != nil {
+			return nil, 0, nil, ErrInvalid{Reason: "invalid deflate block: " + err.Error()}
+		}
+	default:
+		return nil, 0, nil, ErrInvalid{Reason: "unsupported compression " + strconv.Itoa(img.Compression)}
+	}
+
+	bytesPerSample := img.BitsPerSample / 8
+	rowBytes := img.BlockW * img.Stride * bytesPerSample
+	rows := img.BlockH
+	if img.BlocksAcross == 1 && (index+1)*img.BlockH > img.Height {
+		// the last strip may be shorter
+		rows = img.Height - index*img.BlockH
+	}
+	if len(data) < rows*rowBytes {
+		return nil, 0, nil, ErrInvalid{Reason: "short block"}
+	}
+
+	order := g.order
+	switch img.Predictor {
+	case PredictorNone:
+	case PredictorHorizontal:
+		undoHorizontalPredictor(data[:rows*rowBytes], rowBytes, img.Stride, bytesPerSample, order)
+	case PredictorFloatingPoint:
+		undoFloatingPointPredictor(data[:rows*rowBytes], rowBytes, img.Stride, bytesPerSample)
+		order = binary.BigEndian
+	default:
+		return nil, 0, nil, ErrInvalid{Reason: "unsupported predictor " + strconv.Itoa(img.Predictor)}
+	}
+
+	return data, rows, order, nil
+}
+
+// undoHorizontalPredictor reverses the differencing of integer samples along each row
+func undoHorizontalPredictor(data []byte, rowBytes, stride, size int, order binary.ByteOrder) {
+	step := stride * size
+	for row := 0; row+rowBytes <= len(data); row += rowBytes {
+		for i := row + step; i < row+rowBytes; i += size {
+			switch size {
+			case 1:
+				data[i] += data[i-step]
+			case 2:
+				order.PutUint16(data[i:], order.Uint16(data[i:])+order.Uint16(data[i-step:]))
+			case 4:
+				order.PutUint32(data[i:], order.Uint32(data[i:])+order.Uint32(data[i-step:]))
+			default:
+				order.PutUint64(data[i:], order.Uint64(data[i:])+order.Uint64(data[i-step:]))
+			}
+		}
+	}
+}
+
+// undoFloatingPointPredictor reverses the byte differencing of each row and reassembles the
+// samples from their byte planes. The samples are big endian afterwards.
+func undoFloatingPointPredictor(data []byte, rowBytes, stride, size int) {
+	tmp := make([]byte, rowBytes)
+	samples := rowBytes / size
+	for row := 0; row+rowBytes <= len(data); row += rowBytes {
+		r := data[row : row+rowBytes]
+		for i := stride; i < rowBytes; i++ {
+			r[i] += r[i-stride]
+		}
+		for i := 0; i < samples; i++ {
+			for b := 0; b < size; b++ {
+				tmp[i*size+b] = r[b*samples+i]
+			}
+		}
+		copy(r, tmp)
+	}
+}
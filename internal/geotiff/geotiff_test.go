@@ -0,0 +1,74 @@
+package geotiff_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-spatial/tegola/internal/geotiff"
+)
+
+func TestOpenInvalid(t *testing.T) {
+	type tcase struct {
+		data []byte
+		err  error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := geotiff.Open(bytes.NewReader(tc.data))
+			if err != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"empty": {
+			err: geotiff.ErrInvalid{Reason: "missing header"},
+		},
+		"byte order": {
+			data: []byte("XX\x2a\x00\x08\x00\x00\x00"),
+			err:  geotiff.ErrInvalid{Reason: "invalid byte order"},
+		},
+		"not a tiff": {
+			data: []byte("II\x2b\x01\x08\x00\x00\x00"),
+			err:  geotiff.ErrInvalid{Reason: "not a TIFF file"},
+		},
+		"no images": {
+			data: []byte("II\x2a\x00\x00\x00\x00\x00"),
+			err:  geotiff.ErrInvalid{Reason: "no images"},
+		},
+		"truncated ifd": {
+			data: []byte("II\x2a\x00\x10\x00\x00\x00"),
+			err:  geotiff.ErrInvalid{Reason: "truncated IFD"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestBlockCache(t *testing.T) {
+	bc := geotiff.NewBlockCache(2)
+	for i := 0; i < 3; i++ {
+		bc.Set(geotiff.BlockKey{Block: i}, i)
+		// the first block is used most recently
+		if _, ok := bc.Get(geotiff.BlockKey{Block: 0}); !ok {
+			t.Fatalf("block 0, expected to be cached after setting block %v", i)
+		}
+	}
+	if _, ok := bc.Get(geotiff.BlockKey{Block: 1}); ok {
+		t.Errorf("block 1, expected to be evicted")
+	}
+	if v, ok := bc.Get(geotiff.BlockKey{Block: 2}); !ok || v != 2 {
+		t.Errorf("block 2, expected 2 got %v", v)
+	}
+
+	// a nil cache caches nothing
+	var nc *geotiff.BlockCache
+	nc.Set(geotiff.BlockKey{}, 0)
+	if _, ok := nc.Get(geotiff.BlockKey{}); ok {
+		t.Errorf("nil cache, expected no block")
+	}
+}
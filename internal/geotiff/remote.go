@@ -0,0 +1,22 @@
+package geotiff
+
+import (
+	"net/http"
+
+	"github.com/go-spatial/tegola/internal/remote"
+)
+
+// the bytes requested per range of a remote GeoTIFF, and the max number of ranges kept. The
+// header and IFDs of a GeoTIFF are read as many small reads, which mostly hit the same range.
+const (
+	remoteChunkSize = 64 * 1024
+	remoteMaxChunks = 32
+)
+
+// NewHTTPReaderAt returns a reader of the GeoTIFF at the url, read with range requests. The most
+// recently read ranges are kept, so the GeoTIFF's metadata isn't requested again. The decoded
+// samples are cached separately by the block cache. headers are added to each request.
+func NewHTTPReaderAt(url string, headers map[string]string, client *http.Client) *remote.Reader {
+	src := remote.HTTPSource{URL: url, Headers: headers, Client: client}
+	return remote.NewReader(&src, remoteChunkSize, remoteMaxChunks)
+}
@@ -3,6 +3,7 @@ package log
 import (
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 )
 
@@ -67,10 +68,37 @@ func (lvl Level) String() string {
 	}
 }
 
+// ParseLevel converts a level name (i.e. "debug", "INFO") into a Level
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "TRACE":
+		return TRACE, nil
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN", "WARNING":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	case "FATAL":
+		return FATAL, nil
+	default:
+		return INFO, fmt.Errorf("log: unknown level (%v)", name)
+	}
+}
+
 func SetOutput(w io.Writer) {
 	logger.SetOutput(w)
 }
 
+// GetLogLevel returns the currently configured log level
+func GetLogLevel() Level {
+	lock.Lock()
+	defer lock.Unlock()
+	return level
+}
+
 func SetLogLevel(lvl Level) {
 	lock.Lock()
 	IsTrace = false
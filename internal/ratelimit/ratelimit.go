@@ -0,0 +1,111 @@
+// Package ratelimit limits the rate of requests with token buckets
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultMaxClients is the number of clients a Limiter tracks when MaxClients is 0
+const DefaultMaxClients = 100000
+
+// Bucket is a token bucket refilled at Rate tokens per second up to Burst tokens. The zero
+// value of the tokens is a full bucket.
+type Bucket struct {
+	// Rate is the number of tokens added per second
+	Rate float64
+	// Burst is the size of the bucket
+	Burst int
+
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens of the time since the bucket was last taken from
+func (b *Bucket) refill(now time.Time) {
+	if b.last.IsZero() {
+		b.tokens = float64(b.Burst)
+	} else {
+		b.tokens = math.Min(float64(b.Burst), b.tokens+now.Sub(b.last).Seconds()*b.Rate)
+	}
+	b.last = now
+}
+
+// Take takes a token from the bucket. When the bucket is empty false is returned with the
+// time until the next token is added.
+func (b *Bucket) Take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / b.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Limiter limits the rate of the requests of each client with a bucket per client
+type Limiter struct {
+	// Rate is the number of requests per second of a client
+	Rate float64
+	// Burst is the number of requests of a client allowed at once above the rate. Defaults to
+	// the rate rounded up.
+	Burst int
+	// MaxClients is the number of clients tracked at once. When more clients make requests
+	// the least recently seen clients are forgotten. Defaults to DefaultMaxClients.
+	MaxClients int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// lru are the clients, the most recently seen first
+	lru *list.List
+}
+
+// client is the bucket of a client in the lru
+type client struct {
+	name   string
+	bucket Bucket
+}
+
+// Allow takes a request of the client. When the client is over its rate false is returned
+// with the time until its next request is allowed.
+func (l *Limiter) Allow(name string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = map[string]*list.Element{}
+		l.lru = list.New()
+	}
+
+	if e, ok := l.buckets[name]; ok {
+		l.lru.MoveToFront(e)
+		return e.Value.(*client).bucket.Take(now)
+	}
+
+	max := l.MaxClients
+	if max <= 0 {
+		max = DefaultMaxClients
+	}
+	// forget the least recently seen clients, which are the most likely to have full buckets
+	for l.lru.Len() >= max {
+		e := l.lru.Back()
+		l.lru.Remove(e)
+		delete(l.buckets, e.Value.(*client).name)
+	}
+
+	c := &client{name: name, bucket: Bucket{Rate: l.Rate, Burst: BurstFor(l.Rate, l.Burst)}}
+	l.buckets[name] = l.lru.PushFront(c)
+	return c.bucket.Take(now)
+}
+
+// BurstFor returns the burst, or the rate rounded up (at least 1) when the burst is 0
+func BurstFor(rate float64, burst int) int {
+	if burst > 0 {
+		return burst
+	}
+	if b := int(math.Ceil(rate)); b > 0 {
+		return b
+	}
+	return 1
+}
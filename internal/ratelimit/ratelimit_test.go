@@ -0,0 +1,57 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/ratelimit"
+)
+
+func TestLimiter(t *testing.T) {
+	l := ratelimit.Limiter{Rate: 1, Burst: 2, MaxClients: 2}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a", now); !ok {
+			t.Fatalf("request %v, expected the burst to be allowed", i)
+		}
+	}
+	ok, retry := l.Allow("a", now)
+	if ok || retry != time.Second {
+		t.Errorf("expected the request over the burst to be limited for 1s, got %v, %v", ok, retry)
+	}
+	if ok, _ := l.Allow("b", now); !ok {
+		t.Errorf("expected the clients to be limited separately")
+	}
+	if ok, _ := l.Allow("a", now.Add(time.Second)); !ok {
+		t.Errorf("expected the bucket to refill at the rate")
+	}
+
+	// the least recently seen client (b) is forgotten, the limited client is kept
+	if ok, _ := l.Allow("a", now.Add(time.Second)); ok {
+		t.Errorf("expected the client to be limited")
+	}
+	if ok, _ := l.Allow("c", now.Add(time.Second)); !ok {
+		t.Errorf("expected a new client to be allowed")
+	}
+	if ok, _ := l.Allow("a", now.Add(time.Second)); ok {
+		t.Errorf("expected the limited client to be kept")
+	}
+}
+
+func TestBurstFor(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		burst    int
+		expected int
+	}{
+		{rate: 10, burst: 20, expected: 20},
+		{rate: 2.5, expected: 3},
+		{rate: 0.1, expected: 1},
+	}
+	for _, tc := range tests {
+		if got := ratelimit.BurstFor(tc.rate, tc.burst); got != tc.expected {
+			t.Errorf("BurstFor(%v, %v), expected %v got %v", tc.rate, tc.burst, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRangeUnsupported is returned when a server ignores the Range header of a request
+var ErrRangeUnsupported = errors.New("remote: server does not support range requests")
+
+// ErrStatus is returned when a server responds with an unexpected status
+type ErrStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("remote: request (%v) responded with status %v", e.URL, e.Status)
+}
+
+type ErrUnsupportedScheme struct {
+	URL string
+}
+
+func (e ErrUnsupportedScheme) Error() string {
+	return fmt.Sprintf("remote: unsupported url (%v), expected an http://, https://, s3://, gs:// or file:// url", e.URL)
+}
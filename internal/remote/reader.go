@@ -0,0 +1,129 @@
+package remote
+
+import (
+	"context"
+	"io"
+
+	"github.com/go-spatial/tegola/internal/ttlcache"
+)
+
+// Reader reads a source in fixed size blocks, keeping the most recently used blocks so repeated
+// reads (i.e. the directories of an archive or the IFDs of a GeoTIFF) don't reach the source.
+// It's safe for concurrent use.
+type Reader struct {
+	src       Source
+	blockSize int64
+	// blocks are keyed by their index. nil when the blocks aren't cached
+	blocks *ttlcache.Cache
+}
+
+// NewReader returns a reader of the source which caches up to maxBlocks blocks. Nothing is
+// cached when maxBlocks is 0.
+func NewReader(src Source, blockSize int64, maxBlocks int) *Reader {
+	r := Reader{
+		src:       src,
+		blockSize: blockSize,
+	}
+	if maxBlocks > 0 {
+		r.blocks = ttlcache.New(maxBlocks, 0)
+	}
+	return &r
+}
+
+func (r *Reader) block(index int64) ([]byte, bool) {
+	b, ok := r.blocks.Get(index)
+	if !ok {
+		return nil, false
+	}
+	return b.([]byte), true
+}
+
+// ReadRange returns length bytes starting at off. Missing runs of consecutive blocks are read
+// from the source with a single range. Fewer bytes are returned at the end of the source.
+func (r *Reader) ReadRange(ctx context.Context, off, length int64) ([]byte, error) {
+	if length <= 0 {
+		return nil, nil
+	}
+
+	first, last := off/r.blockSize, (off+length-1)/r.blockSize
+	blocks := make([][]byte, last-first+1)
+
+	for i := first; i <= last; {
+		if data, ok := r.block(i); ok {
+			blocks[i-first] = data
+			i++
+			continue
+		}
+
+		// find the run of missing blocks
+		end := i + 1
+		for ; end <= last; end++ {
+			if _, ok := r.block(end); ok {
+				break
+			}
+		}
+
+		data, err := r.src.ReadRange(ctx, i*r.blockSize, (end-i)*r.blockSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := i; j < end; j++ {
+			start := (j - i) * r.blockSize
+			if start > int64(len(data)) {
+				start = int64(len(data))
+			}
+			stop := start + r.blockSize
+			if stop > int64(len(data)) {
+				stop = int64(len(data))
+			}
+			// copy the block so the cached block doesn't hold on to the whole range
+			b := append([]byte(nil), data[start:stop]...)
+			r.blocks.Set(j, b)
+			blocks[j-first] = b
+		}
+		i = end
+	}
+
+	buf := make([]byte, 0, length)
+	for i, b := range blocks {
+		if i == 0 {
+			start := off - first*r.blockSize
+			if start >= int64(len(b)) {
+				break
+			}
+			b = b[start:]
+		}
+		buf = append(buf, b...)
+		// a short block is the end of the source
+		if int64(len(blocks[i])) < r.blockSize {
+			break
+		}
+	}
+	if int64(len(buf)) > length {
+		buf = buf[:length]
+	}
+
+	return buf, nil
+}
+
+// ReadAt implements io.ReaderAt. io.EOF is returned when the range ends past the end of the source.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	b, err := r.ReadRange(context.Background(), off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, b)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close implements io.Closer, closing the source when it needs to be closed
+func (r *Reader) Close() error {
+	if c, ok := r.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
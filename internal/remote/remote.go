@@ -0,0 +1,181 @@
+// Package remote reads byte ranges of files on HTTP(S), S3 (or an S3 compatible store), Google
+// Cloud Storage or local disk. Files are read in blocks and the most recently read blocks are
+// kept in memory, so archives and rasters can be served without copying them to the server's disk.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/awsutil"
+)
+
+// DefaultGCSEndpoint is the endpoint of gs:// urls without an endpoint config param
+const DefaultGCSEndpoint = "https://storage.googleapis.com"
+
+// Source reads byte ranges of a file. Reads past the end of the file return the available bytes.
+type Source interface {
+	ReadRange(ctx context.Context, off, length int64) ([]byte, error)
+}
+
+// NewSource returns the source for the url's scheme: http(s)://, s3://bucket/key, gs://bucket/key,
+// file:// or a local path. headers are added to the requests of http(s) and gs urls.
+//
+// The config holds the connection params of s3 urls (see awsutil.ConfigFromDict). The endpoint
+// param overrides the endpoint of gs urls, which are read with the Cloud Storage XML API.
+func NewSource(rawURL string, config dict.Dicter, headers map[string]string, timeout time.Duration) (Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return &HTTPSource{
+			URL:     rawURL,
+			Headers: headers,
+			Client:  &http.Client{Timeout: timeout},
+		}, nil
+	case "s3":
+		// connection options (region, endpoint, credentials, path style addressing and TLS)
+		awsConfig, err := awsutil.ConfigFromDict(config)
+		if err != nil {
+			return nil, err
+		}
+		sess, err := awsConfig.Session()
+		if err != nil {
+			return nil, err
+		}
+		return &S3Source{
+			Bucket: u.Host,
+			Key:    strings.TrimPrefix(u.Path, "/"),
+			Client: s3.New(sess),
+		}, nil
+	case "gs":
+		endpoint := DefaultGCSEndpoint
+		if endpoint, err = config.String(awsutil.ConfigKeyEndpoint, &endpoint); err != nil {
+			return nil, err
+		}
+		if endpoint == "" {
+			endpoint = DefaultGCSEndpoint
+		}
+		// private objects are read with an OAuth token in the Authorization header
+		return &HTTPSource{
+			URL:     strings.TrimSuffix(endpoint, "/") + "/" + u.Host + u.EscapedPath(),
+			Headers: headers,
+			Client:  &http.Client{Timeout: timeout},
+		}, nil
+	case "file", "":
+		path := u.Path
+		if u.Scheme == "" {
+			path = rawURL
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return &FileSource{File: f}, nil
+	default:
+		return nil, ErrUnsupportedScheme{URL: rawURL}
+	}
+}
+
+// rangeHeader returns the value of the Range header for the inclusive byte range
+func rangeHeader(off, length int64) string {
+	return fmt.Sprintf("bytes=%d-%d", off, off+length-1)
+}
+
+// HTTPSource reads a file with HTTP range requests. Servers must support range requests.
+type HTTPSource struct {
+	URL string
+	// Headers are added to every request (i.e. API keys)
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func (s *HTTPSource) ReadRange(ctx context.Context, off, length int64) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Range", rangeHeader(off, length))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the range starts past the end of the file
+		return nil, nil
+	case http.StatusOK:
+		// reading the whole file for every range defeats the purpose of range reads
+		return nil, ErrRangeUnsupported
+	default:
+		return nil, ErrStatus{URL: s.URL, Status: resp.StatusCode}
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, length))
+}
+
+// S3Source reads an object with ranged GetObject requests
+type S3Source struct {
+	Bucket string
+	Key    string
+	Client *s3.S3
+}
+
+func (s *S3Source) ReadRange(ctx context.Context, off, length int64) ([]byte, error) {
+	resp, err := s.Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+		Range:  aws.String(rangeHeader(off, length)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == http.StatusRequestedRangeNotSatisfiable {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, length))
+}
+
+// FileSource reads a local file
+type FileSource struct {
+	File *os.File
+}
+
+func (s *FileSource) ReadRange(_ context.Context, off, length int64) ([]byte, error) {
+	buf := make([]byte, length)
+	n, err := s.File.ReadAt(buf, off)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// Close closes the file
+func (s *FileSource) Close() error {
+	return s.File.Close()
+}
@@ -0,0 +1,176 @@
+package remote_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/remote"
+)
+
+type countingSource struct {
+	data  []byte
+	reads int
+}
+
+func (s *countingSource) ReadRange(_ context.Context, off, length int64) ([]byte, error) {
+	s.reads++
+	if off >= int64(len(s.data)) {
+		return nil, nil
+	}
+	end := off + length
+	if end > int64(len(s.data)) {
+		end = int64(len(s.data))
+	}
+	return s.data[off:end], nil
+}
+
+func TestReader(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	src := &countingSource{data: data}
+	r := remote.NewReader(src, 16, 4)
+	ctx := context.Background()
+
+	type tcase struct {
+		off, length int64
+		reads       int
+	}
+
+	// reads accumulate across the cases
+	for i, tc := range []tcase{
+		{off: 10, length: 20, reads: 1},
+		{off: 16, length: 10, reads: 1},
+		{off: 0, length: 48, reads: 2},
+		{off: 90, length: 20, reads: 3},
+	} {
+		got, err := r.ReadRange(ctx, tc.off, tc.length)
+		if err != nil {
+			t.Fatalf("case %v, unexpected err: %v", i, err)
+		}
+
+		end := tc.off + tc.length
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		if !bytes.Equal(got, data[tc.off:end]) {
+			t.Errorf("case %v, expected %v got %v", i, data[tc.off:end], got)
+		}
+		if src.reads != tc.reads {
+			t.Errorf("case %v, expected %v source reads got %v", i, tc.reads, src.reads)
+		}
+	}
+
+	// reads past the end
+	p := make([]byte, 20)
+	if n, err := r.ReadAt(p, 90); n != 10 || err != io.EOF {
+		t.Errorf("read past the end, expected 10 bytes and EOF got %v %v", n, err)
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	data := make([]byte, 100*1024)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.ServeContent(w, r, "test.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	src := &remote.HTTPSource{URL: srv.URL, Headers: map[string]string{"X-Key": "secret"}, Client: srv.Client()}
+	r := remote.NewReader(src, 64*1024, 32)
+
+	// a read across the first two blocks
+	p := make([]byte, 1024)
+	if n, err := r.ReadAt(p, 64*1024-512); err != nil || n != len(p) {
+		t.Fatalf("read, expected %v bytes got %v: %v", len(p), n, err)
+	}
+	if !bytes.Equal(p, data[64*1024-512:64*1024+512]) {
+		t.Errorf("read, unexpected bytes")
+	}
+	// both blocks are kept
+	if _, err := r.ReadAt(p[:16], 10); err != nil || requests != 1 {
+		t.Errorf("cached read, expected 1 request got %v: %v", requests, err)
+	}
+
+	src = &remote.HTTPSource{URL: srv.URL, Client: srv.Client()}
+	expected := remote.ErrStatus{URL: srv.URL, Status: http.StatusForbidden}
+	if _, err := src.ReadRange(context.Background(), 0, 16); err != expected {
+		t.Errorf("forbidden, expected %v got %v", expected, err)
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		w.Header().Set("Content-Range", "bytes 0-3/4")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("test"))
+	}))
+	defer srv.Close()
+
+	type tcase struct {
+		url    string
+		config dict.Dict
+		path   string
+		err    error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			src, err := remote.NewSource(tc.url, tc.config, nil, time.Second)
+			if err != tc.err {
+				t.Fatalf("expected error %v got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			b, err := src.ReadRange(context.Background(), 0, 4)
+			if err != nil || string(b) != "test" {
+				t.Fatalf("read, expected test got %q: %v", b, err)
+			}
+			if path != tc.path {
+				t.Errorf("path, expected %v got %v", tc.path, path)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"http": {
+			url:    srv.URL + "/tiles/test.pmtiles",
+			config: dict.Dict{},
+			path:   "/tiles/test.pmtiles",
+		},
+		"gs": {
+			url:    "gs://bucket/tiles/a b.pmtiles",
+			config: dict.Dict{"endpoint": srv.URL},
+			path:   "/bucket/tiles/a%20b.pmtiles",
+		},
+		"unsupported": {
+			url:    "ftp://example.com/test.pmtiles",
+			config: dict.Dict{},
+			err:    remote.ErrUnsupportedScheme{URL: "ftp://example.com/test.pmtiles"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
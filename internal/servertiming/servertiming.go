@@ -0,0 +1,91 @@
+// Package servertiming collects the durations of the steps of a request, i.e. the query of each
+// layer, the encoding of the tile and the cache read, and formats them as a Server-Timing header
+// (https://www.w3.org/TR/server-timing/) so they show in the network panel of browser devtools.
+// Durations are only collected for contexts from WithTimings.
+package servertiming
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header is the name of the response header the timings are written to
+const Header = "Server-Timing"
+
+// Metric is the duration of a step of a request
+type Metric struct {
+	// Name is the step, i.e. layer, encode or cache
+	Name string
+	// Desc distinguishes metrics of the same step, i.e. the name of the layer
+	Desc string
+	Dur  time.Duration
+}
+
+// String formats the metric as an entry of the Server-Timing header, the duration in milliseconds
+func (m Metric) String() string {
+	var sb strings.Builder
+	sb.WriteString(m.Name)
+	if m.Desc != "" {
+		sb.WriteString(";desc=")
+		sb.WriteString(strconv.Quote(m.Desc))
+	}
+	sb.WriteString(";dur=")
+	sb.WriteString(strconv.FormatFloat(float64(m.Dur)/float64(time.Millisecond), 'f', 1, 64))
+	return sb.String()
+}
+
+type timingsKey struct{}
+
+// timings are the metrics recorded with a context, which may be recorded concurrently
+type timings struct {
+	sync.Mutex
+	metrics []Metric
+}
+
+// WithTimings returns a context the durations of the steps of a request are recorded with.
+// They are read back with Metrics.
+func WithTimings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, timingsKey{}, &timings{})
+}
+
+// Record records the duration of a step of the request of the context. Nothing is recorded
+// for contexts which aren't from WithTimings.
+func Record(ctx context.Context, name, desc string, d time.Duration) {
+	t, ok := ctx.Value(timingsKey{}).(*timings)
+	if !ok {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.metrics = append(t.metrics, Metric{Name: name, Desc: desc, Dur: d})
+}
+
+// Since records the duration of a step started at start, for use with defer
+func Since(ctx context.Context, name, desc string, start time.Time) {
+	Record(ctx, name, desc, time.Since(start))
+}
+
+// Metrics returns the metrics recorded with the context, in the order they were recorded
+func Metrics(ctx context.Context) []Metric {
+	t, ok := ctx.Value(timingsKey{}).(*timings)
+	if !ok {
+		return nil
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	return append([]Metric(nil), t.metrics...)
+}
+
+// Format formats the metrics as the value of a Server-Timing header
+func Format(metrics []Metric) string {
+	entries := make([]string, len(metrics))
+	for i, m := range metrics {
+		entries[i] = m.String()
+	}
+	return strings.Join(entries, ", ")
+}
@@ -0,0 +1,27 @@
+package servertiming_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/servertiming"
+)
+
+func TestRecord(t *testing.T) {
+	// nothing is recorded without timings
+	servertiming.Record(context.Background(), "layer", "roads", time.Millisecond)
+	if got := servertiming.Metrics(context.Background()); got != nil {
+		t.Errorf("metrics without timings, expected nil got %v", got)
+	}
+
+	ctx := servertiming.WithTimings(context.Background())
+	servertiming.Record(ctx, "cache", "miss", 400*time.Microsecond)
+	servertiming.Record(ctx, "layer", `roads "major"`, 12345*time.Microsecond)
+	servertiming.Record(ctx, "encode", "", 4100*time.Microsecond)
+
+	expected := `cache;desc="miss";dur=0.4, layer;desc="roads \"major\"";dur=12.3, encode;dur=4.1`
+	if got := servertiming.Format(servertiming.Metrics(ctx)); got != expected {
+		t.Errorf("header, expected %v got %v", expected, got)
+	}
+}
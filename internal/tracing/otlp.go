@@ -0,0 +1,179 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OTLPExporter exports spans to an OpenTelemetry collector with the OTLP/HTTP protocol, JSON
+// encoded
+type OTLPExporter struct {
+	// Endpoint is the base url of the collector, i.e. http://otel-collector:4318. The spans
+	// are posted to its /v1/traces path.
+	Endpoint string
+	// Headers are added to the export requests, i.e. for the authentication of a vendor's
+	// collector
+	Headers map[string]string
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              Kind            `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+// attribute converts an attribute value to its OTLP value. Unsupported types are formatted
+// as strings.
+func attribute(key string, v interface{}) otlpAttribute {
+	var val otlpValue
+	switch t := v.(type) {
+	case string:
+		val.StringValue = &t
+	case bool:
+		val.BoolValue = &t
+	case int:
+		s := strconv.FormatInt(int64(t), 10)
+		val.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(t, 10)
+		val.IntValue = &s
+	case uint:
+		s := strconv.FormatUint(uint64(t), 10)
+		val.IntValue = &s
+	case uint64:
+		s := strconv.FormatUint(t, 10)
+		val.IntValue = &s
+	case float64:
+		val.DoubleValue = &t
+	default:
+		s := fmt.Sprint(t)
+		val.StringValue = &s
+	}
+	return otlpAttribute{Key: key, Value: val}
+}
+
+// encode returns the OTLP JSON export request of the spans
+func encode(serviceName string, spans []*Span) otlpRequest {
+	var scope otlpScopeSpans
+	scope.Scope.Name = "github.com/go-spatial/tegola"
+
+	for _, s := range spans {
+		s.mu.Lock()
+		out := otlpSpan{
+			TraceID:           hex.EncodeToString(s.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.SpanID[:]),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+		if s.ParentID != [8]byte{} {
+			out.ParentSpanID = hex.EncodeToString(s.ParentID[:])
+		}
+		keys := make([]string, 0, len(s.Attributes))
+		for k := range s.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out.Attributes = append(out.Attributes, attribute(k, s.Attributes[k]))
+		}
+		if s.Err != nil {
+			out.Status = otlpStatus{Code: 2, Message: s.Err.Error()}
+		}
+		s.mu.Unlock()
+
+		scope.Spans = append(scope.Spans, out)
+	}
+
+	var rs otlpResourceSpans
+	rs.Resource.Attributes = []otlpAttribute{attribute("service.name", serviceName)}
+	rs.ScopeSpans = []otlpScopeSpans{scope}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+// Export posts the spans to the collector
+func (e OTLPExporter) Export(ctx context.Context, serviceName string, spans []*Span) error {
+	body, err := json.Marshal(encode(serviceName, spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(e.Endpoint, "/")+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("tracing: collector responded with %v", resp.Status)
+	}
+	return nil
+}
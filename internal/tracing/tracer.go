@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const (
+	// DefaultBatchSize is the number of spans exported at once
+	DefaultBatchSize = 512
+	// DefaultInterval is the time between the exports of the queued spans
+	DefaultInterval = 5 * time.Second
+	// DefaultQueueSize is the number of finished spans held for export. Spans finished while
+	// the queue is full are dropped.
+	DefaultQueueSize = 4096
+)
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	Export(ctx context.Context, serviceName string, spans []*Span) error
+}
+
+// Tracer samples traces and exports their spans in batches
+type Tracer struct {
+	// ServiceName is the service.name resource attribute of the spans
+	ServiceName string
+	// SampleRate is the fraction of new traces which are recorded, between 0 and 1. Traces
+	// continued from a traceparent header follow the header's sampled flag.
+	SampleRate float64
+	// Exporter sends the spans
+	Exporter Exporter
+
+	spans chan *Span
+}
+
+// NewTracer returns a tracer exporting its spans with the exporter. Run must be called for
+// the spans to be exported.
+func NewTracer(serviceName string, sampleRate float64, exporter Exporter) *Tracer {
+	return &Tracer{
+		ServiceName: serviceName,
+		SampleRate:  sampleRate,
+		Exporter:    exporter,
+		spans:       make(chan *Span, DefaultQueueSize),
+	}
+}
+
+// queue queues the finished span for export, dropping it when the queue is full
+func (t *Tracer) queue(s *Span) {
+	select {
+	case t.spans <- s:
+	default:
+		log.Debugf("tracing: export queue full, dropping span (%v)", s.Name)
+	}
+}
+
+// Run exports the finished spans until the context is done, then exports the spans still
+// queued
+func (t *Tracer) Run(ctx context.Context) {
+	ticker := time.NewTicker(DefaultInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, DefaultBatchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		exportCtx, cancel := context.WithTimeout(context.Background(), DefaultInterval)
+		defer cancel()
+		if err := t.Exporter.Export(exportCtx, t.ServiceName, batch); err != nil {
+			log.Warnf("tracing: error exporting %v spans: %v", len(batch), err)
+		}
+		batch = make([]*Span, 0, DefaultBatchSize)
+	}
+
+	for {
+		select {
+		case s := <-t.spans:
+			if batch = append(batch, s); len(batch) >= DefaultBatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case <-ctx.Done():
+			for {
+				select {
+				case s := <-t.spans:
+					batch = append(batch, s)
+				default:
+					export()
+					return
+				}
+			}
+		}
+	}
+}
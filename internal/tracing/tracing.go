@@ -0,0 +1,204 @@
+// Package tracing records the spans of tile requests and exports them to an OpenTelemetry
+// collector, so slow tiles can be traced to the layer or query responsible. Spans are only
+// recorded once a Tracer is set with SetTracer; the methods of a nil *Span are no-ops.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	mathrand "math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentHeader is the W3C Trace Context header carrying the trace of a request
+const TraceparentHeader = "traceparent"
+
+// Kind is the OpenTelemetry kind of a span
+type Kind int
+
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// Span is an operation of a trace
+type Span struct {
+	TraceID  [16]byte
+	SpanID   [8]byte
+	ParentID [8]byte
+	Name     string
+	Kind     Kind
+	Start    time.Time
+	End      time.Time
+	// Attributes are string, bool, int, int64, uint, float64 values
+	Attributes map[string]interface{}
+	// Err is the error the operation failed with
+	Err error
+
+	mu      sync.Mutex
+	sampled bool
+	tracer  *Tracer
+}
+
+var (
+	tracerLock sync.RWMutex
+	tracer     *Tracer
+)
+
+// SetTracer sets the tracer spans are recorded by. Tracing is disabled when nil.
+func SetTracer(t *Tracer) {
+	tracerLock.Lock()
+	tracer = t
+	tracerLock.Unlock()
+}
+
+// Enabled reports if spans are recorded
+func Enabled() bool {
+	tracerLock.RLock()
+	defer tracerLock.RUnlock()
+	return tracer != nil
+}
+
+type spanKey struct{}
+
+// FromContext returns the span of the context, nil when there's none
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Start starts a span which is a child of the span of the context, or the root of a new trace.
+// A nil span is returned when tracing is disabled.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return start(ctx, name, KindInternal, FromContext(ctx))
+}
+
+// StartRequest starts the server span of a request, continuing the trace of the request's
+// traceparent header when it has one
+func StartRequest(r *http.Request, name string) (context.Context, *Span) {
+	parent := FromContext(r.Context())
+	if parent == nil {
+		parent = parseTraceparent(r.Header.Get(TraceparentHeader))
+	}
+	return start(r.Context(), name, KindServer, parent)
+}
+
+func start(ctx context.Context, name string, kind Kind, parent *Span) (context.Context, *Span) {
+	tracerLock.RLock()
+	t := tracer
+	tracerLock.RUnlock()
+	if t == nil {
+		return ctx, nil
+	}
+
+	s := &Span{
+		Name:   name,
+		Kind:   kind,
+		Start:  time.Now(),
+		tracer: t,
+	}
+	if parent != nil {
+		s.TraceID, s.ParentID, s.sampled = parent.TraceID, parent.SpanID, parent.sampled
+	} else {
+		rand.Read(s.TraceID[:])
+		s.sampled = t.sample()
+	}
+	rand.Read(s.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// SetAttribute sets an attribute of the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil || !s.sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = map[string]interface{}{}
+	}
+	s.Attributes[key] = value
+}
+
+// SetError records the error the operation failed with. nil errors are ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil || !s.sampled {
+		return
+	}
+	s.mu.Lock()
+	s.Err = err
+	s.mu.Unlock()
+}
+
+// Finish ends the span and queues it for export, when its trace is sampled
+func (s *Span) Finish() {
+	if s == nil || !s.sampled {
+		return
+	}
+	s.mu.Lock()
+	s.End = time.Now()
+	s.mu.Unlock()
+	s.tracer.queue(s)
+}
+
+// TraceIDString returns the hex encoded id of the span's trace, "" for nil spans
+func (s *Span) TraceIDString() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.TraceID[:])
+}
+
+// Traceparent returns the W3C Trace Context header value of the span, so the trace can be
+// continued by the services the span calls
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(s.TraceID[:]) + "-" + hex.EncodeToString(s.SpanID[:]) + "-" + flags
+}
+
+// parseTraceparent returns the remote parent span of the traceparent header value, nil when
+// the value is invalid
+func parseTraceparent(v string) *Span {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil
+	}
+
+	var s Span
+	if _, err := hex.Decode(s.TraceID[:], []byte(parts[1])); err != nil || s.TraceID == [16]byte{} {
+		return nil
+	}
+	if _, err := hex.Decode(s.SpanID[:], []byte(parts[2])); err != nil || s.SpanID == [8]byte{} {
+		return nil
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return nil
+	}
+	s.sampled = flags[0]&1 == 1
+	return &s
+}
+
+// sample decides if a new trace is sampled
+func (t *Tracer) sample() bool {
+	switch {
+	case t.SampleRate >= 1:
+		return true
+	case t.SampleRate <= 0:
+		return false
+	default:
+		return mathrand.Float64() < t.SampleRate
+	}
+}
@@ -0,0 +1,115 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-spatial/tegola/internal/tracing"
+)
+
+// recorder is an exporter recording the exported spans
+type recorder struct {
+	sync.Mutex
+	spans []*tracing.Span
+}
+
+func (r *recorder) Export(ctx context.Context, serviceName string, spans []*tracing.Span) error {
+	r.Lock()
+	defer r.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func TestSpans(t *testing.T) {
+	var rec recorder
+	tracer := tracing.NewTracer("tegola", 1, &rec)
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tracer.Run(ctx)
+		close(done)
+	}()
+
+	r := httptest.NewRequest("GET", "/maps/osm/1/0/0.pbf", nil)
+	r.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	reqCtx, root := tracing.StartRequest(r, "GET /maps/:map_name/:z/:x/:y")
+	_, child := tracing.Start(reqCtx, "provider.tile_features")
+	child.SetAttribute("tegola.layer", "roads")
+	child.SetError(errors.New("timeout"))
+	child.Finish()
+	root.Finish()
+
+	// the spans still queued are exported when the tracer stops
+	cancel()
+	<-done
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", len(rec.spans))
+	}
+	if got := root.TraceIDString(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id, expected the traceparent's trace id got %v", got)
+	}
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID {
+		t.Errorf("expected the child to be a span of the root's trace")
+	}
+	if child.Attributes["tegola.layer"] != "roads" || child.Err == nil {
+		t.Errorf("expected the child's attributes and error to be recorded, got %v, %v", child.Attributes, child.Err)
+	}
+}
+
+func TestUnsampled(t *testing.T) {
+	var rec recorder
+	tracing.SetTracer(tracing.NewTracer("tegola", 1, &rec))
+	defer tracing.SetTracer(nil)
+
+	// the sampled flag of the traceparent is followed
+	r := httptest.NewRequest("GET", "/maps/osm/1/0/0.pbf", nil)
+	r.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := tracing.StartRequest(r, "GET /maps/:map_name/:z/:x/:y")
+	if got := span.Traceparent(); got[len(got)-2:] != "00" {
+		t.Errorf("traceparent, expected the trace to not be sampled got %v", got)
+	}
+
+	tracing.SetTracer(nil)
+	if _, span := tracing.Start(context.Background(), "atlas.encode"); span != nil {
+		t.Errorf("expected no span when tracing is disabled")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	tracing.SetTracer(tracing.NewTracer("tegola", 1, nil))
+	_, span := tracing.Start(context.Background(), "cache.get")
+	span.SetAttribute("tegola.cache_hit", true)
+	tracing.SetTracer(nil)
+
+	exporter := tracing.OTLPExporter{Endpoint: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}}
+	if err := exporter.Export(context.Background(), "tegola", []*tracing.Span{span}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	spans := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	exported := spans[0].(map[string]interface{})
+	if exported["name"] != "cache.get" || exported["traceId"] != span.TraceIDString() {
+		t.Errorf("unexpected span %v", exported)
+	}
+}
@@ -0,0 +1,99 @@
+// Package ttlcache provides a size and time bound LRU cache
+package ttlcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key     interface{}
+	expires time.Time
+	val     interface{}
+}
+
+// Cache keeps the most recently used values until they expire. A nil Cache never hits.
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	ll         *list.List
+	entries    map[interface{}]*list.Element
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// New returns a cache of up to maxEntries values kept for ttl. 0 means no max number
+// of values and no expiration respectively.
+func New(maxEntries int, ttl time.Duration) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		ll:         list.New(),
+		entries:    map[interface{}]*list.Element{},
+		now:        time.Now,
+	}
+}
+
+// Get returns the value of the key, if it's cached and hasn't expired. The key must be comparable.
+func (c *Cache) Get(key interface{}) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if c.ttl > 0 && c.now().After(e.expires) {
+		c.ll.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return e.val, true
+}
+
+// Set caches the value of the key, evicting the least recently used values over the max
+func (c *Cache) Set(key, val interface{}) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := &entry{key: key, expires: c.now().Add(c.ttl), val: val}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.ll.PushFront(e)
+
+	for c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// Len returns the number of cached values, including the expired ones not evicted yet
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
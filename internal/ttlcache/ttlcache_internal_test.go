@@ -0,0 +1,91 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type tcase struct {
+		maxEntries int
+		ttl        time.Duration
+		// the keys set, in order, one second apart
+		set []string
+		// the time of the gets after the last set
+		after time.Duration
+		hits  map[string]bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			c := New(tc.maxEntries, tc.ttl)
+			clock := now
+			c.now = func() time.Time { return clock }
+
+			for i, key := range tc.set {
+				c.Set(key, i)
+				clock = clock.Add(time.Second)
+			}
+			clock = clock.Add(tc.after)
+
+			for key, hit := range tc.hits {
+				if _, ok := c.Get(key); ok != hit {
+					t.Errorf("key %v, expected hit %v got %v", key, hit, ok)
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"unbounded": {
+			set:   []string{"a", "b", "c"},
+			after: time.Hour,
+			hits:  map[string]bool{"a": true, "b": true, "c": true, "d": false},
+		},
+		"max entries": {
+			maxEntries: 2,
+			set:        []string{"a", "b", "c"},
+			hits:       map[string]bool{"a": false, "b": true, "c": true},
+		},
+		"reset keeps one entry": {
+			maxEntries: 2,
+			set:        []string{"a", "a", "b"},
+			hits:       map[string]bool{"a": true, "b": true},
+		},
+		"expired": {
+			ttl:  2500 * time.Millisecond,
+			set:  []string{"a", "b", "c"},
+			hits: map[string]bool{"a": false, "b": true, "c": true},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestCacheLRU(t *testing.T) {
+	c := New(2, 0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// a is used more recently than b
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("a, expected 1 got %v", v)
+	}
+	c.Set("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("b, expected to be evicted")
+	}
+	if c.Len() != 2 {
+		t.Errorf("len, expected 2 got %v", c.Len())
+	}
+
+	// a nil cache caches nothing
+	var nc *Cache
+	nc.Set("a", 1)
+	if _, ok := nc.Get("a"); ok {
+		t.Errorf("nil cache, expected no value")
+	}
+}
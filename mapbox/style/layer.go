@@ -1,5 +1,7 @@
 package style
 
+import "encoding/json"
+
 const (
 	LayerTypeFill          = "fill"
 	LayerTypeLine          = "line"
@@ -26,6 +28,27 @@ type LayerPaint struct {
 	FillOpacity      uint8  `json:"fill-opacity,omitempty"`
 	CircleRadius     uint8  `json:"circle-radius,omitempty"`
 	CircleColor      string `json:"circle-color,omitempty"`
+	// Overrides are paint properties set over the properties above, i.e. from the config
+	Overrides map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON encodes the paint properties with the Overrides set over them
+func (lp LayerPaint) MarshalJSON() ([]byte, error) {
+	// the alias type doesn't have the MarshalJSON method
+	type layerPaint LayerPaint
+	b, err := json.Marshal(layerPaint(lp))
+	if err != nil || len(lp.Overrides) == 0 {
+		return b, err
+	}
+
+	paint := map[string]interface{}{}
+	if err := json.Unmarshal(b, &paint); err != nil {
+		return nil, err
+	}
+	for k, v := range lp.Overrides {
+		paint[k] = v
+	}
+	return json.Marshal(paint)
 }
 
 const (
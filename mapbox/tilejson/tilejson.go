@@ -14,8 +14,11 @@ const (
 )
 
 const (
-	SchemeXYZ  = "xyz"
-	SchemeTMLS = "tms"
+	SchemeXYZ = "xyz"
+	SchemeTMS = "tms"
+
+	// Deprecated: use SchemeTMS
+	SchemeTMLS = SchemeTMS
 )
 
 type TileJSON struct {
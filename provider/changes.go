@@ -0,0 +1,18 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-spatial/geom"
+)
+
+// Changes is implemented by providers which can report where the data of a layer changed.
+// It's used to re-seed the cached tiles of the changed data.
+type Changes interface {
+	// LayerChanges returns the extents, in WGS84, of the features of the provider layer added,
+	// updated or removed since the time. An extent covering both the old and new geometry of a
+	// moved feature, or a single extent covering every change, are both valid. An error which
+	// is ErrUnsupported is returned for layers which are not configured to report their changes.
+	LayerChanges(ctx context.Context, layerID string, since time.Time) ([]geom.Extent, error)
+}
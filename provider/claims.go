@@ -0,0 +1,21 @@
+package provider
+
+import (
+	"context"
+)
+
+type claimsKey struct{}
+
+// WithClaims returns a context carrying the claims of the authenticated client of a tile
+// request (i.e. the claims of a JWT), so providers can limit the features of a tile to the
+// client, i.e. with row level security.
+func WithClaims(ctx context.Context, claims map[string]interface{}) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// Claims returns the claims of the context from WithClaims. false is returned when the
+// request was not authenticated.
+func Claims(ctx context.Context) (map[string]interface{}, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(map[string]interface{})
+	return claims, ok
+}
@@ -0,0 +1,260 @@
+// Package composite provides a meta provider whose layers union the layers of other
+// providers, so i.e. a national PostGIS layer and a regional GeoPackage layer are served
+// as one layer. The source providers must be configured before the composite provider.
+package composite
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "composite"
+
+const (
+	ConfigKeyLayers = "layers"
+
+	ConfigKeyLayerName    = "name"
+	ConfigKeySources      = "sources"
+	ConfigKeySourceTag    = "source_tag"
+	ConfigKeyGeometryType = "geometry_type"
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, nil)
+}
+
+// Provider serves layers which union the layers of other providers
+type Provider struct {
+	// map of layer name and corresponding sources
+	layers map[string]Layer
+}
+
+// NewTileProvider instantiates and returns a new composite provider or an error.
+//
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		sources ([]string): [Required] the provider layers unioned into the layer, in the provider.layer syntax
+//		source_tag (string): [Optional] the tag the source of each feature is written to. defaults to no tag
+//		geometry_type (string): [Optional] the geometry type of the layer, reported in the capabilities. defaults to the sources' geometry type when they share one
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	p := Provider{
+		layers: map[string]Layer{},
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// AddLayer adds a layer unioning its sources to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	sources, err := layerConf.StringSlice(ConfigKeySources)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeySources, err)
+	}
+	if len(sources) == 0 {
+		return ErrMissingSources{LayerName: name}
+	}
+
+	empty := ""
+	sourceTag, err := layerConf.String(ConfigKeySourceTag, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeySourceTag, err)
+	}
+	gtype, err := layerConf.String(ConfigKeyGeometryType, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyGeometryType, err)
+	}
+
+	l := Layer{
+		name:      name,
+		sourceTag: sourceTag,
+	}
+
+	var infos []provider.LayerInfo
+	for _, s := range sources {
+		// split the source (syntax is provider.layer)
+		parts := strings.Split(s, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return ErrInvalidSource{LayerName: name, Source: s}
+		}
+
+		prov, ok := provider.Instance(parts[0])
+		if !ok {
+			return ErrSourceProviderNotFound{LayerName: name, Provider: parts[0]}
+		}
+		if prov.Std == nil {
+			return ErrMVTSource{LayerName: name, Provider: parts[0]}
+		}
+
+		info, ok := prov.Std.Layer(parts[1])
+		if !ok {
+			return ErrSourceLayerNotFound{LayerName: name, Source: s}
+		}
+		infos = append(infos, info)
+
+		l.sources = append(l.sources, source{
+			name:     s,
+			provider: prov.Std,
+			layerID:  parts[1],
+		})
+	}
+
+	// the sources' features keep their SRIDs, the first source's is reported for the layer
+	l.srid = infos[0].SRID()
+
+	var ok bool
+	if l.geomType, ok = provider.GeometryType(gtype); !ok {
+		return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+	}
+	if gtype == "" {
+		l.geomType = commonGeometryType(infos)
+	}
+
+	p.layers[name] = l
+
+	return nil
+}
+
+// commonGeometryType returns the geometry type of the layers when it's the same for all of them
+func commonGeometryType(infos []provider.LayerInfo) geom.Geometry {
+	gt := infos[0].GeomType()
+	for _, info := range infos[1:] {
+		if gt == nil || reflect.TypeOf(info.GeomType()) != reflect.TypeOf(gt) {
+			return nil
+		}
+	}
+	return gt
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent returns the union of the extents of the layer's sources
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return geom.Extent{}, ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	var ext *geom.Extent
+	for _, s := range layer.sources {
+		sext, err := s.provider.LayerExtent(s.layerID)
+		if err != nil {
+			return geom.Extent{}, err
+		}
+		if ext == nil {
+			ext = &sext
+			continue
+		}
+		ext.Add(&sext)
+	}
+	return *ext, nil
+}
+
+// LayerMinZoom returns the lowest min zoom of the layer's sources
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return 0
+	}
+
+	min := layer.sources[0].provider.LayerMinZoom(layer.sources[0].layerID)
+	for _, s := range layer.sources[1:] {
+		if z := s.provider.LayerMinZoom(s.layerID); z < min {
+			min = z
+		}
+	}
+	return min
+}
+
+// LayerMaxZoom returns the highest max zoom of the layer's sources
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return 0
+	}
+
+	max := layer.sources[0].provider.LayerMaxZoom(layer.sources[0].layerID)
+	for _, s := range layer.sources[1:] {
+		if z := s.provider.LayerMaxZoom(s.layerID); z > max {
+			max = z
+		}
+	}
+	return max
+}
+
+// TileFeatures streams the features of the layer's sources, one source after the other in
+// the order they're configured
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	for _, s := range layer.sources {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		sfn := fn
+		if layer.sourceTag != "" {
+			name := s.name
+			sfn = func(f *provider.Feature) error {
+				if f.Tags == nil {
+					f.Tags = map[string]interface{}{}
+				}
+				f.Tags[layer.sourceTag] = name
+				return fn(f)
+			}
+		}
+
+		if err := s.provider.TileFeatures(ctx, s.layerID, tile, sfn); err != nil {
+			if err == provider.ErrCanceled {
+				return err
+			}
+			return fmt.Errorf("composite: layer (%v) source (%v): %w", layer.name, s.name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package composite_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/composite"
+	"github.com/go-spatial/tegola/provider/memory"
+)
+
+// newSource configures a memory provider with a roads layer holding the feature
+func newSource(t *testing.T, name string, f provider.Feature) {
+	p, err := memory.New(tegola.WGS84)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.AddLayer(dict.Dict{"name": "roads", "geometry_type": "linestring"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.AddFeature("roads", f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	provider.SetInstance(name, provider.TilerUnion{Std: p})
+}
+
+func TestTileFeatures(t *testing.T) {
+	national := provider.Feature{ID: 1, Geometry: geom.LineString{{-100, 40}, {-90, 40}}, SRID: tegola.WGS84, Tags: map[string]interface{}{"name": "I-80"}}
+	regional := provider.Feature{ID: 2, Geometry: geom.LineString{{-95, 45}, {-94, 46}}, SRID: tegola.WGS84, Tags: map[string]interface{}{"name": "MN-23"}}
+	newSource(t, "national", national)
+	newSource(t, "regional", regional)
+
+	p, err := composite.NewTileProvider(dict.Dict{
+		"layers": []map[string]interface{}{
+			{"name": "roads", "sources": []string{"national.roads", "regional.roads"}, "source_tag": "source"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, ok := p.Layer("roads")
+	if !ok {
+		t.Fatalf("layer, expected roads")
+	}
+	if _, ok := info.GeomType().(geom.LineString); !ok || info.SRID() != tegola.WGS84 {
+		t.Errorf("layer info, expected linestring 4326 got %T %v", info.GeomType(), info.SRID())
+	}
+
+	var features []provider.Feature
+	err = p.TileFeatures(context.Background(), "roads", provider.NewTile(0, 0, 0, 0, tegola.WebMercator), func(f *provider.Feature) error {
+		features = append(features, *f)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	national.Tags = map[string]interface{}{"name": "I-80", "source": "national.roads"}
+	regional.Tags = map[string]interface{}{"name": "MN-23", "source": "regional.roads"}
+	if expected := []provider.Feature{national, regional}; !reflect.DeepEqual(features, expected) {
+		t.Errorf("features, expected %+v got %+v", expected, features)
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		layer map[string]interface{}
+		err   string
+	}
+
+	newSource(t, "national", provider.Feature{ID: 1, Geometry: geom.Point{0, 0}})
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer["name"] = "test"
+			_, err := composite.NewTileProvider(dict.Dict{"layers": []map[string]interface{}{tc.layer}})
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing sources": {
+			layer: map[string]interface{}{},
+			err:   "composite: layer (test) is missing 'sources'",
+		},
+		"invalid source": {
+			layer: map[string]interface{}{"sources": []string{"roads"}},
+			err:   "composite: layer (test) source (roads) is invalid, expected the provider.layer syntax",
+		},
+		"provider not found": {
+			layer: map[string]interface{}{"sources": []string{"missing.roads"}},
+			err:   "composite: layer (test) source provider (missing) not found, sources must be configured before the composite provider",
+		},
+		"layer not found": {
+			layer: map[string]interface{}{"sources": []string{"national.rivers"}},
+			err:   "composite: layer (test) source (national.rivers) not found",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
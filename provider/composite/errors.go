@@ -0,0 +1,84 @@
+package composite
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrMissingLayerName = errors.New("composite: layer is missing 'name'")
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("composite: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("composite: layer (%v) not found", e.LayerName)
+}
+
+// ErrMissingSources is returned when a layer doesn't list any sources
+type ErrMissingSources struct {
+	LayerName string
+}
+
+func (e ErrMissingSources) Error() string {
+	return fmt.Sprintf("composite: layer (%v) is missing 'sources'", e.LayerName)
+}
+
+// ErrInvalidSource is returned when a source doesn't have the provider.layer syntax
+type ErrInvalidSource struct {
+	LayerName string
+	Source    string
+}
+
+func (e ErrInvalidSource) Error() string {
+	return fmt.Sprintf("composite: layer (%v) source (%v) is invalid, expected the provider.layer syntax", e.LayerName, e.Source)
+}
+
+// ErrSourceProviderNotFound is returned when the provider of a source isn't configured
+// before the composite provider
+type ErrSourceProviderNotFound struct {
+	LayerName string
+	Provider  string
+}
+
+func (e ErrSourceProviderNotFound) Error() string {
+	return fmt.Sprintf("composite: layer (%v) source provider (%v) not found, sources must be configured before the composite provider", e.LayerName, e.Provider)
+}
+
+// ErrSourceLayerNotFound is returned when the layer of a source isn't a layer of its provider
+type ErrSourceLayerNotFound struct {
+	LayerName string
+	Source    string
+}
+
+func (e ErrSourceLayerNotFound) Error() string {
+	return fmt.Sprintf("composite: layer (%v) source (%v) not found", e.LayerName, e.Source)
+}
+
+// ErrMVTSource is returned when the provider of a source is an MVT provider, whose tiles
+// are already encoded
+type ErrMVTSource struct {
+	LayerName string
+	Provider  string
+}
+
+func (e ErrMVTSource) Error() string {
+	return fmt.Sprintf("composite: layer (%v) source provider (%v) is an MVT provider, only standard providers can be composited", e.LayerName, e.Provider)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("composite: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
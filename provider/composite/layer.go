@@ -0,0 +1,29 @@
+package composite
+
+import (
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+// source is a layer of another provider whose features are part of a composite layer
+type source struct {
+	// name is the source in the provider.layer syntax
+	name     string
+	provider provider.Tiler
+	layerID  string
+}
+
+type Layer struct {
+	name     string
+	geomType geom.Geometry
+	srid     uint64
+	// sourceTag is the tag set to the name of each feature's source, not set when empty
+	sourceTag string
+	sources   []source
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
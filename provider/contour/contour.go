@@ -0,0 +1,488 @@
+// Package contour provides a provider which generates contour lines on the fly from a raster
+// digital elevation model (DEM). The DEM is a single band GeoTIFF or Cloud Optimized GeoTIFF
+// in EPSG:4326 or EPSG:3857, on local disk or read with HTTP range requests. For every tile the
+// DEM is sampled from the overview closest to the tile's resolution and contour lines are traced
+// with marching squares, at an interval which can be configured per zoom. The contours of the
+// most recently requested tiles are kept in memory.
+package contour
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/geotiff"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/ttlcache"
+	"github.com/go-spatial/tegola/maths/webmercator"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "contour"
+
+const (
+	ConfigKeyFilePath   = "filepath"
+	ConfigKeySRID       = "srid"
+	ConfigKeyNoData     = "nodata"
+	ConfigKeyScale      = "scale"
+	ConfigKeyResolution = "resolution"
+	ConfigKeyCacheSize  = "cache_size_mb"
+	ConfigKeyTileCache  = "tile_cache_size"
+	ConfigKeyHeaders    = "headers"
+	ConfigKeyTimeout    = "timeout"
+	ConfigKeyLayers     = "layers"
+
+	ConfigKeyLayerName  = "name"
+	ConfigKeyInterval   = "interval"
+	ConfigKeyIndexEvery = "index_every"
+	ConfigKeyIntervals  = "intervals"
+	ConfigKeyMinZoom    = "min_zoom"
+)
+
+const (
+	DefaultResolution = 128
+	DefaultCacheSize  = 64
+	DefaultTileCache  = 256
+	DefaultTimeout    = 30
+	DefaultInterval   = 10
+	DefaultIndexEvery = 5
+)
+
+// tags of the contour features
+const (
+	TagElevation = "elevation"
+	TagIndex     = "index"
+)
+
+// the latitude bounds of web mercator
+const maxMercatorLat = 85.0511287798
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// providers are tracked so their DEMs can be closed during cleanup
+var (
+	providersLock sync.Mutex
+	providers     []*Provider
+)
+
+// tileKey is the layer and the buffered extent of a tile in the contour tile cache
+type tileKey struct {
+	layer  string
+	z      uint
+	extent geom.Extent
+	srid   uint64
+}
+
+// Provider generates contour lines from a DEM
+type Provider struct {
+	filepath   string
+	closer     io.Closer
+	dem        *geoTIFF
+	scale      float64
+	resolution int
+	// the contour features of the most recently requested tiles, so a tile requested
+	// again, i.e. by another map or after an eviction from the tile cache, isn't sampled
+	// and traced again. nil when disabled
+	tiles *ttlcache.Cache
+
+	// map of layer name and corresponding contour settings
+	layers map[string]Layer
+}
+
+// NewTileProvider instantiates and returns a new contour provider or an error.
+// The DEM's header is read when the provider is created.
+//
+//	filepath (string): [Required] the path or http(s) url of the GeoTIFF or Cloud Optimized GeoTIFF
+//	headers (map[string]string): [Optional] headers added to the requests of a remote DEM (i.e. API keys)
+//	timeout (int): [Optional] the number of seconds allowed per request of a remote DEM. defaults to 30
+//	srid (int): [Optional] the SRID of the DEM, when the GeoTIFF's geo keys can't be identified. 4326 or 3857
+//	nodata (float): [Optional] the elevation of pixels without data. defaults to the GeoTIFF's GDAL_NODATA tag
+//	scale (float): [Optional] a multiplier applied to elevations, i.e. 3.28084 for feet from meters. defaults to 1
+//	resolution (int): [Optional] the number of elevation samples across a tile. defaults to 128
+//	cache_size_mb (int): [Optional] the megabytes of decoded DEM blocks kept in memory, 0 disables the cache. defaults to 64
+//	tile_cache_size (int): [Optional] the number of layer tiles whose contours are kept in memory, 0 disables the cache. defaults to 256
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		interval (float): [Optional] the elevation between contour lines. defaults to 10
+//		index_every (int): [Optional] every nth contour is tagged as an index contour. defaults to 5
+//		intervals ([]struct{}): [Optional] the interval used from a zoom on
+//			min_zoom (int): [Required] the first zoom of the interval
+//			interval (float): [Required] the elevation between contour lines
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	filepath, err := config.String(ConfigKeyFilePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if filepath == "" {
+		return nil, ErrMissingFilePath
+	}
+
+	ints := []struct {
+		key string
+		val int
+	}{
+		{ConfigKeySRID, 0},
+		{ConfigKeyResolution, DefaultResolution},
+		{ConfigKeyCacheSize, DefaultCacheSize},
+		{ConfigKeyTileCache, DefaultTileCache},
+		{ConfigKeyTimeout, DefaultTimeout},
+	}
+	for i := range ints {
+		if ints[i].val, err = config.Int(ints[i].key, &ints[i].val); err != nil {
+			return nil, err
+		}
+		if ints[i].val < 0 {
+			return nil, fmt.Errorf("contour: %v must not be negative, got %v", ints[i].key, ints[i].val)
+		}
+	}
+	srid, resolution, cacheSize, tileCacheSize, timeout := ints[0].val, ints[1].val, ints[2].val, ints[3].val, ints[4].val
+	if resolution == 0 {
+		resolution = DefaultResolution
+	}
+	if srid != 0 && srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return nil, ErrUnsupportedSRID{SRID: srid}
+	}
+
+	scale := 1.0
+	if scale, err = config.Float(ConfigKeyScale, &scale); err != nil {
+		return nil, err
+	}
+
+	headers, err := dict.StringMap(config, ConfigKeyHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	var f interface {
+		io.ReaderAt
+		io.Closer
+	}
+	if lower := strings.ToLower(filepath); strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		f = geotiff.NewHTTPReaderAt(filepath, headers, &http.Client{Timeout: time.Duration(timeout) * time.Second})
+	} else if f, err = os.Open(filepath); err != nil {
+		return nil, ErrInvalidFilePath{FilePath: filepath}
+	}
+
+	dem, err := openGeoTIFF(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if srid != 0 {
+		dem.SRID = uint64(srid)
+	}
+	if dem.SRID == 0 {
+		f.Close()
+		return nil, ErrUnknownSRID
+	}
+
+	if _, ok := config.Interface(ConfigKeyNoData); ok {
+		if dem.NoData, err = config.Float(ConfigKeyNoData, nil); err != nil {
+			f.Close()
+			return nil, err
+		}
+		dem.HasNoData = true
+	}
+
+	img := dem.Images[0]
+	dem.Cache = geotiff.NewBlockCache(cacheSize * 1024 * 1024 / (img.BlockW * img.BlockH * 8))
+
+	p := Provider{
+		filepath:   filepath,
+		closer:     f,
+		dem:        dem,
+		scale:      scale,
+		resolution: resolution,
+		layers:     map[string]Layer{},
+	}
+	if tileCacheSize > 0 {
+		p.tiles = ttlcache.New(tileCacheSize, 0)
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	providersLock.Lock()
+	providers = append(providers, &p)
+	providersLock.Unlock()
+
+	return &p, nil
+}
+
+// AddLayer adds a contour layer to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	l := Layer{
+		name:       name,
+		interval:   DefaultInterval,
+		indexEvery: DefaultIndexEvery,
+	}
+
+	if l.interval, err = layerConf.Float(ConfigKeyInterval, &l.interval); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyInterval, err)
+	}
+	if l.interval <= 0 {
+		return ErrInvalidInterval{LayerName: name, Interval: l.interval}
+	}
+
+	if l.indexEvery, err = layerConf.Int(ConfigKeyIndexEvery, &l.indexEvery); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyIndexEvery, err)
+	}
+
+	intervals, err := layerConf.MapSlice(ConfigKeyIntervals)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyIntervals, err)
+	}
+	for _, intervalConf := range intervals {
+		minZoom, err := intervalConf.Uint(ConfigKeyMinZoom, nil)
+		if err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyIntervals, err)
+		}
+		interval, err := intervalConf.Float(ConfigKeyInterval, nil)
+		if err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyIntervals, err)
+		}
+		if interval <= 0 {
+			return ErrInvalidInterval{LayerName: name, Interval: interval}
+		}
+		l.intervals = append(l.intervals, zoomInterval{minZoom: minZoom, interval: interval})
+	}
+	sort.Slice(l.intervals, func(i, j int) bool {
+		return l.intervals[i].minZoom < l.intervals[j].minZoom
+	})
+
+	p.layers[name] = l
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent returns the extent of the DEM in its SRID
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	img := p.dem.Images[0]
+	return geom.Extent{
+		img.OriginX,
+		img.OriginY - float64(img.Height)*img.ScaleY,
+		img.OriginX + float64(img.Width)*img.ScaleX,
+		img.OriginY,
+	}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures generates a feature for each contour elevation crossing the tile's buffered extent.
+// The features are tagged with their elevation and if they are an index contour. The features
+// of recently requested tiles are served from the tile cache.
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	z, _, _ := tile.ZXY()
+	ext, tileSRID := tile.BufferedExtent()
+
+	// the buffer of the tile depends on the map, so the buffered extent is part of the key
+	key := tileKey{layer: lyrID, z: z, extent: *ext, srid: tileSRID}
+	var features []provider.Feature
+	if v, ok := p.tiles.Get(key); ok {
+		features = v.([]provider.Feature)
+	} else {
+		var err error
+		if features, err = p.contours(ctx, layer, z, *ext, tileSRID); err != nil {
+			return err
+		}
+		p.tiles.Set(key, features)
+	}
+
+	for i := range features {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// callers are allowed to modify the tags so each gets their own copy
+		f := features[i]
+		f.Tags = provider.CopyTags(f.Tags)
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// contours traces the contour lines of the layer across ext, in the SRID of the tile
+func (p *Provider) contours(ctx context.Context, layer Layer, z uint, ext geom.Extent, tileSRID uint64) ([]provider.Feature, error) {
+	g, err := p.grid(ctx, ext, tileSRID)
+	if err != nil {
+		return nil, err
+	}
+
+	min, max, ok := g.bounds()
+	if !ok {
+		return nil, nil
+	}
+
+	interval := layer.intervalAt(z)
+	first, last := math.Ceil(min/interval), math.Floor(max/interval)
+
+	var features []provider.Feature
+	dx, dy := (ext.MaxX()-ext.MinX())/float64(g.n), (ext.MaxY()-ext.MinY())/float64(g.n)
+	for k := first; k <= last; k++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		level := k * interval
+		lines := isolines(g, level)
+		if len(lines) == 0 {
+			continue
+		}
+
+		mls := make(geom.MultiLineString, len(lines))
+		for i, line := range lines {
+			ls := make(geom.LineString, len(line))
+			for j, pt := range line {
+				ls[j] = [2]float64{ext.MinX() + pt[0]*dx, ext.MaxY() - pt[1]*dy}
+			}
+			mls[i] = ls
+		}
+
+		var elevation interface{} = level
+		if level == math.Trunc(level) {
+			elevation = int64(level)
+		}
+
+		features = append(features, provider.Feature{
+			ID:       uint64(k-first) + 1,
+			Geometry: mls,
+			SRID:     tileSRID,
+			Tags: map[string]interface{}{
+				TagElevation: elevation,
+				TagIndex:     layer.indexEvery > 0 && int64(k)%int64(layer.indexEvery) == 0,
+			},
+		})
+	}
+
+	return features, nil
+}
+
+// grid samples the DEM across ext, in the SRID of the tile
+func (p *Provider) grid(ctx context.Context, ext geom.Extent, srid uint64) (grid, error) {
+	toDEM := func(x, y float64) (float64, float64) { return x, y }
+	switch {
+	case srid == tegola.WebMercator && p.dem.SRID == tegola.WGS84:
+		toDEM = func(x, y float64) (float64, float64) {
+			return webmercator.PXToLon(x), webmercator.PYToLat(y)
+		}
+	case srid == tegola.WGS84 && p.dem.SRID == tegola.WebMercator:
+		toDEM = func(x, y float64) (float64, float64) {
+			y = math.Max(-maxMercatorLat, math.Min(maxMercatorLat, y))
+			return webmercator.PLonToX(x), webmercator.PLatToY(y)
+		}
+	case srid != p.dem.SRID:
+		return grid{}, ErrUnsupportedSRID{SRID: int(srid)}
+	}
+
+	n := p.resolution
+	minX, _ := toDEM(ext.MinX(), ext.MinY())
+	maxX, _ := toDEM(ext.MaxX(), ext.MaxY())
+	s := p.dem.sampler(p.dem.Level((maxX - minX) / float64(n)))
+
+	g := grid{n: n, values: make([]float64, (n+1)*(n+1))}
+	dx, dy := (ext.MaxX()-ext.MinX())/float64(n), (ext.MaxY()-ext.MinY())/float64(n)
+	for r := 0; r <= n; r++ {
+		if ctx.Err() != nil {
+			return grid{}, ctx.Err()
+		}
+
+		for c := 0; c <= n; c++ {
+			x, y := toDEM(ext.MinX()+float64(c)*dx, ext.MaxY()-float64(r)*dy)
+			v, err := s.sample(x, y)
+			if err != nil {
+				return grid{}, err
+			}
+			g.values[r*(n+1)+c] = v * p.scale
+		}
+	}
+
+	return g, nil
+}
+
+// Close closes the DEM
+func (p *Provider) Close() error {
+	return p.closer.Close()
+}
+
+// Cleanup will close all contour providers' DEMs
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if len(providers) > 0 {
+		log.Infof("cleaning up contour providers")
+	}
+
+	for i := range providers {
+		if err := providers[i].Close(); err != nil {
+			log.Errorf("err closing DEM: %v", err)
+		}
+	}
+
+	providers = nil
+}
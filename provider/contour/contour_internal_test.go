@@ -0,0 +1,345 @@
+package contour
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/geotiff"
+	"github.com/go-spatial/tegola/provider"
+)
+
+func TestIsolines(t *testing.T) {
+	type tcase struct {
+		grid     grid
+		level    float64
+		expected [][][2]float64
+	}
+
+	nan := math.NaN()
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			lines := isolines(tc.grid, tc.level)
+			if len(lines) != len(tc.expected) {
+				t.Fatalf("expected %v lines got %v: %v", len(tc.expected), len(lines), lines)
+			}
+			for i := range lines {
+				if len(lines[i]) != len(tc.expected[i]) {
+					t.Fatalf("line %v: expected %v got %v", i, tc.expected[i], lines[i])
+				}
+				for j := range lines[i] {
+					if lines[i][j] != tc.expected[i][j] {
+						t.Errorf("line %v: expected %v got %v", i, tc.expected[i], lines[i])
+						break
+					}
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"ramp": {
+			grid:     grid{n: 2, values: []float64{0, 1, 2, 0, 1, 2, 0, 1, 2}},
+			level:    0.5,
+			expected: [][][2]float64{{{0.5, 0}, {0.5, 1}, {0.5, 2}}},
+		},
+		"peak ring": {
+			grid:     grid{n: 2, values: []float64{0, 0, 0, 0, 10, 0, 0, 0, 0}},
+			level:    5,
+			expected: [][][2]float64{{{0.5, 1}, {1, 0.5}, {1.5, 1}, {1, 1.5}, {0.5, 1}}},
+		},
+		"level above grid": {
+			grid:  grid{n: 2, values: []float64{0, 1, 2, 0, 1, 2, 0, 1, 2}},
+			level: 3,
+		},
+		"nodata": {
+			grid:  grid{n: 2, values: []float64{0, 1, 2, 0, nan, 2, 0, 1, 2}},
+			level: 0.5,
+		},
+		// the center is above the level, joining the top left and bottom right corners
+		"saddle": {
+			grid:     grid{n: 1, values: []float64{10, 0, 0, 10}},
+			level:    4,
+			expected: [][][2]float64{{{0.6, 0}, {1, 0.4}}, {{0, 0.6}, {0.4, 1}}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+// writeDEM writes a 1 degree WGS84 DEM of a cone 3000 high at 0, 0 dropping 50 per degree
+func writeDEM(t *testing.T, geoKeys []uint16) string {
+	width, height := 360, 180
+	vs := make([]float64, width*height)
+	for r := 0; r < height; r++ {
+		for c := 0; c < width; c++ {
+			lon, lat := -180+float64(c)+0.5, 90-float64(r)-0.5
+			vs[r*width+c] = math.Max(0, 3000-50*math.Hypot(lon, lat))
+		}
+	}
+
+	b := encodeGeoTIFF(t, binary.LittleEndian,
+		testGeo{originX: -180, originY: 90, scale: 1, geoKeys: geoKeys, nodata: "-32768"},
+		testImage{width: width, height: height, tileSize: 64, compression: geotiff.CompressionDeflate, predictor: geotiff.PredictorHorizontal, bits: 16, sampleFormat: geotiff.SampleFormatInt, values: vs},
+	)
+
+	dir, err := ioutil.TempDir("", "contour")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "dem.tif")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		geoKeys []uint16
+		config  dict.Dict
+		err     error
+	}
+
+	wgs84 := []uint16{geotiff.GeoKeyGeographicType, 0, 1, 4326}
+	layers := []map[string]interface{}{{"name": "contours"}}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			if _, ok := tc.config[ConfigKeyFilePath]; !ok {
+				tc.config[ConfigKeyFilePath] = writeDEM(t, tc.geoKeys)
+			}
+
+			p, err := NewTileProvider(tc.config)
+			if tc.err != nil {
+				if err != tc.err {
+					t.Fatalf("expected error %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			p.(*Provider).Close()
+		}
+	}
+
+	tests := map[string]tcase{
+		"valid": {
+			geoKeys: wgs84,
+			config:  dict.Dict{ConfigKeyLayers: layers},
+		},
+		"missing filepath": {
+			config: dict.Dict{ConfigKeyFilePath: ""},
+			err:    ErrMissingFilePath,
+		},
+		"invalid filepath": {
+			config: dict.Dict{ConfigKeyFilePath: "does/not/exist.tif"},
+			err:    ErrInvalidFilePath{FilePath: "does/not/exist.tif"},
+		},
+		"unknown srid": {
+			config: dict.Dict{ConfigKeyLayers: layers},
+			err:    ErrUnknownSRID,
+		},
+		"srid set": {
+			config: dict.Dict{ConfigKeySRID: 4326, ConfigKeyLayers: layers},
+		},
+		"unsupported srid": {
+			config: dict.Dict{ConfigKeySRID: 2193, ConfigKeyLayers: layers},
+			err:    ErrUnsupportedSRID{SRID: 2193},
+		},
+		"invalid interval": {
+			geoKeys: wgs84,
+			config:  dict.Dict{ConfigKeyLayers: []map[string]interface{}{{"name": "contours", "interval": -1.0}}},
+			err:     ErrInvalidInterval{LayerName: "contours", Interval: -1},
+		},
+		"duplicate layer": {
+			geoKeys: wgs84,
+			config:  dict.Dict{ConfigKeyLayers: []map[string]interface{}{{"name": "contours"}, {"name": "contours"}}},
+			err:     ErrDuplicateLayerName{LayerName: "contours"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		tile     provider.Tile
+		interval float64
+		expected int
+	}
+
+	p, err := NewTileProvider(dict.Dict{
+		ConfigKeyFilePath: writeDEM(t, []uint16{geotiff.GeoKeyGeographicType, 0, 1, 4326}),
+		ConfigKeyLayers: []map[string]interface{}{{
+			"name":     "contours",
+			"interval": 100.0,
+			"intervals": []map[string]interface{}{
+				{"min_zoom": uint(4), "interval": 50.0},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer p.(*Provider).Close()
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			var count int
+			ext, _ := tc.tile.BufferedExtent()
+			err := p.TileFeatures(context.Background(), "contours", tc.tile, func(f *provider.Feature) error {
+				count++
+
+				elevation, ok := f.Tags[TagElevation].(int64)
+				if !ok || math.Mod(float64(elevation), tc.interval) != 0 {
+					t.Errorf("expected an elevation at an interval of %v got %v", tc.interval, f.Tags[TagElevation])
+				}
+				if index := elevation%int64(5*tc.interval) == 0; f.Tags[TagIndex] != index {
+					t.Errorf("elevation %v: expected index %v got %v", elevation, index, f.Tags[TagIndex])
+				}
+				if f.SRID != tegola.WebMercator {
+					t.Errorf("expected srid %v got %v", tegola.WebMercator, f.SRID)
+				}
+
+				mls, ok := f.Geometry.(geom.MultiLineString)
+				if !ok || len(mls) == 0 {
+					t.Fatalf("expected a multi line string got %T", f.Geometry)
+				}
+				for _, ls := range mls {
+					for _, pt := range ls {
+						if !ext.ContainsPoint(pt) {
+							t.Fatalf("point %v outside of the tile's buffered extent %v", pt, ext)
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if count != tc.expected {
+				t.Errorf("expected %v features got %v", tc.expected, count)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		// the cone reaches from 0 to 3000, every 100 are 29 contours
+		"world": {
+			tile:     provider.NewTile(0, 0, 0, 0, tegola.WebMercator),
+			interval: 100,
+			expected: 29,
+		},
+		// the corner of the cone from its peak down to about 1430, every 50 are 31 contours
+		"zoom interval": {
+			tile:     provider.NewTile(4, 8, 7, 0, tegola.WebMercator),
+			interval: 50,
+			expected: 31,
+		},
+		"flat": {
+			tile:     provider.NewTile(6, 0, 0, 0, tegola.WebMercator),
+			interval: 50,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestRemoteDEM(t *testing.T) {
+	path := writeDEM(t, []uint16{geotiff.GeoKeyGeographicType, 0, 1, 4326})
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("x-api-key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		// serves range requests
+		http.ServeContent(w, r, "dem.tif", time.Time{}, bytes.NewReader(body))
+	}))
+	defer srv.Close()
+
+	p, err := NewTileProvider(dict.Dict{
+		ConfigKeyFilePath: srv.URL + "/dem.tif",
+		ConfigKeyHeaders:  map[string]interface{}{"x-api-key": "secret"},
+		ConfigKeyLayers:   []map[string]interface{}{{"name": "contours", "interval": 100.0}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer p.(*Provider).Close()
+
+	count := func() int {
+		var n int
+		err := p.TileFeatures(context.Background(), "contours", provider.NewTile(0, 0, 0, 0, tegola.WebMercator), func(f *provider.Feature) error {
+			// modifying the tags doesn't change the cached features
+			f.Tags[TagElevation] = nil
+			n++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return n
+	}
+
+	if n := count(); n != 29 {
+		t.Errorf("expected 29 features got %v", n)
+	}
+
+	// the contours of the tile are cached
+	before := atomic.LoadInt32(&requests)
+	if n := count(); n != 29 {
+		t.Errorf("expected 29 cached features got %v", n)
+	}
+	if after := atomic.LoadInt32(&requests); after != before {
+		t.Errorf("expected the cached tile not to request the DEM, got %v requests", after-before)
+	}
+	ext, srid := provider.NewTile(0, 0, 0, 0, tegola.WebMercator).BufferedExtent()
+	v, ok := p.(*Provider).tiles.Get(tileKey{layer: "contours", z: 0, extent: *ext, srid: srid})
+	features, _ := v.([]provider.Feature)
+	if !ok || len(features) != 29 || features[0].Tags[TagElevation] == nil {
+		t.Errorf("expected 29 cached features with elevations, got %v", features)
+	}
+}
+
+func TestRemoteDEMRangeUnsupported(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("II*\x00"))
+	}))
+	defer srv.Close()
+
+	_, err := NewTileProvider(dict.Dict{ConfigKeyFilePath: srv.URL})
+	if err != ErrRangeUnsupported {
+		t.Errorf("expected error %v got %v", ErrRangeUnsupported, err)
+	}
+}
@@ -0,0 +1,92 @@
+package contour
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingFilePath  = errors.New("contour: provider is missing 'filepath'")
+	ErrMissingLayerName = errors.New("contour: layer is missing 'name'")
+	ErrUnknownSRID      = errors.New("contour: the DEM's SRID can't be identified, set 'srid' to 4326 or 3857")
+)
+
+type ErrInvalidFilePath struct {
+	FilePath string
+}
+
+func (e ErrInvalidFilePath) Error() string {
+	return fmt.Sprintf("contour: invalid filepath: %v", e.FilePath)
+}
+
+type ErrUnsupportedSRID struct {
+	SRID int
+}
+
+func (e ErrUnsupportedSRID) Error() string {
+	return fmt.Sprintf("contour: unsupported srid (%v), expected 4326 or 3857", e.SRID)
+}
+
+type ErrInvalidInterval struct {
+	LayerName string
+	Interval  float64
+}
+
+func (e ErrInvalidInterval) Error() string {
+	return fmt.Sprintf("contour: layer (%v) interval must be greater than 0, got %v", e.LayerName, e.Interval)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("contour: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("contour: layer (%v) not found", e.LayerName)
+}
+
+// ErrInvalidGeoTIFF is returned when the DEM is not a GeoTIFF the provider can read
+type ErrInvalidGeoTIFF struct {
+	Reason string
+}
+
+func (e ErrInvalidGeoTIFF) Error() string {
+	return fmt.Sprintf("contour: invalid GeoTIFF: %v", e.Reason)
+}
+
+type ErrUnsupportedCompression struct {
+	Compression int
+}
+
+func (e ErrUnsupportedCompression) Error() string {
+	return fmt.Sprintf("contour: unsupported GeoTIFF compression (%v), expected none (1) or deflate (8)", e.Compression)
+}
+
+type ErrUnsupportedSampleFormat struct {
+	SampleFormat  int
+	BitsPerSample int
+}
+
+func (e ErrUnsupportedSampleFormat) Error() string {
+	return fmt.Sprintf("contour: unsupported GeoTIFF sample format (%v) of %v bits", e.SampleFormat, e.BitsPerSample)
+}
+
+// ErrRangeUnsupported is returned when the server of a remote DEM ignores the Range header of a request
+var ErrRangeUnsupported = errors.New("contour: server does not support range requests")
+
+// ErrStatus is returned when the server of a remote DEM responds with an unexpected status
+type ErrStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("contour: DEM (%v) responded with status %v", e.URL, e.Status)
+}
@@ -0,0 +1,186 @@
+package contour
+
+import (
+	"io"
+	"math"
+
+	"github.com/go-spatial/tegola/internal/geotiff"
+	"github.com/go-spatial/tegola/internal/remote"
+)
+
+// geoTIFF reads the elevations of a single band GeoTIFF or Cloud Optimized GeoTIFF.
+// Uncompressed and deflate compressed images of integer or floating point samples are supported.
+type geoTIFF struct {
+	*geotiff.File
+}
+
+func openGeoTIFF(r io.ReaderAt) (*geoTIFF, error) {
+	f, err := geotiff.Open(r)
+	if err != nil {
+		return nil, geoTIFFError(err)
+	}
+
+	for _, img := range f.Images {
+		switch img.Compression {
+		case geotiff.CompressionNone, geotiff.CompressionDeflate, geotiff.CompressionDeflateOld:
+		default:
+			return nil, ErrUnsupportedCompression{Compression: img.Compression}
+		}
+
+		switch img.SampleFormat {
+		case geotiff.SampleFormatUint, geotiff.SampleFormatInt:
+			if img.BitsPerSample != 8 && img.BitsPerSample != 16 && img.BitsPerSample != 32 && img.BitsPerSample != 64 {
+				return nil, ErrUnsupportedSampleFormat{SampleFormat: img.SampleFormat, BitsPerSample: img.BitsPerSample}
+			}
+		case geotiff.SampleFormatFloat:
+			if img.BitsPerSample != 32 && img.BitsPerSample != 64 {
+				return nil, ErrUnsupportedSampleFormat{SampleFormat: img.SampleFormat, BitsPerSample: img.BitsPerSample}
+			}
+		default:
+			return nil, ErrUnsupportedSampleFormat{SampleFormat: img.SampleFormat, BitsPerSample: img.BitsPerSample}
+		}
+	}
+
+	return &geoTIFF{File: f}, nil
+}
+
+// geoTIFFError returns the provider's error for an error of the GeoTIFF reader
+func geoTIFFError(err error) error {
+	switch e := err.(type) {
+	case geotiff.ErrInvalid:
+		return ErrInvalidGeoTIFF{Reason: e.Reason}
+	case remote.ErrStatus:
+		return ErrStatus{URL: e.URL, Status: e.Status}
+	}
+	if err == remote.ErrRangeUnsupported {
+		return ErrRangeUnsupported
+	}
+	return err
+}
+
+// readBlock reads and decodes a block of the image. Pixels matching the nodata value are NaN.
+func (g *geoTIFF) readBlock(img *geotiff.Image, index int) ([]float64, error) {
+	key := geotiff.BlockKey{Image: img.Index, Block: index}
+	if vs, ok := g.Cache.Get(key); ok {
+		return vs.([]float64), nil
+	}
+
+	// sparse blocks have no rows
+	data, rows, order, err := g.DecodeBlock(img, index)
+	if err != nil {
+		return nil, geoTIFFError(err)
+	}
+
+	bytesPerSample := img.BitsPerSample / 8
+	vs := make([]float64, img.BlockW*img.BlockH)
+	for i := range vs {
+		if i >= rows*img.BlockW {
+			vs[i] = math.NaN()
+			continue
+		}
+
+		b := data[i*img.Stride*bytesPerSample:]
+		var v float64
+		switch {
+		case img.SampleFormat == geotiff.SampleFormatFloat && bytesPerSample == 4:
+			v = float64(math.Float32frombits(order.Uint32(b)))
+		case img.SampleFormat == geotiff.SampleFormatFloat:
+			v = math.Float64frombits(order.Uint64(b))
+		case img.SampleFormat == geotiff.SampleFormatInt:
+			switch bytesPerSample {
+			case 1:
+				v = float64(int8(b[0]))
+			case 2:
+				v = float64(int16(order.Uint16(b)))
+			case 4:
+				v = float64(int32(order.Uint32(b)))
+			default:
+				v = float64(int64(order.Uint64(b)))
+			}
+		default:
+			switch bytesPerSample {
+			case 1:
+				v = float64(b[0])
+			case 2:
+				v = float64(order.Uint16(b))
+			case 4:
+				v = float64(order.Uint32(b))
+			default:
+				v = float64(order.Uint64(b))
+			}
+		}
+
+		if g.HasNoData && v == g.NoData {
+			v = math.NaN()
+		}
+		vs[i] = v
+	}
+
+	g.Cache.Set(key, vs)
+	return vs, nil
+}
+
+// sampler reads elevations from an image, keeping the blocks it has read
+type sampler struct {
+	g      *geoTIFF
+	img    *geotiff.Image
+	blocks map[int][]float64
+}
+
+func (g *geoTIFF) sampler(img *geotiff.Image) *sampler {
+	return &sampler{g: g, img: img, blocks: map[int][]float64{}}
+}
+
+// pixel returns the value of the pixel at column c and row r, clamped to the image
+func (s *sampler) pixel(c, r int) (float64, error) {
+	img := s.img
+	if c < 0 {
+		c = 0
+	} else if c >= img.Width {
+		c = img.Width - 1
+	}
+	if r < 0 {
+		r = 0
+	} else if r >= img.Height {
+		r = img.Height - 1
+	}
+
+	index := (r/img.BlockH)*img.BlocksAcross + c/img.BlockW
+	vs, ok := s.blocks[index]
+	if !ok {
+		var err error
+		if vs, err = s.g.readBlock(img, index); err != nil {
+			return 0, err
+		}
+		s.blocks[index] = vs
+	}
+	return vs[(r%img.BlockH)*img.BlockW+c%img.BlockW], nil
+}
+
+// sample returns the bilinear interpolated elevation at x, y in the DEM's SRID.
+// NaN is returned outside of the DEM or next to nodata pixels.
+func (s *sampler) sample(x, y float64) (float64, error) {
+	img := s.img
+	fc := (x-img.OriginX)/img.ScaleX - 0.5
+	fr := (img.OriginY-y)/img.ScaleY - 0.5
+	if fc < -0.5 || fr < -0.5 || fc > float64(img.Width)-0.5 || fr > float64(img.Height)-0.5 {
+		return math.NaN(), nil
+	}
+
+	c0, r0 := math.Floor(fc), math.Floor(fr)
+	tx, ty := fc-c0, fr-r0
+	c, r := int(c0), int(r0)
+
+	var vs [4]float64
+	for i, p := range [4][2]int{{c, r}, {c + 1, r}, {c, r + 1}, {c + 1, r + 1}} {
+		v, err := s.pixel(p[0], p[1])
+		if err != nil {
+			return 0, err
+		}
+		vs[i] = v
+	}
+
+	top := vs[0]*(1-tx) + vs[1]*tx
+	bottom := vs[2]*(1-tx) + vs[3]*tx
+	return top*(1-ty) + bottom*ty, nil
+}
@@ -0,0 +1,422 @@
+package contour
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/go-spatial/tegola/internal/geotiff"
+)
+
+// testImage describes an image written by encodeGeoTIFF
+type testImage struct {
+	width, height int
+	// tileSize of 0 writes strips of stripRows rows
+	tileSize     int
+	stripRows    int
+	compression  int
+	predictor    int
+	bits         int
+	sampleFormat int
+	values       []float64
+}
+
+type testGeo struct {
+	originX, originY float64
+	scale            float64
+	geoKeys          []uint16
+	nodata           string
+}
+
+type testEntry struct {
+	tag, typ uint16
+	count    uint32
+	data     []byte
+}
+
+// encodeGeoTIFF writes a classic TIFF of the images. The first image is georeferenced with geo,
+// the others are written as reduced resolution overviews.
+func encodeGeoTIFF(t *testing.T, order binary.ByteOrder, geo testGeo, imgs ...testImage) []byte {
+	var buf bytes.Buffer
+	if order == binary.ByteOrder(binary.LittleEndian) {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(0))
+
+	shorts := func(vs ...uint16) []byte {
+		b := make([]byte, 2*len(vs))
+		for i, v := range vs {
+			order.PutUint16(b[i*2:], v)
+		}
+		return b
+	}
+	longs := func(vs ...uint32) []byte {
+		b := make([]byte, 4*len(vs))
+		for i, v := range vs {
+			order.PutUint32(b[i*4:], v)
+		}
+		return b
+	}
+	doubles := func(vs ...float64) []byte {
+		b := make([]byte, 8*len(vs))
+		for i, v := range vs {
+			order.PutUint64(b[i*8:], math.Float64bits(v))
+		}
+		return b
+	}
+
+	var ifds [][]testEntry
+	for i, img := range imgs {
+		blockW, blockH := img.width, img.stripRows
+		if img.tileSize > 0 {
+			blockW, blockH = img.tileSize, img.tileSize
+		}
+		if blockH == 0 {
+			blockH = img.height
+		}
+
+		var offsets, counts []uint32
+		for by := 0; by*blockH < img.height; by++ {
+			for bx := 0; bx*blockW < img.width; bx++ {
+				rows := blockH
+				if img.tileSize == 0 && (by+1)*blockH > img.height {
+					rows = img.height - by*blockH
+				}
+				data := encodeBlock(img, order, bx*blockW, by*blockH, blockW, rows)
+				offsets = append(offsets, uint32(buf.Len()))
+				counts = append(counts, uint32(len(data)))
+				buf.Write(data)
+			}
+		}
+
+		entries := []testEntry{
+			{geotiff.TagImageWidth, 4, 1, longs(uint32(img.width))},
+			{geotiff.TagImageLength, 4, 1, longs(uint32(img.height))},
+			{geotiff.TagBitsPerSample, 3, 1, shorts(uint16(img.bits))},
+			{geotiff.TagCompression, 3, 1, shorts(uint16(img.compression))},
+			{geotiff.TagSamplesPerPixel, 3, 1, shorts(1)},
+			{geotiff.TagPredictor, 3, 1, shorts(uint16(img.predictor))},
+			{geotiff.TagSampleFormat, 3, 1, shorts(uint16(img.sampleFormat))},
+		}
+		if img.tileSize > 0 {
+			entries = append(entries,
+				testEntry{geotiff.TagTileWidth, 3, 1, shorts(uint16(blockW))},
+				testEntry{geotiff.TagTileLength, 3, 1, shorts(uint16(blockH))},
+				testEntry{geotiff.TagTileOffsets, 4, uint32(len(offsets)), longs(offsets...)},
+				testEntry{geotiff.TagTileByteCounts, 4, uint32(len(counts)), longs(counts...)},
+			)
+		} else {
+			entries = append(entries,
+				testEntry{geotiff.TagRowsPerStrip, 3, 1, shorts(uint16(blockH))},
+				testEntry{geotiff.TagStripOffsets, 4, uint32(len(offsets)), longs(offsets...)},
+				testEntry{geotiff.TagStripByteCounts, 4, uint32(len(counts)), longs(counts...)},
+			)
+		}
+
+		if i == 0 {
+			entries = append(entries,
+				testEntry{geotiff.TagModelPixelScale, 12, 3, doubles(geo.scale, geo.scale, 0)},
+				testEntry{geotiff.TagModelTiepoint, 12, 6, doubles(0, 0, 0, geo.originX, geo.originY, 0)},
+			)
+			if len(geo.geoKeys) > 0 {
+				dir := append([]uint16{1, 1, 0, uint16(len(geo.geoKeys) / 4)}, geo.geoKeys...)
+				entries = append(entries, testEntry{geotiff.TagGeoKeyDirectory, 3, uint32(len(dir)), shorts(dir...)})
+			}
+			if geo.nodata != "" {
+				s := append([]byte(geo.nodata), 0)
+				entries = append(entries, testEntry{geotiff.TagGDALNoData, 2, uint32(len(s)), s})
+			}
+		} else {
+			entries = append(entries, testEntry{geotiff.TagNewSubfileType, 4, 1, longs(1)})
+		}
+
+		sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+		ifds = append(ifds, entries)
+	}
+
+	// the IFDs are written after the image data, each followed by its external values
+	b := buf.Bytes()
+	order.PutUint32(b[4:], uint32(len(b)))
+	for i, entries := range ifds {
+		start := buf.Len()
+		external := start + 2 + len(entries)*12 + 4
+		var values []byte
+
+		binary.Write(&buf, order, uint16(len(entries)))
+		for _, e := range entries {
+			binary.Write(&buf, order, e.tag)
+			binary.Write(&buf, order, e.typ)
+			binary.Write(&buf, order, e.count)
+			if len(e.data) <= 4 {
+				v := make([]byte, 4)
+				copy(v, e.data)
+				buf.Write(v)
+				continue
+			}
+			binary.Write(&buf, order, uint32(external+len(values)))
+			values = append(values, e.data...)
+		}
+
+		var next uint32
+		if i < len(ifds)-1 {
+			next = uint32(external + len(values))
+		}
+		binary.Write(&buf, order, next)
+		buf.Write(values)
+	}
+
+	return buf.Bytes()
+}
+
+// encodeBlock writes the samples of a block, applying the image's predictor and compression
+func encodeBlock(img testImage, order binary.ByteOrder, x0, y0, w, rows int) []byte {
+	size := img.bits / 8
+	if img.predictor == geotiff.PredictorFloatingPoint {
+		order = binary.BigEndian
+	}
+
+	data := make([]byte, w*rows*size)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < w; c++ {
+			var v float64
+			if x0+c < img.width && y0+r < img.height {
+				v = img.values[(y0+r)*img.width+x0+c]
+			}
+
+			b := data[(r*w+c)*size:]
+			switch {
+			case img.sampleFormat == geotiff.SampleFormatFloat && size == 4:
+				order.PutUint32(b, math.Float32bits(float32(v)))
+			case img.sampleFormat == geotiff.SampleFormatFloat && size == 8:
+				order.PutUint64(b, math.Float64bits(v))
+			case size == 1:
+				b[0] = byte(int8(v))
+			case size == 2:
+				order.PutUint16(b, uint16(int16(v)))
+			default:
+				order.PutUint32(b, uint32(int32(v)))
+			}
+		}
+
+		row := data[r*w*size : (r+1)*w*size]
+		switch img.predictor {
+		case geotiff.PredictorHorizontal:
+			for c := w - 1; c > 0; c-- {
+				switch size {
+				case 1:
+					row[c] -= row[c-1]
+				case 2:
+					order.PutUint16(row[c*2:], order.Uint16(row[c*2:])-order.Uint16(row[(c-1)*2:]))
+				default:
+					order.PutUint32(row[c*4:], order.Uint32(row[c*4:])-order.Uint32(row[(c-1)*4:]))
+				}
+			}
+		case geotiff.PredictorFloatingPoint:
+			planes := make([]byte, len(row))
+			for c := 0; c < w; c++ {
+				for k := 0; k < size; k++ {
+					planes[k*w+c] = row[c*size+k]
+				}
+			}
+			for i := len(planes) - 1; i > 0; i-- {
+				planes[i] -= planes[i-1]
+			}
+			copy(row, planes)
+		}
+	}
+
+	if img.compression == geotiff.CompressionNone {
+		return data
+	}
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// ramp returns the values of a width x height image where each pixel is 10*row + col
+func ramp(width, height int) []float64 {
+	vs := make([]float64, width*height)
+	for r := 0; r < height; r++ {
+		for c := 0; c < width; c++ {
+			vs[r*width+c] = float64(10*r + c)
+		}
+	}
+	return vs
+}
+
+func TestGeoTIFF(t *testing.T) {
+	type tcase struct {
+		order binary.ByteOrder
+		geo   testGeo
+		img   testImage
+		// expected pixel values at the given column and row. NaN for nodata
+		pixels     map[[2]int]float64
+		srid       uint64
+		err        error
+		overviewed bool
+	}
+
+	geo := testGeo{originX: -180, originY: 90, scale: 1, geoKeys: []uint16{geotiff.GeoKeyGeographicType, 0, 1, 4326}}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			imgs := []testImage{tc.img}
+			if tc.overviewed {
+				ov := tc.img
+				ov.width, ov.height = tc.img.width/2, tc.img.height/2
+				ov.values = make([]float64, ov.width*ov.height)
+				imgs = append(imgs, ov)
+			}
+
+			b := encodeGeoTIFF(t, tc.order, tc.geo, imgs...)
+			g, err := openGeoTIFF(bytes.NewReader(b))
+			if tc.err != nil {
+				if err != tc.err {
+					t.Fatalf("expected error %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if g.SRID != tc.srid {
+				t.Errorf("expected srid %v got %v", tc.srid, g.SRID)
+			}
+
+			if tc.overviewed {
+				if len(g.Images) != 2 {
+					t.Fatalf("expected 2 images got %v", len(g.Images))
+				}
+				if ov := g.Images[1]; ov.ScaleX != 2*tc.geo.scale || ov.OriginX != tc.geo.originX {
+					t.Errorf("expected overview scale %v and origin %v got %v and %v", 2*tc.geo.scale, tc.geo.originX, ov.ScaleX, ov.OriginX)
+				}
+				if g.Level(1.5) != g.Images[0] || g.Level(2) != g.Images[1] {
+					t.Errorf("unexpected level for resolution")
+				}
+			}
+
+			s := g.sampler(g.Images[0])
+			for p, expected := range tc.pixels {
+				v, err := s.pixel(p[0], p[1])
+				if err != nil {
+					t.Fatalf("unexpected error reading pixel %v: %v", p, err)
+				}
+				if math.IsNaN(expected) != math.IsNaN(v) || (!math.IsNaN(expected) && v != expected) {
+					t.Errorf("pixel %v: expected %v got %v", p, expected, v)
+				}
+			}
+		}
+	}
+
+	pixels := map[[2]int]float64{{0, 0}: 0, {5, 0}: 5, {0, 3}: 30, {7, 5}: 57, {9, 9}: 99}
+
+	tests := map[string]tcase{
+		"strips uncompressed int16": {
+			order:  binary.LittleEndian,
+			geo:    geo,
+			img:    testImage{width: 10, height: 10, stripRows: 3, compression: geotiff.CompressionNone, predictor: geotiff.PredictorNone, bits: 16, sampleFormat: geotiff.SampleFormatInt, values: ramp(10, 10)},
+			pixels: pixels,
+			srid:   4326,
+		},
+		"tiles deflate int16 horizontal predictor": {
+			order:  binary.LittleEndian,
+			geo:    geo,
+			img:    testImage{width: 10, height: 10, tileSize: 4, compression: geotiff.CompressionDeflate, predictor: geotiff.PredictorHorizontal, bits: 16, sampleFormat: geotiff.SampleFormatInt, values: ramp(10, 10)},
+			pixels: pixels,
+			srid:   4326,
+		},
+		"big endian tiles deflate float32 floating point predictor": {
+			order:  binary.BigEndian,
+			geo:    geo,
+			img:    testImage{width: 10, height: 10, tileSize: 8, compression: geotiff.CompressionDeflate, predictor: geotiff.PredictorFloatingPoint, bits: 32, sampleFormat: geotiff.SampleFormatFloat, values: ramp(10, 10)},
+			pixels: pixels,
+			srid:   4326,
+		},
+		"float64 web mercator nodata": {
+			order:  binary.LittleEndian,
+			geo:    testGeo{originX: 0, originY: 0, scale: 30, geoKeys: []uint16{geotiff.GeoKeyProjectedType, 0, 1, 3857}, nodata: "5"},
+			img:    testImage{width: 10, height: 10, compression: geotiff.CompressionNone, predictor: geotiff.PredictorNone, bits: 64, sampleFormat: geotiff.SampleFormatFloat, values: ramp(10, 10)},
+			pixels: map[[2]int]float64{{5, 0}: math.NaN(), {6, 0}: 6},
+			srid:   3857,
+		},
+		"overview": {
+			order:      binary.LittleEndian,
+			geo:        geo,
+			img:        testImage{width: 10, height: 10, tileSize: 4, compression: geotiff.CompressionNone, predictor: geotiff.PredictorNone, bits: 8, sampleFormat: geotiff.SampleFormatUint, values: ramp(10, 10)},
+			pixels:     pixels,
+			srid:       4326,
+			overviewed: true,
+		},
+		"unknown srid": {
+			order:  binary.LittleEndian,
+			geo:    testGeo{originX: 0, originY: 0, scale: 1},
+			img:    testImage{width: 2, height: 2, compression: geotiff.CompressionNone, predictor: geotiff.PredictorNone, bits: 8, sampleFormat: geotiff.SampleFormatUint, values: ramp(2, 2)},
+			pixels: map[[2]int]float64{{1, 1}: 11},
+		},
+		"lzw unsupported": {
+			order: binary.LittleEndian,
+			geo:   geo,
+			img:   testImage{width: 2, height: 2, compression: 5, predictor: geotiff.PredictorNone, bits: 8, sampleFormat: geotiff.SampleFormatUint, values: ramp(2, 2)},
+			err:   ErrUnsupportedCompression{Compression: 5},
+		},
+		"float16 unsupported": {
+			order: binary.LittleEndian,
+			geo:   geo,
+			img:   testImage{width: 2, height: 2, compression: geotiff.CompressionNone, predictor: geotiff.PredictorNone, bits: 16, sampleFormat: geotiff.SampleFormatFloat, values: ramp(2, 2)},
+			err:   ErrUnsupportedSampleFormat{SampleFormat: geotiff.SampleFormatFloat, BitsPerSample: 16},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestSample(t *testing.T) {
+	type tcase struct {
+		x, y     float64
+		expected float64
+	}
+
+	b := encodeGeoTIFF(t, binary.LittleEndian,
+		testGeo{originX: 0, originY: 10, scale: 1, geoKeys: []uint16{geotiff.GeoKeyGeographicType, 0, 1, 4326}},
+		testImage{width: 10, height: 10, tileSize: 4, compression: geotiff.CompressionNone, predictor: geotiff.PredictorNone, bits: 16, sampleFormat: geotiff.SampleFormatInt, values: ramp(10, 10)},
+	)
+	g, err := openGeoTIFF(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			v, err := g.sampler(g.Images[0]).sample(tc.x, tc.y)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.IsNaN(tc.expected) != math.IsNaN(v) || (!math.IsNaN(tc.expected) && math.Abs(v-tc.expected) > 1e-9) {
+				t.Errorf("expected %v got %v", tc.expected, v)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"pixel center":   {x: 2.5, y: 6.5, expected: 32},
+		"between pixels": {x: 3, y: 6, expected: 37.5},
+		"across blocks":  {x: 4, y: 6.5, expected: 33.5},
+		"edge clamped":   {x: 0.1, y: 9.9, expected: 0},
+		"outside":        {x: -1, y: 5, expected: math.NaN()},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
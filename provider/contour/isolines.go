@@ -0,0 +1,175 @@
+package contour
+
+import "math"
+
+// grid is a square grid of (n+1) x (n+1) elevations sampled row by row from the
+// top left corner. NaN values have no elevation.
+type grid struct {
+	n      int
+	values []float64
+}
+
+func (g grid) at(r, c int) float64 { return g.values[r*(g.n+1)+c] }
+
+// bounds returns the lowest and highest elevation of the grid, false when the grid has no elevations
+func (g grid) bounds() (min, max float64, ok bool) {
+	min, max = math.Inf(1), math.Inf(-1)
+	for _, v := range g.values {
+		if math.IsNaN(v) {
+			continue
+		}
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	return min, max, !math.IsInf(min, 1)
+}
+
+// cell edges, each crossing point of a contour is identified by the edge it lies on
+const (
+	edgeTop = iota
+	edgeRight
+	edgeBottom
+	edgeLeft
+)
+
+// segments holds the edges joined within a cell for each marching squares case.
+// the case is built from the corners at or above the level: top left 8, top right 4,
+// bottom right 2 and bottom left 1. The saddles (5 and 10) are resolved separately.
+var segments = [16][][2]int{
+	1:  {{edgeLeft, edgeBottom}},
+	2:  {{edgeBottom, edgeRight}},
+	3:  {{edgeLeft, edgeRight}},
+	4:  {{edgeTop, edgeRight}},
+	6:  {{edgeTop, edgeBottom}},
+	7:  {{edgeLeft, edgeTop}},
+	8:  {{edgeLeft, edgeTop}},
+	9:  {{edgeTop, edgeBottom}},
+	11: {{edgeTop, edgeRight}},
+	12: {{edgeLeft, edgeRight}},
+	13: {{edgeBottom, edgeRight}},
+	14: {{edgeLeft, edgeBottom}},
+}
+
+// isolines traces the isolines of level through the grid with marching squares. The lines
+// are returned in grid coordinates: x is the column and y the row.
+func isolines(g grid, level float64) [][][2]float64 {
+	w := g.n + 1
+
+	// edges are numbered from the point they start at: even numbers are the edges to the
+	// right of the point, odd numbers the edges below it
+	edgeID := func(r, c, edge int) int {
+		switch edge {
+		case edgeTop:
+			return 2 * (r*w + c)
+		case edgeBottom:
+			return 2 * ((r+1)*w + c)
+		case edgeLeft:
+			return 2*(r*w+c) + 1
+		default:
+			return 2*(r*w+c+1) + 1
+		}
+	}
+
+	point := func(id int) [2]float64 {
+		p := id / 2
+		r, c := p/w, p%w
+		a := g.at(r, c)
+		if id%2 == 0 {
+			b := g.at(r, c+1)
+			return [2]float64{float64(c) + (level-a)/(b-a), float64(r)}
+		}
+		b := g.at(r+1, c)
+		return [2]float64{float64(c), float64(r) + (level-a)/(b-a)}
+	}
+
+	adjacent := map[int][]int{}
+	var order []int
+	join := func(a, b int) {
+		for _, id := range [2]int{a, b} {
+			if _, ok := adjacent[id]; !ok {
+				order = append(order, id)
+			}
+		}
+		adjacent[a] = append(adjacent[a], b)
+		adjacent[b] = append(adjacent[b], a)
+	}
+
+	for r := 0; r < g.n; r++ {
+		for c := 0; c < g.n; c++ {
+			tl, tr, br, bl := g.at(r, c), g.at(r, c+1), g.at(r+1, c+1), g.at(r+1, c)
+			if math.IsNaN(tl) || math.IsNaN(tr) || math.IsNaN(br) || math.IsNaN(bl) {
+				continue
+			}
+
+			idx := 0
+			for i, v := range [4]float64{bl, br, tr, tl} {
+				if v >= level {
+					idx |= 1 << uint(i)
+				}
+			}
+
+			segs := segments[idx]
+			centerAbove := (tl+tr+br+bl)/4 >= level
+			switch {
+			case idx == 5 && centerAbove, idx == 10 && !centerAbove:
+				// the line separates the top left and bottom right corners
+				segs = [][2]int{{edgeLeft, edgeTop}, {edgeBottom, edgeRight}}
+			case idx == 5, idx == 10:
+				segs = [][2]int{{edgeTop, edgeRight}, {edgeLeft, edgeBottom}}
+			}
+
+			for _, s := range segs {
+				join(edgeID(r, c, s[0]), edgeID(r, c, s[1]))
+			}
+		}
+	}
+
+	var lines [][][2]float64
+	visited := map[int]bool{}
+	walk := func(start int) {
+		line := [][2]float64{point(start)}
+		visited[start] = true
+
+		for cur := start; ; {
+			next := -1
+			for _, id := range adjacent[cur] {
+				if !visited[id] {
+					next = id
+					break
+				}
+			}
+			if next == -1 {
+				// close rings
+				if len(line) > 2 {
+					for _, id := range adjacent[cur] {
+						if id == start {
+							line = append(line, line[0])
+							break
+						}
+					}
+				}
+				break
+			}
+			visited[next] = true
+			line = append(line, point(next))
+			cur = next
+		}
+
+		if len(line) > 1 {
+			lines = append(lines, line)
+		}
+	}
+
+	// open lines start at their ends, the remaining edges are on rings
+	for _, id := range order {
+		if !visited[id] && len(adjacent[id]) == 1 {
+			walk(id)
+		}
+	}
+	for _, id := range order {
+		if !visited[id] {
+			walk(id)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,39 @@
+package contour
+
+import (
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+)
+
+// zoomInterval is the contour interval used from a zoom on
+type zoomInterval struct {
+	minZoom  uint
+	interval float64
+}
+
+type Layer struct {
+	name string
+	// interval between contour lines, in the DEM's (scaled) elevation units
+	interval float64
+	// intervals per zoom, sorted by min zoom
+	intervals []zoomInterval
+	// every indexEvery'th contour is tagged as an index contour
+	indexEvery int
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return geom.MultiLineString{} }
+func (l Layer) SRID() uint64            { return tegola.WebMercator }
+
+// intervalAt returns the contour interval used at zoom z
+func (l Layer) intervalAt(z uint) float64 {
+	interval := l.interval
+	for _, zi := range l.intervals {
+		if zi.minZoom > z {
+			break
+		}
+		interval = zi.interval
+	}
+	return interval
+}
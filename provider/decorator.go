@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-spatial/tegola/dict"
+)
+
+// ConfigKeyDecorators is the provider config key holding the decorator chain
+const ConfigKeyDecorators = "decorators"
+
+// ConfigKeyDecoratorType is the decorator config key holding the registered decorator name
+const ConfigKeyDecoratorType = "type"
+
+// DecoratorFunc wraps a standard provider. The config is the decorator's config table.
+// The returned Tiler should pass calls it does not intercept through to next.
+type DecoratorFunc func(next Tiler, config dict.Dicter) (Tiler, error)
+
+// MVTDecoratorFunc wraps an MVT provider. The config is the decorator's config table.
+// The returned MVTTiler should pass calls it does not intercept through to next.
+type MVTDecoratorFunc func(next MVTTiler, config dict.Dicter) (MVTTiler, error)
+
+type dfns struct {
+	std DecoratorFunc
+	mvt MVTDecoratorFunc
+}
+
+var decorators map[string]dfns
+
+// RegisterDecorator registers a provider decorator with the system. This call is generally made in
+// the init functions of the decorator. Either decorate function may be nil if
+// the decorator does not support that provider type, but not both.
+func RegisterDecorator(name string, std DecoratorFunc, mvt MVTDecoratorFunc) error {
+	if std == nil && mvt == nil {
+		return ErrNilInitFunc
+	}
+	if decorators == nil {
+		decorators = make(map[string]dfns)
+	}
+
+	if _, ok := decorators[name]; ok {
+		return fmt.Errorf("provider decorator %v already exists", name)
+	}
+
+	decorators[name] = dfns{
+		std: std,
+		mvt: mvt,
+	}
+
+	return nil
+}
+
+// Decorators returns a sorted list of the registered decorators
+func Decorators() (l []string) {
+	for k := range decorators {
+		l = append(l, k)
+	}
+	sort.Strings(l)
+	return l
+}
+
+// ErrUnknownDecorator is returned when a decorator is not registered
+type ErrUnknownDecorator struct {
+	Name            string
+	KnownDecorators []string
+}
+
+func (err ErrUnknownDecorator) Error() string {
+	return fmt.Sprintf("provider: no decorator registered by the name (%v), known decorators: %v", err.Name, err.KnownDecorators)
+}
+
+// ErrDecoratorUnsupported is returned when a decorator does not support the provider type it's applied to
+type ErrDecoratorUnsupported struct {
+	Name string
+	Type providerType
+}
+
+func (err ErrDecoratorUnsupported) Error() string {
+	return fmt.Sprintf("provider: decorator (%v) does not support %v", err.Name, err.Type)
+}
+
+// Decorate wraps the provider with the chain of decorators described by configs. The first
+// decorator in the chain is the outermost, meaning it's the first to see every request.
+func Decorate(tu TilerUnion, configs []dict.Dicter) (TilerUnion, error) {
+	// wrap from the innermost decorator out
+	for i := len(configs) - 1; i >= 0; i-- {
+		name, err := configs[i].String(ConfigKeyDecoratorType, nil)
+		if err != nil {
+			return tu, err
+		}
+
+		d, ok := decorators[name]
+		if !ok {
+			return tu, ErrUnknownDecorator{Name: name, KnownDecorators: Decorators()}
+		}
+
+		switch {
+		case tu.Std != nil:
+			if d.std == nil {
+				return tu, ErrDecoratorUnsupported{Name: name, Type: TypeStd}
+			}
+			if tu.Std, err = d.std(tu.Std, configs[i]); err != nil {
+				return tu, fmt.Errorf("provider: decorator (%v): %w", name, err)
+			}
+		case tu.Mvt != nil:
+			if d.mvt == nil {
+				return tu, ErrDecoratorUnsupported{Name: name, Type: TypeMvt}
+			}
+			if tu.Mvt, err = d.mvt(tu.Mvt, configs[i]); err != nil {
+				return tu, fmt.Errorf("provider: decorator (%v): %w", name, err)
+			}
+		default:
+			return tu, ErrNilInitFunc
+		}
+	}
+
+	return tu, nil
+}
@@ -0,0 +1,125 @@
+package decorators
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/ttlcache"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const CacheType = "cache"
+
+const (
+	ConfigKeyCacheTTL        = "ttl"
+	ConfigKeyCacheMaxEntries = "max_entries"
+)
+
+const (
+	DefaultCacheTTL        = 60
+	DefaultCacheMaxEntries = 1000
+)
+
+// newLRU returns the cache of the ttl and max_entries config params
+func newLRU(config dict.Dicter) (*ttlcache.Cache, error) {
+	var err error
+
+	ttl := DefaultCacheTTL
+	if ttl, err = config.Int(ConfigKeyCacheTTL, &ttl); err != nil {
+		return nil, err
+	}
+
+	maxEntries := DefaultCacheMaxEntries
+	if maxEntries, err = config.Int(ConfigKeyCacheMaxEntries, &maxEntries); err != nil {
+		return nil, err
+	}
+
+	return ttlcache.New(maxEntries, time.Duration(ttl)*time.Second), nil
+}
+
+// Cache keeps the features of recently requested tiles in memory
+type Cache struct {
+	provider.Tiler
+	cache *ttlcache.Cache
+}
+
+// NewCache wraps the provider with a feature cache. The config supports the following params:
+//
+// 	ttl (int): [Optional] the number of seconds features are cached for. 0 means no expiration. defaults to 60
+// 	max_entries (int): [Optional] the max number of layer tiles kept in the cache. 0 means no max. defaults to 1000
+func NewCache(next provider.Tiler, config dict.Dicter) (provider.Tiler, error) {
+	c, err := newLRU(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{Tiler: next, cache: c}, nil
+}
+
+func (c *Cache) TileFeatures(ctx context.Context, lyrID string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	key := lyrID + "@" + tileKey(t)
+
+	if v, ok := c.cache.Get(key); ok {
+		for _, f := range v.([]provider.Feature) {
+			f.Tags = provider.CopyTags(f.Tags)
+
+			if err := fn(&f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var features []provider.Feature
+	err := c.Tiler.TileFeatures(ctx, lyrID, t, func(f *provider.Feature) error {
+		cf := *f
+		cf.Tags = provider.CopyTags(f.Tags)
+		features = append(features, cf)
+		return fn(f)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.cache.Set(key, features)
+	return nil
+}
+
+// MVTCache keeps recently requested MVT tiles in memory
+type MVTCache struct {
+	provider.MVTTiler
+	cache *ttlcache.Cache
+}
+
+// NewMVTCache wraps the MVT provider with a tile cache. The config is the same as NewCache
+func NewMVTCache(next provider.MVTTiler, config dict.Dicter) (provider.MVTTiler, error) {
+	c, err := newLRU(config)
+	if err != nil {
+		return nil, err
+	}
+	return &MVTCache{MVTTiler: next, cache: c}, nil
+}
+
+func (c *MVTCache) MVTForLayers(ctx context.Context, t provider.Tile, layers []provider.Layer) ([]byte, error) {
+	var key strings.Builder
+	for i := range layers {
+		key.WriteString(layers[i].ID)
+		key.WriteByte(':')
+		key.WriteString(layers[i].MVTName)
+		key.WriteByte(',')
+	}
+	key.WriteString(tileKey(t))
+
+	if v, ok := c.cache.Get(key.String()); ok {
+		return v.([]byte), nil
+	}
+
+	tile, err := c.MVTTiler.MVTForLayers(ctx, t, layers)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.Set(key.String(), tile)
+	return tile, nil
+}
@@ -0,0 +1,64 @@
+// Package decorators implements the built in provider decorators. Decorators wrap
+// a provider to add cross-cutting behavior and are declared in a provider's config:
+//
+// 	[[providers]]
+// 	name = "osm"
+// 	type = "postgis"
+//
+// 		[[providers.decorators]]
+// 		type = "metrics"
+// 		name = "osm"
+//
+// 		[[providers.decorators]]
+// 		type = "retry"
+// 		attempts = 3
+//
+// The first decorator in the chain is the first to see every request.
+package decorators
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+func init() {
+	provider.RegisterDecorator(RetryType, NewRetry, NewMVTRetry)
+	provider.RegisterDecorator(CacheType, NewCache, NewMVTCache)
+	provider.RegisterDecorator(MetricsType, NewMetrics, NewMVTMetrics)
+	provider.RegisterDecorator(FilterType, NewFilter, nil)
+}
+
+// tileKey returns a key for the tile including the buffered extent so tiles requested
+// with different buffers don't collide
+func tileKey(t provider.Tile) string {
+	z, x, y := t.ZXY()
+	ext, _ := t.BufferedExtent()
+	if ext == nil {
+		return fmt.Sprintf("%v/%v/%v", z, x, y)
+	}
+	return fmt.Sprintf("%v/%v/%v:%v", z, x, y, *ext)
+}
+
+// tagTable reads a config table of tag names to values
+func tagTable(config dict.Dicter, key string) (map[string]string, error) {
+	v, ok := config.Interface(key)
+	if !ok {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, dict.ErrKeyType{Key: key, Value: v, T: reflect.TypeOf(map[string]interface{}{})}
+	}
+
+	tags := make(map[string]string, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		tags[iter.Key().String()] = fmt.Sprint(iter.Value().Interface())
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,158 @@
+package decorators_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/decorators"
+	"github.com/go-spatial/tegola/provider/test"
+)
+
+// flakyProvider fails the first fails requests
+type flakyProvider struct {
+	test.TileProvider
+	fails    int
+	requests int
+}
+
+func (fp *flakyProvider) TileFeatures(ctx context.Context, layer string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	fp.requests++
+	if fp.requests <= fp.fails {
+		return errors.New("flaky")
+	}
+	return fp.TileProvider.TileFeatures(ctx, layer, t, fn)
+}
+
+func TestDecorate(t *testing.T) {
+	type tcase struct {
+		decorators []dict.Dicter
+		fails      int
+		// requests is the number of TileFeatures calls made by the test
+		requests int
+		features int
+		// providerRequests is the expected number of requests which reached the provider
+		providerRequests int
+		err              bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			fp := &flakyProvider{fails: tc.fails}
+
+			tu, err := provider.Decorate(provider.TilerUnion{Std: fp}, tc.decorators)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			var features int
+			for i := 0; i < tc.requests; i++ {
+				err = tu.Std.TileFeatures(context.Background(), "test-layer", provider.NewTile(0, 0, 0, 0, 3857), func(f *provider.Feature) error {
+					features++
+					// modifying cached features must not affect later requests
+					f.Tags["added"] = true
+					return nil
+				})
+			}
+			if (err != nil) != tc.err {
+				t.Errorf("expected err %v got %v", tc.err, err)
+			}
+			if features != tc.features {
+				t.Errorf("expected %v features got %v", tc.features, features)
+			}
+			if fp.requests != tc.providerRequests {
+				t.Errorf("expected %v provider requests got %v", tc.providerRequests, fp.requests)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"retry": {
+			decorators:       []dict.Dicter{dict.Dict{"type": "retry", "attempts": 3, "delay_ms": 1}},
+			fails:            2,
+			requests:         1,
+			features:         1,
+			providerRequests: 3,
+		},
+		"retry exhausted": {
+			decorators:       []dict.Dicter{dict.Dict{"type": "retry", "attempts": 2, "delay_ms": 1}},
+			fails:            2,
+			requests:         1,
+			providerRequests: 2,
+			err:              true,
+		},
+		"cache": {
+			decorators:       []dict.Dicter{dict.Dict{"type": "cache"}},
+			requests:         3,
+			features:         3,
+			providerRequests: 1,
+		},
+		"filter match": {
+			decorators:       []dict.Dicter{dict.Dict{"type": "filter", "match": map[string]interface{}{"type": "debug_buffer_outline"}}},
+			requests:         1,
+			features:         1,
+			providerRequests: 1,
+		},
+		"filter exclude": {
+			decorators:       []dict.Dicter{dict.Dict{"type": "filter", "exclude": map[string]interface{}{"type": "debug_buffer_outline"}}},
+			requests:         1,
+			providerRequests: 1,
+		},
+		"filter other layer": {
+			decorators:       []dict.Dicter{dict.Dict{"type": "filter", "layers": []string{"other"}, "exclude": map[string]interface{}{"type": "debug_buffer_outline"}}},
+			requests:         1,
+			features:         1,
+			providerRequests: 1,
+		},
+		"cache before retry": {
+			decorators: []dict.Dicter{
+				dict.Dict{"type": "cache"},
+				dict.Dict{"type": "retry", "delay_ms": 1},
+			},
+			fails:            1,
+			requests:         2,
+			features:         2,
+			providerRequests: 2,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestDecorateErrors(t *testing.T) {
+	_, err := provider.Decorate(provider.TilerUnion{Std: &test.TileProvider{}}, []dict.Dicter{dict.Dict{"type": "nope"}})
+	if _, ok := err.(provider.ErrUnknownDecorator); !ok {
+		t.Errorf("expected ErrUnknownDecorator got %v", err)
+	}
+
+	_, err = provider.Decorate(provider.TilerUnion{Mvt: &test.TileProvider{}}, []dict.Dicter{dict.Dict{"type": decorators.FilterType}})
+	if _, ok := err.(provider.ErrDecoratorUnsupported); !ok {
+		t.Errorf("expected ErrDecoratorUnsupported got %v", err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	tu, err := provider.Decorate(provider.TilerUnion{Std: &test.TileProvider{}}, []dict.Dicter{dict.Dict{"type": "metrics", "name": "test-metrics"}})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		tu.Std.TileFeatures(context.Background(), "test-layer", provider.NewTile(0, 0, 0, 0, 3857), func(f *provider.Feature) error { return nil })
+	}
+
+	for _, m := range decorators.Metrics() {
+		if m.Name != "test-metrics" {
+			continue
+		}
+		if m.Requests != 2 || m.Features != 2 || m.Errors != 0 {
+			t.Errorf("expected 2 requests, 2 features and 0 errors got %+v", m)
+		}
+		return
+	}
+	t.Errorf("metrics for test-metrics not found")
+}
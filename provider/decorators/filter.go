@@ -0,0 +1,87 @@
+package decorators
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const FilterType = "filter"
+
+const (
+	ConfigKeyFilterLayers  = "layers"
+	ConfigKeyFilterMatch   = "match"
+	ConfigKeyFilterExclude = "exclude"
+)
+
+// Filter drops features based on their tags
+type Filter struct {
+	provider.Tiler
+	// LayerIDs limits the filter to these provider layers. Empty means all layers.
+	LayerIDs map[string]struct{}
+	// Match are tags a feature must have, all must match
+	Match map[string]string
+	// Exclude are tags a feature must not have, any match drops the feature
+	Exclude map[string]string
+}
+
+// NewFilter wraps the provider with a feature filter. Tag values are compared as strings.
+// The config supports the following params:
+//
+// 	layers ([]string): [Optional] the provider layers to filter. defaults to all layers
+// 	match (table): [Optional] tags and values a feature must have to be kept
+// 	exclude (table): [Optional] tags and values which cause a feature to be dropped
+func NewFilter(next provider.Tiler, config dict.Dicter) (provider.Tiler, error) {
+	layers, err := config.StringSlice(ConfigKeyFilterLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	f := Filter{
+		Tiler:    next,
+		LayerIDs: make(map[string]struct{}, len(layers)),
+	}
+	for _, l := range layers {
+		f.LayerIDs[l] = struct{}{}
+	}
+
+	if f.Match, err = tagTable(config, ConfigKeyFilterMatch); err != nil {
+		return nil, err
+	}
+	if f.Exclude, err = tagTable(config, ConfigKeyFilterExclude); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// keep reports if the feature passes the filter
+func (f *Filter) keep(tags map[string]interface{}) bool {
+	for k, v := range f.Match {
+		tv, ok := tags[k]
+		if !ok || fmt.Sprint(tv) != v {
+			return false
+		}
+	}
+	for k, v := range f.Exclude {
+		if tv, ok := tags[k]; ok && fmt.Sprint(tv) == v {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Filter) TileFeatures(ctx context.Context, lyrID string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	if _, ok := f.LayerIDs[lyrID]; len(f.LayerIDs) != 0 && !ok {
+		return f.Tiler.TileFeatures(ctx, lyrID, t, fn)
+	}
+
+	return f.Tiler.TileFeatures(ctx, lyrID, t, func(feat *provider.Feature) error {
+		if !f.keep(feat.Tags) {
+			return nil
+		}
+		return fn(feat)
+	})
+}
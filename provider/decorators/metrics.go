@@ -0,0 +1,137 @@
+package decorators
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const MetricsType = "metrics"
+
+const ConfigKeyMetricsName = "name"
+
+// LayerMetrics are the request metrics collected for a provider layer
+type LayerMetrics struct {
+	// Name is the metrics decorator name, generally the provider name
+	Name  string `json:"name"`
+	Layer string `json:"layer"`
+	// Requests is the number of tile requests made
+	Requests uint64 `json:"requests"`
+	// Errors is the number of tile requests which returned an error
+	Errors uint64 `json:"errors"`
+	// Features is the number of features returned
+	Features uint64 `json:"features"`
+	// Duration is the total time spent in the provider in milliseconds
+	Duration int64 `json:"duration"`
+}
+
+var (
+	metricsLock sync.Mutex
+	// metrics is keyed by the metrics decorator name and layer
+	metrics = map[[2]string]*LayerMetrics{}
+)
+
+// Metrics returns the metrics collected by all metrics decorators sorted by name and layer
+func Metrics() []LayerMetrics {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	ms := make([]LayerMetrics, 0, len(metrics))
+	for _, m := range metrics {
+		ms = append(ms, *m)
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		if ms[i].Name != ms[j].Name {
+			return ms[i].Name < ms[j].Name
+		}
+		return ms[i].Layer < ms[j].Layer
+	})
+
+	return ms
+}
+
+func record(name, layer string, start time.Time, features int, err error) {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	key := [2]string{name, layer}
+	m, ok := metrics[key]
+	if !ok {
+		m = &LayerMetrics{Name: name, Layer: layer}
+		metrics[key] = m
+	}
+
+	m.Requests++
+	m.Features += uint64(features)
+	m.Duration += int64(time.Since(start) / time.Millisecond)
+	if err != nil {
+		m.Errors++
+	}
+}
+
+// MetricsTiler records request metrics per layer
+type MetricsTiler struct {
+	provider.Tiler
+	Name string
+}
+
+// NewMetrics wraps the provider with a metrics decorator. The collected metrics are
+// available from Metrics(). The config supports the following params:
+//
+// 	name (string): [Optional] the name metrics are reported under. defaults to ''
+func NewMetrics(next provider.Tiler, config dict.Dicter) (provider.Tiler, error) {
+	name := ""
+	name, err := config.String(ConfigKeyMetricsName, &name)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricsTiler{Tiler: next, Name: name}, nil
+}
+
+func (m *MetricsTiler) TileFeatures(ctx context.Context, lyrID string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	var (
+		start    = time.Now()
+		features int
+	)
+
+	err := m.Tiler.TileFeatures(ctx, lyrID, t, func(f *provider.Feature) error {
+		features++
+		return fn(f)
+	})
+
+	record(m.Name, lyrID, start, features, err)
+	return err
+}
+
+// MVTMetricsTiler records request metrics for MVT providers. Metrics are recorded per
+// request rather than per layer as MVT providers encode all layers in one query.
+type MVTMetricsTiler struct {
+	provider.MVTTiler
+	Name string
+}
+
+// NewMVTMetrics wraps the MVT provider with a metrics decorator. The config is the same as NewMetrics
+func NewMVTMetrics(next provider.MVTTiler, config dict.Dicter) (provider.MVTTiler, error) {
+	name := ""
+	name, err := config.String(ConfigKeyMetricsName, &name)
+	if err != nil {
+		return nil, err
+	}
+	return &MVTMetricsTiler{MVTTiler: next, Name: name}, nil
+}
+
+func (m *MVTMetricsTiler) MVTForLayers(ctx context.Context, t provider.Tile, layers []provider.Layer) ([]byte, error) {
+	start := time.Now()
+
+	tile, err := m.MVTTiler.MVTForLayers(ctx, t, layers)
+
+	for i := range layers {
+		record(m.Name, layers[i].ID, start, 0, err)
+	}
+	return tile, err
+}
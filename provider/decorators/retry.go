@@ -0,0 +1,125 @@
+package decorators
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const RetryType = "retry"
+
+const (
+	ConfigKeyRetryAttempts = "attempts"
+	ConfigKeyRetryDelay    = "delay_ms"
+)
+
+const (
+	DefaultRetryAttempts = 3
+	DefaultRetryDelay    = 100
+)
+
+// retry runs fn until it succeeds, the attempts are exhausted or the context is done.
+// The delay doubles after every failed attempt. shouldRetry can stop retrying early.
+func retry(ctx context.Context, attempts int, delay time.Duration, fn func() error, shouldRetry func() bool) (err error) {
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || !shouldRetry() {
+			return err
+		}
+		if i == attempts-1 {
+			break
+		}
+
+		log.Debugf("provider retry: attempt %v of %v failed: %v", i+1, attempts, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return err
+}
+
+func retryConfig(config dict.Dicter) (attempts int, delay time.Duration, err error) {
+	attempts = DefaultRetryAttempts
+	if attempts, err = config.Int(ConfigKeyRetryAttempts, &attempts); err != nil {
+		return 0, 0, err
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	ms := DefaultRetryDelay
+	if ms, err = config.Int(ConfigKeyRetryDelay, &ms); err != nil {
+		return 0, 0, err
+	}
+
+	return attempts, time.Duration(ms) * time.Millisecond, nil
+}
+
+// Retry retries failed provider requests
+type Retry struct {
+	provider.Tiler
+	Attempts int
+	Delay    time.Duration
+}
+
+// NewRetry wraps the provider with a Retry decorator. The config supports the following params:
+//
+// 	attempts (int): [Optional] the max number of attempts per request. defaults to 3
+// 	delay_ms (int): [Optional] the delay in milliseconds before retrying, doubled after each attempt. defaults to 100
+func NewRetry(next provider.Tiler, config dict.Dicter) (provider.Tiler, error) {
+	attempts, delay, err := retryConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Retry{Tiler: next, Attempts: attempts, Delay: delay}, nil
+}
+
+// TileFeatures retries the request as long as no features were passed to fn, so features are never duplicated
+func (r *Retry) TileFeatures(ctx context.Context, lyrID string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	var (
+		streamed bool
+		fnErr    error
+	)
+
+	return retry(ctx, r.Attempts, r.Delay, func() error {
+		return r.Tiler.TileFeatures(ctx, lyrID, t, func(f *provider.Feature) error {
+			streamed = true
+			fnErr = fn(f)
+			return fnErr
+		})
+	}, func() bool { return !streamed && fnErr == nil })
+}
+
+// MVTRetry retries failed MVT provider requests
+type MVTRetry struct {
+	provider.MVTTiler
+	Attempts int
+	Delay    time.Duration
+}
+
+// NewMVTRetry wraps the MVT provider with a retry decorator. The config is the same as NewRetry
+func NewMVTRetry(next provider.MVTTiler, config dict.Dicter) (provider.MVTTiler, error) {
+	attempts, delay, err := retryConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &MVTRetry{MVTTiler: next, Attempts: attempts, Delay: delay}, nil
+}
+
+func (r *MVTRetry) MVTForLayers(ctx context.Context, t provider.Tile, layers []provider.Layer) (tile []byte, err error) {
+	err = retry(ctx, r.Attempts, r.Delay, func() (err error) {
+		tile, err = r.MVTTiler.MVTForLayers(ctx, t, layers)
+		return err
+	}, func() bool { return true })
+	return tile, err
+}
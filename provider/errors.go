@@ -81,3 +81,24 @@ type ErrInvalidRegisteredProvider struct {
 func (err ErrInvalidRegisteredProvider) Error() string {
 	return fmt.Sprintf("provider %v did not register correctly, nil init functions registered", err.Name)
 }
+
+// ErrPlugin is returned when a provider plugin can't be loaded
+type ErrPlugin struct {
+	Path string
+	Err  error
+}
+
+func (err ErrPlugin) Error() string {
+	return fmt.Sprintf("provider plugin (%v): %v", err.Path, err.Err)
+}
+
+// ErrSourceStatus is returned when the url of a polled source responds with a status other
+// than 200 or 304
+type ErrSourceStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrSourceStatus) Error() string {
+	return fmt.Sprintf("provider: url (%v) responded with status %v", e.URL, e.Status)
+}
@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"math"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+)
+
+// MaxLat is the latitude limit of web mercator
+const MaxLat = 85.0511287798066
+
+// QueryExtent returns the tile's buffered extent in the srid of a layer. The extents of web
+// mercator tiles are reprojected for WGS84 layers and clamped to the valid coordinates, as the
+// buffered extents of the edge tiles reach past the poles. Other srids get the extent as is.
+func QueryExtent(tile Tile, srid uint64) (*geom.Extent, error) {
+	ext, tileSRID := tile.BufferedExtent()
+	if srid != tegola.WGS84 || tileSRID == tegola.WGS84 {
+		return ext, nil
+	}
+
+	min, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MinX(), ext.MinY()})
+	if err != nil {
+		return nil, err
+	}
+	max, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MaxX(), ext.MaxY()})
+	if err != nil {
+		return nil, err
+	}
+	minPt, maxPt := min.(geom.Point), max.(geom.Point)
+
+	clamp := func(v, limit float64) float64 { return math.Max(-limit, math.Min(limit, v)) }
+	return &geom.Extent{
+		clamp(minPt.X(), 180), clamp(minPt.Y(), MaxLat),
+		clamp(maxPt.X(), 180), clamp(maxPt.Y(), MaxLat),
+	}, nil
+}
+
+// ExtentsOverlap reports if the extents intersect or touch. Unlike geom.Extent.Intersect it
+// matches the empty extents of points.
+func ExtentsOverlap(a, b *geom.Extent) bool {
+	return a.MinX() <= b.MaxX() && a.MaxX() >= b.MinX() && a.MinY() <= b.MaxY() && a.MaxY() >= b.MinY()
+}
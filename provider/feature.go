@@ -1,7 +1,11 @@
 package provider
 
 import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"strconv"
+	"strings"
 
 	"github.com/go-spatial/geom"
 )
@@ -40,3 +44,64 @@ func ConvertFeatureID(v interface{}) (uint64, error) {
 		return 0, ErrUnableToConvertFeatureID{val: v}
 	}
 }
+
+// FeatureID returns the id of a feature from a decoded JSON or string id. Unsigned integer ids
+// are used as is, other ids are hashed. 0 is returned for a missing (nil) id.
+func FeatureID(id interface{}) uint64 {
+	var s string
+	switch v := id.(type) {
+	case nil:
+		return 0
+	case float64:
+		if v >= 0 && v == float64(uint64(v)) {
+			return uint64(v)
+		}
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n
+	}
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// GeometryType returns the geometry for a geometry_type config value of a layer. nil is
+// returned for an empty value, which keeps the features of any geometry type.
+func GeometryType(s string) (geom.Geometry, bool) {
+	switch strings.ToLower(s) {
+	case "":
+		return nil, true
+	case "point":
+		return geom.Point{}, true
+	case "multipoint":
+		return geom.MultiPoint{}, true
+	case "linestring":
+		return geom.LineString{}, true
+	case "multilinestring":
+		return geom.MultiLineString{}, true
+	case "polygon":
+		return geom.Polygon{}, true
+	case "multipolygon":
+		return geom.MultiPolygon{}, true
+	default:
+		return nil, false
+	}
+}
+
+// CopyTags returns a copy of the tags of a feature shared between tiles, i.e. a cached
+// feature, as callers are allowed to modify the tags
+func CopyTags(tags map[string]interface{}) map[string]interface{} {
+	cp := make(map[string]interface{}, len(tags))
+	for k, v := range tags {
+		cp[k] = v
+	}
+	return cp
+}
@@ -0,0 +1,78 @@
+package provider_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+func TestFeatureID(t *testing.T) {
+	type tcase struct {
+		id       interface{}
+		expected uint64
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			if id := provider.FeatureID(tc.id); id != tc.expected {
+				t.Errorf("expected %v got %v", tc.expected, id)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"nil":         {id: nil, expected: 0},
+		"number":      {id: float64(42), expected: 42},
+		"json number": {id: json.Number("42"), expected: 42},
+		"string":      {id: "42", expected: 42},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+
+	if provider.FeatureID("bus-1") != provider.FeatureID("bus-1") {
+		t.Errorf("expected stable ids for the same string")
+	}
+	if provider.FeatureID("bus-1") == provider.FeatureID("bus-2") {
+		t.Errorf("expected distinct ids for distinct strings")
+	}
+	if provider.FeatureID(-1.5) != provider.FeatureID("-1.5") {
+		t.Errorf("expected the id of a fraction to be the id of its string")
+	}
+}
+
+func TestGeometryType(t *testing.T) {
+	type tcase struct {
+		geometryType string
+		expected     geom.Geometry
+		ok           bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			g, ok := provider.GeometryType(tc.geometryType)
+			if ok != tc.ok {
+				t.Fatalf("ok, expected %v got %v", tc.ok, ok)
+			}
+			if !reflect.DeepEqual(g, tc.expected) {
+				t.Errorf("expected %T got %T", tc.expected, g)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"any":          {geometryType: "", ok: true},
+		"point":        {geometryType: "Point", expected: geom.Point{}, ok: true},
+		"multipolygon": {geometryType: "multipolygon", expected: geom.MultiPolygon{}, ok: true},
+		"invalid":      {geometryType: "circle"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
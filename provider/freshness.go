@@ -0,0 +1,14 @@
+package provider
+
+import (
+	"context"
+	"time"
+)
+
+// Freshness is implemented by providers which can report when the data of a layer was last
+// updated. It's used to monitor the freshness SLAs of map layers.
+type Freshness interface {
+	// LayerUpdated returns the time the data of the provider layer was last updated. The zero
+	// time is returned when the layer has not been updated yet.
+	LayerUpdated(ctx context.Context, layerID string) (time.Time, error)
+}
@@ -0,0 +1,263 @@
+package georss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// feed matches the items of RSS 2.0 and RSS 1.0 feeds and the entries of Atom feeds
+type feed struct {
+	Channel struct {
+		Items []entry `xml:"item"`
+	} `xml:"channel"`
+	Items   []entry `xml:"item"`
+	Entries []entry `xml:"entry"`
+}
+
+type entry struct {
+	Title       string `xml:"title"`
+	Links       []text `xml:"link"`
+	Description string `xml:"description"`
+	Summary     string `xml:"summary"`
+	GUID        string `xml:"guid"`
+	ID          string `xml:"id"`
+	PubDate     string `xml:"pubDate"`
+	Published   string `xml:"published"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Updated     string `xml:"updated"`
+	Categories  []text `xml:"category"`
+
+	// GeoRSS Simple
+	Point   string `xml:"http://www.georss.org/georss point"`
+	Line    string `xml:"http://www.georss.org/georss line"`
+	Polygon string `xml:"http://www.georss.org/georss polygon"`
+	Box     string `xml:"http://www.georss.org/georss box"`
+
+	// GeoRSS GML
+	Where struct {
+		Point struct {
+			Pos string `xml:"http://www.opengis.net/gml pos"`
+		} `xml:"http://www.opengis.net/gml Point"`
+		LineString struct {
+			PosList string `xml:"http://www.opengis.net/gml posList"`
+		} `xml:"http://www.opengis.net/gml LineString"`
+		Polygon struct {
+			PosList string `xml:"http://www.opengis.net/gml exterior>LinearRing>posList"`
+		} `xml:"http://www.opengis.net/gml Polygon"`
+		Envelope struct {
+			Lower string `xml:"http://www.opengis.net/gml lowerCorner"`
+			Upper string `xml:"http://www.opengis.net/gml upperCorner"`
+		} `xml:"http://www.opengis.net/gml Envelope"`
+	} `xml:"http://www.georss.org/georss where"`
+
+	// W3C Basic Geo, directly in the item or in a geo:Point
+	Lat      string `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# lat"`
+	Long     string `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# long"`
+	GeoPoint struct {
+		Lat  string `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# lat"`
+		Long string `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# long"`
+	} `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# Point"`
+}
+
+// text is an element whose value is its text, as in RSS, or an attribute, as in Atom
+type text struct {
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr"`
+	Term  string `xml:"term,attr"`
+	Value string `xml:",chardata"`
+}
+
+// decode returns the features of the feed's items with a location. Items without a location
+// or with an invalid one are skipped. Feature IDs are the item's guid, id or link, hashed
+// unless numeric, or its 1 based position in the feed.
+func decode(body []byte) ([]provider.PolledItem, error) {
+	var f feed
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	// feeds are mostly utf-8, and the text of the tags is passed through as is otherwise
+	dec.CharsetReader = func(charset string, r io.Reader) (io.Reader, error) { return r, nil }
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("georss: decoding: %v", err)
+	}
+
+	entries := append(append(f.Channel.Items, f.Items...), f.Entries...)
+
+	var items []provider.PolledItem
+	for i, e := range entries {
+		g, ok := e.geometry()
+		if !ok {
+			continue
+		}
+		ext, err := geom.NewExtentFromGeometry(g)
+		if err != nil {
+			continue
+		}
+
+		id := uint64(i + 1)
+		if key := first(e.GUID, e.ID, e.link()); key != "" {
+			id = provider.FeatureID(key)
+		}
+
+		items = append(items, provider.PolledItem{
+			Feature: provider.Feature{
+				ID:       id,
+				Geometry: g,
+				SRID:     tegola.WGS84,
+				Tags:     e.tags(),
+			},
+			Extent: *ext,
+		})
+	}
+
+	return items, nil
+}
+
+// geometry returns the first location of the item
+func (e entry) geometry() (geom.Geometry, bool) {
+	w := e.Where
+	switch {
+	case strings.TrimSpace(e.Point) != "":
+		return point(e.Point)
+	case strings.TrimSpace(e.Line) != "":
+		return lineString(e.Line)
+	case strings.TrimSpace(e.Polygon) != "":
+		return polygon(e.Polygon)
+	case strings.TrimSpace(e.Box) != "":
+		return box(e.Box)
+	case strings.TrimSpace(w.Point.Pos) != "":
+		return point(w.Point.Pos)
+	case strings.TrimSpace(w.LineString.PosList) != "":
+		return lineString(w.LineString.PosList)
+	case strings.TrimSpace(w.Polygon.PosList) != "":
+		return polygon(w.Polygon.PosList)
+	case strings.TrimSpace(w.Envelope.Lower) != "":
+		return box(w.Envelope.Lower + " " + w.Envelope.Upper)
+	case strings.TrimSpace(e.Lat) != "":
+		return point(e.Lat + " " + e.Long)
+	case strings.TrimSpace(e.GeoPoint.Lat) != "":
+		return point(e.GeoPoint.Lat + " " + e.GeoPoint.Long)
+	default:
+		return nil, false
+	}
+}
+
+// link returns the url of the item, the alternate link of Atom entries
+func (e entry) link() string {
+	for _, l := range e.Links {
+		if v := strings.TrimSpace(l.Value); v != "" {
+			return v
+		}
+		if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// tags returns the tags of the item's title, link, description, dates and categories
+func (e entry) tags() map[string]interface{} {
+	tags := map[string]interface{}{}
+	setString := func(k, v string) {
+		if v = strings.TrimSpace(v); v != "" {
+			tags[k] = v
+		}
+	}
+
+	setString("title", e.Title)
+	setString("link", e.link())
+	setString("description", first(e.Description, e.Summary))
+	setString("published", first(e.PubDate, e.Published, e.Date))
+	setString("updated", e.Updated)
+
+	var categories []string
+	for _, c := range e.Categories {
+		if v := first(c.Value, c.Term); v != "" {
+			categories = append(categories, v)
+		}
+	}
+	setString("category", strings.Join(categories, ","))
+
+	return tags
+}
+
+// coords parses the "lat lon" pairs of GeoRSS and GML coordinates into lon / lat points.
+// false is returned for an odd number of values or coordinates out of range.
+func coords(s string) ([][2]float64, bool) {
+	vals := strings.Fields(strings.Replace(s, ",", " ", -1))
+	if len(vals) == 0 || len(vals)%2 != 0 {
+		return nil, false
+	}
+
+	pts := make([][2]float64, 0, len(vals)/2)
+	for i := 0; i < len(vals); i += 2 {
+		lat, err := strconv.ParseFloat(vals[i], 64)
+		if err != nil {
+			return nil, false
+		}
+		lon, err := strconv.ParseFloat(vals[i+1], 64)
+		if err != nil {
+			return nil, false
+		}
+		if lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+			return nil, false
+		}
+		pts = append(pts, [2]float64{lon, lat})
+	}
+	return pts, true
+}
+
+func point(s string) (geom.Geometry, bool) {
+	pts, ok := coords(s)
+	if !ok || len(pts) != 1 {
+		return nil, false
+	}
+	return geom.Point(pts[0]), true
+}
+
+func lineString(s string) (geom.Geometry, bool) {
+	pts, ok := coords(s)
+	if !ok || len(pts) < 2 {
+		return nil, false
+	}
+	return geom.LineString(pts), true
+}
+
+// polygon returns the polygon of the ring, without its closing point
+func polygon(s string) (geom.Geometry, bool) {
+	pts, ok := coords(s)
+	if ok && len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+	if !ok || len(pts) < 3 {
+		return nil, false
+	}
+	return geom.Polygon{pts}, true
+}
+
+// box returns the polygon of the lower and upper corners of a box
+func box(s string) (geom.Geometry, bool) {
+	pts, ok := coords(s)
+	if !ok || len(pts) != 2 {
+		return nil, false
+	}
+	min, max := pts[0], pts[1]
+	return geom.Polygon{{min, {max[0], min[1]}, max, {min[0], max[1]}}}, true
+}
+
+// first returns the first of the values which isn't empty
+func first(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
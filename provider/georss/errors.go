@@ -0,0 +1,53 @@
+package georss
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingLayerName = errors.New("georss: layer is missing 'name'")
+)
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("georss: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+// ErrPathOrURL is returned when a layer defines both or neither of 'path' and 'url'
+type ErrPathOrURL struct {
+	LayerName string
+}
+
+func (e ErrPathOrURL) Error() string {
+	return fmt.Sprintf("georss: layer (%v) must define either 'path' or 'url'", e.LayerName)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("georss: layer (%v) has invalid geometry_type (%v), expected point, linestring or polygon", e.LayerName, e.GeometryType)
+}
+
+type ErrInvalidInterval struct {
+	LayerName string
+	Interval  int
+}
+
+func (e ErrInvalidInterval) Error() string {
+	return fmt.Sprintf("georss: layer (%v) has invalid interval (%v), expected 0 or more seconds", e.LayerName, e.Interval)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("georss: layer (%v) not found", e.LayerName)
+}
@@ -0,0 +1,283 @@
+// Package georss provides a provider for RSS and Atom feeds with GeoRSS or W3C Basic Geo
+// locations, read from a local path or a url. Each layer serves the located items of a feed,
+// which is checked for changes on an interval, so feed based data such as incidents can be
+// overlaid without converting it first.
+package georss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "georss"
+
+const (
+	ConfigKeyTimeout = "timeout"
+	ConfigKeyLayers  = "layers"
+
+	ConfigKeyLayerName = "name"
+	ConfigKeyPath      = "path"
+	ConfigKeyURL       = "url"
+	ConfigKeyInterval  = "interval"
+	ConfigKeyGeomType  = "geometry_type"
+)
+
+const (
+	DefaultTimeout  = 30
+	DefaultInterval = 300
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// Provider serves the located items of GeoRSS feeds
+type Provider struct {
+	client *http.Client
+
+	// map of layer name and corresponding feed
+	layers map[string]Layer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// providers are tracked so their pollers can be stopped during cleanup
+var (
+	providersLock sync.Mutex
+	providers     []*Provider
+)
+
+// NewTileProvider instantiates and returns a new georss provider or an error.
+// Local feeds are read when the provider is created, urls by the layer's poller. When a
+// later read fails the layer keeps its items.
+//
+//	timeout (int): [Optional] the number of seconds allowed per url request. defaults to 30
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		path (string): [*Required] the path of a local feed
+//		url (string): [*Required] the url of a remote feed
+//		interval (int): [Optional] the number of seconds between checking the feed for changes. 0 reads it once. defaults to 300
+//		geometry_type (string): [Optional] only serve the items with point, linestring or polygon locations
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	timeout := DefaultTimeout
+	timeout, err := config.Int(ConfigKeyTimeout, &timeout)
+	if err != nil {
+		return nil, err
+	}
+	if timeout < 0 {
+		return nil, fmt.Errorf("georss: %v must not be negative, got %v", ConfigKeyTimeout, timeout)
+	}
+
+	p := Provider{
+		client: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		layers: map[string]Layer{},
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+	for _, l := range p.layers {
+		if !l.source.Polled() {
+			continue
+		}
+		p.wg.Add(1)
+		go func(l Layer) {
+			defer p.wg.Done()
+			l.source.Poll(ctx, p.client, func(err error) {
+				log.Errorf("georss: layer (%v) refresh failed, keeping the last features: %v", l.name, err)
+			})
+		}(l)
+	}
+
+	providersLock.Lock()
+	providers = append(providers, &p)
+	providersLock.Unlock()
+
+	return &p, nil
+}
+
+// AddLayer adds a feed layer to the provider. Local feeds are read before the layer is added.
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	strs := []struct {
+		key string
+		val string
+	}{
+		{ConfigKeyPath, ""},
+		{ConfigKeyURL, ""},
+		{ConfigKeyGeomType, ""},
+	}
+	for i := range strs {
+		if strs[i].val, err = layerConf.String(strs[i].key, &strs[i].val); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, strs[i].key, err)
+		}
+	}
+	path, url, gtype := strs[0].val, strs[1].val, strs[2].val
+
+	if (path == "") == (url == "") {
+		return ErrPathOrURL{LayerName: name}
+	}
+
+	geomType, ok := provider.GeometryType(gtype)
+	switch geomType.(type) {
+	case geom.MultiPoint, geom.MultiLineString, geom.MultiPolygon:
+		// multi geometries match the single geometry types of the layers
+		ok = false
+	}
+	if !ok {
+		return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+	}
+
+	interval := DefaultInterval
+	if interval, err = layerConf.Int(ConfigKeyInterval, &interval); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyInterval, err)
+	}
+	if interval < 0 {
+		return ErrInvalidInterval{LayerName: name, Interval: interval}
+	}
+
+	l := Layer{
+		name:     name,
+		geomType: geomType,
+		source: &provider.PolledSource{
+			Path:     path,
+			URL:      url,
+			Accept:   "application/rss+xml, application/atom+xml, application/xml, text/xml",
+			Interval: time.Duration(interval) * time.Second,
+			Decode: func(body []byte) ([]provider.PolledItem, error) {
+				items, err := decode(body)
+				if err != nil || geomType == nil {
+					return items, err
+				}
+				return filterGeomType(items, geomType), nil
+			},
+		},
+	}
+
+	if path != "" {
+		if err := l.source.Refresh(context.Background(), p.client); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyPath, err)
+		}
+	}
+
+	p.layers[name] = l
+
+	return nil
+}
+
+// filterGeomType returns the items whose geometries are of the geometry type
+func filterGeomType(items []provider.PolledItem, geomType geom.Geometry) []provider.PolledItem {
+	filtered := items[:0]
+	for _, it := range items {
+		if reflect.TypeOf(it.Feature.Geometry) == reflect.TypeOf(geomType) {
+			filtered = append(filtered, it)
+		}
+	}
+	return filtered
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures streams the layer's features intersecting the tile's buffered extent
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	return layer.source.TileFeatures(ctx, tile, fn)
+}
+
+// LayerUpdated returns the modification time of the layer's feed. The zero time is returned
+// until a url has been fetched.
+func (p *Provider) LayerUpdated(ctx context.Context, lyrID string) (time.Time, error) {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return time.Time{}, ErrLayerNotFound{LayerName: lyrID}
+	}
+	_, updated := layer.source.Items()
+	return updated, nil
+}
+
+// Close stops the layer pollers
+func (p *Provider) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// Cleanup will stop the pollers of all the providers and remove the providers from the list
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if len(providers) > 0 {
+		log.Infof("cleaning up georss providers")
+	}
+
+	for i := range providers {
+		providers[i].Close()
+	}
+
+	providers = nil
+}
@@ -0,0 +1,194 @@
+package georss_test
+
+import (
+	"context"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/georss"
+)
+
+// tileFeatures returns the features of the north west quarter of the world, without Paris
+func tileFeatures(t *testing.T, p provider.Tiler, layer string) []provider.Feature {
+	var features []provider.Feature
+	err := p.TileFeatures(context.Background(), layer, provider.NewTile(1, 0, 0, 0, tegola.WebMercator), func(f *provider.Feature) error {
+		features = append(features, *f)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return features
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		layer    map[string]interface{}
+		expected []provider.Feature
+	}
+
+	feature := func(id uint64, g geom.Geometry, tags map[string]interface{}) provider.Feature {
+		return provider.Feature{ID: id, Geometry: g, SRID: tegola.WGS84, Tags: tags}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer["name"] = "test"
+			tc.layer["interval"] = 0
+			p, err := georss.NewTileProvider(dict.Dict{
+				"layers": []map[string]interface{}{tc.layer},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer p.(*georss.Provider).Close()
+
+			features := tileFeatures(t, p, "test")
+			if !reflect.DeepEqual(features, tc.expected) {
+				t.Errorf("features, expected %+v got %+v", tc.expected, features)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"rss": {
+			layer: map[string]interface{}{"path": "testdata/incidents.rss"},
+			expected: []provider.Feature{
+				feature(1001, geom.Point{-122.4, 37.8}, map[string]interface{}{
+					"title":       "Road closed",
+					"link":        "https://example.com/incidents/1",
+					"description": "Landslide",
+					"published":   "Fri, 01 May 2020 10:00:00 GMT",
+					"category":    "roads,closure",
+				}),
+				feature(hash("https://example.com/incidents/2"), geom.LineString{{-122.4, 37.8}, {-122.3, 37.9}}, map[string]interface{}{"title": "Detour"}),
+				feature(3, geom.Polygon{{{-122.0, 37.0}, {-122.0, 37.5}, {-121.5, 37.5}}}, map[string]interface{}{"title": "Fire area"}),
+				feature(4, geom.Point{-0.1, 51.5}, map[string]interface{}{"title": "Sensor"}),
+			},
+		},
+		"rss points": {
+			layer: map[string]interface{}{"path": "testdata/incidents.rss", "geometry_type": "point"},
+			expected: []provider.Feature{
+				feature(1001, geom.Point{-122.4, 37.8}, map[string]interface{}{
+					"title":       "Road closed",
+					"link":        "https://example.com/incidents/1",
+					"description": "Landslide",
+					"published":   "Fri, 01 May 2020 10:00:00 GMT",
+					"category":    "roads,closure",
+				}),
+				feature(4, geom.Point{-0.1, 51.5}, map[string]interface{}{"title": "Sensor"}),
+			},
+		},
+		"atom": {
+			layer: map[string]interface{}{"path": "testdata/earthquakes.atom"},
+			expected: []provider.Feature{
+				feature(hash("urn:quake:1"), geom.Point{-122.5, 37.7}, map[string]interface{}{
+					"title":       "M 4.2",
+					"link":        "https://example.com/quakes/1",
+					"description": "Near the coast",
+					"updated":     "2020-05-01T10:00:00Z",
+					"category":    "earthquake",
+				}),
+				feature(hash("urn:quake:2"), geom.Polygon{{{-123.0, 37.0}, {-122.0, 37.0}, {-122.0, 38.0}, {-123.0, 38.0}}}, map[string]interface{}{"title": "Aftershock zone"}),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestURL(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/earthquakes.atom")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	modified := time.Date(2020, 5, 1, 11, 0, 0, 0, time.UTC)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	p, err := georss.NewTileProvider(dict.Dict{
+		"layers": []map[string]interface{}{{
+			"name":     "test",
+			"url":      srv.URL,
+			"interval": 0,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer p.(*georss.Provider).Close()
+
+	// the url is fetched by the layer's poller
+	var updated time.Time
+	for deadline := time.Now().Add(5 * time.Second); updated.IsZero() && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if updated, err = p.(*georss.Provider).LayerUpdated(context.Background(), "test"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !updated.Equal(modified) {
+		t.Errorf("updated, expected %v got %v", modified, updated)
+	}
+
+	if features := tileFeatures(t, p, "test"); len(features) != 2 {
+		t.Errorf("features, expected 2 got %v", len(features))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		layer map[string]interface{}
+		err   string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer["name"] = "test"
+			_, err := georss.NewTileProvider(dict.Dict{
+				"layers": []map[string]interface{}{tc.layer},
+			})
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing path and url": {
+			layer: map[string]interface{}{},
+			err:   "georss: layer (test) must define either 'path' or 'url'",
+		},
+		"invalid geometry type": {
+			layer: map[string]interface{}{"path": "testdata/incidents.rss", "geometry_type": "multipoint"},
+			err:   "georss: layer (test) has invalid geometry_type (multipoint), expected point, linestring or polygon",
+		},
+		"invalid feed": {
+			layer: map[string]interface{}{"path": "testdata/truncated.rss"},
+			err:   "for layer (test) path has an error: georss: decoding: XML syntax error on line 2: unexpected EOF",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,21 @@
+package georss
+
+import (
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/provider"
+)
+
+type Layer struct {
+	name string
+	// geomType filters the items of the feed by geometry type when set
+	geomType geom.Geometry
+	// source is the layer's local or remote feed
+	source *provider.PolledSource
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return tegola.WGS84 }
@@ -227,6 +227,11 @@ func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider
 	return nil
 }
 
+// Ping checks the geopackage can be queried
+func (p *Provider) Ping(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
 // Close will close the Provider's database connection
 func (p *Provider) Close() error {
 	return p.db.Close()
@@ -0,0 +1,193 @@
+package gpx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// the elements of GPX 1.0 and 1.1 files. The namespaces are ignored so both versions decode.
+type gpxFile struct {
+	Waypoints []gpxPoint `xml:"wpt"`
+	Routes    []gpxRoute `xml:"rte"`
+	Tracks    []gpxTrack `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Lat  float64  `xml:"lat,attr"`
+	Lon  float64  `xml:"lon,attr"`
+	Ele  *float64 `xml:"ele"`
+	Time string   `xml:"time"`
+	Name string   `xml:"name"`
+	Cmt  string   `xml:"cmt"`
+	Desc string   `xml:"desc"`
+	Sym  string   `xml:"sym"`
+	Type string   `xml:"type"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name"`
+	Cmt    string     `xml:"cmt"`
+	Desc   string     `xml:"desc"`
+	Type   string     `xml:"type"`
+	Number *int       `xml:"number"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name     string `xml:"name"`
+	Cmt      string `xml:"cmt"`
+	Desc     string `xml:"desc"`
+	Type     string `xml:"type"`
+	Number   *int   `xml:"number"`
+	Segments []struct {
+		Points []gpxPoint `xml:"trkpt"`
+	} `xml:"trkseg"`
+}
+
+// valid reports if the point's coordinates are in range
+func (pt gpxPoint) valid() bool {
+	return pt.Lon >= -180 && pt.Lon <= 180 && pt.Lat >= -90 && pt.Lat <= 90
+}
+
+// decode returns the features of the gpx file. Feature IDs are the 1 based positions of the
+// waypoints, routes, tracks or track points in the file.
+func decode(body []byte, features string) ([]provider.PolledItem, error) {
+	var f gpxFile
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	// gpx files are utf-8, but some devices declare other encodings
+	dec.CharsetReader = func(charset string, r io.Reader) (io.Reader, error) { return r, nil }
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("gpx: decoding: %v", err)
+	}
+
+	var items []provider.PolledItem
+	add := func(g geom.Geometry, tags map[string]interface{}) {
+		ext, err := geom.NewExtentFromGeometry(g)
+		if err != nil {
+			return
+		}
+		items = append(items, provider.PolledItem{
+			Feature: provider.Feature{
+				ID:       uint64(len(items) + 1),
+				Geometry: g,
+				SRID:     tegola.WGS84,
+				Tags:     tags,
+			},
+			Extent: *ext,
+		})
+	}
+
+	switch features {
+	case FeaturesWaypoints:
+		for _, pt := range f.Waypoints {
+			if pt.valid() {
+				add(geom.Point{pt.Lon, pt.Lat}, pointTags(pt))
+			}
+		}
+
+	case FeaturesRoutes:
+		for _, rte := range f.Routes {
+			line := lineString(rte.Points)
+			if len(line) < 2 {
+				continue
+			}
+			add(line, describedTags(rte.Name, rte.Cmt, rte.Desc, rte.Type, rte.Number))
+		}
+
+	case FeaturesTrackPoints:
+		for _, trk := range f.Tracks {
+			for i, seg := range trk.Segments {
+				for _, pt := range seg.Points {
+					if !pt.valid() {
+						continue
+					}
+					tags := pointTags(pt)
+					setString(tags, "track", trk.Name)
+					tags["segment"] = i
+					add(geom.Point{pt.Lon, pt.Lat}, tags)
+				}
+			}
+		}
+
+	default:
+		for _, trk := range f.Tracks {
+			var (
+				mls        geom.MultiLineString
+				start, end string
+			)
+			for _, seg := range trk.Segments {
+				if line := lineString(seg.Points); len(line) >= 2 {
+					mls = append(mls, line)
+				}
+				for _, pt := range seg.Points {
+					if pt.Time == "" {
+						continue
+					}
+					if start == "" {
+						start = pt.Time
+					}
+					end = pt.Time
+				}
+			}
+			if len(mls) == 0 {
+				continue
+			}
+
+			tags := describedTags(trk.Name, trk.Cmt, trk.Desc, trk.Type, trk.Number)
+			setString(tags, "start_time", start)
+			setString(tags, "end_time", end)
+			add(mls, tags)
+		}
+	}
+
+	return items, nil
+}
+
+// lineString returns the line of the valid points
+func lineString(pts []gpxPoint) geom.LineString {
+	line := make(geom.LineString, 0, len(pts))
+	for _, pt := range pts {
+		if pt.valid() {
+			line = append(line, [2]float64{pt.Lon, pt.Lat})
+		}
+	}
+	return line
+}
+
+func pointTags(pt gpxPoint) map[string]interface{} {
+	tags := describedTags(pt.Name, pt.Cmt, pt.Desc, pt.Type, nil)
+	setString(tags, "sym", pt.Sym)
+	setString(tags, "time", pt.Time)
+	if pt.Ele != nil {
+		tags["ele"] = *pt.Ele
+	}
+	return tags
+}
+
+// describedTags returns the tags of the descriptive elements shared by gpx points, routes and tracks
+func describedTags(name, cmt, desc, typ string, number *int) map[string]interface{} {
+	tags := map[string]interface{}{}
+	setString(tags, "name", name)
+	setString(tags, "cmt", cmt)
+	setString(tags, "desc", desc)
+	setString(tags, "type", typ)
+	if number != nil {
+		tags["number"] = *number
+	}
+	return tags
+}
+
+// setString sets the tag when the value isn't empty
+func setString(tags map[string]interface{}, k, v string) {
+	if v = strings.TrimSpace(v); v != "" {
+		tags[k] = v
+	}
+}
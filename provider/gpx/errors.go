@@ -0,0 +1,53 @@
+package gpx
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingLayerName = errors.New("gpx: layer is missing 'name'")
+)
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("gpx: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+// ErrPathOrURL is returned when a layer defines both or neither of 'path' and 'url'
+type ErrPathOrURL struct {
+	LayerName string
+}
+
+func (e ErrPathOrURL) Error() string {
+	return fmt.Sprintf("gpx: layer (%v) must define either 'path' or 'url'", e.LayerName)
+}
+
+type ErrInvalidFeatures struct {
+	LayerName string
+	Features  string
+}
+
+func (e ErrInvalidFeatures) Error() string {
+	return fmt.Sprintf("gpx: layer (%v) has invalid features (%v), expected tracks, track_points, routes or waypoints", e.LayerName, e.Features)
+}
+
+type ErrInvalidInterval struct {
+	LayerName string
+	Interval  int
+}
+
+func (e ErrInvalidInterval) Error() string {
+	return fmt.Sprintf("gpx: layer (%v) has invalid interval (%v), expected 0 or more seconds", e.LayerName, e.Interval)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("gpx: layer (%v) not found", e.LayerName)
+}
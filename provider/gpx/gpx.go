@@ -0,0 +1,264 @@
+// Package gpx provides a provider for GPX files, read from a local path or a url. Each layer
+// serves the tracks, track points, routes or waypoints of a file. Files are checked for changes
+// on an interval, so tracks recorded in the field can be overlaid as they're synced.
+package gpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "gpx"
+
+const (
+	ConfigKeyTimeout = "timeout"
+	ConfigKeyLayers  = "layers"
+
+	ConfigKeyLayerName = "name"
+	ConfigKeyPath      = "path"
+	ConfigKeyURL       = "url"
+	ConfigKeyFeatures  = "features"
+	ConfigKeyInterval  = "interval"
+)
+
+const (
+	DefaultTimeout  = 30
+	DefaultFeatures = FeaturesTracks
+	DefaultInterval = 300
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// Provider serves the features of GPX files
+type Provider struct {
+	client *http.Client
+
+	// map of layer name and corresponding file
+	layers map[string]Layer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// providers are tracked so their pollers can be stopped during cleanup
+var (
+	providersLock sync.Mutex
+	providers     []*Provider
+)
+
+// NewTileProvider instantiates and returns a new gpx provider or an error.
+// Local files are read when the provider is created, urls by the layer's poller. When a
+// later read fails the layer keeps its features.
+//
+//	timeout (int): [Optional] the number of seconds allowed per url request. defaults to 30
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		path (string): [*Required] the path of a local gpx file
+//		url (string): [*Required] the url of a remote gpx file
+//		features (string): [Optional] tracks, track_points, routes or waypoints. defaults to tracks
+//		interval (int): [Optional] the number of seconds between checking the file for changes. 0 reads it once. defaults to 300
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	timeout := DefaultTimeout
+	timeout, err := config.Int(ConfigKeyTimeout, &timeout)
+	if err != nil {
+		return nil, err
+	}
+	if timeout < 0 {
+		return nil, fmt.Errorf("gpx: %v must not be negative, got %v", ConfigKeyTimeout, timeout)
+	}
+
+	p := Provider{
+		client: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		layers: map[string]Layer{},
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+	for _, l := range p.layers {
+		if !l.source.Polled() {
+			continue
+		}
+		p.wg.Add(1)
+		go func(l Layer) {
+			defer p.wg.Done()
+			l.source.Poll(ctx, p.client, func(err error) {
+				log.Errorf("gpx: layer (%v) refresh failed, keeping the last features: %v", l.name, err)
+			})
+		}(l)
+	}
+
+	providersLock.Lock()
+	providers = append(providers, &p)
+	providersLock.Unlock()
+
+	return &p, nil
+}
+
+// AddLayer adds a gpx file layer to the provider. Local files are read before the layer is added.
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	strs := []struct {
+		key string
+		val string
+	}{
+		{ConfigKeyPath, ""},
+		{ConfigKeyURL, ""},
+		{ConfigKeyFeatures, DefaultFeatures},
+	}
+	for i := range strs {
+		if strs[i].val, err = layerConf.String(strs[i].key, &strs[i].val); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, strs[i].key, err)
+		}
+	}
+	path, url, features := strs[0].val, strs[1].val, strings.ToLower(strs[2].val)
+
+	if (path == "") == (url == "") {
+		return ErrPathOrURL{LayerName: name}
+	}
+
+	switch features {
+	case FeaturesTracks, FeaturesTrackPoints, FeaturesRoutes, FeaturesWaypoints:
+	default:
+		return ErrInvalidFeatures{LayerName: name, Features: features}
+	}
+
+	interval := DefaultInterval
+	if interval, err = layerConf.Int(ConfigKeyInterval, &interval); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyInterval, err)
+	}
+	if interval < 0 {
+		return ErrInvalidInterval{LayerName: name, Interval: interval}
+	}
+
+	l := Layer{
+		name:     name,
+		features: features,
+		source: &provider.PolledSource{
+			Path:     path,
+			URL:      url,
+			Accept:   "application/gpx+xml, application/xml, text/xml",
+			Interval: time.Duration(interval) * time.Second,
+			Decode: func(body []byte) ([]provider.PolledItem, error) {
+				return decode(body, features)
+			},
+		},
+	}
+
+	if path != "" {
+		if err := l.source.Refresh(context.Background(), p.client); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyPath, err)
+		}
+	}
+
+	p.layers[name] = l
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures streams the layer's features intersecting the tile's buffered extent
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	return layer.source.TileFeatures(ctx, tile, fn)
+}
+
+// LayerUpdated returns the modification time of the layer's file. The zero time is returned
+// until a url has been fetched.
+func (p *Provider) LayerUpdated(ctx context.Context, lyrID string) (time.Time, error) {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return time.Time{}, ErrLayerNotFound{LayerName: lyrID}
+	}
+	_, updated := layer.source.Items()
+	return updated, nil
+}
+
+// Close stops the layer pollers
+func (p *Provider) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// Cleanup will stop the pollers of all the providers and remove the providers from the list
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if len(providers) > 0 {
+		log.Infof("cleaning up gpx providers")
+	}
+
+	for i := range providers {
+		providers[i].Close()
+	}
+
+	providers = nil
+}
@@ -0,0 +1,185 @@
+package gpx_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/gpx"
+)
+
+// tileFeatures returns the features of the north west quarter of the world, without Paris
+func tileFeatures(t *testing.T, p provider.Tiler, layer string) []provider.Feature {
+	var features []provider.Feature
+	err := p.TileFeatures(context.Background(), layer, provider.NewTile(1, 0, 0, 0, tegola.WebMercator), func(f *provider.Feature) error {
+		features = append(features, *f)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return features
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		features string
+		expected []provider.Feature
+	}
+
+	feature := func(id uint64, g geom.Geometry, tags map[string]interface{}) provider.Feature {
+		return provider.Feature{ID: id, Geometry: g, SRID: tegola.WGS84, Tags: tags}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			p, err := gpx.NewTileProvider(dict.Dict{
+				"layers": []map[string]interface{}{{
+					"name":     "test",
+					"path":     "testdata/field.gpx",
+					"features": tc.features,
+					"interval": 0,
+				}},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer p.(*gpx.Provider).Close()
+
+			features := tileFeatures(t, p, "test")
+			if !reflect.DeepEqual(features, tc.expected) {
+				t.Errorf("features, expected %+v got %+v", tc.expected, features)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"tracks": {
+			features: "tracks",
+			expected: []provider.Feature{
+				feature(1, geom.MultiLineString{{{-122.4, 37.8}, {-122.41, 37.81}}}, map[string]interface{}{
+					"name":       "Survey",
+					"type":       "walking",
+					"start_time": "2020-05-01T10:00:00Z",
+					"end_time":   "2020-05-01T10:30:00Z",
+				}),
+			},
+		},
+		"track points": {
+			features: "track_points",
+			expected: []provider.Feature{
+				feature(1, geom.Point{-122.4, 37.8}, map[string]interface{}{"track": "Survey", "segment": 0, "time": "2020-05-01T10:00:00Z"}),
+				feature(2, geom.Point{-122.41, 37.81}, map[string]interface{}{"track": "Survey", "segment": 0, "time": "2020-05-01T10:05:00Z"}),
+				feature(3, geom.Point{-122.42, 37.82}, map[string]interface{}{"track": "Survey", "segment": 1, "time": "2020-05-01T10:30:00Z", "ele": 20.0}),
+			},
+		},
+		"routes": {
+			features: "routes",
+			expected: []provider.Feature{
+				feature(1, geom.LineString{{-122.4, 37.8}, {-122.3, 37.9}}, map[string]interface{}{"name": "Ridge", "number": 2}),
+			},
+		},
+		"waypoints": {
+			features: "waypoints",
+			expected: []provider.Feature{
+				feature(1, geom.Point{-122.4, 37.8}, map[string]interface{}{"name": "Camp", "sym": "Campground", "ele": 12.5}),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestURL(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/field.gpx")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	modified := time.Date(2020, 5, 1, 11, 0, 0, 0, time.UTC)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	p, err := gpx.NewTileProvider(dict.Dict{
+		"layers": []map[string]interface{}{{
+			"name":     "test",
+			"url":      srv.URL,
+			"features": "waypoints",
+			"interval": 0,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer p.(*gpx.Provider).Close()
+
+	// the url is fetched by the layer's poller
+	var updated time.Time
+	for deadline := time.Now().Add(5 * time.Second); updated.IsZero() && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if updated, err = p.(*gpx.Provider).LayerUpdated(context.Background(), "test"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !updated.Equal(modified) {
+		t.Errorf("updated, expected %v got %v", modified, updated)
+	}
+
+	if features := tileFeatures(t, p, "test"); len(features) != 1 {
+		t.Errorf("features, expected 1 got %v", len(features))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		layer map[string]interface{}
+		err   string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer["name"] = "test"
+			_, err := gpx.NewTileProvider(dict.Dict{
+				"layers": []map[string]interface{}{tc.layer},
+			})
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"path and url": {
+			layer: map[string]interface{}{"path": "testdata/field.gpx", "url": "http://localhost/field.gpx"},
+			err:   "gpx: layer (test) must define either 'path' or 'url'",
+		},
+		"invalid features": {
+			layer: map[string]interface{}{"path": "testdata/field.gpx", "features": "tracklogs"},
+			err:   "gpx: layer (test) has invalid features (tracklogs), expected tracks, track_points, routes or waypoints",
+		},
+		"negative interval": {
+			layer: map[string]interface{}{"path": "testdata/field.gpx", "interval": -1},
+			err:   "gpx: layer (test) has invalid interval (-1), expected 0 or more seconds",
+		},
+		"missing file": {
+			layer: map[string]interface{}{"path": "testdata/missing.gpx"},
+			err:   "for layer (test) path has an error: stat testdata/missing.gpx: no such file or directory",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
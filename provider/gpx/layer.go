@@ -0,0 +1,38 @@
+package gpx
+
+import (
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// the features of a gpx file a layer can serve
+const (
+	FeaturesTracks      = "tracks"
+	FeaturesTrackPoints = "track_points"
+	FeaturesRoutes      = "routes"
+	FeaturesWaypoints   = "waypoints"
+)
+
+type Layer struct {
+	name string
+	// features is one of the Features values
+	features string
+	// source is the layer's local or remote file
+	source *provider.PolledSource
+}
+
+func (l Layer) ID() string   { return l.name }
+func (l Layer) Name() string { return l.name }
+func (l Layer) GeomType() geom.Geometry {
+	switch l.features {
+	case FeaturesTracks:
+		return geom.MultiLineString{}
+	case FeaturesRoutes:
+		return geom.LineString{}
+	default:
+		return geom.Point{}
+	}
+}
+func (l Layer) SRID() uint64 { return tegola.WGS84 }
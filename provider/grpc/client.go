@@ -0,0 +1,245 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+
+	"github.com/go-spatial/tegola/provider/grpc/providerpb"
+)
+
+// maxMessageSize is the largest message accepted from a plugin
+const maxMessageSize = 64 * 1024 * 1024
+
+// the gRPC status code for a successful call
+const statusOK = "0"
+
+// client calls the Provider service of a plugin. gRPC is HTTP/2 with length prefixed
+// protobuf messages and the call's status in the trailers, so the standard library's
+// HTTP/2 support is used rather than depending on grpc-go.
+type client struct {
+	baseURL  string
+	metadata map[string]string
+	timeout  time.Duration
+	http     *http.Client
+}
+
+type clientOptions struct {
+	// address of the plugin, host:port or unix:/path/to/socket
+	address            string
+	tls                bool
+	caCert             string
+	insecureSkipVerify bool
+	metadata           map[string]string
+	timeout            time.Duration
+}
+
+func newClient(opts clientOptions) (*client, error) {
+	// dial connects to the plugin, over the unix socket when the address is one
+	var dialer net.Dialer
+	dial := dialer.DialContext
+
+	scheme, host := "http", opts.address
+	if strings.HasPrefix(opts.address, "unix:") {
+		path := strings.TrimPrefix(strings.TrimPrefix(opts.address, "unix:"), "//")
+		if path == "" {
+			return nil, ErrInvalidAddress{Address: opts.address}
+		}
+		host = "localhost"
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+	} else if _, _, err := net.SplitHostPort(opts.address); err != nil {
+		return nil, ErrInvalidAddress{Address: opts.address}
+	}
+
+	var transport http.RoundTripper
+	if opts.tls {
+		scheme = "https"
+		tlsConfig := &tls.Config{InsecureSkipVerify: opts.insecureSkipVerify}
+		if opts.caCert != "" {
+			pem, err := ioutil.ReadFile(opts.caCert)
+			if err != nil {
+				return nil, fmt.Errorf("grpc: reading ca_cert (%v): %v", opts.caCert, err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("grpc: ca_cert (%v) has no certificates", opts.caCert)
+			}
+		}
+		transport = &http.Transport{
+			DialContext:       dial,
+			TLSClientConfig:   tlsConfig,
+			ForceAttemptHTTP2: true,
+			IdleConnTimeout:   90 * time.Second,
+		}
+	} else {
+		// plugins without TLS speak HTTP/2 with prior knowledge (h2c)
+		transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(context.Background(), network, addr)
+			},
+		}
+	}
+
+	return &client{
+		baseURL:  scheme + "://" + host + "/" + providerpb.ServiceName + "/",
+		metadata: opts.metadata,
+		timeout:  opts.timeout,
+		http:     &http.Client{Transport: transport},
+	}, nil
+}
+
+// unary calls a method returning a single message
+func (c *client) unary(ctx context.Context, method string, req proto.Message, resp proto.Message) error {
+	var received bool
+	err := c.stream(ctx, method, req, func(b []byte) error {
+		received = true
+		return proto.Unmarshal(b, resp)
+	})
+	if err != nil {
+		return err
+	}
+	if !received {
+		return ErrStatus{Method: method, Code: "13", Message: "no response message"}
+	}
+	return nil
+}
+
+// stream calls a method and passes every message of the response to fn
+func (c *client) stream(ctx context.Context, method string, req proto.Message, fn func([]byte) error) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	writeMessage(&body, b)
+
+	httpReq, err := http.NewRequest(http.MethodPost, c.baseURL+method, &body)
+	if err != nil {
+		return err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/grpc+proto")
+	httpReq.Header.Set("TE", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		httpReq.Header.Set("Grpc-Timeout", grpcTimeout(time.Until(deadline)))
+	}
+	for k, v := range c.metadata {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ErrStatus{Method: method, Code: "14", Message: "http status " + strconv.Itoa(resp.StatusCode)}
+	}
+	// errors without messages are sent in the headers (trailers only responses)
+	if code := resp.Header.Get("Grpc-Status"); code != "" && code != statusOK {
+		return statusError(method, resp.Header)
+	}
+
+	for {
+		msg, err := readMessage(resp.Body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+
+	status := resp.Trailer
+	if status.Get("Grpc-Status") == "" {
+		status = resp.Header
+	}
+	switch status.Get("Grpc-Status") {
+	case statusOK:
+		return nil
+	case "":
+		return ErrStatus{Method: method, Code: "13", Message: "missing grpc-status"}
+	default:
+		return statusError(method, status)
+	}
+}
+
+func statusError(method string, h http.Header) error {
+	msg, err := url.PathUnescape(h.Get("Grpc-Message"))
+	if err != nil {
+		msg = h.Get("Grpc-Message")
+	}
+	return ErrStatus{Method: method, Code: h.Get("Grpc-Status"), Message: msg}
+}
+
+// grpcTimeout formats the timeout as a grpc-timeout header value in milliseconds
+func grpcTimeout(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10) + "m"
+}
+
+// writeMessage writes a length prefixed, uncompressed message
+func writeMessage(w io.Writer, msg []byte) error {
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(msg)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// readMessage reads a length prefixed message. io.EOF is returned at the end of the stream.
+func readMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("grpc: reading message: %v", err)
+	}
+	if prefix[0] != 0 {
+		return nil, fmt.Errorf("grpc: compressed messages are not supported")
+	}
+
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("grpc: message of %v bytes is larger than %v bytes", size, maxMessageSize)
+	}
+
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, fmt.Errorf("grpc: reading message: %v", err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingAddress = errors.New("grpc: provider is missing 'address'")
+)
+
+type ErrInvalidAddress struct {
+	Address string
+}
+
+func (e ErrInvalidAddress) Error() string {
+	return fmt.Sprintf("grpc: invalid address (%v), expected host:port or unix:/path/to/socket", e.Address)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("grpc: layer (%v) not found", e.LayerName)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("grpc: plugin layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("grpc: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
+
+// ErrStatus is returned when a plugin call fails
+type ErrStatus struct {
+	Method string
+	// Code is the gRPC status code
+	Code    string
+	Message string
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("grpc: %v failed with status %v: %v", e.Method, e.Code, e.Message)
+}
@@ -0,0 +1,325 @@
+// Package grpc provides a provider which reads features from an external plugin process over
+// gRPC, so providers can be written in any language without forking tegola. The plugin
+// implements the Provider service of providerpb/provider.proto.
+//
+// The provider is registered twice: "grpc" streams features from the plugin's TileFeatures
+// method and "mvt_grpc" reads encoded tiles from its MVTForLayers method.
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkb"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/grpc/providerpb"
+)
+
+const Name = "grpc"
+
+const (
+	ConfigKeyAddress            = "address"
+	ConfigKeyTLS                = "tls"
+	ConfigKeyCACert             = "ca_cert"
+	ConfigKeyInsecureSkipVerify = "insecure_skip_verify"
+	ConfigKeyMetadata           = "metadata"
+	ConfigKeyTimeout            = "timeout"
+	ConfigKeyLayers             = "layers"
+	ConfigKeyLayerName          = "name"
+)
+
+const (
+	DefaultTimeout = 30
+	DefaultSRID    = tegola.WebMercator
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, nil)
+	provider.MVTRegister(provider.TypeMvt.Prefix()+Name, NewMVTTileProvider, nil)
+}
+
+// Provider calls an external provider plugin
+type Provider struct {
+	address string
+	client  *client
+
+	// the layers of the plugin, from ListLayers
+	pluginLayers map[string]Layer
+	// the layers exposed to maps. every plugin layer when no layers are configured
+	layers map[string]Layer
+}
+
+// NewTileProvider instantiates and returns a new grpc provider or an error.
+// The plugin's layers are listed when the provider is created.
+//
+//	address (string): [Required] the plugin's host:port or unix:/path/to/socket
+//	tls (bool): [Optional] connect with TLS. defaults to false, HTTP/2 without TLS (h2c)
+//	ca_cert (string): [Optional] the path to the CA certificate used to verify the plugin
+//	insecure_skip_verify (bool): [Optional] skip verifying the plugin's certificate. defaults to false
+//	metadata (map[string]string): [Optional] metadata sent with every call (i.e. API keys)
+//	timeout (int): [Optional] the number of seconds allowed per call. defaults to 30
+//	layers (map[string]struct{})  — [Optional] limits the layers exposed to the listed plugin layers.
+//		name (string): [Required] the name of the plugin layer
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	return newProvider(config)
+}
+
+// NewMVTTileProvider instantiates and returns a new mvt_grpc provider or an error. It takes the
+// same config as NewTileProvider.
+func NewMVTTileProvider(config dict.Dicter) (provider.MVTTiler, error) {
+	return newProvider(config)
+}
+
+func newProvider(config dict.Dicter) (*Provider, error) {
+	address, err := config.String(ConfigKeyAddress, nil)
+	if err != nil {
+		return nil, err
+	}
+	if address == "" {
+		return nil, ErrMissingAddress
+	}
+
+	opts := clientOptions{address: address}
+	if opts.tls, err = config.Bool(ConfigKeyTLS, &opts.tls); err != nil {
+		return nil, err
+	}
+	if opts.insecureSkipVerify, err = config.Bool(ConfigKeyInsecureSkipVerify, &opts.insecureSkipVerify); err != nil {
+		return nil, err
+	}
+	if opts.caCert, err = config.String(ConfigKeyCACert, &opts.caCert); err != nil {
+		return nil, err
+	}
+
+	timeout := DefaultTimeout
+	if timeout, err = config.Int(ConfigKeyTimeout, &timeout); err != nil {
+		return nil, err
+	}
+	if timeout < 0 {
+		return nil, fmt.Errorf("grpc: %v must not be negative, got %v", ConfigKeyTimeout, timeout)
+	}
+	opts.timeout = time.Duration(timeout) * time.Second
+
+	if opts.metadata, err = dict.StringMap(config, ConfigKeyMetadata); err != nil {
+		return nil, err
+	}
+
+	c, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Provider{
+		address:      address,
+		client:       c,
+		pluginLayers: map[string]Layer{},
+		layers:       map[string]Layer{},
+	}
+
+	var resp providerpb.ListLayersResponse
+	if err := c.unary(context.Background(), providerpb.MethodListLayers, &providerpb.ListLayersRequest{}, &resp); err != nil {
+		return nil, err
+	}
+	for _, pl := range resp.Layers {
+		if _, ok := p.pluginLayers[pl.Name]; ok {
+			return nil, ErrDuplicateLayerName{LayerName: pl.Name}
+		}
+
+		l := Layer{
+			name:    pl.Name,
+			srid:    pl.Srid,
+			minZoom: int(pl.MinZoom),
+			maxZoom: int(pl.MaxZoom),
+		}
+		var ok bool
+		if l.geomType, ok = provider.GeometryType(pl.GeometryType); !ok {
+			return nil, ErrInvalidGeometryType{LayerName: pl.Name, GeometryType: pl.GeometryType}
+		}
+		if l.srid == 0 {
+			l.srid = DefaultSRID
+		}
+		if l.maxZoom == 0 {
+			l.maxZoom = tegola.MaxZ
+		}
+		p.pluginLayers[l.name] = l
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+	if len(layers) == 0 {
+		p.layers = p.pluginLayers
+	}
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// AddLayer exposes a layer of the plugin
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+
+	l, ok := p.pluginLayers[name]
+	if !ok {
+		return ErrLayerNotFound{LayerName: name}
+	}
+	p.layers[name] = l
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom returns the plugin layer's min zoom
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return p.layers[lyrID].minZoom
+}
+
+// LayerMaxZoom returns the plugin layer's max zoom
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	l, ok := p.layers[lyrID]
+	if !ok {
+		return tegola.MaxZ
+	}
+	return l.maxZoom
+}
+
+func tileMessage(tile provider.Tile) *providerpb.Tile {
+	z, x, y := tile.ZXY()
+	ext, srid := tile.Extent()
+	bufExt, _ := tile.BufferedExtent()
+
+	extent := func(e *geom.Extent) *providerpb.Extent {
+		return &providerpb.Extent{MinX: e.MinX(), MinY: e.MinY(), MaxX: e.MaxX(), MaxY: e.MaxY()}
+	}
+
+	return &providerpb.Tile{
+		Z:              uint32(z),
+		X:              uint32(x),
+		Y:              uint32(y),
+		Extent:         extent(ext),
+		BufferedExtent: extent(bufExt),
+		Srid:           srid,
+	}
+}
+
+// TileFeatures streams the features of the layer from the plugin
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	req := providerpb.TileFeaturesRequest{
+		Layer: lyrID,
+		Tile:  tileMessage(tile),
+	}
+
+	err := p.client.stream(ctx, providerpb.MethodTileFeatures, &req, func(b []byte) error {
+		var pf providerpb.Feature
+		if err := proto.Unmarshal(b, &pf); err != nil {
+			return fmt.Errorf("grpc: decoding feature of layer (%v): %v", lyrID, err)
+		}
+
+		f, err := decodeFeature(&pf, layer.srid)
+		if err != nil {
+			return fmt.Errorf("grpc: layer (%v) feature (%v): %v", lyrID, pf.Id, err)
+		}
+		return fn(f)
+	})
+	if err != nil && ctx.Err() != nil {
+		return provider.ErrCanceled
+	}
+	return err
+}
+
+func decodeFeature(pf *providerpb.Feature, srid uint64) (*provider.Feature, error) {
+	g, err := wkb.DecodeBytes(pf.Geometry)
+	if err != nil {
+		return nil, err
+	}
+
+	f := provider.Feature{
+		ID:       pf.Id,
+		Geometry: g,
+		SRID:     pf.Srid,
+		Tags:     make(map[string]interface{}, len(pf.Tags)),
+	}
+	if f.SRID == 0 {
+		f.SRID = srid
+	}
+
+	for k, v := range pf.Tags {
+		if v == nil {
+			continue
+		}
+		switch v.Type {
+		case providerpb.Value_STRING:
+			f.Tags[k] = v.StringValue
+		case providerpb.Value_DOUBLE:
+			f.Tags[k] = v.DoubleValue
+		case providerpb.Value_INT:
+			f.Tags[k] = v.IntValue
+		case providerpb.Value_UINT:
+			f.Tags[k] = v.UintValue
+		case providerpb.Value_BOOL:
+			f.Tags[k] = v.BoolValue
+		}
+	}
+
+	return &f, nil
+}
+
+// MVTForLayers reads the encoded tile of the layers from the plugin
+func (p *Provider) MVTForLayers(ctx context.Context, tile provider.Tile, layers []provider.Layer) ([]byte, error) {
+	req := providerpb.MVTForLayersRequest{
+		Tile: tileMessage(tile),
+	}
+	for i := range layers {
+		if _, ok := p.layers[layers[i].ID]; !ok {
+			return nil, ErrLayerNotFound{LayerName: layers[i].ID}
+		}
+		req.Layers = append(req.Layers, &providerpb.MVTLayer{Id: layers[i].ID, MvtName: layers[i].MVTName})
+	}
+
+	var resp providerpb.MVTForLayersResponse
+	if err := p.client.unary(ctx, providerpb.MethodMVTForLayers, &req, &resp); err != nil {
+		if ctx.Err() != nil {
+			return nil, provider.ErrCanceled
+		}
+		return nil, err
+	}
+
+	return resp.Data, nil
+}
@@ -0,0 +1,338 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkb"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/grpc/providerpb"
+)
+
+// testPlugin is an in process plugin served over h2c
+type testPlugin struct {
+	layers   []*providerpb.Layer
+	features []*providerpb.Feature
+	mvt      []byte
+	// status returned by TileFeatures after the features
+	status  string
+	message string
+
+	// the last requests received
+	tileReq *providerpb.TileFeaturesRequest
+	mvtReq  *providerpb.MVTForLayersRequest
+	apiKey  string
+}
+
+func (tp *testPlugin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tp.apiKey = r.Header.Get("X-Api-Key")
+
+	if r.ProtoMajor != 2 || r.Header.Get("Content-Type") != "application/grpc+proto" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	req, err := readMessage(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	status, message := "0", ""
+
+	var msgs []proto.Message
+	switch strings.TrimPrefix(r.URL.Path, "/"+providerpb.ServiceName+"/") {
+	case providerpb.MethodListLayers:
+		msgs = append(msgs, &providerpb.ListLayersResponse{Layers: tp.layers})
+	case providerpb.MethodTileFeatures:
+		tp.tileReq = &providerpb.TileFeaturesRequest{}
+		proto.Unmarshal(req, tp.tileReq)
+		for _, f := range tp.features {
+			msgs = append(msgs, f)
+		}
+		if tp.status != "" {
+			status, message = tp.status, tp.message
+		}
+	case providerpb.MethodMVTForLayers:
+		tp.mvtReq = &providerpb.MVTForLayersRequest{}
+		proto.Unmarshal(req, tp.mvtReq)
+		msgs = append(msgs, &providerpb.MVTForLayersResponse{Data: tp.mvt})
+	default:
+		// unimplemented, as a trailers only response
+		w.Header().Set("Grpc-Status", "12")
+		w.Header().Set("Grpc-Message", "unknown%20method")
+		return
+	}
+
+	// the trailers are declared, they are sent after the messages even when there are none
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	for _, m := range msgs {
+		b, _ := proto.Marshal(m)
+		writeMessage(w, b)
+	}
+	w.Header().Set("Grpc-Status", status)
+	w.Header().Set("Grpc-Message", message)
+}
+
+func newTestPlugin(t *testing.T, tp *testPlugin) *httptest.Server {
+	// the plugin speaks HTTP/2 without TLS (h2c)
+	srv := httptest.NewServer(h2c.NewHandler(tp, &http2.Server{}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func mustWKB(t *testing.T, g geom.Geometry) []byte {
+	b, err := wkb.EncodeBytes(g)
+	if err != nil {
+		t.Fatalf("encoding wkb: %v", err)
+	}
+	return b
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		layers   []*providerpb.Layer
+		config   map[string]interface{}
+		expected map[string]Layer
+		err      string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			srv := newTestPlugin(t, &testPlugin{layers: tc.layers})
+
+			config := map[string]interface{}{ConfigKeyAddress: srv.Listener.Addr().String()}
+			for k, v := range tc.config {
+				config[k] = v
+			}
+
+			p, err := NewTileProvider(dict.Dict(config))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := p.(*Provider).layers; !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("layers, expected %+v got %+v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"plugin layers": {
+			layers: []*providerpb.Layer{
+				{Name: "roads", GeometryType: "LineString", Srid: 4326, MinZoom: 4, MaxZoom: 14},
+				{Name: "pois"},
+			},
+			expected: map[string]Layer{
+				"roads": {name: "roads", geomType: geom.LineString{}, srid: 4326, minZoom: 4, maxZoom: 14},
+				"pois":  {name: "pois", srid: tegola.WebMercator, maxZoom: tegola.MaxZ},
+			},
+		},
+		"configured layers": {
+			layers: []*providerpb.Layer{{Name: "roads"}, {Name: "pois"}},
+			config: map[string]interface{}{
+				ConfigKeyLayers: []map[string]interface{}{{ConfigKeyLayerName: "pois"}},
+			},
+			expected: map[string]Layer{
+				"pois": {name: "pois", srid: tegola.WebMercator, maxZoom: tegola.MaxZ},
+			},
+		},
+		"configured layer not in plugin": {
+			layers: []*providerpb.Layer{{Name: "roads"}},
+			config: map[string]interface{}{
+				ConfigKeyLayers: []map[string]interface{}{{ConfigKeyLayerName: "pois"}},
+			},
+			err: "layer (pois) not found",
+		},
+		"duplicate layer": {
+			layers: []*providerpb.Layer{{Name: "roads"}, {Name: "roads"}},
+			err:    "(roads) is duplicated",
+		},
+		"invalid geometry type": {
+			layers: []*providerpb.Layer{{Name: "roads", GeometryType: "curve"}},
+			err:    "invalid geometry_type (curve)",
+		},
+		"invalid address": {
+			config: map[string]interface{}{ConfigKeyAddress: "localhost"},
+			err:    "invalid address (localhost)",
+		},
+		"negative timeout": {
+			config: map[string]interface{}{ConfigKeyTimeout: -1},
+			err:    "timeout must not be negative",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		plugin   testPlugin
+		expected []provider.Feature
+		err      string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.plugin.layers = []*providerpb.Layer{{Name: "pois", GeometryType: "Point"}}
+			srv := newTestPlugin(t, &tc.plugin)
+
+			p, err := NewTileProvider(dict.Dict{
+				ConfigKeyAddress:  srv.Listener.Addr().String(),
+				ConfigKeyMetadata: map[string]interface{}{"x-api-key": "secret"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []provider.Feature
+			err = p.TileFeatures(context.Background(), "pois", provider.NewTile(2, 1, 3, 64, tegola.WebMercator), func(f *provider.Feature) error {
+				got = append(got, *f)
+				return nil
+			})
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("features, expected %+v got %+v", tc.expected, got)
+			}
+
+			req := tc.plugin.tileReq
+			if req.Layer != "pois" || req.Tile.Z != 2 || req.Tile.X != 1 || req.Tile.Y != 3 || req.Tile.Srid != tegola.WebMercator {
+				t.Errorf("request, got %v", req)
+			}
+			if req.Tile.BufferedExtent.MinX >= req.Tile.Extent.MinX {
+				t.Errorf("buffered extent %v is not larger than extent %v", req.Tile.BufferedExtent, req.Tile.Extent)
+			}
+			if tc.plugin.apiKey != "secret" {
+				t.Errorf("metadata, expected secret got %q", tc.plugin.apiKey)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"features": {
+			plugin: testPlugin{
+				features: []*providerpb.Feature{
+					{
+						Id:       1,
+						Geometry: mustWKB(t, geom.Point{1, 2}),
+						Tags: map[string]*providerpb.Value{
+							"name":   {Type: providerpb.Value_STRING, StringValue: "cafe"},
+							"rating": {Type: providerpb.Value_DOUBLE, DoubleValue: 4.5},
+							"floors": {Type: providerpb.Value_INT, IntValue: -1},
+							"seats":  {Type: providerpb.Value_UINT, UintValue: 20},
+							"open":   {Type: providerpb.Value_BOOL, BoolValue: true},
+							"closed": {Type: providerpb.Value_NULL},
+						},
+					},
+					{
+						Id:       2,
+						Geometry: mustWKB(t, geom.Point{3, 4}),
+						Srid:     tegola.WGS84,
+					},
+				},
+			},
+			expected: []provider.Feature{
+				{
+					ID:       1,
+					Geometry: geom.Point{1, 2},
+					SRID:     tegola.WebMercator,
+					Tags: map[string]interface{}{
+						"name":   "cafe",
+						"rating": 4.5,
+						"floors": int64(-1),
+						"seats":  uint64(20),
+						"open":   true,
+					},
+				},
+				{
+					ID:       2,
+					Geometry: geom.Point{3, 4},
+					SRID:     tegola.WGS84,
+					Tags:     map[string]interface{}{},
+				},
+			},
+		},
+		"error status": {
+			plugin: testPlugin{
+				status:  "13",
+				message: "database%20unavailable",
+			},
+			err: "TileFeatures failed with status 13: database unavailable",
+		},
+		"invalid geometry": {
+			plugin: testPlugin{
+				features: []*providerpb.Feature{{Id: 1, Geometry: []byte{1, 2, 3}}},
+			},
+			err: "layer (pois) feature (1)",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestMVTForLayers(t *testing.T) {
+	mvt := []byte{0x1a, 0x02, 0x78, 0x02}
+	tp := testPlugin{
+		layers: []*providerpb.Layer{{Name: "pois"}, {Name: "roads"}},
+		mvt:    mvt,
+	}
+	srv := newTestPlugin(t, &tp)
+
+	p, err := NewMVTTileProvider(dict.Dict{ConfigKeyAddress: srv.Listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := p.MVTForLayers(context.Background(), provider.NewTile(0, 0, 0, 64, tegola.WebMercator), []provider.Layer{
+		{ID: "pois", MVTName: "points"},
+		{ID: "roads", MVTName: "roads"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, mvt) {
+		t.Errorf("data, expected %v got %v", mvt, got)
+	}
+
+	expected := []*providerpb.MVTLayer{{Id: "pois", MvtName: "points"}, {Id: "roads", MvtName: "roads"}}
+	if !reflect.DeepEqual(tp.mvtReq.Layers, expected) {
+		t.Errorf("layers, expected %v got %v", expected, tp.mvtReq.Layers)
+	}
+
+	_, err = p.MVTForLayers(context.Background(), provider.NewTile(0, 0, 0, 64, tegola.WebMercator), []provider.Layer{{ID: "water"}})
+	if _, ok := err.(ErrLayerNotFound); !ok {
+		t.Errorf("expected ErrLayerNotFound, got %v", err)
+	}
+}
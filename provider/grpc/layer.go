@@ -0,0 +1,18 @@
+package grpc
+
+import (
+	"github.com/go-spatial/geom"
+)
+
+type Layer struct {
+	name     string
+	geomType geom.Geometry
+	srid     uint64
+	minZoom  int
+	maxZoom  int
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
@@ -0,0 +1,181 @@
+// Package providerpb holds the messages of provider.proto, the protocol between tegola and
+// external provider plugins.
+//
+// The messages follow the layout protoc-gen-go generates for github.com/golang/protobuf, but
+// are maintained by hand alongside provider.proto so tegola doesn't depend on grpc-go.
+// Keep the field numbers and tags in sync with provider.proto.
+package providerpb
+
+import proto "github.com/golang/protobuf/proto"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// ServiceName is the fully qualified name of the Provider service
+const ServiceName = "tegola.provider.v1.Provider"
+
+// the methods of the Provider service
+const (
+	MethodListLayers   = "ListLayers"
+	MethodTileFeatures = "TileFeatures"
+	MethodMVTForLayers = "MVTForLayers"
+)
+
+type Value_Type int32
+
+const (
+	Value_NULL   Value_Type = 0
+	Value_STRING Value_Type = 1
+	Value_DOUBLE Value_Type = 2
+	Value_INT    Value_Type = 3
+	Value_UINT   Value_Type = 4
+	Value_BOOL   Value_Type = 5
+)
+
+var Value_Type_name = map[int32]string{
+	0: "NULL",
+	1: "STRING",
+	2: "DOUBLE",
+	3: "INT",
+	4: "UINT",
+	5: "BOOL",
+}
+var Value_Type_value = map[string]int32{
+	"NULL":   0,
+	"STRING": 1,
+	"DOUBLE": 2,
+	"INT":    3,
+	"UINT":   4,
+	"BOOL":   5,
+}
+
+func (x Value_Type) String() string {
+	return proto.EnumName(Value_Type_name, int32(x))
+}
+
+type ListLayersRequest struct {
+}
+
+func (m *ListLayersRequest) Reset()         { *m = ListLayersRequest{} }
+func (m *ListLayersRequest) String() string { return proto.CompactTextString(m) }
+func (*ListLayersRequest) ProtoMessage()    {}
+
+type ListLayersResponse struct {
+	Layers []*Layer `protobuf:"bytes,1,rep,name=layers" json:"layers,omitempty"`
+}
+
+func (m *ListLayersResponse) Reset()         { *m = ListLayersResponse{} }
+func (m *ListLayersResponse) String() string { return proto.CompactTextString(m) }
+func (*ListLayersResponse) ProtoMessage()    {}
+
+type Layer struct {
+	Name         string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	GeometryType string `protobuf:"bytes,2,opt,name=geometry_type,json=geometryType,proto3" json:"geometry_type,omitempty"`
+	Srid         uint64 `protobuf:"varint,3,opt,name=srid,proto3" json:"srid,omitempty"`
+	MinZoom      uint32 `protobuf:"varint,4,opt,name=min_zoom,json=minZoom,proto3" json:"min_zoom,omitempty"`
+	MaxZoom      uint32 `protobuf:"varint,5,opt,name=max_zoom,json=maxZoom,proto3" json:"max_zoom,omitempty"`
+}
+
+func (m *Layer) Reset()         { *m = Layer{} }
+func (m *Layer) String() string { return proto.CompactTextString(m) }
+func (*Layer) ProtoMessage()    {}
+
+type Extent struct {
+	MinX float64 `protobuf:"fixed64,1,opt,name=min_x,json=minX,proto3" json:"min_x,omitempty"`
+	MinY float64 `protobuf:"fixed64,2,opt,name=min_y,json=minY,proto3" json:"min_y,omitempty"`
+	MaxX float64 `protobuf:"fixed64,3,opt,name=max_x,json=maxX,proto3" json:"max_x,omitempty"`
+	MaxY float64 `protobuf:"fixed64,4,opt,name=max_y,json=maxY,proto3" json:"max_y,omitempty"`
+}
+
+func (m *Extent) Reset()         { *m = Extent{} }
+func (m *Extent) String() string { return proto.CompactTextString(m) }
+func (*Extent) ProtoMessage()    {}
+
+type Tile struct {
+	Z              uint32  `protobuf:"varint,1,opt,name=z,proto3" json:"z,omitempty"`
+	X              uint32  `protobuf:"varint,2,opt,name=x,proto3" json:"x,omitempty"`
+	Y              uint32  `protobuf:"varint,3,opt,name=y,proto3" json:"y,omitempty"`
+	Extent         *Extent `protobuf:"bytes,4,opt,name=extent" json:"extent,omitempty"`
+	BufferedExtent *Extent `protobuf:"bytes,5,opt,name=buffered_extent,json=bufferedExtent" json:"buffered_extent,omitempty"`
+	Srid           uint64  `protobuf:"varint,6,opt,name=srid,proto3" json:"srid,omitempty"`
+}
+
+func (m *Tile) Reset()         { *m = Tile{} }
+func (m *Tile) String() string { return proto.CompactTextString(m) }
+func (*Tile) ProtoMessage()    {}
+
+type TileFeaturesRequest struct {
+	Layer string `protobuf:"bytes,1,opt,name=layer,proto3" json:"layer,omitempty"`
+	Tile  *Tile  `protobuf:"bytes,2,opt,name=tile" json:"tile,omitempty"`
+}
+
+func (m *TileFeaturesRequest) Reset()         { *m = TileFeaturesRequest{} }
+func (m *TileFeaturesRequest) String() string { return proto.CompactTextString(m) }
+func (*TileFeaturesRequest) ProtoMessage()    {}
+
+type Value struct {
+	Type        Value_Type `protobuf:"varint,1,opt,name=type,proto3,enum=tegola.provider.v1.Value_Type" json:"type,omitempty"`
+	StringValue string     `protobuf:"bytes,2,opt,name=string_value,json=stringValue,proto3" json:"string_value,omitempty"`
+	DoubleValue float64    `protobuf:"fixed64,3,opt,name=double_value,json=doubleValue,proto3" json:"double_value,omitempty"`
+	IntValue    int64      `protobuf:"varint,4,opt,name=int_value,json=intValue,proto3" json:"int_value,omitempty"`
+	UintValue   uint64     `protobuf:"varint,5,opt,name=uint_value,json=uintValue,proto3" json:"uint_value,omitempty"`
+	BoolValue   bool       `protobuf:"varint,6,opt,name=bool_value,json=boolValue,proto3" json:"bool_value,omitempty"`
+}
+
+func (m *Value) Reset()         { *m = Value{} }
+func (m *Value) String() string { return proto.CompactTextString(m) }
+func (*Value) ProtoMessage()    {}
+
+type Feature struct {
+	Id       uint64            `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Geometry []byte            `protobuf:"bytes,2,opt,name=geometry,proto3" json:"geometry,omitempty"`
+	Srid     uint64            `protobuf:"varint,3,opt,name=srid,proto3" json:"srid,omitempty"`
+	Tags     map[string]*Value `protobuf:"bytes,4,rep,name=tags" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *Feature) Reset()         { *m = Feature{} }
+func (m *Feature) String() string { return proto.CompactTextString(m) }
+func (*Feature) ProtoMessage()    {}
+
+type MVTLayer struct {
+	Id      string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	MvtName string `protobuf:"bytes,2,opt,name=mvt_name,json=mvtName,proto3" json:"mvt_name,omitempty"`
+}
+
+func (m *MVTLayer) Reset()         { *m = MVTLayer{} }
+func (m *MVTLayer) String() string { return proto.CompactTextString(m) }
+func (*MVTLayer) ProtoMessage()    {}
+
+type MVTForLayersRequest struct {
+	Tile   *Tile       `protobuf:"bytes,1,opt,name=tile" json:"tile,omitempty"`
+	Layers []*MVTLayer `protobuf:"bytes,2,rep,name=layers" json:"layers,omitempty"`
+}
+
+func (m *MVTForLayersRequest) Reset()         { *m = MVTForLayersRequest{} }
+func (m *MVTForLayersRequest) String() string { return proto.CompactTextString(m) }
+func (*MVTForLayersRequest) ProtoMessage()    {}
+
+type MVTForLayersResponse struct {
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *MVTForLayersResponse) Reset()         { *m = MVTForLayersResponse{} }
+func (m *MVTForLayersResponse) String() string { return proto.CompactTextString(m) }
+func (*MVTForLayersResponse) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterType((*ListLayersRequest)(nil), "tegola.provider.v1.ListLayersRequest")
+	proto.RegisterType((*ListLayersResponse)(nil), "tegola.provider.v1.ListLayersResponse")
+	proto.RegisterType((*Layer)(nil), "tegola.provider.v1.Layer")
+	proto.RegisterType((*Extent)(nil), "tegola.provider.v1.Extent")
+	proto.RegisterType((*Tile)(nil), "tegola.provider.v1.Tile")
+	proto.RegisterType((*TileFeaturesRequest)(nil), "tegola.provider.v1.TileFeaturesRequest")
+	proto.RegisterType((*Value)(nil), "tegola.provider.v1.Value")
+	proto.RegisterType((*Feature)(nil), "tegola.provider.v1.Feature")
+	proto.RegisterType((*MVTLayer)(nil), "tegola.provider.v1.MVTLayer")
+	proto.RegisterType((*MVTForLayersRequest)(nil), "tegola.provider.v1.MVTForLayersRequest")
+	proto.RegisterType((*MVTForLayersResponse)(nil), "tegola.provider.v1.MVTForLayersResponse")
+	proto.RegisterEnum("tegola.provider.v1.Value_Type", Value_Type_name, Value_Type_value)
+}
@@ -0,0 +1,62 @@
+package gtfsrt
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingLayerName = errors.New("gtfsrt: layer is missing 'name'")
+)
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("gtfsrt: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrMissingURL struct {
+	LayerName string
+}
+
+func (e ErrMissingURL) Error() string {
+	return fmt.Sprintf("gtfsrt: layer (%v) is missing 'url'", e.LayerName)
+}
+
+type ErrInvalidInterval struct {
+	LayerName string
+	Interval  int
+}
+
+func (e ErrInvalidInterval) Error() string {
+	return fmt.Sprintf("gtfsrt: layer (%v) has invalid interval (%v), expected 1 or more seconds", e.LayerName, e.Interval)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("gtfsrt: layer (%v) not found", e.LayerName)
+}
+
+// ErrStatus is returned when a feed responds with a status other than 200
+type ErrStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("gtfsrt: feed (%v) responded with status %v", e.URL, e.Status)
+}
+
+// ErrDifferentialFeed is returned for feeds with DIFFERENTIAL incrementality, which aren't supported
+type ErrDifferentialFeed struct {
+	URL string
+}
+
+func (e ErrDifferentialFeed) Error() string {
+	return fmt.Sprintf("gtfsrt: feed (%v) is differential, only full dataset feeds are supported", e.URL)
+}
@@ -0,0 +1,165 @@
+package gtfsrt
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-spatial/geom"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/gtfsrt/gtfsrtpb"
+)
+
+// fetch requests and decodes a GTFS Realtime feed
+func (p *Provider) fetch(ctx context.Context, u string) (*gtfsrtpb.FeedMessage, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/x-protobuf")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrStatus{URL: u, Status: resp.StatusCode}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed gtfsrtpb.FeedMessage
+	if err := proto.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("gtfsrt: decoding feed (%v): %v", u, err)
+	}
+	if feed.GetHeader().GetIncrementality() != gtfsrtpb.FeedHeader_FULL_DATASET {
+		return nil, ErrDifferentialFeed{URL: u}
+	}
+
+	return &feed, nil
+}
+
+// tripDelays returns the delays, in seconds, of the trip updates of the feed keyed by trip id.
+// The trip's delay is used when it's set, otherwise the delay of its first stop time update.
+func tripDelays(feed *gtfsrtpb.FeedMessage, delays map[string]int32) {
+	for _, e := range feed.GetEntity() {
+		tu := e.GetTripUpdate()
+		if tu == nil || e.GetIsDeleted() {
+			continue
+		}
+		tripID := tu.GetTrip().GetTripId()
+		if tripID == "" {
+			continue
+		}
+
+		if tu.Delay != nil {
+			delays[tripID] = tu.GetDelay()
+			continue
+		}
+		for _, stu := range tu.GetStopTimeUpdate() {
+			if ev := stu.GetArrival(); ev != nil && ev.Delay != nil {
+				delays[tripID] = ev.GetDelay()
+				break
+			}
+			if ev := stu.GetDeparture(); ev != nil && ev.Delay != nil {
+				delays[tripID] = ev.GetDelay()
+				break
+			}
+		}
+	}
+}
+
+// vehicleFeatures returns the point features of the vehicle positions of the feed. Entities
+// without a position or with coordinates out of range are skipped.
+func vehicleFeatures(feed *gtfsrtpb.FeedMessage, delays map[string]int32) []provider.Feature {
+	var features []provider.Feature
+	for _, e := range feed.GetEntity() {
+		vp := e.GetVehicle()
+		if vp == nil || vp.Position == nil || e.GetIsDeleted() {
+			continue
+		}
+		pos := vp.GetPosition()
+		lon, lat := float64(pos.GetLongitude()), float64(pos.GetLatitude())
+		if lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+			continue
+		}
+
+		tags := map[string]interface{}{
+			"entity_id":      e.GetId(),
+			"current_status": vp.GetCurrentStatus().String(),
+		}
+		setString := func(k, v string) {
+			if v != "" {
+				tags[k] = v
+			}
+		}
+
+		vehicle := vp.GetVehicle()
+		setString("vehicle_id", vehicle.GetId())
+		setString("vehicle_label", vehicle.GetLabel())
+		setString("license_plate", vehicle.GetLicensePlate())
+
+		trip := vp.GetTrip()
+		setString("trip_id", trip.GetTripId())
+		setString("route_id", trip.GetRouteId())
+		setString("start_date", trip.GetStartDate())
+		setString("start_time", trip.GetStartTime())
+		if trip != nil && trip.DirectionId != nil {
+			tags["direction_id"] = trip.GetDirectionId()
+		}
+		if delay, ok := delays[trip.GetTripId()]; ok && trip.GetTripId() != "" {
+			tags["delay"] = delay
+		}
+
+		if pos.Bearing != nil {
+			tags["bearing"] = pos.GetBearing()
+		}
+		if pos.Speed != nil {
+			tags["speed"] = pos.GetSpeed()
+		}
+		if pos.Odometer != nil {
+			tags["odometer"] = pos.GetOdometer()
+		}
+
+		setString("stop_id", vp.GetStopId())
+		if vp.CurrentStopSequence != nil {
+			tags["current_stop_sequence"] = vp.GetCurrentStopSequence()
+		}
+		if vp.CongestionLevel != nil {
+			tags["congestion_level"] = vp.GetCongestionLevel().String()
+		}
+		if vp.OccupancyStatus != nil {
+			tags["occupancy_status"] = vp.GetOccupancyStatus().String()
+		}
+		if vp.Timestamp != nil {
+			tags["timestamp"] = vp.GetTimestamp()
+		}
+
+		// vehicles keep their id between refreshes when the feed identifies them
+		id := vehicle.GetId()
+		if id == "" {
+			id = e.GetId()
+		}
+
+		features = append(features, provider.Feature{
+			ID:       provider.FeatureID(id),
+			Geometry: geom.Point{lon, lat},
+			SRID:     tegola.WGS84,
+			Tags:     tags,
+		})
+	}
+	return features
+}
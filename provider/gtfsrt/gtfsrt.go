@@ -0,0 +1,307 @@
+// Package gtfsrt provides a provider which polls GTFS Realtime VehiclePositions feeds and
+// serves the vehicles as point layers. Each layer refreshes its feed on an interval. Delays
+// are joined from the trip updates of the feed or a separate TripUpdates feed.
+package gtfsrt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "gtfsrt"
+
+const (
+	ConfigKeyHeaders = "headers"
+	ConfigKeyTimeout = "timeout"
+	ConfigKeyLayers  = "layers"
+
+	ConfigKeyLayerName      = "name"
+	ConfigKeyURL            = "url"
+	ConfigKeyTripUpdatesURL = "trip_updates_url"
+	ConfigKeyInterval       = "interval"
+)
+
+const (
+	DefaultTimeout  = 30
+	DefaultInterval = 30
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// Provider serves layers of vehicles polled from GTFS Realtime feeds
+type Provider struct {
+	headers map[string]string
+	client  *http.Client
+
+	// map of layer name and corresponding feed
+	layers map[string]Layer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// providers are tracked so their pollers can be stopped during cleanup
+var (
+	providersLock sync.Mutex
+	providers     []*Provider
+)
+
+// NewTileProvider instantiates and returns a new gtfsrt provider or an error.
+// A poller is started for every layer. When a refresh fails the layer keeps its vehicles.
+//
+//	headers (map[string]string): [Optional] headers added to every request (i.e. API keys)
+//	timeout (int): [Optional] the number of seconds allowed per request. defaults to 30
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		url (string): [Required] the url of the VehiclePositions feed
+//		trip_updates_url (string): [Optional] the url of a TripUpdates feed the vehicle delays are read from
+//		interval (int): [Optional] the number of seconds between refreshing the feeds. defaults to 30
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	timeout := DefaultTimeout
+	timeout, err := config.Int(ConfigKeyTimeout, &timeout)
+	if err != nil {
+		return nil, err
+	}
+	if timeout < 0 {
+		return nil, fmt.Errorf("gtfsrt: %v must not be negative, got %v", ConfigKeyTimeout, timeout)
+	}
+
+	headers, err := dict.StringMap(config, ConfigKeyHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Provider{
+		headers: headers,
+		client:  &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		layers:  map[string]Layer{},
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+	for _, l := range p.layers {
+		p.wg.Add(1)
+		go p.poll(ctx, l)
+	}
+
+	providersLock.Lock()
+	providers = append(providers, &p)
+	providersLock.Unlock()
+
+	return &p, nil
+}
+
+// AddLayer adds a feed layer to the provider. The feed is polled once the provider is created.
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	empty := ""
+	url, err := layerConf.String(ConfigKeyURL, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyURL, err)
+	}
+	if url == "" {
+		return ErrMissingURL{LayerName: name}
+	}
+
+	tripUpdatesURL, err := layerConf.String(ConfigKeyTripUpdatesURL, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyTripUpdatesURL, err)
+	}
+
+	interval := DefaultInterval
+	if interval, err = layerConf.Int(ConfigKeyInterval, &interval); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyInterval, err)
+	}
+	if interval < 1 {
+		return ErrInvalidInterval{LayerName: name, Interval: interval}
+	}
+
+	p.layers[name] = Layer{
+		name:           name,
+		url:            url,
+		tripUpdatesURL: tripUpdatesURL,
+		interval:       time.Duration(interval) * time.Second,
+		vehicles:       &vehicles{},
+	}
+
+	return nil
+}
+
+// poll refreshes the layer's vehicles every interval until the context is canceled
+func (p *Provider) poll(ctx context.Context, l Layer) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.refresh(ctx, l); err != nil && ctx.Err() == nil {
+			log.Errorf("gtfsrt: layer (%v) refresh failed, keeping the last vehicles: %v", l.name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh fetches the layer's feeds and replaces its vehicles
+func (p *Provider) refresh(ctx context.Context, l Layer) error {
+	feed, err := p.fetch(ctx, l.url)
+	if err != nil {
+		return err
+	}
+
+	delays := map[string]int32{}
+	tripDelays(feed, delays)
+	if l.tripUpdatesURL != "" {
+		updates, err := p.fetch(ctx, l.tripUpdatesURL)
+		if err != nil {
+			return err
+		}
+		tripDelays(updates, delays)
+	}
+
+	// the feed's timestamp is when the producer created it, so feeds which stopped being
+	// updated upstream are reported as stale
+	updated := time.Now()
+	if ts := feed.GetHeader().GetTimestamp(); ts > 0 {
+		updated = time.Unix(int64(ts), 0)
+	}
+	l.vehicles.set(vehicleFeatures(feed, delays), updated)
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures streams the layer's vehicles within the tile's buffered extent
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	ext, err := provider.QueryExtent(tile, tegola.WGS84)
+	if err != nil {
+		return err
+	}
+
+	features, _ := layer.vehicles.get()
+	for _, f := range features {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		pt := f.Geometry.(geom.Point)
+		if !ext.ContainsPoint(pt) {
+			continue
+		}
+
+		// the tags are copied as they're shared between tiles
+		f.Tags = provider.CopyTags(f.Tags)
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LayerUpdated returns the time of the layer's vehicle positions feed. The zero time is
+// returned until the feed has been fetched.
+func (p *Provider) LayerUpdated(ctx context.Context, lyrID string) (time.Time, error) {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return time.Time{}, ErrLayerNotFound{LayerName: lyrID}
+	}
+	_, updated := layer.vehicles.get()
+	return updated, nil
+}
+
+// Close stops the layer pollers
+func (p *Provider) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// Cleanup will stop the pollers of all the providers and remove the providers from the list
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if len(providers) > 0 {
+		log.Infof("cleaning up gtfsrt providers")
+	}
+
+	for i := range providers {
+		providers[i].Close()
+	}
+
+	providers = nil
+}
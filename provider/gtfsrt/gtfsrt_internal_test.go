@@ -0,0 +1,272 @@
+package gtfsrt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/gtfsrt/gtfsrtpb"
+)
+
+func feed(entities ...*gtfsrtpb.FeedEntity) *gtfsrtpb.FeedMessage {
+	return &gtfsrtpb.FeedMessage{
+		Header: &gtfsrtpb.FeedHeader{GtfsRealtimeVersion: proto.String("2.0")},
+		Entity: entities,
+	}
+}
+
+func vehicleEntity(id string, lon, lat float32, trip string) *gtfsrtpb.FeedEntity {
+	return &gtfsrtpb.FeedEntity{
+		Id: proto.String(id),
+		Vehicle: &gtfsrtpb.VehiclePosition{
+			Trip:     &gtfsrtpb.TripDescriptor{TripId: proto.String(trip), RouteId: proto.String("R1")},
+			Vehicle:  &gtfsrtpb.VehicleDescriptor{Id: proto.String(id + "-v")},
+			Position: &gtfsrtpb.Position{Latitude: proto.Float32(lat), Longitude: proto.Float32(lon), Bearing: proto.Float32(90)},
+		},
+	}
+}
+
+func tripUpdateEntity(id, trip string, delay *int32, stopDelay int32) *gtfsrtpb.FeedEntity {
+	return &gtfsrtpb.FeedEntity{
+		Id: proto.String(id),
+		TripUpdate: &gtfsrtpb.TripUpdate{
+			Trip:  &gtfsrtpb.TripDescriptor{TripId: proto.String(trip)},
+			Delay: delay,
+			StopTimeUpdate: []*gtfsrtpb.TripUpdate_StopTimeUpdate{
+				{StopSequence: proto.Uint32(3), Departure: &gtfsrtpb.TripUpdate_StopTimeEvent{Delay: proto.Int32(stopDelay)}},
+			},
+		},
+	}
+}
+
+func TestVehicleFeatures(t *testing.T) {
+	type tcase struct {
+		feeds    []*gtfsrtpb.FeedMessage
+		expected []provider.Feature
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			delays := map[string]int32{}
+			for _, f := range tc.feeds {
+				tripDelays(f, delays)
+			}
+			got := vehicleFeatures(tc.feeds[0], delays)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("features, expected %+v got %+v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"vehicle": {
+			feeds: []*gtfsrtpb.FeedMessage{
+				feed(&gtfsrtpb.FeedEntity{
+					Id: proto.String("e1"),
+					Vehicle: &gtfsrtpb.VehiclePosition{
+						Trip: &gtfsrtpb.TripDescriptor{
+							TripId:      proto.String("T1"),
+							RouteId:     proto.String("R1"),
+							DirectionId: proto.Uint32(0),
+						},
+						Vehicle:             &gtfsrtpb.VehicleDescriptor{Id: proto.String("12"), Label: proto.String("Bus 12")},
+						Position:            &gtfsrtpb.Position{Latitude: proto.Float32(2), Longitude: proto.Float32(1), Speed: proto.Float32(5)},
+						CurrentStatus:       gtfsrtpb.VehiclePosition_STOPPED_AT.Enum(),
+						CurrentStopSequence: proto.Uint32(4),
+						StopId:              proto.String("S4"),
+						OccupancyStatus:     gtfsrtpb.VehiclePosition_FULL.Enum(),
+						Timestamp:           proto.Uint64(1591012800),
+					},
+				}),
+			},
+			expected: []provider.Feature{{
+				ID:       12,
+				Geometry: geom.Point{1, 2},
+				SRID:     tegola.WGS84,
+				Tags: map[string]interface{}{
+					"entity_id":             "e1",
+					"vehicle_id":            "12",
+					"vehicle_label":         "Bus 12",
+					"trip_id":               "T1",
+					"route_id":              "R1",
+					"direction_id":          uint32(0),
+					"speed":                 float32(5),
+					"current_status":        "STOPPED_AT",
+					"current_stop_sequence": uint32(4),
+					"stop_id":               "S4",
+					"occupancy_status":      "FULL",
+					"timestamp":             uint64(1591012800),
+				},
+			}},
+		},
+		"trip delay": {
+			feeds: []*gtfsrtpb.FeedMessage{
+				feed(vehicleEntity("1", 1, 2, "T1"), tripUpdateEntity("u1", "T1", proto.Int32(120), 60)),
+			},
+			expected: []provider.Feature{{
+				ID:       provider.FeatureID("1-v"),
+				Geometry: geom.Point{1, 2},
+				SRID:     tegola.WGS84,
+				Tags: map[string]interface{}{
+					"entity_id":      "1",
+					"vehicle_id":     "1-v",
+					"trip_id":        "T1",
+					"route_id":       "R1",
+					"bearing":        float32(90),
+					"current_status": "IN_TRANSIT_TO",
+					"delay":          int32(120),
+				},
+			}},
+		},
+		"stop delay from trip updates feed": {
+			feeds: []*gtfsrtpb.FeedMessage{
+				feed(vehicleEntity("1", 1, 2, "T1")),
+				feed(tripUpdateEntity("u1", "T1", nil, 60), tripUpdateEntity("u2", "T2", nil, 30)),
+			},
+			expected: []provider.Feature{{
+				ID:       provider.FeatureID("1-v"),
+				Geometry: geom.Point{1, 2},
+				SRID:     tegola.WGS84,
+				Tags: map[string]interface{}{
+					"entity_id":      "1",
+					"vehicle_id":     "1-v",
+					"trip_id":        "T1",
+					"route_id":       "R1",
+					"bearing":        float32(90),
+					"current_status": "IN_TRANSIT_TO",
+					"delay":          int32(60),
+				},
+			}},
+		},
+		"skipped": {
+			feeds: []*gtfsrtpb.FeedMessage{
+				feed(
+					&gtfsrtpb.FeedEntity{Id: proto.String("no position"), Vehicle: &gtfsrtpb.VehiclePosition{}},
+					&gtfsrtpb.FeedEntity{Id: proto.String("deleted"), IsDeleted: proto.Bool(true), Vehicle: vehicleEntity("2", 1, 2, "").Vehicle},
+					vehicleEntity("out of range", 200, 2, ""),
+				),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+// waitFor polls cond until it's true or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	feeds := map[string]*gtfsrtpb.FeedMessage{
+		"/vehicles": feed(vehicleEntity("1", 10, 10, "T1"), vehicleEntity("2", -10, -10, "T2")),
+		"/updates":  feed(tripUpdateEntity("u1", "T1", proto.Int32(-30), 0)),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f, ok := feeds[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b, err := proto.Marshal(f)
+		if err != nil {
+			t.Errorf("marshal: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	p, err := NewTileProvider(dict.Dict{
+		ConfigKeyHeaders: map[string]interface{}{"X-Api-Key": "secret"},
+		ConfigKeyLayers: []map[string]interface{}{{
+			ConfigKeyLayerName:      "buses",
+			ConfigKeyURL:            srv.URL + "/vehicles",
+			ConfigKeyTripUpdatesURL: srv.URL + "/updates",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer p.(*Provider).Close()
+
+	layer := p.(*Provider).layers["buses"]
+	waitFor(t, "refresh", func() bool {
+		_, updated := layer.vehicles.get()
+		return !updated.IsZero()
+	})
+
+	var got []provider.Feature
+	err = p.TileFeatures(context.Background(), "buses", provider.NewTile(1, 1, 0, 0, tegola.WebMercator), func(f *provider.Feature) error {
+		got = append(got, *f)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("features, expected 1 got %v", len(got))
+	}
+	if got[0].ID != provider.FeatureID("1-v") || got[0].Tags["delay"] != int32(-30) {
+		t.Errorf("feature, expected vehicle 1 with delay -30, got %v %v", got[0].ID, got[0].Tags)
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		layer map[string]interface{}
+		err   string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := NewTileProvider(dict.Dict{
+				ConfigKeyLayers: []map[string]interface{}{tc.layer},
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing name": {
+			layer: map[string]interface{}{ConfigKeyURL: "http://localhost/vehicles"},
+			err:   "layer's name field",
+		},
+		"missing url": {
+			layer: map[string]interface{}{ConfigKeyLayerName: "a"},
+			err:   "missing 'url'",
+		},
+		"invalid interval": {
+			layer: map[string]interface{}{ConfigKeyLayerName: "a", ConfigKeyURL: "http://localhost/vehicles", ConfigKeyInterval: 0},
+			err:   "invalid interval (0)",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
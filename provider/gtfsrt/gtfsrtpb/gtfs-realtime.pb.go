@@ -0,0 +1,578 @@
+// Package gtfsrtpb holds the messages of gtfs-realtime.proto, the subset of the GTFS Realtime
+// protocol read by the gtfsrt provider.
+//
+// The messages follow the layout protoc-gen-go generates for github.com/golang/protobuf, but
+// are maintained by hand alongside gtfs-realtime.proto. Keep the field numbers and tags in
+// sync with gtfs-realtime.proto.
+package gtfsrtpb
+
+import proto "github.com/golang/protobuf/proto"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type FeedHeader_Incrementality int32
+
+const (
+	FeedHeader_FULL_DATASET FeedHeader_Incrementality = 0
+	FeedHeader_DIFFERENTIAL FeedHeader_Incrementality = 1
+)
+
+var FeedHeader_Incrementality_name = map[int32]string{
+	0: "FULL_DATASET",
+	1: "DIFFERENTIAL",
+}
+var FeedHeader_Incrementality_value = map[string]int32{
+	"FULL_DATASET": 0,
+	"DIFFERENTIAL": 1,
+}
+
+func (x FeedHeader_Incrementality) Enum() *FeedHeader_Incrementality {
+	p := new(FeedHeader_Incrementality)
+	*p = x
+	return p
+}
+func (x FeedHeader_Incrementality) String() string {
+	return proto.EnumName(FeedHeader_Incrementality_name, int32(x))
+}
+
+type VehiclePosition_VehicleStopStatus int32
+
+const (
+	VehiclePosition_INCOMING_AT   VehiclePosition_VehicleStopStatus = 0
+	VehiclePosition_STOPPED_AT    VehiclePosition_VehicleStopStatus = 1
+	VehiclePosition_IN_TRANSIT_TO VehiclePosition_VehicleStopStatus = 2
+)
+
+var VehiclePosition_VehicleStopStatus_name = map[int32]string{
+	0: "INCOMING_AT",
+	1: "STOPPED_AT",
+	2: "IN_TRANSIT_TO",
+}
+var VehiclePosition_VehicleStopStatus_value = map[string]int32{
+	"INCOMING_AT":   0,
+	"STOPPED_AT":    1,
+	"IN_TRANSIT_TO": 2,
+}
+
+func (x VehiclePosition_VehicleStopStatus) Enum() *VehiclePosition_VehicleStopStatus {
+	p := new(VehiclePosition_VehicleStopStatus)
+	*p = x
+	return p
+}
+func (x VehiclePosition_VehicleStopStatus) String() string {
+	return proto.EnumName(VehiclePosition_VehicleStopStatus_name, int32(x))
+}
+
+type VehiclePosition_CongestionLevel int32
+
+const (
+	VehiclePosition_UNKNOWN_CONGESTION_LEVEL VehiclePosition_CongestionLevel = 0
+	VehiclePosition_RUNNING_SMOOTHLY         VehiclePosition_CongestionLevel = 1
+	VehiclePosition_STOP_AND_GO              VehiclePosition_CongestionLevel = 2
+	VehiclePosition_CONGESTION               VehiclePosition_CongestionLevel = 3
+	VehiclePosition_SEVERE_CONGESTION        VehiclePosition_CongestionLevel = 4
+)
+
+var VehiclePosition_CongestionLevel_name = map[int32]string{
+	0: "UNKNOWN_CONGESTION_LEVEL",
+	1: "RUNNING_SMOOTHLY",
+	2: "STOP_AND_GO",
+	3: "CONGESTION",
+	4: "SEVERE_CONGESTION",
+}
+var VehiclePosition_CongestionLevel_value = map[string]int32{
+	"UNKNOWN_CONGESTION_LEVEL": 0,
+	"RUNNING_SMOOTHLY":         1,
+	"STOP_AND_GO":              2,
+	"CONGESTION":               3,
+	"SEVERE_CONGESTION":        4,
+}
+
+func (x VehiclePosition_CongestionLevel) Enum() *VehiclePosition_CongestionLevel {
+	p := new(VehiclePosition_CongestionLevel)
+	*p = x
+	return p
+}
+func (x VehiclePosition_CongestionLevel) String() string {
+	return proto.EnumName(VehiclePosition_CongestionLevel_name, int32(x))
+}
+
+type VehiclePosition_OccupancyStatus int32
+
+const (
+	VehiclePosition_EMPTY                      VehiclePosition_OccupancyStatus = 0
+	VehiclePosition_MANY_SEATS_AVAILABLE       VehiclePosition_OccupancyStatus = 1
+	VehiclePosition_FEW_SEATS_AVAILABLE        VehiclePosition_OccupancyStatus = 2
+	VehiclePosition_STANDING_ROOM_ONLY         VehiclePosition_OccupancyStatus = 3
+	VehiclePosition_CRUSHED_STANDING_ROOM_ONLY VehiclePosition_OccupancyStatus = 4
+	VehiclePosition_FULL                       VehiclePosition_OccupancyStatus = 5
+	VehiclePosition_NOT_ACCEPTING_PASSENGERS   VehiclePosition_OccupancyStatus = 6
+)
+
+var VehiclePosition_OccupancyStatus_name = map[int32]string{
+	0: "EMPTY",
+	1: "MANY_SEATS_AVAILABLE",
+	2: "FEW_SEATS_AVAILABLE",
+	3: "STANDING_ROOM_ONLY",
+	4: "CRUSHED_STANDING_ROOM_ONLY",
+	5: "FULL",
+	6: "NOT_ACCEPTING_PASSENGERS",
+}
+var VehiclePosition_OccupancyStatus_value = map[string]int32{
+	"EMPTY":                      0,
+	"MANY_SEATS_AVAILABLE":       1,
+	"FEW_SEATS_AVAILABLE":        2,
+	"STANDING_ROOM_ONLY":         3,
+	"CRUSHED_STANDING_ROOM_ONLY": 4,
+	"FULL":                       5,
+	"NOT_ACCEPTING_PASSENGERS":   6,
+}
+
+func (x VehiclePosition_OccupancyStatus) Enum() *VehiclePosition_OccupancyStatus {
+	p := new(VehiclePosition_OccupancyStatus)
+	*p = x
+	return p
+}
+func (x VehiclePosition_OccupancyStatus) String() string {
+	return proto.EnumName(VehiclePosition_OccupancyStatus_name, int32(x))
+}
+
+type FeedMessage struct {
+	Header *FeedHeader   `protobuf:"bytes,1,req,name=header" json:"header,omitempty"`
+	Entity []*FeedEntity `protobuf:"bytes,2,rep,name=entity" json:"entity,omitempty"`
+}
+
+func (m *FeedMessage) Reset()         { *m = FeedMessage{} }
+func (m *FeedMessage) String() string { return proto.CompactTextString(m) }
+func (*FeedMessage) ProtoMessage()    {}
+
+func (m *FeedMessage) GetHeader() *FeedHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *FeedMessage) GetEntity() []*FeedEntity {
+	if m != nil {
+		return m.Entity
+	}
+	return nil
+}
+
+type FeedHeader struct {
+	GtfsRealtimeVersion *string                    `protobuf:"bytes,1,req,name=gtfs_realtime_version,json=gtfsRealtimeVersion" json:"gtfs_realtime_version,omitempty"`
+	Incrementality      *FeedHeader_Incrementality `protobuf:"varint,2,opt,name=incrementality,enum=transit_realtime.FeedHeader_Incrementality,def=0" json:"incrementality,omitempty"`
+	Timestamp           *uint64                    `protobuf:"varint,3,opt,name=timestamp" json:"timestamp,omitempty"`
+}
+
+func (m *FeedHeader) Reset()         { *m = FeedHeader{} }
+func (m *FeedHeader) String() string { return proto.CompactTextString(m) }
+func (*FeedHeader) ProtoMessage()    {}
+
+const Default_FeedHeader_Incrementality FeedHeader_Incrementality = FeedHeader_FULL_DATASET
+
+func (m *FeedHeader) GetGtfsRealtimeVersion() string {
+	if m != nil && m.GtfsRealtimeVersion != nil {
+		return *m.GtfsRealtimeVersion
+	}
+	return ""
+}
+
+func (m *FeedHeader) GetIncrementality() FeedHeader_Incrementality {
+	if m != nil && m.Incrementality != nil {
+		return *m.Incrementality
+	}
+	return Default_FeedHeader_Incrementality
+}
+
+func (m *FeedHeader) GetTimestamp() uint64 {
+	if m != nil && m.Timestamp != nil {
+		return *m.Timestamp
+	}
+	return 0
+}
+
+type FeedEntity struct {
+	Id         *string          `protobuf:"bytes,1,req,name=id" json:"id,omitempty"`
+	IsDeleted  *bool            `protobuf:"varint,2,opt,name=is_deleted,json=isDeleted,def=0" json:"is_deleted,omitempty"`
+	TripUpdate *TripUpdate      `protobuf:"bytes,3,opt,name=trip_update,json=tripUpdate" json:"trip_update,omitempty"`
+	Vehicle    *VehiclePosition `protobuf:"bytes,4,opt,name=vehicle" json:"vehicle,omitempty"`
+}
+
+func (m *FeedEntity) Reset()         { *m = FeedEntity{} }
+func (m *FeedEntity) String() string { return proto.CompactTextString(m) }
+func (*FeedEntity) ProtoMessage()    {}
+
+const Default_FeedEntity_IsDeleted bool = false
+
+func (m *FeedEntity) GetId() string {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return ""
+}
+
+func (m *FeedEntity) GetIsDeleted() bool {
+	if m != nil && m.IsDeleted != nil {
+		return *m.IsDeleted
+	}
+	return Default_FeedEntity_IsDeleted
+}
+
+func (m *FeedEntity) GetTripUpdate() *TripUpdate {
+	if m != nil {
+		return m.TripUpdate
+	}
+	return nil
+}
+
+func (m *FeedEntity) GetVehicle() *VehiclePosition {
+	if m != nil {
+		return m.Vehicle
+	}
+	return nil
+}
+
+type TripUpdate struct {
+	Trip           *TripDescriptor              `protobuf:"bytes,1,req,name=trip" json:"trip,omitempty"`
+	Vehicle        *VehicleDescriptor           `protobuf:"bytes,3,opt,name=vehicle" json:"vehicle,omitempty"`
+	StopTimeUpdate []*TripUpdate_StopTimeUpdate `protobuf:"bytes,2,rep,name=stop_time_update,json=stopTimeUpdate" json:"stop_time_update,omitempty"`
+	Timestamp      *uint64                      `protobuf:"varint,4,opt,name=timestamp" json:"timestamp,omitempty"`
+	Delay          *int32                       `protobuf:"varint,5,opt,name=delay" json:"delay,omitempty"`
+}
+
+func (m *TripUpdate) Reset()         { *m = TripUpdate{} }
+func (m *TripUpdate) String() string { return proto.CompactTextString(m) }
+func (*TripUpdate) ProtoMessage()    {}
+
+func (m *TripUpdate) GetTrip() *TripDescriptor {
+	if m != nil {
+		return m.Trip
+	}
+	return nil
+}
+
+func (m *TripUpdate) GetVehicle() *VehicleDescriptor {
+	if m != nil {
+		return m.Vehicle
+	}
+	return nil
+}
+
+func (m *TripUpdate) GetStopTimeUpdate() []*TripUpdate_StopTimeUpdate {
+	if m != nil {
+		return m.StopTimeUpdate
+	}
+	return nil
+}
+
+func (m *TripUpdate) GetTimestamp() uint64 {
+	if m != nil && m.Timestamp != nil {
+		return *m.Timestamp
+	}
+	return 0
+}
+
+func (m *TripUpdate) GetDelay() int32 {
+	if m != nil && m.Delay != nil {
+		return *m.Delay
+	}
+	return 0
+}
+
+type TripUpdate_StopTimeEvent struct {
+	Delay       *int32 `protobuf:"varint,1,opt,name=delay" json:"delay,omitempty"`
+	Time        *int64 `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+	Uncertainty *int32 `protobuf:"varint,3,opt,name=uncertainty" json:"uncertainty,omitempty"`
+}
+
+func (m *TripUpdate_StopTimeEvent) Reset()         { *m = TripUpdate_StopTimeEvent{} }
+func (m *TripUpdate_StopTimeEvent) String() string { return proto.CompactTextString(m) }
+func (*TripUpdate_StopTimeEvent) ProtoMessage()    {}
+
+func (m *TripUpdate_StopTimeEvent) GetDelay() int32 {
+	if m != nil && m.Delay != nil {
+		return *m.Delay
+	}
+	return 0
+}
+
+func (m *TripUpdate_StopTimeEvent) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+func (m *TripUpdate_StopTimeEvent) GetUncertainty() int32 {
+	if m != nil && m.Uncertainty != nil {
+		return *m.Uncertainty
+	}
+	return 0
+}
+
+type TripUpdate_StopTimeUpdate struct {
+	StopSequence *uint32                   `protobuf:"varint,1,opt,name=stop_sequence,json=stopSequence" json:"stop_sequence,omitempty"`
+	StopId       *string                   `protobuf:"bytes,4,opt,name=stop_id,json=stopId" json:"stop_id,omitempty"`
+	Arrival      *TripUpdate_StopTimeEvent `protobuf:"bytes,2,opt,name=arrival" json:"arrival,omitempty"`
+	Departure    *TripUpdate_StopTimeEvent `protobuf:"bytes,3,opt,name=departure" json:"departure,omitempty"`
+}
+
+func (m *TripUpdate_StopTimeUpdate) Reset()         { *m = TripUpdate_StopTimeUpdate{} }
+func (m *TripUpdate_StopTimeUpdate) String() string { return proto.CompactTextString(m) }
+func (*TripUpdate_StopTimeUpdate) ProtoMessage()    {}
+
+func (m *TripUpdate_StopTimeUpdate) GetStopSequence() uint32 {
+	if m != nil && m.StopSequence != nil {
+		return *m.StopSequence
+	}
+	return 0
+}
+
+func (m *TripUpdate_StopTimeUpdate) GetStopId() string {
+	if m != nil && m.StopId != nil {
+		return *m.StopId
+	}
+	return ""
+}
+
+func (m *TripUpdate_StopTimeUpdate) GetArrival() *TripUpdate_StopTimeEvent {
+	if m != nil {
+		return m.Arrival
+	}
+	return nil
+}
+
+func (m *TripUpdate_StopTimeUpdate) GetDeparture() *TripUpdate_StopTimeEvent {
+	if m != nil {
+		return m.Departure
+	}
+	return nil
+}
+
+type VehiclePosition struct {
+	Trip                *TripDescriptor                    `protobuf:"bytes,1,opt,name=trip" json:"trip,omitempty"`
+	Vehicle             *VehicleDescriptor                 `protobuf:"bytes,8,opt,name=vehicle" json:"vehicle,omitempty"`
+	Position            *Position                          `protobuf:"bytes,2,opt,name=position" json:"position,omitempty"`
+	CurrentStopSequence *uint32                            `protobuf:"varint,3,opt,name=current_stop_sequence,json=currentStopSequence" json:"current_stop_sequence,omitempty"`
+	StopId              *string                            `protobuf:"bytes,7,opt,name=stop_id,json=stopId" json:"stop_id,omitempty"`
+	CurrentStatus       *VehiclePosition_VehicleStopStatus `protobuf:"varint,4,opt,name=current_status,json=currentStatus,enum=transit_realtime.VehiclePosition_VehicleStopStatus,def=2" json:"current_status,omitempty"`
+	Timestamp           *uint64                            `protobuf:"varint,5,opt,name=timestamp" json:"timestamp,omitempty"`
+	CongestionLevel     *VehiclePosition_CongestionLevel   `protobuf:"varint,6,opt,name=congestion_level,json=congestionLevel,enum=transit_realtime.VehiclePosition_CongestionLevel" json:"congestion_level,omitempty"`
+	OccupancyStatus     *VehiclePosition_OccupancyStatus   `protobuf:"varint,9,opt,name=occupancy_status,json=occupancyStatus,enum=transit_realtime.VehiclePosition_OccupancyStatus" json:"occupancy_status,omitempty"`
+}
+
+func (m *VehiclePosition) Reset()         { *m = VehiclePosition{} }
+func (m *VehiclePosition) String() string { return proto.CompactTextString(m) }
+func (*VehiclePosition) ProtoMessage()    {}
+
+const Default_VehiclePosition_CurrentStatus VehiclePosition_VehicleStopStatus = VehiclePosition_IN_TRANSIT_TO
+
+func (m *VehiclePosition) GetTrip() *TripDescriptor {
+	if m != nil {
+		return m.Trip
+	}
+	return nil
+}
+
+func (m *VehiclePosition) GetVehicle() *VehicleDescriptor {
+	if m != nil {
+		return m.Vehicle
+	}
+	return nil
+}
+
+func (m *VehiclePosition) GetPosition() *Position {
+	if m != nil {
+		return m.Position
+	}
+	return nil
+}
+
+func (m *VehiclePosition) GetCurrentStopSequence() uint32 {
+	if m != nil && m.CurrentStopSequence != nil {
+		return *m.CurrentStopSequence
+	}
+	return 0
+}
+
+func (m *VehiclePosition) GetStopId() string {
+	if m != nil && m.StopId != nil {
+		return *m.StopId
+	}
+	return ""
+}
+
+func (m *VehiclePosition) GetCurrentStatus() VehiclePosition_VehicleStopStatus {
+	if m != nil && m.CurrentStatus != nil {
+		return *m.CurrentStatus
+	}
+	return Default_VehiclePosition_CurrentStatus
+}
+
+func (m *VehiclePosition) GetTimestamp() uint64 {
+	if m != nil && m.Timestamp != nil {
+		return *m.Timestamp
+	}
+	return 0
+}
+
+func (m *VehiclePosition) GetCongestionLevel() VehiclePosition_CongestionLevel {
+	if m != nil && m.CongestionLevel != nil {
+		return *m.CongestionLevel
+	}
+	return VehiclePosition_UNKNOWN_CONGESTION_LEVEL
+}
+
+func (m *VehiclePosition) GetOccupancyStatus() VehiclePosition_OccupancyStatus {
+	if m != nil && m.OccupancyStatus != nil {
+		return *m.OccupancyStatus
+	}
+	return VehiclePosition_EMPTY
+}
+
+type Position struct {
+	Latitude  *float32 `protobuf:"fixed32,1,req,name=latitude" json:"latitude,omitempty"`
+	Longitude *float32 `protobuf:"fixed32,2,req,name=longitude" json:"longitude,omitempty"`
+	Bearing   *float32 `protobuf:"fixed32,3,opt,name=bearing" json:"bearing,omitempty"`
+	Odometer  *float64 `protobuf:"fixed64,4,opt,name=odometer" json:"odometer,omitempty"`
+	Speed     *float32 `protobuf:"fixed32,5,opt,name=speed" json:"speed,omitempty"`
+}
+
+func (m *Position) Reset()         { *m = Position{} }
+func (m *Position) String() string { return proto.CompactTextString(m) }
+func (*Position) ProtoMessage()    {}
+
+func (m *Position) GetLatitude() float32 {
+	if m != nil && m.Latitude != nil {
+		return *m.Latitude
+	}
+	return 0
+}
+
+func (m *Position) GetLongitude() float32 {
+	if m != nil && m.Longitude != nil {
+		return *m.Longitude
+	}
+	return 0
+}
+
+func (m *Position) GetBearing() float32 {
+	if m != nil && m.Bearing != nil {
+		return *m.Bearing
+	}
+	return 0
+}
+
+func (m *Position) GetOdometer() float64 {
+	if m != nil && m.Odometer != nil {
+		return *m.Odometer
+	}
+	return 0
+}
+
+func (m *Position) GetSpeed() float32 {
+	if m != nil && m.Speed != nil {
+		return *m.Speed
+	}
+	return 0
+}
+
+type TripDescriptor struct {
+	TripId      *string `protobuf:"bytes,1,opt,name=trip_id,json=tripId" json:"trip_id,omitempty"`
+	RouteId     *string `protobuf:"bytes,5,opt,name=route_id,json=routeId" json:"route_id,omitempty"`
+	DirectionId *uint32 `protobuf:"varint,6,opt,name=direction_id,json=directionId" json:"direction_id,omitempty"`
+	StartTime   *string `protobuf:"bytes,2,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	StartDate   *string `protobuf:"bytes,3,opt,name=start_date,json=startDate" json:"start_date,omitempty"`
+}
+
+func (m *TripDescriptor) Reset()         { *m = TripDescriptor{} }
+func (m *TripDescriptor) String() string { return proto.CompactTextString(m) }
+func (*TripDescriptor) ProtoMessage()    {}
+
+func (m *TripDescriptor) GetTripId() string {
+	if m != nil && m.TripId != nil {
+		return *m.TripId
+	}
+	return ""
+}
+
+func (m *TripDescriptor) GetRouteId() string {
+	if m != nil && m.RouteId != nil {
+		return *m.RouteId
+	}
+	return ""
+}
+
+func (m *TripDescriptor) GetDirectionId() uint32 {
+	if m != nil && m.DirectionId != nil {
+		return *m.DirectionId
+	}
+	return 0
+}
+
+func (m *TripDescriptor) GetStartTime() string {
+	if m != nil && m.StartTime != nil {
+		return *m.StartTime
+	}
+	return ""
+}
+
+func (m *TripDescriptor) GetStartDate() string {
+	if m != nil && m.StartDate != nil {
+		return *m.StartDate
+	}
+	return ""
+}
+
+type VehicleDescriptor struct {
+	Id           *string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Label        *string `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	LicensePlate *string `protobuf:"bytes,3,opt,name=license_plate,json=licensePlate" json:"license_plate,omitempty"`
+}
+
+func (m *VehicleDescriptor) Reset()         { *m = VehicleDescriptor{} }
+func (m *VehicleDescriptor) String() string { return proto.CompactTextString(m) }
+func (*VehicleDescriptor) ProtoMessage()    {}
+
+func (m *VehicleDescriptor) GetId() string {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return ""
+}
+
+func (m *VehicleDescriptor) GetLabel() string {
+	if m != nil && m.Label != nil {
+		return *m.Label
+	}
+	return ""
+}
+
+func (m *VehicleDescriptor) GetLicensePlate() string {
+	if m != nil && m.LicensePlate != nil {
+		return *m.LicensePlate
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*FeedMessage)(nil), "transit_realtime.FeedMessage")
+	proto.RegisterType((*FeedHeader)(nil), "transit_realtime.FeedHeader")
+	proto.RegisterType((*FeedEntity)(nil), "transit_realtime.FeedEntity")
+	proto.RegisterType((*TripUpdate)(nil), "transit_realtime.TripUpdate")
+	proto.RegisterType((*TripUpdate_StopTimeEvent)(nil), "transit_realtime.TripUpdate.StopTimeEvent")
+	proto.RegisterType((*TripUpdate_StopTimeUpdate)(nil), "transit_realtime.TripUpdate.StopTimeUpdate")
+	proto.RegisterType((*VehiclePosition)(nil), "transit_realtime.VehiclePosition")
+	proto.RegisterType((*Position)(nil), "transit_realtime.Position")
+	proto.RegisterType((*TripDescriptor)(nil), "transit_realtime.TripDescriptor")
+	proto.RegisterType((*VehicleDescriptor)(nil), "transit_realtime.VehicleDescriptor")
+	proto.RegisterEnum("transit_realtime.FeedHeader_Incrementality", FeedHeader_Incrementality_name, FeedHeader_Incrementality_value)
+	proto.RegisterEnum("transit_realtime.VehiclePosition_VehicleStopStatus", VehiclePosition_VehicleStopStatus_name, VehiclePosition_VehicleStopStatus_value)
+	proto.RegisterEnum("transit_realtime.VehiclePosition_CongestionLevel", VehiclePosition_CongestionLevel_name, VehiclePosition_CongestionLevel_value)
+	proto.RegisterEnum("transit_realtime.VehiclePosition_OccupancyStatus", VehiclePosition_OccupancyStatus_name, VehiclePosition_OccupancyStatus_value)
+}
@@ -0,0 +1,48 @@
+package gtfsrt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/provider"
+)
+
+type Layer struct {
+	name string
+	// url is the VehiclePositions feed of the layer
+	url string
+	// tripUpdatesURL is the TripUpdates feed the delays of vehicles are read from. optional
+	tripUpdatesURL string
+	// interval is the time between refreshing the feeds
+	interval time.Duration
+
+	vehicles *vehicles
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return geom.Point{} }
+func (l Layer) SRID() uint64            { return tegola.WGS84 }
+
+// vehicles holds the features of the last refresh of a layer's feeds. It's safe for concurrent use.
+type vehicles struct {
+	mu       sync.RWMutex
+	features []provider.Feature
+	// updated is the timestamp of the feed, or the time of the refresh when the feed has no timestamp
+	updated time.Time
+}
+
+func (v *vehicles) set(features []provider.Feature, updated time.Time) {
+	v.mu.Lock()
+	v.features, v.updated = features, updated
+	v.mu.Unlock()
+}
+
+func (v *vehicles) get() ([]provider.Feature, time.Time) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.features, v.updated
+}
@@ -0,0 +1,88 @@
+package httpjson
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingLayerName = errors.New("httpjson: layer is missing 'name'")
+)
+
+type ErrMissingURL struct {
+	LayerName string
+}
+
+func (e ErrMissingURL) Error() string {
+	return fmt.Sprintf("httpjson: layer (%v) is missing 'url'", e.LayerName)
+}
+
+type ErrMissingGeometry struct {
+	LayerName string
+}
+
+func (e ErrMissingGeometry) Error() string {
+	return fmt.Sprintf("httpjson: layer (%v) needs either 'geometry' or both 'x' and 'y'", e.LayerName)
+}
+
+type ErrInvalidExpression struct {
+	Expression string
+	Reason     string
+}
+
+func (e ErrInvalidExpression) Error() string {
+	return fmt.Sprintf("httpjson: invalid expression (%v): %v", e.Expression, e.Reason)
+}
+
+type ErrUnsupportedSRID struct {
+	SRID int
+}
+
+func (e ErrUnsupportedSRID) Error() string {
+	return fmt.Sprintf("httpjson: unsupported srid (%v), expected 4326 or 3857", e.SRID)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("httpjson: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("httpjson: layer (%v) not found", e.LayerName)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("httpjson: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
+
+// ErrStatus is returned when the API responds with an unexpected status
+type ErrStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("httpjson: request (%v) responded with status %v", e.URL, e.Status)
+}
+
+// ErrNotAList is returned when a layer's features expression doesn't select a list
+type ErrNotAList struct {
+	LayerName  string
+	Expression string
+}
+
+func (e ErrNotAList) Error() string {
+	return fmt.Sprintf("httpjson: layer (%v) features expression (%v) did not select a list", e.LayerName, e.Expression)
+}
@@ -0,0 +1,243 @@
+package httpjson
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type stepKind int
+
+const (
+	stepField stepKind = iota
+	stepIndex
+	// stepProject applies the remaining steps to each element of a list or each value of an object
+	stepProject
+	// stepFlatten flattens a list of lists one level before projecting
+	stepFlatten
+)
+
+type step struct {
+	kind  stepKind
+	field string
+	index int
+}
+
+// expression is a compiled JMESPath expression. The subset of JMESPath needed to select
+// values from API responses is supported:
+//
+//	@                  the current value
+//	foo.bar            object fields, "quoted fields" for names with special characters
+//	foo[0], foo[-1]    list indexes
+//	foo[*].bar         list projections
+//	foo.*              object value projections
+//	foo[].bar          flatten projections
+//
+// JSONPath style expressions starting at the root ($.foo.bar, $['foo'][0]) are
+// accepted as well.
+type expression struct {
+	src   string
+	steps []step
+}
+
+func compileExpression(src string) (expression, error) {
+	e := expression{src: src}
+	s := strings.TrimSpace(src)
+
+	fail := func(reason string) (expression, error) {
+		return expression{}, ErrInvalidExpression{Expression: src, Reason: reason}
+	}
+
+	// JSONPath root
+	if strings.HasPrefix(s, "$") {
+		s = strings.TrimPrefix(s[1:], ".")
+	}
+	if s == "" || s == "@" {
+		return e, nil
+	}
+
+	// a field is expected at the start of the expression and after a dot
+	expectField := true
+	for len(s) > 0 {
+		switch {
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end == -1 {
+				return fail("missing ]")
+			}
+			inner := strings.TrimSpace(s[1:end])
+			switch {
+			case inner == "*":
+				e.steps = append(e.steps, step{kind: stepProject})
+			case inner == "":
+				e.steps = append(e.steps, step{kind: stepFlatten})
+			case inner[0] == '\'' || inner[0] == '"':
+				// JSONPath bracket notation
+				if len(inner) < 2 || inner[len(inner)-1] != inner[0] {
+					return fail("unterminated field name")
+				}
+				e.steps = append(e.steps, step{kind: stepField, field: inner[1 : len(inner)-1]})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return fail("invalid index " + inner)
+				}
+				e.steps = append(e.steps, step{kind: stepIndex, index: i})
+			}
+			s = s[end+1:]
+			expectField = false
+
+		case s[0] == '.':
+			if expectField {
+				return fail("unexpected .")
+			}
+			s = s[1:]
+			expectField = true
+
+		case !expectField:
+			return fail("unexpected " + strconv.Quote(s[:1]))
+
+		case s[0] == '*':
+			e.steps = append(e.steps, step{kind: stepProject})
+			s = s[1:]
+			expectField = false
+
+		case s[0] == '@':
+			s = s[1:]
+			expectField = false
+
+		case s[0] == '"':
+			end := 1
+			for ; end < len(s) && s[end] != '"'; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(s) {
+				return fail("unterminated field name")
+			}
+			field, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				return fail("invalid field name " + s[:end+1])
+			}
+			e.steps = append(e.steps, step{kind: stepField, field: field})
+			s = s[end+1:]
+			expectField = false
+
+		default:
+			end := 0
+			for ; end < len(s) && isIdentifier(s[end], end == 0); end++ {
+			}
+			if end == 0 {
+				return fail("unexpected " + strconv.Quote(s[:1]))
+			}
+			e.steps = append(e.steps, step{kind: stepField, field: s[:end]})
+			s = s[end:]
+			expectField = false
+		}
+	}
+	if expectField {
+		return fail("missing field after .")
+	}
+
+	return e, nil
+}
+
+func isIdentifier(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// String returns the expression's source
+func (e expression) String() string { return e.src }
+
+// search evaluates the expression against a decoded JSON value. nil is returned when
+// the expression doesn't match, as JMESPath does.
+func (e expression) search(v interface{}) interface{} {
+	return evaluate(e.steps, v)
+}
+
+func evaluate(steps []step, v interface{}) interface{} {
+	for i, s := range steps {
+		if v == nil {
+			return nil
+		}
+
+		switch s.kind {
+		case stepField:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			v = m[s.field]
+
+		case stepIndex:
+			l, ok := v.([]interface{})
+			if !ok {
+				return nil
+			}
+			idx := s.index
+			if idx < 0 {
+				idx += len(l)
+			}
+			if idx < 0 || idx >= len(l) {
+				return nil
+			}
+			v = l[idx]
+
+		case stepProject, stepFlatten:
+			var elems []interface{}
+			switch vv := v.(type) {
+			case []interface{}:
+				if s.kind == stepProject {
+					elems = vv
+					break
+				}
+				for _, el := range vv {
+					if l, ok := el.([]interface{}); ok {
+						elems = append(elems, l...)
+					} else {
+						elems = append(elems, el)
+					}
+				}
+			case map[string]interface{}:
+				if s.kind == stepFlatten {
+					return nil
+				}
+				// object values are projected in key order for stable results
+				keys := make([]string, 0, len(vv))
+				for k := range vv {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				for _, k := range keys {
+					elems = append(elems, vv[k])
+				}
+			default:
+				return nil
+			}
+
+			// the projection ends at the next flatten, which flattens the projected results
+			rest, next := steps[i+1:], []step(nil)
+			for j := range rest {
+				if rest[j].kind == stepFlatten {
+					rest, next = rest[:j], rest[j:]
+					break
+				}
+			}
+
+			// null results are dropped from projections
+			result := []interface{}{}
+			for _, el := range elems {
+				if r := evaluate(rest, el); r != nil {
+					result = append(result, r)
+				}
+			}
+			if next != nil {
+				return evaluate(next, result)
+			}
+			return result
+		}
+	}
+
+	return v
+}
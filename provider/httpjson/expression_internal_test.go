@@ -0,0 +1,104 @@
+package httpjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExpression(t *testing.T) {
+	type tcase struct {
+		expr     string
+		expected interface{}
+		err      error
+	}
+
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{
+		"data": {
+			"items": [
+				{"id": 1, "loc": {"lon": -122.4, "lat": 37.7}, "tags": ["a", "b"]},
+				{"id": 2, "loc": {"lon": -122.5, "lat": 37.8}, "tags": ["c"]},
+				{"id": 3}
+			]
+		},
+		"odd key": {"v": "x"}
+	}`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			e, err := compileExpression(tc.expr)
+			if tc.err != nil {
+				if err != tc.err {
+					t.Fatalf("expected error %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := e.search(doc); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"current": {
+			expr:     "@.data.items[0].id",
+			expected: 1.0,
+		},
+		"fields": {
+			expr:     "data.items[1].loc.lat",
+			expected: 37.8,
+		},
+		"negative index": {
+			expr:     "data.items[-1].id",
+			expected: 3.0,
+		},
+		"missing": {
+			expr:     "data.nope.id",
+			expected: nil,
+		},
+		"projection": {
+			expr:     "data.items[*].loc.lon",
+			expected: []interface{}{-122.4, -122.5},
+		},
+		"flatten": {
+			expr:     "data.items[].tags[]",
+			expected: []interface{}{"a", "b", "c"},
+		},
+		"object projection": {
+			expr:     `"odd key".*`,
+			expected: []interface{}{"x"},
+		},
+		"jsonpath": {
+			expr:     "$.data['items'][0].id",
+			expected: 1.0,
+		},
+		"jsonpath root": {
+			expr:     "$",
+			expected: doc,
+		},
+		"trailing dot": {
+			expr: "data.",
+			err:  ErrInvalidExpression{Expression: "data.", Reason: "missing field after ."},
+		},
+		"missing bracket": {
+			expr: "data.items[0",
+			err:  ErrInvalidExpression{Expression: "data.items[0", Reason: "missing ]"},
+		},
+		"invalid index": {
+			expr: "data.items[a]",
+			err:  ErrInvalidExpression{Expression: "data.items[a]", Reason: "invalid index a"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
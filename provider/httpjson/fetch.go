@@ -0,0 +1,162 @@
+package httpjson
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// tileURL fills the layer's url template with the tile's buffered extent, in the provider's SRID, and zxy
+func (p *Provider) tileURL(layer Layer, tile provider.Tile) (string, error) {
+	ext, tileSRID := tile.BufferedExtent()
+	if p.srid == tegola.WGS84 && tileSRID != tegola.WGS84 {
+		min, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MinX(), ext.MinY()})
+		if err != nil {
+			return "", err
+		}
+		max, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MaxX(), ext.MaxY()})
+		if err != nil {
+			return "", err
+		}
+		minPt, maxPt := min.(geom.Point), max.(geom.Point)
+		ext = &geom.Extent{minPt.X(), minPt.Y(), maxPt.X(), maxPt.Y()}
+	}
+
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	z, x, y := tile.ZXY()
+
+	r := strings.NewReplacer(
+		"{minx}", f(ext.MinX()),
+		"{miny}", f(ext.MinY()),
+		"{maxx}", f(ext.MaxX()),
+		"{maxy}", f(ext.MaxY()),
+		"{bbox}", f(ext.MinX())+","+f(ext.MinY())+","+f(ext.MaxX())+","+f(ext.MaxY()),
+		"{z}", strconv.FormatUint(uint64(z), 10),
+		"{x}", strconv.FormatUint(uint64(x), 10),
+		"{y}", strconv.FormatUint(uint64(y), 10),
+	)
+	return r.Replace(layer.url), nil
+}
+
+// get requests the url and decodes the JSON response
+func (p *Provider) get(ctx context.Context, u string) (interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, provider.ErrCanceled
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrStatus{URL: u, Status: resp.StatusCode}
+	}
+
+	var body interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("httpjson: decoding (%v): %v", u, err)
+	}
+
+	return body, nil
+}
+
+// decode maps a response item to a provider feature. Items without a geometry are skipped.
+func (l Layer) decode(item interface{}) (provider.Feature, bool, error) {
+	var g geom.Geometry
+	if l.geometry != nil {
+		v := l.geometry.search(item)
+		if v == nil {
+			return provider.Feature{}, false, nil
+		}
+
+		// the geometry is re-encoded so the geojson package can decode it
+		b, err := json.Marshal(v)
+		if err != nil {
+			return provider.Feature{}, false, err
+		}
+		var gg geojson.Geometry
+		if err := json.Unmarshal(b, &gg); err != nil {
+			return provider.Feature{}, false, err
+		}
+		g = gg.Geometry
+	} else {
+		x, okX := number(l.x.search(item))
+		y, okY := number(l.y.search(item))
+		if !okX || !okY {
+			return provider.Feature{}, false, nil
+		}
+		g = geom.Point{x, y}
+	}
+
+	tags := map[string]interface{}{}
+	if l.properties != nil {
+		if props, ok := l.properties.search(item).(map[string]interface{}); ok {
+			for k, v := range props {
+				setTag(tags, k, v)
+			}
+		}
+	}
+	for k, expr := range l.fields {
+		setTag(tags, k, expr.search(item))
+	}
+
+	var id uint64
+	if l.id != nil {
+		id = provider.FeatureID(l.id.search(item))
+	}
+
+	return provider.Feature{
+		ID:       id,
+		Geometry: g,
+		SRID:     l.srid,
+		Tags:     tags,
+	}, true, nil
+}
+
+// number converts a JSON number or numeric string to a float
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// setTag adds the value to the tags. null values are dropped, objects and arrays are encoded as JSON strings
+func setTag(tags map[string]interface{}, k string, v interface{}) {
+	switch v.(type) {
+	case nil:
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		tags[k] = string(b)
+	default:
+		tags[k] = v
+	}
+}
@@ -0,0 +1,286 @@
+// Package httpjson provides a provider which fetches features from arbitrary HTTP JSON APIs.
+// Each layer requests a URL template filled with the tile's bounding box and zoom, and maps
+// the fields of the response to feature geometries and tags with JMESPath expressions.
+package httpjson
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "httpjson"
+
+const (
+	ConfigKeyHeaders = "headers"
+	ConfigKeyTimeout = "timeout"
+	ConfigKeySRID    = "srid"
+	ConfigKeyLayers  = "layers"
+
+	ConfigKeyLayerName    = "name"
+	ConfigKeyURL          = "url"
+	ConfigKeyFeatures     = "features"
+	ConfigKeyGeometry     = "geometry"
+	ConfigKeyX            = "x"
+	ConfigKeyY            = "y"
+	ConfigKeyID           = "id"
+	ConfigKeyProperties   = "properties"
+	ConfigKeyFields       = "fields"
+	ConfigKeyGeometryType = "geometry_type"
+)
+
+const (
+	DefaultTimeout  = 30
+	DefaultSRID     = tegola.WGS84
+	DefaultFeatures = "@"
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, nil)
+}
+
+// Provider fetches features from HTTP JSON APIs
+type Provider struct {
+	headers map[string]string
+	srid    uint64
+	client  *http.Client
+
+	// map of layer name and corresponding request and mapping
+	layers map[string]Layer
+}
+
+// NewTileProvider instantiates and returns a new httpjson provider or an error.
+//
+//	headers (map[string]string): [Optional] headers added to every request (i.e. API keys)
+//	timeout (int): [Optional] the number of seconds allowed per request. defaults to 30
+//	srid (int): [Optional] the SRID of the response coordinates and the bbox in the url. 4326 or 3857. defaults to 4326
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		url (string): [Required] the url template. {minx}, {miny}, {maxx}, {maxy}, {bbox}, {z}, {x} and {y} are replaced
+//		features (string): [Optional] the expression selecting the list of items in the response. defaults to "@"
+//		geometry (string): [Optional] the expression selecting an item's GeoJSON geometry
+//		x, y (string): [Optional] the expressions selecting an item's point coordinates, when geometry is not set
+//		id (string): [Optional] the expression selecting an item's id
+//		properties (string): [Optional] the expression selecting an object of an item encoded as tags
+//		fields (map[string]string): [Optional] tag names and the expressions selecting their values
+//		geometry_type (string): [Optional] the geometry type of the layer, reported in the capabilities
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	ints := []struct {
+		key string
+		val int
+	}{
+		{ConfigKeyTimeout, DefaultTimeout},
+		{ConfigKeySRID, DefaultSRID},
+	}
+	var err error
+	for i := range ints {
+		if ints[i].val, err = config.Int(ints[i].key, &ints[i].val); err != nil {
+			return nil, err
+		}
+		if ints[i].val < 0 {
+			return nil, fmt.Errorf("httpjson: %v must not be negative, got %v", ints[i].key, ints[i].val)
+		}
+	}
+	timeout, srid := ints[0].val, ints[1].val
+	if srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return nil, ErrUnsupportedSRID{SRID: srid}
+	}
+
+	headers, err := dict.StringMap(config, ConfigKeyHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Provider{
+		headers: headers,
+		srid:    uint64(srid),
+		client:  &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		layers:  map[string]Layer{},
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// AddLayer adds an API layer to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	empty := ""
+
+	url, err := layerConf.String(ConfigKeyURL, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyURL, err)
+	}
+	if url == "" {
+		return ErrMissingURL{LayerName: name}
+	}
+
+	l := Layer{
+		name:   name,
+		url:    url,
+		srid:   p.srid,
+		fields: map[string]expression{},
+	}
+
+	features := DefaultFeatures
+	if features, err = layerConf.String(ConfigKeyFeatures, &features); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyFeatures, err)
+	}
+	if l.features, err = compileExpression(features); err != nil {
+		return err
+	}
+
+	// the optional expressions of the layer
+	exprs := []struct {
+		key  string
+		expr **expression
+	}{
+		{ConfigKeyGeometry, &l.geometry},
+		{ConfigKeyX, &l.x},
+		{ConfigKeyY, &l.y},
+		{ConfigKeyID, &l.id},
+		{ConfigKeyProperties, &l.properties},
+	}
+	for _, e := range exprs {
+		src, err := layerConf.String(e.key, &empty)
+		if err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, e.key, err)
+		}
+		if src == "" {
+			continue
+		}
+		expr, err := compileExpression(src)
+		if err != nil {
+			return err
+		}
+		*e.expr = &expr
+	}
+	if l.geometry == nil && (l.x == nil || l.y == nil) {
+		return ErrMissingGeometry{LayerName: name}
+	}
+
+	fields, err := dict.StringMap(layerConf, ConfigKeyFields)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyFields, err)
+	}
+	for tag, src := range fields {
+		if l.fields[tag], err = compileExpression(src); err != nil {
+			return err
+		}
+	}
+
+	gtype, err := layerConf.String(ConfigKeyGeometryType, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyGeometryType, err)
+	}
+	var ok bool
+	if l.geomType, ok = provider.GeometryType(gtype); !ok {
+		return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+	}
+	if l.geomType == nil && l.geometry == nil {
+		l.geomType = geom.Point{}
+	}
+
+	p.layers[name] = l
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures requests the layer's url for the tile and maps the items of the response to features
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	u, err := p.tileURL(layer, tile)
+	if err != nil {
+		return err
+	}
+
+	body, err := p.get(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	items, ok := layer.features.search(body).([]interface{})
+	if !ok {
+		return ErrNotAList{LayerName: layer.name, Expression: layer.features.String()}
+	}
+
+	for i := range items {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		f, ok, err := layer.decode(items[i])
+		if err != nil {
+			return fmt.Errorf("httpjson: layer (%v) item %v: %v", layer.name, i, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package httpjson_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/httpjson"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/stations":
+			if r.URL.Query().Get("z") != "0" || r.URL.Query().Get("bbox") == "" {
+				t.Errorf("unexpected query (%v)", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"result": {"stations": [
+				{"code": 7, "position": {"lon": "-122.4", "lat": 37.6}, "info": {"name": "SFO", "bikes": 3, "open": true}},
+				{"code": "12", "position": {"lon": -122.3, "lat": 37.8}, "info": {"name": "Oakland", "bikes": 0, "open": false}},
+				{"code": "none", "position": null}
+			]}}`))
+		case "/features":
+			w.Write([]byte(`[{"geom": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}, "props": {"kind": "road", "lanes": [1, 2]}}]`))
+		case "/object":
+			w.Write([]byte(`{"result": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		layer    map[string]interface{}
+		expected []provider.Feature
+		err      string
+	}
+
+	srv := newServer(t)
+	defer srv.Close()
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer["name"] = "test"
+			p, err := httpjson.NewTileProvider(dict.Dict{
+				"headers": map[string]interface{}{"X-Api-Key": "secret"},
+				"layers":  []map[string]interface{}{tc.layer},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var features []provider.Feature
+			err = p.TileFeatures(context.Background(), "test", provider.NewTile(0, 0, 0, 64, tegola.WebMercator), func(f *provider.Feature) error {
+				features = append(features, *f)
+				return nil
+			})
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("expected error %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(features, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, features)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"points": {
+			layer: map[string]interface{}{
+				"url":      srv.URL + "/stations?bbox={bbox}&z={z}",
+				"features": "result.stations",
+				"x":        "position.lon",
+				"y":        "position.lat",
+				"id":       "code",
+				"fields":   map[string]interface{}{"name": "info.name", "bikes": "info.bikes"},
+			},
+			expected: []provider.Feature{
+				{ID: 7, Geometry: geom.Point{-122.4, 37.6}, SRID: tegola.WGS84, Tags: map[string]interface{}{"name": "SFO", "bikes": 3.0}},
+				{ID: 12, Geometry: geom.Point{-122.3, 37.8}, SRID: tegola.WGS84, Tags: map[string]interface{}{"name": "Oakland", "bikes": 0.0}},
+			},
+		},
+		"geojson geometry": {
+			layer: map[string]interface{}{
+				"url":        srv.URL + "/features",
+				"geometry":   "geom",
+				"properties": "props",
+			},
+			expected: []provider.Feature{
+				{Geometry: geom.LineString{{0, 0}, {1, 1}}, SRID: tegola.WGS84, Tags: map[string]interface{}{"kind": "road", "lanes": "[1,2]"}},
+			},
+		},
+		"not a list": {
+			layer: map[string]interface{}{
+				"url":      srv.URL + "/object",
+				"features": "result",
+				"geometry": "geom",
+			},
+			err: "httpjson: layer (test) features expression (result) did not select a list",
+		},
+		"status": {
+			layer: map[string]interface{}{
+				"url":      srv.URL + "/missing",
+				"geometry": "geom",
+			},
+			err: httpjson.ErrStatus{URL: srv.URL + "/missing", Status: http.StatusNotFound}.Error(),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		config dict.Dict
+		err    error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := httpjson.NewTileProvider(tc.config)
+			if err != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"valid": {
+			config: dict.Dict{"layers": []map[string]interface{}{{"name": "a", "url": "http://example.com", "x": "lon", "y": "lat"}}},
+		},
+		"missing url": {
+			config: dict.Dict{"layers": []map[string]interface{}{{"name": "a", "x": "lon", "y": "lat"}}},
+			err:    httpjson.ErrMissingURL{LayerName: "a"},
+		},
+		"missing geometry": {
+			config: dict.Dict{"layers": []map[string]interface{}{{"name": "a", "url": "http://example.com", "x": "lon"}}},
+			err:    httpjson.ErrMissingGeometry{LayerName: "a"},
+		},
+		"invalid expression": {
+			config: dict.Dict{"layers": []map[string]interface{}{{"name": "a", "url": "http://example.com", "geometry": "geom["}}},
+			err:    httpjson.ErrInvalidExpression{Expression: "geom[", Reason: "missing ]"},
+		},
+		"unsupported srid": {
+			config: dict.Dict{"srid": 2193},
+			err:    httpjson.ErrUnsupportedSRID{SRID: 2193},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,30 @@
+package httpjson
+
+import (
+	"github.com/go-spatial/geom"
+)
+
+type Layer struct {
+	name string
+	// url is the request template of the layer
+	url string
+	// features selects the list of items from the response
+	features expression
+	// geometry selects a GeoJSON geometry of an item. x and y are used when it's not set
+	geometry *expression
+	x, y     *expression
+	// id selects the feature id of an item
+	id *expression
+	// properties selects an object of an item whose values are all encoded as tags
+	properties *expression
+	// fields maps tag names to the expressions selecting their values
+	fields map[string]expression
+	// geomType is the configured geometry type of the layer, nil when unknown
+	geomType geom.Geometry
+	srid     uint64
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
@@ -0,0 +1,39 @@
+package provider
+
+import "sync"
+
+// instances are the configured providers keyed by their config name, so providers which
+// wrap other providers (i.e. composite) can look up the providers configured before them
+var (
+	instancesLock sync.RWMutex
+	instances     = map[string]TilerUnion{}
+)
+
+// SetInstance records the configured provider under its config name, replacing any
+// provider previously configured with the name
+func SetInstance(name string, p TilerUnion) {
+	instancesLock.Lock()
+	instances[name] = p
+	instancesLock.Unlock()
+}
+
+// Instance returns the provider configured with the name
+func Instance(name string) (TilerUnion, bool) {
+	instancesLock.RLock()
+	defer instancesLock.RUnlock()
+
+	p, ok := instances[name]
+	return p, ok
+}
+
+// Instances returns the configured providers keyed by their config name
+func Instances() map[string]TilerUnion {
+	instancesLock.RLock()
+	defer instancesLock.RUnlock()
+
+	ps := make(map[string]TilerUnion, len(instances))
+	for name, p := range instances {
+		ps[name] = p
+	}
+	return ps
+}
@@ -0,0 +1,79 @@
+package live
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingLayerName = errors.New("live: layer is missing 'name'")
+	ErrEmptyGeometry    = errors.New("live: feature geometry is empty")
+)
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("live: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrInvalidSource struct {
+	LayerName string
+	Source    string
+}
+
+func (e ErrInvalidSource) Error() string {
+	return fmt.Sprintf("live: layer (%v) has invalid source (%v), expected one of: nats, kafka", e.LayerName, e.Source)
+}
+
+type ErrMissingTopic struct {
+	LayerName string
+}
+
+func (e ErrMissingTopic) Error() string {
+	return fmt.Sprintf("live: layer (%v) is missing 'topic'", e.LayerName)
+}
+
+type ErrMissingURL struct {
+	LayerName string
+}
+
+func (e ErrMissingURL) Error() string {
+	return fmt.Sprintf("live: layer (%v) is missing 'url'", e.LayerName)
+}
+
+type ErrUnsupportedSRID struct {
+	LayerName string
+	SRID      int
+}
+
+func (e ErrUnsupportedSRID) Error() string {
+	return fmt.Sprintf("live: layer (%v) has unsupported srid (%v), expected 4326 or 3857", e.LayerName, e.SRID)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("live: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("live: layer (%v) not found", e.LayerName)
+}
+
+type ErrInvalidWindow struct {
+	LayerName string
+	Window    int
+}
+
+func (e ErrInvalidWindow) Error() string {
+	return fmt.Sprintf("live: layer (%v) has invalid window (%v), expected 0 or more seconds", e.LayerName, e.Window)
+}
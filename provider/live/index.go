@@ -0,0 +1,190 @@
+package live
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+// maxEntryCells is the number of cells above which a feature is kept in the list of large
+// features, which is scanned by every query, rather than in each of its cells
+const maxEntryCells = 256
+
+type cell struct {
+	x, y int
+}
+
+type entry struct {
+	feature provider.Feature
+	extent  geom.Extent
+	cells   []cell
+	// at is the time of the feature's last update
+	at time.Time
+}
+
+// index is a grid spatial index of features keyed by feature ID. It's safe for concurrent use.
+type index struct {
+	cellSize float64
+
+	mu      sync.RWMutex
+	entries map[uint64]*entry
+	cells   map[cell]map[uint64]*entry
+	large   map[uint64]*entry
+}
+
+func newIndex(cellSize float64) *index {
+	return &index{
+		cellSize: cellSize,
+		entries:  map[uint64]*entry{},
+		cells:    map[cell]map[uint64]*entry{},
+		large:    map[uint64]*entry{},
+	}
+}
+
+// cellRange returns the cells covering the extent
+func (idx *index) cellRange(ext *geom.Extent) (min, max cell) {
+	min = cell{int(math.Floor(ext.MinX() / idx.cellSize)), int(math.Floor(ext.MinY() / idx.cellSize))}
+	max = cell{int(math.Floor(ext.MaxX() / idx.cellSize)), int(math.Floor(ext.MaxY() / idx.cellSize))}
+	return min, max
+}
+
+// upsert adds the feature updated at the time, replacing the feature with the same ID.
+// Updates older than the indexed feature are ignored, as messages can arrive out of order.
+func (idx *index) upsert(f provider.Feature, at time.Time) error {
+	ext, err := geom.NewExtentFromGeometry(f.Geometry)
+	if err != nil {
+		return err
+	}
+	if ext == nil {
+		return ErrEmptyGeometry
+	}
+
+	e := &entry{feature: f, extent: *ext, at: at}
+	min, max := idx.cellRange(ext)
+	if n := (max.x - min.x + 1) * (max.y - min.y + 1); n <= maxEntryCells {
+		e.cells = make([]cell, 0, n)
+		for x := min.x; x <= max.x; x++ {
+			for y := min.y; y <= max.y; y++ {
+				e.cells = append(e.cells, cell{x, y})
+			}
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if old, ok := idx.entries[f.ID]; ok && old.at.After(at) {
+		return nil
+	}
+	idx.removeLocked(f.ID)
+	idx.entries[f.ID] = e
+	if e.cells == nil {
+		idx.large[f.ID] = e
+		return nil
+	}
+	for _, c := range e.cells {
+		m, ok := idx.cells[c]
+		if !ok {
+			m = map[uint64]*entry{}
+			idx.cells[c] = m
+		}
+		m[f.ID] = e
+	}
+
+	return nil
+}
+
+// remove deletes the feature with the ID
+func (idx *index) remove(id uint64) {
+	idx.mu.Lock()
+	idx.removeLocked(id)
+	idx.mu.Unlock()
+}
+
+func (idx *index) removeLocked(id uint64) {
+	e, ok := idx.entries[id]
+	if !ok {
+		return
+	}
+	delete(idx.entries, id)
+	delete(idx.large, id)
+	for _, c := range e.cells {
+		delete(idx.cells[c], id)
+		if len(idx.cells[c]) == 0 {
+			delete(idx.cells, c)
+		}
+	}
+}
+
+// evict deletes the features last updated before the time and returns the number deleted
+func (idx *index) evict(before time.Time) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var n int
+	for id, e := range idx.entries {
+		if e.at.Before(before) {
+			idx.removeLocked(id)
+			n++
+		}
+	}
+	return n
+}
+
+// len returns the number of features in the index
+func (idx *index) len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.entries)
+}
+
+// query returns the features whose extent intersects the extent, updated at or after since.
+// A zero since returns every feature.
+func (idx *index) query(ext *geom.Extent, since time.Time) []provider.Feature {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var features []provider.Feature
+	add := func(e *entry) {
+		if !e.at.Before(since) && overlaps(&e.extent, ext) {
+			features = append(features, e.feature)
+		}
+	}
+
+	min, max := idx.cellRange(ext)
+	// scanning every feature is cheaper than visiting the cells of large extents
+	if n := float64(max.x-min.x+1) * float64(max.y-min.y+1); n >= float64(len(idx.entries)) {
+		for _, e := range idx.entries {
+			add(e)
+		}
+		return features
+	}
+
+	seen := map[uint64]struct{}{}
+	for x := min.x; x <= max.x; x++ {
+		for y := min.y; y <= max.y; y++ {
+			for id, e := range idx.cells[cell{x, y}] {
+				if _, ok := seen[id]; ok {
+					continue
+				}
+				seen[id] = struct{}{}
+				add(e)
+			}
+		}
+	}
+	for _, e := range idx.large {
+		add(e)
+	}
+
+	return features
+}
+
+// overlaps reports if the extents overlap, including touching edges, as the extents of
+// points have no area
+func overlaps(a, b *geom.Extent) bool {
+	return a.MinX() <= b.MaxX() && a.MaxX() >= b.MinX() && a.MinY() <= b.MaxY() && a.MaxY() >= b.MinY()
+}
@@ -0,0 +1,111 @@
+package live
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+func TestIndex(t *testing.T) {
+	type tcase struct {
+		features []provider.Feature
+		removed  []uint64
+		query    geom.Extent
+		expected []uint64
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			idx := newIndex(0.1)
+			for _, f := range tc.features {
+				if err := idx.upsert(f, time.Time{}); err != nil {
+					t.Fatalf("upsert (%v): %v", f.ID, err)
+				}
+			}
+			for _, id := range tc.removed {
+				idx.remove(id)
+			}
+
+			var got []uint64
+			for _, f := range idx.query(&tc.query, time.Time{}) {
+				got = append(got, f.ID)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+
+			if len(got) != len(tc.expected) {
+				t.Fatalf("ids, expected %v got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Fatalf("ids, expected %v got %v", tc.expected, got)
+				}
+			}
+		}
+	}
+
+	// enough features around the query that the cells are visited rather than scanned
+	var scattered []provider.Feature
+	for i := 0; i < 100; i++ {
+		scattered = append(scattered, provider.Feature{ID: uint64(100 + i), Geometry: geom.Point{float64(i), 50}})
+	}
+
+	tests := map[string]tcase{
+		"points": {
+			features: []provider.Feature{
+				{ID: 1, Geometry: geom.Point{1.05, 1.05}},
+				{ID: 2, Geometry: geom.Point{1.25, 1.05}},
+				{ID: 3, Geometry: geom.Point{5, 5}},
+			},
+			query:    geom.Extent{1, 1, 1.1, 1.1},
+			expected: []uint64{1},
+		},
+		"upsert moves feature": {
+			features: append([]provider.Feature{
+				{ID: 1, Geometry: geom.Point{1.05, 1.05}},
+				{ID: 1, Geometry: geom.Point{5, 5}},
+				{ID: 2, Geometry: geom.Point{1.06, 1.06}},
+			}, scattered...),
+			query:    geom.Extent{1, 1, 1.1, 1.1},
+			expected: []uint64{2},
+		},
+		"removed": {
+			features: append([]provider.Feature{
+				{ID: 1, Geometry: geom.Point{1.05, 1.05}},
+				{ID: 2, Geometry: geom.Point{1.06, 1.06}},
+			}, scattered...),
+			removed:  []uint64{1},
+			query:    geom.Extent{1, 1, 1.1, 1.1},
+			expected: []uint64{2},
+		},
+		"line spanning cells": {
+			features: append([]provider.Feature{
+				{ID: 1, Geometry: geom.LineString{{0.95, 0.95}, {1.35, 1.05}}},
+			}, scattered...),
+			query:    geom.Extent{1.3, 1, 1.4, 1.1},
+			expected: []uint64{1},
+		},
+		"large polygon": {
+			features: append([]provider.Feature{
+				{ID: 1, Geometry: geom.Polygon{{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}}}},
+			}, scattered...),
+			query:    geom.Extent{1, 1, 1.1, 1.1},
+			expected: []uint64{1},
+		},
+		"scan": {
+			features: []provider.Feature{
+				{ID: 1, Geometry: geom.Point{-170, -80}},
+				{ID: 2, Geometry: geom.Point{170, 80}},
+			},
+			query:    geom.Extent{-180, -85, 0, 0},
+			expected: []uint64{1},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,154 @@
+package live
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// content types of the Kafka REST Proxy v2 API
+const (
+	kafkaContentType = "application/vnd.kafka.v2+json"
+	kafkaJSONRecords = "application/vnd.kafka.json.v2+json"
+)
+
+// kafkaSource consumes a Kafka topic through a Kafka REST Proxy (v2 API), so tegola doesn't
+// need a native Kafka client. The topic is read from the earliest offset with a consumer
+// that doesn't commit offsets, so the layer is rebuilt from the topic (i.e. a compacted topic)
+// every time tegola starts.
+type kafkaSource struct {
+	url   string
+	topic string
+	group string
+	// records are polled with this timeout
+	pollTimeout time.Duration
+	client      *http.Client
+}
+
+func newKafkaSource(url, topic, group string) *kafkaSource {
+	if group == "" {
+		group = "tegola-" + randomID()
+	}
+	return &kafkaSource{
+		url:         strings.TrimSuffix(url, "/"),
+		topic:       topic,
+		group:       group,
+		pollTimeout: time.Second,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *kafkaSource) String() string { return "kafka " + s.url + " " + s.topic }
+
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type kafkaRecord struct {
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// run creates a consumer instance, subscribes to the topic and passes the records to fn until
+// the context is canceled or a request fails. The consumer instance is deleted when run returns.
+func (s *kafkaSource) run(ctx context.Context, fn func(message)) error {
+	var instance struct {
+		InstanceID string `json:"instance_id"`
+		BaseURI    string `json:"base_uri"`
+	}
+	err := s.do(ctx, http.MethodPost, s.url+"/consumers/"+s.group, map[string]string{
+		"name":               "tegola-" + randomID(),
+		"format":             "json",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}, &instance)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// the consumer's context may already be canceled
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.do(ctx, http.MethodDelete, instance.BaseURI, nil, nil)
+	}()
+
+	err = s.do(ctx, http.MethodPost, instance.BaseURI+"/subscription", map[string][]string{
+		"topics": {s.topic},
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	recordsURL := fmt.Sprintf("%v/records?timeout=%d", instance.BaseURI, s.pollTimeout.Milliseconds())
+	for {
+		var records []kafkaRecord
+		if err := s.do(ctx, http.MethodGet, recordsURL, nil, &records); err != nil {
+			return err
+		}
+
+		for _, r := range records {
+			msg := message{}
+			if len(r.Key) != 0 {
+				if err := json.Unmarshal(r.Key, &msg.key); err != nil {
+					msg.key = string(r.Key)
+				}
+			}
+			if len(r.Value) != 0 && string(r.Value) != "null" {
+				msg.value = r.Value
+			}
+			fn(msg)
+		}
+	}
+}
+
+// do makes a Kafka REST Proxy request, encoding body and decoding the response into resp
+func (s *kafkaSource) do(ctx context.Context, method, url string, body interface{}, resp interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", kafkaContentType)
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Accept", kafkaJSONRecords)
+	} else {
+		req.Header.Set("Accept", kafkaContentType)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("kafka rest proxy %v %v: %v %s", method, url, res.Status, bytes.TrimSpace(msg))
+	}
+	if resp == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(resp)
+}
@@ -0,0 +1,27 @@
+package live
+
+import (
+	"time"
+
+	"github.com/go-spatial/geom"
+)
+
+type Layer struct {
+	name     string
+	geomType geom.Geometry
+	srid     uint64
+	// idField is the property holding the feature id. the GeoJSON id is used when empty
+	idField string
+	// timeField is the property holding the time of the feature. the time it's received is used when empty
+	timeField string
+	// window is how long features are kept after their last update. features are kept until deleted when 0
+	window time.Duration
+
+	source source
+	index  *index
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
@@ -0,0 +1,391 @@
+// Package live provides a provider which consumes GeoJSON features from a NATS subject or a
+// Kafka topic into an in-memory spatial index and serves tiles from it. Messages upsert features
+// by their ID and features with a null geometry (or Kafka tombstones) delete them, so layers
+// follow the stream in near real time without a database round trip.
+package live
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "live"
+
+// sources messages are consumed from
+const (
+	SourceNATS  = "nats"
+	SourceKafka = "kafka"
+)
+
+const (
+	ConfigKeyLayers = "layers"
+
+	ConfigKeyLayerName    = "name"
+	ConfigKeySource       = "source"
+	ConfigKeyURL          = "url"
+	ConfigKeyTopic        = "topic"
+	ConfigKeyQueue        = "queue"
+	ConfigKeyGroup        = "group"
+	ConfigKeyIDField      = "id_field"
+	ConfigKeyTimeField    = "time_field"
+	ConfigKeyWindow       = "window"
+	ConfigKeySRID         = "srid"
+	ConfigKeyGeometryType = "geometry_type"
+)
+
+const (
+	DefaultNATSURL = "nats://127.0.0.1:4222"
+	DefaultSRID    = tegola.WGS84
+)
+
+// the size of the index cells per srid
+var cellSizes = map[uint64]float64{
+	tegola.WGS84:       0.1,
+	tegola.WebMercator: 10000,
+}
+
+// the delays between reconnecting to a source
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// the least time between evicting the features which have left a layer's window
+const minEvictInterval = time.Second
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// Provider serves layers of features consumed from message streams
+type Provider struct {
+	// map of layer name and corresponding stream
+	layers map[string]Layer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// providers are tracked so their consumers can be stopped during cleanup
+var (
+	providersLock sync.Mutex
+	providers     []*Provider
+)
+
+// NewTileProvider instantiates and returns a new live provider or an error.
+// A consumer is started for every layer. Consumers reconnect when their source fails.
+//
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		source (string): [Required] where messages are consumed from, "nats" or "kafka"
+//		url (string): [Optional] the NATS server url, defaults to "nats://127.0.0.1:4222". [Required] the Kafka REST Proxy url
+//		topic (string): [Required] the NATS subject or the Kafka topic
+//		queue (string): [Optional] the NATS queue group to subscribe with
+//		group (string): [Optional] the Kafka consumer group. defaults to a random group
+//		id_field (string): [Optional] the property holding the feature id. defaults to the GeoJSON id, then the Kafka record key
+//		time_field (string): [Optional] the property holding the time of the feature, an RFC 3339 time or unix seconds. defaults to the time the message is received
+//		window (int): [Optional] the seconds features are served for after their last update. defaults to 0 (until deleted)
+//		srid (int): [Optional] the SRID of the feature coordinates. 4326 or 3857. defaults to 4326
+//		geometry_type (string): [Optional] the geometry type of the layer, reported in the capabilities
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	p := Provider{
+		layers: map[string]Layer{},
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+	for _, l := range p.layers {
+		p.wg.Add(1)
+		go p.consume(ctx, l)
+
+		if l.window > 0 {
+			p.wg.Add(1)
+			go p.evict(ctx, l)
+		}
+	}
+
+	providersLock.Lock()
+	providers = append(providers, &p)
+	providersLock.Unlock()
+
+	return &p, nil
+}
+
+// AddLayer adds a stream layer to the provider. The layer is consumed once the provider is created.
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	empty := ""
+	strs := []struct {
+		key string
+		val string
+	}{
+		{ConfigKeySource, ""},
+		{ConfigKeyURL, ""},
+		{ConfigKeyTopic, ""},
+		{ConfigKeyQueue, ""},
+		{ConfigKeyGroup, ""},
+		{ConfigKeyIDField, ""},
+		{ConfigKeyTimeField, ""},
+		{ConfigKeyGeometryType, ""},
+	}
+	for i := range strs {
+		if strs[i].val, err = layerConf.String(strs[i].key, &empty); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, strs[i].key, err)
+		}
+	}
+	src, url, topic, queue, group, idField, timeField, gtype := strs[0].val, strs[1].val, strs[2].val, strs[3].val, strs[4].val, strs[5].val, strs[6].val, strs[7].val
+
+	if topic == "" {
+		return ErrMissingTopic{LayerName: name}
+	}
+
+	l := Layer{
+		name:      name,
+		idField:   idField,
+		timeField: timeField,
+	}
+
+	switch src = strings.ToLower(src); src {
+	case SourceNATS:
+		if url == "" {
+			url = DefaultNATSURL
+		}
+		if l.source, err = newNATSSource(url, topic, queue); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyURL, err)
+		}
+	case SourceKafka:
+		if url == "" {
+			return ErrMissingURL{LayerName: name}
+		}
+		l.source = newKafkaSource(url, topic, group)
+	default:
+		return ErrInvalidSource{LayerName: name, Source: src}
+	}
+
+	srid := DefaultSRID
+	if srid, err = layerConf.Int(ConfigKeySRID, &srid); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeySRID, err)
+	}
+	if srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return ErrUnsupportedSRID{LayerName: name, SRID: srid}
+	}
+	l.srid = uint64(srid)
+	l.index = newIndex(cellSizes[l.srid])
+
+	window := 0
+	if window, err = layerConf.Int(ConfigKeyWindow, &window); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyWindow, err)
+	}
+	if window < 0 {
+		return ErrInvalidWindow{LayerName: name, Window: window}
+	}
+	l.window = time.Duration(window) * time.Second
+
+	var ok bool
+	if l.geomType, ok = provider.GeometryType(gtype); !ok {
+		return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+	}
+
+	p.layers[name] = l
+
+	return nil
+}
+
+// consume applies the messages of the layer's source to its index, reconnecting when the
+// source fails, until the context is canceled
+func (p *Provider) consume(ctx context.Context, l Layer) {
+	defer p.wg.Done()
+
+	delay := minRetryDelay
+	for {
+		received := false
+		err := l.source.run(ctx, func(msg message) {
+			received = true
+			l.apply(msg, time.Now())
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			delay = minRetryDelay
+		}
+		log.Errorf("live: layer (%v) %v failed, reconnecting in %v: %v", l.name, l.source, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// evict periodically deletes the features which have left the layer's window until the context is canceled.
+// Features outside of the window are not served in the meantime.
+func (p *Provider) evict(ctx context.Context, l Layer) {
+	defer p.wg.Done()
+
+	interval := l.window / 10
+	if interval < minEvictInterval {
+		interval = minEvictInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := l.index.evict(now.Add(-l.window)); n > 0 {
+				log.Debugf("live: layer (%v) evicted %v features", l.name, n)
+			}
+		}
+	}
+}
+
+// apply upserts or deletes the features of a message received at now. Features without a
+// time are updated at now and features which are already outside of the window are skipped.
+func (l Layer) apply(msg message, now time.Time) {
+	changes, err := l.decode(msg)
+	if err != nil {
+		log.Warnf("live: layer (%v) skipping message: %v", l.name, err)
+		return
+	}
+
+	for _, c := range changes {
+		if c.feature == nil {
+			l.index.remove(c.id)
+			continue
+		}
+		at := c.at
+		if at.IsZero() {
+			at = now
+		}
+		if l.window > 0 && at.Before(now.Add(-l.window)) {
+			continue
+		}
+		if err := l.index.upsert(*c.feature, at); err != nil {
+			log.Warnf("live: layer (%v) skipping feature (%v): %v", l.name, c.id, err)
+		}
+	}
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures streams the features of the layer's index within the tile's buffered extent
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	ext, err := provider.QueryExtent(tile, layer.srid)
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if layer.window > 0 {
+		since = time.Now().Add(-layer.window)
+	}
+
+	for _, f := range layer.index.query(ext, since) {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		// the indexed tags are copied as they're shared between tiles
+		f.Tags = provider.CopyTags(f.Tags)
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close stops the layer consumers
+func (p *Provider) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// Cleanup will stop the consumers of all the providers and remove the providers from the list
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if len(providers) > 0 {
+		log.Infof("cleaning up live providers")
+	}
+
+	for i := range providers {
+		providers[i].Close()
+	}
+
+	providers = nil
+}
@@ -0,0 +1,490 @@
+package live
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+func TestDecode(t *testing.T) {
+	type tcase struct {
+		layer    Layer
+		msg      message
+		expected []change
+		err      string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer.srid = tegola.WGS84
+			got, err := tc.layer.decode(tc.msg)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("changes, expected %+v got %+v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"feature": {
+			msg: message{value: []byte(`{"type":"Feature","id":7,"geometry":{"type":"Point","coordinates":[1,2]},"properties":{"name":"bus","route":{"id":4},"stop":null}}`)},
+			expected: []change{{id: 7, feature: &provider.Feature{
+				ID:       7,
+				Geometry: geom.Point{1, 2},
+				SRID:     tegola.WGS84,
+				Tags:     map[string]interface{}{"name": "bus", "route": `{"id":4}`},
+			}}},
+		},
+		"id field": {
+			layer: Layer{idField: "vehicle"},
+			msg:   message{value: []byte(`{"type":"Feature","id":7,"geometry":null,"properties":{"vehicle":"12"}}`)},
+			expected: []change{
+				{id: 12},
+			},
+		},
+		"string id": {
+			msg: message{value: []byte(`{"type":"Feature","id":"bus-1","geometry":null}`)},
+			expected: []change{
+				{id: provider.FeatureID("bus-1")},
+			},
+		},
+		"key id": {
+			msg: message{key: "42", value: []byte(`{"type":"Feature","geometry":null}`)},
+			expected: []change{
+				{id: 42},
+			},
+		},
+		"feature collection": {
+			msg: message{value: []byte(`{"type":"FeatureCollection","features":[{"type":"Feature","id":1,"geometry":null},{"type":"Feature","id":2,"geometry":null}]}`)},
+			expected: []change{
+				{id: 1},
+				{id: 2},
+			},
+		},
+		"tombstone": {
+			msg: message{key: "bus-1"},
+			expected: []change{
+				{id: provider.FeatureID("bus-1")},
+			},
+		},
+		"tombstone without key": {
+			msg: message{},
+			err: "tombstone without a key",
+		},
+		"missing id": {
+			msg: message{value: []byte(`{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]}}`)},
+			err: "feature has no id",
+		},
+		"time field rfc3339": {
+			layer: Layer{timeField: "ts"},
+			msg:   message{value: []byte(`{"type":"Feature","id":7,"geometry":{"type":"Point","coordinates":[1,2]},"properties":{"ts":"2020-06-01T12:00:00Z"}}`)},
+			expected: []change{{id: 7, at: time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC), feature: &provider.Feature{
+				ID:       7,
+				Geometry: geom.Point{1, 2},
+				SRID:     tegola.WGS84,
+				Tags:     map[string]interface{}{"ts": "2020-06-01T12:00:00Z"},
+			}}},
+		},
+		"time field unix seconds": {
+			layer: Layer{timeField: "ts"},
+			msg:   message{value: []byte(`{"type":"Feature","id":7,"geometry":{"type":"Point","coordinates":[1,2]},"properties":{"ts":1591012800.5}}`)},
+			expected: []change{{id: 7, at: time.Unix(1591012800, 5e8), feature: &provider.Feature{
+				ID:       7,
+				Geometry: geom.Point{1, 2},
+				SRID:     tegola.WGS84,
+				Tags:     map[string]interface{}{"ts": 1591012800.5},
+			}}},
+		},
+		"invalid time field": {
+			layer: Layer{timeField: "ts"},
+			msg:   message{value: []byte(`{"type":"Feature","id":7,"geometry":{"type":"Point","coordinates":[1,2]},"properties":{"ts":true}}`)},
+			err:   "expected an RFC 3339 time",
+		},
+		"not a feature": {
+			msg: message{value: []byte(`{"type":"Point","coordinates":[1,2]}`)},
+			err: "expected a GeoJSON Feature",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestApplyWindow(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	point := func(id int, x float64, ts string) message {
+		return message{value: []byte(fmt.Sprintf(`{"type":"Feature","id":%v,"geometry":{"type":"Point","coordinates":[%v,1]},"properties":{"ts":"%v"}}`, id, x, ts))}
+	}
+
+	type tcase struct {
+		msgs []message
+		// evict is the time features are evicted at, if set
+		evict    time.Time
+		size     int
+		expected map[uint64]float64
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			l := Layer{
+				srid:      tegola.WGS84,
+				timeField: "ts",
+				window:    time.Minute,
+				index:     newIndex(cellSizes[tegola.WGS84]),
+			}
+			for _, msg := range tc.msgs {
+				l.apply(msg, now)
+			}
+			if !tc.evict.IsZero() {
+				l.index.evict(tc.evict.Add(-l.window))
+			}
+			if got := l.index.len(); got != tc.size {
+				t.Errorf("size, expected %v got %v", tc.size, got)
+			}
+
+			got := map[uint64]float64{}
+			for _, f := range l.index.query(&geom.Extent{-180, -85, 180, 85}, now.Add(-l.window)) {
+				got[f.ID] = f.Geometry.(geom.Point).X()
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("features, expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"within window": {
+			msgs:     []message{point(1, 1, "2020-06-01T11:59:30Z")},
+			size:     1,
+			expected: map[uint64]float64{1: 1},
+		},
+		"outside window": {
+			msgs:     []message{point(1, 1, "2020-06-01T11:58:00Z")},
+			expected: map[uint64]float64{},
+		},
+		"out of order": {
+			msgs: []message{
+				point(1, 2, "2020-06-01T11:59:50Z"),
+				point(1, 1, "2020-06-01T11:59:30Z"),
+			},
+			size:     1,
+			expected: map[uint64]float64{1: 2},
+		},
+		"evicted": {
+			msgs: []message{
+				point(1, 1, "2020-06-01T11:59:10Z"),
+				point(2, 2, "2020-06-01T11:59:50Z"),
+			},
+			evict:    now.Add(30 * time.Second),
+			size:     1,
+			expected: map[uint64]float64{2: 2},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+// fakeNATS is a NATS server which publishes the payloads to the first subscription
+type fakeNATS struct {
+	ln       net.Listener
+	payloads []string
+
+	mu      sync.Mutex
+	connect string
+	sub     string
+}
+
+func newFakeNATS(t *testing.T, payloads ...string) *fakeNATS {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeNATS{ln: ln, payloads: payloads}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeNATS) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"test\"}\r\n")
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			s.connect = line
+		case strings.HasPrefix(line, "SUB "):
+			s.sub = line
+		}
+		s.mu.Unlock()
+		if strings.HasPrefix(line, "SUB ") {
+			break
+		}
+	}
+
+	fmt.Fprintf(conn, "PING\r\n")
+	for _, p := range s.payloads {
+		fmt.Fprintf(conn, "MSG vehicles.positions 1 %d\r\n%s\r\n", len(p), p)
+	}
+
+	// hold the connection open until the client goes away
+	for {
+		if _, err := readLine(r); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeNATS) subscription() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connect, s.sub
+}
+
+// waitFor polls cond until it's true or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func tileFeatureIDs(t *testing.T, p provider.Tiler, layer string, tile provider.Tile) []uint64 {
+	var ids []uint64
+	err := p.TileFeatures(context.Background(), layer, tile, func(f *provider.Feature) error {
+		ids = append(ids, f.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TileFeatures: %v", err)
+	}
+	return ids
+}
+
+func TestNATS(t *testing.T) {
+	srv := newFakeNATS(t,
+		`{"type":"Feature","id":1,"geometry":{"type":"Point","coordinates":[-60,-60]},"properties":{"route":"4"}}`,
+		`{"type":"Feature","id":2,"geometry":{"type":"Point","coordinates":[60,60]}}`,
+		`not json`,
+		`{"type":"Feature","id":3,"geometry":{"type":"Point","coordinates":[-60,-60]}}`,
+		`{"type":"Feature","id":3,"geometry":null}`,
+		`{"type":"Feature","id":4,"geometry":{"type":"Point","coordinates":[-61,-61]}}`,
+	)
+
+	p, err := NewTileProvider(dict.Dict{
+		ConfigKeyLayers: []map[string]interface{}{{
+			ConfigKeyLayerName: "vehicles",
+			ConfigKeySource:    SourceNATS,
+			ConfigKeyURL:       "nats://secret@" + srv.ln.Addr().String(),
+			ConfigKeyTopic:     "vehicles.>",
+			ConfigKeyQueue:     "tegola",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer p.(*Provider).Close()
+
+	layer := p.(*Provider).layers["vehicles"]
+	// the last message is applied once feature 4 is indexed
+	waitFor(t, "messages", func() bool { return len(layer.index.query(&geom.Extent{-62, -62, -61, -61}, time.Time{})) == 1 })
+
+	connect, sub := srv.subscription()
+	if !strings.Contains(connect, `"auth_token":"secret"`) {
+		t.Errorf("connect, expected auth_token, got %v", connect)
+	}
+	if sub != "SUB vehicles.> tegola 1" {
+		t.Errorf("sub, expected %q got %q", "SUB vehicles.> tegola 1", sub)
+	}
+
+	// the south west tile holds features 1 and 4, feature 3 was deleted
+	got := tileFeatureIDs(t, p, "vehicles", provider.NewTile(1, 0, 1, 0, tegola.WebMercator))
+	if len(got) != 2 || got[0]+got[1] != 5 {
+		t.Errorf("south west tile, expected features 1 and 4, got %v", got)
+	}
+	got = tileFeatureIDs(t, p, "vehicles", provider.NewTile(1, 1, 0, 0, tegola.WebMercator))
+	if !reflect.DeepEqual(got, []uint64{2}) {
+		t.Errorf("north east tile, expected feature 2, got %v", got)
+	}
+}
+
+func TestKafka(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		polls   int
+		deleted bool
+	)
+	batches := [][]kafkaRecord{
+		{
+			{Key: json.RawMessage(`"a"`), Value: json.RawMessage(`{"type":"Feature","geometry":{"type":"Point","coordinates":[10,10]}}`)},
+			{Key: json.RawMessage(`"b"`), Value: json.RawMessage(`{"type":"Feature","geometry":{"type":"Point","coordinates":[11,11]}}`)},
+		},
+		{
+			{Key: json.RawMessage(`"a"`), Value: json.RawMessage(`null`)},
+		},
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/consumers/positions":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["auto.offset.reset"] != "earliest" || body["format"] != "json" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"instance_id":"i","base_uri":"%v/consumers/positions/instances/i"}`, srv.URL)
+		case r.Method == http.MethodPost && r.URL.Path == "/consumers/positions/instances/i/subscription":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/consumers/positions/instances/i/records":
+			if r.Header.Get("Accept") != kafkaJSONRecords {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			records := []kafkaRecord{}
+			if polls < len(batches) {
+				records = batches[polls]
+			}
+			polls++
+			json.NewEncoder(w).Encode(records)
+		case r.Method == http.MethodDelete && r.URL.Path == "/consumers/positions/instances/i":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := NewTileProvider(dict.Dict{
+		ConfigKeyLayers: []map[string]interface{}{{
+			ConfigKeyLayerName: "positions",
+			ConfigKeySource:    SourceKafka,
+			ConfigKeyURL:       srv.URL,
+			ConfigKeyTopic:     "positions",
+			ConfigKeyGroup:     "positions",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitFor(t, "records", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return polls > len(batches)
+	})
+
+	got := tileFeatureIDs(t, p, "positions", provider.NewTile(0, 0, 0, 0, tegola.WebMercator))
+	if !reflect.DeepEqual(got, []uint64{provider.FeatureID("b")}) {
+		t.Errorf("features, expected b got %v", got)
+	}
+
+	p.(*Provider).Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if !deleted {
+		t.Errorf("expected the consumer instance to be deleted")
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		layer map[string]interface{}
+		err   string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := NewTileProvider(dict.Dict{
+				ConfigKeyLayers: []map[string]interface{}{tc.layer},
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing name": {
+			layer: map[string]interface{}{ConfigKeySource: SourceNATS, ConfigKeyTopic: "a"},
+			err:   "layer's name field",
+		},
+		"missing topic": {
+			layer: map[string]interface{}{ConfigKeyLayerName: "a", ConfigKeySource: SourceNATS},
+			err:   "missing 'topic'",
+		},
+		"invalid source": {
+			layer: map[string]interface{}{ConfigKeyLayerName: "a", ConfigKeySource: "mqtt", ConfigKeyTopic: "a"},
+			err:   "invalid source (mqtt)",
+		},
+		"kafka missing url": {
+			layer: map[string]interface{}{ConfigKeyLayerName: "a", ConfigKeySource: SourceKafka, ConfigKeyTopic: "a"},
+			err:   "missing 'url'",
+		},
+		"invalid nats url": {
+			layer: map[string]interface{}{ConfigKeyLayerName: "a", ConfigKeySource: SourceNATS, ConfigKeyTopic: "a", ConfigKeyURL: "http://localhost"},
+			err:   "nats url scheme",
+		},
+		"unsupported srid": {
+			layer: map[string]interface{}{ConfigKeyLayerName: "a", ConfigKeySource: SourceNATS, ConfigKeyTopic: "a", ConfigKeySRID: 27700},
+			err:   "unsupported srid (27700)",
+		},
+		"invalid window": {
+			layer: map[string]interface{}{ConfigKeyLayerName: "a", ConfigKeySource: SourceNATS, ConfigKeyTopic: "a", ConfigKeyWindow: -1},
+			err:   "invalid window (-1)",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,151 @@
+package live
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-spatial/geom/encoding/geojson"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+// message is a message consumed from a source
+type message struct {
+	// key of the message (i.e. the Kafka record key). nil when the source has no keys
+	key interface{}
+	// value is the message's GeoJSON. nil for tombstones
+	value []byte
+}
+
+// change is an upsert or delete of a feature decoded from a message
+type change struct {
+	id uint64
+	// feature is nil when the feature is deleted
+	feature *provider.Feature
+	// at is the time of the feature read from the layer's time_field. zero when not set
+	at time.Time
+}
+
+// geojsonFeature is decoded separately from the geojson package's Feature so string ids
+// and null geometries can be read
+type geojsonFeature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+	Features   []geojsonFeature       `json:"features"`
+}
+
+// decode returns the changes of a message. A message holds a GeoJSON Feature or FeatureCollection.
+// Features with a null geometry and tombstones (messages without a value) delete the feature.
+func (l Layer) decode(msg message) ([]change, error) {
+	if len(msg.value) == 0 || bytes.Equal(bytes.TrimSpace(msg.value), []byte("null")) {
+		if msg.key == nil {
+			return nil, fmt.Errorf("tombstone without a key")
+		}
+		return []change{{id: provider.FeatureID(msg.key)}}, nil
+	}
+
+	var gf geojsonFeature
+	if err := json.Unmarshal(msg.value, &gf); err != nil {
+		return nil, err
+	}
+
+	switch gf.Type {
+	case "FeatureCollection":
+		changes := make([]change, 0, len(gf.Features))
+		for i := range gf.Features {
+			c, err := l.decodeFeature(gf.Features[i], nil)
+			if err != nil {
+				return nil, fmt.Errorf("feature %v: %v", i, err)
+			}
+			changes = append(changes, c)
+		}
+		return changes, nil
+	case "Feature":
+		c, err := l.decodeFeature(gf, msg.key)
+		if err != nil {
+			return nil, err
+		}
+		return []change{c}, nil
+	default:
+		return nil, fmt.Errorf("expected a GeoJSON Feature or FeatureCollection, got type (%v)", gf.Type)
+	}
+}
+
+// decodeFeature decodes a GeoJSON feature. The id is read from the layer's id_field, the
+// feature's id or the message key, in that order.
+func (l Layer) decodeFeature(gf geojsonFeature, key interface{}) (change, error) {
+	id := gf.ID
+	if l.idField != "" {
+		id = gf.Properties[l.idField]
+	}
+	if id == nil {
+		id = key
+	}
+	if id == nil {
+		return change{}, fmt.Errorf("feature has no id")
+	}
+
+	c := change{id: provider.FeatureID(id)}
+	if len(gf.Geometry) == 0 || bytes.Equal(bytes.TrimSpace(gf.Geometry), []byte("null")) {
+		return c, nil
+	}
+
+	var g geojson.Geometry
+	if err := json.Unmarshal(gf.Geometry, &g); err != nil {
+		return change{}, err
+	}
+
+	if l.timeField != "" {
+		at, err := featureTime(gf.Properties[l.timeField])
+		if err != nil {
+			return change{}, fmt.Errorf("feature (%v) %v: %v", id, l.timeField, err)
+		}
+		c.at = at
+	}
+
+	tags := make(map[string]interface{}, len(gf.Properties))
+	for k, v := range gf.Properties {
+		setTag(tags, k, v)
+	}
+
+	c.feature = &provider.Feature{
+		ID:       c.id,
+		Geometry: g.Geometry,
+		SRID:     l.srid,
+		Tags:     tags,
+	}
+	return c, nil
+}
+
+// setTag adds the value to the tags. null values are dropped, objects and arrays are encoded as JSON strings
+func setTag(tags map[string]interface{}, k string, v interface{}) {
+	switch v.(type) {
+	case nil:
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		tags[k] = string(b)
+	default:
+		tags[k] = v
+	}
+}
+
+// featureTime returns the time of an RFC 3339 string or a number of unix seconds
+func featureTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339, t)
+	case float64:
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	default:
+		return time.Time{}, fmt.Errorf("expected an RFC 3339 time or unix seconds, got (%v)", v)
+	}
+}
@@ -0,0 +1,177 @@
+package live
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// natsSource subscribes to a NATS subject. The client side of the NATS protocol needed to
+// subscribe is small and text based, so it's implemented here rather than adding a client.
+// Core NATS doesn't persist messages: the layer holds the features published since tegola started.
+type natsSource struct {
+	url     *url.URL
+	subject string
+	// queue group the subscription joins. empty for none
+	queue string
+}
+
+func newNATSSource(rawURL, subject, queue string) (*natsSource, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "nats", "tls":
+	default:
+		return nil, fmt.Errorf("nats url scheme must be nats:// or tls://, got (%v)", rawURL)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	return &natsSource{url: u, subject: subject, queue: queue}, nil
+}
+
+func (s *natsSource) String() string { return "nats " + s.url.Host + " " + s.subject }
+
+// natsInfo is the subset of the server's INFO message used by the client
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// natsConnect is the CONNECT message sent to the server
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Protocol  int    `json:"protocol"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// run connects, subscribes and passes the messages to fn until the context is canceled or the
+// connection fails
+func (s *natsSource) run(ctx context.Context, fn func(message)) error {
+	var d net.Dialer
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	conn, err := d.DialContext(dialCtx, "tcp", s.url.Host)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	// close the connection to unblock reads when the context is canceled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	line, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("expected INFO, got (%v)", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(line[len("INFO "):]), &info); err != nil {
+		return fmt.Errorf("decoding INFO: %v", err)
+	}
+
+	var w io.Writer = conn
+	if info.TLSRequired || s.url.Scheme == "tls" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: s.url.Hostname()})
+		// the handshake is unblocked by closing the connection when the context is canceled
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		r, w = bufio.NewReader(tlsConn), tlsConn
+	}
+
+	connect := natsConnect{Name: "tegola", Lang: "go", Protocol: 1}
+	if s.url.User != nil {
+		if pass, ok := s.url.User.Password(); ok {
+			connect.User, connect.Pass = s.url.User.Username(), pass
+		} else {
+			connect.AuthToken = s.url.User.Username()
+		}
+	}
+	b, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+
+	sub := "SUB " + s.subject + " 1\r\n"
+	if s.queue != "" {
+		sub = "SUB " + s.subject + " " + s.queue + " 1\r\n"
+	}
+	if _, err := io.WriteString(w, "CONNECT "+string(b)+"\r\n"+sub); err != nil {
+		return err
+	}
+
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		op := line
+		if i := strings.IndexByte(line, ' '); i != -1 {
+			op = line[:i]
+		}
+		switch strings.ToUpper(op) {
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				return fmt.Errorf("invalid MSG (%v)", line)
+			}
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 {
+				return fmt.Errorf("invalid MSG (%v)", line)
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return err
+			}
+			fn(message{value: payload[:size]})
+		case "PING":
+			if _, err := io.WriteString(w, "PONG\r\n"); err != nil {
+				return err
+			}
+		case "-ERR":
+			return fmt.Errorf("nats: %v", strings.TrimSpace(strings.TrimPrefix(line, op)))
+		case "+OK", "PONG", "INFO":
+		default:
+			return fmt.Errorf("unexpected nats operation (%v)", line)
+		}
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,11 @@
+package live
+
+import "context"
+
+// source is a stream of messages
+type source interface {
+	// run consumes messages and passes them to fn until the context is canceled or the
+	// source fails
+	run(ctx context.Context, fn func(message)) error
+	String() string
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingLayerName = errors.New("memory: layer is missing 'name'")
+	ErrEmptyGeometry    = errors.New("memory: feature geometry is empty")
+	ErrMissingFeatureID = errors.New("memory: feature is missing an id")
+)
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("memory: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrUnsupportedSRID struct {
+	SRID int
+}
+
+func (e ErrUnsupportedSRID) Error() string {
+	return fmt.Sprintf("memory: unsupported srid (%v), expected 4326 or 3857", e.SRID)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("memory: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("memory: layer (%v) not found", e.LayerName)
+}
+
+// ErrSRIDMismatch is returned when a feature's SRID differs from the SRID of its layer
+type ErrSRIDMismatch struct {
+	LayerName string
+	SRID      uint64
+	Expected  uint64
+}
+
+func (e ErrSRIDMismatch) Error() string {
+	return fmt.Sprintf("memory: layer (%v) expects features with srid (%v), got (%v)", e.LayerName, e.Expected, e.SRID)
+}
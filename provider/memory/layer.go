@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+type Layer struct {
+	name     string
+	geomType geom.Geometry
+	srid     uint64
+
+	store *store
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
+
+type entry struct {
+	feature provider.Feature
+	extent  geom.Extent
+}
+
+// maxChanges is the number of changes a layer remembers for LayerChanges
+const maxChanges = 10000
+
+// change is the extent of the features changed at a time
+type change struct {
+	at     time.Time
+	extent geom.Extent
+}
+
+// store holds the features of a layer keyed by feature ID. It's safe for concurrent use.
+type store struct {
+	mu      sync.RWMutex
+	entries map[uint64]entry
+	// updated is the time the features last changed
+	updated time.Time
+	// changes are the most recent changes, oldest first
+	changes []change
+	// forgotten is the time of the newest change dropped from changes
+	forgotten time.Time
+}
+
+func newStore() *store {
+	return &store{entries: map[uint64]entry{}}
+}
+
+// changed records the change of the extents. The lock must be held.
+func (s *store) changed(extents ...geom.Extent) {
+	s.updated = time.Now()
+	for _, ext := range extents {
+		s.changes = append(s.changes, change{at: s.updated, extent: ext})
+	}
+	if n := len(s.changes) - maxChanges; n > 0 {
+		s.forgotten = s.changes[n-1].at
+		s.changes = append(s.changes[:0:0], s.changes[n:]...)
+	}
+}
+
+// changedSince returns the extents changed since the time. ok is false when changes since
+// the time were forgotten.
+func (s *store) changedSince(since time.Time) (extents []geom.Extent, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.forgotten.IsZero() && !s.forgotten.Before(since) {
+		return nil, false
+	}
+	for _, c := range s.changes {
+		if c.at.After(since) {
+			extents = append(extents, c.extent)
+		}
+	}
+	return extents, true
+}
+
+// newEntry validates the feature for the layer and copies its tags, so the caller can
+// keep using the feature
+func (l Layer) newEntry(f provider.Feature) (entry, error) {
+	if f.ID == 0 {
+		return entry{}, ErrMissingFeatureID
+	}
+	if f.SRID == 0 {
+		f.SRID = l.srid
+	}
+	if f.SRID != l.srid {
+		return entry{}, ErrSRIDMismatch{LayerName: l.name, SRID: f.SRID, Expected: l.srid}
+	}
+	if f.Geometry == nil {
+		return entry{}, ErrEmptyGeometry
+	}
+
+	ext, err := geom.NewExtentFromGeometry(f.Geometry)
+	if err != nil {
+		return entry{}, err
+	}
+	if ext == nil {
+		return entry{}, ErrEmptyGeometry
+	}
+
+	f.Tags = copyTags(f.Tags)
+	return entry{feature: f, extent: *ext}, nil
+}
+
+// upsert adds the entry, replacing the feature with the same ID
+func (s *store) upsert(e entry) {
+	s.mu.Lock()
+	extents := []geom.Extent{e.extent}
+	if old, ok := s.entries[e.feature.ID]; ok {
+		extents = append(extents, old.extent)
+	}
+	s.entries[e.feature.ID] = e
+	s.changed(extents...)
+	s.mu.Unlock()
+}
+
+// remove deletes the feature with the ID
+func (s *store) remove(id uint64) {
+	s.mu.Lock()
+	if old, ok := s.entries[id]; ok {
+		delete(s.entries, id)
+		s.changed(old.extent)
+	}
+	s.mu.Unlock()
+}
+
+// replace swaps all the features for the entries
+func (s *store) replace(entries map[uint64]entry) {
+	s.mu.Lock()
+	// the features of both the old and new entries changed
+	var ext *geom.Extent
+	for _, es := range []map[uint64]entry{s.entries, entries} {
+		for _, e := range es {
+			if ext == nil {
+				ext = &geom.Extent{}
+				*ext = e.extent
+				continue
+			}
+			ext.Add(&e.extent)
+		}
+	}
+	s.entries = entries
+	if ext != nil {
+		s.changed(*ext)
+	} else {
+		s.updated = time.Now()
+	}
+	s.mu.Unlock()
+}
+
+// lastUpdated returns the time the features last changed
+func (s *store) lastUpdated() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updated
+}
+
+// query returns the features whose extent intersects the extent, ordered by ID so tiles
+// are encoded the same way every time
+func (s *store) query(ext *geom.Extent) []provider.Feature {
+	s.mu.RLock()
+	var features []provider.Feature
+	for _, e := range s.entries {
+		if overlaps(&e.extent, ext) {
+			features = append(features, e.feature)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(features, func(i, j int) bool { return features[i].ID < features[j].ID })
+	return features
+}
+
+// overlaps reports if the extents overlap, including touching edges, as the extents of
+// points have no area
+func overlaps(a, b *geom.Extent) bool {
+	return a.MinX() <= b.MaxX() && a.MaxX() >= b.MinX() && a.MinY() <= b.MaxY() && a.MaxY() >= b.MinY()
+}
+
+func copyTags(tags map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(tags))
+	for k, v := range tags {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,339 @@
+// Package memory provides a provider which tiles features held in memory. Applications
+// embedding tegola push features to its layers from code with AddFeature, RemoveFeature and
+// ReplaceLayer, and the features are served by the next tile requested. It suits tests
+// and embedded use where the data doesn't live in a database.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "memory"
+
+const (
+	ConfigKeyName   = "name"
+	ConfigKeySRID   = "srid"
+	ConfigKeyLayers = "layers"
+
+	ConfigKeyLayerName    = "name"
+	ConfigKeyLayerSRID    = "srid"
+	ConfigKeyGeometryType = "geometry_type"
+)
+
+const DefaultSRID = tegola.WGS84
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// Provider serves layers of features pushed from code
+type Provider struct {
+	srid uint64
+
+	mu sync.RWMutex
+	// map of layer name and corresponding features
+	layers map[string]Layer
+}
+
+// providers are tracked by their config name so applications can look up the providers
+// created from their config
+var (
+	providersLock sync.Mutex
+	providers     = map[string]*Provider{}
+)
+
+// New returns a provider without layers. Layers use the srid unless they configure their own.
+func New(srid uint64) (*Provider, error) {
+	if srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return nil, ErrUnsupportedSRID{SRID: int(srid)}
+	}
+	return &Provider{
+		srid:   srid,
+		layers: map[string]Layer{},
+	}, nil
+}
+
+// NewTileProvider instantiates and returns a new memory provider or an error. The layers
+// start empty. A provider with a name can be looked up with Lookup.
+//
+//	name (string): [Optional] the name of the provider
+//	srid (int): [Optional] the default SRID of the layers. 4326 or 3857. defaults to 4326
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		srid (int): [Optional] the SRID of the layer's features. defaults to the provider's srid
+//		geometry_type (string): [Optional] the geometry type of the layer, reported in the capabilities
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	empty := ""
+	name, err := config.String(ConfigKeyName, &empty)
+	if err != nil {
+		return nil, err
+	}
+
+	srid := DefaultSRID
+	if srid, err = config.Int(ConfigKeySRID, &srid); err != nil {
+		return nil, err
+	}
+
+	p, err := New(uint64(srid))
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	if name != "" {
+		providersLock.Lock()
+		providers[name] = p
+		providersLock.Unlock()
+	}
+
+	return p, nil
+}
+
+// Lookup returns the provider created with the name from the config
+func Lookup(name string) (*Provider, bool) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	p, ok := providers[name]
+	return p, ok
+}
+
+// AddLayer adds an empty layer to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+
+	srid := int(p.srid)
+	if srid, err = layerConf.Int(ConfigKeyLayerSRID, &srid); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyLayerSRID, err)
+	}
+	if srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return ErrUnsupportedSRID{SRID: srid}
+	}
+
+	empty := ""
+	gtype, err := layerConf.String(ConfigKeyGeometryType, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyGeometryType, err)
+	}
+	geomType, ok := provider.GeometryType(gtype)
+	if !ok {
+		return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+	p.layers[name] = Layer{
+		name:     name,
+		geomType: geomType,
+		srid:     uint64(srid),
+		store:    newStore(),
+	}
+
+	return nil
+}
+
+func (p *Provider) layer(name string) (Layer, error) {
+	p.mu.RLock()
+	l, ok := p.layers[name]
+	p.mu.RUnlock()
+	if !ok {
+		return l, ErrLayerNotFound{LayerName: name}
+	}
+	return l, nil
+}
+
+// AddFeature adds the feature to the layer, replacing the feature with the same ID. The
+// feature must have an ID and its SRID must be the layer's SRID or 0. The tags are copied.
+func (p *Provider) AddFeature(layerName string, f provider.Feature) error {
+	l, err := p.layer(layerName)
+	if err != nil {
+		return err
+	}
+
+	e, err := l.newEntry(f)
+	if err != nil {
+		return err
+	}
+
+	l.store.upsert(e)
+	return nil
+}
+
+// RemoveFeature removes the feature with the ID from the layer. Removing a feature which
+// isn't in the layer does nothing.
+func (p *Provider) RemoveFeature(layerName string, id uint64) error {
+	l, err := p.layer(layerName)
+	if err != nil {
+		return err
+	}
+
+	l.store.remove(id)
+	return nil
+}
+
+// ReplaceLayer replaces all the features of the layer at once, so tiles never see part of
+// the new features. The layer is left unchanged when a feature is invalid.
+func (p *Provider) ReplaceLayer(layerName string, features []provider.Feature) error {
+	l, err := p.layer(layerName)
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[uint64]entry, len(features))
+	for i := range features {
+		e, err := l.newEntry(features[i])
+		if err != nil {
+			return fmt.Errorf("memory: layer (%v) feature (%v): %v", layerName, i, err)
+		}
+		entries[e.feature.ID] = e
+	}
+
+	l.store.replace(entries)
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, err := p.layer(lyrID)
+	return l, err == nil
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// LayerUpdated returns the time the features of the layer last changed. The zero time is
+// returned until features are added.
+func (p *Provider) LayerUpdated(ctx context.Context, lyrID string) (time.Time, error) {
+	l, err := p.layer(lyrID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return l.store.lastUpdated(), nil
+}
+
+// LayerChanges returns the extents of the features of the layer added, updated or removed
+// since the time. The world is returned when the layer changed too often since the time to
+// remember every change.
+func (p *Provider) LayerChanges(ctx context.Context, lyrID string, since time.Time) ([]geom.Extent, error) {
+	l, err := p.layer(lyrID)
+	if err != nil {
+		return nil, err
+	}
+
+	extents, ok := l.store.changedSince(since)
+	if !ok {
+		return []geom.Extent{{-180, -provider.MaxLat, 180, provider.MaxLat}}, nil
+	}
+	if l.srid == tegola.WGS84 {
+		return extents, nil
+	}
+
+	for i, ext := range extents {
+		min, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MinX(), ext.MinY()})
+		if err != nil {
+			return nil, err
+		}
+		max, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MaxX(), ext.MaxY()})
+		if err != nil {
+			return nil, err
+		}
+		minPt, maxPt := min.(geom.Point), max.(geom.Point)
+		extents[i] = geom.Extent{minPt.X(), minPt.Y(), maxPt.X(), maxPt.Y()}
+	}
+	return extents, nil
+}
+
+// TileFeatures sends the features of the layer within the tile's buffered extent to fn
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, err := p.layer(lyrID)
+	if err != nil {
+		return err
+	}
+
+	ext, err := provider.QueryExtent(tile, layer.srid)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range layer.store.query(ext) {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		// the stored tags are copied as they're shared between tiles
+		f.Tags = copyTags(f.Tags)
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Cleanup forgets the providers created from configs
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if len(providers) > 0 {
+		log.Infof("cleaning up memory providers")
+	}
+
+	providers = map[string]*Provider{}
+}
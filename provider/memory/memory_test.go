@@ -0,0 +1,191 @@
+package memory_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/memory"
+)
+
+// tileFeatures returns the features of the north west quarter of the world
+func tileFeatures(t *testing.T, p *memory.Provider, layer string) []provider.Feature {
+	var features []provider.Feature
+	err := p.TileFeatures(context.Background(), layer, provider.NewTile(1, 0, 0, 0, tegola.WebMercator), func(f *provider.Feature) error {
+		features = append(features, *f)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return features
+}
+
+func TestProvider(t *testing.T) {
+	tiler, err := memory.NewTileProvider(dict.Dict{
+		"name":   "embedded",
+		"layers": []map[string]interface{}{{"name": "places", "geometry_type": "point"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer memory.Cleanup()
+
+	p, ok := memory.Lookup("embedded")
+	if !ok || p != tiler {
+		t.Fatalf("lookup, expected the provider got %v", p)
+	}
+
+	if updated, _ := p.LayerUpdated(context.Background(), "places"); !updated.IsZero() {
+		t.Errorf("updated, expected zero time got %v", updated)
+	}
+
+	sf := provider.Feature{ID: 1, Geometry: geom.Point{-122.4, 37.6}, Tags: map[string]interface{}{"name": "San Francisco"}}
+	paris := provider.Feature{ID: 2, Geometry: geom.Point{2.35, 48.85}, Tags: map[string]interface{}{"name": "Paris"}}
+	london := provider.Feature{ID: 3, Geometry: geom.Point{-0.1, 51.5}, SRID: tegola.WGS84, Tags: map[string]interface{}{"name": "London"}}
+
+	for _, f := range []provider.Feature{london, paris, sf} {
+		if err := p.AddFeature("places", f); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the stored tags are not shared with the caller
+	sf.Tags["name"] = "SF"
+	sf = provider.Feature{ID: 1, Geometry: geom.Point{-122.4, 37.6}, SRID: tegola.WGS84, Tags: map[string]interface{}{"name": "San Francisco"}}
+
+	if features := tileFeatures(t, p, "places"); !reflect.DeepEqual(features, []provider.Feature{sf, london}) {
+		t.Errorf("features, expected %+v got %+v", []provider.Feature{sf, london}, features)
+	}
+	if updated, _ := p.LayerUpdated(context.Background(), "places"); updated.IsZero() {
+		t.Errorf("updated, expected the time of the last change")
+	}
+
+	// upsert by id
+	moved := provider.Feature{ID: 1, Geometry: geom.Point{3, 50}, Tags: map[string]interface{}{"name": "moved"}}
+	if err := p.AddFeature("places", moved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.RemoveFeature("places", 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if features := tileFeatures(t, p, "places"); len(features) != 0 {
+		t.Errorf("features, expected none got %+v", features)
+	}
+
+	// an invalid feature leaves the layer unchanged
+	err = p.ReplaceLayer("places", []provider.Feature{sf, {ID: 4, Geometry: geom.Point{0, 0}, SRID: tegola.WebMercator}})
+	if err == nil || err.Error() != "memory: layer (places) feature (1): memory: layer (places) expects features with srid (4326), got (3857)" {
+		t.Fatalf("replace, unexpected error: %v", err)
+	}
+	if features := tileFeatures(t, p, "places"); len(features) != 0 {
+		t.Errorf("features, expected none got %+v", features)
+	}
+
+	if err := p.ReplaceLayer("places", []provider.Feature{london}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if features := tileFeatures(t, p, "places"); !reflect.DeepEqual(features, []provider.Feature{london}) {
+		t.Errorf("features, expected %+v got %+v", []provider.Feature{london}, features)
+	}
+}
+
+func TestLayerChanges(t *testing.T) {
+	p, _ := memory.New(tegola.WGS84)
+	if err := p.AddLayer(dict.Dict{"name": "places"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := p.AddFeature("places", provider.Feature{ID: 1, Geometry: geom.Point{-122.4, 37.6}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	moved := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := p.AddFeature("places", provider.Feature{ID: 1, Geometry: geom.Point{2.35, 48.85}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	extents, err := p.LayerChanges(context.Background(), "places", start)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(extents) != 3 {
+		t.Errorf("changes since start, expected 3 extents got %v", extents)
+	}
+
+	// a moved feature changed both where it was and where it is
+	extents, _ = p.LayerChanges(context.Background(), "places", moved)
+	expected := []geom.Extent{{2.35, 48.85, 2.35, 48.85}, {-122.4, 37.6, -122.4, 37.6}}
+	if !reflect.DeepEqual(extents, expected) {
+		t.Errorf("changes since move, expected %v got %v", expected, extents)
+	}
+
+	if extents, _ = p.LayerChanges(context.Background(), "places", time.Now()); len(extents) != 0 {
+		t.Errorf("changes since now, expected none got %v", extents)
+	}
+
+	if _, err = p.LayerChanges(context.Background(), "roads", start); err == nil {
+		t.Errorf("unknown layer, expected an error")
+	}
+}
+
+func TestAddFeatureErrors(t *testing.T) {
+	type tcase struct {
+		layer   string
+		feature provider.Feature
+		err     string
+	}
+
+	p, err := memory.New(tegola.WebMercator)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.AddLayer(dict.Dict{"name": "test"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			err := p.AddFeature(tc.layer, tc.feature)
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"layer not found": {
+			layer:   "missing",
+			feature: provider.Feature{ID: 1, Geometry: geom.Point{0, 0}},
+			err:     "memory: layer (missing) not found",
+		},
+		"missing id": {
+			layer:   "test",
+			feature: provider.Feature{Geometry: geom.Point{0, 0}},
+			err:     memory.ErrMissingFeatureID.Error(),
+		},
+		"missing geometry": {
+			layer:   "test",
+			feature: provider.Feature{ID: 1},
+			err:     memory.ErrEmptyGeometry.Error(),
+		},
+		"srid mismatch": {
+			layer:   "test",
+			feature: provider.Feature{ID: 1, Geometry: geom.Point{0, 0}, SRID: tegola.WGS84},
+			err:     "memory: layer (test) expects features with srid (3857), got (4326)",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,67 @@
+package ogcapi
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingURL       = errors.New("ogcapi: provider is missing 'url'")
+	ErrMissingLayerName = errors.New("ogcapi: layer is missing 'name'")
+)
+
+type ErrMissingCollection struct {
+	LayerName string
+}
+
+func (e ErrMissingCollection) Error() string {
+	return fmt.Sprintf("ogcapi: layer (%v) is missing 'collection'", e.LayerName)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("ogcapi: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("ogcapi: layer (%v) not found", e.LayerName)
+}
+
+type ErrInvalidProtocol struct {
+	Protocol string
+}
+
+func (e ErrInvalidProtocol) Error() string {
+	return fmt.Sprintf("ogcapi: invalid protocol (%v), expected one of: features, wfs", e.Protocol)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("ogcapi: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
+
+// ErrStatus is returned when the remote endpoint responds with an unexpected status
+type ErrStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("ogcapi: request (%v) responded with status %v", e.URL, e.Status)
+}
+
+// retryable reports if the request may succeed when retried
+func (e ErrStatus) retryable() bool {
+	return e.Status == 429 || e.Status >= 500
+}
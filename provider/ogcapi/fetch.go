@@ -0,0 +1,286 @@
+package ogcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// crs84 is the OGC identifier for WGS84 with lon / lat axis order
+const crs84 = "urn:ogc:def:crs:OGC:1.3:CRS84"
+
+// lonLatExtent returns the extent in lon / lat
+func lonLatExtent(ext *geom.Extent, extSRID uint64) (*geom.Extent, error) {
+	if extSRID == tegola.WGS84 {
+		return ext, nil
+	}
+
+	min, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MinX(), ext.MinY()})
+	if err != nil {
+		return nil, err
+	}
+	max, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MaxX(), ext.MaxY()})
+	if err != nil {
+		return nil, err
+	}
+	minPt, maxPt := min.(geom.Point), max.(geom.Point)
+
+	return &geom.Extent{minPt.X(), minPt.Y(), maxPt.X(), maxPt.Y()}, nil
+}
+
+func formatBBox(bbox *geom.Extent) string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return f(bbox.MinX()) + "," + f(bbox.MinY()) + "," + f(bbox.MaxX()) + "," + f(bbox.MaxY())
+}
+
+// firstPageURL returns the url of the first page of features within the bbox
+func (p *Provider) firstPageURL(layer Layer, bbox *geom.Extent) string {
+	q := url.Values{}
+
+	switch p.protocol {
+	case ProtocolWFS:
+		q.Set("service", "WFS")
+		q.Set("version", "2.0.0")
+		q.Set("request", "GetFeature")
+		q.Set("typeNames", layer.collection)
+		q.Set("outputFormat", p.outputFormat)
+		q.Set("srsName", crs84)
+		q.Set("bbox", formatBBox(bbox)+","+crs84)
+		q.Set("count", strconv.Itoa(p.pageSize))
+		q.Set("startIndex", "0")
+		return p.url + "?" + q.Encode()
+	default:
+		q.Set("bbox", formatBBox(bbox))
+		q.Set("limit", strconv.Itoa(p.pageSize))
+		q.Set("f", "json")
+		return p.url + "/collections/" + url.PathEscape(layer.collection) + "/items?" + q.Encode()
+	}
+}
+
+// wfsPageURL returns the url of the WFS page starting at index start
+func wfsPageURL(u string, start int) (string, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	q := pu.Query()
+	q.Set("startIndex", strconv.Itoa(start))
+	pu.RawQuery = q.Encode()
+	return pu.String(), nil
+}
+
+// featureCollection is a page of a GeoJSON response. The features are decoded separately
+// since ids can be strings and geometries can be null.
+type featureCollection struct {
+	Features       []feature `json:"features"`
+	NumberReturned *int      `json:"numberReturned"`
+	NumberMatched  *int      `json:"numberMatched"`
+	Links          []struct {
+		Href string `json:"href"`
+		Rel  string `json:"rel"`
+		Type string `json:"type"`
+	} `json:"links"`
+}
+
+type feature struct {
+	ID         json.RawMessage        `json:"id"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// nextURL returns the url of the "next" link, or "" on the last page
+func (fc featureCollection) nextURL() string {
+	for _, l := range fc.Links {
+		if l.Rel == "next" && (l.Type == "" || l.Type == "application/geo+json" || l.Type == "application/json") {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// fetch reads every page of features within the bbox. At most max_pages are read per tile.
+func (p *Provider) fetch(ctx context.Context, layer Layer, bbox *geom.Extent) ([]provider.Feature, error) {
+	var (
+		features []provider.Feature
+		next     = p.firstPageURL(layer, bbox)
+		read     int
+	)
+
+	for page := 0; next != ""; page++ {
+		if page == p.maxPages {
+			log.Warnf("ogcapi: layer (%v) has more than %v pages of features for bbox %v, the remaining features are dropped", layer.name, p.maxPages, formatBBox(bbox))
+			break
+		}
+
+		fc, err := p.fetchPage(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range fc.Features {
+			f, ok, err := layer.decode(fc.Features[i])
+			if err != nil {
+				return nil, fmt.Errorf("ogcapi: layer (%v): %v", layer.name, err)
+			}
+			if ok {
+				features = append(features, f)
+			}
+		}
+		read += len(fc.Features)
+
+		switch p.protocol {
+		case ProtocolWFS:
+			// WFS 2.0 has no next link in the GeoJSON output, page until a short page
+			// or every matched feature has been read
+			next = ""
+			if len(fc.Features) < p.pageSize || len(fc.Features) == 0 {
+				break
+			}
+			if fc.NumberMatched != nil && read >= *fc.NumberMatched {
+				break
+			}
+			if next, err = wfsPageURL(p.firstPageURL(layer, bbox), read); err != nil {
+				return nil, err
+			}
+		default:
+			next = fc.nextURL()
+		}
+	}
+
+	return features, nil
+}
+
+// fetchPage requests a single page, retrying network errors, 429 and 5xx responses
+func (p *Provider) fetchPage(ctx context.Context, u string) (*featureCollection, error) {
+	var (
+		fc    *featureCollection
+		err   error
+		delay = p.retryDelay
+	)
+
+	for attempt := 1; attempt <= p.retryAttempts; attempt++ {
+		if fc, err = p.get(ctx, u); err == nil {
+			return fc, nil
+		}
+
+		var es ErrStatus
+		if ctx.Err() != nil || (errors.As(err, &es) && !es.retryable()) || attempt == p.retryAttempts {
+			break
+		}
+
+		log.Debugf("ogcapi: attempt %v of %v for (%v) failed: %v", attempt, p.retryAttempts, u, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, provider.ErrCanceled
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	if ctx.Err() != nil {
+		return nil, provider.ErrCanceled
+	}
+	return nil, err
+}
+
+func (p *Provider) get(ctx context.Context, u string) (*featureCollection, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/geo+json, application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrStatus{URL: u, Status: resp.StatusCode}
+	}
+
+	var fc featureCollection
+	if err := json.NewDecoder(resp.Body).Decode(&fc); err != nil {
+		return nil, fmt.Errorf("ogcapi: decoding (%v): %v", u, err)
+	}
+
+	return &fc, nil
+}
+
+// decode converts a GeoJSON feature to a provider feature. Features without a geometry are skipped.
+func (l Layer) decode(f feature) (provider.Feature, bool, error) {
+	if len(f.Geometry) == 0 || string(f.Geometry) == "null" {
+		return provider.Feature{}, false, nil
+	}
+
+	var g geojson.Geometry
+	if err := json.Unmarshal(f.Geometry, &g); err != nil {
+		return provider.Feature{}, false, err
+	}
+
+	tags := make(map[string]interface{}, len(f.Properties))
+	if len(l.fields) == 0 {
+		for k, v := range f.Properties {
+			setTag(tags, k, v)
+		}
+	} else {
+		for _, k := range l.fields {
+			setTag(tags, k, f.Properties[k])
+		}
+	}
+
+	return provider.Feature{
+		ID:       l.featureID(f),
+		Geometry: g.Geometry,
+		SRID:     srid,
+		Tags:     tags,
+	}, true, nil
+}
+
+// setTag adds the property to the tags. null values are dropped, objects and arrays are encoded as JSON strings
+func setTag(tags map[string]interface{}, k string, v interface{}) {
+	switch v.(type) {
+	case nil:
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		tags[k] = string(b)
+	default:
+		tags[k] = v
+	}
+}
+
+// featureID returns the numeric feature id, the id_fieldname property or a hash of the string id
+func (l Layer) featureID(f feature) uint64 {
+	var id interface{}
+	if len(f.ID) != 0 {
+		json.Unmarshal(f.ID, &id)
+	}
+	if l.idFieldname != "" {
+		if v, ok := f.Properties[l.idFieldname]; ok {
+			id = v
+		}
+	}
+
+	return provider.FeatureID(id)
+}
@@ -0,0 +1,22 @@
+package ogcapi
+
+import (
+	"github.com/go-spatial/geom"
+)
+
+type Layer struct {
+	name string
+	// collection is the OGC API collection id or the WFS feature type name
+	collection string
+	// idFieldname is the property used as the feature id when the feature has no numeric id
+	idFieldname string
+	// fields limits the properties encoded as tags. empty encodes every property
+	fields []string
+	// geomType is the configured geometry type of the layer, nil when unknown
+	geomType geom.Geometry
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return srid }
@@ -0,0 +1,326 @@
+// Package ogcapi provides a provider which fetches features from a remote OGC API - Features
+// or WFS 2.0 endpoint. Each tile request fetches the features within the tile's bounding box,
+// following the endpoint's paging until every feature has been read.
+package ogcapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/ttlcache"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "ogcapi"
+
+// srid of the features. OGC API - Features defaults to CRS84 (lon / lat) and WFS requests ask for it.
+const srid = tegola.WGS84
+
+// protocols supported by the provider
+const (
+	// ProtocolFeatures is OGC API - Features (Part 1: Core)
+	ProtocolFeatures = "features"
+	// ProtocolWFS is WFS 2.0 with a GeoJSON output format
+	ProtocolWFS = "wfs"
+)
+
+const (
+	ConfigKeyURL             = "url"
+	ConfigKeyProtocol        = "protocol"
+	ConfigKeyHeaders         = "headers"
+	ConfigKeyTimeout         = "timeout"
+	ConfigKeyPageSize        = "page_size"
+	ConfigKeyMaxPages        = "max_pages"
+	ConfigKeyRetryAttempts   = "retry_attempts"
+	ConfigKeyRetryDelay      = "retry_delay_ms"
+	ConfigKeyCacheTTL        = "cache_ttl"
+	ConfigKeyCacheMaxEntries = "cache_max_entries"
+	ConfigKeyOutputFormat    = "output_format"
+	ConfigKeyLayers          = "layers"
+
+	ConfigKeyLayerName    = "name"
+	ConfigKeyCollection   = "collection"
+	ConfigKeyIDFieldname  = "id_fieldname"
+	ConfigKeyFields       = "fields"
+	ConfigKeyGeometryType = "geometry_type"
+)
+
+const (
+	DefaultProtocol        = ProtocolFeatures
+	DefaultTimeout         = 30
+	DefaultPageSize        = 1000
+	DefaultMaxPages        = 10
+	DefaultRetryAttempts   = 3
+	DefaultRetryDelay      = 200
+	DefaultCacheTTL        = 60
+	DefaultCacheMaxEntries = 1000
+	DefaultOutputFormat    = "application/json"
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, nil)
+}
+
+// Provider fetches features from an OGC API - Features or WFS endpoint
+type Provider struct {
+	url          string
+	protocol     string
+	headers      map[string]string
+	outputFormat string
+	pageSize     int
+	maxPages     int
+
+	retryAttempts int
+	retryDelay    time.Duration
+
+	client *http.Client
+	// cache holds the features of recently requested tiles. nil disables caching
+	cache *ttlcache.Cache
+
+	// map of layer name and corresponding collection
+	layers map[string]Layer
+}
+
+// NewTileProvider instantiates and returns a new ogcapi provider or an error.
+//
+//	url (string): [Required] the landing page of the OGC API or the WFS endpoint
+//	protocol (string): [Optional] "features" or "wfs". defaults to "features"
+//	headers (map[string]string): [Optional] headers added to every request (i.e. API keys)
+//	timeout (int): [Optional] the number of seconds allowed per request. defaults to 30
+//	page_size (int): [Optional] the number of features requested per page. defaults to 1000
+//	max_pages (int): [Optional] the maximum number of pages read per tile. defaults to 10
+//	retry_attempts (int): [Optional] the number of attempts per page request. defaults to 3
+//	retry_delay_ms (int): [Optional] the delay before retrying, doubled after each attempt. defaults to 200
+//	cache_ttl (int): [Optional] the number of seconds tile results are cached for, 0 disables caching. defaults to 60
+//	cache_max_entries (int): [Optional] the maximum number of cached tile results. defaults to 1000
+//	output_format (string): [Optional] the WFS GeoJSON output format. defaults to "application/json"
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		collection (string): [Required] the collection id (OGC API) or feature type name (WFS)
+//		id_fieldname (string): [Optional] the property used as the feature id when the feature id is not numeric
+//		fields ([]string): [Optional] the properties to encode as tags. defaults to all properties
+//		geometry_type (string): [Optional] the geometry type of the layer, reported in the capabilities
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	url, err := config.String(ConfigKeyURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if url == "" {
+		return nil, ErrMissingURL
+	}
+
+	protocol := DefaultProtocol
+	if protocol, err = config.String(ConfigKeyProtocol, &protocol); err != nil {
+		return nil, err
+	}
+	switch protocol = strings.ToLower(protocol); protocol {
+	case ProtocolFeatures, ProtocolWFS:
+	default:
+		return nil, ErrInvalidProtocol{Protocol: protocol}
+	}
+
+	outputFormat := DefaultOutputFormat
+	if outputFormat, err = config.String(ConfigKeyOutputFormat, &outputFormat); err != nil {
+		return nil, err
+	}
+
+	ints := []struct {
+		key string
+		val int
+	}{
+		{ConfigKeyTimeout, DefaultTimeout},
+		{ConfigKeyPageSize, DefaultPageSize},
+		{ConfigKeyMaxPages, DefaultMaxPages},
+		{ConfigKeyRetryAttempts, DefaultRetryAttempts},
+		{ConfigKeyRetryDelay, DefaultRetryDelay},
+		{ConfigKeyCacheTTL, DefaultCacheTTL},
+		{ConfigKeyCacheMaxEntries, DefaultCacheMaxEntries},
+	}
+	for i := range ints {
+		if ints[i].val, err = config.Int(ints[i].key, &ints[i].val); err != nil {
+			return nil, err
+		}
+		if ints[i].val < 0 {
+			return nil, fmt.Errorf("ogcapi: %v must not be negative, got %v", ints[i].key, ints[i].val)
+		}
+	}
+	timeout, pageSize, maxPages := ints[0].val, ints[1].val, ints[2].val
+	retryAttempts, retryDelay := ints[3].val, ints[4].val
+	cacheTTL, cacheMaxEntries := ints[5].val, ints[6].val
+
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	if maxPages == 0 {
+		maxPages = DefaultMaxPages
+	}
+	if retryAttempts == 0 {
+		retryAttempts = 1
+	}
+
+	headers, err := dict.StringMap(config, ConfigKeyHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Provider{
+		url:           strings.TrimSuffix(url, "/"),
+		protocol:      protocol,
+		headers:       headers,
+		outputFormat:  outputFormat,
+		pageSize:      pageSize,
+		maxPages:      maxPages,
+		retryAttempts: retryAttempts,
+		retryDelay:    time.Duration(retryDelay) * time.Millisecond,
+		client:        &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		layers:        map[string]Layer{},
+	}
+	if cacheTTL > 0 && cacheMaxEntries > 0 {
+		p.cache = ttlcache.New(cacheMaxEntries, time.Duration(cacheTTL)*time.Second)
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// AddLayer adds a collection layer to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	empty := ""
+
+	collection, err := layerConf.String(ConfigKeyCollection, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyCollection, err)
+	}
+	if collection == "" {
+		return ErrMissingCollection{LayerName: name}
+	}
+
+	idFieldname, err := layerConf.String(ConfigKeyIDFieldname, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyIDFieldname, err)
+	}
+
+	fields, err := layerConf.StringSlice(ConfigKeyFields)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyFields, err)
+	}
+
+	gtype, err := layerConf.String(ConfigKeyGeometryType, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyGeometryType, err)
+	}
+	geomType, ok := provider.GeometryType(gtype)
+	if !ok {
+		return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+	}
+
+	p.layers[name] = Layer{
+		name:        name,
+		collection:  collection,
+		idFieldname: idFieldname,
+		fields:      fields,
+		geomType:    geomType,
+	}
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures fetches the features of the layer's collection within the tile's buffered extent
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	z, x, y := tile.ZXY()
+	key := fmt.Sprintf("%v/%v/%v/%v", lyrID, z, x, y)
+
+	var features []provider.Feature
+	if v, hit := p.cache.Get(key); hit {
+		features = v.([]provider.Feature)
+	} else {
+		ext, tileSRID := tile.BufferedExtent()
+		bbox, err := lonLatExtent(ext, tileSRID)
+		if err != nil {
+			return err
+		}
+
+		if features, err = p.fetch(ctx, layer, bbox); err != nil {
+			return err
+		}
+		p.cache.Set(key, features)
+	}
+
+	for i := range features {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		// the features may be cached, so callers get their own tags to modify
+		f := features[i]
+		f.Tags = provider.CopyTags(f.Tags)
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
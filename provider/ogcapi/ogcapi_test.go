@@ -0,0 +1,235 @@
+package ogcapi_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/ogcapi"
+)
+
+func feature(id interface{}, name string, lon, lat float64) string {
+	return fmt.Sprintf(`{"type":"Feature","id":%q,"geometry":{"type":"Point","coordinates":[%v,%v]},"properties":{"name":%q,"tags":{"a":1},"empty":null}}`, fmt.Sprint(id), lon, lat, name)
+}
+
+// newServer returns a test server with 3 features, paged 2 at a time. The first
+// request of every page fails with a 503 to exercise the retries.
+func newServer(t *testing.T, requests *int32) *httptest.Server {
+	features := []string{
+		feature(1, "one", -122.4, 37.7),
+		feature("two", "two", -122.41, 37.71),
+		feature(3, "three", -122.42, 37.72),
+	}
+	failed := map[string]bool{}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if !failed[r.URL.String()] {
+			failed[r.URL.String()] = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		q := r.URL.Query()
+		if q.Get("bbox") == "" {
+			t.Errorf("request (%v) is missing the bbox", r.URL)
+		}
+
+		var start, limit int
+		switch r.URL.Path {
+		case "/collections/places/items":
+			start, _ = strconv.Atoi(q.Get("offset"))
+			limit, _ = strconv.Atoi(q.Get("limit"))
+		case "/wfs":
+			if q.Get("typeNames") != "places" {
+				t.Errorf("unexpected typeNames (%v)", q.Get("typeNames"))
+			}
+			start, _ = strconv.Atoi(q.Get("startIndex"))
+			limit, _ = strconv.Atoi(q.Get("count"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		end := start + limit
+		if end > len(features) {
+			end = len(features)
+		}
+
+		body := `{"type":"FeatureCollection","numberMatched":3,"features":[`
+		for i, f := range features[start:end] {
+			if i > 0 {
+				body += ","
+			}
+			body += f
+		}
+		body += `]`
+		if r.URL.Path != "/wfs" && end < len(features) {
+			next := *r.URL
+			nq := next.Query()
+			nq.Set("offset", strconv.Itoa(end))
+			next.RawQuery = nq.Encode()
+			body += fmt.Sprintf(`,"links":[{"rel":"next","type":"application/geo+json","href":%q}]`, srv.URL+next.String())
+		}
+		body += `}`
+
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Write([]byte(body))
+	}))
+
+	return srv
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		protocol string
+		path     string
+		fields   []string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			var requests int32
+			srv := newServer(t, &requests)
+			defer srv.Close()
+
+			layer := map[string]interface{}{
+				"name":       "places",
+				"collection": "places",
+			}
+			if tc.fields != nil {
+				layer["fields"] = tc.fields
+			}
+
+			p, err := ogcapi.NewTileProvider(dict.Dict{
+				"url":            srv.URL + tc.path,
+				"protocol":       tc.protocol,
+				"page_size":      2,
+				"retry_delay_ms": 1,
+				"headers":        map[string]interface{}{"X-Api-Key": "secret"},
+				"layers":         []map[string]interface{}{layer},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			tile := provider.NewTile(10, 163, 395, 64, tegola.WebMercator)
+
+			for run := 0; run < 2; run++ {
+				var got []*provider.Feature
+				err = p.TileFeatures(context.Background(), "places", tile, func(f *provider.Feature) error {
+					got = append(got, f)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if len(got) != 3 {
+					t.Fatalf("expected 3 features got %v", len(got))
+				}
+				if got[0].ID != 1 || got[2].ID != 3 || got[1].ID == 0 {
+					t.Errorf("unexpected feature ids %v, %v, %v", got[0].ID, got[1].ID, got[2].ID)
+				}
+				if got[0].Tags["name"] != "one" {
+					t.Errorf("expected tag name one got %v", got[0].Tags["name"])
+				}
+				if _, ok := got[0].Tags["empty"]; ok {
+					t.Errorf("expected null properties to be dropped")
+				}
+				if tc.fields == nil && got[0].Tags["tags"] != `{"a":1}` {
+					t.Errorf("expected object properties encoded as JSON got %v", got[0].Tags["tags"])
+				}
+				if tc.fields != nil && len(got[0].Tags) != len(tc.fields) {
+					t.Errorf("expected only %v tags got %v", tc.fields, got[0].Tags)
+				}
+
+				// modifying the tags must not change the cached features
+				delete(got[0].Tags, "name")
+			}
+
+			// 2 pages, each retried once. the second run is served from the cache
+			if requests != 4 {
+				t.Errorf("expected 4 requests got %v", requests)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"features": {
+			protocol: "features",
+		},
+		"features fields": {
+			protocol: "features",
+			fields:   []string{"name"},
+		},
+		"wfs": {
+			protocol: "wfs",
+			path:     "/wfs",
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		config      dict.Dict
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := ogcapi.NewTileProvider(tc.config)
+			if err != tc.expectedErr {
+				t.Errorf("expected err %v got %v", tc.expectedErr, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing url": {
+			config:      dict.Dict{"url": ""},
+			expectedErr: ogcapi.ErrMissingURL,
+		},
+		"invalid protocol": {
+			config:      dict.Dict{"url": "https://example.com", "protocol": "wms"},
+			expectedErr: ogcapi.ErrInvalidProtocol{Protocol: "wms"},
+		},
+		"missing collection": {
+			config: dict.Dict{
+				"url":    "https://example.com",
+				"layers": []map[string]interface{}{{"name": "places"}},
+			},
+			expectedErr: ogcapi.ErrMissingCollection{LayerName: "places"},
+		},
+		"invalid geometry type": {
+			config: dict.Dict{
+				"url":    "https://example.com",
+				"layers": []map[string]interface{}{{"name": "places", "collection": "places", "geometry_type": "circle"}},
+			},
+			expectedErr: ogcapi.ErrInvalidGeometryType{LayerName: "places", GeometryType: "circle"},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,29 @@
+// Package ogr provides a provider backed by the GDAL/OGR library, which can read any vector
+// format OGR supports (FileGDB, Shapefile, DXF, GML, KML, ...). Every OGR layer of the data
+// source can be exposed as a tegola layer and features are filtered by the tile's bounding box.
+//
+// The provider requires cgo and the GDAL development files. It's only built with the `gdal` build tag:
+//
+//	go build -tags 'gdal'
+package ogr
+
+const Name = "ogr"
+
+const (
+	ConfigKeySource         = "source"
+	ConfigKeyOpenOptions    = "open_options"
+	ConfigKeyMaxConnections = "max_connections"
+	ConfigKeyLayers         = "layers"
+
+	ConfigKeyLayerName = "name"
+	ConfigKeyOGRLayer  = "layer"
+	ConfigKeyFields    = "fields"
+	ConfigKeyWhere     = "where"
+	ConfigKeySRID      = "srid"
+)
+
+const (
+	// DefaultMaxConnections is the number of data source handles opened concurrently.
+	// OGR handles can't be shared between goroutines.
+	DefaultMaxConnections = 4
+)
@@ -0,0 +1,54 @@
+package ogr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingSource    = errors.New("ogr: provider is missing 'source'")
+	ErrMissingLayerName = errors.New("ogr: layer is missing 'name'")
+)
+
+type ErrOpen struct {
+	Source string
+}
+
+func (e ErrOpen) Error() string {
+	return fmt.Sprintf("ogr: unable to open source (%v)", e.Source)
+}
+
+type ErrOGRLayerNotFound struct {
+	LayerName string
+	OGRLayer  string
+}
+
+func (e ErrOGRLayerNotFound) Error() string {
+	return fmt.Sprintf("ogr: layer (%v) references the missing OGR layer (%v)", e.LayerName, e.OGRLayer)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("ogr: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("ogr: layer (%v) not found", e.LayerName)
+}
+
+// ErrUnsupportedSRID is returned for OGR layers which are not in EPSG:4326 or EPSG:3857
+type ErrUnsupportedSRID struct {
+	LayerName string
+	SRID      uint64
+}
+
+func (e ErrUnsupportedSRID) Error() string {
+	return fmt.Sprintf("ogr: layer (%v) has the unsupported SRID (%v), expected 4326 or 3857. set 'srid' to override", e.LayerName, e.SRID)
+}
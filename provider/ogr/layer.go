@@ -0,0 +1,23 @@
+package ogr
+
+import (
+	"github.com/go-spatial/geom"
+)
+
+type Layer struct {
+	name string
+	// ogrLayer is the name of the layer within the OGR data source
+	ogrLayer string
+	// fields limits the OGR fields encoded as tags. empty encodes every field
+	fields []string
+	// where is an OGR attribute filter applied to the layer
+	where    string
+	geomType geom.Geometry
+	srid     uint64
+	bbox     geom.Extent
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
@@ -0,0 +1,520 @@
+// +build cgo,gdal
+
+package ogr
+
+/*
+#cgo pkg-config: gdal
+#include <stdlib.h>
+#include "gdal.h"
+#include "ogr_api.h"
+#include "ogr_srs_api.h"
+#include "cpl_string.h"
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"unsafe"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkb"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+func init() {
+	C.GDALAllRegister()
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// providers are tracked so their data sources can be closed during cleanup
+var (
+	providersLock sync.Mutex
+	providers     []*Provider
+)
+
+// datasetPool hands out data source handles. A handle is only used by a single goroutine at a time.
+type datasetPool struct {
+	source      string
+	openOptions []string
+
+	handles chan C.GDALDatasetH
+
+	sync.Mutex
+	opened int
+	max    int
+	all    []C.GDALDatasetH
+}
+
+func (p *datasetPool) open() (C.GDALDatasetH, error) {
+	csource := C.CString(p.source)
+	defer C.free(unsafe.Pointer(csource))
+
+	var options **C.char
+	for _, o := range p.openOptions {
+		co := C.CString(o)
+		options = C.CSLAddString(options, co)
+		C.free(unsafe.Pointer(co))
+	}
+	defer C.CSLDestroy(options)
+
+	ds := C.GDALOpenEx(csource, C.GDAL_OF_VECTOR|C.GDAL_OF_READONLY, nil, options, nil)
+	if ds == nil {
+		return nil, ErrOpen{Source: p.source}
+	}
+
+	p.all = append(p.all, ds)
+	return ds, nil
+}
+
+// get returns an idle handle, opening a new handle while fewer than max are open
+func (p *datasetPool) get(ctx context.Context) (C.GDALDatasetH, error) {
+	select {
+	case ds := <-p.handles:
+		return ds, nil
+	default:
+	}
+
+	p.Lock()
+	if p.opened < p.max {
+		ds, err := p.open()
+		if err == nil {
+			p.opened++
+		}
+		p.Unlock()
+		return ds, err
+	}
+	p.Unlock()
+
+	select {
+	case ds := <-p.handles:
+		return ds, nil
+	case <-ctx.Done():
+		return nil, provider.ErrCanceled
+	}
+}
+
+func (p *datasetPool) put(ds C.GDALDatasetH) {
+	p.handles <- ds
+}
+
+func (p *datasetPool) close() {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, ds := range p.all {
+		C.GDALClose(ds)
+	}
+	p.all = nil
+}
+
+// Provider reads features from an OGR data source
+type Provider struct {
+	pool *datasetPool
+	// map of layer name and corresponding OGR layer
+	layers map[string]Layer
+}
+
+// NewTileProvider instantiates and returns a new OGR provider or an error.
+// When no layers are configured every OGR layer of the data source is exposed with its OGR name.
+//
+//	source (string): [Required] the data source opened by OGR (i.e. a file path, directory or connection string)
+//	open_options ([]string): [Optional] the driver open options (i.e. "LIST_ALL_TABLES=YES")
+//	max_connections (int): [Optional] the number of data source handles opened concurrently. defaults to 4
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		layer (string): [Optional] the name of the OGR layer. defaults to name
+//		fields ([]string): [Optional] the fields to encode as tags. defaults to every field
+//		where (string): [Optional] an OGR SQL attribute filter (i.e. "type = 'road'")
+//		srid (int): [Optional] the SRID of the layer, when OGR can't identify it. 4326 or 3857
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	source, err := config.String(ConfigKeySource, nil)
+	if err != nil {
+		return nil, err
+	}
+	if source == "" {
+		return nil, ErrMissingSource
+	}
+
+	openOptions, err := config.StringSlice(ConfigKeyOpenOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	maxConnections := DefaultMaxConnections
+	if maxConnections, err = config.Int(ConfigKeyMaxConnections, &maxConnections); err != nil {
+		return nil, err
+	}
+	if maxConnections < 1 {
+		maxConnections = 1
+	}
+
+	p := Provider{
+		pool: &datasetPool{
+			source:      source,
+			openOptions: openOptions,
+			handles:     make(chan C.GDALDatasetH, maxConnections),
+			max:         maxConnections,
+		},
+		layers: map[string]Layer{},
+	}
+
+	// open the first handle to validate the source and read the layers' metadata
+	ds, err := p.pool.get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer p.pool.put(ds)
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		p.pool.close()
+		return nil, err
+	}
+
+	if len(layers) == 0 {
+		for i := 0; i < int(C.GDALDatasetGetLayerCount(ds)); i++ {
+			name := C.GoString(C.OGR_L_GetName(C.GDALDatasetGetLayer(ds, C.int(i))))
+			layers = append(layers, dict.Dict{ConfigKeyLayerName: name})
+		}
+	}
+
+	for _, layerConf := range layers {
+		if err := p.addLayer(ds, layerConf); err != nil {
+			p.pool.close()
+			return nil, err
+		}
+	}
+
+	providersLock.Lock()
+	providers = append(providers, &p)
+	providersLock.Unlock()
+
+	return &p, nil
+}
+
+// AddLayer adds an OGR layer to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	ds, err := p.pool.get(context.Background())
+	if err != nil {
+		return err
+	}
+	defer p.pool.put(ds)
+
+	return p.addLayer(ds, layerConf)
+}
+
+func (p *Provider) addLayer(ds C.GDALDatasetH, layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	ogrLayer, err := layerConf.String(ConfigKeyOGRLayer, &name)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyOGRLayer, err)
+	}
+
+	fields, err := layerConf.StringSlice(ConfigKeyFields)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyFields, err)
+	}
+
+	empty := ""
+	where, err := layerConf.String(ConfigKeyWhere, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyWhere, err)
+	}
+
+	cname := C.CString(ogrLayer)
+	defer C.free(unsafe.Pointer(cname))
+
+	lyr := C.GDALDatasetGetLayerByName(ds, cname)
+	if lyr == nil {
+		return ErrOGRLayerNotFound{LayerName: name, OGRLayer: ogrLayer}
+	}
+
+	var srid uint64
+	if srs := C.OGR_L_GetSpatialRef(lyr); srs != nil {
+		C.OSRAutoIdentifyEPSG(srs)
+		if code := C.OSRGetAuthorityCode(srs, nil); code != nil {
+			srid, _ = strconv.ParseUint(C.GoString(code), 10, 64)
+		}
+	}
+	var configSRID uint
+	if configSRID, err = layerConf.Uint(ConfigKeySRID, &configSRID); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeySRID, err)
+	}
+	if configSRID != 0 {
+		srid = uint64(configSRID)
+	}
+	if srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return ErrUnsupportedSRID{LayerName: name, SRID: srid}
+	}
+
+	var env C.OGREnvelope
+	bbox := geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}
+	if C.OGR_L_GetExtent(lyr, &env, 1) == C.OGRERR_NONE {
+		bbox = geom.Extent{float64(env.MinX), float64(env.MinY), float64(env.MaxX), float64(env.MaxY)}
+		if srid == tegola.WebMercator {
+			if bbox, err = toWGS84(bbox); err != nil {
+				return err
+			}
+		}
+	}
+
+	p.layers[name] = Layer{
+		name:     name,
+		ogrLayer: ogrLayer,
+		fields:   fields,
+		where:    where,
+		geomType: geometryType(C.OGR_GT_Flatten(C.OGR_L_GetGeomType(lyr))),
+		srid:     srid,
+		bbox:     bbox,
+	}
+
+	return nil
+}
+
+func toWGS84(ext geom.Extent) (geom.Extent, error) {
+	min, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MinX(), ext.MinY()})
+	if err != nil {
+		return ext, err
+	}
+	max, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MaxX(), ext.MaxY()})
+	if err != nil {
+		return ext, err
+	}
+	minPt, maxPt := min.(geom.Point), max.(geom.Point)
+	return geom.Extent{minPt.X(), minPt.Y(), maxPt.X(), maxPt.Y()}, nil
+}
+
+// geometryType returns the geometry for a flattened OGR geometry type, nil for unknown or mixed layers
+func geometryType(t C.OGRwkbGeometryType) geom.Geometry {
+	switch t {
+	case C.wkbPoint:
+		return geom.Point{}
+	case C.wkbMultiPoint:
+		return geom.MultiPoint{}
+	case C.wkbLineString, C.wkbCircularString, C.wkbCompoundCurve:
+		return geom.LineString{}
+	case C.wkbMultiLineString, C.wkbMultiCurve:
+		return geom.MultiLineString{}
+	case C.wkbPolygon, C.wkbCurvePolygon:
+		return geom.Polygon{}
+	case C.wkbMultiPolygon, C.wkbMultiSurface:
+		return geom.MultiPolygon{}
+	case C.wkbGeometryCollection:
+		return geom.Collection{}
+	default:
+		return nil
+	}
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent returns the extent of the OGR layer
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	l, ok := p.layers[lyrID]
+	if !ok {
+		return geom.Extent{}, ErrLayerNotFound{LayerName: lyrID}
+	}
+	return l.bbox, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures reads the features of the OGR layer intersecting the tile's buffered extent
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	ext, tileSRID := tile.BufferedExtent()
+	if layer.srid == tegola.WGS84 && tileSRID != tegola.WGS84 {
+		wgs84, err := toWGS84(*ext)
+		if err != nil {
+			return err
+		}
+		ext = &wgs84
+	}
+
+	ds, err := p.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.pool.put(ds)
+
+	cname := C.CString(layer.ogrLayer)
+	defer C.free(unsafe.Pointer(cname))
+
+	lyr := C.GDALDatasetGetLayerByName(ds, cname)
+	if lyr == nil {
+		return ErrOGRLayerNotFound{LayerName: layer.name, OGRLayer: layer.ogrLayer}
+	}
+
+	// the handle is reused, so the filters are always reset
+	var cwhere *C.char
+	if layer.where != "" {
+		cwhere = C.CString(layer.where)
+		defer C.free(unsafe.Pointer(cwhere))
+	}
+	if C.OGR_L_SetAttributeFilter(lyr, cwhere) != C.OGRERR_NONE {
+		return fmt.Errorf("ogr: layer (%v) has an invalid where filter (%v)", layer.name, layer.where)
+	}
+	C.OGR_L_SetSpatialFilterRect(lyr, C.double(ext.MinX()), C.double(ext.MinY()), C.double(ext.MaxX()), C.double(ext.MaxY()))
+	C.OGR_L_ResetReading(lyr)
+
+	var fields map[string]bool
+	if len(layer.fields) > 0 {
+		fields = make(map[string]bool, len(layer.fields))
+		for _, f := range layer.fields {
+			fields[f] = true
+		}
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		f := C.OGR_L_GetNextFeature(lyr)
+		if f == nil {
+			return nil
+		}
+
+		feature, ok, err := decodeFeature(f, fields, layer.srid)
+		C.OGR_F_Destroy(f)
+		if err != nil {
+			log.Warnf("ogr: layer (%v) feature (%v) skipped: %v", layer.name, feature.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if err := fn(&feature); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeFeature converts an OGR feature to a provider feature. Features without a geometry are skipped.
+func decodeFeature(f C.OGRFeatureH, fields map[string]bool, srid uint64) (provider.Feature, bool, error) {
+	feature := provider.Feature{
+		ID:   uint64(C.OGR_F_GetFID(f)),
+		SRID: srid,
+		Tags: map[string]interface{}{},
+	}
+
+	g := C.OGR_F_GetGeometryRef(f)
+	if g == nil || C.OGR_G_IsEmpty(g) != 0 {
+		return feature, false, nil
+	}
+
+	// curves are approximated with line strings and the encoder only supports 2D geometries
+	if C.OGR_G_HasCurveGeometry(g, 0) != 0 {
+		g = C.OGR_G_GetLinearGeometry(g, 0, nil)
+		if g == nil {
+			return feature, false, fmt.Errorf("unable to linearize the curve geometry")
+		}
+		defer C.OGR_G_DestroyGeometry(g)
+	}
+	C.OGR_G_FlattenTo2D(g)
+
+	buf := make([]byte, int(C.OGR_G_WkbSize(g)))
+	if len(buf) == 0 {
+		return feature, false, nil
+	}
+	if C.OGR_G_ExportToWkb(g, C.wkbNDR, (*C.uchar)(unsafe.Pointer(&buf[0]))) != C.OGRERR_NONE {
+		return feature, false, fmt.Errorf("unable to export the geometry as WKB")
+	}
+
+	var err error
+	if feature.Geometry, err = wkb.DecodeBytes(buf); err != nil {
+		return feature, false, err
+	}
+
+	for i := 0; i < int(C.OGR_F_GetFieldCount(f)); i++ {
+		ci := C.int(i)
+		defn := C.OGR_F_GetFieldDefnRef(f, ci)
+		name := C.GoString(C.OGR_Fld_GetNameRef(defn))
+
+		if fields != nil && !fields[name] {
+			continue
+		}
+		if C.OGR_F_IsFieldSetAndNotNull(f, ci) == 0 {
+			continue
+		}
+
+		switch C.OGR_Fld_GetType(defn) {
+		case C.OFTInteger:
+			feature.Tags[name] = int64(C.OGR_F_GetFieldAsInteger(f, ci))
+		case C.OFTInteger64:
+			feature.Tags[name] = int64(C.OGR_F_GetFieldAsInteger64(f, ci))
+		case C.OFTReal:
+			feature.Tags[name] = float64(C.OGR_F_GetFieldAsDouble(f, ci))
+		default:
+			// strings, dates and lists are encoded as OGR formats them
+			feature.Tags[name] = C.GoString(C.OGR_F_GetFieldAsString(f, ci))
+		}
+	}
+
+	return feature, true, nil
+}
+
+// Close closes the provider's data source handles
+func (p *Provider) Close() error {
+	p.pool.close()
+	return nil
+}
+
+// Cleanup will close all OGR data sources
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if len(providers) > 0 {
+		log.Infof("cleaning up ogr providers")
+	}
+
+	for i := range providers {
+		providers[i].Close()
+	}
+
+	providers = nil
+}
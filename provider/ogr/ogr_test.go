@@ -0,0 +1,98 @@
+// +build cgo,gdal
+
+package ogr_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/ogr"
+)
+
+const places = `{
+"type": "FeatureCollection",
+"features": [
+{"type": "Feature", "id": 1, "properties": {"name": "one", "kind": "city"}, "geometry": {"type": "Point", "coordinates": [-122.4, 37.7]}},
+{"type": "Feature", "id": 2, "properties": {"name": "two", "kind": "town"}, "geometry": {"type": "Point", "coordinates": [-122.41, 37.71]}},
+{"type": "Feature", "id": 3, "properties": {"name": "far", "kind": "city"}, "geometry": {"type": "Point", "coordinates": [10, 10]}}
+]}`
+
+func TestTileFeatures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ogr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "places.geojson")
+	if err := ioutil.WriteFile(source, []byte(places), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type tcase struct {
+		layer    map[string]interface{}
+		expected []string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			p, err := ogr.NewTileProvider(dict.Dict{
+				"source": source,
+				"layers": []map[string]interface{}{tc.layer},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer p.(*ogr.Provider).Close()
+
+			tile := provider.NewTile(10, 163, 395, 64, tegola.WebMercator)
+
+			var got []string
+			err = p.TileFeatures(context.Background(), "places", tile, func(f *provider.Feature) error {
+				got = append(got, f.Tags["name"].(string))
+				if _, ok := f.Tags["kind"]; ok && tc.layer["fields"] != nil {
+					t.Errorf("expected only the configured fields got %v", f.Tags)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %v got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("expected %v got %v", tc.expected, got)
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"bbox filter": {
+			layer:    map[string]interface{}{"name": "places"},
+			expected: []string{"one", "two"},
+		},
+		"where": {
+			layer:    map[string]interface{}{"name": "places", "where": "kind = 'city'"},
+			expected: []string{"one"},
+		},
+		"fields": {
+			layer:    map[string]interface{}{"name": "places", "fields": []string{"name"}},
+			expected: []string{"one", "two"},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,103 @@
+package overpass
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+// queryKey is the layer and the tile of a query
+type queryKey struct {
+	layer   string
+	z, x, y uint
+}
+
+// call is a query in flight, shared by the tiles requested while it runs
+type call struct {
+	done  chan struct{}
+	items []item
+	err   error
+}
+
+// limiter bounds the number of concurrent queries and their rate, and backs off when the
+// server responds with a rate limit
+type limiter struct {
+	slots    chan struct{}
+	interval time.Duration
+
+	mu           sync.Mutex
+	next         time.Time
+	backoffUntil time.Time
+}
+
+func newLimiter(maxConcurrent int, perMinute int) *limiter {
+	l := limiter{}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	if perMinute > 0 {
+		l.interval = time.Minute / time.Duration(perMinute)
+	}
+	return &l
+}
+
+// wait blocks until a query can be sent. release must be called when the query is done.
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	until := l.backoffUntil
+	l.mu.Unlock()
+	if time.Now().Before(until) {
+		return ErrRateLimited{Until: until}
+	}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return provider.ErrCanceled
+		}
+	}
+
+	if l.interval == 0 {
+		return nil
+	}
+
+	// the queries are spaced by the interval, in the order they reserve their time
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			l.release()
+			return provider.ErrCanceled
+		}
+	}
+	return nil
+}
+
+func (l *limiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// backoff fails the queries until the time
+func (l *limiter) backoff(until time.Time) {
+	l.mu.Lock()
+	if until.After(l.backoffUntil) {
+		l.backoffUntil = until
+	}
+	l.mu.Unlock()
+}
@@ -0,0 +1,359 @@
+package overpass
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+// response is the JSON output (out:json) of an Overpass query
+type response struct {
+	Remark   string    `json:"remark"`
+	Elements []element `json:"elements"`
+}
+
+type element struct {
+	Type string            `json:"type"`
+	ID   int64             `json:"id"`
+	Lat  *float64          `json:"lat"`
+	Lon  *float64          `json:"lon"`
+	Tags map[string]string `json:"tags"`
+	// Nodes are the node ids of a way, resolved with the nodes of the response when the
+	// way has no geometry
+	Nodes []int64 `json:"nodes"`
+	// Geometry is the geometry of a way output with "out geom"
+	Geometry []*latLon `json:"geometry"`
+	// Center is the center of a way or relation output with "out center"
+	Center  *latLon  `json:"center"`
+	Members []member `json:"members"`
+}
+
+type latLon struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type member struct {
+	Type     string    `json:"type"`
+	Ref      int64     `json:"ref"`
+	Role     string    `json:"role"`
+	Geometry []*latLon `json:"geometry"`
+}
+
+// item is a decoded feature with the extent it's matched with the tiles by
+type item struct {
+	feature provider.Feature
+	extent  geom.Extent
+}
+
+// areaKeys are the keys of the tags making a closed way an area, unless it's tagged
+// with area=no
+var areaKeys = map[string]bool{
+	"building": true,
+	"landuse":  true,
+	"leisure":  true,
+	"natural":  true,
+	"amenity":  true,
+	"shop":     true,
+	"tourism":  true,
+	"place":    true,
+	"water":    true,
+	"aeroway":  true,
+	"military": true,
+}
+
+// the suffixes of the feature ids of the element types, so the ids of the elements of
+// different types don't collide
+var typeIDs = map[string]uint64{
+	"node":     1,
+	"way":      2,
+	"relation": 3,
+}
+
+// decode parses the Overpass response and returns its elements as features. Nodes
+// without tags only referenced by the ways of the response, i.e. the output of a
+// recurse down (>), are skipped.
+func decode(r io.Reader) ([]item, error) {
+	var resp response
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(resp.Remark, "runtime error") {
+		return nil, ErrRemark{Remark: resp.Remark}
+	}
+
+	nodes := map[int64]geom.Point{}
+	ways := map[int64][][2]float64{}
+	referenced := map[int64]bool{}
+	for i := range resp.Elements {
+		e := &resp.Elements[i]
+		switch e.Type {
+		case "node":
+			if e.Lat != nil && e.Lon != nil {
+				nodes[e.ID] = geom.Point{*e.Lon, *e.Lat}
+			}
+		case "way":
+			for _, id := range e.Nodes {
+				referenced[id] = true
+			}
+		}
+	}
+	for i := range resp.Elements {
+		e := &resp.Elements[i]
+		if e.Type == "way" {
+			ways[e.ID] = wayCoords(e, nodes)
+		}
+	}
+
+	var items []item
+	for i := range resp.Elements {
+		e := &resp.Elements[i]
+
+		var g geom.Geometry
+		switch e.Type {
+		case "node":
+			pt, ok := nodes[e.ID]
+			if !ok || (len(e.Tags) == 0 && referenced[e.ID]) {
+				continue
+			}
+			g = pt
+		case "way":
+			g = wayGeometry(ways[e.ID], e.Tags)
+		case "relation":
+			g = relationGeometry(e, ways)
+		}
+		if g == nil && e.Center != nil {
+			g = geom.Point{e.Center.Lon, e.Center.Lat}
+		}
+		if g == nil {
+			continue
+		}
+
+		ext, err := geom.NewExtentFromGeometry(g)
+		if err != nil {
+			continue
+		}
+
+		tags := make(map[string]interface{}, len(e.Tags)+2)
+		for k, v := range e.Tags {
+			tags[k] = v
+		}
+		tags["osm_id"] = e.ID
+		tags["osm_type"] = e.Type
+
+		items = append(items, item{
+			feature: provider.Feature{
+				ID:       uint64(e.ID)*10 + typeIDs[e.Type],
+				Geometry: g,
+				SRID:     tegola.WGS84,
+				Tags:     tags,
+			},
+			extent: *ext,
+		})
+	}
+
+	return items, nil
+}
+
+// wayCoords returns the coordinates of the way from its geometry or its nodes. Nodes
+// missing from the response are skipped.
+func wayCoords(e *element, nodes map[int64]geom.Point) [][2]float64 {
+	if len(e.Geometry) > 0 {
+		return latLonCoords(e.Geometry)
+	}
+
+	coords := make([][2]float64, 0, len(e.Nodes))
+	for _, id := range e.Nodes {
+		if pt, ok := nodes[id]; ok {
+			coords = append(coords, pt)
+		}
+	}
+	return coords
+}
+
+// latLonCoords returns the coordinates of a geometry. The null coordinates of the members
+// outside of the bbox of a query are skipped.
+func latLonCoords(geometry []*latLon) [][2]float64 {
+	coords := make([][2]float64, 0, len(geometry))
+	for _, ll := range geometry {
+		if ll != nil {
+			coords = append(coords, [2]float64{ll.Lon, ll.Lat})
+		}
+	}
+	return coords
+}
+
+// wayGeometry returns a polygon for the closed ways of areas and a line string otherwise.
+// nil is returned for ways with less than 2 coordinates.
+func wayGeometry(coords [][2]float64, tags map[string]string) geom.Geometry {
+	if len(coords) < 2 {
+		return nil
+	}
+	if len(coords) >= 4 && coords[0] == coords[len(coords)-1] && isArea(tags) {
+		return geom.Polygon{coords[:len(coords)-1]}
+	}
+	return geom.LineString(coords)
+}
+
+// isArea reports if the tags of a closed way make it an area
+func isArea(tags map[string]string) bool {
+	switch tags["area"] {
+	case "yes":
+		return true
+	case "no":
+		return false
+	}
+	if tags["natural"] == "coastline" {
+		return false
+	}
+	if tags["waterway"] == "riverbank" {
+		return true
+	}
+	for k := range tags {
+		if areaKeys[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// relationGeometry returns the polygons of multipolygon and boundary relations, assembled
+// from the outer and inner ways of their members, and the lines of the member ways of other
+// relations. nil is returned when the relation has no member ways with coordinates.
+func relationGeometry(e *element, ways map[int64][][2]float64) geom.Geometry {
+	var outers, inners, lines [][][2]float64
+	for _, m := range e.Members {
+		if m.Type != "way" {
+			continue
+		}
+
+		coords := ways[m.Ref]
+		if len(m.Geometry) > 0 {
+			coords = latLonCoords(m.Geometry)
+		}
+		if len(coords) < 2 {
+			continue
+		}
+
+		switch m.Role {
+		case "outer", "":
+			outers = append(outers, coords)
+		case "inner":
+			inners = append(inners, coords)
+		}
+		lines = append(lines, coords)
+	}
+
+	switch e.Tags["type"] {
+	case "multipolygon", "boundary":
+		return polygons(joinRings(outers), joinRings(inners))
+	}
+
+	switch len(lines) {
+	case 0:
+		return nil
+	case 1:
+		return geom.LineString(lines[0])
+	default:
+		mls := make(geom.MultiLineString, len(lines))
+		for i := range lines {
+			mls[i] = lines[i]
+		}
+		return mls
+	}
+}
+
+// joinRings joins the ways into closed rings, by their matching end points. The ways
+// which can't be closed are dropped. The returned rings aren't closed, as the rings of
+// geom polygons.
+func joinRings(ways [][][2]float64) [][][2]float64 {
+	used := make([]bool, len(ways))
+	var rings [][][2]float64
+
+	for i := range ways {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		ring := append([][2]float64{}, ways[i]...)
+
+		for ring[0] != ring[len(ring)-1] {
+			end := ring[len(ring)-1]
+			joined := false
+			for j := range ways {
+				if used[j] {
+					continue
+				}
+				w := ways[j]
+				switch end {
+				case w[0]:
+					ring = append(ring, w[1:]...)
+				case w[len(w)-1]:
+					for k := len(w) - 2; k >= 0; k-- {
+						ring = append(ring, w[k])
+					}
+				default:
+					continue
+				}
+				used[j], joined = true, true
+				break
+			}
+			if !joined {
+				break
+			}
+		}
+
+		if len(ring) >= 4 && ring[0] == ring[len(ring)-1] {
+			rings = append(rings, ring[:len(ring)-1])
+		}
+	}
+
+	return rings
+}
+
+// polygons assigns the inner rings to the outer rings containing them and returns a
+// polygon for a single outer ring and a multi polygon otherwise
+func polygons(outers, inners [][][2]float64) geom.Geometry {
+	if len(outers) == 0 {
+		return nil
+	}
+
+	polys := make([]geom.Polygon, len(outers))
+	for i := range outers {
+		polys[i] = geom.Polygon{outers[i]}
+	}
+	for _, inner := range inners {
+		for i := range outers {
+			if contains(outers[i], inner[0]) {
+				polys[i] = append(polys[i], inner)
+				break
+			}
+		}
+	}
+
+	if len(polys) == 1 {
+		return polys[0]
+	}
+	mp := make(geom.MultiPolygon, len(polys))
+	for i := range polys {
+		mp[i] = polys[i]
+	}
+	return mp
+}
+
+// contains reports if the point is inside the ring, by ray casting
+func contains(ring [][2]float64, pt [2]float64) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > pt[1]) != (b[1] > pt[1]) && pt[0] < (b[0]-a[0])*(pt[1]-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,91 @@
+package overpass
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrMissingLayerName = errors.New("overpass: layer is missing 'name'")
+)
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("overpass: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrMissingQuery struct {
+	LayerName string
+}
+
+func (e ErrMissingQuery) Error() string {
+	return fmt.Sprintf("overpass: layer (%v) is missing 'query'", e.LayerName)
+}
+
+type ErrMissingBBoxToken struct {
+	LayerName string
+}
+
+func (e ErrMissingBBoxToken) Error() string {
+	return fmt.Sprintf("overpass: layer (%v) query must contain the %v token", e.LayerName, bboxToken)
+}
+
+type ErrInvalidQueryZoom struct {
+	LayerName string
+	Zoom      int
+}
+
+func (e ErrInvalidQueryZoom) Error() string {
+	return fmt.Sprintf("overpass: layer (%v) has invalid query_zoom (%v)", e.LayerName, e.Zoom)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("overpass: layer (%v) not found", e.LayerName)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("overpass: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
+
+// ErrStatus is returned when the Overpass API responds with an unexpected status
+type ErrStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("overpass: request (%v) responded with status %v", e.URL, e.Status)
+}
+
+// ErrRateLimited is returned for the queries of the tiles requested while the Overpass
+// API asked the provider to back off
+type ErrRateLimited struct {
+	Until time.Time
+}
+
+func (e ErrRateLimited) Error() string {
+	return fmt.Sprintf("overpass: rate limited by the server until %v", e.Until.Format(time.RFC3339))
+}
+
+// ErrRemark is returned when the Overpass API reports a runtime error, i.e. a query
+// timeout, in the remark of a response
+type ErrRemark struct {
+	Remark string
+}
+
+func (e ErrRemark) Error() string {
+	return fmt.Sprintf("overpass: query failed: %v", e.Remark)
+}
@@ -0,0 +1,53 @@
+package overpass
+
+import (
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+)
+
+// tokens replaced in the layer query
+const (
+	// bboxToken is replaced with the south, west, north and east bounds of the queried
+	// tile, the bbox order of Overpass QL, as overpass turbo does
+	bboxToken = "{{bbox}}"
+	zoomToken = "{{z}}"
+)
+
+type Layer struct {
+	name string
+	// query is the Overpass QL template of the layer
+	query string
+	// queryZoom is the zoom of the tiles queried for the tiles above it, so the tiles
+	// covered by a queried tile are served from a single request
+	queryZoom uint
+	// geomType filters the features of the layer, nil when all features are kept
+	geomType geom.Geometry
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return tegola.WGS84 }
+
+// matchesGeomType reports if the geometry is of the layer's geometry type. Multi
+// geometries match the type of their parts.
+func (l Layer) matchesGeomType(g geom.Geometry) bool {
+	switch l.geomType.(type) {
+	case nil:
+		return true
+	case geom.Point:
+		_, ok := g.(geom.Point)
+		return ok
+	case geom.LineString:
+		switch g.(type) {
+		case geom.LineString, geom.MultiLineString:
+			return true
+		}
+	case geom.Polygon:
+		switch g.(type) {
+		case geom.Polygon, geom.MultiPolygon:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,420 @@
+// Package overpass provides a provider which queries the Overpass API, so layers derived
+// from OpenStreetMap can be prototyped without importing any data. Each layer runs an
+// Overpass QL template bounded by the bbox of the tile. The tiles above the layer's query
+// zoom are served from the query of their ancestor at that zoom, the features of the
+// queries are cached and the queries are rate limited, as the public Overpass instances
+// ask of their users.
+package overpass
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/ttlcache"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "overpass"
+
+const (
+	ConfigKeyURL               = "url"
+	ConfigKeyHeaders           = "headers"
+	ConfigKeyTimeout           = "timeout"
+	ConfigKeyMaxConcurrent     = "max_concurrent"
+	ConfigKeyRequestsPerMinute = "requests_per_minute"
+	ConfigKeyCacheSize         = "cache_size"
+	ConfigKeyCacheTTL          = "cache_ttl"
+	ConfigKeyLayers            = "layers"
+
+	ConfigKeyLayerName    = "name"
+	ConfigKeyQuery        = "query"
+	ConfigKeyQueryZoom    = "query_zoom"
+	ConfigKeyGeometryType = "geometry_type"
+)
+
+const (
+	DefaultURL               = "https://overpass-api.de/api/interpreter"
+	DefaultTimeout           = 60
+	DefaultMaxConcurrent     = 2
+	DefaultRequestsPerMinute = 30
+	DefaultCacheSize         = 1024
+	DefaultCacheTTL          = 86400
+	DefaultQueryZoom         = 12
+)
+
+// the back off applied when a rate limited response has no Retry-After header
+const defaultRetryAfter = time.Minute
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, nil)
+}
+
+// Provider queries the Overpass API
+type Provider struct {
+	url     string
+	headers map[string]string
+	timeout int
+	client  *http.Client
+
+	// the features of the most recently queried tiles until they expire. nil when disabled
+	cache   *ttlcache.Cache
+	limiter *limiter
+
+	// the queries in flight, shared by the tiles of the same query
+	mu       sync.Mutex
+	inflight map[queryKey]*call
+
+	// map of layer name and corresponding query
+	layers map[string]Layer
+}
+
+// NewTileProvider instantiates and returns a new overpass provider or an error.
+//
+//	url (string): [Optional] the interpreter url of the Overpass API. defaults to https://overpass-api.de/api/interpreter
+//	headers (map[string]string): [Optional] headers added to every request (i.e. a User-Agent)
+//	timeout (int): [Optional] the number of seconds allowed per query. defaults to 60
+//	max_concurrent (int): [Optional] the max number of queries sent at once. 0 is unlimited. defaults to 2
+//	requests_per_minute (int): [Optional] the max number of queries sent per minute. 0 is unlimited. defaults to 30
+//	cache_size (int): [Optional] the number of queries whose features are kept in memory. 0 disables the cache. defaults to 1024
+//	cache_ttl (int): [Optional] the number of seconds the features of a query are cached. defaults to 86400
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		query (string): [Required] the Overpass QL template. {{bbox}} and {{z}} are replaced
+//		query_zoom (int): [Optional] the tiles above the zoom are served from the query of their ancestor at the zoom. defaults to 12
+//		geometry_type (string): [Optional] keeps the point, linestring or polygon features only
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	ints := []struct {
+		key string
+		val int
+	}{
+		{ConfigKeyTimeout, DefaultTimeout},
+		{ConfigKeyMaxConcurrent, DefaultMaxConcurrent},
+		{ConfigKeyRequestsPerMinute, DefaultRequestsPerMinute},
+		{ConfigKeyCacheSize, DefaultCacheSize},
+		{ConfigKeyCacheTTL, DefaultCacheTTL},
+	}
+	var err error
+	for i := range ints {
+		if ints[i].val, err = config.Int(ints[i].key, &ints[i].val); err != nil {
+			return nil, err
+		}
+		if ints[i].val < 0 {
+			return nil, fmt.Errorf("overpass: %v must not be negative, got %v", ints[i].key, ints[i].val)
+		}
+	}
+	timeout := ints[0].val
+
+	u := DefaultURL
+	if u, err = config.String(ConfigKeyURL, &u); err != nil {
+		return nil, err
+	}
+
+	headers, err := dict.StringMap(config, ConfigKeyHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Provider{
+		url:      u,
+		headers:  headers,
+		timeout:  timeout,
+		client:   &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		limiter:  newLimiter(ints[1].val, ints[2].val),
+		inflight: map[queryKey]*call{},
+		layers:   map[string]Layer{},
+	}
+	if ints[3].val > 0 && ints[4].val > 0 {
+		p.cache = ttlcache.New(ints[3].val, time.Duration(ints[4].val)*time.Second)
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// AddLayer adds a query layer to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	empty := ""
+
+	query, err := layerConf.String(ConfigKeyQuery, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyQuery, err)
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return ErrMissingQuery{LayerName: name}
+	}
+	// a query which isn't bounded would download the matches of the whole planet
+	if !strings.Contains(query, bboxToken) {
+		return ErrMissingBBoxToken{LayerName: name}
+	}
+
+	queryZoom := DefaultQueryZoom
+	if queryZoom, err = layerConf.Int(ConfigKeyQueryZoom, &queryZoom); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyQueryZoom, err)
+	}
+	if queryZoom < 0 || queryZoom > tegola.MaxZ {
+		return ErrInvalidQueryZoom{LayerName: name, Zoom: queryZoom}
+	}
+
+	gtype, err := layerConf.String(ConfigKeyGeometryType, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyGeometryType, err)
+	}
+	geomType, ok := provider.GeometryType(gtype)
+	switch geomType.(type) {
+	case geom.MultiPoint, geom.MultiLineString, geom.MultiPolygon:
+		// multi geometries match the single geometry types of the layers
+		ok = false
+	}
+	if !ok {
+		return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+	}
+
+	p.layers[name] = Layer{
+		name:      name,
+		query:     p.settings(query),
+		queryZoom: uint(queryZoom),
+		geomType:  geomType,
+	}
+
+	return nil
+}
+
+// settings adds the json output format to the settings of the query, and the provider's
+// timeout when the query has no settings, as the features are decoded from the json output
+func (p *Provider) settings(query string) string {
+	switch {
+	case strings.Contains(query, "[out:json]"):
+		return query
+	case strings.HasPrefix(query, "["):
+		return "[out:json]" + query
+	default:
+		return fmt.Sprintf("[out:json][timeout:%v];\n%v", p.timeout, query)
+	}
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures sends the features of the layer's query intersecting the tile's buffered
+// extent to fn. The tiles above the layer's query zoom are filtered from the features of
+// the query of their ancestor at the query zoom.
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	z, x, y := tile.ZXY()
+	key := queryKey{layer: layer.name, z: z, x: x, y: y}
+	if z > layer.queryZoom {
+		d := z - layer.queryZoom
+		key = queryKey{layer: layer.name, z: layer.queryZoom, x: x >> d, y: y >> d}
+	}
+
+	items, err := p.items(ctx, layer, key)
+	if err != nil {
+		return err
+	}
+
+	ext, err := provider.QueryExtent(tile, tegola.WGS84)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+		if !provider.ExtentsOverlap(&items[i].extent, ext) || !layer.matchesGeomType(items[i].feature.Geometry) {
+			continue
+		}
+
+		// the features are shared by the tiles of the query
+		f := items[i].feature
+		f.Tags = provider.CopyTags(f.Tags)
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// items returns the features of the query, from the cache or from a query shared by the
+// tiles requested while it runs
+func (p *Provider) items(ctx context.Context, layer Layer, key queryKey) ([]item, error) {
+	if items, ok := p.cache.Get(key); ok {
+		return items.([]item), nil
+	}
+
+	p.mu.Lock()
+	c, ok := p.inflight[key]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		p.inflight[key] = c
+		p.mu.Unlock()
+
+		// the query isn't bound to the context of the first tile, so the tiles sharing
+		// it aren't canceled with it
+		go func() {
+			c.items, c.err = p.query(layer, key)
+			if c.err == nil {
+				p.cache.Set(key, c.items)
+			}
+
+			p.mu.Lock()
+			delete(p.inflight, key)
+			p.mu.Unlock()
+			close(c.done)
+		}()
+	} else {
+		p.mu.Unlock()
+	}
+
+	select {
+	case <-c.done:
+		return c.items, c.err
+	case <-ctx.Done():
+		return nil, provider.ErrCanceled
+	}
+}
+
+// query sends the layer's query for the tile of the key and decodes the response
+func (p *Provider) query(layer Layer, key queryKey) ([]item, error) {
+	// the limiter's wait counts towards the timeout
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if p.client.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.client.Timeout)
+	}
+	defer cancel()
+
+	if err := p.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	defer p.limiter.release()
+
+	ext, err := provider.QueryExtent(provider.NewTile(key.z, key.x, key.y, uint(tegola.DefaultTileBuffer), tegola.WebMercator), tegola.WGS84)
+	if err != nil {
+		return nil, err
+	}
+
+	q := replaceTokens(layer.query, ext, key.z)
+	if provider.SQLDebugFor(layer.name).ExecuteSQL {
+		log.Debugf("TEGOLA_SQL_DEBUG:EXECUTE_SQL for layer (%v): %v", layer.name, q)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.url, strings.NewReader(url.Values{"data": {q}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		until := time.Now().Add(retryAfter(resp.Header.Get("Retry-After")))
+		log.Warnf("overpass: layer (%v) query rate limited by (%v), backing off until %v", layer.name, p.url, until.Format(time.RFC3339))
+		p.limiter.backoff(until)
+		return nil, ErrStatus{URL: p.url, Status: resp.StatusCode}
+	default:
+		return nil, ErrStatus{URL: p.url, Status: resp.StatusCode}
+	}
+
+	items, err := decode(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("overpass: layer (%v) decoding (%v): %w", layer.name, p.url, err)
+	}
+	return items, nil
+}
+
+// retryAfter parses the seconds of a Retry-After header
+func retryAfter(v string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || secs <= 0 {
+		return defaultRetryAfter
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// replaceTokens fills the query with the south, west, north and east bounds of the extent
+// and the zoom
+func replaceTokens(query string, ext *geom.Extent, z uint) string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	r := strings.NewReplacer(
+		bboxToken, f(ext.MinY())+","+f(ext.MinX())+","+f(ext.MaxY())+","+f(ext.MaxX()),
+		zoomToken, strconv.FormatUint(uint64(z), 10),
+	)
+	return r.Replace(query)
+}
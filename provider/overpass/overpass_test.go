@@ -0,0 +1,306 @@
+package overpass_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/overpass"
+)
+
+// server responds to the queries with the file, or with the status when it's set, and
+// records the queries
+type server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	queries []string
+}
+
+func newServer(t *testing.T, file string, status int) *server {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.queries = append(s.queries, r.PostFormValue("data"))
+		s.mu.Unlock()
+
+		if status != 0 {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	return &s
+}
+
+func (s *server) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.queries...)
+}
+
+func tileFeatures(p provider.Tiler, layer string, tile provider.Tile) ([]provider.Feature, error) {
+	var features []provider.Feature
+	err := p.TileFeatures(context.Background(), layer, tile, func(f *provider.Feature) error {
+		features = append(features, *f)
+		return nil
+	})
+	return features, err
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		layer    map[string]interface{}
+		query    string
+		expected []uint64
+	}
+
+	s := newServer(t, "testdata/mission.json", 0)
+	defer s.Close()
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer["name"] = "test"
+			p, err := overpass.NewTileProvider(dict.Dict{
+				"url":    s.URL,
+				"layers": []map[string]interface{}{tc.layer},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the north west quarter of the world, without Paris
+			features, err := tileFeatures(p, "test", provider.NewTile(1, 0, 0, 0, tegola.WebMercator))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var ids []uint64
+			for _, f := range features {
+				ids = append(ids, f.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("feature ids, expected %v got %v", tc.expected, ids)
+			}
+
+			queries := s.requests()
+			if len(queries) == 0 || queries[len(queries)-1] != tc.query {
+				t.Errorf("query, expected %q got %q", tc.query, queries)
+			}
+		}
+	}
+
+	// the south, west, north and east bounds of the tile 1/0/0, with the default buffer
+	const bbox = "-2.8113711929400975,-180,85.0511287798066,2.812499999608497"
+
+	tests := map[string]tcase{
+		"default settings": {
+			layer:    map[string]interface{}{"query": `nwr["amenity"]({{bbox}}); out geom;`},
+			query:    "[out:json][timeout:60];\nnwr[\"amenity\"](" + bbox + "); out geom;",
+			expected: []uint64{11, 102, 112, 203, 303},
+		},
+		"query zoom": {
+			layer:    map[string]interface{}{"query": `[timeout:10];way({{bbox}}); out geom; // {{z}}`, "query_zoom": 1},
+			query:    "[out:json][timeout:10];way(" + bbox + "); out geom; // 1",
+			expected: []uint64{11, 102, 112, 203, 303},
+		},
+		"json settings": {
+			layer:    map[string]interface{}{"query": `[out:json];node({{bbox}});out;`, "geometry_type": "polygon", "query_zoom": 1},
+			query:    "[out:json];node(" + bbox + ");out;",
+			expected: []uint64{102, 203},
+		},
+		"points": {
+			layer:    map[string]interface{}{"query": `[out:json];nwr({{bbox}});out center;`, "geometry_type": "point", "query_zoom": 1},
+			query:    "[out:json];nwr(" + bbox + ");out center;",
+			expected: []uint64{11, 303},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestGeometries(t *testing.T) {
+	s := newServer(t, "testdata/mission.json", 0)
+	defer s.Close()
+
+	p, err := overpass.NewTileProvider(dict.Dict{
+		"url":    s.URL,
+		"layers": []map[string]interface{}{{"name": "test", "query": "nwr({{bbox}});out geom;", "query_zoom": 1}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	features, err := tileFeatures(p, "test", provider.NewTile(1, 0, 0, 0, tegola.WebMercator))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	feature := func(id uint64, g geom.Geometry, tags map[string]interface{}) provider.Feature {
+		return provider.Feature{ID: id, Geometry: g, SRID: tegola.WGS84, Tags: tags}
+	}
+	expected := []provider.Feature{
+		feature(11, geom.Point{-122.41, 37.77}, map[string]interface{}{"amenity": "cafe", "name": "Blue", "osm_id": int64(1), "osm_type": "node"}),
+		feature(102, geom.Polygon{{{-122.43, 37.76}, {-122.42, 37.76}, {-122.42, 37.77}, {-122.43, 37.77}}}, map[string]interface{}{"building": "yes", "osm_id": int64(10), "osm_type": "way"}),
+		feature(112, geom.LineString{{-122.4, 37.78}, {-122.39, 37.78}}, map[string]interface{}{"highway": "residential", "osm_id": int64(11), "osm_type": "way"}),
+		feature(203, geom.Polygon{
+			{{-122.5, 37.7}, {-122.45, 37.7}, {-122.45, 37.75}, {-122.5, 37.75}},
+			{{-122.48, 37.72}, {-122.47, 37.72}, {-122.47, 37.73}},
+		}, map[string]interface{}{"type": "multipolygon", "landuse": "grass", "osm_id": int64(20), "osm_type": "relation"}),
+		feature(303, geom.Point{-122.42, 37.76}, map[string]interface{}{"type": "route", "route": "bus", "osm_id": int64(30), "osm_type": "relation"}),
+	}
+	if !reflect.DeepEqual(features, expected) {
+		t.Errorf("features, expected %+v got %+v", expected, features)
+	}
+}
+
+func TestCache(t *testing.T) {
+	s := newServer(t, "testdata/mission.json", 0)
+	defer s.Close()
+
+	p, err := overpass.NewTileProvider(dict.Dict{
+		"url":    s.URL,
+		"layers": []map[string]interface{}{{"name": "test", "query": "nwr({{bbox}});out geom;", "query_zoom": 0}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the tiles of the zoom 0 query, requested at once
+	tiles := []provider.Tile{
+		provider.NewTile(1, 0, 0, 0, tegola.WebMercator),
+		provider.NewTile(1, 1, 0, 0, tegola.WebMercator),
+		provider.NewTile(2, 0, 1, 0, tegola.WebMercator),
+	}
+	counts := make([]int, len(tiles))
+	var wg sync.WaitGroup
+	for i := range tiles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			features, err := tileFeatures(p, "test", tiles[i])
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			counts[i] = len(features)
+
+			// the tags of the cached features aren't shared
+			for _, f := range features {
+				f.Tags["osm_type"] = "changed"
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if !reflect.DeepEqual(counts, []int{5, 1, 5}) {
+		t.Errorf("feature counts, expected [5 1 5] got %v", counts)
+	}
+
+	features, err := tileFeatures(p, "test", tiles[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(features) != 1 || features[0].Tags["osm_type"] != "node" {
+		t.Errorf("cached features, expected node 6 got %+v", features)
+	}
+
+	if n := len(s.requests()); n != 1 {
+		t.Errorf("requests, expected 1 got %v", n)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	s := newServer(t, "testdata/mission.json", http.StatusTooManyRequests)
+	defer s.Close()
+
+	p, err := overpass.NewTileProvider(dict.Dict{
+		"url":    s.URL,
+		"layers": []map[string]interface{}{{"name": "test", "query": "nwr({{bbox}});out geom;"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = tileFeatures(p, "test", provider.NewTile(1, 0, 0, 0, tegola.WebMercator))
+	expected := overpass.ErrStatus{URL: s.URL, Status: http.StatusTooManyRequests}
+	if err != expected {
+		t.Errorf("error, expected %v got %v", expected, err)
+	}
+
+	// the provider backs off without querying the server
+	_, err = tileFeatures(p, "test", provider.NewTile(1, 1, 0, 0, tegola.WebMercator))
+	var rerr overpass.ErrRateLimited
+	if !errors.As(err, &rerr) {
+		t.Errorf("error, expected ErrRateLimited got %v", err)
+	}
+
+	if n := len(s.requests()); n != 1 {
+		t.Errorf("requests, expected 1 got %v", n)
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		config dict.Dict
+		err    string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := overpass.NewTileProvider(tc.config)
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	layer := func(l map[string]interface{}) dict.Dict {
+		l["name"] = "test"
+		return dict.Dict{"layers": []map[string]interface{}{l}}
+	}
+
+	tests := map[string]tcase{
+		"missing query": {
+			config: layer(map[string]interface{}{}),
+			err:    "overpass: layer (test) is missing 'query'",
+		},
+		"missing bbox": {
+			config: layer(map[string]interface{}{"query": "node[amenity=cafe];out;"}),
+			err:    "overpass: layer (test) query must contain the {{bbox}} token",
+		},
+		"invalid query zoom": {
+			config: layer(map[string]interface{}{"query": "node({{bbox}});out;", "query_zoom": 23}),
+			err:    "overpass: layer (test) has invalid query_zoom (23)",
+		},
+		"invalid geometry type": {
+			config: layer(map[string]interface{}{"query": "node({{bbox}});out;", "geometry_type": "multipoint"}),
+			err:    "overpass: layer (test) has invalid geometry_type (multipoint)",
+		},
+		"negative rate": {
+			config: dict.Dict{"requests_per_minute": -1},
+			err:    "overpass: requests_per_minute must not be negative, got -1",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,11 @@
+package provider
+
+import "context"
+
+// Pinger is implemented by providers which can check the connection to their data source.
+// It's used by the readiness endpoint, so instances which can't reach their data are taken
+// out of service.
+type Pinger interface {
+	// Ping returns an error when the data source of the provider can't be reached
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+
+	"github.com/go-spatial/tegola/dict"
+)
+
+// The symbols looked up in provider plugins
+const (
+	// PluginSymbolName is a string variable holding the name the plugin's providers register under
+	PluginSymbolName = "Name"
+	// PluginSymbolInit is a function with the signature of an InitFunc
+	PluginSymbolInit = "NewTileProvider"
+	// PluginSymbolMVTInit is a function with the signature of an MVTInitFunc
+	PluginSymbolMVTInit = "NewMVTTileProvider"
+	// PluginSymbolCleanup is a function with the signature of a CleanupFunc
+	PluginSymbolCleanup = "Cleanup"
+)
+
+// symbolLooker is satisfied by *plugin.Plugin
+type symbolLooker interface {
+	Lookup(symName string) (plugin.Symbol, error)
+}
+
+// LoadPlugins opens the Go plugins (files with the .so extension) in dir and registers the
+// providers they export. A plugin exports a Name string variable and a NewTileProvider and / or
+// NewMVTTileProvider function, with the signatures of InitFunc and MVTInitFunc. The MVT provider
+// is registered under the name with the "mvt_" prefix. An optional Cleanup function is called
+// during shutdown. Plugins without a Name may instead register their providers from their
+// init functions.
+//
+// Plugins must be built with the same Go version and tegola module versions as the tegola binary.
+// The names of the registered providers are returned.
+func LoadPlugins(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, path := range paths {
+		p, err := plugin.Open(path)
+		if err != nil {
+			return names, ErrPlugin{Path: path, Err: err}
+		}
+
+		registered, err := registerPlugin(p)
+		if err != nil {
+			return names, ErrPlugin{Path: path, Err: err}
+		}
+		names = append(names, registered...)
+	}
+
+	return names, nil
+}
+
+// registerPlugin registers the providers exported by the plugin
+func registerPlugin(p symbolLooker) ([]string, error) {
+	sym, err := p.Lookup(PluginSymbolName)
+	if err != nil {
+		// the plugin registers its providers from its init functions
+		return nil, nil
+	}
+	namePtr, ok := sym.(*string)
+	if !ok {
+		return nil, fmt.Errorf("%v must be a string variable, got %T", PluginSymbolName, sym)
+	}
+	name := *namePtr
+	if name == "" {
+		return nil, fmt.Errorf("%v must not be empty", PluginSymbolName)
+	}
+
+	var cleanup CleanupFunc
+	if sym, err := p.Lookup(PluginSymbolCleanup); err == nil {
+		fn, ok := sym.(func())
+		if !ok {
+			return nil, fmt.Errorf("%v must be a func(), got %T", PluginSymbolCleanup, sym)
+		}
+		cleanup = fn
+	}
+
+	var names []string
+	if sym, err := p.Lookup(PluginSymbolInit); err == nil {
+		fn, ok := sym.(func(dict.Dicter) (Tiler, error))
+		if !ok {
+			return nil, fmt.Errorf("%v must be a provider.InitFunc, got %T", PluginSymbolInit, sym)
+		}
+		stdName := TypeStd.Prefix() + name
+		if err := Register(stdName, fn, cleanup); err != nil {
+			return nil, err
+		}
+		names = append(names, stdName)
+		// the cleanup function is only called once
+		cleanup = nil
+	}
+
+	if sym, err := p.Lookup(PluginSymbolMVTInit); err == nil {
+		fn, ok := sym.(func(dict.Dicter) (MVTTiler, error))
+		if !ok {
+			return names, fmt.Errorf("%v must be a provider.MVTInitFunc, got %T", PluginSymbolMVTInit, sym)
+		}
+		mvtName := TypeMvt.Prefix() + name
+		if err := MVTRegister(mvtName, fn, cleanup); err != nil {
+			return names, err
+		}
+		names = append(names, mvtName)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("exports neither %v nor %v", PluginSymbolInit, PluginSymbolMVTInit)
+	}
+
+	return names, nil
+}
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"fmt"
+	"plugin"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/dict"
+)
+
+// fakePlugin implements symbolLooker with a map of symbols
+type fakePlugin map[string]plugin.Symbol
+
+func (fp fakePlugin) Lookup(symName string) (plugin.Symbol, error) {
+	sym, ok := fp[symName]
+	if !ok {
+		return nil, fmt.Errorf("symbol %v not found", symName)
+	}
+	return sym, nil
+}
+
+func TestRegisterPlugin(t *testing.T) {
+	type tcase struct {
+		plugin   fakePlugin
+		expected []string
+		err      string
+	}
+
+	name := func(s string) *string { return &s }
+	initFn := func(dict.Dicter) (Tiler, error) { return nil, nil }
+	mvtInitFn := func(dict.Dicter) (MVTTiler, error) { return nil, nil }
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			got, err := registerPlugin(tc.plugin)
+			defer func() {
+				for _, n := range got {
+					delete(providers, n)
+				}
+			}()
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("names, expected %v got %v", tc.expected, got)
+			}
+			for _, n := range got {
+				if _, ok := providers[n]; !ok {
+					t.Errorf("provider %v not registered", n)
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"std and mvt": {
+			plugin: fakePlugin{
+				PluginSymbolName:    name("plugintest"),
+				PluginSymbolInit:    initFn,
+				PluginSymbolMVTInit: mvtInitFn,
+				PluginSymbolCleanup: func() {},
+			},
+			expected: []string{"plugintest", "mvt_plugintest"},
+		},
+		"mvt only": {
+			plugin: fakePlugin{
+				PluginSymbolName:    name("plugintest"),
+				PluginSymbolMVTInit: mvtInitFn,
+			},
+			expected: []string{"mvt_plugintest"},
+		},
+		"registers from init": {
+			plugin: fakePlugin{},
+		},
+		"name not a string": {
+			plugin: fakePlugin{PluginSymbolName: 1},
+			err:    "Name must be a string variable",
+		},
+		"empty name": {
+			plugin: fakePlugin{PluginSymbolName: name("")},
+			err:    "Name must not be empty",
+		},
+		"no init functions": {
+			plugin: fakePlugin{PluginSymbolName: name("plugintest")},
+			err:    "exports neither",
+		},
+		"invalid init function": {
+			plugin: fakePlugin{
+				PluginSymbolName: name("plugintest"),
+				PluginSymbolInit: func() {},
+			},
+			err: "NewTileProvider must be a provider.InitFunc",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,217 @@
+package provider
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+)
+
+// PolledItem is a feature of a polled source with the extent of its geometry
+type PolledItem struct {
+	Feature Feature
+	Extent  geom.Extent
+}
+
+// PolledSource is a local file or url of WGS84 features, i.e. a GPX file or a GeoRSS feed,
+// which is checked for changes on an interval. When a later read fails the source keeps its
+// features. It's safe for concurrent use.
+type PolledSource struct {
+	// Path is the local file of the source, URL the remote one. Only one is set
+	Path, URL string
+	// Accept is the Accept header of the url requests
+	Accept string
+	// Interval is the time between checking the source for changes. 0 reads it once
+	Interval time.Duration
+	// Decode returns the items of the source's contents
+	Decode func(body []byte) ([]PolledItem, error)
+
+	mu    sync.RWMutex
+	items []PolledItem
+	// updated is the modification time of the source
+	updated time.Time
+
+	// version of the last successful read, only used by Refresh
+	version sourceVersion
+}
+
+// sourceVersion identifies a read of a source, to detect if it changed since
+type sourceVersion struct {
+	modTime      time.Time
+	etag         string
+	lastModified string
+}
+
+// Items returns the items of the last successful read and the modification time of the
+// source. The zero time is returned until a url has been fetched.
+func (s *PolledSource) Items() ([]PolledItem, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.items, s.updated
+}
+
+// Polled reports if the source needs a poller. Local files without an interval are only
+// read when they're added.
+func (s *PolledSource) Polled() bool {
+	return s.URL != "" || s.Interval > 0
+}
+
+// Poll refreshes the source every interval until the context is canceled. Sources without an
+// interval are refreshed once. Local files are expected to have been refreshed already.
+func (s *PolledSource) Poll(ctx context.Context, client *http.Client, onError func(err error)) {
+	var tick <-chan time.Time
+	if s.Interval > 0 {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	refresh := s.Path == ""
+	for {
+		if refresh {
+			if err := s.Refresh(ctx, client); err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+		refresh = true
+
+		if tick == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		}
+	}
+}
+
+// Refresh reads the source and replaces its items when it changed
+func (s *PolledSource) Refresh(ctx context.Context, client *http.Client) error {
+	body, v, err := s.read(ctx, client)
+	if err != nil || body == nil {
+		return err
+	}
+
+	items, err := s.Decode(body)
+	if err != nil {
+		return err
+	}
+
+	s.version = v
+	s.mu.Lock()
+	s.items, s.updated = items, v.modTime
+	s.mu.Unlock()
+
+	return nil
+}
+
+// TileFeatures streams the items intersecting the tile's buffered extent
+func (s *PolledSource) TileFeatures(ctx context.Context, tile Tile, fn func(f *Feature) error) error {
+	ext, err := QueryExtent(tile, tegola.WGS84)
+	if err != nil {
+		return err
+	}
+
+	items, _ := s.Items()
+	for i := range items {
+		if ctx.Err() != nil {
+			return ErrCanceled
+		}
+		if !ExtentsOverlap(&items[i].Extent, ext) {
+			continue
+		}
+
+		f := items[i].Feature
+		f.Tags = CopyTags(f.Tags)
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// read returns the contents and version of the source. nil contents are returned when the
+// source is unchanged since the last successful read, which is detected with the modification
+// time of local files and the ETag or Last-Modified headers of urls.
+func (s *PolledSource) read(ctx context.Context, client *http.Client) ([]byte, sourceVersion, error) {
+	if s.Path != "" {
+		return s.readFile()
+	}
+	return s.fetch(ctx, client)
+}
+
+func (s *PolledSource) readFile() ([]byte, sourceVersion, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, sourceVersion{}, err
+	}
+	if info.ModTime().Equal(s.version.modTime) {
+		return nil, sourceVersion{}, nil
+	}
+
+	body, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, sourceVersion{}, err
+	}
+
+	return body, sourceVersion{modTime: info.ModTime()}, nil
+}
+
+func (s *PolledSource) fetch(ctx context.Context, client *http.Client) ([]byte, sourceVersion, error) {
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, sourceVersion{}, err
+	}
+	req = req.WithContext(ctx)
+	if s.Accept != "" {
+		req.Header.Set("Accept", s.Accept)
+	}
+	if v := s.version; v.etag != "" {
+		req.Header.Set("If-None-Match", v.etag)
+	} else if v.lastModified != "" {
+		req.Header.Set("If-Modified-Since", v.lastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, sourceVersion{}, ErrCanceled
+		}
+		return nil, sourceVersion{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, sourceVersion{}, nil
+	default:
+		return nil, sourceVersion{}, ErrSourceStatus{URL: s.URL, Status: resp.StatusCode}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, sourceVersion{}, err
+	}
+
+	v := sourceVersion{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	// urls without a Last-Modified header were modified when they were fetched
+	v.modTime = time.Now()
+	if t, err := http.ParseTime(v.lastModified); err == nil {
+		v.modTime = t
+	}
+
+	return body, v, nil
+}
@@ -0,0 +1,130 @@
+package provider_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+// decodeNames returns an item per line of the body, named by the line
+func decodeNames(body []byte) ([]provider.PolledItem, error) {
+	var items []provider.PolledItem
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("<broken")) {
+			return nil, fmt.Errorf("broken line")
+		}
+		items = append(items, provider.PolledItem{
+			Feature: provider.Feature{
+				Geometry: geom.Point{2, 1},
+				Tags:     map[string]interface{}{"name": string(line)},
+			},
+			Extent: geom.Extent{2, 1, 2, 1},
+		})
+	}
+	return items, nil
+}
+
+func TestPolledSourceFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tegola-polled")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "names.txt")
+
+	write := func(name string, modTime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	name := func(s *provider.PolledSource) interface{} {
+		items, _ := s.Items()
+		if len(items) != 1 {
+			t.Fatalf("items, expected 1 got %v", len(items))
+		}
+		return items[0].Feature.Tags["name"]
+	}
+
+	s := provider.PolledSource{Path: path, Decode: decodeNames}
+
+	first := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	write("first", first)
+	if err := s.Refresh(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := name(&s); got != "first" {
+		t.Errorf("name, expected first got %v", got)
+	}
+
+	// an invalid file keeps the last items and is read again
+	write("<broken", first.Add(time.Minute))
+	if err := s.Refresh(context.Background(), nil); err == nil {
+		t.Errorf("expected an error decoding the file")
+	}
+	if got := name(&s); got != "first" {
+		t.Errorf("name, expected first got %v", got)
+	}
+	write("second", first.Add(time.Minute))
+	if err := s.Refresh(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := name(&s); got != "second" {
+		t.Errorf("name, expected second got %v", got)
+	}
+	if _, updated := s.Items(); !updated.Equal(first.Add(time.Minute)) {
+		t.Errorf("updated, expected %v got %v", first.Add(time.Minute), updated)
+	}
+}
+
+func TestPolledSourceURL(t *testing.T) {
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("first"))
+	}))
+	defer srv.Close()
+
+	s := provider.PolledSource{URL: srv.URL, Decode: decodeNames}
+	for i := 0; i < 2; i++ {
+		if err := s.Refresh(context.Background(), srv.Client()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if items, _ := s.Items(); len(items) != 1 {
+			t.Errorf("refresh %v items, expected 1 got %v", i, len(items))
+		}
+	}
+	// the second request is answered as not modified
+	if requests != 2 || notModified != 1 {
+		t.Errorf("expected 2 requests, 1 not modified, got %v requests, %v not modified", requests, notModified)
+	}
+
+	missing := provider.PolledSource{URL: srv.URL + "/missing", Decode: decodeNames}
+	expected := provider.ErrSourceStatus{URL: srv.URL + "/missing", Status: http.StatusNotFound}
+	if err := missing.Refresh(context.Background(), srv.Client()); err != expected {
+		t.Errorf("missing, expected %v got %v", expected, err)
+	}
+}
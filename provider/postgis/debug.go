@@ -3,6 +3,8 @@ package postgis
 import (
 	"os"
 	"strings"
+
+	"github.com/go-spatial/tegola/provider"
 )
 
 // debug determines weather extra debugging output is enabled.
@@ -25,3 +27,15 @@ func init() {
 	debugLayerSQL = strings.Contains(os.Getenv(EnvSQLDebugName), EnvSQLDebugLayer)
 	debugExecuteSQL = strings.Contains(os.Getenv(EnvSQLDebugName), EnvSQLDebugExecute)
 }
+
+// isLayerSQLDebug reports if the layer SQL should be logged for the layer, either
+// via the environment or a runtime setting (i.e. the admin API)
+func isLayerSQLDebug(lyrID string) bool {
+	return debugLayerSQL || provider.SQLDebugFor(lyrID).LayerSQL
+}
+
+// isExecuteSQLDebug reports if executed SQL should be logged for the layer, either
+// via the environment or a runtime setting (i.e. the admin API)
+func isExecuteSQLDebug(lyrID string) bool {
+	return debugExecuteSQL || provider.SQLDebugFor(lyrID).ExecuteSQL
+}
@@ -0,0 +1,72 @@
+package postgis
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/go-spatial/geom"
+)
+
+// SQL dialects supported by the provider
+const (
+	// DialectPostGIS is a Postgres database with the PostGIS extension installed
+	DialectPostGIS = "postgis"
+	// DialectCockroachDB is a CockroachDB cluster. CockroachDB speaks the Postgres wire protocol
+	// and implements most of the PostGIS functions used to fetch features, but is missing
+	// ST_AsMVT, ST_AsMVTGeom and the box2d output of ST_Extent.
+	DialectCockroachDB = "cockroachdb"
+)
+
+const DefaultDialect = DialectPostGIS
+
+// allZoomsPostGIS and allZoomsCockroachDB are used to replace the !ZOOM! token during
+// layer inspection so no features are filtered out by zoom
+const (
+	allZoomsPostGIS     = "ANY('{0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24}')"
+	allZoomsCockroachDB = "ANY(ARRAY[0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24])"
+)
+
+// validateDialect returns the normalized dialect or an error if it's not supported
+func validateDialect(d string) (string, error) {
+	switch d = strings.ToLower(strings.TrimSpace(d)); d {
+	case DialectPostGIS, DialectCockroachDB:
+		return d, nil
+	default:
+		return "", ErrInvalidDialect(d)
+	}
+}
+
+// isCockroachDB reports if the provider is configured for CockroachDB
+func (p Provider) isCockroachDB() bool { return p.dialect == DialectCockroachDB }
+
+// allZooms returns the expression which matches every zoom for the provider's dialect
+func (p Provider) allZooms() string {
+	if p.isCockroachDB() {
+		return allZoomsCockroachDB
+	}
+	return allZoomsPostGIS
+}
+
+// extentSelect returns the select clause used to inspect the extent of a layer's geometry field
+func (p Provider) extentSelect(geomField string) string {
+	if p.isCockroachDB() {
+		// CockroachDB's ST_Extent does not return a box2d so the bounds are aggregated directly
+		return fmt.Sprintf("SELECT min(ST_XMin(%[1]s)), min(ST_YMin(%[1]s)), max(ST_XMax(%[1]s)), max(ST_YMax(%[1]s)) FROM", geomField)
+	}
+	return fmt.Sprintf("SELECT ST_Extent(%s) FROM", geomField)
+}
+
+// scanCockroachDBExtent reads the extent returned by the CockroachDB extent select clause
+func (p Provider) scanCockroachDBExtent(query string) (geom.Extent, error) {
+	var minx, miny, maxx, maxy sql.NullFloat64
+
+	if err := p.pool.QueryRow(query).Scan(&minx, &miny, &maxx, &maxy); err != nil {
+		return geom.Extent{}, err
+	}
+	if !minx.Valid || !miny.Valid || !maxx.Valid || !maxy.Valid {
+		return geom.Extent{}, fmt.Errorf("no geometries returned")
+	}
+
+	return geom.Extent{minx.Float64, miny.Float64, maxx.Float64, maxy.Float64}, nil
+}
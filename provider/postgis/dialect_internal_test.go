@@ -0,0 +1,49 @@
+package postgis
+
+import "testing"
+
+func TestValidateDialect(t *testing.T) {
+	type tcase struct {
+		dialect  string
+		expected string
+		err      error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			d, err := validateDialect(tc.dialect)
+			if err != tc.err {
+				t.Errorf("expected err %v got %v", tc.err, err)
+				return
+			}
+			if d != tc.expected {
+				t.Errorf("expected %v got %v", tc.expected, d)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"postgis":     {dialect: "postgis", expected: DialectPostGIS},
+		"cockroachdb": {dialect: " CockroachDB ", expected: DialectCockroachDB},
+		"invalid":     {dialect: "mysql", err: ErrInvalidDialect("mysql")},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestDialectSQL(t *testing.T) {
+	pg := Provider{dialect: DialectPostGIS}
+	crdb := Provider{dialect: DialectCockroachDB}
+
+	if got, expected := pg.extentSelect("geom"), "SELECT ST_Extent(geom) FROM"; got != expected {
+		t.Errorf("postgis extent: expected %v got %v", expected, got)
+	}
+	if got, expected := crdb.extentSelect("geom"), "SELECT min(ST_XMin(geom)), min(ST_YMin(geom)), max(ST_XMax(geom)), max(ST_YMax(geom)) FROM"; got != expected {
+		t.Errorf("cockroachdb extent: expected %v got %v", expected, got)
+	}
+	if got := crdb.allZooms(); got != allZoomsCockroachDB {
+		t.Errorf("cockroachdb zooms: expected %v got %v", allZoomsCockroachDB, got)
+	}
+}
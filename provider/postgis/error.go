@@ -3,6 +3,8 @@ package postgis
 import (
 	"errors"
 	"fmt"
+
+	"github.com/go-spatial/tegola/provider"
 )
 
 var (
@@ -17,12 +19,46 @@ func (e ErrLayerNotFound) Error() string {
 	return fmt.Sprintf("postgis: layer (%v) not found ", e.LayerName)
 }
 
+// ErrUpdatedSQLNotConfigured is returned when the freshness of a layer without an updated_sql is requested
+type ErrUpdatedSQLNotConfigured struct {
+	LayerName string
+}
+
+func (e ErrUpdatedSQLNotConfigured) Error() string {
+	return fmt.Sprintf("postgis: layer (%v) has no %v to report its freshness", e.LayerName, ConfigKeyUpdatedSQL)
+}
+
+// ErrChangedSQLNotConfigured is returned when the changes of a layer without a changed_sql or changed_field are requested
+type ErrChangedSQLNotConfigured struct {
+	LayerName string
+}
+
+func (e ErrChangedSQLNotConfigured) Error() string {
+	return fmt.Sprintf("postgis: layer (%v) has no %v or %v to report its changes", e.LayerName, ConfigKeyChangedSQL, ConfigKeyChangedField)
+}
+
+// Is reports the layer doesn't support reporting its changes
+func (e ErrChangedSQLNotConfigured) Is(target error) bool { return target == provider.ErrUnsupported }
+
 type ErrInvalidSSLMode string
 
 func (e ErrInvalidSSLMode) Error() string {
 	return fmt.Sprintf("postgis: invalid ssl mode (%v)", string(e))
 }
 
+type ErrInvalidDialect string
+
+func (e ErrInvalidDialect) Error() string {
+	return fmt.Sprintf("postgis: invalid dialect (%v), expected %v or %v", string(e), DialectPostGIS, DialectCockroachDB)
+}
+
+// ErrMVTUnsupported is returned when MVT tiles are requested from a dialect without ST_AsMVT
+type ErrMVTUnsupported string
+
+func (e ErrMVTUnsupported) Error() string {
+	return fmt.Sprintf("postgis: dialect (%v) does not support ST_AsMVT, use the %v provider type instead of %v", string(e), Name, provider.TypeMvt.Prefix()+Name)
+}
+
 type ErrUnclosedToken string
 
 func (e ErrUnclosedToken) Error() string {
@@ -0,0 +1,200 @@
+package postgis
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const (
+	ConfigKeyH3              = "h3"
+	ConfigKeyH3CellSize      = "h3_cell_size"
+	ConfigKeyH3MinResolution = "h3_min_resolution"
+	ConfigKeyH3MaxResolution = "h3_max_resolution"
+	ConfigKeyH3Statistics    = "h3_statistics"
+)
+
+const (
+	// DefaultH3CellSize is the width of the hexagons in pixels of a 256x256 tile
+	DefaultH3CellSize      = 16
+	DefaultH3MinResolution = 0
+	DefaultH3MaxResolution = 15
+)
+
+const (
+	h3ResolutionToken = "!H3_RESOLUTION!"
+	h3MarginToken     = "!H3_MARGIN!"
+
+	// h3IDField and h3IndexField are the feature id and the hexagon index tag of aggregated layers
+	h3IDField    = "h3_id"
+	h3IndexField = "h3_index"
+	h3CountField = "count"
+
+	// h3EdgeLength0 is the average hexagon edge length of resolution 0 in meters
+	h3EdgeLength0 = 1107712.591
+	// earthCircumference is the circumference of the WebMercator world in meters
+	earthCircumference = 2 * math.Pi * 6378137
+)
+
+// h3Statistics are the aggregate functions supported by h3_statistics
+var h3Statistics = map[string]bool{
+	"sum": true,
+	"avg": true,
+	"min": true,
+	"max": true,
+}
+
+// h3Statistic is an aggregate of a field over the points of a hexagon
+type h3Statistic struct {
+	fn    string
+	field string
+}
+
+// tag is the name of the tag the statistic is encoded as, i.e. sum_population
+func (s h3Statistic) tag() string { return s.fn + "_" + s.field }
+
+// h3Aggregation configures a layer which aggregates points into H3 hexagons
+type h3Aggregation struct {
+	// cellSize is the width of the hexagons in pixels of a 256x256 tile, used to derive the resolution from the zoom
+	cellSize int
+	minRes   int
+	maxRes   int
+	stats    []h3Statistic
+}
+
+// h3AggregationFromConfig reads the h3 aggregation of the layer config. nil is returned when the layer is not aggregated.
+func h3AggregationFromConfig(lid string, layer dict.Dicter) (*h3Aggregation, error) {
+	enabled, err := layer.Bool(ConfigKeyH3, nil)
+	if err != nil {
+		return nil, fmt.Errorf("for layer (%v) %v has an error: %v", lid, ConfigKeyH3, err)
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	agg := h3Aggregation{
+		cellSize: DefaultH3CellSize,
+		minRes:   DefaultH3MinResolution,
+		maxRes:   DefaultH3MaxResolution,
+	}
+
+	if agg.cellSize, err = layer.Int(ConfigKeyH3CellSize, &agg.cellSize); err != nil {
+		return nil, fmt.Errorf("for layer (%v) %v has an error: %v", lid, ConfigKeyH3CellSize, err)
+	}
+	if agg.cellSize < 1 {
+		return nil, fmt.Errorf("for layer (%v) %v has an error: must be greater than 0, got %v", lid, ConfigKeyH3CellSize, agg.cellSize)
+	}
+	if agg.minRes, err = layer.Int(ConfigKeyH3MinResolution, &agg.minRes); err != nil {
+		return nil, fmt.Errorf("for layer (%v) %v has an error: %v", lid, ConfigKeyH3MinResolution, err)
+	}
+	if agg.maxRes, err = layer.Int(ConfigKeyH3MaxResolution, &agg.maxRes); err != nil {
+		return nil, fmt.Errorf("for layer (%v) %v has an error: %v", lid, ConfigKeyH3MaxResolution, err)
+	}
+	if agg.minRes < DefaultH3MinResolution || agg.maxRes > DefaultH3MaxResolution || agg.minRes > agg.maxRes {
+		return nil, fmt.Errorf("for layer (%v) %v (%v) and %v (%v) must be within %v and %v", lid, ConfigKeyH3MinResolution, agg.minRes, ConfigKeyH3MaxResolution, agg.maxRes, DefaultH3MinResolution, DefaultH3MaxResolution)
+	}
+
+	stats, err := layer.StringSlice(ConfigKeyH3Statistics)
+	if err != nil {
+		return nil, fmt.Errorf("for layer (%v) %v has an error: %v", lid, ConfigKeyH3Statistics, err)
+	}
+	for _, s := range stats {
+		// statistics are configured as function:field, i.e. sum:population
+		parts := strings.SplitN(s, ":", 2)
+		if len(parts) != 2 || parts[1] == "" || !h3Statistics[strings.ToLower(parts[0])] {
+			return nil, fmt.Errorf("for layer (%v) %v has an invalid statistic (%v), expected sum, avg, min or max:field", lid, ConfigKeyH3Statistics, s)
+		}
+		agg.stats = append(agg.stats, h3Statistic{fn: strings.ToLower(parts[0]), field: parts[1]})
+	}
+
+	return &agg, nil
+}
+
+// resolution returns the H3 resolution for the zoom. The resolution is the coarsest one
+// with hexagons no wider than the configured cell size at the equator.
+func (agg *h3Aggregation) resolution(z uint) int {
+	// the width of the cell in meters at the equator
+	width := earthCircumference / math.Exp2(float64(z)) * float64(agg.cellSize) / 256
+	// hexagons are about twice their edge length wide and the edge length shrinks by sqrt(7) each resolution
+	res := int(math.Ceil(math.Log(2*h3EdgeLength0/width) / math.Log(math.Sqrt(7))))
+
+	if res < agg.minRes {
+		return agg.minRes
+	}
+	if res > agg.maxRes {
+		return agg.maxRes
+	}
+	return res
+}
+
+// margin returns the distance, in units of the srid, the tile's bounding box is expanded by so
+// every point of the hexagons overlapping the tile is counted. Hexagons are wider than the cell
+// size away from the equator, so the margin is twice the cell size.
+func (agg *h3Aggregation) margin(tile provider.Tile, srid uint64) float64 {
+	z, _, _ := tile.ZXY()
+	m := 2 * earthCircumference / math.Exp2(float64(z)) * float64(agg.cellSize) / 256
+	if srid == tegola.WGS84 {
+		return m / earthCircumference * 360
+	}
+	return m
+}
+
+// replaceH3Tokens replaces the H3 tokens of the layer's SQL for the tile
+func replaceH3Tokens(sql string, lyr *Layer, tile provider.Tile) string {
+	if lyr.h3 == nil {
+		return sql
+	}
+	z, _, _ := tile.ZXY()
+	return strings.NewReplacer(
+		h3ResolutionToken, strconv.Itoa(lyr.h3.resolution(z)),
+		h3MarginToken, strconv.FormatFloat(lyr.h3.margin(tile, lyr.srid), 'f', -1, 64),
+	).Replace(sql)
+}
+
+// genH3SQL generates the SQL which aggregates the points of the table into H3 hexagons, using the
+// h3 and h3_postgis extensions. The hexagons are returned with their point count and statistics.
+// When mvt is set the hexagons are encoded with ST_AsMVTGeom, otherwise with ST_AsBinary.
+func genH3SQL(l *Layer, tblname string, mvt bool) string {
+	cols := []string{
+		fmt.Sprintf(`h3_lat_lng_to_cell(ST_Transform("%v", 4326), %v) AS cell`, l.geomField, h3ResolutionToken),
+		fmt.Sprintf(`count(*) AS "%v"`, h3CountField),
+	}
+	for _, s := range l.h3.stats {
+		cols = append(cols, fmt.Sprintf(`%v("%v") AS "%v"`, s.fn, s.field, s.tag()))
+	}
+
+	hexagon := fmt.Sprintf(`ST_Transform(h3_cell_to_boundary_geometry(h.cell), %v)`, l.srid)
+	geomCol := fmt.Sprintf(`ST_AsBinary(%v) AS "%v"`, hexagon, l.geomField)
+	if mvt {
+		geomCol = fmt.Sprintf(`ST_AsMVTGeom(%v,%v) AS "%v"`, hexagon, bboxToken, l.geomField)
+	}
+
+	flds := []string{
+		geomCol,
+		fmt.Sprintf(`h.cell::bigint AS "%v"`, h3IDField),
+		fmt.Sprintf(`h.cell::text AS "%v"`, h3IndexField),
+		fmt.Sprintf(`h."%v"`, h3CountField),
+	}
+	for _, s := range l.h3.stats {
+		flds = append(flds, fmt.Sprintf(`h."%v"`, s.tag()))
+	}
+
+	// points are selected from around the tile so hexagons crossing the tile's edge have the same
+	// values in neighbouring tiles. Only the hexagons overlapping the tile are returned.
+	return fmt.Sprintf(
+		`SELECT %v FROM (SELECT %v FROM %v WHERE "%v" && ST_Expand(%v, %v) GROUP BY 1) AS h WHERE %v && %v`,
+		strings.Join(flds, ", "),
+		strings.Join(cols, ", "),
+		tblname,
+		l.geomField,
+		bboxToken,
+		h3MarginToken,
+		hexagon,
+		bboxToken,
+	)
+}
@@ -0,0 +1,127 @@
+package postgis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+func TestH3AggregationFromConfig(t *testing.T) {
+	type tcase struct {
+		config   dict.Dict
+		expected *h3Aggregation
+		err      string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			agg, err := h3AggregationFromConfig("hex", tc.config)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(agg, tc.expected) {
+				t.Errorf("expected %+v got %+v", tc.expected, agg)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"disabled": {
+			config: dict.Dict{},
+		},
+		"defaults": {
+			config:   dict.Dict{ConfigKeyH3: true},
+			expected: &h3Aggregation{cellSize: DefaultH3CellSize, minRes: DefaultH3MinResolution, maxRes: DefaultH3MaxResolution},
+		},
+		"statistics": {
+			config: dict.Dict{
+				ConfigKeyH3:              true,
+				ConfigKeyH3CellSize:      32,
+				ConfigKeyH3MaxResolution: 9,
+				ConfigKeyH3Statistics:    []string{"SUM:population", "avg:speed"},
+			},
+			expected: &h3Aggregation{
+				cellSize: 32,
+				maxRes:   9,
+				stats:    []h3Statistic{{fn: "sum", field: "population"}, {fn: "avg", field: "speed"}},
+			},
+		},
+		"invalid statistic": {
+			config: dict.Dict{ConfigKeyH3: true, ConfigKeyH3Statistics: []string{"median:speed"}},
+			err:    "invalid statistic (median:speed)",
+		},
+		"invalid resolutions": {
+			config: dict.Dict{ConfigKeyH3: true, ConfigKeyH3MinResolution: 10, ConfigKeyH3MaxResolution: 8},
+			err:    "must be within 0 and 15",
+		},
+		"invalid cell size": {
+			config: dict.Dict{ConfigKeyH3: true, ConfigKeyH3CellSize: 0},
+			err:    "must be greater than 0",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestH3Resolution(t *testing.T) {
+	agg := h3Aggregation{cellSize: DefaultH3CellSize, minRes: 1, maxRes: 12}
+
+	tests := map[uint]int{
+		0:  1,
+		2:  2,
+		6:  5,
+		10: 7,
+		14: 10,
+		20: 12,
+	}
+
+	for z, expected := range tests {
+		if got := agg.resolution(z); got != expected {
+			t.Errorf("zoom %v: expected resolution %v got %v", z, expected, got)
+		}
+	}
+}
+
+func TestGenH3SQL(t *testing.T) {
+	l := Layer{
+		geomField: "geom",
+		idField:   h3IDField,
+		srid:      tegola.WebMercator,
+		h3:        &h3Aggregation{cellSize: DefaultH3CellSize, maxRes: DefaultH3MaxResolution, stats: []h3Statistic{{fn: "sum", field: "population"}}},
+	}
+
+	sql, err := replaceTokens(genH3SQL(&l, "places", false), &l, provider.NewTile(10, 0, 0, 0, tegola.WebMercator), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		`SELECT ST_AsBinary(ST_Transform(h3_cell_to_boundary_geometry(h.cell), 3857)) AS "geom", h.cell::bigint AS "h3_id", h.cell::text AS "h3_index", h."count", h."sum_population"`,
+		`FROM (SELECT h3_lat_lng_to_cell(ST_Transform("geom", 4326), 7) AS cell, count(*) AS "count", sum("population") AS "sum_population" FROM places`,
+		`WHERE "geom" && ST_Expand(ST_MakeEnvelope(`,
+		`GROUP BY 1) AS h WHERE ST_Transform(h3_cell_to_boundary_geometry(h.cell), 3857) && ST_MakeEnvelope(`,
+	} {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("expected sql to contain %v, got %v", expected, sql)
+		}
+	}
+	if strings.Contains(sql, "!") {
+		t.Errorf("expected all tokens to be replaced, got %v", sql)
+	}
+
+	if mvt := genH3SQL(&l, "places", true); !strings.Contains(mvt, `ST_AsMVTGeom(ST_Transform(h3_cell_to_boundary_geometry(h.cell), 3857),!BBOX!) AS "geom"`) {
+		t.Errorf("expected mvt sql to encode hexagons with ST_AsMVTGeom, got %v", mvt)
+	}
+}
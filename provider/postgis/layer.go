@@ -18,6 +18,12 @@ type Layer struct {
 	// The SRID that the data in the table is stored in. This will default to WebMercator
 	srid   uint64
 	fields []string
+	// updatedSQL returns the time the layer's data was last updated
+	updatedSQL string
+	// changedSQL returns the geometries of the features changed since its $1 argument
+	changedSQL string
+	// h3 aggregates the layer's points into H3 hexagons when set
+	h3 *h3Aggregation
 }
 
 func (l Layer) ID() string {
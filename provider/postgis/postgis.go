@@ -8,10 +8,10 @@ import (
 	"io/ioutil"
 	"log"
 	"math"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx"
 	"github.com/jackc/pgx/pgtype"
@@ -33,6 +33,8 @@ type Provider struct {
 	layers     map[string]Layer
 	srid       uint64
 	firstlayer string
+	// dialect is the SQL dialect of the database. see DialectPostGIS and DialectCockroachDB
+	dialect string
 }
 
 const (
@@ -74,6 +76,12 @@ const (
 	ConfigKeyGeomIDField = "id_fieldname"
 	ConfigKeyGeomType    = "geometry_type"
 	ConfigKeyLayerType   = "type"
+	ConfigKeyDialect     = "dialect"
+	ConfigKeyUpdatedSQL  = "updated_sql"
+
+	// the changes of a layer's data, used to re-seed its cached tiles
+	ConfigKeyChangedSQL   = "changed_sql"
+	ConfigKeyChangedField = "changed_field"
 )
 
 // isSelectQuery is a regexp to check if a query starts with `SELECT`,
@@ -92,6 +100,7 @@ var isSelectQuery = regexp.MustCompile(`(?i)^((\s*)(--.*\n)?)*select`)
 // 	password (string): [Required] postgis database password
 // 	srid (int): [Optional] The default SRID for the provider. Defaults to WebMercator (3857) but also supports WGS84 (4326)
 // 	max_connections : [Optional] The max connections to maintain in the connection pool. Default is 100. 0 means no max.
+// 	dialect (string): [Optional] The SQL dialect of the database. "postgis" (default) or "cockroachdb".
 // 	layers (map[string]struct{})  — This is map of layers keyed by the layer name. supports the following properties
 //
 // 		name (string): [Required] the name of the layer. This is used to reference this layer from map layers.
@@ -105,6 +114,15 @@ var isSelectQuery = regexp.MustCompile(`(?i)^((\s*)(--.*\n)?)*select`)
 // 			!BBOX! - [Required] will be replaced with the bounding box of the tile before the query is sent to the database.
 // 			!ZOOM! - [Optional] will be replaced with the "Z" (zoom) value of the requested tile.
 //
+// 		updated_sql (string): [Optional] SQL returning the timestamptz the layer's data was last updated, used for freshness monitoring.
+// 		changed_sql (string): [Optional] SQL returning the WGS84 geometries of the features changed since the $1 timestamptz, used to re-seed the cached tiles of changed features.
+// 		changed_field (string): [Optional] a timestamptz column of the tablename set when a row changes. Generates the changed_sql.
+// 		h3 (bool): [Optional] aggregate the points of the table into H3 hexagons. Requires the h3 and h3_postgis extensions.
+// 		h3_cell_size (int): [Optional] the width of the hexagons in pixels, used to derive the resolution from the zoom. Defaults to 16.
+// 		h3_min_resolution (int): [Optional] the minimum H3 resolution. Defaults to 0.
+// 		h3_max_resolution (int): [Optional] the maximum H3 resolution. Defaults to 15.
+// 		h3_statistics ([]string): [Optional] aggregates of fields added to the hexagons as function:field. Supports sum, avg, min and max.
+//
 func CreateProvider(config dict.Dicter) (*Provider, error) {
 
 	host, err := config.String(ConfigKeyHost, nil)
@@ -166,6 +184,14 @@ func CreateProvider(config dict.Dicter) (*Provider, error) {
 		return nil, err
 	}
 
+	dialect := DefaultDialect
+	if dialect, err = config.String(ConfigKeyDialect, &dialect); err != nil {
+		return nil, err
+	}
+	if dialect, err = validateDialect(dialect); err != nil {
+		return nil, err
+	}
+
 	connConfig := pgx.ConnConfig{
 		Host:     host,
 		Port:     uint16(port),
@@ -185,7 +211,8 @@ func CreateProvider(config dict.Dicter) (*Provider, error) {
 	}
 
 	p := Provider{
-		srid: uint64(srid),
+		srid:    uint64(srid),
+		dialect: dialect,
 		config: pgx.ConnPoolConfig{
 			ConnConfig:     connConfig,
 			MaxConnections: int(maxcon),
@@ -327,7 +354,7 @@ func (p Provider) inspectLayerGeomType(l *Layer) error {
 	sql = fmt.Sprintf("%v LIMIT 1", sql)
 	// if a !ZOOM! token exists, all features could be filtered out so we don't have a geometry to inspect it's type.
 	// address this by replacing the !ZOOM! token with an ANY statement which includes all zooms
-	sql = strings.Replace(sql, "!ZOOM!", "ANY('{0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24}')", 1)
+	sql = strings.Replace(sql, "!ZOOM!", p.allZooms(), 1)
 	// we need a tile to run our sql through the replacer
 	tile := provider.NewTile(0, 0, 0, 64, tegola.WebMercator)
 
@@ -406,10 +433,10 @@ func (p Provider) inspectLayerExtent(l *Layer) (geom.Extent, error) {
 	var rgx1 = regexp.MustCompile(`(?i)select(.*?)(?i)from`)
 	idx = rgx1.FindStringIndex(l.sql)
 	if 2 == len(idx) {
-		rps := fmt.Sprintf("SELECT ST_Extent(%s) FROM", l.geomField)
+		rps := p.extentSelect(l.geomField)
 		sql = strings.Replace(l.sql, l.sql[idx[0]:idx[1]], rps, 1)
 	}
-	sql = strings.Replace(sql, "!ZOOM!", "ANY('{0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24}')", 1)
+	sql = strings.Replace(sql, "!ZOOM!", p.allZooms(), 1)
 	// we need a tile to run our sql through the replacer
 	tile := provider.NewTile(0, 0, 0, 64, tegola.WebMercator)
 	// normal replacer
@@ -417,6 +444,13 @@ func (p Provider) inspectLayerExtent(l *Layer) (geom.Extent, error) {
 	if err != nil {
 		return ext, err
 	}
+	if p.isCockroachDB() {
+		e, err := p.scanCockroachDBExtent(sql)
+		if err != nil {
+			return ext, fmt.Errorf("inspect layer(%s) extent error: %v", l.name, err)
+		}
+		return e, nil
+	}
 	row := p.pool.QueryRow(sql)
 	var box string
 	err = row.Scan(&box)
@@ -473,7 +507,7 @@ func (p Provider) inspectLayerMaxZoom(l *Layer) int {
 			rps := "SELECT COUNT(*) FROM"
 			sql = strings.Replace(l.sql, l.sql[idx[0]:idx[1]], rps, 1)
 		}
-		sql = strings.Replace(sql, "!ZOOM!", "ANY('{0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24}')", 1)
+		sql = strings.Replace(sql, "!ZOOM!", p.allZooms(), 1)
 		// we need a tile to run our sql through the replacer
 		tile := provider.NewTile(uint(z), tx, ty, 64, tegola.WebMercator)
 		// normal replacer
@@ -520,12 +554,17 @@ func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider
 		return ErrLayerNotFound{lyrID}
 	}
 
-	sql, err := replaceTokens(plyr.sql, &plyr, tile, true)
+	sql, err := replaceClaimsToken(ctx, plyr.sql)
+	if err != nil {
+		return fmt.Errorf("error replacing claims token for layer (%v): %v", lyrID, err)
+	}
+	sql, err = replaceTokens(sql, &plyr, tile, true)
 	if err != nil {
 		return fmt.Errorf("error replacing layer tokens for layer (%v) SQL (%v): %v", lyrID, sql, err)
 	}
+	sql = requestIDComment(ctx) + sql
 
-	if debugExecuteSQL {
+	if isExecuteSQLDebug(lyrID) {
 		log.Printf("TEGOLA_SQL_DEBUG:EXECUTE_SQL for layer (%v): %v", lyrID, sql)
 	}
 
@@ -619,13 +658,85 @@ func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider
 	return rows.Err()
 }
 
+// Ping checks a connection of the pool can query the database
+func (p *Provider) Ping(ctx context.Context) error {
+	_, err := p.pool.ExecEx(ctx, "SELECT 1", nil)
+	return err
+}
+
+// LayerUpdated runs the layer's updated_sql to report when the layer's data was last updated
+func (p *Provider) LayerUpdated(ctx context.Context, lyrID string) (time.Time, error) {
+	plyr, ok := p.layers[lyrID]
+	if !ok {
+		return time.Time{}, ErrLayerNotFound{lyrID}
+	}
+	if plyr.updatedSQL == "" {
+		return time.Time{}, ErrUpdatedSQLNotConfigured{lyrID}
+	}
+
+	var updated pgtype.Timestamptz
+	if err := p.pool.QueryRowEx(ctx, plyr.updatedSQL, nil).Scan(&updated); err != nil {
+		return time.Time{}, fmt.Errorf("error running layer (%v) %v (%v): %v", lyrID, ConfigKeyUpdatedSQL, plyr.updatedSQL, err)
+	}
+	if updated.Status != pgtype.Present {
+		return time.Time{}, nil
+	}
+	return updated.Time, nil
+}
+
+// LayerChanges runs the layer's changed_sql to report the extents of the features changed since the time
+func (p *Provider) LayerChanges(ctx context.Context, lyrID string, since time.Time) ([]geom.Extent, error) {
+	plyr, ok := p.layers[lyrID]
+	if !ok {
+		return nil, ErrLayerNotFound{lyrID}
+	}
+	if plyr.changedSQL == "" {
+		return nil, ErrChangedSQLNotConfigured{lyrID}
+	}
+
+	rows, err := p.pool.QueryEx(ctx, plyr.changedSQL, nil, since)
+	if err != nil {
+		return nil, fmt.Errorf("error running layer (%v) %v (%v): %v", lyrID, ConfigKeyChangedSQL, plyr.changedSQL, err)
+	}
+	defer rows.Close()
+
+	var extents []geom.Extent
+	for rows.Next() {
+		var geobytes []byte
+		if err := rows.Scan(&geobytes); err != nil {
+			return nil, fmt.Errorf("error scanning layer (%v) %v: %v", lyrID, ConfigKeyChangedSQL, err)
+		}
+		// deleted features may not have a geometry
+		if len(geobytes) == 0 {
+			continue
+		}
+
+		geometry, err := wkb.DecodeBytes(geobytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode layer (%v) %v geometry: %v", lyrID, ConfigKeyChangedSQL, err)
+		}
+		ext, err := geom.NewExtentFromGeometry(geometry)
+		if err != nil || ext == nil {
+			continue
+		}
+		extents = append(extents, *ext)
+	}
+	return extents, rows.Err()
+}
+
 // MVTForLayers xxx
 func (p *Provider) MVTForLayers(ctx context.Context, tile provider.Tile, layers []provider.Layer) ([]byte, error) {
 	var (
 		err  error
 		sqls = make([]string, 0, len(layers))
+		// executeSQLDebug is set if any of the requested layers has EXECUTE_SQL debugging enabled
+		executeSQLDebug = debugExecuteSQL
 	)
 
+	if p.isCockroachDB() {
+		return nil, ErrMVTUnsupported(p.dialect)
+	}
+
 	for i := range layers {
 		if debug {
 			log.Printf("looking for layer: %v", layers[i])
@@ -637,10 +748,15 @@ func (p *Provider) MVTForLayers(ctx context.Context, tile provider.Tile, layers
 			// spam the user?
 			log.Printf("provider layer not found %v", layers[i].ID)
 		}
-		if debugLayerSQL {
+		if isLayerSQLDebug(layers[i].ID) {
 			log.Printf("SQL for Layer(%v):\n%v\n", l.Name(), l.sql)
 		}
-		sql, err := replaceTokens(l.sql, &l, tile, false)
+		executeSQLDebug = executeSQLDebug || isExecuteSQLDebug(layers[i].ID)
+		sql, err := replaceClaimsToken(ctx, l.sql)
+		if err != nil {
+			return nil, err
+		}
+		sql, err = replaceTokens(sql, &l, tile, false)
 		if err != nil {
 			return nil, err
 		}
@@ -667,14 +783,14 @@ func (p *Provider) MVTForLayers(ctx context.Context, tile provider.Tile, layers
 		sqls = append(sqls, asmvt)
 	}
 	subsqls := strings.Join(sqls, "||")
-	fsql := fmt.Sprintf(`SELECT (%s) AS data`, subsqls)
+	fsql := fmt.Sprintf(`%sSELECT (%s) AS data`, requestIDComment(ctx), subsqls)
 	// fmt.Println(fsql)
 	var data pgtype.Bytea
-	if debugExecuteSQL {
+	if executeSQLDebug {
 		log.Printf("%s:%s: %v", EnvSQLDebugName, EnvSQLDebugExecute, fsql)
 	}
 	err = p.pool.QueryRow(fsql).Scan(&data)
-	if debugExecuteSQL {
+	if executeSQLDebug {
 		log.Printf("%s:%s: %v", EnvSQLDebugName, EnvSQLDebugExecute, fsql)
 		if err != nil {
 			log.Printf("%s:%s: returned error %v", EnvSQLDebugName, EnvSQLDebugExecute, err)
@@ -785,6 +901,25 @@ func (p *Provider) AddLayer(layer dict.Dicter) error {
 		srid:      uint64(lsrid),
 	}
 
+	if l.updatedSQL, err = layer.String(ConfigKeyUpdatedSQL, &l.updatedSQL); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", lid, ConfigKeyUpdatedSQL, err)
+	}
+
+	if l.changedSQL, err = layer.String(ConfigKeyChangedSQL, &l.changedSQL); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", lid, ConfigKeyChangedSQL, err)
+	}
+	var changedField string
+	if changedField, err = layer.String(ConfigKeyChangedField, &changedField); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", lid, ConfigKeyChangedField, err)
+	}
+	if changedField != "" && l.changedSQL != "" {
+		return fmt.Errorf("for layer (%v) %v and %v can not both be set", lid, ConfigKeyChangedField, ConfigKeyChangedSQL)
+	}
+
+	if l.h3, err = h3AggregationFromConfig(lid, layer); err != nil {
+		return err
+	}
+
 	if sql != "" && !isSelectQuery.MatchString(sql) {
 		// if it is not a SELECT query, then we assume we have a sub-query
 		// (`(select ...) as foo`) which we can handle like a tablename
@@ -792,7 +927,20 @@ func (p *Provider) AddLayer(layer dict.Dicter) error {
 		sql = ""
 	}
 
-	if sql != "" {
+	if l.h3 != nil {
+		// hexagons are aggregated from a table or sub-query, the generated SQL selects the fields
+		if sql != "" {
+			return fmt.Errorf("for layer (%v) %v can not be used with a SELECT %v, use a %v or sub-query", lid, ConfigKeyH3, ConfigKeySQL, ConfigKeyTablename)
+		}
+		if p.isCockroachDB() {
+			return fmt.Errorf("for layer (%v) %v is not supported by the %v dialect", lid, ConfigKeyH3, p.dialect)
+		}
+		layerType, _ := layer.String(ConfigKeyLayerType, nil)
+		// aggregated layers are always hexagons
+		l.idField = h3IDField
+		geomType = "polygon"
+		l.sql = genH3SQL(&l, tblName, layerType != "postgis")
+	} else if sql != "" {
 		// convert !BOX! (MapServer) and !bbox! (Mapnik) to !BBOX! for compatibility
 		sql := strings.Replace(strings.Replace(sql, "!BOX!", "!BBOX!", -1), "!bbox!", "!BBOX!", -1)
 		// make sure that the sql has a !BBOX! token
@@ -815,7 +963,8 @@ func (p *Provider) AddLayer(layer dict.Dicter) error {
 		// and if not add them to the list. If Fields list is empty/nil we will use '*' for the field list.
 		//默认mvt
 		layerType, _ := layer.String(ConfigKeyLayerType, nil)
-		if layerType == "postgis" {
+		// CockroachDB does not support ST_AsMVTGeom so the geometry is always fetched as WKB
+		if layerType == "postgis" || p.isCockroachDB() {
 			l.sql, err = genSQL(&l, p.pool, tblName, fields, true)
 		} else {
 			l.sql, err = genMvtSQL(&l, p.pool, tblName, fields, true)
@@ -825,7 +974,15 @@ func (p *Provider) AddLayer(layer dict.Dicter) error {
 		}
 	}
 
-	if strings.Contains(os.Getenv("TEGOLA_SQL_DEBUG"), "LAYER_SQL") {
+	if changedField != "" {
+		// the changes are queried from the table or sub-query, not the layer's SELECT
+		if sql != "" {
+			return fmt.Errorf("for layer (%v) %v requires a %v or sub-query, use %v with a SELECT %v", lid, ConfigKeyChangedField, ConfigKeyTablename, ConfigKeyChangedSQL, ConfigKeySQL)
+		}
+		l.changedSQL = genChangedSQL(geomfld, tblName, changedField, l.srid)
+	}
+
+	if isLayerSQLDebug(lid) {
 		log.Printf("SQL for Layer(%v):\n%v\n", lid, l.sql)
 	}
 
@@ -35,5 +35,15 @@ func init() {
 // 			!BBOX! - [Required] will be replaced with the bounding box of the tile before the query is sent to the database.
 // 			!ZOOM! - [Optional] will be replaced with the "Z" (zoom) value of the requested tile.
 //
-func NewTileProvider(config dict.Dicter) (provider.Tiler, error)       { return CreateProvider(config) }
-func NewMVTTileProvider(config dict.Dicter) (provider.MVTTiler, error) { return CreateProvider(config) }
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) { return CreateProvider(config) }
+func NewMVTTileProvider(config dict.Dicter) (provider.MVTTiler, error) {
+	p, err := CreateProvider(config)
+	if err != nil {
+		return nil, err
+	}
+	if p.isCockroachDB() {
+		p.Close()
+		return nil, ErrMVTUnsupported(p.dialect)
+	}
+	return p, nil
+}
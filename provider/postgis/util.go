@@ -2,8 +2,10 @@ package postgis
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -77,6 +79,12 @@ func genSQL(l *Layer, pool *pgx.ConnPool, tblname string, flds []string, buffer
 	return fmt.Sprintf(stdSQL, selectClause, tblname, l.geomField), nil
 }
 
+// genChangedSQL returns the SQL selecting the WGS84 envelopes of the rows of the table or
+// sub-query whose changed field is after the $1 argument
+func genChangedSQL(geomField, tblname, changedField string, srid uint64) string {
+	return fmt.Sprintf(`SELECT ST_AsBinary(ST_Transform(ST_SetSRID(ST_Envelope("%v"), %v), 4326)) FROM %v WHERE "%v" > $1`, geomField, srid, tblname, changedField)
+}
+
 // genSQL will fill in the SQL field of a layer given a pool, and list of fields.
 func genMvtSQL(l *Layer, pool *pgx.ConnPool, tblname string, flds []string, buffer bool) (sql string, err error) {
 
@@ -150,6 +158,7 @@ const (
 	idFieldToken          = "!ID_FIELD!"
 	geomFieldToken        = "!GEOM_FIELD!"
 	geomTypeToken         = "!GEOM_TYPE!"
+	claimsToken           = "!CLAIMS!"
 )
 
 // replaceTokens replaces tokens in the provided SQL string
@@ -164,6 +173,9 @@ const (
 // !PIXEL_HEIGHT! - the pixel height in meters, assuming 256x256 tiles
 // !GEOM_FIELD! - the geom field name
 // !GEOM_TYPE! - the geom field type if defined otherwise ""
+// !H3_RESOLUTION! - the H3 resolution for the tile's zoom of h3 layers
+// !H3_MARGIN! - the distance the bounding box is expanded by for h3 layers
+// !CLAIMS! - NULL, the claims of authenticated requests are replaced by replaceClaimsToken
 func replaceTokens(sql string, lyr *Layer, tile provider.Tile, withBuffer bool) (string, error) {
 	var (
 		extent  *geom.Extent
@@ -175,14 +187,20 @@ func replaceTokens(sql string, lyr *Layer, tile provider.Tile, withBuffer bool)
 	}
 	srid := lyr.SRID()
 
+	var (
+		tileSRID uint64
+		err      error
+	)
 	if withBuffer {
-		extent, _ = tile.BufferedExtent()
+		extent, tileSRID = tile.BufferedExtent()
 	} else {
-		extent, _ = tile.Extent()
+		extent, tileSRID = tile.Extent()
+	}
+	if extent, err = webMercatorExtent(extent, tileSRID); err != nil {
+		return "", err
 	}
 
 	// TODO: leverage helper functions for minx / miny to make this easier to follow
-	// TODO: it's currently assumed the tile will always be in WebMercator. Need to support different projections
 	minGeo, err := basic.FromWebMercator(srid, geom.Point{extent.MinX(), extent.MinY()})
 	if err != nil {
 		return "", fmt.Errorf("Error trying to convert tile point: %v ", err)
@@ -197,8 +215,9 @@ func replaceTokens(sql string, lyr *Layer, tile provider.Tile, withBuffer bool)
 
 	bbox := fmt.Sprintf("ST_MakeEnvelope(%g,%g,%g,%g,%d)", minPt.X(), minPt.Y(), maxPt.X(), maxPt.Y(), srid)
 
-	extent, _ = tile.Extent()
-	// TODO: Always convert to meter if we support different projections
+	if extent, err = webMercatorExtent(tile.Extent()); err != nil {
+		return "", err
+	}
 	pixelWidth := (extent.MaxX() - extent.MinX()) / 256
 	pixelHeight := (extent.MaxY() - extent.MinY()) / 256
 	scaleDenominator := pixelWidth / 0.00028 /* px size in m */
@@ -221,14 +240,35 @@ func replaceTokens(sql string, lyr *Layer, tile provider.Tile, withBuffer bool)
 		scaleDenominatorToken, strconv.FormatFloat(scaleDenominator, 'f', -1, 64),
 		pixelWidthToken, strconv.FormatFloat(pixelWidth, 'f', -1, 64),
 		pixelHeightToken, strconv.FormatFloat(pixelHeight, 'f', -1, 64),
+		claimsToken, "NULL::jsonb",
 	)
 
-	uppercaseTokenSQL := uppercaseTokens(sql)
+	uppercaseTokenSQL := uppercaseTokens(replaceH3Tokens(sql, lyr, tile))
 
 	return tokenReplacer.Replace(uppercaseTokenSQL), nil
 }
 
-var tokenRe = regexp.MustCompile("![a-zA-Z0-9_-]+!")
+var (
+	tokenRe       = regexp.MustCompile("![a-zA-Z0-9_-]+!")
+	claimsTokenRe = regexp.MustCompile("(?i)!claims!")
+)
+
+// replaceClaimsToken replaces the !CLAIMS! token with the claims of the client of the tile
+// request as a jsonb literal, i.e. for row level security. The token is left for replaceTokens
+// when the request was not authenticated.
+func replaceClaimsToken(ctx context.Context, sql string) (string, error) {
+	claims, ok := provider.Claims(ctx)
+	if !ok || !claimsTokenRe.MatchString(sql) {
+		return sql, nil
+	}
+
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	literal := "'" + strings.Replace(string(b), "'", "''", -1) + "'::jsonb"
+	return claimsTokenRe.ReplaceAllLiteralString(sql, literal), nil
+}
 
 //	uppercaseTokens converts all !tokens! to uppercase !TOKENS!. Tokens can
 //	contain alphanumerics, dash and underline chars.
@@ -370,3 +410,37 @@ func gId(v interface{}) (gid uint64, err error) {
 		return gid, fmt.Errorf("unable to convert field into a uint64.")
 	}
 }
+
+// requestIDComment returns a comment with the id of the tile request to prefix a query with,
+// so the query can be correlated with the access log (i.e. in pg_stat_activity). "" is
+// returned when the request has no id.
+func requestIDComment(ctx context.Context) string {
+	id := provider.RequestID(ctx)
+	if id == "" {
+		return ""
+	}
+	return "/* request_id: " + strings.Replace(id, "*/", "", -1) + " */ "
+}
+
+// maxLat is the largest latitude of web mercator
+const maxLat = 85.0511287798066
+
+// webMercatorExtent returns the extent of a tile in web mercator. The latitudes of WGS84 tile
+// extents are clamped to the latitudes web mercator covers.
+func webMercatorExtent(extent *geom.Extent, srid uint64) (*geom.Extent, error) {
+	if srid != tegola.WGS84 {
+		return extent, nil
+	}
+
+	clamp := func(lat float64) float64 { return math.Max(-maxLat, math.Min(maxLat, lat)) }
+	min, err := basic.ToWebMercator(srid, geom.Point{extent.MinX(), clamp(extent.MinY())})
+	if err != nil {
+		return nil, fmt.Errorf("Error trying to convert tile point: %v ", err)
+	}
+	max, err := basic.ToWebMercator(srid, geom.Point{extent.MaxX(), clamp(extent.MaxY())})
+	if err != nil {
+		return nil, fmt.Errorf("Error trying to convert tile point: %v ", err)
+	}
+	minPt, maxPt := min.(geom.Point), max.(geom.Point)
+	return geom.NewExtent([2]float64{minPt.X(), minPt.Y()}, [2]float64{maxPt.X(), maxPt.Y()}), nil
+}
@@ -114,6 +114,107 @@ func TestUppercaseTokens(t *testing.T) {
 	}
 }
 
+func TestReplaceClaimsToken(t *testing.T) {
+	type tcase struct {
+		ctx      context.Context
+		sql      string
+		expected string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			out, err := replaceClaimsToken(tc.ctx, tc.sql)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if out != tc.expected {
+				t.Errorf("expected \n \t%v\n out \n \t%v", tc.expected, out)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"claims": {
+			ctx:      provider.WithClaims(context.Background(), map[string]interface{}{"sub": "o'brien"}),
+			sql:      "SELECT * FROM parcels WHERE owner = !claims!->>'sub' AND geom && !BBOX!",
+			expected: `SELECT * FROM parcels WHERE owner = '{"sub":"o''brien"}'::jsonb->>'sub' AND geom && !BBOX!`,
+		},
+		"unauthenticated": {
+			ctx:      context.Background(),
+			sql:      "SELECT * FROM parcels WHERE owner = !CLAIMS!->>'sub'",
+			expected: "SELECT * FROM parcels WHERE owner = !CLAIMS!->>'sub'",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestRequestIDComment(t *testing.T) {
+	tests := map[string]struct {
+		ctx      context.Context
+		expected string
+	}{
+		"request id": {
+			ctx:      provider.WithRequestID(context.Background(), "4f9c2a"),
+			expected: "/* request_id: 4f9c2a */ ",
+		},
+		"comment end": {
+			ctx:      provider.WithRequestID(context.Background(), "a*/b"),
+			expected: "/* request_id: ab */ ",
+		},
+		"no request id": {
+			ctx: context.Background(),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if out := requestIDComment(tc.ctx); out != tc.expected {
+				t.Errorf("expected %q got %q", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestGenChangedSQL(t *testing.T) {
+	type tcase struct {
+		tblname  string
+		srid     uint64
+		expected string
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			out := genChangedSQL("geom", tc.tblname, "updated_at", tc.srid)
+
+			if out != tc.expected {
+				t.Errorf("expected \n \t%v\n out \n \t%v", tc.expected, out)
+				return
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"table": {
+			tblname:  "gis.rivers",
+			srid:     3857,
+			expected: `SELECT ST_AsBinary(ST_Transform(ST_SetSRID(ST_Envelope("geom"), 3857), 4326)) FROM gis.rivers WHERE "updated_at" > $1`,
+		},
+		"sub-query": {
+			tblname:  "(SELECT * FROM gis.rivers WHERE scalerank < 5) AS rivers",
+			srid:     4326,
+			expected: `SELECT ST_AsBinary(ST_Transform(ST_SetSRID(ST_Envelope("geom"), 4326), 4326)) FROM (SELECT * FROM gis.rivers WHERE scalerank < 5) AS rivers WHERE "updated_at" > $1`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
 func TestDecipherFields(t *testing.T) {
 	ttools.ShouldSkip(t, TESTENV)
 
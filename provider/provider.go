@@ -229,7 +229,7 @@ func Drivers(types ...providerType) (l []string) {
 
 // For function returns a configure provider of the given type; The provider may be a mvt provider or
 // a std provider. The correct entry in TilerUnion will not be nil. If there is an error both entries
-// will be nil.
+// will be nil. Decorators declared under the "decorators" config key are applied to the provider.
 func For(name string, config dict.Dicter) (val TilerUnion, err error) {
 	var (
 		driversList = Drivers()
@@ -241,15 +241,33 @@ func For(name string, config dict.Dicter) (val TilerUnion, err error) {
 	if !ok {
 		return val, ErrUnknownProvider{KnownProviders: driversList, Name: name}
 	}
-	if p.init != nil {
+	switch {
+	case p.init != nil:
 		val.Std, err = p.init(config)
-		return val, err
-	}
-	if p.mvtInit != nil {
+	case p.mvtInit != nil:
 		val.Mvt, err = p.mvtInit(config)
+	default:
+		return val, ErrInvalidRegisteredProvider{Name: name}
+	}
+	if err != nil {
 		return val, err
 	}
-	return val, ErrInvalidRegisteredProvider{Name: name}
+
+	// wrap the provider with any decorators declared in the config
+	if config == nil {
+		return val, nil
+	}
+	decs, err := config.MapSlice(ConfigKeyDecorators)
+	if err != nil {
+		return TilerUnion{}, err
+	}
+	if len(decs) == 0 {
+		return val, nil
+	}
+	if val, err = Decorate(val, decs); err != nil {
+		return TilerUnion{}, err
+	}
+	return val, nil
 }
 
 // Cleanup is called at the end of the run to allow providers to cleanup
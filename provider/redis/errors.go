@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingLayerName = errors.New("redis: layer is missing 'name'")
+)
+
+type ErrMissingLayerKey struct {
+	LayerName string
+}
+
+func (e ErrMissingLayerKey) Error() string {
+	return fmt.Sprintf("redis: layer (%v) is missing 'key'", e.LayerName)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("redis: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrInvalidAttributes struct {
+	LayerName  string
+	Attributes string
+}
+
+func (e ErrInvalidAttributes) Error() string {
+	return fmt.Sprintf("redis: layer (%v) has invalid attributes (%v), expected one of: none, hash, json", e.LayerName, e.Attributes)
+}
+
+type ErrInvalidCommand struct {
+	Command string
+}
+
+func (e ErrInvalidCommand) Error() string {
+	return fmt.Sprintf("redis: invalid command (%v), expected one of: geosearch, georadius", e.Command)
+}
+
+// ErrUnexpectedReply is returned when a reply from redis can't be decoded
+type ErrUnexpectedReply struct {
+	Command string
+	Reply   interface{}
+}
+
+func (e ErrUnexpectedReply) Error() string {
+	return fmt.Sprintf("redis: unexpected %v reply: %v", e.Command, e.Reply)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("redis: layer (%v) not found", e.LayerName)
+}
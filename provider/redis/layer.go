@@ -0,0 +1,32 @@
+package redis
+
+import "github.com/go-spatial/geom"
+
+// attribute sources for a layer's features
+const (
+	// AttributesNone only encodes the member name
+	AttributesNone = "none"
+	// AttributesHash reads the attributes from a hash stored at the attributes key (HGETALL)
+	AttributesHash = "hash"
+	// AttributesJSON reads the attributes from a RedisJSON document stored at the attributes key (JSON.GET)
+	AttributesJSON = "json"
+)
+
+type Layer struct {
+	name string
+	// key is the GEO set the member positions are read from
+	key string
+	// attributes is where the member attributes are read from
+	attributes string
+	// attributesKeyPrefix is prepended to the member name to build the attributes key
+	attributesKeyPrefix string
+	// memberFieldname is the tag the member name is encoded in
+	memberFieldname string
+	// maxFeatures limits the number of members returned per tile. 0 is unlimited
+	maxFeatures uint
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return geom.Point{} }
+func (l Layer) SRID() uint64            { return srid }
@@ -0,0 +1,290 @@
+package redis
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/go-redis/redis"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/basic"
+)
+
+// earthRadius is the radius (in meters) redis uses for its distance calculations
+const earthRadius = 6372797.560856
+
+// the coordinate limits of a GEO set
+const (
+	maxLon = 180.0
+	maxLat = 85.05112878
+)
+
+// member is a GEO set member and its position
+type member struct {
+	name     string
+	lon, lat float64
+}
+
+// lonLatExtent returns the extent in lon/lat clamped to the coordinates a GEO set can hold
+func lonLatExtent(ext *geom.Extent, extSRID uint64) (*geom.Extent, error) {
+	bbox := *ext
+	if extSRID != tegola.WGS84 {
+		min, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MinX(), ext.MinY()})
+		if err != nil {
+			return nil, err
+		}
+		max, err := basic.FromWebMercator(tegola.WGS84, geom.Point{ext.MaxX(), ext.MaxY()})
+		if err != nil {
+			return nil, err
+		}
+		minPt, maxPt := min.(geom.Point), max.(geom.Point)
+		bbox = geom.Extent{minPt.X(), minPt.Y(), maxPt.X(), maxPt.Y()}
+	}
+
+	return &geom.Extent{
+		clamp(bbox.MinX(), maxLon),
+		clamp(bbox.MinY(), maxLat),
+		clamp(bbox.MaxX(), maxLon),
+		clamp(bbox.MaxY(), maxLat),
+	}, nil
+}
+
+func clamp(v, limit float64) float64 {
+	return math.Max(-limit, math.Min(limit, v))
+}
+
+// searchArea returns the center of the extent and a radius (in meters) which covers every corner
+func searchArea(bbox *geom.Extent) (lon, lat, radius float64) {
+	lon = (bbox.MinX() + bbox.MaxX()) / 2
+	lat = (bbox.MinY() + bbox.MaxY()) / 2
+
+	for _, c := range [][2]float64{
+		{bbox.MinX(), bbox.MinY()},
+		{bbox.MinX(), bbox.MaxY()},
+		{bbox.MaxX(), bbox.MinY()},
+		{bbox.MaxX(), bbox.MaxY()},
+	} {
+		radius = math.Max(radius, distance(lon, lat, c[0], c[1]))
+	}
+
+	// round up so corner members are not lost to float precision
+	return lon, lat, math.Ceil(radius) + 1
+}
+
+// distance returns the haversine distance in meters between two lon/lat positions
+func distance(lon1, lat1, lon2, lat2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// searchArgs builds the GEO set query for the search area. redis can only search by
+// radius or by a box measured in distance, so the area searched is a circle covering
+// the bbox and the results are filtered to the bbox afterwards.
+func searchArgs(command string, layer Layer, lon, lat, radius float64) []interface{} {
+	var args []interface{}
+
+	switch command {
+	case CommandGeoRadius:
+		args = []interface{}{"GEORADIUS", layer.key, lon, lat, radius, "m", "WITHCOORD"}
+	default:
+		args = []interface{}{"GEOSEARCH", layer.key, "FROMLONLAT", lon, lat, "BYRADIUS", radius, "m", "WITHCOORD"}
+	}
+
+	if layer.maxFeatures > 0 {
+		// nearest to the center of the tile first
+		args = append(args, "COUNT", layer.maxFeatures, "ASC")
+	}
+
+	return args
+}
+
+// search returns the members of the layer's GEO set which are within the bbox
+func (p *Provider) search(layer Layer, bbox *geom.Extent) ([]member, error) {
+	lon, lat, radius := searchArea(bbox)
+
+	cmd := redis.NewCmd(searchArgs(p.command, layer, lon, lat, radius)...)
+	if err := p.client.Process(cmd); err != nil {
+		return nil, err
+	}
+
+	members, err := parseMembers(p.command, cmd.Val())
+	if err != nil {
+		return nil, err
+	}
+
+	// drop the members in the search circle but outside the bbox
+	n := 0
+	for _, m := range members {
+		if bbox.ContainsPoint([2]float64{m.lon, m.lat}) {
+			members[n] = m
+			n++
+		}
+	}
+
+	return members[:n], nil
+}
+
+// parseMembers decodes a WITHCOORD reply: an array of [member, [lon, lat]]
+func parseMembers(command string, reply interface{}) ([]member, error) {
+	items, ok := reply.([]interface{})
+	if !ok {
+		return nil, ErrUnexpectedReply{Command: command, Reply: reply}
+	}
+
+	members := make([]member, 0, len(items))
+	for _, item := range items {
+		fields, ok := item.([]interface{})
+		if !ok || len(fields) != 2 {
+			return nil, ErrUnexpectedReply{Command: command, Reply: item}
+		}
+
+		name, ok := fields[0].(string)
+		if !ok {
+			return nil, ErrUnexpectedReply{Command: command, Reply: item}
+		}
+
+		coord, ok := fields[1].([]interface{})
+		if !ok || len(coord) != 2 {
+			return nil, ErrUnexpectedReply{Command: command, Reply: item}
+		}
+
+		lon, err := replyFloat(coord[0])
+		if err != nil {
+			return nil, ErrUnexpectedReply{Command: command, Reply: item}
+		}
+		lat, err := replyFloat(coord[1])
+		if err != nil {
+			return nil, ErrUnexpectedReply{Command: command, Reply: item}
+		}
+
+		members = append(members, member{name: name, lon: lon, lat: lat})
+	}
+
+	return members, nil
+}
+
+func replyFloat(v interface{}) (float64, error) {
+	switch f := v.(type) {
+	case string:
+		return strconv.ParseFloat(f, 64)
+	case float64:
+		return f, nil
+	default:
+		return 0, strconv.ErrSyntax
+	}
+}
+
+// attributes fetches the attributes of the members in a single pipeline. The returned slice
+// is index aligned with members, members without attributes have a nil map.
+func (p *Provider) attributes(layer Layer, members []member) ([]map[string]interface{}, error) {
+	attrs := make([]map[string]interface{}, len(members))
+	if layer.attributes == AttributesNone {
+		return attrs, nil
+	}
+
+	pipe := p.client.Pipeline()
+	defer pipe.Close()
+
+	cmds := make([]*redis.Cmd, len(members))
+	for i, m := range members {
+		key := layer.attributesKeyPrefix + m.name
+		switch layer.attributes {
+		case AttributesHash:
+			cmds[i] = redis.NewCmd("HGETALL", key)
+		case AttributesJSON:
+			cmds[i] = redis.NewCmd("JSON.GET", key)
+		}
+		pipe.Process(cmds[i])
+	}
+
+	// a missing key is not an error, each command's error is checked below
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, cmd := range cmds {
+		val, err := cmd.Result()
+		switch {
+		case err == redis.Nil:
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		switch layer.attributes {
+		case AttributesHash:
+			attrs[i], err = hashTags(val)
+		case AttributesJSON:
+			attrs[i], err = jsonTags(val)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return attrs, nil
+}
+
+// hashTags decodes a HGETALL reply: an array of alternating fields and values
+func hashTags(reply interface{}) (map[string]interface{}, error) {
+	items, ok := reply.([]interface{})
+	if !ok || len(items)%2 != 0 {
+		return nil, ErrUnexpectedReply{Command: "HGETALL", Reply: reply}
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	tags := make(map[string]interface{}, len(items)/2+1)
+	for i := 0; i < len(items); i += 2 {
+		k, kok := items[i].(string)
+		v, vok := items[i+1].(string)
+		if !kok || !vok {
+			return nil, ErrUnexpectedReply{Command: "HGETALL", Reply: reply}
+		}
+		tags[k] = v
+	}
+
+	return tags, nil
+}
+
+// jsonTags decodes a JSON.GET reply. The top level members of the document are used as tags,
+// nested objects and arrays are encoded as JSON strings.
+func jsonTags(reply interface{}) (map[string]interface{}, error) {
+	s, ok := reply.(string)
+	if !ok {
+		return nil, ErrUnexpectedReply{Command: "JSON.GET", Reply: reply}
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		return nil, ErrUnexpectedReply{Command: "JSON.GET", Reply: reply}
+	}
+
+	tags := make(map[string]interface{}, len(doc)+1)
+	for k, raw := range doc {
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+
+		switch v.(type) {
+		case nil:
+			// null values don't encode
+		case map[string]interface{}, []interface{}:
+			tags[k] = string(raw)
+		default:
+			tags[k] = v
+		}
+	}
+
+	return tags, nil
+}
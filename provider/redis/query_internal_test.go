@@ -0,0 +1,144 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+)
+
+func TestSearchArea(t *testing.T) {
+	type tcase struct {
+		bbox geom.Extent
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			lon, lat, radius := searchArea(&tc.bbox)
+
+			for _, c := range [][2]float64{
+				{tc.bbox.MinX(), tc.bbox.MinY()},
+				{tc.bbox.MinX(), tc.bbox.MaxY()},
+				{tc.bbox.MaxX(), tc.bbox.MinY()},
+				{tc.bbox.MaxX(), tc.bbox.MaxY()},
+			} {
+				if d := distance(lon, lat, c[0], c[1]); d > radius {
+					t.Errorf("corner %v is outside the search radius, distance %v radius %v", c, d, radius)
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"world": {
+			bbox: geom.Extent{-180, -maxLat, 180, maxLat},
+		},
+		"city": {
+			bbox: geom.Extent{-122.52, 37.70, -122.35, 37.83},
+		},
+		"southern hemisphere": {
+			bbox: geom.Extent{151.1, -33.95, 151.3, -33.8},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestParseMembers(t *testing.T) {
+	type tcase struct {
+		reply    interface{}
+		expected []member
+		err      bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			members, err := parseMembers(CommandGeoSearch, tc.reply)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if !reflect.DeepEqual(members, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, members)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"empty": {
+			reply:    []interface{}{},
+			expected: []member{},
+		},
+		"members": {
+			reply: []interface{}{
+				[]interface{}{"bus-1", []interface{}{"-122.4194", "37.7749"}},
+				[]interface{}{"42", []interface{}{"13.405", "52.52"}},
+			},
+			expected: []member{
+				{name: "bus-1", lon: -122.4194, lat: 37.7749},
+				{name: "42", lon: 13.405, lat: 52.52},
+			},
+		},
+		"missing coord": {
+			reply: []interface{}{
+				[]interface{}{"bus-1"},
+			},
+			err: true,
+		},
+		"not an array": {
+			reply: "OK",
+			err:   true,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestJSONTags(t *testing.T) {
+	tags, err := jsonTags(`{"speed":12.5,"route":"42","active":true,"stop":null,"next":{"id":1},"path":[1,2]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"speed":  12.5,
+		"route":  "42",
+		"active": true,
+		"next":   `{"id":1}`,
+		"path":   `[1,2]`,
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected %v got %v", expected, tags)
+	}
+}
+
+func TestHashTags(t *testing.T) {
+	tags, err := hashTags([]interface{}{"speed", "12.5", "route", "42"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"speed": "12.5",
+		"route": "42",
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected %v got %v", expected, tags)
+	}
+
+	if _, err := hashTags([]interface{}{"speed"}); err == nil {
+		t.Errorf("expected error for odd number of fields, got nil")
+	}
+}
@@ -0,0 +1,335 @@
+// Package redis provides a provider which serves point features stored in redis GEO sets.
+// It's intended for fast changing point data (vehicles, sensors, etc.) where the positions
+// are written to redis by another process and served as tiles while they're live.
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "redis"
+
+// srid of the positions stored in GEO sets
+const srid = tegola.WGS84
+
+// commands used to query a GEO set
+const (
+	// CommandGeoSearch uses GEOSEARCH (redis 6.2+)
+	CommandGeoSearch = "geosearch"
+	// CommandGeoRadius uses GEORADIUS for older versions of redis
+	CommandGeoRadius = "georadius"
+)
+
+const (
+	ConfigKeyNetwork  = "network"
+	ConfigKeyAddress  = "address"
+	ConfigKeyPassword = "password"
+	ConfigKeyDB       = "db"
+	ConfigKeyCommand  = "command"
+	ConfigKeyLayers   = "layers"
+
+	ConfigKeyLayerName           = "name"
+	ConfigKeyKey                 = "key"
+	ConfigKeyAttributes          = "attributes"
+	ConfigKeyAttributesKeyPrefix = "attributes_key_prefix"
+	ConfigKeyMemberFieldname     = "member_fieldname"
+	ConfigKeyMaxFeatures         = "max_features"
+)
+
+const (
+	DefaultNetwork         = "tcp"
+	DefaultAddress         = "127.0.0.1:6379"
+	DefaultPassword        = ""
+	DefaultDB              = 0
+	DefaultCommand         = CommandGeoSearch
+	DefaultMemberFieldname = "member"
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// Provider serves the members of redis GEO sets as point features
+type Provider struct {
+	client  *redis.Client
+	command string
+	// map of layer name and corresponding GEO set
+	layers map[string]Layer
+}
+
+// providers are tracked so their connections can be closed during cleanup
+var (
+	providersLock sync.Mutex
+	providers     []*Provider
+)
+
+// NewTileProvider instantiates and returns a new redis provider or an error.
+// The function will validate the config and ping the redis server.
+//
+//	network (string): [Optional] the network type. defaults to "tcp"
+//	address (string): [Optional] the host and port of the redis server. defaults to "127.0.0.1:6379"
+//	password (string): [Optional] the redis password. defaults to ""
+//	db (int): [Optional] the redis database. defaults to 0
+//	command (string): [Optional] the command used to query GEO sets, "geosearch" or "georadius". defaults to "geosearch"
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		key (string): [Required] the key of the GEO set
+//		attributes (string): [Optional] where feature attributes are read from, "none", "hash" or "json". defaults to "none"
+//		attributes_key_prefix (string): [Optional] prepended to the member to build the attributes key. defaults to "<key>:"
+//		member_fieldname (string): [Optional] the tag the member name is encoded in. defaults to "member"
+//		max_features (int): [Optional] the maximum number of members returned per tile. defaults to 0 (unlimited)
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	network, err := config.String(ConfigKeyNetwork, ptrString(DefaultNetwork))
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := config.String(ConfigKeyAddress, ptrString(DefaultAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := config.String(ConfigKeyPassword, ptrString(DefaultPassword))
+	if err != nil {
+		return nil, err
+	}
+
+	defaultDB := DefaultDB
+	db, err := config.Int(ConfigKeyDB, &defaultDB)
+	if err != nil {
+		return nil, err
+	}
+
+	command, err := config.String(ConfigKeyCommand, ptrString(DefaultCommand))
+	if err != nil {
+		return nil, err
+	}
+	switch command = strings.ToLower(command); command {
+	case CommandGeoSearch, CommandGeoRadius:
+	default:
+		return nil, ErrInvalidCommand{Command: command}
+	}
+
+	p := Provider{
+		command: command,
+		layers:  map[string]Layer{},
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	p.client = redis.NewClient(&redis.Options{
+		Network:     network,
+		Addr:        addr,
+		Password:    password,
+		DB:          db,
+		DialTimeout: 3 * time.Second,
+	})
+
+	pong, err := p.client.Ping().Result()
+	if err != nil {
+		p.client.Close()
+		return nil, err
+	}
+	if pong != "PONG" {
+		p.client.Close()
+		return nil, fmt.Errorf("redis did not respond with 'PONG', '%s'", pong)
+	}
+
+	providersLock.Lock()
+	providers = append(providers, &p)
+	providersLock.Unlock()
+
+	return &p, nil
+}
+
+// AddLayer adds a GEO set layer to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	key, err := layerConf.String(ConfigKeyKey, ptrString(""))
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyKey, err)
+	}
+	if key == "" {
+		return ErrMissingLayerKey{LayerName: name}
+	}
+
+	attributes, err := layerConf.String(ConfigKeyAttributes, ptrString(AttributesNone))
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyAttributes, err)
+	}
+	switch attributes = strings.ToLower(attributes); attributes {
+	case AttributesNone, AttributesHash, AttributesJSON:
+	default:
+		return ErrInvalidAttributes{LayerName: name, Attributes: attributes}
+	}
+
+	prefix, err := layerConf.String(ConfigKeyAttributesKeyPrefix, ptrString(key+":"))
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyAttributesKeyPrefix, err)
+	}
+
+	memberFieldname, err := layerConf.String(ConfigKeyMemberFieldname, ptrString(DefaultMemberFieldname))
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyMemberFieldname, err)
+	}
+
+	var defaultMaxFeatures uint
+	maxFeatures, err := layerConf.Uint(ConfigKeyMaxFeatures, &defaultMaxFeatures)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyMaxFeatures, err)
+	}
+
+	p.layers[name] = Layer{
+		name:                name,
+		key:                 key,
+		attributes:          attributes,
+		attributesKeyPrefix: prefix,
+		memberFieldname:     memberFieldname,
+		maxFeatures:         maxFeatures,
+	}
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent GEO sets can only hold positions within the web mercator bounds
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112878, 180.0, 85.05112878}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures streams the members of the layer's GEO set within the tile's buffered extent
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	ext, tileSRID := tile.BufferedExtent()
+	bbox, err := lonLatExtent(ext, tileSRID)
+	if err != nil {
+		return err
+	}
+
+	members, err := p.search(layer, bbox)
+	if err != nil {
+		return fmt.Errorf("redis: layer (%v) %v: %v", lyrID, p.command, err)
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	attrs, err := p.attributes(layer, members)
+	if err != nil {
+		return fmt.Errorf("redis: layer (%v) attributes: %v", lyrID, err)
+	}
+
+	for i, m := range members {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		tags := attrs[i]
+		if tags == nil {
+			tags = make(map[string]interface{}, 1)
+		}
+		tags[layer.memberFieldname] = m.name
+
+		f := provider.Feature{
+			ID:       provider.FeatureID(m.name),
+			Geometry: geom.Point{m.lon, m.lat},
+			SRID:     srid,
+			Tags:     tags,
+		}
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Ping checks the redis server responds
+func (p *Provider) Ping(ctx context.Context) error {
+	return p.client.WithContext(ctx).Ping().Err()
+}
+
+// Close closes the redis connection
+func (p *Provider) Close() error {
+	return p.client.Close()
+}
+
+// Cleanup will close all the redis connections and remove the providers from the list
+func Cleanup() {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if len(providers) > 0 {
+		log.Infof("cleaning up redis providers")
+	}
+
+	for i := range providers {
+		if err := providers[i].Close(); err != nil {
+			log.Errorf("err closing connection: %v", err)
+		}
+	}
+
+	providers = nil
+}
+
+func ptrString(s string) *string { return &s }
@@ -0,0 +1,106 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+
+	goredis "github.com/go-redis/redis"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/ttools"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/redis"
+)
+
+// TESTENV is the environment variable that must be set to "yes" to run the redis tests.
+const TESTENV = "RUN_REDIS_TESTS"
+
+// TestTileFeatures will run tests against a local redis (6.2+) instance
+// on 127.0.0.1:6379
+func TestTileFeatures(t *testing.T) {
+	ttools.ShouldSkip(t, TESTENV)
+
+	client := goredis.NewClient(&goredis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+
+	const key = "tegola:test:vehicles"
+	client.Del(key, key+":bus-1")
+	defer client.Del(key, key+":bus-1")
+
+	if err := client.GeoAdd(key,
+		&goredis.GeoLocation{Name: "bus-1", Longitude: -122.4194, Latitude: 37.7749},
+		&goredis.GeoLocation{Name: "2", Longitude: 13.405, Latitude: 52.52},
+	).Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.HSet(key+":bus-1", "route", "42").Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type tcase struct {
+		command  string
+		tile     provider.Tile
+		expected map[string]string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			p, err := redis.NewTileProvider(dict.Dict{
+				"command": tc.command,
+				"layers": []map[string]interface{}{
+					{
+						"name":       "vehicles",
+						"key":        key,
+						"attributes": "hash",
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := map[string]string{}
+			err = p.TileFeatures(context.Background(), "vehicles", tc.tile, func(f *provider.Feature) error {
+				route, _ := f.Tags["route"].(string)
+				got[f.Tags["member"].(string)] = route
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %v features got %v", len(tc.expected), len(got))
+			}
+			for k, v := range tc.expected {
+				if got[k] != v {
+					t.Errorf("member (%v) expected route %q got %q", k, v, got[k])
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"geosearch world": {
+			command:  "geosearch",
+			tile:     provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: map[string]string{"bus-1": "42", "2": ""},
+		},
+		"georadius world": {
+			command:  "georadius",
+			tile:     provider.NewTile(0, 0, 0, 64, tegola.WebMercator),
+			expected: map[string]string{"bus-1": "42", "2": ""},
+		},
+		"geosearch san francisco": {
+			command:  "geosearch",
+			tile:     provider.NewTile(10, 163, 395, 64, tegola.WebMercator),
+			expected: map[string]string{"bus-1": "42"},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,100 @@
+package remotefile
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-spatial/tegola/internal/remote"
+)
+
+var (
+	ErrMissingURL       = errors.New("remotefile: provider is missing 'url'")
+	ErrMissingLayerName = errors.New("remotefile: layer is missing 'name'")
+	// ErrRangeUnsupported is returned when a server ignores the Range header of a request
+	ErrRangeUnsupported = errors.New("remotefile: server does not support range requests")
+)
+
+type ErrUnsupportedFormat struct {
+	Format string
+}
+
+func (e ErrUnsupportedFormat) Error() string {
+	return fmt.Sprintf("remotefile: unsupported format (%v), expected pmtiles for mvt_remotefile providers or one of: gpkg, flatgeobuf", e.Format)
+}
+
+type ErrUnsupportedScheme struct {
+	URL string
+}
+
+func (e ErrUnsupportedScheme) Error() string {
+	return fmt.Sprintf("remotefile: unsupported url (%v), expected an http://, https://, s3://, gs:// or file:// url", e.URL)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("remotefile: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("remotefile: layer (%v) not found", e.LayerName)
+}
+
+// ErrTableNotFound is returned when a GeoPackage has no feature table with the name
+type ErrTableNotFound struct {
+	URL   string
+	Table string
+}
+
+func (e ErrTableNotFound) Error() string {
+	return fmt.Sprintf("remotefile: (%v) has no feature table (%v)", e.URL, e.Table)
+}
+
+type ErrUnsupportedSRID struct {
+	LayerName string
+	SRID      uint64
+}
+
+func (e ErrUnsupportedSRID) Error() string {
+	return fmt.Sprintf("remotefile: layer (%v) has an unsupported SRID (%v), expected 4326 or 3857", e.LayerName, e.SRID)
+}
+
+// ErrStatus is returned when the remote server responds with an unexpected status
+type ErrStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("remotefile: request (%v) responded with status %v", e.URL, e.Status)
+}
+
+// ErrInvalidArchive is returned when the file is not a valid archive of the configured format
+type ErrInvalidArchive struct {
+	URL    string
+	Reason string
+}
+
+func (e ErrInvalidArchive) Error() string {
+	return fmt.Sprintf("remotefile: (%v) is not a valid archive: %v", e.URL, e.Reason)
+}
+
+// remoteError returns the provider's error for an error of the remote reader
+func remoteError(err error) error {
+	switch e := err.(type) {
+	case remote.ErrStatus:
+		return ErrStatus{URL: e.URL, Status: e.Status}
+	case remote.ErrUnsupportedScheme:
+		return ErrUnsupportedScheme{URL: e.URL}
+	}
+	if err == remote.ErrRangeUnsupported {
+		return ErrRangeUnsupported
+	}
+	return err
+}
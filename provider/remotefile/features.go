@@ -0,0 +1,195 @@
+package remotefile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// the geometry type names of the FlatGeobuf geometry types
+var fgbGeometryTypes = map[uint8]string{
+	fgbPoint:           "point",
+	fgbLineString:      "linestring",
+	fgbPolygon:         "polygon",
+	fgbMultiPoint:      "multipoint",
+	fgbMultiLineString: "multilinestring",
+	fgbMultiPolygon:    "multipolygon",
+}
+
+// featureFile streams the features of a layer within an extent
+type featureFile interface {
+	features(ctx context.Context, l FeatureLayer, ext *geom.Extent, fn func(f *provider.Feature) error) error
+}
+
+// FeatureProvider reads the features of a remote GeoPackage or FlatGeobuf file
+type FeatureProvider struct {
+	url     string
+	timeout time.Duration
+	file    featureFile
+	// the file as a GeoPackage, whose tables are looked up when layers are added, or as a
+	// FlatGeobuf file. Only one is set
+	gpkg *gpkgFile
+	fgb  *fgbFile
+
+	layers map[string]FeatureLayer
+}
+
+// NewTileProvider instantiates and returns a new remotefile provider of features or an error.
+// The file's header (and the GeoPackage's schema) are read when the provider is created.
+//
+//	url (string): [Required] the file's http://, https://, s3://bucket/key, gs://bucket/key or file:// url
+//	format (string): [Optional] "gpkg" or "flatgeobuf". defaults to the format of the url's extension (.gpkg or .fgb)
+//	headers, timeout, block_size, cache_size_mb, region, ... : [Optional] the connection options of the mvt provider
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		source_layer (string): [Optional] the GeoPackage feature table of the layer. defaults to name
+//		id_fieldname (string): [Optional] the column of the feature ids. defaults to the table's primary key or the feature's position
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	f, err := newFile(config, "")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+
+	p := FeatureProvider{
+		url:     f.url,
+		timeout: f.timeout,
+		layers:  map[string]FeatureLayer{},
+	}
+	switch f.format {
+	case FormatGeoPackage:
+		if p.gpkg, err = openGeoPackage(ctx, f.url, f.r); err != nil {
+			return nil, remoteError(err)
+		}
+		p.file = p.gpkg
+	case FormatFlatGeobuf:
+		if p.fgb, err = openFlatGeobuf(ctx, f.url, f.r); err != nil {
+			return nil, remoteError(err)
+		}
+		p.file = p.fgb
+	default:
+		return nil, ErrUnsupportedFormat{Format: f.format}
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// AddLayer adds a layer of a GeoPackage feature table or of the FlatGeobuf file to the provider
+func (p *FeatureProvider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	empty := ""
+	idField, err := layerConf.String(ConfigKeyIDField, &empty)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyIDField, err)
+	}
+
+	l := FeatureLayer{
+		name:    name,
+		idField: idField,
+	}
+
+	var geomType string
+	if p.gpkg != nil {
+		table, err := layerConf.String(ConfigKeySourceName, &name)
+		if err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeySourceName, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+		defer cancel()
+		if l.table, err = p.gpkg.featureTable(ctx, table); err != nil {
+			return remoteError(err)
+		}
+		l.srid, geomType = l.table.srid, l.table.geomType
+	} else {
+		l.srid, geomType = p.fgb.header.srid, fgbGeometryTypes[p.fgb.header.geomType]
+	}
+
+	if l.srid != tegola.WGS84 && l.srid != tegola.WebMercator {
+		return ErrUnsupportedSRID{LayerName: name, SRID: l.srid}
+	}
+	// generic geometry types (i.e. GEOMETRY) are reported as unknown
+	l.geomType, _ = provider.GeometryType(geomType)
+
+	p.layers[name] = l
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *FeatureProvider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *FeatureProvider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent returns the extent of the world, the files' extents aren't read
+func (p *FeatureProvider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -provider.MaxLat, 180.0, provider.MaxLat}, nil
+}
+
+// LayerMinZoom returns 0, features are served at every zoom
+func (p *FeatureProvider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom returns the max zoom of tegola
+func (p *FeatureProvider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures sends the features of the layer within the tile's buffered extent to fn
+func (p *FeatureProvider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	l, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	ext, err := provider.QueryExtent(tile, l.srid)
+	if err != nil {
+		return err
+	}
+
+	if err := p.file.features(ctx, l, ext, fn); err != nil {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+		return remoteError(err)
+	}
+	return nil
+}
@@ -0,0 +1,425 @@
+package remotefile_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/remotefile"
+)
+
+// fbTable, fbVector and fbTables are written by fbWrite as a flatbuffer. Table fields are
+// nil when they're not set, []byte for scalars or one of the types for references.
+type fbTable []interface{}
+
+type fbVector struct {
+	n    int
+	data []byte
+}
+
+type fbTables []fbTable
+
+func fbString(s string) fbVector { return fbVector{n: len(s), data: []byte(s)} }
+
+func fbFloat64s(vs ...float64) fbVector {
+	v := fbVector{n: len(vs)}
+	for _, f := range vs {
+		v.data = le(v.data, math.Float64bits(f), 8)
+	}
+	return v
+}
+
+// le appends the little endian bytes of v
+func le(b []byte, v uint64, size int) []byte {
+	for i := 0; i < size; i++ {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
+
+// fbWrite appends the object to buf, children after their parents as offsets are unsigned
+func fbWrite(buf []byte, obj interface{}) ([]byte, int) {
+	var refs []struct {
+		at  int
+		obj interface{}
+	}
+	ref := func(at int, obj interface{}) {
+		refs = append(refs, struct {
+			at  int
+			obj interface{}
+		}{at, obj})
+	}
+
+	var pos int
+	switch o := obj.(type) {
+	case fbTable:
+		// the vtable comes first, the table refers to it with a negative offset
+		vt := len(buf)
+		buf = le(buf, uint64(4+2*len(o)), 2)
+		buf = le(buf, 0, 2)
+		for range o {
+			buf = le(buf, 0, 2)
+		}
+
+		pos = len(buf)
+		buf = le(buf, uint64(pos-vt), 4)
+		for i, f := range o {
+			if f == nil {
+				continue
+			}
+			binary.LittleEndian.PutUint16(buf[vt+4+2*i:], uint16(len(buf)-pos))
+			if b, ok := f.([]byte); ok {
+				buf = append(buf, b...)
+				continue
+			}
+			ref(len(buf), f)
+			buf = le(buf, 0, 4)
+		}
+		binary.LittleEndian.PutUint16(buf[vt+2:], uint16(len(buf)-pos))
+	case fbVector:
+		pos = len(buf)
+		buf = le(buf, uint64(o.n), 4)
+		buf = append(buf, o.data...)
+	case fbTables:
+		pos = len(buf)
+		buf = le(buf, uint64(len(o)), 4)
+		for _, t := range o {
+			ref(len(buf), t)
+			buf = le(buf, 0, 4)
+		}
+	}
+
+	for _, r := range refs {
+		var child int
+		buf, child = fbWrite(buf, r.obj)
+		binary.LittleEndian.PutUint32(buf[r.at:], uint32(child-r.at))
+	}
+	return buf, pos
+}
+
+// fbRoot returns the flatbuffer of the table prefixed by its size
+func fbRoot(t fbTable) []byte {
+	b, pos := fbWrite(make([]byte, 4), t)
+	binary.LittleEndian.PutUint32(b, uint32(pos))
+	return append(le(nil, uint64(len(b)), 4), b...)
+}
+
+type fgbFeature struct {
+	geomType byte
+	xy       []float64
+	ends     []uint32
+	name     string
+	pop      int32
+}
+
+// flatGeobuf returns a FlatGeobuf file of the features with a name (string) and a pop (int)
+// column. The index is written when nodeSize isn't 0.
+func flatGeobuf(features []fgbFeature, nodeSize uint16, srid int32) []byte {
+	u8 := func(v uint8) []byte { return []byte{v} }
+
+	columns := fbTables{
+		{fbString("name"), u8(11)},
+		{fbString("pop"), u8(5)},
+	}
+	header := fbRoot(fbTable{
+		fbString("test"),
+		nil,
+		u8(0), // unknown, the features have their own types
+		nil, nil, nil, nil,
+		columns,
+		le(nil, uint64(len(features)), 8),
+		le(nil, uint64(nodeSize), 2),
+		fbTable{nil, le(nil, uint64(srid), 4)},
+	})
+
+	var data []byte
+	var leaves [][5]float64
+	for _, f := range features {
+		var ends interface{}
+		if f.ends != nil {
+			var b []byte
+			for _, e := range f.ends {
+				b = le(b, uint64(e), 4)
+			}
+			ends = fbVector{n: len(f.ends), data: b}
+		}
+		props := le(le(nil, 0, 2), uint64(len(f.name)), 4)
+		props = append(props, f.name...)
+		props = le(le(props, 1, 2), uint64(uint32(f.pop)), 4)
+
+		ext := geom.Extent{math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+		for i := 0; i < len(f.xy); i += 2 {
+			ext.AddPoints([2]float64{f.xy[i], f.xy[i+1]})
+		}
+		leaves = append(leaves, [5]float64{ext[0], ext[1], ext[2], ext[3], float64(len(data))})
+
+		data = append(data, fbRoot(fbTable{
+			fbTable{ends, fbFloat64s(f.xy...), nil, nil, nil, nil, u8(f.geomType)},
+			fbVector{n: len(props), data: props},
+		})...)
+	}
+
+	b := append([]byte("fgb\x03fgb\x00"), header...)
+	if nodeSize > 0 {
+		// a single root node is enough for the test's features
+		node := func(ext [5]float64) {
+			for _, v := range ext[:4] {
+				b = le(b, math.Float64bits(v), 8)
+			}
+			b = le(b, uint64(ext[4]), 8)
+		}
+		root := [5]float64{math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64, 1}
+		for _, l := range leaves {
+			root[0], root[1] = math.Min(root[0], l[0]), math.Min(root[1], l[1])
+			root[2], root[3] = math.Max(root[2], l[2]), math.Max(root[3], l[3])
+		}
+		node(root)
+		for _, l := range leaves {
+			node(l)
+		}
+	}
+	return append(b, data...)
+}
+
+var fgbFeatures = []fgbFeature{
+	{geomType: 1, xy: []float64{10, 10}, name: "point", pop: 5},
+	{geomType: 2, xy: []float64{-100, -50, -90, -40}, name: "line", pop: -2},
+	{geomType: 3, xy: []float64{100, 50, 110, 50, 110, 60, 100, 50}, ends: []uint32{4}, name: "polygon", pop: 7},
+}
+
+func TestTileFeaturesFlatGeobuf(t *testing.T) {
+	type tcase struct {
+		nodeSize uint16
+		z, x, y  uint
+		expected map[uint64]geom.Geometry
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			b := flatGeobuf(fgbFeatures, tc.nodeSize, tegola.WGS84)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.ServeContent(w, r, "test.fgb", time.Time{}, bytes.NewReader(b))
+			}))
+			defer srv.Close()
+
+			p, err := remotefile.NewTileProvider(dict.Dict{
+				"url":        srv.URL + "/test.fgb",
+				"block_size": 64,
+				"layers":     []map[string]interface{}{{"name": "places"}},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := map[uint64]geom.Geometry{}
+			tile := provider.NewTile(tc.z, tc.x, tc.y, 64, tegola.WebMercator)
+			err = p.TileFeatures(context.Background(), "places", tile, func(f *provider.Feature) error {
+				if f.SRID != tegola.WGS84 {
+					t.Errorf("expected srid %v got %v", tegola.WGS84, f.SRID)
+				}
+				if _, ok := f.Tags["name"].(string); !ok {
+					t.Errorf("expected a name tag got %v", f.Tags)
+				}
+				if _, ok := f.Tags["pop"].(int64); !ok {
+					t.Errorf("expected a pop tag got %v", f.Tags)
+				}
+				got[f.ID] = f.Geometry
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected features %v got %v", tc.expected, got)
+			}
+			for id, g := range tc.expected {
+				if !reflect.DeepEqual(got[id], g) {
+					t.Errorf("feature %v, expected %v got %v", id, g, got[id])
+				}
+			}
+		}
+	}
+
+	ne := map[uint64]geom.Geometry{
+		1: geom.Point{10, 10},
+		3: geom.Polygon{{{100, 50}, {110, 50}, {110, 60}, {100, 50}}},
+	}
+	tests := map[string]tcase{
+		"index world": {
+			nodeSize: 16,
+			expected: map[uint64]geom.Geometry{
+				1: geom.Point{10, 10},
+				2: geom.LineString{{-100, -50}, {-90, -40}},
+				3: ne[3],
+			},
+		},
+		"index north east": {
+			nodeSize: 16,
+			z:        1,
+			x:        1,
+			expected: ne,
+		},
+		"no index north east": {
+			z:        1,
+			x:        1,
+			expected: ne,
+		},
+		"no index south east": {
+			z: 1,
+			x: 1,
+			y: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestTileFeaturesGeoPackage(t *testing.T) {
+	b, err := ioutil.ReadFile("../gpkg/testdata/athens-osm-20170921.gpkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "athens.gpkg", time.Time{}, bytes.NewReader(b))
+	}))
+	defer srv.Close()
+
+	type tcase struct {
+		layer string
+		// the number of features and the sum of their ids, as returned by the table's rtree
+		count int
+		idSum uint64
+	}
+
+	p, err := remotefile.NewTileProvider(dict.Dict{
+		"url": srv.URL + "/athens.gpkg",
+		"layers": []map[string]interface{}{
+			{"name": "amenities_points"},
+			{"name": "roads", "source_layer": "roads_lines"},
+			{"name": "boundary"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			var count int
+			var idSum uint64
+			tile := provider.NewTile(13, 4635, 3162, 64, tegola.WebMercator)
+			err := p.TileFeatures(context.Background(), tc.layer, tile, func(f *provider.Feature) error {
+				count++
+				idSum += f.ID
+				if _, ok := f.Tags["fid"]; ok {
+					t.Errorf("expected the primary key not to be a tag")
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if count != tc.count || idSum != tc.idSum {
+				t.Errorf("expected %v features with an id sum of %v got %v, %v", tc.count, tc.idSum, count, idSum)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"points": {
+			layer: "amenities_points",
+			count: 216,
+			idSum: 85710,
+		},
+		"lines": {
+			layer: "roads",
+			count: 1313,
+			idSum: 5085243,
+		},
+		"multipolygon": {
+			layer: "boundary",
+			count: 1,
+			idSum: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remotefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "lambert.fgb")
+	if err := ioutil.WriteFile(path, flatGeobuf(fgbFeatures, 16, 2154), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type tcase struct {
+		config      dict.Dict
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := remotefile.NewTileProvider(tc.config)
+			if err != tc.expectedErr {
+				t.Errorf("expected err %v got %v", tc.expectedErr, err)
+			}
+		}
+	}
+
+	athens := "../gpkg/testdata/athens-osm-20170921.gpkg"
+	tests := map[string]tcase{
+		"missing url": {
+			config:      dict.Dict{"url": ""},
+			expectedErr: remotefile.ErrMissingURL,
+		},
+		"unsupported format": {
+			config:      dict.Dict{"url": athens, "format": "pmtiles"},
+			expectedErr: remotefile.ErrUnsupportedFormat{Format: "pmtiles"},
+		},
+		"missing table": {
+			config: dict.Dict{
+				"url":    athens,
+				"layers": []map[string]interface{}{{"name": "parks"}},
+			},
+			expectedErr: remotefile.ErrTableNotFound{URL: athens, Table: "parks"},
+		},
+		"unsupported srid": {
+			config: dict.Dict{
+				"url":    "file://" + path,
+				"layers": []map[string]interface{}{{"name": "places"}},
+			},
+			expectedErr: remotefile.ErrUnsupportedSRID{LayerName: "places", SRID: 2154},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,592 @@
+package remotefile
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/internal/remote"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// FlatGeobuf (v3) files. ref: https://github.com/flatgeobuf/flatgeobuf/tree/master/src/fbs
+
+var fgbMagic = []byte{'f', 'g', 'b', 3}
+
+const (
+	// the magic bytes and the size of the header
+	fgbPreludeLength = 12
+	// the size of an entry of the packed Hilbert R-tree: its extent and offset
+	fgbNodeLength = 40
+	// the node size of files without the index_node_size field
+	fgbDefaultNodeSize = 16
+)
+
+// geometry types
+const (
+	fgbUnknown         = 0
+	fgbPoint           = 1
+	fgbLineString      = 2
+	fgbPolygon         = 3
+	fgbMultiPoint      = 4
+	fgbMultiLineString = 5
+	fgbMultiPolygon    = 6
+)
+
+// column types
+const (
+	fgbByte = iota
+	fgbUByte
+	fgbBool
+	fgbShort
+	fgbUShort
+	fgbInt
+	fgbUInt
+	fgbLong
+	fgbULong
+	fgbFloat
+	fgbDouble
+	fgbString
+	fgbJSON
+	fgbDateTime
+	fgbBinary
+)
+
+// fbTable is a table of a flatbuffer. Fields are looked up with their id, the position of the
+// field in the schema. Malformed buffers panic, which the decoders recover from.
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+// fbRoot returns the root table of the buffer
+func fbRoot(buf []byte) fbTable {
+	return fbTable{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+// field returns the position of the field, 0 if it's not set
+func (t fbTable) field(id int) int {
+	vt := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*id >= int(binary.LittleEndian.Uint16(t.buf[vt:])) {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(t.buf[vt+4+2*id:]))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t fbTable) uint8(id int, def uint8) uint8 {
+	if p := t.field(id); p != 0 {
+		return t.buf[p]
+	}
+	return def
+}
+
+func (t fbTable) uint16(id int, def uint16) uint16 {
+	if p := t.field(id); p != 0 {
+		return binary.LittleEndian.Uint16(t.buf[p:])
+	}
+	return def
+}
+
+func (t fbTable) int32(id int, def int32) int32 {
+	if p := t.field(id); p != 0 {
+		return int32(binary.LittleEndian.Uint32(t.buf[p:]))
+	}
+	return def
+}
+
+func (t fbTable) uint64(id int, def uint64) uint64 {
+	if p := t.field(id); p != 0 {
+		return binary.LittleEndian.Uint64(t.buf[p:])
+	}
+	return def
+}
+
+// indirect returns the position of the object the offset at p refers to
+func (t fbTable) indirect(p int) int {
+	return p + int(binary.LittleEndian.Uint32(t.buf[p:]))
+}
+
+// vector returns the position of the first element and the length of a vector field
+func (t fbTable) vector(id int) (int, int) {
+	p := t.field(id)
+	if p == 0 {
+		return 0, 0
+	}
+	p = t.indirect(p)
+	return p + 4, int(binary.LittleEndian.Uint32(t.buf[p:]))
+}
+
+func (t fbTable) bytes(id int) []byte {
+	start, n := t.vector(id)
+	return t.buf[start : start+n]
+}
+
+func (t fbTable) string(id int) string {
+	return string(t.bytes(id))
+}
+
+func (t fbTable) table(id int) (fbTable, bool) {
+	p := t.field(id)
+	if p == 0 {
+		return fbTable{}, false
+	}
+	return fbTable{buf: t.buf, pos: t.indirect(p)}, true
+}
+
+// tables returns the tables of a vector field
+func (t fbTable) tables(id int) []fbTable {
+	start, n := t.vector(id)
+	ts := make([]fbTable, n)
+	for i := range ts {
+		ts[i] = fbTable{buf: t.buf, pos: t.indirect(start + 4*i)}
+	}
+	return ts
+}
+
+func (t fbTable) float64s(id int) []float64 {
+	start, n := t.vector(id)
+	vs := make([]float64, n)
+	for i := range vs {
+		vs[i] = math.Float64frombits(binary.LittleEndian.Uint64(t.buf[start+8*i:]))
+	}
+	return vs
+}
+
+func (t fbTable) uint32s(id int) []uint32 {
+	start, n := t.vector(id)
+	vs := make([]uint32, n)
+	for i := range vs {
+		vs[i] = binary.LittleEndian.Uint32(t.buf[start+4*i:])
+	}
+	return vs
+}
+
+// fbDecode calls fn, returning an error when fn reads past the end of a malformed buffer
+func fbDecode(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed flatbuffer: %v", r)
+		}
+	}()
+	return fn()
+}
+
+type fgbColumn struct {
+	name string
+	typ  uint8
+}
+
+type fgbHeader struct {
+	geomType      uint8
+	hasZ, hasM    bool
+	hasT, hasTM   bool
+	columns       []fgbColumn
+	featuresCount uint64
+	indexNodeSize uint16
+	srid          uint64
+}
+
+// parseFGBHeader decodes the header flatbuffer
+func parseFGBHeader(b []byte) (fgbHeader, error) {
+	var h fgbHeader
+	err := fbDecode(func() error {
+		t := fbRoot(b)
+		h.geomType = t.uint8(2, fgbUnknown)
+		h.hasZ, h.hasM = t.uint8(3, 0) != 0, t.uint8(4, 0) != 0
+		h.hasT, h.hasTM = t.uint8(5, 0) != 0, t.uint8(6, 0) != 0
+		h.columns = fgbColumns(t.tables(7))
+		h.featuresCount = t.uint64(8, 0)
+		h.indexNodeSize = t.uint16(9, fgbDefaultNodeSize)
+
+		// files without a crs are WGS84
+		h.srid = tegola.WGS84
+		if crs, ok := t.table(10); ok {
+			if code := crs.int32(1, 0); code > 0 {
+				h.srid = uint64(code)
+			}
+		}
+		return nil
+	})
+	return h, err
+}
+
+func fgbColumns(ts []fbTable) []fgbColumn {
+	cols := make([]fgbColumn, len(ts))
+	for i, t := range ts {
+		cols[i] = fgbColumn{name: t.string(0), typ: t.uint8(1, fgbByte)}
+	}
+	return cols
+}
+
+// fgbLevelBounds returns the ranges of node indexes of each level of the packed Hilbert R-tree,
+// leaves first, and the number of nodes
+func fgbLevelBounds(numItems uint64, nodeSize uint16) ([][2]uint64, uint64) {
+	n, numNodes := numItems, numItems
+	levelNumNodes := []uint64{n}
+	for {
+		n = (n + uint64(nodeSize) - 1) / uint64(nodeSize)
+		numNodes += n
+		levelNumNodes = append(levelNumNodes, n)
+		if n == 1 {
+			break
+		}
+	}
+
+	bounds := make([][2]uint64, len(levelNumNodes))
+	n = numNodes
+	for i, size := range levelNumNodes {
+		bounds[i] = [2]uint64{n - size, n}
+		n -= size
+	}
+	return bounds, numNodes
+}
+
+// fgbFile reads the features of a FlatGeobuf file
+type fgbFile struct {
+	url    string
+	r      *remote.Reader
+	header fgbHeader
+
+	// the offsets of the index and the features. The index is empty for files without one
+	indexOffset, featuresOffset int64
+	levels                      [][2]uint64
+	numNodes                    uint64
+}
+
+func openFlatGeobuf(ctx context.Context, url string, r *remote.Reader) (*fgbFile, error) {
+	b, err := r.ReadRange(ctx, 0, fgbPreludeLength)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < fgbPreludeLength || !bytes.Equal(b[:4], fgbMagic) {
+		return nil, ErrInvalidArchive{URL: url, Reason: "missing FlatGeobuf v3 magic bytes"}
+	}
+
+	headerLength := int64(binary.LittleEndian.Uint32(b[8:]))
+	if b, err = r.ReadRange(ctx, fgbPreludeLength, headerLength); err != nil {
+		return nil, err
+	}
+	if int64(len(b)) < headerLength {
+		return nil, ErrInvalidArchive{URL: url, Reason: "truncated header"}
+	}
+	h, err := parseFGBHeader(b)
+	if err != nil {
+		return nil, ErrInvalidArchive{URL: url, Reason: err.Error()}
+	}
+	if h.indexNodeSize == 1 {
+		return nil, ErrInvalidArchive{URL: url, Reason: "index node size must be 0 or at least 2"}
+	}
+
+	f := fgbFile{
+		url:            url,
+		r:              r,
+		header:         h,
+		indexOffset:    fgbPreludeLength + headerLength,
+		featuresOffset: fgbPreludeLength + headerLength,
+	}
+	if h.indexNodeSize > 0 && h.featuresCount > 0 {
+		f.levels, f.numNodes = fgbLevelBounds(h.featuresCount, h.indexNodeSize)
+		f.featuresOffset += int64(f.numNodes * fgbNodeLength)
+	}
+
+	return &f, nil
+}
+
+// fgbHit is a feature of the index, its offset in the features and its position in the file
+type fgbHit struct {
+	offset uint64
+	index  uint64
+}
+
+// search returns the features of the index whose extents overlap the extent, in file order
+func (f *fgbFile) search(ctx context.Context, ext *geom.Extent) ([]fgbHit, error) {
+	type node struct {
+		index uint64
+		level int
+	}
+
+	nodeSize := uint64(f.header.indexNodeSize)
+	leaves := f.levels[0][0]
+
+	var hits []fgbHit
+	queue := []node{{index: 0, level: len(f.levels) - 1}}
+	for len(queue) > 0 {
+		if ctx.Err() != nil {
+			return nil, provider.ErrCanceled
+		}
+
+		n := queue[0]
+		queue = queue[1:]
+
+		end := n.index + nodeSize
+		if levelEnd := f.levels[n.level][1]; end > levelEnd {
+			end = levelEnd
+		}
+		b, err := f.r.ReadRange(ctx, f.indexOffset+int64(n.index*fgbNodeLength), int64((end-n.index)*fgbNodeLength))
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(b)) < (end-n.index)*fgbNodeLength {
+			return nil, ErrInvalidArchive{URL: f.url, Reason: "truncated index"}
+		}
+
+		for pos := n.index; pos < end; pos++ {
+			nb := b[(pos-n.index)*fgbNodeLength:]
+			f64 := func(i int) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(nb[i:])) }
+			nodeExt := geom.Extent{f64(0), f64(8), f64(16), f64(24)}
+			if !provider.ExtentsOverlap(&nodeExt, ext) {
+				continue
+			}
+
+			offset := binary.LittleEndian.Uint64(nb[32:])
+			if n.index >= leaves {
+				hits = append(hits, fgbHit{offset: offset, index: pos - leaves})
+				continue
+			}
+			queue = append(queue, node{index: offset, level: n.level - 1})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].offset < hits[j].offset })
+	return hits, nil
+}
+
+// feature reads the size prefixed feature at the offset of the features. nil is returned at
+// the end of the file.
+func (f *fgbFile) feature(ctx context.Context, offset uint64) ([]byte, error) {
+	b, err := f.r.ReadRange(ctx, f.featuresOffset+int64(offset), 4)
+	if err != nil || len(b) == 0 {
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, ErrInvalidArchive{URL: f.url, Reason: "truncated feature"}
+	}
+
+	length := int64(binary.LittleEndian.Uint32(b))
+	if b, err = f.r.ReadRange(ctx, f.featuresOffset+int64(offset)+4, length); err != nil {
+		return nil, err
+	}
+	if int64(len(b)) < length {
+		return nil, ErrInvalidArchive{URL: f.url, Reason: "truncated feature"}
+	}
+	return b, nil
+}
+
+// features streams the features overlapping the extent. Features are read through the index
+// when the file has one, otherwise every feature is read.
+func (f *fgbFile) features(ctx context.Context, l FeatureLayer, ext *geom.Extent, fn func(f *provider.Feature) error) error {
+	// features read without the index are filtered once their geometry is decoded
+	send := func(b []byte, index uint64, filter bool) error {
+		feat, err := f.decodeFeature(b, l)
+		if err != nil {
+			return ErrInvalidArchive{URL: f.url, Reason: fmt.Sprintf("feature %v: %v", index, err)}
+		}
+		if feat.Geometry == nil {
+			return nil
+		}
+		if filter {
+			fext, err := geom.NewExtentFromGeometry(feat.Geometry)
+			if err != nil || !provider.ExtentsOverlap(fext, ext) {
+				return nil
+			}
+		}
+		if feat.ID == 0 {
+			feat.ID = index + 1
+		}
+		return fn(feat)
+	}
+
+	if f.levels != nil {
+		hits, err := f.search(ctx, ext)
+		if err != nil {
+			return err
+		}
+		for _, hit := range hits {
+			if ctx.Err() != nil {
+				return provider.ErrCanceled
+			}
+			b, err := f.feature(ctx, hit.offset)
+			if err != nil {
+				return err
+			}
+			if err := send(b, hit.index, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var offset uint64
+	for index := uint64(0); f.header.featuresCount == 0 || index < f.header.featuresCount; index++ {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+		b, err := f.feature(ctx, offset)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return nil
+		}
+		offset += 4 + uint64(len(b))
+
+		if err := send(b, index, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFeature decodes the feature's geometry and properties. Features with empty or
+// unsupported geometries are returned without a geometry.
+func (f *fgbFile) decodeFeature(b []byte, l FeatureLayer) (*provider.Feature, error) {
+	feat := provider.Feature{SRID: f.header.srid, Tags: map[string]interface{}{}}
+	err := fbDecode(func() error {
+		t := fbRoot(b)
+		if g, ok := t.table(0); ok {
+			feat.Geometry = fgbGeometry(g, f.header.geomType)
+		}
+
+		columns := f.header.columns
+		if ts := t.tables(2); len(ts) > 0 {
+			columns = fgbColumns(ts)
+		}
+		return fgbProperties(t.bytes(1), columns, l, &feat)
+	})
+	return &feat, err
+}
+
+// fgbGeometry decodes the geometry table. Z and M values are dropped. nil is returned for
+// empty geometries and unsupported geometry types.
+func fgbGeometry(t fbTable, geomType uint8) geom.Geometry {
+	if gt := t.uint8(6, fgbUnknown); gt != fgbUnknown {
+		geomType = gt
+	}
+
+	xy := t.float64s(1)
+	ends := t.uint32s(0)
+	points := func(from, to int) [][2]float64 {
+		pts := make([][2]float64, 0, to-from)
+		for i := from; i < to; i++ {
+			pts = append(pts, [2]float64{xy[2*i], xy[2*i+1]})
+		}
+		return pts
+	}
+	// rings returns the lines of the coordinates split at the ends
+	rings := func() [][][2]float64 {
+		if len(ends) == 0 {
+			return [][][2]float64{points(0, len(xy)/2)}
+		}
+		var lines [][][2]float64
+		start := 0
+		for _, end := range ends {
+			lines = append(lines, points(start, int(end)))
+			start = int(end)
+		}
+		return lines
+	}
+
+	if len(xy) < 2 && geomType != fgbMultiPolygon {
+		return nil
+	}
+
+	switch geomType {
+	case fgbPoint:
+		return geom.Point{xy[0], xy[1]}
+	case fgbMultiPoint:
+		return geom.MultiPoint(points(0, len(xy)/2))
+	case fgbLineString:
+		return geom.LineString(points(0, len(xy)/2))
+	case fgbMultiLineString:
+		var mls geom.MultiLineString
+		for _, line := range rings() {
+			mls = append(mls, line)
+		}
+		return mls
+	case fgbPolygon:
+		return geom.Polygon(rings())
+	case fgbMultiPolygon:
+		var mp geom.MultiPolygon
+		for _, part := range t.tables(7) {
+			if p, ok := fgbGeometry(part, fgbPolygon).(geom.Polygon); ok {
+				mp = append(mp, p)
+			}
+		}
+		if len(mp) == 0 {
+			return nil
+		}
+		return mp
+	default:
+		return nil
+	}
+}
+
+// fgbProperties decodes the properties of a feature into its tags. The value of the layer's id
+// field is the id of the feature. Binary values can't be encoded in a tile and are dropped.
+func fgbProperties(b []byte, columns []fgbColumn, l FeatureLayer, feat *provider.Feature) error {
+	for i := 0; i < len(b); {
+		col := int(binary.LittleEndian.Uint16(b[i:]))
+		i += 2
+		if col >= len(columns) {
+			return fmt.Errorf("invalid column %v", col)
+		}
+
+		var v interface{}
+		switch columns[col].typ {
+		case fgbByte:
+			v, i = int64(int8(b[i])), i+1
+		case fgbUByte:
+			v, i = uint64(b[i]), i+1
+		case fgbBool:
+			v, i = b[i] != 0, i+1
+		case fgbShort:
+			v, i = int64(int16(binary.LittleEndian.Uint16(b[i:]))), i+2
+		case fgbUShort:
+			v, i = uint64(binary.LittleEndian.Uint16(b[i:])), i+2
+		case fgbInt:
+			v, i = int64(int32(binary.LittleEndian.Uint32(b[i:]))), i+4
+		case fgbUInt:
+			v, i = uint64(binary.LittleEndian.Uint32(b[i:])), i+4
+		case fgbLong:
+			v, i = int64(binary.LittleEndian.Uint64(b[i:])), i+8
+		case fgbULong:
+			v, i = binary.LittleEndian.Uint64(b[i:]), i+8
+		case fgbFloat:
+			v, i = float64(math.Float32frombits(binary.LittleEndian.Uint32(b[i:]))), i+4
+		case fgbDouble:
+			v, i = math.Float64frombits(binary.LittleEndian.Uint64(b[i:])), i+8
+		case fgbString, fgbJSON, fgbDateTime, fgbBinary:
+			n := int(binary.LittleEndian.Uint32(b[i:]))
+			if columns[col].typ != fgbBinary {
+				v = string(b[i+4 : i+4+n])
+			}
+			i += 4 + n
+		default:
+			return fmt.Errorf("unsupported type %v of column (%v)", columns[col].typ, columns[col].name)
+		}
+
+		name := columns[col].name
+		if name == l.idField && l.idField != "" {
+			id, err := provider.ConvertFeatureID(v)
+			if err != nil {
+				return err
+			}
+			feat.ID = id
+			continue
+		}
+		if v != nil {
+			feat.Tags[name] = v
+		}
+	}
+	return nil
+}
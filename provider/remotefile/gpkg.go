@@ -0,0 +1,233 @@
+package remotefile
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkb"
+
+	"github.com/go-spatial/tegola/internal/remote"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// GeoPackage feature tables. ref: http://www.geopackage.org/spec/
+
+// the envelope lengths of the geometry blob header by the envelope indicator
+var gpkgEnvelopeLengths = []int{0, 32, 48, 48, 64}
+
+// gpkgFile reads the feature tables of a GeoPackage
+type gpkgFile struct {
+	db *sqliteDB
+}
+
+// gpkgTable is a feature table and its spatial index
+type gpkgTable struct {
+	table      sqliteTable
+	geomColumn int
+	geomType   string
+	srid       uint64
+	// the shadow table of the nodes of the table's rtree. nil without a spatial index
+	rtree *sqliteTable
+}
+
+func openGeoPackage(ctx context.Context, url string, r *remote.Reader) (*gpkgFile, error) {
+	db, err := openSQLite(ctx, url, r)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := db.table("gpkg_geometry_columns"); !ok {
+		return nil, ErrInvalidArchive{URL: url, Reason: "missing gpkg_geometry_columns table"}
+	}
+	return &gpkgFile{db: db}, nil
+}
+
+// featureTable returns the feature table with the name
+func (g *gpkgFile) featureTable(ctx context.Context, name string) (gpkgTable, error) {
+	t, ok := g.db.table(name)
+	if !ok {
+		return gpkgTable{}, ErrTableNotFound{URL: g.db.url, Table: name}
+	}
+
+	gt := gpkgTable{table: t, geomColumn: -1}
+	columns, _ := g.db.table("gpkg_geometry_columns")
+	var geomColumn string
+	err := g.db.scan(ctx, columns, func(row sqliteRow) error {
+		values := map[string]interface{}{}
+		for i, c := range columns.columns {
+			values[strings.ToLower(c)] = row.values[i]
+		}
+		if tn, _ := values["table_name"].(string); !strings.EqualFold(tn, name) {
+			return nil
+		}
+
+		geomColumn, _ = values["column_name"].(string)
+		gt.geomType, _ = values["geometry_type_name"].(string)
+		srid, _ := values["srs_id"].(int64)
+		gt.srid = uint64(srid)
+		return nil
+	})
+	if err != nil {
+		return gt, err
+	}
+	if gt.geomColumn = t.column(geomColumn); geomColumn == "" || gt.geomColumn < 0 {
+		return gt, ErrTableNotFound{URL: g.db.url, Table: name}
+	}
+
+	if rtree, ok := g.db.table(fmt.Sprintf("rtree_%v_%v_node", t.name, geomColumn)); ok {
+		gt.rtree = &rtree
+	}
+	return gt, nil
+}
+
+// search returns the sorted rowids of the features whose extents overlap the extent in the
+// table's rtree
+func (g *gpkgFile) search(ctx context.Context, t gpkgTable, ext *geom.Extent) ([]int64, error) {
+	var rowids []int64
+
+	var walk func(nodes []int64, depth int) error
+	walk = func(nodes []int64, depth int) error {
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+
+		var children []int64
+		err := g.db.lookup(ctx, *t.rtree, nodes, func(row sqliteRow) error {
+			data, _ := row.values[len(row.values)-1].([]byte)
+			if len(data) < 4 {
+				return g.db.invalid("rtree node %v is too short", row.rowid)
+			}
+			// the depth of the tree is stored in the root node
+			if row.rowid == 1 {
+				depth = int(binary.BigEndian.Uint16(data))
+			}
+
+			count := int(binary.BigEndian.Uint16(data[2:]))
+			if len(data) < 4+24*count {
+				return g.db.invalid("rtree node %v is too short", row.rowid)
+			}
+			for i := 0; i < count; i++ {
+				cell := data[4+24*i:]
+				f32 := func(i int) float64 { return float64(math.Float32frombits(binary.BigEndian.Uint32(cell[i:]))) }
+				cellExt := geom.Extent{f32(8), f32(16), f32(12), f32(20)}
+				if !provider.ExtentsOverlap(&cellExt, ext) {
+					continue
+				}
+
+				id := int64(binary.BigEndian.Uint64(cell))
+				if depth == 0 {
+					rowids = append(rowids, id)
+				} else {
+					children = append(children, id)
+				}
+			}
+			return nil
+		})
+		if err != nil || len(children) == 0 {
+			return err
+		}
+		if depth > sqliteMaxDepth {
+			return g.db.invalid("invalid rtree depth %v", depth)
+		}
+		return walk(children, depth-1)
+	}
+
+	if err := walk([]int64{1}, 0); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rowids, func(i, j int) bool { return rowids[i] < rowids[j] })
+	return rowids, nil
+}
+
+// features streams the features of the table overlapping the extent. Features are read through
+// the spatial index when the table has one, otherwise every feature is read.
+func (g *gpkgFile) features(ctx context.Context, l FeatureLayer, ext *geom.Extent, fn func(f *provider.Feature) error) error {
+	t := l.table
+	idColumn := -1
+	if l.idField != "" {
+		idColumn = t.table.column(l.idField)
+	}
+
+	send := func(row sqliteRow, filter bool) error {
+		blob, _ := row.values[t.geomColumn].([]byte)
+		geo, err := gpkgGeometry(blob)
+		if err != nil {
+			return g.db.invalid("table (%v) row %v: %v", t.table.name, row.rowid, err)
+		}
+		if geo == nil {
+			return nil
+		}
+		if filter {
+			gext, err := geom.NewExtentFromGeometry(geo)
+			if err != nil || !provider.ExtentsOverlap(gext, ext) {
+				return nil
+			}
+		}
+
+		feat := provider.Feature{
+			ID:       uint64(row.rowid),
+			Geometry: geo,
+			SRID:     t.srid,
+			Tags:     map[string]interface{}{},
+		}
+		for i, v := range row.values {
+			switch {
+			case i == t.geomColumn || i == t.table.rowidColumn:
+			case i == idColumn:
+				if feat.ID, err = provider.ConvertFeatureID(v); err != nil {
+					return err
+				}
+			default:
+				// blobs can't be encoded in a tile
+				if _, ok := v.([]byte); ok || v == nil {
+					continue
+				}
+				feat.Tags[t.table.columns[i]] = v
+			}
+		}
+		return fn(&feat)
+	}
+
+	if t.rtree == nil {
+		return g.db.scan(ctx, t.table, func(row sqliteRow) error {
+			return send(row, true)
+		})
+	}
+
+	rowids, err := g.search(ctx, t, ext)
+	if err != nil {
+		return err
+	}
+	return g.db.lookup(ctx, t.table, rowids, func(row sqliteRow) error {
+		return send(row, false)
+	})
+}
+
+// gpkgGeometry decodes a GeoPackage geometry blob. nil is returned for empty geometries.
+func gpkgGeometry(b []byte) (geom.Geometry, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	if len(b) < 8 || b[0] != 'G' || b[1] != 'P' {
+		return nil, fmt.Errorf("missing geometry blob magic")
+	}
+
+	flags := b[3]
+	// the empty geometry flag
+	if flags&0x10 != 0 {
+		return nil, nil
+	}
+	envelope := int(flags>>1) & 0x07
+	if envelope >= len(gpkgEnvelopeLengths) {
+		return nil, fmt.Errorf("invalid envelope indicator %v", envelope)
+	}
+	offset := 8 + gpkgEnvelopeLengths[envelope]
+	if len(b) < offset {
+		return nil, fmt.Errorf("truncated geometry blob")
+	}
+
+	return wkb.DecodeBytes(b[offset:])
+}
@@ -0,0 +1,32 @@
+package remotefile
+
+import (
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+)
+
+type Layer struct {
+	name string
+	// sourceLayer is the name of the layer within the archive's tiles
+	sourceLayer string
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return nil }
+func (l Layer) SRID() uint64            { return tegola.WebMercator }
+
+// FeatureLayer is a layer of a GeoPackage feature table or of a FlatGeobuf file
+type FeatureLayer struct {
+	name     string
+	idField  string
+	geomType geom.Geometry
+	srid     uint64
+	// the feature table of GeoPackage layers
+	table gpkgTable
+}
+
+func (l FeatureLayer) ID() string              { return l.name }
+func (l FeatureLayer) Name() string            { return l.name }
+func (l FeatureLayer) GeomType() geom.Geometry { return l.geomType }
+func (l FeatureLayer) SRID() uint64            { return l.srid }
@@ -0,0 +1,298 @@
+package remotefile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/go-spatial/tegola/internal/remote"
+)
+
+// PMTiles v3 archives. ref: https://github.com/protomaps/PMTiles/blob/main/spec/v3/spec.md
+
+const (
+	pmtilesHeaderLength = 127
+	// the root directory is always within the first 16 KiB of the archive
+	pmtilesRootLength = 16384
+	// clients should not follow more than 3 directory levels
+	pmtilesMaxDepth = 3
+)
+
+// compression types
+const (
+	compressionUnknown = 0
+	compressionNone    = 1
+	compressionGzip    = 2
+)
+
+// tile types
+const (
+	tileTypeUnknown = 0
+	tileTypeMVT     = 1
+)
+
+type pmtilesHeader struct {
+	rootOffset, rootLength uint64
+	metadataOffset         uint64
+	metadataLength         uint64
+	leafOffset, leafLength uint64
+	dataOffset, dataLength uint64
+	internalCompression    uint8
+	tileCompression        uint8
+	tileType               uint8
+	minZoom, maxZoom       uint8
+	minLon, minLat         float64
+	maxLon, maxLat         float64
+}
+
+type dirEntry struct {
+	tileID    uint64
+	offset    uint64
+	length    uint32
+	runLength uint32
+}
+
+// parsePMTilesHeader decodes the fixed size header at the start of the archive
+func parsePMTilesHeader(b []byte) (pmtilesHeader, error) {
+	var h pmtilesHeader
+	if len(b) < pmtilesHeaderLength || string(b[0:7]) != "PMTiles" {
+		return h, fmt.Errorf("missing PMTiles magic number")
+	}
+	if b[7] != 3 {
+		return h, fmt.Errorf("unsupported PMTiles version %v, expected 3", b[7])
+	}
+
+	u64 := func(i int) uint64 { return binary.LittleEndian.Uint64(b[i : i+8]) }
+	e7 := func(i int) float64 { return float64(int32(binary.LittleEndian.Uint32(b[i:i+4]))) / 10000000 }
+
+	h.rootOffset, h.rootLength = u64(8), u64(16)
+	h.metadataOffset, h.metadataLength = u64(24), u64(32)
+	h.leafOffset, h.leafLength = u64(40), u64(48)
+	h.dataOffset, h.dataLength = u64(56), u64(64)
+	h.internalCompression = b[97]
+	h.tileCompression = b[98]
+	h.tileType = b[99]
+	h.minZoom, h.maxZoom = b[100], b[101]
+	h.minLon, h.minLat = e7(102), e7(106)
+	h.maxLon, h.maxLat = e7(110), e7(114)
+
+	return h, nil
+}
+
+// decompress returns the decompressed bytes for the PMTiles compression type
+func decompress(b []byte, compression uint8) ([]byte, error) {
+	switch compression {
+	case compressionNone, compressionUnknown:
+		return b, nil
+	case compressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unsupported compression type %v", compression)
+	}
+}
+
+// parseDirectory decodes a (decompressed) directory. Every column is stored as
+// varints: the delta encoded tile ids, run lengths, lengths and offsets.
+func parseDirectory(b []byte) ([]dirEntry, error) {
+	r := bytes.NewReader(b)
+	read := func() (uint64, error) { return binary.ReadUvarint(r) }
+
+	n, err := read()
+	if err != nil {
+		return nil, err
+	}
+	// every entry takes at least 4 bytes
+	if n > uint64(len(b)) {
+		return nil, fmt.Errorf("directory has an invalid number of entries %v", n)
+	}
+
+	entries := make([]dirEntry, n)
+
+	var lastID uint64
+	for i := range entries {
+		v, err := read()
+		if err != nil {
+			return nil, err
+		}
+		lastID += v
+		entries[i].tileID = lastID
+	}
+	for i := range entries {
+		v, err := read()
+		if err != nil {
+			return nil, err
+		}
+		entries[i].runLength = uint32(v)
+	}
+	for i := range entries {
+		v, err := read()
+		if err != nil {
+			return nil, err
+		}
+		entries[i].length = uint32(v)
+	}
+	for i := range entries {
+		v, err := read()
+		if err != nil {
+			return nil, err
+		}
+		// 0 means the entry directly follows the previous one
+		if v == 0 && i > 0 {
+			entries[i].offset = entries[i-1].offset + uint64(entries[i-1].length)
+		} else {
+			entries[i].offset = v - 1
+		}
+	}
+
+	return entries, nil
+}
+
+// findTile returns the entry covering the tile id. run lengths of 0 point to leaf directories.
+func findTile(entries []dirEntry, tileID uint64) (dirEntry, bool) {
+	lo, hi := 0, len(entries)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		switch {
+		case entries[mid].tileID < tileID:
+			lo = mid + 1
+		case entries[mid].tileID > tileID:
+			hi = mid - 1
+		default:
+			return entries[mid], true
+		}
+	}
+
+	// hi is the last entry before the tile id
+	if hi >= 0 {
+		e := entries[hi]
+		if e.runLength == 0 {
+			return e, true
+		}
+		if tileID-e.tileID < uint64(e.runLength) {
+			return e, true
+		}
+	}
+
+	return dirEntry{}, false
+}
+
+// zxyToTileID returns the PMTiles tile id: the tiles of the lower zooms followed by
+// the position of the tile on the zoom's hilbert curve
+func zxyToTileID(z uint8, x, y uint32) uint64 {
+	var acc uint64
+	for tz := uint8(0); tz < z; tz++ {
+		acc += uint64(1) << (2 * tz)
+	}
+
+	n := uint64(1) << z
+	tx, ty := uint64(x), uint64(y)
+
+	var d uint64
+	for s := n / 2; s > 0; s /= 2 {
+		var rx, ry uint64
+		if tx&s > 0 {
+			rx = 1
+		}
+		if ty&s > 0 {
+			ry = 1
+		}
+		d += s * s * ((3 * rx) ^ ry)
+
+		// rotate the quadrant
+		if ry == 0 {
+			if rx == 1 {
+				tx = n - 1 - tx
+				ty = n - 1 - ty
+			}
+			tx, ty = ty, tx
+		}
+	}
+
+	return acc + d
+}
+
+// pmtilesArchive reads tiles from a PMTiles archive
+type pmtilesArchive struct {
+	url    string
+	r      *remote.Reader
+	header pmtilesHeader
+	root   []dirEntry
+}
+
+func openPMTiles(ctx context.Context, url string, r *remote.Reader) (*pmtilesArchive, error) {
+	b, err := r.ReadRange(ctx, 0, pmtilesRootLength)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := parsePMTilesHeader(b)
+	if err != nil {
+		return nil, ErrInvalidArchive{URL: url, Reason: err.Error()}
+	}
+	if h.tileType != tileTypeMVT && h.tileType != tileTypeUnknown {
+		return nil, ErrInvalidArchive{URL: url, Reason: fmt.Sprintf("tile type %v is not MVT", h.tileType)}
+	}
+
+	a := pmtilesArchive{
+		url:    url,
+		r:      r,
+		header: h,
+	}
+	if a.root, err = a.directory(ctx, h.rootOffset, h.rootLength); err != nil {
+		return nil, ErrInvalidArchive{URL: url, Reason: err.Error()}
+	}
+
+	return &a, nil
+}
+
+func (a *pmtilesArchive) directory(ctx context.Context, off, length uint64) ([]dirEntry, error) {
+	b, err := a.r.ReadRange(ctx, int64(off), int64(length))
+	if err != nil {
+		return nil, err
+	}
+	if b, err = decompress(b, a.header.internalCompression); err != nil {
+		return nil, err
+	}
+	return parseDirectory(b)
+}
+
+// tile returns the uncompressed tile, or nil if the archive doesn't contain the tile
+func (a *pmtilesArchive) tile(ctx context.Context, z uint8, x, y uint32) ([]byte, error) {
+	if z < a.header.minZoom || z > a.header.maxZoom {
+		return nil, nil
+	}
+
+	tileID := zxyToTileID(z, x, y)
+	entries := a.root
+
+	for depth := 0; depth <= pmtilesMaxDepth; depth++ {
+		e, ok := findTile(entries, tileID)
+		if !ok {
+			return nil, nil
+		}
+
+		if e.runLength > 0 {
+			b, err := a.r.ReadRange(ctx, int64(a.header.dataOffset+e.offset), int64(e.length))
+			if err != nil {
+				return nil, err
+			}
+			return decompress(b, a.header.tileCompression)
+		}
+
+		leaf, err := a.directory(ctx, a.header.leafOffset+e.offset, uint64(e.length))
+		if err != nil {
+			return nil, err
+		}
+		entries = leaf
+	}
+
+	return nil, ErrInvalidArchive{URL: a.url, Reason: "too many directory levels"}
+}
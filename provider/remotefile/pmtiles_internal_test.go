@@ -0,0 +1,89 @@
+package remotefile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestZXYToTileID(t *testing.T) {
+	type tcase struct {
+		z    uint8
+		x, y uint32
+		id   uint64
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			if got := zxyToTileID(tc.z, tc.x, tc.y); got != tc.id {
+				t.Errorf("expected %v got %v", tc.id, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"0/0/0": {z: 0, x: 0, y: 0, id: 0},
+		"1/0/0": {z: 1, x: 0, y: 0, id: 1},
+		"1/0/1": {z: 1, x: 0, y: 1, id: 2},
+		"1/1/1": {z: 1, x: 1, y: 1, id: 3},
+		"1/1/0": {z: 1, x: 1, y: 0, id: 4},
+		"2/0/0": {z: 2, x: 0, y: 0, id: 5},
+		"2/3/0": {z: 2, x: 3, y: 0, id: 20},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestParseDirectory(t *testing.T) {
+	var buf []byte
+	put := func(vs ...uint64) {
+		for _, v := range vs {
+			var b [binary.MaxVarintLen64]byte
+			buf = append(buf, b[:binary.PutUvarint(b[:], v)]...)
+		}
+	}
+	// 3 entries: tile ids 1, 2 (run of 2) and 10, the second entry follows the first
+	put(3)
+	put(1, 1, 8)
+	put(1, 2, 0)
+	put(100, 50, 20)
+	put(1, 0, 301)
+
+	entries, err := parseDirectory(buf)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	expected := []dirEntry{
+		{tileID: 1, runLength: 1, length: 100, offset: 0},
+		{tileID: 2, runLength: 2, length: 50, offset: 100},
+		{tileID: 10, runLength: 0, length: 20, offset: 300},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %v entries got %v", len(expected), len(entries))
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("entry %v, expected %+v got %+v", i, expected[i], entries[i])
+		}
+	}
+
+	type tcase struct {
+		tileID uint64
+		found  bool
+		offset uint64
+	}
+	for _, tc := range []tcase{
+		{tileID: 0, found: false},
+		{tileID: 1, found: true, offset: 0},
+		{tileID: 3, found: true, offset: 100},
+		{tileID: 4, found: false},
+		{tileID: 12, found: true, offset: 300},
+	} {
+		e, ok := findTile(entries, tc.tileID)
+		if ok != tc.found || (ok && e.offset != tc.offset) {
+			t.Errorf("tile %v, expected found %v offset %v got %v %v", tc.tileID, tc.found, tc.offset, ok, e.offset)
+		}
+	}
+}
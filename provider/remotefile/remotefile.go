@@ -0,0 +1,292 @@
+// Package remotefile provides providers which read files on HTTP(S), S3 (or an S3 compatible
+// store), Google Cloud Storage or local disk. Only the byte ranges needed for a tile are
+// requested and the most recently read blocks are kept in memory, so files don't need to be
+// copied to the server's disk.
+//
+// The MVT provider serves the tiles of PMTiles (v3) archives. The standard provider serves the
+// features of GeoPackages and FlatGeobuf files.
+package remotefile
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/remote"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "remotefile"
+
+// formats supported by the provider
+const (
+	FormatPMTiles    = "pmtiles"
+	FormatGeoPackage = "gpkg"
+	FormatFlatGeobuf = "flatgeobuf"
+)
+
+// the formats of the file extensions
+var extensionFormats = map[string]string{
+	".pmtiles": FormatPMTiles,
+	".gpkg":    FormatGeoPackage,
+	".fgb":     FormatFlatGeobuf,
+}
+
+const (
+	ConfigKeyURL        = "url"
+	ConfigKeyFormat     = "format"
+	ConfigKeyHeaders    = "headers"
+	ConfigKeyTimeout    = "timeout"
+	ConfigKeyBlockSize  = "block_size"
+	ConfigKeyCacheSize  = "cache_size_mb"
+	ConfigKeyLayers     = "layers"
+	ConfigKeyLayerName  = "name"
+	ConfigKeySourceName = "source_layer"
+	ConfigKeyIDField    = "id_fieldname"
+)
+
+const (
+	DefaultFormat    = FormatPMTiles
+	DefaultTimeout   = 30
+	DefaultBlockSize = 64 * 1024
+	DefaultCacheSize = 64
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, nil)
+	provider.MVTRegister(provider.TypeMvt.Prefix()+Name, NewMVTTileProvider, nil)
+}
+
+// Provider reads the tiles of a remote archive
+type Provider struct {
+	url     string
+	archive *pmtilesArchive
+
+	// map of layer name and corresponding source layer
+	layers map[string]Layer
+}
+
+// NewMVTTileProvider instantiates and returns a new remotefile provider or an error.
+// The archive's header and root directory are read when the provider is created.
+//
+//	url (string): [Required] the archive's http://, https://, s3://bucket/key, gs://bucket/key or file:// url
+//	format (string): [Optional] the archive format. defaults to "pmtiles"
+//	headers (map[string]string): [Optional] headers added to every http request (i.e. API keys)
+//	timeout (int): [Optional] the number of seconds allowed per http request. defaults to 30
+//	block_size (int): [Optional] the number of bytes read and cached per block. defaults to 65536
+//	cache_size_mb (int): [Optional] the megabytes of blocks kept in memory, 0 disables the cache. defaults to 64
+//	region, endpoint, aws_access_key_id, ... : [Optional] the S3 connection options. See the s3 cache.
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		source_layer (string): [Optional] the name of the layer within the archive's tiles. defaults to name
+func NewMVTTileProvider(config dict.Dicter) (provider.MVTTiler, error) {
+	f, err := newFile(config, DefaultFormat)
+	if err != nil {
+		return nil, err
+	}
+	if f.format != FormatPMTiles {
+		return nil, ErrUnsupportedFormat{Format: f.format}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+
+	p := Provider{
+		url:    f.url,
+		layers: map[string]Layer{},
+	}
+	if p.archive, err = openPMTiles(ctx, f.url, f.r); err != nil {
+		return nil, remoteError(err)
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// file is the remote file of a provider
+type file struct {
+	url     string
+	format  string
+	r       *remote.Reader
+	timeout time.Duration
+}
+
+// newFile reads the connection properties of the config. The format defaults to the format of
+// the url's extension, or def for other extensions.
+func newFile(config dict.Dicter, def string) (file, error) {
+	url, err := config.String(ConfigKeyURL, nil)
+	if err != nil {
+		return file{}, err
+	}
+	if url == "" {
+		return file{}, ErrMissingURL
+	}
+
+	format := def
+	if f, ok := extensionFormats[strings.ToLower(path.Ext(url))]; ok {
+		format = f
+	}
+	if format, err = config.String(ConfigKeyFormat, &format); err != nil {
+		return file{}, err
+	}
+
+	ints := []struct {
+		key string
+		val int
+	}{
+		{ConfigKeyTimeout, DefaultTimeout},
+		{ConfigKeyBlockSize, DefaultBlockSize},
+		{ConfigKeyCacheSize, DefaultCacheSize},
+	}
+	for i := range ints {
+		if ints[i].val, err = config.Int(ints[i].key, &ints[i].val); err != nil {
+			return file{}, err
+		}
+		if ints[i].val < 0 {
+			return file{}, fmt.Errorf("remotefile: %v must not be negative, got %v", ints[i].key, ints[i].val)
+		}
+	}
+	timeout, blockSize, cacheSize := ints[0].val, ints[1].val, ints[2].val
+	if blockSize == 0 {
+		blockSize = DefaultBlockSize
+	}
+
+	headers, err := dict.StringMap(config, ConfigKeyHeaders)
+	if err != nil {
+		return file{}, err
+	}
+
+	src, err := remote.NewSource(url, config, headers, time.Duration(timeout)*time.Second)
+	if err != nil {
+		return file{}, remoteError(err)
+	}
+
+	return file{
+		url:     url,
+		format:  strings.ToLower(format),
+		r:       remote.NewReader(src, int64(blockSize), cacheSize*1024*1024/blockSize),
+		timeout: time.Duration(timeout) * time.Second,
+	}, nil
+}
+
+// AddLayer adds an archive layer to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	sourceLayer, err := layerConf.String(ConfigKeySourceName, &name)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeySourceName, err)
+	}
+
+	p.layers[name] = Layer{
+		name:        name,
+		sourceLayer: sourceLayer,
+	}
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent returns the bounds of the archive
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	h := p.archive.header
+	return geom.Extent{h.minLon, h.minLat, h.maxLon, h.maxLat}, nil
+}
+
+// LayerMinZoom returns the min zoom of the archive
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return int(p.archive.header.minZoom)
+}
+
+// LayerMaxZoom returns the max zoom of the archive
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return int(p.archive.header.maxZoom)
+}
+
+// MVTForLayers reads the tile from the archive and returns the requested layers, renamed to their MVT names.
+// Tiles missing from the archive are returned as empty tiles.
+func (p *Provider) MVTForLayers(ctx context.Context, tile provider.Tile, layers []provider.Layer) ([]byte, error) {
+	z, x, y := tile.ZXY()
+	if z > tegola.MaxZ {
+		return nil, nil
+	}
+
+	b, err := p.archive.tile(ctx, uint8(z), uint32(x), uint32(y))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, provider.ErrCanceled
+		}
+		return nil, remoteError(err)
+	}
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	var src vectorTile.Tile
+	if err := proto.Unmarshal(b, &src); err != nil {
+		return nil, fmt.Errorf("remotefile: decoding tile (%v/%v/%v) of (%v): %v", z, x, y, p.url, err)
+	}
+
+	var dst vectorTile.Tile
+	for i := range layers {
+		l, ok := p.layers[layers[i].ID]
+		if !ok {
+			log.Warnf("remotefile: provider layer not found %v", layers[i].ID)
+			continue
+		}
+
+		for _, sl := range src.Layers {
+			if sl.GetName() != l.sourceLayer {
+				continue
+			}
+			// copy the layer so source layers can be used by multiple map layers
+			ml := proto.Clone(sl).(*vectorTile.Tile_Layer)
+			ml.Name = proto.String(layers[i].MVTName)
+			dst.Layers = append(dst.Layers, ml)
+		}
+	}
+
+	return proto.Marshal(&dst)
+}
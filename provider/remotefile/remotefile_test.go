@@ -0,0 +1,288 @@
+package remotefile_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/remotefile"
+)
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type entry struct {
+	tileID, runLength, offset, length uint64
+}
+
+func directory(t *testing.T, entries []entry) []byte {
+	var buf []byte
+	put := func(v uint64) {
+		var b [binary.MaxVarintLen64]byte
+		buf = append(buf, b[:binary.PutUvarint(b[:], v)]...)
+	}
+
+	put(uint64(len(entries)))
+	var last uint64
+	for _, e := range entries {
+		put(e.tileID - last)
+		last = e.tileID
+	}
+	for _, e := range entries {
+		put(e.runLength)
+	}
+	for _, e := range entries {
+		put(e.length)
+	}
+	for _, e := range entries {
+		put(e.offset + 1)
+	}
+	return gzipBytes(t, buf)
+}
+
+func mvtTile(t *testing.T, layers ...string) []byte {
+	var vt vectorTile.Tile
+	for _, name := range layers {
+		vt.Layers = append(vt.Layers, &vectorTile.Tile_Layer{
+			Version: proto.Uint32(2),
+			Name:    proto.String(name),
+			Extent:  proto.Uint32(4096),
+		})
+	}
+	b, err := proto.Marshal(&vt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gzipBytes(t, b)
+}
+
+// archive returns a PMTiles archive with zooms 0 and 1. The zoom 1 tiles are a single
+// run in a leaf directory.
+func archive(t *testing.T) []byte {
+	z0 := mvtTile(t, "water", "roads")
+	z1 := mvtTile(t, "water")
+
+	data := append(append([]byte{}, z0...), z1...)
+	leaf := directory(t, []entry{{tileID: 1, runLength: 4, offset: uint64(len(z0)), length: uint64(len(z1))}})
+	// the root is written after the header, the leaf directory after the tile data
+	root := directory(t, []entry{
+		{tileID: 0, runLength: 1, offset: 0, length: uint64(len(z0))},
+		{tileID: 1, runLength: 0, offset: 0, length: uint64(len(leaf))},
+	})
+
+	header := make([]byte, 127)
+	copy(header, "PMTiles")
+	header[7] = 3
+	u64 := func(i int, v uint64) { binary.LittleEndian.PutUint64(header[i:], v) }
+	i32 := func(i int, v int32) { binary.LittleEndian.PutUint32(header[i:], uint32(v)) }
+
+	rootOffset := uint64(len(header))
+	dataOffset := rootOffset + uint64(len(root))
+	leafOffset := dataOffset + uint64(len(data))
+
+	u64(8, rootOffset)
+	u64(16, uint64(len(root)))
+	u64(40, leafOffset)
+	u64(48, uint64(len(leaf)))
+	u64(56, dataOffset)
+	u64(64, uint64(len(data)))
+	header[97] = 2 // gzip
+	header[98] = 2 // gzip
+	header[99] = 1 // mvt
+	header[100], header[101] = 0, 1
+	i32(102, -1800000000)
+	i32(106, -850000000)
+	i32(110, 1800000000)
+	i32(114, 850000000)
+
+	var b []byte
+	for _, part := range [][]byte{header, root, data, leaf} {
+		b = append(b, part...)
+	}
+	return b
+}
+
+func layerNames(t *testing.T, b []byte) []string {
+	var vt vectorTile.Tile
+	if err := proto.Unmarshal(b, &vt); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, l := range vt.Layers {
+		names = append(names, l.GetName())
+	}
+	return names
+}
+
+func TestMVTForLayers(t *testing.T) {
+	b := archive(t)
+
+	dir, err := ioutil.TempDir("", "remotefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.pmtiles")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.ServeContent(w, r, "test.pmtiles", time.Time{}, bytes.NewReader(b))
+	}))
+	defer srv.Close()
+
+	type tcase struct {
+		url      string
+		z, x, y  uint
+		expected []string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			p, err := remotefile.NewMVTTileProvider(dict.Dict{
+				"url":        tc.url,
+				"block_size": 64,
+				"headers":    map[string]interface{}{"X-Api-Key": "secret"},
+				"layers": []map[string]interface{}{
+					{"name": "water"},
+					{"name": "streets", "source_layer": "roads"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if p.LayerMaxZoom("water") != 1 {
+				t.Errorf("expected max zoom 1 got %v", p.LayerMaxZoom("water"))
+			}
+
+			tile := provider.NewTile(tc.z, tc.x, tc.y, 64, tegola.WebMercator)
+			got, err := p.MVTForLayers(context.Background(), tile, []provider.Layer{
+				{ID: "streets", MVTName: "streets"},
+				{ID: "water", MVTName: "ocean"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			names := layerNames(t, got)
+			if len(names) != len(tc.expected) {
+				t.Fatalf("expected layers %v got %v", tc.expected, names)
+			}
+			for i := range names {
+				if names[i] != tc.expected[i] {
+					t.Errorf("expected layers %v got %v", tc.expected, names)
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"http root tile": {
+			url:      srv.URL,
+			expected: []string{"streets", "ocean"},
+		},
+		"http leaf tile": {
+			url:      srv.URL,
+			z:        1,
+			x:        1,
+			y:        0,
+			expected: []string{"ocean"},
+		},
+		"http missing tile": {
+			url: srv.URL,
+			z:   2,
+		},
+		"file leaf tile": {
+			url:      "file://" + path,
+			z:        1,
+			expected: []string{"ocean"},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+
+	if requests == 0 {
+		t.Errorf("expected requests to the http server")
+	}
+}
+
+func TestNewMVTTileProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ignore the range header
+		w.Write([]byte("PMTiles"))
+	}))
+	defer srv.Close()
+
+	type tcase struct {
+		config      dict.Dict
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := remotefile.NewMVTTileProvider(tc.config)
+			if err != tc.expectedErr {
+				t.Errorf("expected err %v got %v", tc.expectedErr, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing url": {
+			config:      dict.Dict{"url": ""},
+			expectedErr: remotefile.ErrMissingURL,
+		},
+		"unsupported format": {
+			config:      dict.Dict{"url": srv.URL, "format": "gpkg"},
+			expectedErr: remotefile.ErrUnsupportedFormat{Format: "gpkg"},
+		},
+		"unsupported scheme": {
+			config:      dict.Dict{"url": "ftp://example.com/test.pmtiles"},
+			expectedErr: remotefile.ErrUnsupportedScheme{URL: "ftp://example.com/test.pmtiles"},
+		},
+		"range unsupported": {
+			config:      dict.Dict{"url": srv.URL},
+			expectedErr: remotefile.ErrRangeUnsupported,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, fn(tc))
+	}
+}
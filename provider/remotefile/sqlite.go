@@ -0,0 +1,499 @@
+package remotefile
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-spatial/tegola/internal/remote"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// A read-only reader of the table b-trees of SQLite databases, enough to read the tables of a
+// GeoPackage without a local copy of the file. ref: https://www.sqlite.org/fileformat2.html
+
+const (
+	sqliteHeaderLength = 100
+	// the maximum depth of a b-tree, guarding against cycles in malformed files
+	sqliteMaxDepth = 20
+)
+
+var sqliteMagic = "SQLite format 3\x00"
+
+// page types
+const (
+	pageInteriorTable = 5
+	pageLeafTable     = 13
+)
+
+// sqliteDB reads the pages of a database
+type sqliteDB struct {
+	url        string
+	r          *remote.Reader
+	pageSize   int64
+	usableSize int64
+	// the tables of the schema keyed by their lower case name
+	tables map[string]sqliteTable
+}
+
+// sqliteTable is a table of the schema
+type sqliteTable struct {
+	name     string
+	rootPage uint32
+	columns  []string
+	// the index of the INTEGER PRIMARY KEY column, which is stored as the rowid. -1 without one
+	rowidColumn int
+}
+
+// column returns the index of the column, -1 when the table has no such column
+func (t sqliteTable) column(name string) int {
+	for i, c := range t.columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sqliteRow is a row of a table. INTEGER PRIMARY KEY columns hold the rowid.
+type sqliteRow struct {
+	rowid  int64
+	values []interface{}
+}
+
+func openSQLite(ctx context.Context, url string, r *remote.Reader) (*sqliteDB, error) {
+	b, err := r.ReadRange(ctx, 0, sqliteHeaderLength)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < sqliteHeaderLength || string(b[:16]) != sqliteMagic {
+		return nil, ErrInvalidArchive{URL: url, Reason: "missing SQLite magic string"}
+	}
+
+	db := sqliteDB{
+		url:      url,
+		r:        r,
+		pageSize: int64(binary.BigEndian.Uint16(b[16:])),
+	}
+	if db.pageSize == 1 {
+		db.pageSize = 65536
+	}
+	if db.pageSize < 512 || db.pageSize&(db.pageSize-1) != 0 {
+		return nil, ErrInvalidArchive{URL: url, Reason: fmt.Sprintf("invalid page size %v", db.pageSize)}
+	}
+	db.usableSize = db.pageSize - int64(b[20])
+	if enc := binary.BigEndian.Uint32(b[56:]); enc > 1 {
+		return nil, ErrInvalidArchive{URL: url, Reason: "only UTF-8 databases are supported"}
+	}
+
+	// the schema table is rooted at the first page
+	schema := sqliteTable{
+		name:        "sqlite_master",
+		rootPage:    1,
+		columns:     []string{"type", "name", "tbl_name", "rootpage", "sql"},
+		rowidColumn: -1,
+	}
+	db.tables = map[string]sqliteTable{}
+	err = db.scan(ctx, schema, func(row sqliteRow) error {
+		typ, _ := row.values[0].(string)
+		name, _ := row.values[1].(string)
+		rootPage, _ := row.values[3].(int64)
+		sql, _ := row.values[4].(string)
+		if typ != "table" || rootPage <= 0 {
+			return nil
+		}
+
+		t := sqliteTable{name: name, rootPage: uint32(rootPage)}
+		t.columns, t.rowidColumn = parseCreateTable(sql)
+		db.tables[strings.ToLower(name)] = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &db, nil
+}
+
+// table returns the table with the name
+func (db *sqliteDB) table(name string) (sqliteTable, bool) {
+	t, ok := db.tables[strings.ToLower(name)]
+	return t, ok
+}
+
+func (db *sqliteDB) invalid(format string, args ...interface{}) error {
+	return ErrInvalidArchive{URL: db.url, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (db *sqliteDB) page(ctx context.Context, n uint32) ([]byte, error) {
+	if n == 0 {
+		return nil, db.invalid("invalid page number 0")
+	}
+	b, err := db.r.ReadRange(ctx, int64(n-1)*db.pageSize, db.pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) < db.pageSize {
+		return nil, db.invalid("page %v is past the end of the file", n)
+	}
+	return b, nil
+}
+
+// btreePage is a page of a table b-tree
+type btreePage struct {
+	leaf  bool
+	cells []int
+	// the right most child of interior pages
+	right uint32
+	data  []byte
+}
+
+func (db *sqliteDB) btreePage(ctx context.Context, n uint32) (btreePage, error) {
+	b, err := db.page(ctx, n)
+	if err != nil {
+		return btreePage{}, err
+	}
+
+	hdr := 0
+	if n == 1 {
+		hdr = sqliteHeaderLength
+	}
+
+	p := btreePage{data: b}
+	switch b[hdr] {
+	case pageLeafTable:
+		p.leaf = true
+	case pageInteriorTable:
+		p.right = binary.BigEndian.Uint32(b[hdr+8:])
+	default:
+		return p, db.invalid("page %v is not a table b-tree page (type %v)", n, b[hdr])
+	}
+
+	cellPtrs := hdr + 12
+	if p.leaf {
+		cellPtrs = hdr + 8
+	}
+	p.cells = make([]int, binary.BigEndian.Uint16(b[hdr+3:]))
+	for i := range p.cells {
+		ptr := cellPtrs + 2*i
+		if ptr+2 > len(b) {
+			return p, db.invalid("page %v has too many cells", n)
+		}
+		p.cells[i] = int(binary.BigEndian.Uint16(b[ptr:]))
+		if p.cells[i] >= len(b) {
+			return p, db.invalid("page %v has an invalid cell pointer", n)
+		}
+	}
+	return p, nil
+}
+
+// child returns the left child page and the key of a cell of an interior page
+func (p btreePage) child(i int) (uint32, int64) {
+	c := p.cells[i]
+	key, _ := sqliteVarint(p.data[c+4:])
+	return binary.BigEndian.Uint32(p.data[c:]), key
+}
+
+// row decodes the cell of a leaf page, reading the overflow pages of large rows
+func (db *sqliteDB) row(ctx context.Context, p btreePage, i int) (sqliteRow, error) {
+	c := p.cells[i]
+	size, n := sqliteVarint(p.data[c:])
+	c += n
+	rowid, n := sqliteVarint(p.data[c:])
+	c += n
+
+	// the part of the payload stored in the page
+	u := db.usableSize
+	maxLocal, minLocal := u-35, (u-12)*32/255-23
+	local := size
+	if size > maxLocal {
+		local = minLocal + (size-minLocal)%(u-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if int64(c)+local > int64(len(p.data)) {
+		return sqliteRow{}, db.invalid("row %v overflows its page", rowid)
+	}
+
+	payload := append([]byte(nil), p.data[c:int64(c)+local]...)
+	if local < size {
+		next := binary.BigEndian.Uint32(p.data[int64(c)+local:])
+		for int64(len(payload)) < size {
+			if next == 0 {
+				return sqliteRow{}, db.invalid("row %v is missing overflow pages", rowid)
+			}
+			b, err := db.page(ctx, next)
+			if err != nil {
+				return sqliteRow{}, err
+			}
+			next = binary.BigEndian.Uint32(b)
+			chunk := b[4:u]
+			if rest := size - int64(len(payload)); int64(len(chunk)) > rest {
+				chunk = chunk[:rest]
+			}
+			payload = append(payload, chunk...)
+		}
+	}
+
+	values, err := sqliteRecord(payload)
+	if err != nil {
+		return sqliteRow{}, db.invalid("row %v: %v", rowid, err)
+	}
+	return sqliteRow{rowid: rowid, values: values}, nil
+}
+
+// withRowid fills in the INTEGER PRIMARY KEY column and pads rows written before columns were
+// added to the table
+func (t sqliteTable) withRowid(row sqliteRow) sqliteRow {
+	for len(row.values) < len(t.columns) {
+		row.values = append(row.values, nil)
+	}
+	if t.rowidColumn >= 0 {
+		row.values[t.rowidColumn] = row.rowid
+	}
+	return row
+}
+
+// scan calls fn with every row of the table in rowid order
+func (db *sqliteDB) scan(ctx context.Context, t sqliteTable, fn func(sqliteRow) error) error {
+	var walk func(n uint32, depth int) error
+	walk = func(n uint32, depth int) error {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+		if depth > sqliteMaxDepth {
+			return db.invalid("table (%v) is too deep", t.name)
+		}
+
+		p, err := db.btreePage(ctx, n)
+		if err != nil {
+			return err
+		}
+		for i := range p.cells {
+			if !p.leaf {
+				child, _ := p.child(i)
+				if err := walk(child, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+
+			row, err := db.row(ctx, p, i)
+			if err != nil {
+				return err
+			}
+			if err := fn(t.withRowid(row)); err != nil {
+				return err
+			}
+		}
+		if !p.leaf {
+			return walk(p.right, depth+1)
+		}
+		return nil
+	}
+	return walk(t.rootPage, 0)
+}
+
+// lookup calls fn with the rows of the table with the rowids, which must be sorted. Missing
+// rows are skipped.
+func (db *sqliteDB) lookup(ctx context.Context, t sqliteTable, rowids []int64, fn func(sqliteRow) error) error {
+	var walk func(n uint32, rowids []int64, depth int) error
+	walk = func(n uint32, rowids []int64, depth int) error {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+		if depth > sqliteMaxDepth {
+			return db.invalid("table (%v) is too deep", t.name)
+		}
+
+		p, err := db.btreePage(ctx, n)
+		if err != nil {
+			return err
+		}
+
+		if p.leaf {
+			i, key := 0, int64(0)
+			for _, rowid := range rowids {
+				for ; i < len(p.cells); i++ {
+					c := p.cells[i]
+					_, n := sqliteVarint(p.data[c:])
+					if key, _ = sqliteVarint(p.data[c+n:]); key >= rowid {
+						break
+					}
+				}
+				if i == len(p.cells) {
+					return nil
+				}
+				if key != rowid {
+					continue
+				}
+
+				row, err := db.row(ctx, p, i)
+				if err != nil {
+					return err
+				}
+				if err := fn(t.withRowid(row)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		// the rowids of a child are at most the child's key
+		for i := range p.cells {
+			if len(rowids) == 0 {
+				return nil
+			}
+			child, key := p.child(i)
+			j := 0
+			for j < len(rowids) && rowids[j] <= key {
+				j++
+			}
+			if j == 0 {
+				continue
+			}
+			if err := walk(child, rowids[:j], depth+1); err != nil {
+				return err
+			}
+			rowids = rowids[j:]
+		}
+		if len(rowids) == 0 {
+			return nil
+		}
+		return walk(p.right, rowids, depth+1)
+	}
+	return walk(t.rootPage, rowids, 0)
+}
+
+// sqliteVarint decodes a big endian varint of up to 9 bytes, returning the value and its length
+func sqliteVarint(b []byte) (int64, int) {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return int64(v), i + 1
+		}
+	}
+	return int64(v<<8 | uint64(b[8])), 9
+}
+
+// sqliteRecord decodes the values of a record. Integers are returned as int64, reals as
+// float64, text as string and blobs as []byte.
+func sqliteRecord(b []byte) (values []interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed record: %v", r)
+		}
+	}()
+
+	hdrLen, n := sqliteVarint(b)
+	var types []int64
+	for i := n; i < int(hdrLen); {
+		t, n := sqliteVarint(b[i:])
+		types = append(types, t)
+		i += n
+	}
+
+	body := b[hdrLen:]
+	for _, t := range types {
+		var v interface{}
+		switch {
+		case t == 0:
+		case t >= 1 && t <= 6:
+			size := []int{0, 1, 2, 3, 4, 6, 8}[t]
+			var i int64
+			for _, c := range body[:size] {
+				i = i<<8 | int64(c)
+			}
+			// sign extend
+			shift := uint(64 - 8*size)
+			v, body = i<<shift>>shift, body[size:]
+		case t == 7:
+			v, body = math.Float64frombits(binary.BigEndian.Uint64(body)), body[8:]
+		case t == 8:
+			v = int64(0)
+		case t == 9:
+			v = int64(1)
+		case t >= 12 && t%2 == 0:
+			size := (t - 12) / 2
+			v, body = body[:size], body[size:]
+		case t >= 13:
+			size := (t - 13) / 2
+			v, body = string(body[:size]), body[size:]
+		default:
+			return nil, fmt.Errorf("invalid serial type %v", t)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// parseCreateTable returns the names of the columns of a CREATE TABLE statement and the index
+// of its INTEGER PRIMARY KEY column, -1 when there is none
+func parseCreateTable(sql string) ([]string, int) {
+	start, end := strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if start < 0 || end < start {
+		return nil, -1
+	}
+
+	// split the definitions on the commas outside of parentheses and quotes
+	var defs []string
+	depth, quote, from := 0, rune(0), start+1
+	for i, c := range sql[start+1 : end] {
+		switch {
+		case quote != 0:
+			if c == quote || (quote == '[' && c == ']') {
+				quote = 0
+			}
+		case c == '"' || c == '`' || c == '\'' || c == '[':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			defs = append(defs, sql[from:start+1+i])
+			from = start + 2 + i
+		}
+	}
+	defs = append(defs, sql[from:end])
+
+	var columns []string
+	rowidColumn := -1
+	for _, def := range defs {
+		def = strings.TrimSpace(def)
+		name, rest := sqliteIdentifier(def)
+		switch strings.ToUpper(name) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			if !strings.ContainsAny(def[:1], "\"`[") {
+				continue
+			}
+		}
+
+		rest = strings.ToUpper(rest)
+		if fields := strings.Fields(rest); len(fields) > 0 && fields[0] == "INTEGER" && strings.Contains(rest, "PRIMARY KEY") {
+			rowidColumn = len(columns)
+		}
+		columns = append(columns, name)
+	}
+	return columns, rowidColumn
+}
+
+// sqliteIdentifier splits a column definition into the column name, unquoted, and the rest
+func sqliteIdentifier(def string) (string, string) {
+	if def == "" {
+		return "", ""
+	}
+	if end := map[byte]byte{'"': '"', '`': '`', '[': ']', '\'': '\''}[def[0]]; end != 0 {
+		if i := strings.IndexByte(def[1:], end); i >= 0 {
+			return def[1 : i+1], def[i+2:]
+		}
+	}
+	if i := strings.IndexAny(def, " \t\n\r"); i >= 0 {
+		return def[:i], def[i:]
+	}
+	return def, ""
+}
@@ -0,0 +1,66 @@
+package remotefile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCreateTable(t *testing.T) {
+	type tcase struct {
+		sql         string
+		columns     []string
+		rowidColumn int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			columns, rowidColumn := parseCreateTable(tc.sql)
+			if !reflect.DeepEqual(columns, tc.columns) {
+				t.Errorf("expected columns %v got %v", tc.columns, columns)
+			}
+			if rowidColumn != tc.rowidColumn {
+				t.Errorf("expected rowid column %v got %v", tc.rowidColumn, rowidColumn)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"gpkg feature table": {
+			sql:         `CREATE TABLE 'roads' ( "fid" INTEGER PRIMARY KEY AUTOINCREMENT, "geom" LINESTRING, "addr:street" TEXT)`,
+			columns:     []string{"fid", "geom", "addr:street"},
+			rowidColumn: 0,
+		},
+		"constraints": {
+			sql:         "CREATE TABLE t (a TEXT, id INTEGER NOT NULL PRIMARY KEY, b NUMERIC(10, 2), [primary] TEXT, CONSTRAINT u UNIQUE (a, b))",
+			columns:     []string{"a", "id", "b", "primary"},
+			rowidColumn: 1,
+		},
+		"no rowid alias": {
+			sql:         "CREATE TABLE t (id INT PRIMARY KEY, data BLOB)",
+			columns:     []string{"id", "data"},
+			rowidColumn: -1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestSQLiteRecord(t *testing.T) {
+	// a header of 7 bytes: its length, null, int8, int16, float, 3 byte text, 1 byte blob
+	b := []byte{7, 0, 1, 2, 7, 19, 14, 0xff, 0x01, 0x00, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0, 'a', 'b', 'c', 9}
+	values, err := sqliteRecord(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{nil, int64(-1), int64(256), 1.0, "abc", []byte{9}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v got %v", expected, values)
+	}
+
+	if _, err := sqliteRecord(b[:10]); err == nil {
+		t.Errorf("truncated record, expected an error")
+	}
+}
@@ -0,0 +1,20 @@
+package provider
+
+import (
+	"context"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the id of the tile request, so providers can tag
+// their queries and logs with it for correlation with the access log
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the id of the tile request of the context from WithRequestID, "" when
+// the context has none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
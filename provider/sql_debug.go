@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"sync"
+	"time"
+)
+
+// SQLDebugAllLayers is the layer key used to toggle SQL debugging for every layer of every provider
+const SQLDebugAllLayers = "*"
+
+// SQLDebug describes the SQL debugging output enabled for a layer at runtime.
+// Providers which build SQL (i.e. postgis) should consult SQLDebugFor in addition to
+// any environment variables they support.
+type SQLDebug struct {
+	// LayerSQL enables output of the layer SQL as it's parsed or used
+	LayerSQL bool `json:"layer_sql"`
+	// ExecuteSQL enables output of the SQL executed for each tile request
+	ExecuteSQL bool `json:"execute_sql"`
+	// Expires is when the setting is automatically removed. The zero value never expires.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// expired reports if the setting is no longer in effect at time t
+func (d SQLDebug) expired(t time.Time) bool {
+	return !d.Expires.IsZero() && !t.Before(d.Expires)
+}
+
+var (
+	sqlDebugLock sync.RWMutex
+	// sqlDebug is keyed by provider layer ID
+	sqlDebug = map[string]SQLDebug{}
+)
+
+// SetSQLDebug enables SQL debugging for the layer. Use SQLDebugAllLayers to enable it
+// for all layers. An existing setting for the layer is overwritten.
+func SetSQLDebug(lyrID string, d SQLDebug) {
+	sqlDebugLock.Lock()
+	defer sqlDebugLock.Unlock()
+
+	sqlDebug[lyrID] = d
+}
+
+// ClearSQLDebug removes the runtime SQL debug setting for the layer
+func ClearSQLDebug(lyrID string) {
+	sqlDebugLock.Lock()
+	defer sqlDebugLock.Unlock()
+
+	delete(sqlDebug, lyrID)
+}
+
+// SQLDebugFor returns the SQL debug settings in effect for a layer, combining the
+// layer's own setting with the setting for all layers. Expired settings are ignored.
+func SQLDebugFor(lyrID string) (d SQLDebug) {
+	now := time.Now()
+
+	sqlDebugLock.RLock()
+	defer sqlDebugLock.RUnlock()
+
+	for _, key := range [...]string{SQLDebugAllLayers, lyrID} {
+		s, ok := sqlDebug[key]
+		if !ok || s.expired(now) {
+			continue
+		}
+		d.LayerSQL = d.LayerSQL || s.LayerSQL
+		d.ExecuteSQL = d.ExecuteSQL || s.ExecuteSQL
+	}
+
+	return d
+}
+
+// SQLDebugSettings returns a copy of the SQL debug settings currently in effect keyed by layer ID.
+// Expired settings are purged as part of this call.
+func SQLDebugSettings() map[string]SQLDebug {
+	now := time.Now()
+
+	sqlDebugLock.Lock()
+	defer sqlDebugLock.Unlock()
+
+	settings := make(map[string]SQLDebug, len(sqlDebug))
+	for k, v := range sqlDebug {
+		if v.expired(now) {
+			delete(sqlDebug, k)
+			continue
+		}
+		settings[k] = v
+	}
+
+	return settings
+}
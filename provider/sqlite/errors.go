@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingFilepath  = errors.New("sqlite: provider is missing 'filepath'")
+	ErrMissingLayerName = errors.New("sqlite: layer is missing 'name'")
+)
+
+type ErrInvalidFilePath struct {
+	FilePath string
+}
+
+func (e ErrInvalidFilePath) Error() string {
+	return fmt.Sprintf("sqlite: invalid filepath: %v", e.FilePath)
+}
+
+type ErrUnsupportedSRID struct {
+	SRID int
+}
+
+func (e ErrUnsupportedSRID) Error() string {
+	return fmt.Sprintf("sqlite: unsupported srid (%v), expected 4326 or 3857", e.SRID)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("sqlite: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("sqlite: layer (%v) not found", e.LayerName)
+}
+
+// ErrTablenameOrSQL is returned when a layer doesn't define exactly one of tablename and sql
+type ErrTablenameOrSQL struct {
+	LayerName string
+}
+
+func (e ErrTablenameOrSQL) Error() string {
+	return fmt.Sprintf("sqlite: layer (%v) must define either 'tablename' or 'sql'", e.LayerName)
+}
+
+type ErrInvalidGeometryEncoding struct {
+	LayerName string
+	Encoding  string
+}
+
+func (e ErrInvalidGeometryEncoding) Error() string {
+	return fmt.Sprintf("sqlite: layer (%v) has invalid geometry_encoding (%v), expected wkb, wkt or lonlat", e.LayerName, e.Encoding)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("sqlite: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
+
+// ErrIndexRequiresTablename is returned when an in-memory index is configured for a sql layer
+type ErrIndexRequiresTablename struct {
+	LayerName string
+}
+
+func (e ErrIndexRequiresTablename) Error() string {
+	return fmt.Sprintf("sqlite: layer (%v) can only be indexed when it's configured with a 'tablename'", e.LayerName)
+}
+
+// ErrMissingColumn is returned when the rows of a layer's query lack a geometry column
+type ErrMissingColumn struct {
+	LayerName string
+	Column    string
+}
+
+func (e ErrMissingColumn) Error() string {
+	return fmt.Sprintf("sqlite: layer (%v) query did not return the (%v) column", e.LayerName, e.Column)
+}
+
+// ErrInvalidWKT is returned when a WKT geometry can't be decoded
+type ErrInvalidWKT struct {
+	WKT    string
+	Reason string
+}
+
+func (e ErrInvalidWKT) Error() string {
+	wkt := e.WKT
+	if len(wkt) > 64 {
+		wkt = wkt[:64] + "..."
+	}
+	return fmt.Sprintf("sqlite: invalid wkt (%v): %v", wkt, e.Reason)
+}
@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/provider"
+)
+
+// the max number of grid cells per side of an index
+const maxIndexCells = 1024
+
+type indexEntry struct {
+	rowid  int64
+	extent geom.Extent
+}
+
+// gridIndex is an in-memory index of the extents of a table's rows. The extent of the
+// table is divided into a grid and every cell lists the rows intersecting it.
+type gridIndex struct {
+	extent       geom.Extent
+	cols, rows   int
+	cellW, cellH float64
+	// cells lists the entries of the cells, row by row
+	cells   [][]int32
+	entries []indexEntry
+}
+
+func newGridIndex(entries []indexEntry) *gridIndex {
+	idx := gridIndex{entries: entries}
+	if len(entries) == 0 {
+		return &idx
+	}
+
+	idx.extent = entries[0].extent
+	for i := range entries[1:] {
+		idx.extent.Add(&entries[i+1].extent)
+	}
+
+	// about 4 rows per cell
+	n := int(math.Ceil(math.Sqrt(float64(len(entries)) / 4)))
+	if n < 1 {
+		n = 1
+	}
+	if n > maxIndexCells {
+		n = maxIndexCells
+	}
+	idx.cols, idx.rows = n, n
+	idx.cellW = idx.extent.XSpan() / float64(n)
+	idx.cellH = idx.extent.YSpan() / float64(n)
+	idx.cells = make([][]int32, n*n)
+
+	for i := range entries {
+		minCol, minRow, maxCol, maxRow := idx.cellRange(&entries[i].extent)
+		for r := minRow; r <= maxRow; r++ {
+			for c := minCol; c <= maxCol; c++ {
+				idx.cells[r*idx.cols+c] = append(idx.cells[r*idx.cols+c], int32(i))
+			}
+		}
+	}
+
+	return &idx
+}
+
+// cell returns the cell of the coordinate along an axis, clamped to the grid
+func cell(v, min, size float64, n int) int {
+	if size == 0 {
+		return 0
+	}
+	c := int((v - min) / size)
+	if c < 0 {
+		return 0
+	}
+	if c >= n {
+		return n - 1
+	}
+	return c
+}
+
+// cellRange returns the range of the cells the extent intersects
+func (idx *gridIndex) cellRange(ext *geom.Extent) (minCol, minRow, maxCol, maxRow int) {
+	return cell(ext.MinX(), idx.extent.MinX(), idx.cellW, idx.cols),
+		cell(ext.MinY(), idx.extent.MinY(), idx.cellH, idx.rows),
+		cell(ext.MaxX(), idx.extent.MinX(), idx.cellW, idx.cols),
+		cell(ext.MaxY(), idx.extent.MinY(), idx.cellH, idx.rows)
+}
+
+// query returns the sorted rowids of the rows whose extents intersect the extent
+func (idx *gridIndex) query(ext *geom.Extent) []int64 {
+	if len(idx.entries) == 0 {
+		return nil
+	}
+	if !provider.ExtentsOverlap(&idx.extent, ext) {
+		return nil
+	}
+
+	var matches []int32
+	minCol, minRow, maxCol, maxRow := idx.cellRange(ext)
+	for r := minRow; r <= maxRow; r++ {
+		for c := minCol; c <= maxCol; c++ {
+			for _, i := range idx.cells[r*idx.cols+c] {
+				if provider.ExtentsOverlap(&idx.entries[i].extent, ext) {
+					matches = append(matches, i)
+				}
+			}
+		}
+	}
+
+	// entries spanning several cells are matched more than once
+	sort.Slice(matches, func(i, j int) bool { return matches[i] < matches[j] })
+
+	rowids := make([]int64, 0, len(matches))
+	for i, m := range matches {
+		if i > 0 && m == matches[i-1] {
+			continue
+		}
+		rowids = append(rowids, idx.entries[m].rowid)
+	}
+	return rowids
+}
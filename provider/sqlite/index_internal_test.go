@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+)
+
+func TestGridIndexQuery(t *testing.T) {
+	type tcase struct {
+		extent   geom.Extent
+		expected []int64
+	}
+
+	var entries []indexEntry
+	// a 10 x 10 grid of points
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			entries = append(entries, indexEntry{
+				rowid:  int64(x*10 + y + 1),
+				extent: geom.Extent{float64(x), float64(y), float64(x), float64(y)},
+			})
+		}
+	}
+	// a line spanning the cells of the grid
+	entries = append(entries, indexEntry{rowid: 1000, extent: geom.Extent{0, 4.5, 9, 4.5}})
+
+	idx := newGridIndex(entries)
+
+	var all []int64
+	for i := int64(1); i <= 100; i++ {
+		all = append(all, i)
+	}
+	all = append(all, 1000)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			rowids := idx.query(&tc.extent)
+			if !reflect.DeepEqual(rowids, tc.expected) {
+				t.Errorf("expected %v got %v", tc.expected, rowids)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"single point": {
+			extent:   geom.Extent{2.5, 2.5, 3, 3},
+			expected: []int64{34},
+		},
+		"touching": {
+			extent:   geom.Extent{8.5, 4, 9, 4.5},
+			expected: []int64{95, 1000},
+		},
+		"outside": {
+			extent:   geom.Extent{20, 20, 30, 30},
+			expected: nil,
+		},
+		"everything": {
+			extent:   geom.Extent{-1, -1, 11, 11},
+			expected: all,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,140 @@
+package sqlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom"
+)
+
+// geometry encodings
+const (
+	GeometryEncodingWKB    = "wkb"
+	GeometryEncodingWKT    = "wkt"
+	GeometryEncodingLonLat = "lonlat"
+)
+
+// tokens replaced in the layer sql
+const (
+	bboxToken = "!BBOX!"
+	minxToken = "!MINX!"
+	minyToken = "!MINY!"
+	maxxToken = "!MAXX!"
+	maxyToken = "!MAXY!"
+	zoomToken = "!ZOOM!"
+	// rowidsToken is replaced with the rowids selected by the index of a layer
+	rowidsToken = "!ROWIDS!"
+)
+
+type Layer struct {
+	name string
+	// tablename is empty for sql layers
+	tablename string
+	// sql of the layer with the tokens to be replaced per tile
+	sql string
+	// encoding of the geometry, one of the GeometryEncoding values
+	encoding  string
+	geomField string
+	// lonField and latField are the point columns of the lonlat encoding
+	lonField, latField string
+	idField            string
+	// filter is set when the rows aren't filtered by the sql, so the geometries are
+	// filtered by their extents instead
+	filter bool
+	// index is the in-memory index of the table's rows, nil when not indexed
+	index    *gridIndex
+	geomType geom.Geometry
+	srid     uint64
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
+
+// quote returns the identifier quoted for SQLite
+func quote(id string) string {
+	return `"` + strings.Replace(id, `"`, `""`, -1) + `"`
+}
+
+// geometryColumns returns the quoted geometry columns of the layer
+func (l Layer) geometryColumns() []string {
+	if l.encoding == GeometryEncodingLonLat {
+		return []string{quote(l.lonField), quote(l.latField)}
+	}
+	return []string{quote(l.geomField)}
+}
+
+// selectClause returns the select clause of a table layer. The id is aliased so the
+// column keeps its name when it's an alias of the rowid.
+func (l Layer) selectClause(fields []string) string {
+	cols := append([]string{quote(l.idField) + " AS " + quote(l.idField)}, l.geometryColumns()...)
+	for _, f := range fields {
+		if f == l.idField || f == l.geomField || (l.encoding == GeometryEncodingLonLat && (f == l.lonField || f == l.latField)) {
+			continue
+		}
+		cols = append(cols, quote(f))
+	}
+	return "SELECT " + strings.Join(cols, ", ")
+}
+
+// tableSQL generates the sql of a table layer. Points are filtered by their lon / lat columns
+// and other geometries by the bboxFields (the minx, miny, maxx and maxy columns of the rows'
+// bounding boxes) when set.
+func (l *Layer) tableSQL(fields, bboxFields []string) {
+	where := []string{}
+	switch {
+	case l.encoding == GeometryEncodingLonLat:
+		where = append(where,
+			quote(l.lonField)+" >= "+minxToken,
+			quote(l.lonField)+" <= "+maxxToken,
+			quote(l.latField)+" >= "+minyToken,
+			quote(l.latField)+" <= "+maxyToken,
+		)
+	case len(bboxFields) == 4:
+		where = append(where,
+			quote(bboxFields[2])+" >= "+minxToken,
+			quote(bboxFields[0])+" <= "+maxxToken,
+			quote(bboxFields[3])+" >= "+minyToken,
+			quote(bboxFields[1])+" <= "+maxyToken,
+		)
+	default:
+		where = append(where, quote(l.geomField)+" IS NOT NULL")
+		l.filter = true
+	}
+
+	l.sql = fmt.Sprintf("%v FROM %v WHERE %v", l.selectClause(fields), quote(l.tablename), strings.Join(where, " AND "))
+}
+
+// indexedSQL returns the sql selecting the rows of an indexed table layer by rowid
+func (l Layer) indexedSQL(fields []string) string {
+	return fmt.Sprintf("%v FROM %v WHERE rowid IN (%v) ORDER BY rowid", l.selectClause(fields), quote(l.tablename), rowidsToken)
+}
+
+// replaceTokens fills the layer's sql with the tile's bbox, in the layer's SRID, and zoom.
+// !BBOX! is replaced with an overlap test of the minx, miny, maxx and maxy columns, as the
+// gpkg provider does.
+func replaceTokens(sql string, ext *geom.Extent, z uint) string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	r := strings.NewReplacer(
+		bboxToken, fmt.Sprintf("minx <= %v AND maxx >= %v AND miny <= %v AND maxy >= %v", f(ext.MaxX()), f(ext.MinX()), f(ext.MaxY()), f(ext.MinY())),
+		minxToken, f(ext.MinX()),
+		minyToken, f(ext.MinY()),
+		maxxToken, f(ext.MaxX()),
+		maxyToken, f(ext.MaxY()),
+		zoomToken, strconv.FormatUint(uint64(z), 10),
+	)
+	return r.Replace(sql)
+}
+
+// hasBBoxToken reports if the sql filters its rows with the tile's bbox
+func hasBBoxToken(sql string) bool {
+	for _, t := range []string{bboxToken, minxToken, minyToken, maxxToken, maxyToken} {
+		if strings.Contains(sql, t) {
+			return true
+		}
+	}
+	return false
+}
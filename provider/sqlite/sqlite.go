@@ -0,0 +1,335 @@
+// Package sqlite provides a provider for plain SQLite databases, where the geometries of
+// the tables are stored as WKB or WKT columns or as lon / lat point columns, without the
+// metadata tables of a GeoPackage. Rows are filtered by the tile's bbox in the sql when
+// the layer has point or bounding box columns, by the extents of their geometries
+// otherwise, or with an optional in-memory index built when the provider starts.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkb"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "sqlite"
+
+const (
+	ConfigKeyFilePath = "filepath"
+	ConfigKeySRID     = "srid"
+	ConfigKeyLayers   = "layers"
+
+	ConfigKeyLayerName    = "name"
+	ConfigKeyTablename    = "tablename"
+	ConfigKeySQL          = "sql"
+	ConfigKeyGeomEncoding = "geometry_encoding"
+	ConfigKeyGeomField    = "geometry_fieldname"
+	ConfigKeyLonField     = "lon_fieldname"
+	ConfigKeyLatField     = "lat_fieldname"
+	ConfigKeyIDField      = "id_fieldname"
+	ConfigKeyFields       = "fields"
+	ConfigKeyBBoxFields   = "bbox_fieldnames"
+	ConfigKeyIndex        = "index"
+	ConfigKeyGeomType     = "geometry_type"
+	ConfigKeyLayerSRID    = "srid"
+)
+
+const (
+	DefaultSRID         = tegola.WGS84
+	DefaultGeomEncoding = GeometryEncodingWKB
+	DefaultGeomField    = "geom"
+	DefaultLonField     = "lon"
+	DefaultLatField     = "lat"
+	DefaultIDField      = "rowid"
+)
+
+// the max number of rowids selected per query of an indexed layer
+const indexQueryBatch = 500
+
+// Provider queries the tables of a SQLite database
+type Provider struct {
+	db   *sql.DB
+	srid uint64
+	// map of layer name and corresponding sql
+	layers map[string]Layer
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures sends the features of the layer intersecting the tile's buffered extent to fn
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	ext, err := provider.QueryExtent(tile, layer.srid)
+	if err != nil {
+		return err
+	}
+
+	if layer.index != nil {
+		// the extents of the indexed rows have been matched already
+		rowids := layer.index.query(ext)
+		for len(rowids) > 0 {
+			n := len(rowids)
+			if n > indexQueryBatch {
+				n = indexQueryBatch
+			}
+
+			ids := make([]string, n)
+			for i, id := range rowids[:n] {
+				ids[i] = strconv.FormatInt(id, 10)
+			}
+			rowids = rowids[n:]
+
+			if err := p.query(ctx, layer, strings.Replace(layer.sql, rowidsToken, strings.Join(ids, ","), 1), nil, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var filter *geom.Extent
+	if layer.filter {
+		filter = ext
+	}
+
+	z, _, _ := tile.ZXY()
+	return p.query(ctx, layer, replaceTokens(layer.sql, ext, z), filter, fn)
+}
+
+// query sends the features of the rows of the sql to fn. When filter is set only the
+// features whose geometries intersect it are sent.
+func (p *Provider) query(ctx context.Context, layer Layer, sql string, filter *geom.Extent, fn func(f *provider.Feature) error) error {
+	if provider.SQLDebugFor(layer.name).ExecuteSQL {
+		log.Debugf("TEGOLA_SQL_DEBUG:EXECUTE_SQL for layer (%v): %v", layer.name, sql)
+	}
+
+	rows, err := p.db.QueryContext(ctx, sql)
+	if err != nil {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	c, err := layer.columns(cols)
+	if err != nil {
+		return err
+	}
+
+	vals := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+
+	for rows.Next() {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+
+		g, err := layer.decodeGeometry(c, vals)
+		if err != nil {
+			return fmt.Errorf("sqlite: layer (%v): %v", layer.name, err)
+		}
+		if g == nil {
+			continue
+		}
+
+		if filter != nil {
+			ext, err := geom.NewExtentFromGeometry(g)
+			if err != nil || !provider.ExtentsOverlap(ext, filter) {
+				continue
+			}
+		}
+
+		f := provider.Feature{
+			Geometry: g,
+			SRID:     layer.srid,
+			Tags:     map[string]interface{}{},
+		}
+
+		for i, v := range vals {
+			switch i {
+			case c.geom, c.lon, c.lat:
+			case c.id:
+				if v == nil {
+					continue
+				}
+				if f.ID, err = provider.ConvertFeatureID(v); err != nil {
+					return fmt.Errorf("sqlite: layer (%v) has an invalid id: %v", layer.name, err)
+				}
+			default:
+				setTag(f.Tags, cols[i], v)
+			}
+		}
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return provider.ErrCanceled
+	}
+	return rows.Err()
+}
+
+// Close closes the database of the provider
+func (p *Provider) Close() error {
+	return p.db.Close()
+}
+
+// layerColumns are the indices of the special columns of a layer's rows, -1 when absent
+type layerColumns struct {
+	id, geom, lon, lat int
+}
+
+// columns returns the indices of the layer's id and geometry columns
+func (l Layer) columns(cols []string) (layerColumns, error) {
+	c := layerColumns{id: -1, geom: -1, lon: -1, lat: -1}
+	for i, name := range cols {
+		switch {
+		case name == l.idField:
+			c.id = i
+		case l.encoding == GeometryEncodingLonLat && name == l.lonField:
+			c.lon = i
+		case l.encoding == GeometryEncodingLonLat && name == l.latField:
+			c.lat = i
+		case l.encoding != GeometryEncodingLonLat && name == l.geomField:
+			c.geom = i
+		}
+	}
+
+	switch {
+	case l.encoding != GeometryEncodingLonLat && c.geom == -1:
+		return c, ErrMissingColumn{LayerName: l.name, Column: l.geomField}
+	case l.encoding == GeometryEncodingLonLat && c.lon == -1:
+		return c, ErrMissingColumn{LayerName: l.name, Column: l.lonField}
+	case l.encoding == GeometryEncodingLonLat && c.lat == -1:
+		return c, ErrMissingColumn{LayerName: l.name, Column: l.latField}
+	}
+	return c, nil
+}
+
+// decodeGeometry decodes the geometry of a row. nil is returned for null geometries.
+func (l Layer) decodeGeometry(c layerColumns, vals []interface{}) (geom.Geometry, error) {
+	switch l.encoding {
+	case GeometryEncodingLonLat:
+		lon, ok, err := toFloat(vals[c.lon])
+		if err != nil || !ok {
+			return nil, err
+		}
+		lat, ok, err := toFloat(vals[c.lat])
+		if err != nil || !ok {
+			return nil, err
+		}
+		return geom.Point{lon, lat}, nil
+
+	case GeometryEncodingWKT:
+		switch v := vals[c.geom].(type) {
+		case nil:
+			return nil, nil
+		case string:
+			return decodeWKT(v)
+		case []byte:
+			return decodeWKT(string(v))
+		default:
+			return nil, fmt.Errorf("expected a wkt geometry, got %T", v)
+		}
+
+	default:
+		switch v := vals[c.geom].(type) {
+		case nil:
+			return nil, nil
+		case []byte:
+			return wkb.DecodeBytes(v)
+		case string:
+			return wkb.DecodeBytes([]byte(v))
+		default:
+			return nil, fmt.Errorf("expected a wkb geometry, got %T", v)
+		}
+	}
+}
+
+// toFloat converts a coordinate value. false is returned for null values.
+func toFloat(v interface{}) (float64, bool, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, false, nil
+	case float64:
+		return val, true, nil
+	case int64:
+		return float64(val), true, nil
+	case []byte:
+		f, err := strconv.ParseFloat(string(val), 64)
+		return f, err == nil, err
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil, err
+	default:
+		return 0, false, fmt.Errorf("expected a numeric coordinate, got %T", v)
+	}
+}
+
+// setTag adds the value to the tags. null values are dropped, blobs are converted to
+// strings and times are formatted with RFC 3339
+func setTag(tags map[string]interface{}, k string, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+	case []byte:
+		tags[k] = string(val)
+	case time.Time:
+		tags[k] = val.Format(time.RFC3339)
+	default:
+		tags[k] = v
+	}
+}
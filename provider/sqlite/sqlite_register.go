@@ -0,0 +1,290 @@
+// +build cgo
+
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, Cleanup)
+}
+
+// NewTileProvider instantiates and returns a new sqlite provider or an error.
+//
+//	filepath (string): [Required] the path of the SQLite database
+//	srid (int): [Optional] the default SRID of the layers. 4326 or 3857. defaults to 4326
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		tablename (string): [*Required] the table to query
+//		sql (string): [*Required] custom sql, optionally with a !BBOX! token or the !MINX!, !MINY!, !MAXX! and !MAXY! tokens
+//		geometry_encoding (string): [Optional] the encoding of the geometries, wkb, wkt or lonlat. defaults to wkb
+//		geometry_fieldname (string): [Optional] the wkb or wkt geometry column. defaults to geom
+//		lon_fieldname (string): [Optional] the longitude column of the lonlat encoding. defaults to lon
+//		lat_fieldname (string): [Optional] the latitude column of the lonlat encoding. defaults to lat
+//		id_fieldname (string): [Optional] the feature id column. defaults to rowid
+//		fields ([]string): [Optional] the columns of the tablename included as tags
+//		bbox_fieldnames ([]string): [Optional] the minx, miny, maxx and maxy columns of the tablename's feature bounding boxes
+//		index (bool): [Optional] build an in-memory index of the tablename's geometries when the provider starts. defaults to false
+//		geometry_type (string): [Optional] the geometry type of the layer, reported in the capabilities
+//		srid (int): [Optional] the SRID of the layer. defaults to the provider's srid
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	empty := ""
+
+	filepath, err := config.String(ConfigKeyFilePath, &empty)
+	if err != nil {
+		return nil, err
+	}
+	if filepath == "" {
+		return nil, ErrMissingFilepath
+	}
+
+	// check the file exists, sqlite would create it otherwise
+	if _, err := os.Stat(filepath); os.IsNotExist(err) {
+		return nil, ErrInvalidFilePath{FilePath: filepath}
+	}
+
+	srid := DefaultSRID
+	if srid, err = config.Int(ConfigKeySRID, &srid); err != nil {
+		return nil, err
+	}
+	if srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return nil, ErrUnsupportedSRID{SRID: srid}
+	}
+
+	db, err := sql.Open("sqlite3", filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Provider{
+		db:     db,
+		srid:   uint64(srid),
+		layers: map[string]Layer{},
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	providers = append(providers, p)
+
+	return &p, nil
+}
+
+// AddLayer adds a table or sql layer to the provider. The index of an indexed layer is
+// built before the layer is added.
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	strs := []struct {
+		key string
+		val string
+	}{
+		{ConfigKeyTablename, ""},
+		{ConfigKeySQL, ""},
+		{ConfigKeyGeomEncoding, DefaultGeomEncoding},
+		{ConfigKeyGeomField, DefaultGeomField},
+		{ConfigKeyLonField, DefaultLonField},
+		{ConfigKeyLatField, DefaultLatField},
+		{ConfigKeyIDField, DefaultIDField},
+		{ConfigKeyGeomType, ""},
+	}
+	for i := range strs {
+		if strs[i].val, err = layerConf.String(strs[i].key, &strs[i].val); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, strs[i].key, err)
+		}
+	}
+	tablename, sql, gtype := strs[0].val, strs[1].val, strs[7].val
+
+	if (tablename == "") == (sql == "") {
+		return ErrTablenameOrSQL{LayerName: name}
+	}
+
+	l := Layer{
+		name:      name,
+		tablename: tablename,
+		sql:       sql,
+		encoding:  strings.ToLower(strs[2].val),
+		geomField: strs[3].val,
+		lonField:  strs[4].val,
+		latField:  strs[5].val,
+		idField:   strs[6].val,
+	}
+
+	switch l.encoding {
+	case GeometryEncodingWKB, GeometryEncodingWKT, GeometryEncodingLonLat:
+	default:
+		return ErrInvalidGeometryEncoding{LayerName: name, Encoding: l.encoding}
+	}
+
+	index := false
+	if index, err = layerConf.Bool(ConfigKeyIndex, &index); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyIndex, err)
+	}
+
+	if tablename != "" {
+		fields, err := layerConf.StringSlice(ConfigKeyFields)
+		if err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyFields, err)
+		}
+
+		bboxFields, err := layerConf.StringSlice(ConfigKeyBBoxFields)
+		if err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyBBoxFields, err)
+		}
+		if len(bboxFields) != 0 && len(bboxFields) != 4 {
+			return fmt.Errorf("for layer (%v) %v has an error: expected the minx, miny, maxx and maxy fields, got %v", name, ConfigKeyBBoxFields, bboxFields)
+		}
+
+		if index {
+			l.sql = l.indexedSQL(fields)
+		} else {
+			l.tableSQL(fields, bboxFields)
+		}
+	} else {
+		if index {
+			return ErrIndexRequiresTablename{LayerName: name}
+		}
+		// rows of sql without a bbox are filtered by their geometries
+		l.filter = !hasBBoxToken(sql)
+	}
+
+	srid := int(p.srid)
+	if srid, err = layerConf.Int(ConfigKeyLayerSRID, &srid); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyLayerSRID, err)
+	}
+	if srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return ErrUnsupportedSRID{SRID: srid}
+	}
+	l.srid = uint64(srid)
+
+	var ok bool
+	if l.geomType, ok = provider.GeometryType(gtype); !ok {
+		return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+	}
+
+	if index {
+		if l.index, err = p.buildIndex(l); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyIndex, err)
+		}
+	}
+
+	p.layers[name] = l
+
+	return nil
+}
+
+// buildIndex reads the geometries of the layer's table and indexes their extents
+func (p *Provider) buildIndex(l Layer) (*gridIndex, error) {
+	// the geometry columns are selected as the layer's sql would, so the rowid is the id
+	// of the rows
+	sql := fmt.Sprintf("SELECT rowid AS %v, %v FROM %v", quote("rowid"), strings.Join(l.geometryColumns(), ", "), quote(l.tablename))
+
+	rows, err := p.db.Query(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	// the rowid column is the first column whatever the id of the layer is
+	il := l
+	il.idField = "rowid"
+	c, err := il.columns(cols)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []indexEntry
+	vals := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		rowid, ok := vals[0].(int64)
+		if !ok {
+			return nil, fmt.Errorf("expected an integer rowid, got %T", vals[0])
+		}
+
+		g, err := il.decodeGeometry(c, vals)
+		if err != nil {
+			return nil, fmt.Errorf("row (%v): %v", rowid, err)
+		}
+		if g == nil {
+			continue
+		}
+
+		ext, err := geom.NewExtentFromGeometry(g)
+		if err != nil {
+			// geometries without points, i.e. empty collections
+			continue
+		}
+
+		entries = append(entries, indexEntry{rowid: rowid, extent: *ext})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	log.Infof("sqlite: indexed %v rows of layer (%v)", len(entries), l.name)
+
+	return newGridIndex(entries), nil
+}
+
+// reference to all instantiated providers
+var providers []Provider
+
+// Cleanup will close all database connections and destroy all previously instantiated Provider instances
+func Cleanup() {
+	if len(providers) > 0 {
+		log.Infof("cleaning up sqlite providers")
+	}
+
+	for i := range providers {
+		if err := providers[i].Close(); err != nil {
+			log.Errorf("err closing connection: %v", err)
+		}
+	}
+
+	providers = make([]Provider, 0)
+}
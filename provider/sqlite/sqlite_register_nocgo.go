@@ -0,0 +1,11 @@
+// +build !cgo
+
+package sqlite
+
+import "github.com/go-spatial/tegola/provider"
+
+func NewTileProvider(config map[string]interface{}) (provider.Tiler, error) {
+	return nil, provider.ErrUnsupported
+}
+
+func Cleanup() {}
@@ -0,0 +1,282 @@
+// +build cgo
+
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkb"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/sqlite"
+)
+
+// newDB creates a database with the same places stored as wkb, wkt and lon / lat columns
+func newDB(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "tegola-sqlite")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(dir, "places.sqlite")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	stmts := []string{
+		"CREATE TABLE places_wkb (id INTEGER PRIMARY KEY, geom BLOB, name TEXT, pop INTEGER, minx REAL, miny REAL, maxx REAL, maxy REAL)",
+		"CREATE TABLE places_wkt (geom TEXT, name TEXT, pop INTEGER)",
+		"CREATE TABLE places_lonlat (fid INTEGER, lon REAL, lat REAL, name TEXT, pop INTEGER)",
+	}
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	places := []struct {
+		name string
+		pt   geom.Point
+		wkt  string
+		pop  interface{}
+	}{
+		{"San Francisco", geom.Point{-122.4, 37.6}, "POINT (-122.4 37.6)", 870000},
+		{"Paris", geom.Point{2.35, 48.85}, "SRID=4326;POINT Z (2.35 48.85 35)", 2100000},
+		{"London", geom.Point{-0.1, 51.5}, "POINT(-0.1 51.5)", nil},
+	}
+	for i, pl := range places {
+		b, err := wkb.EncodeBytes(pl.pt)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO places_wkb VALUES (?, ?, ?, ?, ?, ?, ?, ?)", i+1, b, pl.name, pl.pop, pl.pt[0], pl.pt[1], pl.pt[0], pl.pt[1]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO places_wkt VALUES (?, ?, ?)", pl.wkt, pl.name, pl.pop); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO places_lonlat VALUES (?, ?, ?, ?, ?)", i+10, pl.pt[0], pl.pt[1], pl.name, pl.pop); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// rows without geometries are skipped
+	if _, err := db.Exec("INSERT INTO places_wkt VALUES (NULL, 'nowhere', 0)"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		layer    map[string]interface{}
+		expected []provider.Feature
+	}
+
+	path, cleanup := newDB(t)
+	defer cleanup()
+
+	place := func(id uint64, pt geom.Point, tags map[string]interface{}) provider.Feature {
+		return provider.Feature{ID: id, Geometry: pt, SRID: tegola.WGS84, Tags: tags}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer["name"] = "test"
+			p, err := sqlite.NewTileProvider(dict.Dict{
+				"filepath": path,
+				"layers":   []map[string]interface{}{tc.layer},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer p.(*sqlite.Provider).Close()
+
+			// the north west quarter of the world, without Paris
+			var features []provider.Feature
+			err = p.TileFeatures(context.Background(), "test", provider.NewTile(1, 0, 0, 0, tegola.WebMercator), func(f *provider.Feature) error {
+				features = append(features, *f)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(features, tc.expected) {
+				t.Errorf("features, expected %+v got %+v", tc.expected, features)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"wkb": {
+			layer: map[string]interface{}{
+				"tablename":    "places_wkb",
+				"id_fieldname": "id",
+				"fields":       []string{"name", "pop"},
+			},
+			expected: []provider.Feature{
+				place(1, geom.Point{-122.4, 37.6}, map[string]interface{}{"name": "San Francisco", "pop": int64(870000)}),
+				place(3, geom.Point{-0.1, 51.5}, map[string]interface{}{"name": "London"}),
+			},
+		},
+		"wkb bbox fieldnames": {
+			layer: map[string]interface{}{
+				"tablename":       "places_wkb",
+				"id_fieldname":    "id",
+				"fields":          []string{"name"},
+				"bbox_fieldnames": []string{"minx", "miny", "maxx", "maxy"},
+			},
+			expected: []provider.Feature{
+				place(1, geom.Point{-122.4, 37.6}, map[string]interface{}{"name": "San Francisco"}),
+				place(3, geom.Point{-0.1, 51.5}, map[string]interface{}{"name": "London"}),
+			},
+		},
+		"wkb indexed": {
+			layer: map[string]interface{}{
+				"tablename":    "places_wkb",
+				"id_fieldname": "id",
+				"fields":       []string{"name"},
+				"index":        true,
+			},
+			expected: []provider.Feature{
+				place(1, geom.Point{-122.4, 37.6}, map[string]interface{}{"name": "San Francisco"}),
+				place(3, geom.Point{-0.1, 51.5}, map[string]interface{}{"name": "London"}),
+			},
+		},
+		"wkt rowid": {
+			layer: map[string]interface{}{
+				"tablename":         "places_wkt",
+				"geometry_encoding": "wkt",
+				"fields":            []string{"name"},
+			},
+			expected: []provider.Feature{
+				place(1, geom.Point{-122.4, 37.6}, map[string]interface{}{"name": "San Francisco"}),
+				place(3, geom.Point{-0.1, 51.5}, map[string]interface{}{"name": "London"}),
+			},
+		},
+		"wkt indexed": {
+			layer: map[string]interface{}{
+				"tablename":         "places_wkt",
+				"geometry_encoding": "wkt",
+				"fields":            []string{"name"},
+				"index":             true,
+			},
+			expected: []provider.Feature{
+				place(1, geom.Point{-122.4, 37.6}, map[string]interface{}{"name": "San Francisco"}),
+				place(3, geom.Point{-0.1, 51.5}, map[string]interface{}{"name": "London"}),
+			},
+		},
+		"lonlat": {
+			layer: map[string]interface{}{
+				"tablename":         "places_lonlat",
+				"geometry_encoding": "lonlat",
+				"id_fieldname":      "fid",
+				"fields":            []string{"name"},
+			},
+			expected: []provider.Feature{
+				place(10, geom.Point{-122.4, 37.6}, map[string]interface{}{"name": "San Francisco"}),
+				place(12, geom.Point{-0.1, 51.5}, map[string]interface{}{"name": "London"}),
+			},
+		},
+		"sql with tokens": {
+			layer: map[string]interface{}{
+				"sql":               "SELECT fid, lon, lat, name FROM places_lonlat WHERE lon BETWEEN !MINX! AND !MAXX! AND lat BETWEEN !MINY! AND !MAXY! AND !ZOOM! = 1 ORDER BY fid",
+				"geometry_encoding": "lonlat",
+				"id_fieldname":      "fid",
+			},
+			expected: []provider.Feature{
+				place(10, geom.Point{-122.4, 37.6}, map[string]interface{}{"name": "San Francisco"}),
+				place(12, geom.Point{-0.1, 51.5}, map[string]interface{}{"name": "London"}),
+			},
+		},
+		"sql filtered by geometry": {
+			layer: map[string]interface{}{
+				"sql":               "SELECT geom, name FROM places_wkt WHERE pop > 0",
+				"geometry_encoding": "wkt",
+			},
+			expected: []provider.Feature{
+				place(0, geom.Point{-122.4, 37.6}, map[string]interface{}{"name": "San Francisco"}),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		config dict.Dict
+		err    string
+	}
+
+	path, cleanup := newDB(t)
+	defer cleanup()
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := sqlite.NewTileProvider(tc.config)
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing filepath": {
+			config: dict.Dict{},
+			err:    sqlite.ErrMissingFilepath.Error(),
+		},
+		"invalid filepath": {
+			config: dict.Dict{"filepath": path + ".missing"},
+			err:    "sqlite: invalid filepath: " + path + ".missing",
+		},
+		"tablename and sql": {
+			config: dict.Dict{
+				"filepath": path,
+				"layers":   []map[string]interface{}{{"name": "test", "tablename": "a", "sql": "SELECT 1"}},
+			},
+			err: "sqlite: layer (test) must define either 'tablename' or 'sql'",
+		},
+		"invalid geometry encoding": {
+			config: dict.Dict{
+				"filepath": path,
+				"layers":   []map[string]interface{}{{"name": "test", "tablename": "places_wkb", "geometry_encoding": "geojson"}},
+			},
+			err: "sqlite: layer (test) has invalid geometry_encoding (geojson), expected wkb, wkt or lonlat",
+		},
+		"indexed sql": {
+			config: dict.Dict{
+				"filepath": path,
+				"layers":   []map[string]interface{}{{"name": "test", "sql": "SELECT geom FROM places_wkb", "index": true}},
+			},
+			err: "sqlite: layer (test) can only be indexed when it's configured with a 'tablename'",
+		},
+		"indexed invalid wkt": {
+			config: dict.Dict{
+				"filepath": path,
+				"layers":   []map[string]interface{}{{"name": "test", "tablename": "places_wkb", "geometry_fieldname": "name", "geometry_encoding": "wkt", "index": true}},
+			},
+			err: "for layer (test) index has an error: row (1): sqlite: invalid wkt (San Francisco): unsupported geometry type (SAN) at offset 3",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
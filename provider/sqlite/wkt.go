@@ -0,0 +1,298 @@
+package sqlite
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom"
+)
+
+// decodeWKT decodes the 2D geometry of a WKT (or EWKT) string. Z and M ordinates are dropped.
+func decodeWKT(s string) (geom.Geometry, error) {
+	p := wktParser{s: s}
+
+	// EWKT srid prefix, i.e. SRID=4326;POINT(1 2)
+	if i := strings.IndexByte(s, ';'); i != -1 && strings.HasPrefix(strings.ToUpper(strings.TrimSpace(s[:i])), "SRID=") {
+		p.pos = i + 1
+	}
+
+	g, err := p.geometry()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return nil, p.errorf("unexpected trailing characters")
+	}
+	return g, nil
+}
+
+// the supported geometry types
+var wktTypes = map[string]bool{
+	"POINT":              true,
+	"LINESTRING":         true,
+	"POLYGON":            true,
+	"MULTIPOINT":         true,
+	"MULTILINESTRING":    true,
+	"MULTIPOLYGON":       true,
+	"GEOMETRYCOLLECTION": true,
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) errorf(reason string) error {
+	return ErrInvalidWKT{WKT: p.s, Reason: reason + " at offset " + strconv.Itoa(p.pos)}
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+// word reads the next upper cased word, i.e. the geometry type
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			break
+		}
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+// peek returns the next non space character, or 0 at the end
+func (p *wktParser) peek() byte {
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *wktParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("expected '" + string(c) + "'")
+	}
+	p.pos++
+	return nil
+}
+
+// empty reads the EMPTY keyword or the opening parenthesis of the geometry's body.
+// true is returned for empty geometries.
+func (p *wktParser) empty() (bool, error) {
+	if p.peek() == '(' {
+		p.pos++
+		return false, nil
+	}
+
+	start := p.pos
+	if p.word() == "EMPTY" {
+		return true, nil
+	}
+	p.pos = start
+	return false, p.errorf("expected '(' or EMPTY")
+}
+
+// list reads the comma separated items of a parenthesized list whose opening parenthesis has been read
+func (p *wktParser) list(item func() error) error {
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return nil
+		default:
+			return p.errorf("expected ',' or ')'")
+		}
+	}
+}
+
+func (p *wktParser) number() (float64, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if (c < '0' || c > '9') && c != '.' && c != '-' && c != '+' && c != 'e' && c != 'E' {
+			break
+		}
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return 0, p.errorf("expected a number")
+	}
+	return f, nil
+}
+
+// point reads the ordinates of a point, keeping x and y
+func (p *wktParser) point() ([2]float64, error) {
+	var pt [2]float64
+	var err error
+	if pt[0], err = p.number(); err != nil {
+		return pt, err
+	}
+	if pt[1], err = p.number(); err != nil {
+		return pt, err
+	}
+	// z and m
+	for c := p.peek(); c != ',' && c != ')' && c != 0; c = p.peek() {
+		if _, err := p.number(); err != nil {
+			return pt, err
+		}
+	}
+	return pt, nil
+}
+
+// points reads a parenthesized list of points
+func (p *wktParser) points() ([][2]float64, error) {
+	empty, err := p.empty()
+	if err != nil || empty {
+		return nil, err
+	}
+
+	var pts [][2]float64
+	err = p.list(func() error {
+		pt, err := p.point()
+		pts = append(pts, pt)
+		return err
+	})
+	return pts, err
+}
+
+// rings reads a parenthesized list of point lists
+func (p *wktParser) rings() ([][][2]float64, error) {
+	empty, err := p.empty()
+	if err != nil || empty {
+		return nil, err
+	}
+
+	var rings [][][2]float64
+	err = p.list(func() error {
+		ring, err := p.points()
+		rings = append(rings, ring)
+		return err
+	})
+	return rings, err
+}
+
+func (p *wktParser) geometry() (geom.Geometry, error) {
+	typ := p.word()
+
+	// the dimensions of the ordinates, i.e. POINT Z (1 2 3) or POINTZM (1 2 3 4)
+	if !wktTypes[typ] {
+		for _, dims := range []string{"ZM", "Z", "M"} {
+			if t := strings.TrimSuffix(typ, dims); wktTypes[t] {
+				typ = t
+				break
+			}
+		}
+	}
+	start := p.pos
+	switch p.word() {
+	case "Z", "M", "ZM":
+	default:
+		p.pos = start
+	}
+
+	switch typ {
+	case "POINT":
+		empty, err := p.empty()
+		if err != nil || empty {
+			return nil, err
+		}
+		pt, err := p.point()
+		if err != nil {
+			return nil, err
+		}
+		return geom.Point(pt), p.expect(')')
+
+	case "LINESTRING":
+		pts, err := p.points()
+		if err != nil || pts == nil {
+			return nil, err
+		}
+		return geom.LineString(pts), nil
+
+	case "POLYGON":
+		rings, err := p.rings()
+		if err != nil || rings == nil {
+			return nil, err
+		}
+		return geom.Polygon(rings), nil
+
+	case "MULTIPOINT":
+		empty, err := p.empty()
+		if err != nil || empty {
+			return nil, err
+		}
+		var pts geom.MultiPoint
+		err = p.list(func() error {
+			// the points may or may not be parenthesized
+			paren := p.peek() == '('
+			if paren {
+				p.pos++
+			}
+			pt, err := p.point()
+			if err != nil {
+				return err
+			}
+			pts = append(pts, pt)
+			if paren {
+				return p.expect(')')
+			}
+			return nil
+		})
+		return pts, err
+
+	case "MULTILINESTRING":
+		lines, err := p.rings()
+		if err != nil || lines == nil {
+			return nil, err
+		}
+		return geom.MultiLineString(lines), nil
+
+	case "MULTIPOLYGON":
+		empty, err := p.empty()
+		if err != nil || empty {
+			return nil, err
+		}
+		var polys geom.MultiPolygon
+		err = p.list(func() error {
+			rings, err := p.rings()
+			polys = append(polys, rings)
+			return err
+		})
+		return polys, err
+
+	case "GEOMETRYCOLLECTION":
+		empty, err := p.empty()
+		if err != nil || empty {
+			return nil, err
+		}
+		var col geom.Collection
+		err = p.list(func() error {
+			g, err := p.geometry()
+			if g != nil {
+				col = append(col, g)
+			}
+			return err
+		})
+		return col, err
+
+	default:
+		return nil, p.errorf("unsupported geometry type (" + typ + ")")
+	}
+}
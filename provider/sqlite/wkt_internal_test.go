@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+)
+
+func TestDecodeWKT(t *testing.T) {
+	type tcase struct {
+		wkt      string
+		expected geom.Geometry
+		err      string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			g, err := decodeWKT(tc.wkt)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("expected error %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(g, tc.expected) {
+				t.Errorf("expected %#v got %#v", tc.expected, g)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"point": {
+			wkt:      "POINT (1 2)",
+			expected: geom.Point{1, 2},
+		},
+		"point z ewkt": {
+			wkt:      "SRID=4326;point z (1.5 -2e1 3)",
+			expected: geom.Point{1.5, -20},
+		},
+		"point zm suffix": {
+			wkt:      "POINTZM(1 2 3 4)",
+			expected: geom.Point{1, 2},
+		},
+		"empty point": {
+			wkt:      "POINT EMPTY",
+			expected: nil,
+		},
+		"linestring": {
+			wkt:      "LINESTRING (0 0, 1 1, 2 0)",
+			expected: geom.LineString{{0, 0}, {1, 1}, {2, 0}},
+		},
+		"polygon": {
+			wkt:      "POLYGON ((0 0, 4 0, 4 4, 0 0), (1 1, 2 1, 2 2, 1 1))",
+			expected: geom.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 0}}, {{1, 1}, {2, 1}, {2, 2}, {1, 1}}},
+		},
+		"multipoint": {
+			wkt:      "MULTIPOINT ((0 0), 1 1)",
+			expected: geom.MultiPoint{{0, 0}, {1, 1}},
+		},
+		"multilinestring": {
+			wkt:      "MULTILINESTRING ((0 0, 1 1), (2 2, 3 3))",
+			expected: geom.MultiLineString{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}},
+		},
+		"multipolygon": {
+			wkt:      "MULTIPOLYGON (((0 0, 1 0, 1 1, 0 0)), ((2 2, 3 2, 3 3, 2 2)))",
+			expected: geom.MultiPolygon{{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}, {{{2, 2}, {3, 2}, {3, 3}, {2, 2}}}},
+		},
+		"geometrycollection": {
+			wkt:      "GEOMETRYCOLLECTION (POINT (1 2), POINT EMPTY, LINESTRING (0 0, 1 1))",
+			expected: geom.Collection{geom.Point{1, 2}, geom.LineString{{0, 0}, {1, 1}}},
+		},
+		"unsupported type": {
+			wkt: "CIRCLE (1 2)",
+			err: "sqlite: invalid wkt (CIRCLE (1 2)): unsupported geometry type (CIRCLE) at offset 6",
+		},
+		"missing ordinate": {
+			wkt: "POINT (1)",
+			err: "sqlite: invalid wkt (POINT (1)): expected a number at offset 8",
+		},
+		"trailing characters": {
+			wkt: "POINT (1 2) x",
+			err: "sqlite: invalid wkt (POINT (1 2) x): unexpected trailing characters at offset 12",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
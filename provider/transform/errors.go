@@ -0,0 +1,76 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingProvider  = errors.New("transform: missing 'provider'")
+	ErrMissingLayerName = errors.New("transform: layer is missing 'name'")
+)
+
+// ErrProviderNotFound is returned when the wrapped provider isn't configured before the
+// transform provider
+type ErrProviderNotFound struct {
+	Provider string
+}
+
+func (e ErrProviderNotFound) Error() string {
+	return fmt.Sprintf("transform: provider (%v) not found, it must be configured before the transform provider", e.Provider)
+}
+
+// ErrMVTProvider is returned when the wrapped provider is an MVT provider, whose tiles are
+// already encoded
+type ErrMVTProvider struct {
+	Provider string
+}
+
+func (e ErrMVTProvider) Error() string {
+	return fmt.Sprintf("transform: provider (%v) is an MVT provider, only standard providers can be transformed", e.Provider)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("transform: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+// ErrSourceLayerNotFound is returned when the source layer isn't a layer of the wrapped provider
+type ErrSourceLayerNotFound struct {
+	LayerName   string
+	SourceLayer string
+}
+
+func (e ErrSourceLayerNotFound) Error() string {
+	return fmt.Sprintf("transform: layer (%v) source layer (%v) not found", e.LayerName, e.SourceLayer)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("transform: layer (%v) not found", e.LayerName)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("transform: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
+
+// ErrInvalidExpression is returned when a filter or computed tag expression can't be compiled
+type ErrInvalidExpression struct {
+	Expression string
+	Reason     string
+}
+
+func (e ErrInvalidExpression) Error() string {
+	return fmt.Sprintf("transform: invalid expression (%v): %v", e.Expression, e.Reason)
+}
@@ -0,0 +1,330 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type literalNode struct{ val interface{} }
+
+func (n literalNode) eval(map[string]interface{}) interface{} { return n.val }
+
+type tagNode struct{ name string }
+
+func (n tagNode) eval(tags map[string]interface{}) interface{} { return tags[n.name] }
+
+type notNode struct{ n node }
+
+func (n notNode) eval(tags map[string]interface{}) interface{} { return !truthy(n.n.eval(tags)) }
+
+type logicNode struct {
+	and         bool
+	left, right node
+}
+
+func (n logicNode) eval(tags map[string]interface{}) interface{} {
+	l := truthy(n.left.eval(tags))
+	if n.and {
+		return l && truthy(n.right.eval(tags))
+	}
+	return l || truthy(n.right.eval(tags))
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(tags map[string]interface{}) interface{} {
+	l, r := n.left.eval(tags), n.right.eval(tags)
+	switch n.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		return !equal(l, r)
+	}
+
+	c, ok := compare(l, r)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+type arithNode struct {
+	op          string
+	left, right node
+}
+
+func (n arithNode) eval(tags map[string]interface{}) interface{} {
+	l, r := n.left.eval(tags), n.right.eval(tags)
+	if l == nil || r == nil {
+		return nil
+	}
+
+	if n.op == "+" {
+		_, ls := l.(string)
+		_, rs := r.(string)
+		if ls || rs {
+			return toString(l) + toString(r)
+		}
+	}
+
+	a, ok := toNumber(l)
+	if !ok {
+		return nil
+	}
+	b, ok := toNumber(r)
+	if !ok {
+		return nil
+	}
+
+	switch n.op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		if b == 0 {
+			return nil
+		}
+		return a / b
+	default:
+		if b == 0 {
+			return nil
+		}
+		return math.Mod(a, b)
+	}
+}
+
+type inNode struct {
+	value node
+	list  []node
+}
+
+func (n inNode) eval(tags map[string]interface{}) interface{} {
+	v := n.value.eval(tags)
+	for _, item := range n.list {
+		if equal(v, item.eval(tags)) {
+			return true
+		}
+	}
+	return false
+}
+
+type callNode struct {
+	fn   func(args []interface{}) interface{}
+	args []node
+}
+
+func (n callNode) eval(tags map[string]interface{}) interface{} {
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		args[i] = a.eval(tags)
+	}
+	return n.fn(args)
+}
+
+type function struct {
+	// maxArgs is -1 for functions with any number of arguments
+	minArgs, maxArgs int
+	call             func(args []interface{}) interface{}
+}
+
+// functions of the expressions, by name
+var functions = map[string]function{
+	// coalesce returns the first argument which isn't null
+	"coalesce": {1, -1, func(args []interface{}) interface{} {
+		for _, a := range args {
+			if a != nil {
+				return a
+			}
+		}
+		return nil
+	}},
+	// concat joins the arguments which aren't null
+	"concat": {1, -1, func(args []interface{}) interface{} {
+		var sb strings.Builder
+		for _, a := range args {
+			if a != nil {
+				sb.WriteString(toString(a))
+			}
+		}
+		return sb.String()
+	}},
+	"lower": stringFunc(strings.ToLower),
+	"upper": stringFunc(strings.ToUpper),
+	"trim":  stringFunc(strings.TrimSpace),
+	"length": {1, 1, func(args []interface{}) interface{} {
+		if args[0] == nil {
+			return nil
+		}
+		return float64(len([]rune(toString(args[0]))))
+	}},
+	"string": {1, 1, func(args []interface{}) interface{} {
+		if args[0] == nil {
+			return nil
+		}
+		return toString(args[0])
+	}},
+	"number": {1, 1, func(args []interface{}) interface{} {
+		if f, ok := toNumber(args[0]); ok {
+			return f
+		}
+		return nil
+	}},
+	// round rounds to the optional number of digits
+	"round": {1, 2, func(args []interface{}) interface{} {
+		f, ok := toNumber(args[0])
+		if !ok {
+			return nil
+		}
+		if len(args) == 1 {
+			return math.Round(f)
+		}
+		digits, ok := toNumber(args[1])
+		if !ok {
+			return nil
+		}
+		pow := math.Pow(10, math.Trunc(digits))
+		return math.Round(f*pow) / pow
+	}},
+	"floor": numberFunc(math.Floor),
+	"ceil":  numberFunc(math.Ceil),
+	"abs":   numberFunc(math.Abs),
+}
+
+func stringFunc(fn func(string) string) function {
+	return function{1, 1, func(args []interface{}) interface{} {
+		if args[0] == nil {
+			return nil
+		}
+		return fn(toString(args[0]))
+	}}
+}
+
+func numberFunc(fn func(float64) float64) function {
+	return function{1, 1, func(args []interface{}) interface{} {
+		f, ok := toNumber(args[0])
+		if !ok {
+			return nil
+		}
+		return fn(f)
+	}}
+}
+
+// truthy reports if the value is true, a number other than 0 or a string other than ""
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	default:
+		f, ok := toNumber(v)
+		return !ok || f != 0
+	}
+}
+
+// toNumber converts numbers and numeric strings to float64
+func toNumber(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int8:
+		return float64(val), true
+	case int16:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case uint:
+		return float64(val), true
+	case uint8:
+		return float64(val), true
+	case uint16:
+		return float64(val), true
+	case uint32:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toString formats the value, numbers without trailing zeros
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// equal compares the values as numbers when they're both numeric, otherwise as strings.
+// null is only equal to null.
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x == y
+		}
+	}
+	if x, ok := a.(bool); ok {
+		y, ok := b.(bool)
+		return ok && x == y
+	}
+	return toString(a) == toString(b)
+}
+
+// compare orders the values numerically when they're both numeric, otherwise as strings.
+// false is returned when either value is null.
+func compare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	}
+	return strings.Compare(toString(a), toString(b)), true
+}
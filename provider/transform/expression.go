@@ -0,0 +1,395 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// expression is a compiled expression evaluated against the tags of a feature. The
+// expressions are small and side effect free:
+//
+//	name, "name:en"             tags, quoted with double quotes for names with special characters
+//	'text', 12, 1.5, true, null literals, strings are single quoted
+//	+ - * / %                   arithmetic, + concatenates when either value is a string
+//	== != < <= > >=             comparisons, numeric strings are compared as numbers
+//	&& || !, and or not         logic
+//	x in ('a', 'b')             membership
+//	lower(x), coalesce(a, b)    functions, see the functions map
+//
+// Missing tags are null. Arithmetic with null, or with values which aren't numbers, is null
+// so a computed tag is dropped rather than failing the tile.
+type expression struct {
+	src  string
+	root node
+}
+
+type node interface {
+	eval(tags map[string]interface{}) interface{}
+}
+
+func compileExpression(src string) (*expression, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, ErrInvalidExpression{Expression: src, Reason: err.Error()}
+	}
+
+	p := parser{toks: toks}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected %v at offset %v", p.peek(), p.peek().pos)
+	}
+	if err != nil {
+		return nil, ErrInvalidExpression{Expression: src, Reason: err.Error()}
+	}
+
+	return &expression{src: src, root: root}, nil
+}
+
+// eval returns the value of the expression for the tags
+func (e *expression) eval(tags map[string]interface{}) interface{} {
+	return e.root.eval(tags)
+}
+
+// match reports if the expression is truthy for the tags
+func (e *expression) match(tags map[string]interface{}) bool {
+	return truthy(e.root.eval(tags))
+}
+
+// lexer
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	// tokTag is a double quoted tag name, which is never a keyword or function
+	tokTag
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	val  string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return "'" + t.val + "'"
+	case tokTag:
+		return `"` + t.val + `"`
+	default:
+		return t.val
+	}
+}
+
+// the operators, two character operators first so they're matched before their prefixes
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ","}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '\'' || c == '"':
+			// single quotes are strings, double quotes are tag names
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j == len(src) {
+				return nil, fmt.Errorf("unterminated quote at offset %v", i)
+			}
+			kind := tokString
+			if c == '"' {
+				kind = tokTag
+			}
+			toks = append(toks, token{kind: kind, val: sb.String(), pos: i})
+			i = j + 1
+
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				(src[j] == '-' || src[j] == '+') && (src[j-1] == 'e' || src[j-1] == 'E')) {
+				j++
+			}
+			if _, err := strconv.ParseFloat(src[i:j], 64); err != nil {
+				return nil, fmt.Errorf("invalid number (%v) at offset %v", src[i:j], i)
+			}
+			toks = append(toks, token{kind: tokNumber, val: src[i:j], pos: i})
+			i = j
+
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] == ':' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, val: src[i:j], pos: i})
+			i = j
+
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character (%c) at offset %v", c, i)
+			}
+			toks = append(toks, token{kind: tokOp, val: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// parser
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token when it's one of the operators or keywords
+func (p *parser) accept(vals ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return "", false
+	}
+	for _, v := range vals {
+		if t.val == v {
+			p.next()
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		return fmt.Errorf("expected %v at offset %v, got %v", op, p.peek().pos, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{and: false, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{and: true, left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.accept("in"); ok {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return inNode{value: left, list: args}, nil
+	}
+
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return arithNode{op: "-", left: literalNode{0.0}, right: n}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parseArgs parses a comma separated list of expressions closed with a parenthesis. The
+// opening parenthesis has been consumed.
+func (p *parser) parseArgs() ([]node, error) {
+	var args []node
+	if _, ok := p.accept(")"); ok {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		if _, ok := p.accept(")"); ok {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		f, _ := strconv.ParseFloat(t.val, 64)
+		return literalNode{f}, nil
+
+	case tokString:
+		return literalNode{t.val}, nil
+
+	case tokTag:
+		return tagNode{t.val}, nil
+
+	case tokIdent:
+		switch t.val {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null":
+			return literalNode{nil}, nil
+		}
+
+		if _, ok := p.accept("("); !ok {
+			return tagNode{t.val}, nil
+		}
+		fn, ok := functions[t.val]
+		if !ok {
+			return nil, fmt.Errorf("unknown function (%v) at offset %v", t.val, t.pos)
+		}
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+			return nil, fmt.Errorf("function (%v) at offset %v called with %v arguments", t.val, t.pos, len(args))
+		}
+		return callNode{fn: fn.call, args: args}, nil
+
+	case tokOp:
+		if t.val == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %v at offset %v", t, t.pos)
+}
@@ -0,0 +1,83 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpression(t *testing.T) {
+	type tcase struct {
+		expr     string
+		expected interface{}
+	}
+
+	tags := map[string]interface{}{
+		"name":    "Main St",
+		"name:en": "Main Street",
+		"lanes":   "2",
+		"width":   int64(12),
+		"speed":   37.5,
+		"oneway":  true,
+		"highway": "primary",
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			e, err := compileExpression(tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := e.eval(tags); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v (%T) got %v (%T)", tc.expected, tc.expected, got, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"tag":                  {expr: "width", expected: int64(12)},
+		"quoted tag":           {expr: `"name:en"`, expected: "Main Street"},
+		"missing tag":          {expr: "ref", expected: nil},
+		"arithmetic":           {expr: "width * 2 + number(lanes)", expected: 26.0},
+		"precedence":           {expr: "(width - 2) * -2", expected: -20.0},
+		"division by zero":     {expr: "width / 0", expected: nil},
+		"null arithmetic":      {expr: "ref + 1", expected: nil},
+		"concatenation":        {expr: "name + ' (' + width + ')'", expected: "Main St (12)"},
+		"numeric strings":      {expr: "lanes >= 2 && lanes < 3", expected: true},
+		"string comparison":    {expr: "highway == 'primary' and not oneway == false", expected: true},
+		"null comparison":      {expr: "ref == null || ref > 1", expected: true},
+		"in":                   {expr: "highway in ('primary', 'secondary')", expected: true},
+		"not in":               {expr: "!(highway in ('service'))", expected: true},
+		"coalesce":             {expr: `coalesce(ref, "name:en", name)`, expected: "Main Street"},
+		"concat":               {expr: "concat(name, ref, '!')", expected: "Main St!"},
+		"lower":                {expr: "upper(lower(name))", expected: "MAIN ST"},
+		"length":               {expr: "length(name)", expected: 7.0},
+		"round":                {expr: "round(speed / 3.6, 1)", expected: 10.4},
+		"numeric string":       {expr: "lanes * 1 + floor(1.7)", expected: 3.0},
+		"number of non number": {expr: "number(name)", expected: nil},
+		"string":               {expr: "string(speed)", expected: "37.5"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestCompileExpressionErrors(t *testing.T) {
+	tests := map[string]string{
+		"unterminated string": "name == 'main",
+		"unknown function":    "titlecase(name)",
+		"missing operand":     "lanes >",
+		"missing paren":       "(lanes > 1",
+		"trailing tokens":     "lanes 1",
+		"argument count":      "lower(name, ref)",
+		"invalid character":   "lanes # 1",
+	}
+
+	for name, expr := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := compileExpression(expr); err == nil {
+				t.Errorf("expected an error compiling (%v)", expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package transform
+
+import (
+	"github.com/go-spatial/geom"
+)
+
+// coerce converts the geometry to the geometry type. Single geometries are wrapped in their
+// multi geometry and multi geometries of one geometry are unwrapped. false is returned when
+// the geometry can't be converted.
+func coerce(g geom.Geometry, to geom.Geometry) (geom.Geometry, bool) {
+	switch to.(type) {
+	case geom.Point:
+		switch v := g.(type) {
+		case geom.Point:
+			return v, true
+		case geom.MultiPoint:
+			if len(v) == 1 {
+				return geom.Point(v[0]), true
+			}
+		}
+	case geom.MultiPoint:
+		switch v := g.(type) {
+		case geom.Point:
+			return geom.MultiPoint{v}, true
+		case geom.MultiPoint:
+			return v, true
+		}
+	case geom.LineString:
+		switch v := g.(type) {
+		case geom.LineString:
+			return v, true
+		case geom.MultiLineString:
+			if len(v) == 1 {
+				return geom.LineString(v[0]), true
+			}
+		}
+	case geom.MultiLineString:
+		switch v := g.(type) {
+		case geom.LineString:
+			return geom.MultiLineString{v}, true
+		case geom.MultiLineString:
+			return v, true
+		}
+	case geom.Polygon:
+		switch v := g.(type) {
+		case geom.Polygon:
+			return v, true
+		case geom.MultiPolygon:
+			if len(v) == 1 {
+				return geom.Polygon(v[0]), true
+			}
+		}
+	case geom.MultiPolygon:
+		switch v := g.(type) {
+		case geom.Polygon:
+			return geom.MultiPolygon{v}, true
+		case geom.MultiPolygon:
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,89 @@
+package transform
+
+import (
+	"github.com/go-spatial/geom"
+)
+
+// rename is a tag renamed from the wrapped layer's name
+type rename struct {
+	from, to string
+}
+
+// computed is a tag set to the value of an expression
+type computed struct {
+	tag  string
+	expr *expression
+}
+
+type Layer struct {
+	name string
+	// sourceLayer is the layer of the wrapped provider
+	sourceLayer string
+	geomType    geom.Geometry
+	srid        uint64
+
+	renames  []rename
+	computed []computed
+	// filter drops the features it doesn't match, nil keeps all the features
+	filter *expression
+	drop   []string
+	// coerce converts the features' geometries to the geomType when set
+	coerce bool
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
+
+// apply transforms the feature's tags and geometry. false is returned when the feature is
+// filtered out or its geometry can't be coerced. The tags of the feature are replaced, not
+// modified, as they may be shared by the wrapped provider.
+func (l Layer) apply(tags map[string]interface{}, g geom.Geometry) (map[string]interface{}, geom.Geometry, bool) {
+	out := make(map[string]interface{}, len(tags)+len(l.computed))
+	for k, v := range tags {
+		out[k] = v
+	}
+
+	// tags are renamed at once, so swapping the names of two tags works
+	for _, r := range l.renames {
+		delete(out, r.from)
+	}
+	for _, r := range l.renames {
+		if v, ok := tags[r.from]; ok {
+			out[r.to] = v
+		}
+	}
+
+	// computed tags are evaluated against the renamed tags, so they don't depend on each other
+	if len(l.computed) > 0 {
+		vals := make([]interface{}, len(l.computed))
+		for i, c := range l.computed {
+			vals[i] = c.expr.eval(out)
+		}
+		for i, c := range l.computed {
+			if vals[i] == nil {
+				delete(out, c.tag)
+				continue
+			}
+			out[c.tag] = vals[i]
+		}
+	}
+
+	if l.filter != nil && !l.filter.match(out) {
+		return nil, nil, false
+	}
+
+	for _, k := range l.drop {
+		delete(out, k)
+	}
+
+	if l.coerce {
+		var ok bool
+		if g, ok = coerce(g, l.geomType); !ok {
+			return nil, nil, false
+		}
+	}
+
+	return out, g, true
+}
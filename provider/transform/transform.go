@@ -0,0 +1,281 @@
+// Package transform provides a meta provider which wraps the layers of another provider
+// with declarative transformations: renamed tags, tags computed with expressions, feature
+// filters, dropped tags and geometry type coercion. The wrapped provider must be configured
+// before the transform provider.
+package transform
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "transform"
+
+const (
+	ConfigKeyProvider = "provider"
+	ConfigKeyLayers   = "layers"
+
+	ConfigKeyLayerName    = "name"
+	ConfigKeySourceLayer  = "source_layer"
+	ConfigKeyRename       = "rename"
+	ConfigKeyCompute      = "compute"
+	ConfigKeyFilter       = "filter"
+	ConfigKeyDrop         = "drop"
+	ConfigKeyGeometryType = "geometry_type"
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, nil)
+}
+
+// Provider serves transformed layers of another provider
+type Provider struct {
+	// name of the wrapped provider
+	name     string
+	provider provider.Tiler
+
+	// map of layer name and corresponding transformations
+	layers map[string]Layer
+}
+
+// NewTileProvider instantiates and returns a new transform provider or an error.
+//
+//	provider (string): [Required] the name of the wrapped provider
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		source_layer (string): [Optional] the layer of the wrapped provider. defaults to the name
+//		rename (map[string]string): [Optional] tags renamed, keyed by their name in the source layer
+//		compute (map[string]string): [Optional] tags set to the value of expressions, keyed by tag name
+//		filter (string): [Optional] an expression features must match to be kept
+//		drop ([]string): [Optional] tags removed from the features
+//		geometry_type (string): [Optional] the geometry type the features are coerced to. defaults to the source layer's geometry type
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	empty := ""
+	name, err := config.String(ConfigKeyProvider, &empty)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, ErrMissingProvider
+	}
+
+	prov, ok := provider.Instance(name)
+	if !ok {
+		return nil, ErrProviderNotFound{Provider: name}
+	}
+	if prov.Std == nil {
+		return nil, ErrMVTProvider{Provider: name}
+	}
+
+	p := Provider{
+		name:     name,
+		provider: prov.Std,
+		layers:   map[string]Layer{},
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// sortedKeys returns the keys of the map in order, so the tags are transformed the same
+// way every time
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AddLayer adds a transformed layer of the wrapped provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	strs := []struct {
+		key string
+		val string
+	}{
+		{ConfigKeySourceLayer, name},
+		{ConfigKeyFilter, ""},
+		{ConfigKeyGeometryType, ""},
+	}
+	for i := range strs {
+		if strs[i].val, err = layerConf.String(strs[i].key, &strs[i].val); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, strs[i].key, err)
+		}
+	}
+	sourceLayer, filter, gtype := strs[0].val, strs[1].val, strs[2].val
+
+	info, ok := p.provider.Layer(sourceLayer)
+	if !ok {
+		return ErrSourceLayerNotFound{LayerName: name, SourceLayer: sourceLayer}
+	}
+
+	l := Layer{
+		name:        name,
+		sourceLayer: sourceLayer,
+		geomType:    info.GeomType(),
+		srid:        info.SRID(),
+	}
+
+	renames, err := dict.StringMap(layerConf, ConfigKeyRename)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyRename, err)
+	}
+	for _, from := range sortedKeys(renames) {
+		l.renames = append(l.renames, rename{from: from, to: renames[from]})
+	}
+
+	compute, err := dict.StringMap(layerConf, ConfigKeyCompute)
+	if err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyCompute, err)
+	}
+	for _, tag := range sortedKeys(compute) {
+		expr, err := compileExpression(compute[tag])
+		if err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyCompute, err)
+		}
+		l.computed = append(l.computed, computed{tag: tag, expr: expr})
+	}
+
+	if filter != "" {
+		if l.filter, err = compileExpression(filter); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyFilter, err)
+		}
+	}
+
+	if l.drop, err = layerConf.StringSlice(ConfigKeyDrop); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyDrop, err)
+	}
+
+	if gtype != "" {
+		if l.geomType, ok = provider.GeometryType(gtype); !ok {
+			return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+		}
+		l.coerce = true
+	}
+
+	p.layers[name] = l
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent returns the extent of the source layer
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return geom.Extent{}, ErrLayerNotFound{LayerName: lyrID}
+	}
+	return p.provider.LayerExtent(layer.sourceLayer)
+}
+
+// LayerMinZoom returns the min zoom of the source layer
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return 0
+	}
+	return p.provider.LayerMinZoom(layer.sourceLayer)
+}
+
+// LayerMaxZoom returns the max zoom of the source layer
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return 0
+	}
+	return p.provider.LayerMaxZoom(layer.sourceLayer)
+}
+
+// TileFeatures streams the transformed features of the source layer
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	err := p.provider.TileFeatures(ctx, layer.sourceLayer, tile, func(f *provider.Feature) error {
+		tags, g, ok := layer.apply(f.Tags, f.Geometry)
+		if !ok {
+			return nil
+		}
+
+		tf := *f
+		tf.Tags, tf.Geometry = tags, g
+		return fn(&tf)
+	})
+	if err != nil && err != provider.ErrCanceled {
+		return fmt.Errorf("transform: layer (%v) provider (%v): %w", layer.name, p.name, err)
+	}
+	return err
+}
+
+// LayerUpdated returns the freshness of the source layer, when the wrapped provider reports it
+func (p *Provider) LayerUpdated(ctx context.Context, lyrID string) (time.Time, error) {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return time.Time{}, ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	f, ok := p.provider.(provider.Freshness)
+	if !ok {
+		return time.Time{}, fmt.Errorf("transform: provider (%v) does not report freshness", p.name)
+	}
+	return f.LayerUpdated(ctx, layer.sourceLayer)
+}
+
+// LayerChanges returns the changes of the source layer, when the wrapped provider reports them
+func (p *Provider) LayerChanges(ctx context.Context, lyrID string, since time.Time) ([]geom.Extent, error) {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return nil, ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	c, ok := p.provider.(provider.Changes)
+	if !ok {
+		return nil, provider.ErrUnsupported
+	}
+	return c.LayerChanges(ctx, layer.sourceLayer, since)
+}
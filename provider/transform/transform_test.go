@@ -0,0 +1,183 @@
+package transform_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/memory"
+	"github.com/go-spatial/tegola/provider/transform"
+)
+
+// newSource configures a memory provider with a roads layer holding the features
+func newSource(t *testing.T, name string, features ...provider.Feature) {
+	p, err := memory.New(tegola.WGS84)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.AddLayer(dict.Dict{"name": "roads", "geometry_type": "linestring"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, f := range features {
+		if err := p.AddFeature("roads", f); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	provider.SetInstance(name, provider.TilerUnion{Std: p})
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		layer    map[string]interface{}
+		expected []provider.Feature
+	}
+
+	main := provider.Feature{ID: 1, Geometry: geom.LineString{{0, 0}, {1, 1}}, SRID: tegola.WGS84, Tags: map[string]interface{}{
+		"name": "Main St", "name:en": "Main Street", "highway": "primary", "lanes": int64(4), "osm_id": int64(101),
+	}}
+	service := provider.Feature{ID: 2, Geometry: geom.LineString{{1, 1}, {2, 2}}, SRID: tegola.WGS84, Tags: map[string]interface{}{
+		"highway": "service", "lanes": int64(1), "osm_id": int64(102),
+	}}
+	newSource(t, "osm", main, service)
+
+	feature := func(f provider.Feature, g geom.Geometry, tags map[string]interface{}) provider.Feature {
+		f.Geometry, f.Tags = g, tags
+		return f
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			tc.layer["name"] = "roads"
+			p, err := transform.NewTileProvider(dict.Dict{
+				"provider": "osm",
+				"layers":   []map[string]interface{}{tc.layer},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var features []provider.Feature
+			err = p.TileFeatures(context.Background(), "roads", provider.NewTile(0, 0, 0, 0, tegola.WebMercator), func(f *provider.Feature) error {
+				features = append(features, *f)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(features, tc.expected) {
+				t.Errorf("features, expected %+v got %+v", tc.expected, features)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"passthrough": {
+			layer:    map[string]interface{}{},
+			expected: []provider.Feature{main, service},
+		},
+		"rename, compute and drop": {
+			layer: map[string]interface{}{
+				"rename":  map[string]interface{}{"name:en": "name_en", "highway": "class"},
+				"compute": map[string]interface{}{"label": "coalesce(name_en, name, class)", "width": "lanes * 3.5"},
+				"drop":    []string{"osm_id", "name"},
+			},
+			expected: []provider.Feature{
+				feature(main, main.Geometry, map[string]interface{}{"name_en": "Main Street", "class": "primary", "lanes": int64(4), "label": "Main Street", "width": 14.0}),
+				feature(service, service.Geometry, map[string]interface{}{"class": "service", "lanes": int64(1), "label": "service", "width": 3.5}),
+			},
+		},
+		"filter": {
+			layer: map[string]interface{}{
+				"filter": "highway != 'service' && lanes >= 2",
+			},
+			expected: []provider.Feature{main},
+		},
+		"filter computed tags": {
+			layer: map[string]interface{}{
+				"compute": map[string]interface{}{"major": "highway in ('motorway', 'primary')"},
+				"filter":  "!major",
+			},
+			expected: []provider.Feature{
+				feature(service, service.Geometry, map[string]interface{}{"highway": "service", "lanes": int64(1), "osm_id": int64(102), "major": false}),
+			},
+		},
+		"geometry type": {
+			layer: map[string]interface{}{
+				"geometry_type": "multilinestring",
+				"drop":          []string{"name", "name:en", "highway", "lanes", "osm_id"},
+			},
+			expected: []provider.Feature{
+				feature(main, geom.MultiLineString{{{0, 0}, {1, 1}}}, map[string]interface{}{}),
+				feature(service, geom.MultiLineString{{{1, 1}, {2, 2}}}, map[string]interface{}{}),
+			},
+		},
+		"geometry type mismatch": {
+			layer:    map[string]interface{}{"geometry_type": "polygon"},
+			expected: nil,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		config dict.Dict
+		err    string
+	}
+
+	newSource(t, "osm")
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := transform.NewTileProvider(tc.config)
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing provider": {
+			config: dict.Dict{},
+			err:    transform.ErrMissingProvider.Error(),
+		},
+		"provider not found": {
+			config: dict.Dict{"provider": "missing"},
+			err:    "transform: provider (missing) not found, it must be configured before the transform provider",
+		},
+		"source layer not found": {
+			config: dict.Dict{
+				"provider": "osm",
+				"layers":   []map[string]interface{}{{"name": "roads", "source_layer": "rails"}},
+			},
+			err: "transform: layer (roads) source layer (rails) not found",
+		},
+		"invalid filter": {
+			config: dict.Dict{
+				"provider": "osm",
+				"layers":   []map[string]interface{}{{"name": "roads", "filter": "lanes >"}},
+			},
+			err: "for layer (roads) filter has an error: transform: invalid expression (lanes >): unexpected end of expression at offset 7",
+		},
+		"invalid geometry type": {
+			config: dict.Dict{
+				"provider": "osm",
+				"layers":   []map[string]interface{}{{"name": "roads", "geometry_type": "curve"}},
+			},
+			err: "transform: layer (roads) has invalid geometry_type (curve)",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,174 @@
+package trino
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// the delay before retrying a request the coordinator is too busy for
+const busyDelay = 100 * time.Millisecond
+
+// column of a query result
+type column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// queryError is the error of a failed query
+type queryError struct {
+	Message   string `json:"message"`
+	ErrorName string `json:"errorName"`
+}
+
+// queryResults is a page of the results of a query, see https://trino.io/docs/current/develop/client-protocol.html
+type queryResults struct {
+	ID      string          `json:"id"`
+	NextURI string          `json:"nextUri"`
+	Columns []column        `json:"columns"`
+	Data    [][]interface{} `json:"data"`
+	Error   *queryError     `json:"error"`
+}
+
+// client speaks the REST client protocol of a Trino (or Presto) coordinator
+type client struct {
+	url      string
+	user     string
+	password string
+	catalog  string
+	schema   string
+	// headerPrefix is "X-Trino-" or "X-Presto-"
+	headerPrefix string
+	http         *http.Client
+}
+
+// query runs the SQL statement and returns the columns and rows of its results.
+// When the context is done the query is cancelled on the cluster.
+func (c *client) query(ctx context.Context, sql string) ([]column, [][]interface{}, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(c.url, "/")+"/v1/statement", strings.NewReader(sql))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set(c.headerPrefix+"Catalog", c.catalog)
+	req.Header.Set(c.headerPrefix+"Schema", c.schema)
+
+	var (
+		columns []column
+		rows    [][]interface{}
+	)
+
+	// next is the uri of the next page of a running query
+	var next string
+	for req != nil {
+		res, err := c.do(ctx, req)
+		if err != nil {
+			if err == provider.ErrCanceled && next != "" {
+				go c.cancel(next)
+			}
+			return nil, nil, err
+		}
+		if res.Error != nil {
+			return nil, nil, ErrQuery{QueryID: res.ID, ErrorName: res.Error.ErrorName, Message: res.Error.Message}
+		}
+
+		if columns == nil && len(res.Columns) > 0 {
+			columns = res.Columns
+		}
+		rows = append(rows, res.Data...)
+
+		req, next = nil, res.NextURI
+		if next != "" {
+			if ctx.Err() != nil {
+				go c.cancel(next)
+				return nil, nil, provider.ErrCanceled
+			}
+			if req, err = http.NewRequest(http.MethodGet, next, nil); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return columns, rows, nil
+}
+
+// do sends the request until the coordinator isn't too busy to accept it and decodes the results
+func (c *client) do(ctx context.Context, req *http.Request) (*queryResults, error) {
+	req = req.WithContext(ctx)
+	req.Header.Set(c.headerPrefix+"User", c.user)
+	req.Header.Set(c.headerPrefix+"Source", "tegola")
+	if c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	for {
+		// the body of the statement is sent again when retrying
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, provider.ErrCanceled
+			}
+			return nil, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			defer resp.Body.Close()
+
+			dec := json.NewDecoder(resp.Body)
+			// bigint ids and values would lose precision as floats
+			dec.UseNumber()
+
+			var res queryResults
+			if err := dec.Decode(&res); err != nil {
+				return nil, fmt.Errorf("trino: decoding (%v): %v", req.URL, err)
+			}
+			return &res, nil
+
+		case http.StatusServiceUnavailable, http.StatusTooManyRequests:
+			resp.Body.Close()
+
+			select {
+			case <-ctx.Done():
+				return nil, provider.ErrCanceled
+			case <-time.After(busyDelay):
+			}
+
+		default:
+			resp.Body.Close()
+			return nil, ErrStatus{URL: req.URL.String(), Status: resp.StatusCode}
+		}
+	}
+}
+
+// cancel frees the cluster's resources of an abandoned query
+func (c *client) cancel(nextURI string) {
+	req, err := http.NewRequest(http.MethodDelete, nextURI, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set(c.headerPrefix+"User", c.user)
+	if c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		log.Warnf("trino: cancelling query: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
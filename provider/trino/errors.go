@@ -0,0 +1,101 @@
+package trino
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingURL       = errors.New("trino: provider is missing 'url'")
+	ErrMissingLayerName = errors.New("trino: layer is missing 'name'")
+)
+
+type ErrUnsupportedSRID struct {
+	SRID int
+}
+
+func (e ErrUnsupportedSRID) Error() string {
+	return fmt.Sprintf("trino: unsupported srid (%v), expected 4326 or 3857", e.SRID)
+}
+
+type ErrDuplicateLayerName struct {
+	LayerName string
+}
+
+func (e ErrDuplicateLayerName) Error() string {
+	return fmt.Sprintf("trino: layer names must be unique. (%v) is duplicated", e.LayerName)
+}
+
+type ErrLayerNotFound struct {
+	LayerName string
+}
+
+func (e ErrLayerNotFound) Error() string {
+	return fmt.Sprintf("trino: layer (%v) not found", e.LayerName)
+}
+
+// ErrTablenameOrSQL is returned when a layer doesn't define exactly one of tablename and sql
+type ErrTablenameOrSQL struct {
+	LayerName string
+}
+
+func (e ErrTablenameOrSQL) Error() string {
+	return fmt.Sprintf("trino: layer (%v) must define either 'tablename' or 'sql'", e.LayerName)
+}
+
+type ErrMissingBBOXToken struct {
+	LayerName string
+}
+
+func (e ErrMissingBBOXToken) Error() string {
+	return fmt.Sprintf("trino: layer (%v) sql is missing the !BBOX! token", e.LayerName)
+}
+
+type ErrInvalidGeometryFormat struct {
+	LayerName string
+	Format    string
+}
+
+func (e ErrInvalidGeometryFormat) Error() string {
+	return fmt.Sprintf("trino: layer (%v) has invalid geometry_format (%v), expected wkb or wkt", e.LayerName, e.Format)
+}
+
+type ErrInvalidGeometryType struct {
+	LayerName    string
+	GeometryType string
+}
+
+func (e ErrInvalidGeometryType) Error() string {
+	return fmt.Sprintf("trino: layer (%v) has invalid geometry_type (%v)", e.LayerName, e.GeometryType)
+}
+
+// ErrMissingColumn is returned when the result of a layer's query lacks the geometry column
+type ErrMissingColumn struct {
+	LayerName string
+	Column    string
+}
+
+func (e ErrMissingColumn) Error() string {
+	return fmt.Sprintf("trino: layer (%v) query did not return the (%v) column", e.LayerName, e.Column)
+}
+
+// ErrStatus is returned when the coordinator responds with an unexpected status
+type ErrStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("trino: request (%v) responded with status %v", e.URL, e.Status)
+}
+
+// ErrQuery is returned when the query failed on the cluster
+type ErrQuery struct {
+	QueryID   string
+	ErrorName string
+	Message   string
+}
+
+func (e ErrQuery) Error() string {
+	return fmt.Sprintf("trino: query (%v) failed: %v: %v", e.QueryID, e.ErrorName, e.Message)
+}
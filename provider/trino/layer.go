@@ -0,0 +1,106 @@
+package trino
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom"
+)
+
+// geometry formats of table geometry columns
+const (
+	GeometryFormatWKB = "wkb"
+	GeometryFormatWKT = "wkt"
+)
+
+// tokens replaced in the layer sql
+const (
+	bboxToken = "!BBOX!"
+	minxToken = "!MINX!"
+	minyToken = "!MINY!"
+	maxxToken = "!MAXX!"
+	maxyToken = "!MAXY!"
+	zoomToken = "!ZOOM!"
+	zToken    = "!Z!"
+	xToken    = "!X!"
+	yToken    = "!Y!"
+)
+
+type Layer struct {
+	name string
+	// sql of the layer with the tokens to be replaced per tile
+	sql string
+	// geomField is the column of the query results holding the WKB geometry
+	geomField string
+	// idField is the column of the query results holding the feature id, empty for none
+	idField string
+	// geomType is the configured geometry type of the layer, nil when unknown
+	geomType geom.Geometry
+	srid     uint64
+}
+
+func (l Layer) ID() string              { return l.name }
+func (l Layer) Name() string            { return l.name }
+func (l Layer) GeomType() geom.Geometry { return l.geomType }
+func (l Layer) SRID() uint64            { return l.srid }
+
+// quote returns the identifier quoted for Trino
+func quote(id string) string {
+	return `"` + strings.Replace(id, `"`, `""`, -1) + `"`
+}
+
+// tableSQL generates the sql of a layer querying a table. The geometries are filtered with
+// the tile's bbox and returned as WKB. When bboxFields (the minx, miny, maxx and maxy columns
+// of the features' bounding boxes) are set they are compared with the tile's bbox as well,
+// letting the connector prune files and partitions with their statistics.
+func tableSQL(tablename, geomField, geomFormat, idField string, fields, bboxFields []string) string {
+	geomExpr := "ST_GeomFromBinary(" + quote(geomField) + ")"
+	if geomFormat == GeometryFormatWKT {
+		geomExpr = "ST_GeometryFromText(" + quote(geomField) + ")"
+	}
+
+	cols := []string{"ST_AsBinary(" + geomExpr + ") AS " + quote(geomField)}
+	if idField != "" {
+		cols = append(cols, quote(idField))
+	}
+	for _, f := range fields {
+		if f == idField || f == geomField {
+			continue
+		}
+		cols = append(cols, quote(f))
+	}
+
+	where := []string{"ST_Intersects(" + geomExpr + ", " + bboxToken + ")"}
+	if len(bboxFields) == 4 {
+		where = append(where,
+			quote(bboxFields[2])+" >= "+minxToken,
+			quote(bboxFields[0])+" <= "+maxxToken,
+			quote(bboxFields[3])+" >= "+minyToken,
+			quote(bboxFields[1])+" <= "+maxyToken,
+		)
+	}
+
+	return fmt.Sprintf("SELECT %v FROM %v WHERE %v", strings.Join(cols, ", "), tablename, strings.Join(where, " AND "))
+}
+
+// replaceTokens fills the layer's sql with the tile's bbox, in the layer's SRID, and zxy
+func (l Layer) replaceTokens(ext *geom.Extent, z, x, y uint) string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	u := func(v uint) string { return strconv.FormatUint(uint64(v), 10) }
+
+	bbox := fmt.Sprintf("ST_Envelope(ST_GeometryFromText('LINESTRING (%v %v, %v %v)'))", f(ext.MinX()), f(ext.MinY()), f(ext.MaxX()), f(ext.MaxY()))
+
+	r := strings.NewReplacer(
+		bboxToken, bbox,
+		minxToken, f(ext.MinX()),
+		minyToken, f(ext.MinY()),
+		maxxToken, f(ext.MaxX()),
+		maxyToken, f(ext.MaxY()),
+		zoomToken, u(z),
+		zToken, u(z),
+		xToken, u(x),
+		yToken, u(y),
+	)
+	return r.Replace(l.sql)
+}
@@ -0,0 +1,468 @@
+// Package trino provides a provider which queries Trino (or Presto) clusters, i.e. Iceberg
+// or Hive tables with WKB or WKT geometry columns. Every tile issues a bbox filtered query
+// over the coordinator's REST protocol. The results of recent tile queries are cached and
+// the number of concurrent queries is limited to protect the cluster.
+package trino
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkb"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/ttlcache"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const Name = "trino"
+
+const (
+	ConfigKeyURL             = "url"
+	ConfigKeyUser            = "user"
+	ConfigKeyPassword        = "password"
+	ConfigKeyCatalog         = "catalog"
+	ConfigKeySchema          = "schema"
+	ConfigKeyPresto          = "presto"
+	ConfigKeyTimeout         = "timeout"
+	ConfigKeySRID            = "srid"
+	ConfigKeyMaxConcurrency  = "max_concurrency"
+	ConfigKeyCacheTTL        = "cache_ttl"
+	ConfigKeyCacheMaxEntries = "cache_max_entries"
+	ConfigKeyLayers          = "layers"
+
+	ConfigKeyLayerName  = "name"
+	ConfigKeyTablename  = "tablename"
+	ConfigKeySQL        = "sql"
+	ConfigKeyGeomField  = "geometry_fieldname"
+	ConfigKeyGeomFormat = "geometry_format"
+	ConfigKeyIDField    = "id_fieldname"
+	ConfigKeyFields     = "fields"
+	ConfigKeyBBoxFields = "bbox_fieldnames"
+	ConfigKeyGeomType   = "geometry_type"
+	ConfigKeyLayerSRID  = "srid"
+)
+
+const (
+	DefaultUser            = "tegola"
+	DefaultTimeout         = 60
+	DefaultSRID            = tegola.WGS84
+	DefaultMaxConcurrency  = 4
+	DefaultCacheTTL        = 300
+	DefaultCacheMaxEntries = 1000
+	DefaultGeomField       = "geom"
+	DefaultGeomFormat      = GeometryFormatWKB
+)
+
+func init() {
+	provider.Register(provider.TypeStd.Prefix()+Name, NewTileProvider, nil)
+}
+
+// Provider queries the layers of a Trino cluster
+type Provider struct {
+	client *client
+	srid   uint64
+
+	// sem limits the number of queries running on the cluster
+	sem chan struct{}
+	// cache is nil when caching is turned off
+	cache *ttlcache.Cache
+
+	// map of layer name and corresponding sql
+	layers map[string]Layer
+}
+
+// NewTileProvider instantiates and returns a new trino provider or an error.
+//
+//	url (string): [Required] the url of the coordinator, i.e. http://localhost:8080
+//	user (string): [Optional] the user the queries are run as. defaults to tegola
+//	password (string): [Optional] the password of the user, sent with basic auth
+//	catalog (string): [Optional] the default catalog of the queries
+//	schema (string): [Optional] the default schema of the queries
+//	presto (bool): [Optional] send the headers of the Presto protocol instead of Trino's. defaults to false
+//	timeout (int): [Optional] the number of seconds allowed per request. defaults to 60
+//	srid (int): [Optional] the default SRID of the layers. 4326 or 3857. defaults to 4326
+//	max_concurrency (int): [Optional] the max number of queries running at once. 0 means no max. defaults to 4
+//	cache_ttl (int): [Optional] the number of seconds the results of a tile query are cached for. 0 turns caching off. defaults to 300
+//	cache_max_entries (int): [Optional] the max number of tile queries cached. 0 means no max. defaults to 1000
+//	layers (map[string]struct{})  — This is map of layers keyed by the layer name.
+//		name (string): [Required] the name of the layer
+//		tablename (string): [*Required] the table to query, i.e. iceberg.gis.buildings
+//		sql (string): [*Required] custom sql with a !BBOX! token, returning the geometry with ST_AsBinary
+//		geometry_fieldname (string): [Optional] the geometry column. defaults to geom
+//		geometry_format (string): [Optional] the format of the tablename's geometry column, wkb or wkt. defaults to wkb
+//		id_fieldname (string): [Optional] the feature id column
+//		fields ([]string): [Optional] the columns of the tablename included as tags
+//		bbox_fieldnames ([]string): [Optional] the minx, miny, maxx and maxy columns of the tablename's feature bounding boxes
+//		geometry_type (string): [Optional] the geometry type of the layer, reported in the capabilities
+//		srid (int): [Optional] the SRID of the layer. defaults to the provider's srid
+func NewTileProvider(config dict.Dicter) (provider.Tiler, error) {
+	empty := ""
+
+	url, err := config.String(ConfigKeyURL, &empty)
+	if err != nil {
+		return nil, err
+	}
+	if url == "" {
+		return nil, ErrMissingURL
+	}
+
+	strs := []struct {
+		key string
+		val string
+	}{
+		{ConfigKeyUser, DefaultUser},
+		{ConfigKeyPassword, ""},
+		{ConfigKeyCatalog, ""},
+		{ConfigKeySchema, ""},
+	}
+	for i := range strs {
+		if strs[i].val, err = config.String(strs[i].key, &strs[i].val); err != nil {
+			return nil, err
+		}
+	}
+
+	ints := []struct {
+		key string
+		val int
+	}{
+		{ConfigKeyTimeout, DefaultTimeout},
+		{ConfigKeySRID, DefaultSRID},
+		{ConfigKeyMaxConcurrency, DefaultMaxConcurrency},
+		{ConfigKeyCacheTTL, DefaultCacheTTL},
+		{ConfigKeyCacheMaxEntries, DefaultCacheMaxEntries},
+	}
+	for i := range ints {
+		if ints[i].val, err = config.Int(ints[i].key, &ints[i].val); err != nil {
+			return nil, err
+		}
+		if ints[i].val < 0 {
+			return nil, fmt.Errorf("trino: %v must not be negative, got %v", ints[i].key, ints[i].val)
+		}
+	}
+	timeout, srid, maxConcurrency, cacheTTL, cacheMaxEntries := ints[0].val, ints[1].val, ints[2].val, ints[3].val, ints[4].val
+	if srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return nil, ErrUnsupportedSRID{SRID: srid}
+	}
+
+	presto := false
+	if presto, err = config.Bool(ConfigKeyPresto, &presto); err != nil {
+		return nil, err
+	}
+	headerPrefix := "X-Trino-"
+	if presto {
+		headerPrefix = "X-Presto-"
+	}
+
+	p := Provider{
+		client: &client{
+			url:          url,
+			user:         strs[0].val,
+			password:     strs[1].val,
+			catalog:      strs[2].val,
+			schema:       strs[3].val,
+			headerPrefix: headerPrefix,
+			http:         &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		},
+		srid:   uint64(srid),
+		layers: map[string]Layer{},
+	}
+	if maxConcurrency > 0 {
+		p.sem = make(chan struct{}, maxConcurrency)
+	}
+	if cacheTTL > 0 {
+		p.cache = ttlcache.New(cacheMaxEntries, time.Duration(cacheTTL)*time.Second)
+	}
+
+	layers, err := config.MapSlice(ConfigKeyLayers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerConf := range layers {
+		if err := p.AddLayer(layerConf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// AddLayer adds a table or sql layer to the provider
+func (p *Provider) AddLayer(layerConf dict.Dicter) error {
+	name, err := layerConf.String(ConfigKeyLayerName, nil)
+	if err != nil {
+		return fmt.Errorf("for layer (%s) we got the following error trying to get the layer's name field: %v", name, err)
+	}
+	if name == "" {
+		return ErrMissingLayerName
+	}
+	if _, ok := p.layers[name]; ok {
+		return ErrDuplicateLayerName{LayerName: name}
+	}
+
+	strs := []struct {
+		key string
+		val string
+	}{
+		{ConfigKeyTablename, ""},
+		{ConfigKeySQL, ""},
+		{ConfigKeyGeomField, DefaultGeomField},
+		{ConfigKeyGeomFormat, DefaultGeomFormat},
+		{ConfigKeyIDField, ""},
+		{ConfigKeyGeomType, ""},
+	}
+	for i := range strs {
+		if strs[i].val, err = layerConf.String(strs[i].key, &strs[i].val); err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, strs[i].key, err)
+		}
+	}
+	tablename, sql, geomField, geomFormat, idField, gtype := strs[0].val, strs[1].val, strs[2].val, strs[3].val, strs[4].val, strs[5].val
+
+	if (tablename == "") == (sql == "") {
+		return ErrTablenameOrSQL{LayerName: name}
+	}
+
+	l := Layer{
+		name:      name,
+		sql:       sql,
+		geomField: geomField,
+		idField:   idField,
+		srid:      p.srid,
+	}
+
+	if tablename != "" {
+		geomFormat = strings.ToLower(geomFormat)
+		if geomFormat != GeometryFormatWKB && geomFormat != GeometryFormatWKT {
+			return ErrInvalidGeometryFormat{LayerName: name, Format: geomFormat}
+		}
+
+		fields, err := layerConf.StringSlice(ConfigKeyFields)
+		if err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyFields, err)
+		}
+
+		bboxFields, err := layerConf.StringSlice(ConfigKeyBBoxFields)
+		if err != nil {
+			return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyBBoxFields, err)
+		}
+		if len(bboxFields) != 0 && len(bboxFields) != 4 {
+			return fmt.Errorf("for layer (%v) %v has an error: expected the minx, miny, maxx and maxy fields, got %v", name, ConfigKeyBBoxFields, bboxFields)
+		}
+
+		l.sql = tableSQL(tablename, geomField, geomFormat, idField, fields, bboxFields)
+	} else if !strings.Contains(sql, bboxToken) {
+		return ErrMissingBBOXToken{LayerName: name}
+	}
+
+	srid := int(p.srid)
+	if srid, err = layerConf.Int(ConfigKeyLayerSRID, &srid); err != nil {
+		return fmt.Errorf("for layer (%v) %v has an error: %v", name, ConfigKeyLayerSRID, err)
+	}
+	if srid != tegola.WGS84 && srid != tegola.WebMercator {
+		return ErrUnsupportedSRID{SRID: srid}
+	}
+	l.srid = uint64(srid)
+
+	var ok bool
+	if l.geomType, ok = provider.GeometryType(gtype); !ok {
+		return ErrInvalidGeometryType{LayerName: name, GeometryType: gtype}
+	}
+
+	p.layers[name] = l
+
+	return nil
+}
+
+// Layer returns the layer info for the layer id
+func (p *Provider) Layer(lyrID string) (provider.LayerInfo, bool) {
+	l, ok := p.layers[lyrID]
+	return l, ok
+}
+
+// Layers returns the layers of the provider
+func (p *Provider) Layers() ([]provider.LayerInfo, error) {
+	ls := make([]provider.LayerInfo, 0, len(p.layers))
+	for _, l := range p.layers {
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// LayerExtent xxx
+func (p *Provider) LayerExtent(lyrID string) (geom.Extent, error) {
+	return geom.Extent{-180.0, -85.05112877980659, 180.0, 85.0511287798066}, nil
+}
+
+// LayerMinZoom xxx
+func (p *Provider) LayerMinZoom(lyrID string) int {
+	return 0
+}
+
+// LayerMaxZoom xxx
+func (p *Provider) LayerMaxZoom(lyrID string) int {
+	return tegola.MaxZ
+}
+
+// TileFeatures queries the layer for the tile's buffered extent. The features of the
+// query are served from the cache when the same query ran recently.
+func (p *Provider) TileFeatures(ctx context.Context, lyrID string, tile provider.Tile, fn func(f *provider.Feature) error) error {
+	layer, ok := p.layers[lyrID]
+	if !ok {
+		return ErrLayerNotFound{LayerName: lyrID}
+	}
+
+	ext, err := provider.QueryExtent(tile, layer.srid)
+	if err != nil {
+		return err
+	}
+	z, x, y := tile.ZXY()
+	sql := layer.replaceTokens(ext, z, x, y)
+
+	if features, ok := p.cache.Get(sql); ok {
+		return send(ctx, features.([]provider.Feature), fn)
+	}
+
+	features, err := p.query(ctx, layer, sql)
+	if err != nil {
+		return err
+	}
+
+	p.cache.Set(sql, features)
+
+	return send(ctx, features, fn)
+}
+
+// query runs the sql once a query slot is free and decodes the rows to features
+func (p *Provider) query(ctx context.Context, layer Layer, sql string) ([]provider.Feature, error) {
+	if p.sem != nil {
+		select {
+		case <-ctx.Done():
+			return nil, provider.ErrCanceled
+		case p.sem <- struct{}{}:
+		}
+		defer func() { <-p.sem }()
+	}
+
+	debug := provider.SQLDebugFor(layer.name).ExecuteSQL
+	if debug {
+		log.Debugf("TEGOLA_SQL_DEBUG:EXECUTE_SQL for layer (%v): %v", layer.name, sql)
+	}
+
+	columns, rows, err := p.client.query(ctx, sql)
+	if err != nil {
+		if debug {
+			log.Debugf("TEGOLA_SQL_DEBUG:EXECUTE_SQL for layer (%v) failed: %v", layer.name, err)
+		}
+		return nil, err
+	}
+
+	geomIdx, idIdx := -1, -1
+	for i, c := range columns {
+		switch c.Name {
+		case layer.geomField:
+			geomIdx = i
+		case layer.idField:
+			idIdx = i
+		}
+	}
+	if geomIdx == -1 {
+		return nil, ErrMissingColumn{LayerName: layer.name, Column: layer.geomField}
+	}
+
+	features := make([]provider.Feature, 0, len(rows))
+	for r, row := range rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("trino: layer (%v) row %v has %v values, expected %v", layer.name, r, len(row), len(columns))
+		}
+
+		g, err := decodeGeometry(row[geomIdx])
+		if err != nil {
+			return nil, fmt.Errorf("trino: layer (%v) row %v: %v", layer.name, r, err)
+		}
+		if g == nil {
+			continue
+		}
+
+		f := provider.Feature{
+			Geometry: g,
+			SRID:     layer.srid,
+			Tags:     map[string]interface{}{},
+		}
+		for i, v := range row {
+			switch i {
+			case geomIdx:
+			case idIdx:
+				f.ID = provider.FeatureID(v)
+			default:
+				setTag(f.Tags, columns[i].Name, v)
+			}
+		}
+
+		features = append(features, f)
+	}
+
+	return features, nil
+}
+
+// send passes copies of the features to fn, so callers can modify the tags of cached features
+func send(ctx context.Context, features []provider.Feature, fn func(f *provider.Feature) error) error {
+	for _, f := range features {
+		if ctx.Err() != nil {
+			return provider.ErrCanceled
+		}
+
+		f.Tags = provider.CopyTags(f.Tags)
+
+		if err := fn(&f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeGeometry decodes a varbinary WKB value, which the protocol encodes with base64.
+// nil is returned for null geometries.
+func decodeGeometry(v interface{}) (geom.Geometry, error) {
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a varbinary geometry, got %T", v)
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return wkb.DecodeBytes(b)
+}
+
+// setTag adds the value to the tags. null values are dropped, numbers are converted to
+// int64 or float64 and arrays, maps and rows are encoded as JSON strings
+func setTag(tags map[string]interface{}, k string, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			tags[k] = i
+		} else if f, err := val.Float64(); err == nil {
+			tags[k] = f
+		}
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		tags[k] = string(b)
+	default:
+		tags[k] = v
+	}
+}
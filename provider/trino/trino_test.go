@@ -0,0 +1,243 @@
+package trino_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkb"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/dict"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/trino"
+)
+
+// coordinator fakes the statement API of a Trino coordinator. The results of a
+// query are split over two pages.
+type coordinator struct {
+	*httptest.Server
+	t *testing.T
+
+	lock    sync.Mutex
+	queries []string
+}
+
+func newCoordinator(t *testing.T) *coordinator {
+	c := coordinator{t: t}
+	c.Server = httptest.NewServer(http.HandlerFunc(c.handle))
+	return &c
+}
+
+func (c *coordinator) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Trino-User") != "tiles" || r.Header.Get("X-Trino-Source") != "tegola" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	pt, err := wkb.EncodeBytes(geom.Point{-122.4, 37.6})
+	if err != nil {
+		c.t.Fatalf("encoding wkb: %v", err)
+	}
+	encodedPt := base64.StdEncoding.EncodeToString(pt)
+
+	var res map[string]interface{}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/statement":
+		if r.Header.Get("X-Trino-Catalog") != "iceberg" || r.Header.Get("X-Trino-Schema") != "gis" {
+			c.t.Errorf("unexpected catalog (%v) and schema (%v)", r.Header.Get("X-Trino-Catalog"), r.Header.Get("X-Trino-Schema"))
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		c.lock.Lock()
+		c.queries = append(c.queries, string(body))
+		c.lock.Unlock()
+
+		if strings.Contains(string(body), "missing_table") {
+			res = map[string]interface{}{
+				"id":    "q2",
+				"error": map[string]interface{}{"message": "Table 'iceberg.gis.missing_table' does not exist", "errorName": "TABLE_NOT_FOUND"},
+			}
+			break
+		}
+		res = map[string]interface{}{
+			"id":      "q1",
+			"nextUri": c.URL + "/v1/statement/q1/1",
+		}
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/statement/q1/1":
+		res = map[string]interface{}{
+			"id":      "q1",
+			"nextUri": c.URL + "/v1/statement/q1/2",
+			"columns": []map[string]string{{"name": "geom", "type": "varbinary"}, {"name": "id", "type": "bigint"}, {"name": "name", "type": "varchar"}, {"name": "height", "type": "double"}},
+			"data":    [][]interface{}{{encodedPt, 9007199254740993, "SFO", 12.5}},
+		}
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/statement/q1/2":
+		res = map[string]interface{}{
+			"id":   "q1",
+			"data": [][]interface{}{{nil, 2, "no geometry", nil}, {encodedPt, 3, nil, []int{1, 2}}},
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+func TestTileFeatures(t *testing.T) {
+	type tcase struct {
+		layer    map[string]interface{}
+		sql      string
+		expected []provider.Feature
+		err      string
+	}
+
+	c := newCoordinator(t)
+	defer c.Close()
+
+	points := []provider.Feature{
+		{ID: 9007199254740993, Geometry: geom.Point{-122.4, 37.6}, SRID: tegola.WGS84, Tags: map[string]interface{}{"name": "SFO", "height": 12.5}},
+		{ID: 3, Geometry: geom.Point{-122.4, 37.6}, SRID: tegola.WGS84, Tags: map[string]interface{}{"height": "[1,2]"}},
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			c.queries = nil
+
+			tc.layer["name"] = "test"
+			p, err := trino.NewTileProvider(dict.Dict{
+				"url":     c.URL,
+				"user":    "tiles",
+				"catalog": "iceberg",
+				"schema":  "gis",
+				"layers":  []map[string]interface{}{tc.layer},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the second request is answered by the cache
+			for i := 0; i < 2; i++ {
+				var features []provider.Feature
+				err = p.TileFeatures(context.Background(), "test", provider.NewTile(0, 0, 0, 0, tegola.WebMercator), func(f *provider.Feature) error {
+					features = append(features, *f)
+					return nil
+				})
+				if tc.err != "" {
+					if err == nil || err.Error() != tc.err {
+						t.Fatalf("expected error %v got %v", tc.err, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(features, tc.expected) {
+					t.Errorf("features, expected %+v got %+v", tc.expected, features)
+				}
+			}
+
+			if len(c.queries) != 1 {
+				t.Fatalf("queries, expected 1 got %v", len(c.queries))
+			}
+			if c.queries[0] != tc.sql {
+				t.Errorf("sql, expected\n%v\ngot\n%v", tc.sql, c.queries[0])
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"tablename": {
+			layer: map[string]interface{}{
+				"tablename":       "buildings",
+				"geometry_format": "wkt",
+				"id_fieldname":    "id",
+				"fields":          []string{"name", "height"},
+				"bbox_fieldnames": []string{"xmin", "ymin", "xmax", "ymax"},
+			},
+			sql:      `SELECT ST_AsBinary(ST_GeometryFromText("geom")) AS "geom", "id", "name", "height" FROM buildings WHERE ST_Intersects(ST_GeometryFromText("geom"), ST_Envelope(ST_GeometryFromText('LINESTRING (-179.99999997494382 -85.05112877764508, 179.99999997494382 85.05112877764508)'))) AND "xmax" >= -179.99999997494382 AND "xmin" <= 179.99999997494382 AND "ymax" >= -85.05112877764508 AND "ymin" <= 85.05112877764508`,
+			expected: points,
+		},
+		"sql": {
+			layer: map[string]interface{}{
+				"sql":          "SELECT ST_AsBinary(geom) AS geom, id, name, height FROM buildings WHERE ST_Intersects(geom, !BBOX!) AND !ZOOM! >= 0",
+				"id_fieldname": "id",
+			},
+			sql:      `SELECT ST_AsBinary(geom) AS geom, id, name, height FROM buildings WHERE ST_Intersects(geom, ST_Envelope(ST_GeometryFromText('LINESTRING (-179.99999997494382 -85.05112877764508, 179.99999997494382 85.05112877764508)'))) AND 0 >= 0`,
+			expected: points,
+		},
+		"missing geometry column": {
+			layer: map[string]interface{}{
+				"tablename":          "buildings",
+				"geometry_fieldname": "shape",
+			},
+			err: "trino: layer (test) query did not return the (shape) column",
+		},
+		"query error": {
+			layer: map[string]interface{}{
+				"tablename": "missing_table",
+			},
+			err: "trino: query (q2) failed: TABLE_NOT_FOUND: Table 'iceberg.gis.missing_table' does not exist",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestNewTileProvider(t *testing.T) {
+	type tcase struct {
+		config dict.Dict
+		err    string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			_, err := trino.NewTileProvider(tc.config)
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing url": {
+			config: dict.Dict{},
+			err:    trino.ErrMissingURL.Error(),
+		},
+		"tablename and sql": {
+			config: dict.Dict{
+				"url":    "http://localhost:8080",
+				"layers": []map[string]interface{}{{"name": "test", "tablename": "a", "sql": "SELECT 1"}},
+			},
+			err: "trino: layer (test) must define either 'tablename' or 'sql'",
+		},
+		"missing bbox token": {
+			config: dict.Dict{
+				"url":    "http://localhost:8080",
+				"layers": []map[string]interface{}{{"name": "test", "sql": "SELECT geom FROM a"}},
+			},
+			err: "trino: layer (test) sql is missing the !BBOX! token",
+		},
+		"invalid geometry format": {
+			config: dict.Dict{
+				"url":    "http://localhost:8080",
+				"layers": []map[string]interface{}{{"name": "test", "tablename": "a", "geometry_format": "geojson"}},
+			},
+			err: "trino: layer (test) has invalid geometry_format (geojson), expected wkb or wkt",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,162 @@
+// Package raster renders raster tiles from GeoTIFFs and Cloud Optimized GeoTIFFs (COGs),
+// on local disk or on an HTTP(S) server, so maps can serve raster tiles alongside their
+// vector tiles. Only the blocks of the GeoTIFF covering a tile are read, with range requests
+// for remote GeoTIFFs, from the overview closest to the tile's resolution.
+package raster
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/geom/slippy"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/internal/geotiff"
+)
+
+// ContentType of the rendered tiles
+const ContentType = "image/png"
+
+// TileSize is the width and height, in pixels, of the rendered tiles
+const TileSize = 256
+
+const (
+	// DefaultTimeout bounds each request of a remote GeoTIFF
+	DefaultTimeout = 30 * time.Second
+	// DefaultCacheSizeMB is the megabytes of decoded blocks kept in memory
+	DefaultCacheSizeMB = 64
+)
+
+// the radius of the web mercator sphere
+const earthRadius = 6378137.0
+
+// COG renders web mercator PNG tiles from a GeoTIFF in EPSG:4326 or EPSG:3857. Pixels are
+// sampled with nearest neighbour resampling.
+type COG struct {
+	location string
+	closer   io.Closer
+	tiff     *geoTIFF
+}
+
+// Open opens the GeoTIFF at the path or http(s) url. headers are added to every request of
+// a remote GeoTIFF and timeout bounds each of them, 0 is DefaultTimeout. Up to cacheSizeMB
+// megabytes of decoded blocks are kept in memory, 0 disables the block cache.
+func Open(location string, headers map[string]string, timeout time.Duration, cacheSizeMB int) (*COG, error) {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	var f interface {
+		io.ReaderAt
+		io.Closer
+	}
+	if lower := strings.ToLower(location); strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		f = geotiff.NewHTTPReaderAt(location, headers, &http.Client{Timeout: timeout})
+	} else {
+		var err error
+		if f, err = os.Open(location); err != nil {
+			return nil, err
+		}
+	}
+
+	g, err := openGeoTIFF(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if g.SRID == 0 {
+		f.Close()
+		return nil, ErrUnknownSRID
+	}
+
+	img := g.Images[0]
+	g.Cache = geotiff.NewBlockCache(cacheSizeMB * 1024 * 1024 / (img.BlockW * img.BlockH * 4))
+
+	return &COG{
+		location: location,
+		closer:   f,
+		tiff:     g,
+	}, nil
+}
+
+// String returns the location of the GeoTIFF
+func (c *COG) String() string { return c.location }
+
+// SRID returns the SRID of the GeoTIFF
+func (c *COG) SRID() uint64 { return c.tiff.SRID }
+
+// Close closes the GeoTIFF
+func (c *COG) Close() error { return c.closer.Close() }
+
+// Tile renders the tile as a PNG. Pixels of the tile without data are transparent.
+// ErrTileNotFound is returned for tiles outside of the GeoTIFF's extent.
+func (c *COG) Tile(ctx context.Context, z, x, y uint) ([]byte, error) {
+	g := c.tiff
+	ext := slippy.NewTile(z, x, y).Extent3857()
+	res := (ext.MaxX() - ext.MinX()) / TileSize
+
+	// the tile's web mercator coordinates in the GeoTIFF's SRID
+	toX, toY := func(x float64) float64 { return x }, func(y float64) float64 { return y }
+	if g.SRID == tegola.WGS84 {
+		toX = func(x float64) float64 { return x / earthRadius * 180 / math.Pi }
+		toY = func(y float64) float64 { return (math.Pi/2 - 2*math.Atan(math.Exp(-y/earthRadius))) * 180 / math.Pi }
+	}
+
+	full := g.Images[0]
+	minX, maxY := full.OriginX, full.OriginY
+	maxX, minY := minX+float64(full.Width)*full.ScaleX, maxY-float64(full.Height)*full.ScaleY
+	if toX(ext.MaxX()) <= minX || toX(ext.MinX()) >= maxX || toY(ext.MaxY()) <= minY || toY(ext.MinY()) >= maxY {
+		return nil, ErrTileNotFound{Z: z, X: x, Y: y}
+	}
+
+	img := g.Level((toX(ext.MaxX()) - toX(ext.MinX())) / TileSize)
+	blocks := map[int]*image.NRGBA{}
+
+	// the columns of the pixels are the same for every row
+	cols := make([]int, TileSize)
+	for px := range cols {
+		cols[px] = int(math.Floor((toX(ext.MinX()+(float64(px)+0.5)*res) - img.OriginX) / img.ScaleX))
+	}
+
+	tile := image.NewNRGBA(image.Rect(0, 0, TileSize, TileSize))
+	for py := 0; py < TileSize; py++ {
+		if ctx.Err() != nil {
+			return nil, context.Canceled
+		}
+
+		row := int(math.Floor((img.OriginY - toY(ext.MaxY()-(float64(py)+0.5)*res)) / img.ScaleY))
+		if row < 0 || row >= img.Height {
+			continue
+		}
+
+		for px, col := range cols {
+			if col < 0 || col >= img.Width {
+				continue
+			}
+
+			index := (row/img.BlockH)*img.BlocksAcross + col/img.BlockW
+			block, ok := blocks[index]
+			if !ok {
+				var err error
+				if block, err = g.readBlock(img, index); err != nil {
+					return nil, err
+				}
+				blocks[index] = block
+			}
+			tile.SetNRGBA(px, py, block.NRGBAAt(col%img.BlockW, row%img.BlockH))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, tile); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,365 @@
+package raster_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/raster"
+)
+
+// the web mercator extent of the world
+const worldMercator = 20037508.342789244
+
+type testTIFF struct {
+	width, height int
+	samples       int
+	// tileSize is the size of the blocks of a tiled image, 0 writes a strip per row
+	tileSize int
+	deflate  bool
+	srid     int
+	// originX, originY is the top left corner and scale the size of the pixels
+	originX, originY, scale float64
+	nodata                  string
+	// pixel returns the samples of the pixel at column c and row r
+	pixel func(c, r int) []byte
+}
+
+type tiffEntry struct {
+	tag, typ uint16
+	count    uint32
+	data     []byte
+}
+
+// encodeTIFF writes a little endian GeoTIFF of 8 bit samples
+func encodeTIFF(t *testing.T, tt testTIFF) []byte {
+	t.Helper()
+	le := binary.LittleEndian
+
+	shorts := func(vs ...uint16) []byte {
+		b := make([]byte, 2*len(vs))
+		for i, v := range vs {
+			le.PutUint16(b[i*2:], v)
+		}
+		return b
+	}
+	longs := func(vs ...uint32) []byte {
+		b := make([]byte, 4*len(vs))
+		for i, v := range vs {
+			le.PutUint32(b[i*4:], v)
+		}
+		return b
+	}
+	doubles := func(vs ...float64) []byte {
+		b := make([]byte, 8*len(vs))
+		for i, v := range vs {
+			le.PutUint64(b[i*8:], math.Float64bits(v))
+		}
+		return b
+	}
+
+	blockW, blockH := tt.width, 1
+	if tt.tileSize > 0 {
+		blockW, blockH = tt.tileSize, tt.tileSize
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 8))
+	var offsets, counts []uint32
+	for by := 0; by*blockH < tt.height; by++ {
+		for bx := 0; bx*blockW < tt.width; bx++ {
+			var block []byte
+			for r := 0; r < blockH; r++ {
+				for c := 0; c < blockW; c++ {
+					px := make([]byte, tt.samples)
+					if bx*blockW+c < tt.width && by*blockH+r < tt.height {
+						px = tt.pixel(bx*blockW+c, by*blockH+r)
+					}
+					block = append(block, px...)
+				}
+			}
+			if tt.deflate {
+				var zb bytes.Buffer
+				zw := zlib.NewWriter(&zb)
+				zw.Write(block)
+				zw.Close()
+				block = zb.Bytes()
+			}
+			offsets = append(offsets, uint32(buf.Len()))
+			counts = append(counts, uint32(len(block)))
+			buf.Write(block)
+		}
+	}
+
+	compression, photometric := uint16(1), uint16(1)
+	if tt.deflate {
+		compression = 8
+	}
+	if tt.samples >= 3 {
+		photometric = 2
+	}
+	bits := make([]uint16, tt.samples)
+	for i := range bits {
+		bits[i] = 8
+	}
+
+	geoKeys := shorts(1, 1, 0, 2, 1024, 0, 1, 1, 3072, 0, 1, uint16(tt.srid))
+	if tt.srid == tegola.WGS84 {
+		geoKeys = shorts(1, 1, 0, 2, 1024, 0, 1, 2, 2048, 0, 1, uint16(tt.srid))
+	}
+
+	entries := []tiffEntry{
+		{256, 4, 1, longs(uint32(tt.width))},
+		{257, 4, 1, longs(uint32(tt.height))},
+		{258, 3, uint32(tt.samples), shorts(bits...)},
+		{259, 3, 1, shorts(compression)},
+		{262, 3, 1, shorts(photometric)},
+		{277, 3, 1, shorts(uint16(tt.samples))},
+		{33550, 12, 3, doubles(tt.scale, tt.scale, 0)},
+		{33922, 12, 6, doubles(0, 0, 0, tt.originX, tt.originY, 0)},
+		{34735, 3, uint32(len(geoKeys) / 2), geoKeys},
+	}
+	if tt.tileSize > 0 {
+		entries = append(entries,
+			tiffEntry{322, 3, 1, shorts(uint16(blockW))},
+			tiffEntry{323, 3, 1, shorts(uint16(blockH))},
+			tiffEntry{324, 4, uint32(len(offsets)), longs(offsets...)},
+			tiffEntry{325, 4, uint32(len(counts)), longs(counts...)},
+		)
+	} else {
+		entries = append(entries,
+			tiffEntry{273, 4, uint32(len(offsets)), longs(offsets...)},
+			tiffEntry{278, 3, 1, shorts(uint16(blockH))},
+			tiffEntry{279, 4, uint32(len(counts)), longs(counts...)},
+		)
+	}
+	if tt.nodata != "" {
+		entries = append(entries, tiffEntry{42113, 2, uint32(len(tt.nodata) + 1), append([]byte(tt.nodata), 0)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	ifdOffset := buf.Len()
+	extra := ifdOffset + 2 + 12*len(entries) + 4
+	var ifd, values bytes.Buffer
+	ifd.Write(shorts(uint16(len(entries))))
+	for _, e := range entries {
+		ifd.Write(shorts(e.tag, e.typ))
+		ifd.Write(longs(e.count))
+		if len(e.data) <= 4 {
+			ifd.Write(append(e.data, make([]byte, 4-len(e.data))...))
+			continue
+		}
+		ifd.Write(longs(uint32(extra + values.Len())))
+		values.Write(e.data)
+	}
+	ifd.Write(longs(0))
+
+	b := buf.Bytes()
+	copy(b, "II")
+	le.PutUint16(b[2:], 42)
+	le.PutUint32(b[4:], uint32(ifdOffset))
+
+	return append(append(b, ifd.Bytes()...), values.Bytes()...)
+}
+
+// writeTIFF writes the GeoTIFF to a temporary file
+func writeTIFF(t *testing.T, b []byte) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "tegola-raster")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(dir, "raster.tif")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+// quadrants colors the pixels of the north west, north east, south west and south east
+// quarters of the image red, green, blue and white
+func quadrants(size int) func(c, r int) []byte {
+	return func(c, r int) []byte {
+		switch {
+		case c < size/2 && r < size/2:
+			return []byte{255, 0, 0}
+		case r < size/2:
+			return []byte{0, 255, 0}
+		case c < size/2:
+			return []byte{0, 0, 255}
+		default:
+			return []byte{255, 255, 255}
+		}
+	}
+}
+
+func TestTile(t *testing.T) {
+	type tcase struct {
+		tiff    testTIFF
+		z, x, y uint
+		// pixels are the expected colors of the tile's pixels
+		pixels map[image.Point]color.NRGBA
+		err    error
+	}
+
+	red, green, blue, white := color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 255, 0, 255}, color.NRGBA{0, 0, 255, 255}, color.NRGBA{255, 255, 255, 255}
+	transparent := color.NRGBA{}
+
+	// the world in web mercator
+	mercator := testTIFF{
+		width: 64, height: 64, samples: 3, tileSize: 16, deflate: true,
+		srid: tegola.WebMercator, originX: -worldMercator, originY: worldMercator, scale: 2 * worldMercator / 64,
+		pixel: quadrants(64),
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			path, cleanup := writeTIFF(t, encodeTIFF(t, tc.tiff))
+			defer cleanup()
+
+			cog, err := raster.Open(path, nil, 0, raster.DefaultCacheSizeMB)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer cog.Close()
+
+			b, err := cog.Tile(context.Background(), tc.z, tc.x, tc.y)
+			if tc.err != nil {
+				if err != tc.err {
+					t.Fatalf("expected error %v got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if img.Bounds() != image.Rect(0, 0, raster.TileSize, raster.TileSize) {
+				t.Errorf("bounds, expected 256x256 got %v", img.Bounds())
+			}
+			for pt, expected := range tc.pixels {
+				if got := color.NRGBAModel.Convert(img.At(pt.X, pt.Y)); got != expected {
+					t.Errorf("pixel %v, expected %v got %v", pt, expected, got)
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"world": {
+			tiff: mercator,
+			pixels: map[image.Point]color.NRGBA{
+				{10, 10}: red, {200, 10}: green, {10, 200}: blue, {200, 200}: white,
+			},
+		},
+		"zoomed in": {
+			tiff: mercator,
+			z:    2, x: 1, y: 1,
+			pixels: map[image.Point]color.NRGBA{
+				{0, 0}: red, {255, 255}: red,
+			},
+		},
+		"strips": {
+			tiff: testTIFF{
+				width: 64, height: 64, samples: 3,
+				srid: tegola.WebMercator, originX: -worldMercator, originY: worldMercator, scale: 2 * worldMercator / 64,
+				pixel: quadrants(64),
+			},
+			z: 1, x: 1, y: 1,
+			pixels: map[image.Point]color.NRGBA{
+				{128, 128}: white,
+			},
+		},
+		"partial tile": {
+			// the eastern hemisphere, transparent in the west
+			tiff: testTIFF{
+				width: 32, height: 64, samples: 3, tileSize: 16,
+				srid: tegola.WebMercator, originX: 0, originY: worldMercator, scale: 2 * worldMercator / 64,
+				pixel: quadrants(64),
+			},
+			pixels: map[image.Point]color.NRGBA{
+				{10, 10}: transparent, {200, 10}: red,
+			},
+		},
+		"wgs84 gray with nodata": {
+			tiff: testTIFF{
+				width: 360, height: 180, samples: 1, tileSize: 32, deflate: true,
+				srid: tegola.WGS84, originX: -180, originY: 90, scale: 1, nodata: "0",
+				pixel: func(c, r int) []byte {
+					if c < 180 {
+						return []byte{0}
+					}
+					return []byte{128}
+				},
+			},
+			pixels: map[image.Point]color.NRGBA{
+				{10, 128}: transparent, {200, 128}: {128, 128, 128, 255},
+			},
+		},
+		"outside": {
+			tiff: testTIFF{
+				width: 16, height: 16, samples: 3, tileSize: 16,
+				srid: tegola.WebMercator, originX: 0, originY: worldMercator, scale: worldMercator / 16,
+				pixel: quadrants(16),
+			},
+			z: 1, x: 0, y: 1,
+			err: raster.ErrTileNotFound{Z: 1, X: 0, Y: 1},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestOpenRemote(t *testing.T) {
+	b := encodeTIFF(t, testTIFF{
+		width: 64, height: 64, samples: 3, tileSize: 16, deflate: true,
+		srid: tegola.WebMercator, originX: -worldMercator, originY: worldMercator, scale: 2 * worldMercator / 64,
+		pixel: quadrants(64),
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		// ServeContent responds to range requests
+		http.ServeContent(w, r, "raster.tif", time.Time{}, bytes.NewReader(b))
+	}))
+	defer srv.Close()
+
+	if _, err := raster.Open(srv.URL, nil, 0, 0); err == nil {
+		t.Errorf("expected an error without the api key")
+	}
+
+	cog, err := raster.Open(srv.URL, map[string]string{"X-Api-Key": "secret"}, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cog.Close()
+
+	if cog.SRID() != tegola.WebMercator {
+		t.Errorf("srid, expected %v got %v", tegola.WebMercator, cog.SRID())
+	}
+	if _, err := cog.Tile(context.Background(), 1, 1, 0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
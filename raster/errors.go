@@ -0,0 +1,58 @@
+package raster
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownSRID is returned when the SRID of a GeoTIFF can't be identified from its geo keys
+var ErrUnknownSRID = errors.New("raster: the SRID of the GeoTIFF could not be identified, it must be 4326 or 3857")
+
+// ErrRangeUnsupported is returned when the server of a remote GeoTIFF ignores the Range header of a request
+var ErrRangeUnsupported = errors.New("raster: server does not support range requests")
+
+// ErrInvalidGeoTIFF is returned when the file is not a GeoTIFF which can be rendered
+type ErrInvalidGeoTIFF struct {
+	Reason string
+}
+
+func (e ErrInvalidGeoTIFF) Error() string {
+	return fmt.Sprintf("raster: invalid GeoTIFF: %v", e.Reason)
+}
+
+type ErrUnsupportedCompression struct {
+	Compression int
+}
+
+func (e ErrUnsupportedCompression) Error() string {
+	return fmt.Sprintf("raster: unsupported GeoTIFF compression (%v), expected none (1), deflate (8) or jpeg (7)", e.Compression)
+}
+
+type ErrUnsupportedImage struct {
+	Photometric     int
+	SamplesPerPixel int
+	BitsPerSample   int
+}
+
+func (e ErrUnsupportedImage) Error() string {
+	return fmt.Sprintf("raster: unsupported GeoTIFF image of photometric interpretation (%v), %v samples per pixel of %v bits", e.Photometric, e.SamplesPerPixel, e.BitsPerSample)
+}
+
+// ErrTileNotFound is returned for tiles outside of the extent of the GeoTIFF
+type ErrTileNotFound struct {
+	Z, X, Y uint
+}
+
+func (e ErrTileNotFound) Error() string {
+	return fmt.Sprintf("raster: GeoTIFF has no data for tile (%v/%v/%v)", e.Z, e.X, e.Y)
+}
+
+// ErrStatus is returned when the server of a remote GeoTIFF responds with an unexpected status
+type ErrStatus struct {
+	URL    string
+	Status int
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("raster: GeoTIFF (%v) responded with status %v", e.URL, e.Status)
+}
@@ -0,0 +1,203 @@
+package raster
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	"github.com/go-spatial/tegola/internal/geotiff"
+	"github.com/go-spatial/tegola/internal/remote"
+)
+
+// geoTIFF reads the pixels of a GeoTIFF or Cloud Optimized GeoTIFF as 8 bit color images.
+// Gray, RGB, palette and JPEG compressed YCbCr images of 8 or 16 bit unsigned samples are
+// supported, with an optional alpha sample.
+type geoTIFF struct {
+	*geotiff.File
+
+	// palettes of the palette images, by image index
+	palettes map[int][]color.NRGBA
+}
+
+func openGeoTIFF(r io.ReaderAt) (*geoTIFF, error) {
+	f, err := geotiff.Open(r)
+	if err != nil {
+		return nil, geoTIFFError(err)
+	}
+
+	g := geoTIFF{File: f, palettes: map[int][]color.NRGBA{}}
+	for _, img := range f.Images {
+		switch img.Compression {
+		case geotiff.CompressionNone, geotiff.CompressionDeflate, geotiff.CompressionDeflateOld, geotiff.CompressionJPEG:
+		default:
+			return nil, ErrUnsupportedCompression{Compression: img.Compression}
+		}
+
+		unsupported := ErrUnsupportedImage{Photometric: img.Photometric, SamplesPerPixel: img.SamplesPerPixel, BitsPerSample: img.BitsPerSample}
+		if img.SampleFormat != geotiff.SampleFormatUint || (img.BitsPerSample != 8 && img.BitsPerSample != 16) {
+			return nil, unsupported
+		}
+		switch {
+		case img.Compression == geotiff.CompressionJPEG:
+			// the jpeg decoder converts the samples
+			if img.BitsPerSample != 8 || (img.SamplesPerPixel != 1 && img.SamplesPerPixel != 3) {
+				return nil, unsupported
+			}
+		case img.Photometric == geotiff.PhotometricMinIsBlack, img.Photometric == geotiff.PhotometricMinIsWhite:
+			if img.SamplesPerPixel > 2 {
+				return nil, unsupported
+			}
+		case img.Photometric == geotiff.PhotometricRGB:
+			if img.SamplesPerPixel < 3 || img.SamplesPerPixel > 4 {
+				return nil, unsupported
+			}
+		case img.Photometric == geotiff.PhotometricPalette:
+			cm := img.ColorMap
+			n := 1 << uint(img.BitsPerSample)
+			if img.SamplesPerPixel != 1 || len(cm) < 3*n {
+				return nil, unsupported
+			}
+			palette := make([]color.NRGBA, n)
+			for i := range palette {
+				palette[i] = color.NRGBA{R: uint8(cm[i] >> 8), G: uint8(cm[n+i] >> 8), B: uint8(cm[2*n+i] >> 8), A: 0xff}
+			}
+			g.palettes[img.Index] = palette
+		default:
+			return nil, unsupported
+		}
+
+		if img.Planar && img.SamplesPerPixel > 1 {
+			return nil, ErrInvalidGeoTIFF{Reason: "planar images are not supported"}
+		}
+	}
+
+	return &g, nil
+}
+
+// geoTIFFError returns the package's error for an error of the GeoTIFF reader
+func geoTIFFError(err error) error {
+	switch e := err.(type) {
+	case geotiff.ErrInvalid:
+		return ErrInvalidGeoTIFF{Reason: e.Reason}
+	case remote.ErrStatus:
+		return ErrStatus{URL: e.URL, Status: e.Status}
+	}
+	if err == remote.ErrRangeUnsupported {
+		return ErrRangeUnsupported
+	}
+	return err
+}
+
+// hasAlpha reports if the pixels of the image have an alpha sample after their color samples
+func hasAlpha(img *geotiff.Image) bool {
+	switch img.Photometric {
+	case geotiff.PhotometricMinIsBlack, geotiff.PhotometricMinIsWhite:
+		return img.SamplesPerPixel == 2
+	case geotiff.PhotometricRGB:
+		return img.SamplesPerPixel == 4
+	}
+	return false
+}
+
+// readBlock reads and decodes a block of the image. Pixels matching the nodata value are transparent.
+func (g *geoTIFF) readBlock(img *geotiff.Image, index int) (*image.NRGBA, error) {
+	key := geotiff.BlockKey{Image: img.Index, Block: index}
+	if block, ok := g.Cache.Get(key); ok {
+		return block.(*image.NRGBA), nil
+	}
+
+	block := image.NewNRGBA(image.Rect(0, 0, img.BlockW, img.BlockH))
+	if img.Compression == geotiff.CompressionJPEG {
+		data, err := g.ReadBlock(img, index)
+		if err != nil {
+			return nil, geoTIFFError(err)
+		}
+		// sparse blocks have no data
+		if data != nil {
+			if len(img.JPEGTables) > 2 && len(data) > 2 {
+				// the tables end with an EOI marker and the block starts with a SOI marker
+				data = append(append([]byte(nil), img.JPEGTables[:len(img.JPEGTables)-2]...), data[2:]...)
+			}
+			src, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, ErrInvalidGeoTIFF{Reason: "invalid jpeg block: " + err.Error()}
+			}
+			draw.Draw(block, block.Bounds(), src, src.Bounds().Min, draw.Src)
+		}
+		g.Cache.Set(key, block)
+		return block, nil
+	}
+
+	// sparse blocks have no rows
+	data, rows, order, err := g.DecodeBlock(img, index)
+	if err != nil {
+		return nil, geoTIFFError(err)
+	}
+
+	// the color samples of a pixel, without the alpha sample
+	alpha := hasAlpha(img)
+	colors := img.SamplesPerPixel
+	if alpha {
+		colors--
+	}
+	bytesPerSample := img.BitsPerSample / 8
+	samples := make([]int, img.SamplesPerPixel)
+
+	for i := 0; i < rows*img.BlockW; i++ {
+		for s := range samples {
+			b := data[(i*img.SamplesPerPixel+s)*bytesPerSample:]
+			if bytesPerSample == 1 {
+				samples[s] = int(b[0])
+			} else {
+				samples[s] = int(order.Uint16(b))
+			}
+		}
+
+		if g.HasNoData && isNodata(samples[:colors], g.NoData) {
+			continue
+		}
+
+		var c color.NRGBA
+		switch img.Photometric {
+		case geotiff.PhotometricPalette:
+			c = g.palettes[img.Index][samples[0]]
+		case geotiff.PhotometricRGB:
+			c = color.NRGBA{R: to8(samples[0], img), G: to8(samples[1], img), B: to8(samples[2], img), A: 0xff}
+		default:
+			v := to8(samples[0], img)
+			if img.Photometric == geotiff.PhotometricMinIsWhite {
+				v = 0xff - v
+			}
+			c = color.NRGBA{R: v, G: v, B: v, A: 0xff}
+		}
+		if alpha {
+			c.A = to8(samples[colors], img)
+		}
+
+		block.SetNRGBA(i%img.BlockW, i/img.BlockW, c)
+	}
+
+	g.Cache.Set(key, block)
+	return block, nil
+}
+
+// to8 scales a sample of the image to 8 bits
+func to8(v int, img *geotiff.Image) uint8 {
+	if img.BitsPerSample == 16 {
+		return uint8(v >> 8)
+	}
+	return uint8(v)
+}
+
+// isNodata reports if all the color samples of a pixel are the nodata value
+func isNodata(samples []int, nodata float64) bool {
+	for _, s := range samples {
+		if float64(s) != nodata {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+var (
+	// ACME obtains and renews the certificates of the server from an ACME certificate authority.
+	// When set the server listens for HTTPS, instead of reading SSLCert and SSLKey.
+	// configurable via the tegola config.toml file (set in main.go)
+	ACME *autocert.Manager
+
+	// ACMEHTTPPort is the port answering the HTTP-01 challenges of the certificate authority and
+	// redirecting the other requests to HTTPS. Not listened on when empty.
+	// configurable via the tegola config.toml file (set in main.go)
+	ACMEHTTPPort string
+)
+
+// NewACME returns a manager obtaining the certificates of the hosts from the certificate
+// authority at the directory url, Let's Encrypt when empty. The account key and certificates
+// are kept in the cache dir.
+func NewACME(hosts []string, email, cacheDir, directoryURL string) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      email,
+	}
+	if directoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: directoryURL}
+	}
+	return m
+}
+
+// listenACMEHTTP answers the HTTP-01 challenges of the certificate authority on ACMEHTTPPort,
+// redirecting the other requests to HTTPS
+func listenACMEHTTP() {
+	log.Infof("answering acme challenges on port %v", ACMEHTTPPort)
+
+	err := http.ListenAndServe(ACMEHTTPPort, ACME.HTTPHandler(nil))
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
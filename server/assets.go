@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/go-spatial/tegola/internal/awsutil"
+)
+
+// ErrAssetNotFound is returned by Assets when the requested asset doesn't exist
+var ErrAssetNotFound = errors.New("asset not found")
+
+// Assets are the static files served to map clients, i.e. the glyphs of the fonts and the
+// sprites of the styles
+type Assets interface {
+	// Open returns the asset at the slash separated name, or ErrAssetNotFound
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// NewAssets returns the Assets at the location, which is either a directory or a prefix of
+// an S3 (or S3 compatible) bucket in the format s3://bucket/prefix. The S3 connection
+// options are read from the environment. see the awsutil package.
+func NewAssets(location string) (Assets, error) {
+	if !strings.HasPrefix(location, "s3://") {
+		info, err := os.Stat(location)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("assets location (%v) is not a directory", location)
+		}
+		return DirAssets(location), nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("expected s3://bucket/prefix got %v", location)
+	}
+
+	sess, err := awsutil.ConfigFromEnv().Session()
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Assets{
+		Client: s3.New(sess),
+		Bucket: u.Host,
+		Prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+// assetName cleans the name of a requested asset, which must not leave the assets' root
+func assetName(name string) (string, bool) {
+	name = strings.TrimPrefix(name, "/")
+	if name == "" || path.Clean("/"+name) != "/"+name {
+		return "", false
+	}
+	return name, true
+}
+
+// DirAssets are the assets in a directory
+type DirAssets string
+
+// Open opens the file of the asset
+func (d DirAssets) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	name, ok := assetName(name)
+	if !ok {
+		return nil, ErrAssetNotFound
+	}
+
+	f, err := os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, ErrAssetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// directories are not assets
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		f.Close()
+		return nil, ErrAssetNotFound
+	}
+	return f, nil
+}
+
+// S3Assets are the assets under a prefix of an S3 bucket
+type S3Assets struct {
+	Client *s3.S3
+	Bucket string
+	Prefix string
+}
+
+// Open fetches the object of the asset
+func (s *S3Assets) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	name, ok := assetName(name)
+	if !ok {
+		return nil, ErrAssetNotFound
+	}
+
+	res, err := s.Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(path.Join(s.Prefix, name)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrAssetNotFound
+		}
+		return nil, err
+	}
+	return res.Body, nil
+}
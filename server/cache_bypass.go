@@ -0,0 +1,39 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// NoCacheHeader skips reading the tile from the cache, the freshly rendered tile is written
+// back to the cache. Only authorized requests bypass the cache, see cacheBypassed.
+const NoCacheHeader = "X-Tegola-No-Cache"
+
+var (
+	// CacheBypassSecret is the shared secret the NoCacheHeader of a request must match for
+	// the request to bypass the cache. configurable via the tegola config.toml file (set in main.go)
+	CacheBypassSecret string
+
+	// CacheBypassKeyClasses are the key classes whose requests bypass the cache with any
+	// NoCacheHeader value. configurable via the tegola config.toml file (set in main.go)
+	CacheBypassKeyClasses []string
+)
+
+// cacheBypassed reports if the request skips reading the tile cache. The NoCacheHeader must be
+// set to the CacheBypassSecret, or the API key of the request belong to one of the
+// CacheBypassKeyClasses. The header of unauthorized requests is ignored.
+func cacheBypassed(r *http.Request) bool {
+	value := strings.TrimSpace(r.Header.Get(NoCacheHeader))
+	if value == "" {
+		return false
+	}
+	if CacheBypassSecret != "" && subtle.ConstantTimeCompare([]byte(value), []byte(CacheBypassSecret)) == 1 {
+		return true
+	}
+	if len(CacheBypassKeyClasses) == 0 {
+		return false
+	}
+	class := keyClass(r)
+	return class != KeyClassAnonymous && contains(CacheBypassKeyClasses, class)
+}
@@ -0,0 +1,72 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestCacheBypass(t *testing.T) {
+	type tcase struct {
+		header        map[string]string
+		expectedCache string
+	}
+
+	defer func(secret string, classes []string, keys map[string]string) {
+		server.CacheBypassSecret, server.CacheBypassKeyClasses, server.KeyClasses = secret, classes, keys
+	}(server.CacheBypassSecret, server.CacheBypassKeyClasses, server.KeyClasses)
+	server.CacheBypassSecret = "s3cret"
+	server.CacheBypassKeyClasses = []string{"editors"}
+	server.KeyClasses = map[string]string{"editor-key": "editors", "viewer-key": "viewers"}
+
+	a := &atlas.Atlas{}
+	m := atlas.NewWebMercatorMap(testMapName)
+	m.Layers = append(m.Layers, testLayer2, testLayer3)
+	a.AddMap(m)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	router := server.NewRouter(a)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", "/maps/test-map/11/1/1.pbf", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tc.header {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, expected %v got %v: %v", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Tegola-Cache"); got != tc.expectedCache {
+				t.Errorf("header Tegola-Cache, expected %v got %v", tc.expectedCache, got)
+			}
+		}
+	}
+
+	// run in order, the requests share the cached tile
+	tests := []struct {
+		name string
+		tcase
+	}{
+		{"miss", tcase{expectedCache: "MISS"}},
+		{"hit", tcase{expectedCache: "HIT"}},
+		{"secret", tcase{header: map[string]string{server.NoCacheHeader: "s3cret"}, expectedCache: "BYPASS"}},
+		{"wrong secret", tcase{header: map[string]string{server.NoCacheHeader: "guess"}, expectedCache: "HIT"}},
+		{"authorized key class", tcase{header: map[string]string{server.NoCacheHeader: "true", server.APIKeyHeader: "editor-key"}, expectedCache: "BYPASS"}},
+		{"unauthorized key class", tcase{header: map[string]string{server.NoCacheHeader: "true", server.APIKeyHeader: "viewer-key"}, expectedCache: "HIT"}},
+		// the bypassing requests write the tile back
+		{"hit after bypass", tcase{expectedCache: "HIT"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, fn(tc.tcase))
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+var (
+	// cacheLatencyBuckets are the upper bounds, in milliseconds, of the latency histograms
+	cacheLatencyBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+	// cacheSizeBuckets are the upper bounds, in bytes, of the tile size histograms
+	cacheSizeBuckets = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20}
+)
+
+// tileCacheStats are the statistics of the tile cache of the tile endpoints
+var tileCacheStats = newCacheStats()
+
+// HistogramBucket is the number of observations less than or equal to LE
+type HistogramBucket struct {
+	LE    float64 `json:"le"`
+	Count uint64  `json:"count"`
+}
+
+// Histogram reports the distribution of observations. The buckets are cumulative, the
+// observations greater than the last bucket are only included in Count.
+type Histogram struct {
+	Buckets []HistogramBucket `json:"buckets"`
+	Count   uint64            `json:"count"`
+	Sum     float64           `json:"sum"`
+}
+
+// CacheStats are the statistics of the tile cache of a map
+type CacheStats struct {
+	// Hits is the number of tile requests served from the cache
+	Hits uint64 `json:"hits"`
+	// Misses is the number of tile requests rendered as the tile was not cached
+	Misses uint64 `json:"misses"`
+	// Errors is the number of failed reads from and writes to the cache
+	Errors uint64 `json:"errors"`
+	// HitRatio is the ratio of hits to cache lookups
+	HitRatio float64 `json:"hit_ratio"`
+	// GetLatency is the latency of the cache lookups in milliseconds
+	GetLatency Histogram `json:"get_latency_ms"`
+	// FillLatency is the time in milliseconds a missed tile took to render and write to the cache
+	FillLatency Histogram `json:"fill_latency_ms"`
+	// TileSize is the size in bytes of the tiles served from and written to the cache
+	TileSize Histogram `json:"tile_size_bytes"`
+}
+
+// CacheStatsReport are the statistics of the tile cache of all maps
+type CacheStatsReport struct {
+	// Since is when the statistics started to be collected
+	Since time.Time             `json:"since"`
+	Total CacheStats            `json:"total"`
+	Maps  map[string]CacheStats `json:"maps"`
+}
+
+// histogram collects observations in buckets
+type histogram struct {
+	bounds []float64
+	// counts are the observations within each bound, the last one beyond the bounds
+	counts []uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) histogram {
+	return histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i]++
+	h.sum += v
+}
+
+func (h *histogram) merge(o histogram) {
+	for i := range o.counts {
+		h.counts[i] += o.counts[i]
+	}
+	h.sum += o.sum
+}
+
+func (h histogram) snapshot() Histogram {
+	s := Histogram{Buckets: make([]HistogramBucket, len(h.bounds)), Sum: h.sum}
+	for i, b := range h.bounds {
+		s.Count += h.counts[i]
+		s.Buckets[i] = HistogramBucket{LE: b, Count: s.Count}
+	}
+	s.Count += h.counts[len(h.bounds)]
+	return s
+}
+
+// mapCacheStats collects the statistics of the tile cache of a map
+type mapCacheStats struct {
+	hits, misses, errors              uint64
+	getLatency, fillLatency, tileSize histogram
+}
+
+func newMapCacheStats() *mapCacheStats {
+	return &mapCacheStats{
+		getLatency:  newHistogram(cacheLatencyBuckets),
+		fillLatency: newHistogram(cacheLatencyBuckets),
+		tileSize:    newHistogram(cacheSizeBuckets),
+	}
+}
+
+func (s *mapCacheStats) merge(o *mapCacheStats) {
+	s.hits += o.hits
+	s.misses += o.misses
+	s.errors += o.errors
+	s.getLatency.merge(o.getLatency)
+	s.fillLatency.merge(o.fillLatency)
+	s.tileSize.merge(o.tileSize)
+}
+
+func (s *mapCacheStats) snapshot() CacheStats {
+	cs := CacheStats{
+		Hits:        s.hits,
+		Misses:      s.misses,
+		Errors:      s.errors,
+		GetLatency:  s.getLatency.snapshot(),
+		FillLatency: s.fillLatency.snapshot(),
+		TileSize:    s.tileSize.snapshot(),
+	}
+	if lookups := s.hits + s.misses; lookups > 0 {
+		cs.HitRatio = float64(s.hits) / float64(lookups)
+	}
+	return cs
+}
+
+// cacheStats collects the statistics of the tile cache by map
+type cacheStats struct {
+	mu    sync.Mutex
+	since time.Time
+	maps  map[string]*mapCacheStats
+}
+
+func newCacheStats() *cacheStats {
+	return &cacheStats{
+		since: time.Now(),
+		maps:  map[string]*mapCacheStats{},
+	}
+}
+
+// record records the statistics of the map's tile cache with fn. Only the maps of the atlas
+// are recorded, so requests of arbitrary map names don't grow the statistics.
+func (cs *cacheStats) record(a *atlas.Atlas, mapName string, fn func(s *mapCacheStats)) {
+	if _, err := a.Map(mapName); err != nil {
+		return
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	s, ok := cs.maps[mapName]
+	if !ok {
+		s = newMapCacheStats()
+		cs.maps[mapName] = s
+	}
+	fn(s)
+}
+
+func (cs *cacheStats) hit(a *atlas.Atlas, mapName string, latency time.Duration, size int) {
+	cs.record(a, mapName, func(s *mapCacheStats) {
+		s.hits++
+		s.getLatency.observe(milliseconds(latency))
+		s.tileSize.observe(float64(size))
+	})
+}
+
+func (cs *cacheStats) miss(a *atlas.Atlas, mapName string, latency time.Duration) {
+	cs.record(a, mapName, func(s *mapCacheStats) {
+		s.misses++
+		s.getLatency.observe(milliseconds(latency))
+	})
+}
+
+func (cs *cacheStats) fill(a *atlas.Atlas, mapName string, latency time.Duration, size int) {
+	cs.record(a, mapName, func(s *mapCacheStats) {
+		s.fillLatency.observe(milliseconds(latency))
+		s.tileSize.observe(float64(size))
+	})
+}
+
+func (cs *cacheStats) error(a *atlas.Atlas, mapName string) {
+	cs.record(a, mapName, func(s *mapCacheStats) { s.errors++ })
+}
+
+func (cs *cacheStats) report() CacheStatsReport {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	total := newMapCacheStats()
+	report := CacheStatsReport{
+		Since: cs.since,
+		Maps:  make(map[string]CacheStats, len(cs.maps)),
+	}
+	for name, s := range cs.maps {
+		total.merge(s)
+		report.Maps[name] = s.snapshot()
+	}
+	report.Total = total.snapshot()
+	return report
+}
+
+// reset clears the statistics
+func (cs *cacheStats) reset() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.since = time.Now()
+	cs.maps = map[string]*mapCacheStats{}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ConfiguredURLRoot is the scheme, host and path prefix of the absolute urls of the
+	// responses, i.e. https://example.com/tiles. Takes precedence over the HostName and the
+	// X-Forwarded headers when set. configurable via the tegola config.toml file (set in main.go)
+	ConfiguredURLRoot *url.URL
+
+	// TrustedProxies are the networks of the proxies whose X-Forwarded-Proto, X-Forwarded-Host
+	// and X-Forwarded-Prefix headers are used to build the absolute urls of the responses. When
+	// nil the X-Forwarded-Proto of every request is used, and the other headers are ignored.
+	// configurable via the tegola config.toml file (set in main.go)
+	TrustedProxies []*net.IPNet
+)
+
+// forwardedHeader returns the first value of an X-Forwarded header of the request, when the
+// request is from a trusted proxy
+func forwardedHeader(r *http.Request, name string) string {
+	switch {
+	case TrustedProxies != nil:
+		if !trustedProxy(r) {
+			return ""
+		}
+	case name != "X-Forwarded-Proto":
+		// without trusted proxies only the X-Forwarded-Proto is used, of any request
+		return ""
+	}
+
+	// proxies chained in front of tegola append their values, the first is of the client
+	v := r.Header.Get(name)
+	if i := strings.IndexByte(v, ','); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
+
+// trustedProxy reports if the request is from the address of a trusted proxy
+func trustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
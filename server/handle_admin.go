@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// setupAdmin registers the admin endpoints. The endpoints are only registered
+// when an AdminToken or the admin scope of the JWT verifier has been configured.
+func setupAdmin(group *httptreemux.Group, a *atlas.Atlas) {
+	if AdminToken == "" && (JWT == nil || JWT.AdminScope == "") {
+		return
+	}
+
+	hLogLevel := HandleAdminLogLevel{}
+	group.UsingContext().Handler("GET", "/admin/log_level", AdminHandler(hLogLevel))
+	group.UsingContext().Handler("PUT", "/admin/log_level", AdminHandler(hLogLevel))
+
+	hSQLDebug := HandleAdminSQLDebug{}
+	group.UsingContext().Handler("GET", "/admin/sql_debug", AdminHandler(hSQLDebug))
+	group.UsingContext().Handler("PUT", "/admin/sql_debug", AdminHandler(hSQLDebug))
+	group.UsingContext().Handler("DELETE", "/admin/sql_debug/:layer_id", AdminHandler(hSQLDebug))
+
+	group.UsingContext().Handler("GET", "/admin/queue", AdminHandler(HandleAdminQueue{}))
+	group.UsingContext().Handler("GET", "/admin/provider_metrics", AdminHandler(HandleAdminProviderMetrics{}))
+	group.UsingContext().Handler("GET", "/admin/freshness", AdminHandler(HandleAdminFreshness{}))
+	group.UsingContext().Handler("GET", "/admin/reseed", AdminHandler(HandleAdminReseed{}))
+	group.UsingContext().Handler("GET", "/admin/negative_cache", AdminHandler(HandleAdminNegativeCache{}))
+	group.UsingContext().Handler("GET", "/admin/stats", AdminHandler(HandleAdminStats{}))
+	group.UsingContext().Handler("DELETE", "/admin/stats", AdminHandler(HandleAdminStats{}))
+
+	// management of the api keys
+	if APIKeys != nil {
+		hAPIKeys := HandleAdminAPIKeys{}
+		group.UsingContext().Handler("GET", "/admin/api_keys", AdminHandler(hAPIKeys))
+		group.UsingContext().Handler("PUT", "/admin/api_keys/:key", AdminHandler(hAPIKeys))
+		group.UsingContext().Handler("DELETE", "/admin/api_keys/:key", AdminHandler(hAPIKeys))
+	}
+
+	// signing of tile urls
+	if SignedURLs != nil {
+		group.UsingContext().Handler("GET", "/admin/signed_urls/:map_name", AdminHandler(HandleAdminSignedURLs{Atlas: a}))
+	}
+
+	// batch registration of provider layers
+	if LayerImporter != nil {
+		group.UsingContext().Handler("POST", "/admin/layers/import", AdminHandler(HandleAdminLayerImport{Atlas: a}))
+	}
+
+	// runtime management of the maps
+	hMaps := HandleAdminMaps{Atlas: a}
+	group.UsingContext().Handler("GET", "/admin/maps", AdminHandler(hMaps))
+	group.UsingContext().Handler("GET", "/admin/maps/:map_name", AdminHandler(hMaps))
+	group.UsingContext().Handler("DELETE", "/admin/maps/:map_name", AdminHandler(hMaps))
+	if MapRegistrar != nil {
+		group.UsingContext().Handler("PUT", "/admin/maps/:map_name", AdminHandler(hMaps))
+	}
+	if LayerImporter != nil {
+		group.UsingContext().Handler("POST", "/admin/providers/:provider_name/layers", AdminHandler(HandleAdminProviderLayers{Atlas: a}))
+	}
+	if Reloader != nil {
+		group.UsingContext().Handler("POST", "/admin/reload", AdminHandler(HandleAdminReload{Atlas: a}))
+	}
+
+	// cache purging for CI pipelines and editing tools
+	hCache := HandleAdminCache{Atlas: a}
+	group.UsingContext().Handler("DELETE", "/admin/cache/:map_name", AdminHandler(hCache))
+	group.UsingContext().Handler("DELETE", "/admin/cache/:map_name/:z/:x/:y", AdminHandler(hCache))
+
+	// cache purging for CDN / caching proxy tooling
+	hPurge := HandlePurge{Atlas: a}
+	group.UsingContext().Handler(MethodPurge, "/maps/:map_name", AdminHandler(hPurge))
+	group.UsingContext().Handler(MethodPurge, "/maps/:map_name/:z/:x/:y", AdminHandler(hPurge))
+	group.UsingContext().Handler(MethodPurge, "/maps/:map_name/:layer_name/:z/:x/:y", AdminHandler(hPurge))
+}
+
+// writeAdminJSON encodes v as the JSON response body for admin requests
+func writeAdminJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("error encoding admin response: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/apikey"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// HandleAdminAPIKeys manages the API keys of the APIKeys store
+//
+// URI scheme: /admin/api_keys
+// 	GET - returns the keys with their number of requests and rate limited requests
+// 	PUT /admin/api_keys/:key - adds or replaces the key. i.e. {"name": "acme", "maps": ["osm"], "rate_limit": 10}
+// 	DELETE /admin/api_keys/:key - removes the key
+type HandleAdminAPIKeys struct{}
+
+func (req HandleAdminAPIKeys) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := httptreemux.ContextParams(r.Context())["key"]
+
+	switch r.Method {
+	case http.MethodGet:
+		writeAdminJSON(w, APIKeys.Usage())
+
+	case http.MethodDelete:
+		err := APIKeys.Delete(r.Context(), key)
+		if err == apikey.ErrKeyNotFound {
+			http.Error(w, "api key not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Errorf("deleting api key: %v", err)
+			http.Error(w, fmt.Sprintf("error deleting api key: %v", err), http.StatusInternalServerError)
+			return
+		}
+		log.Infof("api key deleted via the admin API")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		var body apikey.Key
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		body.Key = key
+		if err := body.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := APIKeys.Put(r.Context(), body); err != nil {
+			log.Errorf("putting api key: %v", err)
+			http.Error(w, fmt.Sprintf("error putting api key: %v", err), http.StatusInternalServerError)
+			return
+		}
+		log.Infof("api key (%v) put via the admin API", body.Name)
+		writeAdminJSON(w, body)
+	}
+}
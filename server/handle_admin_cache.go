@@ -0,0 +1,264 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// MaxAdminPurgeTiles bounds the tiles a bounds purge of the cache admin endpoint purges, so a
+// mistyped bounds or zoom doesn't keep the server busy for hours. Larger areas can be purged
+// with tegola cache purge.
+var MaxAdminPurgeTiles uint64 = 100000
+
+// HandleAdminCache purges the tiles of a map from the cache backend
+//
+// URI scheme: /admin/cache/:map_name
+// 	DELETE - purges every cached tile of the map. The keys of the cache are listed, which the
+// 		file, memory, redis and s3 caches support.
+// 		?bounds=minx,miny,maxx,maxy (lng/lat) purges the tiles within the bounds instead, at
+// 		the zooms of the map or between ?min_zoom and ?max_zoom.
+//
+// URI scheme: /admin/cache/:map_name/:z/:x/:y
+// 	DELETE - purges the tile of the map and of its layers
+type HandleAdminCache struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+func (req HandleAdminCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := httptreemux.ContextParams(r.Context())
+
+	m, err := req.Atlas.Map(params["map_name"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("map (%v) not configured", params["map_name"]), http.StatusNotFound)
+		return
+	}
+
+	cacher := req.Atlas.GetCache()
+	if cacher == nil {
+		http.Error(w, "no cache configured", http.StatusNotFound)
+		return
+	}
+
+	var purged []cache.Key
+	purge := func(key cache.Key) error {
+		tileSurrogateIndex.remove(key)
+		tileNegativeCache.purge(key)
+		if err := cacher.Purge(&key); err != nil {
+			return fmt.Errorf("error purging tile (%v): %v", key.String(), err)
+		}
+		purged = append(purged, key)
+		return nil
+	}
+
+	query := r.URL.Query()
+	switch {
+	case params["z"] != "":
+		var z, x, y uint
+		if z, x, y, err = parseAdminTile(m.TileGrid(), params["z"], params["x"], params["y"]); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, key := range mapTileKeys(m, z, x, y) {
+			if err = purge(key); err != nil {
+				break
+			}
+		}
+
+	case query.Get("bounds") != "":
+		var tiles []*slippy.Tile
+		if tiles, err = boundsTiles(m, query.Get("bounds"), query.Get("min_zoom"), query.Get("max_zoom")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, tile := range tiles {
+			z, x, y := tile.ZXY()
+			for _, key := range mapTileKeys(m, z, x, y) {
+				if err = purge(key); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+
+	default:
+		err = cache.List(cacher, func(key *cache.Key) error {
+			if key.MapName != m.Name {
+				return nil
+			}
+			return purge(*key)
+		})
+		if errors.Is(err, cache.ErrNotListable) {
+			http.Error(w, "the cache can't list its keys, purge the tiles within bounds instead", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the other instances purge the tiles purged before an error too
+	publishInvalidation(purged, nil)
+	PublishInvalidationEvents(purged)
+
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("purged %v tiles via %v %v", len(purged), r.Method, r.URL.String())
+
+	writeAdminJSON(w, purgeResponse{Purged: len(purged)})
+}
+
+// parseAdminTile parses the z/x/y of a tile url
+func parseAdminTile(grid atlas.Grid, zs, xs, ys string) (z, x, y uint, err error) {
+	zz, err := strconv.ParseUint(zs, 10, 32)
+	if err != nil || zz > uint64(grid.MaxZoom()) {
+		return 0, 0, 0, fmt.Errorf("invalid z (%v)", zs)
+	}
+	cols, rows := grid.MatrixSize(uint(zz))
+
+	xx, err := strconv.ParseUint(xs, 10, 32)
+	if err != nil || xx >= uint64(cols) {
+		return 0, 0, 0, fmt.Errorf("invalid x (%v) at z (%v)", xs, zs)
+	}
+	yy, err := strconv.ParseUint(ys, 10, 32)
+	if err != nil || yy >= uint64(rows) {
+		return 0, 0, 0, fmt.Errorf("invalid y (%v) at z (%v)", ys, zs)
+	}
+	return uint(zz), uint(xx), uint(yy), nil
+}
+
+// mapTileKeys returns the cache keys of the tile of the map, its raster and UTFGrid tiles and
+// of the map's layers
+func mapTileKeys(m atlas.Map, z, x, y uint) []cache.Key {
+	keys := []cache.Key{{MapName: m.Name, Z: z, X: x, Y: y}}
+	if m.HasRaster() {
+		keys = append(keys, cache.Key{MapName: m.Name, Z: z, X: x, Y: y, Format: m.Raster.Format()})
+	}
+	if m.FilterLayersByZoom(z).HasUTFGrid() {
+		keys = append(keys, cache.Key{MapName: m.Name, Z: z, X: x, Y: y, Format: atlas.UTFGridFormat})
+	}
+	for _, l := range m.FilterLayersByZoom(z).Layers {
+		keys = append(keys, cache.Key{MapName: m.Name, LayerName: l.MVTName(), Z: z, X: x, Y: y})
+	}
+	return keys
+}
+
+// mapZooms returns the zooms the map serves tiles at
+func mapZooms(m atlas.Map) (min, max uint) {
+	min = tegola.MaxZ
+	for _, l := range m.Layers {
+		lmax := l.ServedMaxZoom()
+		if lmax == 0 {
+			lmax = atlas.MaxZoom
+		}
+		if l.MinZoom < min {
+			min = l.MinZoom
+		}
+		if lmax > max {
+			max = lmax
+		}
+	}
+	if m.HasUpstream() {
+		min, max = m.Upstream.MinZoom, m.Upstream.MaxZoom
+	}
+	if m.HasRaster() {
+		if m.Raster.MinZoom < min {
+			min = m.Raster.MinZoom
+		}
+		if m.Raster.MaxZoom > max {
+			max = m.Raster.MaxZoom
+		}
+	}
+	if min > max {
+		min = max
+	}
+	return min, max
+}
+
+// boundsTiles returns the tiles within the lng/lat bounds between the zooms, which default to
+// the zooms of the map. At most MaxAdminPurgeTiles tiles are returned.
+func boundsTiles(m atlas.Map, bounds, minZoom, maxZoom string) ([]*slippy.Tile, error) {
+	parts := strings.Split(bounds, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid bounds (%v), expected minx,miny,maxx,maxy", bounds)
+	}
+	var b [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		limit := 180.0
+		if i%2 == 1 {
+			limit = 90
+		}
+		if err != nil || math.Abs(v) > limit {
+			return nil, fmt.Errorf("invalid bounds (%v), expected minx,miny,maxx,maxy in lng/lat", bounds)
+		}
+		b[i] = v
+	}
+	minZ, maxZ := mapZooms(m)
+	for _, z := range []struct {
+		val string
+		z   *uint
+	}{{minZoom, &minZ}, {maxZoom, &maxZ}} {
+		if z.val == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(z.val, 10, 32)
+		if err != nil || v > tegola.MaxZ {
+			return nil, fmt.Errorf("invalid zoom (%v)", z.val)
+		}
+		*z.z = uint(v)
+	}
+	if minZ > maxZ {
+		return nil, fmt.Errorf("min_zoom (%v) is greater than max_zoom (%v)", minZ, maxZ)
+	}
+
+	type tileRange struct{ z, xi, xf, yi, yf uint }
+	var (
+		ranges []tileRange
+		count  uint64
+	)
+	// the tiles of the map's grid, the tiles beyond the latitudes of web mercator are its edge tiles
+	grid := m.TileGrid()
+	if grid.TileBounds(0, 0, 0) == nil {
+		return nil, fmt.Errorf("bounds can't be projected to the grid (srid %v) of map (%v), purge its tiles by zoom, column and row", grid.SRID, m.Name)
+	}
+	ext := geom.Extent{math.Min(b[0], b[2]), math.Min(b[1], b[3]), math.Max(b[0], b[2]), math.Max(b[1], b[3])}
+	for z := minZ; z <= maxZ; z++ {
+		xi, yi, xf, yf, ok := grid.TileRange(z, ext, 0)
+		if !ok {
+			continue
+		}
+
+		count += uint64(xf-xi+1) * uint64(yf-yi+1)
+		if count > MaxAdminPurgeTiles {
+			return nil, fmt.Errorf("the bounds cover more than %v tiles between zooms %v and %v, narrow the bounds or zooms", MaxAdminPurgeTiles, minZ, maxZ)
+		}
+		ranges = append(ranges, tileRange{z, xi, xf, yi, yf})
+	}
+
+	tiles := make([]*slippy.Tile, 0, count)
+	for _, r := range ranges {
+		for x := r.xi; x <= r.xf; x++ {
+			for y := r.yi; y <= r.yf; y++ {
+				tiles = append(tiles, slippy.NewTile(r.z, x, y))
+			}
+		}
+	}
+	return tiles, nil
+}
@@ -0,0 +1,129 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleAdminCache(t *testing.T) {
+	type tcase struct {
+		uri          string
+		token        string
+		expectedCode int
+		expected     int
+	}
+
+	const tileURI = "/maps/test-map/10/2/3.pbf"
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			server.AdminToken = testAdminToken
+			defer func() { server.AdminToken = "" }()
+
+			a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+			cacher, _ := memory.New(nil)
+			a.SetCache(cacher)
+
+			// prime the cache
+			w, router, err := doRequest(a, "GET", tileURI, nil)
+			if err != nil {
+				t.Fatalf("error making request, expected nil got %v", err)
+			}
+			if w.Header().Get("Tegola-Cache") != "MISS" {
+				t.Fatalf("header Tegola-Cache, expected MISS got %v", w.Header().Get("Tegola-Cache"))
+			}
+
+			r, err := http.NewRequest("DELETE", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Purged int `json:"purged"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("unable to decode response: %v", err)
+			}
+			if resp.Purged != tc.expected {
+				t.Errorf("purged, expected %v got %v", tc.expected, resp.Purged)
+			}
+
+			// the tile should have been purged from the cache
+			r, _ = http.NewRequest("GET", tileURI, nil)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Header().Get("Tegola-Cache") != "MISS" {
+				t.Errorf("header Tegola-Cache after purge, expected MISS got %v", w.Header().Get("Tegola-Cache"))
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"map": {
+			uri:          "/admin/cache/test-map",
+			token:        testAdminToken,
+			expectedCode: http.StatusOK,
+			expected:     1,
+		},
+		"tile": {
+			// the map tile and the tiles of its two layers at zoom 10
+			uri:          "/admin/cache/test-map/10/2/3",
+			token:        testAdminToken,
+			expectedCode: http.StatusOK,
+			expected:     3,
+		},
+		"bounds": {
+			// within tile 10/2/3
+			uri:          "/admin/cache/test-map?bounds=-179.13,84.94,-179.12,84.95&min_zoom=10&max_zoom=10",
+			token:        testAdminToken,
+			expectedCode: http.StatusOK,
+			expected:     3,
+		},
+		"bounds too large": {
+			uri:          "/admin/cache/test-map?bounds=-180,-90,180,90",
+			token:        testAdminToken,
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid bounds": {
+			uri:          "/admin/cache/test-map?bounds=-180,-90,180",
+			token:        testAdminToken,
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid tile": {
+			uri:          "/admin/cache/test-map/10/2000/3",
+			token:        testAdminToken,
+			expectedCode: http.StatusBadRequest,
+		},
+		"unknown map": {
+			uri:          "/admin/cache/other-map",
+			token:        testAdminToken,
+			expectedCode: http.StatusNotFound,
+		},
+		"unauthorized": {
+			uri:          "/admin/cache/test-map",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
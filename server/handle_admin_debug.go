@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const (
+	// DefaultSQLDebugTTL is how long runtime SQL debugging stays enabled when a ttl is not provided
+	DefaultSQLDebugTTL = 15 * time.Minute
+	// MaxSQLDebugTTL is the longest runtime SQL debugging can be enabled for
+	MaxSQLDebugTTL = 24 * time.Hour
+)
+
+var (
+	// logLevelRevert holds the timer used to restore the log level after a ttl expires
+	logLevelRevert     *time.Timer
+	logLevelRevertLock sync.Mutex
+)
+
+// AdminLogLevel is the request and response body of the log level admin endpoint
+type AdminLogLevel struct {
+	Level string `json:"level"`
+	// TTL is optional. when set, the previous log level is restored once the duration has elapsed.
+	// the value is parsed with time.ParseDuration (i.e. "10m")
+	TTL string `json:"ttl,omitempty"`
+}
+
+// HandleAdminLogLevel reports and changes the log level at runtime
+//
+// URI scheme: /admin/log_level
+// 	GET - returns the current log level
+// 	PUT - sets the log level. i.e. {"level": "debug", "ttl": "10m"}
+type HandleAdminLogLevel struct{}
+
+func (req HandleAdminLogLevel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		writeAdminJSON(w, AdminLogLevel{Level: log.GetLogLevel().String()})
+		return
+	}
+
+	var body AdminLogLevel
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	lvl, err := log.ParseLevel(body.Level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var ttl time.Duration
+	if body.TTL != "" {
+		if ttl, err = time.ParseDuration(body.TTL); err != nil || ttl <= 0 {
+			http.Error(w, fmt.Sprintf("invalid ttl (%v)", body.TTL), http.StatusBadRequest)
+			return
+		}
+	}
+
+	logLevelRevertLock.Lock()
+	// a new level replaces any pending revert
+	if logLevelRevert != nil {
+		logLevelRevert.Stop()
+		logLevelRevert = nil
+	}
+
+	prev := log.GetLogLevel()
+	log.SetLogLevel(lvl)
+	log.Infof("log level changed from %v to %v via the admin API", prev, lvl)
+
+	if ttl > 0 {
+		logLevelRevert = time.AfterFunc(ttl, func() {
+			logLevelRevertLock.Lock()
+			defer logLevelRevertLock.Unlock()
+
+			log.SetLogLevel(prev)
+			log.Infof("log level restored to %v after %v", prev, ttl)
+			logLevelRevert = nil
+		})
+	}
+	logLevelRevertLock.Unlock()
+
+	writeAdminJSON(w, AdminLogLevel{Level: lvl.String(), TTL: body.TTL})
+}
+
+// AdminSQLDebug is the request body for enabling SQL debugging at runtime
+type AdminSQLDebug struct {
+	// LayerID is the provider layer ID to enable debugging for. "*" or empty enables all layers
+	LayerID    string `json:"layer_id"`
+	LayerSQL   bool   `json:"layer_sql"`
+	ExecuteSQL bool   `json:"execute_sql"`
+	// TTL is how long the setting stays active, parsed with time.ParseDuration. Defaults to DefaultSQLDebugTTL
+	TTL string `json:"ttl,omitempty"`
+}
+
+// HandleAdminSQLDebug toggles per layer SQL debugging for providers which support it.
+// Settings expire automatically so debugging output is not left on in production.
+//
+// URI scheme: /admin/sql_debug
+// 	GET - returns the active settings keyed by layer id
+// 	PUT - enables debugging. i.e. {"layer_id": "roads", "execute_sql": true, "ttl": "5m"}
+// 	DELETE /admin/sql_debug/:layer_id - removes the setting for the layer
+type HandleAdminSQLDebug struct{}
+
+func (req HandleAdminSQLDebug) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeAdminJSON(w, provider.SQLDebugSettings())
+
+	case http.MethodDelete:
+		lyrID := httptreemux.ContextParams(r.Context())["layer_id"]
+		provider.ClearSQLDebug(lyrID)
+		log.Infof("SQL debugging disabled for layer (%v) via the admin API", lyrID)
+
+		writeAdminJSON(w, provider.SQLDebugSettings())
+
+	default:
+		var body AdminSQLDebug
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		ttl := DefaultSQLDebugTTL
+		if body.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(body.TTL); err != nil || ttl <= 0 {
+				http.Error(w, fmt.Sprintf("invalid ttl (%v)", body.TTL), http.StatusBadRequest)
+				return
+			}
+		}
+		if ttl > MaxSQLDebugTTL {
+			ttl = MaxSQLDebugTTL
+		}
+
+		lyrID := body.LayerID
+		if lyrID == "" {
+			lyrID = provider.SQLDebugAllLayers
+		}
+
+		if !body.LayerSQL && !body.ExecuteSQL {
+			provider.ClearSQLDebug(lyrID)
+		} else {
+			provider.SetSQLDebug(lyrID, provider.SQLDebug{
+				LayerSQL:   body.LayerSQL,
+				ExecuteSQL: body.ExecuteSQL,
+				Expires:    time.Now().Add(ttl),
+			})
+			log.Infof("SQL debugging enabled for layer (%v) for %v via the admin API", lyrID, ttl)
+		}
+
+		writeAdminJSON(w, provider.SQLDebugSettings())
+	}
+}
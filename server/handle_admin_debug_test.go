@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/server"
+)
+
+const testAdminToken = "test-admin-token"
+
+func TestHandleAdminLogLevel(t *testing.T) {
+	type tcase struct {
+		method       string
+		token        string
+		body         string
+		expectedCode int
+		expected     log.Level
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			lvl := log.GetLogLevel()
+			defer log.SetLogLevel(lvl)
+
+			server.AdminToken = testAdminToken
+			defer func() { server.AdminToken = "" }()
+
+			router := server.NewRouter(nil)
+
+			r, err := http.NewRequest(tc.method, "/admin/log_level", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v", tc.expectedCode, w.Code)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp server.AdminLogLevel
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("unable to decode response: %v", err)
+			}
+			if resp.Level != tc.expected.String() {
+				t.Errorf("level, expected %v got %v", tc.expected, resp.Level)
+			}
+			if log.GetLogLevel() != tc.expected {
+				t.Errorf("log level, expected %v got %v", tc.expected, log.GetLogLevel())
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"get": {
+			method:       "GET",
+			token:        testAdminToken,
+			expectedCode: http.StatusOK,
+			expected:     log.INFO,
+		},
+		"set": {
+			method:       "PUT",
+			token:        testAdminToken,
+			body:         `{"level":"debug","ttl":"1m"}`,
+			expectedCode: http.StatusOK,
+			expected:     log.DEBUG,
+		},
+		"invalid level": {
+			method:       "PUT",
+			token:        testAdminToken,
+			body:         `{"level":"chatty"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		"missing token": {
+			method:       "GET",
+			expectedCode: http.StatusUnauthorized,
+		},
+		"wrong token": {
+			method:       "GET",
+			token:        "foo",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestHandleAdminSQLDebug(t *testing.T) {
+	server.AdminToken = testAdminToken
+	defer func() { server.AdminToken = "" }()
+
+	router := server.NewRouter(nil)
+
+	do := func(method, uri, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, uri, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+testAdminToken)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("PUT", "/admin/sql_debug", `{"layer_id":"roads","execute_sql":true,"ttl":"1m"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+	}
+	if !provider.SQLDebugFor("roads").ExecuteSQL {
+		t.Errorf("expected execute_sql to be enabled for layer roads")
+	}
+	if provider.SQLDebugFor("rivers").ExecuteSQL {
+		t.Errorf("expected execute_sql to be disabled for layer rivers")
+	}
+
+	if w = do("PUT", "/admin/sql_debug", `{"ttl":"forever"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("status code, expected %v got %v", http.StatusBadRequest, w.Code)
+	}
+
+	if w = do("DELETE", "/admin/sql_debug/roads", ""); w.Code != http.StatusOK {
+		t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+	}
+	if provider.SQLDebugFor("roads").ExecuteSQL {
+		t.Errorf("expected execute_sql to be disabled for layer roads")
+	}
+}
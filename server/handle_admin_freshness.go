@@ -0,0 +1,15 @@
+package server
+
+import (
+	"net/http"
+)
+
+// HandleAdminFreshness reports the freshness of the map layers with a freshness SLA,
+// including the number of SLA violations
+//
+// 	GET /admin/freshness
+type HandleAdminFreshness struct{}
+
+func (req HandleAdminFreshness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, FreshnessMonitor.Freshness())
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+// MaxLayerManifestBytes bounds the size of a layer import manifest
+const MaxLayerManifestBytes = 8 << 20
+
+// LayerImporter registers the layers of a manifest with their providers and adds them to
+// their maps. The layers are validated before any is registered, and the maps only change
+// once every layer has been registered, so a failed import leaves the maps as they were.
+// With dryRun the manifest is only validated. Configured via cmd/tegola/cmd/server.go, the
+// import endpoint is not registered when nil.
+var LayerImporter func(a *atlas.Atlas, entries []LayerManifestEntry, dryRun bool) LayerImportReport
+
+// LayerManifestEntry describes a provider layer to import
+type LayerManifestEntry struct {
+	// Provider is the name of the provider the layer is registered with
+	Provider string `json:"provider"`
+	// Name is the name of the provider layer, and its id for providers with layer ids
+	Name string `json:"name"`
+	// Map the layer is added to. Optional, the layer is only registered with the provider when empty.
+	Map string `json:"map,omitempty"`
+	// MinZoom and MaxZoom of the map layer
+	MinZoom *uint `json:"min_zoom,omitempty"`
+	MaxZoom *uint `json:"max_zoom,omitempty"`
+	// Config is the provider layer config, as in the providers' layers of the config file
+	// (i.e. tablename, fields, sql). The name and id are set to Name.
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// LayerImportReport is the response of the layer import endpoint
+type LayerImportReport struct {
+	DryRun bool `json:"dry_run"`
+	// Imported is the number of layers registered
+	Imported int `json:"imported"`
+	// Layers are the results of the manifest's layers, in order
+	Layers []LayerImportResult `json:"layers"`
+}
+
+// Failed reports if any layer of the import failed
+func (r LayerImportReport) Failed() bool {
+	for _, l := range r.Layers {
+		if l.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// LayerImportResult is the result of a manifest layer
+type LayerImportResult struct {
+	Provider string `json:"provider"`
+	Name     string `json:"name"`
+	Map      string `json:"map,omitempty"`
+	// Error is empty for valid layers
+	Error string `json:"error,omitempty"`
+}
+
+// HandleAdminLayerImport registers many provider layers at once from a manifest
+//
+// URI scheme: /admin/layers/import
+// 	POST - imports the layers of a JSON (an array of LayerManifestEntry) or CSV (Content-Type: text/csv) manifest.
+// 		?dry_run=true only validates the manifest.
+type HandleAdminLayerImport struct {
+	Atlas *atlas.Atlas
+}
+
+func (req HandleAdminLayerImport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	body := io.LimitReader(r.Body, MaxLayerManifestBytes)
+
+	var (
+		entries []LayerManifestEntry
+		err     error
+	)
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "text/csv" {
+		entries, err = readCSVLayerManifest(body)
+	} else if err = json.NewDecoder(body).Decode(&entries); err == nil {
+		for i := range entries {
+			jsonConfigValue(entries[i].Config)
+		}
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid layer manifest: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(entries) == 0 {
+		http.Error(w, "layer manifest has no layers", http.StatusBadRequest)
+		return
+	}
+
+	report := LayerImporter(req.Atlas, entries, dryRun)
+	if report.Failed() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+	writeAdminJSON(w, report)
+}
+
+// readCSVLayerManifest reads a manifest with a header row. The provider, name, map, min_zoom
+// and max_zoom columns describe the layer and the other columns are its provider config.
+// Values of the fields column are split at ';' and empty values are left out of the config.
+func readCSVLayerManifest(r io.Reader) ([]LayerManifestEntry, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []LayerManifestEntry
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e := LayerManifestEntry{Config: map[string]interface{}{}}
+		for i, col := range header {
+			val := strings.TrimSpace(row[i])
+			if val == "" {
+				continue
+			}
+
+			switch col {
+			case "provider":
+				e.Provider = val
+			case "name":
+				e.Name = val
+			case "map":
+				e.Map = val
+			case "min_zoom", "max_zoom":
+				z, err := strconv.ParseUint(val, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("line %v: invalid %v (%v)", len(entries)+2, col, val)
+				}
+				zoom := uint(z)
+				if col == "min_zoom" {
+					e.MinZoom = &zoom
+				} else {
+					e.MaxZoom = &zoom
+				}
+			case "fields":
+				e.Config[col] = strings.Split(val, ";")
+			default:
+				e.Config[col] = val
+			}
+		}
+		entries = append(entries, e)
+	}
+}
+
+// jsonConfigValue converts the integers of a JSON decoded config value, decoded as floats,
+// into the integers provider configs expect
+func jsonConfigValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			return int64(val)
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = jsonConfigValue(val[i])
+		}
+	case map[string]interface{}:
+		for k := range val {
+			val[k] = jsonConfigValue(val[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/internal/env"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// MaxMapConfigBytes bounds the size of a map config put via the admin API
+const MaxMapConfigBytes = 1 << 20
+
+var (
+	// MapRegistrar validates the config of a map and registers the map with the atlas,
+	// replacing the map with the same name. Configured via cmd/tegola/cmd/server.go, maps
+	// can't be put when nil.
+	MapRegistrar func(a *atlas.Atlas, m config.Map) error
+
+	// Reloader reloads the providers and maps of the config file, replacing the maps of the
+	// atlas. Configured via cmd/tegola/cmd/server.go, the reload endpoint is not registered
+	// when nil.
+	Reloader func(a *atlas.Atlas) error
+)
+
+// AdminMap describes a map of the atlas
+type AdminMap struct {
+	Name   string          `json:"name"`
+	Layers []AdminMapLayer `json:"layers"`
+}
+
+// AdminMapLayer describes a layer of a map
+type AdminMapLayer struct {
+	Name            string `json:"name"`
+	ID              string `json:"id,omitempty"`
+	ProviderLayerID string `json:"provider_layer_id"`
+	MinZoom         uint   `json:"min_zoom"`
+	MaxZoom         uint   `json:"max_zoom"`
+}
+
+// adminMap describes the map
+func adminMap(m atlas.Map) AdminMap {
+	am := AdminMap{
+		Name:   m.Name,
+		Layers: []AdminMapLayer{},
+	}
+	for _, l := range m.Layers {
+		am.Layers = append(am.Layers, AdminMapLayer{
+			Name:            l.MVTName(),
+			ID:              l.ID,
+			ProviderLayerID: l.ProviderLayerID,
+			MinZoom:         l.MinZoom,
+			MaxZoom:         l.MaxZoom,
+		})
+	}
+	return am
+}
+
+// HandleAdminMaps manages the maps of the atlas at runtime. Changes are not written back to
+// the config file, so they are lost on restart or reload.
+//
+// URI scheme: /admin/maps
+// 	GET - returns the maps with their layers
+// 	GET /admin/maps/:map_name - returns the map with its layers
+// 	PUT /admin/maps/:map_name - adds or replaces the map. The body is the TOML of the map, as in the [[maps]] of the config file
+// 	DELETE /admin/maps/:map_name - removes the map
+type HandleAdminMaps struct {
+	Atlas *atlas.Atlas
+}
+
+func (req HandleAdminMaps) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mapName := httptreemux.ContextParams(r.Context())["map_name"]
+
+	switch r.Method {
+	case http.MethodGet:
+		if mapName != "" {
+			m, err := req.Atlas.Map(mapName)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("map (%v) not found", mapName), http.StatusNotFound)
+				return
+			}
+			writeAdminJSON(w, adminMap(m))
+			return
+		}
+
+		maps := []AdminMap{}
+		for _, m := range req.Atlas.AllMaps() {
+			maps = append(maps, adminMap(m))
+		}
+		sort.Slice(maps, func(i, j int) bool { return maps[i].Name < maps[j].Name })
+		writeAdminJSON(w, maps)
+
+	case http.MethodDelete:
+		if !req.Atlas.RemoveMap(mapName) {
+			http.Error(w, fmt.Sprintf("map (%v) not found", mapName), http.StatusNotFound)
+			return
+		}
+		log.Infof("map (%v) removed via the admin API", mapName)
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodPut:
+		var cfg config.Map
+		if _, err := toml.DecodeReader(io.LimitReader(r.Body, MaxMapConfigBytes), &cfg); err != nil {
+			http.Error(w, fmt.Sprintf("invalid map config: %v", err), http.StatusBadRequest)
+			return
+		}
+		if cfg.Name != "" && string(cfg.Name) != mapName {
+			http.Error(w, fmt.Sprintf("map config name (%v) does not match the url (%v)", cfg.Name, mapName), http.StatusBadRequest)
+			return
+		}
+		cfg.Name = env.String(mapName)
+
+		if err := MapRegistrar(req.Atlas, cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		log.Infof("map (%v) put via the admin API", mapName)
+
+		m, err := req.Atlas.Map(mapName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeAdminJSON(w, adminMap(m))
+	}
+}
+
+// HandleAdminProviderLayers registers a layer with a provider at runtime, with the provider's
+// AddLayer. The layer can then be added to maps.
+//
+// URI scheme: /admin/providers/:provider_name/layers
+// 	POST - registers the layer. i.e. {"name": "roads", "map": "osm", "config": {"tablename": "roads"}}
+type HandleAdminProviderLayers struct {
+	Atlas *atlas.Atlas
+}
+
+func (req HandleAdminProviderLayers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var entry LayerManifestEntry
+	if err := json.NewDecoder(io.LimitReader(r.Body, MaxLayerManifestBytes)).Decode(&entry); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	jsonConfigValue(entry.Config)
+	entry.Provider = httptreemux.ContextParams(r.Context())["provider_name"]
+
+	report := LayerImporter(req.Atlas, []LayerManifestEntry{entry}, false)
+	if report.Failed() {
+		http.Error(w, report.Layers[0].Error, http.StatusUnprocessableEntity)
+		return
+	}
+	log.Infof("layer (%v) added to provider (%v) via the admin API", entry.Name, entry.Provider)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report.Layers[0])
+}
+
+// HandleAdminReload reloads the providers and maps of the config file
+//
+// URI scheme: /admin/reload
+// 	POST - reloads the config. The maps are unchanged when the config fails to load.
+type HandleAdminReload struct {
+	Atlas *atlas.Atlas
+}
+
+func (req HandleAdminReload) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := Reloader(req.Atlas); err != nil {
+		log.Errorf("reloading the config: %v", err)
+		http.Error(w, fmt.Sprintf("error reloading the config: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	log.Infof("config reloaded via the admin API")
+	writeAdminJSON(w, map[string]int{"maps": len(req.Atlas.AllMaps())})
+}
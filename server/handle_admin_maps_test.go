@@ -0,0 +1,99 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/config"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleAdminMaps(t *testing.T) {
+	server.AdminToken = testAdminToken
+	server.MapRegistrar = func(a *atlas.Atlas, m config.Map) error {
+		if len(m.Layers) == 0 {
+			return errors.New("map has no layers")
+		}
+		newMap := atlas.NewWebMercatorMap(string(m.Name))
+		newMap.Layers = append(newMap.Layers, testLayer1)
+		a.AddMap(newMap)
+		return nil
+	}
+	defer func() {
+		server.AdminToken = ""
+		server.MapRegistrar = nil
+	}()
+
+	a := newTestMapWithLayers(testLayer1)
+	router := server.NewRouter(a)
+
+	type tcase struct {
+		method       string
+		uri          string
+		body         string
+		token        string
+		expectedCode int
+		expectedMaps []string
+	}
+
+	// the cases run in order against the same atlas
+	tests := []tcase{
+		{method: "GET", uri: "/admin/maps", expectedCode: http.StatusUnauthorized},
+		{method: "GET", uri: "/admin/maps", token: testAdminToken, expectedCode: http.StatusOK, expectedMaps: []string{"test-map"}},
+		{method: "PUT", uri: "/admin/maps/other-map", body: "not = [toml", token: testAdminToken, expectedCode: http.StatusBadRequest},
+		{method: "PUT", uri: "/admin/maps/other-map", body: `name = "renamed"`, token: testAdminToken, expectedCode: http.StatusBadRequest},
+		{method: "PUT", uri: "/admin/maps/other-map", body: `center = [0.0, 0.0, 1.0]`, token: testAdminToken, expectedCode: http.StatusUnprocessableEntity},
+		{method: "PUT", uri: "/admin/maps/other-map", body: "[[layers]]\nprovider_layer = \"provider.layer\"", token: testAdminToken, expectedCode: http.StatusOK},
+		{method: "GET", uri: "/admin/maps", token: testAdminToken, expectedCode: http.StatusOK, expectedMaps: []string{"other-map", "test-map"}},
+		{method: "GET", uri: "/admin/maps/other-map", token: testAdminToken, expectedCode: http.StatusOK},
+		{method: "DELETE", uri: "/admin/maps/test-map", token: testAdminToken, expectedCode: http.StatusNoContent},
+		{method: "DELETE", uri: "/admin/maps/test-map", token: testAdminToken, expectedCode: http.StatusNotFound},
+		{method: "GET", uri: "/admin/maps/test-map", token: testAdminToken, expectedCode: http.StatusNotFound},
+		{method: "GET", uri: "/admin/maps", token: testAdminToken, expectedCode: http.StatusOK, expectedMaps: []string{"other-map"}},
+	}
+
+	for i, tc := range tests {
+		r, err := http.NewRequest(tc.method, tc.uri, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != tc.expectedCode {
+			t.Fatalf("case %v %v %v: status code, expected %v got %v: %v", i, tc.method, tc.uri, tc.expectedCode, w.Code, w.Body.String())
+		}
+		if tc.expectedMaps == nil {
+			continue
+		}
+
+		var maps []server.AdminMap
+		if err := json.NewDecoder(w.Body).Decode(&maps); err != nil {
+			t.Fatalf("case %v: unable to decode response: %v", i, err)
+		}
+		var names []string
+		for _, m := range maps {
+			names = append(names, m.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tc.expectedMaps, ",") {
+			t.Errorf("case %v: maps, expected %v got %v", i, tc.expectedMaps, names)
+		}
+	}
+
+	// the maps are served from the atlas
+	r, _ := http.NewRequest("GET", "/maps/other-map/5/2/3.pbf", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("tile of the added map, expected %v got %v: %v", http.StatusOK, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-spatial/tegola/provider/decorators"
+)
+
+// HandleAdminProviderMetrics reports the metrics collected by providers
+// wrapped with the metrics decorator
+//
+// 	GET /admin/provider_metrics
+type HandleAdminProviderMetrics struct{}
+
+func (req HandleAdminProviderMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, decorators.Metrics())
+}
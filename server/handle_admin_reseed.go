@@ -0,0 +1,15 @@
+package server
+
+import (
+	"net/http"
+)
+
+// HandleAdminReseed reports the re-seeding of the map layers whose providers report their
+// changes, including the number of cached tiles re-rendered
+//
+// 	GET /admin/reseed
+type HandleAdminReseed struct{}
+
+func (req HandleAdminReseed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, Reseeder.Reseeds())
+}
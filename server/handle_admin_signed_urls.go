@@ -0,0 +1,85 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+// signedURLDefaultTTL is the expiry of the signed urls when no ttl is requested
+const signedURLDefaultTTL = time.Hour
+
+// HandleAdminSignedURLs signs the tile url template of a map for sharing with a client
+//
+// 	GET /admin/signed_urls/:map_name?key=partner&ttl=3600
+//
+// The query parameters:
+// 	key - the name of the signing key. Required.
+// 	ttl - the number of seconds the url is valid for. Defaults to 3600.
+// 	layer - the name of a layer, to sign the tiles of the layer instead of the map.
+type HandleAdminSignedURLs struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+// SignedURL is the response of the signed urls endpoint
+type SignedURL struct {
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+func (req HandleAdminSignedURLs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mapName := httptreemux.ContextParams(r.Context())["map_name"]
+	query := r.URL.Query()
+
+	m, err := req.Atlas.Map(mapName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("map (%v) not configured. check your config file", mapName), http.StatusNotFound)
+		return
+	}
+
+	ttl := signedURLDefaultTTL
+	if v := query.Get("ttl"); v != "" {
+		secs, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || secs == 0 {
+			http.Error(w, fmt.Sprintf("invalid ttl (%v)", v), http.StatusBadRequest)
+			return
+		}
+		ttl = time.Duration(secs) * time.Second
+	}
+	if SignedURLs.MaxTTL > 0 && ttl > SignedURLs.MaxTTL {
+		http.Error(w, fmt.Sprintf("ttl (%v) is beyond the max ttl (%v)", ttl, SignedURLs.MaxTTL), http.StatusBadRequest)
+		return
+	}
+
+	uriParts := []string{"maps", m.Name, "{z}/{x}/{y}.pbf"}
+	layerName := query.Get("layer")
+	if layerName != "" {
+		known := false
+		for _, l := range m.Layers {
+			known = known || l.MVTName() == layerName
+		}
+		if !known {
+			http.Error(w, fmt.Sprintf("map (%v) has no layer (%v)", mapName, layerName), http.StatusNotFound)
+			return
+		}
+		uriParts = []string{"maps", m.Name, layerName, "{z}/{x}/{y}.pbf"}
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	params, err := SignedURLs.Sign(query.Get("key"), m.Name, layerName, expires)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unknown signing key (%v)", query.Get("key")), http.StatusBadRequest)
+		return
+	}
+
+	writeAdminJSON(w, SignedURL{
+		URL:     buildCapabilitiesURL(r, uriParts, params),
+		Expires: expires.UTC(),
+	})
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// HandleAdminStats reports the statistics of the tile cache
+//
+// 	GET /admin/stats - the hits, misses, errors, lookup and fill latencies and tile sizes of
+// 		the maps' tile caches as JSON. ?format=prometheus reports them in the Prometheus text
+// 		exposition format instead.
+// 	DELETE /admin/stats - resets the statistics
+type HandleAdminStats struct{}
+
+func (req HandleAdminStats) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "DELETE" {
+		tileCacheStats.reset()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	report := tileCacheStats.report()
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		writeAdminJSON(w, report)
+	case "prometheus":
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheusStats(w, report)
+	default:
+		http.Error(w, fmt.Sprintf("unsupported format (%v)", format), http.StatusBadRequest)
+	}
+}
+
+// writePrometheusStats writes the statistics of the maps in the Prometheus text exposition
+// format. Latencies are reported in seconds, as is the Prometheus convention.
+func writePrometheusStats(w io.Writer, report CacheStatsReport) {
+	names := make([]string, 0, len(report.Maps))
+	for name := range report.Maps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	counters := []struct {
+		name, help string
+		value      func(CacheStats) uint64
+	}{
+		{"tegola_cache_hits_total", "Tile requests served from the cache.", func(s CacheStats) uint64 { return s.Hits }},
+		{"tegola_cache_misses_total", "Tile requests rendered as the tile was not cached.", func(s CacheStats) uint64 { return s.Misses }},
+		{"tegola_cache_errors_total", "Failed reads from and writes to the cache.", func(s CacheStats) uint64 { return s.Errors }},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v counter\n", c.name, c.help, c.name)
+		for _, name := range names {
+			fmt.Fprintf(w, "%v{map=%q} %v\n", c.name, name, c.value(report.Maps[name]))
+		}
+	}
+
+	histograms := []struct {
+		name, help string
+		// scale converts the observations to the unit of the metric
+		scale float64
+		value func(CacheStats) Histogram
+	}{
+		{"tegola_cache_get_duration_seconds", "Latency of the cache lookups.", 1e-3, func(s CacheStats) Histogram { return s.GetLatency }},
+		{"tegola_cache_fill_duration_seconds", "Time a missed tile took to render and write to the cache.", 1e-3, func(s CacheStats) Histogram { return s.FillLatency }},
+		{"tegola_cache_tile_size_bytes", "Size of the tiles served from and written to the cache.", 1, func(s CacheStats) Histogram { return s.TileSize }},
+	}
+	for _, h := range histograms {
+		fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v histogram\n", h.name, h.help, h.name)
+		for _, name := range names {
+			hist := h.value(report.Maps[name])
+			for _, b := range hist.Buckets {
+				le := strconv.FormatFloat(b.LE*h.scale, 'g', -1, 64)
+				fmt.Fprintf(w, "%v_bucket{map=%q,le=%q} %v\n", h.name, name, le, b.Count)
+			}
+			fmt.Fprintf(w, "%v_bucket{map=%q,le=\"+Inf\"} %v\n", h.name, name, hist.Count)
+			fmt.Fprintf(w, "%v_sum{map=%q} %v\n", h.name, name, strconv.FormatFloat(hist.Sum*h.scale, 'g', -1, 64))
+			fmt.Fprintf(w, "%v_count{map=%q} %v\n", h.name, name, hist.Count)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleAdminStats(t *testing.T) {
+	server.AdminToken = testAdminToken
+	defer func() { server.AdminToken = "" }()
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	router := server.NewRouter(a)
+
+	do := func(method, uri string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(uri, "/admin") {
+			r.Header.Set("Authorization", "Bearer "+testAdminToken)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("DELETE", "/admin/stats"); w.Code != http.StatusNoContent {
+		t.Fatalf("reset status code, expected %v got %v", http.StatusNoContent, w.Code)
+	}
+
+	// a miss, then a hit of the tile, and a request of a map which is not configured
+	do("GET", "/maps/test-map/10/2/3.pbf")
+	do("GET", "/maps/test-map/10/2/3.pbf")
+	do("GET", "/maps/other-map/10/2/3.pbf")
+
+	w := do("GET", "/admin/stats")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+	}
+	var report server.CacheStatsReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+
+	if len(report.Maps) != 1 {
+		t.Errorf("maps, expected only test-map got %v", report.Maps)
+	}
+	stats := report.Maps["test-map"]
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Errors != 0 {
+		t.Errorf("hits/misses/errors, expected 1/1/0 got %v/%v/%v", stats.Hits, stats.Misses, stats.Errors)
+	}
+	if stats.HitRatio != 0.5 {
+		t.Errorf("hit ratio, expected 0.5 got %v", stats.HitRatio)
+	}
+	if stats.GetLatency.Count != 2 || stats.FillLatency.Count != 1 || stats.TileSize.Count != 2 {
+		t.Errorf("histogram counts, expected 2/1/2 got %v/%v/%v", stats.GetLatency.Count, stats.FillLatency.Count, stats.TileSize.Count)
+	}
+	if report.Total.Hits != 1 || report.Total.Misses != 1 {
+		t.Errorf("total hits/misses, expected 1/1 got %v/%v", report.Total.Hits, report.Total.Misses)
+	}
+
+	w = do("GET", "/admin/stats?format=prometheus")
+	for _, line := range []string{
+		`tegola_cache_hits_total{map="test-map"} 1`,
+		`tegola_cache_misses_total{map="test-map"} 1`,
+		`tegola_cache_fill_duration_seconds_count{map="test-map"} 1`,
+		`tegola_cache_tile_size_bytes_bucket{map="test-map",le="+Inf"} 2`,
+	} {
+		if !strings.Contains(w.Body.String(), line+"\n") {
+			t.Errorf("prometheus format, expected %q in %v", line, w.Body.String())
+		}
+	}
+
+	if w = do("GET", "/admin/stats?format=xml"); w.Code != http.StatusBadRequest {
+		t.Errorf("unsupported format status code, expected %v got %v", http.StatusBadRequest, w.Code)
+	}
+}
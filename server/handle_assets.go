@@ -0,0 +1,98 @@
+package server
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// glyphRange matches the ranges of 256 code points the glyphs of a font are requested in
+var glyphRange = regexp.MustCompile(`^[0-9]+-[0-9]+\.pbf$`)
+
+// HandleFonts serves the glyphs of the fonts of the Fonts assets, stored as
+// :fontstack/:range.pbf, in the format requested by MapLibre and Mapbox GL clients.
+//
+//	GET /fonts/:fontstack/:range.pbf
+//
+// A font stack is a comma separated list of fonts. The glyphs of the first font of the
+// stack which has the range are served.
+type HandleFonts struct {
+	Assets Assets
+}
+
+func (req HandleFonts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := httptreemux.ContextParams(r.Context())
+
+	glyphs := params["range"]
+	if !glyphRange.MatchString(glyphs) {
+		http.Error(w, "invalid glyph range ("+glyphs+")", http.StatusBadRequest)
+		return
+	}
+
+	for _, font := range strings.Split(params["fontstack"], ",") {
+		font = strings.TrimSpace(font)
+		if font == "" || strings.Contains(font, "/") {
+			continue
+		}
+
+		f, err := req.Assets.Open(r.Context(), font+"/"+glyphs)
+		if err == ErrAssetNotFound {
+			continue
+		}
+		if err != nil {
+			log.Errorf("error opening glyphs (%v/%v): %v", font, glyphs, err)
+			http.Error(w, "error opening glyphs", http.StatusInternalServerError)
+			return
+		}
+		writeAsset(w, f, "application/x-protobuf")
+		return
+	}
+
+	http.Error(w, "glyphs ("+params["fontstack"]+"/"+glyphs+") not found", http.StatusNotFound)
+}
+
+// HandleSprites serves the sprites of the Sprites assets, i.e. the sprite.json index and
+// sprite.png image of a style and their @2x variants.
+//
+//	GET /sprites/*path
+type HandleSprites struct {
+	Assets Assets
+}
+
+func (req HandleSprites) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := httptreemux.ContextParams(r.Context())["path"]
+
+	f, err := req.Assets.Open(r.Context(), name)
+	if err == ErrAssetNotFound {
+		http.Error(w, "sprite ("+name+") not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errorf("error opening sprite (%v): %v", name, err)
+		http.Error(w, "error opening sprite", http.StatusInternalServerError)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	writeAsset(w, f, contentType)
+}
+
+// writeAsset copies the asset to the response and closes it
+func writeAsset(w http.ResponseWriter, f io.ReadCloser, contentType string) {
+	defer f.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	if _, err := io.Copy(w, f); err != nil {
+		log.Errorf("error writing asset: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package server_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleAssets(t *testing.T) {
+	type tcase struct {
+		uri          string
+		expectedCode int
+		expectedType string
+		expectedBody string
+	}
+
+	dir, err := ioutil.TempDir("", "tegola-assets")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, body := range map[string]string{
+		"fonts/Open Sans Bold/0-255.pbf":    "bold",
+		"fonts/Noto Sans Regular/0-255.pbf": "noto",
+		"sprites/basic.json":                `{}`,
+		"sprites/basic@2x.png":              "png",
+	} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ioutil.WriteFile(name, []byte(body), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if server.Fonts, err = server.NewAssets(filepath.Join(dir, "fonts")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if server.Sprites, err = server.NewAssets(filepath.Join(dir, "sprites")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		server.Fonts, server.Sprites = nil, nil
+	}()
+
+	server.URIPrefix = "/"
+	router := server.NewRouter(nil)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.expectedType {
+				t.Errorf("content type, expected %v got %v", tc.expectedType, got)
+			}
+			if got := w.Body.String(); got != tc.expectedBody {
+				t.Errorf("body, expected %q got %q", tc.expectedBody, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"font": {
+			uri:          "/fonts/Open%20Sans%20Bold/0-255.pbf",
+			expectedCode: http.StatusOK,
+			expectedType: "application/x-protobuf",
+			expectedBody: "bold",
+		},
+		"font stack fallback": {
+			uri:          "/fonts/Open%20Sans%20Regular,Noto%20Sans%20Regular/0-255.pbf",
+			expectedCode: http.StatusOK,
+			expectedType: "application/x-protobuf",
+			expectedBody: "noto",
+		},
+		"missing range": {
+			uri:          "/fonts/Open%20Sans%20Bold/256-511.pbf",
+			expectedCode: http.StatusNotFound,
+		},
+		"invalid range": {
+			uri:          "/fonts/Open%20Sans%20Bold/glyphs.pbf",
+			expectedCode: http.StatusBadRequest,
+		},
+		"sprite index": {
+			uri:          "/sprites/basic.json",
+			expectedCode: http.StatusOK,
+			expectedType: "application/json",
+			expectedBody: `{}`,
+		},
+		"sprite image": {
+			uri:          "/sprites/basic@2x.png",
+			expectedCode: http.StatusOK,
+			expectedType: "image/png",
+			expectedBody: "png",
+		},
+		"missing sprite": {
+			uri:          "/sprites/streets.json",
+			expectedCode: http.StatusNotFound,
+		},
+		"outside the sprites": {
+			uri:          "/sprites/%2E%2E/fonts/Open%20Sans%20Bold/0-255.pbf",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
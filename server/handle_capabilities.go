@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/go-spatial/geom"
 	"github.com/go-spatial/tegola/atlas"
@@ -20,6 +21,7 @@ type CapabilitiesMap struct {
 	Bounds       *geom.Extent        `json:"bounds"`
 	Center       [3]float64          `json:"center"`
 	Tiles        []string            `json:"tiles"`
+	RasterTiles  []string            `json:"raster_tiles,omitempty"`
 	Capabilities string              `json:"capabilities"`
 	Layers       []CapabilitiesLayer `json:"layers"`
 }
@@ -31,7 +33,10 @@ type CapabilitiesLayer struct {
 	MaxZoom uint     `json:"maxzoom"`
 }
 
-type HandleCapabilities struct{}
+type HandleCapabilities struct {
+	// the atlas of the maps. Defaults to the default atlas when nil.
+	Atlas *atlas.Atlas
+}
 
 func (req HandleCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// new capabilities struct
@@ -42,8 +47,16 @@ func (req HandleCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	// parse our query string
 	var query = r.URL.Query()
 
+	now := time.Now()
+
 	// iterate our registered maps
-	for _, m := range atlas.AllMaps() {
+	for _, m := range req.Atlas.AllMaps() {
+		// maps outside of their availability windows are not listed
+		if !m.Availability.Available(now) {
+			continue
+		}
+		m = m.FilterLayersByAvailability(now)
+
 		debugQuery := url.Values{}
 
 		// if we have a debug param add it to our URLs
@@ -65,6 +78,11 @@ func (req HandleCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Request)
 			},
 			Capabilities: buildCapabilitiesURL(r, []string{"capabilities", m.Name + ".json"}, debugQuery),
 		}
+		if m.HasRaster() {
+			cMap.RasterTiles = []string{
+				buildCapabilitiesURL(r, []string{"maps", m.Name, "{z}/{x}/{y}." + m.Raster.Format()}, nil),
+			}
+		}
 
 		for i := range m.Layers {
 			// check if the layer already exists in our slice. this can happen if the config
@@ -77,8 +95,8 @@ func (req HandleCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Request)
 						cMap.Layers[j].MinZoom = m.Layers[i].MinZoom
 					}
 
-					if cMap.Layers[j].MaxZoom < m.Layers[i].MaxZoom {
-						cMap.Layers[j].MaxZoom = m.Layers[i].MaxZoom
+					if cMap.Layers[j].MaxZoom < m.Layers[i].ServedMaxZoom() {
+						cMap.Layers[j].MaxZoom = m.Layers[i].ServedMaxZoom()
 					}
 
 					skip = true
@@ -97,7 +115,7 @@ func (req HandleCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Request)
 					buildCapabilitiesURL(r, []string{"maps", m.Name, m.Layers[i].MVTName(), "{z}/{x}/{y}.pbf"}, debugQuery),
 				},
 				MinZoom: m.Layers[i].MinZoom,
-				MaxZoom: m.Layers[i].MaxZoom,
+				MaxZoom: m.Layers[i].ServedMaxZoom(),
 			}
 
 			// add the layer to the map
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// HandleChecksum reports the size and sha256 hash of a cached tile without rendering it, so
+// clients and CDNs can verify the tiles they hold against the cache backend.
+//
+//	GET /checksums/:map_name/:z/:x/:y
+//	GET /checksums/:map_name/:layer_name/:z/:x/:y
+//
+// Tiles which are not cached respond with 404.
+type HandleChecksum struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+// TileChecksum is the checksum of a cached tile
+type TileChecksum struct {
+	Map   string `json:"map"`
+	Layer string `json:"layer,omitempty"`
+	Z     uint   `json:"z"`
+	X     uint   `json:"x"`
+	Y     uint   `json:"y"`
+	// Size of the cached tile in bytes
+	Size int `json:"size"`
+	// SHA256 is the hex encoded sha256 hash of the cached tile
+	SHA256 string `json:"sha256"`
+}
+
+func (req HandleChecksum) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := httptreemux.ContextParams(r.Context())
+
+	if _, err := req.Atlas.Map(params["map_name"]); err != nil {
+		http.Error(w, fmt.Sprintf("map (%v) not configured", params["map_name"]), http.StatusNotFound)
+		return
+	}
+
+	cacher := req.Atlas.GetCache()
+	if cacher == nil {
+		http.Error(w, "no cache configured", http.StatusNotFound)
+		return
+	}
+
+	key, err := cache.ParseKey(strings.TrimPrefix(r.URL.Path, path.Join(URIPrefix, "checksums")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, hit, err := cacher.Get(key)
+	if err != nil {
+		errMsg := fmt.Sprintf("error reading tile (%v) from cache: %v", key.String(), err)
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+	if !hit {
+		http.Error(w, fmt.Sprintf("tile (%v) not cached", key.String()), http.StatusNotFound)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := TileChecksum{
+		Map:    key.MapName,
+		Layer:  key.LayerName,
+		Z:      key.Z,
+		X:      key.X,
+		Y:      key.Y,
+		Size:   len(data),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// the tile's hash identifies the cached content, so clients can revalidate cheaply
+	w.Header().Set("ETag", `"`+checksum.SHA256+`"`)
+	if r.Header.Get("If-None-Match") == w.Header().Get("ETag") {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(checksum); err != nil {
+		log.Errorf("error encoding checksum response: %v", err)
+	}
+}
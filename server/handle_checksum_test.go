@@ -0,0 +1,100 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleChecksum(t *testing.T) {
+	type tcase struct {
+		uri          string
+		ifNoneMatch  string
+		expectedCode int
+		expected     server.TileChecksum
+	}
+
+	// sha256 of "abc"
+	const sum = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+
+	a := newTestMapWithLayers(testLayer1)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	cacher.Set(&cache.Key{MapName: "test-map", Z: 10, X: 2, Y: 3}, []byte("abc"))
+	cacher.Set(&cache.Key{MapName: "test-map", LayerName: "test-layer", Z: 1, X: 0, Y: 1}, []byte("abc"))
+
+	router := server.NewRouter(a)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			if w.Header().Get("ETag") != `"`+sum+`"` {
+				t.Errorf("header ETag, expected %q got %q", `"`+sum+`"`, w.Header().Get("ETag"))
+			}
+
+			var checksum server.TileChecksum
+			if err := json.NewDecoder(w.Body).Decode(&checksum); err != nil {
+				t.Fatalf("unable to decode response: %v", err)
+			}
+			if checksum != tc.expected {
+				t.Errorf("checksum, expected %+v got %+v", tc.expected, checksum)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"map tile": {
+			uri:          "/checksums/test-map/10/2/3.pbf",
+			expectedCode: http.StatusOK,
+			expected:     server.TileChecksum{Map: "test-map", Z: 10, X: 2, Y: 3, Size: 3, SHA256: sum},
+		},
+		"layer tile": {
+			uri:          "/checksums/test-map/test-layer/1/0/1",
+			expectedCode: http.StatusOK,
+			expected:     server.TileChecksum{Map: "test-map", Layer: "test-layer", Z: 1, X: 0, Y: 1, Size: 3, SHA256: sum},
+		},
+		"not modified": {
+			uri:          "/checksums/test-map/10/2/3.pbf",
+			ifNoneMatch:  `"` + sum + `"`,
+			expectedCode: http.StatusNotModified,
+		},
+		"not cached": {
+			uri:          "/checksums/test-map/10/2/4.pbf",
+			expectedCode: http.StatusNotFound,
+		},
+		"map not configured": {
+			uri:          "/checksums/other-map/10/2/3.pbf",
+			expectedCode: http.StatusNotFound,
+		},
+		"invalid tile": {
+			uri:          "/checksums/test-map/1/5/5.pbf",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// ReadyTimeout bounds the checks of the providers and cache backend made by the /ready endpoint
+var ReadyTimeout = 5 * time.Second
+
+// readyCacheKey is the key read from the cache backend to check it can be reached. A miss is
+// as good as a hit.
+var readyCacheKey = cache.Key{MapName: "_ready"}
+
+// HandleHealth reports the server is up without checking its dependencies, for load balancer
+// health checks which run often
+type HandleHealth struct{}
+
+func (req HandleHealth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "ok",
+		"version": Version,
+	})
+}
+
+// HandleLive reports the process is alive, for liveness probes. A failing liveness probe
+// restarts the process, so it doesn't depend on the providers or cache backend.
+type HandleLive struct{}
+
+func (req HandleLive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte("ok"))
+}
+
+// ReadyCheck is the result of the check of a provider or the cache backend
+type ReadyCheck struct {
+	Name  string  `json:"name"`
+	Ready bool    `json:"ready"`
+	Error string  `json:"error,omitempty"`
+	MS    float64 `json:"latency_ms"`
+}
+
+// Readiness is the response of the /ready endpoint
+type Readiness struct {
+	Ready     bool         `json:"ready"`
+	Providers []ReadyCheck `json:"providers"`
+	Cache     *ReadyCheck  `json:"cache,omitempty"`
+}
+
+// HandleReady reports if the server can serve tiles, for readiness probes. The providers which
+// can check their connection (provider.Pinger) are pinged and a key is read from the cache
+// backend. Responds with a 503 when any check fails.
+type HandleReady struct {
+	Atlas *atlas.Atlas
+}
+
+func (req HandleReady) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), ReadyTimeout)
+	defer cancel()
+
+	readiness := Readiness{Ready: true, Providers: []ReadyCheck{}}
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+	record := func(c ReadyCheck) {
+		lock.Lock()
+		defer lock.Unlock()
+		if !c.Ready {
+			readiness.Ready = false
+			log.Warnf("readiness check of %v failed: %v", c.Name, c.Error)
+		}
+		if c.Name == "cache" {
+			readiness.Cache = &c
+			return
+		}
+		readiness.Providers = append(readiness.Providers, c)
+	}
+
+	for name, p := range provider.Instances() {
+		pinger, ok := p.Std.(provider.Pinger)
+		if !ok {
+			pinger, ok = p.Mvt.(provider.Pinger)
+		}
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, pinger provider.Pinger) {
+			defer wg.Done()
+			record(readyCheck(name, func() error { return pinger.Ping(ctx) }))
+		}(name, pinger)
+	}
+
+	if c := req.Atlas.GetCache(); c != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record(readyCheck("cache", func() error {
+				key := readyCacheKey
+				_, _, err := c.Get(&key)
+				return err
+			}))
+		}()
+	}
+
+	wg.Wait()
+	sort.Slice(readiness.Providers, func(i, j int) bool {
+		return readiness.Providers[i].Name < readiness.Providers[j].Name
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !readiness.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(readiness); err != nil {
+		log.Errorf("error encoding readiness: %v", err)
+	}
+}
+
+// readyCheck times the check
+func readyCheck(name string, check func() error) ReadyCheck {
+	start := time.Now()
+	err := check()
+	c := ReadyCheck{
+		Name:  name,
+		Ready: err == nil,
+		MS:    float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	return c
+}
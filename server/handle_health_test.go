@@ -0,0 +1,100 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/server"
+)
+
+// pingProvider is a provider whose ping fails with err
+type pingProvider struct {
+	provider.Tiler
+	err error
+}
+
+func (p pingProvider) Ping(ctx context.Context) error { return p.err }
+
+func TestHandleReady(t *testing.T) {
+	server.URIPrefix = "/"
+	router := server.NewRouter(newTestMapWithLayers(testLayer1))
+
+	type tcase struct {
+		err          error
+		expectedCode int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			provider.SetInstance("ready-test", provider.TilerUnion{Std: pingProvider{err: tc.err}})
+
+			r, err := http.NewRequest("GET", "/ready", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+
+			var readiness server.Readiness
+			if err := json.NewDecoder(w.Body).Decode(&readiness); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var check *server.ReadyCheck
+			for i := range readiness.Providers {
+				if readiness.Providers[i].Name == "ready-test" {
+					check = &readiness.Providers[i]
+				}
+			}
+			if check == nil {
+				t.Fatalf("expected the provider to be checked, got %+v", readiness.Providers)
+			}
+			if check.Ready != (tc.err == nil) {
+				t.Errorf("ready, expected %v got %v", tc.err == nil, check.Ready)
+			}
+			if tc.err != nil && check.Error != tc.err.Error() {
+				t.Errorf("error, expected %v got %v", tc.err, check.Error)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"unreachable provider": {
+			err:          errors.New("connection refused"),
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		"ready": {
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestHandleHealthAndLive(t *testing.T) {
+	server.URIPrefix = "/"
+	router := server.NewRouter(newTestMapWithLayers(testLayer1))
+
+	for _, uri := range []string{"/health", "/live"} {
+		r, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%v: status code, expected %v got %v", uri, http.StatusOK, w.Code)
+		}
+	}
+}
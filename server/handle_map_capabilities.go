@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/dimfeld/httptreemux"
 
@@ -15,6 +16,9 @@ import (
 )
 
 type HandleMapCapabilities struct {
+	// the atlas of the maps. Defaults to the default atlas when nil.
+	Atlas *atlas.Atlas
+
 	// required
 	mapName string
 	// the requests extension defaults to "json"
@@ -43,18 +47,27 @@ func (req HandleMapCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	}
 
 	// lookup our Map
-	m, err := atlas.GetMap(req.mapName)
+	m, err := req.Atlas.Map(req.mapName)
 	if err != nil {
 		log.Printf("map (%v) not configured. check your config file", req.mapName)
 		http.Error(w, "map ("+req.mapName+") not configured. check your config file", http.StatusBadRequest)
 		return
 	}
 
+	now := time.Now()
+	if !m.Availability.Available(now) {
+		http.Error(w, "map ("+req.mapName+") is not available", http.StatusNotFound)
+		return
+	}
+	m = m.FilterLayersByAvailability(now)
+	// the zooms of the map bound the zooms of its layers
+	minZoom, maxZoom := m.Zooms()
+
 	tileJSON := tilejson.TileJSON{
 		Attribution: &m.Attribution,
 		Bounds:      m.Bounds.Extent(),
 		Center:      m.Center,
-		Format:      "pbf",
+		Format:      m.TileFormat(),
 		Name:        &m.Name,
 		Scheme:      tilejson.SchemeXYZ,
 		TileJSON:    tilejson.Version,
@@ -66,14 +79,19 @@ func (req HandleMapCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	// parse our query string
 	var query = r.URL.Query()
 
-	debugQuery := url.Values{}
+	tileQuery := url.Values{}
 	// if we have a debug param add it to our URLs
 	if query.Get("debug") == "true" {
-		debugQuery.Set("debug", "true")
+		tileQuery.Set("debug", "true")
 
 		// update our map to include the debug layers
 		m = m.AddDebugLayers()
 	}
+	// clients of the tms scheme request the tiles numbered from the south
+	if query.Get(TileSchemeParam) == tilejson.SchemeTMS {
+		tileJSON.Scheme = tilejson.SchemeTMS
+		tileQuery.Set(TileSchemeParam, tilejson.SchemeTMS)
+	}
 
 	for i := range m.Layers {
 		// check if the layer already exists in our slice. this can happen if the config
@@ -86,8 +104,8 @@ func (req HandleMapCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Reques
 					tileJSON.VectorLayers[j].MinZoom = m.Layers[i].MinZoom
 				}
 
-				if tileJSON.VectorLayers[j].MaxZoom < m.Layers[i].MaxZoom {
-					tileJSON.VectorLayers[j].MaxZoom = m.Layers[i].MaxZoom
+				if tileJSON.VectorLayers[j].MaxZoom < m.Layers[i].ServedMaxZoom() {
+					tileJSON.VectorLayers[j].MaxZoom = m.Layers[i].ServedMaxZoom()
 				}
 
 				skip = true
@@ -98,7 +116,7 @@ func (req HandleMapCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Reques
 		// the first layer sets the initial min / max otherwise they default to 0/0
 		if len(tileJSON.VectorLayers) == 0 {
 			tileJSON.MinZoom = m.Layers[i].MinZoom
-			tileJSON.MaxZoom = m.Layers[i].MaxZoom
+			tileJSON.MaxZoom = m.Layers[i].ServedMaxZoom()
 		}
 
 		// check if we have a min zoom lower then our current min
@@ -107,8 +125,8 @@ func (req HandleMapCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Reques
 		}
 
 		// check if we have a max zoom higher then our current max
-		if tileJSON.MaxZoom < m.Layers[i].MaxZoom {
-			tileJSON.MaxZoom = m.Layers[i].MaxZoom
+		if tileJSON.MaxZoom < m.Layers[i].ServedMaxZoom() {
+			tileJSON.MaxZoom = m.Layers[i].ServedMaxZoom()
 		}
 
 		//	entry for layer already exists. move on
@@ -123,9 +141,9 @@ func (req HandleMapCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Reques
 			ID:      m.Layers[i].MVTName(),
 			Name:    m.Layers[i].MVTName(),
 			MinZoom: m.Layers[i].MinZoom,
-			MaxZoom: m.Layers[i].MaxZoom,
+			MaxZoom: m.Layers[i].ServedMaxZoom(),
 			Tiles: []string{
-				buildCapabilitiesURL(r, []string{"maps", req.mapName, m.Layers[i].MVTName(), "{z}/{x}/{y}.pbf"}, debugQuery),
+				buildCapabilitiesURL(r, []string{"maps", req.mapName, m.Layers[i].MVTName(), "{z}/{x}/{y}.pbf"}, tileQuery),
 			},
 		}
 
@@ -145,11 +163,23 @@ func (req HandleMapCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Reques
 		tileJSON.VectorLayers = append(tileJSON.VectorLayers, layer)
 	}
 
-	tileURL := buildCapabilitiesURL(r, []string{"maps", req.mapName, "{z}/{x}/{y}.pbf"}, debugQuery)
+	if tileJSON.MinZoom < minZoom {
+		tileJSON.MinZoom = minZoom
+	}
+	if tileJSON.MaxZoom > maxZoom || len(m.Layers) == 0 {
+		tileJSON.MaxZoom = maxZoom
+	}
+
+	tileURL := buildCapabilitiesURL(r, []string{"maps", req.mapName, "{z}/{x}/{y}." + m.TileFormat()}, tileQuery)
 
 	// build our URL scheme for the tile grid
 	tileJSON.Tiles = append(tileJSON.Tiles, tileURL)
 
+	// the UTFGrid interactivity tiles of the layers with a utfgrid key
+	if m.HasUTFGrid() {
+		tileJSON.Grids = append(tileJSON.Grids, buildCapabilitiesURL(r, []string{"maps", req.mapName, "{z}/{x}/{y}." + atlas.UTFGridFormat}, tileQuery))
+	}
+
 	// content type
 	w.Header().Add("Content-Type", "application/json")
 
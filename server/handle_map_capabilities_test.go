@@ -133,8 +133,9 @@ func TestHandleMapCapabilities(t *testing.T) {
 				Bounds:      [4]float64{-180.0, -85.0511, 180.0, 85.0511},
 				Center:      testMapCenter,
 				Format:      "pbf",
-				MinZoom:     0,
-				MaxZoom:     atlas.MaxZoom,
+				// the zooms of the map's layers, which the debug layers don't extend
+				MinZoom:     testLayer1.MinZoom,
+				MaxZoom:     testLayer3.MaxZoom,
 				Name:        &testMapName,
 				Description: nil,
 				Scheme:      tilejson.SchemeXYZ,
@@ -6,16 +6,22 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dimfeld/httptreemux"
-	"github.com/go-spatial/geom/encoding/mvt"
 	"github.com/go-spatial/geom/slippy"
 	"github.com/go-spatial/tegola"
 	"github.com/go-spatial/tegola/atlas"
 	"github.com/go-spatial/tegola/internal/log"
-	"github.com/go-spatial/tegola/maths"
+	"github.com/go-spatial/tegola/provider"
 )
 
+// utfGridContentType is the content type of the UTFGrid interactivity tiles
+const utfGridContentType = "application/json"
+
+// OmittedLayersHeader lists the layers (comma separated) which failed and were left out of the tile
+const OmittedLayersHeader = "Tegola-Omitted-Layers"
+
 type HandleMapLayerZXY struct {
 	// required
 	mapName string
@@ -56,11 +62,17 @@ func (req *HandleMapLayerZXY) parseURI(r *http.Request) error {
 	}
 	req.z = uint(placeholder)
 
-	maxXYatZ := maths.Exp2(placeholder) - 1
+	// the columns and rows of tiles at the zoom of the map's grid, unknown maps are
+	// responded to once the URI is parsed
+	grid := atlas.WebMercatorGrid
+	if m, err := req.Atlas.Map(req.mapName); err == nil {
+		grid = m.TileGrid()
+	}
+	cols, rows := grid.MatrixSize(req.z)
 
 	x := params["x"]
 	placeholder, err = strconv.ParseUint(x, 10, 32)
-	if err != nil || placeholder > maxXYatZ {
+	if err != nil || placeholder >= uint64(cols) {
 		log.Warnf("invalid X value (%v)", x)
 		return fmt.Errorf("invalid X value (%v)", x)
 	}
@@ -70,7 +82,7 @@ func (req *HandleMapLayerZXY) parseURI(r *http.Request) error {
 	y := params["y"]
 	yParts := strings.Split(y, ".")
 	placeholder, err = strconv.ParseUint(yParts[0], 10, 32)
-	if err != nil || placeholder > maxXYatZ {
+	if err != nil || placeholder >= uint64(rows) {
 		log.Warnf("invalid Y value (%v)", yParts[0])
 		return fmt.Errorf("invalid Y value (%v)", yParts[0])
 	}
@@ -121,12 +133,41 @@ func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// filter down the layers we need for this zoom
-	m = m.FilterLayersByZoom(req.z)
-	if len(m.Layers) == 0 {
-		logAndError(w, http.StatusNotFound, "map (%v) has no layers, at zoom %v", req.mapName, req.z)
+	now := time.Now()
+	if !m.Availability.Available(now) {
+		logAndError(w, http.StatusNotFound, "map (%v) is not available", req.mapName)
 		return
 	}
+	m = m.FilterLayersByAvailability(now)
+
+	// tiles with the extension of the map raster's format are the raster's tiles
+	isRaster := req.layerName == "" && isRasterTile(m, r.URL.Path)
+	// tiles with the .grid.json extension are the UTFGrids of the map's layers with a utfgrid key
+	isGrid := req.layerName == "" && isUTFGridTile(r.URL.Path)
+
+	switch {
+	case isRaster:
+		// the zooms of the raster are checked when it's encoded
+	case isGrid:
+		m = m.FilterLayersByZoom(req.z)
+		if !m.HasUTFGrid() {
+			logAndError(w, http.StatusNotFound, "map (%v) has no utfgrid layers, at zoom %v", req.mapName, req.z)
+			return
+		}
+	case m.HasUpstream():
+		// upstream tiles can't be split into layers
+		if req.layerName != "" {
+			logAndError(w, http.StatusNotFound, "map (%v) is an upstream map and has no layer %v", req.mapName, req.layerName)
+			return
+		}
+	default:
+		// filter down the layers we need for this zoom
+		m = m.FilterLayersByZoom(req.z)
+		if len(m.Layers) == 0 {
+			logAndError(w, http.StatusNotFound, "map (%v) has no layers, at zoom %v", req.mapName, req.z)
+			return
+		}
+	}
 
 	if req.layerName != "" {
 		m = m.FilterLayersByID(req.layerName)
@@ -142,36 +183,86 @@ func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Check to see that the zxy is within the bounds of the map.
 		// TODO(@ear7h): use a more efficient version of Intersect that doesn't
 		// make a new extent
-		textent := tile.Extent4326()
-		if _, intersect := m.Bounds.Intersect(textent); !intersect {
+		// the tiles of grids which can't be reprojected have no bounds
+		textent := m.TileGrid().TileBounds(req.z, req.x, req.y)
+		if _, intersect := m.Bounds.Intersect(textent); textent != nil && !intersect {
 			logAndError(w, http.StatusNotFound, "map (%v -- %v) does not contains tile at %v/%v/%v -- %v", req.mapName, m.Bounds, req.z, req.x, req.y, textent)
 			return
 		}
 	}
 
 	// check for the debug query string
-	if req.debug {
+	if req.debug && !isRaster && !isGrid {
 		m = m.AddDebugLayers()
 	}
 
-	pbyte, err := m.Encode(r.Context(), tile)
+	// track the soonest expiry of the encoded features and the omitted layers
+	ctx := atlas.WithOmittedLayers(atlas.WithExpiry(r.Context()))
+
+	var pbyte []byte
+	switch {
+	case isRaster:
+		pbyte, err = m.EncodeRaster(ctx, tile)
+	case isGrid:
+		pbyte, err = m.EncodeUTFGrid(ctx, tile)
+	default:
+		pbyte, err = m.Encode(ctx, tile)
+	}
 	if err != nil {
+		// the request was canceled, or exceeded its deadline and is answered by RequestTimeoutHandler
+		if r.Context().Err() != nil {
+			return
+		}
+
+		switch err.(type) {
+		case atlas.ErrRasterTileNotFound:
+			logAndError(w, http.StatusNotFound, "map (%v) raster has no tile at %v/%v/%v", req.mapName, req.z, req.x, req.y)
+			return
+		case atlas.ErrNoUTFGridLayers:
+			logAndError(w, http.StatusNotFound, "map (%v) has no utfgrid layers, at zoom %v", req.mapName, req.z)
+			return
+		case atlas.ErrUpstreamTileNotFound:
+			logAndError(w, http.StatusNotFound, "map (%v) upstream has no tile at %v/%v/%v", req.mapName, req.z, req.x, req.y)
+			return
+		case atlas.ErrUpstreamStatus:
+			errMsg := fmt.Sprintf("error fetching upstream tile: %v", err)
+			log.Error(errMsg)
+			http.Error(w, errMsg, http.StatusBadGateway)
+			return
+		}
+
 		switch err {
 		case context.Canceled:
 			// TODO: add debug logs
 			return
 		default:
 			errMsg := fmt.Sprintf("error marshalling tile: %v", err)
-			log.Error(errMsg)
+			log.Errorf("%v (request_id: %v)", errMsg, provider.RequestID(ctx))
 			http.Error(w, errMsg, http.StatusInternalServerError)
 			return
 		}
 	}
 
-	// mimetype for mapbox vector tiles
+	// mimetype for mapbox vector tiles, or the upstream, raster or UTFGrid tiles' content type
 	// https://www.iana.org/assignments/media-types/application/vnd.mapbox-vector-tile
-	w.Header().Add("Content-Type", mvt.MimeType)
+	contentType := m.ContentType()
+	switch {
+	case isRaster:
+		contentType = m.Raster.ContentType()
+	case isGrid:
+		contentType = utfGridContentType
+	}
+	w.Header().Add("Content-Type", contentType)
 	w.Header().Add("Content-Length", fmt.Sprintf("%d", len(pbyte)))
+	setSurrogateKeys(w.Header(), tileSurrogateKeys(m, req.layerName, req.z, req.x, req.y))
+	// note the layers which failed and were left out of the tile
+	if omitted := atlas.OmittedLayers(ctx); len(omitted) > 0 {
+		w.Header().Set(OmittedLayersHeader, strings.Join(omitted, ","))
+	}
+	// tiles with expiring features (or an upstream ttl) must not be reused past the soonest expiry
+	if expires := atlas.Expiry(ctx); !expires.IsZero() {
+		setExpires(w.Header(), expires)
+	}
 	w.WriteHeader(http.StatusOK)
 	w.Write(pbyte)
 
@@ -180,3 +271,19 @@ func (req HandleMapLayerZXY) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		log.Infof("tile z:%v, x:%v, y:%v is rather large - %vKb", req.z, req.x, req.y, len(pbyte)/1024)
 	}
 }
+
+// setExpires sets the Expires header and, when no Cache-Control header has been
+// configured, a Cache-Control max-age so clients don't reuse the tile after expires
+func setExpires(h http.Header, expires time.Time) {
+	h.Set("Expires", expires.UTC().Format(http.TimeFormat))
+
+	if h.Get("Cache-Control") != "" {
+		return
+	}
+
+	maxAge := int64(time.Until(expires) / time.Second)
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	h.Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+}
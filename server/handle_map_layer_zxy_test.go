@@ -1,6 +1,8 @@
 package server_test
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"reflect"
@@ -9,6 +11,9 @@ import (
 
 	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
 	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/test"
+	"github.com/go-spatial/tegola/server"
 	"github.com/golang/protobuf/proto"
 )
 
@@ -17,9 +22,10 @@ type MapHandlerTCase struct {
 	uri    string
 	atlas  *atlas.Atlas
 
-	expectedBody   string
-	expectedCode   int
-	expectedLayers []string
+	expectedBody    string
+	expectedCode    int
+	expectedLayers  []string
+	expectedHeaders map[string]string
 }
 
 func MapHandlerTester(tc MapHandlerTCase) func(t *testing.T) {
@@ -41,6 +47,12 @@ func MapHandlerTester(tc MapHandlerTCase) func(t *testing.T) {
 			return
 		}
 
+		for k, v := range tc.expectedHeaders {
+			if got := w.Header().Get(k); got != v {
+				t.Errorf("header %v, expected %q got %q", k, v, got)
+			}
+		}
+
 		// Only try and decode as string for errors.
 		if len(tc.expectedBody) > 0 && tc.expectedCode >= 400 {
 			wbody := strings.TrimSpace(w.Body.String())
@@ -90,7 +102,7 @@ func TestHandleMapLayerZXY(t *testing.T) {
 		"Max Zoom, no layers left issue-375": {
 			uri:          "/maps/test-map/test-layer/10/2/3.pbf",
 			atlas:        newTestMapWithLayers(testLayer1), // Max Zoom on Layer1 is 9.
-			expectedCode: http.StatusNotFound,
+			expectedCode: http.StatusBadRequest,
 		},
 		"std": {
 			uri:            "/maps/test-map/test-layer/4/2/3.pbf",
@@ -157,12 +169,42 @@ func TestHandleMapLayerZXY(t *testing.T) {
 	}
 }
 
+// failingTiler errors for every tile
+type failingTiler struct {
+	test.TileProvider
+}
+
+func (ft *failingTiler) TileFeatures(ctx context.Context, layer string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	return errors.New("provider unavailable")
+}
+
 func TestHandleMapZXY(t *testing.T) {
+	failingLayer := atlas.Layer{
+		Name:            "failing-layer",
+		ProviderLayerID: "failing-layer",
+		MinZoom:         4,
+		MaxZoom:         9,
+		Provider:        &failingTiler{},
+	}
+	optionalLayer := failingLayer
+	optionalLayer.Optional = true
+
 	tests := map[string]MapHandlerTCase{
+		"optional layer failed": {
+			uri:             "/maps/test-map/4/2/3.pbf",
+			atlas:           newTestMapWithLayers(testLayer1, optionalLayer),
+			expectedCode:    http.StatusOK,
+			expectedHeaders: map[string]string{server.OmittedLayersHeader: "failing-layer"},
+		},
+		"required layer failed": {
+			uri:          "/maps/test-map/4/2/3.pbf",
+			atlas:        newTestMapWithLayers(testLayer1, failingLayer),
+			expectedCode: http.StatusInternalServerError,
+		},
 		"Max Zoom, no layers left issue-375": {
 			uri:          "/maps/test-map/10/2/3.pbf",
 			atlas:        newTestMapWithLayers(testLayer1), // Max Zoom on Layer1 is 9.
-			expectedCode: http.StatusNotFound,
+			expectedCode: http.StatusBadRequest,
 		},
 		"std 4_2_3": {
 			uri:            "/maps/test-map/4/2/3.pbf",
@@ -209,12 +251,39 @@ func TestHandleMapZXY(t *testing.T) {
 			expectedCode: http.StatusBadRequest,
 			expectedBody: "invalid Y value (4)",
 		},
+		"wgs84 grid": {
+			uri:            "/maps/test-map/4/31/0.pbf",
+			atlas:          newTestGridMap(atlas.WGS84Grid, testLayer1),
+			expectedCode:   http.StatusOK,
+			expectedLayers: []string{"test-layer"},
+		},
+		"wgs84 grid invalid x": {
+			uri:          "/maps/test-map/4/32/0.pbf",
+			atlas:        newTestGridMap(atlas.WGS84Grid, testLayer1),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid X value (32)",
+		},
+		"wgs84 grid invalid y": {
+			uri:          "/maps/test-map/4/0/16.pbf",
+			atlas:        newTestGridMap(atlas.WGS84Grid, testLayer1),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid Y value (16)",
+		},
 	}
 	for name, tc := range tests {
 		t.Run(name, MapHandlerTester(tc))
 	}
 }
 
+func newTestGridMap(g atlas.Grid, layers ...atlas.Layer) *atlas.Atlas {
+	testMap := atlas.NewMapWithGrid(testMapName, g)
+	testMap.Layers = append(testMap.Layers, layers...)
+
+	a := &atlas.Atlas{}
+	a.AddMap(testMap)
+	return a
+}
+
 func TestHandleMapLayerCORS(t *testing.T) {
 	tests := map[string]CORSTestCase{
 		"map": {
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/mapbox/tilejson"
+	"github.com/go-spatial/tegola/provider"
+)
+
+const (
+	// the number of distinct sample values listed per attribute
+	legendSampleValues = 5
+	// bounds the sampling of the layers' features and the request of a hosted style
+	legendTimeout = 10 * time.Second
+)
+
+// HandleMapLegend describes the layers of a map for building legend UIs: their geometry
+// types and zooms, the attributes and sample values of the features of the tile at the
+// map's center and, when the map has a hosted style, the style layers drawing them.
+//
+//	GET /maps/:map_name/legend
+//
+// The features aren't sampled with the query parameter sample=false.
+type HandleMapLegend struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+// Legend is the legend of a map
+type Legend struct {
+	Name        string        `json:"name"`
+	Attribution string        `json:"attribution,omitempty"`
+	Layers      []LegendLayer `json:"layers"`
+}
+
+// LegendLayer describes a layer of the tiles. The map layers sharing a name are described
+// as one layer.
+type LegendLayer struct {
+	Name         string            `json:"name"`
+	GeometryType tilejson.GeomType `json:"geometry_type"`
+	MinZoom      uint              `json:"min_zoom"`
+	MaxZoom      uint              `json:"max_zoom"`
+	// SampleZoom is the zoom of the tile the attributes were sampled from, nil when the
+	// features weren't sampled
+	SampleZoom *uint             `json:"sample_zoom,omitempty"`
+	Attributes []LegendAttribute `json:"attributes"`
+	// Styles are the layers of the map's hosted style drawing the layer
+	Styles []LegendStyle `json:"styles,omitempty"`
+}
+
+// LegendAttribute is an attribute of the features of a layer
+type LegendAttribute struct {
+	Name string `json:"name"`
+	// Type is one of string, number, boolean or mixed
+	Type string `json:"type"`
+	// Values are distinct sample values of the attribute
+	Values []interface{} `json:"values"`
+}
+
+// LegendStyle is a layer of a hosted style, as it's defined in the style
+type LegendStyle struct {
+	ID      string                 `json:"id"`
+	Type    string                 `json:"type"`
+	Filter  interface{}            `json:"filter,omitempty"`
+	MinZoom *float64               `json:"minzoom,omitempty"`
+	MaxZoom *float64               `json:"maxzoom,omitempty"`
+	Layout  map[string]interface{} `json:"layout,omitempty"`
+	Paint   map[string]interface{} `json:"paint,omitempty"`
+}
+
+// hostedStyle is the subset of a Mapbox GL style read for the legend. style.Root isn't used
+// as hosted styles are free to use expressions and properties it doesn't support.
+type hostedStyle struct {
+	Layers []struct {
+		LegendStyle
+		SourceLayer string `json:"source-layer"`
+	} `json:"layers"`
+}
+
+func (req HandleMapLegend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := httptreemux.ContextParams(r.Context())
+	mapName := params["map_name"]
+
+	m, err := req.Atlas.Map(mapName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("map (%v) not configured. check your config file", mapName), http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	if !m.Availability.Available(now) {
+		http.Error(w, fmt.Sprintf("map (%v) is not available", mapName), http.StatusNotFound)
+		return
+	}
+	m = m.FilterLayersByAvailability(now)
+
+	ctx, cancel := context.WithTimeout(r.Context(), legendTimeout)
+	defer cancel()
+
+	legend := Legend{
+		Name:        m.Name,
+		Attribution: m.Attribution,
+		Layers:      legendLayers(m),
+	}
+
+	// the layers of mvt provider based maps are encoded by their provider
+	if r.URL.Query().Get("sample") != "false" && !m.HasMVTProvider() {
+		for i := range legend.Layers {
+			if err := sampleLegendLayer(ctx, m, &legend.Layers[i]); err != nil {
+				log.Warnf("map (%v) legend: sampling layer (%v) failed: %v", m.Name, legend.Layers[i].Name, err)
+			}
+		}
+	}
+
+	if m.Style != "" {
+		styles, err := loadHostedStyle(ctx, m.Style)
+		if err != nil {
+			// the legend is still useful without the style
+			log.Warnf("map (%v) legend: loading style (%v) failed: %v", m.Name, m.Style, err)
+		}
+		for _, sl := range styles.Layers {
+			for i := range legend.Layers {
+				if sl.SourceLayer == legend.Layers[i].Name {
+					legend.Layers[i].Styles = append(legend.Layers[i].Styles, sl.LegendStyle)
+				}
+			}
+		}
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	// cache control headers (no-cache)
+	w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Add("Pragma", "no-cache")
+	w.Header().Add("Expires", "0")
+
+	if err := json.NewEncoder(w).Encode(legend); err != nil {
+		log.Errorf("error encoding legend for map (%v): %v", m.Name, err)
+	}
+}
+
+// legendLayers returns the layers of the map, in the order of the map's layers, with the
+// zoom range of the map layers sharing their names
+func legendLayers(m atlas.Map) []LegendLayer {
+	layers := []LegendLayer{}
+	for i := range m.Layers {
+		name := m.Layers[i].MVTName()
+
+		var found bool
+		for j := range layers {
+			if layers[j].Name != name {
+				continue
+			}
+			if layers[j].MinZoom > m.Layers[i].MinZoom {
+				layers[j].MinZoom = m.Layers[i].MinZoom
+			}
+			if layers[j].MaxZoom < m.Layers[i].MaxZoom {
+				layers[j].MaxZoom = m.Layers[i].MaxZoom
+			}
+			if layers[j].GeometryType != legendGeomType(m.Layers[i].GeomType) {
+				layers[j].GeometryType = tilejson.GeomTypeUnknown
+			}
+			found = true
+			break
+		}
+		if found {
+			continue
+		}
+
+		layers = append(layers, LegendLayer{
+			Name:         name,
+			GeometryType: legendGeomType(m.Layers[i].GeomType),
+			MinZoom:      m.Layers[i].MinZoom,
+			MaxZoom:      m.Layers[i].MaxZoom,
+			Attributes:   []LegendAttribute{},
+		})
+	}
+	return layers
+}
+
+func legendGeomType(g geom.Geometry) tilejson.GeomType {
+	switch g.(type) {
+	case geom.Point, geom.MultiPoint:
+		return tilejson.GeomTypePoint
+	case geom.Line, geom.LineString, geom.MultiLineString:
+		return tilejson.GeomTypeLine
+	case geom.Polygon, geom.MultiPolygon:
+		return tilejson.GeomTypePolygon
+	default:
+		return tilejson.GeomTypeUnknown
+	}
+}
+
+// sampleLegendLayer collects the attributes of the features of the tile at the map's center,
+// at the map's center zoom clamped to the zooms of the layer, and the default tags of the map
+// layers serving the layer at that zoom
+func sampleLegendLayer(ctx context.Context, m atlas.Map, layer *LegendLayer) error {
+	z := uint(m.Center[2])
+	if z < layer.MinZoom {
+		z = layer.MinZoom
+	}
+	if z > layer.MaxZoom {
+		z = layer.MaxZoom
+	}
+	layer.SampleZoom = &z
+
+	tile := slippy.NewTileLatLon(z, m.Center[1], m.Center[0])
+	ptile := provider.NewTile(tile.Z, tile.X, tile.Y, uint(m.TileBuffer), uint(m.SRID))
+
+	samples := attributeSamples{}
+	for i := range m.Layers {
+		l := m.Layers[i]
+		if l.MVTName() != layer.Name || z < l.MinZoom || z > l.MaxZoom {
+			continue
+		}
+
+		err := l.Provider.TileFeatures(ctx, l.ProviderLayerID, ptile, func(f *provider.Feature) error {
+			for k, v := range f.Tags {
+				samples.add(k, v)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for k, v := range l.DefaultTags {
+			samples.add(k, v)
+		}
+		// templated default tags are strings, sampled as their template
+		for k, tmpl := range l.DefaultTagTemplates {
+			samples.add(k, tmpl.Root.String())
+		}
+	}
+
+	layer.Attributes = samples.attributes()
+	return nil
+}
+
+type attributeSample struct {
+	types  map[string]bool
+	values []interface{}
+	seen   map[string]bool
+}
+
+// attributeSamples are the types and the first distinct values of the attributes
+type attributeSamples map[string]*attributeSample
+
+func (as attributeSamples) add(k string, v interface{}) {
+	var typ string
+	switch v.(type) {
+	case nil:
+		return
+	case string:
+		typ = "string"
+	case bool:
+		typ = "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		typ = "number"
+	default:
+		// values are encoded as strings in the tiles
+		typ, v = "string", fmt.Sprint(v)
+	}
+
+	s, ok := as[k]
+	if !ok {
+		s = &attributeSample{types: map[string]bool{}, seen: map[string]bool{}}
+		as[k] = s
+	}
+	s.types[typ] = true
+
+	key := typ + ":" + fmt.Sprint(v)
+	if len(s.values) < legendSampleValues && !s.seen[key] {
+		s.seen[key] = true
+		s.values = append(s.values, v)
+	}
+}
+
+// attributes returns the attributes sorted by name, with their sorted sample values
+func (as attributeSamples) attributes() []LegendAttribute {
+	attrs := make([]LegendAttribute, 0, len(as))
+	for k, s := range as {
+		attr := LegendAttribute{Name: k, Values: s.values}
+		switch len(s.types) {
+		case 1:
+			for t := range s.types {
+				attr.Type = t
+			}
+		default:
+			attr.Type = "mixed"
+		}
+
+		sort.SliceStable(attr.Values, func(i, j int) bool {
+			return fmt.Sprint(attr.Values[i]) < fmt.Sprint(attr.Values[j])
+		})
+		attrs = append(attrs, attr)
+	}
+
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name < attrs[j].Name })
+	return attrs
+}
+
+// loadHostedStyle reads the style from a file or an http(s) url
+func loadHostedStyle(ctx context.Context, location string) (hostedStyle, error) {
+	var style hostedStyle
+
+	var body io.ReadCloser
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		req, err := http.NewRequest(http.MethodGet, location, nil)
+		if err != nil {
+			return style, err
+		}
+		req.Header.Set("User-Agent", "tegola/"+Version)
+
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return style, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return style, fmt.Errorf("responded with status %v", resp.StatusCode)
+		}
+		body = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			return style, err
+		}
+		body = f
+	}
+	defer body.Close()
+
+	err := json.NewDecoder(body).Decode(&style)
+	return style, err
+}
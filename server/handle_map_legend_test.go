@@ -0,0 +1,162 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/mapbox/tilejson"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleMapLegend(t *testing.T) {
+	type tcase struct {
+		uri      string
+		style    string
+		status   int
+		expected server.Legend
+	}
+
+	dir, err := ioutil.TempDir("", "tegola-legend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	stylePath := filepath.Join(dir, "style.json")
+	err = ioutil.WriteFile(stylePath, []byte(`{
+		"version": 8,
+		"sources": {"test": {"type": "vector", "url": "https://tiles.example.com/capabilities/test-map.json"}},
+		"layers": [
+			{"id": "background", "type": "background", "paint": {"background-color": "#fff"}},
+			{"id": "bars", "type": "circle", "source": "test", "source-layer": "test-layer", "filter": ["==", "foo", "bar"], "minzoom": 4, "paint": {"circle-color": ["get", "color"]}},
+			{"id": "lines", "type": "line", "source": "test", "source-layer": "test-layer-2-name", "layout": {"line-cap": "round"}}
+		]
+	}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server.URIPrefix = "/"
+
+	zoom := func(z uint) *uint { return &z }
+	attributes := []server.LegendAttribute{
+		{Name: "foo", Type: "string", Values: []interface{}{"bar"}},
+		{Name: "type", Type: "string", Values: []interface{}{"debug_buffer_outline"}},
+	}
+	minZoom := 4.0
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			m := atlas.NewWebMercatorMap(testMapName)
+			m.Attribution = testMapAttribution
+			m.Center = testMapCenter
+			m.Style = tc.style
+			m.Layers = append(m.Layers, testLayer1, testLayer2, testLayer3)
+			a := &atlas.Atlas{}
+			a.AddMap(m)
+
+			w, _, err := doRequest(a, "GET", tc.uri, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if w.Code != tc.status {
+				t.Fatalf("status, expected %v got %v: %v", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			var legend server.Legend
+			if err := json.NewDecoder(w.Body).Decode(&legend); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(legend, tc.expected) {
+				t.Errorf("legend, expected %+v got %+v", tc.expected, legend)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"sampled": {
+			uri:    "/maps/test-map/legend",
+			status: http.StatusOK,
+			expected: server.Legend{
+				Name:        testMapName,
+				Attribution: testMapAttribution,
+				Layers: []server.LegendLayer{
+					// the center zoom (3) is clamped to the zooms of the layers
+					{Name: "test-layer", GeometryType: tilejson.GeomTypePoint, MinZoom: 4, MaxZoom: 20, SampleZoom: zoom(4), Attributes: attributes},
+					{Name: "test-layer-2-name", GeometryType: tilejson.GeomTypeLine, MinZoom: 10, MaxZoom: 15, SampleZoom: zoom(10), Attributes: attributes},
+				},
+			},
+		},
+		"not sampled": {
+			uri:    "/maps/test-map/legend?sample=false",
+			status: http.StatusOK,
+			expected: server.Legend{
+				Name:        testMapName,
+				Attribution: testMapAttribution,
+				Layers: []server.LegendLayer{
+					{Name: "test-layer", GeometryType: tilejson.GeomTypePoint, MinZoom: 4, MaxZoom: 20, Attributes: []server.LegendAttribute{}},
+					{Name: "test-layer-2-name", GeometryType: tilejson.GeomTypeLine, MinZoom: 10, MaxZoom: 15, Attributes: []server.LegendAttribute{}},
+				},
+			},
+		},
+		"hosted style": {
+			uri:    "/maps/test-map/legend?sample=false",
+			style:  stylePath,
+			status: http.StatusOK,
+			expected: server.Legend{
+				Name:        testMapName,
+				Attribution: testMapAttribution,
+				Layers: []server.LegendLayer{
+					{
+						Name: "test-layer", GeometryType: tilejson.GeomTypePoint, MinZoom: 4, MaxZoom: 20, Attributes: []server.LegendAttribute{},
+						Styles: []server.LegendStyle{{
+							ID:      "bars",
+							Type:    "circle",
+							Filter:  []interface{}{"==", "foo", "bar"},
+							MinZoom: &minZoom,
+							Paint:   map[string]interface{}{"circle-color": []interface{}{"get", "color"}},
+						}},
+					},
+					{
+						Name: "test-layer-2-name", GeometryType: tilejson.GeomTypeLine, MinZoom: 10, MaxZoom: 15, Attributes: []server.LegendAttribute{},
+						Styles: []server.LegendStyle{{
+							ID:     "lines",
+							Type:   "line",
+							Layout: map[string]interface{}{"line-cap": "round"},
+						}},
+					},
+				},
+			},
+		},
+		"missing style": {
+			uri:    "/maps/test-map/legend?sample=false",
+			style:  filepath.Join(dir, "missing.json"),
+			status: http.StatusOK,
+			expected: server.Legend{
+				Name:        testMapName,
+				Attribution: testMapAttribution,
+				Layers: []server.LegendLayer{
+					{Name: "test-layer", GeometryType: tilejson.GeomTypePoint, MinZoom: 4, MaxZoom: 20, Attributes: []server.LegendAttribute{}},
+					{Name: "test-layer-2-name", GeometryType: tilejson.GeomTypeLine, MinZoom: 10, MaxZoom: 15, Attributes: []server.LegendAttribute{}},
+				},
+			},
+		},
+		"map not found": {
+			uri:    "/maps/missing/legend",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
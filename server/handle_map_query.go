@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const (
+	// the radius of a query, in pixels, when it's not requested
+	queryDefaultRadius = 5
+	// the largest radius of a query, in pixels
+	queryMaxRadius = 256
+	// the number of features of a query when no limit is requested, and the largest limit
+	queryDefaultLimit = 50
+	queryMaxLimit     = 1000
+	// bounds the providers' queries
+	queryTimeout = 10 * time.Second
+)
+
+// HandleMapQuery returns the features of a map's layers near a point as a GeoJSON feature
+// collection, nearest first, i.e. to identify the features clicked on in a client.
+//
+//	GET /maps/:map_name/query?lon=&lat=&zoom=
+//
+// The query parameters:
+// 	lon, lat - the point, in WGS84
+// 	zoom - the zoom the map is displayed at. Only the layers of the zoom are queried.
+// 	radius - the distance of the features from the point, in pixels (of 256 pixel tiles) at the zoom. Defaults to 5.
+// 	layers - the comma separated names of the layers to query. Defaults to all the layers of the zoom.
+// 	limit - the maximum number of features returned. Defaults to 50.
+type HandleMapQuery struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+// GeoJSONFeatureCollection is the response of the query endpoint
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature is a feature found by a query. Layer, the map layer of the feature, is a
+// foreign member of the feature.
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         uint64                 `json:"id"`
+	Layer      string                 `json:"layer"`
+	Geometry   interface{}            `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+func (req HandleMapQuery) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mapName := httptreemux.ContextParams(r.Context())["map_name"]
+	query := r.URL.Query()
+
+	lon, err := strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		http.Error(w, fmt.Sprintf("invalid lon (%v)", query.Get("lon")), http.StatusBadRequest)
+		return
+	}
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || lat < -85.0511 || lat > 85.0511 {
+		http.Error(w, fmt.Sprintf("invalid lat (%v)", query.Get("lat")), http.StatusBadRequest)
+		return
+	}
+	zoom, err := strconv.ParseUint(query.Get("zoom"), 10, 32)
+	if err != nil || zoom > tegola.MaxZ {
+		http.Error(w, fmt.Sprintf("invalid zoom (%v)", query.Get("zoom")), http.StatusBadRequest)
+		return
+	}
+	radius := float64(queryDefaultRadius)
+	if v := query.Get("radius"); v != "" {
+		if radius, err = strconv.ParseFloat(v, 64); err != nil || radius < 0 || radius > queryMaxRadius {
+			http.Error(w, fmt.Sprintf("invalid radius (%v), must be between 0 and %v", v, queryMaxRadius), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := queryDefaultLimit
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > queryMaxLimit {
+			http.Error(w, fmt.Sprintf("invalid limit (%v), must be between 1 and %v", v, queryMaxLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the queried region is restricted as the tiles of the map
+	if len(Geofences) > 0 {
+		ext, err := atlas.QueryExtent(lon, lat, uint(zoom), radius)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+			return
+		}
+		if geofenceBlocked(w, r, mapName, uint(zoom), ext, fmt.Sprintf("query (%v, %v) at zoom (%v)", lon, lat, zoom)) {
+			return
+		}
+	}
+
+	m, err := req.Atlas.Map(mapName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("map (%v) not configured. check your config file", mapName), http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	if !m.Availability.Available(now) {
+		http.Error(w, fmt.Sprintf("map (%v) is not available", mapName), http.StatusNotFound)
+		return
+	}
+	if m.HasUpstream() {
+		http.Error(w, fmt.Sprintf("map (%v) is an upstream map and can't be queried", mapName), http.StatusBadRequest)
+		return
+	}
+	m = m.FilterLayersByAvailability(now).FilterLayersByZoom(uint(zoom))
+	if names := parseFields(query.Get("layers")); len(names) > 0 {
+		var layers []atlas.Layer
+		for _, l := range m.Layers {
+			for _, name := range names {
+				if l.MVTName() == name {
+					layers = append(layers, l)
+					break
+				}
+			}
+		}
+		m.Layers = layers
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+	defer cancel()
+
+	features, err := m.QueryFeatures(ctx, lon, lat, uint(zoom), radius)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		errMsg := fmt.Sprintf("error querying map (%v): %v", mapName, err)
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+	if len(features) > limit {
+		features = features[:limit]
+	}
+
+	fc := GeoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []GeoJSONFeature{},
+	}
+	for _, f := range features {
+		g, err := geoJSONGeometry(f.Geometry)
+		if err != nil {
+			log.Warnf("map (%v) query: feature (%v) of layer (%v): %v", mapName, f.ID, f.Layer, err)
+			continue
+		}
+		fc.Features = append(fc.Features, GeoJSONFeature{
+			Type:       "Feature",
+			ID:         f.ID,
+			Layer:      f.Layer,
+			Geometry:   g,
+			Properties: f.Tags,
+		})
+	}
+
+	w.Header().Add("Content-Type", "application/geo+json")
+
+	// cache control headers (no-cache)
+	w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Add("Pragma", "no-cache")
+	w.Header().Add("Expires", "0")
+
+	if err := json.NewEncoder(w).Encode(fc); err != nil {
+		log.Errorf("error encoding query of map (%v): %v", mapName, err)
+	}
+}
+
+// geoJSONGeometry returns the GeoJSON geometry object of the geometry
+func geoJSONGeometry(g geom.Geometry) (map[string]interface{}, error) {
+	switch g := g.(type) {
+	case geom.Point:
+		return map[string]interface{}{"type": "Point", "coordinates": g}, nil
+	case geom.MultiPoint:
+		return map[string]interface{}{"type": "MultiPoint", "coordinates": g}, nil
+	case geom.LineString:
+		return map[string]interface{}{"type": "LineString", "coordinates": g}, nil
+	case geom.MultiLineString:
+		return map[string]interface{}{"type": "MultiLineString", "coordinates": g}, nil
+	case geom.Polygon:
+		return map[string]interface{}{"type": "Polygon", "coordinates": g}, nil
+	case geom.MultiPolygon:
+		return map[string]interface{}{"type": "MultiPolygon", "coordinates": g}, nil
+	case geom.Collection:
+		geometries := []interface{}{}
+		for _, cg := range g {
+			gj, err := geoJSONGeometry(cg)
+			if err != nil {
+				return nil, err
+			}
+			geometries = append(geometries, gj)
+		}
+		return map[string]interface{}{"type": "GeometryCollection", "geometries": geometries}, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %T", g)
+	}
+}
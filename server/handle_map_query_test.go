@@ -0,0 +1,104 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleMapQuery(t *testing.T) {
+	type tcase struct {
+		uri              string
+		expectedCode     int
+		expectedFeatures int
+	}
+
+	server.URIPrefix = "/"
+	router := server.NewRouter(newTestMapWithLayers(testLayer1))
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var fc server.GeoJSONFeatureCollection
+			if err := json.NewDecoder(w.Body).Decode(&fc); err != nil {
+				t.Fatalf("unable to decode response: %v", err)
+			}
+			if fc.Type != "FeatureCollection" {
+				t.Errorf("type, expected FeatureCollection got %v", fc.Type)
+			}
+			if len(fc.Features) != tc.expectedFeatures {
+				t.Fatalf("features, expected %v got %v", tc.expectedFeatures, len(fc.Features))
+			}
+			for _, f := range fc.Features {
+				if f.Layer != "test-layer" {
+					t.Errorf("layer, expected test-layer got %v", f.Layer)
+				}
+				if f.Properties["foo"] != "bar" {
+					t.Errorf("properties, expected the default tags got %v", f.Properties)
+				}
+				if g, ok := f.Geometry.(map[string]interface{}); !ok || g["type"] != "Polygon" {
+					t.Errorf("geometry, expected a Polygon got %v", f.Geometry)
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"point": {
+			uri:              "/maps/test-map/query?lon=-122.4&lat=37.8&zoom=6",
+			expectedCode:     http.StatusOK,
+			expectedFeatures: 1,
+		},
+		"layers": {
+			uri:              "/maps/test-map/query?lon=-122.4&lat=37.8&zoom=6&radius=1&layers=test-layer",
+			expectedCode:     http.StatusOK,
+			expectedFeatures: 1,
+		},
+		"other layers": {
+			uri:              "/maps/test-map/query?lon=-122.4&lat=37.8&zoom=6&layers=roads",
+			expectedCode:     http.StatusOK,
+			expectedFeatures: 0,
+		},
+		"zoom without layers": {
+			uri:              "/maps/test-map/query?lon=-122.4&lat=37.8&zoom=12",
+			expectedCode:     http.StatusOK,
+			expectedFeatures: 0,
+		},
+		"missing zoom": {
+			uri:          "/maps/test-map/query?lon=-122.4&lat=37.8",
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid lat": {
+			uri:          "/maps/test-map/query?lon=-122.4&lat=91&zoom=6",
+			expectedCode: http.StatusBadRequest,
+		},
+		"invalid radius": {
+			uri:          "/maps/test-map/query?lon=-122.4&lat=37.8&zoom=6&radius=1000",
+			expectedCode: http.StatusBadRequest,
+		},
+		"unknown map": {
+			uri:          "/maps/unknown/query?lon=-122.4&lat=37.8&zoom=6",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,114 @@
+package server_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleMapRaster(t *testing.T) {
+	pngTile := []byte("\x89PNG tile")
+
+	var requests int
+	upstreamSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/4/2/3.png" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(pngTile)
+	}))
+	defer upstreamSrv.Close()
+
+	upstream, err := atlas.NewUpstream(upstreamSrv.URL+"/{z}/{x}/{y}.png", "", 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := atlas.NewWebMercatorMap(testMapName)
+	m.Layers = append(m.Layers, testLayer1)
+	m.Raster = &atlas.Raster{Upstream: upstream, MaxZoom: 10}
+
+	a := &atlas.Atlas{}
+	a.AddMap(m)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	server.URIPrefix = "/"
+	router := server.NewRouter(a)
+
+	type tcase struct {
+		uri         string
+		status      int
+		contentType string
+		cache       string
+		body        []byte
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Fatalf("status, expected %v got %v: %v", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("content type, expected %v got %v", tc.contentType, got)
+			}
+			if got := w.Header().Get("Tegola-Cache"); got != tc.cache {
+				t.Errorf("header Tegola-Cache, expected %v got %v", tc.cache, got)
+			}
+			if tc.body == nil {
+				return
+			}
+			gz, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body, err := ioutil.ReadAll(gz)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(body, tc.body) {
+				t.Errorf("body, expected %q got %q", tc.body, body)
+			}
+		}
+	}
+
+	// the cases run in order, the raster tile is cached apart from the vector tile
+	tests := []struct {
+		name string
+		tcase
+	}{
+		{"raster", tcase{uri: "/maps/test-map/4/2/3.png", status: http.StatusOK, contentType: "image/png", cache: "MISS", body: pngTile}},
+		{"raster cached", tcase{uri: "/maps/test-map/4/2/3.png", status: http.StatusOK, contentType: "image/png", cache: "HIT", body: pngTile}},
+		{"vector", tcase{uri: "/maps/test-map/4/2/3.pbf", status: http.StatusOK, contentType: "application/vnd.mapbox-vector-tile", cache: "MISS"}},
+		{"missing raster", tcase{uri: "/maps/test-map/4/2/4.png", status: http.StatusNotFound}},
+		{"raster above max zoom", tcase{uri: "/maps/test-map/11/2/3.png", status: http.StatusBadRequest}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, fn(tc.tcase))
+	}
+
+	// the upstream was requested for the missing tile, not the zoom out of range or the cached tile
+	if requests != 2 {
+		t.Errorf("upstream requests, expected 2 got %v", requests)
+	}
+}
@@ -22,6 +22,8 @@ type HandleMapStyle struct {
 	mapName string
 	// the requests extension defaults to "json"
 	extension string
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
 }
 
 // returns details about a map according to the
@@ -47,7 +49,7 @@ func (req HandleMapStyle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// lookup our Map
-	m, err := atlas.GetMap(req.mapName)
+	m, err := req.Atlas.Map(req.mapName)
 	if err != nil {
 		log.Errorf("map (%v) not configured. check your config file", req.mapName)
 		http.Error(w, "map ("+req.mapName+") not configured. check your config file", http.StatusNotFound)
@@ -93,6 +95,20 @@ func (req HandleMapStyle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
+		// the configured paint properties of the layers sharing the name
+		var overrides map[string]interface{}
+		for _, ol := range m.Layers {
+			if ol.MVTName() != l.MVTName() {
+				continue
+			}
+			for k, v := range ol.Paint {
+				if overrides == nil {
+					overrides = map[string]interface{}{}
+				}
+				overrides[k] = v
+			}
+		}
+
 		// build our vector layer details
 		layer := style.Layer{
 			ID:          l.MVTName(),
@@ -139,6 +155,8 @@ func (req HandleMapStyle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
+		layer.Paint.Overrides = overrides
+
 		// add our layer to our tile layer response
 		mapboxStyle.Layers = append(mapboxStyle.Layers, layer)
 	}
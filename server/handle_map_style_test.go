@@ -168,6 +168,46 @@ func TestHandleMapStyle(t *testing.T) {
 	}
 }
 
+func TestHandleMapStylePaint(t *testing.T) {
+	layer := testLayer2
+	layer.Paint = map[string]interface{}{
+		"line-color": "#f00",
+		"line-width": []interface{}{"interpolate", []interface{}{"linear"}, []interface{}{"zoom"}, 10, 1, 15, 4},
+	}
+	a := newTestMapWithLayers(testLayer1, layer)
+	server.URIPrefix = "/"
+
+	w, _, err := doRequest(a, "GET", fmt.Sprintf("/maps/%v/style.json", testMapName), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var output struct {
+		Layers []struct {
+			ID    string                 `json:"id"`
+			Paint map[string]interface{} `json:"paint"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &output); err != nil {
+		t.Fatalf("unable to unmarshal JSON response body: %v", err)
+	}
+	if len(output.Layers) != 2 {
+		t.Fatalf("expected 2 layers got %+v", output.Layers)
+	}
+
+	// the generated paint of the other layer is kept
+	if c := output.Layers[0].Paint["circle-color"]; c != "#56f8aa" {
+		t.Errorf("circle-color, expected #56f8aa got %v", c)
+	}
+	paint := output.Layers[1].Paint
+	if paint["line-color"] != "#f00" {
+		t.Errorf("line-color, expected the override #f00 got %v", paint["line-color"])
+	}
+	if _, ok := paint["line-width"].([]interface{}); !ok {
+		t.Errorf("line-width, expected the override expression got %v", paint["line-width"])
+	}
+}
+
 func TestHandleMapStyleCORS(t *testing.T) {
 	tests := map[string]CORSTestCase{
 		"1": {
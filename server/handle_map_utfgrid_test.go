@@ -0,0 +1,125 @@
+package server_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleMapUTFGrid(t *testing.T) {
+	gridLayer := testLayer1
+	// the test provider's feature covers the tile
+	gridLayer.UTFGridKey = "type"
+
+	m := atlas.NewWebMercatorMap(testMapName)
+	m.Layers = append(m.Layers, gridLayer, testLayer2)
+
+	a := &atlas.Atlas{}
+	a.AddMap(m)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	server.URIPrefix = "/"
+	router := server.NewRouter(a)
+
+	type tcase struct {
+		uri         string
+		status      int
+		contentType string
+		cache       string
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Fatalf("status, expected %v got %v: %v", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("content type, expected %v got %v", tc.contentType, got)
+			}
+			if got := w.Header().Get("Tegola-Cache"); got != tc.cache {
+				t.Errorf("header Tegola-Cache, expected %v got %v", tc.cache, got)
+			}
+			if tc.contentType != "application/json" {
+				return
+			}
+
+			gz, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var grid atlas.UTFGrid
+			if err := json.NewDecoder(gz).Decode(&grid); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(grid.Grid) != 64 || len(grid.Keys) != 2 || grid.Keys[1] != "debug_buffer_outline" {
+				t.Fatalf("grid, expected 64 rows keyed by the test feature, got %v rows, keys %v", len(grid.Grid), grid.Keys)
+			}
+			// the feature covers every cell
+			if row := strings.Repeat("!", 64); grid.Grid[31] != row {
+				t.Errorf("row 31, expected %q got %q", row, grid.Grid[31])
+			}
+			if got := grid.Data["debug_buffer_outline"]["foo"]; got != "bar" {
+				t.Errorf("data default tag foo, expected bar got %v", got)
+			}
+		}
+	}
+
+	// the cases run in order, the grid is cached apart from the vector tile
+	tests := []struct {
+		name string
+		tcase
+	}{
+		{"grid", tcase{uri: "/maps/test-map/4/2/3.grid.json", status: http.StatusOK, contentType: "application/json", cache: "MISS"}},
+		{"grid cached", tcase{uri: "/maps/test-map/4/2/3.grid.json", status: http.StatusOK, contentType: "application/json", cache: "HIT"}},
+		// tile 4/2/3 is 4/2/12 numbered from the south
+		{"grid tms", tcase{uri: "/maps/test-map/4/2/12.grid.json?scheme=tms", status: http.StatusOK, contentType: "application/json", cache: "HIT"}},
+		{"vector", tcase{uri: "/maps/test-map/4/2/3.pbf", status: http.StatusOK, contentType: "application/vnd.mapbox-vector-tile", cache: "MISS"}},
+		{"no grid layers at zoom", tcase{uri: "/maps/test-map/10/2/3.grid.json", status: http.StatusNotFound}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, fn(tc.tcase))
+	}
+
+	t.Run("tilejson grids", func(t *testing.T) {
+		defer func(hostName string) { server.HostName = hostName }(server.HostName)
+		server.HostName = ""
+
+		r, err := http.NewRequest("GET", "http://localhost:8080/capabilities/test-map.json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var tileJSON struct {
+			Grids []string `json:"grids"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&tileJSON); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "http://localhost:8080/maps/test-map/{z}/{x}/{y}.grid.json"
+		if len(tileJSON.Grids) != 1 || tileJSON.Grids[0] != expected {
+			t.Errorf("grids, expected [%v] got %v", expected, tileJSON.Grids)
+		}
+	})
+}
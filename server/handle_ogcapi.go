@@ -0,0 +1,525 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const (
+	// OGCAPITileMatrixSet is the identifier of the web mercator tile matrix set of the OGC API
+	// Tiles endpoints
+	OGCAPITileMatrixSet = "WebMercatorQuad"
+
+	ogcWebMercatorQuadURI = "http://www.opengis.net/def/tilematrixset/OGC/1.0/WebMercatorQuad"
+	ogcCRS3857            = "http://www.opengis.net/def/crs/EPSG/0/3857"
+	ogcCRS84              = "http://www.opengis.net/def/crs/OGC/1.3/CRS84"
+
+	ogcRelConformance   = "http://www.opengis.net/def/rel/ogc/1.0/conformance"
+	ogcRelData          = "http://www.opengis.net/def/rel/ogc/1.0/data"
+	ogcRelTilesets      = "http://www.opengis.net/def/rel/ogc/1.0/tilesets-vector"
+	ogcRelTilingSchemes = "http://www.opengis.net/def/rel/ogc/1.0/tiling-schemes"
+	ogcRelTilingScheme  = "http://www.opengis.net/def/rel/ogc/1.0/tiling-scheme"
+)
+
+// ogcConformance are the conformance classes implemented by the OGC API endpoints
+var ogcConformance = []string{
+	"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/core",
+	"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/landing-page",
+	"http://www.opengis.net/spec/ogcapi-common-1/1.0/conf/json",
+	"http://www.opengis.net/spec/ogcapi-common-2/1.0/conf/collections",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/core",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/tileset",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/tilesets-list",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/dataset-tilesets",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/geodata-tilesets",
+	"http://www.opengis.net/spec/ogcapi-tiles-1/1.0/conf/mvt",
+	"http://www.opengis.net/spec/tms/2.0/conf/json-tilematrixset",
+}
+
+// OGCLink is a link of the OGC API responses
+type OGCLink struct {
+	Href      string `json:"href"`
+	Rel       string `json:"rel"`
+	Type      string `json:"type,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+}
+
+// OGCLandingPage is the landing page of the OGC API
+type OGCLandingPage struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Links       []OGCLink `json:"links"`
+}
+
+// OGCConformance lists the conformance classes of the OGC API
+type OGCConformance struct {
+	ConformsTo []string `json:"conformsTo"`
+}
+
+// OGCCollection is a map of the atlas
+type OGCCollection struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Attribution string    `json:"attribution,omitempty"`
+	Extent      OGCExtent `json:"extent"`
+	DataType    string    `json:"dataType"`
+	Links       []OGCLink `json:"links"`
+}
+
+// OGCExtent is the spatial extent of a collection
+type OGCExtent struct {
+	Spatial struct {
+		BBox [][4]float64 `json:"bbox"`
+		CRS  string       `json:"crs"`
+	} `json:"spatial"`
+}
+
+// OGCCollections lists the collections of the OGC API
+type OGCCollections struct {
+	Links       []OGCLink       `json:"links"`
+	Collections []OGCCollection `json:"collections"`
+}
+
+// OGCTileMatrix is a zoom of a tile matrix set
+type OGCTileMatrix struct {
+	ID               string     `json:"id"`
+	ScaleDenominator float64    `json:"scaleDenominator"`
+	CellSize         float64    `json:"cellSize"`
+	CornerOfOrigin   string     `json:"cornerOfOrigin"`
+	PointOfOrigin    [2]float64 `json:"pointOfOrigin"`
+	TileWidth        uint       `json:"tileWidth"`
+	TileHeight       uint       `json:"tileHeight"`
+	MatrixWidth      uint       `json:"matrixWidth"`
+	MatrixHeight     uint       `json:"matrixHeight"`
+}
+
+// OGCTileMatrixSet is a tile matrix set, in the JSON encoding of the OGC Two Dimensional Tile
+// Matrix Set standard
+type OGCTileMatrixSet struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	URI          string          `json:"uri"`
+	CRS          string          `json:"crs"`
+	OrderedAxes  []string        `json:"orderedAxes"`
+	TileMatrices []OGCTileMatrix `json:"tileMatrices"`
+}
+
+// OGCTileMatrixSets lists the tile matrix sets of the OGC API
+type OGCTileMatrixSets struct {
+	TileMatrixSets []struct {
+		ID    string    `json:"id"`
+		Title string    `json:"title"`
+		URI   string    `json:"uri"`
+		Links []OGCLink `json:"links"`
+	} `json:"tileMatrixSets"`
+}
+
+// OGCTileMatrixSetLimits are the tiles of a zoom of a tileset
+type OGCTileMatrixSetLimits struct {
+	TileMatrix string `json:"tileMatrix"`
+	MinTileRow uint   `json:"minTileRow"`
+	MaxTileRow uint   `json:"maxTileRow"`
+	MinTileCol uint   `json:"minTileCol"`
+	MaxTileCol uint   `json:"maxTileCol"`
+}
+
+// OGCTilesetLayer is a vector layer of the tiles of a tileset
+type OGCTilesetLayer struct {
+	ID                string `json:"id"`
+	DataType          string `json:"dataType"`
+	GeometryDimension *int   `json:"geometryDimension,omitempty"`
+	MinTileMatrix     string `json:"minTileMatrix"`
+	MaxTileMatrix     string `json:"maxTileMatrix"`
+}
+
+// OGCTileset describes the tiles of a map in a tile matrix set
+type OGCTileset struct {
+	Title               string                   `json:"title"`
+	DataType            string                   `json:"dataType"`
+	CRS                 string                   `json:"crs"`
+	TileMatrixSetURI    string                   `json:"tileMatrixSetURI"`
+	TileMatrixSetLimits []OGCTileMatrixSetLimits `json:"tileMatrixSetLimits,omitempty"`
+	Layers              []OGCTilesetLayer        `json:"layers,omitempty"`
+	Links               []OGCLink                `json:"links"`
+}
+
+// OGCTilesets lists the tilesets of a map, or of all maps
+type OGCTilesets struct {
+	Tilesets []OGCTileset `json:"tilesets"`
+}
+
+func writeOGCJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("error encoding OGC API response: %v", err)
+	}
+}
+
+func ogcURL(r *http.Request, parts ...string) string {
+	return buildCapabilitiesURL(r, append([]string{"ogcapi"}, parts...), nil)
+}
+
+// ogcDataType returns the data type of the map's tiles
+func ogcDataType(m atlas.Map) string {
+	if m.HasUpstream() && m.TileFormat() != "pbf" {
+		return "map"
+	}
+	return "vector"
+}
+
+// ogcMaps returns the available maps of the atlas sorted by name
+func ogcMaps(a *atlas.Atlas, now time.Time) []atlas.Map {
+	var maps []atlas.Map
+	for _, m := range a.AllMaps() {
+		// maps outside of their availability windows are not listed
+		if !m.Availability.Available(now) {
+			continue
+		}
+		maps = append(maps, m.FilterLayersByAvailability(now))
+	}
+	sort.Slice(maps, func(i, j int) bool { return maps[i].Name < maps[j].Name })
+	return maps
+}
+
+// ogcMap returns the available map of the request's map_name, writing a 404 when there is none
+func ogcMap(w http.ResponseWriter, r *http.Request, a *atlas.Atlas) (atlas.Map, bool) {
+	mapName := httptreemux.ContextParams(r.Context())["map_name"]
+	m, err := a.Map(mapName)
+	if err != nil {
+		logAndError(w, http.StatusNotFound, "collection (%v) not found", mapName)
+		return m, false
+	}
+	now := time.Now()
+	if !m.Availability.Available(now) {
+		logAndError(w, http.StatusNotFound, "collection (%v) is not available", mapName)
+		return m, false
+	}
+	return m.FilterLayersByAvailability(now), true
+}
+
+// HandleOGCAPI serves the landing page, conformance, tile matrix sets and collections of the
+// OGC API - Tiles endpoints, generated from the maps of the atlas. Each map is a collection
+// with a tileset in the WebMercatorQuad tile matrix set.
+//
+// URI scheme: /ogcapi, /ogcapi/conformance, /ogcapi/tileMatrixSets, /ogcapi/tileMatrixSets/:tile_matrix_set,
+// /ogcapi/collections, /ogcapi/collections/:map_name, /ogcapi/tiles and /ogcapi/collections/:map_name/tiles
+type HandleOGCAPI struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+	// Resource is the resource of the endpoint, i.e. "conformance"
+	Resource string
+}
+
+func (req HandleOGCAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch req.Resource {
+	case "":
+		writeOGCJSON(w, OGCLandingPage{
+			Title:       "tegola",
+			Description: "Vector tiles of the tegola maps",
+			Links: []OGCLink{
+				{Href: ogcURL(r), Rel: "self", Type: "application/json", Title: "This document"},
+				{Href: ogcURL(r, "conformance"), Rel: ogcRelConformance, Type: "application/json", Title: "Conformance classes"},
+				{Href: ogcURL(r, "collections"), Rel: ogcRelData, Type: "application/json", Title: "Collections"},
+				{Href: ogcURL(r, "tiles"), Rel: ogcRelTilesets, Type: "application/json", Title: "Tilesets"},
+				{Href: ogcURL(r, "tileMatrixSets"), Rel: ogcRelTilingSchemes, Type: "application/json", Title: "Tile matrix sets"},
+			},
+		})
+
+	case "conformance":
+		writeOGCJSON(w, OGCConformance{ConformsTo: ogcConformance})
+
+	case "tileMatrixSets":
+		var tmss OGCTileMatrixSets
+		tmss.TileMatrixSets = append(tmss.TileMatrixSets, struct {
+			ID    string    `json:"id"`
+			Title string    `json:"title"`
+			URI   string    `json:"uri"`
+			Links []OGCLink `json:"links"`
+		}{
+			ID:    OGCAPITileMatrixSet,
+			Title: "Google Maps Compatible for the World",
+			URI:   ogcWebMercatorQuadURI,
+			Links: []OGCLink{{Href: ogcURL(r, "tileMatrixSets", OGCAPITileMatrixSet), Rel: "self", Type: "application/json"}},
+		})
+		writeOGCJSON(w, tmss)
+
+	case "tileMatrixSet":
+		tms := httptreemux.ContextParams(r.Context())["tile_matrix_set"]
+		if tms != OGCAPITileMatrixSet {
+			logAndError(w, http.StatusNotFound, "tile matrix set (%v) not found", tms)
+			return
+		}
+		writeOGCJSON(w, webMercatorQuad())
+
+	case "collections":
+		collections := OGCCollections{
+			Links:       []OGCLink{{Href: ogcURL(r, "collections"), Rel: "self", Type: "application/json"}},
+			Collections: []OGCCollection{},
+		}
+		for _, m := range ogcMaps(req.Atlas, time.Now()) {
+			collections.Collections = append(collections.Collections, ogcCollection(r, m))
+		}
+		writeOGCJSON(w, collections)
+
+	case "collection":
+		m, ok := ogcMap(w, r, req.Atlas)
+		if !ok {
+			return
+		}
+		writeOGCJSON(w, ogcCollection(r, m))
+
+	case "tiles":
+		tilesets := OGCTilesets{Tilesets: []OGCTileset{}}
+		for _, m := range ogcMaps(req.Atlas, time.Now()) {
+			tilesets.Tilesets = append(tilesets.Tilesets, ogcTileset(r, m, false))
+		}
+		writeOGCJSON(w, tilesets)
+
+	case "collectionTiles":
+		m, ok := ogcMap(w, r, req.Atlas)
+		if !ok {
+			return
+		}
+		writeOGCJSON(w, OGCTilesets{Tilesets: []OGCTileset{ogcTileset(r, m, false)}})
+
+	case "tileset":
+		m, ok := ogcMap(w, r, req.Atlas)
+		if !ok {
+			return
+		}
+		tms := httptreemux.ContextParams(r.Context())["tile_matrix_set"]
+		if tms != OGCAPITileMatrixSet {
+			logAndError(w, http.StatusNotFound, "tile matrix set (%v) not found", tms)
+			return
+		}
+		writeOGCJSON(w, ogcTileset(r, m, true))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// webMercatorQuad returns the WebMercatorQuad tile matrix set
+func webMercatorQuad() OGCTileMatrixSet {
+	tms := OGCTileMatrixSet{
+		ID:          OGCAPITileMatrixSet,
+		Title:       "Google Maps Compatible for the World",
+		URI:         ogcWebMercatorQuadURI,
+		CRS:         ogcCRS3857,
+		OrderedAxes: []string{"X", "Y"},
+	}
+	for z := uint(0); z <= tegola.MaxZ; z++ {
+		n := uint(1) << z
+		tms.TileMatrices = append(tms.TileMatrices, OGCTileMatrix{
+			ID:               strconv.FormatUint(uint64(z), 10),
+			ScaleDenominator: webMercatorScaleDenominator / float64(n),
+			CellSize:         webMercatorCellSize / float64(n),
+			CornerOfOrigin:   "topLeft",
+			PointOfOrigin:    [2]float64{-webMercatorOrigin, webMercatorOrigin},
+			TileWidth:        256,
+			TileHeight:       256,
+			MatrixWidth:      n,
+			MatrixHeight:     n,
+		})
+	}
+	return tms
+}
+
+func ogcCollection(r *http.Request, m atlas.Map) OGCCollection {
+	c := OGCCollection{
+		ID:          m.Name,
+		Title:       m.Name,
+		Attribution: m.Attribution,
+		DataType:    ogcDataType(m),
+		Links: []OGCLink{
+			{Href: ogcURL(r, "collections", m.Name), Rel: "self", Type: "application/json"},
+			{Href: ogcURL(r, "collections", m.Name, "tiles"), Rel: ogcRelTilesets, Type: "application/json", Title: "Tilesets of " + m.Name},
+		},
+	}
+	bounds := m.Bounds
+	if bounds == nil {
+		bounds = tegola.WGS84Bounds
+	}
+	c.Extent.Spatial.BBox = [][4]float64{bounds.Extent()}
+	c.Extent.Spatial.CRS = ogcCRS84
+	return c
+}
+
+// ogcTileset returns the map's tileset. The detailed tileset includes the tiles of the map's
+// bounds at each zoom and the layers of the tiles.
+func ogcTileset(r *http.Request, m atlas.Map, detailed bool) OGCTileset {
+	tilesetURL := ogcURL(r, "collections", m.Name, "tiles", OGCAPITileMatrixSet)
+	ts := OGCTileset{
+		Title:            m.Name,
+		DataType:         ogcDataType(m),
+		CRS:              ogcCRS3857,
+		TileMatrixSetURI: ogcWebMercatorQuadURI,
+		Links: []OGCLink{
+			{Href: tilesetURL, Rel: "self", Type: "application/json", Title: "Tileset of " + m.Name},
+			{Href: ogcURL(r, "tileMatrixSets", OGCAPITileMatrixSet), Rel: ogcRelTilingScheme, Type: "application/json"},
+		},
+	}
+
+	// the tiles of the map's formats
+	formats := tileFormats(m)
+	exts := make([]string, 0, len(formats))
+	for ext := range formats {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		query := url.Values{}
+		if ext != m.TileFormat() {
+			query.Set("f", ext)
+		}
+		href := buildCapabilitiesURL(r, []string{"ogcapi", "collections", m.Name, "tiles", OGCAPITileMatrixSet, "{tileMatrix}/{tileRow}/{tileCol}"}, query)
+		ts.Links = append(ts.Links, OGCLink{Href: href, Rel: "item", Type: formats[ext], Templated: true})
+	}
+
+	if !detailed {
+		return ts
+	}
+
+	bounds := m.Bounds
+	if bounds == nil {
+		bounds = tegola.WGS84Bounds
+	}
+	minZoom, maxZoom := uint(0), tileMaxZoom(m)
+	if len(m.Layers) > 0 && !m.HasUpstream() {
+		minZoom = maxZoom
+		for _, l := range m.Layers {
+			if l.MinZoom < minZoom {
+				minZoom = l.MinZoom
+			}
+		}
+	}
+	for z := minZoom; z <= maxZoom; z++ {
+		ts.TileMatrixSetLimits = append(ts.TileMatrixSetLimits, ogcTileMatrixSetLimits(z, bounds))
+	}
+
+	// the map layers sharing a name are one layer of the tiles
+	for _, l := range m.Layers {
+		id := l.MVTName()
+		i := 0
+		for ; i < len(ts.Layers) && ts.Layers[i].ID != id; i++ {
+		}
+		minZ, maxZ := strconv.FormatUint(uint64(l.MinZoom), 10), strconv.FormatUint(uint64(l.ServedMaxZoom()), 10)
+		if i == len(ts.Layers) {
+			ts.Layers = append(ts.Layers, OGCTilesetLayer{
+				ID:                id,
+				DataType:          "vector",
+				GeometryDimension: ogcGeometryDimension(l.GeomType),
+				MinTileMatrix:     minZ,
+				MaxTileMatrix:     maxZ,
+			})
+			continue
+		}
+		if cur, _ := strconv.ParseUint(ts.Layers[i].MinTileMatrix, 10, 32); uint(cur) > l.MinZoom {
+			ts.Layers[i].MinTileMatrix = minZ
+		}
+		if cur, _ := strconv.ParseUint(ts.Layers[i].MaxTileMatrix, 10, 32); uint(cur) < l.ServedMaxZoom() {
+			ts.Layers[i].MaxTileMatrix = maxZ
+		}
+	}
+	return ts
+}
+
+// ogcTileMatrixSetLimits returns the tiles of the zoom covering the WGS84 bounds
+func ogcTileMatrixSetLimits(z uint, bounds *geom.Extent) OGCTileMatrixSetLimits {
+	max := uint(1)<<z - 1
+	tile := func(lon, lat float64) (x, y uint) {
+		t := tegola.Tile{Z: z, Long: lon, Lat: lat}
+		fx, fy := t.Deg2Num()
+		clamp := func(v int) uint {
+			switch {
+			case v < 0:
+				return 0
+			case uint(v) > max:
+				return max
+			}
+			return uint(v)
+		}
+		return clamp(fx), clamp(fy)
+	}
+	minCol, minRow := tile(bounds.MinX(), bounds.MaxY())
+	maxCol, maxRow := tile(bounds.MaxX(), bounds.MinY())
+	return OGCTileMatrixSetLimits{
+		TileMatrix: strconv.FormatUint(uint64(z), 10),
+		MinTileRow: minRow,
+		MaxTileRow: maxRow,
+		MinTileCol: minCol,
+		MaxTileCol: maxCol,
+	}
+}
+
+// ogcGeometryDimension returns the dimension of the geometry type, nil when unknown
+func ogcGeometryDimension(g geom.Geometry) *int {
+	var d int
+	switch g.(type) {
+	case geom.Point, geom.MultiPoint:
+		d = 0
+	case geom.Line, geom.LineString, geom.MultiLineString:
+		d = 1
+	case geom.Polygon, geom.MultiPolygon:
+		d = 2
+	default:
+		return nil
+	}
+	return &d
+}
+
+// HandleOGCAPITile serves the tiles of the OGC API tilesets with the Tiles handler, as the
+// tiles of the /maps/:map_name/:z/:x/:y endpoint. The format of the map's raster is requested
+// with the f query parameter, i.e. ?f=png.
+//
+// URI scheme: /ogcapi/collections/:map_name/tiles/:tile_matrix_set/:z/:y/:x
+type HandleOGCAPITile struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+	// Tiles serves the map tiles
+	Tiles http.Handler
+}
+
+func (req HandleOGCAPITile) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m, ok := ogcMap(w, r, req.Atlas)
+	if !ok {
+		return
+	}
+
+	params := httptreemux.ContextParams(r.Context())
+	if tms := params["tile_matrix_set"]; tms != OGCAPITileMatrixSet {
+		logAndError(w, http.StatusNotFound, "tile matrix set (%v) not found", tms)
+		return
+	}
+	// the tiles of maps of other grids are only served by the map endpoints
+	if !m.TileGrid().IsWebMercator() {
+		logAndError(w, http.StatusNotFound, "collection (%v) is not served in tile matrix set (%v)", m.Name, OGCAPITileMatrixSet)
+		return
+	}
+
+	ext := m.TileFormat()
+	if f := r.URL.Query().Get("f"); f != "" {
+		if f == "mvt" {
+			f = "pbf"
+		}
+		if _, ok := tileFormats(m)[f]; !ok {
+			logAndError(w, http.StatusBadRequest, "format (%v) is not supported by collection (%v)", f, m.Name)
+			return
+		}
+		ext = f
+	}
+
+	req.Tiles.ServeHTTP(w, mapTileRequest(r, m.Name, params["z"], params["x"], params["y"], ext))
+}
@@ -0,0 +1,94 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleOGCAPI(t *testing.T) {
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+
+	get := func(t *testing.T, uri string, status int, v interface{}) {
+		t.Helper()
+		w, _, err := doRequest(a, "GET", uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != status {
+			t.Fatalf("%v, expected status %v got %v: %v", uri, status, w.Code, w.Body.String())
+		}
+		if v == nil {
+			return
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%v, unexpected err: %v", uri, err)
+		}
+	}
+
+	t.Run("landing page", func(t *testing.T) {
+		var lp server.OGCLandingPage
+		get(t, "http://localhost:8080/ogcapi", http.StatusOK, &lp)
+		if len(lp.Links) != 5 || !strings.HasSuffix(lp.Links[1].Href, "/ogcapi/conformance") {
+			t.Errorf("links, expected 5 links to the resources got %+v", lp.Links)
+		}
+	})
+
+	t.Run("conformance", func(t *testing.T) {
+		var c server.OGCConformance
+		get(t, "http://localhost:8080/ogcapi/conformance", http.StatusOK, &c)
+		if len(c.ConformsTo) == 0 {
+			t.Errorf("expected conformance classes")
+		}
+	})
+
+	t.Run("tile matrix set", func(t *testing.T) {
+		var tms server.OGCTileMatrixSet
+		get(t, "http://localhost:8080/ogcapi/tileMatrixSets/WebMercatorQuad", http.StatusOK, &tms)
+		if tms.ID != server.OGCAPITileMatrixSet || len(tms.TileMatrices) == 0 || tms.TileMatrices[1].MatrixWidth != 2 {
+			t.Errorf("expected the WebMercatorQuad tile matrix set got %+v", tms)
+		}
+		get(t, "http://localhost:8080/ogcapi/tileMatrixSets/WorldCRS84Quad", http.StatusNotFound, nil)
+	})
+
+	t.Run("collections", func(t *testing.T) {
+		var cs server.OGCCollections
+		get(t, "http://localhost:8080/ogcapi/collections", http.StatusOK, &cs)
+		if len(cs.Collections) != 1 || cs.Collections[0].ID != testMapName {
+			t.Fatalf("expected the collection of the map got %+v", cs.Collections)
+		}
+		get(t, "http://localhost:8080/ogcapi/collections/missing", http.StatusNotFound, nil)
+	})
+
+	t.Run("tileset", func(t *testing.T) {
+		var ts server.OGCTileset
+		get(t, "http://localhost:8080/ogcapi/collections/test-map/tiles/WebMercatorQuad", http.StatusOK, &ts)
+
+		// the layers sharing a name are one layer with the zooms of both
+		if len(ts.Layers) != 2 || ts.Layers[0].ID != "test-layer" || ts.Layers[0].MinTileMatrix != "4" || ts.Layers[0].MaxTileMatrix != "20" {
+			t.Errorf("layers, expected test-layer from 4 to 20 got %+v", ts.Layers)
+		}
+		if len(ts.TileMatrixSetLimits) != 17 || ts.TileMatrixSetLimits[0].TileMatrix != "4" || ts.TileMatrixSetLimits[0].MaxTileCol != 15 {
+			t.Errorf("limits, expected the tiles of zooms 4 to 20 got %+v", ts.TileMatrixSetLimits)
+		}
+
+		var item string
+		for _, l := range ts.Links {
+			if l.Rel == "item" {
+				item = l.Href
+			}
+		}
+		if !strings.HasSuffix(item, "/ogcapi/collections/test-map/tiles/WebMercatorQuad/{tileMatrix}/{tileRow}/{tileCol}") {
+			t.Errorf("item link, got %v", item)
+		}
+	})
+
+	t.Run("tile", func(t *testing.T) {
+		get(t, "http://localhost:8080/ogcapi/collections/test-map/tiles/WebMercatorQuad/4/3/2", http.StatusOK, nil)
+		get(t, "http://localhost:8080/ogcapi/collections/test-map/tiles/WebMercatorQuad/4/3/2?f=png", http.StatusBadRequest, nil)
+		get(t, "http://localhost:8080/ogcapi/collections/test-map/tiles/WorldCRS84Quad/4/3/2", http.StatusNotFound, nil)
+	})
+}
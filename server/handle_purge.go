@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// MethodPurge is the non standard method used by Varnish, NGINX, Envoy and most CDNs to invalidate cached responses
+const MethodPurge = "PURGE"
+
+// HandlePurge purges tiles from the cache backend using the PURGE method semantics of caching proxies.
+//
+//	PURGE /maps/:map_name/:z/:x/:y                purge the tile at the url
+//	PURGE /maps/:map_name/:layer_name/:z/:x/:y    purge the layer tile at the url
+//	PURGE /maps/:map_name                         with a Surrogate-Key header, purge the tiles tagged with the keys
+//
+// A Surrogate-Key request header (space separated keys) can be sent with any of the urls
+// to additionally purge the tiles tagged with the keys.
+type HandlePurge struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+type purgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+func (req HandlePurge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := httptreemux.ContextParams(r.Context())
+
+	if _, err := req.Atlas.Map(params["map_name"]); err != nil {
+		http.Error(w, fmt.Sprintf("map (%v) not configured", params["map_name"]), http.StatusNotFound)
+		return
+	}
+
+	cacher := req.Atlas.GetCache()
+	if cacher == nil {
+		http.Error(w, "no cache configured", http.StatusNotFound)
+		return
+	}
+
+	var keys []cache.Key
+
+	// a tile url
+	if params["z"] != "" {
+		key, err := tileCacheKey(req.Atlas, r.URL.Path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tileSurrogateIndex.remove(*key)
+		keys = append(keys, *key)
+	}
+
+	surrogateKeys := parseSurrogateKeys(r.Header.Get(SurrogateKeyHeader))
+	if params["z"] == "" && len(surrogateKeys) == 0 {
+		http.Error(w, "a tile url or "+SurrogateKeyHeader+" header is required", http.StatusBadRequest)
+		return
+	}
+
+	// only keys of the map in the url can be purged
+	mapName := params["map_name"]
+	for _, sk := range surrogateKeys {
+		if sk != mapName && !strings.HasPrefix(sk, mapName+"/") && !strings.HasPrefix(sk, mapName+":") {
+			http.Error(w, fmt.Sprintf("surrogate key (%v) does not belong to map (%v)", sk, mapName), http.StatusBadRequest)
+			return
+		}
+	}
+	keys = append(keys, tileSurrogateIndex.take(surrogateKeys)...)
+
+	for i := range keys {
+		tileNegativeCache.purge(keys[i])
+		if err := cacher.Purge(&keys[i]); err != nil {
+			publishInvalidation(keys[:i], surrogateKeys)
+			PublishInvalidationEvents(keys[:i])
+
+			errMsg := fmt.Sprintf("error purging tile (%v): %v", keys[i].String(), err)
+			log.Error(errMsg)
+			http.Error(w, errMsg, http.StatusInternalServerError)
+			return
+		}
+	}
+	publishInvalidation(keys, surrogateKeys)
+	PublishInvalidationEvents(keys)
+
+	log.Infof("purged %v tiles via %v %v", len(keys), r.Method, r.URL.Path)
+
+	writeAdminJSON(w, purgeResponse{Purged: len(keys)})
+}
@@ -0,0 +1,121 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandlePurge(t *testing.T) {
+	type tcase struct {
+		uri           string
+		token         string
+		surrogateKeys string
+		expectedCode  int
+		expected      int
+	}
+
+	const tileURI = "/maps/test-map/10/2/3.pbf"
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			server.AdminToken = testAdminToken
+			defer func() { server.AdminToken = "" }()
+
+			a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+			cacher, _ := memory.New(nil)
+			a.SetCache(cacher)
+
+			// prime the cache
+			w, router, err := doRequest(a, "GET", tileURI, nil)
+			if err != nil {
+				t.Fatalf("error making request, expected nil got %v", err)
+			}
+			if w.Header().Get("Tegola-Cache") != "MISS" {
+				t.Fatalf("header Tegola-Cache, expected MISS got %v", w.Header().Get("Tegola-Cache"))
+			}
+			if sk := w.Header().Get(server.SurrogateKeyHeader); !strings.Contains(sk, "test-map/10/2/3") {
+				t.Fatalf("header %v, expected the tile key got %q", server.SurrogateKeyHeader, sk)
+			}
+
+			r, err := http.NewRequest(server.MethodPurge, tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			if tc.surrogateKeys != "" {
+				r.Header.Set(server.SurrogateKeyHeader, tc.surrogateKeys)
+			}
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Purged int `json:"purged"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("unable to decode response: %v", err)
+			}
+			if resp.Purged != tc.expected {
+				t.Errorf("purged, expected %v got %v", tc.expected, resp.Purged)
+			}
+
+			// the tile should have been purged from the cache
+			r, _ = http.NewRequest("GET", tileURI, nil)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Header().Get("Tegola-Cache") != "MISS" {
+				t.Errorf("header Tegola-Cache after purge, expected MISS got %v", w.Header().Get("Tegola-Cache"))
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"tile url": {
+			uri:          tileURI,
+			token:        testAdminToken,
+			expectedCode: http.StatusOK,
+			expected:     1,
+		},
+		"surrogate key": {
+			uri:           "/maps/test-map",
+			token:         testAdminToken,
+			surrogateKeys: "test-map/10",
+			expectedCode:  http.StatusOK,
+			expected:      1,
+		},
+		"surrogate key of another map": {
+			uri:           "/maps/test-map",
+			token:         testAdminToken,
+			surrogateKeys: "other-map",
+			expectedCode:  http.StatusBadRequest,
+		},
+		"missing surrogate key": {
+			uri:          "/maps/test-map",
+			token:        testAdminToken,
+			expectedCode: http.StatusBadRequest,
+		},
+		"unauthorized": {
+			uri:          tileURI,
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/basic"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const tmsVersion = "1.0.0"
+
+// tmsProfile returns the profile, see the TMS specification, and the SRS of the tile maps of the
+// grid. ok is false for the grids with resolutions, whose tiles don't share the origin of a
+// tile map.
+func tmsProfile(grid atlas.Grid) (profile, srs string, ok bool) {
+	if len(grid.Resolutions) > 0 {
+		return "", "", false
+	}
+	srs = fmt.Sprintf("EPSG:%v", grid.SRID)
+	cols, rows := grid.MatrixSize(0)
+	switch {
+	case grid.IsWebMercator():
+		return "global-mercator", srs, true
+	case grid.SRID == tegola.WGS84 && grid.Extent == atlas.WGS84Grid.Extent && cols == 2 && rows == 1:
+		return "global-geodetic", srs, true
+	default:
+		return "local", srs, true
+	}
+}
+
+type tmsTileMapService struct {
+	XMLName  xml.Name         `xml:"TileMapService"`
+	Version  string           `xml:"version,attr"`
+	Services string           `xml:"services,attr"`
+	Title    string           `xml:"Title"`
+	Abstract string           `xml:"Abstract"`
+	TileMaps []tmsTileMapLink `xml:"TileMaps>TileMap"`
+}
+
+type tmsTileMapLink struct {
+	Title   string `xml:"title,attr"`
+	SRS     string `xml:"srs,attr"`
+	Profile string `xml:"profile,attr"`
+	Href    string `xml:"href,attr"`
+}
+
+type tmsTileMap struct {
+	XMLName        xml.Name `xml:"TileMap"`
+	Version        string   `xml:"version,attr"`
+	TileMapService string   `xml:"tilemapservice,attr"`
+	Title          string   `xml:"Title"`
+	Abstract       string   `xml:"Abstract"`
+	SRS            string   `xml:"SRS"`
+	BoundingBox    struct {
+		MinX string `xml:"minx,attr"`
+		MinY string `xml:"miny,attr"`
+		MaxX string `xml:"maxx,attr"`
+		MaxY string `xml:"maxy,attr"`
+	} `xml:"BoundingBox"`
+	Origin struct {
+		X string `xml:"x,attr"`
+		Y string `xml:"y,attr"`
+	} `xml:"Origin"`
+	TileFormat struct {
+		Width     uint   `xml:"width,attr"`
+		Height    uint   `xml:"height,attr"`
+		MimeType  string `xml:"mime-type,attr"`
+		Extension string `xml:"extension,attr"`
+	} `xml:"TileFormat"`
+	TileSets struct {
+		Profile  string       `xml:"profile,attr"`
+		TileSets []tmsTileSet `xml:"TileSet"`
+	} `xml:"TileSets"`
+}
+
+type tmsTileSet struct {
+	Href          string `xml:"href,attr"`
+	UnitsPerPixel string `xml:"units-per-pixel,attr"`
+	Order         uint   `xml:"order,attr"`
+}
+
+// HandleTMS serves the maps as a Tile Map Service (https://wiki.osgeo.org/wiki/Tile_Map_Service_Specification),
+// whose tiles are numbered from the south, for clients which expect TMS numbering. Tiles are
+// served by the Tiles handler, as the tiles of the /maps/:map_name/:z/:x/:y endpoint.
+//
+// URI scheme: /tms/1.0.0 - the tile maps of the service, one per map
+// URI scheme: /tms/1.0.0/:map_name - the tile map of a map
+// URI scheme: /tms/1.0.0/:map_name/:z/:x/:y - a tile, i.e. /tms/1.0.0/osm/1/0/1.pbf
+type HandleTMS struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+	// Tiles serves the map tiles
+	Tiles http.Handler
+}
+
+func (req HandleTMS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := httptreemux.ContextParams(r.Context())
+	mapName, ok := params["map_name"]
+	switch {
+	case !ok:
+		req.serveTileMapService(w, r)
+	case params["y"] == "":
+		req.serveTileMap(w, r, mapName)
+	default:
+		req.serveTile(w, r, mapName, params["z"], params["x"], params["y"])
+	}
+}
+
+func (req HandleTMS) serveTileMapService(w http.ResponseWriter, r *http.Request) {
+	service := tmsTileMapService{
+		Version:  tmsVersion,
+		Services: buildCapabilitiesURL(r, []string{"tms"}, nil) + "/",
+		Title:    "tegola",
+	}
+
+	now := time.Now()
+	for _, m := range req.Atlas.AllMaps() {
+		// maps outside of their availability windows are not listed
+		if !m.Availability.Available(now) {
+			continue
+		}
+		profile, srs, ok := tmsProfile(m.TileGrid())
+		if !ok {
+			continue
+		}
+		service.TileMaps = append(service.TileMaps, tmsTileMapLink{
+			Title:   m.Name,
+			SRS:     srs,
+			Profile: profile,
+			Href:    buildCapabilitiesURL(r, []string{"tms", tmsVersion, m.Name}, nil),
+		})
+	}
+	sort.Slice(service.TileMaps, func(i, j int) bool { return service.TileMaps[i].Title < service.TileMaps[j].Title })
+
+	writeTMSXML(w, service)
+}
+
+func (req HandleTMS) serveTileMap(w http.ResponseWriter, r *http.Request, mapName string) {
+	m, err := req.Atlas.Map(mapName)
+	if err != nil || !m.Availability.Available(time.Now()) {
+		http.Error(w, fmt.Sprintf("map (%v) not configured. check your config file", mapName), http.StatusNotFound)
+		return
+	}
+	grid := m.TileGrid()
+	profile, srs, ok := tmsProfile(grid)
+	if !ok {
+		http.Error(w, fmt.Sprintf("map (%v) tile grid can't be served as a tile map", mapName), http.StatusNotFound)
+		return
+	}
+
+	tileMap := tmsTileMap{
+		Version:        tmsVersion,
+		TileMapService: buildCapabilitiesURL(r, []string{"tms", tmsVersion}, nil) + "/",
+		Title:          m.Name,
+		Abstract:       m.Attribution,
+		SRS:            srs,
+	}
+
+	bounds := m.Bounds
+	if bounds == nil {
+		bounds = tegola.WGS84Bounds
+	}
+	// the bounding box is in the grid's CRS, the grid's extent when the bounds can't be projected
+	var ll, ur geom.Point
+	switch grid.SRID {
+	case tegola.WebMercator:
+		lowerLeft, err := basic.ToWebMercator(tegola.WGS84, geom.Point{bounds.MinX(), bounds.MinY()})
+		if err != nil {
+			log.Errorf("error projecting the bounds of map (%v): %v", mapName, err)
+			http.Error(w, "error projecting the bounds of the map", http.StatusInternalServerError)
+			return
+		}
+		upperRight, err := basic.ToWebMercator(tegola.WGS84, geom.Point{bounds.MaxX(), bounds.MaxY()})
+		if err != nil {
+			log.Errorf("error projecting the bounds of map (%v): %v", mapName, err)
+			http.Error(w, "error projecting the bounds of the map", http.StatusInternalServerError)
+			return
+		}
+		ll, ur = lowerLeft.(geom.Point), upperRight.(geom.Point)
+	case tegola.WGS84:
+		ll, ur = bounds.Min(), bounds.Max()
+	default:
+		ll, ur = grid.Extent.Min(), grid.Extent.Max()
+	}
+	tileMap.BoundingBox.MinX, tileMap.BoundingBox.MinY = tmsCoord(ll.X()), tmsCoord(ll.Y())
+	tileMap.BoundingBox.MaxX, tileMap.BoundingBox.MaxY = tmsCoord(ur.X()), tmsCoord(ur.Y())
+
+	// TMS tiles are numbered from the bottom left corner
+	tileMap.Origin.X, tileMap.Origin.Y = tmsCoord(grid.Extent.MinX()), tmsCoord(grid.Extent.MinY())
+	tileMap.TileFormat.Width, tileMap.TileFormat.Height = 256, 256
+	tileMap.TileFormat.MimeType = m.ContentType()
+	tileMap.TileFormat.Extension = m.TileFormat()
+
+	tileMap.TileSets.Profile = profile
+	maxZoom := tileMaxZoom(m)
+	if maxZoom > grid.MaxZoom() {
+		maxZoom = grid.MaxZoom()
+	}
+	for z := uint(0); z <= maxZoom; z++ {
+		unitsPerPixel := webMercatorCellSize / float64(uint(1)<<z)
+		if !grid.IsWebMercator() {
+			unitsPerPixel = grid.TileExtent(z, 0, 0).XSpan() / 256
+		}
+		tileMap.TileSets.TileSets = append(tileMap.TileSets.TileSets, tmsTileSet{
+			Href:          buildCapabilitiesURL(r, []string{"tms", tmsVersion, m.Name, strconv.FormatUint(uint64(z), 10)}, nil),
+			UnitsPerPixel: strconv.FormatFloat(unitsPerPixel, 'f', -1, 64),
+			Order:         z,
+		})
+	}
+
+	writeTMSXML(w, tileMap)
+}
+
+// serveTile serves the map tile, numbered from the south, with the Tiles handler
+func (req HandleTMS) serveTile(w http.ResponseWriter, r *http.Request, mapName, z, x, y string) {
+	m, err := req.Atlas.Map(mapName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("map (%v) not configured. check your config file", mapName), http.StatusNotFound)
+		return
+	}
+	ext := strings.TrimPrefix(path.Ext(y), ".")
+	if _, ok := tileFormats(m)[ext]; !ok {
+		http.Error(w, fmt.Sprintf("format (%v) is not supported by map (%v)", ext, mapName), http.StatusNotFound)
+		return
+	}
+
+	if _, _, ok := tmsProfile(m.TileGrid()); !ok {
+		http.Error(w, fmt.Sprintf("map (%v) tile grid can't be served as a tile map", mapName), http.StatusNotFound)
+		return
+	}
+
+	flipped, ok := flipTileY(m.TileGrid(), z, y)
+	if !ok {
+		http.Error(w, fmt.Sprintf("tile (%v/%v/%v) is out of range", z, x, y), http.StatusBadRequest)
+		return
+	}
+
+	req.Tiles.ServeHTTP(w, mapTileRequest(r, mapName, z, x, strings.TrimSuffix(flipped, path.Ext(flipped)), ext))
+}
+
+// tmsCoord formats a coordinate of a TMS resource, in meters
+func tmsCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// writeTMSXML writes a TMS resource
+func writeTMSXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Errorf("error encoding TMS resource: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/mapbox/tilejson"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestTMS(t *testing.T) {
+	server.URIPrefix = "/"
+	hostName := server.HostName
+	server.HostName = ""
+	defer func() { server.HostName = hostName }()
+	a := newTestMapWithLayers(testLayer1)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	router := server.NewRouter(a)
+
+	type tcase struct {
+		uri           string
+		expectedCode  int
+		expectedCache string
+		expectedBody  []string
+	}
+
+	// the cases run in order, the first request caches the tile the TMS requests are served
+	tests := []tcase{
+		{uri: "/maps/test-map/5/2/3.pbf", expectedCode: http.StatusOK, expectedCache: "MISS"},
+		// tile 5/2/3 is 5/2/28 numbered from the south
+		{uri: "/maps/test-map/5/2/28.pbf?scheme=tms", expectedCode: http.StatusOK, expectedCache: "HIT"},
+		{uri: "/tms/1.0.0/test-map/5/2/28.pbf", expectedCode: http.StatusOK, expectedCache: "HIT"},
+		{uri: "/maps/test-map/5/2/3.pbf?scheme=xyz", expectedCode: http.StatusOK, expectedCache: "HIT"},
+		{uri: "/maps/test-map/5/2/3.pbf?scheme=quadkey", expectedCode: http.StatusBadRequest},
+		{uri: "/tms/1.0.0/test-map/5/2/32.pbf", expectedCode: http.StatusBadRequest},
+		{uri: "/tms/1.0.0/test-map/5/2/28.png", expectedCode: http.StatusNotFound},
+		{
+			uri:          "/tms/1.0.0",
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`<TileMap title="test-map" srs="EPSG:3857" profile="global-mercator" href="http://localhost:8080/tms/1.0.0/test-map">`},
+		},
+		{
+			uri:          "/tms/1.0.0/test-map",
+			expectedCode: http.StatusOK,
+			expectedBody: []string{
+				`<Origin x="-20037508.34" y="-20037508.34">`,
+				`<TileFormat width="256" height="256" mime-type="application/vnd.mapbox-vector-tile" extension="pbf">`,
+				`<TileSet href="http://localhost:8080/tms/1.0.0/test-map/5" units-per-pixel="4891.96981025128" order="5">`,
+			},
+		},
+		{uri: "/tms/1.0.0/missing-map", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		r, err := http.NewRequest("GET", "http://localhost:8080"+tc.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != tc.expectedCode {
+			t.Errorf("%v: status code, expected %v got %v: %v", tc.uri, tc.expectedCode, w.Code, w.Body.String())
+			continue
+		}
+		if tc.expectedCache != "" && w.Header().Get("Tegola-Cache") != tc.expectedCache {
+			t.Errorf("%v: header Tegola-Cache, expected %v got %v", tc.uri, tc.expectedCache, w.Header().Get("Tegola-Cache"))
+		}
+		for _, s := range tc.expectedBody {
+			if !strings.Contains(w.Body.String(), s) {
+				t.Errorf("%v: body, expected to contain %v got %v", tc.uri, s, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestMapCapabilitiesTMSScheme(t *testing.T) {
+	server.URIPrefix = "/"
+	hostName := server.HostName
+	server.HostName = ""
+	defer func() { server.HostName = hostName }()
+	router := server.NewRouter(newTestMapWithLayers(testLayer1))
+
+	r, err := http.NewRequest("GET", "http://localhost:8080/capabilities/test-map.json?scheme=tms", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, expected %v got %v: %v", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var tj tilejson.TileJSON
+	if err := json.NewDecoder(w.Body).Decode(&tj); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	if tj.Scheme != tilejson.SchemeTMS {
+		t.Errorf("scheme, expected %v got %v", tilejson.SchemeTMS, tj.Scheme)
+	}
+	expected := "http://localhost:8080/maps/test-map/{z}/{x}/{y}.pbf?scheme=tms"
+	if len(tj.Tiles) != 1 || tj.Tiles[0] != expected {
+		t.Errorf("tiles, expected [%v] got %v", expected, tj.Tiles)
+	}
+}
+
+func TestTMSGrid(t *testing.T) {
+	server.URIPrefix = "/"
+	hostName := server.HostName
+	server.HostName = ""
+	defer func() { server.HostName = hostName }()
+	router := server.NewRouter(newTestGridMap(atlas.WGS84Grid, testLayer1))
+
+	type tcase struct {
+		uri          string
+		expectedCode int
+		expectedBody []string
+	}
+
+	// the rows are flipped within the 16 rows of zoom 4 of the grid
+	tests := []tcase{
+		{uri: "/maps/test-map/4/3/15.pbf?scheme=tms", expectedCode: http.StatusOK},
+		{uri: "/tms/1.0.0/test-map/4/3/0.pbf", expectedCode: http.StatusOK},
+		{uri: "/tms/1.0.0/test-map/4/3/16.pbf", expectedCode: http.StatusBadRequest},
+		{
+			uri:          "/tms/1.0.0",
+			expectedCode: http.StatusOK,
+			expectedBody: []string{`<TileMap title="test-map" srs="EPSG:4326" profile="global-geodetic" href="http://localhost:8080/tms/1.0.0/test-map">`},
+		},
+		{
+			uri:          "/tms/1.0.0/test-map",
+			expectedCode: http.StatusOK,
+			expectedBody: []string{
+				`<Origin x="-180.00" y="-90.00">`,
+				`<TileSet href="http://localhost:8080/tms/1.0.0/test-map/4" units-per-pixel="0.0439453125" order="4">`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		r, err := http.NewRequest("GET", "http://localhost:8080"+tc.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != tc.expectedCode {
+			t.Errorf("%v: status code, expected %v got %v: %v", tc.uri, tc.expectedCode, w.Code, w.Body.String())
+			continue
+		}
+		for _, s := range tc.expectedBody {
+			if !strings.Contains(w.Body.String(), s) {
+				t.Errorf("%v: body, expected to contain %v got %v", tc.uri, s, w.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const (
+	// WMTSTileMatrixSet is the identifier of the well known web mercator tile matrix set of the
+	// WMTS endpoints, with 256 pixel tiles
+	WMTSTileMatrixSet = "GoogleMapsCompatible"
+	// WMTSStyle is the identifier of the only style of the WMTS layers
+	WMTSStyle = "default"
+
+	wmtsVersion = "1.0.0"
+)
+
+// WMTS exception codes, see OGC 07-057r7 section 8.2
+const (
+	wmtsMissingParameterValue = "MissingParameterValue"
+	wmtsInvalidParameterValue = "InvalidParameterValue"
+	wmtsOperationNotSupported = "OperationNotSupported"
+	wmtsTileOutOfRange        = "TileOutOfRange"
+)
+
+type wmtsCapabilities struct {
+	XMLName       xml.Name            `xml:"Capabilities"`
+	Xmlns         string              `xml:"xmlns,attr"`
+	XmlnsOWS      string              `xml:"xmlns:ows,attr"`
+	XmlnsXlink    string              `xml:"xmlns:xlink,attr"`
+	Version       string              `xml:"version,attr"`
+	Title         string              `xml:"ows:ServiceIdentification>ows:Title"`
+	ServiceType   string              `xml:"ows:ServiceIdentification>ows:ServiceType"`
+	ServiceTypeV  string              `xml:"ows:ServiceIdentification>ows:ServiceTypeVersion"`
+	Operations    []wmtsOperation     `xml:"ows:OperationsMetadata>ows:Operation"`
+	Layers        []wmtsLayer         `xml:"Contents>Layer"`
+	TileMatrixSet wmtsTileMatrixSet   `xml:"Contents>TileMatrixSet"`
+	ServiceMeta   wmtsServiceMetadata `xml:"ServiceMetadataURL"`
+}
+
+type wmtsOperation struct {
+	Name string `xml:"name,attr"`
+	Get  struct {
+		Href       string `xml:"xlink:href,attr"`
+		Constraint struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"ows:AllowedValues>ows:Value"`
+		} `xml:"ows:Constraint"`
+	} `xml:"ows:DCP>ows:HTTP>ows:Get"`
+}
+
+type wmtsLayer struct {
+	Title       string `xml:"ows:Title"`
+	Identifier  string `xml:"ows:Identifier"`
+	BoundingBox struct {
+		LowerCorner string `xml:"ows:LowerCorner"`
+		UpperCorner string `xml:"ows:UpperCorner"`
+	} `xml:"ows:WGS84BoundingBox"`
+	Style struct {
+		IsDefault  bool   `xml:"isDefault,attr"`
+		Identifier string `xml:"ows:Identifier"`
+	} `xml:"Style"`
+	Formats       []string          `xml:"Format"`
+	TileMatrixSet string            `xml:"TileMatrixSetLink>TileMatrixSet"`
+	ResourceURLs  []wmtsResourceURL `xml:"ResourceURL"`
+}
+
+type wmtsResourceURL struct {
+	Format       string `xml:"format,attr"`
+	ResourceType string `xml:"resourceType,attr"`
+	Template     string `xml:"template,attr"`
+}
+
+type wmtsTileMatrixSet struct {
+	Identifier   string           `xml:"ows:Identifier"`
+	SupportedCRS string           `xml:"ows:SupportedCRS"`
+	WellKnown    string           `xml:"WellKnownScaleSet"`
+	TileMatrices []wmtsTileMatrix `xml:"TileMatrix"`
+}
+
+type wmtsTileMatrix struct {
+	Identifier       string `xml:"ows:Identifier"`
+	ScaleDenominator string `xml:"ScaleDenominator"`
+	TopLeftCorner    string `xml:"TopLeftCorner"`
+	TileWidth        uint   `xml:"TileWidth"`
+	TileHeight       uint   `xml:"TileHeight"`
+	MatrixWidth      uint   `xml:"MatrixWidth"`
+	MatrixHeight     uint   `xml:"MatrixHeight"`
+}
+
+type wmtsServiceMetadata struct {
+	Href string `xml:"xlink:href,attr"`
+}
+
+type wmtsExceptionReport struct {
+	XMLName   xml.Name `xml:"ExceptionReport"`
+	Xmlns     string   `xml:"xmlns,attr"`
+	Version   string   `xml:"version,attr"`
+	Exception struct {
+		Code    string `xml:"exceptionCode,attr"`
+		Locator string `xml:"locator,attr,omitempty"`
+		Text    string `xml:"ExceptionText"`
+	} `xml:"Exception"`
+}
+
+// wmtsError writes a WMTS exception report
+func wmtsError(w http.ResponseWriter, status int, code, locator, format string, vals ...interface{}) {
+	var report wmtsExceptionReport
+	report.Xmlns = "http://www.opengis.net/ows/1.1"
+	report.Version = wmtsVersion
+	report.Exception.Code = code
+	report.Exception.Locator = locator
+	report.Exception.Text = fmt.Sprintf(format, vals...)
+
+	log.Info(report.Exception.Text)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(report)
+}
+
+// HandleWMTSCapabilities returns the WMTS capabilities of the maps of the atlas, one layer per
+// map in the GoogleMapsCompatible tile matrix set, with the tiles of the map's formats
+//
+// URI scheme: /wmts/1.0.0/WMTSCapabilities.xml
+type HandleWMTSCapabilities struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+func (req HandleWMTSCapabilities) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	caps := wmtsCapabilities{
+		Xmlns:        "http://www.opengis.net/wmts/1.0",
+		XmlnsOWS:     "http://www.opengis.net/ows/1.1",
+		XmlnsXlink:   "http://www.w3.org/1999/xlink",
+		Version:      wmtsVersion,
+		Title:        "tegola",
+		ServiceType:  "OGC WMTS",
+		ServiceTypeV: wmtsVersion,
+		ServiceMeta: wmtsServiceMetadata{
+			Href: buildCapabilitiesURL(r, []string{"wmts", wmtsVersion, "WMTSCapabilities.xml"}, nil),
+		},
+	}
+
+	kvpURL := buildCapabilitiesURL(r, []string{"wmts"}, nil) + "?"
+	for _, name := range []string{"GetCapabilities", "GetTile"} {
+		op := wmtsOperation{Name: name}
+		op.Get.Href = kvpURL
+		op.Get.Constraint.Name = "GetEncoding"
+		op.Get.Constraint.Value = "KVP"
+		caps.Operations = append(caps.Operations, op)
+	}
+
+	now := time.Now()
+	var maxZoom uint
+	for _, m := range req.Atlas.AllMaps() {
+		// maps outside of their availability windows are not listed
+		if !m.Availability.Available(now) {
+			continue
+		}
+		m = m.FilterLayersByAvailability(now)
+
+		layer := wmtsLayer{
+			Title:         m.Name,
+			Identifier:    m.Name,
+			TileMatrixSet: WMTSTileMatrixSet,
+		}
+		bounds := m.Bounds
+		if bounds == nil {
+			bounds = tegola.WGS84Bounds
+		}
+		layer.BoundingBox.LowerCorner = fmt.Sprintf("%v %v", bounds.MinX(), bounds.MinY())
+		layer.BoundingBox.UpperCorner = fmt.Sprintf("%v %v", bounds.MaxX(), bounds.MaxY())
+		layer.Style.IsDefault = true
+		layer.Style.Identifier = WMTSStyle
+
+		formats := tileFormats(m)
+		exts := make([]string, 0, len(formats))
+		for ext := range formats {
+			exts = append(exts, ext)
+		}
+		sort.Strings(exts)
+		for _, ext := range exts {
+			layer.Formats = append(layer.Formats, formats[ext])
+			layer.ResourceURLs = append(layer.ResourceURLs, wmtsResourceURL{
+				Format:       formats[ext],
+				ResourceType: "tile",
+				Template:     buildCapabilitiesURL(r, []string{"wmts", wmtsVersion, m.Name, "{Style}", "{TileMatrixSet}", "{TileMatrix}/{TileRow}/{TileCol}." + ext}, nil),
+			})
+		}
+		caps.Layers = append(caps.Layers, layer)
+
+		if z := tileMaxZoom(m); z > maxZoom {
+			maxZoom = z
+		}
+	}
+	sort.Slice(caps.Layers, func(i, j int) bool { return caps.Layers[i].Identifier < caps.Layers[j].Identifier })
+
+	caps.TileMatrixSet = wmtsTileMatrixSet{
+		Identifier:   WMTSTileMatrixSet,
+		SupportedCRS: "urn:ogc:def:crs:EPSG::3857",
+		WellKnown:    "urn:ogc:def:wkss:OGC:1.0:GoogleMapsCompatible",
+	}
+	topLeft := fmt.Sprintf("%f %f", -webMercatorOrigin, webMercatorOrigin)
+	for z := uint(0); z <= maxZoom; z++ {
+		n := uint(1) << z
+		caps.TileMatrixSet.TileMatrices = append(caps.TileMatrixSet.TileMatrices, wmtsTileMatrix{
+			Identifier:       strconv.FormatUint(uint64(z), 10),
+			ScaleDenominator: strconv.FormatFloat(webMercatorScaleDenominator/float64(n), 'f', -1, 64),
+			TopLeftCorner:    topLeft,
+			TileWidth:        256,
+			TileHeight:       256,
+			MatrixWidth:      n,
+			MatrixHeight:     n,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(caps); err != nil {
+		log.Errorf("error encoding WMTS capabilities: %v", err)
+	}
+}
+
+// HandleWMTS answers the KVP encoded WMTS requests, GetCapabilities and GetTile, and the
+// RESTful tile requests. Tiles are served by the Tiles handler, as the tiles of the
+// /maps/:map_name/:z/:x/:y endpoint.
+//
+// URI scheme: /wmts?SERVICE=WMTS&REQUEST=GetTile&LAYER=:map_name&TILEMATRIXSET=GoogleMapsCompatible&TILEMATRIX=:z&TILEROW=:y&TILECOL=:x&FORMAT=:content_type
+// URI scheme: /wmts/1.0.0/:map_name/:style/:tile_matrix_set/:z/:y/:x
+type HandleWMTS struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+	// Tiles serves the map tiles
+	Tiles http.Handler
+}
+
+func (req HandleWMTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := httptreemux.ContextParams(r.Context())
+	if mapName, ok := params["map_name"]; ok {
+		// RESTful tile request, the tile column holds the extension
+		col := params["x"]
+		ext := strings.TrimPrefix(path.Ext(col), ".")
+		req.serveTile(w, r, mapName, params["tile_matrix_set"], params["z"], params["y"], strings.TrimSuffix(col, path.Ext(col)), ext)
+		return
+	}
+
+	// the parameter names of KVP requests are case insensitive
+	query := map[string]string{}
+	for k, v := range r.URL.Query() {
+		if len(v) > 0 {
+			query[strings.ToUpper(k)] = v[0]
+		}
+	}
+
+	if service := query["SERVICE"]; service != "" && !strings.EqualFold(service, "WMTS") {
+		wmtsError(w, http.StatusBadRequest, wmtsInvalidParameterValue, "SERVICE", "service (%v) is not supported, expected WMTS", service)
+		return
+	}
+
+	switch request := query["REQUEST"]; {
+	case request == "":
+		wmtsError(w, http.StatusBadRequest, wmtsMissingParameterValue, "REQUEST", "REQUEST is required")
+	case strings.EqualFold(request, "GetCapabilities"):
+		HandleWMTSCapabilities{Atlas: req.Atlas}.ServeHTTP(w, r)
+	case strings.EqualFold(request, "GetTile"):
+		for _, p := range []string{"LAYER", "TILEMATRIXSET", "TILEMATRIX", "TILEROW", "TILECOL"} {
+			if query[p] == "" {
+				wmtsError(w, http.StatusBadRequest, wmtsMissingParameterValue, p, "%v is required", p)
+				return
+			}
+		}
+		m, err := req.Atlas.Map(query["LAYER"])
+		if err != nil {
+			wmtsError(w, http.StatusBadRequest, wmtsInvalidParameterValue, "LAYER", "layer (%v) is not configured", query["LAYER"])
+			return
+		}
+		// the format defaults to the format of the map's tiles
+		ext := m.TileFormat()
+		if format := query["FORMAT"]; format != "" {
+			ext = ""
+			for e, contentType := range tileFormats(m) {
+				if contentType == format {
+					ext = e
+				}
+			}
+			if ext == "" {
+				wmtsError(w, http.StatusBadRequest, wmtsInvalidParameterValue, "FORMAT", "format (%v) is not supported by layer (%v)", format, m.Name)
+				return
+			}
+		}
+		req.serveTile(w, r, m.Name, query["TILEMATRIXSET"], query["TILEMATRIX"], query["TILEROW"], query["TILECOL"], ext)
+	default:
+		wmtsError(w, http.StatusBadRequest, wmtsOperationNotSupported, "REQUEST", "request (%v) is not supported", request)
+	}
+}
+
+// serveTile serves the map tile with the Tiles handler
+func (req HandleWMTS) serveTile(w http.ResponseWriter, r *http.Request, mapName, tileMatrixSet, z, row, col, ext string) {
+	m, err := req.Atlas.Map(mapName)
+	if err != nil {
+		wmtsError(w, http.StatusNotFound, wmtsInvalidParameterValue, "LAYER", "layer (%v) is not configured", mapName)
+		return
+	}
+	if tileMatrixSet != WMTSTileMatrixSet {
+		wmtsError(w, http.StatusNotFound, wmtsInvalidParameterValue, "TILEMATRIXSET", "tile matrix set (%v) is not supported, expected %v", tileMatrixSet, WMTSTileMatrixSet)
+		return
+	}
+	// the tiles of maps of other grids are only served by the map endpoints
+	if !m.TileGrid().IsWebMercator() {
+		wmtsError(w, http.StatusNotFound, wmtsInvalidParameterValue, "TILEMATRIXSET", "layer (%v) is not served in tile matrix set (%v)", mapName, tileMatrixSet)
+		return
+	}
+	if _, ok := tileFormats(m)[ext]; !ok {
+		wmtsError(w, http.StatusNotFound, wmtsInvalidParameterValue, "FORMAT", "format (%v) is not supported by layer (%v)", ext, mapName)
+		return
+	}
+
+	zoom, err := strconv.ParseUint(z, 10, 32)
+	if err != nil || zoom > uint64(tileMaxZoom(m)) {
+		wmtsError(w, http.StatusBadRequest, wmtsTileOutOfRange, "TILEMATRIX", "tile matrix (%v) is out of range", z)
+		return
+	}
+	for locator, v := range map[string]string{"TILEROW": row, "TILECOL": col} {
+		if i, err := strconv.ParseUint(v, 10, 32); err != nil || i >= 1<<zoom {
+			wmtsError(w, http.StatusBadRequest, wmtsTileOutOfRange, locator, "%v (%v) is out of range", strings.ToLower(locator), v)
+			return
+		}
+	}
+
+	req.Tiles.ServeHTTP(w, mapTileRequest(r, mapName, z, col, row, ext))
+}
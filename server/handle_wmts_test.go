@@ -0,0 +1,114 @@
+package server_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleWMTSCapabilities(t *testing.T) {
+	a := newTestMapWithLayers(testLayer1, testLayer2)
+
+	for _, uri := range []string{
+		"http://localhost:8080/wmts/1.0.0/WMTSCapabilities.xml",
+		"http://localhost:8080/wmts?service=WMTS&request=GetCapabilities",
+	} {
+		w, _, err := doRequest(a, "GET", uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusOK {
+			t.Fatalf("%v, expected status %v got %v: %v", uri, http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var caps struct {
+			Layers []struct {
+				Identifier   string `xml:"Identifier"`
+				Formats      []string
+				ResourceURLs []struct {
+					Template string `xml:"template,attr"`
+				} `xml:"ResourceURL"`
+			} `xml:"Contents>Layer"`
+			TileMatrices []struct {
+				Identifier string `xml:"Identifier"`
+			} `xml:"Contents>TileMatrixSet>TileMatrix"`
+		}
+		if err := xml.Unmarshal(w.Body.Bytes(), &caps); err != nil {
+			t.Fatalf("%v, unexpected err: %v", uri, err)
+		}
+
+		if len(caps.Layers) != 1 || caps.Layers[0].Identifier != testMapName {
+			t.Fatalf("%v, expected the layer of the map got %+v", uri, caps.Layers)
+		}
+		expected := "/wmts/1.0.0/test-map/{Style}/{TileMatrixSet}/{TileMatrix}/{TileRow}/{TileCol}.pbf"
+		if urls := caps.Layers[0].ResourceURLs; len(urls) != 1 || !strings.HasSuffix(urls[0].Template, expected) {
+			t.Errorf("%v, resource urls, expected %v got %+v", uri, expected, urls)
+		}
+		// the tile matrices of zooms 0 to the max zoom of the layers
+		if len(caps.TileMatrices) != 16 {
+			t.Errorf("%v, tile matrices, expected 16 got %v", uri, len(caps.TileMatrices))
+		}
+	}
+}
+
+func TestHandleWMTS(t *testing.T) {
+	a := newTestMapWithLayers(testLayer1)
+
+	tests := map[string]struct {
+		uri    string
+		status int
+		body   string
+	}{
+		"rest tile": {
+			uri:    "http://localhost:8080/wmts/1.0.0/test-map/default/GoogleMapsCompatible/4/3/2.pbf",
+			status: http.StatusOK,
+		},
+		"kvp tile": {
+			uri:    "http://localhost:8080/wmts?SERVICE=WMTS&REQUEST=GetTile&LAYER=test-map&STYLE=default&TILEMATRIXSET=GoogleMapsCompatible&TILEMATRIX=4&TILEROW=3&TILECOL=2&FORMAT=application/vnd.mapbox-vector-tile",
+			status: http.StatusOK,
+		},
+		"unknown tile matrix set": {
+			uri:    "http://localhost:8080/wmts/1.0.0/test-map/default/WGS84/4/3/2.pbf",
+			status: http.StatusNotFound,
+			body:   server.WMTSTileMatrixSet,
+		},
+		"unknown format": {
+			uri:    "http://localhost:8080/wmts?SERVICE=WMTS&REQUEST=GetTile&LAYER=test-map&TILEMATRIXSET=GoogleMapsCompatible&TILEMATRIX=4&TILEROW=3&TILECOL=2&FORMAT=image/png",
+			status: http.StatusBadRequest,
+			body:   `locator="FORMAT"`,
+		},
+		"tile out of range": {
+			uri:    "http://localhost:8080/wmts/1.0.0/test-map/default/GoogleMapsCompatible/4/16/2.pbf",
+			status: http.StatusBadRequest,
+			body:   "TileOutOfRange",
+		},
+		"missing parameter": {
+			uri:    "http://localhost:8080/wmts?SERVICE=WMTS&REQUEST=GetTile&LAYER=test-map",
+			status: http.StatusBadRequest,
+			body:   "MissingParameterValue",
+		},
+		"unsupported request": {
+			uri:    "http://localhost:8080/wmts?SERVICE=WMTS&REQUEST=GetFeatureInfo",
+			status: http.StatusBadRequest,
+			body:   "OperationNotSupported",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			w, _, err := doRequest(a, "GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tc.status {
+				t.Fatalf("expected status %v got %v: %v", tc.status, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tc.body) {
+				t.Errorf("expected body to contain %v got %v", tc.body, w.Body.String())
+			}
+		})
+	}
+}
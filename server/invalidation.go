@@ -0,0 +1,79 @@
+package server
+
+import (
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/invalidation"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// InvalidationBus, when set, propagates the purges of cached tiles made through the purge
+// endpoints to the other instances of the deployment, and purges the tiles the other
+// instances purged from the memory of this instance: the memory tiers of the cache backend,
+// the negative cache and the surrogate key index.
+// configurable via the tegola config.toml file (set in main.go)
+var InvalidationBus invalidation.Bus
+
+// invalidationBatchSize is the most keys published in a message
+const invalidationBatchSize = 1000
+
+// publishInvalidation publishes the purged keys to the InvalidationBus
+func publishInvalidation(keys []cache.Key, surrogateKeys []string) {
+	if InvalidationBus == nil || len(keys)+len(surrogateKeys) == 0 {
+		return
+	}
+
+	for {
+		msg := invalidation.Message{Origin: invalidation.InstanceID, SurrogateKeys: surrogateKeys}
+		n := len(keys)
+		if n > invalidationBatchSize {
+			n = invalidationBatchSize
+		}
+		msg.Keys, keys = keys[:n], keys[n:]
+
+		if err := InvalidationBus.Publish(msg); err != nil {
+			log.Errorf("error publishing the invalidation of %v tiles: %v", len(msg.Keys)+len(msg.SurrogateKeys), err)
+		}
+
+		// the surrogate keys are sent once
+		surrogateKeys = nil
+		if len(keys) == 0 {
+			return
+		}
+	}
+}
+
+// SubscribeInvalidations purges the tiles purged by the other instances of the deployment from
+// the memory of this instance, as they are published to the InvalidationBus
+func SubscribeInvalidations(a *atlas.Atlas) error {
+	if InvalidationBus == nil {
+		return nil
+	}
+	return InvalidationBus.Subscribe(func(msg invalidation.Message) {
+		applyInvalidation(a, msg)
+	})
+}
+
+// applyInvalidation purges the tiles of the message, published by another instance, from memory
+func applyInvalidation(a *atlas.Atlas, msg invalidation.Message) {
+	if msg.Origin == invalidation.InstanceID {
+		return
+	}
+
+	keys := append(msg.Keys, tileSurrogateIndex.take(msg.SurrogateKeys)...)
+	cacher := a.GetCache()
+	for i := range keys {
+		tileSurrogateIndex.remove(keys[i])
+		tileNegativeCache.purge(keys[i])
+		if cacher == nil {
+			continue
+		}
+		if err := cache.PurgeLocal(cacher, &keys[i]); err != nil {
+			log.Warnf("error purging tile (%v) invalidated by instance %v: %v", keys[i].String(), msg.Origin, err)
+		}
+	}
+
+	PublishInvalidationEvents(keys)
+
+	log.Debugf("purged %v tiles from memory invalidated by instance %v", len(keys), msg.Origin)
+}
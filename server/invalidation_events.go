@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const (
+	// invalidationEventMaxTiles is the most tiles listed by an event, the events of more tiles
+	// have their bounds instead
+	invalidationEventMaxTiles = 256
+	// invalidationEventBuffer is the number of events queued for a client. Clients which fall
+	// further behind are disconnected, so they reconnect and reload their tiles.
+	invalidationEventBuffer = 64
+	// invalidationEventsKeepAlive is the interval of the comments keeping idle streams open
+	// through proxies
+	invalidationEventsKeepAlive = 30 * time.Second
+)
+
+// InvalidationEvent is pushed to the clients of the events endpoint when tiles of a map are
+// purged from the cache or re-seeded. Either Tiles or Bounds is set.
+type InvalidationEvent struct {
+	Map string `json:"map"`
+	// Tiles are the z/x/y of the invalidated tiles. The tiles of the map's layers are included
+	// as the tiles of the map.
+	Tiles []string `json:"tiles,omitempty"`
+	// Bounds (minx, miny, maxx, maxy in lng/lat) include the invalidated tiles between
+	// MinZoom and MaxZoom, when there are too many tiles to list
+	Bounds  []float64 `json:"bounds,omitempty"`
+	MinZoom *uint     `json:"min_zoom,omitempty"`
+	MaxZoom *uint     `json:"max_zoom,omitempty"`
+}
+
+// invalidationBroker fans the invalidation events out to the clients of the events endpoint
+type invalidationBroker struct {
+	sync.Mutex
+	// subscribers are keyed by their channel, with the map they subscribed to
+	subscribers map[chan InvalidationEvent]string
+}
+
+var invalidationEvents = &invalidationBroker{
+	subscribers: map[chan InvalidationEvent]string{},
+}
+
+func (b *invalidationBroker) subscribe(mapName string) chan InvalidationEvent {
+	b.Lock()
+	defer b.Unlock()
+
+	ch := make(chan InvalidationEvent, invalidationEventBuffer)
+	b.subscribers[ch] = mapName
+	return ch
+}
+
+func (b *invalidationBroker) unsubscribe(ch chan InvalidationEvent) {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish sends the event to the subscribers of its map. Subscribers whose queue is full are
+// dropped.
+func (b *invalidationBroker) publish(ev InvalidationEvent) {
+	b.Lock()
+	defer b.Unlock()
+
+	for ch, mapName := range b.subscribers {
+		if mapName != ev.Map {
+			continue
+		}
+		select {
+		case ch <- ev:
+		default:
+			log.Warnf("invalidation events: dropping a client of map (%v) which fell behind", mapName)
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// PublishInvalidationEvents pushes the events of the invalidated keys to the clients of the
+// events endpoint, i.e. the keys re-seeded by the Reseeder
+func PublishInvalidationEvents(keys []cache.Key) {
+	for _, ev := range newInvalidationEvents(keys) {
+		invalidationEvents.publish(ev)
+	}
+}
+
+// newInvalidationEvents returns the events of the keys, one for each map
+func newInvalidationEvents(keys []cache.Key) []InvalidationEvent {
+	tiles := map[string]map[[3]uint]bool{}
+	var maps []string
+	for _, key := range keys {
+		if tiles[key.MapName] == nil {
+			tiles[key.MapName] = map[[3]uint]bool{}
+			maps = append(maps, key.MapName)
+		}
+		tiles[key.MapName][[3]uint{key.Z, key.X, key.Y}] = true
+	}
+
+	events := make([]InvalidationEvent, 0, len(maps))
+	for _, mapName := range maps {
+		zxys := make([][3]uint, 0, len(tiles[mapName]))
+		for zxy := range tiles[mapName] {
+			zxys = append(zxys, zxy)
+		}
+		sort.Slice(zxys, func(i, j int) bool {
+			a, b := zxys[i], zxys[j]
+			if a[0] != b[0] {
+				return a[0] < b[0]
+			}
+			if a[1] != b[1] {
+				return a[1] < b[1]
+			}
+			return a[2] < b[2]
+		})
+
+		ev := InvalidationEvent{Map: mapName}
+		if len(zxys) <= invalidationEventMaxTiles {
+			for _, zxy := range zxys {
+				ev.Tiles = append(ev.Tiles, fmt.Sprintf("%v/%v/%v", zxy[0], zxy[1], zxy[2]))
+			}
+			events = append(events, ev)
+			continue
+		}
+
+		var bounds *geom.Extent
+		minZoom, maxZoom := zxys[0][0], zxys[len(zxys)-1][0]
+		for _, zxy := range zxys {
+			ext := slippy.NewTile(zxy[0], zxy[1], zxy[2]).Extent4326()
+			if bounds == nil {
+				bounds = ext
+				continue
+			}
+			bounds.Add(ext)
+		}
+		ev.Bounds = bounds[:]
+		ev.MinZoom, ev.MaxZoom = &minZoom, &maxZoom
+		events = append(events, ev)
+	}
+	return events
+}
+
+// HandleMapEvents streams the invalidation events of a map as server-sent events, so clients
+// can reload the tiles purged from the cache or re-seeded:
+//
+//	GET /maps/:map_name/events
+//
+//	event: invalidate
+//	data: {"map":"osm","tiles":["14/2621/6333"]}
+//
+// Events aren't replayed, clients reload their tiles when they reconnect.
+type HandleMapEvents struct {
+	// the Atlas to use, nil (default) is the default atlas
+	Atlas *atlas.Atlas
+}
+
+func (req HandleMapEvents) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mapName := httptreemux.ContextParams(r.Context())["map_name"]
+	if _, err := req.Atlas.Map(mapName); err != nil {
+		http.Error(w, fmt.Sprintf("map (%v) not configured. check your config file", mapName), http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := invalidationEvents.subscribe(mapName)
+	defer invalidationEvents.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// turn off the buffering of NGINX
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(invalidationEventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			b, err := json.Marshal(ev)
+			if err != nil {
+				log.Errorf("error encoding invalidation event of map (%v): %v", mapName, err)
+				continue
+			}
+			fmt.Fprintf(w, "event: invalidate\ndata: %s\n\n", b)
+			flusher.Flush()
+		}
+	}
+}
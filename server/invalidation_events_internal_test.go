@@ -0,0 +1,69 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+)
+
+func TestNewInvalidationEvents(t *testing.T) {
+	type tcase struct {
+		keys     []cache.Key
+		expected []InvalidationEvent
+	}
+
+	uintPtr := func(v uint) *uint { return &v }
+
+	// the 340 tiles of zooms 1 to 4, more than are listed by an event
+	var manyKeys []cache.Key
+	for z := uint(1); z <= 4; z++ {
+		for x := uint(0); x < 1<<z; x++ {
+			for y := uint(0); y < 1<<z; y++ {
+				manyKeys = append(manyKeys, cache.Key{MapName: "a", Z: z, X: x, Y: y})
+			}
+		}
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			got := newInvalidationEvents(tc.keys)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("events, expected %+v got %+v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"no keys": {
+			expected: []InvalidationEvent{},
+		},
+		"tiles by map": {
+			keys: []cache.Key{
+				{MapName: "a", Z: 2, X: 1, Y: 1},
+				{MapName: "b", Z: 0},
+				{MapName: "a", LayerName: "roads", Z: 2, X: 1, Y: 1},
+				{MapName: "a", Z: 1, X: 1, Y: 0},
+			},
+			expected: []InvalidationEvent{
+				{Map: "a", Tiles: []string{"1/1/0", "2/1/1"}},
+				{Map: "b", Tiles: []string{"0/0/0"}},
+			},
+		},
+		"bounds": {
+			keys: manyKeys,
+			expected: []InvalidationEvent{
+				{
+					Map:     "a",
+					Bounds:  []float64{-180, -85.05112877980659, 180, 85.05112877980659},
+					MinZoom: uintPtr(1),
+					MaxZoom: uintPtr(4),
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,102 @@
+package server_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestHandleMapEvents(t *testing.T) {
+	server.AdminToken = testAdminToken
+	defer func() { server.AdminToken = "" }()
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+
+	ts := httptest.NewServer(server.NewRouter(a))
+	defer ts.Close()
+
+	// an unknown map
+	resp, err := http.Get(ts.URL + "/maps/missing-map/events")
+	if err != nil {
+		t.Fatalf("error making request, expected nil got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status code of unknown map, expected %v got %v", http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/maps/test-map/events")
+	if err != nil {
+		t.Fatalf("error making request, expected nil got %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("header Content-Type, expected text/event-stream got %v", ct)
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	readLine := func() string {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("events stream closed")
+			}
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out reading the events stream")
+		}
+		return ""
+	}
+
+	// the client is subscribed once the stream is connected
+	if line := readLine(); line != ": connected" {
+		t.Fatalf("first line, expected %q got %q", ": connected", line)
+	}
+	readLine()
+
+	r, _ := http.NewRequest(server.MethodPurge, ts.URL+"/maps/test-map/10/2/3.pbf", nil)
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	purgeResp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatalf("error purging tile, expected nil got %v", err)
+	}
+	purgeResp.Body.Close()
+	if purgeResp.StatusCode != http.StatusOK {
+		t.Fatalf("purge status code, expected %v got %v", http.StatusOK, purgeResp.StatusCode)
+	}
+
+	if line := readLine(); line != "event: invalidate" {
+		t.Fatalf("event line, expected %q got %q", "event: invalidate", line)
+	}
+	line := readLine()
+	if !strings.HasPrefix(line, "data: ") {
+		t.Fatalf("data line, expected data prefix got %q", line)
+	}
+
+	var ev server.InvalidationEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+		t.Fatalf("unable to decode event: %v", err)
+	}
+	if ev.Map != "test-map" {
+		t.Errorf("event map, expected test-map got %v", ev.Map)
+	}
+	if len(ev.Tiles) != 1 || ev.Tiles[0] != "10/2/3" {
+		t.Errorf("event tiles, expected [10/2/3] got %v", ev.Tiles)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/invalidation"
+	"github.com/go-spatial/tegola/cache/memory"
+)
+
+// testBus delivers the published messages to its subscribers
+type testBus struct {
+	published []invalidation.Message
+	subs      []func(invalidation.Message)
+}
+
+func (b *testBus) Publish(msg invalidation.Message) error {
+	b.published = append(b.published, msg)
+	for _, fn := range b.subs {
+		fn(msg)
+	}
+	return nil
+}
+
+func (b *testBus) Subscribe(fn func(invalidation.Message)) error {
+	b.subs = append(b.subs, fn)
+	return nil
+}
+
+func (b *testBus) Close() error { return nil }
+
+func TestInvalidation(t *testing.T) {
+	bus := &testBus{}
+	InvalidationBus = bus
+	defer func() { InvalidationBus = nil }()
+
+	a := &atlas.Atlas{}
+	a.AddMap(atlas.NewWebMercatorMap("test-map"))
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	if err := SubscribeInvalidations(a); err != nil {
+		t.Fatalf("subscribe, expected nil got %v", err)
+	}
+
+	tile := cache.Key{MapName: "test-map", Z: 1, X: 1, Y: 1}
+	indexed := cache.Key{MapName: "test-map", Z: 2, X: 1, Y: 1}
+	cached := func(key cache.Key) bool {
+		_, hit, _ := a.GetCache().Get(&key)
+		return hit
+	}
+	for _, key := range []cache.Key{tile, indexed} {
+		if err := a.GetCache().Set(&key, []byte("tile")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tileSurrogateIndex.add(indexed, []string{"test-map:roads"})
+
+	// the messages of this instance are ignored
+	publishInvalidation([]cache.Key{tile}, nil)
+	if !cached(tile) {
+		t.Fatalf("tile purged by a message of this instance")
+	}
+
+	applyInvalidation(a, invalidation.Message{Origin: "other", Keys: []cache.Key{tile}, SurrogateKeys: []string{"test-map:roads"}})
+	if cached(tile) || cached(indexed) {
+		t.Errorf("tiles, expected the tiles invalidated by another instance to be purged")
+	}
+
+	// large purges are published in batches, the surrogate keys once
+	bus.published = nil
+	keys := make([]cache.Key, 2*invalidationBatchSize+1)
+	publishInvalidation(keys, []string{"test-map"})
+	if len(bus.published) != 3 {
+		t.Fatalf("messages, expected 3 got %v", len(bus.published))
+	}
+	for i, msg := range bus.published {
+		if (i == 0) != (len(msg.SurrogateKeys) == 1) {
+			t.Errorf("message %v surrogate keys, expected them in the first message only got %v", i, msg.SurrogateKeys)
+		}
+		if msg.Origin != invalidation.InstanceID {
+			t.Errorf("message %v origin, expected %v got %v", i, invalidation.InstanceID, msg.Origin)
+		}
+	}
+	if n := len(bus.published[2].Keys); n != 1 {
+		t.Errorf("keys of the last message, expected 1 got %v", n)
+	}
+}
@@ -0,0 +1,413 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwtLeeway is the clock skew allowed when checking the exp and nbf claims
+	jwtLeeway = time.Minute
+	// jwksRefreshInterval is how often the keys of a JWKS url are refreshed
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefreshInterval bounds the refreshes triggered by tokens signed by unknown keys
+	jwksMinRefreshInterval = time.Minute
+	// jwksTimeout bounds the requests of the JWKS url
+	jwksTimeout = 10 * time.Second
+)
+
+// jwksClient fetches the JWKS of the verifiers without a Client
+var jwksClient = &http.Client{Timeout: jwksTimeout}
+
+var (
+	ErrJWTMalformed   = errors.New("jwt: malformed token")
+	ErrJWTAlgorithm   = errors.New("jwt: unsupported signing algorithm")
+	ErrJWTSignature   = errors.New("jwt: invalid signature")
+	ErrJWTExpired     = errors.New("jwt: token is expired")
+	ErrJWTNotValidYet = errors.New("jwt: token is not valid yet")
+	ErrJWTIssuer      = errors.New("jwt: invalid issuer")
+	ErrJWTAudience    = errors.New("jwt: invalid audience")
+	ErrJWTUnknownKey  = errors.New("jwt: unknown signing key")
+)
+
+// JWTVerifier verifies the signature and registered claims of JSON Web Tokens, signed with a
+// shared secret (HS256, HS384, HS512) or the keys of a JWKS url (RS256, RS384, RS512,
+// ES256, ES384, ES512).
+type JWTVerifier struct {
+	// Secret the HMAC signed tokens are verified with
+	Secret []byte
+	// JWKSURL is the url of the JSON Web Key Set the RSA and ECDSA signed tokens are verified
+	// with. The keys are fetched when first needed and refreshed hourly, or when a token is
+	// signed by an unknown key.
+	JWKSURL string
+	// Issuer the iss claim must match, when set
+	Issuer string
+	// Audience the aud claim must contain, when set
+	Audience string
+	// AdminScope authorizes the tokens whose scope claim contains it on the admin endpoints,
+	// when set
+	AdminScope string
+	// Private marks the tiles of authenticated requests as depending on their claims. The tiles
+	// skip the shared caches and are sent with a private Cache-Control.
+	Private bool
+	// Client fetches the JWKS. A client with a jwksTimeout when nil.
+	Client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetched   time.Time
+	fetchErr  error
+	lastFetch time.Time
+	// fetching is closed when the keys being fetched are stored, nil when not fetching
+	fetching chan struct{}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify verifies the token and returns its claims
+func (v *JWTVerifier) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+	if err := v.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := v.verifyClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// HasScope reports if the space separated scope claim (or scp list claim) of the claims
+// contains the scope
+func HasScope(claims map[string]interface{}, scope string) bool {
+	switch s := claims["scope"].(type) {
+	case string:
+		for _, v := range strings.Fields(s) {
+			if v == scope {
+				return true
+			}
+		}
+	}
+	if scp, ok := claims["scp"].([]interface{}); ok {
+		for _, v := range scp {
+			if v == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return ErrJWTMalformed
+	}
+	return nil
+}
+
+// jwtHashes are the hashes of the signing algorithms by their size
+var jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// jwtCurves are the curves of the ECDSA signing algorithms by their size
+var jwtCurves = map[string]string{
+	"256": "P-256",
+	"384": "P-384",
+	"512": "P-521",
+}
+
+func (v *JWTVerifier) verifySignature(header jwtHeader, signed string, signature []byte) error {
+	if len(header.Alg) != 5 {
+		return ErrJWTAlgorithm
+	}
+	hash, ok := jwtHashes[header.Alg[2:]]
+	if !ok {
+		return ErrJWTAlgorithm
+	}
+
+	switch header.Alg[:2] {
+	case "HS":
+		if len(v.Secret) == 0 {
+			return ErrJWTAlgorithm
+		}
+		mac := hmac.New(hash.New, v.Secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrJWTSignature
+		}
+		return nil
+
+	case "RS", "ES":
+		if v.JWKSURL == "" {
+			return ErrJWTAlgorithm
+		}
+		key, err := v.key(header.Kid)
+		if err != nil {
+			return err
+		}
+		h := hash.New()
+		h.Write([]byte(signed))
+		digest := h.Sum(nil)
+
+		switch key := key.(type) {
+		case *rsa.PublicKey:
+			if header.Alg[:2] != "RS" {
+				return ErrJWTAlgorithm
+			}
+			if rsa.VerifyPKCS1v15(key, hash, digest, signature) != nil {
+				return ErrJWTSignature
+			}
+			return nil
+		case *ecdsa.PublicKey:
+			if header.Alg[:2] != "ES" || key.Curve.Params().Name != jwtCurves[header.Alg[2:]] {
+				return ErrJWTAlgorithm
+			}
+			size := (key.Curve.Params().BitSize + 7) / 8
+			if len(signature) != 2*size {
+				return ErrJWTSignature
+			}
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if !ecdsa.Verify(key, digest, r, s) {
+				return ErrJWTSignature
+			}
+			return nil
+		}
+	}
+	return ErrJWTAlgorithm
+}
+
+func (v *JWTVerifier) verifyClaims(claims map[string]interface{}, now time.Time) error {
+	if exp, ok := numericClaim(claims, "exp"); ok && now.After(exp.Add(jwtLeeway)) {
+		return ErrJWTExpired
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(jwtLeeway).Before(nbf) {
+		return ErrJWTNotValidYet
+	}
+	if v.Issuer != "" && claims["iss"] != v.Issuer {
+		return ErrJWTIssuer
+	}
+	if v.Audience != "" {
+		switch aud := claims["aud"].(type) {
+		case string:
+			if aud == v.Audience {
+				return nil
+			}
+		case []interface{}:
+			for _, a := range aud {
+				if a == v.Audience {
+					return nil
+				}
+			}
+		}
+		return ErrJWTAudience
+	}
+	return nil
+}
+
+// numericClaim returns the time of a NumericDate claim
+func numericClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// key returns the JWKS key with the id, fetching the keys when they are stale or don't
+// have the key. The keys are fetched without holding the lock, by one request at a time, so
+// verifying the tokens of known keys isn't held up by a slow JWKS url.
+func (v *JWTVerifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	now := time.Now()
+	key, ok := v.lookupKey(kid)
+	stale := now.Sub(v.fetched) > jwksRefreshInterval
+	switch {
+	case (!ok || stale) && v.fetching == nil && now.Sub(v.lastFetch) > jwksMinRefreshInterval:
+		v.lastFetch = now
+		done := make(chan struct{})
+		v.fetching = done
+		v.mu.Unlock()
+
+		keys, err := v.fetchKeys()
+
+		v.mu.Lock()
+		if err == nil {
+			v.keys, v.fetched = keys, now
+		}
+		v.fetchErr = err
+		v.fetching = nil
+		close(done)
+		key, ok = v.lookupKey(kid)
+
+	case !ok && v.fetching != nil:
+		// wait for the keys being fetched by another request
+		done := v.fetching
+		v.mu.Unlock()
+		<-done
+		v.mu.Lock()
+		key, ok = v.lookupKey(kid)
+	}
+	fetchErr := v.fetchErr
+	v.mu.Unlock()
+
+	if !ok {
+		if fetchErr != nil {
+			return nil, fmt.Errorf("jwt: fetching jwks (%v): %w", v.JWKSURL, fetchErr)
+		}
+		return nil, ErrJWTUnknownKey
+	}
+	return key, nil
+}
+
+// lookupKey returns the key with the id. Tokens without a key id use the only key of the set.
+func (v *JWTVerifier) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// ECDSA
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *JWTVerifier) fetchKeys() (map[string]crypto.PublicKey, error) {
+	client := v.Client
+	if client == nil {
+		client = jwksClient
+	}
+	resp, err := client.Get(v.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// keys of unsupported types are skipped
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %v", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %v", jwk.Kty)
+}
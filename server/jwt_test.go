@@ -0,0 +1,266 @@
+package server_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+// signJWT encodes the claims as a JWT signed by sign
+func signJWT(t *testing.T, header, claims map[string]interface{}, sign func([]byte) []byte) string {
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256(secret string) func([]byte) []byte {
+	return func(b []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(b)
+		return mac.Sum(nil)
+	}
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+				{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+			},
+		})
+	}))
+	defer jwks.Close()
+
+	rs256 := func(b []byte) []byte {
+		digest := sha256.Sum256(b)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return sig
+	}
+	es256 := func(b []byte) []byte {
+		digest := sha256.Sum256(b)
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// r and s are left padded to the 32 bytes of the curve
+		sig := make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+		return sig
+	}
+
+	now := time.Now().Unix()
+	claims := map[string]interface{}{"sub": "alice", "iss": "https://auth.example.com", "aud": []string{"tiles"}, "exp": now + 60}
+
+	type tcase struct {
+		token       string
+		expectedErr error
+	}
+
+	verifier := &server.JWTVerifier{
+		Secret:   []byte("secret"),
+		JWKSURL:  jwks.URL,
+		Issuer:   "https://auth.example.com",
+		Audience: "tiles",
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			got, err := verifier.Verify(tc.token)
+			if err != tc.expectedErr {
+				t.Fatalf("error, expected %v got %v", tc.expectedErr, err)
+			}
+			if err == nil && got["sub"] != "alice" {
+				t.Errorf("sub, expected alice got %v", got["sub"])
+			}
+		}
+	}
+
+	with := func(name string, value interface{}) map[string]interface{} {
+		c := map[string]interface{}{}
+		for k, v := range claims {
+			c[k] = v
+		}
+		c[name] = value
+		return c
+	}
+
+	tests := map[string]tcase{
+		"HS256": {
+			token: signJWT(t, map[string]interface{}{"alg": "HS256"}, claims, hs256("secret")),
+		},
+		"RS256": {
+			token: signJWT(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims, rs256),
+		},
+		"ES256": {
+			token: signJWT(t, map[string]interface{}{"alg": "ES256", "kid": "ec"}, claims, es256),
+		},
+		"ES384 of a P-256 key": {
+			token:       signJWT(t, map[string]interface{}{"alg": "ES384", "kid": "ec"}, claims, es256),
+			expectedErr: server.ErrJWTAlgorithm,
+		},
+		"ES256 of an RSA key": {
+			token:       signJWT(t, map[string]interface{}{"alg": "ES256", "kid": "rsa"}, claims, es256),
+			expectedErr: server.ErrJWTAlgorithm,
+		},
+		"wrong secret": {
+			token:       signJWT(t, map[string]interface{}{"alg": "HS256"}, claims, hs256("guess")),
+			expectedErr: server.ErrJWTSignature,
+		},
+		"unknown key": {
+			token:       signJWT(t, map[string]interface{}{"alg": "RS256", "kid": "other"}, claims, rs256),
+			expectedErr: server.ErrJWTUnknownKey,
+		},
+		"none algorithm": {
+			token:       signJWT(t, map[string]interface{}{"alg": "none"}, claims, func([]byte) []byte { return nil }),
+			expectedErr: server.ErrJWTAlgorithm,
+		},
+		"expired": {
+			token:       signJWT(t, map[string]interface{}{"alg": "HS256"}, with("exp", now-3600), hs256("secret")),
+			expectedErr: server.ErrJWTExpired,
+		},
+		"not valid yet": {
+			token:       signJWT(t, map[string]interface{}{"alg": "HS256"}, with("nbf", now+3600), hs256("secret")),
+			expectedErr: server.ErrJWTNotValidYet,
+		},
+		"issuer": {
+			token:       signJWT(t, map[string]interface{}{"alg": "HS256"}, with("iss", "https://evil.example.com"), hs256("secret")),
+			expectedErr: server.ErrJWTIssuer,
+		},
+		"audience": {
+			token:       signJWT(t, map[string]interface{}{"alg": "HS256"}, with("aud", "admin"), hs256("secret")),
+			expectedErr: server.ErrJWTAudience,
+		},
+		"malformed": {
+			token:       "not.a-token",
+			expectedErr: server.ErrJWTMalformed,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestJWTHandler(t *testing.T) {
+	server.URIPrefix = "/"
+	server.JWT = &server.JWTVerifier{Secret: []byte("secret"), AdminScope: "tegola:admin", Private: true}
+	defer func() { server.JWT = nil }()
+
+	a := newTestMapWithLayers(testLayer1)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	router := server.NewRouter(a)
+
+	token := signJWT(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "alice"}, hs256("secret"))
+	adminToken := signJWT(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "ops", "scope": "tegola:admin"}, hs256("secret"))
+
+	type tcase struct {
+		uri          string
+		auth         string
+		expectedCode int
+	}
+
+	tests := map[string]tcase{
+		"without token": {
+			uri:          "/maps/test-map/5/2/3.pbf",
+			expectedCode: http.StatusUnauthorized,
+		},
+		"invalid token": {
+			uri:          "/maps/test-map/5/2/3.pbf",
+			auth:         "Bearer " + token + "x",
+			expectedCode: http.StatusUnauthorized,
+		},
+		"bearer token": {
+			uri:          "/maps/test-map/5/2/3.pbf",
+			auth:         "Bearer " + token,
+			expectedCode: http.StatusOK,
+		},
+		"query parameter": {
+			uri:          "/maps/test-map/5/2/3.pbf?access_token=" + token,
+			expectedCode: http.StatusOK,
+		},
+		"legend without token": {
+			uri:          "/maps/test-map/legend",
+			expectedCode: http.StatusUnauthorized,
+		},
+		"checksum without token": {
+			uri:          "/checksums/test-map/5/2/3",
+			expectedCode: http.StatusUnauthorized,
+		},
+		"admin without scope": {
+			uri:          "/admin/stats",
+			auth:         "Bearer " + token,
+			expectedCode: http.StatusUnauthorized,
+		},
+		"admin scope": {
+			uri:          "/admin/stats",
+			auth:         "Bearer " + adminToken,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.auth != "" {
+				r.Header.Set("Authorization", tc.auth)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+			// the tiles of private requests aren't cached
+			if w.Code == http.StatusOK && tc.uri != "/admin/stats" {
+				if got := w.Header().Get("Tegola-Cache"); got != "" {
+					t.Errorf("header Tegola-Cache, expected none got %v", got)
+				}
+				if got := w.Header().Get("Cache-Control"); got != "private" {
+					t.Errorf("header Cache-Control, expected private got %v", got)
+				}
+			}
+		})
+	}
+}
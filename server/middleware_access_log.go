@@ -0,0 +1,156 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/tracing"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// RequestIDHeader is the header carrying the id of a tile request. The id of the request is
+// used when it's set by a proxy in front of tegola, otherwise an id is generated. The id is
+// sent back in the header of the response.
+const RequestIDHeader = "X-Request-Id"
+
+var (
+	// AccessLog writes a JSON line for every tile request to AccessLogOutput.
+	// configurable via the tegola config.toml file (set in main.go)
+	AccessLog bool
+
+	// AccessLogOutput is where the access log is written, stdout by default
+	AccessLogOutput io.Writer = os.Stdout
+
+	// accessLogLock keeps the lines of concurrent requests apart
+	accessLogLock sync.Mutex
+)
+
+// validRequestID matches the request ids accepted from clients and proxies
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// AccessLogEntry is a line of the access log
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	// TraceID is the id of the request's trace, when tracing is enabled
+	TraceID    string  `json:"trace_id,omitempty"`
+	Method     string  `json:"method"`
+	URI        string  `json:"uri"`
+	Status     int     `json:"status"`
+	Bytes      int     `json:"bytes"`
+	DurationMS float64 `json:"duration_ms"`
+	RemoteAddr string  `json:"remote_addr"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+	Map        string  `json:"map,omitempty"`
+	Layer      string  `json:"layer,omitempty"`
+	Z          string  `json:"z,omitempty"`
+	X          string  `json:"x,omitempty"`
+	Y          string  `json:"y,omitempty"`
+	// Cache is the Tegola-Cache header of the response: HIT, MISS or BYPASS
+	Cache string `json:"cache,omitempty"`
+}
+
+// AccessLogHandler is middleware which identifies each tile request with a request id, passed
+// to the providers through the request's context (see provider.RequestID) and sent back in the
+// RequestIDHeader, and writes the access log when AccessLog is set.
+func AccessLogHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		r = r.WithContext(provider.WithRequestID(r.Context(), id))
+
+		if !AccessLog {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		lw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(lw, r)
+
+		params := httptreemux.ContextParams(r.Context())
+		entry := AccessLogEntry{
+			Time:       start.UTC(),
+			RequestID:  id,
+			TraceID:    tracing.FromContext(r.Context()).TraceIDString(),
+			Method:     r.Method,
+			URI:        r.URL.RequestURI(),
+			Status:     lw.status,
+			Bytes:      lw.bytes,
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+			RemoteAddr: r.RemoteAddr,
+			UserAgent:  r.UserAgent(),
+			Map:        params["map_name"],
+			Layer:      params["layer_name"],
+			Z:          params["z"],
+			X:          params["x"],
+			Y:          strings.Split(params["y"], ".")[0],
+			Cache:      w.Header().Get("Tegola-Cache"),
+		}
+		if ip := clientIP(r); ip != nil {
+			entry.RemoteAddr = ip.String()
+		}
+		writeAccessLog(entry)
+	})
+}
+
+// writeAccessLog writes the entry as a line of JSON to AccessLogOutput
+func writeAccessLog(entry AccessLogEntry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		log.Errorf("error encoding access log entry: %v", err)
+		return
+	}
+
+	accessLogLock.Lock()
+	defer accessLogLock.Unlock()
+	if _, err := AccessLogOutput.Write(append(b, '\n')); err != nil {
+		log.Errorf("error writing access log: %v", err)
+	}
+}
+
+// newRequestID returns a random request id
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strings.Replace(time.Now().UTC().Format("20060102150405.000000000"), ".", "", 1)
+	}
+	return hex.EncodeToString(b)
+}
+
+// statusWriter records the status and size of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
@@ -0,0 +1,106 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestAccessLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	server.URIPrefix = "/"
+	server.AccessLog = true
+	server.AccessLogOutput = &buf
+	defer func() {
+		server.AccessLog = false
+		server.AccessLogOutput = os.Stdout
+	}()
+	router := server.NewRouter(newTestMapWithLayers(testLayer1))
+
+	type tcase struct {
+		uri       string
+		requestID string
+		expected  server.AccessLogEntry
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			buf.Reset()
+
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.RemoteAddr = "192.0.2.1:1234"
+			if tc.requestID != "" {
+				r.Header.Set(server.RequestIDHeader, tc.requestID)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			var entry server.AccessLogEntry
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("unexpected error decoding %q: %v", buf.String(), err)
+			}
+
+			id := w.Header().Get(server.RequestIDHeader)
+			if id == "" || entry.RequestID != id {
+				t.Errorf("request id, expected the response header (%v) got %v", id, entry.RequestID)
+			}
+			if tc.requestID != "" && id != tc.requestID {
+				t.Errorf("request id, expected %v got %v", tc.requestID, id)
+			}
+			if entry.Bytes != w.Body.Len() {
+				t.Errorf("bytes, expected %v got %v", w.Body.Len(), entry.Bytes)
+			}
+
+			// the fields which vary
+			tc.expected.Time, tc.expected.RequestID, tc.expected.Bytes = entry.Time, entry.RequestID, entry.Bytes
+			tc.expected.DurationMS = entry.DurationMS
+			if entry != tc.expected {
+				t.Errorf("entry, expected %+v got %+v", tc.expected, entry)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"map tile": {
+			uri:       "/maps/test-map/5/2/3.pbf",
+			requestID: "lb-1234",
+			expected: server.AccessLogEntry{
+				Method:     "GET",
+				URI:        "/maps/test-map/5/2/3.pbf",
+				Status:     http.StatusOK,
+				RemoteAddr: "192.0.2.1",
+				Map:        "test-map",
+				Z:          "5",
+				X:          "2",
+				Y:          "3",
+			},
+		},
+		"invalid zoom": {
+			uri: "/maps/test-map/test-layer/50/2/3.pbf",
+			expected: server.AccessLogEntry{
+				Method:     "GET",
+				URI:        "/maps/test-map/test-layer/50/2/3.pbf",
+				Status:     http.StatusBadRequest,
+				RemoteAddr: "192.0.2.1",
+				Map:        "test-map",
+				Layer:      "test-layer",
+				Z:          "50",
+				X:          "2",
+				Y:          "3",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminHandler is middleware which guards the admin endpoints. Requests must supply the
+// configured AdminToken as a bearer token (i.e. "Authorization: Bearer <token>"), or a JWT
+// with the admin scope of the JWT verifier
+func AdminHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+
+		if (AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(AdminToken)) != 1) && !jwtAdmin(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tegola admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// admin responses should never be cached
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+		next.ServeHTTP(w, r)
+		return
+	})
+}
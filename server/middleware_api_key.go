@@ -0,0 +1,60 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/apikey"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// APIKeys are the keys tile requests are scoped and rate limited by. Tile requests are not
+// checked when nil. configurable via the tegola config.toml file (set in main.go)
+var APIKeys *apikey.Keys
+
+// APIKeyHandler is middleware which limits the tile requests of an API key, read from the
+// APIKeyHeader or APIKeyParam, to the maps and rate of the key. Requests without a known key
+// are rejected when the keys are required, apart from the requests of signed urls without a key.
+func APIKeyHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if APIKeys == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := requestAPIKey(r)
+		k, ok := APIKeys.Lookup(key)
+		if key == "" || !ok {
+			if APIKeys.Required && !(key == "" && signedRequest(r)) {
+				http.Error(w, "missing or unknown api key", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if k.Disabled {
+			http.Error(w, "api key is disabled", http.StatusForbidden)
+			return
+		}
+
+		mapName := httptreemux.ContextParams(r.Context())["map_name"]
+		if !k.AllowsMap(mapName) {
+			log.Infof("api key (%v) is not allowed map (%v)", k.Name, mapName)
+			http.Error(w, "api key is not allowed map ("+mapName+")", http.StatusForbidden)
+			return
+		}
+
+		if allowed, retry := APIKeys.Allow(key, time.Now()); !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			http.Error(w, "api key rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,72 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/apikey"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestAPIKeyHandler(t *testing.T) {
+	c, _ := memory.New(nil)
+	keys := apikey.New(apikey.NewCacheStore(c))
+	keys.Required = true
+	for _, k := range []apikey.Key{
+		{Key: "scoped", Name: "acme", Maps: []string{"test-map"}},
+		{Key: "other", Name: "globex", Maps: []string{"other-map"}},
+		{Key: "limited", Name: "initech", RateLimit: 1},
+		{Key: "disabled", Name: "umbrella", Disabled: true},
+	} {
+		if err := keys.Put(context.Background(), k); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	server.URIPrefix = "/"
+	server.APIKeys = keys
+	defer func() { server.APIKeys = nil }()
+	router := server.NewRouter(newTestMapWithLayers(testLayer1))
+
+	type tcase struct {
+		uri          string
+		expectedCode int
+	}
+
+	// the cases run in order, the rate limited key is used up by the first request
+	tests := []tcase{
+		{uri: "/maps/test-map/5/2/3.pbf", expectedCode: http.StatusUnauthorized},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=unknown", expectedCode: http.StatusUnauthorized},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=scoped", expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=other", expectedCode: http.StatusForbidden},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=disabled", expectedCode: http.StatusForbidden},
+		// the legend and checksums of the map are authorized as its tiles
+		{uri: "/maps/test-map/legend", expectedCode: http.StatusUnauthorized},
+		{uri: "/maps/test-map/legend?api_key=other", expectedCode: http.StatusForbidden},
+		{uri: "/maps/test-map/legend?api_key=scoped", expectedCode: http.StatusOK},
+		{uri: "/checksums/test-map/5/2/3", expectedCode: http.StatusUnauthorized},
+		{uri: "/checksums/test-map/5/2/3?api_key=other", expectedCode: http.StatusForbidden},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=limited", expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=limited", expectedCode: http.StatusTooManyRequests},
+	}
+
+	for _, tc := range tests {
+		r, err := http.NewRequest("GET", tc.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != tc.expectedCode {
+			t.Errorf("%v: status code, expected %v got %v: %v", tc.uri, tc.expectedCode, w.Code, w.Body.String())
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "1" {
+			t.Errorf("%v: header Retry-After, expected 1 got %v", tc.uri, w.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+// CacheControlHandler is middleware which sets the Cache-Control header of the tiles of the
+// maps with cache controls at the tile's zoom, overriding a Cache-Control of the configured
+// headers. The header is only set on the responses shared caches may reuse (200, 204 and 404),
+// and not on private tiles.
+func CacheControlHandler(a *atlas.Atlas, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := httptreemux.ContextParams(r.Context())
+		m, err := a.Map(params["map_name"])
+		if err != nil || len(m.CacheControls) == 0 || privateTile(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		z, err := strconv.ParseUint(params["z"], 10, 32)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cc, ok := m.CacheControlAt(uint(z))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, cc: cc}, r)
+	})
+}
+
+// cacheControlWriter sets the Cache-Control header when the response's header is written
+type cacheControlWriter struct {
+	http.ResponseWriter
+	cc          atlas.CacheControl
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		switch status {
+		case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+			w.Header().Set("Cache-Control", cacheControlHeader(w.cc, w.Header().Get("Expires"), time.Now()))
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// cacheControlHeader formats the cache control as a Cache-Control header. The ages are limited
+// by the Expires header of tiles with expiring features, so they aren't reused past it.
+func cacheControlHeader(cc atlas.CacheControl, expires string, now time.Time) string {
+	maxAge, sMaxAge := cc.MaxAge, cc.SMaxAge
+	if t, err := http.ParseTime(expires); err == nil {
+		until := t.Sub(now)
+		if until < 0 {
+			until = 0
+		}
+		if maxAge > until {
+			maxAge = until
+		}
+		if sMaxAge > until {
+			sMaxAge = until
+		}
+	}
+
+	directives := []string{"public", fmt.Sprintf("max-age=%d", int64(maxAge/time.Second))}
+	if sMaxAge > 0 {
+		directives = append(directives, fmt.Sprintf("s-maxage=%d", int64(sMaxAge/time.Second)))
+	}
+	if cc.StaleWhileRevalidate > 0 {
+		directives = append(directives, fmt.Sprintf("stale-while-revalidate=%d", int64(cc.StaleWhileRevalidate/time.Second)))
+	}
+	return strings.Join(directives, ", ")
+}
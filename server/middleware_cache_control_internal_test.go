@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+func TestCacheControlHeader(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	cc := atlas.CacheControl{MaxAge: time.Hour, SMaxAge: 24 * time.Hour, StaleWhileRevalidate: time.Minute}
+
+	tests := map[string]struct {
+		expires  string
+		expected string
+	}{
+		"no expiry": {
+			expected: "public, max-age=3600, s-maxage=86400, stale-while-revalidate=60",
+		},
+		"expires before max age": {
+			expires:  now.Add(10 * time.Minute).Format(http.TimeFormat),
+			expected: "public, max-age=600, s-maxage=600, stale-while-revalidate=60",
+		},
+		"expired": {
+			expires:  now.Add(-time.Minute).Format(http.TimeFormat),
+			expected: "public, max-age=0, stale-while-revalidate=60",
+		},
+	}
+
+	for name, tc := range tests {
+		if got := cacheControlHeader(cc, tc.expires, now); got != tc.expected {
+			t.Errorf("%v: expected %v got %v", name, tc.expected, got)
+		}
+	}
+}
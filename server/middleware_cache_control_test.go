@@ -0,0 +1,73 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestCacheControlHandler(t *testing.T) {
+	type tcase struct {
+		uri      string
+		expected string
+	}
+
+	server.URIPrefix = "/"
+	server.Headers["Cache-Control"] = "max-age=60"
+	defer delete(server.Headers, "Cache-Control")
+
+	m := atlas.NewWebMercatorMap(testMapName)
+	m.Layers = []atlas.Layer{testLayer1}
+	// the map serves the zooms below its layers, as tiles without features
+	minZoom := uint(0)
+	m.MinZoom = &minZoom
+	m.CacheControls = []atlas.CacheControl{
+		{MinZoom: 0, MaxZoom: 5, MaxAge: time.Hour, SMaxAge: 24 * time.Hour, StaleWhileRevalidate: time.Minute},
+		{MinZoom: 5, MaxZoom: 7, MaxAge: time.Minute},
+	}
+	a := &atlas.Atlas{}
+	a.AddMap(m)
+	router := server.NewRouter(a)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Cache-Control"); got != tc.expected {
+				t.Errorf("header Cache-Control, expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"first matching zooms": {
+			uri:      "/maps/test-map/5/2/3.pbf",
+			expected: "public, max-age=3600, s-maxage=86400, stale-while-revalidate=60",
+		},
+		"second zooms": {
+			uri:      "/maps/test-map/6/2/3.pbf",
+			expected: "public, max-age=60",
+		},
+		"no layers at zoom": {
+			uri:      "/maps/test-map/1/0/0.pbf",
+			expected: "public, max-age=3600, s-maxage=86400, stale-while-revalidate=60",
+		},
+		"configured headers": {
+			uri:      "/maps/test-map/8/2/3.pbf",
+			expected: "max-age=60",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+)
+
+// CoalescedHeader is set on tile responses shared with a concurrent identical request
+const CoalescedHeader = "Tegola-Coalesced"
+
+// CoalesceTileRequests shares the response of a tile request with the identical tile
+// requests made while it's served, so a tile which is not cached is only rendered once
+// however many clients request it at the same time. Defaults to true.
+// configurable via the tegola config.toml file (set in main.go)
+var CoalesceTileRequests = true
+
+// bufferedResponse is a response written to memory, i.e. to share it with identical requests
+type bufferedResponse struct {
+	status int
+	header http.Header
+	body   bytes.Buffer
+	// shared are the headers set by the handlers serving the tile, the headers of the
+	// outer handlers are left to the handlers of each request
+	shared http.Header
+}
+
+func (cr *bufferedResponse) Header() http.Header { return cr.header }
+
+func (cr *bufferedResponse) Write(b []byte) (int, error) {
+	if cr.status == 0 {
+		cr.status = http.StatusOK
+	}
+	return cr.body.Write(b)
+}
+
+func (cr *bufferedResponse) WriteHeader(status int) {
+	if cr.status == 0 {
+		cr.status = status
+	}
+}
+
+// writeTo writes the response to w, with the headers of h
+func (cr *bufferedResponse) writeTo(w http.ResponseWriter, h http.Header) {
+	for k, v := range h {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(cr.status)
+	w.Write(cr.body.Bytes())
+}
+
+// coalescedCall is a tile request in flight
+type coalescedCall struct {
+	done chan struct{}
+	// resp is nil when the request was canceled
+	resp *bufferedResponse
+}
+
+// requestCoalescer tracks the tile requests in flight by their url
+type requestCoalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+// CoalesceHandler serves identical concurrent tile requests with a single request to next.
+// The first request is served by next and the requests made while it's in flight wait for
+// its response. The response to the tile of a canceled request is not shared, the waiting
+// requests are served by next instead.
+func CoalesceHandler(next http.Handler) http.Handler {
+	coalescer := &requestCoalescer{calls: map[string]*coalescedCall{}}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// requests bypassing the cache want a freshly rendered tile, not a shared one
+		if !CoalesceTileRequests || cacheBypassed(r) || privateTile(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the tile is determined by the url
+		key := r.URL.Path + "?" + r.URL.RawQuery
+
+		coalescer.mu.Lock()
+		if call, ok := coalescer.calls[key]; ok {
+			coalescer.mu.Unlock()
+			markRenderQueued(r.Context())
+
+			select {
+			case <-call.done:
+			case <-r.Context().Done():
+				return
+			}
+			if call.resp == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(CoalescedHeader, "true")
+			call.resp.writeTo(w, call.resp.shared)
+			return
+		}
+
+		call := &coalescedCall{done: make(chan struct{})}
+		coalescer.calls[key] = call
+		coalescer.mu.Unlock()
+
+		resp := &bufferedResponse{header: http.Header{}}
+		served := false
+		defer func() {
+			coalescer.mu.Lock()
+			delete(coalescer.calls, key)
+			coalescer.mu.Unlock()
+
+			if served && r.Context().Err() == nil {
+				call.resp = resp
+			}
+			close(call.done)
+		}()
+
+		// the headers set by the outer handlers are seen by next, i.e. a configured Cache-Control
+		outer := w.Header().Clone()
+		for k, v := range outer {
+			resp.header[k] = append([]string(nil), v...)
+		}
+
+		next.ServeHTTP(resp, r)
+		if resp.status == 0 {
+			resp.status = http.StatusOK
+		}
+
+		resp.shared = http.Header{}
+		for k, v := range resp.header {
+			if !equalHeaderValues(outer[k], v) {
+				resp.shared[k] = v
+			}
+		}
+		served = true
+
+		resp.writeTo(w, resp.header)
+	})
+}
+
+func equalHeaderValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
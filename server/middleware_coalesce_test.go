@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestCoalesceHandler(t *testing.T) {
+	const requests = 10
+
+	var (
+		calls   int32
+		release = make(chan struct{})
+	)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Header().Set("X-Tile", "rendered")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "tile")
+	})
+	h := server.CoalesceHandler(next)
+
+	// the outer handlers' headers of each request are kept
+	serve := func(ctx context.Context, i int) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/maps/test-map/1/0/0.pbf", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		w.Header().Set("X-Request", fmt.Sprint(i))
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("coalesced", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		release = make(chan struct{})
+
+		var (
+			wg        sync.WaitGroup
+			responses = make([]*httptest.ResponseRecorder, requests)
+		)
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				responses[i] = serve(context.Background(), i)
+			}(i)
+		}
+		// let the requests reach the handler
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if c := atomic.LoadInt32(&calls); c != 1 {
+			t.Errorf("calls, expected 1 got %v", c)
+		}
+		coalesced := 0
+		for i, w := range responses {
+			if w.Code != http.StatusOK || w.Body.String() != "tile" || w.Header().Get("X-Tile") != "rendered" {
+				t.Errorf("response %v, expected the tile got %v %q %v", i, w.Code, w.Body.String(), w.Header())
+			}
+			if w.Header().Get("X-Request") != fmt.Sprint(i) {
+				t.Errorf("response %v, expected the request's own header got %v", i, w.Header().Get("X-Request"))
+			}
+			if w.Header().Get(server.CoalescedHeader) != "" {
+				coalesced++
+			}
+		}
+		if coalesced != requests-1 {
+			t.Errorf("coalesced, expected %v got %v", requests-1, coalesced)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		release = make(chan struct{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go serve(ctx, 0)
+		time.Sleep(20 * time.Millisecond)
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- serve(context.Background(), 1) }()
+		time.Sleep(20 * time.Millisecond)
+
+		// the waiting request is served by next once the first is canceled
+		cancel()
+		close(release)
+
+		w := <-done
+		if w.Body.String() != "tile" || w.Header().Get(server.CoalescedHeader) != "" {
+			t.Errorf("expected the tile of its own request, got %q %v", w.Body.String(), w.Header())
+		}
+		if c := atomic.LoadInt32(&calls); c != 2 {
+			t.Errorf("calls, expected 2 got %v", c)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom/encoding/mvt"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// EmptyTileHandler is middleware which serves the tiles without features of the maps with an
+// EmptyTileStatus with the status and no body, as some clients and CDNs handle a 204 or 404
+// better than an empty tile. The empty tiles are still cached, so they aren't rendered again.
+func EmptyTileHandler(a *atlas.Atlas, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m, err := a.Map(httptreemux.ContextParams(r.Context())["map_name"])
+		if err != nil || !hasEmptyTileStatus(m) || isRasterTile(m, r.URL.Path) || isUTFGridTile(r.URL.Path) || m.ContentType() != mvt.MimeType {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		resp := &bufferedResponse{header: w.Header().Clone()}
+		next.ServeHTTP(resp, r)
+		if r.Context().Err() != nil {
+			return
+		}
+		if resp.status != 0 && resp.status != http.StatusOK {
+			resp.writeTo(w, resp.header)
+			return
+		}
+
+		empty, err := atlas.IsEmptyTile(resp.body.Bytes())
+		if err != nil {
+			log.Warnf("empty tile middleware: map (%v): %v", m.Name, err)
+		}
+		if !empty {
+			resp.status = http.StatusOK
+			resp.writeTo(w, resp.header)
+			return
+		}
+
+		// the caching and surrogate key headers apply to the status too
+		resp.header.Del("Content-Type")
+		resp.header.Del("Content-Length")
+		resp.header.Del("Content-Encoding")
+		for k, v := range resp.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(m.EmptyTileStatus)
+	})
+}
+
+// hasEmptyTileStatus reports if the empty tiles of the map are served with another status than 200
+func hasEmptyTileStatus(m atlas.Map) bool {
+	return m.EmptyTileStatus != 0 && m.EmptyTileStatus != http.StatusOK
+}
@@ -0,0 +1,101 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestEmptyTileHandler(t *testing.T) {
+	type tcase struct {
+		status   int
+		features bool
+
+		expectedCode int
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			server.URIPrefix = "/"
+
+			layer := testLayer1
+			if !tc.features {
+				layer = atlas.Layer{
+					Name:            "empty-layer",
+					ProviderLayerID: "empty-layer",
+					MinZoom:         4,
+					MaxZoom:         9,
+					GeomType:        geom.Point{},
+					Provider:        &countingTiler{},
+				}
+			}
+			m := atlas.NewWebMercatorMap(testMapName)
+			m.Layers = []atlas.Layer{layer}
+			m.EmptyTileStatus = tc.status
+
+			a := &atlas.Atlas{}
+			a.AddMap(m)
+			cacher, _ := memory.New(nil)
+			a.SetCache(cacher)
+			router := server.NewRouter(a)
+
+			// the second request is served from the cache
+			for i, expectedCache := range []string{"MISS", "HIT"} {
+				r, err := http.NewRequest("GET", "/maps/test-map/5/2/3.pbf", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+
+				if w.Code != tc.expectedCode {
+					t.Fatalf("request %v: status code, expected %v got %v", i, tc.expectedCode, w.Code)
+				}
+				if got := w.Header().Get("Tegola-Cache"); got != expectedCache {
+					t.Errorf("request %v: header Tegola-Cache, expected %v got %v", i, expectedCache, got)
+				}
+				if tc.expectedCode == http.StatusOK {
+					if w.Body.Len() == 0 {
+						t.Errorf("request %v: body, expected a tile got none", i)
+					}
+					continue
+				}
+				if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+					t.Errorf("request %v: expected no body and content type got %v bytes of %v", i, w.Body.Len(), w.Header().Get("Content-Type"))
+				}
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"default": {
+			expectedCode: http.StatusOK,
+		},
+		"empty mvt": {
+			status:       http.StatusOK,
+			expectedCode: http.StatusOK,
+		},
+		"no content": {
+			status:       http.StatusNoContent,
+			expectedCode: http.StatusNoContent,
+		},
+		"not found": {
+			status:       http.StatusNotFound,
+			expectedCode: http.StatusNotFound,
+		},
+		"not empty": {
+			status:       http.StatusNoContent,
+			features:     true,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
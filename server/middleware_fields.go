@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom/encoding/mvt"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// FieldsHandler restricts the tags of the features of vector tiles to the comma separated
+// keys of the fields query param (i.e. ?fields=name,class), for clients which don't need
+// every tag. The tags are filtered from the tile served by next, so the whole tile is
+// cached and shared by the requests of any fields. Raster, UTFGrid and upstream tiles which
+// are not vector tiles are served as is.
+func FieldsHandler(a *atlas.Atlas, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields := parseFields(r.URL.Query().Get("fields"))
+		if len(fields) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m, err := a.Map(httptreemux.ContextParams(r.Context())["map_name"])
+		if err != nil || isRasterTile(m, r.URL.Path) || isUTFGridTile(r.URL.Path) || m.ContentType() != mvt.MimeType {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		resp := &bufferedResponse{header: w.Header().Clone()}
+		next.ServeHTTP(resp, r)
+		if r.Context().Err() != nil {
+			return
+		}
+		if resp.status != 0 && resp.status != http.StatusOK {
+			resp.writeTo(w, resp.header)
+			return
+		}
+
+		tile, err := atlas.FilterTileFields(resp.body.Bytes(), fields)
+		if err != nil {
+			errMsg := fmt.Sprintf("error filtering tile fields: %v", err)
+			log.Error(errMsg)
+			http.Error(w, errMsg, http.StatusInternalServerError)
+			return
+		}
+
+		for k, v := range resp.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(tile)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(tile)
+	})
+}
+
+// parseFields returns the non-empty fields of the comma separated list
+func parseFields(s string) []string {
+	var fields []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,70 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestFieldsHandler(t *testing.T) {
+	type tcase struct {
+		uri           string
+		expectedKeys  []string
+		expectedCache string
+	}
+
+	a := newTestMapWithLayers(testLayer1)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	server.URIPrefix = "/"
+	router := server.NewRouter(a)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, expected %v got %v: %v", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Tegola-Cache"); got != tc.expectedCache {
+				t.Errorf("header Tegola-Cache, expected %v got %v", tc.expectedCache, got)
+			}
+
+			var tile vectorTile.Tile
+			if err := proto.Unmarshal(w.Body.Bytes(), &tile); err != nil {
+				t.Fatalf("error decoding tile, expected nil got %v", err)
+			}
+			if len(tile.Layers) != 1 {
+				t.Fatalf("layers, expected 1 got %v", len(tile.Layers))
+			}
+			if keys := tile.Layers[0].Keys; !reflect.DeepEqual(keys, tc.expectedKeys) {
+				t.Errorf("keys, expected %v got %v", tc.expectedKeys, keys)
+			}
+		}
+	}
+
+	// run in order, the filtered tiles are served from the cached tile
+	tests := []struct {
+		name string
+		tcase
+	}{
+		{"fields", tcase{uri: "/maps/test-map/5/2/3.pbf?fields=foo", expectedKeys: []string{"foo"}, expectedCache: "MISS"}},
+		{"all fields", tcase{uri: "/maps/test-map/5/2/3.pbf", expectedKeys: []string{"foo", "type"}, expectedCache: "HIT"}},
+		{"unknown field", tcase{uri: "/maps/test-map/5/2/3.pbf?fields=name,+", expectedKeys: nil, expectedCache: "HIT"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, fn(tc.tcase))
+	}
+}
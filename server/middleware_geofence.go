@@ -0,0 +1,229 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+const (
+	// APIKeyHeader is the request header API keys are read from
+	APIKeyHeader = "X-Api-Key"
+	// APIKeyParam is the query parameter API keys are read from when the header is not set
+	APIKeyParam = "api_key"
+	// KeyClassAnonymous is the key class of requests without a known API key
+	KeyClassAnonymous = "anonymous"
+)
+
+var (
+	// Geofences are the sensitive regions where tile and query requests are blocked or logged.
+	// configurable via the tegola config.toml file (set in main.go)
+	Geofences []Geofence
+
+	// KeyClasses maps API keys to their key class, used to limit geofences to classes of requests.
+	// configurable via the tegola config.toml file (set in main.go)
+	KeyClasses = map[string]string{}
+)
+
+// Geofence is a sensitive region where tile requests from a zoom are blocked or logged
+type Geofence struct {
+	Name string
+	// Extent of the region in WGS84
+	Extent geom.Extent
+	// Polygons of the region in WGS84. The region is the extent when empty.
+	Polygons []geom.Polygon
+	// MinZoom is the zoom the geofence applies from
+	MinZoom uint
+	// Block requests inside the region, otherwise they're logged
+	Block bool
+	// Maps the geofence applies to. Every map when empty.
+	Maps []string
+	// KeyClasses the geofence applies to. Every request when empty.
+	KeyClasses []string
+}
+
+// applies reports if the geofence applies to a request for the map at the zoom by the key class
+func (g Geofence) applies(mapName string, z uint, class string) bool {
+	if z < g.MinZoom {
+		return false
+	}
+	return (len(g.Maps) == 0 || contains(g.Maps, mapName)) &&
+		(len(g.KeyClasses) == 0 || contains(g.KeyClasses, class))
+}
+
+// Intersects reports if the extent, in WGS84, intersects the region
+func (g Geofence) Intersects(ext *geom.Extent) bool {
+	if !extentsOverlap(&g.Extent, ext) {
+		return false
+	}
+	if len(g.Polygons) == 0 {
+		return true
+	}
+	for _, p := range g.Polygons {
+		if polygonIntersectsExtent(p, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// extentsOverlap reports if the extents overlap, excluding touching edges so tiles which
+// only share an edge with a region are not matched
+func extentsOverlap(a, b *geom.Extent) bool {
+	return a.MinX() < b.MaxX() && a.MaxX() > b.MinX() && a.MinY() < b.MaxY() && a.MaxY() > b.MinY()
+}
+
+// polygonIntersectsExtent reports if the polygon, with its holes, intersects the extent
+func polygonIntersectsExtent(p geom.Polygon, ext *geom.Extent) bool {
+	if len(p) == 0 {
+		return false
+	}
+
+	// a vertex of the outer ring inside the extent
+	for _, pt := range p[0] {
+		if ext.ContainsPoint(pt) {
+			return true
+		}
+	}
+
+	// the extent's center inside the polygon, which covers the extent being inside the region
+	center := [2]float64{(ext.MinX() + ext.MaxX()) / 2, (ext.MinY() + ext.MaxY()) / 2}
+	if pointInPolygon(center, p) {
+		return true
+	}
+
+	// an edge of the polygon crossing an edge of the extent
+	edges := ext.Edges(nil)
+	for _, ring := range p {
+		for i := range ring {
+			seg := [2][2]float64{ring[i], ring[(i+1)%len(ring)]}
+			for _, e := range edges {
+				if segmentsIntersect(seg, e) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// pointInPolygon reports if the point is inside the outer ring and outside the holes of the polygon
+func pointInPolygon(pt [2]float64, p geom.Polygon) bool {
+	for i, ring := range p {
+		if pointInRing(pt, ring) != (i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// pointInRing uses ray casting to report if the point is inside the ring
+func pointInRing(pt [2]float64, ring [][2]float64) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > pt[1]) != (b[1] > pt[1]) && pt[0] < (b[0]-a[0])*(pt[1]-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
+
+// segmentsIntersect reports if the line segments intersect
+func segmentsIntersect(a, b [2][2]float64) bool {
+	orient := func(p, q, r [2]float64) float64 {
+		return (q[0]-p[0])*(r[1]-p[1]) - (q[1]-p[1])*(r[0]-p[0])
+	}
+	d1, d2 := orient(b[0], b[1], a[0]), orient(b[0], b[1], a[1])
+	d3, d4 := orient(a[0], a[1], b[0]), orient(a[0], a[1], b[1])
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// requestAPIKey returns the API key of the request
+func requestAPIKey(r *http.Request) string {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		key = r.URL.Query().Get(APIKeyParam)
+	}
+	return key
+}
+
+// keyClass returns the key class of the request's API key, from the KeyClasses or the class
+// of the key in the APIKeys
+func keyClass(r *http.Request) string {
+	key := requestAPIKey(r)
+	if key == "" {
+		return KeyClassAnonymous
+	}
+	if class, ok := KeyClasses[key]; ok {
+		return class
+	}
+	if APIKeys != nil {
+		if k, ok := APIKeys.Lookup(key); ok && k.Class != "" && !k.Disabled {
+			return k.Class
+		}
+	}
+	return KeyClassAnonymous
+}
+
+// GeofenceHandler is middleware which blocks or logs tile requests inside the configured geofences.
+// It runs before the tile cache so blocked tiles are never served from the cache.
+func GeofenceHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(Geofences) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// invalid requests are left for the tile handler to respond to
+		var req HandleMapLayerZXY
+		if err := req.parseURI(r); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ext := slippy.NewTile(req.z, req.x, req.y).Extent4326()
+		what := fmt.Sprintf("tile (%v/%v/%v)", req.z, req.x, req.y)
+		if geofenceBlocked(w, r, req.mapName, req.z, ext, what) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// geofenceBlocked logs the requests for the map at the zoom inside the geofences and responds
+// with a 403 when a geofence blocks the request. what describes the request in the logs.
+func geofenceBlocked(w http.ResponseWriter, r *http.Request, mapName string, z uint, ext *geom.Extent, what string) bool {
+	class := keyClass(r)
+
+	for _, g := range Geofences {
+		if !g.applies(mapName, z, class) || !g.Intersects(ext) {
+			continue
+		}
+
+		if g.Block {
+			log.Infof("geofence (%v) blocked map (%v) %v for key class (%v) from %v", g.Name, mapName, what, class, r.RemoteAddr)
+			w.Header().Set("Cache-Control", "no-store")
+			http.Error(w, "map region is restricted", http.StatusForbidden)
+			return true
+		}
+		log.Infof("geofence (%v) matched map (%v) %v for key class (%v) from %v", g.Name, mapName, what, class, r.RemoteAddr)
+	}
+
+	return false
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+func TestGeofenceIntersects(t *testing.T) {
+	// a triangle with a hole
+	triangle := geom.Polygon{
+		{{0, 0}, {10, 0}, {0, 10}},
+		{{1, 1}, {3, 1}, {1, 3}},
+	}
+
+	type tcase struct {
+		geofence Geofence
+		ext      geom.Extent
+		expected bool
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			if got := tc.geofence.Intersects(&tc.ext); got != tc.expected {
+				t.Errorf("intersects, expected %v got %v", tc.expected, got)
+			}
+		}
+	}
+
+	bounds := Geofence{Extent: geom.Extent{0, 0, 10, 10}}
+	polygon := Geofence{Extent: geom.Extent{0, 0, 10, 10}, Polygons: []geom.Polygon{triangle}}
+
+	tests := map[string]tcase{
+		"bounds overlap": {
+			geofence: bounds,
+			ext:      geom.Extent{9, 9, 11, 11},
+			expected: true,
+		},
+		"bounds touching edge": {
+			geofence: bounds,
+			ext:      geom.Extent{10, 0, 11, 1},
+		},
+		"bounds outside": {
+			geofence: bounds,
+			ext:      geom.Extent{20, 20, 21, 21},
+		},
+		"extent inside polygon": {
+			geofence: polygon,
+			ext:      geom.Extent{4, 0.5, 4.5, 1},
+			expected: true,
+		},
+		"polygon inside extent": {
+			geofence: polygon,
+			ext:      geom.Extent{-1, -1, 11, 11},
+			expected: true,
+		},
+		"edge crossing": {
+			geofence: polygon,
+			ext:      geom.Extent{4, 4, 8, 8},
+			expected: true,
+		},
+		"outside polygon within bounds": {
+			geofence: polygon,
+			ext:      geom.Extent{8, 8, 9, 9},
+		},
+		"inside hole": {
+			geofence: polygon,
+			ext:      geom.Extent{1.2, 1.2, 1.5, 1.5},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestGeofenceHandler(t *testing.T) {
+	type tcase struct {
+		geofences  []Geofence
+		uri        string
+		key        string
+		statusCode int
+	}
+
+	// z14 tiles around Washington DC and Baltimore
+	dc := Geofence{
+		Name:       "dc",
+		Extent:     geom.Extent{-77.12, 38.80, -76.91, 39.0},
+		MinZoom:    12,
+		Block:      true,
+		KeyClasses: []string{KeyClassAnonymous, "public"},
+	}
+
+	KeyClasses = map[string]string{"public-key": "public", "partner-key": "partner"}
+	defer func() { KeyClasses = map[string]string{} }()
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			Geofences = tc.geofences
+			defer func() { Geofences = nil }()
+
+			router := httptreemux.New()
+			router.UsingContext().Handler("GET", "/maps/:map_name/:z/:x/:y", GeofenceHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})))
+
+			r := httptest.NewRequest("GET", tc.uri, nil)
+			if tc.key != "" {
+				r.Header.Set(APIKeyHeader, tc.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Errorf("status code, expected %v got %v", tc.statusCode, w.Code)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"no geofences": {
+			uri:        "/maps/osm/14/4686/6268.pbf",
+			statusCode: http.StatusOK,
+		},
+		"blocked": {
+			geofences:  []Geofence{dc},
+			uri:        "/maps/osm/14/4686/6268.pbf",
+			statusCode: http.StatusForbidden,
+		},
+		"blocked key class": {
+			geofences:  []Geofence{dc},
+			uri:        "/maps/osm/14/4686/6268.pbf",
+			key:        "public-key",
+			statusCode: http.StatusForbidden,
+		},
+		"allowed key class": {
+			geofences:  []Geofence{dc},
+			uri:        "/maps/osm/14/4686/6268.pbf",
+			key:        "partner-key",
+			statusCode: http.StatusOK,
+		},
+		"api key param": {
+			geofences:  []Geofence{dc},
+			uri:        "/maps/osm/14/4686/6268.pbf?api_key=partner-key",
+			statusCode: http.StatusOK,
+		},
+		"below min zoom": {
+			geofences:  []Geofence{dc},
+			uri:        "/maps/osm/8/73/97.pbf",
+			statusCode: http.StatusOK,
+		},
+		"outside region": {
+			geofences:  []Geofence{dc},
+			uri:        "/maps/osm/14/4700/6240.pbf",
+			statusCode: http.StatusOK,
+		},
+		"other map": {
+			geofences:  []Geofence{func() Geofence { g := dc; g.Maps = []string{"imagery"}; return g }()},
+			uri:        "/maps/osm/14/4686/6268.pbf",
+			statusCode: http.StatusOK,
+		},
+		"log only": {
+			geofences:  []Geofence{func() Geofence { g := dc; g.Block = false; return g }()},
+			uri:        "/maps/osm/14/4686/6268.pbf",
+			statusCode: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestGeofenceQuery(t *testing.T) {
+	type tcase struct {
+		uri        string
+		statusCode int
+	}
+
+	Geofences = []Geofence{{
+		Name:    "dc",
+		Extent:  geom.Extent{-77.12, 38.80, -76.91, 39.0},
+		MinZoom: 12,
+		Block:   true,
+	}}
+	defer func() { Geofences = nil }()
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			router := httptreemux.New()
+			router.UsingContext().Handler("GET", "/maps/:map_name/query", HandleMapQuery{Atlas: &atlas.Atlas{}})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tc.uri, nil))
+
+			if w.Code != tc.statusCode {
+				t.Errorf("status code, expected %v got %v", tc.statusCode, w.Code)
+			}
+		}
+	}
+
+	// queries which aren't blocked fall through to the unknown map
+	tests := map[string]tcase{
+		"blocked": {
+			uri:        "/maps/osm/query?lon=-77.0&lat=38.9&zoom=14",
+			statusCode: http.StatusForbidden,
+		},
+		"radius into region": {
+			uri:        "/maps/osm/query?lon=-77.121&lat=38.9&zoom=14&radius=50",
+			statusCode: http.StatusForbidden,
+		},
+		"below min zoom": {
+			uri:        "/maps/osm/query?lon=-77.0&lat=38.9&zoom=8",
+			statusCode: http.StatusNotFound,
+		},
+		"outside region": {
+			uri:        "/maps/osm/query?lon=-76.6&lat=39.3&zoom=14",
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
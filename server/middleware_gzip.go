@@ -3,55 +3,181 @@ package server
 import (
 	"bytes"
 	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/go-spatial/tegola/internal/brotli"
+	"github.com/go-spatial/tegola/internal/ttlcache"
+)
+
+// the content codings of the tiles
+const (
+	encodingBrotli   = "br"
+	encodingGzip     = "gzip"
+	encodingIdentity = "identity"
+)
+
+// ContentEncodingCacheSize is the maximum number of tiles kept in memory encoded with a
+// content coding other than gzip, so they are only encoded once. 0 disables it.
+// configurable via the tegola config.toml file (set in main.go)
+var ContentEncodingCacheSize uint = 1000
+
+// contentEncoding compresses responses for a content coding other than gzip
+type contentEncoding struct {
+	name      string
+	newWriter func(w io.Writer) io.WriteCloser
+	// wildcard is set for the codings served to clients accepting "*"
+	wildcard bool
+}
+
+var (
+	contentEncodingsMu sync.RWMutex
+	// contentEncodings are the codings tiles can be transcoded to, in order of preference.
+	// gzip is served as cached and has no writer.
+	contentEncodings = []contentEncoding{
+		{name: encodingBrotli, newWriter: func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }},
+		{name: encodingGzip, wildcard: true},
+		{name: "deflate", newWriter: func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }, wildcard: true},
+	}
 )
 
-// GZipHandler is responsible for determining if the incoming request should be served gzipped data.
-// All response data is assumed to be compressed prior to being passed to this handler.
+// encodedTileKey is the key of a tile encoded with a content coding, the tiles are keyed by
+// the hash of their gzip compressed data
+type encodedTileKey struct {
+	encoding string
+	sum      [sha256.Size]byte
+}
+
+var (
+	encodedTilesOnce sync.Once
+	encodedTilesLRU  *ttlcache.Cache
+)
+
+// encodedTiles returns the cache of the encoded tiles, or nil when it's disabled
+func encodedTiles() *ttlcache.Cache {
+	encodedTilesOnce.Do(func() {
+		if ContentEncodingCacheSize > 0 {
+			encodedTilesLRU = ttlcache.New(int(ContentEncodingCacheSize), 0)
+		}
+	})
+	return encodedTilesLRU
+}
+
+// RegisterContentEncoding registers a content coding, i.e. "zstd", the tiles are served with
+// to clients accepting it. Tiles are cached gzip compressed and transcoded for the coding.
+// Registered codings are preferred over the built in codings when a client accepts them with
+// the same quality, and are not served to clients only accepting them as "*".
+func RegisterContentEncoding(name string, newWriter func(w io.Writer) io.WriteCloser) error {
+	name = strings.ToLower(name)
+
+	contentEncodingsMu.Lock()
+	defer contentEncodingsMu.Unlock()
+
+	if name == encodingIdentity {
+		return fmt.Errorf("server: content encoding (%v) can't be registered", name)
+	}
+	for _, enc := range contentEncodings {
+		if enc.name == name {
+			return fmt.Errorf("server: content encoding (%v) already registered", name)
+		}
+	}
+	contentEncodings = append([]contentEncoding{{name: name, newWriter: newWriter}}, contentEncodings...)
+	return nil
+}
+
+// negotiateEncoding returns the content coding of the response for the Accept-Encoding
+// request header: the coding accepted with the highest quality, then the most preferred.
+// Without an acceptable coding the response is not encoded.
+func negotiateEncoding(acceptEncoding string) contentEncoding {
+	identity := contentEncoding{name: encodingIdentity}
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return identity
+	}
+
+	qualities := map[string]float64{}
+	for _, v := range strings.Split(acceptEncoding, ",") {
+		parts := strings.Split(v, ";")
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if pq, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = pq
+			}
+		}
+		qualities[name] = q
+	}
+
+	quality := func(enc contentEncoding) float64 {
+		if q, ok := qualities[enc.name]; ok {
+			return q
+		}
+		if q, ok := qualities["*"]; ok && enc.wildcard {
+			return q
+		}
+		return 0
+	}
+
+	contentEncodingsMu.RLock()
+	defer contentEncodingsMu.RUnlock()
+
+	best, bestQ := identity, 0.0
+	for _, enc := range contentEncodings {
+		if q := quality(enc); q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// GZipHandler is responsible for serving the tiles with the content coding the request accepts.
+// All response data is assumed to be gzip compressed prior to being passed to this handler.
 //
 // If the incoming request has the "Accept-Encoding" header set with the values of "gzip" or "*"
 // the response header "Content-Encoding: gzip" is set and the compressed data is returned.
+// Requests accepting "br", a registered coding (see RegisterContentEncoding) or "deflate" with
+// the same or a higher quality are served the response transcoded to that coding, brotli
+// being preferred. The transcoded tiles are kept in memory, up to ContentEncodingCacheSize.
 //
 // If no "Accept-Encoding" header is present or "Accept-Encoding" has a value of "gzip;q=0" or
 // "*;q=0" the response is decompressed prior to being sent to the client.
 func GZipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// caches in front of tegola have to keep the encodings of the tiles apart
+		w.Header().Add("Vary", "Accept-Encoding")
 
-		acceptEncoding := r.Header.Get("Accept-Encoding")
-		if acceptEncoding == "" {
-			// decompress
-			next.ServeHTTP(&gzipDecompressResponseWriter{resp: w}, r)
-			return
-		}
-
-		decompress := false
-		for _, v := range strings.Split(acceptEncoding, ",") {
-			if (strings.Contains(v, "gzip") || strings.Contains(v, "*")) && strings.HasSuffix(v, ";q=0") {
-				decompress = true
-			}
-		}
+		enc := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if enc.name == encodingGzip {
+			// set appropriate header
+			w.Header().Set("Content-Encoding", encodingGzip)
 
-		if decompress {
-			next.ServeHTTP(&gzipDecompressResponseWriter{resp: w}, r)
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		// set appropriate header
-		w.Header().Set("Content-Encoding", "gzip")
-
-		next.ServeHTTP(w, r)
-		return
+		next.ServeHTTP(&gzipDecompressResponseWriter{resp: w, encoding: enc}, r)
 	})
 }
 
-// gzipDecompressResponseWriter is responsible for decompressing responses
-// when the http status code == 200.
+// gzipDecompressResponseWriter is responsible for decompressing responses, and encoding them
+// with the negotiated coding, when the http status code == 200.
 type gzipDecompressResponseWriter struct {
-	status int
-	resp   http.ResponseWriter
+	status   int
+	resp     http.ResponseWriter
+	encoding contentEncoding
 }
 
 func (w *gzipDecompressResponseWriter) Header() http.Header {
@@ -59,29 +185,75 @@ func (w *gzipDecompressResponseWriter) Header() http.Header {
 }
 
 func (w *gzipDecompressResponseWriter) Write(b []byte) (int, error) {
+	// writing without a status, i.e. cached tiles, is an OK response
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
 	//	check that we have an OK response, if not, don't process the body
 	if w.status != http.StatusOK {
 		return w.resp.Write(b)
 	}
 
-	//	setup new gzip reader
-	r, err := gzip.NewReader(bytes.NewReader(b))
+	if w.encoding.newWriter == nil {
+		//	setup new gzip reader
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+
+		if _, err = io.Copy(w.resp, r); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	encoded, err := encodeTile(w.encoding, b)
 	if err != nil {
 		return 0, err
 	}
-	defer r.Close()
+	if _, err = w.resp.Write(encoded); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// encodeTile returns the gzip compressed tile encoded with the content coding, from the
+// encoded tiles cache when it was encoded before
+func encodeTile(enc contentEncoding, b []byte) ([]byte, error) {
+	key := encodedTileKey{encoding: enc.name, sum: sha256.Sum256(b)}
+	if encoded, ok := encodedTiles().Get(key); ok {
+		return encoded.([]byte), nil
+	}
 
-	var respSize int64
-	respSize, err = io.Copy(w.resp, r)
+	r, err := gzip.NewReader(bytes.NewReader(b))
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	ew := enc.newWriter(&buf)
+	if _, err = io.Copy(ew, r); err != nil {
+		return nil, err
 	}
-	w.resp.Header().Set("Content-Length", fmt.Sprintf("%d", respSize))
-	return int(respSize), nil
+	if err = ew.Close(); err != nil {
+		return nil, err
+	}
+
+	encodedTiles().Set(key, buf.Bytes())
+	return buf.Bytes(), nil
 }
 
 func (w *gzipDecompressResponseWriter) WriteHeader(i int) {
-	w.resp.Header().Del("Content-Length")
 	w.status = i
+	if i == http.StatusOK {
+		// the length of the compressed response
+		w.resp.Header().Del("Content-Length")
+		if w.encoding.newWriter != nil {
+			w.resp.Header().Set("Content-Encoding", w.encoding.name)
+		}
+	}
 	w.resp.WriteHeader(i)
 }
@@ -1,11 +1,19 @@
 package server_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
 	"testing"
 
 	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/internal/brotli"
 	"github.com/go-spatial/tegola/server"
 )
 
@@ -134,3 +142,160 @@ func TestMiddlewareGzipHandler(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestMiddlewareGzipHandlerEncodings(t *testing.T) {
+	type tcase struct {
+		acceptEncoding string
+		encoding       string
+	}
+
+	const uri = "/maps/test-map/10/2/3.pbf"
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+			cacher, _ := memory.New(nil)
+			a.SetCache(cacher)
+			router := server.NewRouter(a)
+
+			// the cache miss is encoded as the cache hit
+			var tile []byte
+			for _, cacheStatus := range []string{"MISS", "HIT"} {
+				r, _ := http.NewRequest("GET", uri, nil)
+				if tc.acceptEncoding != "" {
+					r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+
+				if w.Header().Get("Tegola-Cache") != cacheStatus {
+					t.Fatalf("header Tegola-Cache, expected %v got %v", cacheStatus, w.Header().Get("Tegola-Cache"))
+				}
+				if enc := w.Header().Get("Content-Encoding"); enc != tc.encoding {
+					t.Errorf("%v: Content-Encoding, expected %q got %q", cacheStatus, tc.encoding, enc)
+				}
+				if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+					t.Errorf("%v: Vary, expected Accept-Encoding got %q", cacheStatus, vary)
+				}
+				if cl := w.Header().Get("Content-Length"); cl != "" && cl != strconv.Itoa(w.Body.Len()) {
+					t.Errorf("%v: Content-Length, expected %v got %v", cacheStatus, w.Body.Len(), cl)
+				}
+
+				var (
+					body io.Reader = w.Body
+					err  error
+				)
+				switch tc.encoding {
+				case "gzip":
+					body, err = gzip.NewReader(body)
+				case "deflate":
+					body, err = zlib.NewReader(body)
+				case "br":
+					// there's no brotli decoder, the tile must be the encoded decompressed tile
+					if !bytes.Equal(w.Body.Bytes(), brotli.Encode(identityTile(t, router, uri))) {
+						t.Fatalf("%v: expected the brotli encoded tile", cacheStatus)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%v: unable to decode the response: %v", cacheStatus, err)
+				}
+				b, err := ioutil.ReadAll(body)
+				if err != nil {
+					t.Fatalf("%v: unable to decode the response: %v", cacheStatus, err)
+				}
+				if len(b) == 0 || bytes.HasPrefix(b, []byte{0x1f, 0x8b}) {
+					t.Fatalf("%v: expected the decoded tile", cacheStatus)
+				}
+				if tile != nil && !bytes.Equal(b, tile) {
+					t.Errorf("%v: expected the tile of the cache miss", cacheStatus)
+				}
+				tile = b
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"missing":          {},
+		"gzip":             {acceptEncoding: "gzip", encoding: "gzip"},
+		"browser":          {acceptEncoding: "gzip, deflate, br", encoding: "br"},
+		"brotli":           {acceptEncoding: "br", encoding: "br"},
+		"brotli quality":   {acceptEncoding: "br;q=0.5, gzip", encoding: "gzip"},
+		"wildcard":         {acceptEncoding: "*", encoding: "gzip"},
+		"deflate":          {acceptEncoding: "deflate", encoding: "deflate"},
+		"deflate quality":  {acceptEncoding: "gzip;q=0.5, deflate", encoding: "deflate"},
+		"unsupported only": {acceptEncoding: "zstd"},
+		"identity":         {acceptEncoding: "identity, gzip;q=0"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+
+	for _, name := range []string{"gzip", "br"} {
+		if err := server.RegisterContentEncoding(name, nil); err == nil {
+			t.Errorf("registering %v, expected an error", name)
+		}
+	}
+}
+
+// identityTile returns the decompressed tile of the uri
+func identityTile(t *testing.T, router http.Handler, uri string) []byte {
+	r, _ := http.NewRequest("GET", uri, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("expected the decompressed tile, got status %v with Content-Encoding %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+	return w.Body.Bytes()
+}
+
+// countingWriter counts the tiles encoded by the registered test coding
+type countingWriter struct {
+	io.Writer
+	encoded *int32
+}
+
+func (w countingWriter) Close() error {
+	atomic.AddInt32(w.encoded, 1)
+	return nil
+}
+
+func TestMiddlewareGzipHandlerEncodedTilesCache(t *testing.T) {
+	const uri = "/maps/test-map/10/2/3.pbf"
+
+	var encoded int32
+	err := server.RegisterContentEncoding("x-tegola-count", func(w io.Writer) io.WriteCloser {
+		return countingWriter{Writer: w, encoded: &encoded}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := newTestMapWithLayers(testLayer1, testLayer2, testLayer3)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	router := server.NewRouter(a)
+	tile := identityTile(t, router, uri)
+
+	// the tile is encoded once, then served encoded from memory
+	for _, cacheStatus := range []string{"HIT", "HIT"} {
+		r, _ := http.NewRequest("GET", uri, nil)
+		r.Header.Set("Accept-Encoding", "x-tegola-count, gzip")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Header().Get("Tegola-Cache") != cacheStatus {
+			t.Fatalf("header Tegola-Cache, expected %v got %v", cacheStatus, w.Header().Get("Tegola-Cache"))
+		}
+		if enc := w.Header().Get("Content-Encoding"); enc != "x-tegola-count" {
+			t.Errorf("Content-Encoding, expected x-tegola-count got %q", enc)
+		}
+		if !bytes.Equal(w.Body.Bytes(), tile) {
+			t.Errorf("expected the tile")
+		}
+	}
+	if n := atomic.LoadInt32(&encoded); n != 1 {
+		t.Errorf("expected the tile to be encoded once, got %v", n)
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/provider"
+)
+
+// JWTParam is the query parameter tokens are read from when the Authorization header is not
+// set, for map clients which can't set request headers
+const JWTParam = "access_token"
+
+// JWT verifies the tokens of the tile requests. Tile requests are not authenticated when nil.
+// configurable via the tegola config.toml file (set in main.go)
+var JWT *JWTVerifier
+
+// JWTHandler is middleware which requires tile requests to supply a JWT verified by JWT, as a
+// bearer token or the JWTParam query parameter. The claims of the token are added to the
+// request's context for the providers, see provider.Claims. Requests of signed urls don't
+// need a token.
+func JWTHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if JWT == nil || signedRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := jwtToken(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tegola"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := JWT.Verify(token)
+		if err != nil {
+			log.Infof("jwt: rejected request (%v): %v", r.URL.Path, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tegola", error="invalid_token"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		log.Debugf("jwt: request (%v) by subject (%v)", r.URL.Path, claims["sub"])
+
+		r = r.WithContext(provider.WithClaims(r.Context(), claims))
+		if JWT.Private {
+			w.Header().Set("Cache-Control", "private")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// jwtToken returns the bearer token of the request, or its JWTParam query parameter
+func jwtToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return r.URL.Query().Get(JWTParam)
+}
+
+// jwtAdmin reports if the request supplies a JWT authorized on the admin endpoints
+func jwtAdmin(r *http.Request) bool {
+	if JWT == nil || JWT.AdminScope == "" {
+		return false
+	}
+
+	token := jwtToken(r)
+	if token == "" {
+		return false
+	}
+	claims, err := JWT.Verify(token)
+	if err != nil {
+		log.Infof("jwt: rejected admin request (%v): %v", r.URL.Path, err)
+		return false
+	}
+	return HasScope(claims, JWT.AdminScope)
+}
+
+// privateTile reports if the tile of the request depends on the claims of its token, so it
+// must not be shared with other requests
+func privateTile(r *http.Request) bool {
+	if JWT == nil || !JWT.Private {
+		return false
+	}
+	_, ok := provider.Claims(r.Context())
+	return ok
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+)
+
+// NegativeCacheHeader reports a tile response served from the negative cache, HIT-EMPTY
+// for an empty tile and HIT-ERROR for a failed request
+const NegativeCacheHeader = "Tegola-Negative-Cache"
+
+// the largest error body kept by the negative cache
+const negativeCacheMaxErrorBody = 4096
+
+var (
+	// NegativeCacheEmptyTTL is how long tiles without features are cached in memory, so
+	// repeated requests for tiles over empty areas don't reach the providers. 0 disables it.
+	// configurable via the tegola config.toml file (set in main.go)
+	NegativeCacheEmptyTTL time.Duration
+
+	// NegativeCacheErrorTTL is how long failed tile requests, and tiles missing the optional
+	// layers which failed, are cached in memory, so repeated requests for tiles of failing
+	// layers don't hammer their providers. 0 disables it.
+	// configurable via the tegola config.toml file (set in main.go)
+	NegativeCacheErrorTTL time.Duration
+
+	// NegativeCacheSize is the maximum number of negative results cached.
+	// configurable via the tegola config.toml file (set in main.go)
+	NegativeCacheSize uint = 10000
+)
+
+// the kinds of negative results
+const (
+	negativeEmpty = "EMPTY"
+	negativeError = "ERROR"
+)
+
+type negativeResult struct {
+	key     cache.Key
+	kind    string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// NegativeCacheStats are the counts of the negative cache
+type NegativeCacheStats struct {
+	Entries int `json:"entries"`
+	// EmptyHits and ErrorHits are the requests served from cached empty tiles and errors
+	EmptyHits uint64 `json:"empty_hits"`
+	ErrorHits uint64 `json:"error_hits"`
+	// EmptyStored and ErrorStored are the empty tiles and errors cached
+	EmptyStored uint64 `json:"empty_stored"`
+	ErrorStored uint64 `json:"error_stored"`
+}
+
+// negativeCache keeps the most recent negative results of the tile requests until they expire
+type negativeCache struct {
+	mu      sync.Mutex
+	ll      *list.List
+	results map[cache.Key]*list.Element
+
+	emptyHits, errorHits     uint64
+	emptyStored, errorStored uint64
+}
+
+// tileNegativeCache is the negative cache of the tile endpoints
+var tileNegativeCache = newNegativeCache()
+
+func newNegativeCache() *negativeCache {
+	return &negativeCache{
+		ll:      list.New(),
+		results: map[cache.Key]*list.Element{},
+	}
+}
+
+func (nc *negativeCache) get(key cache.Key, now time.Time) (*negativeResult, bool) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	el, ok := nc.results[key]
+	if !ok {
+		return nil, false
+	}
+	res := el.Value.(*negativeResult)
+	if !now.Before(res.expires) {
+		nc.ll.Remove(el)
+		delete(nc.results, key)
+		return nil, false
+	}
+
+	switch res.kind {
+	case negativeEmpty:
+		atomic.AddUint64(&nc.emptyHits, 1)
+	case negativeError:
+		atomic.AddUint64(&nc.errorHits, 1)
+	}
+	return res, true
+}
+
+func (nc *negativeCache) set(res *negativeResult) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if el, ok := nc.results[res.key]; ok {
+		nc.ll.Remove(el)
+	}
+	nc.results[res.key] = nc.ll.PushFront(res)
+
+	for uint(nc.ll.Len()) > NegativeCacheSize {
+		el := nc.ll.Back()
+		nc.ll.Remove(el)
+		delete(nc.results, el.Value.(*negativeResult).key)
+	}
+
+	switch res.kind {
+	case negativeEmpty:
+		atomic.AddUint64(&nc.emptyStored, 1)
+	case negativeError:
+		atomic.AddUint64(&nc.errorStored, 1)
+	}
+}
+
+// purge removes the negative result of the tile
+func (nc *negativeCache) purge(key cache.Key) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if el, ok := nc.results[key]; ok {
+		nc.ll.Remove(el)
+		delete(nc.results, key)
+	}
+}
+
+func (nc *negativeCache) stats() NegativeCacheStats {
+	nc.mu.Lock()
+	entries := nc.ll.Len()
+	nc.mu.Unlock()
+
+	return NegativeCacheStats{
+		Entries:     entries,
+		EmptyHits:   atomic.LoadUint64(&nc.emptyHits),
+		ErrorHits:   atomic.LoadUint64(&nc.errorHits),
+		EmptyStored: atomic.LoadUint64(&nc.emptyStored),
+		ErrorStored: atomic.LoadUint64(&nc.errorStored),
+	}
+}
+
+// HandleAdminNegativeCache reports the entries and the hits of the negative cache
+//
+// 	GET /admin/negative_cache
+type HandleAdminNegativeCache struct{}
+
+func (req HandleAdminNegativeCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, tileNegativeCache.stats())
+}
+
+// NegativeCacheHandler serves the tiles without features and the failed tile requests
+// cached in memory, for NegativeCacheEmptyTTL and NegativeCacheErrorTTL, for requests of
+// the URLs with a /:z/:x/:y scheme suffix (i.e. /osm/1/3/4.pbf). Empty tiles are not written
+// to the cache backend, so the negative cache is checked before the tile cache.
+func NegativeCacheHandler(a *atlas.Atlas, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// debug tiles are never empty
+		// and the tiles of private requests aren't shared
+		if (NegativeCacheEmptyTTL == 0 && NegativeCacheErrorTTL == 0) || r.URL.Query().Get("debug") == "true" || privateTile(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := tileCacheKey(a, r.URL.Path)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// requests bypassing the cache render the tile again, replacing the cached result
+		if cacheBypassed(r) {
+			tileNegativeCache.purge(*key)
+		} else if res, ok := tileNegativeCache.get(*key, time.Now()); ok {
+			for k, v := range res.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set(NegativeCacheHeader, "HIT-"+res.kind)
+			w.WriteHeader(res.status)
+			w.Write(res.body)
+			return
+		}
+
+		// count the features encoded by the tile handler
+		r = r.WithContext(atlas.WithFeatureCount(r.Context()))
+		nw := &negativeCacheResponseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(nw, r)
+
+		// check if our request context has been canceled
+		if r.Context().Err() != nil {
+			return
+		}
+
+		now := time.Now()
+		res := negativeResult{
+			key:    *key,
+			status: nw.status,
+			header: w.Header().Clone(),
+		}
+
+		n, counted := atlas.FeatureCount(r.Context())
+		switch {
+		case nw.status == http.StatusServiceUnavailable:
+			// overloaded, see MaxInFlightHandler
+			return
+
+		case nw.status >= http.StatusInternalServerError, nw.status == http.StatusOK && w.Header().Get(OmittedLayersHeader) != "":
+			if NegativeCacheErrorTTL == 0 {
+				return
+			}
+			res.kind = negativeError
+			res.expires = now.Add(NegativeCacheErrorTTL)
+
+		case nw.status == http.StatusOK && counted && n == 0:
+			if NegativeCacheEmptyTTL == 0 {
+				return
+			}
+			res.kind = negativeEmpty
+			res.expires = now.Add(NegativeCacheEmptyTTL)
+			// the tile must not be reused once its map's availability changes
+			if expires, err := http.ParseTime(w.Header().Get("Expires")); err == nil && expires.Before(res.expires) {
+				res.expires = expires
+			}
+
+		default:
+			return
+		}
+
+		if nw.truncated || !now.Before(res.expires) {
+			return
+		}
+		res.body = nw.body.Bytes()
+		tileNegativeCache.set(&res)
+	})
+}
+
+// negativeCacheResponseWriter records the status and a copy of the body of a response
+type negativeCacheResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	// truncated is set when the body of an error exceeds negativeCacheMaxErrorBody
+	truncated bool
+}
+
+func (w *negativeCacheResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *negativeCacheResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	// only the bodies of empty tiles and errors are cached
+	if w.status == http.StatusOK || w.body.Len()+len(b) <= negativeCacheMaxErrorBody {
+		w.body.Write(b)
+	} else {
+		w.truncated = true
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,167 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/provider"
+	"github.com/go-spatial/tegola/provider/test"
+	"github.com/go-spatial/tegola/server"
+)
+
+// countingTiler counts the tiles requested from it, returning no features or failing
+type countingTiler struct {
+	test.TileProvider
+	fail  bool
+	calls int32
+}
+
+func (ct *countingTiler) TileFeatures(ctx context.Context, layer string, t provider.Tile, fn func(f *provider.Feature) error) error {
+	atomic.AddInt32(&ct.calls, 1)
+	if ct.fail {
+		return errors.New("provider unavailable")
+	}
+	return nil
+}
+
+func TestMiddlewareNegativeCacheHandler(t *testing.T) {
+	type tcase struct {
+		uri      string
+		fail     bool
+		optional bool
+		emptyTTL time.Duration
+		errorTTL time.Duration
+		// the features of the other layer of the map
+		features bool
+
+		expectedCode  int
+		expectedHit   string
+		expectedCalls int32
+	}
+
+	fn := func(tc tcase) func(t *testing.T) {
+		return func(t *testing.T) {
+			server.URIPrefix = "/"
+			server.NegativeCacheEmptyTTL, server.NegativeCacheErrorTTL = tc.emptyTTL, tc.errorTTL
+			defer func() {
+				server.NegativeCacheEmptyTTL, server.NegativeCacheErrorTTL = 0, 0
+			}()
+
+			tiler := &countingTiler{fail: tc.fail}
+			layers := []atlas.Layer{{
+				Name:            "negative-layer",
+				ProviderLayerID: "negative-layer",
+				MinZoom:         4,
+				MaxZoom:         9,
+				GeomType:        geom.Point{},
+				Provider:        tiler,
+				Optional:        tc.optional,
+			}}
+			if tc.features {
+				layers = append(layers, testLayer1)
+			}
+			a := newTestMapWithLayers(layers...)
+
+			w, router, err := doRequest(a, "GET", tc.uri, nil)
+			if err != nil {
+				t.Fatalf("error making request, expected nil got %v", err)
+			}
+			if w.Code != tc.expectedCode {
+				t.Fatalf("status code, expected %v got %v", tc.expectedCode, w.Code)
+			}
+			if w.Header().Get(server.NegativeCacheHeader) != "" {
+				t.Errorf("header %v of first request, expected none got %v", server.NegativeCacheHeader, w.Header().Get(server.NegativeCacheHeader))
+			}
+
+			// play the request again
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatalf("error making request, expected nil got %v", err)
+			}
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("status code of second request, expected %v got %v", tc.expectedCode, w.Code)
+			}
+			if w.Header().Get(server.NegativeCacheHeader) != tc.expectedHit {
+				t.Errorf("header %v, expected %v got %v", server.NegativeCacheHeader, tc.expectedHit, w.Header().Get(server.NegativeCacheHeader))
+			}
+			if calls := atomic.LoadInt32(&tiler.calls); calls != tc.expectedCalls {
+				t.Errorf("provider calls, expected %v got %v", tc.expectedCalls, calls)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"empty tile": {
+			uri:           "/maps/test-map/5/1/1.pbf",
+			emptyTTL:      time.Minute,
+			expectedCode:  http.StatusOK,
+			expectedHit:   "HIT-EMPTY",
+			expectedCalls: 1,
+		},
+		"empty map layer tile": {
+			uri:           "/maps/test-map/negative-layer/5/1/2.pbf",
+			emptyTTL:      time.Minute,
+			expectedCode:  http.StatusOK,
+			expectedHit:   "HIT-EMPTY",
+			expectedCalls: 1,
+		},
+		"tile with features": {
+			uri:           "/maps/test-map/5/1/3.pbf",
+			emptyTTL:      time.Minute,
+			features:      true,
+			expectedCode:  http.StatusOK,
+			expectedCalls: 2,
+		},
+		"empty tile ttl disabled": {
+			uri:           "/maps/test-map/5/1/4.pbf",
+			errorTTL:      time.Minute,
+			expectedCode:  http.StatusOK,
+			expectedCalls: 2,
+		},
+		"empty tile debug": {
+			uri:           "/maps/test-map/5/1/5.pbf?debug=true",
+			emptyTTL:      time.Minute,
+			expectedCode:  http.StatusOK,
+			expectedCalls: 2,
+		},
+		"required layer failed": {
+			uri:           "/maps/test-map/5/2/1.pbf",
+			fail:          true,
+			errorTTL:      time.Minute,
+			expectedCode:  http.StatusInternalServerError,
+			expectedHit:   "HIT-ERROR",
+			expectedCalls: 1,
+		},
+		"optional layer failed": {
+			uri:           "/maps/test-map/5/2/2.pbf",
+			fail:          true,
+			optional:      true,
+			features:      true,
+			errorTTL:      time.Minute,
+			expectedCode:  http.StatusOK,
+			expectedHit:   "HIT-ERROR",
+			expectedCalls: 1,
+		},
+		"failed ttl disabled": {
+			uri:           "/maps/test-map/5/2/3.pbf",
+			fail:          true,
+			emptyTTL:      time.Minute,
+			expectedCode:  http.StatusInternalServerError,
+			expectedCalls: 2,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
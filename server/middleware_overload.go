@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+var (
+	// RequestTimeout is the deadline of a tile request, passed to the providers through the
+	// request's context. Requests exceeding it are answered with a 504. 0 disables the deadline.
+	// configurable via the tegola config.toml file (set in main.go)
+	RequestTimeout time.Duration
+
+	// MaxInFlightTiles is the number of tiles rendered at once. Tile requests over the limit are
+	// answered with a 503 and a Retry-After header, rather than queueing more queries on the
+	// providers' databases. 0 is unlimited. Tiles served from the cache aren't limited.
+	// configurable via the tegola config.toml file (set in main.go)
+	MaxInFlightTiles int
+
+	// OverloadRetryAfter is sent in the Retry-After header of the requests over MaxInFlightTiles
+	OverloadRetryAfter = time.Second
+
+	// inFlightTiles is the number of tiles being rendered
+	inFlightTiles int64
+)
+
+// RequestTimeoutHandler is middleware which bounds the tile request by RequestTimeout. A 504 is
+// written when the deadline passes before the response is written.
+func RequestTimeoutHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if RequestTimeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
+		defer cancel()
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		if ctx.Err() == context.DeadlineExceeded && !sw.wroteHeader {
+			logAndError(w, http.StatusGatewayTimeout, "tile request (%v) exceeded the request timeout (%v)", r.URL.Path, RequestTimeout)
+		}
+	})
+}
+
+// MaxInFlightHandler is middleware which limits the tiles rendered at once to MaxInFlightTiles
+func MaxInFlightHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if MaxInFlightTiles <= 0 {
+			markRenderStarted(r.Context())
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		defer atomic.AddInt64(&inFlightTiles, -1)
+		if atomic.AddInt64(&inFlightTiles, 1) > int64(MaxInFlightTiles) {
+			log.Warnf("tile request (%v) rejected, %v tiles are being rendered", r.URL.Path, MaxInFlightTiles)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(OverloadRetryAfter.Seconds()))))
+			http.Error(w, "too many tiles are being rendered, retry later", http.StatusServiceUnavailable)
+			return
+		}
+
+		// the request holds a render slot
+		markRenderStarted(r.Context())
+		next.ServeHTTP(w, r)
+	})
+}
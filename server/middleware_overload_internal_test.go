@@ -0,0 +1,93 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeoutHandler(t *testing.T) {
+	RequestTimeout = 10 * time.Millisecond
+	defer func() { RequestTimeout = 0 }()
+
+	type tcase struct {
+		handler      http.HandlerFunc
+		expectedCode int
+	}
+
+	tests := map[string]tcase{
+		"served": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if _, ok := r.Context().Deadline(); !ok {
+					t.Errorf("deadline, expected the request's context to have a deadline")
+				}
+				w.Write([]byte("tile"))
+			},
+			expectedCode: http.StatusOK,
+		},
+		"exceeded": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			},
+			expectedCode: http.StatusGatewayTimeout,
+		},
+		"exceeded after writing": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				<-r.Context().Done()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			RequestTimeoutHandler(tc.handler).ServeHTTP(w, httptest.NewRequest("GET", "/maps/osm/1/0/0.pbf", nil))
+			if w.Code != tc.expectedCode {
+				t.Errorf("status code, expected %v got %v", tc.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestMaxInFlightHandler(t *testing.T) {
+	MaxInFlightTiles = 1
+	defer func() { MaxInFlightTiles = 0 }()
+
+	rendering, release := make(chan struct{}), make(chan struct{})
+	h := MaxInFlightHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(rendering)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/maps/osm/1/0/0.pbf", nil))
+	}()
+	<-rendering
+
+	// over the limit while the first tile is rendered
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/maps/osm/1/0/1.pbf", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code, expected %v got %v", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("header Retry-After, expected 1 got %v", got)
+	}
+
+	close(release)
+	<-done
+
+	// within the limit once it's rendered
+	h = MaxInFlightHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/maps/osm/1/0/1.pbf", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status code after rendering, expected %v got %v", http.StatusOK, w.Code)
+	}
+}
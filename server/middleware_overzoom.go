@@ -0,0 +1,93 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+	"github.com/go-spatial/geom/encoding/mvt"
+	"github.com/go-spatial/geom/slippy"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// OverzoomHeader is set to the tile (z/x/y) a tile above the cache max zoom of its map was extracted from
+const OverzoomHeader = "Tegola-Overzoom"
+
+// OverzoomHandler serves the vector tiles above the CacheMaxZoom of their map from their
+// ancestor at the zoom, which is requested from next, so it's read from or written to the
+// cache like any other tile. The tiles above the zoom are not cached. Debug, raster and
+// UTFGrid tiles, maps without a cache max zoom or of other grids than web mercator and
+// atlases without a cache are served by next, as are the tiles whose ancestor can't be served.
+func OverzoomHandler(a *atlas.Atlas, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := httptreemux.ContextParams(r.Context())
+
+		m, err := a.Map(params["map_name"])
+		if err != nil || m.CacheMaxZoom == nil || a.GetCache() == nil ||
+			r.URL.Query().Get("debug") == "true" || isRasterTile(m, r.URL.Path) || isUTFGridTile(r.URL.Path) || m.ContentType() != mvt.MimeType ||
+			!m.TileGrid().IsWebMercator() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		yParts := strings.SplitN(params["y"], ".", 2)
+		z, x, y, err := parseAdminTile(m.TileGrid(), params["z"], params["x"], yParts[0])
+		if err != nil || z <= *m.CacheMaxZoom {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		dz := z - *m.CacheMaxZoom
+		parent := slippy.NewTile(*m.CacheMaxZoom, x>>dz, y>>dz)
+		pz, px, py := parent.ZXY()
+
+		// the request of the ancestor, keeping the extension of the tile
+		ext := ""
+		if len(yParts) > 1 {
+			ext = "." + yParts[1]
+		}
+		parentParams := map[string]string{}
+		for k, v := range params {
+			parentParams[k] = v
+		}
+		parentParams["z"], parentParams["x"], parentParams["y"] = fmt.Sprint(pz), fmt.Sprint(px), fmt.Sprint(py)+ext
+
+		parentURL := *r.URL
+		parentURL.Path = path.Join(path.Dir(path.Dir(path.Dir(r.URL.Path))), parentParams["z"], parentParams["x"], parentParams["y"])
+		parentURL.RawPath = ""
+
+		pr := r.WithContext(httptreemux.AddParamsToContext(r.Context(), parentParams))
+		pr.URL = &parentURL
+
+		resp := &bufferedResponse{header: w.Header().Clone()}
+		next.ServeHTTP(resp, pr)
+		if r.Context().Err() != nil {
+			return
+		}
+		if resp.status != 0 && resp.status != http.StatusOK {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tile, err := m.OverzoomTile(resp.body.Bytes(), parent, slippy.NewTile(z, x, y))
+		if err != nil {
+			log.Errorf("overzoom middleware: error extracting tile (%v/%v/%v) of map (%v): %v", z, x, y, m.Name, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for k, v := range resp.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(tile)))
+		w.Header().Set(OverzoomHeader, fmt.Sprintf("%v/%v/%v", pz, px, py))
+		setSurrogateKeys(w.Header(), tileSurrogateKeys(m, params["layer_name"], z, x, y))
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(tile)
+	})
+}
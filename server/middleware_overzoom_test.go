@@ -0,0 +1,90 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vectorTile "github.com/go-spatial/geom/encoding/mvt/vector_tile"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestOverzoomHandler(t *testing.T) {
+	type tcase struct {
+		uri string
+		// the ancestor the tile is extracted from, "" for none
+		expectedOverzoom string
+		expectedCache    string
+	}
+
+	maxZoom := uint(12)
+	m := atlas.NewWebMercatorMap(testMapName)
+	m.Layers = append(m.Layers, testLayer2, testLayer3)
+	m.CacheMaxZoom = &maxZoom
+
+	a := &atlas.Atlas{}
+	a.AddMap(m)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	router := server.NewRouter(a)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code, expected %v got %v: %v", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get(server.OverzoomHeader); got != tc.expectedOverzoom {
+				t.Errorf("header %v, expected %q got %q", server.OverzoomHeader, tc.expectedOverzoom, got)
+			}
+			if got := w.Header().Get("Tegola-Cache"); got != tc.expectedCache {
+				t.Errorf("header Tegola-Cache, expected %v got %v", tc.expectedCache, got)
+			}
+
+			var tile vectorTile.Tile
+			if err := proto.Unmarshal(w.Body.Bytes(), &tile); err != nil {
+				t.Errorf("error decoding tile, expected nil got %v", err)
+			}
+		}
+	}
+
+	// run in order, the tiles share their ancestor
+	tests := []struct {
+		name string
+		tcase
+	}{
+		{"below max zoom", tcase{uri: "/maps/test-map/11/1/1.pbf", expectedCache: "MISS"}},
+		{"at max zoom", tcase{uri: "/maps/test-map/12/2/3.pbf", expectedCache: "MISS"}},
+		{"above max zoom", tcase{uri: "/maps/test-map/14/8/12.pbf", expectedOverzoom: "12/2/3", expectedCache: "HIT"}},
+		{"far above max zoom", tcase{uri: "/maps/test-map/16/39/63.pbf", expectedOverzoom: "12/2/3", expectedCache: "HIT"}},
+		// debug tiles are rendered
+		{"debug above max zoom", tcase{uri: "/maps/test-map/14/9/13.pbf?debug=true", expectedCache: "MISS"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, fn(tc.tcase))
+	}
+
+	// the overzoomed tiles are not cached
+	var keys []string
+	cacher.(cache.Lister).ListKeys(func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	for _, key := range keys {
+		if key == "test-map/14/8/12" || key == "test-map/16/39/63" {
+			t.Errorf("cached keys, expected no overzoomed tiles got %v", keys)
+			break
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/ratelimit"
+)
+
+var (
+	// IPRateLimiter limits the tile requests of each client address. The requests are not
+	// limited by address when nil. configurable via the tegola config.toml file (set in main.go)
+	IPRateLimiter *ratelimit.Limiter
+
+	// KeyRateLimiter limits the tile requests of each configured API key. The requests of a key
+	// are limited by address when nil, or when the key is unknown. configurable via the tegola config.toml file (set in main.go)
+	KeyRateLimiter *ratelimit.Limiter
+
+	// RateLimitAllowlist are the networks whose requests are not rate limited.
+	// configurable via the tegola config.toml file (set in main.go)
+	RateLimitAllowlist []*net.IPNet
+
+	// RateLimitAllowlistKeys are the API keys whose requests are not rate limited.
+	// configurable via the tegola config.toml file (set in main.go)
+	RateLimitAllowlistKeys []string
+
+	// RateLimitForwardedFor reads the client address from the last address of the
+	// X-Forwarded-For header, the address appended by the proxy in front of tegola.
+	// configurable via the tegola config.toml file (set in main.go)
+	RateLimitForwardedFor bool
+)
+
+// RateLimitHandler is middleware which limits the rate of the tile requests of each configured
+// API key, or of each client address for the requests without a known key, protecting the providers from
+// scraping and runaway clients. Requests over the rate are rejected with a Retry-After header.
+func RateLimitHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IPRateLimiter == nil && KeyRateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := requestAPIKey(r)
+		if key != "" && contains(RateLimitAllowlistKeys, key) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip := clientIP(r)
+		for _, n := range RateLimitAllowlist {
+			if ip != nil && n.Contains(ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		limiter, client := IPRateLimiter, ip.String()
+		if KeyRateLimiter != nil && configuredAPIKey(key) {
+			limiter, client = KeyRateLimiter, "key:"+key
+		}
+		if limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed, retry := limiter.Allow(client, time.Now()); !allowed {
+			log.Debugf("rate limit exceeded by client (%v): %v", ip, r.URL.Path)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// configuredAPIKey reports if the key is one of the APIKeys or KeyClasses, so unknown keys
+// can't be used to get a fresh bucket
+func configuredAPIKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	if _, ok := KeyClasses[key]; ok {
+		return true
+	}
+	if APIKeys == nil {
+		return false
+	}
+	_, ok := APIKeys.Lookup(key)
+	return ok
+}
+
+// clientIP returns the address of the request's client, or nil when it can't be parsed
+func clientIP(r *http.Request) net.IP {
+	if RateLimitForwardedFor {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			addrs := strings.Split(xff, ",")
+			if ip := net.ParseIP(strings.TrimSpace(addrs[len(addrs)-1])); ip != nil {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,71 @@
+package server_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/internal/ratelimit"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	_, allowed, _ := net.ParseCIDR("10.0.0.0/8")
+
+	server.URIPrefix = "/"
+	server.KeyClasses = map[string]string{"abc": "partner"}
+	server.IPRateLimiter = &ratelimit.Limiter{Rate: 1}
+	server.KeyRateLimiter = &ratelimit.Limiter{Rate: 1, Burst: 2}
+	server.RateLimitAllowlist = []*net.IPNet{allowed}
+	server.RateLimitAllowlistKeys = []string{"internal"}
+	defer func() {
+		server.IPRateLimiter, server.KeyRateLimiter = nil, nil
+		server.RateLimitAllowlist, server.RateLimitAllowlistKeys = nil, nil
+		server.KeyClasses = map[string]string{}
+	}()
+	router := server.NewRouter(newTestMapWithLayers(testLayer1))
+
+	type tcase struct {
+		uri          string
+		remoteAddr   string
+		expectedCode int
+	}
+
+	// the cases run in order, sharing the buckets of the clients
+	tests := []tcase{
+		{uri: "/maps/test-map/5/2/3.pbf", remoteAddr: "192.0.2.1:1234", expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/5/2/3.pbf", remoteAddr: "192.0.2.1:1234", expectedCode: http.StatusTooManyRequests},
+		{uri: "/maps/test-map/5/2/3.pbf", remoteAddr: "192.0.2.2:1234", expectedCode: http.StatusOK},
+		// the requests of a key are limited by the key's bucket
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=abc", remoteAddr: "192.0.2.1:1234", expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=abc", remoteAddr: "192.0.2.3:1234", expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=abc", remoteAddr: "192.0.2.4:1234", expectedCode: http.StatusTooManyRequests},
+		// unknown keys are limited by address
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=unknown", remoteAddr: "192.0.2.1:1234", expectedCode: http.StatusTooManyRequests},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=unknown", remoteAddr: "192.0.2.5:1234", expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=random", remoteAddr: "192.0.2.5:1234", expectedCode: http.StatusTooManyRequests},
+		// the allowlist isn't limited
+		{uri: "/maps/test-map/5/2/3.pbf", remoteAddr: "10.1.2.3:1234", expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/5/2/3.pbf", remoteAddr: "10.1.2.3:1234", expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/5/2/3.pbf?api_key=internal", remoteAddr: "192.0.2.1:1234", expectedCode: http.StatusOK},
+	}
+
+	for i, tc := range tests {
+		r, err := http.NewRequest("GET", tc.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = tc.remoteAddr
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != tc.expectedCode {
+			t.Errorf("%v %v: status code, expected %v got %v: %v", i, tc.uri, tc.expectedCode, w.Code, w.Body.String())
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "1" {
+			t.Errorf("%v %v: header Retry-After, expected 1 got %v", i, tc.uri, w.Header().Get("Retry-After"))
+		}
+	}
+}
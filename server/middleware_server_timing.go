@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-spatial/tegola/internal/servertiming"
+)
+
+// ServerTiming sends the durations of the steps of the tile requests in a Server-Timing header:
+// the query of each layer, the encoding of the tile, the cache read and the total time until the
+// response is written. configurable via the tegola config.toml file (set in main.go)
+var ServerTiming bool
+
+// ServerTimingHandler is middleware which collects the durations of the steps of a tile request
+// and sends them in the Server-Timing header of the response when ServerTiming is set, i.e.
+//
+//	Server-Timing: cache;desc="miss";dur=0.4, layer;desc="roads";dur=12.3, encode;dur=4.1, total;dur=18.2
+//
+// The header is written before the body, so steps after the response is written (i.e. writing
+// the tile to the cache) aren't included.
+func ServerTimingHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ServerTiming {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := servertiming.WithTimings(r.Context())
+		tw := &serverTimingWriter{ResponseWriter: w, ctx: ctx, start: time.Now()}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+	})
+}
+
+// serverTimingWriter sets the Server-Timing header from the durations recorded with its
+// context when the response's header is written
+type serverTimingWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	start       time.Time
+	wroteHeader bool
+}
+
+func (w *serverTimingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		servertiming.Since(w.ctx, "total", "", w.start)
+		w.Header().Set(servertiming.Header, servertiming.Format(servertiming.Metrics(w.ctx)))
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *serverTimingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
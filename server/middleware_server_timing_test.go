@@ -0,0 +1,68 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/tegola/cache/memory"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestServerTimingHandler(t *testing.T) {
+	a := newTestMapWithLayers(testLayer1)
+	cacher, _ := memory.New(nil)
+	a.SetCache(cacher)
+	server.URIPrefix = "/"
+	router := server.NewRouter(a)
+
+	get := func(uri string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status code, expected %v got %v: %v", http.StatusOK, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	// disabled by default
+	if got := get("/maps/test-map/5/2/4.pbf").Header().Get("Server-Timing"); got != "" {
+		t.Errorf("header Server-Timing when disabled, expected none got %v", got)
+	}
+
+	server.ServerTiming = true
+	defer func() { server.ServerTiming = false }()
+
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{
+			name:     "miss",
+			expected: []string{`cache;desc="miss";dur=`, `layer;desc="` + testLayer1.MVTName() + `";dur=`, "encode;dur=", "total;dur="},
+		},
+		{
+			name:     "hit",
+			expected: []string{`cache;desc="hit";dur=`, "total;dur="},
+		},
+	}
+
+	// run in order, the second request is served from the cache
+	for _, tc := range tests {
+		got := get("/maps/test-map/5/2/3.pbf").Header().Get("Server-Timing")
+		metrics := strings.Split(got, ", ")
+		if len(metrics) != len(tc.expected) {
+			t.Fatalf("%v: header Server-Timing, expected %v metrics got %v", tc.name, len(tc.expected), got)
+		}
+		for i, prefix := range tc.expected {
+			if !strings.HasPrefix(metrics[i], prefix) {
+				t.Errorf("%v: metric %v, expected prefix %v got %v", tc.name, i, prefix, metrics[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// SignedURLs verifies the signed tile urls. Signatures are not checked when nil.
+// configurable via the tegola config.toml file (set in main.go)
+var SignedURLs *URLSigner
+
+type signedURLKey struct{}
+
+// SignedURLHandler is middleware which verifies the signature and expiry of signed tile urls.
+// Requests with a valid signature don't need a JWT or API key, so tiles can be shared with
+// clients without persistent credentials. Requests without a signature are passed on to the
+// other authentication, unless signatures are required.
+func SignedURLHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if SignedURLs == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		if query.Get(SignedURLSignatureParam) == "" {
+			if SignedURLs.Required {
+				http.Error(w, "missing url signature", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		params := httptreemux.ContextParams(r.Context())
+		if err := SignedURLs.Verify(query, params["map_name"], params["layer_name"], time.Now()); err != nil {
+			log.Infof("signed url: rejected request (%v): %v", r.URL.Path, err)
+			http.Error(w, "invalid or expired url signature", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), signedURLKey{}, true)))
+	})
+}
+
+// signedRequest reports if the request's url signature was verified
+func signedRequest(r *http.Request) bool {
+	signed, _ := r.Context().Value(signedURLKey{}).(bool)
+	return signed
+}
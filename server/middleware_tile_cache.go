@@ -7,11 +7,14 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/go-spatial/geom/encoding/mvt"
 	"github.com/go-spatial/tegola/atlas"
 	"github.com/go-spatial/tegola/cache"
 	"github.com/go-spatial/tegola/internal/log"
+	"github.com/go-spatial/tegola/internal/servertiming"
+	"github.com/go-spatial/tegola/internal/tracing"
 )
 
 // TileCacheHandler implements a request cache for tiles on requests when the URLs
@@ -22,30 +25,62 @@ func TileCacheHandler(a *atlas.Atlas, next http.Handler) http.Handler {
 
 		// check if a cache backend exists
 		cacher := a.GetCache()
-		if cacher == nil {
+		// the tiles of private requests depend on their claims and aren't cached
+		if cacher == nil || privateTile(r) {
 			// nope. move on
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		// parse our URI into a cache key structure (remove any configured URIPrefix + "maps/" )
-		key, err := cache.ParseKey(strings.TrimPrefix(r.URL.Path, path.Join(URIPrefix, "maps")))
+		key, err := tileCacheKey(a, r.URL.Path)
 		if err != nil {
 			log.Errorf("cache middleware: ParseKey err: %v", err)
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		// maps may not be cached when their own cache backends are configured
+		if cache.MapBackend(cacher, key.MapName) == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// authorized requests can skip reading the cache, the fresh tile is written back to it
+		bypass := cacheBypassed(r)
+
 		// use the URL path as the key
-		cachedTile, hit, err := cacher.Get(key)
+		start := time.Now()
+		var cachedTile []byte
+		var hit bool
+		if !bypass {
+			_, span := tracing.Start(r.Context(), "cache.get")
+			cachedTile, hit, err = cacher.Get(key)
+			span.SetAttribute("tegola.cache_hit", hit)
+			span.SetError(err)
+			span.Finish()
+		}
+		latency := time.Since(start)
+		if !bypass {
+			servertiming.Record(r.Context(), "cache", cacheTimingDesc(hit, err), latency)
+		}
 		if err != nil {
+			tileCacheStats.error(a, key.MapName)
 			log.Errorf("cache middleware: error reading from cache: %v", err)
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		setCacheTierHeaders(w.Header(), cacher, key.MapName)
+
 		// cache miss
 		if !hit {
+			if bypass {
+				w.Header().Set("Tegola-Cache", "BYPASS")
+			} else {
+				tileCacheStats.miss(a, key.MapName, latency)
+			}
+
 			// buffer which will hold a copy of the response for writing to the cache
 			var buff bytes.Buffer
 
@@ -64,14 +99,43 @@ func TileCacheHandler(a *atlas.Atlas, next http.Handler) http.Handler {
 				return
 			}
 
-			if err := cacher.Set(key, buff.Bytes()); err != nil {
+			// bound the cache entry by the expiry of the tile's features
+			expires, _ := http.ParseTime(w.Header().Get("Expires"))
+
+			_, span := tracing.Start(r.Context(), "cache.set")
+			err := cache.SetExpires(cacher, key, buff.Bytes(), expires)
+			span.SetAttribute("tegola.bytes", buff.Len())
+			span.SetError(err)
+			span.Finish()
+			if err != nil {
+				tileCacheStats.error(a, key.MapName)
 				log.Warnf("cache response writer err: %v", err)
+				return
+			}
+			tileCacheStats.fill(a, key.MapName, time.Since(start), buff.Len())
+
+			// index the cached tile so it can be purged by surrogate key
+			if sk := w.Header().Get(SurrogateKeyHeader); sk != "" {
+				tileSurrogateIndex.add(*key, parseSurrogateKeys(sk))
 			}
 			return
 		}
 
-		// mimetype for mapbox vector tiles
-		w.Header().Add("Content-Type", mvt.MimeType)
+		tileCacheStats.hit(a, key.MapName, latency, len(cachedTile))
+
+		// mimetype for mapbox vector tiles, or the upstream tiles' content type
+		contentType := mvt.MimeType
+		if m, err := a.Map(key.MapName); err == nil {
+			contentType = m.ContentType()
+			switch {
+			case key.Format == atlas.UTFGridFormat:
+				contentType = utfGridContentType
+			case key.Format != "":
+				contentType = m.Raster.ContentType()
+			}
+			setSurrogateKeys(w.Header(), tileSurrogateKeys(m, key.LayerName, key.Z, key.X, key.Y))
+		}
+		w.Header().Add("Content-Type", contentType)
 
 		// communicate the cache is being used
 		w.Header().Add("Tegola-Cache", "HIT")
@@ -82,6 +146,69 @@ func TileCacheHandler(a *atlas.Atlas, next http.Handler) http.Handler {
 	})
 }
 
+// cacheTimingDesc describes the outcome of a cache read in the Server-Timing header
+func cacheTimingDesc(hit bool, err error) string {
+	switch {
+	case err != nil:
+		return "error"
+	case hit:
+		return "hit"
+	default:
+		return "miss"
+	}
+}
+
+// tileCacheKey parses the path of a tile url into a cache key. The keys of a map's raster
+// and UTFGrid tiles include their format, so they don't collide with the map's vector tiles.
+func tileCacheKey(a *atlas.Atlas, urlPath string) (*cache.Key, error) {
+	key, err := cache.ParseKey(strings.TrimPrefix(urlPath, path.Join(URIPrefix, "maps")))
+	if err != nil {
+		return nil, err
+	}
+
+	if key.LayerName == "" {
+		if isUTFGridTile(urlPath) {
+			key.Format = atlas.UTFGridFormat
+		} else if m, err := a.Map(key.MapName); err == nil && isRasterTile(m, urlPath) {
+			key.Format = m.Raster.Format()
+		}
+	}
+	return key, nil
+}
+
+// isRasterTile reports if the extension of the tile url is the format of the map's raster
+func isRasterTile(m atlas.Map, urlPath string) bool {
+	if !m.HasRaster() {
+		return false
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(urlPath), "."))
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	return ext == m.Raster.Format()
+}
+
+// isUTFGridTile reports if the tile url is of a UTFGrid interactivity tile
+func isUTFGridTile(urlPath string) bool {
+	return strings.HasSuffix(strings.ToLower(urlPath), "."+atlas.UTFGridFormat)
+}
+
+// setCacheTierHeaders reports the cache backend and namespace the tile of the map is served
+// from or written to
+func setCacheTierHeaders(h http.Header, cacher cache.Interface, mapName string) {
+	tier := CacheTier
+	if t, ok := MapCacheTiers[mapName]; ok {
+		tier = t
+	}
+	if tier != "" {
+		h.Set(CacheTierHeader, tier)
+	}
+	if ns := cache.NamespaceOf(cache.MapBackend(cacher, mapName)); ns != "" {
+		h.Set(CacheNamespaceHeader, ns)
+	}
+}
+
 func newTileCacheResponseWriter(resp http.ResponseWriter, w io.Writer) http.ResponseWriter {
 	return &tileCacheResponseWriter{
 		resp:  resp,
@@ -99,8 +226,10 @@ type tileCacheResponseWriter struct {
 }
 
 func (w *tileCacheResponseWriter) Header() http.Header {
-	// communicate the cache is being used
-	w.resp.Header().Set("Tegola-Cache", "MISS")
+	// communicate the cache is being used, unless it was bypassed
+	if w.resp.Header().Get("Tegola-Cache") == "" {
+		w.resp.Header().Set("Tegola-Cache", "MISS")
+	}
 
 	return w.resp.Header()
 }
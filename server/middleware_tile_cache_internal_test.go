@@ -6,6 +6,9 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/cache/memory"
 )
 
 func TestTileCacheResponseWriter(t *testing.T) {
@@ -56,3 +59,36 @@ func TestTileCacheResponseWriter(t *testing.T) {
 		t.Run(name, fn(tc))
 	}
 }
+
+func TestSetCacheTierHeaders(t *testing.T) {
+	mc, _ := memory.New(nil)
+	ns, err := cache.NewNamespace(mc, "eu-west-1")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	defer func(tier string) { CacheTier = tier }(CacheTier)
+	CacheTier = "redis"
+
+	h := http.Header{}
+	setCacheTierHeaders(h, ns, "osm")
+	if h.Get(CacheTierHeader) != "redis" || h.Get(CacheNamespaceHeader) != "eu-west-1" {
+		t.Errorf("unexpected headers %v", h)
+	}
+
+	h = http.Header{}
+	setCacheTierHeaders(h, mc, "osm")
+	if _, ok := h[CacheNamespaceHeader]; ok {
+		t.Errorf("expected no namespace header, got %v", h)
+	}
+
+	// the tier and namespace of a map with its own cache backend
+	defer func(tiers map[string]string) { MapCacheTiers = tiers }(MapCacheTiers)
+	MapCacheTiers = map[string]string{"live": "memory"}
+
+	h = http.Header{}
+	setCacheTierHeaders(h, cache.NewByMap(mc, map[string]cache.Interface{"live": ns}), "live")
+	if h.Get(CacheTierHeader) != "memory" || h.Get(CacheNamespaceHeader) != "eu-west-1" {
+		t.Errorf("unexpected map headers %v", h)
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/mapbox/tilejson"
+)
+
+// TileSchemeParam is the query parameter selecting the order of the y axis of the tile
+// coordinates: xyz (default), north to south, or tms, south to north
+const TileSchemeParam = "scheme"
+
+// TMSHandler is middleware which serves the tiles requested with ?scheme=tms, whose y axis goes
+// from south to north, as the XYZ tiles of the same map, so both schemes share the cached tiles.
+// The rows are flipped within the tile matrix of the map's grid. Tile coordinates which can't
+// be flipped are passed on as requested, for the tile handler to reject.
+func TMSHandler(a *atlas.Atlas, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch scheme := r.URL.Query().Get(TileSchemeParam); scheme {
+		case "", tilejson.SchemeXYZ:
+			next.ServeHTTP(w, r)
+			return
+		case tilejson.SchemeTMS:
+		default:
+			http.Error(w, fmt.Sprintf("invalid scheme (%v), expected %v or %v", scheme, tilejson.SchemeXYZ, tilejson.SchemeTMS), http.StatusBadRequest)
+			return
+		}
+
+		params := httptreemux.ContextParams(r.Context())
+		grid := atlas.WebMercatorGrid
+		if m, err := a.Map(params["map_name"]); err == nil {
+			grid = m.TileGrid()
+		}
+		y, ok := flipTileY(grid, params["z"], params["y"])
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		flipped := make(map[string]string, len(params))
+		for k, v := range params {
+			flipped[k] = v
+		}
+		flipped["y"] = y
+
+		tileReq := r.WithContext(httptreemux.AddParamsToContext(r.Context(), flipped))
+		u := *r.URL
+		u.Path = path.Join(path.Dir(r.URL.Path), y)
+		tileReq.URL = &u
+		next.ServeHTTP(w, tileReq)
+	})
+}
+
+// flipTileY flips the y (with its extension, i.e. 3.pbf or 3.grid.json) of a tile of the grid
+// between the XYZ and TMS orders. ok is false for coordinates outside of the zoom.
+func flipTileY(grid atlas.Grid, z, y string) (flipped string, ok bool) {
+	var ext string
+	if i := strings.Index(y, "."); i >= 0 {
+		ext = y[i:]
+	}
+	zoom, err := strconv.ParseUint(z, 10, 32)
+	if err != nil || zoom > uint64(grid.MaxZoom()) {
+		return "", false
+	}
+	_, rows := grid.MatrixSize(uint(zoom))
+	row, err := strconv.ParseUint(strings.TrimSuffix(y, ext), 10, 64)
+	if err != nil || row >= uint64(rows) {
+		return "", false
+	}
+	return strconv.FormatUint(uint64(rows)-1-row, 10) + ext, true
+}
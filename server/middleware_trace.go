@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/internal/tracing"
+)
+
+// TraceHandler is middleware which records the server span of a tile request, continuing the
+// trace of the request's traceparent header. The spans of the atlas, providers and cache are
+// its children. Nothing is recorded when tracing is disabled.
+func TraceHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tracing.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		params := httptreemux.ContextParams(r.Context())
+		route := "/maps/:map_name/:z/:x/:y"
+		if params["layer_name"] != "" {
+			route = "/maps/:map_name/:layer_name/:z/:x/:y"
+		}
+
+		ctx, span := tracing.StartRequest(r, r.Method+" "+route)
+		defer span.Finish()
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("url.path", r.URL.Path)
+		span.SetAttribute("tegola.map", params["map_name"])
+		if params["layer_name"] != "" {
+			span.SetAttribute("tegola.layer", params["layer_name"])
+		}
+		span.SetAttribute("tegola.tile", params["z"]+"/"+params["x"]+"/"+strings.Split(params["y"], ".")[0])
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttribute("http.response.status_code", sw.status)
+		span.SetAttribute("http.response.body.size", sw.bytes)
+		if c := w.Header().Get("Tegola-Cache"); c != "" {
+			span.SetAttribute("tegola.cache", c)
+		}
+		if sw.status >= http.StatusInternalServerError {
+			span.SetError(httpError(sw.status))
+		}
+	})
+}
+
+// httpError is the error of a failed response, recorded on its span
+type httpError int
+
+func (e httpError) Error() string { return http.StatusText(int(e)) }
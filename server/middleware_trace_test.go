@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-spatial/tegola/internal/tracing"
+	"github.com/go-spatial/tegola/server"
+)
+
+// spanRecorder is an exporter recording the names of the exported spans and their parents
+type spanRecorder struct {
+	sync.Mutex
+	spans map[string]*tracing.Span
+}
+
+func (r *spanRecorder) Export(ctx context.Context, serviceName string, spans []*tracing.Span) error {
+	r.Lock()
+	defer r.Unlock()
+	for _, s := range spans {
+		r.spans[s.Name] = s
+	}
+	return nil
+}
+
+func TestTraceHandler(t *testing.T) {
+	rec := spanRecorder{spans: map[string]*tracing.Span{}}
+	tracer := tracing.NewTracer("tegola", 1, &rec)
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	server.URIPrefix = "/"
+	router := server.NewRouter(newTestMapWithLayers(testLayer1))
+
+	r, err := http.NewRequest("GET", "/maps/test-map/5/2/3.pbf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code, expected %v got %v", http.StatusOK, w.Code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tracer.Run(ctx)
+
+	root, ok := rec.spans["GET /maps/:map_name/:z/:x/:y"]
+	if !ok {
+		t.Fatalf("expected the request span, got %v", rec.spans)
+	}
+	if root.Attributes["tegola.tile"] != "5/2/3" || root.Attributes["http.response.status_code"] != http.StatusOK {
+		t.Errorf("unexpected request span attributes %v", root.Attributes)
+	}
+
+	parents := map[string]string{
+		"atlas.encode":           "GET /maps/:map_name/:z/:x/:y",
+		"provider.tile_features": "atlas.encode",
+		"mvt.encode":             "atlas.encode",
+	}
+	for name, parent := range parents {
+		s, ok := rec.spans[name]
+		if !ok {
+			t.Errorf("expected the %v span", name)
+			continue
+		}
+		if s.TraceID != root.TraceID || s.ParentID != rec.spans[parent].SpanID {
+			t.Errorf("expected the %v span to be a child of %v", name, parent)
+		}
+	}
+}
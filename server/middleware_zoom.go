@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+// ZoomHandler is middleware which rejects the requests for the tiles of a map outside of the
+// map's zooms (see atlas.Map.Zooms) with a 400, before they reach the cache or the providers.
+// The requests of unknown maps and invalid tiles are served by next, which responds to them.
+func ZoomHandler(a *atlas.Atlas, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := httptreemux.ContextParams(r.Context())
+
+		m, err := a.Map(params["map_name"])
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		yParts := strings.SplitN(params["y"], ".", 2)
+		z, _, _, err := parseAdminTile(m.TileGrid(), params["z"], params["x"], yParts[0])
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		minZoom, maxZoom := m.Zooms()
+		if z < minZoom || z > maxZoom {
+			logAndError(w, http.StatusBadRequest, "map (%v) serves zooms %v to %v, not zoom %v", m.Name, minZoom, maxZoom, z)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,77 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/mapbox/tilejson"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestZoomHandler(t *testing.T) {
+	type tcase struct {
+		uri          string
+		expectedCode int
+	}
+
+	server.URIPrefix = "/"
+
+	// the max zoom of the map is below the max zoom of its layers, 15
+	maxZoom := uint(12)
+	m := atlas.NewWebMercatorMap(testMapName)
+	m.Layers = []atlas.Layer{testLayer1, testLayer2}
+	m.MaxZoom = &maxZoom
+	a := &atlas.Atlas{}
+	a.AddMap(m)
+	router := server.NewRouter(a)
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"min zoom":            {uri: "/maps/test-map/4/2/3.pbf", expectedCode: http.StatusOK},
+		"max zoom":            {uri: "/maps/test-map/12/2/3.pbf", expectedCode: http.StatusOK},
+		"below min zoom":      {uri: "/maps/test-map/3/2/3.pbf", expectedCode: http.StatusBadRequest},
+		"above max zoom":      {uri: "/maps/test-map/13/2/3.pbf", expectedCode: http.StatusBadRequest},
+		"layer above max":     {uri: "/maps/test-map/test-layer-2-name/13/2/3.pbf", expectedCode: http.StatusBadRequest},
+		"invalid tile":        {uri: "/maps/test-map/3/8/3.pbf", expectedCode: http.StatusBadRequest},
+		"unknown map":         {uri: "/maps/missing-map/3/2/3.pbf", expectedCode: http.StatusNotFound},
+		"grid below min zoom": {uri: "/maps/test-map/3/2/3.grid.json", expectedCode: http.StatusBadRequest},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+
+	t.Run("tilejson", func(t *testing.T) {
+		r, err := http.NewRequest("GET", "/capabilities/test-map.json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var tileJSON tilejson.TileJSON
+		if err := json.NewDecoder(w.Body).Decode(&tileJSON); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tileJSON.MinZoom != 4 || tileJSON.MaxZoom != 12 {
+			t.Errorf("zooms, expected 4-12 got %v-%v", tileJSON.MinZoom, tileJSON.MaxZoom)
+		}
+	})
+}
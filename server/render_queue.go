@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+)
+
+// renderQueue tracks the tile requests which are waiting to be rendered or are being rendered.
+// Requests are added when they first wait, for an identical request in CoalesceHandler, or when
+// they take a render slot in MaxInFlightHandler, so cache hits are not tracked.
+type renderQueue struct {
+	sync.Mutex
+	seq     uint64
+	entries map[uint64]*renderEntry
+}
+
+// renderEntry is a single tile request in the render queue
+type renderEntry struct {
+	id        uint64
+	mapName   string
+	layerName string
+	z, x, y   string
+	queued    time.Time
+	// started is the zero value until rendering begins
+	started time.Time
+	// added is true once the entry is tracked by the queue
+	added bool
+}
+
+type renderEntryKey struct{}
+
+// tileRenderQueue is the render queue for the tile endpoints
+var tileRenderQueue = &renderQueue{
+	entries: map[uint64]*renderEntry{},
+}
+
+func (q *renderQueue) add(e *renderEntry) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.addLocked(e)
+}
+
+func (q *renderQueue) addLocked(e *renderEntry) {
+	if e.added {
+		return
+	}
+	q.seq++
+	e.id = q.seq
+	e.added = true
+	q.entries[e.id] = e
+}
+
+func (q *renderQueue) remove(e *renderEntry) {
+	q.Lock()
+	defer q.Unlock()
+
+	delete(q.entries, e.id)
+	e.added = false
+}
+
+// start adds the entry, when it's not tracked yet, as rendering
+func (q *renderQueue) start(e *renderEntry) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.addLocked(e)
+	if e.started.IsZero() {
+		e.started = time.Now()
+	}
+}
+
+// markRenderQueued adds the request associated with ctx to the render queue, waiting to be
+// rendered. Requests which are not tracked by the render queue are ignored.
+func markRenderQueued(ctx context.Context) {
+	e, ok := ctx.Value(renderEntryKey{}).(*renderEntry)
+	if !ok {
+		return
+	}
+
+	tileRenderQueue.add(e)
+}
+
+// markRenderStarted flags the request associated with ctx as rendering. Requests
+// which are not tracked by the render queue are ignored.
+func markRenderStarted(ctx context.Context) {
+	e, ok := ctx.Value(renderEntryKey{}).(*renderEntry)
+	if !ok {
+		return
+	}
+
+	tileRenderQueue.start(e)
+}
+
+// RenderQueueHandler tracks tile requests in the render queue while they wait for an identical
+// request (see CoalesceHandler) or for a render slot (see MaxInFlightHandler), and while they
+// are rendered. Requests are queued from the time they reach the handler until they take a
+// render slot.
+func RenderQueueHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := httptreemux.ContextParams(r.Context())
+
+		e := renderEntry{
+			mapName:   params["map_name"],
+			layerName: params["layer_name"],
+			z:         params["z"],
+			x:         params["x"],
+			y:         strings.Split(params["y"], ".")[0],
+			queued:    time.Now(),
+		}
+
+		defer tileRenderQueue.remove(&e)
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), renderEntryKey{}, &e)))
+	})
+}
+
+// RenderQueueRequest describes a tile request in the render queue
+type RenderQueueRequest struct {
+	Map    string    `json:"map"`
+	Layer  string    `json:"layer,omitempty"`
+	Z      uint      `json:"z"`
+	X      uint      `json:"x"`
+	Y      uint      `json:"y"`
+	Queued time.Time `json:"queued"`
+	// Started is when the request started rendering, nil while it is queued
+	Started *time.Time `json:"started,omitempty"`
+	// Waiting is the time spent queued in milliseconds
+	Waiting int64 `json:"waiting_ms"`
+	// Rendering is the time spent rendering in milliseconds
+	Rendering int64 `json:"rendering_ms"`
+}
+
+// RenderQueueMap is the render queue usage of a single map
+type RenderQueueMap struct {
+	InFlight int `json:"in_flight"`
+	Queued   int `json:"queued"`
+}
+
+// RenderQueue is a snapshot of the render queue
+type RenderQueue struct {
+	InFlight int                       `json:"in_flight"`
+	Queued   int                       `json:"queued"`
+	Maps     map[string]RenderQueueMap `json:"maps"`
+	// OldestQueued is the request which has been waiting the longest, nil when nothing is queued
+	OldestQueued *RenderQueueRequest `json:"oldest_queued"`
+	// Requests are ordered by the time they were queued
+	Requests []RenderQueueRequest `json:"requests"`
+}
+
+// snapshot returns the state of the render queue at time now
+func (q *renderQueue) snapshot(now time.Time) RenderQueue {
+	q.Lock()
+	defer q.Unlock()
+
+	rq := RenderQueue{
+		Maps:     map[string]RenderQueueMap{},
+		Requests: make([]RenderQueueRequest, 0, len(q.entries)),
+	}
+
+	for _, e := range q.entries {
+		req := RenderQueueRequest{
+			Map:    e.mapName,
+			Layer:  e.layerName,
+			Z:      parseQueueUint(e.z),
+			X:      parseQueueUint(e.x),
+			Y:      parseQueueUint(e.y),
+			Queued: e.queued,
+		}
+
+		m := rq.Maps[e.mapName]
+		if e.started.IsZero() {
+			req.Waiting = now.Sub(e.queued).Milliseconds()
+			m.Queued++
+			rq.Queued++
+		} else {
+			started := e.started
+			req.Started = &started
+			req.Waiting = e.started.Sub(e.queued).Milliseconds()
+			req.Rendering = now.Sub(e.started).Milliseconds()
+			m.InFlight++
+			rq.InFlight++
+		}
+		rq.Maps[e.mapName] = m
+
+		rq.Requests = append(rq.Requests, req)
+	}
+
+	sort.Slice(rq.Requests, func(i, j int) bool {
+		return rq.Requests[i].Queued.Before(rq.Requests[j].Queued)
+	})
+
+	for i := range rq.Requests {
+		if rq.Requests[i].Started == nil {
+			oldest := rq.Requests[i]
+			rq.OldestQueued = &oldest
+			break
+		}
+	}
+
+	return rq
+}
+
+func parseQueueUint(s string) uint {
+	v, _ := strconv.ParseUint(s, 10, 32)
+	return uint(v)
+}
+
+// HandleAdminQueue reports the state of the tile render queue
+//
+// URI scheme: /admin/queue
+type HandleAdminQueue struct{}
+
+func (req HandleAdminQueue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, tileRenderQueue.snapshot(time.Now()))
+}
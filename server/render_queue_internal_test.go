@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimfeld/httptreemux"
+)
+
+func TestRenderQueueSnapshot(t *testing.T) {
+	q := &renderQueue{
+		entries: map[uint64]*renderEntry{},
+	}
+
+	now := time.Now()
+
+	rendering := renderEntry{mapName: "osm", z: "1", x: "0", y: "1", queued: now.Add(-3 * time.Second)}
+	waiting := renderEntry{mapName: "osm", z: "2", x: "1", y: "1", queued: now.Add(-2 * time.Second)}
+	other := renderEntry{mapName: "roads", z: "3", x: "1", y: "1", queued: now.Add(-time.Second)}
+
+	q.add(&rendering)
+	q.add(&waiting)
+	q.add(&other)
+	q.start(&rendering)
+
+	snap := q.snapshot(now)
+	if snap.InFlight != 1 || snap.Queued != 2 {
+		t.Errorf("counts, expected 1 in flight and 2 queued got %v and %v", snap.InFlight, snap.Queued)
+	}
+	if m := snap.Maps["osm"]; m.InFlight != 1 || m.Queued != 1 {
+		t.Errorf("osm counts, expected 1 in flight and 1 queued got %+v", m)
+	}
+	if snap.OldestQueued == nil || snap.OldestQueued.Z != 2 {
+		t.Fatalf("oldest queued, expected z 2 got %+v", snap.OldestQueued)
+	}
+	if snap.OldestQueued.Waiting != 2000 {
+		t.Errorf("oldest waiting, expected 2000ms got %v", snap.OldestQueued.Waiting)
+	}
+	if len(snap.Requests) != 3 || snap.Requests[0].Map != "osm" || snap.Requests[2].Map != "roads" {
+		t.Errorf("requests, expected ordered by queue time got %+v", snap.Requests)
+	}
+	if started := snap.Requests[0].Started; started == nil || !started.Equal(rendering.started) {
+		t.Errorf("rendering started, expected %v got %v", rendering.started, started)
+	}
+	if started := snap.Requests[1].Started; started != nil {
+		t.Errorf("queued started, expected nil got %v", started)
+	}
+	// queued requests are encoded without a start time
+	b, err := json.Marshal(snap.Requests[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(b), `"started"`) {
+		t.Errorf("queued request json, expected no started got %s", b)
+	}
+
+	q.remove(&waiting)
+	q.remove(&other)
+	if snap = q.snapshot(now); snap.OldestQueued != nil || snap.Queued != 0 {
+		t.Errorf("expected no queued requests got %+v", snap)
+	}
+}
+
+func TestRenderQueueHandler(t *testing.T) {
+	maxInFlight := MaxInFlightTiles
+	MaxInFlightTiles = 1
+	defer func() { MaxInFlightTiles = maxInFlight }()
+
+	// the first request renders until it's released
+	rendering, release := make(chan struct{}), make(chan struct{})
+	router := httptreemux.New()
+	router.UsingContext().Handler("GET", "/maps/:map_name/:z/:x/:y", RenderQueueHandler(CoalesceHandler(MaxInFlightHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rendering <- struct{}{}
+		<-release
+	})))))
+
+	serve := func(done chan<- struct{}) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/maps/osm/1/0/1.pbf", nil))
+		done <- struct{}{}
+	}
+	done := make(chan struct{}, 2)
+	go serve(done)
+	<-rendering
+
+	// the identical request waits for the first one
+	go serve(done)
+	var snap RenderQueue
+	for i := 0; i < 100; i++ {
+		if snap = tileRenderQueue.snapshot(time.Now()); snap.Queued == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if snap.InFlight != 1 || snap.Queued != 1 {
+		t.Errorf("counts, expected 1 in flight and 1 queued got %v and %v", snap.InFlight, snap.Queued)
+	}
+	if snap.OldestQueued == nil || snap.OldestQueued.Map != "osm" || snap.OldestQueued.Y != 1 || snap.OldestQueued.Started != nil {
+		t.Errorf("oldest queued, expected the waiting request got %+v", snap.OldestQueued)
+	}
+
+	close(release)
+	<-done
+	<-done
+	if snap = tileRenderQueue.snapshot(time.Now()); len(snap.Requests) != 0 {
+		t.Errorf("expected no tracked requests got %+v", snap.Requests)
+	}
+}
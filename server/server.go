@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 
 	"github.com/dimfeld/httptreemux"
 
@@ -13,6 +14,15 @@ import (
 	"github.com/go-spatial/tegola/internal/log"
 )
 
+const (
+	// RegionHeader reports the region of the deployment which served the response
+	RegionHeader = "Tegola-Region"
+	// CacheTierHeader reports the cache backend a tile was served from or written to
+	CacheTierHeader = "Tegola-Cache-Tier"
+	// CacheNamespaceHeader reports the cache namespace a tile was served from or written to
+	CacheNamespaceHeader = "Tegola-Cache-Namespace"
+)
+
 const (
 	// MaxTileSize is 500k. Currently just throws a warning when tile
 	// is larger than MaxTileSize
@@ -38,10 +48,44 @@ var (
 	// SSLKey is a filepath to an SSL key, this will be used to enable https
 	SSLKey string
 
+	// AdminToken is the shared secret required to access the /admin endpoints.
+	// The admin endpoints are not registered when AdminToken is empty, unless the admin
+	// scope of the JWT verifier is configured.
+	// configurable via the tegola config.toml file (set in main.go)
+	AdminToken string
+
+	// FreshnessMonitor reports the freshness of map layers with a freshness SLA on the
+	// /admin/freshness endpoint. configurable via the tegola config.toml file (set in main.go)
+	FreshnessMonitor *atlas.FreshnessMonitor
+
+	// Reseeder re-seeds the cached tiles of changed data and reports its state on the
+	// /admin/reseed endpoint. configurable via the tegola config.toml file (set in main.go)
+	Reseeder *atlas.Reseeder
+
+	// Region is the region of the deployment, reported in the RegionHeader of every response.
+	// configurable via the tegola config.toml file (set in main.go)
+	Region string
+
+	// CacheTier is the name of the cache backend, reported in the CacheTierHeader of cached
+	// tile responses (set in main.go)
+	CacheTier string
+
+	// MapCacheTiers are the names of the cache backends of the maps configured with their own
+	// backend, by map name, reported instead of CacheTier (set in main.go)
+	MapCacheTiers = map[string]string{}
+
 	// Headers is the map of user defined response headers.
 	// configurable via the tegola config.toml file (set in main.go)
 	Headers = map[string]string{}
 
+	// Fonts are the glyphs served on /fonts. The endpoint is not registered when nil.
+	// configurable via the tegola config.toml file (set in main.go)
+	Fonts Assets
+
+	// Sprites are the sprites served on /sprites. The endpoint is not registered when nil.
+	// configurable via the tegola config.toml file (set in main.go)
+	Sprites Assets
+
 	// URIPrefix sets a prefix on all server endpoints. This is often used
 	// when the server sits behind a reverse proxy with a prefix (i.e. /tegola)
 	URIPrefix = "/"
@@ -61,17 +105,78 @@ func NewRouter(a *atlas.Atlas) *httptreemux.TreeMux {
 	// one handler to respond to all OPTIONS requests for registered routes with our CORS headers
 	r.OptionsHandler = corsHandler
 
+	// health checks and kubernetes probes
+	group.UsingContext().Handler("GET", "/health", HandleHealth{})
+	group.UsingContext().Handler("GET", "/live", HandleLive{})
+	group.UsingContext().Handler("GET", "/ready", HandleReady{Atlas: a})
+
 	// capabilities endpoints
-	group.UsingContext().Handler("GET", "/capabilities", HeadersHandler(HandleCapabilities{}))
-	group.UsingContext().Handler("GET", "/capabilities/:map_name", HeadersHandler(HandleMapCapabilities{}))
+	group.UsingContext().Handler("GET", "/capabilities", HeadersHandler(HandleCapabilities{Atlas: a}))
+	group.UsingContext().Handler("GET", "/capabilities/:map_name", HeadersHandler(HandleMapCapabilities{Atlas: a}))
 
 	// map tiles
 	hMapLayerZXY := HandleMapLayerZXY{Atlas: a}
-	group.UsingContext().Handler("GET", "/maps/:map_name/:z/:x/:y", HeadersHandler(GZipHandler(TileCacheHandler(a, hMapLayerZXY))))
-	group.UsingContext().Handler("GET", "/maps/:map_name/:layer_name/:z/:x/:y", HeadersHandler(GZipHandler(TileCacheHandler(a, hMapLayerZXY))))
+	hTiles := TraceHandler(ServerTimingHandler(AccessLogHandler(RequestTimeoutHandler(SignedURLHandler(JWTHandler(CacheControlHandler(a, APIKeyHandler(RateLimitHandler(GeofenceHandler(ZoomHandler(a, GZipHandler(EmptyTileHandler(a, FieldsHandler(a, NegativeCacheHandler(a, OverzoomHandler(a, RenderQueueHandler(CoalesceHandler(TileCacheHandler(a, MaxInFlightHandler(hMapLayerZXY))))))))))))))))))))
+	group.UsingContext().Handler("GET", "/maps/:map_name/:z/:x/:y", HeadersHandler(TMSHandler(a, hTiles)))
+	group.UsingContext().Handler("GET", "/maps/:map_name/:layer_name/:z/:x/:y", HeadersHandler(TMSHandler(a, hTiles)))
+
+	// WMTS capabilities and tiles, served by the map tile handlers
+	hWMTS := HandleWMTS{Atlas: a, Tiles: hTiles}
+	group.UsingContext().Handler("GET", "/wmts", HeadersHandler(hWMTS))
+	group.UsingContext().Handler("GET", "/wmts/1.0.0/WMTSCapabilities.xml", HeadersHandler(HandleWMTSCapabilities{Atlas: a}))
+	group.UsingContext().Handler("GET", "/wmts/1.0.0/:map_name/:style/:tile_matrix_set/:z/:y/:x", HeadersHandler(hWMTS))
+
+	// TMS, tiles numbered from the south, served by the map tile handlers
+	hTMS := HandleTMS{Atlas: a, Tiles: hTiles}
+	group.UsingContext().Handler("GET", "/tms/1.0.0", HeadersHandler(hTMS))
+	group.UsingContext().Handler("GET", "/tms/1.0.0/:map_name", HeadersHandler(hTMS))
+	group.UsingContext().Handler("GET", "/tms/1.0.0/:map_name/:z/:x/:y", HeadersHandler(hTMS))
+
+	// OGC API - Tiles, served by the map tile handlers
+	for uri, resource := range map[string]string{
+		"/ogcapi":                "",
+		"/ogcapi/conformance":    "conformance",
+		"/ogcapi/tileMatrixSets": "tileMatrixSets",
+		"/ogcapi/tileMatrixSets/:tile_matrix_set":              "tileMatrixSet",
+		"/ogcapi/collections":                                  "collections",
+		"/ogcapi/collections/:map_name":                        "collection",
+		"/ogcapi/tiles":                                        "tiles",
+		"/ogcapi/collections/:map_name/tiles":                  "collectionTiles",
+		"/ogcapi/collections/:map_name/tiles/:tile_matrix_set": "tileset",
+	} {
+		group.UsingContext().Handler("GET", uri, HeadersHandler(HandleOGCAPI{Atlas: a, Resource: resource}))
+	}
+	group.UsingContext().Handler("GET", "/ogcapi/collections/:map_name/tiles/:tile_matrix_set/:z/:y/:x", HeadersHandler(HandleOGCAPITile{Atlas: a, Tiles: hTiles}))
+
+	// checksums of cached tiles, authorized as the tiles
+	hChecksum := SignedURLHandler(JWTHandler(APIKeyHandler(HandleChecksum{Atlas: a})))
+	group.UsingContext().Handler("GET", "/checksums/:map_name/:z/:x/:y", HeadersHandler(hChecksum))
+	group.UsingContext().Handler("GET", "/checksums/:map_name/:layer_name/:z/:x/:y", HeadersHandler(hChecksum))
 
 	// map style
-	group.UsingContext().Handler("GET", "/maps/:map_name/style.json", HeadersHandler(HandleMapStyle{}))
+	group.UsingContext().Handler("GET", "/maps/:map_name/style.json", HeadersHandler(HandleMapStyle{Atlas: a}))
+
+	// map legend, authorized as the tiles
+	group.UsingContext().Handler("GET", "/maps/:map_name/legend", HeadersHandler(SignedURLHandler(JWTHandler(APIKeyHandler(HandleMapLegend{Atlas: a})))))
+
+	// features near a point, authorized and rate limited as the tiles
+	hQuery := TraceHandler(AccessLogHandler(SignedURLHandler(JWTHandler(APIKeyHandler(RateLimitHandler(HandleMapQuery{Atlas: a}))))))
+	group.UsingContext().Handler("GET", "/maps/:map_name/query", HeadersHandler(hQuery))
+
+	// invalidation events of the map's tiles, authorized as the tiles. Rate limited requests
+	// would only be the reconnections of a stream.
+	group.UsingContext().Handler("GET", "/maps/:map_name/events", HeadersHandler(SignedURLHandler(JWTHandler(APIKeyHandler(HandleMapEvents{Atlas: a})))))
+
+	// glyphs and sprites of the styles
+	if Fonts != nil {
+		group.UsingContext().Handler("GET", "/fonts/:fontstack/:range", HeadersHandler(HandleFonts{Assets: Fonts}))
+	}
+	if Sprites != nil {
+		group.UsingContext().Handler("GET", "/sprites/*path", HeadersHandler(HandleSprites{Assets: Sprites}))
+	}
+
+	// admin endpoints, only available when an admin token is configured
+	setupAdmin(group, a)
 
 	// setup viewer routes, which can be excluded via build flags
 	setupViewer(group)
@@ -86,14 +191,30 @@ func Start(a *atlas.Atlas, port string) *http.Server {
 	log.Infof("starting tegola server on port %v", port)
 
 	srv := &http.Server{Addr: port, Handler: NewRouter(a)}
+	if len(Tenants) > 0 {
+		srv.Handler = NewTenantRouter(a)
+	}
+
+	// purge the tiles purged by the other instances
+	if err := SubscribeInvalidations(a); err != nil {
+		log.Errorf("error subscribing to the invalidation bus: %v", err)
+	}
 
 	// start our server
 	go func() {
 		var err error
 
-		if SSLCert+SSLKey != "" {
+		switch {
+		case ACME != nil:
+			// the certificates are obtained when first requested by a client
+			srv.TLSConfig = ACME.TLSConfig()
+			if ACMEHTTPPort != "" {
+				go listenACMEHTTP()
+			}
+			err = srv.ListenAndServeTLS("", "")
+		case SSLCert+SSLKey != "":
 			err = srv.ListenAndServeTLS(SSLCert, SSLKey)
-		} else {
+		default:
 			err = srv.ListenAndServe()
 		}
 
@@ -113,13 +234,16 @@ func Start(a *atlas.Atlas, port string) *http.Server {
 	return srv
 }
 
-// hostName determines weather to use an user defined HostName
-// or the host from the incoming request
+// hostName determines weather to use an user defined HostName, the X-Forwarded-Host of a
+// trusted proxy or the host from the incoming request
 func hostName(r *http.Request) string {
 	// if the HostName has been configured, don't mutate it
 	if HostName != "" {
 		return HostName
 	}
+	if host := forwardedHeader(r, "X-Forwarded-Host"); host != "" {
+		return host
+	}
 
 	return r.Host
 }
@@ -127,8 +251,8 @@ func hostName(r *http.Request) string {
 // various checks to determin if the request is http or https. the scheme is needed for the TileURLs
 // r.URL.Scheme can be empty if a relative request is issued from the client. (i.e. GET /foo.html)
 func scheme(r *http.Request) string {
-	if r.Header.Get("X-Forwarded-Proto") != "" {
-		return r.Header.Get("X-Forwarded-Proto")
+	if proto := forwardedHeader(r, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		return proto
 	} else if r.TLS != nil {
 		return "https"
 	}
@@ -139,10 +263,18 @@ func scheme(r *http.Request) string {
 // URLRoot builds a string containing the scheme, host and port based on a combination of user defined values,
 // headers and request parameters. The function is public so it can be overridden for other implementations.
 var URLRoot = func(r *http.Request) *url.URL {
+	if ConfiguredURLRoot != nil {
+		root := *ConfiguredURLRoot
+		return &root
+	}
+
 	root := url.URL{
 		Scheme: scheme(r),
 		Host:   hostName(r),
 	}
+	if prefix := strings.Trim(forwardedHeader(r, "X-Forwarded-Prefix"), "/"); prefix != "" {
+		root.Path = "/" + prefix
+	}
 
 	return &root
 }
@@ -183,6 +315,10 @@ func setHeaders(w http.ResponseWriter) {
 		w.Header().Set(name, val)
 	}
 
+	if Region != "" {
+		w.Header().Set(RegionHeader, Region)
+	}
+
 	// set user defined headers
 	for name, val := range Headers {
 		if val == "" {
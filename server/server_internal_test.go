@@ -2,6 +2,7 @@ package server
 
 import (
 	"crypto/tls"
+	"net"
 	"net/http"
 	"net/url"
 	"testing"
@@ -131,7 +132,10 @@ func TestBuildCapabilitiesURL(t *testing.T) {
 		uriParts  []string
 		uriPrefix string
 		query     url.Values
-		expected  string
+		// trustedProxies are the networks of the trusted proxies, in CIDR notation
+		trustedProxies []string
+		urlRoot        string
+		expected       string
 	}
 
 	fn := func(tc tcase) func(t *testing.T) {
@@ -143,6 +147,22 @@ func TestBuildCapabilitiesURL(t *testing.T) {
 				URIPrefix = "/"
 			}
 
+			HostName, TrustedProxies, ConfiguredURLRoot = "", nil, nil
+			for _, cidr := range tc.trustedProxies {
+				_, n, err := net.ParseCIDR(cidr)
+				if err != nil {
+					t.Fatalf("unexpected error parsing %v: %v", cidr, err)
+				}
+				TrustedProxies = append(TrustedProxies, n)
+			}
+			if tc.urlRoot != "" {
+				root, err := url.Parse(tc.urlRoot)
+				if err != nil {
+					t.Fatalf("unexpected error parsing %v: %v", tc.urlRoot, err)
+				}
+				ConfiguredURLRoot = root
+			}
+
 			output := buildCapabilitiesURL(&tc.request, tc.uriParts, tc.query)
 			if output != tc.expected {
 				t.Errorf("expected (%v) got (%v)", tc.expected, output)
@@ -179,6 +199,63 @@ func TestBuildCapabilitiesURL(t *testing.T) {
 			},
 			expected: "http://cdn.tegola.io/tegola/foo/bar?debug=true",
 		},
+		"forwarded headers without trusted proxies": {
+			request: http.Request{
+				Host:       "cdn.tegola.io",
+				RemoteAddr: "10.0.0.5:43210",
+				Header: http.Header{
+					"X-Forwarded-Proto":  {"https"},
+					"X-Forwarded-Host":   {"tiles.example.com, proxy.internal"},
+					"X-Forwarded-Prefix": {"/maps-api/"},
+				},
+			},
+			uriParts: []string{"foo", "bar"},
+			expected: "https://cdn.tegola.io/foo/bar",
+		},
+		"forwarded headers of a trusted proxy": {
+			request: http.Request{
+				Host:       "cdn.tegola.io",
+				RemoteAddr: "10.0.0.5:43210",
+				Header: http.Header{
+					"X-Forwarded-Proto":  {"https"},
+					"X-Forwarded-Host":   {"tiles.example.com, proxy.internal"},
+					"X-Forwarded-Prefix": {"/maps-api/"},
+				},
+			},
+			uriParts:       []string{"foo", "bar"},
+			uriPrefix:      "/tegola",
+			trustedProxies: []string{"10.0.0.0/8"},
+			expected:       "https://tiles.example.com/maps-api/tegola/foo/bar",
+		},
+		"forwarded headers of an untrusted address": {
+			request: http.Request{
+				Host:       "cdn.tegola.io",
+				RemoteAddr: "192.168.1.5:43210",
+				Header: http.Header{
+					"X-Forwarded-Proto":  {"https"},
+					"X-Forwarded-Host":   {"tiles.example.com, proxy.internal"},
+					"X-Forwarded-Prefix": {"/maps-api/"},
+				},
+			},
+			uriParts:       []string{"foo", "bar"},
+			trustedProxies: []string{"10.0.0.0/8"},
+			expected:       "http://cdn.tegola.io/foo/bar",
+		},
+		"url root": {
+			request: http.Request{
+				Host:       "cdn.tegola.io",
+				RemoteAddr: "10.0.0.5:43210",
+				Header: http.Header{
+					"X-Forwarded-Proto":  {"https"},
+					"X-Forwarded-Host":   {"tiles.example.com, proxy.internal"},
+					"X-Forwarded-Prefix": {"/maps-api/"},
+				},
+			},
+			uriParts:       []string{"foo", "bar"},
+			trustedProxies: []string{"10.0.0.0/8"},
+			urlRoot:        "https://example.com/tiles",
+			expected:       "https://example.com/tiles/foo/bar",
+		},
 	}
 
 	for name, tc := range tests {
@@ -189,4 +266,5 @@ func TestBuildCapabilitiesURL(t *testing.T) {
 	// designed as a singleton right now. Eventually this will change so the tests
 	// don't need to consider each other
 	URIPrefix = "/"
+	TrustedProxies, ConfiguredURLRoot = nil, nil
 }
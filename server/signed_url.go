@@ -0,0 +1,94 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// the query parameters of a signed tile url
+const (
+	// SignedURLKeyParam names the key the url is signed with
+	SignedURLKeyParam = "key"
+	// SignedURLExpiresParam is the expiry of the url, in unix seconds
+	SignedURLExpiresParam = "expires"
+	// SignedURLSignatureParam is the base64url encoded HMAC-SHA256 signature of the url
+	SignedURLSignatureParam = "signature"
+)
+
+var (
+	ErrSignedURLUnknownKey = errors.New("signed url: unknown key")
+	ErrSignedURLMalformed  = errors.New("signed url: malformed expiry or signature")
+	ErrSignedURLSignature  = errors.New("signed url: invalid signature")
+	ErrSignedURLExpired    = errors.New("signed url: url is expired")
+	ErrSignedURLMaxTTL     = errors.New("signed url: expiry is beyond the max ttl")
+)
+
+// URLSigner signs and verifies expiring tile urls. A signature covers the map (and layer) of
+// the tiles rather than a tile, so the signed query parameters are appended to the url
+// template of the map, i.e. /maps/osm/{z}/{x}/{y}.pbf?expires=1700000000&key=partner&signature=...
+type URLSigner struct {
+	// Keys are the HMAC-SHA256 secrets of the signing keys, by name
+	Keys map[string][]byte
+	// Required rejects the tile requests without a signature
+	Required bool
+	// MaxTTL bounds the expiry of the urls from the time they are verified, when set
+	MaxTTL time.Duration
+}
+
+// Sign returns the query parameters signing the tiles of the map, or of the layer of the map
+// when layerName is set, with the key until expires
+func (s *URLSigner) Sign(key, mapName, layerName string, expires time.Time) (url.Values, error) {
+	secret, ok := s.Keys[key]
+	if !ok {
+		return nil, ErrSignedURLUnknownKey
+	}
+
+	exp := expires.Unix()
+	return url.Values{
+		SignedURLKeyParam:       {key},
+		SignedURLExpiresParam:   {strconv.FormatInt(exp, 10)},
+		SignedURLSignatureParam: {base64.RawURLEncoding.EncodeToString(signURL(secret, key, mapName, layerName, exp))},
+	}, nil
+}
+
+// Verify verifies the signed query parameters of a request for the tiles of the map and layer
+func (s *URLSigner) Verify(query url.Values, mapName, layerName string, now time.Time) error {
+	key := query.Get(SignedURLKeyParam)
+	secret, ok := s.Keys[key]
+	if !ok {
+		return ErrSignedURLUnknownKey
+	}
+	exp, err := strconv.ParseInt(query.Get(SignedURLExpiresParam), 10, 64)
+	if err != nil {
+		return ErrSignedURLMalformed
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(query.Get(SignedURLSignatureParam))
+	if err != nil {
+		return ErrSignedURLMalformed
+	}
+
+	if !hmac.Equal(signature, signURL(secret, key, mapName, layerName, exp)) {
+		return ErrSignedURLSignature
+	}
+
+	expires := time.Unix(exp, 0)
+	if now.After(expires) {
+		return ErrSignedURLExpired
+	}
+	if s.MaxTTL > 0 && expires.Sub(now) > s.MaxTTL {
+		return ErrSignedURLMaxTTL
+	}
+	return nil
+}
+
+// signURL returns the signature of the tiles of the map and layer, with the key until exp
+func signURL(secret []byte, key, mapName, layerName string, exp int64) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(mapName + "\n" + layerName + "\n" + key + "\n" + strconv.FormatInt(exp, 10)))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,183 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestURLSigner(t *testing.T) {
+	signer := &server.URLSigner{
+		Keys:   map[string][]byte{"partner": []byte("s3cr3t"), "other": []byte("0th3r")},
+		MaxTTL: 24 * time.Hour,
+	}
+	now := time.Unix(1700000000, 0)
+
+	type tcase struct {
+		key         string
+		expires     time.Time
+		mapName     string
+		layerName   string
+		tamper      func(q map[string][]string)
+		expectedErr error
+	}
+
+	fn := func(tc tcase) func(*testing.T) {
+		return func(t *testing.T) {
+			query, err := signer.Sign(tc.key, "test-map", "", tc.expires)
+			if err != nil {
+				t.Fatalf("unexpected error signing: %v", err)
+			}
+			if tc.tamper != nil {
+				tc.tamper(query)
+			}
+
+			mapName := tc.mapName
+			if mapName == "" {
+				mapName = "test-map"
+			}
+			err = signer.Verify(query, mapName, tc.layerName, now)
+			if err != tc.expectedErr {
+				t.Errorf("error, expected %v got %v", tc.expectedErr, err)
+			}
+		}
+	}
+
+	tests := map[string]tcase{
+		"valid": {
+			key:     "partner",
+			expires: now.Add(time.Hour),
+		},
+		"expired": {
+			key:         "partner",
+			expires:     now.Add(-time.Second),
+			expectedErr: server.ErrSignedURLExpired,
+		},
+		"beyond max ttl": {
+			key:         "partner",
+			expires:     now.Add(48 * time.Hour),
+			expectedErr: server.ErrSignedURLMaxTTL,
+		},
+		"other map": {
+			key:         "partner",
+			expires:     now.Add(time.Hour),
+			mapName:     "other-map",
+			expectedErr: server.ErrSignedURLSignature,
+		},
+		"layer of the map": {
+			key:         "partner",
+			expires:     now.Add(time.Hour),
+			layerName:   "test-layer",
+			expectedErr: server.ErrSignedURLSignature,
+		},
+		"extended expiry": {
+			key:     "partner",
+			expires: now.Add(time.Hour),
+			tamper: func(q map[string][]string) {
+				q[server.SignedURLExpiresParam] = []string{"1700007200"}
+			},
+			expectedErr: server.ErrSignedURLSignature,
+		},
+		"other key": {
+			key:     "partner",
+			expires: now.Add(time.Hour),
+			tamper: func(q map[string][]string) {
+				q[server.SignedURLKeyParam] = []string{"other"}
+			},
+			expectedErr: server.ErrSignedURLSignature,
+		},
+		"unknown key": {
+			key:     "partner",
+			expires: now.Add(time.Hour),
+			tamper: func(q map[string][]string) {
+				q[server.SignedURLKeyParam] = []string{"unknown"}
+			},
+			expectedErr: server.ErrSignedURLUnknownKey,
+		},
+		"malformed signature": {
+			key:     "partner",
+			expires: now.Add(time.Hour),
+			tamper: func(q map[string][]string) {
+				q[server.SignedURLSignatureParam] = []string{"not base64!"}
+			},
+			expectedErr: server.ErrSignedURLMalformed,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, fn(tc))
+	}
+}
+
+func TestSignedURLHandler(t *testing.T) {
+	server.URIPrefix = "/"
+	server.AdminToken = testAdminToken
+	server.SignedURLs = &server.URLSigner{Keys: map[string][]byte{"partner": []byte("s3cr3t")}}
+	server.JWT = &server.JWTVerifier{Secret: []byte("jwt-secret")}
+	defer func() {
+		server.AdminToken = ""
+		server.SignedURLs = nil
+		server.JWT = nil
+	}()
+	router := server.NewRouter(newTestMapWithLayers(testLayer1))
+
+	// sign the tile url template of the map
+	r, _ := http.NewRequest("GET", "/admin/signed_urls/test-map?key=partner&ttl=600", nil)
+	r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("signing status code, expected %v got %v: %v", http.StatusOK, w.Code, w.Body.String())
+	}
+	var signed server.SignedURL
+	if err := json.NewDecoder(w.Body).Decode(&signed); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	if !strings.Contains(signed.URL, "/maps/test-map/{z}/{x}/{y}.pbf?expires=") {
+		t.Fatalf("signed url, expected the url template of the map got %v", signed.URL)
+	}
+	query := signed.URL[strings.Index(signed.URL, "?"):]
+
+	type tcase struct {
+		uri          string
+		expectedCode int
+	}
+
+	tests := []tcase{
+		// without a signature the JWT is required
+		{uri: "/maps/test-map/5/2/3.pbf", expectedCode: http.StatusUnauthorized},
+		{uri: "/maps/test-map/5/2/3.pbf" + query, expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/6/4/7.pbf" + query, expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/5/2/3.pbf" + strings.Replace(query, "signature=", "signature=x", 1), expectedCode: http.StatusForbidden},
+		// the legend of the map is authorized as its tiles
+		{uri: "/maps/test-map/legend", expectedCode: http.StatusUnauthorized},
+		{uri: "/maps/test-map/legend" + query, expectedCode: http.StatusOK},
+		// as are its queries and events
+		{uri: "/maps/test-map/query?lon=0&lat=0&zoom=5", expectedCode: http.StatusUnauthorized},
+		{uri: "/maps/test-map/query" + query + "&lon=0&lat=0&zoom=5", expectedCode: http.StatusOK},
+		{uri: "/maps/test-map/query" + strings.Replace(query, "signature=", "signature=x", 1) + "&lon=0&lat=0&zoom=5", expectedCode: http.StatusForbidden},
+		{uri: "/maps/test-map/events", expectedCode: http.StatusUnauthorized},
+		{uri: "/maps/test-map/events" + strings.Replace(query, "signature=", "signature=x", 1), expectedCode: http.StatusForbidden},
+		// the signature covers the map, not the layers of the map
+		{uri: "/maps/test-map/" + testLayer1.MVTName() + "/5/2/3.pbf" + query, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		r, err := http.NewRequest("GET", tc.uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != tc.expectedCode {
+			t.Errorf("%v: status code, expected %v got %v: %v", tc.uri, tc.expectedCode, w.Code, w.Body.String())
+		}
+	}
+}
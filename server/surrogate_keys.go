@@ -0,0 +1,143 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/cache"
+	"github.com/go-spatial/tegola/internal/log"
+)
+
+// SurrogateKeyHeader is the response header tile surrogate keys are sent in. Surrogate keys
+// (also called cache tags) let CDNs and caching proxies purge groups of tiles with a single request.
+const SurrogateKeyHeader = "Surrogate-Key"
+
+// SurrogateKeyIndexSize is the maximum number of cached tiles indexed by surrogate key for
+// purging. Tiles cached once the index is full can only be purged by their URL.
+// configurable via the tegola config.toml file (set in main.go)
+var SurrogateKeyIndexSize uint = 100000
+
+// tileSurrogateKeys returns the surrogate keys of a tile:
+//
+//	<map>                  every tile of the map
+//	<map>/<z>              every tile of the map at zoom z
+//	<map>/<z>/<x>/<y>      the tile
+//	<map>:<layer>          every tile containing the layer
+func tileSurrogateKeys(m atlas.Map, layerName string, z, x, y uint) []string {
+	keys := []string{
+		m.Name,
+		fmt.Sprintf("%v/%v", m.Name, z),
+		fmt.Sprintf("%v/%v/%v/%v", m.Name, z, x, y),
+	}
+
+	seen := map[string]struct{}{}
+	for _, l := range m.FilterLayersByZoom(z).Layers {
+		name := l.MVTName()
+		if layerName != "" && name != layerName {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		keys = append(keys, m.Name+":"+name)
+	}
+
+	return keys
+}
+
+// setSurrogateKeys sets the surrogate key header of a tile response
+func setSurrogateKeys(h http.Header, keys []string) {
+	h.Set(SurrogateKeyHeader, strings.Join(keys, " "))
+}
+
+// parseSurrogateKeys returns the space separated surrogate keys in a header value
+func parseSurrogateKeys(v string) []string {
+	return strings.Fields(v)
+}
+
+// surrogateIndex tracks the tiles written to the cache backend by surrogate key so
+// groups of tiles can be purged. The index is built as tiles are cached by this
+// process, so it's lost on restart and isn't shared between instances.
+type surrogateIndex struct {
+	sync.Mutex
+	// keys maps a surrogate key to the cache keys tagged with it
+	keys map[string]map[cache.Key]struct{}
+	// tiles maps a cache key to its surrogate keys
+	tiles map[cache.Key][]string
+	// full is set once the index has reached SurrogateKeyIndexSize
+	full bool
+}
+
+// tileSurrogateIndex is the surrogate key index of the tile endpoints
+var tileSurrogateIndex = newSurrogateIndex()
+
+func newSurrogateIndex() *surrogateIndex {
+	return &surrogateIndex{
+		keys:  map[string]map[cache.Key]struct{}{},
+		tiles: map[cache.Key][]string{},
+	}
+}
+
+// add indexes the cache key under each of the surrogate keys
+func (si *surrogateIndex) add(key cache.Key, surrogateKeys []string) {
+	si.Lock()
+	defer si.Unlock()
+
+	if _, ok := si.tiles[key]; !ok && uint(len(si.tiles)) >= SurrogateKeyIndexSize {
+		if !si.full {
+			si.full = true
+			log.Warnf("surrogate key index is full (%v tiles), newly cached tiles can only be purged by url", SurrogateKeyIndexSize)
+		}
+		return
+	}
+
+	si.tiles[key] = surrogateKeys
+	for _, sk := range surrogateKeys {
+		if si.keys[sk] == nil {
+			si.keys[sk] = map[cache.Key]struct{}{}
+		}
+		si.keys[sk][key] = struct{}{}
+	}
+}
+
+// remove drops the cache key from the index
+func (si *surrogateIndex) remove(key cache.Key) {
+	si.Lock()
+	defer si.Unlock()
+
+	si.removeLocked(key)
+}
+
+func (si *surrogateIndex) removeLocked(key cache.Key) {
+	for _, sk := range si.tiles[key] {
+		delete(si.keys[sk], key)
+		if len(si.keys[sk]) == 0 {
+			delete(si.keys, sk)
+		}
+	}
+	delete(si.tiles, key)
+
+	if uint(len(si.tiles)) < SurrogateKeyIndexSize {
+		si.full = false
+	}
+}
+
+// take removes and returns the cache keys tagged with any of the surrogate keys
+func (si *surrogateIndex) take(surrogateKeys []string) []cache.Key {
+	si.Lock()
+	defer si.Unlock()
+
+	var keys []cache.Key
+	for _, sk := range surrogateKeys {
+		for key := range si.keys[sk] {
+			keys = append(keys, key)
+			si.removeLocked(key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-spatial/tegola/atlas"
+)
+
+// Tenant is served the maps of its atlas on its host names
+type Tenant struct {
+	// Name of the tenant, matched against the TenantHeader
+	Name string
+	// Hosts are the host names of the requests of the tenant
+	Hosts []string
+	// Atlas holds the maps of the tenant and the cache of its tiles
+	Atlas *atlas.Atlas
+}
+
+var (
+	// Tenants are served the maps of their own atlas. The requests of other hosts are rejected,
+	// unless TenantFallback is set. configurable via the tegola config.toml file (set in main.go)
+	Tenants []Tenant
+
+	// TenantFallback serves the requests of no tenant the maps of the server's atlas, which
+	// holds the maps of every tenant. configurable via the tegola config.toml file (set in main.go)
+	TenantFallback bool
+
+	// TenantHeader is the request header naming the tenant of a request. It must be set by a
+	// proxy, which removes the header sent by clients. The tenants are only matched by host
+	// when empty. configurable via the tegola config.toml file (set in main.go)
+	TenantHeader string
+)
+
+// NewTenantRouter sets up a router for each of the Tenants, routing the requests to the
+// router of their tenant by the TenantHeader, or else by the host of the request. The
+// requests of no tenant are answered with a 404, or routed to the router of a when
+// TenantFallback is set.
+func NewTenantRouter(a *atlas.Atlas) http.Handler {
+	var defaultRouter http.Handler
+	if TenantFallback {
+		defaultRouter = NewRouter(a)
+	}
+
+	byName := map[string]http.Handler{}
+	byHost := map[string]http.Handler{}
+	for _, t := range Tenants {
+		router := NewRouter(t.Atlas)
+		byName[t.Name] = router
+		for _, h := range t.Hosts {
+			byHost[strings.ToLower(h)] = router
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if TenantHeader != "" {
+			if name := r.Header.Get(TenantHeader); name != "" {
+				router, ok := byName[name]
+				if !ok {
+					http.Error(w, "tenant ("+name+") not configured", http.StatusNotFound)
+					return
+				}
+				router.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if router, ok := byHost[strings.ToLower(host)]; ok {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		if defaultRouter == nil {
+			http.Error(w, "host ("+host+") is not served", http.StatusNotFound)
+			return
+		}
+		defaultRouter.ServeHTTP(w, r)
+	})
+}
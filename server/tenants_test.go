@@ -0,0 +1,122 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-spatial/tegola/atlas"
+	"github.com/go-spatial/tegola/server"
+)
+
+func TestNewTenantRouter(t *testing.T) {
+	acme := atlas.NewWebMercatorMap("acme-map")
+	acme.Layers = append(acme.Layers, testLayer1)
+	acmeAtlas := &atlas.Atlas{}
+	acmeAtlas.AddMap(acme)
+
+	server.URIPrefix = "/"
+	server.Tenants = []server.Tenant{
+		{Name: "acme", Hosts: []string{"acme.tiles.example.com"}, Atlas: acmeAtlas},
+	}
+	server.TenantHeader = "X-Tenant"
+	defer func() {
+		server.Tenants = nil
+		server.TenantHeader = ""
+		server.TenantFallback = false
+	}()
+	router := server.NewTenantRouter(newTestMapWithLayers(testLayer1))
+	server.TenantFallback = true
+	fallbackRouter := server.NewTenantRouter(newTestMapWithLayers(testLayer1))
+
+	type tcase struct {
+		host         string
+		tenant       string
+		uri          string
+		fallback     bool
+		expectedCode int
+	}
+
+	tests := map[string]tcase{
+		"tenant host": {
+			host:         "acme.tiles.example.com",
+			uri:          "/maps/acme-map/5/2/3.pbf",
+			expectedCode: http.StatusOK,
+		},
+		"tenant host with port": {
+			host:         "ACME.tiles.example.com:8080",
+			uri:          "/maps/acme-map/5/2/3.pbf",
+			expectedCode: http.StatusOK,
+		},
+		"map of another tenant": {
+			host:         "acme.tiles.example.com",
+			uri:          "/maps/test-map/5/2/3.pbf",
+			expectedCode: http.StatusNotFound,
+		},
+		"tenant header": {
+			host:         "localhost",
+			tenant:       "acme",
+			uri:          "/maps/acme-map/5/2/3.pbf",
+			expectedCode: http.StatusOK,
+		},
+		"unknown tenant header": {
+			host:         "acme.tiles.example.com",
+			tenant:       "globex",
+			uri:          "/maps/acme-map/5/2/3.pbf",
+			expectedCode: http.StatusNotFound,
+		},
+		"other host": {
+			host:         "localhost",
+			uri:          "/maps/test-map/5/2/3.pbf",
+			expectedCode: http.StatusNotFound,
+		},
+		"other host capabilities": {
+			host:         "10.0.0.1:8080",
+			uri:          "/capabilities",
+			expectedCode: http.StatusNotFound,
+		},
+		"other host fallback": {
+			host:         "localhost",
+			uri:          "/maps/test-map/5/2/3.pbf",
+			fallback:     true,
+			expectedCode: http.StatusOK,
+		},
+		"other host fallback tenant map": {
+			host:         "localhost",
+			uri:          "/maps/acme-map/5/2/3.pbf",
+			fallback:     true,
+			expectedCode: http.StatusNotFound,
+		},
+		"tenant host with fallback": {
+			host:         "acme.tiles.example.com",
+			uri:          "/maps/test-map/5/2/3.pbf",
+			fallback:     true,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r, err := http.NewRequest("GET", tc.uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Host = tc.host
+			if tc.tenant != "" {
+				r.Header.Set("X-Tenant", tc.tenant)
+			}
+
+			w := httptest.NewRecorder()
+			if tc.fallback {
+				fallbackRouter.ServeHTTP(w, r)
+			} else {
+				router.ServeHTTP(w, r)
+			}
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("status code, expected %v got %v: %v", tc.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
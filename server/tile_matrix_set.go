@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/dimfeld/httptreemux"
+
+	"github.com/go-spatial/tegola"
+	"github.com/go-spatial/tegola/atlas"
+)
+
+const (
+	// the scale denominator of zoom 0 of the web mercator tile matrix set, with 256 pixel tiles
+	webMercatorScaleDenominator = 559082264.0287178
+	// the size of a pixel at zoom 0 of the web mercator tile matrix set, in meters
+	webMercatorCellSize = 156543.03392804097
+	// the x and -y of the top left corner of the web mercator tile matrix set
+	webMercatorOrigin = 20037508.3427892
+)
+
+// tileFormats returns the content types of the map's tiles by their file extension
+func tileFormats(m atlas.Map) map[string]string {
+	formats := map[string]string{m.TileFormat(): m.ContentType()}
+	if m.HasRaster() {
+		formats[m.Raster.Format()] = m.Raster.ContentType()
+	}
+	return formats
+}
+
+// tileMaxZoom returns the highest zoom the map's tiles are served at
+func tileMaxZoom(m atlas.Map) uint {
+	if m.MaxZoom != nil {
+		return *m.MaxZoom
+	}
+	if m.HasUpstream() || len(m.Layers) == 0 {
+		return tegola.MaxZ
+	}
+
+	var maxZoom uint
+	for _, l := range m.Layers {
+		if l.ServedMaxZoom() > maxZoom {
+			maxZoom = l.ServedMaxZoom()
+		}
+	}
+	if m.HasRaster() && m.Raster.MaxZoom > maxZoom {
+		maxZoom = m.Raster.MaxZoom
+	}
+	if maxZoom > tegola.MaxZ {
+		maxZoom = tegola.MaxZ
+	}
+	return maxZoom
+}
+
+// mapTileRequest returns the request of the map tile as a request of the map's tile endpoint,
+// /maps/:map_name/:z/:x/:y.:ext, so the tile is served by the map tile handlers
+func mapTileRequest(r *http.Request, mapName, z, x, y, ext string) *http.Request {
+	tileReq := r.WithContext(httptreemux.AddParamsToContext(r.Context(), map[string]string{
+		"map_name": mapName,
+		"z":        z,
+		"x":        x,
+		"y":        y + "." + ext,
+	}))
+	u := *r.URL
+	u.Path = path.Join(URIPrefix, "maps", mapName, z, x, y+"."+ext)
+	tileReq.URL = &u
+	return tileReq
+}
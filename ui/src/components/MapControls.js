@@ -0,0 +1,70 @@
+import { store, mutations } from "@/globals/store";
+
+// newControlButton builds the container and button of a control
+function newControlButton(title, className, onclick) {
+  let container = document.createElement("div");
+  container.className = "mapboxgl-ctrl mapboxgl-ctrl-group";
+
+  let btn = document.createElement("button");
+  btn.title = title;
+  btn.className = "mapboxgl-ctrl-icon " + className;
+  btn.onclick = function () {
+    btn.classList.toggle("active", onclick());
+  };
+  container.appendChild(btn);
+
+  return container;
+}
+
+// ToggleTileBoundariesControl is responsible for toggling tile boundary
+// debug outlines
+export class ToggleTileBoundariesControl {
+  // required to meet the iControl interface
+  onAdd(map) {
+    this._map = map;
+
+    // toggle the tile boundaries on / off on click
+    this._container = newControlButton(
+      "Toggle tile boundaries",
+      "mapboxgl-ctrl-toggle-tile-boundaries",
+      function () {
+        map.showTileBoundaries = !map.showTileBoundaries;
+        return map.showTileBoundaries;
+      }
+    );
+
+    return this._container;
+  }
+
+  // required to meet the iControl interface
+  onRemove() {
+    this._container.parentNode.removeChild(this._container);
+    this._map = undefined;
+  }
+}
+
+// ToggleDebugLayersControl is responsible for toggling tegola's debug layers,
+// the outline and center of every tile, which are rendered by the server
+export class ToggleDebugLayersControl {
+  // required to meet the iControl interface
+  onAdd(map) {
+    this._map = map;
+
+    this._container = newControlButton(
+      "Toggle debug layers",
+      "mapboxgl-ctrl-toggle-debug-layers",
+      function () {
+        mutations.setDebug(!store.debug);
+        return store.debug;
+      }
+    );
+
+    return this._container;
+  }
+
+  // required to meet the iControl interface
+  onRemove() {
+    this._container.parentNode.removeChild(this._container);
+    this._map = undefined;
+  }
+}
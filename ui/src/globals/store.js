@@ -10,13 +10,17 @@ export const store = Vue.observable({
   // capabilities holds the TileJSON returned by tegola on load
   capabilities: null,
 
-  // mbglIsReady is a flag to indicate that Mapbox GL is loaded and the style is loaded
+  // debug is a flag to include tegola's debug layers in the active map's style
+  debug: false,
+
+  // mbglIsReady is a flag to indicate that MapLibre GL is loaded and the style is loaded
   mbglIsReady: false
 });
 
 export const mutations = {
   setActiveMap(map) {
     store.mbglIsReady = false;
+    store.debug = false;
     store.activeMap = map;
   },
   setApiRoot(apiRoot) {
@@ -25,6 +29,9 @@ export const mutations = {
   setCapabilities(capabilities) {
     store.capabilities = capabilities;
   },
+  setDebug(val) {
+    store.debug = val;
+  },
   setMbglIsReady(val) {
     store.mbglIsReady = val;
   }